@@ -24,8 +24,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/route"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
+	"gopkg.in/alecthomas/kingpin.v2"
 
 	blocksAPI "github.com/thanos-io/thanos/pkg/api/blocks"
 	"github.com/thanos-io/thanos/pkg/block"
@@ -34,6 +36,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/component"
 	"github.com/thanos-io/thanos/pkg/dedup"
+	hidden "github.com/thanos-io/thanos/pkg/extflag"
 	"github.com/thanos-io/thanos/pkg/extkingpin"
 	"github.com/thanos-io/thanos/pkg/extprom"
 	extpromhttp "github.com/thanos-io/thanos/pkg/extprom/http"
@@ -43,6 +46,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/runutil"
 	httpserver "github.com/thanos-io/thanos/pkg/server/http"
 	"github.com/thanos-io/thanos/pkg/store"
+	storecache "github.com/thanos-io/thanos/pkg/store/cache"
 	"github.com/thanos-io/thanos/pkg/tracing"
 	"github.com/thanos-io/thanos/pkg/ui"
 )
@@ -91,7 +95,7 @@ func registerCompact(app *extkingpin.App) {
 	conf.registerFlag(cmd)
 
 	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, _ <-chan struct{}, _ bool) error {
-		return runCompact(g, logger, tracer, reg, component.Compact, *conf, getFlagsMap(cmd.Flags()))
+		return runCompact(g, logger, tracer, reg, component.Compact, *conf, cmd.Flags())
 	})
 }
 
@@ -105,6 +109,7 @@ type compactMetrics struct {
 	blockCleanupFailures        prometheus.Counter
 	blocksMarked                *prometheus.CounterVec
 	garbageCollectedBlocks      prometheus.Counter
+	blocksMovedToColdTier       prometheus.Counter
 }
 
 func newCompactMetrics(reg *prometheus.Registry, deleteDelay time.Duration) *compactMetrics {
@@ -158,6 +163,10 @@ func newCompactMetrics(reg *prometheus.Registry, deleteDelay time.Duration) *com
 		Name: "thanos_compact_garbage_collected_blocks_total",
 		Help: "Total number of blocks marked for deletion by compactor.",
 	})
+	m.blocksMovedToColdTier = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "thanos_compact_blocks_moved_to_cold_tier_total",
+		Help: "Total number of blocks moved to the cold tier of a tiered bucket by compactor.",
+	})
 	return m
 }
 
@@ -168,22 +177,38 @@ func runCompact(
 	reg *prometheus.Registry,
 	component component.Component,
 	conf compactConfig,
-	flagsMap map[string]string,
+	cmdFlags []*kingpin.FlagModel,
 ) (rerr error) {
 	deleteDelay := time.Duration(conf.deleteDelay)
 	compactMetrics := newCompactMetrics(reg, deleteDelay)
 	downsampleMetrics := newDownsampleMetrics(reg)
 
+	downsampleLevels, err := downsample.ParseLevels(conf.downsampleLevels)
+	if err != nil {
+		return errors.Wrap(err, "parse downsampling levels")
+	}
+
 	httpProbe := prober.NewHTTP()
 	statusProber := prober.Combine(
 		httpProbe,
 		prober.NewInstrumentation(component, logger, extprom.WrapRegistererWithPrefix("thanos_", reg)),
 	)
 
+	authMiddleware, err := oidcMiddleware(logger, reg, conf.http.oidcConfig)
+	if err != nil {
+		return err
+	}
+	ipf, err := ipFilters(logger, reg, conf.http.ipFilterConfig)
+	if err != nil {
+		return err
+	}
 	srv := httpserver.New(logger, reg, component, httpProbe,
 		httpserver.WithListen(conf.http.bindAddress),
 		httpserver.WithGracePeriod(time.Duration(conf.http.gracePeriod)),
 		httpserver.WithTLSConfig(conf.http.tlsConfig),
+		statusOption(cmdFlags),
+		httpserver.WithAuthentication(authMiddleware),
+		httpserver.WithIPFilter(ipf.http),
 	)
 
 	g.Add(func() error {
@@ -202,11 +227,22 @@ func runCompact(
 		return err
 	}
 
-	bkt, err := client.NewBucket(logger, confContentYaml, reg, component.String())
+	bkt, tieredBkt, err := client.NewBucketWithTiers(logger, confContentYaml, reg, component.String())
 	if err != nil {
 		return err
 	}
 
+	cachingBucketConfigYaml, err := conf.cachingBucketConfig.Content()
+	if err != nil {
+		return errors.Wrap(err, "get caching bucket configuration")
+	}
+	if len(cachingBucketConfigYaml) > 0 {
+		bkt, err = storecache.NewMetafileCachingBucketFromYaml(cachingBucketConfigYaml, bkt, logger, reg)
+		if err != nil {
+			return errors.Wrap(err, "create caching bucket")
+		}
+	}
+
 	relabelContentYaml, err := conf.selectorRelabelConf.Content()
 	if err != nil {
 		return errors.Wrap(err, "get content of relabel configuration")
@@ -230,11 +266,16 @@ func runCompact(
 	ignoreDeletionMarkFilter := block.NewIgnoreDeletionMarkFilter(logger, bkt, deleteDelay/2, conf.blockMetaFetchConcurrency)
 	duplicateBlocksFilter := block.NewDeduplicateFilter()
 	noCompactMarkerFilter := compact.NewGatherNoCompactionMarkFilter(logger, bkt, conf.blockMetaFetchConcurrency)
+	noDownsampleMarkerFilter := compact.NewGatherNoDownsampleMarkFilter(logger, bkt, conf.blockMetaFetchConcurrency)
 	labelShardedMetaFilter := block.NewLabelShardedMetaFilter(relabelConfig)
 	consistencyDelayMetaFilter := block.NewConsistencyDelayMetaFilter(logger, conf.consistencyDelay, extprom.WrapRegistererWithPrefix("thanos_", reg))
 	timePartitionMetaFilter := block.NewTimePartitionMetaFilter(conf.filterConf.MinTime, conf.filterConf.MaxTime)
 
-	baseMetaFetcher, err := block.NewBaseFetcher(logger, conf.blockMetaFetchConcurrency, bkt, "", extprom.WrapRegistererWithPrefix("thanos_", reg))
+	var fetcherOpts []block.BaseFetcherOption
+	if conf.bucketIndexUpload {
+		fetcherOpts = append(fetcherOpts, block.WithBucketIndexUpload(bkt))
+	}
+	baseMetaFetcher, err := block.NewBaseFetcher(logger, conf.blockMetaFetchConcurrency, bkt, "", extprom.WrapRegistererWithPrefix("thanos_", reg), fetcherOpts...)
 	if err != nil {
 		return errors.Wrap(err, "create meta fetcher")
 	}
@@ -260,7 +301,7 @@ func runCompact(
 			"/loaded",
 			component,
 		)
-		api = blocksAPI.NewBlocksAPI(logger, conf.webConf.disableCORS, conf.label, flagsMap, bkt)
+		api = blocksAPI.NewBlocksAPI(logger, conf.webConf.disableCORS, conf.label, extkingpin.RedactedFlags(cmdFlags), bkt)
 		sy  *compact.Syncer
 	)
 	{
@@ -273,6 +314,7 @@ func runCompact(
 				ignoreDeletionMarkFilter,
 				duplicateBlocksFilter,
 				noCompactMarkerFilter,
+				noDownsampleMarkerFilter,
 			}, []block.MetadataModifier{block.NewReplicaLabelRemover(logger, conf.dedupReplicaLabels)},
 		)
 		cf.UpdateOnChange(func(blocks []metadata.Meta, err error) {
@@ -357,6 +399,7 @@ func runCompact(
 		compactMetrics.garbageCollectedBlocks,
 		compactMetrics.blocksMarked.WithLabelValues(metadata.NoCompactMarkFilename, metadata.OutOfOrderChunksNoCompactReason),
 		metadata.HashFunc(conf.hashFunc),
+		conf.groupKeyLabels,
 	)
 	tsdbPlanner := compact.NewPlanner(logger, levels, noCompactMarkerFilter)
 	planner := compact.WithLargeTotalIndexSizeFilter(
@@ -396,6 +439,14 @@ func runCompact(
 	if retentionByResolution[compact.ResolutionLevel1h].Seconds() != 0 {
 		level.Info(logger).Log("msg", "retention policy of 1 hour aggregated samples is enabled", "duration", retentionByResolution[compact.ResolutionLevel1h])
 	}
+	for _, r := range conf.retentionResolutions {
+		res, retention, err := parseResolutionRetention(r)
+		if err != nil {
+			return err
+		}
+		retentionByResolution[res] = retention
+		level.Info(logger).Log("msg", "retention policy of aggregated samples is enabled", "resolution", time.Duration(res)*time.Millisecond, "duration", retention)
+	}
 
 	var cleanMtx sync.Mutex
 	// TODO(GiedriusS): we could also apply retention policies here but the logic would be a bit more complex.
@@ -408,7 +459,7 @@ func runCompact(
 			return errors.Wrap(err, "syncing metas")
 		}
 
-		compact.BestEffortCleanAbortedPartialUploads(ctx, logger, sy.Partial(), bkt, compactMetrics.partialUploadDeleteAttempts, compactMetrics.blocksCleaned, compactMetrics.blockCleanupFailures)
+		compact.BestEffortCleanAbortedPartialUploads(ctx, logger, sy.Partial(), bkt, conf.partialUploadThresholdAge, compactMetrics.partialUploadDeleteAttempts, compactMetrics.blocksCleaned, compactMetrics.blockCleanupFailures)
 		if err := blocksCleaner.DeleteMarkedBlocks(ctx); err != nil {
 			return errors.Wrap(err, "cleaning marked blocks")
 		}
@@ -424,28 +475,24 @@ func runCompact(
 
 		if !conf.disableDownsampling {
 			// After all compactions are done, work down the downsampling backlog.
-			// We run two passes of this to ensure that the 1h downsampling is generated
-			// for 5m downsamplings created in the first run.
-			level.Info(logger).Log("msg", "start first pass of downsampling")
-			if err := sy.SyncMetas(ctx); err != nil {
-				return errors.Wrap(err, "sync before first pass of downsampling")
-			}
-
-			for _, meta := range sy.Metas() {
-				groupKey := compact.DefaultGroupKey(meta.Thanos)
-				downsampleMetrics.downsamples.WithLabelValues(groupKey)
-				downsampleMetrics.downsampleFailures.WithLabelValues(groupKey)
-			}
-			if err := downsampleBucket(ctx, logger, downsampleMetrics, bkt, sy.Metas(), downsamplingDir, conf.downsampleConcurrency, metadata.HashFunc(conf.hashFunc)); err != nil {
-				return errors.Wrap(err, "first pass of downsampling failed")
-			}
+			// We run a pass of this per downsampling level to ensure that each resolution is generated
+			// for the blocks of the previous resolution created in the previous pass.
+			for pass := 1; pass <= len(downsampleLevels); pass++ {
+				level.Info(logger).Log("msg", "start pass of downsampling", "pass", pass)
+				if err := sy.SyncMetas(ctx); err != nil {
+					return errors.Wrapf(err, "sync before pass %d of downsampling", pass)
+				}
 
-			level.Info(logger).Log("msg", "start second pass of downsampling")
-			if err := sy.SyncMetas(ctx); err != nil {
-				return errors.Wrap(err, "sync before second pass of downsampling")
-			}
-			if err := downsampleBucket(ctx, logger, downsampleMetrics, bkt, sy.Metas(), downsamplingDir, conf.downsampleConcurrency, metadata.HashFunc(conf.hashFunc)); err != nil {
-				return errors.Wrap(err, "second pass of downsampling failed")
+				if pass == 1 {
+					for _, meta := range sy.Metas() {
+						groupKey := compact.DefaultGroupKey(meta.Thanos)
+						downsampleMetrics.downsamples.WithLabelValues(groupKey)
+						downsampleMetrics.downsampleFailures.WithLabelValues(groupKey)
+					}
+				}
+				if err := downsampleBucket(ctx, logger, downsampleMetrics, bkt, sy.Metas(), downsamplingDir, conf.downsampleConcurrency, metadata.HashFunc(conf.hashFunc), downsampleLevels, excludeNoDownsampleMarked(nil, noDownsampleMarkerFilter.NoDownsampleMarkedBlocks())); err != nil {
+					return errors.Wrapf(err, "pass %d of downsampling failed", pass)
+				}
 			}
 			level.Info(logger).Log("msg", "downsampling iterations done")
 		} else {
@@ -457,13 +504,38 @@ func runCompact(
 			return errors.Wrap(err, "sync before retention")
 		}
 
-		if err := compact.ApplyRetentionPolicyByResolution(ctx, logger, bkt, sy.Metas(), retentionByResolution, compactMetrics.blocksMarked.WithLabelValues(metadata.DeletionMarkFilename, "")); err != nil {
+		if conf.retentionDryRun {
+			report, err := compact.RetentionDryRun(ctx, bkt, sy.Metas(), retentionByResolution)
+			if err != nil {
+				return errors.Wrap(err, "retention dry run failed")
+			}
+			logRetentionReport(logger, report)
+		} else if err := compact.ApplyRetentionPolicyByResolution(ctx, logger, bkt, sy.Metas(), retentionByResolution, compactMetrics.blocksMarked.WithLabelValues(metadata.DeletionMarkFilename, "")); err != nil {
 			return errors.Wrap(err, "retention failed")
 		}
 
+		if tieredBkt != nil {
+			if err := compact.MoveBlocksToColdTier(ctx, logger, tieredBkt, sy.Metas(), compactMetrics.blocksMovedToColdTier); err != nil {
+				return errors.Wrap(err, "moving blocks to cold tier failed")
+			}
+		}
+
 		return cleanPartialMarked()
 	}
 
+	// compactTriggers triggers compaction runs out of the wait interval when the metas of blocks change.
+	compactTriggers := make(chan struct{}, 1)
+	if conf.wait {
+		if err := watchBucketNotifications(g, logger, reg, conf.bucketNotifications, func() {
+			select {
+			case compactTriggers <- struct{}{}:
+			default:
+			}
+		}); err != nil {
+			return err
+		}
+	}
+
 	g.Add(func() error {
 		defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
 
@@ -472,7 +544,7 @@ func runCompact(
 		}
 
 		// --wait=true is specified.
-		return runutil.Repeat(conf.waitInterval, ctx.Done(), func() error {
+		return runutil.RepeatOrTrigger(conf.waitInterval, ctx.Done(), compactTriggers, func() error {
 			err := compactMainFn()
 			if err == nil {
 				compactMetrics.iterations.Inc()
@@ -549,7 +621,7 @@ func runCompact(
 				rs := compact.NewRetentionProgressCalculator(reg, retentionByResolution)
 				var ds *compact.DownsampleProgressCalculator
 				if !conf.disableDownsampling {
-					ds = compact.NewDownsampleProgressCalculator(reg)
+					ds = compact.NewDownsampleProgressCalculator(reg, downsampleLevels)
 				}
 
 				return runutil.Repeat(conf.progressCalculateInterval, ctx.Done(), func() error {
@@ -626,20 +698,28 @@ type compactConfig struct {
 	http                                           httpConfig
 	dataDir                                        string
 	objStore                                       extflag.PathOrContent
+	cachingBucketConfig                            extflag.PathOrContent
+	bucketNotifications                            *extflag.PathOrContent
 	consistencyDelay                               time.Duration
+	partialUploadThresholdAge                      time.Duration
 	retentionRaw, retentionFiveMin, retentionOneHr model.Duration
+	retentionResolutions                           []string
+	retentionDryRun                                bool
 	wait                                           bool
 	waitInterval                                   time.Duration
 	disableDownsampling                            bool
 	blockSyncConcurrency                           int
 	blockMetaFetchConcurrency                      int
+	bucketIndexUpload                              bool
 	blockViewerSyncBlockInterval                   time.Duration
 	blockViewerSyncBlockTimeout                    time.Duration
 	cleanupBlocksInterval                          time.Duration
 	compactionConcurrency                          int
 	downsampleConcurrency                          int
+	downsampleLevels                               []string
 	deleteDelay                                    model.Duration
 	dedupReplicaLabels                             []string
+	groupKeyLabels                                 []string
 	selectorRelabelConf                            extflag.PathOrContent
 	webConf                                        webConfig
 	label                                          string
@@ -652,6 +732,36 @@ type compactConfig struct {
 	filterConf                                     *store.FilterConfig
 }
 
+// logRetentionReport logs the blocks which retention would delete and their totals per external label set.
+func logRetentionReport(logger log.Logger, report compact.RetentionReport) {
+	for _, b := range report.Blocks {
+		level.Info(logger).Log("msg", "retention dry run: block would be marked for deletion", "id", b.Meta.ULID,
+			"labels", labels.FromMap(b.Meta.Thanos.Labels).String(), "resolution", b.Meta.Thanos.Downsample.Resolution,
+			"maxTime", time.Unix(b.Meta.MaxTime/1000, 0).String(), "retention", b.Retention, "objects", b.Objects, "size", b.Size)
+	}
+	for _, t := range report.Totals {
+		level.Info(logger).Log("msg", "retention dry run: total per label set", "labels", t.Labels.String(),
+			"blocks", t.Blocks, "objects", t.Objects, "size", t.Size)
+	}
+}
+
+// parseResolutionRetention parses the retention of a resolution of the form <resolution>:<duration>.
+func parseResolutionRetention(s string) (compact.ResolutionLevel, time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, 0, errors.Errorf("invalid resolution retention %q, expected <resolution>:<duration>", s)
+	}
+	res, err := model.ParseDuration(parts[0])
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "parse resolution of resolution retention %q", s)
+	}
+	retention, err := model.ParseDuration(parts[1])
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "parse duration of resolution retention %q", s)
+	}
+	return compact.ResolutionLevel(time.Duration(res).Milliseconds()), time.Duration(retention), nil
+}
+
 func (cc *compactConfig) registerFlag(cmd extkingpin.FlagClause) {
 	cmd.Flag("debug.halt-on-error", "Halt the process if a critical compaction error is detected.").
 		Hidden().Default("true").BoolVar(&cc.haltOnError)
@@ -668,8 +778,17 @@ func (cc *compactConfig) registerFlag(cmd extkingpin.FlagClause) {
 
 	cc.objStore = *extkingpin.RegisterCommonObjStoreFlags(cmd, "", false)
 
-	cmd.Flag("consistency-delay", fmt.Sprintf("Minimum age of fresh (non-compacted) blocks before they are being processed. Malformed blocks older than the maximum of consistency-delay and %v will be removed.", compact.PartialUploadThresholdAge)).
+	cc.cachingBucketConfig = *extflag.RegisterPathOrContent(hidden.HiddenCmdClause(cmd), "compact.caching-bucket.config",
+		"YAML that contains configuration for caching bucket. Only metadata files are cached, in a cache which can be shared with store gateways. Experimental feature, with high risk of changes. See format details: https://thanos.io/tip/components/store.md/#caching-bucket",
+		extflag.WithEnvSubstitution(),
+	)
+
+	cc.bucketNotifications = registerBucketNotificationsFlag(cmd)
+
+	cmd.Flag("consistency-delay", "Minimum age of fresh (non-compacted) blocks before they are being processed. Malformed blocks older than the maximum of consistency-delay and partial-upload-threshold-age will be removed.").
 		Default("30m").DurationVar(&cc.consistencyDelay)
+	cmd.Flag("partial-upload-threshold-age", "Minimum age of partially uploaded blocks, i.e. blocks without or with malformed meta.json, before their upload is assumed to be aborted and they are removed. The age is based on the block creation time, so keep it longer than uploads can take.").
+		Default(compact.PartialUploadThresholdAge.String()).DurationVar(&cc.partialUploadThresholdAge)
 
 	cmd.Flag("retention.resolution-raw",
 		"How long to retain raw samples in bucket. Setting this to 0d will retain samples of this resolution forever").
@@ -678,6 +797,10 @@ func (cc *compactConfig) registerFlag(cmd extkingpin.FlagClause) {
 		Default("0d").SetValue(&cc.retentionFiveMin)
 	cmd.Flag("retention.resolution-1h", "How long to retain samples of resolution 2 (1 hour) in bucket. Setting this to 0d will retain samples of this resolution forever").
 		Default("0d").SetValue(&cc.retentionOneHr)
+	cmd.Flag("retention.resolution", "How long to retain samples of a custom resolution configured with --downsample.level in bucket, in the form <resolution>:<duration>, e.g. 6h:5y. Samples of resolutions without retention are retained forever. Repeated flag.").
+		StringsVar(&cc.retentionResolutions)
+	cmd.Flag("retention.dry-run", "Log the blocks which exceed retention and would be marked for deletion, with their sizes and the totals per external label set, instead of marking them.").
+		Default("false").BoolVar(&cc.retentionDryRun)
 
 	// TODO(kakkoyun, pgough): https://github.com/thanos-io/thanos/issues/2266.
 	cmd.Flag("wait", "Do not exit after all compactions have been processed and wait for new work.").
@@ -693,6 +816,8 @@ func (cc *compactConfig) registerFlag(cmd extkingpin.FlagClause) {
 		Default("20").IntVar(&cc.blockSyncConcurrency)
 	cmd.Flag("block-meta-fetch-concurrency", "Number of goroutines to use when fetching block metadata from object storage.").
 		Default("32").IntVar(&cc.blockMetaFetchConcurrency)
+	cmd.Flag("bucket-index.upload", "Upload the bucket index, holding the metas of all blocks, to the root of the bucket after each complete sync of metas. Store gateways with --bucket-index.max-stale-period take the metas of blocks from it.").
+		Default("false").BoolVar(&cc.bucketIndexUpload)
 	cmd.Flag("block-viewer.global.sync-block-interval", "Repeat interval for syncing the blocks between local and remote view for /global Block Viewer UI.").
 		Default("1m").DurationVar(&cc.blockViewerSyncBlockInterval)
 	cmd.Flag("block-viewer.global.sync-block-timeout", "Maximum time for syncing the blocks between local and remote view for /global Block Viewer UI.").
@@ -706,6 +831,9 @@ func (cc *compactConfig) registerFlag(cmd extkingpin.FlagClause) {
 		Default("1").IntVar(&cc.compactionConcurrency)
 	cmd.Flag("downsample.concurrency", "Number of goroutines to use when downsampling blocks.").
 		Default("1").IntVar(&cc.downsampleConcurrency)
+	cmd.Flag("downsample.level", "Downsampling resolution and minimum time range of the blocks of the previous resolution downsampled to it, in the form <resolution>:<min source range>, e.g. 6h:60d. "+
+		"Raw blocks are downsampled to the lowest resolution, and blocks of each resolution to the next one. If not specified, the default resolutions 5m:40h and 1h:10d are used. Repeated flag.").
+		StringsVar(&cc.downsampleLevels)
 
 	cmd.Flag("delete-delay", "Time before a block marked for deletion is deleted from bucket. "+
 		"If delete-delay is non zero, blocks will be marked for deletion and compactor component will delete blocks marked for deletion from the bucket. "+
@@ -730,6 +858,11 @@ func (cc *compactConfig) registerFlag(cmd extkingpin.FlagClause) {
 		"If you need a different deduplication algorithm (e.g one that works well with Prometheus replicas), please set it via --deduplication.func.").
 		StringsVar(&cc.dedupReplicaLabels)
 
+	cmd.Flag("compact.group-key-label", "Label forming the compaction group key of blocks, together with their resolution (repeated flag). By default, all the external labels of blocks form it. "+
+		"Blocks with the same values of these labels are compacted together even if their other labels differ, and the resulting block only keeps the labels common to all of them. "+
+		"Overlapping blocks of a group are only compacted with vertical compaction enabled.").
+		StringsVar(&cc.groupKeyLabels)
+
 	// TODO(bwplotka): This is short term fix for https://github.com/thanos-io/thanos/issues/1424, replace with vertical block sharding https://github.com/thanos-io/thanos/pull/3390.
 	cmd.Flag("compact.block-max-index-size", "Maximum index size for the resulted block during any compaction. Note that"+
 		"total size is approximated in worst case. If the block that would be resulted from compaction is estimated to exceed this number, biggest source"+
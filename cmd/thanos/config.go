@@ -9,10 +9,12 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/alecthomas/units"
 	extflag "github.com/efficientgo/tools/extkingpin"
 
 	"github.com/prometheus/common/model"
 	"github.com/thanos-io/thanos/pkg/extkingpin"
+	"github.com/thanos-io/thanos/pkg/shipper"
 )
 
 type grpcConfig struct {
@@ -21,6 +23,8 @@ type grpcConfig struct {
 	tlsSrvCert     string
 	tlsSrvKey      string
 	tlsSrvClientCA string
+	spiffeAddr     *string
+	spiffeIDs      *[]string
 }
 
 func (gc *grpcConfig) registerFlag(cmd extkingpin.FlagClause) *grpcConfig {
@@ -39,6 +43,7 @@ func (gc *grpcConfig) registerFlag(cmd extkingpin.FlagClause) *grpcConfig {
 	cmd.Flag("grpc-server-tls-client-ca",
 		"TLS CA to verify clients against. If no client CA is specified, there is no client verification on server side. (tls.NoClientCert)").
 		Default("").StringVar(&gc.tlsSrvClientCA)
+	gc.spiffeAddr, gc.spiffeIDs = extkingpin.RegisterSPIFFEFlags(cmd)
 	return gc
 }
 
@@ -46,6 +51,9 @@ type httpConfig struct {
 	bindAddress string
 	tlsConfig   string
 	gracePeriod model.Duration
+	oidcConfig  *extflag.PathOrContent
+	// ipFilterConfig is the configuration of the IP filters of all servers of the component, not only the HTTP one.
+	ipFilterConfig *extflag.PathOrContent
 }
 
 func (hc *httpConfig) registerFlag(cmd extkingpin.FlagClause) *httpConfig {
@@ -59,13 +67,16 @@ func (hc *httpConfig) registerFlag(cmd extkingpin.FlagClause) *httpConfig {
 		"http.config",
 		"[EXPERIMENTAL] Path to the configuration file that can enable TLS or authentication for all HTTP endpoints.",
 	).Default("").StringVar(&hc.tlsConfig)
+	hc.oidcConfig = extkingpin.RegisterHTTPOIDCFlags(cmd)
+	hc.ipFilterConfig = extkingpin.RegisterIPFilterFlags(cmd)
 	return hc
 }
 
 type prometheusConfig struct {
-	url          *url.URL
-	readyTimeout time.Duration
-	httpClient   *extflag.PathOrContent
+	url                *url.URL
+	readyTimeout       time.Duration
+	readyCheckInterval time.Duration
+	httpClient         *extflag.PathOrContent
 }
 
 func (pc *prometheusConfig) registerFlag(cmd extkingpin.FlagClause) *prometheusConfig {
@@ -75,6 +86,9 @@ func (pc *prometheusConfig) registerFlag(cmd extkingpin.FlagClause) *prometheusC
 	cmd.Flag("prometheus.ready_timeout",
 		"Maximum time to wait for the Prometheus instance to start up").
 		Default("10m").DurationVar(&pc.readyTimeout)
+	cmd.Flag("prometheus.ready-check-interval",
+		"How often to check the readiness of Prometheus. While Prometheus is not ready, e.g. replaying its WAL after a restart, the sidecar is not ready and advertises an empty time range to queriers.").
+		Default("5s").DurationVar(&pc.readyCheckInterval)
 	pc.httpClient = extflag.RegisterPathOrContent(
 		cmd,
 		"prometheus.http-client",
@@ -96,6 +110,8 @@ func (tc *tsdbConfig) registerFlag(cmd extkingpin.FlagClause) *tsdbConfig {
 type reloaderConfig struct {
 	confFile        string
 	envVarConfFile  string
+	confTemplate    bool
+	confValidate    bool
 	ruleDirectories []string
 	watchInterval   time.Duration
 	retryInterval   time.Duration
@@ -108,6 +124,12 @@ func (rc *reloaderConfig) registerFlag(cmd extkingpin.FlagClause) *reloaderConfi
 	cmd.Flag("reloader.config-envsubst-file",
 		"Output file for environment variable substituted config file.").
 		Default("").StringVar(&rc.envVarConfFile)
+	cmd.Flag("reloader.config-template",
+		"If true, the config file is rendered as a Go template into the output file, with the env, include (content of the files matching a glob) and indent functions. Included files are watched for changes.").
+		Default("false").BoolVar(&rc.confTemplate)
+	cmd.Flag("reloader.config-validate",
+		"If true, the output config file is validated as a Prometheus config before it is written, and reloads are not triggered for invalid configs. Disable it if Prometheus supports config fields unknown to Thanos.").
+		Default("true").BoolVar(&rc.confValidate)
 	cmd.Flag("reloader.rule-dir",
 		"Rule directories for the reloader to refresh (repeated field).").
 		StringsVar(&rc.ruleDirectories)
@@ -126,11 +148,14 @@ type shipperConfig struct {
 	ignoreBlockSize       bool
 	allowOutOfOrderUpload bool
 	hashFunc              string
+	uploadBandwidthLimit  units.Base2Bytes
+	uploadBlackouts       []string
+	uploadHintURLs        []string
 }
 
 func (sc *shipperConfig) registerFlag(cmd extkingpin.FlagClause) *shipperConfig {
 	cmd.Flag("shipper.upload-compacted",
-		"If true shipper will try to upload compacted blocks as well. Useful for migration purposes. Works only if compaction is disabled on Prometheus. Do it once and then disable the flag when done.").
+		"If true shipper will try to upload compacted blocks as well. Useful for migration purposes, or to keep compaction enabled on Prometheus. Compacted blocks are skipped if all their source blocks were uploaded, and uploaded otherwise, the compactor then removing their source blocks uploaded already.").
 		Default("false").BoolVar(&sc.uploadCompacted)
 	cmd.Flag("shipper.ignore-unequal-block-size",
 		"If true shipper will not require prometheus min and max block size flags to be set to the same value. Only use this if you want to keep long retention and compaction enabled on your Prometheus instance, as in the worst case it can result in ~2h data loss for your Thanos bucket storage.").
@@ -142,9 +167,35 @@ func (sc *shipperConfig) registerFlag(cmd extkingpin.FlagClause) *shipperConfig
 		Default("false").Hidden().BoolVar(&sc.allowOutOfOrderUpload)
 	cmd.Flag("hash-func", "Specify which hash function to use when calculating the hashes of produced files. If no function has been specified, it does not happen. This permits avoiding downloading some files twice albeit at some performance cost. Possible values are: \"\", \"SHA256\".").
 		Default("").EnumVar(&sc.hashFunc, "SHA256", "")
+	cmd.Flag("shipper.upload-bandwidth-limit",
+		"Maximum number of bytes uploaded per second by the shipper. 0 disables the limit.").
+		Default("0B").BytesVar(&sc.uploadBandwidthLimit)
+	cmd.Flag("shipper.upload-blackout-window",
+		"Daily time window in UTC during which the shipper defers block uploads, of the form '[<weekdays>] <HH:MM>-<HH:MM>', e.g. 'Mon-Fri 09:00-17:00' (repeated field).").
+		StringsVar(&sc.uploadBlackouts)
+	cmd.Flag("shipper.upload-hint-url",
+		"URL to send a POST request to after uploading blocks, e.g. 'http://<store>/api/v1/blocks/sync' for store gateways to load new blocks without waiting for their next sync (repeated field).").
+		StringsVar(&sc.uploadHintURLs)
 	return sc
 }
 
+// options returns the shipper options for the upload bandwidth limit, blackout windows and hints.
+func (sc *shipperConfig) options() ([]shipper.Option, error) {
+	windows := make([]shipper.BlackoutWindow, 0, len(sc.uploadBlackouts))
+	for _, b := range sc.uploadBlackouts {
+		w, err := shipper.ParseBlackoutWindow(b)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return []shipper.Option{
+		shipper.WithUploadBandwidthLimit(int64(sc.uploadBandwidthLimit)),
+		shipper.WithUploadBlackoutWindows(windows),
+		shipper.WithUploadHintURLs(sc.uploadHintURLs),
+	}, nil
+}
+
 type webConfig struct {
 	routePrefix      string
 	externalPrefix   string
@@ -21,6 +21,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"gopkg.in/alecthomas/kingpin.v2"
 
 	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
@@ -69,11 +70,16 @@ func RunDownsample(
 	httpBindAddr string,
 	httpTLSConfig string,
 	httpGracePeriod time.Duration,
+	httpOIDCConfig *extflag.PathOrContent,
+	ipFilterConfig *extflag.PathOrContent,
 	dataDir string,
 	downsampleConcurrency int,
 	objStoreConfig *extflag.PathOrContent,
 	comp component.Component,
 	hashFunc metadata.HashFunc,
+	levels downsample.Levels,
+	selection downsampleSelection,
+	cmdFlags []*kingpin.FlagModel,
 ) error {
 	confContentYaml, err := objStoreConfig.Content()
 	if err != nil {
@@ -85,8 +91,10 @@ func RunDownsample(
 		return err
 	}
 
+	noDownsampleMarkerFilter := compact.NewGatherNoDownsampleMarkFilter(logger, bkt, block.FetcherConcurrency)
 	metaFetcher, err := block.NewMetaFetcher(logger, block.FetcherConcurrency, bkt, "", extprom.WrapRegistererWithPrefix("thanos_", reg), []block.MetadataFilter{
 		block.NewDeduplicateFilter(),
+		noDownsampleMarkerFilter,
 	}, nil)
 	if err != nil {
 		return errors.Wrap(err, "create meta fetcher")
@@ -114,28 +122,23 @@ func RunDownsample(
 			defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
 			statusProber.Ready()
 
-			level.Info(logger).Log("msg", "start first pass of downsampling")
-			metas, _, err := metaFetcher.Fetch(ctx)
-			if err != nil {
-				return errors.Wrap(err, "sync before first pass of downsampling")
-			}
-
-			for _, meta := range metas {
-				groupKey := compact.DefaultGroupKey(meta.Thanos)
-				metrics.downsamples.WithLabelValues(groupKey)
-				metrics.downsampleFailures.WithLabelValues(groupKey)
-			}
-			if err := downsampleBucket(ctx, logger, metrics, bkt, metas, dataDir, downsampleConcurrency, hashFunc); err != nil {
-				return errors.Wrap(err, "downsampling failed")
-			}
+			for pass := 1; pass <= len(levels); pass++ {
+				level.Info(logger).Log("msg", "start pass of downsampling", "pass", pass)
+				metas, _, err := metaFetcher.Fetch(ctx)
+				if err != nil {
+					return errors.Wrapf(err, "sync before pass %d of downsampling", pass)
+				}
 
-			level.Info(logger).Log("msg", "start second pass of downsampling")
-			metas, _, err = metaFetcher.Fetch(ctx)
-			if err != nil {
-				return errors.Wrap(err, "sync before second pass of downsampling")
-			}
-			if err := downsampleBucket(ctx, logger, metrics, bkt, metas, dataDir, downsampleConcurrency, hashFunc); err != nil {
-				return errors.Wrap(err, "downsampling failed")
+				if pass == 1 {
+					for _, meta := range metas {
+						groupKey := compact.DefaultGroupKey(meta.Thanos)
+						metrics.downsamples.WithLabelValues(groupKey)
+						metrics.downsampleFailures.WithLabelValues(groupKey)
+					}
+				}
+				if err := downsampleBucket(ctx, logger, metrics, bkt, metas, dataDir, downsampleConcurrency, hashFunc, levels, excludeNoDownsampleMarked(selection.filter(metas), noDownsampleMarkerFilter.NoDownsampleMarkedBlocks())); err != nil {
+					return errors.Wrap(err, "downsampling failed")
+				}
 			}
 
 			return nil
@@ -144,10 +147,21 @@ func RunDownsample(
 		})
 	}
 
+	authMiddleware, err := oidcMiddleware(logger, reg, httpOIDCConfig)
+	if err != nil {
+		return err
+	}
+	ipf, err := ipFilters(logger, reg, ipFilterConfig)
+	if err != nil {
+		return err
+	}
 	srv := httpserver.New(logger, reg, comp, httpProbe,
 		httpserver.WithListen(httpBindAddr),
 		httpserver.WithGracePeriod(httpGracePeriod),
 		httpserver.WithTLSConfig(httpTLSConfig),
+		statusOption(cmdFlags),
+		httpserver.WithAuthentication(authMiddleware),
+		httpserver.WithIPFilter(ipf.http),
 	)
 
 	g.Add(func() error {
@@ -165,6 +179,69 @@ func RunDownsample(
 	return nil
 }
 
+// downsampleSelection selects the blocks which are downsampled by ID and time range. The zero value selects all blocks.
+type downsampleSelection struct {
+	ids              map[ulid.ULID]struct{}
+	minTime, maxTime int64
+	timeRange        bool
+}
+
+// filter returns a filter of the blocks to be downsampled, given all blocks of the bucket, or nil if all blocks are
+// selected. Blocks are selected if they overlap with the time range. If IDs are specified, downsampled blocks are
+// also selected if all their sources are sources of the blocks with these IDs, so they are downsampled further in
+// the following passes.
+func (s downsampleSelection) filter(metas map[ulid.ULID]*metadata.Meta) func(m *metadata.Meta) bool {
+	if len(s.ids) == 0 && !s.timeRange {
+		return nil
+	}
+
+	sources := map[ulid.ULID]struct{}{}
+	for id := range s.ids {
+		if m, ok := metas[id]; ok {
+			for _, src := range m.Compaction.Sources {
+				sources[src] = struct{}{}
+			}
+		}
+	}
+
+	return func(m *metadata.Meta) bool {
+		if s.timeRange && (m.MaxTime < s.minTime || m.MinTime > s.maxTime) {
+			return false
+		}
+		if len(s.ids) == 0 {
+			return true
+		}
+		if _, ok := s.ids[m.ULID]; ok {
+			return true
+		}
+		if m.Thanos.Downsample.Resolution == downsample.ResLevel0 || len(m.Compaction.Sources) == 0 {
+			return false
+		}
+		for _, src := range m.Compaction.Sources {
+			if _, ok := sources[src]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// excludeNoDownsampleMarked returns the given filter of the blocks to be downsampled, which may be nil, extended to
+// exclude the blocks which are marked for no downsampling.
+func excludeNoDownsampleMarked(filter func(m *metadata.Meta) bool, marked map[ulid.ULID]*metadata.NoDownsampleMark) func(m *metadata.Meta) bool {
+	if len(marked) == 0 {
+		return filter
+	}
+	return func(m *metadata.Meta) bool {
+		if _, ok := marked[m.ULID]; ok {
+			return false
+		}
+		return filter == nil || filter(m)
+	}
+}
+
+// downsampleBucket downsamples the blocks of the bucket which weren't downsampled yet to the next resolution of the
+// given levels. If filter is not nil, only blocks for which it returns true are downsampled.
 func downsampleBucket(
 	ctx context.Context,
 	logger log.Logger,
@@ -174,6 +251,8 @@ func downsampleBucket(
 	dir string,
 	downsampleConcurrency int,
 	hashFunc metadata.HashFunc,
+	levels downsample.Levels,
+	filter func(m *metadata.Meta) bool,
 ) (rerr error) {
 	if err := os.MkdirAll(dir, 0750); err != nil {
 		return errors.Wrap(err, "create dir")
@@ -190,26 +269,24 @@ func downsampleBucket(
 		}
 	}()
 
-	// mapping from a hash over all source IDs to blocks. We don't need to downsample a block
-	// if a downsampled version with the same hash already exists.
-	sources5m := map[ulid.ULID]struct{}{}
-	sources1h := map[ulid.ULID]struct{}{}
+	// mapping from resolutions to the source IDs of the blocks of this resolution. We don't need to downsample a block
+	// if a downsampled version with the same sources already exists.
+	sources := map[int64]map[ulid.ULID]struct{}{}
+	for _, lvl := range levels {
+		sources[lvl.Resolution] = map[ulid.ULID]struct{}{}
+	}
 
 	for _, m := range metas {
-		switch m.Thanos.Downsample.Resolution {
-		case downsample.ResLevel0:
+		if m.Thanos.Downsample.Resolution == downsample.ResLevel0 {
 			continue
-		case downsample.ResLevel1:
-			for _, id := range m.Compaction.Sources {
-				sources5m[id] = struct{}{}
-			}
-		case downsample.ResLevel2:
-			for _, id := range m.Compaction.Sources {
-				sources1h[id] = struct{}{}
-			}
-		default:
+		}
+		resSources, ok := sources[m.Thanos.Downsample.Resolution]
+		if !ok {
 			return errors.Errorf("unexpected downsampling resolution %d", m.Thanos.Downsample.Resolution)
 		}
+		for _, id := range m.Compaction.Sources {
+			resSources[id] = struct{}{}
+		}
 	}
 
 	ignoreDirs := []string{}
@@ -245,15 +322,10 @@ func downsampleBucket(
 		go func() {
 			defer wg.Done()
 			for m := range metaCh {
-				resolution := downsample.ResLevel1
-				errMsg := "downsampling to 5 min"
-				if m.Thanos.Downsample.Resolution == downsample.ResLevel1 {
-					resolution = downsample.ResLevel2
-					errMsg = "downsampling to 60 min"
-				}
-				if err := processDownsampling(workerCtx, logger, bkt, m, dir, resolution, hashFunc, metrics); err != nil {
+				next, _ := levels.Next(m.Thanos.Downsample.Resolution)
+				if err := processDownsampling(workerCtx, logger, bkt, m, dir, next.Resolution, hashFunc, metrics); err != nil {
 					metrics.downsampleFailures.WithLabelValues(compact.DefaultGroupKey(m.Thanos)).Inc()
-					errCh <- errors.Wrap(err, errMsg)
+					errCh <- errors.Wrapf(err, "downsampling to %v", time.Duration(next.Resolution)*time.Millisecond)
 				}
 				metrics.downsamples.WithLabelValues(compact.DefaultGroupKey(m.Thanos)).Inc()
 			}
@@ -264,47 +336,30 @@ func downsampleBucket(
 metaSendLoop:
 	for _, mk := range metasULIDS {
 		m := metas[mk]
-
-		switch m.Thanos.Downsample.Resolution {
-		case downsample.ResLevel2:
+		if filter != nil && !filter(m) {
 			continue
+		}
 
-		case downsample.ResLevel0:
-			missing := false
-			for _, id := range m.Compaction.Sources {
-				if _, ok := sources5m[id]; !ok {
-					missing = true
-					break
-				}
-			}
-			if !missing {
-				continue
-			}
-			// Only downsample blocks once we are sure to get roughly 2 chunks out of it.
-			// NOTE(fabxc): this must match with at which block size the compactor creates downsampled
-			// blocks. Otherwise we may never downsample some data.
-			if m.MaxTime-m.MinTime < downsample.DownsampleRange0 {
-				continue
-			}
-
-		case downsample.ResLevel1:
-			missing := false
-			for _, id := range m.Compaction.Sources {
-				if _, ok := sources1h[id]; !ok {
-					missing = true
-					break
-				}
-			}
-			if !missing {
-				continue
-			}
-			// Only downsample blocks once we are sure to get roughly 2 chunks out of it.
-			// NOTE(fabxc): this must match with at which block size the compactor creates downsampled
-			// blocks. Otherwise we may never downsample some data.
-			if m.MaxTime-m.MinTime < downsample.DownsampleRange1 {
-				continue
+		next, ok := levels.Next(m.Thanos.Downsample.Resolution)
+		if !ok {
+			continue
+		}
+		missing := false
+		for _, id := range m.Compaction.Sources {
+			if _, ok := sources[next.Resolution][id]; !ok {
+				missing = true
+				break
 			}
 		}
+		if !missing {
+			continue
+		}
+		// Only downsample blocks once we are sure to get roughly 2 chunks out of it.
+		// NOTE(fabxc): this must match with at which block size the compactor creates downsampled
+		// blocks. Otherwise we may never downsample some data.
+		if m.MaxTime-m.MinTime < next.MinSourceRange {
+			continue
+		}
 
 		select {
 		case <-workerCtx.Done():
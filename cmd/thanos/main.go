@@ -5,8 +5,10 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -14,6 +16,7 @@ import (
 	"runtime/debug"
 	"syscall"
 
+	extflag "github.com/efficientgo/tools/extkingpin"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/oklog/run"
@@ -25,8 +28,18 @@ import (
 	"go.uber.org/automaxprocs/maxprocs"
 	"gopkg.in/alecthomas/kingpin.v2"
 
+	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/extkingpin"
+	"github.com/thanos-io/thanos/pkg/extprom"
+	"github.com/thanos-io/thanos/pkg/ipfilter"
 	"github.com/thanos-io/thanos/pkg/logging"
+	"github.com/thanos-io/thanos/pkg/objstore/notifications"
+	"github.com/thanos-io/thanos/pkg/oidc"
+	"github.com/thanos-io/thanos/pkg/profiling"
+	"github.com/thanos-io/thanos/pkg/receive"
+	httpserver "github.com/thanos-io/thanos/pkg/server/http"
+	"github.com/thanos-io/thanos/pkg/spiffe"
+	thanostls "github.com/thanos-io/thanos/pkg/tls"
 	"github.com/thanos-io/thanos/pkg/tracing/client"
 )
 
@@ -47,6 +60,7 @@ func main() {
 	logFormat := app.Flag("log.format", "Log format to use. Possible options: logfmt or json.").
 		Default(logging.LogFormatLogfmt).Enum(logging.LogFormatLogfmt, logging.LogFormatJSON)
 	tracingConfig := extkingpin.RegisterCommonTracingFlags(app)
+	profilingConfig := extkingpin.RegisterProfilingFlags(app)
 
 	registerSidecar(app)
 	registerStore(app)
@@ -124,6 +138,29 @@ func main() {
 			cancel()
 		})
 	}
+	// Setup optional continuous profiling.
+	{
+		confContentYaml, err := profilingConfig.Content()
+		if err != nil {
+			level.Error(logger).Log("msg", "getting profiling config failed", "err", err)
+			os.Exit(1)
+		}
+
+		if len(confContentYaml) > 0 {
+			profiler, err := profiling.NewProfiler(logger, metrics, confContentYaml, cmd)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, errors.Wrapf(err, "profiling failed"))
+				os.Exit(1)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			g.Add(func() error {
+				return profiler.Run(ctx)
+			}, func(error) {
+				cancel()
+			})
+		}
+	}
 	// Create a signal channel to dispatch reload events to sub-commands.
 	reloadCh := make(chan struct{}, 1)
 
@@ -191,18 +228,138 @@ func reload(logger log.Logger, cancel <-chan struct{}, r chan<- struct{}) error
 	}
 }
 
-func getFlagsMap(flags []*kingpin.FlagModel) map[string]string {
-	flagsMap := map[string]string{}
+// statusOption returns the HTTP server option exposing the given flags of the command and the configuration given
+// through them, with secrets redacted.
+func statusOption(flags []*kingpin.FlagModel) httpserver.Option {
+	return httpserver.WithStatus(extkingpin.RedactedFlags(flags), func() (map[string]string, error) {
+		return extkingpin.RedactedConfig(flags)
+	})
+}
 
-	// Exclude kingpin default flags to expose only Thanos ones.
-	boilerplateFlags := kingpin.New("", "").Version("")
+// oidcMiddleware returns the middleware authenticating HTTP requests with the given OIDC configuration, or nil if
+// there is none. If the configuration maps a claim to the tenant, it is set as the default tenant header of the
+// requests.
+func oidcMiddleware(logger log.Logger, reg prometheus.Registerer, oidcConfig *extflag.PathOrContent) (func(http.Handler) http.Handler, error) {
+	return oidcTenantMiddleware(logger, reg, oidcConfig, receive.DefaultTenantHeader)
+}
+
+// oidcTenantMiddleware is like oidcMiddleware, but sets the given tenant header.
+func oidcTenantMiddleware(logger log.Logger, reg prometheus.Registerer, oidcConfig *extflag.PathOrContent, tenantHeader string) (func(http.Handler) http.Handler, error) {
+	confContentYaml, err := oidcConfig.Content()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting OIDC config")
+	}
+	if len(confContentYaml) == 0 {
+		return nil, nil
+	}
+	a, err := oidc.NewAuthenticator(logger, reg, confContentYaml)
+	if err != nil {
+		return nil, errors.Wrap(err, "create OIDC authenticator")
+	}
+	return a.Middleware(tenantHeader), nil
+}
+
+// serverIPFilters are the IP filters of the servers of a component. Filters of servers without rules are nil.
+type serverIPFilters struct {
+	http        *ipfilter.Filter
+	grpc        *ipfilter.Filter
+	remoteWrite *ipfilter.Filter
+}
+
+// ipFilters returns the IP filters of the servers of the component with the given configuration.
+func ipFilters(logger log.Logger, reg prometheus.Registerer, ipFilterConfig *extflag.PathOrContent) (serverIPFilters, error) {
+	confContentYaml, err := ipFilterConfig.Content()
+	if err != nil {
+		return serverIPFilters{}, errors.Wrap(err, "getting IP filter config")
+	}
+	if len(confContentYaml) == 0 {
+		return serverIPFilters{}, nil
+	}
+	conf, err := ipfilter.ParseConfig(confContentYaml)
+	if err != nil {
+		return serverIPFilters{}, err
+	}
 
-	for _, f := range flags {
-		if boilerplateFlags.GetFlag(f.Name) != nil {
-			continue
+	var filters serverIPFilters
+	for _, f := range []struct {
+		filter   **ipfilter.Filter
+		rules    *ipfilter.Rules
+		protocol string
+	}{
+		{filter: &filters.http, rules: conf.HTTP, protocol: "http"},
+		{filter: &filters.grpc, rules: conf.GRPC, protocol: "grpc"},
+		{filter: &filters.remoteWrite, rules: conf.RemoteWrite, protocol: "remote_write"},
+	} {
+		*f.filter, err = ipfilter.NewFilter(log.With(logger, "protocol", f.protocol), extprom.WrapRegistererWith(prometheus.Labels{"protocol": f.protocol}, reg), f.rules)
+		if err != nil {
+			return serverIPFilters{}, errors.Wrapf(err, "create IP filter of %s server", f.protocol)
 		}
-		flagsMap[f.Name] = f.Value.String()
 	}
+	return filters, nil
+}
+
+// spiffeSource returns the source of the SPIFFE workload identity of the gRPC servers and clients of the component, or
+// nil if no SPIFFE Workload API address is set. The source is run in the given group.
+func spiffeSource(g *run.Group, logger log.Logger, reg prometheus.Registerer, addr string, allowedIDs []string) (*spiffe.Source, error) {
+	if addr == "" {
+		return nil, nil
+	}
+	src, err := spiffe.NewSource(log.With(logger, "component", "spiffe"), reg, addr, allowedIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "create SPIFFE source")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	g.Add(func() error {
+		return src.Run(ctx)
+	}, func(error) {
+		cancel()
+	})
+	return src, nil
+}
+
+// registerBucketNotificationsFlag registers the flags of the config of the notifications of changes of the bucket.
+func registerBucketNotificationsFlag(cmd extkingpin.FlagClause) *extflag.PathOrContent {
+	return extflag.RegisterPathOrContent(cmd, "bucket-notifications.config",
+		"YAML that contains the configuration of the notifications of changes of objects sent by the object storage, e.g. S3 event notifications to SQS or GCS notifications to Pub/Sub, to sync the metas of blocks as soon as they change. Periodic syncs are kept to catch up with lost or late notifications. See format details: https://thanos.io/tip/thanos/storage.md/#bucket-notifications",
+		extflag.WithEnvSubstitution(),
+	)
+}
 
-	return flagsMap
+// watchBucketNotifications calls f whenever the bucket notifications of the given config, if any, tell that the
+// meta.json or a marker of a block changed. Notifications are received in the given group.
+func watchBucketNotifications(g *run.Group, logger log.Logger, reg prometheus.Registerer, conf *extflag.PathOrContent, f func()) error {
+	confContentYaml, err := conf.Content()
+	if err != nil {
+		return errors.Wrap(err, "get bucket notifications configuration")
+	}
+	if len(confContentYaml) == 0 {
+		return nil
+	}
+
+	logger = log.With(logger, "component", "bucket-notifications")
+	ctx, cancel := context.WithCancel(context.Background())
+	r, err := notifications.NewReceiver(ctx, logger, confContentYaml)
+	if err != nil {
+		cancel()
+		return errors.Wrap(err, "create bucket notifications receiver")
+	}
+	g.Add(func() error {
+		notifications.Watch(ctx, logger, reg, r, block.IsBlockMetaObject, f)
+		return nil
+	}, func(error) {
+		cancel()
+	})
+	return nil
+}
+
+// grpcServerTLSConfig returns the TLS configuration of the gRPC server of the component. It is the one of the SPIFFE
+// workload identity if there is a source, otherwise the one of the given certificate files, if any.
+func grpcServerTLSConfig(logger log.Logger, reg prometheus.Registerer, src *spiffe.Source, cert, key, clientCA string) (*tls.Config, error) {
+	if src != nil {
+		if cert != "" || key != "" || clientCA != "" {
+			return nil, errors.New("gRPC server TLS certificates can't be set together with a SPIFFE Workload API address")
+		}
+		return src.ServerTLSConfig(), nil
+	}
+	return thanostls.NewServerConfig(log.With(logger, "protocol", "gRPC"), extprom.WrapRegistererWith(prometheus.Labels{"protocol": "grpc"}, reg), cert, key, clientCA)
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"path"
 	"strings"
@@ -17,6 +18,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/oklog/ulid"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	promtest "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/prometheus/model/labels"
 
@@ -160,7 +162,7 @@ func TestRegression4960_Deadlock(t *testing.T) {
 
 	metas, _, err := metaFetcher.Fetch(ctx)
 	testutil.Ok(t, err)
-	err = downsampleBucket(ctx, logger, metrics, bkt, metas, dir, 1, metadata.NoneFunc)
+	err = downsampleBucket(ctx, logger, metrics, bkt, metas, dir, 1, metadata.NoneFunc, downsample.DefaultLevels, nil)
 	testutil.NotOk(t, err)
 
 	testutil.Assert(t, strings.Contains(err.Error(), "some random error has occurred"))
@@ -200,9 +202,109 @@ func TestCleanupDownsampleCacheFolder(t *testing.T) {
 
 	metas, _, err := metaFetcher.Fetch(ctx)
 	testutil.Ok(t, err)
-	testutil.Ok(t, downsampleBucket(ctx, logger, metrics, bkt, metas, dir, 1, metadata.NoneFunc))
+	testutil.Ok(t, downsampleBucket(ctx, logger, metrics, bkt, metas, dir, 1, metadata.NoneFunc, downsample.DefaultLevels, nil))
 	testutil.Equals(t, 1.0, promtest.ToFloat64(metrics.downsamples.WithLabelValues(compact.DefaultGroupKey(meta.Thanos))))
 
 	_, err = os.Stat(dir)
 	testutil.Assert(t, os.IsNotExist(err), "index cache dir should not exist at the end of execution")
 }
+
+func TestDownsampleBucket_Selection(t *testing.T) {
+	logger := log.NewNopLogger()
+	dir, err := ioutil.TempDir("", "test-downsample-selection")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	bkt := objstore.WithNoopInstr(objstore.NewInMemBucket())
+	var ids []ulid.ULID
+	for _, e := range []string{"1", "2"} {
+		id, err := e2eutil.CreateBlock(
+			ctx,
+			dir,
+			[]labels.Labels{{{Name: "a", Value: e}}},
+			1, 0, downsample.DownsampleRange0+1, // Pass the minimum DownsampleRange0 check.
+			labels.Labels{{Name: "e1", Value: e}},
+			downsample.ResLevel0, metadata.NoneFunc)
+		testutil.Ok(t, err)
+		testutil.Ok(t, block.Upload(ctx, logger, bkt, path.Join(dir, id.String()), metadata.NoneFunc))
+		ids = append(ids, id)
+	}
+
+	metaFetcher, err := block.NewMetaFetcher(nil, block.FetcherConcurrency, bkt, "", nil, nil, nil)
+	testutil.Ok(t, err)
+	metas, _, err := metaFetcher.Fetch(ctx)
+	testutil.Ok(t, err)
+
+	// Blocks outside of the time range are not selected.
+	testutil.Assert(t, !downsampleSelection{minTime: downsample.DownsampleRange0 + 2, maxTime: math.MaxInt64, timeRange: true}.filter(metas)(metas[ids[0]]))
+	testutil.Assert(t, downsampleSelection{minTime: 0, maxTime: 0, timeRange: true}.filter(metas)(metas[ids[0]]))
+	testutil.Assert(t, downsampleSelection{}.filter(metas) == nil)
+
+	selection := downsampleSelection{ids: map[ulid.ULID]struct{}{ids[0]: {}}}
+	metrics := newDownsampleMetrics(prometheus.NewRegistry())
+	testutil.Ok(t, downsampleBucket(ctx, logger, metrics, bkt, metas, path.Join(dir, "downsample"), 1, metadata.NoneFunc, downsample.DefaultLevels, selection.filter(metas)))
+
+	metas, _, err = metaFetcher.Fetch(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(metas))
+
+	filter := selection.filter(metas)
+	for id, m := range metas {
+		if id == ids[0] || id == ids[1] {
+			continue
+		}
+		testutil.Equals(t, downsample.ResLevel1, m.Thanos.Downsample.Resolution)
+		testutil.Equals(t, []ulid.ULID{ids[0]}, m.Compaction.Sources)
+		testutil.Assert(t, filter(m), "block downsampled from a selected block must be selected")
+	}
+	testutil.Assert(t, !filter(metas[ids[1]]), "block which is not selected must not be selected")
+}
+
+func TestDownsampleBucket_NoDownsampleMark(t *testing.T) {
+	logger := log.NewNopLogger()
+	dir, err := ioutil.TempDir("", "test-downsample-no-downsample-mark")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	bkt := objstore.WithNoopInstr(objstore.NewInMemBucket())
+	var ids []ulid.ULID
+	for _, e := range []string{"1", "2"} {
+		id, err := e2eutil.CreateBlock(
+			ctx,
+			dir,
+			[]labels.Labels{{{Name: "a", Value: e}}},
+			1, 0, downsample.DownsampleRange0+1, // Pass the minimum DownsampleRange0 check.
+			labels.Labels{{Name: "e1", Value: e}},
+			downsample.ResLevel0, metadata.NoneFunc)
+		testutil.Ok(t, err)
+		testutil.Ok(t, block.Upload(ctx, logger, bkt, path.Join(dir, id.String()), metadata.NoneFunc))
+		ids = append(ids, id)
+	}
+	testutil.Ok(t, block.MarkForNoDownsample(ctx, logger, bkt, ids[0], metadata.ManualNoDownsampleReason, "", promauto.With(nil).NewCounter(prometheus.CounterOpts{})))
+
+	noDownsampleMarkerFilter := compact.NewGatherNoDownsampleMarkFilter(logger, bkt, 1)
+	metaFetcher, err := block.NewMetaFetcher(nil, block.FetcherConcurrency, bkt, "", nil, []block.MetadataFilter{noDownsampleMarkerFilter}, nil)
+	testutil.Ok(t, err)
+	metas, _, err := metaFetcher.Fetch(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(noDownsampleMarkerFilter.NoDownsampleMarkedBlocks()))
+
+	metrics := newDownsampleMetrics(prometheus.NewRegistry())
+	testutil.Ok(t, downsampleBucket(ctx, logger, metrics, bkt, metas, path.Join(dir, "downsample"), 1, metadata.NoneFunc, downsample.DefaultLevels, excludeNoDownsampleMarked(nil, noDownsampleMarkerFilter.NoDownsampleMarkedBlocks())))
+
+	metas, _, err = metaFetcher.Fetch(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(metas))
+	for id, m := range metas {
+		if id == ids[0] || id == ids[1] {
+			continue
+		}
+		testutil.Equals(t, []ulid.ULID{ids[1]}, m.Compaction.Sources)
+	}
+}
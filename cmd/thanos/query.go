@@ -4,10 +4,13 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -26,12 +29,21 @@ import (
 	"github.com/prometheus/prometheus/discovery/targetgroup"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gopkg.in/alecthomas/kingpin.v2"
 
+	extflag "github.com/efficientgo/tools/extkingpin"
+
+	"github.com/thanos-io/thanos/pkg/admin"
 	v1 "github.com/thanos-io/thanos/pkg/api/query"
+	"github.com/thanos-io/thanos/pkg/audit"
+	"github.com/thanos-io/thanos/pkg/authz"
 	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/component"
 	"github.com/thanos-io/thanos/pkg/discovery/cache"
 	"github.com/thanos-io/thanos/pkg/discovery/dns"
+	"github.com/thanos-io/thanos/pkg/discovery/kubernetes"
 	"github.com/thanos-io/thanos/pkg/exemplars"
 	"github.com/thanos-io/thanos/pkg/extgrpc"
 	"github.com/thanos-io/thanos/pkg/extkingpin"
@@ -42,8 +54,11 @@ import (
 	"github.com/thanos-io/thanos/pkg/info/infopb"
 	"github.com/thanos-io/thanos/pkg/logging"
 	"github.com/thanos-io/thanos/pkg/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/objstore/client"
 	"github.com/thanos-io/thanos/pkg/prober"
 	"github.com/thanos-io/thanos/pkg/query"
+	"github.com/thanos-io/thanos/pkg/queryfrontend"
 	"github.com/thanos-io/thanos/pkg/rules"
 	"github.com/thanos-io/thanos/pkg/runutil"
 	grpcserver "github.com/thanos-io/thanos/pkg/server/grpc"
@@ -51,7 +66,8 @@ import (
 	"github.com/thanos-io/thanos/pkg/store"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/targets"
-	"github.com/thanos-io/thanos/pkg/tls"
+	"github.com/thanos-io/thanos/pkg/tenancy"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus"
 	"github.com/thanos-io/thanos/pkg/ui"
 )
 
@@ -67,6 +83,8 @@ func registerQuery(app *extkingpin.App) {
 	cmd := app.Command(comp.String(), "Query node exposing PromQL enabled Query API with data retrieved from multiple store nodes.")
 
 	httpBindAddr, httpGracePeriod, httpTLSConfig := extkingpin.RegisterHTTPFlags(cmd)
+	httpOIDCConfig := extkingpin.RegisterHTTPOIDCFlags(cmd)
+	ipFilterConfig := extkingpin.RegisterIPFilterFlags(cmd)
 	grpcBindAddr, grpcGracePeriod, grpcCert, grpcKey, grpcClientCA, grpcMaxConnAge := extkingpin.RegisterGRPCFlags(cmd)
 
 	secure := cmd.Flag("grpc-client-tls-secure", "Use TLS when talking to the gRPC server").Default("false").Bool()
@@ -75,6 +93,11 @@ func registerQuery(app *extkingpin.App) {
 	key := cmd.Flag("grpc-client-tls-key", "TLS Key for the client's certificate").Default("").String()
 	caCert := cmd.Flag("grpc-client-tls-ca", "TLS CA Certificates to use to verify gRPC servers").Default("").String()
 	serverName := cmd.Flag("grpc-client-server-name", "Server name to verify the hostname on the returned gRPC certificates. See https://tools.ietf.org/html/rfc4366#section-3.1").Default("").String()
+	spiffeAddr, spiffeIDs := extkingpin.RegisterSPIFFEFlags(cmd)
+	grpcCompression := cmd.Flag("grpc-client-compression", "Compression of the Series, LabelNames and LabelValues calls to StoreAPIs. Servers compress their responses with the same compression, so all of them have to support it. Possible values: none, zstd.").
+		Default(extgrpc.CompressionNone).Enum(extgrpc.CompressionOptions...)
+	grpcEndpointCompression := cmd.Flag("grpc-client-endpoint-compression", "Compression of the calls to the endpoint with the given address, overriding --grpc-client-compression (repeatable), e.g. to roll out a compression or to compress the calls to remote endpoints only. The address of endpoints discovered through DNS is the resolved one.").
+		PlaceHolder("<address>=<compression>").StringMap()
 
 	webRoutePrefix := cmd.Flag("web.route-prefix", "Prefix for API and UI endpoints. This allows thanos UI to be served on a sub-path. Defaults to the value of --web.external-prefix. This option is analogous to --web.route-prefix of Prometheus.").Default("").String()
 	webExternalPrefix := cmd.Flag("web.external-prefix", "Static prefix for all HTML links and redirect URLs in the UI query web interface. Actual endpoints are still served on / or the web.route-prefix. This allows thanos UI to be served behind a reverse proxy that strips a URL sub-path.").Default("").String()
@@ -91,6 +114,9 @@ func registerQuery(app *extkingpin.App) {
 
 	lookbackDelta := cmd.Flag("query.lookback-delta", "The maximum lookback duration for retrieving metrics during expression evaluations. PromQL always evaluates the query for the certain timestamp (query range timestamps are deduced by step). Since scrape intervals might be different, PromQL looks back for given amount of time to get latest sample. If it exceeds the maximum lookback delta it assumes series is stale and returns none (a gap). This is why lookback delta should be set to at least 2 times of the slowest scrape interval. If unset it will use the promql default of 5m.").Duration()
 	dynamicLookbackDelta := cmd.Flag("query.dynamic-lookback-delta", "Allow for larger lookback duration for queries based on resolution.").Hidden().Default("true").Bool()
+	downsampleResolutions := cmd.Flag("query.downsample-resolution", "Downsampling resolution of the blocks of the store APIs, used to extend the lookback delta of queries of downsampled data. "+
+		"Set it to all resolutions of the --downsample.level flags of the compactors if custom resolutions are configured. Repeated flag.").
+		Default("5m", "1h").Strings()
 
 	maxConcurrentSelects := cmd.Flag("query.max-concurrent-select", "Maximum number of select requests made concurrently per a query.").
 		Default("4").Int()
@@ -108,6 +134,10 @@ func registerQuery(app *extkingpin.App) {
 	endpoints := extkingpin.Addrs(cmd.Flag("endpoint", "Addresses of statically configured Thanos API servers (repeatable). The scheme may be prefixed with 'dns+' or 'dnssrv+' to detect Thanos API servers through respective DNS lookups.").
 		PlaceHolder("<endpoint>"))
 
+	k8sSelectors := cmd.Flag("endpoint.k8s-selector", "Kubernetes EndpointSlices whose ready endpoints are Thanos API servers (repeatable), as <namespace>/<label selector>:<port name or number>. "+
+		"EndpointSlices are watched with the service account of the pod, and endpoints are updated as soon as they change.").
+		PlaceHolder("<namespace>/<selector>:<port>").Strings()
+
 	stores := extkingpin.Addrs(cmd.Flag("store", "Deprecation Warning - This flag is deprecated and replaced with `endpoint`. Addresses of statically configured store API servers (repeatable). The scheme may be prefixed with 'dns+' or 'dnssrv+' to detect store API servers through respective DNS lookups.").
 		PlaceHolder("<store>"))
 
@@ -128,6 +158,12 @@ func registerQuery(app *extkingpin.App) {
 	strictStores := cmd.Flag("store-strict", "Deprecation Warning - This flag is deprecated and replaced with `endpoint-strict`. Addresses of only statically configured store API servers that are always used, even if the health check fails. Useful if you have a caching layer on top.").
 		PlaceHolder("<staticstore>").Strings()
 
+	frontendAddrs := extkingpin.Addrs(cmd.Flag("query.frontend-address", "Addresses of query frontends with query-frontend.pull.grpc-address to pull queries from over gRPC (repeatable), in addition to serving them over HTTP. The scheme may be prefixed with 'dns+' or 'dnssrv+' to detect query frontends through respective DNS lookups. The gRPC client settings of StoreAPIs are used.").
+		PlaceHolder("<frontend>"))
+
+	frontendConcurrency := cmd.Flag("query.frontend-concurrency", "Number of queries pulled and run concurrently per query frontend.").
+		Default("10").Int()
+
 	strictEndpoints := cmd.Flag("endpoint-strict", "Addresses of only statically configured Thanos API servers that are always used, even if the health check fails. Useful if you have a caching layer on top.").
 		PlaceHolder("<staticendpoint>").Strings()
 
@@ -145,10 +181,18 @@ func registerQuery(app *extkingpin.App) {
 		Default(string(dns.MiekgdnsResolverType)).Hidden().String()
 
 	unhealthyStoreTimeout := extkingpin.ModelDuration(cmd.Flag("store.unhealthy-timeout", "Timeout before an unhealthy store is cleaned from the store UI page.").Default("5m"))
+	storeStalenessGracePeriod := extkingpin.ModelDuration(cmd.Flag("store.staleness-grace-period", "Period during which Thanos API servers which fail their health checks, or disappear from service discovery, keep being queried with their last known metadata, counted from their last successful health check. It avoids gaps in query results when servers are briefly unavailable or their discovery is churning. 0 removes such servers on the next health check.").
+		Default("0s"))
 
 	enableAutodownsampling := cmd.Flag("query.auto-downsampling", "Enable automatic adjustment (step / 5) to what source of data should be used in store gateways if no max_source_resolution param is specified.").
 		Default("false").Bool()
 
+	enableAutodownsamplingHints := cmd.Flag("query.auto-downsampling-hints", "Pick the max source resolution of each part of queries with an automatic max_source_resolution from the time ranges of the resolutions the store APIs advertise, e.g. to query downsampled data past the retention of raw data.").
+		Default("false").Bool()
+
+	enableCounterAwareDedup := cmd.Flag("query.counter-aware-dedup", "Deduplicate the counters of replicas, e.g. for rate(), by carrying over their increases when switching between replicas instead of their values. This avoids artifacts on replica switchovers when the counter totals of replicas differ, e.g. between raw and downsampled data.").
+		Default("false").Bool()
+
 	enableQueryPartialResponse := cmd.Flag("query.partial-response", "Enable partial response for queries if no partial_response param is specified. --no-query.partial-response for disabling.").
 		Default("true").Bool()
 
@@ -161,27 +205,66 @@ func registerQuery(app *extkingpin.App) {
 	enableMetricMetadataPartialResponse := cmd.Flag("metric-metadata.partial-response", "Enable partial response for metric metadata endpoint. --no-metric-metadata.partial-response for disabling.").
 		Hidden().Default("true").Bool()
 
+	metricMetadataCacheTTL := extkingpin.ModelDuration(cmd.Flag("metric-metadata.cache-ttl", "Duration for which responses of the metric metadata API are cached, unless they are partial. 0 disables caching.").
+		Default("0s"))
+
 	featureList := cmd.Flag("enable-feature", "Comma separated experimental feature names to enable.The current list of features is "+promqlNegativeOffset+", "+promqlAtModifier+" and "+queryPushdown+".").Default("").Strings()
 
 	enableExemplarPartialResponse := cmd.Flag("exemplar.partial-response", "Enable partial response for exemplar endpoint. --no-exemplar.partial-response for disabling.").
 		Hidden().Default("true").Bool()
 
+	enableTSDBStatusPartialResponse := cmd.Flag("tsdb-status.partial-response", "Enable partial response for TSDB status endpoint. --no-tsdb-status.partial-response for disabling.").
+		Hidden().Default("true").Bool()
+
+	maxExemplarsPerSeries := cmd.Flag("exemplar.max-per-series", "Maximum number of the most recent exemplars returned for each series by the exemplars API, after deduplication. A warning is returned if exemplars were dropped. 0 means no limit.").
+		Default("0").Int()
+
 	defaultEvaluationInterval := extkingpin.ModelDuration(cmd.Flag("query.default-evaluation-interval", "Set default evaluation interval for sub queries.").Default("1m"))
 
 	defaultRangeQueryStep := extkingpin.ModelDuration(cmd.Flag("query.default-step", "Set default step for range queries. Default step is only used when step is not set in UI. In such cases, Thanos UI will use default step to calculate resolution (resolution = max(rangeSeconds / 250, defaultStep)). This will not work from Grafana, but Grafana has __step variable which can be used.").
 		Default("1s"))
 
 	storeResponseTimeout := extkingpin.ModelDuration(cmd.Flag("store.response-timeout", "If a Store doesn't send any data in this specified duration then a Store will be ignored and partial data will be returned if it's enabled. 0 disables timeout.").Default("0ms"))
+	storeSeriesBatchSize := cmd.Flag("store.series-batch-size", "Maximum number of series of the batches requested from the stores supporting them in Series calls, which reduces the per-message overhead and allocations of queries selecting many series. 0 disables batches.").
+		Default("0").Int()
 	reqLogConfig := extkingpin.RegisterRequestLoggingFlags(cmd)
 
 	alertQueryURL := cmd.Flag("alert.query-url", "The external Thanos Query URL that would be set in all alerts 'Source' field.").String()
 
-	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+	adminAPITokenFile := cmd.Flag("admin-api.token-file", "Path to the file with the bearer token which requests to the admin API on /api/v2/admin have to send in the Authorization header. The admin API is disabled if not set. The token also authenticates the deletions of series against the admin API of receivers, see --receive.admin-api.token-file.").
+		PlaceHolder("<path>").String()
+	deleteSeriesTimeout := extkingpin.ModelDuration(cmd.Flag("admin-api.delete-series-timeout", "Maximum duration of a deletion of series through the admin API, i.e. of recording the tombstone and deleting the series from all receivers.").
+		Default("5m"))
+	tombstonesObjStoreConfig := extkingpin.RegisterCommonObjStoreFlags(cmd, "", false, "Used by the admin API to record the tombstones of deleted series. No tombstones are recorded if not set.")
+
+	enforceTenancy := cmd.Flag("query.enforce-tenancy", "Require a tenant for the query, series and labels APIs and only return the series of it, i.e. with the tenant label set to the tenant. APIs which can't be restricted to a tenant are disabled.").
+		Default("false").Bool()
+	tenantHeader := cmd.Flag("query.tenant-header", "HTTP header with the tenant of requests if tenancy is enforced or the usage of tenants is accounted. It is set by the OIDC authentication if the tenant claim is configured.").
+		Default(tenancy.DefaultTenantHeader).String()
+	tenantLabelName := cmd.Flag("query.tenant-label-name", "Label of series with their tenant if tenancy is enforced.").
+		Default(tenancy.DefaultTenantLabel).String()
+	allowedTenants := cmd.Flag("query.allowed-tenant", "Tenant allowed to query if tenancy is enforced (repeatable). All tenants are allowed if not set.").
+		PlaceHolder("<tenant>").Strings()
+	tenantCertField := cmd.Flag("query.tenant-certificate-field", "Field of the subject of the HTTP client certificate to use as the tenant instead of the tenant header if tenancy is enforced. Requests without a client certificate with the field are rejected. Possible values: organization, organizationalUnit, commonName. Not used if not set.").
+		Default("").Enum(tenancy.CertificateFields...)
+
+	tenantAccounting := extkingpin.RegisterTenantAccountingFlag(cmd)
+
+	auditConfig := extkingpin.RegisterAuditFlags(cmd)
+
+	authzConfig := extflag.RegisterPathOrContent(cmd, "query.authorization-config", "YAML file with the configuration of the HTTP webhook, e.g. of the Open Policy Agent, authorizing every request of the query, series and labels APIs with its tenant, selectors and time range. See format details: https://thanos.io/tip/components/query.md/#external-authorization ", extflag.WithEnvSubstitution())
+
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, reload <-chan struct{}, _ bool) error {
 		selectorLset, err := parseFlagLabels(*selectorLabels)
 		if err != nil {
 			return errors.Wrap(err, "parse federation labels")
 		}
 
+		resolutions, err := downsample.ParseResolutions(*downsampleResolutions)
+		if err != nil {
+			return errors.Wrap(err, "parse downsampling resolutions")
+		}
+
 		var enableNegativeOffset, enableAtModifier, enableQueryPushdown bool
 		for _, feature := range *featureList {
 			if feature == promqlNegativeOffset {
@@ -227,6 +310,7 @@ func registerQuery(app *extkingpin.App) {
 			logger,
 			reg,
 			tracer,
+			reload,
 			httpLogOpts,
 			grpcLogOpts,
 			tagOpts,
@@ -242,9 +326,15 @@ func registerQuery(app *extkingpin.App) {
 			*key,
 			*caCert,
 			*serverName,
+			*spiffeAddr,
+			*spiffeIDs,
+			*grpcCompression,
+			*grpcEndpointCompression,
 			*httpBindAddr,
 			*httpTLSConfig,
 			time.Duration(*httpGracePeriod),
+			httpOIDCConfig,
+			ipFilterConfig,
 			*webRoutePrefix,
 			*webExternalPrefix,
 			*webPrefixHeaderName,
@@ -254,27 +344,38 @@ func registerQuery(app *extkingpin.App) {
 			time.Duration(*queryTimeout),
 			*lookbackDelta,
 			*dynamicLookbackDelta,
+			resolutions,
 			time.Duration(*defaultEvaluationInterval),
 			time.Duration(*storeResponseTimeout),
+			*storeSeriesBatchSize,
 			*queryReplicaLabels,
 			selectorLset,
-			getFlagsMap(cmd.Flags()),
+			cmd.Flags(),
 			*endpoints,
+			*k8sSelectors,
 			*stores,
 			*ruleEndpoints,
 			*targetEndpoints,
 			*metadataEndpoints,
 			*exemplarEndpoints,
+			*frontendAddrs,
+			*frontendConcurrency,
 			*enableAutodownsampling,
+			*enableAutodownsamplingHints,
+			*enableCounterAwareDedup,
 			*enableQueryPartialResponse,
 			*enableRulePartialResponse,
 			*enableTargetPartialResponse,
 			*enableMetricMetadataPartialResponse,
+			time.Duration(*metricMetadataCacheTTL),
 			*enableExemplarPartialResponse,
+			*maxExemplarsPerSeries,
+			*enableTSDBStatusPartialResponse,
 			fileSD,
 			time.Duration(*dnsSDInterval),
 			*dnsSDResolver,
 			time.Duration(*unhealthyStoreTimeout),
+			time.Duration(*storeStalenessGracePeriod),
 			time.Duration(*instantDefaultMaxSourceResolution),
 			*defaultMetadataTimeRange,
 			*strictStores,
@@ -284,6 +385,17 @@ func registerQuery(app *extkingpin.App) {
 			enableNegativeOffset,
 			enableQueryPushdown,
 			*alertQueryURL,
+			*adminAPITokenFile,
+			time.Duration(*deleteSeriesTimeout),
+			tombstonesObjStoreConfig,
+			*enforceTenancy,
+			*tenantHeader,
+			*tenantLabelName,
+			*allowedTenants,
+			tenancy.CertificateField(*tenantCertField),
+			*tenantAccounting,
+			authzConfig,
+			auditConfig,
 			component.Query,
 		)
 	})
@@ -296,6 +408,7 @@ func runQuery(
 	logger log.Logger,
 	reg *prometheus.Registry,
 	tracer opentracing.Tracer,
+	reloadSignal <-chan struct{},
 	httpLogOpts []logging.Option,
 	grpcLogOpts []grpc_logging.Option,
 	tagOpts []tags.Option,
@@ -311,9 +424,15 @@ func runQuery(
 	key string,
 	caCert string,
 	serverName string,
+	spiffeAddr string,
+	spiffeIDs []string,
+	grpcCompression string,
+	grpcEndpointCompression map[string]string,
 	httpBindAddr string,
 	httpTLSConfig string,
 	httpGracePeriod time.Duration,
+	httpOIDCConfig *extflag.PathOrContent,
+	ipFilterConfig *extflag.PathOrContent,
 	webRoutePrefix string,
 	webExternalPrefix string,
 	webPrefixHeaderName string,
@@ -323,27 +442,38 @@ func runQuery(
 	queryTimeout time.Duration,
 	lookbackDelta time.Duration,
 	dynamicLookbackDelta bool,
+	downsampleResolutions []int64,
 	defaultEvaluationInterval time.Duration,
 	storeResponseTimeout time.Duration,
+	storeSeriesBatchSize int,
 	queryReplicaLabels []string,
 	selectorLset labels.Labels,
-	flagsMap map[string]string,
+	cmdFlags []*kingpin.FlagModel,
 	endpointAddrs []string,
+	k8sSelectors []string,
 	storeAddrs []string,
 	ruleAddrs []string,
 	targetAddrs []string,
 	metadataAddrs []string,
 	exemplarAddrs []string,
+	frontendAddrs []string,
+	frontendConcurrency int,
 	enableAutodownsampling bool,
+	enableAutodownsamplingHints bool,
+	enableCounterAwareDedup bool,
 	enableQueryPartialResponse bool,
 	enableRulePartialResponse bool,
 	enableTargetPartialResponse bool,
 	enableMetricMetadataPartialResponse bool,
+	metricMetadataCacheTTL time.Duration,
 	enableExemplarPartialResponse bool,
+	maxExemplarsPerSeries int,
+	enableTSDBStatusPartialResponse bool,
 	fileSD *file.Discovery,
 	dnsSDInterval time.Duration,
 	dnsSDResolver string,
 	unhealthyStoreTimeout time.Duration,
+	storeStalenessGracePeriod time.Duration,
 	instantDefaultMaxSourceResolution time.Duration,
 	defaultMetadataTimeRange time.Duration,
 	strictStores []string,
@@ -353,8 +483,19 @@ func runQuery(
 	enableNegativeOffset bool,
 	enableQueryPushdown bool,
 	alertQueryURL string,
+	adminAPITokenFile string,
+	deleteSeriesTimeout time.Duration,
+	tombstonesObjStoreConfig *extflag.PathOrContent,
+	enforceTenancy bool,
+	tenantHeader string,
+	tenantLabelName string,
+	allowedTenants []string,
+	tenantCertField tenancy.CertificateField,
+	tenantAccounting bool,
+	authzConfig *extflag.PathOrContent,
+	auditConfig *extflag.PathOrContent,
 	comp component.Component,
-) error {
+) (err error) {
 	if alertQueryURL == "" {
 		lastColon := strings.LastIndex(httpBindAddr, ":")
 		if lastColon != -1 {
@@ -368,9 +509,45 @@ func runQuery(
 		Help: "The number of times a duplicated store addresses is detected from the different configs in query",
 	})
 
-	dialOpts, err := extgrpc.StoreClientGRPCOpts(logger, reg, tracer, secure, skipVerify, cert, key, caCert, serverName)
+	spiffeSrc, err := spiffeSource(g, logger, reg, spiffeAddr, spiffeIDs)
 	if err != nil {
-		return errors.Wrap(err, "building gRPC client")
+		return err
+	}
+	var dialOpts []grpc.DialOption
+	if spiffeSrc != nil {
+		if secure {
+			return errors.New("gRPC client TLS can't be enabled together with a SPIFFE Workload API address")
+		}
+		dialOpts = extgrpc.StoreClientGRPCOptsWithCredentials(reg, tracer, credentials.NewTLS(spiffeSrc.ClientTLSConfig()))
+	} else {
+		dialOpts, err = extgrpc.StoreClientGRPCOpts(logger, reg, tracer, secure, skipVerify, cert, key, caCert, serverName)
+		if err != nil {
+			return errors.Wrap(err, "building gRPC client")
+		}
+	}
+	// Queries are pulled from query frontends without the compression of StoreAPI calls.
+	frontendDialOpts := dialOpts
+	if grpcCompression != extgrpc.CompressionNone {
+		compressionOpts, err := extgrpc.StoreClientCompressionOpts(grpcCompression)
+		if err != nil {
+			return err
+		}
+		dialOpts = append(dialOpts, compressionOpts...)
+	}
+	endpointDialOpts := make(map[string][]grpc.DialOption, len(grpcEndpointCompression))
+	for addr, compression := range grpcEndpointCompression {
+		endpointDialOpts[addr], err = extgrpc.StoreClientCompressionOpts(compression)
+		if err != nil {
+			return errors.Wrapf(err, "compression of endpoint %s", addr)
+		}
+	}
+	// newEndpointSpec returns the spec of the endpoint, with its compression if it is overridden.
+	newEndpointSpec := func(addr string, isStrictStatic bool) *query.GRPCEndpointSpec {
+		spec := query.NewGRPCEndpointSpec(addr, isStrictStatic)
+		if opts, ok := endpointDialOpts[addr]; ok {
+			spec = spec.WithDialOptions(opts...)
+		}
+		return spec
 	}
 
 	fileSDCache := cache.New()
@@ -422,6 +599,28 @@ func runQuery(
 		dns.ResolverType(dnsSDResolver),
 	)
 
+	dnsFrontendProvider := dns.NewProvider(
+		logger,
+		extprom.WrapRegistererWithPrefix("thanos_query_frontends_", reg),
+		dns.ResolverType(dnsSDResolver),
+	)
+
+	var k8sWatcher *kubernetes.EndpointSliceWatcher
+	if len(k8sSelectors) > 0 {
+		selectors := make([]kubernetes.Selector, 0, len(k8sSelectors))
+		for _, s := range k8sSelectors {
+			sel, err := kubernetes.ParseSelector(s)
+			if err != nil {
+				return errors.Wrap(err, "parse Kubernetes selector")
+			}
+			selectors = append(selectors, sel)
+		}
+		k8sWatcher, err = kubernetes.NewInClusterEndpointSliceWatcher(logger, extprom.WrapRegistererWithPrefix("thanos_query_endpoints_", reg), selectors)
+		if err != nil {
+			return errors.Wrap(err, "create Kubernetes EndpointSlice watcher")
+		}
+	}
+
 	var (
 		endpoints = query.NewEndpointSet(
 			logger,
@@ -429,11 +628,11 @@ func runQuery(
 			func() (specs []*query.GRPCEndpointSpec) {
 				// Add strict & static nodes.
 				for _, addr := range strictStores {
-					specs = append(specs, query.NewGRPCEndpointSpec(addr, true))
+					specs = append(specs, newEndpointSpec(addr, true))
 				}
 
 				for _, addr := range strictEndpoints {
-					specs = append(specs, query.NewGRPCEndpointSpec(addr, true))
+					specs = append(specs, newEndpointSpec(addr, true))
 				}
 
 				for _, dnsProvider := range []*dns.Provider{
@@ -447,28 +646,38 @@ func runQuery(
 					var tmpSpecs []*query.GRPCEndpointSpec
 
 					for _, addr := range dnsProvider.Addresses() {
-						tmpSpecs = append(tmpSpecs, query.NewGRPCEndpointSpec(addr, false))
+						tmpSpecs = append(tmpSpecs, newEndpointSpec(addr, false))
 					}
 					tmpSpecs = removeDuplicateEndpointSpecs(logger, duplicatedStores, tmpSpecs)
 					specs = append(specs, tmpSpecs...)
 				}
 
+				if k8sWatcher != nil {
+					for _, addr := range k8sWatcher.Addresses() {
+						specs = append(specs, newEndpointSpec(addr, false))
+					}
+				}
+
 				return specs
 			},
 			dialOpts,
 			unhealthyStoreTimeout,
+			query.WithEndpointStalenessGracePeriod(storeStalenessGracePeriod),
 		)
-		proxy            = store.NewProxyStore(logger, reg, endpoints.GetStoreClients, component.Query, selectorLset, storeResponseTimeout)
+		proxy            = store.NewProxyStore(logger, reg, endpoints.GetStoreClients, component.Query, selectorLset, storeResponseTimeout, store.WithSeriesBatchSize(storeSeriesBatchSize))
 		rulesProxy       = rules.NewProxy(logger, endpoints.GetRulesClients)
 		targetsProxy     = targets.NewProxy(logger, endpoints.GetTargetsClients)
 		metadataProxy    = metadata.NewProxy(logger, endpoints.GetMetricMetadataClients)
 		exemplarsProxy   = exemplars.NewProxy(logger, endpoints.GetExemplarsStores, selectorLset)
+		tsdbStatusProxy  = tsdbstatus.NewProxy(logger, endpoints.GetTSDBStatusClients)
 		queryableCreator = query.NewQueryableCreator(
 			logger,
 			extprom.WrapRegistererWithPrefix("thanos_query_", reg),
 			proxy,
 			maxConcurrentSelects,
 			queryTimeout,
+			query.WithTSDBInfos(endpoints.GetTSDBInfos),
+			query.WithCounterAwareDedup(enableCounterAwareDedup),
 		)
 		engineOpts = promql.EngineOpts{
 			Logger: logger,
@@ -499,6 +708,66 @@ func runQuery(
 		})
 	}
 
+	// Watch Kubernetes EndpointSlices and update the endpoint set as soon as they change.
+	if k8sWatcher != nil {
+		updates := make(chan struct{}, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			k8sWatcher.Run(ctx, func() {
+				select {
+				case updates <- struct{}{}:
+				default:
+				}
+			})
+			return nil
+		}, func(error) {
+			cancel()
+		})
+		g.Add(func() error {
+			for {
+				select {
+				case <-updates:
+					endpoints.Update(ctx)
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}, func(error) {
+			cancel()
+		})
+	}
+
+	// Reloads make file SD re-read its files and the addresses of endpoints to be resolved again right away, instead
+	// of on the next refresh and resolution intervals.
+	var (
+		fileSDReloads   = make(chan struct{}, 1)
+		resolveTriggers = make(chan struct{}, 1)
+	)
+	reloadDiscovery := func() {
+		level.Info(logger).Log("msg", "reloading file SD and resolving endpoint addresses")
+		for _, c := range []chan struct{}{fileSDReloads, resolveTriggers} {
+			select {
+			case c <- struct{}{}:
+			default:
+			}
+		}
+	}
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			for {
+				select {
+				case <-reloadSignal:
+					reloadDiscovery()
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}, func(error) {
+			cancel()
+		})
+	}
+
 	// Run File Service Discovery and update the store set when the files are modified.
 	if fileSD != nil {
 		var fileSDUpdates chan []*targetgroup.Group
@@ -507,8 +776,25 @@ func runQuery(
 		fileSDUpdates = make(chan []*targetgroup.Group)
 
 		g.Add(func() error {
-			fileSD.Run(ctxRun, fileSDUpdates)
-			return nil
+			// File SD re-reads all its files when started, so it is restarted on reloads.
+			for {
+				ctx, cancel := context.WithCancel(ctxRun)
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					fileSD.Run(ctx, fileSDUpdates)
+				}()
+
+				select {
+				case <-fileSDReloads:
+					cancel()
+					<-done
+				case <-ctxRun.Done():
+					cancel()
+					<-done
+					return nil
+				}
+			}
 		}, func(error) {
 			cancelRun()
 		})
@@ -542,7 +828,7 @@ func runQuery(
 	{
 		ctx, cancel := context.WithCancel(context.Background())
 		g.Add(func() error {
-			return runutil.Repeat(dnsSDInterval, ctx.Done(), func() error {
+			return runutil.RepeatOrTrigger(dnsSDInterval, ctx.Done(), resolveTriggers, func() error {
 				resolveCtx, resolveCancel := context.WithTimeout(ctx, dnsSDInterval)
 				defer resolveCancel()
 				if err := dnsStoreProvider.Resolve(resolveCtx, append(fileSDCache.Addresses(), storeAddrs...)); err != nil {
@@ -579,6 +865,14 @@ func runQuery(
 		prober.NewInstrumentation(comp, logger, extprom.WrapRegistererWithPrefix("thanos_", reg)),
 	)
 
+	ipf, err := ipFilters(logger, reg, ipFilterConfig)
+	if err != nil {
+		return err
+	}
+
+	// Queries are served over gRPC as well, e.g. for queriers federating this querier.
+	var grpcAPI *v1.GRPCAPI
+
 	// Start query API + UI HTTP server.
 	{
 		router := route.New()
@@ -597,32 +891,88 @@ func runQuery(
 			router = router.WithPrefix(webRoutePrefix)
 		}
 
+		router.Post("/-/reload", func(w http.ResponseWriter, r *http.Request) {
+			reloadDiscovery()
+		})
+
 		// Configure Request Logging for HTTP calls.
 		logMiddleware := logging.NewHTTPServerMiddleware(logger, httpLogOpts...)
 
 		ins := extpromhttp.NewInstrumentationMiddleware(reg, nil)
+
+		var metadataClient metadata.UnaryClient = metadata.NewGRPCClient(metadataProxy)
+		if metricMetadataCacheTTL > 0 {
+			metadataClient = metadata.NewCachedClient(reg, metadataClient, metricMetadataCacheTTL)
+		}
+
 		// TODO(bplotka in PR #513 review): pass all flags, not only the flags needed by prefix rewriting.
 		ui.NewQueryUI(logger, endpoints, webExternalPrefix, webPrefixHeaderName, alertQueryURL).Register(router, ins)
 
+		var tenancyEnforcer *tenancy.Enforcer
+		if enforceTenancy {
+			tenancyEnforcer = tenancy.NewEnforcer(logger, reg, tenantHeader, tenantLabelName, tenantCertField, allowedTenants)
+		}
+		var accounting *tenancy.Accounting
+		if tenantAccounting {
+			accounting = tenancy.NewAccounting(reg)
+		}
+
+		var authorizer *authz.Authorizer
+		authzContentYaml, err := authzConfig.Content()
+		if err != nil {
+			return errors.Wrap(err, "getting authorization config")
+		}
+		if len(authzContentYaml) > 0 {
+			authorizer, err = authz.NewAuthorizer(logger, reg, authzContentYaml)
+			if err != nil {
+				return errors.Wrap(err, "create authorizer")
+			}
+		}
+
+		var auditLogger *audit.Logger
+		auditContentYaml, err := auditConfig.Content()
+		if err != nil {
+			return err
+		}
+		if len(auditContentYaml) > 0 {
+			auditLogger, err = audit.NewLogger(logger, reg, auditContentYaml, comp.String())
+			if err != nil {
+				return errors.Wrap(err, "create audit logger")
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			g.Add(func() error {
+				return auditLogger.Run(ctx)
+			}, func(error) {
+				cancel()
+			})
+		}
+
+		var tsdbInfos func() []infopb.TSDBInfo
+		if enableAutodownsamplingHints {
+			tsdbInfos = endpoints.GetTSDBInfos
+		}
 		api := v1.NewQueryAPI(
 			logger,
 			endpoints.GetEndpointStatus,
-			engineFactory(promql.NewEngine, engineOpts, dynamicLookbackDelta),
+			engineFactory(promql.NewEngine, engineOpts, dynamicLookbackDelta, downsampleResolutions),
 			queryableCreator,
 			// NOTE: Will share the same replica label as the query for now.
 			rules.NewGRPCClientWithDedup(rulesProxy, queryReplicaLabels),
 			targets.NewGRPCClientWithDedup(targetsProxy, queryReplicaLabels),
-			metadata.NewGRPCClient(metadataProxy),
-			exemplars.NewGRPCClientWithDedup(exemplarsProxy, queryReplicaLabels),
+			metadataClient,
+			exemplars.NewGRPCClientWithDedupAndLimit(exemplarsProxy, queryReplicaLabels, maxExemplarsPerSeries),
+			tsdbstatus.NewGRPCClientWithDedup(tsdbStatusProxy, queryReplicaLabels),
 			enableAutodownsampling,
+			tsdbInfos,
 			enableQueryPartialResponse,
 			enableRulePartialResponse,
 			enableTargetPartialResponse,
 			enableMetricMetadataPartialResponse,
 			enableExemplarPartialResponse,
+			enableTSDBStatusPartialResponse,
 			enableQueryPushdown,
 			queryReplicaLabels,
-			flagsMap,
+			extkingpin.RedactedFlags(cmdFlags),
 			defaultRangeQueryStep,
 			instantDefaultMaxSourceResolution,
 			defaultMetadataTimeRange,
@@ -631,15 +981,61 @@ func runQuery(
 				extprom.WrapRegistererWithPrefix("thanos_query_concurrent_", reg),
 				maxConcurrentQueries,
 			),
+			tenancyEnforcer,
+			accounting,
+			tenantHeader,
+			authorizer,
+			auditLogger,
+			lookbackDelta,
 			reg,
 		)
 
 		api.Register(router.WithPrefix("/api/v1"), tracer, logger, ins, logMiddleware)
+		api.RegisterFederate(router, tracer, logger, ins, logMiddleware)
+		grpcAPI = v1.NewGRPCAPI(api)
+
+		if adminAPITokenFile != "" {
+			token, err := ioutil.ReadFile(adminAPITokenFile)
+			if err != nil {
+				return errors.Wrap(err, "read admin API token file")
+			}
+			if len(bytes.TrimSpace(token)) == 0 {
+				return errors.Errorf("admin API token file %s is empty", adminAPITokenFile)
+			}
+
+			var bkt objstore.Bucket
+			confContentYaml, err := tombstonesObjStoreConfig.Content()
+			if err != nil {
+				return err
+			}
+			if len(confContentYaml) > 0 {
+				bkt, err = client.NewBucket(logger, confContentYaml, reg, comp.String())
+				if err != nil {
+					return err
+				}
+				defer func() {
+					if err != nil {
+						runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
+					}
+				}()
+			}
+
+			// Receivers serving the admin API authenticate the deletions with the same token.
+			deleter := admin.NewDeleter(logger, endpoints.GetAdminEndpoints, bkt, deleteSeriesTimeout, string(bytes.TrimSpace(token)))
+			v1.NewAdminAPI(deleter, string(bytes.TrimSpace(token)), tenantHeader, disableCORS).Register(router.WithPrefix("/api/v2/admin"), tracer, logger, ins, logMiddleware)
+		}
 
+		authMiddleware, err := oidcTenantMiddleware(logger, reg, httpOIDCConfig, tenantHeader)
+		if err != nil {
+			return err
+		}
 		srv := httpserver.New(logger, reg, comp, httpProbe,
 			httpserver.WithListen(httpBindAddr),
 			httpserver.WithGracePeriod(httpGracePeriod),
 			httpserver.WithTLSConfig(httpTLSConfig),
+			statusOption(cmdFlags),
+			httpserver.WithAuthentication(authMiddleware),
+			httpserver.WithIPFilter(ipf.http),
 		)
 		srv.Handle("/", router)
 
@@ -653,42 +1049,86 @@ func runQuery(
 
 			srv.Shutdown(err)
 		})
+
+		// Pull queries from query frontends, served by the same handler as over HTTP.
+		if len(frontendAddrs) > 0 {
+			var handler http.Handler = router
+			if authMiddleware != nil {
+				handler = authMiddleware(router)
+			}
+			id, err := os.Hostname()
+			if err != nil {
+				return errors.Wrap(err, "get hostname to identify querier to query frontends")
+			}
+			worker := queryfrontend.NewQuerierWorker(logger, reg, handler, id, frontendConcurrency, frontendDialOpts)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			g.Add(func() error {
+				return runutil.Repeat(dnsSDInterval, ctx.Done(), func() error {
+					resolveCtx, resolveCancel := context.WithTimeout(ctx, dnsSDInterval)
+					defer resolveCancel()
+					if err := dnsFrontendProvider.Resolve(resolveCtx, frontendAddrs); err != nil {
+						level.Error(logger).Log("msg", "failed to resolve addresses for query frontends", "err", err)
+					}
+					worker.SetFrontends(dnsFrontendProvider.Addresses())
+					return nil
+				})
+			}, func(error) {
+				cancel()
+				worker.Stop()
+			})
+		}
 	}
 	// Start query (proxy) gRPC StoreAPI.
 	{
-		tlsCfg, err := tls.NewServerConfig(log.With(logger, "protocol", "gRPC"), grpcCert, grpcKey, grpcClientCA)
+		tlsCfg, err := grpcServerTLSConfig(logger, reg, spiffeSrc, grpcCert, grpcKey, grpcClientCA)
 		if err != nil {
 			return errors.Wrap(err, "setup gRPC server")
 		}
 
-		infoSrv := info.NewInfoServer(
-			component.Query.String(),
+		infoOpts := []info.ServerOptionFunc{
 			info.WithLabelSetFunc(func() []labelpb.ZLabelSet { return proxy.LabelSet() }),
-			info.WithStoreInfoFunc(func() *infopb.StoreInfo {
-				minTime, maxTime := proxy.TimeRange()
-				return &infopb.StoreInfo{
-					MinTime: minTime,
-					MaxTime: maxTime,
-				}
-			}),
-			info.WithExemplarsInfoFunc(),
-			info.WithRulesInfoFunc(),
-			info.WithMetricMetadataInfoFunc(),
-			info.WithTargetsInfoFunc(),
-		)
+		}
+		// The proxied APIs are rejected if tenancy is enforced or requests are authorized, so they are not advertised.
+		if !grpcAPI.Restricted() {
+			infoOpts = append(infoOpts,
+				info.WithStoreInfoFunc(func() *infopb.StoreInfo {
+					minTime, maxTime := proxy.TimeRange()
+					return &infopb.StoreInfo{
+						MinTime:               minTime,
+						MaxTime:               maxTime,
+						SupportsSeriesBatches: true,
+						TsdbInfos:             endpoints.GetTSDBInfos(),
+					}
+				}),
+				info.WithExemplarsInfoFunc(),
+				info.WithRulesInfoFunc(),
+				info.WithMetricMetadataInfoFunc(),
+				info.WithTargetsInfoFunc(),
+				info.WithTSDBStatusInfoFunc(),
+			)
+		}
+		infoSrv := info.NewInfoServer(component.Query.String(), infoOpts...)
 
-		s := grpcserver.New(logger, reg, tracer, grpcLogOpts, tagOpts, comp, grpcProbe,
+		grpcOpts := []grpcserver.Option{
 			grpcserver.WithServer(store.RegisterStoreServer(proxy)),
 			grpcserver.WithServer(rules.RegisterRulesServer(rulesProxy)),
 			grpcserver.WithServer(targets.RegisterTargetsServer(targetsProxy)),
 			grpcserver.WithServer(metadata.RegisterMetadataServer(metadataProxy)),
 			grpcserver.WithServer(exemplars.RegisterExemplarsServer(exemplarsProxy)),
+			grpcserver.WithServer(tsdbstatus.RegisterTSDBStatusServer(tsdbStatusProxy)),
 			grpcserver.WithServer(info.RegisterInfoServer(infoSrv)),
+			grpcserver.WithServer(v1.RegisterQueryServer(grpcAPI)),
 			grpcserver.WithListen(grpcBindAddr),
 			grpcserver.WithGracePeriod(grpcGracePeriod),
 			grpcserver.WithTLSConfig(tlsCfg),
+			grpcserver.WithIPFilter(ipf.grpc),
 			grpcserver.WithMaxConnAge(grpcMaxConnAge),
-		)
+		}
+		for _, o := range grpcAPI.ServerOptions() {
+			grpcOpts = append(grpcOpts, grpcserver.WithGRPCServerOption(o))
+		}
+		s := grpcserver.New(logger, reg, tracer, grpcLogOpts, tagOpts, comp, grpcProbe, grpcOpts...)
 
 		g.Add(func() error {
 			statusProber.Ready()
@@ -720,9 +1160,10 @@ func removeDuplicateEndpointSpecs(logger log.Logger, duplicatedStores prometheus
 	return deduplicated
 }
 
-// engineFactory creates from 1 to 3 promql.Engines depending on
-// dynamicLookbackDelta and eo.LookbackDelta and returns a function
-// that returns appropriate engine for given maxSourceResolutionMillis.
+// engineFactory creates a promql.Engine for raw data and, if dynamicLookbackDelta
+// is enabled, one for each of the given downsampling resolutions, sorted by increasing
+// resolution. It returns a function that returns appropriate engine for given
+// maxSourceResolutionMillis.
 //
 // TODO: it seems like a good idea to tweak Prometheus itself
 // instead of creating several Engines here.
@@ -730,10 +1171,11 @@ func engineFactory(
 	newEngine func(promql.EngineOpts) *promql.Engine,
 	eo promql.EngineOpts,
 	dynamicLookbackDelta bool,
+	downsampleResolutions []int64,
 ) func(int64) *promql.Engine {
 	resolutions := []int64{downsample.ResLevel0}
 	if dynamicLookbackDelta {
-		resolutions = []int64{downsample.ResLevel0, downsample.ResLevel1, downsample.ResLevel2}
+		resolutions = append(resolutions, downsampleResolutions...)
 	}
 	var (
 		engines = make([]*promql.Engine, len(resolutions))
@@ -4,26 +4,35 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"time"
 
-	"github.com/NYTimes/gziphandler"
 	cortexfrontend "github.com/cortexproject/cortex/pkg/frontend"
 	"github.com/cortexproject/cortex/pkg/frontend/transport"
+	cortexfrontendv1 "github.com/cortexproject/cortex/pkg/frontend/v1"
+	"github.com/cortexproject/cortex/pkg/frontend/v1/frontendv1pb"
 	"github.com/cortexproject/cortex/pkg/querier/queryrange"
 	cortexvalidation "github.com/cortexproject/cortex/pkg/util/validation"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/services"
+	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tags"
 	"github.com/oklog/run"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/weaveworks/common/user"
+	"google.golang.org/grpc"
+	"gopkg.in/alecthomas/kingpin.v2"
 	"gopkg.in/yaml.v2"
 
 	extflag "github.com/efficientgo/tools/extkingpin"
 
 	"github.com/thanos-io/thanos/pkg/api"
+	"github.com/thanos-io/thanos/pkg/audit"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/component"
 	"github.com/thanos-io/thanos/pkg/exthttp"
 	"github.com/thanos-io/thanos/pkg/extkingpin"
@@ -32,8 +41,10 @@ import (
 	"github.com/thanos-io/thanos/pkg/logging"
 	"github.com/thanos-io/thanos/pkg/prober"
 	"github.com/thanos-io/thanos/pkg/queryfrontend"
+	grpcserver "github.com/thanos-io/thanos/pkg/server/grpc"
 	httpserver "github.com/thanos-io/thanos/pkg/server/http"
 	"github.com/thanos-io/thanos/pkg/server/http/middleware"
+	"github.com/thanos-io/thanos/pkg/tenancy"
 	"github.com/thanos-io/thanos/pkg/tracing"
 )
 
@@ -41,7 +52,21 @@ type queryFrontendConfig struct {
 	http           httpConfig
 	webDisableCORS bool
 	queryfrontend.Config
-	orgIdHeaders []string
+	orgIdHeaders          []string
+	downsampleResolutions []string
+	tenantAccounting      *bool
+	auditConfig           *extflag.PathOrContent
+	pull                  pullConfig
+}
+
+// pullConfig configures the gRPC server queriers pull queries from.
+type pullConfig struct {
+	grpcAddress             string
+	grpcTLSSrvCert          string
+	grpcTLSSrvKey           string
+	grpcTLSSrvClientCA      string
+	maxOutstandingPerTenant int
+	querierForgetDelay      time.Duration
 }
 
 func registerQueryFrontend(app *extkingpin.App) {
@@ -74,6 +99,10 @@ func registerQueryFrontend(app *extkingpin.App) {
 	cmd.Flag("query-range.request-downsampled", "Make additional query for downsampled data in case of empty or incomplete response to range request.").
 		Default("true").BoolVar(&cfg.QueryRangeConfig.RequestDownsampled)
 
+	cmd.Flag("query-range.downsample-resolution", "Downsampling resolution requested in turn by --query-range.request-downsampled, and used in the keys of cached responses. "+
+		"Set it to all resolutions of the --downsample.level flags of the compactors if custom resolutions are configured. Repeated flag.").
+		Default("5m", "1h").StringsVar(&cfg.downsampleResolutions)
+
 	cmd.Flag("query-range.split-interval", "Split query range requests by an interval and execute in parallel, it should be greater than 0 when query-range.response-cache-config is configured.").
 		Default("24h").DurationVar(&cfg.QueryRangeConfig.SplitQueriesByInterval)
 
@@ -92,8 +121,25 @@ func registerQueryFrontend(app *extkingpin.App) {
 	cmd.Flag("query-range.partial-response", "Enable partial response for query range requests if no partial_response param is specified. --no-query-range.partial-response for disabling.").
 		Default("true").BoolVar(&cfg.QueryRangeConfig.PartialResponseStrategy)
 
+	cmd.Flag("query-range.shards", "Shard range queries of aggregations grouped by labels, e.g. sum by (job) (rate(x[5m])), into this number of queries executed in parallel, "+
+		"each selecting the series whose values of the grouping labels hash to its shard. The results of the shards are cached on their own. "+
+		"Aggregations without grouping labels, grouped by __name__ or with nested aggregations, vector matching, label_replace, label_join, absent, absent_over_time, scalar or vector are not sharded. "+
+		"Requires queriers which support the shard parameters. 0 or 1 disables sharding.").
+		Default("0").IntVar(&cfg.QueryRangeConfig.Shards)
+
 	cfg.QueryRangeConfig.CachePathOrContent = *extflag.RegisterPathOrContent(cmd, "query-range.response-cache-config", "YAML file that contains response cache configuration.", extflag.WithEnvSubstitution())
 
+	// Instant query tripperware flags.
+	cmd.Flag("query-instant.split-interval", "Split instant queries of sum_over_time, count_over_time, max_over_time and min_over_time over ranges longer than this interval, optionally aggregated with the matching aggregation, "+
+		"into queries over ranges aligned to the interval, executed in parallel. Results of complete intervals are cached with query-range.response-cache-config. "+
+		"Other settings are shared with range queries. 0 disables splitting of instant queries.").
+		Default("0").DurationVar(&cfg.QueryRangeConfig.InstantSplitInterval)
+
+	cmd.Flag("query-instant.shards", "Shard instant queries of aggregations grouped by labels, e.g. sum by (job) (rate(x[5m])), into this number of queries executed in parallel, "+
+		"each selecting the series whose values of the grouping labels hash to its shard. The same aggregations as with query-range.shards are not sharded. "+
+		"Requires queriers which support the shard parameters. 0 or 1 disables sharding.").
+		Default("0").IntVar(&cfg.QueryRangeConfig.InstantShards)
+
 	// Labels tripperware flags.
 	cmd.Flag("labels.split-interval", "Split labels requests by an interval and execute in parallel, it should be greater than 0 when labels.response-cache-config is configured.").
 		Default("24h").DurationVar(&cfg.LabelsConfig.SplitQueriesByInterval)
@@ -115,15 +161,60 @@ func registerQueryFrontend(app *extkingpin.App) {
 
 	cfg.LabelsConfig.CachePathOrContent = *extflag.RegisterPathOrContent(cmd, "labels.response-cache-config", "YAML file that contains response cache configuration.", extflag.WithEnvSubstitution())
 
-	cmd.Flag("cache-compression-type", "Use compression in results cache. Supported values are: 'snappy' and '' (disable compression).").
+	cfg.TenantCachePathOrContent = *extflag.RegisterPathOrContent(cmd, "query-frontend.tenant-cache-overrides", "YAML file that contains per-tenant response cache overrides. Tenants with an override get a dedicated cache for query range and labels responses, so they can't evict cached responses of other tenants.", extflag.WithEnvSubstitution())
+
+	cmd.Flag("cache-compression-type", "Use compression in results cache. Supported values are: 'snappy', 'zstd' and '' (disable compression).").
 		Default("").StringVar(&cfg.CacheCompression)
 
 	cmd.Flag("query-frontend.downstream-url", "URL of downstream Prometheus Query compatible API.").
 		Default("http://localhost:9090").StringVar(&cfg.DownstreamURL)
 
+	cmd.Flag("query-frontend.retry-budget-ratio", "Maximum ratio of retries to requests sent downstream across all requests, shared by range queries and labels requests. "+
+		"Retries beyond the budget are not issued and the downstream error is returned. 0 disables the ratio based budget.").
+		Default("0").Float64Var(&cfg.RetryBudgetRatio)
+
+	cmd.Flag("query-frontend.retry-budget-min-retries-per-second", "Minimum number of retries per second always allowed by the retry budget, regardless of the retry ratio. "+
+		"The retry budget is disabled if both this and query-frontend.retry-budget-ratio are 0.").
+		Default("0").Float64Var(&cfg.RetryBudgetMinPerSecond)
+
+	cmd.Flag("query-frontend.hedge-downstream-url", "URL of a secondary downstream Prometheus Query compatible API. If set, split range queries and labels requests "+
+		"that didn't get a response from the downstream URL within query-frontend.hedge-delay are also sent to this URL, and the first successful response is used.").
+		Default("").StringVar(&cfg.HedgeDownstreamURL)
+
+	cmd.Flag("query-frontend.hedge-delay", "Time to wait for a response from the downstream URL before sending the hedged request to query-frontend.hedge-downstream-url.").
+		Default("5s").DurationVar(&cfg.HedgeDelay)
+
+	cmd.Flag("query-frontend.scheduler.max-concurrent-requests", "Maximum number of requests sent downstream concurrently. Requests beyond this are queued per tenant and "+
+		"dequeued fairly between tenants, weighted as configured in query-frontend.scheduler.tenant-config. 0 disables the scheduler.").
+		Default("0").IntVar(&cfg.SchedulerConfig.MaxConcurrent)
+
+	cmd.Flag("query-frontend.scheduler.max-outstanding-requests-per-tenant", "Maximum number of queued requests of a single tenant. Requests beyond this are rejected with 429. 0 means no limit.").
+		Default("100").IntVar(&cfg.SchedulerConfig.MaxOutstandingPerTenant)
+
+	cfg.TenantSchedulerPathOrContent = *extflag.RegisterPathOrContent(cmd, "query-frontend.scheduler.tenant-config", "YAML file that contains per-tenant weights and maximum outstanding requests of the scheduler.", extflag.WithEnvSubstitution())
+
+	cmd.Flag("query-frontend.pull.grpc-address", "Listen ip:port address of the gRPC server queriers with query.frontend-address pull queries from. "+
+		"If set, queries are queued per tenant and dispatched to queriers pulling them instead of being sent to query-frontend.downstream-url.").
+		Default("").StringVar(&cfg.pull.grpcAddress)
+
+	cmd.Flag("query-frontend.pull.grpc-server-tls-cert", "TLS Certificate for the gRPC server queriers pull queries from, leave blank to disable TLS.").
+		Default("").StringVar(&cfg.pull.grpcTLSSrvCert)
+
+	cmd.Flag("query-frontend.pull.grpc-server-tls-key", "TLS Key for the gRPC server queriers pull queries from, leave blank to disable TLS.").
+		Default("").StringVar(&cfg.pull.grpcTLSSrvKey)
+
+	cmd.Flag("query-frontend.pull.grpc-server-tls-client-ca", "TLS CA to verify queriers pulling queries against. If no client CA is specified, there is no client verification on server side.").
+		Default("").StringVar(&cfg.pull.grpcTLSSrvClientCA)
+
+	cmd.Flag("query-frontend.pull.max-outstanding-requests-per-tenant", "Maximum number of queued requests of a single tenant waiting to be pulled by queriers. Requests beyond this are rejected with 429.").
+		Default("100").IntVar(&cfg.pull.maxOutstandingPerTenant)
+
+	cmd.Flag("query-frontend.pull.querier-forget-delay", "Time to keep a querier whose connections broke without notifying its shutdown registered, so that it gets its queued requests back if it reconnects in time.").
+		Default("0s").DurationVar(&cfg.pull.querierForgetDelay)
+
 	cfg.DownstreamTripperConfig.CachePathOrContent = *extflag.RegisterPathOrContent(cmd, "query-frontend.downstream-tripper-config", "YAML file that contains downstream tripper configuration. If your downstream URL is localhost or 127.0.0.1 then it is highly recommended to increase max_idle_conns_per_host to at least 100.", extflag.WithEnvSubstitution())
 
-	cmd.Flag("query-frontend.compress-responses", "Compress HTTP responses.").
+	cmd.Flag("query-frontend.compress-responses", "Compress HTTP responses with the encoding negotiated from the Accept-Encoding header of requests, zstd or gzip.").
 		Default("false").BoolVar(&cfg.CompressResponses)
 
 	cmd.Flag("query-frontend.log-queries-longer-than", "Log queries that are slower than the specified duration. "+
@@ -134,6 +225,9 @@ func registerQueryFrontend(app *extkingpin.App) {
 		"If multiple headers match the request, the first matching arg specified will take precedence. "+
 		"If no headers match 'anonymous' will be used.").PlaceHolder("<http-header-name>").StringsVar(&cfg.orgIdHeaders)
 
+	cfg.tenantAccounting = extkingpin.RegisterTenantAccountingFlag(cmd)
+	cfg.auditConfig = extkingpin.RegisterAuditFlags(cmd)
+
 	cmd.Flag("log.request.decision", "Deprecation Warning - This flag would be soon deprecated, and replaced with `request.logging-config`. Request Logging for logging the start and end of requests. By default this flag is disabled. LogFinishCall : Logs the finish call of the requests. LogStartAndFinishCall : Logs the start and finish call of the requests. NoLogCall : Disable request logging.").Default("").EnumVar(&cfg.RequestLoggingDecision, "NoLogCall", "LogFinishCall", "LogStartAndFinishCall", "")
 	reqLogConfig := extkingpin.RegisterRequestLoggingFlags(cmd)
 
@@ -143,7 +237,17 @@ func registerQueryFrontend(app *extkingpin.App) {
 			return errors.Wrap(err, "error while parsing config for request logging")
 		}
 
-		return runQueryFrontend(g, logger, reg, tracer, httpLogOpts, cfg, comp)
+		tagOpts, grpcLogOpts, err := logging.ParsegRPCOptions(cfg.RequestLoggingDecision, reqLogConfig)
+		if err != nil {
+			return errors.Wrap(err, "error while parsing config for request logging")
+		}
+
+		cfg.QueryRangeConfig.DownsampleResolutions, err = downsample.ParseResolutions(cfg.downsampleResolutions)
+		if err != nil {
+			return errors.Wrap(err, "parse downsampling resolutions")
+		}
+
+		return runQueryFrontend(g, logger, reg, tracer, httpLogOpts, grpcLogOpts, tagOpts, cfg, comp, cmd.Flags())
 	})
 }
 
@@ -188,8 +292,11 @@ func runQueryFrontend(
 	reg *prometheus.Registry,
 	tracer opentracing.Tracer,
 	httpLogOpts []logging.Option,
+	grpcLogOpts []grpc_logging.Option,
+	tagOpts []tags.Option,
 	cfg *queryFrontendConfig,
 	comp component.Component,
+	cmdFlags []*kingpin.FlagModel,
 ) error {
 	queryRangeCacheConfContentYaml, err := cfg.QueryRangeConfig.CachePathOrContent.Content()
 	if err != nil {
@@ -221,6 +328,30 @@ func runQueryFrontend(
 		}
 	}
 
+	tenantCacheConfContentYaml, err := cfg.TenantCachePathOrContent.Content()
+	if err != nil {
+		return err
+	}
+	if len(tenantCacheConfContentYaml) > 0 {
+		tenantCacheConfig, err := queryfrontend.NewTenantCacheConfig(tenantCacheConfContentYaml)
+		if err != nil {
+			return errors.Wrap(err, "initializing the tenant cache config")
+		}
+		cfg.QueryRangeConfig.TenantCacheConfig = tenantCacheConfig
+		cfg.LabelsConfig.TenantCacheConfig = tenantCacheConfig
+	}
+
+	tenantSchedulerConfContentYaml, err := cfg.TenantSchedulerPathOrContent.Content()
+	if err != nil {
+		return err
+	}
+	if len(tenantSchedulerConfContentYaml) > 0 {
+		cfg.SchedulerConfig.Tenants, err = queryfrontend.NewTenantSchedulerConfig(tenantSchedulerConfContentYaml)
+		if err != nil {
+			return errors.Wrap(err, "initializing the tenant scheduler config")
+		}
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return errors.Wrap(err, "error validating the config")
 	}
@@ -240,23 +371,75 @@ func runQueryFrontend(
 		return err
 	}
 
-	roundTripper, err := cortexfrontend.NewDownstreamRoundTripper(cfg.DownstreamURL, downstreamTripper)
-	if err != nil {
-		return errors.Wrap(err, "setup downstream roundtripper")
+	grpcProbe := prober.NewGRPC()
+
+	var roundTripper http.RoundTripper
+	if cfg.pull.grpcAddress != "" {
+		// Queriers pull queries from the frontend instead of the frontend sending them to the downstream URL.
+		limits, err := cortexvalidation.NewOverrides(cortexvalidation.Limits{}, nil)
+		if err != nil {
+			return errors.Wrap(err, "initialize pull limits")
+		}
+		fe, err := cortexfrontendv1.New(cortexfrontendv1.Config{
+			MaxOutstandingPerTenant: cfg.pull.maxOutstandingPerTenant,
+			QuerierForgetDelay:      cfg.pull.querierForgetDelay,
+		}, limits, logger, reg)
+		if err != nil {
+			return errors.Wrap(err, "create pull frontend")
+		}
+		if err := services.StartAndAwaitRunning(context.Background(), fe); err != nil {
+			return errors.Wrap(err, "start pull frontend")
+		}
+		roundTripper = transport.AdaptGrpcRoundTripperToHTTPRoundTripper(fe)
+
+		tlsCfg, err := grpcServerTLSConfig(logger, reg, nil, cfg.pull.grpcTLSSrvCert, cfg.pull.grpcTLSSrvKey, cfg.pull.grpcTLSSrvClientCA)
+		if err != nil {
+			return errors.Wrap(err, "setup gRPC server")
+		}
+		s := grpcserver.New(logger, reg, tracer, grpcLogOpts, tagOpts, comp, grpcProbe,
+			grpcserver.WithServer(func(s *grpc.Server) { frontendv1pb.RegisterFrontendServer(s, fe) }),
+			grpcserver.WithListen(cfg.pull.grpcAddress),
+			grpcserver.WithTLSConfig(tlsCfg),
+		)
+		g.Add(func() error {
+			return s.ListenAndServe()
+		}, func(err error) {
+			s.Shutdown(err)
+			if err := services.StopAndAwaitTerminated(context.Background(), fe); err != nil {
+				level.Warn(logger).Log("msg", "stopping pull frontend failed", "err", err)
+			}
+		})
+	} else {
+		roundTripper, err = cortexfrontend.NewDownstreamRoundTripper(cfg.DownstreamURL, exthttp.NewCompressionTransport(downstreamTripper))
+		if err != nil {
+			return errors.Wrap(err, "setup downstream roundtripper")
+		}
 	}
 
+	if cfg.HedgeDownstreamURL != "" {
+		hedgeRoundTripper, err := cortexfrontend.NewDownstreamRoundTripper(cfg.HedgeDownstreamURL, exthttp.NewCompressionTransport(downstreamTripper))
+		if err != nil {
+			return errors.Wrap(err, "setup hedge downstream roundtripper")
+		}
+		roundTripper = queryfrontend.NewHedgedRoundTripper(roundTripper, hedgeRoundTripper, cfg.HedgeDelay, reg)
+	}
+
+	// Queue downstream requests per tenant once the concurrency limit is reached.
+	roundTripper = queryfrontend.NewScheduler(roundTripper, cfg.SchedulerConfig, reg)
+
 	// Wrap the downstream RoundTripper into query frontend Tripperware.
 	roundTripper = tripperWare(roundTripper)
 
 	// Create the query frontend transport.
 	handler := transport.NewHandler(*cfg.CortexHandlerConfig, roundTripper, logger, nil)
 	if cfg.CompressResponses {
-		handler = gziphandler.GzipHandler(handler)
+		handler = exthttp.NewCompressionHandler(handler)
 	}
 
 	httpProbe := prober.NewHTTP()
 	statusProber := prober.Combine(
 		httpProbe,
+		grpcProbe,
 		prober.NewInstrumentation(comp, logger, extprom.WrapRegistererWithPrefix("thanos_", reg)),
 	)
 
@@ -266,15 +449,50 @@ func runQueryFrontend(
 
 	// Start metrics HTTP server.
 	{
+		authMiddleware, err := oidcMiddleware(logger, reg, cfg.http.oidcConfig)
+		if err != nil {
+			return err
+		}
+		ipf, err := ipFilters(logger, reg, cfg.http.ipFilterConfig)
+		if err != nil {
+			return err
+		}
 		srv := httpserver.New(logger, reg, comp, httpProbe,
 			httpserver.WithListen(cfg.http.bindAddress),
 			httpserver.WithGracePeriod(time.Duration(cfg.http.gracePeriod)),
 			httpserver.WithTLSConfig(cfg.http.tlsConfig),
+			statusOption(cmdFlags),
+			httpserver.WithAuthentication(authMiddleware),
+			httpserver.WithIPFilter(ipf.http),
 		)
 
+		var accounting *tenancy.Accounting
+		if *cfg.tenantAccounting {
+			accounting = tenancy.NewAccounting(reg)
+		}
+
+		var auditLogger *audit.Logger
+		auditContentYaml, err := cfg.auditConfig.Content()
+		if err != nil {
+			return err
+		}
+		if len(auditContentYaml) > 0 {
+			auditLogger, err = audit.NewLogger(logger, reg, auditContentYaml, comp.String())
+			if err != nil {
+				return errors.Wrap(err, "create audit logger")
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			g.Add(func() error {
+				return auditLogger.Run(ctx)
+			}, func(error) {
+				cancel()
+			})
+		}
+
 		instr := func(f http.HandlerFunc) http.HandlerFunc {
 			hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				orgId := extractOrgId(cfg, r)
+				accounting.Query(orgId)
 				name := "query-frontend"
 				if !cfg.webDisableCORS {
 					api.SetCORS(w)
@@ -285,9 +503,11 @@ func runQueryFrontend(
 					logger,
 					ins.NewHandler(
 						name,
-						gziphandler.GzipHandler(
+						exthttp.NewCompressionHandler(
 							middleware.RequestID(
-								logMiddleware.HTTPMiddleware(name, f),
+								logMiddleware.HTTPMiddleware(name, auditLogger.HTTPMiddleware(name, func(r *http.Request) string {
+									return extractOrgId(cfg, r)
+								}, f)),
 							),
 						),
 					),
@@ -9,6 +9,7 @@ import (
 
 	"github.com/prometheus/prometheus/promql"
 
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/testutil"
 )
 
@@ -17,9 +18,12 @@ func TestEngineFactory(t *testing.T) {
 		engineRaw = promql.NewEngine(promql.EngineOpts{})
 		engine5m  = promql.NewEngine(promql.EngineOpts{LookbackDelta: 5 * time.Minute})
 		engine1h  = promql.NewEngine(promql.EngineOpts{LookbackDelta: 1 * time.Hour})
+		engine6h  = promql.NewEngine(promql.EngineOpts{LookbackDelta: 6 * time.Hour})
 	)
 	mockNewEngine := func(opts promql.EngineOpts) *promql.Engine {
 		switch opts.LookbackDelta {
+		case 6 * time.Hour:
+			return engine6h
 		case 1 * time.Hour:
 			return engine1h
 		case 5 * time.Minute:
@@ -102,10 +106,16 @@ func TestEngineFactory(t *testing.T) {
 		}
 	)
 	for _, td := range tData {
-		e := engineFactory(mockNewEngine, promql.EngineOpts{LookbackDelta: td.lookbackDelta}, td.dynamicLookbackDelta)
+		e := engineFactory(mockNewEngine, promql.EngineOpts{LookbackDelta: td.lookbackDelta}, td.dynamicLookbackDelta, []int64{downsample.ResLevel1, downsample.ResLevel2})
 		for _, tc := range td.tcs {
 			got := e(tc.stepMillis)
 			testutil.Equals(t, tc.expect, got)
 		}
 	}
+
+	// Custom downsampling resolutions get their own engines.
+	e := engineFactory(mockNewEngine, promql.EngineOpts{LookbackDelta: 3 * time.Minute}, true, []int64{downsample.ResLevel1, downsample.ResLevel2, 6 * time.Hour.Milliseconds()})
+	testutil.Equals(t, engine1h, e(time.Hour.Milliseconds()))
+	testutil.Equals(t, engine6h, e(2*time.Hour.Milliseconds()))
+	testutil.Equals(t, engine6h, e(6*time.Hour.Milliseconds()))
 }
@@ -4,12 +4,14 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
 	"os"
 	"path"
 	"time"
 
+	"github.com/alecthomas/units"
 	extflag "github.com/efficientgo/tools/extkingpin"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -23,7 +25,11 @@ import (
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/tsdb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gopkg.in/alecthomas/kingpin.v2"
 
+	"github.com/thanos-io/thanos/pkg/admin"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/component"
 	"github.com/thanos-io/thanos/pkg/exemplars"
@@ -32,6 +38,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/extprom"
 	"github.com/thanos-io/thanos/pkg/info"
 	"github.com/thanos-io/thanos/pkg/info/infopb"
+	"github.com/thanos-io/thanos/pkg/ipfilter"
 	"github.com/thanos-io/thanos/pkg/logging"
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/objstore/client"
@@ -40,9 +47,13 @@ import (
 	"github.com/thanos-io/thanos/pkg/runutil"
 	grpcserver "github.com/thanos-io/thanos/pkg/server/grpc"
 	httpserver "github.com/thanos-io/thanos/pkg/server/http"
+	"github.com/thanos-io/thanos/pkg/shipper"
+	"github.com/thanos-io/thanos/pkg/spiffe"
 	"github.com/thanos-io/thanos/pkg/store"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/tenancy"
 	"github.com/thanos-io/thanos/pkg/tls"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus"
 )
 
 func registerReceive(app *extkingpin.App) {
@@ -69,15 +80,21 @@ func registerReceive(app *extkingpin.App) {
 			return errors.Wrap(err, "error while parsing config for request logging")
 		}
 
+		if err := validateWALSegmentSize(conf.walSegmentSize); err != nil {
+			return errors.Wrap(err, "invalid --tsdb.wal-segment-size")
+		}
+
 		tsdbOpts := &tsdb.Options{
-			MinBlockDuration:       int64(time.Duration(*conf.tsdbMinBlockDuration) / time.Millisecond),
-			MaxBlockDuration:       int64(time.Duration(*conf.tsdbMaxBlockDuration) / time.Millisecond),
-			RetentionDuration:      int64(time.Duration(*conf.retention) / time.Millisecond),
-			NoLockfile:             conf.noLockFile,
-			WALCompression:         conf.walCompression,
-			AllowOverlappingBlocks: conf.tsdbAllowOverlappingBlocks,
-			MaxExemplars:           conf.tsdbMaxExemplars,
-			EnableExemplarStorage:  true,
+			MinBlockDuration:               int64(time.Duration(*conf.tsdbMinBlockDuration) / time.Millisecond),
+			MaxBlockDuration:               int64(time.Duration(*conf.tsdbMaxBlockDuration) / time.Millisecond),
+			RetentionDuration:              int64(time.Duration(*conf.retention) / time.Millisecond),
+			NoLockfile:                     conf.noLockFile,
+			WALCompression:                 conf.walCompression,
+			WALSegmentSize:                 int(conf.walSegmentSize),
+			EnableMemorySnapshotOnShutdown: conf.memorySnapshotOnShutdown,
+			AllowOverlappingBlocks:         conf.tsdbAllowOverlappingBlocks,
+			MaxExemplars:                   conf.tsdbMaxExemplars,
+			EnableExemplarStorage:          true,
 		}
 
 		// Are we running in IngestorOnly, RouterOnly or RouterIngestor mode?
@@ -95,6 +112,7 @@ func registerReceive(app *extkingpin.App) {
 			metadata.HashFunc(conf.hashFunc),
 			receiveMode,
 			conf,
+			cmd.Flags(),
 		)
 	})
 }
@@ -112,30 +130,40 @@ func runReceive(
 	hashFunc metadata.HashFunc,
 	receiveMode receive.ReceiverMode,
 	conf *receiveConfig,
+	cmdFlags []*kingpin.FlagModel,
 ) error {
 	logger = log.With(logger, "component", "receive")
 
 	level.Info(logger).Log("mode", receiveMode, "msg", "running receive")
 
-	rwTLSConfig, err := tls.NewServerConfig(log.With(logger, "protocol", "HTTP"), conf.rwServerCert, conf.rwServerKey, conf.rwServerClientCA)
+	rwTLSConfig, err := tls.NewServerConfig(log.With(logger, "protocol", "HTTP"), extprom.WrapRegistererWith(prometheus.Labels{"protocol": "http"}, reg), conf.rwServerCert, conf.rwServerKey, conf.rwServerClientCA)
 	if err != nil {
 		return err
 	}
 
-	dialOpts, err := extgrpc.StoreClientGRPCOpts(
-		logger,
-		reg,
-		tracer,
-		*conf.grpcCert != "",
-		*conf.grpcClientCA == "",
-		conf.rwClientCert,
-		conf.rwClientKey,
-		conf.rwClientServerCA,
-		conf.rwClientServerName,
-	)
+	spiffeSrc, err := spiffeSource(g, logger, reg, *conf.spiffeAddr, *conf.spiffeIDs)
 	if err != nil {
 		return err
 	}
+	var dialOpts []grpc.DialOption
+	if spiffeSrc != nil {
+		dialOpts = extgrpc.StoreClientGRPCOptsWithCredentials(reg, tracer, credentials.NewTLS(spiffeSrc.ClientTLSConfig()))
+	} else {
+		dialOpts, err = extgrpc.StoreClientGRPCOpts(
+			logger,
+			reg,
+			tracer,
+			*conf.grpcCert != "",
+			*conf.grpcClientCA == "",
+			conf.rwClientCert,
+			conf.rwClientKey,
+			conf.rwClientServerCA,
+			conf.rwClientServerName,
+		)
+		if err != nil {
+			return err
+		}
+	}
 
 	var bkt objstore.Bucket
 	confContentYaml, err := conf.objStoreConfig.Content()
@@ -146,6 +174,16 @@ func runReceive(
 	// Has this thanos receive instance been configured to ingest metrics into a local TSDB?
 	enableIngestion := receiveMode == receive.IngestorOnly || receiveMode == receive.RouterIngestor
 
+	tenantBucketsContentYaml, err := conf.tenantBucketsConfig.Content()
+	if err != nil {
+		return err
+	}
+	tenantBuckets := enableIngestion && len(tenantBucketsContentYaml) > 0
+	bktReg := prometheus.Registerer(reg)
+	if tenantBuckets {
+		bktReg = receive.TenantBucketsRegisterer(reg)
+	}
+
 	upload := len(confContentYaml) > 0
 	if enableIngestion {
 		if upload {
@@ -158,7 +196,7 @@ func runReceive(
 			}
 			// The background shipper continuously scans the data directory and uploads
 			// new blocks to object storage service.
-			bkt, err = client.NewBucket(logger, confContentYaml, reg, comp.String())
+			bkt, err = client.NewBucket(logger, confContentYaml, bktReg, comp.String())
 			if err != nil {
 				return err
 			}
@@ -167,12 +205,50 @@ func runReceive(
 		}
 	}
 
+	var tenantBkts *receive.TenantBuckets
+	if tenantBuckets {
+		if !upload {
+			return errors.New("buckets of tenants require the object store configuration of the default bucket")
+		}
+		tenantBucketsConf, err := receive.ParseTenantBucketsConfig(tenantBucketsContentYaml)
+		if err != nil {
+			return err
+		}
+		tenantBkts, err = receive.NewTenantBuckets(logger, reg, tenantBucketsConf, bkt, comp.String())
+		if err != nil {
+			return err
+		}
+	}
+
 	// TODO(brancz): remove after a couple of versions
 	// Migrate non-multi-tsdb capable storage to multi-tsdb disk layout.
 	if err := migrateLegacyStorage(logger, conf.dataDir, conf.defaultTenantID); err != nil {
 		return errors.Wrapf(err, "migrate legacy storage in %v to default tenant %v", conf.dataDir, conf.defaultTenantID)
 	}
 
+	tenantWALSegmentSizes, err := parseTenantWALSegmentSizes(conf.tenantWALSegmentSizes)
+	if err != nil {
+		return err
+	}
+	multiTSDBOpts := []receive.MultiTSDBOption{
+		receive.WithTenantTSDBOptions(func(tenantID string, opts *tsdb.Options) {
+			if size, ok := tenantWALSegmentSizes[tenantID]; ok {
+				opts.WALSegmentSize = size
+			}
+			if len(conf.memorySnapshotTenants) == 0 {
+				return
+			}
+			opts.EnableMemorySnapshotOnShutdown = false
+			for _, t := range conf.memorySnapshotTenants {
+				if t == tenantID {
+					opts.EnableMemorySnapshotOnShutdown = conf.memorySnapshotOnShutdown
+				}
+			}
+		}),
+	}
+	if tenantBkts != nil {
+		multiTSDBOpts = append(multiTSDBOpts, receive.WithTenantBuckets(tenantBkts.Bucket))
+	}
 	dbs := receive.NewMultiTSDB(
 		conf.dataDir,
 		logger,
@@ -183,8 +259,38 @@ func runReceive(
 		bkt,
 		conf.allowOutOfOrderUpload,
 		hashFunc,
+		conf.tenantBucketPrefix,
+		multiTSDBOpts...,
 	)
+	// The same middleware authenticates the requests of the HTTP server and the remote write requests.
+	authMiddleware, err := oidcTenantMiddleware(logger, reg, conf.httpOIDCConfig, conf.tenantHeader)
+	if err != nil {
+		return err
+	}
+	ipf, err := ipFilters(logger, reg, conf.ipFilterConfig)
+	if err != nil {
+		return err
+	}
+
 	writer := receive.NewWriter(log.With(logger, "component", "receive-writer"), dbs)
+	var accounting *tenancy.Accounting
+	if *conf.tenantAccounting {
+		accounting = tenancy.NewAccounting(reg)
+	}
+	var writeLimiter *receive.WriteLimiter
+	if conf.limitsConfigFile != "" {
+		writeLimiter, err = receive.NewWriteLimiter(log.With(logger, "component", "receive-limits"), reg, conf.limitsConfigFile)
+		if err != nil {
+			return errors.Wrap(err, "load write limits")
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			writeLimiter.Run(ctx, time.Duration(*conf.limitsConfigReloadInterval))
+			return nil
+		}, func(error) {
+			cancel()
+		})
+	}
 	webHandler := receive.NewHandler(log.With(logger, "component", "receive-handler"), &receive.Options{
 		Writer:            writer,
 		ListenAddress:     conf.rwAddress,
@@ -199,6 +305,10 @@ func runReceive(
 		TLSConfig:         rwTLSConfig,
 		DialOpts:          dialOpts,
 		ForwardTimeout:    time.Duration(*conf.forwardTimeout),
+		Authentication:    authMiddleware,
+		IPFilter:          ipf.remoteWrite,
+		TenantAccounting:  accounting,
+		WriteLimiter:      writeLimiter,
 	})
 
 	grpcProbe := prober.NewGRPC()
@@ -225,7 +335,7 @@ func runReceive(
 	if enableIngestion {
 		level.Debug(logger).Log("msg", "setting up tsdb")
 		{
-			if err := startTSDBAndUpload(g, logger, reg, dbs, reloadGRPCServer, uploadC, hashringChangedChan, upload, uploadDone, statusProber, bkt); err != nil {
+			if err := startTSDBAndUpload(g, logger, reg, dbs, reloadGRPCServer, uploadC, hashringChangedChan, upload, uploadDone, statusProber, bkt, tenantBkts, conf.uploadHintURLs, time.Duration(*conf.tsdbIdleTenantTimeout)); err != nil {
 				return err
 			}
 		}
@@ -244,6 +354,10 @@ func runReceive(
 			httpserver.WithListen(*conf.httpBindAddr),
 			httpserver.WithGracePeriod(time.Duration(*conf.httpGracePeriod)),
 			httpserver.WithTLSConfig(*conf.httpTLSConfig),
+			statusOption(cmdFlags),
+			httpserver.WithStatusFunc("tenants", func() (interface{}, error) { return dbs.TenantStats() }),
+			httpserver.WithAuthentication(authMiddleware),
+			httpserver.WithIPFilter(ipf.http),
 		)
 		g.Add(func() error {
 			statusProber.Healthy()
@@ -259,7 +373,7 @@ func runReceive(
 
 	level.Debug(logger).Log("msg", "setting up grpc server")
 	{
-		if err := setupAndRunGRPCServer(g, logger, reg, tracer, conf, reloadGRPCServer, comp, dbs, webHandler, grpcLogOpts, tagOpts, grpcProbe); err != nil {
+		if err := setupAndRunGRPCServer(g, logger, reg, tracer, conf, reloadGRPCServer, comp, dbs, webHandler, grpcLogOpts, tagOpts, grpcProbe, ipf.grpc, spiffeSrc); err != nil {
 			return err
 		}
 	}
@@ -280,6 +394,27 @@ func runReceive(
 	return nil
 }
 
+// adminAPIOptions returns the options of the info and gRPC servers which serve the admin API, authenticated with the
+// token of --receive.admin-api.token-file, if --receive.admin-api.enabled is set.
+func adminAPIOptions(conf *receiveConfig, dbs func() map[string]*tsdb.DB) ([]info.ServerOptionFunc, []grpcserver.Option, error) {
+	if !conf.adminAPIEnabled {
+		return nil, nil, nil
+	}
+	if conf.adminAPITokenFile == "" {
+		return nil, nil, errors.New("--receive.admin-api.token-file is required to enable the admin API")
+	}
+	token, err := ioutil.ReadFile(conf.adminAPITokenFile)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read admin API token file")
+	}
+	if len(bytes.TrimSpace(token)) == 0 {
+		return nil, nil, errors.Errorf("admin API token file %s is empty", conf.adminAPITokenFile)
+	}
+	return []info.ServerOptionFunc{info.WithAdminInfoFunc()},
+		[]grpcserver.Option{grpcserver.WithServer(admin.RegisterAdminServer(admin.NewMultiTSDB(dbs, string(bytes.TrimSpace(token)))))},
+		nil
+}
+
 // setupAndRunGRPCServer sets up the configuration for the gRPC server.
 // It also sets up a handler for reloading the server if tsdb reloads.
 func setupAndRunGRPCServer(g *run.Group,
@@ -294,9 +429,15 @@ func setupAndRunGRPCServer(g *run.Group,
 	grpcLogOpts []grpc_logging.Option,
 	tagOpts []tags.Option,
 	grpcProbe *prober.GRPCProbe,
-
+	ipFilter *ipfilter.Filter,
+	spiffeSrc *spiffe.Source,
 ) error {
 
+	adminInfoOpts, adminServerOpts, err := adminAPIOptions(conf, dbs.TSDBs)
+	if err != nil {
+		return err
+	}
+
 	var s *grpcserver.Server
 	// startGRPCListening re-starts the gRPC server once it receives a signal.
 	startGRPCListening := make(chan struct{})
@@ -304,7 +445,7 @@ func setupAndRunGRPCServer(g *run.Group,
 	g.Add(func() error {
 		defer close(startGRPCListening)
 
-		tlsCfg, err := tls.NewServerConfig(log.With(logger, "protocol", "gRPC"), *conf.grpcCert, *conf.grpcKey, *conf.grpcClientCA)
+		tlsCfg, err := grpcServerTLSConfig(logger, reg, spiffeSrc, *conf.grpcCert, *conf.grpcKey, *conf.grpcClientCA)
 		if err != nil {
 			return errors.Wrap(err, "setup gRPC server")
 		}
@@ -325,29 +466,34 @@ func setupAndRunGRPCServer(g *run.Group,
 				WriteableStoreServer: webHandler,
 			}
 
-			infoSrv := info.NewInfoServer(
-				component.Receive.String(),
+			infoOpts := []info.ServerOptionFunc{
 				info.WithLabelSetFunc(func() []labelpb.ZLabelSet { return mts.LabelSet() }),
 				info.WithStoreInfoFunc(func() *infopb.StoreInfo {
 					minTime, maxTime := mts.TimeRange()
 					return &infopb.StoreInfo{
-						MinTime: minTime,
-						MaxTime: maxTime,
+						MinTime:               minTime,
+						MaxTime:               maxTime,
+						SupportsSeriesBatches: true,
 					}
 				}),
 				info.WithExemplarsInfoFunc(),
-			)
+				info.WithTSDBStatusInfoFunc(),
+			}
+			infoSrv := info.NewInfoServer(component.Receive.String(), append(infoOpts, adminInfoOpts...)...)
 
-			s = grpcserver.New(logger, &receive.UnRegisterer{Registerer: reg}, tracer, grpcLogOpts, tagOpts, comp, grpcProbe,
+			srvOpts := []grpcserver.Option{
 				grpcserver.WithServer(store.RegisterStoreServer(rw)),
 				grpcserver.WithServer(store.RegisterWritableStoreServer(rw)),
 				grpcserver.WithServer(exemplars.RegisterExemplarsServer(exemplars.NewMultiTSDB(dbs.TSDBExemplars))),
+				grpcserver.WithServer(tsdbstatus.RegisterTSDBStatusServer(tsdbstatus.NewMultiTSDB(dbs.TSDBStatuses))),
 				grpcserver.WithServer(info.RegisterInfoServer(infoSrv)),
 				grpcserver.WithListen(*conf.grpcBindAddr),
 				grpcserver.WithGracePeriod(time.Duration(*conf.grpcGracePeriod)),
 				grpcserver.WithTLSConfig(tlsCfg),
+				grpcserver.WithIPFilter(ipFilter),
 				grpcserver.WithMaxConnAge(*conf.grpcMaxConnAge),
-			)
+			}
+			s = grpcserver.New(logger, &receive.UnRegisterer{Registerer: reg}, tracer, grpcLogOpts, tagOpts, comp, grpcProbe, append(srvOpts, adminServerOpts...)...)
 			startGRPCListening <- struct{}{}
 		}
 		if s != nil {
@@ -491,7 +637,9 @@ func startTSDBAndUpload(g *run.Group,
 	uploadDone chan struct{},
 	statusProber prober.Probe,
 	bkt objstore.Bucket,
-
+	tenantBkts *receive.TenantBuckets,
+	uploadHintURLs []string,
+	idleTenantTimeout time.Duration,
 ) error {
 
 	log.With(logger, "component", "storage")
@@ -503,6 +651,10 @@ func startTSDBAndUpload(g *run.Group,
 		Name: "thanos_receive_multi_db_updates_completed_total",
 		Help: "Number of Multi DB completed reloads with flush and potential upload due to hashring changes",
 	})
+	idleTenantsPruned := promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "thanos_receive_multi_db_idle_tenants_pruned_total",
+		Help: "Number of TSDBs of idle tenants flushed, uploaded if enabled, and closed",
+	})
 
 	level.Debug(logger).Log("msg", "removing storage lock files if any")
 	if err := dbs.RemoveLockFilesIfAny(); err != nil {
@@ -510,11 +662,23 @@ func startTSDBAndUpload(g *run.Group,
 	}
 
 	// TSDBs reload logic, listening on hashring changes.
-	cancel := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
 	g.Add(func() error {
 		defer close(reloadGRPCServer)
 		defer close(uploadC)
 
+		// TSDBs of idle tenants are pruned in the same routine, so that it doesn't race with reloads.
+		var pruneC <-chan time.Time
+		if idleTenantTimeout > 0 {
+			interval := time.Minute
+			if idleTenantTimeout < interval {
+				interval = idleTenantTimeout
+			}
+			tick := time.NewTicker(interval)
+			defer tick.Stop()
+			pruneC = tick.C
+		}
+
 		// Before quitting, ensure the WAL is flushed and the DBs are closed.
 		defer func() {
 			level.Info(logger).Log("msg", "shutting down storage")
@@ -532,8 +696,17 @@ func startTSDBAndUpload(g *run.Group,
 
 		for {
 			select {
-			case <-cancel:
+			case <-ctx.Done():
 				return nil
+			case <-pruneC:
+				pruned, err := dbs.PruneIdle(ctx, idleTenantTimeout)
+				if err != nil {
+					level.Warn(logger).Log("msg", "pruning idle tenants failed", "err", err)
+				}
+				if pruned > 0 {
+					idleTenantsPruned.Add(float64(pruned))
+					level.Info(logger).Log("msg", "pruned idle tenants", "pruned", pruned)
+				}
 			case _, ok := <-hashringChangedChan:
 				if !ok {
 					return nil
@@ -558,7 +731,7 @@ func startTSDBAndUpload(g *run.Group,
 			}
 		}
 	}, func(err error) {
-		close(cancel)
+		cancel()
 	})
 
 	if upload {
@@ -573,6 +746,9 @@ func startTSDBAndUpload(g *run.Group,
 				return err
 			}
 			level.Debug(logger).Log("msg", "upload phase done", "uploaded", uploaded, "elapsed", time.Since(start))
+			if uploaded > 0 {
+				shipper.NotifyUploads(ctx, logger, uploadHintURLs)
+			}
 			return nil
 		}
 		{
@@ -587,6 +763,10 @@ func startTSDBAndUpload(g *run.Group,
 			g.Add(func() error {
 				// Ensure we clean up everything properly.
 				defer func() {
+					// Buckets of tenants mirror the default bucket, so they are closed first.
+					if tenantBkts != nil {
+						runutil.CloseWithLogOnErr(logger, tenantBkts, "tenant bucket clients")
+					}
 					runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
 				}()
 
@@ -601,6 +781,9 @@ func startTSDBAndUpload(g *run.Group,
 						level.Error(logger).Log("msg", "the final upload failed", "err", err)
 						return
 					}
+					if uploaded > 0 {
+						shipper.NotifyUploads(ctx, logger, uploadHintURLs)
+					}
 					cancel()
 					level.Info(logger).Log("msg", "the final cut block was uploaded", "uploaded", uploaded)
 				}()
@@ -675,6 +858,8 @@ type receiveConfig struct {
 	httpBindAddr    *string
 	httpGracePeriod *model.Duration
 	httpTLSConfig   *string
+	httpOIDCConfig  *extflag.PathOrContent
+	ipFilterConfig  *extflag.PathOrContent
 
 	grpcBindAddr    *string
 	grpcGracePeriod *model.Duration
@@ -682,6 +867,8 @@ type receiveConfig struct {
 	grpcKey         *string
 	grpcClientCA    *string
 	grpcMaxConnAge  *time.Duration
+	spiffeAddr      *string
+	spiffeIDs       *[]string
 
 	rwAddress          string
 	rwServerCert       string
@@ -714,21 +901,39 @@ type receiveConfig struct {
 	tsdbMaxBlockDuration       *model.Duration
 	tsdbAllowOverlappingBlocks bool
 	tsdbMaxExemplars           int64
+	tsdbIdleTenantTimeout      *model.Duration
 
-	walCompression bool
-	noLockFile     bool
+	walCompression           bool
+	walSegmentSize           units.Base2Bytes
+	tenantWALSegmentSizes    map[string]string
+	memorySnapshotOnShutdown bool
+	memorySnapshotTenants    []string
+	noLockFile               bool
+
+	adminAPIEnabled   bool
+	adminAPITokenFile string
 
 	hashFunc string
 
 	ignoreBlockSize       bool
 	allowOutOfOrderUpload bool
+	uploadHintURLs        []string
+	tenantBucketPrefix    bool
+	tenantBucketsConfig   *extflag.PathOrContent
+	tenantAccounting      *bool
+
+	limitsConfigFile           string
+	limitsConfigReloadInterval *model.Duration
 
 	reqLogConfig *extflag.PathOrContent
 }
 
 func (rc *receiveConfig) registerFlag(cmd extkingpin.FlagClause) {
 	rc.httpBindAddr, rc.httpGracePeriod, rc.httpTLSConfig = extkingpin.RegisterHTTPFlags(cmd)
+	rc.httpOIDCConfig = extkingpin.RegisterHTTPOIDCFlags(cmd)
+	rc.ipFilterConfig = extkingpin.RegisterIPFilterFlags(cmd)
 	rc.grpcBindAddr, rc.grpcGracePeriod, rc.grpcCert, rc.grpcKey, rc.grpcClientCA, rc.grpcMaxConnAge = extkingpin.RegisterGRPCFlags(cmd)
+	rc.spiffeAddr, rc.spiffeIDs = extkingpin.RegisterSPIFFEFlags(cmd)
 
 	cmd.Flag("remote-write.address", "Address to listen on for remote write requests.").
 		Default("0.0.0.0:19291").StringVar(&rc.rwAddress)
@@ -771,6 +976,13 @@ func (rc *receiveConfig) registerFlag(cmd extkingpin.FlagClause) {
 
 	cmd.Flag("receive.tenant-label-name", "Label name through which the tenant will be announced.").Default(receive.DefaultTenantLabel).StringVar(&rc.tenantLabelName)
 
+	cmd.Flag("receive.tenant-bucket-prefix", "If true, blocks of each tenant are uploaded under a prefix of the tenant ID in the bucket, and tenants can't access the objects of each other. Tenant IDs must not contain path separators.").
+		Default("false").BoolVar(&rc.tenantBucketPrefix)
+
+	rc.tenantBucketsConfig = extflag.RegisterPathOrContent(cmd, "receive.tenant-buckets-config", "YAML file with the object store configurations of the buckets the blocks of specific tenants are uploaded to, instead of or in addition to the default bucket, e.g. to keep their data in a specific region. See format details: https://thanos.io/tip/components/receive.md/#tenant-buckets", extflag.WithEnvSubstitution())
+
+	rc.tenantAccounting = extkingpin.RegisterTenantAccountingFlag(cmd)
+
 	cmd.Flag("receive.replica-header", "HTTP header specifying the replica number of a write request.").Default(receive.DefaultReplicaHeader).StringVar(&rc.replicaHeader)
 
 	cmd.Flag("receive.replication-factor", "How many times to replicate incoming write requests.").Default("1").Uint64Var(&rc.replicationFactor)
@@ -783,7 +995,25 @@ func (rc *receiveConfig) registerFlag(cmd extkingpin.FlagClause) {
 
 	cmd.Flag("tsdb.allow-overlapping-blocks", "Allow overlapping blocks, which in turn enables vertical compaction and vertical query merge.").Default("false").BoolVar(&rc.tsdbAllowOverlappingBlocks)
 
-	cmd.Flag("tsdb.wal-compression", "Compress the tsdb WAL.").Default("true").BoolVar(&rc.walCompression)
+	cmd.Flag("tsdb.wal-compression", "Compress the tsdb WAL with Snappy.").Default("true").BoolVar(&rc.walCompression)
+
+	cmd.Flag("tsdb.wal-segment-size", "Size at which the WAL of each tenant TSDB is split into segment files, between 10MiB and 256MiB. Larger segments mean less frequent fsyncs of new segments, at the cost of coarser WAL truncations. 0B uses the TSDB default of 128MiB.").
+		Default("0B").BytesVar(&rc.walSegmentSize)
+
+	cmd.Flag("tsdb.tenant-wal-segment-size", "WAL segment size of the TSDB of the given tenant, overriding --tsdb.wal-segment-size (repeatable), e.g. to use larger segments for tenants with high ingestion rates.").
+		PlaceHolder("<tenant>=<size>").StringMapVar(&rc.tenantWALSegmentSizes)
+
+	cmd.Flag("tsdb.memory-snapshot-on-shutdown", "Take a snapshot of the in-memory data of tenant TSDBs on shutdown, and restore it on startup instead of replaying the whole WAL. Shutdowns take longer, but restarts are much faster.").
+		Default("false").BoolVar(&rc.memorySnapshotOnShutdown)
+
+	cmd.Flag("tsdb.memory-snapshot-tenant", "Tenant whose TSDB takes memory snapshots with --tsdb.memory-snapshot-on-shutdown (repeated). If none is given, the TSDBs of all tenants do.").
+		StringsVar(&rc.memorySnapshotTenants)
+
+	cmd.Flag("receive.admin-api.enabled", "Serve the admin gRPC API, which allows queriers with an admin API to delete the series of a tenant from its TSDB.").
+		Default("false").BoolVar(&rc.adminAPIEnabled)
+
+	cmd.Flag("receive.admin-api.token-file", "Path to the file with the bearer token which requests to the admin gRPC API have to send, the same as --admin-api.token-file of the queriers. Required with --receive.admin-api.enabled.").
+		PlaceHolder("<path>").StringVar(&rc.adminAPITokenFile)
 
 	cmd.Flag("tsdb.no-lockfile", "Do not create lockfile in TSDB data directory. In any case, the lockfiles will be deleted on next startup.").Default("false").BoolVar(&rc.noLockFile)
 
@@ -793,6 +1023,10 @@ func (rc *receiveConfig) registerFlag(cmd extkingpin.FlagClause) {
 			" ingesting a new exemplar will evict the oldest exemplar from storage. 0 (or less) value of this flag disables exemplars storage.").
 		Default("0").Int64Var(&rc.tsdbMaxExemplars)
 
+	rc.tsdbIdleTenantTimeout = extkingpin.ModelDuration(cmd.Flag("tsdb.idle-tenant-timeout",
+		"Duration without write requests after which the TSDB of a tenant is flushed, uploaded and closed, freeing its memory. The TSDB is opened again on the next write request of the tenant. 0s disables it.").
+		Default("0s"))
+
 	cmd.Flag("hash-func", "Specify which hash function to use when calculating the hashes of produced files. If no function has been specified, it does not happen. This permits avoiding downloading some files twice albeit at some performance cost. Possible values are: \"\", \"SHA256\".").
 		Default("").EnumVar(&rc.hashFunc, "SHA256", "")
 
@@ -804,6 +1038,17 @@ func (rc *receiveConfig) registerFlag(cmd extkingpin.FlagClause) {
 			"about order.").
 		Default("false").Hidden().BoolVar(&rc.allowOutOfOrderUpload)
 
+	cmd.Flag("shipper.upload-hint-url",
+		"URL to send a POST request to after uploading blocks, e.g. 'http://<store>/api/v1/blocks/sync' for store gateways to load new blocks without waiting for their next sync (repeated field).").
+		StringsVar(&rc.uploadHintURLs)
+
+	cmd.Flag("receive.limits-config-file",
+		"Path to YAML file with the limits of the write requests of tenants, e.g. the maximum number of labels per series. Series exceeding the limits are rejected. The file is reloaded periodically.").
+		PlaceHolder("<path>").StringVar(&rc.limitsConfigFile)
+
+	rc.limitsConfigReloadInterval = extkingpin.ModelDuration(cmd.Flag("receive.limits-config-reload-interval", "Interval to re-read the limits configuration file.").
+		Default("1m"))
+
 	rc.reqLogConfig = extkingpin.RegisterRequestLoggingFlags(cmd)
 }
 
@@ -827,3 +1072,27 @@ func (rc *receiveConfig) determineMode() receive.ReceiverMode {
 		return receive.IngestorOnly
 	}
 }
+
+// validateWALSegmentSize returns an error if the WAL segment size isn't supported by the TSDB. 0 is the default size.
+func validateWALSegmentSize(size units.Base2Bytes) error {
+	if size != 0 && (size < 10*units.MiB || size > 256*units.MiB || size%(32*units.KiB) != 0) {
+		return errors.Errorf("WAL segment size %s has to be a multiple of 32KiB between 10MiB and 256MiB", size)
+	}
+	return nil
+}
+
+// parseTenantWALSegmentSizes returns the WAL segment sizes in bytes by tenant of the given sizes, e.g. 64MiB.
+func parseTenantWALSegmentSizes(sizes map[string]string) (map[string]int, error) {
+	parsed := make(map[string]int, len(sizes))
+	for tenant, s := range sizes {
+		size, err := units.ParseBase2Bytes(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse --tsdb.tenant-wal-segment-size of tenant %s", tenant)
+		}
+		if err := validateWALSegmentSize(size); err != nil {
+			return nil, errors.Wrapf(err, "invalid --tsdb.tenant-wal-segment-size of tenant %s", tenant)
+		}
+		parsed[tenant] = int(size)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/prometheus/tsdb"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/thanos-io/thanos/pkg/info"
+	"github.com/thanos-io/thanos/pkg/info/infopb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestAdminAPIOptions(t *testing.T) {
+	dbs := func() map[string]*tsdb.DB { return nil }
+	parse := func(args ...string) *receiveConfig {
+		app := kingpin.New("test", "")
+		conf := &receiveConfig{}
+		conf.registerFlag(app.Command("receive", ""))
+		_, err := app.Parse(append([]string{"receive"}, args...))
+		testutil.Ok(t, err)
+		return conf
+	}
+
+	adminInfo := func(opts []info.ServerOptionFunc) *infopb.AdminInfo {
+		resp, err := info.NewInfoServer("receive", opts...).Info(context.Background(), &infopb.InfoRequest{})
+		testutil.Ok(t, err)
+		return resp.Admin
+	}
+
+	// The admin API is neither served nor advertised by default.
+	infoOpts, srvOpts, err := adminAPIOptions(parse(), dbs)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(srvOpts))
+	testutil.Assert(t, adminInfo(infoOpts) == nil, "expected the admin API not to be advertised")
+
+	// Enabling it requires a token.
+	_, _, err = adminAPIOptions(parse("--receive.admin-api.enabled"), dbs)
+	testutil.NotOk(t, err)
+
+	dir := t.TempDir()
+	empty, token := filepath.Join(dir, "empty"), filepath.Join(dir, "token")
+	testutil.Ok(t, ioutil.WriteFile(empty, []byte("\n"), 0600))
+	testutil.Ok(t, ioutil.WriteFile(token, []byte("secret\n"), 0600))
+
+	_, _, err = adminAPIOptions(parse("--receive.admin-api.enabled", "--receive.admin-api.token-file="+empty), dbs)
+	testutil.NotOk(t, err)
+
+	infoOpts, srvOpts, err = adminAPIOptions(parse("--receive.admin-api.enabled", "--receive.admin-api.token-file="+token), dbs)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(srvOpts))
+	testutil.Assert(t, adminInfo(infoOpts) != nil, "expected the admin API to be advertised")
+}
+
+func TestParseTenantWALSegmentSizes(t *testing.T) {
+	sizes, err := parseTenantWALSegmentSizes(map[string]string{"a": "64MiB", "b": "0B"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, map[string]int{"a": 64 << 20, "b": 0}, sizes)
+
+	for _, invalid := range []string{"64", "1MiB", "1GiB", "10MiB1KiB"} {
+		_, err := parseTenantWALSegmentSizes(map[string]string{"a": invalid})
+		testutil.NotOk(t, err, "size %s", invalid)
+	}
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/agent"
 	"github.com/prometheus/prometheus/util/strutil"
+	"gopkg.in/alecthomas/kingpin.v2"
 	"gopkg.in/yaml.v2"
 
 	"github.com/thanos-io/thanos/pkg/alert"
@@ -52,6 +53,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/info"
 	"github.com/thanos-io/thanos/pkg/info/infopb"
 	"github.com/thanos-io/thanos/pkg/logging"
+	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/objstore/client"
 	"github.com/thanos-io/thanos/pkg/prober"
 	"github.com/thanos-io/thanos/pkg/promclient"
@@ -63,7 +65,6 @@ import (
 	"github.com/thanos-io/thanos/pkg/store"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
-	"github.com/thanos-io/thanos/pkg/tls"
 	"github.com/thanos-io/thanos/pkg/tracing"
 	"github.com/thanos-io/thanos/pkg/ui"
 )
@@ -84,12 +85,18 @@ type ruleConfig struct {
 
 	rwConfig *extflag.PathOrContent
 
-	resendDelay    time.Duration
-	evalInterval   time.Duration
-	ruleFiles      []string
-	objStoreConfig *extflag.PathOrContent
-	dataDir        string
-	lset           labels.Labels
+	resendDelay     time.Duration
+	outageTolerance time.Duration
+	forGracePeriod  time.Duration
+	evalInterval    time.Duration
+	ruleFiles       []string
+	objStoreConfig  *extflag.PathOrContent
+	dataDir         string
+	lset            labels.Labels
+
+	alertStatePersistenceInterval time.Duration
+	alertStatePersistencePrefix   string
+	alertRestoreFromQuery         bool
 }
 
 func (rc *ruleConfig) registerFlag(cmd extkingpin.FlagClause) {
@@ -125,6 +132,16 @@ func registerRule(app *extkingpin.App) {
 		Default("1m").DurationVar(&conf.resendDelay)
 	cmd.Flag("eval-interval", "The default evaluation interval to use.").
 		Default("30s").DurationVar(&conf.evalInterval)
+	cmd.Flag("for-outage-tolerance", "Max time to tolerate the ruler being down to restore the 'for' state of alerts.").
+		Default("1h").DurationVar(&conf.outageTolerance)
+	cmd.Flag("for-grace-period", "Minimum duration between alert and restored 'for' state. This is maintained only for alerts with configured 'for' time greater than the grace period.").
+		Default("10m").DurationVar(&conf.forGracePeriod)
+	cmd.Flag("alert.state-persistence-interval", "Interval at which the 'for' state of active alerts is persisted to the bucket configured with --objstore.config*, to be restored on startup. It allows alerts to keep their 'for' state when the ruler is rescheduled without its data directory, including in stateless mode. 0 disables it.").
+		Default("0s").DurationVar(&conf.alertStatePersistenceInterval)
+	cmd.Flag("alert.restore-from-query", "Restore the 'for' state of alerts on startup from their ALERTS_FOR_STATE series queried through the query API over the --for-outage-tolerance window. It allows alerts to keep their 'for' state when the ruler restarts in stateless mode, as the series are remote written.").
+		Default("false").BoolVar(&conf.alertRestoreFromQuery)
+	cmd.Flag("alert.state-persistence-prefix", "Prefix of the objects alert state is persisted to in the bucket. Each ruler persists its state to an object named after the hash of its labels.").
+		Default("rule-state").StringVar(&conf.alertStatePersistencePrefix)
 
 	conf.rwConfig = extflag.RegisterPathOrContent(cmd, "remote-write.config", "YAML config for the remote-write configurations, that specify servers where samples should be sent to (see https://prometheus.io/docs/prometheus/latest/configuration/configuration/#remote_write). This automatically enables stateless mode for ruler and no series will be stored in the ruler's TSDB. If an empty config (or file) is provided, the flag is ignored and ruler is run with its own TSDB.", extflag.WithEnvSubstitution())
 
@@ -211,7 +228,7 @@ func registerRule(app *extkingpin.App) {
 			comp,
 			*conf,
 			reload,
-			getFlagsMap(cmd.Flags()),
+			cmd.Flags(),
 			httpLogOpts,
 			grpcLogOpts,
 			tagOpts,
@@ -275,7 +292,7 @@ func runRule(
 	comp component.Component,
 	conf ruleConfig,
 	reloadSignal <-chan struct{},
-	flagsMap map[string]string,
+	cmdFlags []*kingpin.FlagModel,
 	httpLogOpts []logging.Option,
 	grpcLogOpts []grpc_logging.Option,
 	tagOpts []tags.Option,
@@ -457,6 +474,56 @@ func runRule(
 		alertmgrs = append(alertmgrs, alert.NewAlertmanager(logger, amClient, time.Duration(cfg.Timeout), cfg.APIVersion))
 	}
 
+	confContentYaml, err := conf.objStoreConfig.Content()
+	if err != nil {
+		return err
+	}
+
+	var bkt objstore.Bucket
+	if len(confContentYaml) > 0 {
+		bkt, err = client.NewBucket(logger, confContentYaml, reg, component.Rule.String())
+		if err != nil {
+			return err
+		}
+
+		// Ensure we close up everything properly.
+		defer func() {
+			if err != nil {
+				runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
+			}
+		}()
+	}
+
+	// Restore the 'for' state of alerts from the local TSDB, and from the state persisted in the bucket and the
+	// query API, if enabled. The remote write agent cannot be queried.
+	var (
+		ruleQueryable       storage.Queryable
+		alertStatePersister *thanosrules.AlertStatePersister
+	)
+	if tsdbDB != nil {
+		ruleQueryable = tsdbDB
+	}
+	if conf.alertStatePersistenceInterval > 0 {
+		if bkt == nil {
+			return errors.New("--alert.state-persistence-interval requires a bucket configured with --objstore.config")
+		}
+		alertStatePersister = thanosrules.NewAlertStatePersister(logger, bkt, thanosrules.AlertStateObjectName(conf.alertStatePersistencePrefix, conf.lset))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if err := alertStatePersister.Load(ctx); err != nil {
+			level.Warn(logger).Log("msg", "failed to load persisted alert state, alerts will restart pending", "err", err)
+		}
+		cancel()
+
+		ruleQueryable = alertStatePersister.Queryable(ruleQueryable)
+	}
+	if conf.alertRestoreFromQuery {
+		ruleQueryable = thanosrules.QueryAPIAlertStateQueryable(alertStateQueryFunc(logger, queryClients, metrics.duplicatedQuery, conf.query.httpMethod), ruleQueryable)
+	}
+	if ruleQueryable == nil {
+		ruleQueryable = queryable
+	}
+
 	var (
 		ruleMgr *thanosrules.Manager
 		alertQ  = alert.NewQueue(logger, reg, 10000, 100, labelsTSDBToProm(conf.lset), conf.alertmgr.alertExcludeLabels, alertRelabelConfigs)
@@ -493,12 +560,14 @@ func runRule(
 			reg,
 			conf.dataDir,
 			rules.ManagerOptions{
-				NotifyFunc:  notifyFunc,
-				Logger:      logger,
-				Appendable:  appendable,
-				ExternalURL: nil,
-				Queryable:   queryable,
-				ResendDelay: conf.resendDelay,
+				NotifyFunc:      notifyFunc,
+				Logger:          logger,
+				Appendable:      appendable,
+				ExternalURL:     nil,
+				Queryable:       ruleQueryable,
+				ResendDelay:     conf.resendDelay,
+				OutageTolerance: conf.outageTolerance,
+				ForGracePeriod:  conf.forGracePeriod,
 			},
 			queryFuncCreator(logger, queryClients, metrics.duplicatedQuery, metrics.ruleEvalWarnings, conf.query.httpMethod),
 			conf.lset,
@@ -519,6 +588,19 @@ func runRule(
 			ruleMgr.Stop()
 		})
 	}
+	if alertStatePersister != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			return runutil.Repeat(conf.alertStatePersistenceInterval, ctx.Done(), func() error {
+				if err := alertStatePersister.Save(ctx, ruleMgr.RuleGroups(), time.Now()); err != nil {
+					level.Warn(logger).Log("msg", "failed to persist alert state", "err", err)
+				}
+				return nil
+			})
+		}, func(error) {
+			cancel()
+		})
+	}
 	// Run the alert sender.
 	{
 		sdr := alert.NewSender(logger, reg, alertmgrs)
@@ -581,8 +663,17 @@ func runRule(
 		prober.NewInstrumentation(comp, logger, extprom.WrapRegistererWithPrefix("thanos_", reg)),
 	)
 
+	ipf, err := ipFilters(logger, reg, conf.http.ipFilterConfig)
+	if err != nil {
+		return err
+	}
+
 	// Start gRPC server.
-	tlsCfg, err := tls.NewServerConfig(log.With(logger, "protocol", "gRPC"), conf.grpc.tlsSrvCert, conf.grpc.tlsSrvKey, conf.grpc.tlsSrvClientCA)
+	spiffeSrc, err := spiffeSource(g, logger, reg, *conf.grpc.spiffeAddr, *conf.grpc.spiffeIDs)
+	if err != nil {
+		return err
+	}
+	tlsCfg, err := grpcServerTLSConfig(logger, reg, spiffeSrc, conf.grpc.tlsSrvCert, conf.grpc.tlsSrvKey, conf.grpc.tlsSrvClientCA)
 	if err != nil {
 		return errors.Wrap(err, "setup gRPC server")
 	}
@@ -592,6 +683,7 @@ func runRule(
 		grpcserver.WithListen(conf.grpc.bindAddress),
 		grpcserver.WithGracePeriod(time.Duration(conf.grpc.gracePeriod)),
 		grpcserver.WithTLSConfig(tlsCfg),
+		grpcserver.WithIPFilter(ipf.grpc),
 	}
 	infoOptions := []info.ServerOptionFunc{info.WithRulesInfoFunc()}
 	if tsdbDB != nil {
@@ -604,8 +696,9 @@ func runRule(
 			info.WithStoreInfoFunc(func() *infopb.StoreInfo {
 				mint, maxt := tsdbStore.TimeRange()
 				return &infopb.StoreInfo{
-					MinTime: mint,
-					MaxTime: maxt,
+					MinTime:               mint,
+					MaxTime:               maxt,
+					SupportsSeriesBatches: true,
 				}
 			}),
 		)
@@ -656,13 +749,20 @@ func runRule(
 		// TODO(bplotka in PR #513 review): pass all flags, not only the flags needed by prefix rewriting.
 		ui.NewRuleUI(logger, reg, ruleMgr, conf.alertQueryURL.String(), conf.web.externalPrefix, conf.web.prefixHeaderName).Register(router, ins)
 
-		api := v1.NewRuleAPI(logger, reg, thanosrules.NewGRPCClient(ruleMgr), ruleMgr, conf.web.disableCORS, flagsMap)
+		api := v1.NewRuleAPI(logger, reg, thanosrules.NewGRPCClient(ruleMgr), ruleMgr, conf.web.disableCORS, extkingpin.RedactedFlags(cmdFlags))
 		api.Register(router.WithPrefix("/api/v1"), tracer, logger, ins, logMiddleware)
 
+		authMiddleware, err := oidcMiddleware(logger, reg, conf.http.oidcConfig)
+		if err != nil {
+			return err
+		}
 		srv := httpserver.New(logger, reg, comp, httpProbe,
 			httpserver.WithListen(conf.http.bindAddress),
 			httpserver.WithGracePeriod(time.Duration(conf.http.gracePeriod)),
 			httpserver.WithTLSConfig(conf.http.tlsConfig),
+			statusOption(cmdFlags),
+			httpserver.WithAuthentication(authMiddleware),
+			httpserver.WithIPFilter(ipf.http),
 		)
 		srv.Handle("/", router)
 
@@ -678,27 +778,15 @@ func runRule(
 		})
 	}
 
-	confContentYaml, err := conf.objStoreConfig.Content()
-	if err != nil {
-		return err
-	}
-
-	if len(confContentYaml) > 0 {
-		// The background shipper continuously scans the data directory and uploads
-		// new blocks to Google Cloud Storage or an S3-compatible storage service.
-		bkt, err := client.NewBucket(logger, confContentYaml, reg, component.Rule.String())
+	if bkt != nil {
+		shipperOpts, err := conf.shipper.options()
 		if err != nil {
-			return err
+			return errors.Wrap(err, "parse shipper flags")
 		}
 
-		// Ensure we close up everything properly.
-		defer func() {
-			if err != nil {
-				runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
-			}
-		}()
-
-		s := shipper.New(logger, reg, conf.dataDir, bkt, func() labels.Labels { return conf.lset }, metadata.RulerSource, false, conf.shipper.allowOutOfOrderUpload, metadata.HashFunc(conf.shipper.hashFunc))
+		// The background shipper continuously scans the data directory and uploads
+		// new blocks to Google Cloud Storage or an S3-compatible storage service.
+		s := shipper.New(logger, reg, conf.dataDir, bkt, func() labels.Labels { return conf.lset }, metadata.RulerSource, false, conf.shipper.allowOutOfOrderUpload, metadata.HashFunc(conf.shipper.hashFunc), shipperOpts...)
 
 		ctx, cancel := context.WithCancel(context.Background())
 
@@ -840,6 +928,53 @@ func queryFuncCreator(
 	}
 }
 
+// alertStateQueryFunc returns a function querying the raw samples of the ALERTS_FOR_STATE series of alerts from the
+// query API of query peers, in randomized order until one of them answers.
+func alertStateQueryFunc(
+	logger log.Logger,
+	queriers []*httpconfig.Client,
+	duplicatedQuery prometheus.Counter,
+	httpMethod string,
+) thanosrules.MatrixQueryFunc {
+	promClients := make([]*promclient.Client, 0, len(queriers))
+	for _, q := range queriers {
+		promClients = append(promClients, promclient.NewClient(q, logger, "thanos-rule"))
+	}
+
+	return func(ctx context.Context, q string, t time.Time) (promql.Matrix, error) {
+		for _, i := range rand.Perm(len(queriers)) {
+			promClient := promClients[i]
+			endpoints := removeDuplicateQueryEndpoints(logger, duplicatedQuery, queriers[i].Endpoints())
+			for _, i := range rand.Perm(len(endpoints)) {
+				m, _, err := promClient.QueryInstantMatrix(ctx, endpoints[i], q, t, promclient.QueryOptions{
+					Deduplicate:             true,
+					PartialResponseStrategy: storepb.PartialResponseStrategy_WARN,
+					Method:                  httpMethod,
+				})
+				if err != nil {
+					level.Error(logger).Log("msg", "failed to query alert state", "err", err, "query", q)
+					continue
+				}
+
+				matrix := make(promql.Matrix, 0, len(m))
+				for _, ss := range m {
+					s := promql.Series{Metric: labels.New(), Points: make([]promql.Point, 0, len(ss.Values))}
+					for n, v := range ss.Metric {
+						s.Metric = append(s.Metric, labels.Label{Name: string(n), Value: string(v)})
+					}
+					sort.Sort(s.Metric)
+					for _, p := range ss.Values {
+						s.Points = append(s.Points, promql.Point{T: int64(p.Timestamp), V: float64(p.Value)})
+					}
+					matrix = append(matrix, s)
+				}
+				return matrix, nil
+			}
+		}
+		return nil, errors.Errorf("no query API server reachable")
+	}
+}
+
 func addDiscoveryGroups(g *run.Group, c *httpconfig.Client, interval time.Duration) {
 	ctx, cancel := context.WithCancel(context.Background())
 	g.Add(func() error {
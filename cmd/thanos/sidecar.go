@@ -21,7 +21,9 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/model"
+	promconfig "github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/model/labels"
+	"gopkg.in/alecthomas/kingpin.v2"
 
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/component"
@@ -46,7 +48,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/store"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/targets"
-	"github.com/thanos-io/thanos/pkg/tls"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus"
 )
 
 func registerSidecar(app *extkingpin.App) {
@@ -59,18 +61,28 @@ func registerSidecar(app *extkingpin.App) {
 			return errors.Wrap(err, "error while parsing config for request logging")
 		}
 
+		var cfgValidate func([]byte) error
+		if conf.reloader.confValidate {
+			cfgValidate = func(b []byte) error {
+				_, err := promconfig.Load(string(b), false, logger)
+				return err
+			}
+		}
+
 		rl := reloader.New(log.With(logger, "component", "reloader"),
 			extprom.WrapRegistererWithPrefix("thanos_sidecar_", reg),
 			&reloader.Options{
 				ReloadURL:     reloader.ReloadURLFromBase(conf.prometheus.url),
 				CfgFile:       conf.reloader.confFile,
 				CfgOutputFile: conf.reloader.envVarConfFile,
+				CfgTemplate:   conf.reloader.confTemplate,
+				CfgValidate:   cfgValidate,
 				WatchedDirs:   conf.reloader.ruleDirectories,
 				WatchInterval: conf.reloader.watchInterval,
 				RetryInterval: conf.reloader.retryInterval,
 			})
 
-		return runSidecar(g, logger, reg, tracer, rl, component.Sidecar, *conf, grpcLogOpts, tagOpts)
+		return runSidecar(g, logger, reg, tracer, rl, component.Sidecar, *conf, grpcLogOpts, tagOpts, cmd.Flags())
 	})
 }
 
@@ -84,7 +96,13 @@ func runSidecar(
 	conf sidecarConfig,
 	grpcLogOpts []grpc_logging.Option,
 	tagOpts []tags.Option,
+	cmdFlags []*kingpin.FlagModel,
 ) error {
+	if conf.limitMinTime.PrometheusTimestamp() > conf.limitMaxTime.PrometheusTimestamp() {
+		return errors.Errorf("invalid argument: --min-time '%s' can't be greater than --max-time '%s'",
+			conf.limitMinTime.String(), conf.limitMaxTime.String())
+	}
+
 	httpConfContentYaml, err := conf.prometheus.httpClient.Content()
 	if err != nil {
 		return errors.Wrap(err, "getting http client config")
@@ -106,10 +124,11 @@ func runSidecar(
 
 		// Start out with the full time range. The shipper will constrain it later.
 		// TODO(fabxc): minimum timestamp is never adjusted if shipping is disabled.
-		mint: conf.limitMinTime.PrometheusTimestamp(),
+		mint: math.MinInt64,
 		maxt: math.MaxInt64,
 
 		limitMinTime: conf.limitMinTime,
+		limitMaxTime: conf.limitMaxTime,
 		client:       promclient.NewWithTracingClient(logger, httpClient, "thanos-sidecar"),
 	}
 
@@ -132,10 +151,21 @@ func runSidecar(
 		prober.NewInstrumentation(comp, logger, extprom.WrapRegistererWithPrefix("thanos_", reg)),
 	)
 
+	authMiddleware, err := oidcMiddleware(logger, reg, conf.http.oidcConfig)
+	if err != nil {
+		return err
+	}
+	ipf, err := ipFilters(logger, reg, conf.http.ipFilterConfig)
+	if err != nil {
+		return err
+	}
 	srv := httpserver.New(logger, reg, comp, httpProbe,
 		httpserver.WithListen(conf.http.bindAddress),
 		httpserver.WithGracePeriod(time.Duration(conf.http.gracePeriod)),
 		httpserver.WithTLSConfig(conf.http.tlsConfig),
+		statusOption(cmdFlags),
+		httpserver.WithAuthentication(authMiddleware),
+		httpserver.WithIPFilter(ipf.http),
 	)
 
 	g.Add(func() error {
@@ -155,13 +185,21 @@ func runSidecar(
 			Name: "thanos_sidecar_prometheus_up",
 			Help: "Boolean indicator whether the sidecar can reach its Prometheus peer.",
 		})
+		promReady := promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_sidecar_prometheus_ready",
+			Help: "Boolean indicator whether the Prometheus peer is ready to serve queries.",
+		})
+		promConfigReloadSuccess := promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_sidecar_prometheus_config_last_reload_successful",
+			Help: "Boolean indicator whether the last configuration reload of the Prometheus peer succeeded.",
+		})
 
 		ctx, cancel := context.WithCancel(context.Background())
 		g.Add(func() error {
 			// Only check Prometheus's flags when upload is enabled.
 			if uploads {
 				// Check prometheus's flags to ensure same sidecar flags.
-				if err := validatePrometheus(ctx, m.client, logger, conf.shipper.ignoreBlockSize, m); err != nil {
+				if err := validatePrometheus(ctx, m.client, logger, conf.shipper.ignoreBlockSize, conf.shipper.uploadCompacted, m); err != nil {
 					return errors.Wrap(err, "validate Prometheus flags")
 				}
 			}
@@ -197,6 +235,13 @@ func runSidecar(
 					statusProber.NotReady(err)
 					return err
 				}
+				if err := m.UpdateReadiness(ctx); err != nil {
+					level.Warn(logger).Log("msg", "Prometheus is not ready yet. Retrying", "err", err)
+					promReady.Set(0)
+					statusProber.NotReady(err)
+					return err
+				}
+				promReady.Set(1)
 
 				level.Info(logger).Log(
 					"msg", "successfully loaded prometheus external labels",
@@ -215,20 +260,52 @@ func runSidecar(
 			}
 
 			// Periodically query the Prometheus config. We use this as a heartbeat as well as for updating
-			// the external labels we apply.
-			return runutil.Repeat(30*time.Second, ctx.Done(), func() error {
+			// the external labels we apply. In between, the readiness of Prometheus is checked more often, so that
+			// queriers stop querying Prometheus as soon as it restarts, until it replayed its WAL.
+			var (
+				lastHeartbeat = time.Now()
+				heartbeatErr  error
+			)
+			return runutil.Repeat(conf.prometheus.readyCheckInterval, ctx.Done(), func() error {
 				iterCtx, iterCancel := context.WithTimeout(context.Background(), 5*time.Second)
 				defer iterCancel()
 
-				if err := m.UpdateLabels(iterCtx); err != nil {
-					level.Warn(logger).Log("msg", "heartbeat failed", "err", err)
-					promUp.Set(0)
-					statusProber.NotReady(err)
+				if time.Since(lastHeartbeat) >= 30*time.Second {
+					lastHeartbeat = time.Now()
+					if heartbeatErr = m.UpdateLabels(iterCtx); heartbeatErr != nil {
+						level.Warn(logger).Log("msg", "heartbeat failed", "err", heartbeatErr)
+						promUp.Set(0)
+					} else {
+						promUp.Set(1)
+					}
+
+					// Prometheus keeps running with its previous configuration if the reload failed.
+					if ok, err := m.client.ConfigReloadSuccessful(iterCtx, m.promURL); err == nil {
+						if ok {
+							promConfigReloadSuccess.Set(1)
+						} else {
+							level.Warn(logger).Log("msg", "last Prometheus configuration reload failed, Prometheus runs with its previous configuration")
+							promConfigReloadSuccess.Set(0)
+						}
+					}
+				}
+
+				readyErr := m.UpdateReadiness(iterCtx)
+				if readyErr != nil {
+					level.Warn(logger).Log("msg", "Prometheus is not ready", "err", readyErr)
+					promReady.Set(0)
 				} else {
-					promUp.Set(1)
-					statusProber.Ready()
+					promReady.Set(1)
 				}
 
+				switch {
+				case readyErr != nil:
+					statusProber.NotReady(readyErr)
+				case heartbeatErr != nil:
+					statusProber.NotReady(heartbeatErr)
+				default:
+					statusProber.Ready()
+				}
 				return nil
 			})
 		}, func(error) {
@@ -251,8 +328,11 @@ func runSidecar(
 			return errors.Wrap(err, "create Prometheus store")
 		}
 
-		tlsCfg, err := tls.NewServerConfig(log.With(logger, "protocol", "gRPC"),
-			conf.grpc.tlsSrvCert, conf.grpc.tlsSrvKey, conf.grpc.tlsSrvClientCA)
+		spiffeSrc, err := spiffeSource(g, logger, reg, *conf.grpc.spiffeAddr, *conf.grpc.spiffeIDs)
+		if err != nil {
+			return err
+		}
+		tlsCfg, err := grpcServerTLSConfig(logger, reg, spiffeSrc, conf.grpc.tlsSrvCert, conf.grpc.tlsSrvKey, conf.grpc.tlsSrvClientCA)
 		if err != nil {
 			return errors.Wrap(err, "setup gRPC server")
 		}
@@ -267,14 +347,22 @@ func runSidecar(
 			info.WithStoreInfoFunc(func() *infopb.StoreInfo {
 				mint, maxt := promStore.Timestamps()
 				return &infopb.StoreInfo{
-					MinTime: mint,
-					MaxTime: maxt,
+					MinTime:               mint,
+					MaxTime:               maxt,
+					SupportsSeriesBatches: true,
 				}
 			}),
-			info.WithExemplarsInfoFunc(),
+			info.WithExemplarsInfoFunc(func() *infopb.ExemplarsInfo {
+				// Don't advertise the exemplars API if Prometheus doesn't serve it, so queriers don't fan out to us.
+				if !exemplars.SupportsPrometheusVersion(m.Version()) {
+					return nil
+				}
+				return &infopb.ExemplarsInfo{}
+			}),
 			info.WithRulesInfoFunc(),
 			info.WithTargetsInfoFunc(),
 			info.WithMetricMetadataInfoFunc(),
+			info.WithTSDBStatusInfoFunc(),
 		)
 
 		s := grpcserver.New(logger, reg, tracer, grpcLogOpts, tagOpts, comp, grpcProbe,
@@ -283,10 +371,12 @@ func runSidecar(
 			grpcserver.WithServer(targets.RegisterTargetsServer(targets.NewPrometheus(conf.prometheus.url, c, m.Labels))),
 			grpcserver.WithServer(meta.RegisterMetadataServer(meta.NewPrometheus(conf.prometheus.url, c))),
 			grpcserver.WithServer(exemplars.RegisterExemplarsServer(exemplarSrv)),
+			grpcserver.WithServer(tsdbstatus.RegisterTSDBStatusServer(tsdbstatus.NewPrometheus(conf.prometheus.url, c, m.Labels))),
 			grpcserver.WithServer(info.RegisterInfoServer(infoSrv)),
 			grpcserver.WithListen(conf.grpc.bindAddress),
 			grpcserver.WithGracePeriod(time.Duration(conf.grpc.gracePeriod)),
 			grpcserver.WithTLSConfig(tlsCfg),
+			grpcserver.WithIPFilter(ipf.grpc),
 		)
 		g.Add(func() error {
 			statusProber.Ready()
@@ -298,6 +388,11 @@ func runSidecar(
 	}
 
 	if uploads {
+		shipperOpts, err := conf.shipper.options()
+		if err != nil {
+			return errors.Wrap(err, "parse shipper flags")
+		}
+
 		// The background shipper continuously scans the data directory and uploads
 		// new blocks to Google Cloud Storage or an S3-compatible storage service.
 		bkt, err := client.NewBucket(logger, confContentYaml, reg, component.Sidecar.String())
@@ -334,7 +429,7 @@ func runSidecar(
 			}
 
 			s := shipper.New(logger, reg, conf.tsdb.path, bkt, m.Labels, metadata.SidecarSource,
-				conf.shipper.uploadCompacted, conf.shipper.allowOutOfOrderUpload, metadata.HashFunc(conf.shipper.hashFunc))
+				conf.shipper.uploadCompacted, conf.shipper.allowOutOfOrderUpload, metadata.HashFunc(conf.shipper.hashFunc), shipperOpts...)
 
 			return runutil.Repeat(30*time.Second, ctx.Done(), func() error {
 				if uploaded, err := s.Sync(ctx); err != nil {
@@ -358,7 +453,7 @@ func runSidecar(
 	return nil
 }
 
-func validatePrometheus(ctx context.Context, client *promclient.Client, logger log.Logger, ignoreBlockSize bool, m *promMetadata) error {
+func validatePrometheus(ctx context.Context, client *promclient.Client, logger log.Logger, ignoreBlockSize, uploadCompacted bool, m *promMetadata) error {
 	var (
 		flagErr error
 		flags   promclient.Flags
@@ -381,11 +476,16 @@ func validatePrometheus(ctx context.Context, client *promclient.Client, logger l
 
 	// Check if compaction is disabled.
 	if flags.TSDBMinTime != flags.TSDBMaxTime {
-		if !ignoreBlockSize {
+		switch {
+		case uploadCompacted:
+			// Blocks compacted by Prometheus are uploaded too, unless all their sources were uploaded already.
+			level.Info(logger).Log("msg", "Prometheus compaction is enabled; blocks it compacts will be uploaded as well and deduplicated by the compactor")
+		case !ignoreBlockSize:
 			return errors.Errorf("found that TSDB Max time is %s and Min time is %s. "+
 				"Compaction needs to be disabled (storage.tsdb.min-block-duration = storage.tsdb.max-block-duration)", flags.TSDBMaxTime, flags.TSDBMinTime)
+		default:
+			level.Warn(logger).Log("msg", "flag to ignore Prometheus min/max block duration flags differing is being used. If the upload of a 2h block fails and a Prometheus compaction happens that block may be missing from your Thanos bucket storage.")
 		}
-		level.Warn(logger).Log("msg", "flag to ignore Prometheus min/max block duration flags differing is being used. If the upload of a 2h block fails and a Prometheus compaction happens that block may be missing from your Thanos bucket storage.")
 	}
 	// Check if block time is 2h.
 	if flags.TSDBMinTime != model.Duration(2*time.Hour) {
@@ -403,8 +503,10 @@ type promMetadata struct {
 	maxt        int64
 	labels      labels.Labels
 	promVersion string
+	ready       bool
 
 	limitMinTime thanosmodel.TimeOrDurationValue
+	limitMaxTime thanosmodel.TimeOrDurationValue
 
 	client *promclient.Client
 }
@@ -426,10 +528,6 @@ func (s *promMetadata) UpdateTimestamps(mint, maxt int64) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	if mint < s.limitMinTime.PrometheusTimestamp() {
-		mint = s.limitMinTime.PrometheusTimestamp()
-	}
-
 	s.mint = mint
 	s.maxt = maxt
 }
@@ -441,11 +539,37 @@ func (s *promMetadata) Labels() labels.Labels {
 	return s.labels
 }
 
+// UpdateReadiness checks whether Prometheus is ready to serve queries.
+func (s *promMetadata) UpdateReadiness(ctx context.Context) error {
+	err := s.client.IsReady(ctx, s.promURL)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.ready = err == nil
+	return err
+}
+
+// Timestamps returns the time range of the data served by Prometheus, limited by --min-time and --max-time. The
+// time range is empty while Prometheus is not ready, e.g. replaying its WAL, so that queriers do not get empty or
+// partial results from it.
 func (s *promMetadata) Timestamps() (mint, maxt int64) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	return s.mint, s.maxt
+	if !s.ready {
+		return math.MaxInt64, math.MinInt64
+	}
+
+	mint, maxt = s.mint, s.maxt
+	// Relative limits move with the current time.
+	if limit := s.limitMinTime.PrometheusTimestamp(); mint < limit {
+		mint = limit
+	}
+	if limit := s.limitMaxTime.PrometheusTimestamp(); maxt > limit {
+		maxt = limit
+	}
+	return mint, maxt
 }
 
 func (s *promMetadata) BuildVersion(ctx context.Context) error {
@@ -478,6 +602,7 @@ type sidecarConfig struct {
 	objStore     extflag.PathOrContent
 	shipper      shipperConfig
 	limitMinTime thanosmodel.TimeOrDurationValue
+	limitMaxTime thanosmodel.TimeOrDurationValue
 }
 
 func (sc *sidecarConfig) registerFlag(cmd extkingpin.FlagClause) {
@@ -491,4 +616,6 @@ func (sc *sidecarConfig) registerFlag(cmd extkingpin.FlagClause) {
 	sc.shipper.registerFlag(cmd)
 	cmd.Flag("min-time", "Start of time range limit to serve. Thanos sidecar will serve only metrics, which happened later than this value. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y.").
 		Default("0000-01-01T00:00:00Z").SetValue(&sc.limitMinTime)
+	cmd.Flag("max-time", "End of time range limit to serve. Thanos sidecar will serve only metrics, which happened earlier than this value. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y.").
+		Default("9999-12-31T23:59:59Z").SetValue(&sc.limitMaxTime)
 }
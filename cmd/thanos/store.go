@@ -18,6 +18,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/route"
+	"gopkg.in/alecthomas/kingpin.v2"
 
 	commonmodel "github.com/prometheus/common/model"
 
@@ -25,6 +26,7 @@ import (
 
 	blocksAPI "github.com/thanos-io/thanos/pkg/api/blocks"
 	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/indexheader"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/component"
 	hidden "github.com/thanos-io/thanos/pkg/extflag"
@@ -36,6 +38,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/info/infopb"
 	"github.com/thanos-io/thanos/pkg/logging"
 	"github.com/thanos-io/thanos/pkg/model"
+	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/objstore/client"
 	"github.com/thanos-io/thanos/pkg/prober"
 	"github.com/thanos-io/thanos/pkg/runutil"
@@ -44,7 +47,8 @@ import (
 	"github.com/thanos-io/thanos/pkg/store"
 	storecache "github.com/thanos-io/thanos/pkg/store/cache"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
-	"github.com/thanos-io/thanos/pkg/tls"
+	"github.com/thanos-io/thanos/pkg/tenancy"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus"
 	"github.com/thanos-io/thanos/pkg/ui"
 )
 
@@ -58,12 +62,19 @@ type storeConfig struct {
 	chunkPoolSize               units.Base2Bytes
 	maxSampleCount              uint64
 	maxTouchedSeriesCount       uint64
+	maxChunkBytes               units.Base2Bytes
+	maxDownloadedBytes          units.Base2Bytes
 	maxConcurrency              int
+	maxTenantConcurrency        int
+	maxTenantInFlightBytes      units.Base2Bytes
 	component                   component.StoreAPI
 	debugLogging                bool
 	syncInterval                time.Duration
+	syncPollInterval            time.Duration
+	bucketNotifications         *extflag.PathOrContent
 	blockSyncConcurrency        int
 	blockMetaFetchConcurrency   int
+	bucketIndexMaxStale         time.Duration
 	filterConf                  *store.FilterConfig
 	selectorRelabelConf         extflag.PathOrContent
 	advertiseCompatibilityLabel bool
@@ -75,6 +86,10 @@ type storeConfig struct {
 	reqLogConfig                *extflag.PathOrContent
 	lazyIndexReaderEnabled      bool
 	lazyIndexReaderIdleTimeout  time.Duration
+	indexHeaderProtectionWindow time.Duration
+	indexHeaderStrategyConfig   extflag.PathOrContent
+	tenantID                    string
+	tenantAccounting            *bool
 }
 
 func (sc *storeConfig) registerFlag(cmd extkingpin.FlagClause) {
@@ -108,21 +123,48 @@ func (sc *storeConfig) registerFlag(cmd extkingpin.FlagClause) {
 		"Maximum amount of touched series returned via a single Series call. The Series call fails if this limit is exceeded. 0 means no limit.").
 		Default("0").Uint64Var(&sc.maxTouchedSeriesCount)
 
+	cmd.Flag("store.grpc.chunk-bytes-limit",
+		"Maximum amount of bytes of chunks fetched from object storage via a single Series call. The Series call fails with ResourceExhausted if this limit is exceeded. 0 means no limit.").
+		Default("0B").BytesVar(&sc.maxChunkBytes)
+
+	cmd.Flag("store.grpc.downloaded-bytes-limit",
+		"Maximum amount of bytes of postings, series and chunks downloaded from object storage via a single Series call. The Series call fails with ResourceExhausted if this limit is exceeded. 0 means no limit.").
+		Default("0B").BytesVar(&sc.maxDownloadedBytes)
+
 	cmd.Flag("store.grpc.series-max-concurrency", "Maximum number of concurrent Series calls.").Default("20").IntVar(&sc.maxConcurrency)
 
+	cmd.Flag("store.grpc.tenant-series-max-concurrency", "Maximum number of concurrent Series calls of each tenant, as sent by queriers. The calls of a tenant wait for the turn of the tenant before the turn of --store.grpc.series-max-concurrency. 0 means no limit.").
+		Default("0").IntVar(&sc.maxTenantConcurrency)
+
+	cmd.Flag("store.grpc.tenant-in-flight-bytes-limit", "Maximum amount of bytes downloaded from object storage by the in-flight Series calls of each tenant, as sent by queriers. The Series calls of the tenant fail with ResourceExhausted while this limit is exceeded. 0 means no limit.").
+		Default("0B").BytesVar(&sc.maxTenantInFlightBytes)
+
 	sc.component = component.Store
 
 	sc.objStoreConfig = *extkingpin.RegisterCommonObjStoreFlags(cmd, "", true)
 
+	cmd.Flag("store.tenant-id", "If set, only the blocks of this tenant are served, from under the prefix of its tenant ID in the bucket, as uploaded by receivers with --receive.tenant-bucket-prefix. Objects outside of the prefix can't be accessed.").
+		Default("").StringVar(&sc.tenantID)
+
+	sc.tenantAccounting = extkingpin.RegisterTenantAccountingFlag(cmd)
+
 	cmd.Flag("sync-block-duration", "Repeat interval for syncing the blocks between local and remote view.").
 		Default("3m").DurationVar(&sc.syncInterval)
 
+	cmd.Flag("sync-block-poll-interval", "Interval of the detection of new blocks uploaded to the bucket, which only lists the bucket, to sync the blocks as soon as new ones are detected instead of waiting for the next --sync-block-duration. 0 disables it.").
+		Default("0s").DurationVar(&sc.syncPollInterval)
+
+	sc.bucketNotifications = registerBucketNotificationsFlag(cmd)
+
 	cmd.Flag("block-sync-concurrency", "Number of goroutines to use when constructing index-cache.json blocks from object storage. Must be equal or greater than 1.").
 		Default("20").IntVar(&sc.blockSyncConcurrency)
 
 	cmd.Flag("block-meta-fetch-concurrency", "Number of goroutines to use when fetching block metadata from object storage.").
 		Default("32").IntVar(&sc.blockMetaFetchConcurrency)
 
+	cmd.Flag("bucket-index.max-stale-period", "Maximum age of the bucket index uploaded by the compactor with --bucket-index.upload for the metas of blocks to be taken from it instead of being checked and loaded one by one. Blocks are still discovered by listing the bucket. 0 disables the use of the bucket index.").
+		Default("0s").DurationVar(&sc.bucketIndexMaxStale)
+
 	sc.filterConf = &store.FilterConfig{}
 
 	cmd.Flag("min-time", "Start of time range limit to serve. Thanos Store will serve only metrics, which happened later than this value. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y.").
@@ -155,7 +197,15 @@ func (sc *storeConfig) registerFlag(cmd extkingpin.FlagClause) {
 		Default("false").BoolVar(&sc.lazyIndexReaderEnabled)
 
 	cmd.Flag("store.index-header-lazy-reader-idle-timeout", "If index-header lazy reader is enabled and this idle timeout setting is > 0, memory map-ed index-headers will be automatically released after 'idle timeout' inactivity.").
-		Hidden().Default("5m").DurationVar(&sc.lazyIndexReaderIdleTimeout)
+		Default("5m").DurationVar(&sc.lazyIndexReaderIdleTimeout)
+
+	cmd.Flag("store.index-header-lazy-reader-protection-window", "Minimum inactivity after which lazily memory map-ed index-headers are released, whatever their idle timeout, including the ones of index-header strategy rules. It protects recently queried blocks from being released and loaded again. 0 disables it.").
+		Default("0s").DurationVar(&sc.indexHeaderProtectionWindow)
+
+	sc.indexHeaderStrategyConfig = *extflag.RegisterPathOrContent(cmd, "store.index-header-strategy.config",
+		"YAML with the rules choosing, by the age of blocks and the size of their index, whether their index-header is loaded eagerly, or lazily memory mapped on first use and unloaded after an idle timeout. Blocks matching no rule follow --store.enable-index-header-lazy-reader. See format details: https://thanos.io/tip/components/store.md/#index-header-strategy",
+		extflag.WithEnvSubstitution(),
+	)
 
 	cmd.Flag("web.external-prefix", "Static prefix for all HTML links and redirect URLs in the bucket web UI interface. Actual endpoints are still served on / or the web.route-prefix. This allows thanos bucket web UI to be served behind a reverse proxy that strips a URL sub-path.").
 		Default("").StringVar(&sc.webConfig.externalPrefix)
@@ -200,7 +250,7 @@ func registerStore(app *extkingpin.App) {
 			grpcLogOpts,
 			tagOpts,
 			*conf,
-			getFlagsMap(cmd.Flags()),
+			cmd.Flags(),
 		)
 	})
 }
@@ -215,7 +265,7 @@ func runStore(
 	grpcLogOpts []grpclogging.Option,
 	tagOpts []tags.Option,
 	conf storeConfig,
-	flagsMap map[string]string,
+	cmdFlags []*kingpin.FlagModel,
 ) error {
 	grpcProbe := prober.NewGRPC()
 	httpProbe := prober.NewHTTP()
@@ -225,11 +275,22 @@ func runStore(
 		prober.NewInstrumentation(conf.component, logger, extprom.WrapRegistererWithPrefix("thanos_", reg)),
 	)
 
+	authMiddleware, err := oidcMiddleware(logger, reg, conf.httpConfig.oidcConfig)
+	if err != nil {
+		return err
+	}
+	ipf, err := ipFilters(logger, reg, conf.httpConfig.ipFilterConfig)
+	if err != nil {
+		return err
+	}
 	srv := httpserver.New(logger, reg, conf.component, httpProbe,
 		httpserver.WithListen(conf.httpConfig.bindAddress),
 		httpserver.WithGracePeriod(time.Duration(conf.httpConfig.gracePeriod)),
 		httpserver.WithTLSConfig(conf.httpConfig.tlsConfig),
 		httpserver.WithEnableH2C(true), // For groupcache.
+		statusOption(cmdFlags),
+		httpserver.WithAuthentication(authMiddleware),
+		httpserver.WithIPFilter(ipf.http),
 	)
 
 	g.Add(func() error {
@@ -252,6 +313,12 @@ func runStore(
 	if err != nil {
 		return errors.Wrap(err, "create bucket client")
 	}
+	if conf.tenantID != "" {
+		bkt, err = objstore.NewTenantBucket(bkt, conf.tenantID)
+		if err != nil {
+			return errors.Wrap(err, "create tenant bucket")
+		}
+	}
 
 	cachingBucketConfigYaml, err := conf.cachingBucketConfig.Content()
 	if err != nil {
@@ -305,7 +372,7 @@ func runStore(
 			block.NewConsistencyDelayMetaFilter(logger, time.Duration(conf.consistencyDelay), extprom.WrapRegistererWithPrefix("thanos_", reg)),
 			ignoreDeletionMarkFilter,
 			block.NewDeduplicateFilter(),
-		}, nil)
+		}, nil, block.WithBucketIndex(conf.bucketIndexMaxStale))
 	if err != nil {
 		return errors.Wrap(err, "meta fetcher")
 	}
@@ -314,6 +381,9 @@ func runStore(
 	if conf.maxConcurrency < 0 {
 		return errors.Errorf("max concurrency value cannot be lower than 0 (got %v)", conf.maxConcurrency)
 	}
+	if conf.maxTenantConcurrency < 0 {
+		return errors.Errorf("max concurrency of tenants cannot be lower than 0 (got %v)", conf.maxTenantConcurrency)
+	}
 
 	queriesGate := gate.New(extprom.WrapRegistererWithPrefix("thanos_bucket_store_series_", reg), int(conf.maxConcurrency))
 
@@ -334,6 +404,30 @@ func runStore(
 	if conf.debugLogging {
 		options = append(options, store.WithDebugLogging())
 	}
+	indexHeaderStrategyYaml, err := conf.indexHeaderStrategyConfig.Content()
+	if err != nil {
+		return errors.Wrap(err, "get content of index-header strategy configuration")
+	}
+	if len(indexHeaderStrategyYaml) > 0 {
+		strategyConf, err := indexheader.ParseStrategyConfig(indexHeaderStrategyYaml)
+		if err != nil {
+			return err
+		}
+		options = append(options, store.WithIndexHeaderStrategyRules(strategyConf.Rules))
+	}
+	if conf.indexHeaderProtectionWindow > 0 {
+		options = append(options, store.WithIndexHeaderProtectionWindow(conf.indexHeaderProtectionWindow))
+	}
+	if *conf.tenantAccounting {
+		options = append(options, store.WithTenantAccounting(tenancy.NewAccounting(reg)))
+	}
+	if conf.maxTenantConcurrency > 0 || conf.maxTenantInFlightBytes > 0 {
+		options = append(options, store.WithTenantLimiter(store.NewTenantLimiter(reg, conf.maxTenantConcurrency, conf.maxTenantInFlightBytes)))
+	}
+	options = append(options,
+		store.WithChunkBytesLimiterFactory(store.NewBytesLimiterFactory(conf.maxChunkBytes)),
+		store.WithDataBytesLimiterFactory(store.NewBytesLimiterFactory(conf.maxDownloadedBytes)),
+	)
 
 	bs, err := store.NewBucketStore(
 		bkt,
@@ -356,6 +450,14 @@ func runStore(
 
 	// bucketStoreReady signals when bucket store is ready.
 	bucketStoreReady := make(chan struct{})
+	// syncHints triggers syncs of the blocks out of their interval, e.g. when new blocks were uploaded.
+	syncHints := make(chan struct{}, 1)
+	syncHint := func() {
+		select {
+		case syncHints <- struct{}{}:
+		default:
+		}
+	}
 	{
 		ctx, cancel := context.WithCancel(context.Background())
 		g.Add(func() error {
@@ -370,7 +472,7 @@ func runStore(
 			level.Info(logger).Log("msg", "bucket store ready", "init_duration", time.Since(begin).String())
 			close(bucketStoreReady)
 
-			err := runutil.Repeat(conf.syncInterval, ctx.Done(), func() error {
+			err := runutil.RepeatOrTrigger(conf.syncInterval, ctx.Done(), syncHints, func() error {
 				if err := bs.SyncBlocks(ctx); err != nil {
 					level.Warn(logger).Log("msg", "syncing blocks failed", "err", err)
 				}
@@ -384,6 +486,33 @@ func runStore(
 		})
 	}
 
+	if conf.syncPollInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			<-bucketStoreReady
+
+			detector := block.NewUploadDetector(bkt)
+			return runutil.Repeat(conf.syncPollInterval, ctx.Done(), func() error {
+				ids, err := detector.Detect(ctx)
+				if err != nil {
+					level.Warn(logger).Log("msg", "detecting new blocks failed", "err", err)
+					return nil
+				}
+				if len(ids) > 0 {
+					level.Debug(logger).Log("msg", "new blocks detected; syncing blocks", "blocks", len(ids))
+					syncHint()
+				}
+				return nil
+			})
+		}, func(error) {
+			cancel()
+		})
+	}
+
+	if err := watchBucketNotifications(g, logger, reg, conf.bucketNotifications, syncHint); err != nil {
+		return err
+	}
+
 	infoSrv := info.NewInfoServer(
 		component.Store.String(),
 		info.WithLabelSetFunc(func() []labelpb.ZLabelSet {
@@ -392,25 +521,34 @@ func runStore(
 		info.WithStoreInfoFunc(func() *infopb.StoreInfo {
 			mint, maxt := bs.TimeRange()
 			return &infopb.StoreInfo{
-				MinTime: mint,
-				MaxTime: maxt,
+				MinTime:               mint,
+				MaxTime:               maxt,
+				SupportsSeriesBatches: true,
+				TsdbInfos:             bs.TSDBInfos(),
 			}
 		}),
+		info.WithTSDBStatusInfoFunc(),
 	)
 
 	// Start query (proxy) gRPC StoreAPI.
 	{
-		tlsCfg, err := tls.NewServerConfig(log.With(logger, "protocol", "gRPC"), conf.grpcConfig.tlsSrvCert, conf.grpcConfig.tlsSrvKey, conf.grpcConfig.tlsSrvClientCA)
+		spiffeSrc, err := spiffeSource(g, logger, reg, *conf.grpcConfig.spiffeAddr, *conf.grpcConfig.spiffeIDs)
+		if err != nil {
+			return err
+		}
+		tlsCfg, err := grpcServerTLSConfig(logger, reg, spiffeSrc, conf.grpcConfig.tlsSrvCert, conf.grpcConfig.tlsSrvKey, conf.grpcConfig.tlsSrvClientCA)
 		if err != nil {
 			return errors.Wrap(err, "setup gRPC server")
 		}
 
 		s := grpcserver.New(logger, reg, tracer, grpcLogOpts, tagOpts, conf.component, grpcProbe,
 			grpcserver.WithServer(store.RegisterStoreServer(bs)),
+			grpcserver.WithServer(tsdbstatus.RegisterTSDBStatusServer(bs)),
 			grpcserver.WithServer(info.RegisterInfoServer(infoSrv)),
 			grpcserver.WithListen(conf.grpcConfig.bindAddress),
 			grpcserver.WithGracePeriod(time.Duration(conf.grpcConfig.gracePeriod)),
 			grpcserver.WithTLSConfig(tlsCfg),
+			grpcserver.WithIPFilter(ipf.grpc),
 		)
 
 		g.Add(func() error {
@@ -431,7 +569,8 @@ func runStore(
 
 		// Configure Request Logging for HTTP calls.
 		logMiddleware := logging.NewHTTPServerMiddleware(logger, httpLogOpts...)
-		api := blocksAPI.NewBlocksAPI(logger, conf.webConfig.disableCORS, "", flagsMap, bkt)
+		api := blocksAPI.NewBlocksAPI(logger, conf.webConfig.disableCORS, "", extkingpin.RedactedFlags(cmdFlags), bkt)
+		api.SetSyncHint(syncHint)
 		api.Register(r.WithPrefix("/api/v1"), tracer, logger, ins, logMiddleware)
 
 		metaFetcher.UpdateOnChange(func(blocks []metadata.Meta, err error) {
@@ -4,8 +4,15 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -13,14 +20,20 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
 
 	"github.com/thanos-io/thanos/pkg/errutil"
 	"github.com/thanos-io/thanos/pkg/extkingpin"
+	"github.com/thanos-io/thanos/pkg/promclient"
 	"github.com/thanos-io/thanos/pkg/rules"
 )
 
 type checkRulesConfig struct {
-	rulesFiles []string
+	rulesFiles    []string
+	replicaLabels []string
+	queryURL      string
+	output        string
 }
 
 func registerTools(app *extkingpin.App) {
@@ -32,6 +45,12 @@ func registerTools(app *extkingpin.App) {
 
 func (tc *checkRulesConfig) registerFlag(cmd extkingpin.FlagClause) *checkRulesConfig {
 	cmd.Flag("rules", "The rule files glob to check (repeated).").Required().StringsVar(&tc.rulesFiles)
+	cmd.Flag("query.replica-label", "Replica label the queriers of the ruler deduplicate series by (repeated). Rules selecting or aggregating by them are reported.").
+		StringsVar(&tc.replicaLabels)
+	cmd.Flag("query", "Address of a querier, e.g. http://localhost:10902, which is queried to report the selectors of rules matching no series in the last hour.").
+		Default("").StringVar(&tc.queryURL)
+	cmd.Flag("output", "Format of the findings of Thanos specific checks. With 'json', they are printed to the standard output as JSON objects, one per line, instead of being logged.").
+		Default("").EnumVar(&tc.output, "", "json")
 	return tc
 }
 
@@ -42,12 +61,38 @@ func registerCheckRules(app extkingpin.AppClause) {
 	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
 		// Dummy actor to immediately kill the group after the run function returns.
 		g.Add(func() error { return nil }, func(error) {})
-		return checkRulesFiles(logger, &crc.rulesFiles)
+
+		opts := rules.LintOptions{ReplicaLabels: crc.replicaLabels}
+		if crc.queryURL != "" {
+			u, err := url.Parse(crc.queryURL)
+			if err != nil {
+				return errors.Wrapf(err, "parse query address %s", crc.queryURL)
+			}
+			client := promclient.NewDefaultClient()
+			opts.Matches = func(ctx context.Context, matchers []*labels.Matcher) (bool, error) {
+				end := time.Now()
+				series, err := client.SeriesInGRPC(ctx, u, matchers, timestamp.FromTime(end.Add(-time.Hour)), timestamp.FromTime(end))
+				return len(series) > 0, err
+			}
+		}
+		var out io.Writer
+		if crc.output == "json" {
+			out = os.Stdout
+		}
+		return checkRulesFiles(logger, &crc.rulesFiles, opts, out)
 	})
 }
 
-func checkRulesFiles(logger log.Logger, patterns *[]string) error {
-	var failed errutil.MultiError
+// checkRulesFiles checks the rule files matching the given patterns. The findings of Thanos specific checks are
+// written to out as JSON objects if it is not nil, and logged otherwise. Findings of errors fail the check.
+func checkRulesFiles(logger log.Logger, patterns *[]string, lintOpts rules.LintOptions, out io.Writer) error {
+	var (
+		failed errutil.MultiError
+		enc    *json.Encoder
+	)
+	if out != nil {
+		enc = json.NewEncoder(out)
+	}
 
 	for _, p := range *patterns {
 		level.Info(logger).Log("msg", "checking", "pattern", p)
@@ -70,7 +115,45 @@ func checkRulesFiles(logger log.Logger, patterns *[]string) error {
 			}
 			defer func() { _ = f.Close() }()
 
-			n, errs := rules.ValidateAndCount(f)
+			content, er := ioutil.ReadAll(f)
+			if er != nil {
+				level.Error(logger).Log("result", "FAILED", "error", er)
+				level.Info(logger).Log()
+				failed.Add(er)
+				continue
+			}
+
+			findings, er := rules.Lint(context.Background(), filepath.Clean(fn), content, lintOpts)
+			if er != nil {
+				level.Error(logger).Log("result", "FAILED", "error", er)
+				level.Info(logger).Log()
+				failed.Add(er)
+				continue
+			}
+			lintFailed := false
+			for _, finding := range findings {
+				if enc != nil {
+					if err := enc.Encode(finding); err != nil {
+						return errors.Wrap(err, "write finding")
+					}
+				} else if finding.Severity == rules.SeverityError {
+					level.Error(logger).Log("finding", finding.String())
+				} else {
+					level.Warn(logger).Log("finding", finding.String())
+				}
+				if finding.Severity == rules.SeverityError {
+					lintFailed = true
+					failed.Add(errors.New(finding.String()))
+				}
+			}
+			// Files failing Thanos specific checks fail to be parsed as rules as well.
+			if lintFailed {
+				level.Error(logger).Log("result", "FAILED")
+				level.Info(logger).Log()
+				continue
+			}
+
+			n, errs := rules.ValidateAndCount(bytes.NewReader(content))
 			if errs.Err() != nil {
 				level.Error(logger).Log("result", "FAILED")
 				for _, e := range errs {
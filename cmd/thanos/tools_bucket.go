@@ -11,8 +11,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strconv"
@@ -33,14 +35,18 @@ import (
 	"github.com/prometheus/common/route"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
 
 	extflag "github.com/efficientgo/tools/extkingpin"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 	"gopkg.in/yaml.v3"
 
+	"github.com/thanos-io/thanos/pkg/admin"
 	v1 "github.com/thanos-io/thanos/pkg/api/blocks"
 	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
@@ -56,9 +62,11 @@ import (
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/objstore/client"
 	"github.com/thanos-io/thanos/pkg/prober"
+	"github.com/thanos-io/thanos/pkg/receive"
 	"github.com/thanos-io/thanos/pkg/replicate"
 	"github.com/thanos-io/thanos/pkg/runutil"
 	httpserver "github.com/thanos-io/thanos/pkg/server/http"
+	"github.com/thanos-io/thanos/pkg/snapshot"
 	"github.com/thanos-io/thanos/pkg/store"
 	"github.com/thanos-io/thanos/pkg/ui"
 	"github.com/thanos-io/thanos/pkg/verifier"
@@ -71,11 +79,19 @@ var (
 		Verifiers: []verifier.Verifier{verifier.OverlappedBlocksIssue{}},
 		VerifierRepairers: []verifier.VerifierRepairer{
 			verifier.IndexKnownIssues{},
+			verifier.OutOfOrderChunks{},
 			verifier.DuplicatedCompactionBlocks{},
+			verifier.OverlappedBlocksRepair{},
 		},
 	}
 	inspectColumns = []string{"ULID", "FROM", "UNTIL", "RANGE", "UNTIL-DOWN", "#SERIES", "#SAMPLES", "#CHUNKS", "COMP-LEVEL", "COMP-FAILED", "LABELS", "RESOLUTION", "SOURCE"}
-	outputTypes    = []string{"table", "tsv", "csv"}
+	outputTypes    = []string{"table", "tsv", "csv", "json"}
+
+	// blockCSVHeader is the stable header of blocks printed as CSV. Times and resolution are in milliseconds.
+	blockCSVHeader = []string{"ulid", "min_time", "max_time", "resolution", "compaction_level", "source", "num_series", "num_samples", "num_chunks", "labels"}
+	// exportCSVHeader is the stable header of exported samples. Series include the external labels of their blocks and
+	// timestamps are in milliseconds.
+	exportCSVHeader = []string{"series", "timestamp", "value"}
 )
 
 type outputType string
@@ -84,6 +100,7 @@ const (
 	TABLE outputType = "table"
 	CSV   outputType = "csv"
 	TSV   outputType = "tsv"
+	JSON  outputType = "json"
 )
 
 type bucketRewriteConfig struct {
@@ -95,20 +112,41 @@ type bucketRewriteConfig struct {
 }
 
 type bucketInspectConfig struct {
-	selector []string
-	sortBy   []string
-	timeout  time.Duration
+	sortBy  []string
+	timeout time.Duration
+}
+
+type bucketAnalyzeConfig struct {
+	blockIDs []string
+	tmpDir   string
+	limit    int
+}
+
+type bucketExportConfig struct {
+	selectors []string
+	blockIDs  []string
+	output    string
+	tmpDir    string
+}
+
+// bucketFilterConfig selects the blocks on which bucket tools operate by time range and external labels.
+type bucketFilterConfig struct {
+	selectors []string
+	minTime   model.TimeOrDurationValue
+	maxTime   model.TimeOrDurationValue
 }
 
 type bucketVerifyConfig struct {
 	repair         bool
 	ids            []string
 	issuesToVerify []string
+	backupPrefix   string
 }
 
 type bucketLsConfig struct {
 	output        string
 	excludeDelete bool
+	marked        string
 }
 
 type bucketWebConfig struct {
@@ -126,30 +164,92 @@ type bucketReplicateConfig struct {
 	compactions []int
 	matcherStrs []string
 	singleRun   bool
+	concurrency int
+	stateFile   string
+	stateObject string
 }
 
 type bucketDownsampleConfig struct {
 	downsampleConcurrency int
 	dataDir               string
 	hashFunc              string
+	blockIDs              []string
+	levels                []string
 }
 
 type bucketCleanupConfig struct {
-	consistencyDelay     time.Duration
-	blockSyncConcurrency int
-	deleteDelay          time.Duration
+	consistencyDelay          time.Duration
+	blockSyncConcurrency      int
+	deleteDelay               time.Duration
+	partialUploadThresholdAge time.Duration
+	dryRun                    bool
 }
 
 type bucketRetentionConfig struct {
 	consistencyDelay     time.Duration
 	blockSyncConcurrency int
 	deleteDelay          time.Duration
+	dryRun               bool
+}
+
+type bucketImportConfig struct {
+	input         string
+	format        string
+	labels        []string
+	blockDuration prommodel.Duration
+	tmpDir        string
+	allowOverlap  bool
+	dryRun        bool
 }
 
+const (
+	importFormatOpenMetrics = "openmetrics"
+	importFormatTSDB        = "tsdb"
+)
+
 type bucketMarkBlockConfig struct {
 	details  string
 	marker   string
 	blockIDs []string
+	remove   bool
+}
+
+func (tbc *bucketFilterConfig) registerBucketFilterFlag(cmd extkingpin.FlagClause) *bucketFilterConfig {
+	cmd.Flag("selector", "Selects blocks by external labels with PromQL label matchers, e.g. '-l tenant=\\\"team-a\\\" -l replica!~\\\"1|2\\\"'. All matchers must match. Repeated flag.").Short('l').
+		PlaceHolder("<name><op>\\\"<value>\\\"").StringsVar(&tbc.selectors)
+	cmd.Flag("min-time", "Start of the time range of selected blocks. Only blocks which overlap with the time range are selected. "+
+		"Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y.").
+		Default("0000-01-01T00:00:00Z").SetValue(&tbc.minTime)
+	cmd.Flag("max-time", "End of the time range of selected blocks. "+
+		"Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y.").
+		Default("9999-12-31T23:59:59Z").SetValue(&tbc.maxTime)
+	return tbc
+}
+
+// matchers returns the label matchers of the selectors.
+func (tbc *bucketFilterConfig) matchers() ([]*labels.Matcher, error) {
+	var matchers []*labels.Matcher
+	for _, sel := range tbc.selectors {
+		ms, err := parser.ParseMetricSelector("{" + sel + "}")
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse selector %s", sel)
+		}
+		matchers = append(matchers, ms...)
+	}
+	return matchers, nil
+}
+
+// metaFilters returns the filters of blocks which are outside of the time range or don't match the selectors.
+func (tbc *bucketFilterConfig) metaFilters() ([]block.MetadataFilter, error) {
+	matchers, err := tbc.matchers()
+	if err != nil {
+		return nil, err
+	}
+	filters := []block.MetadataFilter{block.NewTimePartitionMetaFilter(tbc.minTime, tbc.maxTime)}
+	if len(matchers) > 0 {
+		filters = append(filters, block.NewLabelMatcherMetaFilter(matchers))
+	}
+	return filters, nil
 }
 
 func (tbc *bucketVerifyConfig) registerBucketVerifyFlag(cmd extkingpin.FlagClause) *bucketVerifyConfig {
@@ -163,20 +263,24 @@ func (tbc *bucketVerifyConfig) registerBucketVerifyFlag(cmd extkingpin.FlagClaus
 
 	cmd.Flag("id", "Block IDs to verify (and optionally repair) only. "+
 		"If none is specified, all blocks will be verified. Repeated field").StringsVar(&tbc.ids)
+
+	cmd.Flag("backup-prefix", "Prefix in the bucket under which repair logic backs up blocks before removal, "+
+		"if no backup bucket is configured. It must be a single path element, which is not a block ID.").
+		Default("").StringVar(&tbc.backupPrefix)
 	return tbc
 }
 
 func (tbc *bucketLsConfig) registerBucketLsFlag(cmd extkingpin.FlagClause) *bucketLsConfig {
-	cmd.Flag("output", "Optional format in which to print each block's information. Options are 'json', 'wide' or a custom template.").
+	cmd.Flag("output", "Optional format in which to print each block's information. Options are 'json', 'csv', 'wide' or a custom template.").
 		Short('o').Default("").StringVar(&tbc.output)
 	cmd.Flag("exclude-delete", "Exclude blocks marked for deletion.").
 		Default("false").BoolVar(&tbc.excludeDelete)
+	cmd.Flag("marked", "Only list blocks with this marker, e.g. to list the blocks excluded from compaction or downsampling.").
+		Default("").EnumVar(&tbc.marked, "", metadata.NoCompactMarkFilename, metadata.NoDownsampleMarkFilename)
 	return tbc
 }
 
 func (tbc *bucketInspectConfig) registerBucketInspectFlag(cmd extkingpin.FlagClause) *bucketInspectConfig {
-	cmd.Flag("selector", "Selects blocks based on label, e.g. '-l key1=\\\"value1\\\" -l key2=\\\"value2\\\"'. All key value pairs must match.").Short('l').
-		PlaceHolder("<name>=\\\"<value>\\\"").StringsVar(&tbc.selector)
 	cmd.Flag("sort-by", "Sort by columns. It's also possible to sort by multiple columns, e.g. '--sort-by FROM --sort-by UNTIL'. I.e., if the 'FROM' value is equal the rows are then further sorted by the 'UNTIL' value.").
 		Default("FROM", "UNTIL").EnumsVar(&tbc.sortBy, inspectColumns...)
 	cmd.Flag("timeout", "Timeout to download metadata from remote storage").Default("5m").DurationVar(&tbc.timeout)
@@ -184,6 +288,24 @@ func (tbc *bucketInspectConfig) registerBucketInspectFlag(cmd extkingpin.FlagCla
 	return tbc
 }
 
+func (tbc *bucketAnalyzeConfig) registerBucketAnalyzeFlag(cmd extkingpin.FlagClause) *bucketAnalyzeConfig {
+	cmd.Flag("id", "ID (ULID) of the blocks to analyze (repeated flag). If none is given, all blocks selected by the other flags are analyzed.").StringsVar(&tbc.blockIDs)
+	cmd.Flag("tmp.dir", "Working directory to which the indexes of the blocks are downloaded one by one.").Default(filepath.Join(os.TempDir(), "thanos-analyze")).StringVar(&tbc.tmpDir)
+	cmd.Flag("limit", "Number of label names and metrics with the highest cardinality to print per block and for all blocks.").Default("20").IntVar(&tbc.limit)
+	return tbc
+}
+
+func (tbc *bucketExportConfig) registerBucketExportFlag(cmd extkingpin.FlagClause) *bucketExportConfig {
+	cmd.Flag("match", "Series selector of the series to export, e.g. 'up{job=\"node\"}'. Series matching any of the selectors are exported. Repeated flag.").
+		Required().PlaceHolder("<series-selector>").StringsVar(&tbc.selectors)
+	cmd.Flag("id", "ID (ULID) of the blocks to export (repeated flag). If none is given, all blocks selected by the other flags are exported.").
+		PlaceHolder("<id>").StringsVar(&tbc.blockIDs)
+	cmd.Flag("output", "Path of the CSV file to write the samples to. If empty, they are written to the standard output.").
+		Default("").StringVar(&tbc.output)
+	cmd.Flag("tmp.dir", "Directory to which the blocks are downloaded, one at a time.").Default("/tmp/thanos-export").StringVar(&tbc.tmpDir)
+	return tbc
+}
+
 func (tbc *bucketWebConfig) registerBucketWebFlag(cmd extkingpin.FlagClause) *bucketWebConfig {
 	cmd.Flag("web.route-prefix", "Prefix for API and UI endpoints. This allows thanos UI to be served on a sub-path. Defaults to the value of --web.external-prefix. This option is analogous to --web.route-prefix of Prometheus.").Default("").StringVar(&tbc.webRoutePrefix)
 
@@ -210,6 +332,16 @@ func (tbc *bucketReplicateConfig) registerBucketReplicateFlag(cmd extkingpin.Fla
 
 	cmd.Flag("single-run", "Run replication only one time, then exit.").Default("false").BoolVar(&tbc.singleRun)
 
+	cmd.Flag("concurrency", "Number of blocks replicated at once. Blocks are started in order of their start time, oldest first.").Default("1").IntVar(&tbc.concurrency)
+
+	cmd.Flag("state-file", "Path to a local file in which the replicated blocks are recorded, so interrupted replication resumes where it stopped. "+
+		"Blocks recorded in the state are not replicated again, even if they are removed from the target bucket.").
+		Default("").StringVar(&tbc.stateFile)
+
+	cmd.Flag("state-object", "Name of an object in the target bucket in which the replicated blocks are recorded, like with --state-file. "+
+		"Mutually exclusive with --state-file.").
+		Default("").StringVar(&tbc.stateObject)
+
 	return tbc
 }
 
@@ -230,24 +362,34 @@ func (tbc *bucketDownsampleConfig) registerBucketDownsampleFlag(cmd extkingpin.F
 		Default("./data").StringVar(&tbc.dataDir)
 	cmd.Flag("hash-func", "Specify which hash function to use when calculating the hashes of produced files. If no function has been specified, it does not happen. This permits avoiding downloading some files twice albeit at some performance cost. Possible values are: \"\", \"SHA256\".").
 		Default("").EnumVar(&tbc.hashFunc, "SHA256", "")
+	cmd.Flag("id", "ID (ULID) of a block to be downsampled. Blocks downsampled from it are downsampled further to the next resolution. If not specified, all blocks are downsampled. Repeated flag.").
+		StringsVar(&tbc.blockIDs)
+	cmd.Flag("downsample.level", "Downsampling resolution and minimum time range of the blocks of the previous resolution downsampled to it, in the form <resolution>:<min source range>, e.g. 6h:60d. "+
+		"Raw blocks are downsampled to the lowest resolution, and blocks of each resolution to the next one. If not specified, the default resolutions 5m:40h and 1h:10d are used. Repeated flag.").
+		StringsVar(&tbc.levels)
 
 	return tbc
 }
 
 func (tbc *bucketMarkBlockConfig) registerBucketMarkBlockFlag(cmd extkingpin.FlagClause) *bucketMarkBlockConfig {
-	cmd.Flag("id", "ID (ULID) of the blocks to be marked for deletion (repeated flag)").Required().StringsVar(&tbc.blockIDs)
-	cmd.Flag("marker", "Marker to be put.").Required().EnumVar(&tbc.marker, metadata.DeletionMarkFilename, metadata.NoCompactMarkFilename)
-	cmd.Flag("details", "Human readable details to be put into marker.").Required().StringVar(&tbc.details)
+	cmd.Flag("id", "ID (ULID) of the blocks to be marked (repeated flag)").Required().StringsVar(&tbc.blockIDs)
+	cmd.Flag("marker", "Marker to be put or removed.").Required().EnumVar(&tbc.marker, metadata.DeletionMarkFilename, metadata.NoCompactMarkFilename, metadata.NoDownsampleMarkFilename)
+	cmd.Flag("details", "Human readable details to be put into marker. When removing the marker, the reason for the removal, which is logged.").Required().StringVar(&tbc.details)
+	cmd.Flag("remove", "Remove the marker from the blocks instead of putting it. Blocks are kept, unless they were already deleted after being marked for deletion.").Default("false").BoolVar(&tbc.remove)
 
 	return tbc
 }
 
 func (tbc *bucketCleanupConfig) registerBucketCleanupFlag(cmd extkingpin.FlagClause) *bucketCleanupConfig {
 	cmd.Flag("delete-delay", "Time before a block marked for deletion is deleted from bucket.").Default("48h").DurationVar(&tbc.deleteDelay)
-	cmd.Flag("consistency-delay", fmt.Sprintf("Minimum age of fresh (non-compacted) blocks before they are being processed. Malformed blocks older than the maximum of consistency-delay and %v will be removed.", compact.PartialUploadThresholdAge)).
+	cmd.Flag("consistency-delay", "Minimum age of fresh (non-compacted) blocks before they are being processed. Malformed blocks older than the maximum of consistency-delay and partial-upload-threshold-age will be removed.").
 		Default("30m").DurationVar(&tbc.consistencyDelay)
+	cmd.Flag("partial-upload-threshold-age", "Minimum age of partially uploaded blocks, i.e. blocks without or with malformed meta.json, before their upload is assumed to be aborted and they are removed. The age is based on the block creation time, so keep it longer than uploads can take.").
+		Default(compact.PartialUploadThresholdAge.String()).DurationVar(&tbc.partialUploadThresholdAge)
 	cmd.Flag("block-sync-concurrency", "Number of goroutines to use when syncing block metadata from object storage.").
 		Default("20").IntVar(&tbc.blockSyncConcurrency)
+	cmd.Flag("dry-run", "Print the aborted partial uploads and the blocks marked for deletion which would be removed, with their sizes, without deleting anything.").
+		BoolVar(&tbc.dryRun)
 	return tbc
 }
 
@@ -257,10 +399,30 @@ func (tbc *bucketRetentionConfig) registerBucketRetentionFlag(cmd extkingpin.Fla
 		Default("30m").DurationVar(&tbc.consistencyDelay)
 	cmd.Flag("block-sync-concurrency", "Number of goroutines to use when syncing block metadata from object storage.").
 		Default("20").IntVar(&tbc.blockSyncConcurrency)
+	cmd.Flag("dry-run", "Print the blocks which exceed retention and would be marked for deletion, with their sizes and the totals per external label set, without marking any of them.").
+		BoolVar(&tbc.dryRun)
 
 	return tbc
 }
 
+func (tbc *bucketImportConfig) registerBucketImportFlag(cmd extkingpin.FlagClause) *bucketImportConfig {
+	cmd.Flag("input", "Path of the OpenMetrics text file, or of the TSDB directory or snapshot with the blocks to be imported.").
+		Required().StringVar(&tbc.input)
+	cmd.Flag("format", "Format of the input. Samples of OpenMetrics input must have timestamps and the samples of each series must be in increasing timestamp order.").
+		Default(importFormatOpenMetrics).EnumVar(&tbc.format, importFormatOpenMetrics, importFormatTSDB)
+	cmd.Flag("label", "External labels of the imported blocks (repeated). Blocks with the same external labels as existing ones are in the same compaction group and must not overlap with them.").
+		Required().PlaceHolder("<name>=\"<value>\"").StringsVar(&tbc.labels)
+	cmd.Flag("block-duration", "Time range of the blocks created from OpenMetrics input, which must be one of the compaction ranges of 2h, 8h, 2d and 14d. The blocks are aligned to it, so they can be compacted further.").
+		Default("2h").SetValue(&tbc.blockDuration)
+	cmd.Flag("tmp.dir", "Working directory for the blocks to be imported. Blocks of TSDB input are hard linked into it, so it must be on the same filesystem as the input.").
+		Default("/tmp/thanos-import").StringVar(&tbc.tmpDir)
+	cmd.Flag("allow-overlap", "Upload the blocks even if they overlap with each other or with existing blocks of the same external labels. The compactor has to run with vertical compaction enabled to merge them.").
+		Default("false").BoolVar(&tbc.allowOverlap)
+	cmd.Flag("dry-run", "Create, verify and check the blocks for overlaps without uploading them.").
+		Default("false").BoolVar(&tbc.dryRun)
+	return tbc
+}
+
 func registerBucket(app extkingpin.AppClause) {
 	cmd := app.Command("bucket", "Bucket utility commands")
 
@@ -268,6 +430,7 @@ func registerBucket(app extkingpin.AppClause) {
 	registerBucketVerify(cmd, objStoreConfig)
 	registerBucketLs(cmd, objStoreConfig)
 	registerBucketInspect(cmd, objStoreConfig)
+	registerBucketAnalyze(cmd, objStoreConfig)
 	registerBucketWeb(cmd, objStoreConfig)
 	registerBucketReplicate(cmd, objStoreConfig)
 	registerBucketDownsample(cmd, objStoreConfig)
@@ -275,6 +438,9 @@ func registerBucket(app extkingpin.AppClause) {
 	registerBucketMarkBlock(cmd, objStoreConfig)
 	registerBucketRewrite(cmd, objStoreConfig)
 	registerBucketRetention(cmd, objStoreConfig)
+	registerBucketImport(cmd, objStoreConfig)
+	registerBucketExport(cmd, objStoreConfig)
+	registerBucketSnapshot(cmd, objStoreConfig)
 }
 
 func registerBucketVerify(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
@@ -284,6 +450,9 @@ func registerBucketVerify(app extkingpin.AppClause, objStoreConfig *extflag.Path
 	tbc := &bucketVerifyConfig{}
 	tbc.registerBucketVerifyFlag(cmd)
 
+	filterConf := &bucketFilterConfig{}
+	filterConf.registerBucketFilterFlag(cmd)
+
 	deleteDelay := extkingpin.ModelDuration(cmd.Flag("delete-delay", "Duration after which blocks marked for deletion would be deleted permanently from source bucket by compactor component. "+
 		"If delete-delay is non zero, blocks will be marked for deletion and compactor component is required to delete blocks from source bucket. "+
 		"If delete-delay is 0, blocks will be deleted straight away. Use this if you want to get rid of or move the block immediately. "+
@@ -309,8 +478,17 @@ func registerBucketVerify(app extkingpin.AppClause, objStoreConfig *extflag.Path
 
 		var backupBkt objstore.Bucket
 		if len(backupconfContentYaml) == 0 {
-			if tbc.repair {
-				return errors.New("repair is specified, so backup client is required")
+			if tbc.backupPrefix != "" {
+				if _, err := ulid.Parse(tbc.backupPrefix); err == nil {
+					return errors.Errorf("backup prefix %s must not be a block ID", tbc.backupPrefix)
+				}
+				// The backup prefix shares the client of the bucket, so it's not closed separately.
+				backupBkt, err = objstore.NewTenantBucket(bkt, tbc.backupPrefix)
+				if err != nil {
+					return errors.Wrap(err, "backup prefix")
+				}
+			} else if tbc.repair {
+				return errors.New("repair is specified, so backup client or backup prefix is required")
 			}
 		} else {
 			// nil Prometheus registerer: don't create conflicting metrics.
@@ -330,7 +508,11 @@ func registerBucketVerify(app extkingpin.AppClause, objStoreConfig *extflag.Path
 			return err
 		}
 
-		fetcher, err := block.NewMetaFetcher(logger, block.FetcherConcurrency, bkt, "", extprom.WrapRegistererWithPrefix(extpromPrefix, reg), nil, nil)
+		filters, err := filterConf.metaFilters()
+		if err != nil {
+			return err
+		}
+		fetcher, err := block.NewMetaFetcher(logger, block.FetcherConcurrency, bkt, "", extprom.WrapRegistererWithPrefix(extpromPrefix, reg), filters, nil)
 		if err != nil {
 			return err
 		}
@@ -369,6 +551,9 @@ func registerBucketLs(app extkingpin.AppClause, objStoreConfig *extflag.PathOrCo
 	tbc := &bucketLsConfig{}
 	tbc.registerBucketLsFlag(cmd)
 
+	filterConf := &bucketFilterConfig{}
+	filterConf.registerBucketFilterFlag(cmd)
+
 	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
 		confContentYaml, err := objStoreConfig.Content()
 		if err != nil {
@@ -380,12 +565,34 @@ func registerBucketLs(app extkingpin.AppClause, objStoreConfig *extflag.PathOrCo
 			return err
 		}
 
-		var filters []block.MetadataFilter
+		filters, err := filterConf.metaFilters()
+		if err != nil {
+			return err
+		}
 
 		if tbc.excludeDelete {
 			ignoreDeletionMarkFilter := block.NewIgnoreDeletionMarkFilter(logger, bkt, 0, block.FetcherConcurrency)
 			filters = append(filters, ignoreDeletionMarkFilter)
 		}
+
+		// marked returns whether a fetched block has the marker to list blocks by, if any.
+		marked := func(ulid.ULID) bool { return true }
+		switch tbc.marked {
+		case metadata.NoCompactMarkFilename:
+			f := compact.NewGatherNoCompactionMarkFilter(logger, bkt, block.FetcherConcurrency)
+			filters = append(filters, f)
+			marked = func(id ulid.ULID) bool {
+				_, ok := f.NoCompactMarkedBlocks()[id]
+				return ok
+			}
+		case metadata.NoDownsampleMarkFilename:
+			f := compact.NewGatherNoDownsampleMarkFilter(logger, bkt, block.FetcherConcurrency)
+			filters = append(filters, f)
+			marked = func(id ulid.ULID) bool {
+				_, ok := f.NoDownsampleMarkedBlocks()[id]
+				return ok
+			}
+		}
 		fetcher, err := block.NewMetaFetcher(logger, block.FetcherConcurrency, bkt, "", extprom.WrapRegistererWithPrefix(extpromPrefix, reg), filters, nil)
 		if err != nil {
 			return err
@@ -430,6 +637,8 @@ func registerBucketLs(app extkingpin.AppClause, objStoreConfig *extflag.PathOrCo
 			printBlock = func(m *metadata.Meta) error {
 				return enc.Encode(&m)
 			}
+		case "csv":
+			// Blocks are printed at once, sorted by ULID, after fetching.
 		default:
 			tmpl, err := template.New("").Parse(format)
 			if err != nil {
@@ -448,6 +657,26 @@ func registerBucketLs(app extkingpin.AppClause, objStoreConfig *extflag.PathOrCo
 		if err != nil {
 			return err
 		}
+		for id := range metas {
+			if !marked(id) {
+				delete(metas, id)
+			}
+		}
+
+		if format == "csv" {
+			blockMetas := make([]*metadata.Meta, 0, len(metas))
+			for _, meta := range metas {
+				blockMetas = append(blockMetas, meta)
+			}
+			sort.Slice(blockMetas, func(i, j int) bool {
+				return blockMetas[i].ULID.Compare(blockMetas[j].ULID) < 0
+			})
+			if err := printBlocksCSV(os.Stdout, blockMetas); err != nil {
+				return errors.Wrap(err, "print blocks")
+			}
+			level.Info(logger).Log("msg", "ls done", "objects", len(blockMetas))
+			return nil
+		}
 
 		for _, meta := range metas {
 			objects++
@@ -466,12 +695,13 @@ func registerBucketInspect(app extkingpin.AppClause, objStoreConfig *extflag.Pat
 	tbc := &bucketInspectConfig{}
 	tbc.registerBucketInspectFlag(cmd)
 
-	output := cmd.Flag("output", "Output format for result. Currently supports table, cvs, tsv.").Default("table").Enum(outputTypes...)
+	filterConf := &bucketFilterConfig{}
+	filterConf.registerBucketFilterFlag(cmd)
 
-	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+	output := cmd.Flag("output", "Output format for result. Currently supports table, tsv, csv and json. The csv and json formats have stable fields with raw values, times and resolution in milliseconds.").Default("table").Enum(outputTypes...)
 
-		// Parse selector.
-		selectorLabels, err := parseFlagLabels(tbc.selector)
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		filters, err := filterConf.metaFilters()
 		if err != nil {
 			return errors.Wrap(err, "error parsing selector flag")
 		}
@@ -486,7 +716,7 @@ func registerBucketInspect(app extkingpin.AppClause, objStoreConfig *extflag.Pat
 			return err
 		}
 
-		fetcher, err := block.NewMetaFetcher(logger, block.FetcherConcurrency, bkt, "", extprom.WrapRegistererWithPrefix(extpromPrefix, reg), nil, nil)
+		fetcher, err := block.NewMetaFetcher(logger, block.FetcherConcurrency, bkt, "", extprom.WrapRegistererWithPrefix(extpromPrefix, reg), filters, nil)
 		if err != nil {
 			return err
 		}
@@ -510,24 +740,171 @@ func registerBucketInspect(app extkingpin.AppClause, objStoreConfig *extflag.Pat
 			blockMetas = append(blockMetas, meta)
 		}
 
-		var opPrinter tablePrinter
-		op := outputType(*output)
-		switch op {
-		case TABLE:
-			opPrinter = printTable
-		case TSV:
-			opPrinter = printTSV
-		case CSV:
-			opPrinter = printCSV
+		return printBlockData(blockMetas, tbc.sortBy, outputType(*output))
+	})
+}
+
+func registerBucketAnalyze(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command("analyze", "Analyze the label and metric cardinality of blocks in the bucket from their indexes, per block and for all of them, like `promtool tsdb analyze`.")
+
+	tbc := &bucketAnalyzeConfig{}
+	tbc.registerBucketAnalyzeFlag(cmd)
+
+	filterConf := &bucketFilterConfig{}
+	filterConf.registerBucketFilterFlag(cmd)
+
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		ids := make(map[ulid.ULID]struct{}, len(tbc.blockIDs))
+		for _, id := range tbc.blockIDs {
+			u, err := ulid.Parse(id)
+			if err != nil {
+				return errors.Wrapf(err, "invalid block ID %s", id)
+			}
+			ids[u] = struct{}{}
+		}
+
+		filters, err := filterConf.metaFilters()
+		if err != nil {
+			return errors.Wrap(err, "error parsing selector flag")
+		}
+
+		confContentYaml, err := objStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+
+		bkt, err := client.NewBucket(logger, confContentYaml, reg, component.Bucket.String())
+		if err != nil {
+			return err
+		}
+
+		fetcher, err := block.NewMetaFetcher(logger, block.FetcherConcurrency, bkt, "", extprom.WrapRegistererWithPrefix(extpromPrefix, reg), filters, nil)
+		if err != nil {
+			return err
+		}
+
+		// Dummy actor to immediately kill the group after the run function returns.
+		g.Add(func() error { return nil }, func(error) {})
+
+		defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
+
+		ctx := context.Background()
+		metas, _, err := fetcher.Fetch(ctx)
+		if err != nil {
+			return err
+		}
+
+		blockMetas := make([]*metadata.Meta, 0, len(metas))
+		for id, m := range metas {
+			if _, ok := ids[id]; len(ids) > 0 && !ok {
+				continue
+			}
+			blockMetas = append(blockMetas, m)
+		}
+		sort.Slice(blockMetas, func(i, j int) bool {
+			return blockMetas[i].ULID.Compare(blockMetas[j].ULID) < 0
+		})
+
+		var total block.CardinalityStats
+		for _, m := range blockMetas {
+			stats, err := analyzeBlock(ctx, logger, bkt, m.ULID, tbc.tmpDir)
+			if err != nil {
+				return err
+			}
+			title := fmt.Sprintf("Block %s %s", m.ULID, labels.FromMap(m.Thanos.Labels))
+			if err := printCardinalityStats(os.Stdout, title, stats, tbc.limit); err != nil {
+				return err
+			}
+			total.Merge(stats)
+		}
+		return printCardinalityStats(os.Stdout, fmt.Sprintf("All %d blocks", len(blockMetas)), total, tbc.limit)
+	})
+}
+
+// analyzeBlock downloads the index of the given block to a directory in dir and returns its cardinality statistics.
+func analyzeBlock(ctx context.Context, logger log.Logger, bkt objstore.BucketReader, id ulid.ULID, dir string) (block.CardinalityStats, error) {
+	bdir := filepath.Join(dir, id.String())
+	defer func() {
+		if err := os.RemoveAll(bdir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove downloaded index", "dir", bdir, "err", err)
+		}
+	}()
+
+	if err := os.MkdirAll(bdir, os.ModePerm); err != nil {
+		return block.CardinalityStats{}, errors.Wrapf(err, "create dir %s", bdir)
+	}
+
+	level.Info(logger).Log("msg", "downloading index", "block", id)
+	fn := filepath.Join(bdir, block.IndexFilename)
+	if err := objstore.DownloadFile(ctx, logger, bkt, path.Join(id.String(), block.IndexFilename), fn); err != nil {
+		return block.CardinalityStats{}, errors.Wrapf(err, "download index of block %s", id)
+	}
+	stats, err := block.GatherIndexCardinalityStats(fn)
+	return stats, errors.Wrapf(err, "analyze index of block %s", id)
+}
+
+// printCardinalityStats prints the number of series and the label names and metrics with the highest cardinality,
+// at most limit of each.
+func printCardinalityStats(w io.Writer, title string, stats block.CardinalityStats, limit int) error {
+	if _, err := fmt.Fprintf(w, "%s: %d series, %d label names, %d metrics\n\n", title, stats.Series, len(stats.LabelValues), len(stats.MetricSeries)); err != nil {
+		return err
+	}
+
+	valueCounts := make(map[string]uint64, len(stats.LabelValues))
+	for name, n := range stats.LabelValues {
+		valueCounts[name] = uint64(n)
+	}
+	lnames := Table{Header: []string{"Label name", "Values"}}
+	for _, c := range topCardinality(valueCounts, limit) {
+		lnames.Lines = append(lnames.Lines, []string{c.name, strconv.FormatUint(c.count, 10)})
+	}
+	if err := printTable(w, lnames); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+
+	metrics := Table{Header: []string{"Metric", "Series"}}
+	for _, c := range topCardinality(stats.MetricSeries, limit) {
+		metrics.Lines = append(metrics.Lines, []string{c.name, strconv.FormatUint(c.count, 10)})
+	}
+	if err := printTable(w, metrics); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+type cardinality struct {
+	name  string
+	count uint64
+}
+
+// topCardinality returns at most limit of the given counts, the highest first. Equal counts are sorted by name.
+func topCardinality(counts map[string]uint64, limit int) []cardinality {
+	res := make([]cardinality, 0, len(counts))
+	for name, n := range counts {
+		res = append(res, cardinality{name: name, count: n})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].count != res[j].count {
+			return res[i].count > res[j].count
 		}
-		return printBlockData(blockMetas, selectorLabels, tbc.sortBy, opPrinter)
+		return res[i].name < res[j].name
 	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res
 }
 
 // registerBucketWeb exposes a web interface for the state of remote store like `pprof web`.
 func registerBucketWeb(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
 	cmd := app.Command("web", "Web interface for remote storage bucket.")
 	httpBindAddr, httpGracePeriod, httpTLSConfig := extkingpin.RegisterHTTPFlags(cmd)
+	httpOIDCConfig := extkingpin.RegisterHTTPOIDCFlags(cmd)
+	ipFilterConfig := extkingpin.RegisterIPFilterFlags(cmd)
 
 	tbc := &bucketWebConfig{}
 	tbc.registerBucketWebFlag(cmd)
@@ -540,6 +917,9 @@ func registerBucketWeb(app extkingpin.AppClause, objStoreConfig *extflag.PathOrC
 	selectorRelabelConf := *extkingpin.RegisterSelectorRelabelFlags(cmd)
 
 	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		// The object store flags are registered on the bucket command.
+		cmdFlags := append(app.Flags(), cmd.Flags()...)
+
 		comp := component.Bucket
 		httpProbe := prober.NewHTTP()
 		statusProber := prober.Combine(
@@ -547,10 +927,21 @@ func registerBucketWeb(app extkingpin.AppClause, objStoreConfig *extflag.PathOrC
 			prober.NewInstrumentation(comp, logger, extprom.WrapRegistererWithPrefix("thanos_", reg)),
 		)
 
+		authMiddleware, err := oidcMiddleware(logger, reg, httpOIDCConfig)
+		if err != nil {
+			return err
+		}
+		ipf, err := ipFilters(logger, reg, ipFilterConfig)
+		if err != nil {
+			return err
+		}
 		srv := httpserver.New(logger, reg, comp, httpProbe,
 			httpserver.WithListen(*httpBindAddr),
 			httpserver.WithGracePeriod(time.Duration(*httpGracePeriod)),
 			httpserver.WithTLSConfig(*httpTLSConfig),
+			statusOption(cmdFlags),
+			httpserver.WithAuthentication(authMiddleware),
+			httpserver.WithIPFilter(ipf.http),
 		)
 
 		if tbc.webRoutePrefix == "" {
@@ -582,8 +973,6 @@ func registerBucketWeb(app extkingpin.AppClause, objStoreConfig *extflag.PathOrC
 		bucketUI := ui.NewBucketUI(logger, tbc.label, tbc.webExternalPrefix, tbc.webPrefixHeaderName, "", component.Bucket)
 		bucketUI.Register(router, true, ins)
 
-		flagsMap := getFlagsMap(cmd.Flags())
-
 		confContentYaml, err := objStoreConfig.Content()
 		if err != nil {
 			return err
@@ -594,7 +983,7 @@ func registerBucketWeb(app extkingpin.AppClause, objStoreConfig *extflag.PathOrC
 			return errors.Wrap(err, "bucket client")
 		}
 
-		api := v1.NewBlocksAPI(logger, tbc.webDisableCORS, tbc.label, flagsMap, bkt)
+		api := v1.NewBlocksAPI(logger, tbc.webDisableCORS, tbc.label, extkingpin.RedactedFlags(cmdFlags), bkt)
 
 		// Configure Request Logging for HTTP calls.
 		opts := []logging.Option{logging.WithDecider(func(_ string, _ error) logging.Decision {
@@ -690,10 +1079,9 @@ func registerBucketReplicate(app extkingpin.AppClause, objStoreConfig *extflag.P
 	tbc := &bucketReplicateConfig{}
 	tbc.registerBucketReplicateFlag(cmd)
 
-	minTime := model.TimeOrDuration(cmd.Flag("min-time", "Start of time range limit to replicate. Thanos Replicate will replicate only metrics, which happened later than this value. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y.").
-		Default("0000-01-01T00:00:00Z"))
-	maxTime := model.TimeOrDuration(cmd.Flag("max-time", "End of time range limit to replicate. Thanos Replicate will replicate only metrics, which happened earlier than this value. Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y.").
-		Default("9999-12-31T23:59:59Z"))
+	filterConf := &bucketFilterConfig{}
+	filterConf.registerBucketFilterFlag(cmd)
+
 	ids := cmd.Flag("id", "Block to be replicated to the destination bucket. IDs will be used to match blocks and other matchers will be ignored. When specified, this command will be run only once after successful replication. Repeated field").Strings()
 
 	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, _ <-chan struct{}, _ bool) error {
@@ -701,6 +1089,11 @@ func registerBucketReplicate(app extkingpin.AppClause, objStoreConfig *extflag.P
 		if err != nil {
 			return errors.Wrap(err, "parse block label matchers")
 		}
+		selectorMatchers, err := filterConf.matchers()
+		if err != nil {
+			return err
+		}
+		matchers = append(matchers, selectorMatchers...)
 
 		var resolutionLevels []compact.ResolutionLevel
 		for _, lvl := range tbc.resolutions {
@@ -730,27 +1123,63 @@ func registerBucketReplicate(app extkingpin.AppClause, objStoreConfig *extflag.P
 			objStoreConfig,
 			toObjStoreConfig,
 			tbc.singleRun,
-			minTime,
-			maxTime,
+			&filterConf.minTime,
+			&filterConf.maxTime,
 			blockIDs,
+			tbc.concurrency,
+			tbc.stateFile,
+			tbc.stateObject,
 		)
 	})
 }
 
 func registerBucketDownsample(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
-	cmd := app.Command(component.Downsample.String(), "Continuously downsamples blocks in an object store bucket.")
+	cmd := app.Command(component.Downsample.String(), "Downsamples blocks in an object store bucket, which can be selected by ID or time range, e.g. to backfill resolutions after downsampling was enabled.")
 	httpAddr, httpGracePeriod, httpTLSConfig := extkingpin.RegisterHTTPFlags(cmd)
+	httpOIDCConfig := extkingpin.RegisterHTTPOIDCFlags(cmd)
+	ipFilterConfig := extkingpin.RegisterIPFilterFlags(cmd)
 
 	tbc := &bucketDownsampleConfig{}
 	tbc.registerBucketDownsampleFlag(cmd)
 
+	minTime := model.TimeOrDuration(cmd.Flag("min-time", "Start of the time range of blocks to be downsampled. Only blocks which overlap with the time range are downsampled. "+
+		"Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y."))
+	maxTime := model.TimeOrDuration(cmd.Flag("max-time", "End of the time range of blocks to be downsampled. "+
+		"Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y."))
+
 	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, _ <-chan struct{}, _ bool) error {
-		return RunDownsample(g, logger, reg, *httpAddr, *httpTLSConfig, time.Duration(*httpGracePeriod), tbc.dataDir, tbc.downsampleConcurrency, objStoreConfig, component.Downsample, metadata.HashFunc(tbc.hashFunc))
+		var selection downsampleSelection
+		if len(tbc.blockIDs) > 0 {
+			selection.ids = make(map[ulid.ULID]struct{}, len(tbc.blockIDs))
+			for _, id := range tbc.blockIDs {
+				u, err := ulid.Parse(id)
+				if err != nil {
+					return errors.Wrapf(err, "invalid block ID %s", id)
+				}
+				selection.ids[u] = struct{}{}
+			}
+		}
+		interval, ok := flagsInterval(minTime, maxTime)
+		if ok {
+			if interval.Mint > interval.Maxt {
+				return errors.Errorf("min-time %d is after max-time %d", interval.Mint, interval.Maxt)
+			}
+			selection.minTime, selection.maxTime, selection.timeRange = interval.Mint, interval.Maxt, true
+		}
+
+		levels, err := downsample.ParseLevels(tbc.levels)
+		if err != nil {
+			return errors.Wrap(err, "parse downsampling levels")
+		}
+
+		// The object store flags are registered on the bucket command.
+		cmdFlags := append(app.Flags(), cmd.Flags()...)
+		return RunDownsample(g, logger, reg, *httpAddr, *httpTLSConfig, time.Duration(*httpGracePeriod), httpOIDCConfig, ipFilterConfig, tbc.dataDir, tbc.downsampleConcurrency, objStoreConfig, component.Downsample, metadata.HashFunc(tbc.hashFunc), levels, selection, cmdFlags)
 	})
 }
 
 func registerBucketCleanup(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
-	cmd := app.Command(component.Cleanup.String(), "Cleans up all blocks marked for deletion.")
+	cmd := app.Command(component.Cleanup.String(), "Cleans up all blocks marked for deletion and aborted partial uploads.")
 
 	tbc := &bucketCleanupConfig{}
 	tbc.registerBucketCleanupFlag(cmd)
@@ -827,7 +1256,20 @@ func registerBucketCleanup(app extkingpin.AppClause, objStoreConfig *extflag.Pat
 
 		level.Info(logger).Log("msg", "synced blocks done")
 
-		compact.BestEffortCleanAbortedPartialUploads(ctx, logger, sy.Partial(), bkt, stubCounter, stubCounter, stubCounter)
+		if tbc.dryRun {
+			partial, err := compact.AbortedPartialUploads(ctx, bkt, sy.Partial(), tbc.partialUploadThresholdAge)
+			if err != nil {
+				return err
+			}
+			t, err := cleanupReport(ctx, bkt, partial, blocksCleaner.BlocksToDelete())
+			if err != nil {
+				return err
+			}
+			level.Info(logger).Log("msg", "dry run, no blocks were deleted")
+			return printTable(os.Stdout, t)
+		}
+
+		compact.BestEffortCleanAbortedPartialUploads(ctx, logger, sy.Partial(), bkt, tbc.partialUploadThresholdAge, stubCounter, stubCounter, stubCounter)
 		if err := blocksCleaner.DeleteMarkedBlocks(ctx); err != nil {
 			return errors.Wrap(err, "error cleaning blocks")
 		}
@@ -837,7 +1279,58 @@ func registerBucketCleanup(app extkingpin.AppClause, objStoreConfig *extflag.Pat
 	})
 }
 
-type tablePrinter func(w io.Writer, t Table) error
+// cleanupReport returns a table of the given aborted partial uploads and blocks marked for deletion, which cleanup
+// would delete, with the number and size of their objects and the totals.
+func cleanupReport(ctx context.Context, bkt objstore.BucketReader, partial []compact.PartialUpload, marked []ulid.ULID) (Table, error) {
+	t := Table{Header: []string{"ULID", "Reason", "Objects", "Size"}}
+
+	var objects, size int64
+	add := func(id ulid.ULID, reason string, o int, sz int64) {
+		t.Lines = append(t.Lines, []string{id.String(), reason, strconv.Itoa(o), strconv.FormatInt(sz, 10)})
+		objects += int64(o)
+		size += sz
+	}
+	for _, p := range partial {
+		add(p.ID, fmt.Sprintf("aborted partial upload: %v", p.Err), p.Objects, p.Size)
+	}
+	for _, id := range marked {
+		o, sz, err := block.SizeInBucket(ctx, bkt, id)
+		if err != nil {
+			return Table{}, err
+		}
+		add(id, "marked for deletion", o, sz)
+	}
+	t.Lines = append(t.Lines, []string{"total", fmt.Sprintf("%d blocks", len(t.Lines)), strconv.FormatInt(objects, 10), strconv.FormatInt(size, 10)})
+	return t, nil
+}
+
+// printRetentionReport prints the blocks exceeding retention and their totals per external label set as tables.
+func printRetentionReport(w io.Writer, report compact.RetentionReport) error {
+	blocks := Table{Header: []string{"ULID", "Labels", "Resolution", "Until", "Retention", "Objects", "Size"}}
+	for _, b := range report.Blocks {
+		blocks.Lines = append(blocks.Lines, []string{
+			b.Meta.ULID.String(),
+			labels.FromMap(b.Meta.Thanos.Labels).String(),
+			time.Duration(b.Meta.Thanos.Downsample.Resolution * int64(time.Millisecond)).String(),
+			time.Unix(b.Meta.MaxTime/1000, 0).UTC().Format(time.RFC3339),
+			prommodel.Duration(b.Retention).String(),
+			strconv.Itoa(b.Objects),
+			strconv.FormatInt(b.Size, 10),
+		})
+	}
+	if err := printTable(w, blocks); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+
+	totals := Table{Header: []string{"Labels", "Blocks", "Objects", "Size"}}
+	for _, t := range report.Totals {
+		totals.Lines = append(totals.Lines, []string{t.Labels.String(), strconv.Itoa(t.Blocks), strconv.Itoa(t.Objects), strconv.FormatInt(t.Size, 10)})
+	}
+	return printTable(w, totals)
+}
 
 func printTable(w io.Writer, t Table) error {
 	table := tablewriter.NewWriter(w)
@@ -852,18 +1345,39 @@ func printTable(w io.Writer, t Table) error {
 	return nil
 }
 
-func printCSV(w io.Writer, t Table) error {
-	csv := csv.NewWriter(w)
-	err := csv.Write(t.Header)
-	if err != nil {
-		return err
+// printBlocksCSV prints the given blocks with the fields of blockCSVHeader.
+func printBlocksCSV(w io.Writer, metas []*metadata.Meta) error {
+	records := make([][]string, 0, len(metas)+1)
+	records = append(records, blockCSVHeader)
+	for _, m := range metas {
+		records = append(records, blockCSVRecord(m))
 	}
-	err = csv.WriteAll(t.Lines)
-	if err != nil {
-		return err
+	return csv.NewWriter(w).WriteAll(records)
+}
+
+func blockCSVRecord(m *metadata.Meta) []string {
+	return []string{
+		m.ULID.String(),
+		strconv.FormatInt(m.MinTime, 10),
+		strconv.FormatInt(m.MaxTime, 10),
+		strconv.FormatInt(m.Thanos.Downsample.Resolution, 10),
+		strconv.Itoa(m.Compaction.Level),
+		string(m.Thanos.Source),
+		strconv.FormatUint(m.Stats.NumSeries, 10),
+		strconv.FormatUint(m.Stats.NumSamples, 10),
+		strconv.FormatUint(m.Stats.NumChunks, 10),
+		labels.FromMap(m.Thanos.Labels).String(),
 	}
-	csv.Flush()
-	return nil
+}
+
+// printBlocksJSON prints the meta.json of the given blocks as a JSON array.
+func printBlocksJSON(w io.Writer, metas []*metadata.Meta) error {
+	if metas == nil {
+		metas = []*metadata.Meta{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(metas)
 }
 
 func newTSVWriter(w io.Writer) *csv.Writer {
@@ -886,21 +1400,18 @@ func printTSV(w io.Writer, t Table) error {
 	return nil
 }
 
-func printBlockData(blockMetas []*metadata.Meta, selectorLabels labels.Labels, sortBy []string, printer tablePrinter) error {
+func printBlockData(blockMetas []*metadata.Meta, sortBy []string, output outputType) error {
 	header := inspectColumns
 
 	var lines [][]string
 	p := message.NewPrinter(language.English)
 
 	for _, blockMeta := range blockMetas {
-		if !matchesSelector(blockMeta, selectorLabels) {
-			continue
-		}
 
 		timeRange := time.Duration((blockMeta.MaxTime - blockMeta.MinTime) * int64(time.Millisecond))
 
 		untilDown := "-"
-		if until, err := compact.UntilNextDownsampling(blockMeta); err == nil {
+		if until, err := compact.UntilNextDownsampling(blockMeta, downsample.DefaultLevels); err == nil {
 			untilDown = until.String()
 		}
 		var labels []string
@@ -936,9 +1447,23 @@ func printBlockData(blockMetas []*metadata.Meta, selectorLabels labels.Labels, s
 		sortByColNum = append(sortByColNum, index)
 	}
 
-	t := Table{Header: header, Lines: lines, SortIndices: sortByColNum}
+	// Blocks are sorted by the formatted columns, so the machine-readable formats keep the order of the table.
+	t := Table{Header: header, Lines: lines, SortIndices: sortByColNum, Metas: blockMetas}
 	sort.Sort(t)
-	err := printer(os.Stdout, t)
+
+	var err error
+	switch output {
+	case TABLE:
+		err = printTable(os.Stdout, t)
+	case TSV:
+		err = printTSV(os.Stdout, t)
+	case CSV:
+		err = printBlocksCSV(os.Stdout, t.Metas)
+	case JSON:
+		err = printBlocksJSON(os.Stdout, t.Metas)
+	default:
+		return errors.Errorf("unknown output type %s", output)
+	}
 	if err != nil {
 		return errors.Errorf("unable to write output.")
 	}
@@ -954,17 +1479,6 @@ func getKeysAlphabetically(labels map[string]string) []string {
 	return keys
 }
 
-// matchesSelector checks if blockMeta contains every label from
-// the selector with the correct value.
-func matchesSelector(blockMeta *metadata.Meta, selectorLabels labels.Labels) bool {
-	for _, l := range selectorLabels {
-		if v, ok := blockMeta.Thanos.Labels[l.Name]; !ok || v != l.Value {
-			return false
-		}
-	}
-	return true
-}
-
 // getIndex calculates the index of s in strs.
 func getIndex(strs []string, s string) int {
 	for i, col := range strs {
@@ -979,11 +1493,18 @@ type Table struct {
 	Header      []string
 	Lines       [][]string
 	SortIndices []int
+	// Metas are the blocks of the lines, if any, which are kept in the same order.
+	Metas []*metadata.Meta
 }
 
 func (t Table) Len() int { return len(t.Lines) }
 
-func (t Table) Swap(i, j int) { t.Lines[i], t.Lines[j] = t.Lines[j], t.Lines[i] }
+func (t Table) Swap(i, j int) {
+	t.Lines[i], t.Lines[j] = t.Lines[j], t.Lines[i]
+	if len(t.Metas) > 0 {
+		t.Metas[i], t.Metas[j] = t.Metas[j], t.Metas[i]
+	}
+}
 
 func (t Table) Less(i, j int) bool {
 	for _, index := range t.SortIndices {
@@ -1016,7 +1537,7 @@ func compare(s1, s2 string) bool {
 }
 
 func registerBucketMarkBlock(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
-	cmd := app.Command(component.Mark.String(), "Mark block for deletion or no-compact in a safe way. NOTE: If the compactor is currently running compacting same block, this operation would be potentially a noop.")
+	cmd := app.Command(component.Mark.String(), "Mark block for deletion, no-compact or no-downsample in a safe way, or remove such a marker. NOTE: If the compactor is currently running compacting same block, this operation would be potentially a noop.")
 
 	tbc := &bucketMarkBlockConfig{}
 	tbc.registerBucketMarkBlockFlag(cmd)
@@ -1044,6 +1565,13 @@ func registerBucketMarkBlock(app extkingpin.AppClause, objStoreConfig *extflag.P
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 		g.Add(func() error {
 			for _, id := range ids {
+				if tbc.remove {
+					if err := block.RemoveMark(ctx, logger, bkt, id, tbc.marker, tbc.details); err != nil {
+						return errors.Wrapf(err, "remove %v from %v", tbc.marker, id)
+					}
+					continue
+				}
+
 				switch tbc.marker {
 				case metadata.DeletionMarkFilename:
 					if err := block.MarkForDeletion(ctx, logger, bkt, id, tbc.details, promauto.With(nil).NewCounter(prometheus.CounterOpts{})); err != nil {
@@ -1053,11 +1581,15 @@ func registerBucketMarkBlock(app extkingpin.AppClause, objStoreConfig *extflag.P
 					if err := block.MarkForNoCompact(ctx, logger, bkt, id, metadata.ManualNoCompactReason, tbc.details, promauto.With(nil).NewCounter(prometheus.CounterOpts{})); err != nil {
 						return errors.Wrapf(err, "mark %v for %v", id, tbc.marker)
 					}
+				case metadata.NoDownsampleMarkFilename:
+					if err := block.MarkForNoDownsample(ctx, logger, bkt, id, metadata.ManualNoDownsampleReason, tbc.details, promauto.With(nil).NewCounter(prometheus.CounterOpts{})); err != nil {
+						return errors.Wrapf(err, "mark %v for %v", id, tbc.marker)
+					}
 				default:
 					return errors.Errorf("not supported marker %v", tbc.marker)
 				}
 			}
-			level.Info(logger).Log("msg", "marking done", "marker", tbc.marker, "IDs", strings.Join(tbc.blockIDs, ","))
+			level.Info(logger).Log("msg", "marking done", "marker", tbc.marker, "removed", tbc.remove, "IDs", strings.Join(tbc.blockIDs, ","))
 			return nil
 		}, func(err error) {
 			cancel()
@@ -1085,6 +1617,18 @@ func registerBucketRewrite(app extkingpin.AppClause, objStoreConfig *extflag.Pat
 	toDelete := extflag.RegisterPathOrContent(cmd, "rewrite.to-delete-config", "YAML file that contains []metadata.DeletionRequest that will be applied to blocks", extflag.WithEnvSubstitution())
 	toRelabel := extflag.RegisterPathOrContent(cmd, "rewrite.to-relabel-config", "YAML file that contains relabel configs that will be applied to blocks", extflag.WithEnvSubstitution())
 	provideChangeLog := cmd.Flag("rewrite.add-change-log", "If specified, all modifications are written to new block directory. Disable if latency is to high.").Default("true").Bool()
+	deleteMatchers := cmd.Flag("rewrite.delete-matchers", "PromQL series selector, like '{__name__=\"up\", job=\"foo\"}', of series to delete, in addition to the deletions of --rewrite.to-delete-config. "+
+		"Samples are deleted within --rewrite.min-time and --rewrite.max-time, or entirely if neither is set. Repeated flag.").PlaceHolder("<selector>").Strings()
+	relabelMatchers := cmd.Flag("rewrite.relabel-matchers", "PromQL series selector of series to which the relabel configs of --rewrite.to-relabel-config are applied. "+
+		"If none is specified, all series are relabelled. Repeated flag.").PlaceHolder("<selector>").Strings()
+	minTime := model.TimeOrDuration(cmd.Flag("rewrite.min-time", "Start of the time range of samples which are deleted by --rewrite.delete-matchers or relabelled. "+
+		"Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y."))
+	maxTime := model.TimeOrDuration(cmd.Flag("rewrite.max-time", "End of the time range of samples which are deleted by --rewrite.delete-matchers or relabelled. "+
+		"Option can be a constant time in RFC3339 format or time duration relative to current time, such as -1d or 2h45m. Valid duration units are ms, s, m, h, d, w, y."))
+	applyTombstones := cmd.Flag("rewrite.apply-tombstones", "Delete the series of the tombstones recorded in the bucket by the admin API of Queriers, in addition to the other deletions. "+
+		"Tombstones are only applied to the blocks of their tenant, and tombstones already applied to a block are skipped.").Default("false").Bool()
+	tenantLabel := cmd.Flag("rewrite.tenant-label-name", "External label of blocks with their tenant, to apply the tombstones of --rewrite.apply-tombstones to.").
+		Default(receive.DefaultTenantLabel).String()
 	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
 		confContentYaml, err := objStoreConfig.Content()
 		if err != nil {
@@ -1098,6 +1642,11 @@ func registerBucketRewrite(app extkingpin.AppClause, objStoreConfig *extflag.Pat
 
 		var modifiers []compactv2.Modifier
 
+		interval, hasInterval := flagsInterval(minTime, maxTime)
+		if hasInterval && interval.Mint > interval.Maxt {
+			return errors.New("rewrite min time must not be after max time")
+		}
+
 		relabelYaml, err := toRelabel.Content()
 		if err != nil {
 			return err
@@ -1108,7 +1657,20 @@ func registerBucketRewrite(app extkingpin.AppClause, objStoreConfig *extflag.Pat
 			if err != nil {
 				return err
 			}
-			modifiers = append(modifiers, compactv2.WithRelabelModifier(relabels...))
+			relabelModifier := compactv2.WithRelabelModifier(relabels...)
+			for _, sel := range *relabelMatchers {
+				matchers, err := parser.ParseMetricSelector(sel)
+				if err != nil {
+					return errors.Wrapf(err, "parse relabel matchers %s", sel)
+				}
+				relabelModifier.WithMatchers(matchers)
+			}
+			if hasInterval {
+				relabelModifier.WithInterval(interval)
+			}
+			modifiers = append(modifiers, relabelModifier)
+		} else if len(*relabelMatchers) > 0 {
+			return errors.New("relabel matchers require relabel configuration")
 		}
 
 		deletionsYaml, err := toDelete.Content()
@@ -1120,10 +1682,23 @@ func registerBucketRewrite(app extkingpin.AppClause, objStoreConfig *extflag.Pat
 			if err := yaml.Unmarshal(deletionsYaml, &deletions); err != nil {
 				return err
 			}
+		}
+		for _, sel := range *deleteMatchers {
+			matchers, err := parser.ParseMetricSelector(sel)
+			if err != nil {
+				return errors.Wrapf(err, "parse deletion matchers %s", sel)
+			}
+			deletion := metadata.DeletionRequest{Matchers: matchers}
+			if hasInterval {
+				deletion.Intervals = tombstones.Intervals{interval}
+			}
+			deletions = append(deletions, deletion)
+		}
+		if len(deletions) > 0 {
 			modifiers = append(modifiers, compactv2.WithDeletionModifier(deletions...))
 		}
 
-		if len(modifiers) == 0 {
+		if len(modifiers) == 0 && !*applyTombstones {
 			return errors.New("rewrite configuration should be provided")
 		}
 
@@ -1148,7 +1723,35 @@ func registerBucketRewrite(app extkingpin.AppClause, objStoreConfig *extflag.Pat
 			chunkPool := chunkenc.NewPool()
 			changeLog := compactv2.NewChangeLog(ioutil.Discard)
 			stubCounter := promauto.With(nil).NewCounter(prometheus.CounterOpts{})
+
+			var tombstones []admin.Tombstone
+			if *applyTombstones {
+				var err error
+				if tombstones, err = admin.ReadTombstones(ctx, bkt); err != nil {
+					return err
+				}
+			}
 			for _, id := range ids {
+				blockDeletions, blockModifiers := deletions, modifiers
+				if *applyTombstones {
+					meta, err := block.DownloadMeta(ctx, logger, bkt, id)
+					if err != nil {
+						return errors.Wrapf(err, "download meta of %v", id)
+					}
+					tombstoneDeletions, err := admin.DeletionRequests(tombstones, meta, *tenantLabel)
+					if err != nil {
+						return err
+					}
+					if len(tombstoneDeletions) > 0 {
+						blockDeletions = append(append([]metadata.DeletionRequest(nil), deletions...), tombstoneDeletions...)
+						blockModifiers = append(append([]compactv2.Modifier(nil), modifiers...), compactv2.WithDeletionModifier(tombstoneDeletions...))
+					}
+				}
+				if len(blockModifiers) == 0 {
+					level.Info(logger).Log("msg", "no tombstones to apply to block, skipping", "source", id)
+					continue
+				}
+
 				// Delete series from block & modify.
 				level.Info(logger).Log("msg", "downloading block", "source", id)
 				if err := block.Download(ctx, logger, bkt, id, filepath.Join(tbc.tmpDir, id.String())); err != nil {
@@ -1169,7 +1772,7 @@ func registerBucketRewrite(app extkingpin.AppClause, objStoreConfig *extflag.Pat
 				meta.ULID = newID
 				meta.Thanos.Rewrites = append(meta.Thanos.Rewrites, metadata.Rewrite{
 					Sources:          meta.Compaction.Sources,
-					DeletionsApplied: deletions,
+					DeletionsApplied: blockDeletions,
 					RelabelsApplied:  relabels,
 				})
 				meta.Compaction.Sources = []ulid.ULID{newID}
@@ -1196,19 +1799,26 @@ func registerBucketRewrite(app extkingpin.AppClause, objStoreConfig *extflag.Pat
 				}
 
 				var comp *compactv2.Compactor
+				statsChangeLog := compactv2.NewStatsChangeLog(changeLog)
 				if tbc.dryRun {
-					comp = compactv2.NewDryRun(tbc.tmpDir, logger, changeLog, chunkPool)
+					comp = compactv2.NewDryRun(tbc.tmpDir, logger, statsChangeLog, chunkPool)
 				} else {
 					comp = compactv2.New(tbc.tmpDir, logger, changeLog, chunkPool)
 				}
 
 				level.Info(logger).Log("msg", "starting rewrite for block", "source", id, "new", newID, "toDelete", string(deletionsYaml), "toRelabel", string(relabelYaml))
-				if err := comp.WriteSeries(ctx, []block.Reader{b}, d, p, modifiers...); err != nil {
+				if err := comp.WriteSeries(ctx, []block.Reader{b}, d, p, blockModifiers...); err != nil {
 					return errors.Wrapf(err, "writing series from %v to %v", id, newID)
 				}
 
 				if tbc.dryRun {
-					level.Info(logger).Log("msg", "dry run finished. Changes should be printed to stderr", "Block ID", id)
+					stats, err := statsChangeLog.Stats(b)
+					if err != nil {
+						return errors.Wrapf(err, "summarize changes of %v", id)
+					}
+					level.Info(logger).Log("msg", "dry run finished. Changes should be printed to stderr", "Block ID", id,
+						"series", b.Meta().Stats.NumSeries, "affected_series", stats.AffectedSeries, "deleted_series", stats.DeletedSeries,
+						"relabelled_series", stats.RelabelledSeries, "affected_chunk_bytes", stats.AffectedChunkBytes)
 					continue
 				}
 
@@ -1352,7 +1962,16 @@ func registerBucketRetention(app extkingpin.AppClause, objStoreConfig *extflag.P
 
 		level.Info(logger).Log("msg", "synced blocks done")
 
-		level.Warn(logger).Log("msg", "GLOBAL COMPACTOR SHOULD __NOT__ BE RUNNING ON THE SAME BUCKET")
+		if tbc.dryRun {
+			report, err := compact.RetentionDryRun(ctx, bkt, sy.Metas(), retentionByResolution)
+			if err != nil {
+				return errors.Wrap(err, "retention dry run failed")
+			}
+			level.Info(logger).Log("msg", "dry run, no blocks were marked for deletion")
+			return printRetentionReport(os.Stdout, report)
+		}
+
+		level.Warn(logger).Log("msg", "GLOBAL COMPACTOR SHOULD __NOT__ BE RUNNING ON THE SAME BUCKET")
 
 		if err := compact.ApplyRetentionPolicyByResolution(ctx, logger, bkt, sy.Metas(), retentionByResolution, stubCounter); err != nil {
 			return errors.Wrap(err, "retention failed")
@@ -1360,3 +1979,567 @@ func registerBucketRetention(app extkingpin.AppClause, objStoreConfig *extflag.P
 		return nil
 	})
 }
+
+func registerBucketImport(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command("import", "Import historical data from OpenMetrics text or TSDB blocks, e.g. of a Prometheus snapshot, into the bucket as blocks with the given external labels. "+
+		"Blocks which overlap with existing blocks of the same external labels are not uploaded, unless allowed.")
+
+	tbc := &bucketImportConfig{}
+	tbc.registerBucketImportFlag(cmd)
+
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		lset, err := parseFlagLabels(tbc.labels)
+		if err != nil {
+			return errors.Wrap(err, "parse labels")
+		}
+		if len(lset) == 0 {
+			return errors.New("imported blocks must have external labels")
+		}
+
+		blockDuration := time.Duration(tbc.blockDuration)
+		if !isCompactionRange(blockDuration) {
+			return errors.Errorf("block duration %s must be one of the compaction ranges 2h, 8h, 2d and 14d", tbc.blockDuration)
+		}
+
+		confContentYaml, err := objStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+
+		bkt, err := client.NewBucket(logger, confContentYaml, reg, component.Bucket.String())
+		if err != nil {
+			return err
+		}
+
+		// Dummy actor to immediately kill the group after the run function returns.
+		g.Add(func() error { return nil }, func(error) {})
+
+		defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
+
+		if err := os.RemoveAll(tbc.tmpDir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(tbc.tmpDir, os.ModePerm); err != nil {
+			return err
+		}
+		defer func() {
+			if err := os.RemoveAll(tbc.tmpDir); err != nil {
+				level.Warn(logger).Log("msg", "failed to delete dir", "dir", tbc.tmpDir, "err", err)
+			}
+		}()
+
+		ctx := context.Background()
+		var dirs []string
+		switch tbc.format {
+		case importFormatOpenMetrics:
+			input, err := ioutil.ReadFile(tbc.input)
+			if err != nil {
+				return errors.Wrap(err, "read input")
+			}
+			ids, err := block.CreateBlocksFromOpenMetrics(ctx, logger, input, tbc.tmpDir, blockDuration.Milliseconds())
+			if err != nil {
+				return errors.Wrap(err, "create blocks")
+			}
+			for _, id := range ids {
+				dirs = append(dirs, filepath.Join(tbc.tmpDir, id.String()))
+			}
+		case importFormatTSDB:
+			if dirs, err = linkTSDBBlocks(tbc.input, tbc.tmpDir); err != nil {
+				return err
+			}
+		}
+		if len(dirs) == 0 {
+			level.Info(logger).Log("msg", "no samples to import")
+			return nil
+		}
+
+		_, err = importBlocks(ctx, logger, bkt, dirs, lset, tbc.allowOverlap, tbc.dryRun)
+		return err
+	})
+}
+
+// isCompactionRange returns true if the given duration is one of the ranges into which the compactor compacts blocks.
+func isCompactionRange(d time.Duration) bool {
+	for _, r := range compactions[1:] {
+		if d == r {
+			return true
+		}
+	}
+	return false
+}
+
+// linkTSDBBlocks hard links the blocks in the TSDB directory src into dst and returns their directories in dst.
+// Blocks with tombstones are rejected, as their deleted series would be imported.
+func linkTSDBBlocks(src, dst string) ([]string, error) {
+	fis, err := ioutil.ReadDir(src)
+	if err != nil {
+		return nil, errors.Wrap(err, "read TSDB directory")
+	}
+
+	var dirs []string
+	for _, fi := range fis {
+		if _, ok := block.IsBlockDir(fi.Name()); !ok || !fi.IsDir() {
+			continue
+		}
+		bdir := filepath.Join(src, fi.Name())
+
+		tr, _, err := tombstones.ReadTombstones(bdir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read tombstones of block %s", bdir)
+		}
+		numTombstones := tr.Total()
+		if err := tr.Close(); err != nil {
+			return nil, err
+		}
+		if numTombstones > 0 {
+			return nil, errors.Errorf("block %s has tombstones, which are not imported; clean them up before importing the block", bdir)
+		}
+
+		dir := filepath.Join(dst, fi.Name())
+		if err := block.HardlinkBlock(bdir, dir); err != nil {
+			return nil, errors.Wrapf(err, "link block %s", bdir)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+// importBlocks sets the given external labels on the blocks in dirs, verifies them and uploads them to the bucket,
+// except for the blocks which exist in it already. Nothing is uploaded if any of the blocks overlaps with another one
+// or an existing block in the same compaction group, unless allowOverlap is set. It returns the metas of the blocks
+// which were, or with dryRun would be, uploaded.
+func importBlocks(ctx context.Context, logger log.Logger, bkt objstore.InstrumentedBucket, dirs []string, lset labels.Labels, allowOverlap, dryRun bool) ([]*metadata.Meta, error) {
+	var (
+		metas   = make([]*metadata.Meta, 0, len(dirs))
+		dirByID = make(map[ulid.ULID]string, len(dirs))
+	)
+	for _, bdir := range dirs {
+		m, err := metadata.ReadFromDir(bdir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read meta of %s", bdir)
+		}
+		m, err = metadata.InjectThanos(logger, bdir, metadata.Thanos{
+			Labels:       lset.Map(),
+			Downsample:   m.Thanos.Downsample,
+			Source:       metadata.BucketImportSource,
+			SegmentFiles: block.GetSegmentFiles(bdir),
+		}, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "set external labels of %s", bdir)
+		}
+		if err := block.VerifyIndex(logger, filepath.Join(bdir, block.IndexFilename), m.MinTime, m.MaxTime); err != nil {
+			return nil, errors.Wrapf(err, "verify block %s", m.ULID)
+		}
+		if !fitsCompactionRange(m) {
+			level.Warn(logger).Log("msg", "block is not within any aligned compaction range and won't be compacted further", "id", m.ULID,
+				"mint", m.MinTime, "maxt", m.MaxTime)
+		}
+		metas = append(metas, m)
+		dirByID[m.ULID] = bdir
+	}
+
+	fetcher, err := block.NewMetaFetcher(logger, block.FetcherConcurrency, bkt, "", nil, []block.MetadataFilter{
+		block.NewIgnoreDeletionMarkFilter(logger, bkt, 0, block.FetcherConcurrency),
+	}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create meta fetcher")
+	}
+	existing, _, err := fetcher.Fetch(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch metas")
+	}
+
+	var (
+		toUpload []*metadata.Meta
+		all      []*metadata.Meta
+	)
+	for _, m := range existing {
+		all = append(all, m)
+	}
+	for _, m := range metas {
+		if _, ok := existing[m.ULID]; ok {
+			// Imported before, e.g. by an interrupted import of the same blocks.
+			level.Info(logger).Log("msg", "block already exists in the bucket, skipping", "id", m.ULID)
+			continue
+		}
+		toUpload = append(toUpload, m)
+		all = append(all, m)
+	}
+
+	var overlapping int
+	for _, m := range toUpload {
+		overlaps := block.OverlappingBlocks(all, m)
+		if len(overlaps) == 0 {
+			continue
+		}
+		overlapping++
+		ids := make([]string, 0, len(overlaps))
+		for _, o := range overlaps {
+			ids = append(ids, o.ULID.String())
+		}
+		level.Warn(logger).Log("msg", "block to be imported overlaps with other blocks of the same external labels", "id", m.ULID,
+			"overlapping", strings.Join(ids, ","))
+	}
+	if overlapping > 0 && !allowOverlap {
+		return nil, errors.Errorf("%d of the blocks to be imported overlap with each other or with existing blocks, see the logged blocks", overlapping)
+	}
+
+	for _, m := range toUpload {
+		if dryRun {
+			level.Info(logger).Log("msg", "dry run, block would be uploaded", "id", m.ULID, "mint", m.MinTime, "maxt", m.MaxTime,
+				"series", m.Stats.NumSeries, "samples", m.Stats.NumSamples)
+			continue
+		}
+		if err := block.Upload(ctx, logger, bkt, dirByID[m.ULID], metadata.NoneFunc); err != nil {
+			return nil, errors.Wrapf(err, "upload block %s", m.ULID)
+		}
+		level.Info(logger).Log("msg", "uploaded block", "id", m.ULID, "mint", m.MinTime, "maxt", m.MaxTime,
+			"series", m.Stats.NumSeries, "samples", m.Stats.NumSamples)
+	}
+	return toUpload, nil
+}
+
+// fitsCompactionRange returns true if the time range of the block is within an aligned range of the compactor.
+func fitsCompactionRange(m *metadata.Meta) bool {
+	for _, r := range compactions[1:] {
+		if m.MinTime/r.Milliseconds() == (m.MaxTime-1)/r.Milliseconds() {
+			return true
+		}
+	}
+	return false
+}
+
+func registerBucketExport(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command("export", "Export the samples of selected series and time ranges from raw blocks in the bucket to CSV, without going through the query path.")
+
+	tbc := &bucketExportConfig{}
+	tbc.registerBucketExportFlag(cmd)
+
+	filterConf := &bucketFilterConfig{}
+	filterConf.registerBucketFilterFlag(cmd)
+
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		ids := make(map[ulid.ULID]struct{}, len(tbc.blockIDs))
+		for _, id := range tbc.blockIDs {
+			u, err := ulid.Parse(id)
+			if err != nil {
+				return errors.Wrapf(err, "invalid block ID %s", id)
+			}
+			ids[u] = struct{}{}
+		}
+
+		matcherSets := make([][]*labels.Matcher, 0, len(tbc.selectors))
+		for _, sel := range tbc.selectors {
+			ms, err := parser.ParseMetricSelector(sel)
+			if err != nil {
+				return errors.Wrapf(err, "parse series selector %s", sel)
+			}
+			matcherSets = append(matcherSets, ms)
+		}
+
+		filters, err := filterConf.metaFilters()
+		if err != nil {
+			return errors.Wrap(err, "error parsing selector flag")
+		}
+
+		confContentYaml, err := objStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+
+		bkt, err := client.NewBucket(logger, confContentYaml, reg, component.Bucket.String())
+		if err != nil {
+			return err
+		}
+
+		// Blocks which are replaced by compacted ones are skipped, so that their samples aren't exported twice.
+		filters = append(filters,
+			block.NewIgnoreDeletionMarkFilter(logger, bkt, 0, block.FetcherConcurrency),
+			block.NewDeduplicateFilter(),
+		)
+		fetcher, err := block.NewMetaFetcher(logger, block.FetcherConcurrency, bkt, "", extprom.WrapRegistererWithPrefix(extpromPrefix, reg), filters, nil)
+		if err != nil {
+			return err
+		}
+
+		// Dummy actor to immediately kill the group after the run function returns.
+		g.Add(func() error { return nil }, func(error) {})
+
+		defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
+
+		ctx := context.Background()
+		metas, _, err := fetcher.Fetch(ctx)
+		if err != nil {
+			return err
+		}
+
+		blockMetas := make([]*metadata.Meta, 0, len(metas))
+		for id, m := range metas {
+			if _, ok := ids[id]; len(ids) > 0 && !ok {
+				continue
+			}
+			if m.Thanos.Downsample.Resolution != downsample.ResLevel0 {
+				continue
+			}
+			blockMetas = append(blockMetas, m)
+		}
+		sort.Slice(blockMetas, func(i, j int) bool {
+			if blockMetas[i].MinTime == blockMetas[j].MinTime {
+				return blockMetas[i].ULID.Compare(blockMetas[j].ULID) < 0
+			}
+			return blockMetas[i].MinTime < blockMetas[j].MinTime
+		})
+
+		var out io.Writer = os.Stdout
+		if tbc.output != "" {
+			f, err := os.Create(tbc.output)
+			if err != nil {
+				return errors.Wrap(err, "create output file")
+			}
+			defer runutil.CloseWithLogOnErr(logger, f, "output file")
+			out = f
+		}
+
+		w := csv.NewWriter(out)
+		if err := w.Write(exportCSVHeader); err != nil {
+			return err
+		}
+		mint, maxt := filterConf.minTime.PrometheusTimestamp(), filterConf.maxTime.PrometheusTimestamp()
+		for _, m := range blockMetas {
+			samples, err := exportBlock(ctx, logger, bkt, m, tbc.tmpDir, matcherSets, mint, maxt, w)
+			if err != nil {
+				return errors.Wrapf(err, "export block %s", m.ULID)
+			}
+			level.Info(logger).Log("msg", "exported block", "id", m.ULID, "samples", samples)
+		}
+		w.Flush()
+		return w.Error()
+	})
+}
+
+// exportBlock downloads the given block to a directory in dir and writes its samples within [mint, maxt] of the
+// series matching any of the matcher sets to w. It returns the number of written samples.
+func exportBlock(ctx context.Context, logger log.Logger, bkt objstore.Bucket, m *metadata.Meta, dir string, matcherSets [][]*labels.Matcher, mint, maxt int64, w *csv.Writer) (_ int, err error) {
+	bdir := filepath.Join(dir, m.ULID.String())
+	defer func() {
+		if err := os.RemoveAll(bdir); err != nil {
+			level.Warn(logger).Log("msg", "failed to delete dir", "dir", bdir, "err", err)
+		}
+	}()
+	if err := block.Download(ctx, logger, bkt, m.ULID, bdir); err != nil {
+		return 0, errors.Wrap(err, "download block")
+	}
+
+	b, err := tsdb.OpenBlock(logger, bdir, downsample.NewPool())
+	if err != nil {
+		return 0, errors.Wrap(err, "open block")
+	}
+	defer runutil.CloseWithErrCapture(&err, b, "block")
+
+	q, err := tsdb.NewBlockQuerier(b, mint, maxt)
+	if err != nil {
+		return 0, errors.Wrap(err, "create block querier")
+	}
+	defer runutil.CloseWithErrCapture(&err, q, "block querier")
+
+	sets := make([]storage.SeriesSet, 0, len(matcherSets))
+	for _, ms := range matcherSets {
+		sets = append(sets, q.Select(true, nil, ms...))
+	}
+	ss := storage.NewMergeSeriesSet(sets, storage.ChainedSeriesMerge)
+
+	extLset := labels.FromMap(m.Thanos.Labels)
+	var samples int
+	for ss.Next() {
+		s := ss.At()
+		lb := labels.NewBuilder(s.Labels())
+		for _, l := range extLset {
+			lb.Set(l.Name, l.Value)
+		}
+		series := lb.Labels().String()
+
+		it := s.Iterator()
+		for it.Next() {
+			t, v := it.At()
+			if err := w.Write([]string{series, strconv.FormatInt(t, 10), strconv.FormatFloat(v, 'f', -1, 64)}); err != nil {
+				return 0, err
+			}
+			samples++
+		}
+		if err := it.Err(); err != nil {
+			return 0, errors.Wrapf(err, "iterate samples of series %s", series)
+		}
+	}
+	if err := ss.Err(); err != nil {
+		return 0, errors.Wrap(err, "select series")
+	}
+	return samples, nil
+}
+
+func registerBucketSnapshot(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command("snapshot", "Record the blocks of the bucket with their objects and markers in a manifest, and verify or restore the bucket against it.")
+	registerBucketSnapshotCreate(cmd, objStoreConfig)
+	registerBucketSnapshotVerify(cmd, objStoreConfig)
+	registerBucketSnapshotRestore(cmd, objStoreConfig)
+}
+
+func registerBucketSnapshotCreate(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command("create", "Write a manifest of the blocks in the bucket with the sizes of their objects and their markers. Blocks without meta.json, e.g. blocks being uploaded, are not included.")
+	output := cmd.Flag("output", "Path of the manifest file to write.").Required().String()
+
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		confContentYaml, err := objStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+
+		bkt, err := client.NewBucket(logger, confContentYaml, reg, component.Bucket.String())
+		if err != nil {
+			return err
+		}
+
+		// Dummy actor to immediately kill the group after the run function returns.
+		g.Add(func() error { return nil }, func(error) {})
+
+		defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
+
+		m, err := snapshot.Create(context.Background(), logger, bkt)
+		if err != nil {
+			return errors.Wrap(err, "create snapshot")
+		}
+
+		f, err := os.Create(*output)
+		if err != nil {
+			return errors.Wrap(err, "create manifest file")
+		}
+		defer runutil.CloseWithLogOnErr(logger, f, "manifest file")
+		if err := m.Write(f); err != nil {
+			return errors.Wrap(err, "write manifest")
+		}
+		level.Info(logger).Log("msg", "snapshot created", "blocks", len(m.Blocks), "manifest", *output)
+		return nil
+	})
+}
+
+func registerBucketSnapshotVerify(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command("verify", "Print the differences of the bucket from a manifest. Fails if any block, object or marker of the manifest is missing or changed. Blocks and objects added since are only printed.")
+	manifest := cmd.Flag("manifest", "Path of the manifest file.").Required().String()
+
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		m, err := readSnapshotManifest(logger, *manifest)
+		if err != nil {
+			return err
+		}
+
+		confContentYaml, err := objStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+
+		bkt, err := client.NewBucket(logger, confContentYaml, reg, component.Bucket.String())
+		if err != nil {
+			return err
+		}
+
+		// Dummy actor to immediately kill the group after the run function returns.
+		g.Add(func() error { return nil }, func(error) {})
+
+		defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
+
+		diffs, err := snapshot.Verify(context.Background(), logger, bkt, m)
+		if err != nil {
+			return errors.Wrap(err, "verify snapshot")
+		}
+		if err := printSnapshotDifferences(os.Stdout, diffs); err != nil {
+			return err
+		}
+		for _, d := range diffs {
+			if d.Type != snapshot.BlockAdded && d.Type != snapshot.ObjectAdded {
+				return errors.Errorf("bucket differs from the snapshot of %s", m.Time.Format(time.RFC3339))
+			}
+		}
+		level.Info(logger).Log("msg", "bucket contains the snapshot", "blocks", len(m.Blocks))
+		return nil
+	})
+}
+
+func registerBucketSnapshotRestore(app extkingpin.AppClause, objStoreConfig *extflag.PathOrContent) {
+	cmd := app.Command("restore", "Restore the blocks, objects and markers of a manifest which are missing or changed in the bucket from a backup bucket, and remove markers added since. "+
+		"Blocks and objects added since are kept. Please make sure no compactor is running on the bucket at the same time.")
+	manifest := cmd.Flag("manifest", "Path of the manifest file.").Required().String()
+	dryRun := cmd.Flag("dry-run", "Print the differences which would be restored without changing the bucket.").Default("false").Bool()
+	backupObjStoreConfig := extkingpin.RegisterCommonObjStoreFlags(cmd, "-backup", true, "The object storage to restore blocks and markers from, e.g. a replica of the bucket.")
+
+	cmd.Setup(func(g *run.Group, logger log.Logger, reg *prometheus.Registry, _ opentracing.Tracer, _ <-chan struct{}, _ bool) error {
+		m, err := readSnapshotManifest(logger, *manifest)
+		if err != nil {
+			return err
+		}
+
+		confContentYaml, err := objStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+
+		bkt, err := client.NewBucket(logger, confContentYaml, reg, component.Bucket.String())
+		if err != nil {
+			return err
+		}
+
+		backupconfContentYaml, err := backupObjStoreConfig.Content()
+		if err != nil {
+			return err
+		}
+
+		backupBkt, err := client.NewBucket(logger, backupconfContentYaml, nil, component.Bucket.String())
+		if err != nil {
+			return err
+		}
+
+		// Dummy actor to immediately kill the group after the run function returns.
+		g.Add(func() error { return nil }, func(error) {})
+
+		defer runutil.CloseWithLogOnErr(logger, bkt, "bucket client")
+		defer runutil.CloseWithLogOnErr(logger, backupBkt, "backup bucket client")
+
+		restored, err := snapshot.Restore(context.Background(), logger, bkt, backupBkt, m, *dryRun)
+		if err != nil {
+			return errors.Wrap(err, "restore snapshot")
+		}
+		if *dryRun {
+			level.Info(logger).Log("msg", "dry run, the bucket was not changed")
+		}
+		return printSnapshotDifferences(os.Stdout, restored)
+	})
+}
+
+func readSnapshotManifest(logger log.Logger, file string) (*snapshot.Manifest, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "open manifest file")
+	}
+	defer runutil.CloseWithLogOnErr(logger, f, "manifest file")
+	return snapshot.Read(f)
+}
+
+func printSnapshotDifferences(w io.Writer, diffs []snapshot.Difference) error {
+	t := Table{Header: []string{"ULID", "Object", "Difference"}}
+	for _, d := range diffs {
+		t.Lines = append(t.Lines, []string{d.ID.String(), d.Name, string(d.Type)})
+	}
+	return printTable(w, t)
+}
+
+// flagsInterval returns the time range of the given time flags without defaults, which is unbounded on the sides which
+// aren't set, and whether any side is set.
+func flagsInterval(minTime, maxTime *model.TimeOrDurationValue) (tombstones.Interval, bool) {
+	interval := tombstones.Interval{Mint: math.MinInt64, Maxt: math.MaxInt64}
+	isSet := func(v *model.TimeOrDurationValue) bool { return v.Time != nil || v.Dur != nil }
+	if isSet(minTime) {
+		interval.Mint = minTime.PrometheusTimestamp()
+	}
+	if isSet(maxTime) {
+		interval.Maxt = maxTime.PrometheusTimestamp()
+	}
+	return interval, isSet(minTime) || isSet(maxTime)
+}
@@ -4,11 +4,30 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
 
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/compact"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/rules"
 	"github.com/thanos-io/thanos/pkg/testutil"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
 )
 
 func Test_CheckRules(t *testing.T) {
@@ -21,27 +40,272 @@ func Test_CheckRules(t *testing.T) {
 		{"./testdata/rules-files/invalid-yaml-format.yaml"},
 		{"./testdata/rules-files/invalid-rules-data.yaml"},
 		{"./testdata/rules-files/invalid-unknown-field.yaml"},
+		{"./testdata/rules-files/invalid-source-tenants.yaml"},
 	}
 
 	logger := log.NewNopLogger()
-	testutil.Ok(t, checkRulesFiles(logger, &validFiles))
+	testutil.Ok(t, checkRulesFiles(logger, &validFiles, rules.LintOptions{}, nil))
 
 	for _, fn := range invalidFiles {
-		testutil.NotOk(t, checkRulesFiles(logger, &fn), "expected err for file %s", fn)
+		testutil.NotOk(t, checkRulesFiles(logger, &fn, rules.LintOptions{}, nil), "expected err for file %s", fn)
 	}
+
+	// Findings are written as JSON objects, one per line.
+	var out bytes.Buffer
+	fn := []string{"./testdata/rules-files/invalid-source-tenants.yaml"}
+	testutil.NotOk(t, checkRulesFiles(logger, &fn, rules.LintOptions{}, &out))
+	var finding rules.Finding
+	testutil.Ok(t, json.Unmarshal(out.Bytes(), &finding))
+	testutil.Equals(t, rules.SeverityError, finding.Severity)
+	testutil.Equals(t, "tenants", finding.Group)
 }
 
 func Test_CheckRules_Glob(t *testing.T) {
 	// regex path
 	files := &[]string{"./testdata/rules-files/valid*.yaml"}
 	logger := log.NewNopLogger()
-	testutil.Ok(t, checkRulesFiles(logger, files))
+	testutil.Ok(t, checkRulesFiles(logger, files, rules.LintOptions{}, nil))
 
 	// direct path
 	files = &[]string{"./testdata/rules-files/valid.yaml"}
-	testutil.Ok(t, checkRulesFiles(logger, files))
+	testutil.Ok(t, checkRulesFiles(logger, files, rules.LintOptions{}, nil))
 
 	// invalid path
 	files = &[]string{"./testdata/rules-files/*.yamlaaa"}
-	testutil.NotOk(t, checkRulesFiles(logger, files), "expected err for file %s", files)
+	testutil.NotOk(t, checkRulesFiles(logger, files, rules.LintOptions{}, nil), "expected err for file %s", files)
+}
+
+func Test_PrintBlocks(t *testing.T) {
+	metas := []*metadata.Meta{
+		{
+			BlockMeta: tsdb.BlockMeta{
+				ULID:       ulid.MustNew(1, nil),
+				MinTime:    1000,
+				MaxTime:    7201000,
+				Stats:      tsdb.BlockStats{NumSeries: 2, NumSamples: 1500, NumChunks: 20},
+				Compaction: tsdb.BlockMetaCompaction{Level: 2},
+			},
+			Thanos: metadata.Thanos{
+				Labels:     map[string]string{"replica": "a", "cluster": "eu, west"},
+				Downsample: metadata.ThanosDownsample{Resolution: 300000},
+				Source:     metadata.CompactorSource,
+			},
+		},
+		{BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(2, nil)}},
+	}
+
+	var b bytes.Buffer
+	testutil.Ok(t, printBlocksCSV(&b, metas))
+	testutil.Equals(t, `ulid,min_time,max_time,resolution,compaction_level,source,num_series,num_samples,num_chunks,labels
+00000000010000000000000000,1000,7201000,300000,2,compactor,2,1500,20,"{cluster=""eu, west"", replica=""a""}"
+00000000020000000000000000,0,0,0,0,,0,0,0,{}
+`, b.String())
+
+	b.Reset()
+	testutil.Ok(t, printBlocksJSON(&b, metas))
+	var got []*metadata.Meta
+	testutil.Ok(t, json.Unmarshal(b.Bytes(), &got))
+	testutil.Equals(t, metas, got)
+
+	b.Reset()
+	testutil.Ok(t, printBlocksJSON(&b, nil))
+	testutil.Equals(t, "[]\n", b.String())
+}
+
+func Test_BucketFilterConfig_Matchers(t *testing.T) {
+	conf := bucketFilterConfig{selectors: []string{`tenant="a"`, `cluster=~"eu-.*", replica!="1"`}}
+	matchers, err := conf.matchers()
+	testutil.Ok(t, err)
+	testutil.Equals(t, []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, "tenant", "a"),
+		labels.MustNewMatcher(labels.MatchRegexp, "cluster", "eu-.*"),
+		labels.MustNewMatcher(labels.MatchNotEqual, "replica", "1"),
+	}, matchers)
+
+	filters, err := conf.metaFilters()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(filters))
+
+	filters, err = (&bucketFilterConfig{}).metaFilters()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(filters))
+
+	_, err = (&bucketFilterConfig{selectors: []string{`tenant=a`}}).matchers()
+	testutil.NotOk(t, err)
+}
+
+func Test_CleanupReport(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	marked := ulid.MustNew(2, nil)
+	testutil.Ok(t, bkt.Upload(ctx, path.Join(marked.String(), "chunks", "000001"), bytes.NewReader([]byte{0, 1, 2})))
+	testutil.Ok(t, bkt.Upload(ctx, path.Join(marked.String(), metadata.DeletionMarkFilename), bytes.NewReader([]byte("{}"))))
+
+	partial := []compact.PartialUpload{{ID: ulid.MustNew(1, nil), Err: errors.New("not found"), Objects: 1, Size: 10}}
+	tbl, err := cleanupReport(ctx, bkt, partial, []ulid.ULID{marked})
+	testutil.Ok(t, err)
+	testutil.Equals(t, [][]string{
+		{"00000000010000000000000000", "aborted partial upload: not found", "1", "10"},
+		{"00000000020000000000000000", "marked for deletion", "2", "5"},
+		{"total", "2 blocks", "3", "15"},
+	}, tbl.Lines)
+}
+
+func Test_AnalyzeBlock(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir("", "test-analyze")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(tmpDir)) }()
+
+	id, err := e2eutil.CreateBlock(ctx, tmpDir, []labels.Labels{
+		labels.FromStrings("__name__", "up", "job", "a"),
+		labels.FromStrings("__name__", "up", "job", "b"),
+		labels.FromStrings("__name__", "requests_total", "job", "a"),
+	}, 10, 0, 1000, labels.FromStrings("replica", "a"), 0, metadata.NoneFunc)
+	testutil.Ok(t, err)
+
+	bkt := objstore.NewInMemBucket()
+	testutil.Ok(t, block.Upload(ctx, logger, bkt, filepath.Join(tmpDir, id.String()), metadata.NoneFunc))
+
+	stats, err := analyzeBlock(ctx, logger, bkt, id, filepath.Join(tmpDir, "analyze"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(3), stats.Series)
+
+	var b bytes.Buffer
+	testutil.Ok(t, printCardinalityStats(&b, "Block", stats, 1))
+	testutil.Equals(t, `Block: 3 series, 2 label names, 2 metrics
+
+| LABEL NAME | VALUES |
+|------------|--------|
+| __name__   | 2      |
+
+| METRIC | SERIES |
+|--------|--------|
+| up     | 2      |
+
+`, b.String())
+
+	// The downloaded index is removed.
+	_, err = os.Stat(filepath.Join(tmpDir, "analyze", id.String()))
+	testutil.Assert(t, os.IsNotExist(err), "index must be removed after analysis")
+}
+
+func Test_ImportBlocks(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir("", "test-import")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(tmpDir)) }()
+
+	series := []labels.Labels{
+		labels.FromStrings("__name__", "up", "job", "a"),
+		labels.FromStrings("__name__", "up", "job", "b"),
+	}
+	srcDir := filepath.Join(tmpDir, "src")
+	id, err := e2eutil.CreateBlock(ctx, srcDir, series, 10, 0, 1000, nil, 0, metadata.NoneFunc)
+	testutil.Ok(t, err)
+
+	inMem := objstore.NewInMemBucket()
+	bkt := objstore.WithNoopInstr(inMem)
+	lset := labels.FromStrings("cluster", "old")
+
+	dirs, err := linkTSDBBlocks(srcDir, filepath.Join(tmpDir, "import-1"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(dirs))
+	metas, err := importBlocks(ctx, logger, bkt, dirs, lset, false, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(metas))
+
+	m, err := block.DownloadMeta(ctx, logger, bkt, id)
+	testutil.Ok(t, err)
+	testutil.Equals(t, lset.Map(), m.Thanos.Labels)
+	testutil.Equals(t, metadata.BucketImportSource, m.Thanos.Source)
+
+	// The source block is not changed.
+	srcMeta, err := metadata.ReadFromDir(filepath.Join(srcDir, id.String()))
+	testutil.Ok(t, err)
+	testutil.Equals(t, metadata.TestSource, srcMeta.Thanos.Source)
+
+	// Blocks which were imported already are skipped.
+	objects := len(inMem.Objects())
+	dirs, err = linkTSDBBlocks(srcDir, filepath.Join(tmpDir, "import-2"))
+	testutil.Ok(t, err)
+	metas, err = importBlocks(ctx, logger, bkt, dirs, lset, false, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(metas))
+	testutil.Equals(t, objects, len(inMem.Objects()))
+
+	// Blocks which overlap with existing blocks of the same external labels are only imported if allowed.
+	_, err = e2eutil.CreateBlock(ctx, filepath.Join(tmpDir, "src-overlapping"), series, 10, 500, 1500, nil, 0, metadata.NoneFunc)
+	testutil.Ok(t, err)
+	dirs, err = linkTSDBBlocks(filepath.Join(tmpDir, "src-overlapping"), filepath.Join(tmpDir, "import-3"))
+	testutil.Ok(t, err)
+	_, err = importBlocks(ctx, logger, bkt, dirs, lset, false, false)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, objects, len(inMem.Objects()))
+
+	metas, err = importBlocks(ctx, logger, bkt, dirs, lset, true, true)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(metas))
+	testutil.Equals(t, objects, len(inMem.Objects()))
+
+	metas, err = importBlocks(ctx, logger, bkt, dirs, labels.FromStrings("cluster", "new"), false, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(metas))
+	testutil.Assert(t, len(inMem.Objects()) > objects, "block with other external labels must be uploaded")
+}
+
+func Test_ExportBlock(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir("", "test-export")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(tmpDir)) }()
+
+	id, err := e2eutil.CreateBlock(ctx, tmpDir, []labels.Labels{
+		labels.FromStrings("__name__", "up", "job", "a"),
+		labels.FromStrings("__name__", "up", "job", "b"),
+		labels.FromStrings("__name__", "requests_total", "job", "a"),
+	}, 10, 0, 1000, labels.FromStrings("replica", "a"), 0, metadata.NoneFunc)
+	testutil.Ok(t, err)
+
+	bkt := objstore.NewInMemBucket()
+	testutil.Ok(t, block.Upload(ctx, logger, bkt, filepath.Join(tmpDir, id.String()), metadata.NoneFunc))
+	m, err := block.DownloadMeta(ctx, logger, bkt, id)
+	testutil.Ok(t, err)
+
+	// Series matching multiple selectors are exported once, with samples in the time range only.
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	samples, err := exportBlock(ctx, logger, bkt, &m, filepath.Join(tmpDir, "export"), [][]*labels.Matcher{
+		{labels.MustNewMatcher(labels.MatchEqual, "__name__", "up")},
+		{labels.MustNewMatcher(labels.MatchEqual, "job", "b")},
+	}, 100, 400, w)
+	testutil.Ok(t, err)
+	w.Flush()
+	testutil.Ok(t, w.Error())
+	testutil.Equals(t, 6, samples)
+
+	records, err := csv.NewReader(&b).ReadAll()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 6, len(records))
+	for i, r := range records {
+		series := `{__name__="up", job="a", replica="a"}`
+		if i >= 3 {
+			series = `{__name__="up", job="b", replica="a"}`
+		}
+		testutil.Equals(t, series, r[0])
+		ts, err := strconv.ParseInt(r[1], 10, 64)
+		testutil.Ok(t, err)
+		testutil.Assert(t, ts >= 100 && ts <= 400, "sample at %d is outside of the time range", ts)
+	}
+
+	// The downloaded block is removed.
+	_, err = os.Stat(filepath.Join(tmpDir, "export", id.String()))
+	testutil.Assert(t, os.IsNotExist(err), "block must be removed after export")
 }
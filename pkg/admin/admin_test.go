@@ -0,0 +1,239 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package admin
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/thanos-io/thanos/pkg/admin/adminpb"
+	blockmeta "github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/runutil"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
+)
+
+func TestMultiTSDB_DeleteSeries(t *testing.T) {
+	dbs := map[string]*tsdb.DB{}
+	for _, tenant := range []string{"a", "b"} {
+		db, err := e2eutil.NewTSDB()
+		testutil.Ok(t, err)
+		defer func() {
+			testutil.Ok(t, db.Close())
+			testutil.Ok(t, os.RemoveAll(db.Dir()))
+		}()
+
+		app := db.Appender(context.Background())
+		for _, lset := range []labels.Labels{
+			labels.FromStrings("__name__", "up", "job", "a"),
+			labels.FromStrings("__name__", "up", "job", "b"),
+		} {
+			for ts := int64(1000); ts <= 3000; ts += 1000 {
+				_, err := app.Append(0, lset, ts, 1)
+				testutil.Ok(t, err)
+			}
+		}
+		testutil.Ok(t, app.Commit())
+		dbs[tenant] = db
+	}
+
+	srv := NewMultiTSDB(func() map[string]*tsdb.DB { return dbs }, "secret")
+	req := &adminpb.DeleteSeriesRequest{
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "a"}},
+		MinTime:  2000,
+		MaxTime:  3000,
+		Tenant:   "a",
+	}
+	var err error
+
+	// Requests without the token are rejected.
+	for _, md := range []metadata.MD{nil, metadata.Pairs("authorization", "Bearer wrong"), metadata.Pairs("authorization", "secret")} {
+		_, err = srv.DeleteSeries(metadata.NewIncomingContext(context.Background(), md), req)
+		testutil.Equals(t, codes.Unauthenticated, status.Code(err))
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	_, err = srv.DeleteSeries(ctx, &adminpb.DeleteSeriesRequest{Tenant: "a"})
+	testutil.Equals(t, codes.InvalidArgument, status.Code(err))
+	_, err = srv.DeleteSeries(ctx, &adminpb.DeleteSeriesRequest{Matchers: req.Matchers})
+	testutil.Equals(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = srv.DeleteSeries(ctx, req)
+	testutil.Ok(t, err)
+
+	// Only the series of the tenant of the request are deleted.
+	testutil.Equals(t, map[string][]int64{
+		`{__name__="up", job="a"}`: {1000},
+		`{__name__="up", job="b"}`: {1000, 2000, 3000},
+	}, samples(t, dbs["a"]))
+	testutil.Equals(t, map[string][]int64{
+		`{__name__="up", job="a"}`: {1000, 2000, 3000},
+		`{__name__="up", job="b"}`: {1000, 2000, 3000},
+	}, samples(t, dbs["b"]))
+
+	// Tenants without TSDB have nothing to delete.
+	_, err = srv.DeleteSeries(ctx, &adminpb.DeleteSeriesRequest{Matchers: req.Matchers, Tenant: "c"})
+	testutil.Ok(t, err)
+}
+
+func samples(t *testing.T, db *tsdb.DB) map[string][]int64 {
+	q, err := db.Querier(context.Background(), 0, 10000)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, q.Close()) }()
+
+	res := map[string][]int64{}
+	ss := q.Select(false, nil, labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"))
+	for ss.Next() {
+		var ts []int64
+		it := ss.At().Iterator()
+		for it.Next() {
+			tt, _ := it.At()
+			ts = append(ts, tt)
+		}
+		testutil.Ok(t, it.Err())
+		res[ss.At().Labels().String()] = ts
+	}
+	testutil.Ok(t, ss.Err())
+	return res
+}
+
+type testAdminClient struct {
+	mtx   sync.Mutex
+	reqs  []*adminpb.DeleteSeriesRequest
+	auths []string
+	err   error
+}
+
+func (c *testAdminClient) DeleteSeries(ctx context.Context, r *adminpb.DeleteSeriesRequest, _ ...grpc.CallOption) (*adminpb.DeleteSeriesResponse, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.err != nil {
+		return nil, c.err
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	c.reqs = append(c.reqs, r)
+	c.auths = append(c.auths, md.Get("authorization")...)
+	return &adminpb.DeleteSeriesResponse{}, nil
+}
+
+func TestDeleter(t *testing.T) {
+	ok, failing := &testAdminClient{}, &testAdminClient{err: errors.New("unavailable")}
+	endpoints := []*adminpb.AdminEndpoint{{AdminClient: ok, Name: "receive-0:10901"}}
+	bkt := objstore.NewInMemBucket()
+	d := NewDeleter(log.NewNopLogger(), func() []*adminpb.AdminEndpoint { return endpoints }, bkt, time.Minute, "secret")
+
+	_, err := d.Delete("team-a", nil, 0, 1000)
+	testutil.NotOk(t, err)
+
+	selectors := [][]*labels.Matcher{
+		{labels.MustNewMatcher(labels.MatchEqual, "job", "a")},
+		{labels.MustNewMatcher(labels.MatchRegexp, "job", "b.*"), labels.MustNewMatcher(labels.MatchNotEqual, "env", "prod")},
+	}
+	_, err = d.Delete("", selectors, 0, 1000)
+	testutil.NotOk(t, err)
+
+	job, err := d.Delete("team-a", selectors, 0, 1000)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "team-a", job.Tenant)
+	testutil.Equals(t, []string{`{job="a"}`, `{job=~"b.*",env!="prod"}`}, job.Matchers)
+	testutil.Equals(t, 1, job.Endpoints)
+
+	job = waitForJob(t, d, job.ID)
+	testutil.Equals(t, JobSucceeded, job.State)
+	testutil.Equals(t, 1, job.EndpointsDone)
+	testutil.Assert(t, job.TombstoneRecorded, "expected the tombstone to be recorded")
+	testutil.Equals(t, 2, len(ok.reqs))
+	testutil.Equals(t, []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "a"}}, ok.reqs[0].Matchers)
+	testutil.Equals(t, int64(1000), ok.reqs[1].MaxTime)
+	testutil.Equals(t, "team-a", ok.reqs[1].Tenant)
+	testutil.Equals(t, []string{"Bearer secret", "Bearer secret"}, ok.auths)
+
+	tombstones, err := ReadTombstones(context.Background(), bkt)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(tombstones))
+	testutil.Equals(t, job.ID, tombstones[0].ID.String())
+	testutil.Equals(t, "team-a", tombstones[0].Tenant)
+	testutil.Equals(t, job.Matchers, tombstones[0].Matchers)
+	testutil.Equals(t, int64(1000), tombstones[0].MaxTime)
+
+	// A failing endpoint fails the job, but the deletion is still done on the others.
+	endpoints = append(endpoints, &adminpb.AdminEndpoint{AdminClient: failing, Name: "receive-1:10901"})
+	job, err = d.Delete("team-a", selectors[:1], 0, 1000)
+	testutil.Ok(t, err)
+	job = waitForJob(t, d, job.ID)
+	testutil.Equals(t, JobFailed, job.State)
+	testutil.Equals(t, 2, job.EndpointsDone)
+	testutil.Equals(t, []string{"delete series on endpoint receive-1:10901: unavailable"}, job.Errors)
+	testutil.Equals(t, 3, len(ok.reqs))
+	testutil.Equals(t, 2, len(d.Jobs()))
+
+	_, found := d.Job("unknown")
+	testutil.Assert(t, !found, "expected unknown job to not be found")
+
+	// Without endpoints and bucket there is nothing to delete from.
+	endpoints = nil
+	_, err = NewDeleter(log.NewNopLogger(), func() []*adminpb.AdminEndpoint { return endpoints }, nil, time.Minute, "").Delete("team-a", selectors, 0, 1000)
+	testutil.NotOk(t, err)
+}
+
+func TestDeletionRequests(t *testing.T) {
+	ts := []Tombstone{
+		{ID: ulid.MustNew(1, nil), Tenant: "a", Matchers: []string{`{job="a"}`, `{job="b"}`}, MinTime: 0, MaxTime: 1000},
+		{ID: ulid.MustNew(2, nil), Tenant: "b", Matchers: []string{`{job="c"}`}, MinTime: 0, MaxTime: 1000},
+		{ID: ulid.MustNew(3, nil), Tenant: "a", Matchers: []string{`{job="d"}`}, MinTime: 2000, MaxTime: 3000},
+	}
+	var meta blockmeta.Meta
+	meta.Thanos.Labels = map[string]string{"tenant_id": "a"}
+
+	reqs, err := DeletionRequests(ts, meta, "tenant_id")
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(reqs))
+	testutil.Equals(t, `{job="b"}`, selectorString(reqs[1].Matchers))
+	testutil.Equals(t, ts[0].ID.String(), reqs[1].RequestID)
+	testutil.Equals(t, tombstones.Intervals{{Mint: 2000, Maxt: 3000}}, reqs[2].Intervals)
+
+	// Tombstones applied by an earlier rewrite are skipped.
+	meta.Thanos.Rewrites = []blockmeta.Rewrite{{DeletionsApplied: reqs[:2]}}
+	reqs, err = DeletionRequests(ts, meta, "tenant_id")
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(reqs))
+	testutil.Equals(t, ts[2].ID.String(), reqs[0].RequestID)
+
+	// Blocks of other tenants are not affected.
+	meta.Thanos.Labels = map[string]string{"tenant_id": "c"}
+	reqs, err = DeletionRequests(ts, meta, "tenant_id")
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(reqs))
+}
+
+func waitForJob(t *testing.T, d *Deleter, id string) DeletionJob {
+	var job DeletionJob
+	testutil.Ok(t, runutil.Retry(10*time.Millisecond, context.Background().Done(), func() error {
+		var ok bool
+		job, ok = d.Job(id)
+		testutil.Assert(t, ok, "expected job %s to exist", id)
+		if job.State == JobRunning {
+			return errors.New("job still running")
+		}
+		return nil
+	}))
+	return job
+}
@@ -0,0 +1,17 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package adminpb
+
+// AdminEndpoint wraps the AdminClient of an endpoint exposing the Admin API.
+type AdminEndpoint struct {
+	AdminClient
+
+	// Name identifies the endpoint, e.g. by its address, in errors.
+	Name string
+}
+
+// String returns the name of the endpoint.
+func (e *AdminEndpoint) String() string {
+	return e.Name
+}
@@ -0,0 +1,251 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package admin
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/thanos-io/thanos/pkg/admin/adminpb"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// maxFinishedJobs is the number of finished deletion jobs which are kept to be polled.
+const maxFinishedJobs = 100
+
+// JobState is the state of a deletion job.
+type JobState string
+
+const (
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+)
+
+// DeletionJob is the progress of the deletion of series.
+type DeletionJob struct {
+	ID       string   `json:"id"`
+	Tenant   string   `json:"tenant"`
+	Matchers []string `json:"matchers"`
+	MinTime  int64    `json:"minTime"`
+	MaxTime  int64    `json:"maxTime"`
+	State    JobState `json:"state"`
+	// Endpoints is the number of endpoints exposing the Admin API, i.e. receivers, the deletion is fanned out to.
+	Endpoints int `json:"endpoints"`
+	// EndpointsDone is the number of endpoints the deletion finished on, successfully or not.
+	EndpointsDone int `json:"endpointsDone"`
+	// TombstoneRecorded is true once the tombstone is written to the bucket.
+	TombstoneRecorded bool      `json:"tombstoneRecorded"`
+	Errors            []string  `json:"errors,omitempty"`
+	StartTime         time.Time `json:"startTime"`
+	EndTime           time.Time `json:"endTime"`
+}
+
+// Deleter runs deletion jobs, which delete the samples of series of a tenant from its TSDBs on all endpoints exposing
+// the Admin API and record a tombstone for the blocks in the bucket.
+type Deleter struct {
+	logger    log.Logger
+	endpoints func() []*adminpb.AdminEndpoint
+	bkt       objstore.Bucket
+	timeout   time.Duration
+	token     string
+
+	mtx  sync.Mutex
+	jobs map[string]*DeletionJob
+}
+
+// NewDeleter returns a Deleter fanning out deletions to the given endpoints, authenticated with the token if it isn't
+// empty. The bucket is optional, without it no tombstones are recorded.
+func NewDeleter(logger log.Logger, endpoints func() []*adminpb.AdminEndpoint, bkt objstore.Bucket, timeout time.Duration, token string) *Deleter {
+	return &Deleter{
+		logger:    logger,
+		endpoints: endpoints,
+		bkt:       bkt,
+		timeout:   timeout,
+		token:     token,
+		jobs:      map[string]*DeletionJob{},
+	}
+}
+
+// Delete starts a job deleting the samples of the series of the tenant matching any of the selectors in the time range
+// and returns it. The progress of the job can be polled with Job.
+func (d *Deleter) Delete(tenant string, selectors [][]*labels.Matcher, mint, maxt int64) (DeletionJob, error) {
+	if tenant == "" {
+		return DeletionJob{}, errors.New("no tenant specified")
+	}
+	if len(selectors) == 0 {
+		return DeletionJob{}, errors.New("no selectors specified")
+	}
+	reqs := make([]*adminpb.DeleteSeriesRequest, 0, len(selectors))
+	matchers := make([]string, 0, len(selectors))
+	for _, sel := range selectors {
+		ms, err := storepb.PromMatchersToMatchers(sel...)
+		if err != nil {
+			return DeletionJob{}, err
+		}
+		reqs = append(reqs, &adminpb.DeleteSeriesRequest{Matchers: ms, MinTime: mint, MaxTime: maxt, Tenant: tenant})
+		matchers = append(matchers, selectorString(sel))
+	}
+
+	endpoints := d.endpoints()
+	if len(endpoints) == 0 && d.bkt == nil {
+		return DeletionJob{}, errors.New("no endpoints exposing the Admin API and no bucket to delete series from")
+	}
+
+	id := ulid.MustNew(ulid.Now(), rand.New(rand.NewSource(time.Now().UnixNano())))
+	job := &DeletionJob{
+		ID:        id.String(),
+		Tenant:    tenant,
+		Matchers:  matchers,
+		MinTime:   mint,
+		MaxTime:   maxt,
+		State:     JobRunning,
+		Endpoints: len(endpoints),
+		StartTime: time.Now(),
+	}
+
+	d.mtx.Lock()
+	d.jobs[job.ID] = job
+	d.cleanUpJobs()
+	res := copyJob(job)
+	d.mtx.Unlock()
+
+	level.Info(d.logger).Log("msg", "starting deletion of series", "job", job.ID, "tenant", tenant, "matchers", strings.Join(matchers, " "), "mint", mint, "maxt", maxt)
+	go d.run(job, id, reqs, endpoints)
+	return res, nil
+}
+
+func (d *Deleter) run(job *DeletionJob, id ulid.ULID, reqs []*adminpb.DeleteSeriesRequest, endpoints []*adminpb.AdminEndpoint) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	if d.bkt != nil {
+		err := WriteTombstone(ctx, d.bkt, Tombstone{
+			ID:           id,
+			Tenant:       job.Tenant,
+			Matchers:     job.Matchers,
+			MinTime:      job.MinTime,
+			MaxTime:      job.MaxTime,
+			CreationTime: job.StartTime.Unix(),
+			Version:      TombstoneVersion1,
+		})
+		d.update(job, func() {
+			if err != nil {
+				job.Errors = append(job.Errors, errors.Wrap(err, "record tombstone").Error())
+				return
+			}
+			job.TombstoneRecorded = true
+		})
+	}
+
+	if d.token != "" {
+		ctx = WithToken(ctx, d.token)
+	}
+
+	var wg sync.WaitGroup
+	for _, e := range endpoints {
+		wg.Add(1)
+		go func(e *adminpb.AdminEndpoint) {
+			defer wg.Done()
+
+			var err error
+			for _, r := range reqs {
+				if _, err = e.DeleteSeries(ctx, r); err != nil {
+					err = errors.Wrapf(err, "delete series on endpoint %s", e.Name)
+					break
+				}
+			}
+			d.update(job, func() {
+				job.EndpointsDone++
+				if err != nil {
+					job.Errors = append(job.Errors, err.Error())
+				}
+			})
+		}(e)
+	}
+	wg.Wait()
+
+	d.update(job, func() {
+		job.EndTime = time.Now()
+		job.State = JobSucceeded
+		if len(job.Errors) > 0 {
+			job.State = JobFailed
+		}
+		level.Info(d.logger).Log("msg", "finished deletion of series", "job", job.ID, "state", job.State, "errors", strings.Join(job.Errors, "; "))
+	})
+}
+
+func (d *Deleter) update(job *DeletionJob, f func()) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	f()
+}
+
+// Job returns the deletion job with the given ID.
+func (d *Deleter) Job(id string) (DeletionJob, bool) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	job, ok := d.jobs[id]
+	if !ok {
+		return DeletionJob{}, false
+	}
+	return copyJob(job), true
+}
+
+// Jobs returns all deletion jobs, sorted by ID.
+func (d *Deleter) Jobs() []DeletionJob {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	jobs := make([]DeletionJob, 0, len(d.jobs))
+	for _, job := range d.jobs {
+		jobs = append(jobs, copyJob(job))
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+// cleanUpJobs removes the oldest finished jobs over maxFinishedJobs. It has to be called with the mutex held.
+func (d *Deleter) cleanUpJobs() {
+	var finished []*DeletionJob
+	for _, job := range d.jobs {
+		if job.State != JobRunning {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].ID < finished[j].ID })
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(d.jobs, job.ID)
+	}
+}
+
+func copyJob(job *DeletionJob) DeletionJob {
+	res := *job
+	res.Errors = append([]string(nil), job.Errors...)
+	return res
+}
+
+// selectorString returns the series selector of the matchers, e.g. {job="a",instance=~"b.*"}.
+func selectorString(ms []*labels.Matcher) string {
+	s := make([]string, 0, len(ms))
+	for _, m := range ms {
+		s = append(s, m.String())
+	}
+	return "{" + strings.Join(s, ",") + "}"
+}
@@ -0,0 +1,133 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+const (
+	// TombstonesDir is the directory of the bucket with the tombstones of deleted series.
+	TombstonesDir = "tombstones"
+	// TombstoneVersion1 is the version of the tombstone format.
+	TombstoneVersion1 = 1
+)
+
+// Tombstone records the deletion of series from the blocks in the bucket. The samples of the series matching any of
+// the matchers in the time range are deleted once the tombstone is applied to the blocks, see DeletionRequests.
+type Tombstone struct {
+	ID ulid.ULID `json:"id"`
+	// Tenant is the tenant of the deleted series. Only the blocks of the tenant are affected.
+	Tenant string `json:"tenant"`
+	// Matchers are the series selectors of the deleted series, e.g. {job="a"}.
+	Matchers []string `json:"matchers"`
+	// MinTime and MaxTime are the inclusive time range in milliseconds of the deleted samples.
+	MinTime int64 `json:"minTime"`
+	MaxTime int64 `json:"maxTime"`
+	// CreationTime is the unix timestamp in seconds of the deletion.
+	CreationTime int64 `json:"creationTime"`
+	Version      int   `json:"version"`
+}
+
+// WriteTombstone uploads the tombstone to the bucket.
+func WriteTombstone(ctx context.Context, bkt objstore.Bucket, t Tombstone) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return errors.Wrap(err, "json encode tombstone")
+	}
+	name := path.Join(TombstonesDir, t.ID.String()+".json")
+	if err := bkt.Upload(ctx, name, bytes.NewReader(b)); err != nil {
+		return errors.Wrapf(err, "upload file %s to bucket", name)
+	}
+	return nil
+}
+
+// ReadTombstones returns all tombstones of the bucket, sorted by ID.
+func ReadTombstones(ctx context.Context, bkt objstore.BucketReader) ([]Tombstone, error) {
+	var tombstones []Tombstone
+	err := bkt.Iter(ctx, TombstonesDir, func(name string) error {
+		if !strings.HasSuffix(name, ".json") {
+			return nil
+		}
+		t, err := readTombstone(ctx, bkt, name)
+		if err != nil {
+			return err
+		}
+		tombstones = append(tombstones, t)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list tombstones")
+	}
+	sort.Slice(tombstones, func(i, j int) bool { return tombstones[i].ID.Compare(tombstones[j].ID) < 0 })
+	return tombstones, nil
+}
+
+func readTombstone(ctx context.Context, bkt objstore.BucketReader, name string) (t Tombstone, err error) {
+	r, err := bkt.Get(ctx, name)
+	if err != nil {
+		return Tombstone{}, errors.Wrapf(err, "get file %s", name)
+	}
+	defer runutil.CloseWithErrCapture(&err, r, "close tombstone reader")
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Tombstone{}, errors.Wrapf(err, "read file %s", name)
+	}
+	if err := json.Unmarshal(b, &t); err != nil {
+		return Tombstone{}, errors.Wrapf(err, "unmarshal tombstone %s", name)
+	}
+	if t.Version != TombstoneVersion1 {
+		return Tombstone{}, errors.Errorf("unexpected tombstone version %d of %s", t.Version, name)
+	}
+	return t, nil
+}
+
+// DeletionRequests returns the deletion requests of the tombstones which apply to the block, i.e. whose tenant is the
+// value of the tenant label of its external labels, and which weren't applied to it by an earlier rewrite yet.
+func DeletionRequests(ts []Tombstone, meta metadata.Meta, tenantLabel string) ([]metadata.DeletionRequest, error) {
+	applied := map[string]struct{}{}
+	for _, rw := range meta.Thanos.Rewrites {
+		for _, d := range rw.DeletionsApplied {
+			applied[d.RequestID] = struct{}{}
+		}
+	}
+
+	var res []metadata.DeletionRequest
+	for _, t := range ts {
+		if t.Tenant != meta.Thanos.Labels[tenantLabel] {
+			continue
+		}
+		if _, ok := applied[t.ID.String()]; ok {
+			continue
+		}
+		for _, sel := range t.Matchers {
+			matchers, err := parser.ParseMetricSelector(sel)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse selector %s of tombstone %s", sel, t.ID)
+			}
+			res = append(res, metadata.DeletionRequest{
+				Matchers:  matchers,
+				Intervals: tombstones.Intervals{{Mint: t.MinTime, Maxt: t.MaxTime}},
+				RequestID: t.ID.String(),
+			})
+		}
+	}
+	return res, nil
+}
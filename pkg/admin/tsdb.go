@@ -0,0 +1,90 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package admin implements the administrative operations on the TSDBs of receivers, like the deletion of series, and
+// the deletion jobs of the querier which fan them out and record them as tombstones in the bucket.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/tsdb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/thanos-io/thanos/pkg/admin/adminpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// authorizationKey is the gRPC metadata key of the bearer token of admin requests.
+const authorizationKey = "authorization"
+
+// WithToken returns a context whose outgoing gRPC requests send the token as bearer token, to authenticate against
+// admin servers.
+func WithToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, authorizationKey, "Bearer "+token)
+}
+
+// MultiTSDB implements adminpb.AdminServer that allows to delete series from the TSDBs of a MultiTSDB instance.
+type MultiTSDB struct {
+	dbs   func() map[string]*tsdb.DB
+	token string
+}
+
+// NewMultiTSDB creates new admin.MultiTSDB. If the token is not empty, requests have to send it as bearer token, see
+// WithToken.
+func NewMultiTSDB(dbs func() map[string]*tsdb.DB, token string) *MultiTSDB {
+	return &MultiTSDB{dbs: dbs, token: token}
+}
+
+func (m *MultiTSDB) authenticate(ctx context.Context) error {
+	if m.token == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get(authorizationKey) {
+		if strings.HasPrefix(auth, "Bearer ") && subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(m.token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+}
+
+// RegisterAdminServer registers the admin server.
+func RegisterAdminServer(adminSrv adminpb.AdminServer) func(*grpc.Server) {
+	return func(s *grpc.Server) {
+		adminpb.RegisterAdminServer(s, adminSrv)
+	}
+}
+
+// DeleteSeries deletes the samples of the matching series in the time range from the TSDB of the tenant of the
+// request. There is nothing to delete if the tenant has no TSDB.
+func (m *MultiTSDB) DeleteSeries(ctx context.Context, r *adminpb.DeleteSeriesRequest) (*adminpb.DeleteSeriesResponse, error) {
+	if err := m.authenticate(ctx); err != nil {
+		return nil, err
+	}
+	if r.Tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, "no tenant specified")
+	}
+	if len(r.Matchers) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no matchers specified")
+	}
+	matchers, err := storepb.MatchersToPromMatchers(r.Matchers...)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	db, ok := m.dbs()[r.Tenant]
+	if !ok {
+		return &adminpb.DeleteSeriesResponse{}, nil
+	}
+	if err := db.Delete(r.MinTime, r.MaxTime, matchers...); err != nil {
+		return nil, status.Error(codes.Internal, errors.Wrapf(err, "delete series for tenant %s", r.Tenant).Error())
+	}
+	return &adminpb.DeleteSeriesResponse{}, nil
+}
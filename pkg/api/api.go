@@ -22,18 +22,22 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
-	"github.com/NYTimes/gziphandler"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/golang/snappy"
 	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
 	"github.com/prometheus/common/route"
 	"github.com/prometheus/common/version"
 
+	"github.com/thanos-io/thanos/pkg/exthttp"
 	extpromhttp "github.com/thanos-io/thanos/pkg/extprom/http"
 	"github.com/thanos-io/thanos/pkg/logging"
 	"github.com/thanos-io/thanos/pkg/server/http/middleware"
@@ -56,6 +60,12 @@ const (
 	ErrorExec     ErrorType = "execution"
 	ErrorBadData  ErrorType = "bad_data"
 	ErrorInternal ErrorType = "internal"
+	// ErrorUnauthorized is returned if the request is missing valid credentials for an authenticated endpoint.
+	ErrorUnauthorized ErrorType = "unauthorized"
+	// ErrorForbidden is returned if the request is not allowed, e.g. because of its tenant.
+	ErrorForbidden ErrorType = "forbidden"
+	// ErrorNotFound is returned if the requested resource, e.g. a job, doesn't exist.
+	ErrorNotFound ErrorType = "not_found"
 )
 
 var corsHeaders = map[string]string{
@@ -193,6 +203,26 @@ func GetRuntimeInfoFunc(logger log.Logger) RuntimeInfoFn {
 	}
 }
 
+const (
+	// ContentTypeProtobuf is the media type of protobuf encoded responses.
+	ContentTypeProtobuf = "application/x-protobuf"
+	// ContentEncodingSnappy is the content coding of snappy block compressed responses.
+	ContentEncodingSnappy = "snappy"
+)
+
+// ProtobufMarshaler is implemented by the data of responses which can be encoded as protobuf instead of JSON.
+type ProtobufMarshaler interface {
+	// MarshalProtobuf returns the protobuf encoding of the successful response with the data and the warnings.
+	MarshalProtobuf(warnings []string) ([]byte, error)
+}
+
+// RawResponder is implemented by the data of responses which are written in another format than the JSON of the API,
+// e.g. the Prometheus exposition format. Errors are still written as JSON.
+type RawResponder interface {
+	// RespondRaw writes the successful response with the data and the warnings.
+	RespondRaw(w http.ResponseWriter, r *http.Request, warnings []error)
+}
+
 type InstrFunc func(name string, f ApiFunc) http.HandlerFunc
 
 // GetInstr returns a http HandlerFunc with the instrumentation middleware.
@@ -210,6 +240,10 @@ func GetInstr(
 			}
 			if data, warnings, err := f(r); err != nil {
 				RespondError(w, err, data)
+			} else if rr, ok := data.(RawResponder); ok {
+				rr.RespondRaw(w, r, warnings)
+			} else if m, ok := data.(ProtobufMarshaler); ok && AcceptsProtobuf(r) {
+				RespondProtobuf(w, r, m, warnings)
 			} else if data != nil {
 				Respond(w, data, warnings)
 			} else {
@@ -219,7 +253,7 @@ func GetInstr(
 
 		return tracing.HTTPMiddleware(tracer, name, logger,
 			ins.NewHandler(name,
-				gziphandler.GzipHandler(
+				exthttp.NewCompressionHandler(
 					middleware.RequestID(
 						logMiddleware.HTTPMiddleware(name, hf),
 					),
@@ -247,6 +281,57 @@ func Respond(w http.ResponseWriter, data interface{}, warnings []error) {
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
+// AcceptsProtobuf returns true if the Accept header of the request lists the protobuf media type.
+func AcceptsProtobuf(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, s := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(s)
+			if err == nil && mediaType == ContentTypeProtobuf {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// acceptsSnappy returns true if the Accept-Encoding header of the request lists the snappy content coding.
+func acceptsSnappy(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept-Encoding") {
+		for _, s := range strings.Split(accept, ",") {
+			if coding := strings.TrimSpace(strings.Split(s, ";")[0]); coding == ContentEncodingSnappy {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RespondProtobuf writes the protobuf encoding of the successful response. The response is snappy block compressed if
+// the request accepts the snappy content coding.
+func RespondProtobuf(w http.ResponseWriter, r *http.Request, data ProtobufMarshaler, warnings []error) {
+	warns := make([]string, 0, len(warnings))
+	for _, warn := range warnings {
+		warns = append(warns, warn.Error())
+	}
+	b, err := data.MarshalProtobuf(warns)
+	if err != nil {
+		RespondError(w, &ApiError{Typ: ErrorInternal, Err: errors.Wrap(err, "encode protobuf response")}, nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeProtobuf)
+	w.Header().Add("Vary", "Accept")
+	if len(warnings) > 0 {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	if acceptsSnappy(r) {
+		b = snappy.Encode(nil, b)
+		w.Header().Set("Content-Encoding", ContentEncodingSnappy)
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b)
+}
+
 func RespondError(w http.ResponseWriter, apiErr *ApiError, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Cache-Control", "no-store")
@@ -261,6 +346,12 @@ func RespondError(w http.ResponseWriter, apiErr *ApiError, data interface{}) {
 		code = http.StatusServiceUnavailable
 	case ErrorInternal:
 		code = http.StatusInternalServerError
+	case ErrorUnauthorized:
+		code = http.StatusUnauthorized
+	case ErrorForbidden:
+		code = http.StatusForbidden
+	case ErrorNotFound:
+		code = http.StatusNotFound
 	default:
 		code = http.StatusInternalServerError
 	}
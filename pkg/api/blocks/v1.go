@@ -5,6 +5,8 @@ package v1
 
 import (
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/go-kit/log"
@@ -31,6 +33,7 @@ type BlocksAPI struct {
 	loadedBlocksInfo *BlocksInfo
 	disableCORS      bool
 	bkt              objstore.Bucket
+	syncHint         func()
 }
 
 type BlocksInfo struct {
@@ -40,6 +43,22 @@ type BlocksInfo struct {
 	Err         error           `json:"err"`
 }
 
+// BlockDetails is the drill-down information of a block in the bucket.
+type BlockDetails struct {
+	Meta          metadata.Meta           `json:"meta"`
+	IndexSize     int64                   `json:"indexSize"`
+	ChunksSize    int64                   `json:"chunksSize"`
+	Files         []BlockFile             `json:"files"`
+	DeletionMark  *metadata.DeletionMark  `json:"deletionMark,omitempty"`
+	NoCompactMark *metadata.NoCompactMark `json:"noCompactMark,omitempty"`
+}
+
+// BlockFile is an object of a block in the bucket, with its name relative to the block directory.
+type BlockFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
 type ActionType int32
 
 const (
@@ -83,7 +102,23 @@ func (bapi *BlocksAPI) Register(r *route.Router, tracer opentracing.Tracer, logg
 	instr := api.GetInstr(tracer, logger, ins, logMiddleware, bapi.disableCORS)
 
 	r.Get("/blocks", instr("blocks", bapi.blocks))
+	r.Get("/blocks/:id", instr("block_details", bapi.blockDetails))
 	r.Post("/blocks/mark", instr("blocks_mark", bapi.markBlock))
+	r.Post("/blocks/sync", instr("blocks_sync", bapi.syncBlocks))
+}
+
+// SetSyncHint sets the function called by requests to sync the blocks, e.g. sent by components which have just
+// uploaded new blocks. Such requests fail if it is not set.
+func (bapi *BlocksAPI) SetSyncHint(f func()) {
+	bapi.syncHint = f
+}
+
+func (bapi *BlocksAPI) syncBlocks(_ *http.Request) (interface{}, []error, *api.ApiError) {
+	if bapi.syncHint == nil {
+		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.New("syncing blocks on request is not supported")}
+	}
+	bapi.syncHint()
+	return nil, nil, nil
 }
 
 func (bapi *BlocksAPI) markBlock(r *http.Request) (interface{}, []error, *api.ApiError) {
@@ -130,6 +165,61 @@ func (bapi *BlocksAPI) blocks(r *http.Request) (interface{}, []error, *api.ApiEr
 	return bapi.globalBlocksInfo, nil, nil
 }
 
+func (bapi *BlocksAPI) blockDetails(r *http.Request) (interface{}, []error, *api.ApiError) {
+	idParam := route.Param(r.Context(), "id")
+	id, err := ulid.Parse(idParam)
+	if err != nil {
+		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("ULID %q is not valid: %v", idParam, err)}
+	}
+
+	ctx := r.Context()
+	meta, err := block.DownloadMeta(ctx, bapi.logger, bapi.bkt, id)
+	if err != nil {
+		if bapi.bkt.IsObjNotFoundErr(errors.Cause(err)) {
+			return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("block %s not found", id)}
+		}
+		return nil, nil, &api.ApiError{Typ: api.ErrorInternal, Err: err}
+	}
+
+	details := &BlockDetails{Meta: meta, Files: []BlockFile{}}
+	if err := bapi.bkt.Iter(ctx, id.String(), func(name string) error {
+		attrs, err := bapi.bkt.Attributes(ctx, name)
+		if err != nil {
+			return errors.Wrapf(err, "attributes of %s", name)
+		}
+		f := BlockFile{Name: strings.TrimPrefix(name, id.String()+objstore.DirDelim), Size: attrs.Size}
+		switch {
+		case f.Name == block.IndexFilename:
+			details.IndexSize = f.Size
+		case strings.HasPrefix(f.Name, block.ChunksDirname+objstore.DirDelim):
+			details.ChunksSize += f.Size
+		}
+		details.Files = append(details.Files, f)
+		return nil
+	}, objstore.WithRecursiveIter); err != nil {
+		return nil, nil, &api.ApiError{Typ: api.ErrorInternal, Err: errors.Wrapf(err, "list files of block %s", id)}
+	}
+	sort.Slice(details.Files, func(i, j int) bool { return details.Files[i].Name < details.Files[j].Name })
+
+	bkt, ok := bapi.bkt.(objstore.InstrumentedBucketReader)
+	if !ok {
+		bkt = objstore.WithNoopInstr(bapi.bkt)
+	}
+	deletionMark := &metadata.DeletionMark{}
+	if err := metadata.ReadMarker(ctx, bapi.logger, bkt, id.String(), deletionMark); err == nil {
+		details.DeletionMark = deletionMark
+	} else if errors.Cause(err) != metadata.ErrorMarkerNotFound {
+		return nil, nil, &api.ApiError{Typ: api.ErrorInternal, Err: err}
+	}
+	noCompactMark := &metadata.NoCompactMark{}
+	if err := metadata.ReadMarker(ctx, bapi.logger, bkt, id.String(), noCompactMark); err == nil {
+		details.NoCompactMark = noCompactMark
+	} else if errors.Cause(err) != metadata.ErrorMarkerNotFound {
+		return nil, nil, &api.ApiError{Typ: api.ErrorInternal, Err: err}
+	}
+	return details, nil, nil
+}
+
 func (b *BlocksInfo) set(blocks []metadata.Meta, err error) {
 	if err != nil {
 		// Last view is maintained.
@@ -19,6 +19,8 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/route"
 	"github.com/prometheus/prometheus/model/labels"
 
@@ -186,3 +188,65 @@ func TestMarkBlockEndpoint(t *testing.T) {
 	_, err = os.Stat(file)
 	testutil.Ok(t, err)
 }
+
+func TestBlockDetailsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	tmpDir, err := ioutil.TempDir("", "test-block-details")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(tmpDir)) }()
+
+	b1, err := e2eutil.CreateBlock(ctx, tmpDir, []labels.Labels{
+		{{Name: "a", Value: "1"}},
+		{{Name: "a", Value: "2"}},
+	}, 100, 0, 1000, labels.Labels{{Name: "ext1", Value: "val1"}}, 124, metadata.NoneFunc)
+	testutil.Ok(t, err)
+
+	bkt := objstore.WithNoopInstr(objstore.NewInMemBucket())
+	logger := log.NewNopLogger()
+	testutil.Ok(t, block.Upload(ctx, logger, bkt, path.Join(tmpDir, b1.String()), metadata.NoneFunc))
+	testutil.Ok(t, block.MarkForNoCompact(ctx, logger, bkt, b1, metadata.ManualNoCompactReason, "test", promauto.With(nil).NewCounter(prometheus.CounterOpts{})))
+
+	api := &BlocksAPI{
+		baseAPI: &baseAPI.BaseAPI{},
+		logger:  logger,
+		bkt:     bkt,
+	}
+
+	for _, test := range []endpointTestCase{
+		{endpoint: api.blockDetails, params: map[string]string{"id": "invalid_id"}, errType: baseAPI.ErrorBadData},
+		{endpoint: api.blockDetails, params: map[string]string{"id": ulid.MustNew(1, nil).String()}, errType: baseAPI.ErrorBadData},
+	} {
+		testEndpoint(t, test, test.params["id"], reflect.DeepEqual)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	testutil.Ok(t, err)
+	resp, _, apiErr := api.blockDetails(req.WithContext(route.WithParam(ctx, "id", b1.String())))
+	testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+
+	details := resp.(*BlockDetails)
+	testutil.Equals(t, b1, details.Meta.ULID)
+	testutil.Equals(t, map[string]string{"ext1": "val1"}, details.Meta.Thanos.Labels)
+	testutil.Assert(t, details.DeletionMark == nil, "block must not be marked for deletion")
+	testutil.Assert(t, details.NoCompactMark != nil, "block must be marked for no compaction")
+	testutil.Equals(t, "test", details.NoCompactMark.Details)
+
+	var names []string
+	for _, f := range details.Files {
+		names = append(names, f.Name)
+		testutil.Assert(t, f.Size > 0, "file %s must not be empty", f.Name)
+	}
+	testutil.Equals(t, []string{"chunks/000001", "index", "meta.json", "no-compact-mark.json"}, names)
+	testutil.Equals(t, details.Files[1].Size, details.IndexSize)
+	testutil.Equals(t, details.Files[0].Size, details.ChunksSize)
+}
+
+func TestSyncBlocksEndpoint(t *testing.T) {
+	api := &BlocksAPI{baseAPI: &baseAPI.BaseAPI{}, logger: log.NewNopLogger()}
+	testEndpoint(t, endpointTestCase{endpoint: api.syncBlocks, method: http.MethodPost, errType: baseAPI.ErrorBadData}, "not supported", reflect.DeepEqual)
+
+	hints := 0
+	api.SetSyncHint(func() { hints++ })
+	testEndpoint(t, endpointTestCase{endpoint: api.syncBlocks, method: http.MethodPost}, "supported", reflect.DeepEqual)
+	testutil.Equals(t, 1, hints)
+}
@@ -0,0 +1,117 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package v1
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/route"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/thanos-io/thanos/pkg/admin"
+	"github.com/thanos-io/thanos/pkg/api"
+	extpromhttp "github.com/thanos-io/thanos/pkg/extprom/http"
+	"github.com/thanos-io/thanos/pkg/logging"
+)
+
+// AdminAPI is the administrative HTTP API of the querier. All its endpoints require the bearer token in the
+// Authorization header.
+type AdminAPI struct {
+	deleter      *admin.Deleter
+	token        string
+	tenantHeader string
+	disableCORS  bool
+}
+
+// NewAdminAPI returns an AdminAPI deleting series with the given deleter, authenticating requests with the token.
+// Series are deleted for the tenant of the tenant header of requests.
+func NewAdminAPI(deleter *admin.Deleter, token, tenantHeader string, disableCORS bool) *AdminAPI {
+	return &AdminAPI{
+		deleter:      deleter,
+		token:        token,
+		tenantHeader: tenantHeader,
+		disableCORS:  disableCORS,
+	}
+}
+
+// Register the API's endpoints in the given router.
+func (a *AdminAPI) Register(r *route.Router, tracer opentracing.Tracer, logger log.Logger, ins extpromhttp.InstrumentationMiddleware, logMiddleware *logging.HTTPServerMiddleware) {
+	instr := api.GetInstr(tracer, logger, ins, logMiddleware, a.disableCORS)
+
+	r.Post("/tsdb/delete_series", instr("delete_series", a.authenticated(a.deleteSeries)))
+	r.Put("/tsdb/delete_series", instr("delete_series", a.authenticated(a.deleteSeries)))
+	r.Get("/tsdb/delete_series", instr("delete_series_jobs", a.authenticated(a.deletionJobs)))
+	r.Get("/tsdb/delete_series/:id", instr("delete_series_job", a.authenticated(a.deletionJob)))
+}
+
+// authenticated returns the ApiFunc which only calls f if the request has the token of the API as bearer token.
+func (a *AdminAPI) authenticated(f api.ApiFunc) api.ApiFunc {
+	return func(r *http.Request) (interface{}, []error, *api.ApiError) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(a.token)) != 1 {
+			return nil, nil, &api.ApiError{Typ: api.ErrorUnauthorized, Err: errors.New("missing or invalid bearer token")}
+		}
+		return f(r)
+	}
+}
+
+func (a *AdminAPI) deleteSeries(r *http.Request) (interface{}, []error, *api.ApiError) {
+	tenant := r.Header.Get(a.tenantHeader)
+	if tenant == "" {
+		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("no tenant specified in the %s header", a.tenantHeader)}
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Wrap(err, "parse form")}
+	}
+	if len(r.Form["match[]"]) == 0 {
+		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.New("no match[] parameter provided")}
+	}
+
+	start, err := parseTimeParam(r, "start", infMinTime)
+	if err != nil {
+		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: err}
+	}
+	end, err := parseTimeParam(r, "end", infMaxTime)
+	if err != nil {
+		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: err}
+	}
+	if end.Before(start) {
+		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.New("end timestamp must not be before start time")}
+	}
+
+	var selectors [][]*labels.Matcher
+	for _, s := range r.Form["match[]"] {
+		matchers, err := parser.ParseMetricSelector(s)
+		if err != nil {
+			return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: err}
+		}
+		selectors = append(selectors, matchers)
+	}
+
+	job, err := a.deleter.Delete(tenant, selectors, timestamp.FromTime(start), timestamp.FromTime(end))
+	if err != nil {
+		return nil, nil, &api.ApiError{Typ: api.ErrorExec, Err: err}
+	}
+	return job, nil, nil
+}
+
+func (a *AdminAPI) deletionJobs(_ *http.Request) (interface{}, []error, *api.ApiError) {
+	return a.deleter.Jobs(), nil, nil
+}
+
+func (a *AdminAPI) deletionJob(r *http.Request) (interface{}, []error, *api.ApiError) {
+	id := route.Param(r.Context(), "id")
+	job, ok := a.deleter.Job(id)
+	if !ok {
+		return nil, nil, &api.ApiError{Typ: api.ErrorNotFound, Err: errors.Errorf("deletion job %s not found", id)}
+	}
+	return job, nil, nil
+}
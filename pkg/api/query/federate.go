@@ -0,0 +1,212 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package v1
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gogo/protobuf/proto"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/thanos-io/thanos/pkg/api"
+	extpromhttp "github.com/thanos-io/thanos/pkg/extprom/http"
+	"github.com/thanos-io/thanos/pkg/logging"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// defaultFederationLookbackDelta is the lookback delta of federation without a configured lookback delta, which is
+// the default lookback delta of the PromQL engine.
+const defaultFederationLookbackDelta = 5 * time.Minute
+
+// RegisterFederate registers the /federate endpoint, which renders the latest samples of the series selected by
+// match[] selectors in the Prometheus exposition format, like the federation endpoint of Prometheus.
+func (qapi *QueryAPI) RegisterFederate(r *route.Router, tracer opentracing.Tracer, logger log.Logger, ins extpromhttp.InstrumentationMiddleware, logMiddleware *logging.HTTPServerMiddleware) {
+	instr := api.GetInstr(tracer, logger, ins, logMiddleware, qapi.disableCORS)
+
+	r.Get("/federate", instr("federate", qapi.tenantAware("federate", qapi.audited("federate", qapi.authorized("federate", qapi.federate)))))
+}
+
+func (qapi *QueryAPI) federate(r *http.Request) (interface{}, []error, *api.ApiError) {
+	if err := r.ParseForm(); err != nil {
+		return nil, nil, &api.ApiError{Typ: api.ErrorInternal, Err: errors.Wrap(err, "parse form")}
+	}
+
+	if len(r.Form[MatcherParam]) == 0 {
+		return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.New("no match[] parameter provided")}
+	}
+
+	var matcherSets [][]*labels.Matcher
+	for _, s := range r.Form[MatcherParam] {
+		matchers, err := parser.ParseMetricSelector(s)
+		if err != nil {
+			return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: err}
+		}
+		matcherSets = append(matcherSets, matchers)
+	}
+
+	enableDedup, apiErr := qapi.parseEnableDedupParam(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	replicaLabels, apiErr := qapi.parseReplicaLabelsParam(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	enablePartialResponse, apiErr := qapi.parsePartialResponseParam(r, qapi.enableQueryPartialResponse)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	mint, maxt := qapi.federationTimeRange()
+
+	// Only raw data is federated, as downsampled data is too old to be within the lookback delta.
+	q, err := qapi.queryableCreate(enableDedup, replicaLabels, nil, 0, enablePartialResponse, false, false).
+		Querier(r.Context(), timestamp.FromTime(mint), timestamp.FromTime(maxt))
+	if err != nil {
+		return nil, nil, &api.ApiError{Typ: api.ErrorExec, Err: err}
+	}
+	defer runutil.CloseWithLogOnErr(qapi.logger, q, "queryable federate")
+
+	hints := &storage.SelectHints{Start: timestamp.FromTime(mint), End: timestamp.FromTime(maxt)}
+
+	var sets []storage.SeriesSet
+	for _, mset := range matcherSets {
+		sets = append(sets, q.Select(true, hints, mset...))
+	}
+
+	var (
+		vec = federation{}
+		set = storage.NewMergeSeriesSet(sets, storage.ChainedSeriesMerge)
+		it  = storage.NewBuffer(maxt.Sub(mint).Milliseconds())
+	)
+	for set.Next() {
+		s := set.At()
+		it.Reset(s.Iterator())
+
+		var (
+			t  int64
+			v  float64
+			ok = it.Seek(timestamp.FromTime(maxt))
+		)
+		if ok {
+			t, v = it.Values()
+		} else {
+			t, v, ok = it.PeekBack(1)
+			if !ok {
+				continue
+			}
+		}
+		// The exposition format does not support stale markers, like for the federation of Prometheus.
+		if value.IsStaleNaN(v) {
+			continue
+		}
+		vec = append(vec, promql.Sample{Metric: s.Labels(), Point: promql.Point{T: t, V: v}})
+	}
+	if set.Err() != nil {
+		return nil, nil, &api.ApiError{Typ: api.ErrorExec, Err: set.Err()}
+	}
+	return vec, set.Warnings(), nil
+}
+
+// federationTimeRange returns the time range within which the latest samples of series are federated.
+func (qapi *QueryAPI) federationTimeRange() (mint, maxt time.Time) {
+	lookbackDelta := qapi.lookbackDelta
+	if lookbackDelta <= 0 {
+		lookbackDelta = defaultFederationLookbackDelta
+	}
+	maxt = qapi.baseAPI.Now()
+	return maxt.Add(-lookbackDelta), maxt
+}
+
+// federation are the latest samples of the federated series.
+type federation promql.Vector
+
+// RespondRaw implements api.RawResponder. The samples are encoded in the exposition format negotiated with the
+// client, in metric families of untyped metrics like the federation of Prometheus. Warnings can't be part of the
+// response, so it is not cached if there are any.
+func (f federation) RespondRaw(w http.ResponseWriter, r *http.Request, warnings []error) {
+	// The series are sorted by their labels already.
+	sort.SliceStable(f, func(i, j int) bool {
+		return f[i].Metric.Get(labels.MetricName) < f[j].Metric.Get(labels.MetricName)
+	})
+
+	format := expfmt.Negotiate(r.Header)
+	w.Header().Set("Content-Type", string(format))
+	if len(warnings) > 0 {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	enc := expfmt.NewEncoder(w, format)
+
+	var (
+		lastMetricName string
+		protMetricFam  *dto.MetricFamily
+	)
+	for _, s := range f {
+		name := s.Metric.Get(labels.MetricName)
+		// Nameless series can't be exposed.
+		if name == "" {
+			continue
+		}
+		if protMetricFam == nil || name != lastMetricName {
+			if protMetricFam != nil {
+				if err := enc.Encode(protMetricFam); err != nil {
+					return
+				}
+			}
+			protMetricFam = &dto.MetricFamily{
+				Type: dto.MetricType_UNTYPED.Enum(),
+				Name: proto.String(name),
+			}
+			lastMetricName = name
+		}
+
+		protMetric := &dto.Metric{
+			Untyped:     &dto.Untyped{Value: proto.Float64(s.V)},
+			TimestampMs: proto.Int64(s.T),
+		}
+		instance := false
+		for _, l := range s.Metric {
+			// No value means unset.
+			if l.Name == labels.MetricName || l.Value == "" {
+				continue
+			}
+			if l.Name == model.InstanceLabel {
+				instance = true
+			}
+			protMetric.Label = append(protMetric.Label, &dto.LabelPair{
+				Name:  proto.String(l.Name),
+				Value: proto.String(l.Value),
+			})
+		}
+		// An empty instance label keeps federating Prometheus servers with honor_labels from attaching the
+		// instance label of their target to the series, like for the federation of Prometheus.
+		if !instance {
+			protMetric.Label = append(protMetric.Label, &dto.LabelPair{
+				Name:  proto.String(model.InstanceLabel),
+				Value: proto.String(""),
+			})
+			sort.Slice(protMetric.Label, func(i, j int) bool { return protMetric.Label[i].GetName() < protMetric.Label[j].GetName() })
+		}
+		protMetricFam.Metric = append(protMetricFam.Metric, protMetric)
+	}
+	if protMetricFam != nil {
+		_ = enc.Encode(protMetricFam)
+	}
+}
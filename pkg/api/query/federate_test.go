@@ -0,0 +1,88 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package v1
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/common/route"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+
+	baseAPI "github.com/thanos-io/thanos/pkg/api"
+	"github.com/thanos-io/thanos/pkg/component"
+	extpromhttp "github.com/thanos-io/thanos/pkg/extprom/http"
+	"github.com/thanos-io/thanos/pkg/logging"
+	"github.com/thanos-io/thanos/pkg/query"
+	"github.com/thanos-io/thanos/pkg/store"
+	"github.com/thanos-io/thanos/pkg/testutil"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
+)
+
+func TestFederate(t *testing.T) {
+	db, err := e2eutil.NewTSDB()
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, db.Close()) }()
+
+	app := db.Appender(context.Background())
+	for _, s := range []struct {
+		lset labels.Labels
+		t    int64
+		v    float64
+	}{
+		{lset: labels.FromStrings("__name__", "up", "job", "a", "replica", "0"), t: 500000, v: 0},
+		{lset: labels.FromStrings("__name__", "up", "job", "a", "replica", "0"), t: 540000, v: 1},
+		{lset: labels.FromStrings("__name__", "up", "job", "a", "replica", "1"), t: 500000, v: 1},
+		{lset: labels.FromStrings("__name__", "up", "instance", "i", "job", "b", "replica", "0"), t: 520000, v: 1},
+		// Too old to be federated.
+		{lset: labels.FromStrings("__name__", "up", "job", "c", "replica", "0"), t: 200000, v: 1},
+		// Stale.
+		{lset: labels.FromStrings("__name__", "up", "job", "d", "replica", "0"), t: 500000, v: 1},
+		{lset: labels.FromStrings("__name__", "up", "job", "d", "replica", "0"), t: 510000, v: math.Float64frombits(value.StaleNaN)},
+		{lset: labels.FromStrings("__name__", "scrape_duration_seconds", "job", "a", "replica", "0"), t: 540000, v: 0.5},
+	} {
+		_, err := app.Append(0, s.lset, s.t, s.v)
+		testutil.Ok(t, err)
+	}
+	testutil.Ok(t, app.Commit())
+
+	qapi := &QueryAPI{
+		baseAPI:         &baseAPI.BaseAPI{Now: func() time.Time { return time.Unix(540, 0) }},
+		logger:          log.NewNopLogger(),
+		queryableCreate: query.NewQueryableCreator(nil, nil, store.NewTSDBStore(nil, db, component.Query, nil), 2, time.Minute),
+		replicaLabels:   []string{"replica"},
+	}
+	r := route.New()
+	qapi.RegisterFederate(r, &opentracing.NoopTracer{}, log.NewNopLogger(), extpromhttp.NewNopInstrumentationMiddleware(), logging.NewHTTPServerMiddleware(log.NewNopLogger()))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, `/federate?match[]=up&match[]={job="a"}&dedup=true`, nil))
+	testutil.Equals(t, http.StatusOK, w.Code)
+	testutil.Equals(t, `# TYPE scrape_duration_seconds untyped
+scrape_duration_seconds{instance="",job="a"} 0.5 540000
+# TYPE up untyped
+up{instance="i",job="b"} 1 520000
+up{instance="",job="a"} 1 540000
+`, w.Body.String())
+
+	// Without deduplication, the series of all replicas are federated.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, `/federate?match[]=up{job="a"}&dedup=false`, nil))
+	testutil.Equals(t, http.StatusOK, w.Code)
+	testutil.Equals(t, `# TYPE up untyped
+up{instance="",job="a",replica="0"} 1 540000
+up{instance="",job="a",replica="1"} 1 500000
+`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, `/federate`, nil))
+	testutil.Equals(t, http.StatusBadRequest, w.Code)
+}
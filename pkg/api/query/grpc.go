@@ -0,0 +1,255 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package v1
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/thanos-io/thanos/pkg/api/query/querypb"
+	"github.com/thanos-io/thanos/pkg/tracing"
+)
+
+// maxSamplesPerQueryResponse is the number of samples after which the series of query results are split into several
+// messages, which keeps messages well below the default maximum message size of gRPC clients.
+const maxSamplesPerQueryResponse = 65536
+
+// GRPCAPI serves the instant and range queries of the query API over gRPC, e.g. for queriers federating other
+// queriers without the overhead of JSON.
+type GRPCAPI struct {
+	qapi *QueryAPI
+}
+
+// NewGRPCAPI returns the gRPC API evaluating queries like the given query API.
+func NewGRPCAPI(qapi *QueryAPI) *GRPCAPI {
+	return &GRPCAPI{qapi: qapi}
+}
+
+// RegisterQueryServer registers the query server.
+func RegisterQueryServer(queryServer querypb.QueryServer) func(*grpc.Server) {
+	return func(s *grpc.Server) {
+		querypb.RegisterQueryServer(s, queryServer)
+	}
+}
+
+// Query implements querypb.QueryServer.
+func (g *GRPCAPI) Query(req *querypb.QueryRequest, srv querypb.Query_QueryServer) error {
+	if err := g.checkAccess(); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeoutSeconds(srv.Context(), req.TimeoutSeconds)
+	defer cancel()
+
+	ts := g.qapi.baseAPI.Now()
+	if req.TimeSeconds != 0 {
+		ts = time.Unix(req.TimeSeconds, 0)
+	}
+
+	maxSourceResolution := g.maxSourceResolutionMillis(req.MaxResolutionSeconds, g.qapi.defaultInstantQueryMaxSourceResolution)
+	ctx, engineResolution := g.qapi.withPlannedResolution(ctx, g.qapi.enableAutodownsampling && req.MaxResolutionSeconds == 0, timestamp.FromTime(ts), timestamp.FromTime(ts), maxSourceResolution)
+	qe := g.qapi.queryEngine(engineResolution)
+
+	span, ctx := tracing.StartSpan(ctx, "promql_instant_query")
+	defer span.Finish()
+
+	qry, err := qe.NewInstantQuery(g.qapi.queryableCreate(req.EnableDedup, g.replicaLabels(req.ReplicaLabels), nil, maxSourceResolution, req.EnablePartialResponse, g.qapi.enableQueryPushdown, false), req.Query, ts)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return g.exec(ctx, qry, srv)
+}
+
+// QueryRange implements querypb.QueryServer.
+func (g *GRPCAPI) QueryRange(req *querypb.QueryRangeRequest, srv querypb.Query_QueryRangeServer) error {
+	if err := g.checkAccess(); err != nil {
+		return err
+	}
+
+	start, end := time.Unix(req.StartTimeSeconds, 0), time.Unix(req.EndTimeSeconds, 0)
+	if end.Before(start) {
+		return status.Error(codes.InvalidArgument, "end timestamp must not be before start time")
+	}
+	step := time.Duration(req.IntervalSeconds) * time.Second
+	if step <= 0 {
+		return status.Error(codes.InvalidArgument, "zero or negative query resolution step widths are not accepted. Try a positive integer")
+	}
+	// For safety, limit the number of returned points per timeseries, like the HTTP API.
+	if end.Sub(start)/step > 11000 {
+		return status.Error(codes.InvalidArgument, "exceeded maximum resolution of 11,000 points per timeseries. Try decreasing the query resolution (interval)")
+	}
+
+	ctx, cancel := withTimeoutSeconds(srv.Context(), req.TimeoutSeconds)
+	defer cancel()
+
+	// If no max resolution is specified fit at least 5 samples between steps.
+	maxSourceResolution := g.maxSourceResolutionMillis(req.MaxResolutionSeconds, step/5)
+	ctx, engineResolution := g.qapi.withPlannedResolution(ctx, g.qapi.enableAutodownsampling && req.MaxResolutionSeconds == 0, timestamp.FromTime(start), timestamp.FromTime(end), maxSourceResolution)
+	qe := g.qapi.queryEngine(engineResolution)
+
+	g.qapi.queryRangeHist.Observe(end.Sub(start).Seconds())
+
+	span, ctx := tracing.StartSpan(ctx, "promql_range_query")
+	defer span.Finish()
+
+	qry, err := qe.NewRangeQuery(
+		g.qapi.queryableCreate(req.EnableDedup, g.replicaLabels(req.ReplicaLabels), nil, maxSourceResolution, req.EnablePartialResponse, g.qapi.enableQueryPushdown, false),
+		req.Query,
+		start,
+		end,
+		step,
+	)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return g.exec(ctx, qry, srv)
+}
+
+// checkAccess rejects queries if tenancy is enforced or requests are authorized, which are only supported by the
+// HTTP API.
+func (g *GRPCAPI) checkAccess() error {
+	if g.Restricted() {
+		return status.Error(codes.PermissionDenied, "queries over gRPC are not available with enforced tenancy or authorization")
+	}
+	return nil
+}
+
+// Restricted returns true if tenancy is enforced or requests are authorized. Only the HTTP API can restrict requests
+// to a tenant and authorize them, so the StoreAPI and the other APIs the querier proxies to its endpoints are rejected.
+func (g *GRPCAPI) Restricted() bool {
+	return g.qapi.tenancy != nil || g.qapi.authorizer != nil
+}
+
+// proxiedServices are the gRPC services the querier proxies to its endpoints.
+var proxiedServices = []string{"thanos.Store", "thanos.Rules", "thanos.Targets", "thanos.Metadata", "thanos.Exemplars", "thanos.TSDBStatus"}
+
+// ServerOptions returns the options of the gRPC server of the querier which reject the requests of the proxied
+// services if the access is restricted, see Restricted.
+func (g *GRPCAPI) ServerOptions() []grpc.ServerOption {
+	if !g.Restricted() {
+		return nil
+	}
+	check := func(method string) error {
+		for _, s := range proxiedServices {
+			if strings.HasPrefix(method, "/"+s+"/") {
+				return status.Errorf(codes.PermissionDenied, "%s is not available over gRPC with enforced tenancy or authorization", s)
+			}
+		}
+		return nil
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := check(info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := check(info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+func (g *GRPCAPI) replicaLabels(replicaLabels []string) []string {
+	if len(replicaLabels) > 0 {
+		return replicaLabels
+	}
+	return g.qapi.replicaLabels
+}
+
+// maxSourceResolutionMillis returns the max source resolution of queries with the given max resolution, like the
+// max_source_resolution parameter of the HTTP API.
+func (g *GRPCAPI) maxSourceResolutionMillis(maxResolutionSeconds int64, defaultVal time.Duration) int64 {
+	if maxResolutionSeconds > 0 {
+		return maxResolutionSeconds * 1000
+	}
+	if g.qapi.enableAutodownsampling {
+		return int64(defaultVal / time.Millisecond)
+	}
+	return 0
+}
+
+type queryResponseSender interface {
+	Send(*querypb.QueryResponse) error
+}
+
+// exec executes the query and sends its result.
+func (g *GRPCAPI) exec(ctx context.Context, qry promql.Query, srv queryResponseSender) error {
+	var err error
+	tracing.DoInSpan(ctx, "query_gate_ismyturn", func(ctx context.Context) {
+		err = g.qapi.gate.Start(ctx)
+	})
+	if err != nil {
+		return status.Error(codes.Aborted, err.Error())
+	}
+	defer g.qapi.gate.Done()
+
+	res := qry.Exec(ctx)
+	if res.Err != nil {
+		switch res.Err.(type) {
+		case promql.ErrQueryCanceled:
+			return status.Error(codes.Canceled, res.Err.Error())
+		case promql.ErrQueryTimeout:
+			return status.Error(codes.DeadlineExceeded, res.Err.Error())
+		case promql.ErrStorage:
+			return status.Error(codes.Internal, res.Err.Error())
+		}
+		// Stores reject queries exceeding their limits with ResourceExhausted.
+		if status.Code(errors.Cause(res.Err)) == codes.ResourceExhausted {
+			return status.Error(codes.ResourceExhausted, res.Err.Error())
+		}
+		return status.Error(codes.Aborted, res.Err.Error())
+	}
+
+	warnings := make([]string, 0, len(res.Warnings))
+	for _, w := range res.Warnings {
+		warnings = append(warnings, w.Error())
+	}
+	resp, err := newQueryResponse(res.Value, warnings)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return sendQueryResponse(srv, resp)
+}
+
+// sendQueryResponse sends the series of the response in messages of at most maxSamplesPerQueryResponse samples, unless
+// a single series has more. The warnings are in the last message.
+func sendQueryResponse(srv queryResponseSender, resp *querypb.QueryResponse) error {
+	series := resp.Series
+	for {
+		n, samples := 0, 0
+		for ; n < len(series); n++ {
+			if n > 0 && samples+len(series[n].Samples) > maxSamplesPerQueryResponse {
+				break
+			}
+			samples += len(series[n].Samples)
+		}
+		if n == len(series) {
+			resp.Series = series
+			return srv.Send(resp)
+		}
+		if err := srv.Send(&querypb.QueryResponse{ResultType: resp.ResultType, Series: series[:n]}); err != nil {
+			return err
+		}
+		series = series[n:]
+	}
+}
+
+func withTimeoutSeconds(ctx context.Context, timeoutSeconds int64) (context.Context, context.CancelFunc) {
+	if timeoutSeconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+}
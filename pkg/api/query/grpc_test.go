@@ -0,0 +1,218 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package v1
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	baseAPI "github.com/thanos-io/thanos/pkg/api"
+	"github.com/thanos-io/thanos/pkg/api/query/querypb"
+	"github.com/thanos-io/thanos/pkg/component"
+	"github.com/thanos-io/thanos/pkg/gate"
+	"github.com/thanos-io/thanos/pkg/query"
+	"github.com/thanos-io/thanos/pkg/store"
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/store/storepb/prompb"
+	"github.com/thanos-io/thanos/pkg/tenancy"
+	"github.com/thanos-io/thanos/pkg/testutil"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
+)
+
+func TestGRPCAPI(t *testing.T) {
+	db, err := e2eutil.NewTSDB()
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, db.Close()) }()
+
+	app := db.Appender(context.Background())
+	for _, lset := range []labels.Labels{
+		labels.FromStrings("__name__", "up", "job", "a", "replica", "0"),
+		labels.FromStrings("__name__", "up", "job", "a", "replica", "1"),
+		labels.FromStrings("__name__", "up", "job", "b", "replica", "0"),
+	} {
+		for i := int64(0); i < 10; i++ {
+			_, err := app.Append(0, lset, i*60000, float64(i))
+			testutil.Ok(t, err)
+		}
+	}
+	testutil.Ok(t, app.Commit())
+
+	qe := promql.NewEngine(promql.EngineOpts{MaxSamples: 10000, Timeout: time.Minute})
+	qapi := &QueryAPI{
+		baseAPI:         &baseAPI.BaseAPI{Now: func() time.Time { return time.Unix(540, 0) }},
+		queryableCreate: query.NewQueryableCreator(nil, nil, store.NewTSDBStore(nil, db, component.Query, nil), 2, time.Minute),
+		queryEngine:     func(int64) *promql.Engine { return qe },
+		gate:            gate.New(nil, 4),
+		replicaLabels:   []string{"replica"},
+		queryRangeHist:  promauto.With(prometheus.NewRegistry()).NewHistogram(prometheus.HistogramOpts{Name: "query_range_hist"}),
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	testutil.Ok(t, err)
+	srv := grpc.NewServer()
+	RegisterQueryServer(NewGRPCAPI(qapi))(srv)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, conn.Close()) }()
+	client := querypb.NewQueryClient(conn)
+
+	recv := func(stream interface {
+		Recv() (*querypb.QueryResponse, error)
+	}) ([]*querypb.QueryResponse, error) {
+		var resps []*querypb.QueryResponse
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return resps, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			resps = append(resps, resp)
+		}
+	}
+	ctx := context.Background()
+
+	t.Run("instant query with dedup", func(t *testing.T) {
+		stream, err := client.Query(ctx, &querypb.QueryRequest{Query: "up", TimeSeconds: 540, EnableDedup: true})
+		testutil.Ok(t, err)
+		resps, err := recv(stream)
+		testutil.Ok(t, err)
+		testutil.Equals(t, []*querypb.QueryResponse{{
+			ResultType: "vector",
+			Series: []prompb.TimeSeries{
+				{Labels: labelpb.ZLabelsFromPromLabels(labels.FromStrings("__name__", "up", "job", "a")), Samples: []prompb.Sample{{Timestamp: 540000, Value: 9}}},
+				{Labels: labelpb.ZLabelsFromPromLabels(labels.FromStrings("__name__", "up", "job", "b")), Samples: []prompb.Sample{{Timestamp: 540000, Value: 9}}},
+			},
+		}}, resps)
+	})
+	t.Run("range query", func(t *testing.T) {
+		stream, err := client.QueryRange(ctx, &querypb.QueryRangeRequest{Query: `sum(up{replica="0"})`, StartTimeSeconds: 0, EndTimeSeconds: 120, IntervalSeconds: 60})
+		testutil.Ok(t, err)
+		resps, err := recv(stream)
+		testutil.Ok(t, err)
+		testutil.Equals(t, []*querypb.QueryResponse{{
+			ResultType: "matrix",
+			Series:     []prompb.TimeSeries{{Samples: []prompb.Sample{{Timestamp: 0, Value: 0}, {Timestamp: 60000, Value: 2}, {Timestamp: 120000, Value: 4}}}},
+		}}, resps)
+	})
+	t.Run("invalid query", func(t *testing.T) {
+		stream, err := client.Query(ctx, &querypb.QueryRequest{Query: "up{"})
+		testutil.Ok(t, err)
+		_, err = recv(stream)
+		testutil.NotOk(t, err)
+		testutil.Equals(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("invalid interval", func(t *testing.T) {
+		stream, err := client.QueryRange(ctx, &querypb.QueryRangeRequest{Query: "up", EndTimeSeconds: 120})
+		testutil.Ok(t, err)
+		_, err = recv(stream)
+		testutil.NotOk(t, err)
+		testutil.Equals(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestGRPCAPI_Restricted(t *testing.T) {
+	db, err := e2eutil.NewTSDB()
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, db.Close()) }()
+
+	app := db.Appender(context.Background())
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "tenant_id", "a"), 0, 1)
+	testutil.Ok(t, err)
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "tenant_id", "b"), 0, 1)
+	testutil.Ok(t, err)
+	testutil.Ok(t, app.Commit())
+	tsdbStore := store.NewTSDBStore(nil, db, component.Query, nil)
+
+	for _, tcase := range []struct {
+		name    string
+		tenancy *tenancy.Enforcer
+	}{
+		{name: "without tenancy"},
+		{name: "with enforced tenancy", tenancy: tenancy.NewEnforcer(nil, prometheus.NewRegistry(), tenancy.DefaultTenantHeader, tenancy.DefaultTenantLabel, "", nil)},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			g := NewGRPCAPI(&QueryAPI{tenancy: tcase.tenancy})
+			testutil.Equals(t, tcase.tenancy != nil, g.Restricted())
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			testutil.Ok(t, err)
+			srv := grpc.NewServer(g.ServerOptions()...)
+			store.RegisterStoreServer(tsdbStore)(srv)
+			RegisterQueryServer(g)(srv)
+			go func() { _ = srv.Serve(l) }()
+			defer srv.Stop()
+
+			conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+			testutil.Ok(t, err)
+			defer func() { testutil.Ok(t, conn.Close()) }()
+
+			stream, err := storepb.NewStoreClient(conn).Series(context.Background(), &storepb.SeriesRequest{
+				MaxTime:  math.MaxInt64,
+				Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "up"}},
+			})
+			testutil.Ok(t, err)
+			var series int
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if tcase.tenancy != nil {
+					// The series of all tenants would be returned, so the StoreAPI is rejected.
+					testutil.Equals(t, codes.PermissionDenied, status.Code(err))
+					return
+				}
+				testutil.Ok(t, err)
+				if resp.GetSeries() != nil {
+					series++
+				}
+			}
+			testutil.Assert(t, tcase.tenancy == nil, "expected Series to be rejected")
+			testutil.Equals(t, 2, series)
+		})
+	}
+}
+
+type queryResponses []*querypb.QueryResponse
+
+func (r *queryResponses) Send(resp *querypb.QueryResponse) error {
+	*r = append(*r, resp)
+	return nil
+}
+
+func TestSendQueryResponse(t *testing.T) {
+	samples := make([]prompb.Sample, maxSamplesPerQueryResponse/2)
+	series := []prompb.TimeSeries{{Samples: samples}, {Samples: samples}, {Samples: samples}, {Samples: make([]prompb.Sample, maxSamplesPerQueryResponse+1)}, {Samples: samples}}
+
+	var resps queryResponses
+	testutil.Ok(t, sendQueryResponse(&resps, &querypb.QueryResponse{ResultType: "matrix", Series: series, Warnings: []string{"partial"}}))
+	testutil.Equals(t, queryResponses{
+		{ResultType: "matrix", Series: series[:2]},
+		{ResultType: "matrix", Series: series[2:3]},
+		{ResultType: "matrix", Series: series[3:4]},
+		{ResultType: "matrix", Series: series[4:], Warnings: []string{"partial"}},
+	}, resps)
+
+	resps = nil
+	testutil.Ok(t, sendQueryResponse(&resps, &querypb.QueryResponse{ResultType: "string", StringResult: &querypb.StringResult{Value: "a"}}))
+	testutil.Equals(t, queryResponses{{ResultType: "string", StringResult: &querypb.StringResult{Value: "a"}}}, resps)
+}
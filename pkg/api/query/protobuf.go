@@ -0,0 +1,89 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package v1
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/thanos-io/thanos/pkg/api"
+	"github.com/thanos-io/thanos/pkg/api/query/querypb"
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb/prompb"
+)
+
+// MarshalProtobuf implements api.ProtobufMarshaler. The statistics of the query are not part of the protobuf encoding.
+func (d *queryData) MarshalProtobuf(warnings []string) ([]byte, error) {
+	resp, err := newQueryResponse(d.Result, warnings)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Marshal()
+}
+
+// newQueryResponse returns the protobuf encoding of the given query result.
+func newQueryResponse(result parser.Value, warnings []string) (*querypb.QueryResponse, error) {
+	resp := &querypb.QueryResponse{
+		ResultType: string(result.Type()),
+		Warnings:   warnings,
+	}
+
+	switch v := result.(type) {
+	case promql.Matrix:
+		resp.Series = make([]prompb.TimeSeries, 0, len(v))
+		for _, s := range v {
+			samples := make([]prompb.Sample, 0, len(s.Points))
+			for _, p := range s.Points {
+				samples = append(samples, prompb.Sample{Timestamp: p.T, Value: p.V})
+			}
+			resp.Series = append(resp.Series, prompb.TimeSeries{Labels: labelpb.ZLabelsFromPromLabels(s.Metric), Samples: samples})
+		}
+	case promql.Vector:
+		resp.Series = make([]prompb.TimeSeries, 0, len(v))
+		for _, s := range v {
+			resp.Series = append(resp.Series, prompb.TimeSeries{
+				Labels:  labelpb.ZLabelsFromPromLabels(s.Metric),
+				Samples: []prompb.Sample{{Timestamp: s.T, Value: s.V}},
+			})
+		}
+	case promql.Scalar:
+		resp.Series = []prompb.TimeSeries{{Samples: []prompb.Sample{{Timestamp: v.T, Value: v.V}}}}
+	case promql.String:
+		resp.StringResult = &querypb.StringResult{Timestamp: v.T, Value: v.V}
+	default:
+		return nil, errors.Errorf("unsupported result type %s", result.Type())
+	}
+	return resp, nil
+}
+
+// seriesData is the data of the series endpoint. It is encoded the same as []labels.Labels in JSON.
+type seriesData []labels.Labels
+
+// MarshalProtobuf implements api.ProtobufMarshaler.
+func (d seriesData) MarshalProtobuf(warnings []string) ([]byte, error) {
+	resp := &querypb.SeriesResponse{
+		Series:   make([]labelpb.ZLabelSet, 0, len(d)),
+		Warnings: warnings,
+	}
+	for _, lset := range d {
+		resp.Series = append(resp.Series, labelpb.ZLabelSet{Labels: labelpb.ZLabelsFromPromLabels(lset)})
+	}
+	return resp.Marshal()
+}
+
+// withSeriesData returns the ApiFunc which returns the series returned by f as seriesData, so they can be encoded as
+// protobuf.
+func withSeriesData(f api.ApiFunc) api.ApiFunc {
+	return func(r *http.Request) (interface{}, []error, *api.ApiError) {
+		data, warnings, apiErr := f(r)
+		if series, ok := data.([]labels.Labels); ok {
+			data = seriesData(series)
+		}
+		return data, warnings, apiErr
+	}
+}
@@ -39,15 +39,19 @@ import (
 	"github.com/prometheus/prometheus/model/timestamp"
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/promql/parser"
+	promrules "github.com/prometheus/prometheus/rules"
 	"github.com/prometheus/prometheus/storage"
 
 	"github.com/prometheus/prometheus/util/stats"
 
 	"github.com/thanos-io/thanos/pkg/api"
+	"github.com/thanos-io/thanos/pkg/audit"
+	"github.com/thanos-io/thanos/pkg/authz"
 	"github.com/thanos-io/thanos/pkg/exemplars"
 	"github.com/thanos-io/thanos/pkg/exemplars/exemplarspb"
 	extpromhttp "github.com/thanos-io/thanos/pkg/extprom/http"
 	"github.com/thanos-io/thanos/pkg/gate"
+	"github.com/thanos-io/thanos/pkg/info/infopb"
 	"github.com/thanos-io/thanos/pkg/logging"
 	"github.com/thanos-io/thanos/pkg/metadata"
 	"github.com/thanos-io/thanos/pkg/metadata/metadatapb"
@@ -55,10 +59,14 @@ import (
 	"github.com/thanos-io/thanos/pkg/rules"
 	"github.com/thanos-io/thanos/pkg/rules/rulespb"
 	"github.com/thanos-io/thanos/pkg/runutil"
+	"github.com/thanos-io/thanos/pkg/store"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 	"github.com/thanos-io/thanos/pkg/targets"
 	"github.com/thanos-io/thanos/pkg/targets/targetspb"
+	"github.com/thanos-io/thanos/pkg/tenancy"
 	"github.com/thanos-io/thanos/pkg/tracing"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus/tsdbstatuspb"
 )
 
 const (
@@ -70,6 +78,10 @@ const (
 	StoreMatcherParam        = "storeMatch[]"
 	Step                     = "step"
 	Stats                    = "stats"
+	LimitParam               = "limit"
+	ShardIndexParam          = "shard_index"
+	ShardCountParam          = "shard_count"
+	ShardByParam             = "shard_by[]"
 )
 
 // QueryAPI is an API used by Thanos Querier.
@@ -84,6 +96,7 @@ type QueryAPI struct {
 	targets     targets.UnaryClient
 	metadatas   metadata.UnaryClient
 	exemplars   exemplars.UnaryClient
+	tsdbStatus  tsdbstatus.UnaryClient
 
 	enableAutodownsampling              bool
 	enableQueryPartialResponse          bool
@@ -91,16 +104,32 @@ type QueryAPI struct {
 	enableTargetPartialResponse         bool
 	enableMetricMetadataPartialResponse bool
 	enableExemplarPartialResponse       bool
+	enableTSDBStatusPartialResponse     bool
 	enableQueryPushdown                 bool
 	disableCORS                         bool
 
 	replicaLabels  []string
 	endpointStatus func() []query.EndpointStatus
+	// tsdbInfos returns the time ranges of the resolutions of the stores, if not nil. Queries with an automatic
+	// max source resolution use them to pick the max source resolution of each sub-range of the query.
+	tsdbInfos func() []infopb.TSDBInfo
 
 	defaultRangeQueryStep                  time.Duration
 	defaultInstantQueryMaxSourceResolution time.Duration
 	defaultMetadataTimeRange               time.Duration
 
+	// tenancy enforces the tenancy of requests, if not nil.
+	tenancy *tenancy.Enforcer
+	// accounting counts the usage of tenants, if not nil. Without enforced tenancy, the tenant of requests is the
+	// value of the tenant header.
+	accounting   *tenancy.Accounting
+	tenantHeader string
+	// authorizer authorizes the requests selecting series, if not nil.
+	authorizer *authz.Authorizer
+	// auditLogger records the requests selecting series, if not nil.
+	auditLogger   *audit.Logger
+	lookbackDelta time.Duration
+
 	queryRangeHist prometheus.Histogram
 }
 
@@ -114,12 +143,15 @@ func NewQueryAPI(
 	targets targets.UnaryClient,
 	metadatas metadata.UnaryClient,
 	exemplars exemplars.UnaryClient,
+	tsdbStatus tsdbstatus.UnaryClient,
 	enableAutodownsampling bool,
+	tsdbInfos func() []infopb.TSDBInfo,
 	enableQueryPartialResponse bool,
 	enableRulePartialResponse bool,
 	enableTargetPartialResponse bool,
 	enableMetricMetadataPartialResponse bool,
 	enableExemplarPartialResponse bool,
+	enableTSDBStatusPartialResponse bool,
 	enableQueryPushdown bool,
 	replicaLabels []string,
 	flagsMap map[string]string,
@@ -128,8 +160,17 @@ func NewQueryAPI(
 	defaultMetadataTimeRange time.Duration,
 	disableCORS bool,
 	gate gate.Gate,
+	tenancyEnforcer *tenancy.Enforcer,
+	accounting *tenancy.Accounting,
+	tenantHeader string,
+	authorizer *authz.Authorizer,
+	auditLogger *audit.Logger,
+	lookbackDelta time.Duration,
 	reg *prometheus.Registry,
 ) *QueryAPI {
+	if tenancyEnforcer != nil {
+		c = tenantQueryableCreator(c, tenancyEnforcer.LabelName())
+	}
 	return &QueryAPI{
 		baseAPI:         api.NewBaseAPI(logger, disableCORS, flagsMap),
 		logger:          logger,
@@ -140,13 +181,16 @@ func NewQueryAPI(
 		targets:         targets,
 		metadatas:       metadatas,
 		exemplars:       exemplars,
+		tsdbStatus:      tsdbStatus,
 
 		enableAutodownsampling:                 enableAutodownsampling,
+		tsdbInfos:                              tsdbInfos,
 		enableQueryPartialResponse:             enableQueryPartialResponse,
 		enableRulePartialResponse:              enableRulePartialResponse,
 		enableTargetPartialResponse:            enableTargetPartialResponse,
 		enableMetricMetadataPartialResponse:    enableMetricMetadataPartialResponse,
 		enableExemplarPartialResponse:          enableExemplarPartialResponse,
+		enableTSDBStatusPartialResponse:        enableTSDBStatusPartialResponse,
 		enableQueryPushdown:                    enableQueryPushdown,
 		replicaLabels:                          replicaLabels,
 		endpointStatus:                         endpointStatus,
@@ -154,6 +198,12 @@ func NewQueryAPI(
 		defaultInstantQueryMaxSourceResolution: defaultInstantQueryMaxSourceResolution,
 		defaultMetadataTimeRange:               defaultMetadataTimeRange,
 		disableCORS:                            disableCORS,
+		tenancy:                                tenancyEnforcer,
+		accounting:                             accounting,
+		tenantHeader:                           tenantHeader,
+		authorizer:                             authorizer,
+		auditLogger:                            auditLogger,
+		lookbackDelta:                          lookbackDelta,
 
 		queryRangeHist: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
 			Name:    "thanos_query_range_requested_timespan_duration_seconds",
@@ -169,40 +219,285 @@ func (qapi *QueryAPI) Register(r *route.Router, tracer opentracing.Tracer, logge
 
 	instr := api.GetInstr(tracer, logger, ins, logMiddleware, qapi.disableCORS)
 
-	r.Get("/query", instr("query", qapi.query))
-	r.Post("/query", instr("query", qapi.query))
+	r.Get("/query", instr("query", qapi.tenantAware("query", qapi.audited("query", qapi.authorized("query", qapi.query)))))
+	r.Post("/query", instr("query", qapi.tenantAware("query", qapi.audited("query", qapi.authorized("query", qapi.query)))))
 
-	r.Get("/query_range", instr("query_range", qapi.queryRange))
-	r.Post("/query_range", instr("query_range", qapi.queryRange))
+	r.Get("/query_range", instr("query_range", qapi.tenantAware("query_range", qapi.audited("query_range", qapi.authorized("query_range", qapi.queryRange)))))
+	r.Post("/query_range", instr("query_range", qapi.tenantAware("query_range", qapi.audited("query_range", qapi.authorized("query_range", qapi.queryRange)))))
 
-	r.Get("/label/:name/values", instr("label_values", qapi.labelValues))
+	r.Get("/label/:name/values", instr("label_values", qapi.tenantAware("label_values", qapi.audited("label_values", qapi.authorized("label_values", qapi.labelValues)))))
 
-	r.Get("/series", instr("series", qapi.series))
-	r.Post("/series", instr("series", qapi.series))
+	r.Get("/series", instr("series", qapi.tenantAware("series", qapi.audited("series", qapi.authorized("series", withSeriesData(qapi.series))))))
+	r.Post("/series", instr("series", qapi.tenantAware("series", qapi.audited("series", qapi.authorized("series", withSeriesData(qapi.series))))))
 
-	r.Get("/labels", instr("label_names", qapi.labelNames))
-	r.Post("/labels", instr("label_names", qapi.labelNames))
+	r.Get("/labels", instr("label_names", qapi.tenantAware("label_names", qapi.audited("label_names", qapi.authorized("label_names", qapi.labelNames)))))
+	r.Post("/labels", instr("label_names", qapi.tenantAware("label_names", qapi.audited("label_names", qapi.authorized("label_names", qapi.labelNames)))))
 
 	r.Get("/stores", instr("stores", qapi.stores))
 
-	r.Get("/rules", instr("rules", NewRulesHandler(qapi.ruleGroups, qapi.enableRulePartialResponse)))
+	r.Get("/rules", instr("rules", qapi.tenantUnaware("rules", NewRulesHandler(qapi.ruleGroups, qapi.enableRulePartialResponse))))
+
+	r.Get("/targets", instr("targets", qapi.tenantUnaware("targets", NewTargetsHandler(qapi.targets, qapi.enableTargetPartialResponse))))
+
+	r.Get("/metadata", instr("metadata", qapi.tenantUnaware("metadata", NewMetricMetadataHandler(qapi.metadatas, qapi.enableMetricMetadataPartialResponse))))
+
+	r.Get("/query_exemplars", instr("exemplars", qapi.tenantUnaware("exemplars", NewExemplarsHandler(qapi.exemplars, qapi.enableExemplarPartialResponse))))
+	r.Post("/query_exemplars", instr("exemplars", qapi.tenantUnaware("exemplars", NewExemplarsHandler(qapi.exemplars, qapi.enableExemplarPartialResponse))))
+
+	r.Get("/status/tsdb", instr("tsdb_status", qapi.tenantUnaware("tsdb_status", NewTSDBStatusHandler(qapi.tsdbStatus, qapi.enableTSDBStatusPartialResponse))))
+}
+
+// tenantAware returns the ApiFunc of the given handler which, if tenancy is enforced, only calls f for requests of
+// allowed tenants, with the tenant in the context of the request. If the usage of tenants is accounted, the tenant
+// of requests is in their context, if any, even if tenancy is not enforced.
+func (qapi *QueryAPI) tenantAware(handler string, f api.ApiFunc) api.ApiFunc {
+	if qapi.tenancy == nil && qapi.accounting == nil {
+		return f
+	}
+	return func(r *http.Request) (interface{}, []error, *api.ApiError) {
+		if qapi.tenancy == nil {
+			tenant := strings.TrimSpace(r.Header.Get(qapi.tenantHeader))
+			qapi.accounting.Query(tenant)
+			if tenant == "" {
+				return f(r)
+			}
+			return f(r.WithContext(tenancy.ContextWithTenant(r.Context(), tenant)))
+		}
+
+		tenant, err := qapi.tenancy.Tenant(r, handler)
+		if err != nil {
+			if errors.Is(err, tenancy.ErrNoTenant) {
+				return nil, nil, &api.ApiError{Typ: api.ErrorUnauthorized, Err: err}
+			}
+			return nil, nil, &api.ApiError{Typ: api.ErrorForbidden, Err: err}
+		}
+		qapi.accounting.Query(tenant)
+		return f(r.WithContext(tenancy.ContextWithTenant(r.Context(), tenant)))
+	}
+}
+
+// tenantUnaware returns the ApiFunc of the given handler which, if tenancy is enforced, rejects all requests, since
+// the data of the handler can't be restricted to the tenant.
+func (qapi *QueryAPI) tenantUnaware(handler string, f api.ApiFunc) api.ApiFunc {
+	if qapi.tenancy == nil {
+		return f
+	}
+	return func(r *http.Request) (interface{}, []error, *api.ApiError) {
+		return nil, nil, &api.ApiError{Typ: api.ErrorForbidden, Err: errors.Errorf("%s is not available with enforced tenancy", handler)}
+	}
+}
+
+// authorized returns the ApiFunc of the given handler which, if there is an authorizer, only calls f for requests
+// allowed by it. Requests which can't be parsed are rejected, as they can't be authorized.
+func (qapi *QueryAPI) authorized(handler string, f api.ApiFunc) api.ApiFunc {
+	if qapi.authorizer == nil {
+		return f
+	}
+	return func(r *http.Request) (interface{}, []error, *api.ApiError) {
+		req, err := qapi.authzRequest(r, handler)
+		if err != nil {
+			return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: err}
+		}
+		req.Tenant, _ = tenancy.TenantFromContext(r.Context())
+		if err := qapi.authorizer.Authorize(r.Context(), req); err != nil {
+			if errors.Is(err, authz.ErrDenied) {
+				return nil, nil, &api.ApiError{Typ: api.ErrorForbidden, Err: err}
+			}
+			return nil, nil, &api.ApiError{Typ: api.ErrorInternal, Err: err}
+		}
+		return f(r)
+	}
+}
+
+// audited returns the ApiFunc of the given handler which, if there is an audit logger, records all requests with
+// their parameters, tenant, status and the size of their result.
+func (qapi *QueryAPI) audited(handler string, f api.ApiFunc) api.ApiFunc {
+	if qapi.auditLogger == nil {
+		return f
+	}
+	return func(r *http.Request) (interface{}, []error, *api.ApiError) {
+		start := time.Now()
+		data, warnings, apiErr := f(r)
+
+		// The form is parsed by the handlers already, so the parameters of POST requests are available.
+		rec := audit.Record{
+			Time:       start,
+			Handler:    handler,
+			RemoteAddr: r.RemoteAddr,
+			Query:      r.FormValue("query"),
+			Start:      r.FormValue("start"),
+			End:        r.FormValue("end"),
+			Step:       r.FormValue("step"),
+			Status:     "success",
+		}
+		if handler == "query" {
+			rec.Start = r.FormValue("time")
+		}
+		rec.Matchers = r.Form[MatcherParam]
+		if tenant, ok := tenancy.TenantFromContext(r.Context()); ok {
+			rec.Tenant = tenant
+		} else {
+			rec.Tenant = strings.TrimSpace(r.Header.Get(qapi.tenantHeader))
+		}
+		if apiErr != nil {
+			rec.Status = string(apiErr.Typ)
+			rec.Error = apiErr.Err.Error()
+		}
+		rec.ResultSeries, rec.ResultSamples = resultSize(data)
+		rec.Duration = time.Since(start).Seconds()
+		qapi.auditLogger.Log(r.Context(), rec)
+
+		return data, warnings, apiErr
+	}
+}
 
-	r.Get("/targets", instr("targets", NewTargetsHandler(qapi.targets, qapi.enableTargetPartialResponse)))
+// resultSize returns the number of series, or of label names or values, and the number of samples of the result of
+// a handler.
+func resultSize(data interface{}) (series, samples int) {
+	switch d := data.(type) {
+	case *queryData:
+		switch v := d.Result.(type) {
+		case promql.Matrix:
+			for _, s := range v {
+				samples += len(s.Points)
+			}
+			return len(v), samples
+		case promql.Vector:
+			return len(v), len(v)
+		case promql.Scalar:
+			return 0, 1
+		}
+	case []labels.Labels:
+		return len(d), 0
+	case seriesData:
+		return len(d), 0
+	case federation:
+		return len(d), len(d)
+	case []string:
+		return len(d), 0
+	}
+	return 0, 0
+}
 
-	r.Get("/metadata", instr("metadata", NewMetricMetadataHandler(qapi.metadatas, qapi.enableMetricMetadataPartialResponse)))
+// authzRequest returns the authorization request of the request of the given handler, with the selectors and the
+// time range of the data it selects.
+func (qapi *QueryAPI) authzRequest(r *http.Request, handler string) (authz.Request, error) {
+	switch handler {
+	case "query", "query_range":
+		var start, end time.Time
+		if handler == "query" {
+			ts, err := parseTimeParam(r, "time", qapi.baseAPI.Now())
+			if err != nil {
+				return authz.Request{}, err
+			}
+			start, end = ts, ts
+		} else {
+			var err error
+			if start, err = parseTime(r.FormValue("start")); err != nil {
+				return authz.Request{}, err
+			}
+			if end, err = parseTime(r.FormValue("end")); err != nil {
+				return authz.Request{}, err
+			}
+		}
+		expr, err := parser.ParseExpr(r.FormValue("query"))
+		if err != nil {
+			return authz.Request{}, err
+		}
+		return authz.QueryRequest(handler, r.FormValue("query"), expr, start, end, qapi.lookbackDelta), nil
+	default:
+		if err := r.ParseForm(); err != nil {
+			return authz.Request{}, err
+		}
+		var start, end time.Time
+		if handler == "federate" {
+			start, end = qapi.federationTimeRange()
+		} else {
+			var err error
+			if start, end, err = parseMetadataTimeRange(r, qapi.defaultMetadataTimeRange); err != nil {
+				return authz.Request{}, err
+			}
+		}
+		var matcherSets [][]*labels.Matcher
+		for _, s := range r.Form[MatcherParam] {
+			matchers, err := parser.ParseMetricSelector(s)
+			if err != nil {
+				return authz.Request{}, err
+			}
+			matcherSets = append(matcherSets, matchers)
+		}
+		req := authz.SeriesRequest(handler, matcherSets, start, end)
+		if handler == "label_values" {
+			req.LabelName = route.Param(r.Context(), "name")
+		}
+		return req, nil
+	}
+}
 
-	r.Get("/query_exemplars", instr("exemplars", NewExemplarsHandler(qapi.exemplars, qapi.enableExemplarPartialResponse)))
-	r.Post("/query_exemplars", instr("exemplars", NewExemplarsHandler(qapi.exemplars, qapi.enableExemplarPartialResponse)))
+// tenantQueryableCreator returns the QueryableCreator whose queryables only select the series of the tenant of the
+// context, i.e. with the tenant label set to it.
+func tenantQueryableCreator(c query.QueryableCreator, labelName string) query.QueryableCreator {
+	return func(deduplicate bool, replicaLabels []string, storeDebugMatchers [][]*labels.Matcher, maxResolutionMillis int64, partialResponse, enableQueryPushdown, skipChunks bool) storage.Queryable {
+		return tenancy.EnforcedQueryable(c(deduplicate, replicaLabels, storeDebugMatchers, maxResolutionMillis, partialResponse, enableQueryPushdown, skipChunks), labelName)
+	}
 }
 
 type queryData struct {
-	ResultType parser.ValueType  `json:"resultType"`
-	Result     parser.Value      `json:"result"`
-	Stats      *stats.QueryStats `json:"stats,omitempty"`
+	ResultType parser.ValueType `json:"resultType"`
+	Result     parser.Value     `json:"result"`
+	Stats      *queryStats      `json:"stats,omitempty"`
 	// Additional Thanos Response field.
 	Warnings []error `json:"warnings,omitempty"`
 }
 
+// queryStats are the statistics of a query which are returned if the stats parameter is set. With stats=all, the
+// timings of the engine are extended with the samples fetched from the stores and with the fan-out of the query
+// to the stores.
+type queryStats struct {
+	*stats.QueryStats
+	Samples *samplesStats `json:"samples,omitempty"`
+	// Additional Thanos Response field.
+	Stores map[string]store.StoreStats `json:"stores,omitempty"`
+}
+
+type samplesStats struct {
+	// TotalQueryableSamples is the number of samples fetched from the stores, before deduplication.
+	TotalQueryableSamples int `json:"totalQueryableSamples"`
+}
+
+// withQueryStats returns the context to execute the query with, recording the store fan-out if all the statistics
+// of the query are requested, or if the usage of tenants is accounted.
+func (qapi *QueryAPI) withQueryStats(ctx context.Context, r *http.Request) (context.Context, *store.QueryStats) {
+	if r.FormValue(Stats) != "all" && qapi.accounting == nil {
+		return ctx, nil
+	}
+	storeStats := store.NewQueryStats()
+	return context.WithValue(ctx, store.QueryStatsKey, storeStats), storeStats
+}
+
+// accountSamples counts the samples fetched from the stores for the executed query for its tenant, if the usage of
+// tenants is accounted.
+func (qapi *QueryAPI) accountSamples(ctx context.Context, storeStats *store.QueryStats) {
+	if qapi.accounting == nil || storeStats == nil {
+		return
+	}
+	tenant, _ := tenancy.TenantFromContext(ctx)
+	qapi.accounting.SamplesScanned(tenant, storeStats.Total().Samples)
+}
+
+// newQueryStats returns the statistics of the executed query if requested with the stats parameter.
+func newQueryStats(r *http.Request, qry promql.Query, storeStats *store.QueryStats) *queryStats {
+	if r.FormValue(Stats) == "" {
+		return nil
+	}
+	qs := &queryStats{QueryStats: stats.NewQueryStats(qry.Stats())}
+	if r.FormValue(Stats) == "all" && storeStats != nil {
+		qs.Samples = &samplesStats{TotalQueryableSamples: storeStats.Total().Samples}
+		qs.Stores = storeStats.Stores()
+	}
+	return qs
+}
+
 func (qapi *QueryAPI) parseEnableDedupParam(r *http.Request) (enableDeduplication bool, _ *api.ApiError) {
 	enableDeduplication = true
 
@@ -230,6 +525,30 @@ func (qapi *QueryAPI) parseReplicaLabelsParam(r *http.Request) (replicaLabels []
 	return replicaLabels, nil
 }
 
+// parseShardParams returns the context to create the querier with, which only selects the series of the shard given
+// by the shard parameters, if any.
+func (qapi *QueryAPI) parseShardParams(ctx context.Context, r *http.Request) (context.Context, *api.ApiError) {
+	if r.FormValue(ShardCountParam) == "" {
+		return ctx, nil
+	}
+	total, err := strconv.Atoi(r.FormValue(ShardCountParam))
+	if err != nil {
+		return nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Wrapf(err, "'%s' parameter", ShardCountParam)}
+	}
+	index, err := strconv.Atoi(r.FormValue(ShardIndexParam))
+	if err != nil {
+		return nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Wrapf(err, "'%s' parameter", ShardIndexParam)}
+	}
+	if total < 1 || index < 0 || index >= total {
+		return nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("'%s' must be between 0 and '%s' minus one", ShardIndexParam, ShardCountParam)}
+	}
+	by := r.Form[ShardByParam]
+	if len(by) == 0 {
+		return nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("'%s' parameter is required to shard queries", ShardByParam)}
+	}
+	return query.WithShard(ctx, query.ShardInfo{Index: index, Total: total, By: by}), nil
+}
+
 func (qapi *QueryAPI) parseStoreDebugMatchersParam(r *http.Request) (storeMatchers [][]*labels.Matcher, _ *api.ApiError) {
 	if err := r.ParseForm(); err != nil {
 		return nil, &api.ApiError{Typ: api.ErrorInternal, Err: errors.Wrap(err, "parse form")}
@@ -268,6 +587,22 @@ func (qapi *QueryAPI) parseDownsamplingParamMillis(r *http.Request, defaultVal t
 	return int64(maxSourceResolution / time.Millisecond), nil
 }
 
+// withAutoDownsampling marks the context of queries with an automatic max source resolution if the time ranges of the
+// resolutions of the stores are known, so that their queriers pick the max source resolution of each sub-range of
+// their selects. It returns the highest max source resolution picked between mint and maxt, which selects the engine.
+func (qapi *QueryAPI) withAutoDownsampling(ctx context.Context, r *http.Request, mint, maxt, maxSourceResolution int64) (context.Context, int64) {
+	val := r.FormValue(MaxSourceResolutionParam)
+	return qapi.withPlannedResolution(ctx, val == "auto" || (qapi.enableAutodownsampling && val == ""), mint, maxt, maxSourceResolution)
+}
+
+// withPlannedResolution is withAutoDownsampling for queries with an automatic max source resolution if auto is true.
+func (qapi *QueryAPI) withPlannedResolution(ctx context.Context, auto bool, mint, maxt, maxSourceResolution int64) (context.Context, int64) {
+	if qapi.tsdbInfos == nil || !auto {
+		return ctx, maxSourceResolution
+	}
+	return query.WithAutoDownsampling(ctx), query.MaxPlannedResolution(qapi.tsdbInfos(), mint, maxt, maxSourceResolution)
+}
+
 func (qapi *QueryAPI) parsePartialResponseParam(r *http.Request, defaultEnablePartialResponse bool) (enablePartialResponse bool, _ *api.ApiError) {
 	// Overwrite the cli flag when provided as a query parameter.
 	if val := r.FormValue(PartialResponseParam); val != "" {
@@ -280,6 +615,35 @@ func (qapi *QueryAPI) parsePartialResponseParam(r *http.Request, defaultEnablePa
 	return defaultEnablePartialResponse, nil
 }
 
+// parseLimitParam returns the maximum number of results to return, or 0 if it's not limited.
+func (qapi *QueryAPI) parseLimitParam(r *http.Request) (int, *api.ApiError) {
+	val := r.FormValue(LimitParam)
+	if val == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Wrapf(err, "'%s' parameter", LimitParam)}
+	}
+	if limit < 0 {
+		return 0, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("negative '%s' is not accepted. Try a positive integer", LimitParam)}
+	}
+	return limit, nil
+}
+
+// withLimit returns the context to create the querier with, which asks the stores for one more result than the limit,
+// so that it's known whether the results were truncated.
+func withLimit(ctx context.Context, limit int) context.Context {
+	if limit == 0 {
+		return ctx
+	}
+	return query.WithLimit(ctx, int64(limit)+1)
+}
+
+func truncatedWarning(limit int) error {
+	return errors.Errorf("results truncated due to limit of %d", limit)
+}
+
 func (qapi *QueryAPI) parseStep(r *http.Request, defaultRangeQueryStep time.Duration, rangeSeconds int64) (time.Duration, *api.ApiError) {
 	// Overwrite the cli flag when provided as a query parameter.
 	if val := r.FormValue(Step); val != "" {
@@ -338,7 +702,13 @@ func (qapi *QueryAPI) query(r *http.Request) (interface{}, []error, *api.ApiErro
 		return nil, nil, apiErr
 	}
 
-	qe := qapi.queryEngine(maxSourceResolution)
+	ctx, apiErr = qapi.parseShardParams(ctx, r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	ctx, engineResolution := qapi.withAutoDownsampling(ctx, r, timestamp.FromTime(ts), timestamp.FromTime(ts), maxSourceResolution)
+	qe := qapi.queryEngine(engineResolution)
 
 	// We are starting promQL tracing span here, because we have no control over promQL code.
 	span, ctx := tracing.StartSpan(ctx, "promql_instant_query")
@@ -357,7 +727,9 @@ func (qapi *QueryAPI) query(r *http.Request) (interface{}, []error, *api.ApiErro
 	}
 	defer qapi.gate.Done()
 
+	ctx, storeStats := qapi.withQueryStats(ctx, r)
 	res := qry.Exec(ctx)
+	qapi.accountSamples(ctx, storeStats)
 	if res.Err != nil {
 		switch res.Err.(type) {
 		case promql.ErrQueryCanceled:
@@ -371,14 +743,10 @@ func (qapi *QueryAPI) query(r *http.Request) (interface{}, []error, *api.ApiErro
 	}
 
 	// Optional stats field in response if parameter "stats" is not empty.
-	var qs *stats.QueryStats
-	if r.FormValue(Stats) != "" {
-		qs = stats.NewQueryStats(qry.Stats())
-	}
 	return &queryData{
 		ResultType: res.Value.Type(),
 		Result:     res.Value,
-		Stats:      qs,
+		Stats:      newQueryStats(r, qry, storeStats),
 	}, res.Warnings, nil
 }
 
@@ -451,7 +819,13 @@ func (qapi *QueryAPI) queryRange(r *http.Request) (interface{}, []error, *api.Ap
 		return nil, nil, apiErr
 	}
 
-	qe := qapi.queryEngine(maxSourceResolution)
+	ctx, apiErr = qapi.parseShardParams(ctx, r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	ctx, engineResolution := qapi.withAutoDownsampling(ctx, r, timestamp.FromTime(start), timestamp.FromTime(end), maxSourceResolution)
+	qe := qapi.queryEngine(engineResolution)
 
 	// Record the query range requested.
 	qapi.queryRangeHist.Observe(end.Sub(start).Seconds())
@@ -479,7 +853,9 @@ func (qapi *QueryAPI) queryRange(r *http.Request) (interface{}, []error, *api.Ap
 	}
 	defer qapi.gate.Done()
 
+	ctx, storeStats := qapi.withQueryStats(ctx, r)
 	res := qry.Exec(ctx)
+	qapi.accountSamples(ctx, storeStats)
 	if res.Err != nil {
 		switch res.Err.(type) {
 		case promql.ErrQueryCanceled:
@@ -491,14 +867,10 @@ func (qapi *QueryAPI) queryRange(r *http.Request) (interface{}, []error, *api.Ap
 	}
 
 	// Optional stats field in response if parameter "stats" is not empty.
-	var qs *stats.QueryStats
-	if r.FormValue(Stats) != "" {
-		qs = stats.NewQueryStats(qry.Stats())
-	}
 	return &queryData{
 		ResultType: res.Value.Type(),
 		Result:     res.Value,
-		Stats:      qs,
+		Stats:      newQueryStats(r, qry, storeStats),
 	}, res.Warnings, nil
 }
 
@@ -534,8 +906,13 @@ func (qapi *QueryAPI) labelValues(r *http.Request) (interface{}, []error, *api.A
 		matcherSets = append(matcherSets, matchers)
 	}
 
+	limit, apiErr := qapi.parseLimitParam(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
 	q, err := qapi.queryableCreate(true, nil, storeDebugMatchers, 0, enablePartialResponse, qapi.enableQueryPushdown, true).
-		Querier(ctx, timestamp.FromTime(start), timestamp.FromTime(end))
+		Querier(withLimit(ctx, limit), timestamp.FromTime(start), timestamp.FromTime(end))
 	if err != nil {
 		return nil, nil, &api.ApiError{Typ: api.ErrorExec, Err: err}
 	}
@@ -574,6 +951,10 @@ func (qapi *QueryAPI) labelValues(r *http.Request) (interface{}, []error, *api.A
 	if vals == nil {
 		vals = make([]string, 0)
 	}
+	if limit > 0 && len(vals) > limit {
+		vals = vals[:limit]
+		warnings = append(warnings, truncatedWarning(limit))
+	}
 
 	return vals, warnings, nil
 }
@@ -621,8 +1002,13 @@ func (qapi *QueryAPI) series(r *http.Request) (interface{}, []error, *api.ApiErr
 		return nil, nil, apiErr
 	}
 
+	limit, apiErr := qapi.parseLimitParam(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
 	q, err := qapi.queryableCreate(enableDedup, replicaLabels, storeDebugMatchers, math.MaxInt64, enablePartialResponse, qapi.enableQueryPushdown, true).
-		Querier(r.Context(), timestamp.FromTime(start), timestamp.FromTime(end))
+		Querier(withLimit(r.Context(), limit), timestamp.FromTime(start), timestamp.FromTime(end))
 	if err != nil {
 		return nil, nil, &api.ApiError{Typ: api.ErrorExec, Err: err}
 	}
@@ -639,11 +1025,19 @@ func (qapi *QueryAPI) series(r *http.Request) (interface{}, []error, *api.ApiErr
 	set := storage.NewMergeSeriesSet(sets, storage.ChainedSeriesMerge)
 	for set.Next() {
 		metrics = append(metrics, set.At().Labels())
+		if limit > 0 && len(metrics) > limit {
+			break
+		}
 	}
 	if set.Err() != nil {
 		return nil, nil, &api.ApiError{Typ: api.ErrorExec, Err: set.Err()}
 	}
-	return metrics, set.Warnings(), nil
+	warnings := set.Warnings()
+	if limit > 0 && len(metrics) > limit {
+		metrics = metrics[:limit]
+		warnings = append(warnings, truncatedWarning(limit))
+	}
+	return metrics, warnings, nil
 }
 
 func (qapi *QueryAPI) labelNames(r *http.Request) (interface{}, []error, *api.ApiError) {
@@ -671,8 +1065,13 @@ func (qapi *QueryAPI) labelNames(r *http.Request) (interface{}, []error, *api.Ap
 		matcherSets = append(matcherSets, matchers)
 	}
 
+	limit, apiErr := qapi.parseLimitParam(r)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
 	q, err := qapi.queryableCreate(true, nil, storeDebugMatchers, 0, enablePartialResponse, qapi.enableQueryPushdown, true).
-		Querier(r.Context(), timestamp.FromTime(start), timestamp.FromTime(end))
+		Querier(withLimit(r.Context(), limit), timestamp.FromTime(start), timestamp.FromTime(end))
 	if err != nil {
 		return nil, nil, &api.ApiError{Typ: api.ErrorExec, Err: err}
 	}
@@ -712,6 +1111,10 @@ func (qapi *QueryAPI) labelNames(r *http.Request) (interface{}, []error, *api.Ap
 	if names == nil {
 		names = make([]string, 0)
 	}
+	if limit > 0 && len(names) > limit {
+		names = names[:limit]
+		warnings = append(warnings, truncatedWarning(limit))
+	}
 
 	return names, warnings, nil
 }
@@ -760,6 +1163,28 @@ func NewTargetsHandler(client targets.UnaryClient, enablePartialResponse bool) f
 	}
 }
 
+// NewTSDBStatusHandler created handler compatible with HTTP /api/v1/status/tsdb https://prometheus.io/docs/prometheus/latest/querying/api/#tsdb-stats
+// which uses gRPC Unary TSDBStatus API.
+func NewTSDBStatusHandler(client tsdbstatus.UnaryClient, enablePartialResponse bool) func(*http.Request) (interface{}, []error, *api.ApiError) {
+	ps := storepb.PartialResponseStrategy_ABORT
+	if enablePartialResponse {
+		ps = storepb.PartialResponseStrategy_WARN
+	}
+
+	return func(r *http.Request) (interface{}, []error, *api.ApiError) {
+		req := &tsdbstatuspb.TSDBStatusRequest{
+			PartialResponseStrategy: ps,
+		}
+
+		s, warnings, err := client.TSDBStatus(r.Context(), req)
+		if err != nil {
+			return nil, nil, &api.ApiError{Typ: api.ErrorInternal, Err: errors.Wrap(err, "retrieving tsdb status")}
+		}
+
+		return s, warnings, nil
+	}
+}
+
 // NewRulesHandler created handler compatible with HTTP /api/v1/rules https://prometheus.io/docs/prometheus/latest/querying/api/#rules
 // which uses gRPC Unary Rules API.
 func NewRulesHandler(client rules.UnaryClient, enablePartialResponse bool) func(*http.Request) (interface{}, []error, *api.ApiError) {
@@ -791,6 +1216,26 @@ func NewRulesHandler(client rules.UnaryClient, enablePartialResponse bool) func(
 		req := &rulespb.RulesRequest{
 			Type:                    rulespb.RulesRequest_Type(typ),
 			PartialResponseStrategy: ps,
+			MatcherString:           r.URL.Query()["match[]"],
+			RuleName:                r.URL.Query()["rule_name[]"],
+			Health:                  r.URL.Query().Get("health"),
+		}
+		for _, m := range req.MatcherString {
+			if _, err := parser.ParseMetricSelector(m); err != nil {
+				return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Wrapf(err, "invalid rules parameter match[]='%v'", m)}
+			}
+		}
+		switch req.Health {
+		case "", string(promrules.HealthGood), string(promrules.HealthBad), string(promrules.HealthUnknown):
+		default:
+			return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("invalid rules parameter health='%v'", req.Health)}
+		}
+		for _, stateParam := range r.URL.Query()["state"] {
+			state, ok := rulespb.AlertState_value[strings.ToUpper(stateParam)]
+			if !ok {
+				return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("invalid rules parameter state='%v'", stateParam)}
+			}
+			req.AlertState = append(req.AlertState, rulespb.AlertState(state))
 		}
 		tracing.DoInSpan(ctx, "retrieve_rules", func(ctx context.Context) {
 			groups, warnings, err = client.Rules(ctx, req)
@@ -949,7 +1394,7 @@ func NewMetricMetadataHandler(client metadata.UnaryClient, enablePartialResponse
 		if limitStr != "" {
 			limit, err := strconv.ParseInt(limitStr, 10, 32)
 			if err != nil {
-				return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("invalid metric metadata limit='%v'", limit)}
+				return nil, nil, &api.ApiError{Typ: api.ErrorBadData, Err: errors.Errorf("invalid metric metadata limit='%v'", limitStr)}
 			}
 			req.Limit = int32(limit)
 		}
@@ -25,6 +25,7 @@ import (
 	"math"
 	"math/rand"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"strings"
@@ -32,6 +33,9 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/golang/snappy"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/route"
@@ -49,13 +53,18 @@ import (
 	"github.com/thanos-io/thanos/pkg/compact"
 
 	baseAPI "github.com/thanos-io/thanos/pkg/api"
+	"github.com/thanos-io/thanos/pkg/api/query/querypb"
+	"github.com/thanos-io/thanos/pkg/authz"
 	"github.com/thanos-io/thanos/pkg/component"
+	extpromhttp "github.com/thanos-io/thanos/pkg/extprom/http"
 	"github.com/thanos-io/thanos/pkg/gate"
+	"github.com/thanos-io/thanos/pkg/logging"
 	"github.com/thanos-io/thanos/pkg/query"
 	"github.com/thanos-io/thanos/pkg/rules/rulespb"
 	"github.com/thanos-io/thanos/pkg/store"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/store/storepb/prompb"
 	"github.com/thanos-io/thanos/pkg/testutil"
 	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
 	"github.com/thanos-io/thanos/pkg/testutil/testpromcompatibility"
@@ -539,6 +548,29 @@ func TestQueryEndpoints(t *testing.T) {
 			},
 			errType: baseAPI.ErrorBadData,
 		},
+		// Bad shard parameters.
+		{
+			endpoint: api.query,
+			query: url.Values{
+				"query":       []string{"sum by (foo) (test_metric1)"},
+				"shard_index": []string{"2"},
+				"shard_count": []string{"2"},
+				"shard_by[]":  []string{"foo"},
+			},
+			errType: baseAPI.ErrorBadData,
+		},
+		{
+			endpoint: api.queryRange,
+			query: url.Values{
+				"query":       []string{"sum by (foo) (test_metric1)"},
+				"start":       []string{"0"},
+				"end":         []string{"2"},
+				"step":        []string{"1"},
+				"shard_index": []string{"0"},
+				"shard_count": []string{"2"},
+			},
+			errType: baseAPI.ErrorBadData,
+		},
 		// Bad query expression.
 		{
 			endpoint: api.query,
@@ -628,7 +660,7 @@ func TestQueryEndpoints(t *testing.T) {
 				"stats": []string{"true"},
 			},
 			response: &queryData{
-				Stats: &stats.QueryStats{},
+				Stats: &queryStats{QueryStats: &stats.QueryStats{}},
 			},
 		},
 	}
@@ -638,6 +670,56 @@ func TestQueryEndpoints(t *testing.T) {
 			return
 		}
 	}
+
+	// With stats=all, the samples fetched from the stores are returned as well.
+	for i, test := range []endpointTestCase{
+		{
+			endpoint: api.query,
+			query: url.Values{
+				"query": []string{"test_metric1"},
+				"time":  []string{"123.4"},
+				"stats": []string{"all"},
+			},
+		},
+		{
+			endpoint: api.queryRange,
+			query: url.Values{
+				"query": []string{"test_metric1"},
+				"start": []string{"0"},
+				"end":   []string{"500"},
+				"step":  []string{"1"},
+				"stats": []string{"all"},
+			},
+		},
+	} {
+		if ok := testEndpoint(t, test, fmt.Sprintf("#%d %s", i, test.query.Encode()), func(a, _ interface{}) bool {
+			qs := a.(*queryData).Stats
+			return qs != nil && qs.QueryStats != nil && qs.Samples != nil
+		}); !ok {
+			return
+		}
+	}
+
+	// Each group of a sharded aggregation is returned by exactly one of the shards.
+	api.replicaLabels = []string{"replica"}
+	groups := map[string]int{}
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com?"+url.Values{
+			"query":       []string{"count by (foo) (test_metric_replica1)"},
+			"time":        []string{"123.4"},
+			"dedup":       []string{"true"},
+			"shard_index": []string{fmt.Sprint(i)},
+			"shard_count": []string{"3"},
+			"shard_by[]":  []string{"foo"},
+		}.Encode(), nil)
+		testutil.Ok(t, err)
+		resp, _, apiErr := api.query(req)
+		testutil.Assert(t, apiErr == nil, "unexpected error: %v", apiErr)
+		for _, s := range resp.(*queryData).Result.(promql.Vector) {
+			groups[s.Metric.Get("foo")]++
+		}
+	}
+	testutil.Equals(t, map[string]int{"bar": 1, "boo": 1}, groups)
 }
 
 func TestMetadataEndpoints(t *testing.T) {
@@ -816,6 +898,40 @@ func TestMetadataEndpoints(t *testing.T) {
 				"replica1",
 			},
 		},
+		{
+			endpoint: api.labelNames,
+			query: url.Values{
+				"limit": []string{"2"},
+			},
+			response: []string{
+				"__name__",
+				"foo",
+			},
+		},
+		{
+			endpoint: api.labelValues,
+			query: url.Values{
+				"limit": []string{"3"},
+			},
+			params: map[string]string{
+				"name": "__name__",
+			},
+			response: []string{
+				"test_metric1",
+				"test_metric2",
+				"test_metric_replica1",
+			},
+		},
+		{
+			endpoint: api.labelValues,
+			query: url.Values{
+				"limit": []string{"abc"},
+			},
+			params: map[string]string{
+				"name": "__name__",
+			},
+			errType: baseAPI.ErrorBadData,
+		},
 		{
 			endpoint: apiWithLabelLookback.labelNames,
 			response: []string{
@@ -1177,6 +1293,26 @@ func TestMetadataEndpoints(t *testing.T) {
 			},
 			method: http.MethodPost,
 		},
+		// Limit the number of series.
+		{
+			endpoint: api.series,
+			query: url.Values{
+				"match[]": []string{`test_metric_replica1`},
+				"limit":   []string{"2"},
+			},
+			response: []labels.Labels{
+				labels.FromStrings("__name__", "test_metric_replica1", "foo", "bar", "replica", "a"),
+				labels.FromStrings("__name__", "test_metric_replica1", "foo", "boo", "replica", "a"),
+			},
+		},
+		{
+			endpoint: api.series,
+			query: url.Values{
+				"match[]": []string{`test_metric_replica1`},
+				"limit":   []string{"-1"},
+			},
+			errType: baseAPI.ErrorBadData,
+		},
 		// Missing match[] query params in series requests.
 		{
 			endpoint: api.series,
@@ -1858,3 +1994,179 @@ func (s sample) T() int64 {
 func (s sample) V() float64 {
 	return s.v
 }
+
+func TestAdminAPI_Authentication(t *testing.T) {
+	a := NewAdminAPI(nil, "secret", "THANOS-TENANT", false)
+	called := false
+	f := a.authenticated(func(r *http.Request) (interface{}, []error, *baseAPI.ApiError) {
+		called = true
+		return nil, nil, nil
+	})
+
+	for _, tcase := range []struct {
+		header string
+		ok     bool
+	}{
+		{header: "", ok: false},
+		{header: "Bearer wrong", ok: false},
+		{header: "secret", ok: false},
+		{header: "Bearer secret", ok: true},
+	} {
+		called = false
+		r := httptest.NewRequest(http.MethodPost, "/tsdb/delete_series", nil)
+		if tcase.header != "" {
+			r.Header.Set("Authorization", tcase.header)
+		}
+		_, _, apiErr := f(r)
+		testutil.Equals(t, tcase.ok, called)
+		if !tcase.ok {
+			testutil.Equals(t, baseAPI.ErrorUnauthorized, apiErr.Typ)
+		}
+	}
+}
+
+func TestAdminAPI_DeleteSeriesWithoutTenant(t *testing.T) {
+	a := NewAdminAPI(nil, "secret", "THANOS-TENANT", false)
+	r := httptest.NewRequest(http.MethodPost, "/tsdb/delete_series?match[]=up", nil)
+	_, _, apiErr := a.deleteSeries(r)
+	testutil.Equals(t, baseAPI.ErrorBadData, apiErr.Typ)
+	testutil.Equals(t, "no tenant specified in the THANOS-TENANT header", apiErr.Err.Error())
+}
+
+func TestQueryAPI_Authorized(t *testing.T) {
+	var received []authz.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input authz.Request `json:"input"`
+		}
+		testutil.Ok(t, json.NewDecoder(r.Body).Decode(&req))
+		received = append(received, req.Input)
+		_, _ = w.Write([]byte(`{"result": true}`))
+	}))
+	defer srv.Close()
+
+	a, err := authz.NewAuthorizer(log.NewNopLogger(), prometheus.NewRegistry(), []byte("url: "+srv.URL))
+	testutil.Ok(t, err)
+	now := time.Now()
+	qapi := &QueryAPI{
+		baseAPI:    &baseAPI.BaseAPI{Now: func() time.Time { return now }},
+		authorizer: a,
+	}
+
+	called := false
+	handler := func(r *http.Request) (interface{}, []error, *baseAPI.ApiError) {
+		called = true
+		return nil, nil, nil
+	}
+
+	// Requests which can't be parsed never reach the handler.
+	for _, tcase := range []struct {
+		handler string
+		url     string
+	}{
+		{handler: "query", url: "/api/v1/query?query=sum(up"},
+		{handler: "query", url: "/api/v1/query?query=up&time=invalid"},
+		{handler: "query_range", url: "/api/v1/query_range?query=up&start=invalid&end=0"},
+		{handler: "series", url: "/api/v1/series?match[]=up{"},
+	} {
+		called = false
+		_, _, apiErr := qapi.authorized(tcase.handler, handler)(httptest.NewRequest(http.MethodGet, tcase.url, nil))
+		testutil.Assert(t, apiErr != nil, "expected error for %s", tcase.url)
+		testutil.Equals(t, baseAPI.ErrorBadData, apiErr.Typ)
+		testutil.Assert(t, !called, "expected %s not to reach the handler", tcase.url)
+	}
+	testutil.Equals(t, 0, len(received))
+
+	// The label name of label values requests is sent to the webhook.
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/label/job/values?match[]=up", nil)
+	r = r.WithContext(route.WithParam(r.Context(), "name", "job"))
+	_, _, apiErr := qapi.authorized("label_values", handler)(r)
+	testutil.Assert(t, apiErr == nil, "unexpected error %v", apiErr)
+	testutil.Assert(t, called, "expected request to reach the handler")
+	testutil.Equals(t, 1, len(received))
+	testutil.Equals(t, "label_values", received[0].Handler)
+	testutil.Equals(t, "job", received[0].LabelName)
+	testutil.Equals(t, []string{`{__name__="up"}`}, received[0].Selectors)
+}
+
+func TestProtobufResponse(t *testing.T) {
+	instr := baseAPI.GetInstr(&opentracing.NoopTracer{}, log.NewNopLogger(), extpromhttp.NewNopInstrumentationMiddleware(), logging.NewHTTPServerMiddleware(log.NewNopLogger()), false)
+
+	matrix := promql.Matrix{{Metric: labels.FromStrings("__name__", "up", "job", "a"), Points: []promql.Point{{T: 1000, V: 1}, {T: 2000, V: 0}}}}
+	query := instr("query", func(r *http.Request) (interface{}, []error, *baseAPI.ApiError) {
+		return &queryData{ResultType: parser.ValueTypeMatrix, Result: matrix}, []error{errors.New("partial")}, nil
+	})
+	series := instr("series", withSeriesData(func(r *http.Request) (interface{}, []error, *baseAPI.ApiError) {
+		return []labels.Labels{labels.FromStrings("__name__", "up", "job", "a")}, nil, nil
+	}))
+
+	// Without the protobuf media type the response is JSON.
+	w := httptest.NewRecorder()
+	series(w, httptest.NewRequest(http.MethodGet, "/series", nil))
+	testutil.Equals(t, "application/json", w.Header().Get("Content-Type"))
+	testutil.Equals(t, `{"status":"success","data":[{"__name__":"up","job":"a"}]}`, strings.TrimSpace(w.Body.String()))
+
+	r := httptest.NewRequest(http.MethodGet, "/series", nil)
+	r.Header.Set("Accept", "application/x-protobuf, application/json;q=0.5")
+	w = httptest.NewRecorder()
+	series(w, r)
+	testutil.Equals(t, baseAPI.ContentTypeProtobuf, w.Header().Get("Content-Type"))
+	var seriesResp querypb.SeriesResponse
+	testutil.Ok(t, seriesResp.Unmarshal(w.Body.Bytes()))
+	testutil.Equals(t, []labels.Labels{labels.FromStrings("__name__", "up", "job", "a")}, labelpb.ZLabelSetsToPromLabelSets(seriesResp.Series...))
+
+	r = httptest.NewRequest(http.MethodGet, "/query_range", nil)
+	r.Header.Set("Accept", "application/x-protobuf")
+	r.Header.Set("Accept-Encoding", "snappy")
+	w = httptest.NewRecorder()
+	query(w, r)
+	testutil.Equals(t, baseAPI.ContentTypeProtobuf, w.Header().Get("Content-Type"))
+	testutil.Equals(t, "snappy", w.Header().Get("Content-Encoding"))
+	b, err := snappy.Decode(nil, w.Body.Bytes())
+	testutil.Ok(t, err)
+	var queryResp querypb.QueryResponse
+	testutil.Ok(t, queryResp.Unmarshal(b))
+	testutil.Equals(t, querypb.QueryResponse{
+		ResultType: "matrix",
+		Series: []prompb.TimeSeries{{
+			Labels:  labelpb.ZLabelsFromPromLabels(labels.FromStrings("__name__", "up", "job", "a")),
+			Samples: []prompb.Sample{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 0}},
+		}},
+		Warnings: []string{"partial"},
+	}, queryResp)
+}
+
+func TestResultSize(t *testing.T) {
+	for _, tc := range []struct {
+		data            interface{}
+		series, samples int
+	}{
+		{
+			data:    &queryData{ResultType: parser.ValueTypeMatrix, Result: promql.Matrix{{Points: []promql.Point{{T: 1, V: 1}, {T: 2, V: 1}}}, {Points: []promql.Point{{T: 1, V: 1}}}}},
+			series:  2,
+			samples: 3,
+		},
+		{
+			data:    &queryData{ResultType: parser.ValueTypeVector, Result: promql.Vector{{}, {}}},
+			series:  2,
+			samples: 2,
+		},
+		{
+			data:    &queryData{ResultType: parser.ValueTypeScalar, Result: promql.Scalar{T: 1, V: 1}},
+			samples: 1,
+		},
+		{
+			data:   seriesData{labels.FromStrings("job", "a")},
+			series: 1,
+		},
+		{
+			data:   []string{"a", "b", "c"},
+			series: 3,
+		},
+		{},
+	} {
+		series, samples := resultSize(tc.data)
+		testutil.Equals(t, tc.series, series)
+		testutil.Equals(t, tc.samples, samples)
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package audit writes records of the queries of users to rotating files which are uploaded to object storage, for
+// environments where the access to the data has to be audited.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+
+	thanosmodel "github.com/thanos-io/thanos/pkg/model"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/objstore/client"
+	"github.com/thanos-io/thanos/pkg/oidc"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+const (
+	// fileExt is the extension of files with records. Files are named after the ULID of their creation time.
+	fileExt = ".jsonl"
+	// openFileExt is the extension of the file records are written to. It is renamed on rotation.
+	openFileExt = fileExt + ".open"
+
+	uploadTimeout = time.Minute
+)
+
+// Config is the configuration of the audit log.
+type Config struct {
+	// Directory is the local directory of the files of records until they are uploaded.
+	Directory string `yaml:"directory"`
+	// MaxFileSize is the size of files at which they are rotated.
+	MaxFileSize thanosmodel.Bytes `yaml:"max_file_size"`
+	// RotationInterval is the interval at which files are rotated and uploaded.
+	RotationInterval model.Duration `yaml:"rotation_interval"`
+	// Claims are the claims of the tokens of authenticated users which are recorded.
+	Claims []string `yaml:"claims"`
+	// Prefix is the prefix of the files in the bucket.
+	Prefix string `yaml:"prefix"`
+	// Objstore is the configuration of the bucket, in the same format as the one of the --objstore.config flag.
+	Objstore client.BucketConfig `yaml:"objstore"`
+}
+
+// DefaultConfig is the default configuration of the audit log, without bucket.
+var DefaultConfig = Config{
+	Directory:        "./audit",
+	MaxFileSize:      64 * 1024 * 1024,
+	RotationInterval: model.Duration(5 * time.Minute),
+	Claims:           []string{"sub"},
+	Prefix:           "audit",
+}
+
+func parseConfig(conf []byte) (Config, error) {
+	config := DefaultConfig
+	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
+		return Config{}, errors.Wrap(err, "parsing audit config YAML")
+	}
+	if config.Directory == "" {
+		return Config{}, errors.New("no directory configured")
+	}
+	if config.MaxFileSize == 0 {
+		return Config{}, errors.New("max_file_size has to be positive")
+	}
+	if config.RotationInterval <= 0 {
+		return Config{}, errors.New("rotation_interval has to be positive")
+	}
+	return config, nil
+}
+
+// Record is the record of a request.
+type Record struct {
+	Time       time.Time              `json:"time"`
+	Component  string                 `json:"component"`
+	Handler    string                 `json:"handler"`
+	RemoteAddr string                 `json:"remote_addr"`
+	Tenant     string                 `json:"tenant,omitempty"`
+	Claims     map[string]interface{} `json:"claims,omitempty"`
+	// Query is the PromQL expression of query requests.
+	Query string `json:"query,omitempty"`
+	// Matchers are the series selectors of series and labels requests.
+	Matchers []string `json:"matchers,omitempty"`
+	// Start, End and Step are the time range of requests, as given by the user.
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	Step  string `json:"step,omitempty"`
+	// Status is success, or the type of error of failed requests.
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// ResultSeries is the number of series, or of label names or values, of the result.
+	ResultSeries int `json:"result_series"`
+	// ResultSamples is the number of samples of the result of queries.
+	ResultSamples int `json:"result_samples,omitempty"`
+	// ResponseBytes is the size of the response of requests recorded by the HTTP middleware.
+	ResponseBytes int     `json:"response_bytes,omitempty"`
+	Duration      float64 `json:"duration_seconds"`
+}
+
+// Logger writes records to files in the directory, and uploads them to the bucket once they are rotated. Files which
+// could not be uploaded, e.g. because of a crash, are uploaded on the next rotation. A nil Logger records nothing.
+type Logger struct {
+	logger           log.Logger
+	bkt              objstore.Bucket
+	dir              string
+	prefix           string
+	component        string
+	maxFileSize      int64
+	rotationInterval time.Duration
+	claims           []string
+
+	mtx  sync.Mutex
+	f    *os.File
+	size int64
+	// entropy makes the ULIDs of files created in the same millisecond increase, so that their records stay in order.
+	entropy io.Reader
+
+	uploadc chan struct{}
+
+	records        prometheus.Counter
+	recordFailures prometheus.Counter
+	uploads        prometheus.Counter
+	uploadFailures prometheus.Counter
+}
+
+// NewLogger returns a Logger with the given configuration. The files are uploaded to
+// <prefix>/<component>/<instance>/<date>/<ULID>.jsonl in the bucket, where instance is the hostname.
+func NewLogger(logger log.Logger, reg prometheus.Registerer, confContentYaml []byte, component string) (*Logger, error) {
+	level.Info(logger).Log("msg", "loading audit configuration")
+	config, err := parseConfig(confContentYaml)
+	if err != nil {
+		return nil, err
+	}
+	bktConf, err := yaml.Marshal(config.Objstore)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal bucket configuration")
+	}
+	// The metrics of the bucket are not registered, as they would collide with the ones of the bucket of the component.
+	bkt, err := client.NewBucket(logger, bktConf, nil, "audit")
+	if err != nil {
+		return nil, errors.Wrap(err, "create audit bucket")
+	}
+	return newLogger(logger, reg, bkt, config, component)
+}
+
+func newLogger(logger log.Logger, reg prometheus.Registerer, bkt objstore.Bucket, config Config, component string) (*Logger, error) {
+	if err := os.MkdirAll(config.Directory, 0750); err != nil {
+		return nil, errors.Wrap(err, "create audit directory")
+	}
+	// Files which were open when the process stopped are complete, as records are written at once.
+	open, err := filepath.Glob(filepath.Join(config.Directory, "*"+openFileExt))
+	if err != nil {
+		return nil, errors.Wrap(err, "list audit files")
+	}
+	for _, name := range open {
+		if err := os.Rename(name, strings.TrimSuffix(name, openFileExt)+fileExt); err != nil {
+			return nil, errors.Wrap(err, "close audit file")
+		}
+	}
+
+	instance, err := os.Hostname()
+	if err != nil {
+		return nil, errors.Wrap(err, "get hostname")
+	}
+
+	return &Logger{
+		logger:           log.With(logger, "component", "audit"),
+		bkt:              bkt,
+		dir:              config.Directory,
+		prefix:           path.Join(config.Prefix, component, instance),
+		component:        component,
+		maxFileSize:      int64(config.MaxFileSize),
+		rotationInterval: time.Duration(config.RotationInterval),
+		claims:           config.Claims,
+		entropy:          ulid.Monotonic(rand.New(rand.NewSource(time.Now().UnixNano())), 0),
+		uploadc:          make(chan struct{}, 1),
+		records: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_audit_records_total",
+			Help: "Total number of audit records written.",
+		}),
+		recordFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_audit_record_failures_total",
+			Help: "Total number of audit records which failed to be written.",
+		}),
+		uploads: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_audit_uploads_total",
+			Help: "Total number of files of audit records uploaded.",
+		}),
+		uploadFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_audit_upload_failures_total",
+			Help: "Total number of files of audit records which failed to be uploaded.",
+		}),
+	}, nil
+}
+
+// Log writes the record, with the component, the time if not set, and the configured claims of the token of the
+// authenticated user of the context.
+func (l *Logger) Log(ctx context.Context, rec Record) {
+	if l == nil {
+		return
+	}
+	rec.Component = l.component
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	if claims, ok := oidc.ClaimsFromContext(ctx); ok {
+		for _, c := range l.claims {
+			if v, ok := claims[c]; ok {
+				if rec.Claims == nil {
+					rec.Claims = map[string]interface{}{}
+				}
+				rec.Claims[c] = v
+			}
+		}
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		l.fail(err)
+		return
+	}
+	b = append(b, '\n')
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.f == nil {
+		id, err := ulid.New(ulid.Now(), l.entropy)
+		if err != nil {
+			l.fail(errors.Wrap(err, "create ULID of audit file"))
+			return
+		}
+		name := filepath.Join(l.dir, id.String()+openFileExt)
+		if l.f, err = os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640); err != nil {
+			l.f = nil
+			l.fail(errors.Wrap(err, "create audit file"))
+			return
+		}
+		l.size = 0
+	}
+	// Records are written at once, so that files are complete if the process stops.
+	n, err := l.f.Write(b)
+	l.size += int64(n)
+	if err != nil {
+		l.fail(errors.Wrap(err, "write audit record"))
+		return
+	}
+	l.records.Inc()
+
+	if l.size >= l.maxFileSize {
+		if err := l.rotate(); err != nil {
+			level.Error(l.logger).Log("msg", "rotating audit file failed", "err", err)
+		}
+		select {
+		case l.uploadc <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (l *Logger) fail(err error) {
+	level.Error(l.logger).Log("msg", "writing audit record failed", "err", err)
+	l.recordFailures.Inc()
+}
+
+// rotate closes the open file, so that it is uploaded. It has to be called with the lock held.
+func (l *Logger) rotate() error {
+	if l.f == nil {
+		return nil
+	}
+	f := l.f
+	l.f = nil
+	if err := f.Close(); err != nil {
+		return err
+	}
+	name := f.Name()
+	return os.Rename(name, strings.TrimSuffix(name, openFileExt)+fileExt)
+}
+
+// Run rotates and uploads the files every rotation interval, or once they reach the maximum size, until the context
+// is canceled. The open file is uploaded before it returns.
+func (l *Logger) Run(ctx context.Context) error {
+	defer func() {
+		l.mtx.Lock()
+		if err := l.rotate(); err != nil {
+			level.Error(l.logger).Log("msg", "rotating audit file failed", "err", err)
+		}
+		l.mtx.Unlock()
+		l.upload(context.Background())
+		if err := l.bkt.Close(); err != nil {
+			level.Warn(l.logger).Log("msg", "closing audit bucket failed", "err", err)
+		}
+	}()
+
+	// Upload the files of previous runs.
+	l.upload(ctx)
+
+	tick := time.NewTicker(l.rotationInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+			l.mtx.Lock()
+			if err := l.rotate(); err != nil {
+				level.Error(l.logger).Log("msg", "rotating audit file failed", "err", err)
+			}
+			l.mtx.Unlock()
+		case <-l.uploadc:
+		}
+		l.upload(ctx)
+	}
+}
+
+// upload uploads the rotated files and removes them once uploaded.
+func (l *Logger) upload(ctx context.Context) {
+	names, err := filepath.Glob(filepath.Join(l.dir, "*"+fileExt))
+	if err != nil {
+		level.Error(l.logger).Log("msg", "listing audit files failed", "err", err)
+		return
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := l.uploadFile(ctx, name); err != nil {
+			level.Error(l.logger).Log("msg", "uploading audit file failed", "file", name, "err", err)
+			l.uploadFailures.Inc()
+			continue
+		}
+		l.uploads.Inc()
+		if err := os.Remove(name); err != nil {
+			level.Warn(l.logger).Log("msg", "removing uploaded audit file failed", "file", name, "err", err)
+		}
+	}
+}
+
+func (l *Logger) uploadFile(ctx context.Context, name string) error {
+	base := filepath.Base(name)
+	id, err := ulid.Parse(strings.TrimSuffix(base, fileExt))
+	if err != nil {
+		return errors.Wrap(err, "parse ULID of file name")
+	}
+	date := ulid.Time(id.Time()).UTC().Format("2006-01-02")
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer runutil.CloseWithLogOnErr(l.logger, f, "close audit file %s", name)
+
+	ctx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+	return l.bkt.Upload(ctx, path.Join(l.prefix, date, base), f)
+}
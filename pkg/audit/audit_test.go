@@ -0,0 +1,227 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/oidc"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestParseConfig(t *testing.T) {
+	_, err := parseConfig([]byte("type: FILESYSTEM\n"))
+	testutil.NotOk(t, err)
+
+	c, err := parseConfig([]byte("prefix: queries\n"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, "queries", c.Prefix)
+	testutil.Equals(t, DefaultConfig.MaxFileSize, c.MaxFileSize)
+	testutil.Equals(t, DefaultConfig.Claims, c.Claims)
+
+	for _, conf := range []string{
+		"directory: ''\n",
+		"max_file_size: 0B\n",
+		"rotation_interval: 0s\n",
+	} {
+		_, err := parseConfig([]byte(conf))
+		testutil.NotOk(t, err, "config %q", conf)
+	}
+}
+
+// readRecords returns the records of all objects in the bucket.
+func readRecords(t *testing.T, bkt objstore.Bucket) (names []string, recs []Record) {
+	ctx := context.Background()
+	testutil.Ok(t, bkt.Iter(ctx, "", func(name string) error {
+		names = append(names, name)
+		r, err := bkt.Get(ctx, name)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			var rec Record
+			if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+				return err
+			}
+			recs = append(recs, rec)
+		}
+		return s.Err()
+	}, objstore.WithRecursiveIter))
+	return names, recs
+}
+
+func TestLogger(t *testing.T) {
+	dir := t.TempDir()
+	// Files of a previous run are uploaded on start.
+	testutil.Ok(t, ioutil.WriteFile(filepath.Join(dir, "01FQVB2ZF4W4Y5XPDWV8K1BXPN"+openFileExt), []byte(`{"handler":"query","status":"success"}`+"\n"), 0640))
+
+	config := DefaultConfig
+	config.Directory = dir
+	config.MaxFileSize = 1024
+	config.RotationInterval = model.Duration(time.Hour)
+	config.Claims = []string{"sub", "email"}
+
+	bkt := objstore.NewInMemBucket()
+	l, err := newLogger(log.NewNopLogger(), prometheus.NewRegistry(), bkt, config, "query")
+	testutil.Ok(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Run(ctx) }()
+
+	claimsCtx := oidc.ContextWithClaims(context.Background(), jwt.MapClaims{"sub": "alice", "email": "alice@example.com", "groups": []string{"admins"}})
+	l.Log(claimsCtx, Record{Handler: "query", Query: "up", Status: "success", ResultSeries: 2, ResultSamples: 2})
+
+	// Records reaching the maximum file size are uploaded at once.
+	for i := 0; i < 10; i++ {
+		l.Log(context.Background(), Record{Handler: "series", Matchers: []string{`{job="` + strings.Repeat("a", 100) + `"}`}, Status: "success"})
+	}
+	rctx, rcancel := context.WithTimeout(ctx, 10*time.Second)
+	defer rcancel()
+	for promtest.ToFloat64(l.uploads) < 2 {
+		select {
+		case <-rctx.Done():
+			t.Fatalf("files not uploaded, got %v", promtest.ToFloat64(l.uploads))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// The open file is uploaded on stop.
+	l.Log(context.Background(), Record{Handler: "labels", Status: "bad_data", Error: "invalid matcher"})
+	cancel()
+	testutil.Ok(t, <-done)
+
+	testutil.Equals(t, 12.0, promtest.ToFloat64(l.records))
+	testutil.Equals(t, 0.0, promtest.ToFloat64(l.recordFailures))
+	testutil.Equals(t, 0.0, promtest.ToFloat64(l.uploadFailures))
+
+	// All files are removed once uploaded.
+	files, err := ioutil.ReadDir(dir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(files))
+
+	instance, err := os.Hostname()
+	testutil.Ok(t, err)
+	names, recs := readRecords(t, bkt)
+	testutil.Equals(t, "audit/query/"+instance+"/2021-12-26/01FQVB2ZF4W4Y5XPDWV8K1BXPN.jsonl", names[0])
+	for _, n := range names {
+		testutil.Assert(t, strings.HasPrefix(n, "audit/query/"+instance+"/") && strings.HasSuffix(n, fileExt), "unexpected object %s", n)
+	}
+
+	testutil.Equals(t, 13, len(recs))
+	testutil.Equals(t, "", recs[0].Component)
+	testutil.Equals(t, "query", recs[1].Component)
+	testutil.Equals(t, "up", recs[1].Query)
+	testutil.Equals(t, map[string]interface{}{"sub": "alice", "email": "alice@example.com"}, recs[1].Claims)
+	testutil.Assert(t, !recs[1].Time.IsZero(), "record without time")
+	testutil.Equals(t, 0, len(recs[2].Claims))
+	testutil.Equals(t, "invalid matcher", recs[12].Error)
+}
+
+func TestLogger_FilesInOrder(t *testing.T) {
+	config := DefaultConfig
+	config.Directory = t.TempDir()
+	// Every record is written to its own file, most of them created in the same millisecond.
+	config.MaxFileSize = 1
+
+	bkt := objstore.NewInMemBucket()
+	l, err := newLogger(log.NewNopLogger(), prometheus.NewRegistry(), bkt, config, "query")
+	testutil.Ok(t, err)
+
+	for i := 0; i < 100; i++ {
+		l.Log(context.Background(), Record{Handler: "query", Query: strconv.Itoa(i), Status: "success"})
+	}
+	l.upload(context.Background())
+	testutil.Equals(t, 100.0, promtest.ToFloat64(l.uploads))
+
+	_, recs := readRecords(t, bkt)
+	testutil.Equals(t, 100, len(recs))
+	for i, rec := range recs {
+		testutil.Equals(t, strconv.Itoa(i), rec.Query)
+	}
+}
+
+func TestLogger_Nil(t *testing.T) {
+	var l *Logger
+	l.Log(context.Background(), Record{})
+
+	called := false
+	h := l.HTTPMiddleware("query-frontend", nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/query", nil))
+	testutil.Assert(t, called, "handler not called")
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	config := DefaultConfig
+	config.Directory = t.TempDir()
+	bkt := objstore.NewInMemBucket()
+	l, err := newLogger(log.NewNopLogger(), prometheus.NewRegistry(), bkt, config, "query-frontend")
+	testutil.Ok(t, err)
+
+	h := l.HTTPMiddleware("query-frontend", func(r *http.Request) string { return r.Header.Get("X-Org-ID") }, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The body is passed on as is.
+		if err := r.ParseForm(); err != nil || r.Form.Get("query") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success"}`))
+	}))
+
+	form := url.Values{"query": []string{"sum(up)"}, "start": []string{"1"}, "end": []string{"2"}, "step": []string{"1s"}}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/query_range", bytes.NewBufferString(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Org-ID", "team-a")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	testutil.Equals(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/series?match[]=up&match[]=down", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	testutil.Equals(t, http.StatusBadRequest, rec.Code)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testutil.Ok(t, l.Run(ctx))
+
+	_, recs := readRecords(t, bkt)
+	testutil.Equals(t, 2, len(recs))
+
+	testutil.Equals(t, "query-frontend /api/v1/query_range", recs[0].Handler)
+	testutil.Equals(t, "team-a", recs[0].Tenant)
+	testutil.Equals(t, "sum(up)", recs[0].Query)
+	testutil.Equals(t, "1", recs[0].Start)
+	testutil.Equals(t, "2", recs[0].End)
+	testutil.Equals(t, "1s", recs[0].Step)
+	testutil.Equals(t, "success", recs[0].Status)
+	testutil.Equals(t, len(`{"status":"success"}`), recs[0].ResponseBytes)
+
+	testutil.Equals(t, "query-frontend /api/v1/series", recs[1].Handler)
+	testutil.Equals(t, []string{"up", "down"}, recs[1].Matchers)
+	testutil.Equals(t, "error", recs[1].Status)
+	testutil.Equals(t, "400 Bad Request", recs[1].Error)
+}
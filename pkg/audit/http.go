@@ -0,0 +1,102 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package audit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// HTTPMiddleware returns the middleware recording all requests passed to next with their parameters, the status code
+// and the size of their response. The tenant of requests is returned by the given function.
+func (l *Logger) HTTPMiddleware(handler string, tenant func(r *http.Request) string, next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		params := requestParams(r)
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		rec := Record{
+			Time:          start,
+			Handler:       handler + " " + r.URL.Path,
+			RemoteAddr:    r.RemoteAddr,
+			Tenant:        tenant(r),
+			Query:         params.Get("query"),
+			Matchers:      params["match[]"],
+			Start:         params.Get("start"),
+			End:           params.Get("end"),
+			Step:          params.Get("step"),
+			Status:        "success",
+			ResponseBytes: rw.size,
+			Duration:      time.Since(start).Seconds(),
+		}
+		if rec.Start == "" {
+			rec.Start = params.Get("time")
+		}
+		if rw.status/100 != 2 {
+			rec.Status = "error"
+			rec.Error = strconv.Itoa(rw.status) + " " + http.StatusText(rw.status)
+		}
+		l.Log(r.Context(), rec)
+	})
+}
+
+// requestParams returns the parameters of the URL and of the form of the request without consuming its body, which is
+// passed on as is.
+func requestParams(r *http.Request) url.Values {
+	params := r.URL.Query()
+	if r.Method != http.MethodPost || r.Body == nil {
+		return params
+	}
+	if ct, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || ct != "application/x-www-form-urlencoded" {
+		return params
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return params
+	}
+	form, err := url.ParseQuery(string(b))
+	if err != nil {
+		return params
+	}
+	for k, v := range form {
+		params[k] = append(params[k], v...)
+	}
+	return params
+}
+
+// responseWriter records the status code and the size of responses.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// Flush implements http.Flusher, if the wrapped writer does.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package authz implements the external authorization of query requests by an HTTP webhook, e.g. the data API of the
+// Open Policy Agent, which decides on every request with its tenant, selectors and time range.
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/httpconfig"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// maxResponseSize is the maximum size of responses of the webhook.
+const maxResponseSize = 1 << 20
+
+// ErrDenied is returned if the webhook denies a request.
+var ErrDenied = errors.New("denied by authorization webhook")
+
+// Config is the configuration of the external authorization.
+type Config struct {
+	// URL is the URL of the webhook the requests are posted to.
+	URL string `yaml:"url"`
+	// Timeout is the timeout of requests to the webhook.
+	Timeout model.Duration `yaml:"timeout"`
+	// FailOpen allows requests if the webhook fails, instead of rejecting them.
+	FailOpen bool `yaml:"fail_open"`
+	// HTTPClientConfig is the configuration of the HTTP client of the webhook.
+	HTTPClientConfig httpconfig.ClientConfig `yaml:"http_config"`
+}
+
+// ParseConfig parses the YAML content of the authorization configuration.
+func ParseConfig(confContentYaml []byte) (Config, error) {
+	conf := Config{Timeout: model.Duration(5 * time.Second)}
+	if err := yaml.UnmarshalStrict(confContentYaml, &conf); err != nil {
+		return Config{}, errors.Wrap(err, "parsing authorization config YAML")
+	}
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return Config{}, errors.Wrap(err, "parsing authorization webhook URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Config{}, errors.Errorf("authorization webhook URL %q has to be an http or https URL", conf.URL)
+	}
+	if conf.Timeout <= 0 {
+		return Config{}, errors.New("timeout of authorization config has to be positive")
+	}
+	return conf, nil
+}
+
+// Request is the metadata of a query request the webhook decides on.
+type Request struct {
+	// Tenant is the tenant of the request, if tenancy is enforced.
+	Tenant string `json:"tenant,omitempty"`
+	// Handler is the name of the handler of the request, e.g. query_range.
+	Handler string `json:"handler"`
+	// Query is the PromQL expression of queries.
+	Query string `json:"query,omitempty"`
+	// LabelName is the name of the label of label values requests.
+	LabelName string `json:"label_name,omitempty"`
+	// Selectors are the series selectors of the request, e.g. {__name__="up",job="node"}.
+	Selectors []string `json:"selectors"`
+	// Start and End are the time range of the data selected by the request, including the ranges and offsets of the
+	// selectors of queries.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// webhookRequest is the body of the requests to the webhook, compatible with the data API of the Open Policy Agent.
+type webhookRequest struct {
+	Input Request `json:"input"`
+}
+
+// webhookResponse is the body of responses of the webhook, compatible with the data API of the Open Policy Agent.
+// The result is either a boolean or an object with the decision and the reason of it.
+type webhookResponse struct {
+	Result json.RawMessage `json:"result"`
+}
+
+type decision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason"`
+}
+
+// Authorizer authorizes query requests with an HTTP webhook.
+type Authorizer struct {
+	logger log.Logger
+	conf   Config
+	client *http.Client
+
+	requests *prometheus.CounterVec
+	duration prometheus.Histogram
+}
+
+// NewAuthorizer returns an Authorizer with the given YAML configuration.
+func NewAuthorizer(logger log.Logger, reg prometheus.Registerer, confContentYaml []byte) (*Authorizer, error) {
+	conf, err := ParseConfig(confContentYaml)
+	if err != nil {
+		return nil, err
+	}
+	client, err := httpconfig.NewHTTPClient(conf.HTTPClientConfig, "authz")
+	if err != nil {
+		return nil, errors.Wrap(err, "create HTTP client of authorization webhook")
+	}
+	client.Timeout = time.Duration(conf.Timeout)
+	level.Info(logger).Log("msg", "enabling external authorization of query requests", "url", conf.URL)
+
+	a := &Authorizer{
+		logger: log.With(logger, "component", "authz"),
+		conf:   conf,
+		client: client,
+		requests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_query_authz_requests_total",
+			Help: "Total number of query requests authorized by the authorization webhook by result.",
+		}, []string{"handler", "result"}),
+		duration: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:    "thanos_query_authz_request_duration_seconds",
+			Help:    "Duration of the requests to the authorization webhook.",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		}),
+	}
+	return a, nil
+}
+
+// Authorize returns nil if the webhook allows the request. If it denies it, the error wraps ErrDenied with the reason
+// of the webhook. If the webhook fails, the request is allowed if the authorizer fails open.
+func (a *Authorizer) Authorize(ctx context.Context, req Request) error {
+	begin := time.Now()
+	allow, reason, err := a.decide(ctx, req)
+	a.duration.Observe(time.Since(begin).Seconds())
+	if err != nil {
+		level.Warn(a.logger).Log("msg", "authorization webhook failed", "handler", req.Handler, "failOpen", a.conf.FailOpen, "err", err)
+		if a.conf.FailOpen {
+			a.requests.WithLabelValues(req.Handler, "error_allowed").Inc()
+			return nil
+		}
+		a.requests.WithLabelValues(req.Handler, "error").Inc()
+		return errors.Wrap(err, "authorize request")
+	}
+	if !allow {
+		a.requests.WithLabelValues(req.Handler, "denied").Inc()
+		if reason == "" {
+			return ErrDenied
+		}
+		return errors.Wrap(ErrDenied, reason)
+	}
+	a.requests.WithLabelValues(req.Handler, "allowed").Inc()
+	return nil
+}
+
+func (a *Authorizer) decide(ctx context.Context, req Request) (allow bool, reason string, err error) {
+	body, err := json.Marshal(webhookRequest{Input: req})
+	if err != nil {
+		return false, "", errors.Wrap(err, "marshal request")
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, a.conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, "", err
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(r)
+	if err != nil {
+		return false, "", err
+	}
+	defer runutil.ExhaustCloseWithErrCapture(&err, resp.Body, "authorization webhook response body")
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return false, "", errors.Wrap(err, "read response")
+	}
+	if resp.StatusCode/100 != 2 {
+		return false, "", errors.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	return parseResponse(b)
+}
+
+// parseResponse returns the decision of the webhook response. Responses without result, e.g. of the Open Policy
+// Agent for undefined decisions, deny the request.
+func parseResponse(b []byte) (allow bool, reason string, err error) {
+	var resp webhookResponse
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return false, "", errors.Wrap(err, "unmarshal response")
+	}
+	if len(resp.Result) == 0 || string(resp.Result) == "null" {
+		return false, "no decision", nil
+	}
+	if err := json.Unmarshal(resp.Result, &allow); err == nil {
+		return allow, "", nil
+	}
+	var d decision
+	if err := json.Unmarshal(resp.Result, &d); err != nil {
+		return false, "", errors.Errorf("result %s of response is neither a boolean nor a decision object", resp.Result)
+	}
+	return d.Allow, d.Reason, nil
+}
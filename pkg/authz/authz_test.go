@@ -0,0 +1,158 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestParseConfig(t *testing.T) {
+	conf, err := ParseConfig([]byte("url: http://opa:8181/v1/data/thanos/allow\nfail_open: true\n"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, "http://opa:8181/v1/data/thanos/allow", conf.URL)
+	testutil.Equals(t, 5*time.Second, time.Duration(conf.Timeout))
+	testutil.Assert(t, conf.FailOpen, "expected fail open")
+
+	for _, c := range []string{
+		"url: opa:8181\n",
+		"url: http://opa:8181\ntimeout: 0s\n",
+		"url: http://opa:8181\nfailopen: true\n",
+	} {
+		_, err := ParseConfig([]byte(c))
+		testutil.NotOk(t, err, "config %q", c)
+	}
+}
+
+func TestAuthorizer_Authorize(t *testing.T) {
+	var (
+		received Request
+		response string
+		status   int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req webhookRequest
+		testutil.Ok(t, json.NewDecoder(r.Body).Decode(&req))
+		received = req.Input
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+	defer srv.Close()
+
+	req := SeriesRequest("series", [][]*labels.Matcher{{labels.MustNewMatcher(labels.MatchEqual, "job", "node")}}, time.Unix(0, 0).UTC(), time.Unix(60, 0).UTC())
+	req.Tenant = "team-a"
+
+	for _, failOpen := range []bool{false, true} {
+		a, err := NewAuthorizer(log.NewNopLogger(), prometheus.NewRegistry(), []byte(fmt.Sprintf("url: %s\nfail_open: %v\n", srv.URL, failOpen)))
+		testutil.Ok(t, err)
+
+		for _, tc := range []struct {
+			status         int
+			response       string
+			expectedErr    bool
+			expectedDenied bool
+			expectedResult string
+		}{
+			{status: http.StatusOK, response: `{"result": true}`, expectedResult: "allowed"},
+			{status: http.StatusOK, response: `{"result": {"allow": true}}`, expectedResult: "allowed"},
+			{status: http.StatusOK, response: `{"result": false}`, expectedErr: true, expectedDenied: true, expectedResult: "denied"},
+			{status: http.StatusOK, response: `{"result": {"allow": false, "reason": "more than 30 days"}}`, expectedErr: true, expectedDenied: true, expectedResult: "denied"},
+			// Undefined decisions of OPA.
+			{status: http.StatusOK, response: `{}`, expectedErr: true, expectedDenied: true, expectedResult: "denied"},
+			// Failures of the webhook.
+			{status: http.StatusOK, response: `{"result": "yes"}`, expectedErr: !failOpen, expectedResult: "error"},
+			{status: http.StatusInternalServerError, response: `oops`, expectedErr: !failOpen, expectedResult: "error"},
+		} {
+			status, response = tc.status, tc.response
+			result := tc.expectedResult
+			if result == "error" && failOpen {
+				result = "error_allowed"
+			}
+			before := promtest.ToFloat64(a.requests.WithLabelValues("series", result))
+
+			err := a.Authorize(context.Background(), req)
+			testutil.Equals(t, tc.expectedErr, err != nil, "response %s, fail open %v: %v", tc.response, failOpen, err)
+			testutil.Equals(t, tc.expectedDenied, errors.Is(err, ErrDenied), "response %s, fail open %v: %v", tc.response, failOpen, err)
+			testutil.Equals(t, before+1, promtest.ToFloat64(a.requests.WithLabelValues("series", result)))
+			testutil.Equals(t, req, received)
+		}
+	}
+}
+
+func TestQueryRequest(t *testing.T) {
+	start, end := time.Unix(1000000, 0).UTC(), time.Unix(1010000, 0).UTC()
+
+	for _, tc := range []struct {
+		query             string
+		expectedSelectors []string
+		expectedStart     time.Time
+		expectedEnd       time.Time
+	}{
+		{
+			query:             `up{job="node"}`,
+			expectedSelectors: []string{`{job="node",__name__="up"}`},
+			expectedStart:     start.Add(-5 * time.Minute), expectedEnd: end,
+		},
+		{
+			query:             `rate(http_requests_total[1h] offset 1d) / on() group_left vector(1)`,
+			expectedSelectors: []string{`{__name__="http_requests_total"}`},
+			expectedStart:     start.Add(-25 * time.Hour), expectedEnd: end.Add(-24 * time.Hour),
+		},
+		{
+			query:             `max_over_time(rate(a[5m])[1d:1m] offset 1h) + b @ 1000000`,
+			expectedSelectors: []string{`{__name__="a"}`, `{__name__="b"}`},
+			expectedStart:     start.Add(-25*time.Hour - 5*time.Minute), expectedEnd: end.Add(-time.Hour),
+		},
+		{
+			query:             `c @ end() - c @ start()`,
+			expectedSelectors: []string{`{__name__="c"}`, `{__name__="c"}`},
+			expectedStart:     start.Add(-5 * time.Minute), expectedEnd: end,
+		},
+		{
+			query:             `vector(1)`,
+			expectedSelectors: []string{},
+			expectedStart:     start, expectedEnd: end,
+		},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			expr, err := parser.ParseExpr(tc.query)
+			testutil.Ok(t, err)
+			req := QueryRequest("query_range", tc.query, expr, start, end, 0)
+			testutil.Equals(t, Request{
+				Handler:   "query_range",
+				Query:     tc.query,
+				Selectors: tc.expectedSelectors,
+				Start:     tc.expectedStart,
+				End:       tc.expectedEnd,
+			}, req)
+		})
+	}
+}
+
+func TestSeriesRequest(t *testing.T) {
+	req := SeriesRequest("label_names", nil, time.Unix(math.MinInt64/1000, 0), time.Unix(math.MaxInt64/1000, 0))
+	testutil.Equals(t, Request{
+		Handler:   "label_names",
+		Selectors: []string{},
+		Start:     time.Unix(0, 0).UTC(),
+		End:       time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+	}, req)
+	_, err := json.Marshal(req)
+	testutil.Ok(t, err)
+}
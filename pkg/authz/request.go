@@ -0,0 +1,153 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package authz
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// defaultLookbackDelta is the default lookback delta of the PromQL engine.
+const defaultLookbackDelta = 5 * time.Minute
+
+var (
+	// minTime and maxTime are the bounds of the time range of requests, which are used for unbounded time ranges.
+	minTime = time.Unix(0, 0).UTC()
+	maxTime = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+)
+
+// QueryRequest returns the request of the given PromQL query evaluated from start to end. The time range of the
+// request is the one of the data selected by the query, i.e. it is extended by the lookback delta, the ranges of
+// matrix selectors and subqueries, and by offsets, bounded like the ones of SeriesRequest. A zero lookback delta is
+// the default one of the PromQL engine.
+func QueryRequest(handler, query string, expr parser.Expr, start, end time.Time, lookbackDelta time.Duration) Request {
+	if lookbackDelta == 0 {
+		lookbackDelta = defaultLookbackDelta
+	}
+	mint, maxt := selectedTimeRange(expr, timestamp.FromTime(start), timestamp.FromTime(end), lookbackDelta)
+	return Request{
+		Handler:   handler,
+		Query:     query,
+		Selectors: selectors(parser.ExtractSelectors(expr)),
+		Start:     clampTime(timestamp.Time(mint)),
+		End:       clampTime(timestamp.Time(maxt)),
+	}
+}
+
+// SeriesRequest returns the request of the series matching any of the given matcher sets from start to end, e.g. of
+// the series or labels handlers. Time ranges are bounded by the Unix epoch and the end of the year 9999.
+func SeriesRequest(handler string, matcherSets [][]*labels.Matcher, start, end time.Time) Request {
+	return Request{
+		Handler:   handler,
+		Selectors: selectors(matcherSets),
+		Start:     clampTime(start),
+		End:       clampTime(end),
+	}
+}
+
+func clampTime(t time.Time) time.Time {
+	if t.Before(minTime) {
+		return minTime
+	}
+	if t.After(maxTime) {
+		return maxTime
+	}
+	return t.UTC()
+}
+
+func selectors(matcherSets [][]*labels.Matcher) []string {
+	s := make([]string, 0, len(matcherSets))
+	for _, ms := range matcherSets {
+		m := make([]string, 0, len(ms))
+		for _, matcher := range ms {
+			m = append(m, matcher.String())
+		}
+		s = append(s, "{"+strings.Join(m, ",")+"}")
+	}
+	return s
+}
+
+// selectedTimeRange returns the time range of the data selected by the expression evaluated from start to end, like
+// the PromQL engine does.
+func selectedTimeRange(expr parser.Expr, start, end int64, lookbackDelta time.Duration) (mint, maxt int64) {
+	mint, maxt = math.MaxInt64, math.MinInt64
+	// The range of a matrix selector applies to the vector selector in it, which is inspected next.
+	var evalRange time.Duration
+	parser.Inspect(expr, func(node parser.Node, path []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			s, e := selectorTimeRange(n, path, start, end, evalRange, lookbackDelta)
+			if s < mint {
+				mint = s
+			}
+			if e > maxt {
+				maxt = e
+			}
+			evalRange = 0
+		case *parser.MatrixSelector:
+			evalRange = n.Range
+		}
+		return nil
+	})
+	if maxt == math.MinInt64 {
+		// No selectors, no data is selected.
+		return start, end
+	}
+	return mint, maxt
+}
+
+func selectorTimeRange(n *parser.VectorSelector, path []parser.Node, start, end int64, evalRange, lookbackDelta time.Duration) (int64, int64) {
+	// The @ modifier with start() or end() always refers to the start or end of the query.
+	s, e := start, end
+	var subqOffset, subqRange time.Duration
+	for _, node := range path {
+		sq, ok := node.(*parser.SubqueryExpr)
+		if !ok {
+			continue
+		}
+		subqOffset += sq.OriginalOffset
+		subqRange += sq.Range
+		// The @ modifier of subqueries resets the offsets and ranges of the outer subqueries.
+		if ts, ok := atTimestamp(sq.Timestamp, sq.StartOrEnd, start, end); ok {
+			s, e = ts, ts
+			subqOffset, subqRange = sq.OriginalOffset, sq.Range
+		}
+	}
+
+	if ts, ok := atTimestamp(n.Timestamp, n.StartOrEnd, start, end); ok {
+		s, e = ts, ts
+	} else {
+		s -= durationMilliseconds(subqOffset + subqRange)
+		e -= durationMilliseconds(subqOffset)
+	}
+	if evalRange == 0 {
+		s -= durationMilliseconds(lookbackDelta)
+	} else {
+		s -= durationMilliseconds(evalRange)
+	}
+	offset := durationMilliseconds(n.OriginalOffset)
+	return s - offset, e - offset
+}
+
+// atTimestamp returns the timestamp of the @ modifier, if any.
+func atTimestamp(ts *int64, startOrEnd parser.ItemType, start, end int64) (int64, bool) {
+	switch {
+	case ts != nil:
+		return *ts, true
+	case startOrEnd == parser.START:
+		return start, true
+	case startOrEnd == parser.END:
+		return end, true
+	}
+	return 0, false
+}
+
+func durationMilliseconds(d time.Duration) int64 {
+	return int64(d / (time.Millisecond / time.Nanosecond))
+}
@@ -301,11 +301,77 @@ func DownloadMeta(ctx context.Context, logger log.Logger, bkt objstore.Bucket, i
 	return m, nil
 }
 
+// SizeInBucket returns the number and the total size in bytes of the objects of the given block in the bucket,
+// including markers and objects of partially uploaded blocks.
+func SizeInBucket(ctx context.Context, bkt objstore.BucketReader, id ulid.ULID) (objects int, size int64, _ error) {
+	err := bkt.Iter(ctx, id.String(), func(name string) error {
+		attrs, err := bkt.Attributes(ctx, name)
+		if err != nil {
+			if bkt.IsObjNotFoundErr(err) {
+				// Deleted concurrently.
+				return nil
+			}
+			return errors.Wrapf(err, "get attributes of %s", name)
+		}
+		objects++
+		size += attrs.Size
+		return nil
+	}, objstore.WithRecursiveIter)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "list objects of block %s", id)
+	}
+	return objects, size, nil
+}
+
 func IsBlockDir(path string) (id ulid.ULID, ok bool) {
 	id, err := ulid.Parse(filepath.Base(path))
 	return id, err == nil
 }
 
+// IsBlockMetaObject returns true if the given object is the meta.json or a marker of a block, i.e. an object whose
+// changes change the metadata of blocks synced by fetchers. Objects may be under a prefix, e.g. of a tenant.
+func IsBlockMetaObject(name string) bool {
+	dir, file := path.Split(name)
+	if _, ok := IsBlockDir(strings.TrimSuffix(dir, "/")); !ok {
+		return false
+	}
+	switch file {
+	case MetaFilename, metadata.DeletionMarkFilename, metadata.NoCompactMarkFilename, metadata.NoDownsampleMarkFilename:
+		return true
+	}
+	return false
+}
+
+// HardlinkBlock hard links the chunks, index and meta.json of the block in src into the dst directory,
+// so meta.json of the linked block can be replaced without changing the source block.
+func HardlinkBlock(src, dst string) error {
+	chunkDir := filepath.Join(dst, ChunksDirname)
+
+	if err := os.MkdirAll(chunkDir, 0750); err != nil {
+		return errors.Wrap(err, "create chunks dir")
+	}
+
+	fis, err := ioutil.ReadDir(filepath.Join(src, ChunksDirname))
+	if err != nil {
+		return errors.Wrap(err, "read chunk dir")
+	}
+	files := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		files = append(files, fi.Name())
+	}
+	for i, fn := range files {
+		files[i] = filepath.Join(ChunksDirname, fn)
+	}
+	files = append(files, MetaFilename, IndexFilename)
+
+	for _, fn := range files {
+		if err := os.Link(filepath.Join(src, fn), filepath.Join(dst, fn)); err != nil {
+			return errors.Wrapf(err, "hard link file %s", fn)
+		}
+	}
+	return nil
+}
+
 // GetSegmentFiles returns list of segment files for given block. Paths are relative to the chunks directory.
 // In case of errors, nil is returned.
 func GetSegmentFiles(blockDir string) []string {
@@ -404,3 +470,55 @@ func MarkForNoCompact(ctx context.Context, logger log.Logger, bkt objstore.Bucke
 	level.Info(logger).Log("msg", "block has been marked for no compaction", "block", id)
 	return nil
 }
+
+// MarkForNoDownsample creates a file which marks block to be not downsampled.
+func MarkForNoDownsample(ctx context.Context, logger log.Logger, bkt objstore.Bucket, id ulid.ULID, reason metadata.NoDownsampleReason, details string, markedForNoDownsample prometheus.Counter) error {
+	m := path.Join(id.String(), metadata.NoDownsampleMarkFilename)
+	noDownsampleMarkExists, err := bkt.Exists(ctx, m)
+	if err != nil {
+		return errors.Wrapf(err, "check exists %s in bucket", m)
+	}
+	if noDownsampleMarkExists {
+		level.Warn(logger).Log("msg", "requested to mark for no downsampling, but file already exists; this should not happen; investigate", "err", errors.Errorf("file %s already exists in bucket", m))
+		return nil
+	}
+
+	noDownsampleMark, err := json.Marshal(metadata.NoDownsampleMark{
+		ID:      id,
+		Version: metadata.NoDownsampleMarkVersion1,
+
+		NoDownsampleTime: time.Now().Unix(),
+		Reason:           reason,
+		Details:          details,
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encode no downsample mark")
+	}
+
+	if err := bkt.Upload(ctx, m, bytes.NewBuffer(noDownsampleMark)); err != nil {
+		return errors.Wrapf(err, "upload file %s to bucket", m)
+	}
+	markedForNoDownsample.Inc()
+	level.Info(logger).Log("msg", "block has been marked for no downsampling", "block", id)
+	return nil
+}
+
+// RemoveMark deletes the marker file with the given name, e.g. metadata.NoCompactMarkFilename, of the block. It's a
+// noop if the block isn't marked. The details are logged as the reason for the removal.
+func RemoveMark(ctx context.Context, logger log.Logger, bkt objstore.Bucket, id ulid.ULID, markerFilename, details string) error {
+	m := path.Join(id.String(), markerFilename)
+	exists, err := bkt.Exists(ctx, m)
+	if err != nil {
+		return errors.Wrapf(err, "check exists %s in bucket", m)
+	}
+	if !exists {
+		level.Warn(logger).Log("msg", "requested to remove marker, but block is not marked", "block", id, "marker", markerFilename)
+		return nil
+	}
+
+	if err := bkt.Delete(ctx, m); err != nil {
+		return errors.Wrapf(err, "delete file %s from bucket", m)
+	}
+	level.Info(logger).Log("msg", "marker has been removed from block", "block", id, "marker", markerFilename, "details", details)
+	return nil
+}
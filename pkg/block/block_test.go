@@ -76,6 +76,24 @@ func TestIsBlockDir(t *testing.T) {
 	}
 }
 
+func TestIsBlockMetaObject(t *testing.T) {
+	id := ulid.MustNew(1, nil).String()
+	for input, exp := range map[string]bool{
+		id + "/meta.json":               true,
+		id + "/deletion-mark.json":      true,
+		id + "/no-downsample-mark.json": true,
+		"tenant/" + id + "/meta.json":   true,
+		id + "/index":                   false,
+		id + "/chunks/000001":           false,
+		"something/meta.json":           false,
+		"meta.json":                     false,
+	} {
+		t.Run(input, func(t *testing.T) {
+			testutil.Equals(t, exp, IsBlockMetaObject(input))
+		})
+	}
+}
+
 func TestUpload(t *testing.T) {
 	defer testutil.TolerantVerifyLeak(t)
 
@@ -391,6 +409,50 @@ func TestMarkForNoCompact(t *testing.T) {
 	}
 }
 
+func TestMarkForNoDownsample(t *testing.T) {
+	defer testutil.TolerantVerifyLeak(t)
+	ctx := context.Background()
+
+	bkt := objstore.NewInMemBucket()
+	id := ulid.MustNew(1, nil)
+
+	c := promauto.With(nil).NewCounter(prometheus.CounterOpts{})
+	testutil.Ok(t, MarkForNoDownsample(ctx, log.NewNopLogger(), bkt, id, metadata.ManualNoDownsampleReason, "too many series", c))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(c))
+
+	m := metadata.NoDownsampleMark{}
+	testutil.Ok(t, metadata.ReadMarker(ctx, log.NewNopLogger(), objstore.WithNoopInstr(bkt), id.String(), &m))
+	testutil.Equals(t, metadata.ManualNoDownsampleReason, m.Reason)
+	testutil.Equals(t, "too many series", m.Details)
+
+	// Marking again is a noop.
+	testutil.Ok(t, MarkForNoDownsample(ctx, log.NewNopLogger(), bkt, id, metadata.ManualNoDownsampleReason, "", c))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(c))
+}
+
+func TestRemoveMark(t *testing.T) {
+	defer testutil.TolerantVerifyLeak(t)
+	ctx := context.Background()
+
+	bkt := objstore.NewInMemBucket()
+	id := ulid.MustNew(1, nil)
+	testutil.Ok(t, bkt.Upload(ctx, path.Join(id.String(), MetaFilename), bytes.NewReader([]byte("{}"))))
+
+	c := promauto.With(nil).NewCounter(prometheus.CounterOpts{})
+	testutil.Ok(t, MarkForNoCompact(ctx, log.NewNopLogger(), bkt, id, metadata.ManualNoCompactReason, "", c))
+
+	testutil.Ok(t, RemoveMark(ctx, log.NewNopLogger(), bkt, id, metadata.NoCompactMarkFilename, "compact again"))
+	exists, err := bkt.Exists(ctx, path.Join(id.String(), metadata.NoCompactMarkFilename))
+	testutil.Ok(t, err)
+	testutil.Assert(t, !exists, "no-compact mark must be removed")
+
+	// The block itself is kept and removing a missing marker is a noop.
+	testutil.Ok(t, RemoveMark(ctx, log.NewNopLogger(), bkt, id, metadata.NoCompactMarkFilename, "compact again"))
+	exists, err = bkt.Exists(ctx, path.Join(id.String(), MetaFilename))
+	testutil.Ok(t, err)
+	testutil.Assert(t, exists, "block must be kept")
+}
+
 // TestHashDownload uploads an empty block to in-memory storage
 // and tries to download it to the same dir. It should not try
 // to download twice.
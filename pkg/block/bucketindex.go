@@ -0,0 +1,96 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package block
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+const (
+	// BucketIndexFilename is the known name of the bucket index, at the root of the bucket.
+	BucketIndexFilename = "bucket-index.json.gz"
+	// BucketIndexVersion1 is the enumeration of the first version of the bucket index.
+	BucketIndexVersion1 = 1
+)
+
+// ErrBucketIndexNotFound is returned when the bucket has no bucket index.
+var ErrBucketIndexNotFound = errors.New("bucket index not found")
+
+// BucketIndex holds the metas of all blocks of the bucket, so that fetchers can take them from a single object
+// instead of checking and loading them one by one.
+type BucketIndex struct {
+	Version int `json:"version"`
+	// UpdatedAt is the time of the update of the index, in Unix seconds.
+	UpdatedAt int64 `json:"updated_at"`
+	// Blocks are the metas of the blocks of the bucket, sorted by ULID.
+	Blocks []*metadata.Meta `json:"blocks"`
+}
+
+// WriteBucketIndex uploads the bucket index holding the given metas.
+func WriteBucketIndex(ctx context.Context, bkt objstore.Bucket, metas map[ulid.ULID]*metadata.Meta) error {
+	idx := BucketIndex{
+		Version:   BucketIndexVersion1,
+		UpdatedAt: time.Now().Unix(),
+		Blocks:    make([]*metadata.Meta, 0, len(metas)),
+	}
+	for _, m := range metas {
+		idx.Blocks = append(idx.Blocks, m)
+	}
+	sort.Slice(idx.Blocks, func(i, j int) bool {
+		return idx.Blocks[i].ULID.Compare(idx.Blocks[j].ULID) < 0
+	})
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gw).Encode(idx); err != nil {
+		return errors.Wrap(err, "encode bucket index")
+	}
+	if err := gw.Close(); err != nil {
+		return errors.Wrap(err, "compress bucket index")
+	}
+	return errors.Wrap(bkt.Upload(ctx, BucketIndexFilename, &buf), "upload bucket index")
+}
+
+// ReadBucketIndex returns the bucket index of the bucket, or ErrBucketIndexNotFound if it has none.
+func ReadBucketIndex(ctx context.Context, bkt objstore.InstrumentedBucketReader) (_ *BucketIndex, err error) {
+	r, err := bkt.ReaderWithExpectedErrs(bkt.IsObjNotFoundErr).Get(ctx, BucketIndexFilename)
+	if bkt.IsObjNotFoundErr(err) {
+		return nil, ErrBucketIndexNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "get bucket index")
+	}
+	defer runutil.CloseWithErrCapture(&err, r, "close bucket index reader")
+
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "decompress bucket index")
+	}
+	b, err := ioutil.ReadAll(gr)
+	if err != nil {
+		return nil, errors.Wrap(err, "read bucket index")
+	}
+
+	idx := &BucketIndex{}
+	if err := json.Unmarshal(b, idx); err != nil {
+		return nil, errors.Wrap(err, "unmarshal bucket index")
+	}
+	if idx.Version != BucketIndexVersion1 {
+		return nil, errors.Errorf("unexpected bucket index version %d", idx.Version)
+	}
+	return idx, nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package block
+
+import (
+	"context"
+	"path"
+
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+// UploadDetector detects the blocks uploaded to the bucket since its previous detection. It only lists the block
+// directories of the bucket and checks the meta.json of new ones, so it is much cheaper than a sync of all metas and
+// can run more often, to sync the metas only when new blocks are uploaded.
+// Not go routine safe.
+type UploadDetector struct {
+	bkt  objstore.InstrumentedBucketReader
+	seen map[ulid.ULID]struct{}
+}
+
+// NewUploadDetector returns an UploadDetector of the given bucket.
+func NewUploadDetector(bkt objstore.InstrumentedBucketReader) *UploadDetector {
+	return &UploadDetector{bkt: bkt}
+}
+
+// Detect returns the IDs of the blocks uploaded since the previous call. Blocks are detected once their meta.json,
+// which is uploaded last, exists. The first call records the blocks already in the bucket and returns none.
+func (d *UploadDetector) Detect(ctx context.Context) ([]ulid.ULID, error) {
+	var listed []ulid.ULID
+	if err := d.bkt.Iter(ctx, "", func(name string) error {
+		if id, ok := IsBlockDir(name); ok {
+			listed = append(listed, id)
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "list blocks")
+	}
+
+	seen := make(map[ulid.ULID]struct{}, len(listed))
+	if d.seen == nil {
+		for _, id := range listed {
+			seen[id] = struct{}{}
+		}
+		d.seen = seen
+		return nil, nil
+	}
+
+	var uploaded []ulid.ULID
+	for _, id := range listed {
+		if _, ok := d.seen[id]; ok {
+			seen[id] = struct{}{}
+			continue
+		}
+		ok, err := d.bkt.Exists(ctx, path.Join(id.String(), MetaFilename))
+		if err != nil {
+			return nil, errors.Wrapf(err, "check meta.json of block %s", id)
+		}
+		// Blocks being uploaded are checked again by the next detection.
+		if !ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		uploaded = append(uploaded, id)
+	}
+	// Deleted blocks are forgotten.
+	d.seen = seen
+	return uploaded, nil
+}
@@ -0,0 +1,50 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package block
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"testing"
+
+	"github.com/oklog/ulid"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestUploadDetector(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.WithNoopInstr(objstore.NewInMemBucket())
+
+	upload := func(id ulid.ULID, file string) {
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id.String(), file), bytes.NewReader([]byte("{}"))))
+	}
+	upload(ULID(1), metadata.MetaFilename)
+
+	d := NewUploadDetector(bkt)
+
+	// Blocks already in the bucket are not detected.
+	ids, err := d.Detect(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(ids))
+
+	// Blocks being uploaded are detected once their meta.json is uploaded.
+	upload(ULID(2), IndexFilename)
+	ids, err = d.Detect(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(ids))
+
+	upload(ULID(2), metadata.MetaFilename)
+	upload(ULID(3), metadata.MetaFilename)
+	ids, err = d.Detect(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []ulid.ULID{ULID(2), ULID(3)}, ids)
+
+	ids, err = d.Detect(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(ids))
+}
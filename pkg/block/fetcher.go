@@ -80,6 +80,8 @@ const (
 
 	// MarkedForNoCompactionMeta is label for blocks which are loaded but also marked for no compaction. This label is also counted in `loaded` label metric.
 	MarkedForNoCompactionMeta = "marked-for-no-compact"
+	// MarkedForNoDownsampleMeta is label for blocks which are loaded but also marked for no downsampling. This label is also counted in `loaded` label metric.
+	MarkedForNoDownsampleMeta = "marked-for-no-downsample"
 
 	// Modified label values.
 	replicaRemovedMeta = "replica-label-removed"
@@ -123,6 +125,7 @@ func NewFetcherMetrics(reg prometheus.Registerer, syncedExtraLabels, modifiedExt
 			{duplicateMeta},
 			{MarkedForDeletionMeta},
 			{MarkedForNoCompactionMeta},
+			{MarkedForNoDownsampleMeta},
 		}, syncedExtraLabels...)...,
 	)
 	m.Modified = extprom.NewTxGaugeVec(
@@ -167,10 +170,37 @@ type BaseFetcher struct {
 
 	mtx    sync.Mutex
 	cached map[ulid.ULID]*metadata.Meta
+
+	// bucketIndexMaxStale is the maximum age of the bucket index the metas of blocks are taken from. 0 disables it.
+	bucketIndexMaxStale time.Duration
+	// bucketIndexBkt is the bucket the bucket index is uploaded to after complete syncs, if not nil.
+	bucketIndexBkt objstore.Bucket
+
+	bucketIndexMetas   prometheus.Counter
+	bucketIndexUploads prometheus.Counter
+}
+
+// BaseFetcherOption configures the BaseFetcher.
+type BaseFetcherOption func(f *BaseFetcher)
+
+// WithBucketIndex makes the fetcher take the metas of the blocks of the bucket index, if it was updated within
+// maxStale, instead of checking and loading them one by one. Blocks which are not in the bucket index yet are still
+// checked and loaded one by one, and blocks which are not in the bucket anymore are dropped.
+func WithBucketIndex(maxStale time.Duration) BaseFetcherOption {
+	return func(f *BaseFetcher) {
+		f.bucketIndexMaxStale = maxStale
+	}
+}
+
+// WithBucketIndexUpload makes the fetcher upload the bucket index with the metas of all blocks after each complete sync.
+func WithBucketIndexUpload(bkt objstore.Bucket) BaseFetcherOption {
+	return func(f *BaseFetcher) {
+		f.bucketIndexBkt = bkt
+	}
 }
 
 // NewBaseFetcher constructs BaseFetcher.
-func NewBaseFetcher(logger log.Logger, concurrency int, bkt objstore.InstrumentedBucketReader, dir string, reg prometheus.Registerer) (*BaseFetcher, error) {
+func NewBaseFetcher(logger log.Logger, concurrency int, bkt objstore.InstrumentedBucketReader, dir string, reg prometheus.Registerer, options ...BaseFetcherOption) (*BaseFetcher, error) {
 	if logger == nil {
 		logger = log.NewNopLogger()
 	}
@@ -183,7 +213,7 @@ func NewBaseFetcher(logger log.Logger, concurrency int, bkt objstore.Instrumente
 		}
 	}
 
-	return &BaseFetcher{
+	f := &BaseFetcher{
 		logger:      log.With(logger, "component", "block.BaseFetcher"),
 		concurrency: concurrency,
 		bkt:         bkt,
@@ -194,7 +224,21 @@ func NewBaseFetcher(logger log.Logger, concurrency int, bkt objstore.Instrumente
 			Name:      "base_syncs_total",
 			Help:      "Total blocks metadata synchronization attempts by base Fetcher",
 		}),
-	}, nil
+		bucketIndexMetas: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Subsystem: fetcherSubSys,
+			Name:      "base_bucket_index_metas_total",
+			Help:      "Total number of block metas taken from the bucket index instead of being checked and loaded one by one by base Fetcher",
+		}),
+		bucketIndexUploads: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Subsystem: fetcherSubSys,
+			Name:      "base_bucket_index_uploads_total",
+			Help:      "Total number of uploads of the bucket index by base Fetcher",
+		}),
+	}
+	for _, option := range options {
+		option(f)
+	}
+	return f, nil
 }
 
 // NewRawMetaFetcher returns basic meta fetcher without proper handling for eventual consistent backends or partial uploads.
@@ -204,8 +248,8 @@ func NewRawMetaFetcher(logger log.Logger, bkt objstore.InstrumentedBucketReader)
 }
 
 // NewMetaFetcher returns meta fetcher.
-func NewMetaFetcher(logger log.Logger, concurrency int, bkt objstore.InstrumentedBucketReader, dir string, reg prometheus.Registerer, filters []MetadataFilter, modifiers []MetadataModifier) (*MetaFetcher, error) {
-	b, err := NewBaseFetcher(logger, concurrency, bkt, dir, reg)
+func NewMetaFetcher(logger log.Logger, concurrency int, bkt objstore.InstrumentedBucketReader, dir string, reg prometheus.Registerer, filters []MetadataFilter, modifiers []MetadataModifier, options ...BaseFetcherOption) (*MetaFetcher, error) {
+	b, err := NewBaseFetcher(logger, concurrency, bkt, dir, reg, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -298,6 +342,40 @@ func (f *BaseFetcher) loadMeta(ctx context.Context, id ulid.ULID) (*metadata.Met
 	return m, nil
 }
 
+// indexedMetas returns the metas of the bucket index, if enabled, present and updated within the maximum staleness.
+// The metas already cached are returned instead of the ones of the index.
+func (f *BaseFetcher) indexedMetas(ctx context.Context) map[ulid.ULID]*metadata.Meta {
+	if f.bucketIndexMaxStale <= 0 {
+		return nil
+	}
+
+	idx, err := ReadBucketIndex(ctx, f.bkt)
+	if errors.Is(err, ErrBucketIndexNotFound) {
+		level.Debug(f.logger).Log("msg", "bucket index not found; loading metas one by one")
+		return nil
+	}
+	if err != nil {
+		level.Warn(f.logger).Log("msg", "reading bucket index failed; loading metas one by one", "err", err)
+		return nil
+	}
+	if age := time.Since(time.Unix(idx.UpdatedAt, 0)); age > f.bucketIndexMaxStale {
+		level.Warn(f.logger).Log("msg", "bucket index is stale; loading metas one by one", "age", age, "max_stale", f.bucketIndexMaxStale)
+		return nil
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	metas := make(map[ulid.ULID]*metadata.Meta, len(idx.Blocks))
+	for _, m := range idx.Blocks {
+		if cached, ok := f.cached[m.ULID]; ok {
+			m = cached
+		}
+		metas[m.ULID] = m
+	}
+	return metas
+}
+
 type response struct {
 	metas   map[ulid.ULID]*metadata.Meta
 	partial map[ulid.ULID]error
@@ -319,11 +397,22 @@ func (f *BaseFetcher) fetchMetadata(ctx context.Context) (interface{}, error) {
 		eg  errgroup.Group
 		ch  = make(chan ulid.ULID, f.concurrency)
 		mtx sync.Mutex
+
+		indexed = f.indexedMetas(ctx)
 	)
-	level.Debug(f.logger).Log("msg", "fetching meta data", "concurrency", f.concurrency)
+	level.Debug(f.logger).Log("msg", "fetching meta data", "concurrency", f.concurrency, "indexed", len(indexed))
 	for i := 0; i < f.concurrency; i++ {
 		eg.Go(func() error {
 			for id := range ch {
+				// Blocks of the bucket index are neither checked nor loaded one by one.
+				if meta, ok := indexed[id]; ok {
+					mtx.Lock()
+					resp.metas[id] = meta
+					mtx.Unlock()
+					f.bucketIndexMetas.Inc()
+					continue
+				}
+
 				meta, err := f.loadMeta(ctx, id)
 				if err == nil {
 					mtx.Lock()
@@ -393,6 +482,14 @@ func (f *BaseFetcher) fetchMetadata(ctx context.Context) (interface{}, error) {
 	f.cached = cached
 	f.mtx.Unlock()
 
+	if f.bucketIndexBkt != nil {
+		if err := WriteBucketIndex(ctx, f.bucketIndexBkt, cached); err != nil {
+			level.Warn(f.logger).Log("msg", "uploading bucket index failed", "err", err)
+		} else {
+			f.bucketIndexUploads.Inc()
+		}
+	}
+
 	// Best effort cleanup of disk-cached metas.
 	if f.cacheDir != "" {
 		fis, err := ioutil.ReadDir(f.cacheDir)
@@ -547,6 +644,32 @@ func (f *TimePartitionMetaFilter) Filter(_ context.Context, metas map[ulid.ULID]
 	return nil
 }
 
+var _ MetadataFilter = &LabelMatcherMetaFilter{}
+
+// LabelMatcherMetaFilter is a BaseFetcher filter that filters out blocks whose external labels don't match all matchers.
+type LabelMatcherMetaFilter struct {
+	matchers []*labels.Matcher
+}
+
+// NewLabelMatcherMetaFilter creates LabelMatcherMetaFilter.
+func NewLabelMatcherMetaFilter(matchers []*labels.Matcher) *LabelMatcherMetaFilter {
+	return &LabelMatcherMetaFilter{matchers: matchers}
+}
+
+// Filter filters out blocks with external labels which don't match. Missing labels match like empty values.
+func (f *LabelMatcherMetaFilter) Filter(_ context.Context, metas map[ulid.ULID]*metadata.Meta, synced *extprom.TxGaugeVec) error {
+	for id, m := range metas {
+		for _, matcher := range f.matchers {
+			if !matcher.Matches(m.Thanos.Labels[matcher.Name]) {
+				synced.WithLabelValues(labelExcludedMeta).Inc()
+				delete(metas, id)
+				break
+			}
+		}
+	}
+	return nil
+}
+
 var _ MetadataFilter = &LabelShardedMetaFilter{}
 
 // LabelShardedMetaFilter represents struct that allows sharding.
@@ -23,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/tsdb"
 
 	"github.com/thanos-io/thanos/pkg/block/metadata"
@@ -296,6 +297,68 @@ func TestMetaFetcher_Fetch(t *testing.T) {
 	})
 }
 
+func TestMetaFetcher_Fetch_BucketIndex(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.WithNoopInstr(objstore.NewInMemBucket())
+
+	upload := func(id ulid.ULID) {
+		var buf bytes.Buffer
+		testutil.Ok(t, json.NewEncoder(&buf).Encode(&metadata.Meta{BlockMeta: tsdb.BlockMeta{Version: 1, ULID: id}}))
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id.String(), metadata.MetaFilename), &buf))
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id.String(), IndexFilename), bytes.NewReader([]byte("index"))))
+	}
+	upload(ULID(1))
+	upload(ULID(2))
+
+	writer, err := NewMetaFetcher(log.NewNopLogger(), 4, bkt, "", nil, nil, nil, WithBucketIndexUpload(bkt))
+	testutil.Ok(t, err)
+	_, _, err = writer.Fetch(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1.0, promtest.ToFloat64(writer.wrapped.bucketIndexUploads))
+
+	idx, err := ReadBucketIndex(ctx, bkt)
+	testutil.Ok(t, err)
+	testutil.Equals(t, BucketIndexVersion1, idx.Version)
+	testutil.Equals(t, 2, len(idx.Blocks))
+	testutil.Equals(t, ULID(1), idx.Blocks[0].ULID)
+	testutil.Equals(t, ULID(2), idx.Blocks[1].ULID)
+
+	// Indexed blocks are not loaded one by one anymore, so their meta is taken from the index even if it is gone.
+	testutil.Ok(t, bkt.Delete(ctx, path.Join(ULID(1).String(), metadata.MetaFilename)))
+	// Indexed blocks which are gone are dropped.
+	testutil.Ok(t, bkt.Delete(ctx, path.Join(ULID(2).String(), metadata.MetaFilename)))
+	testutil.Ok(t, bkt.Delete(ctx, path.Join(ULID(2).String(), IndexFilename)))
+	// Blocks which are not indexed yet are loaded one by one.
+	upload(ULID(3))
+
+	reader, err := NewMetaFetcher(log.NewNopLogger(), 4, bkt, "", nil, nil, nil, WithBucketIndex(time.Hour))
+	testutil.Ok(t, err)
+	metas, partial, err := reader.Fetch(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(partial))
+	testutil.Equals(t, 2, len(metas))
+	testutil.Equals(t, ULID(1), metas[ULID(1)].ULID)
+	testutil.Equals(t, ULID(3), metas[ULID(3)].ULID)
+	testutil.Equals(t, 1.0, promtest.ToFloat64(reader.wrapped.bucketIndexMetas))
+
+	// Without the index, the block without meta is partial.
+	reader, err = NewMetaFetcher(log.NewNopLogger(), 4, bkt, "", nil, nil, nil)
+	testutil.Ok(t, err)
+	metas, partial, err = reader.Fetch(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(metas))
+	testutil.Equals(t, 1, len(partial))
+
+	// Stale indexes are not used.
+	reader, err = NewMetaFetcher(log.NewNopLogger(), 4, bkt, "", nil, nil, nil, WithBucketIndex(time.Nanosecond))
+	testutil.Ok(t, err)
+	time.Sleep(time.Millisecond)
+	metas, _, err = reader.Fetch(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(metas))
+	testutil.Equals(t, 0.0, promtest.ToFloat64(reader.wrapped.bucketIndexMetas))
+}
+
 func TestLabelShardedMetaFilter_Filter_Basic(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
@@ -523,6 +586,34 @@ func TestTimePartitionMetaFilter_Filter(t *testing.T) {
 
 }
 
+func TestLabelMatcherMetaFilter_Filter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	f := NewLabelMatcherMetaFilter([]*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, "tenant", "a"),
+		labels.MustNewMatcher(labels.MatchNotRegexp, "replica", "1|2"),
+	})
+
+	input := map[ulid.ULID]*metadata.Meta{
+		ULID(1): {Thanos: metadata.Thanos{Labels: map[string]string{"tenant": "a", "replica": "0"}}},
+		ULID(2): {Thanos: metadata.Thanos{Labels: map[string]string{"tenant": "a", "replica": "1"}}},
+		ULID(3): {Thanos: metadata.Thanos{Labels: map[string]string{"tenant": "b", "replica": "0"}}},
+		ULID(4): {Thanos: metadata.Thanos{Labels: map[string]string{"tenant": "a"}}},
+		ULID(5): {Thanos: metadata.Thanos{Labels: map[string]string{"replica": "0"}}},
+	}
+	expected := map[ulid.ULID]*metadata.Meta{
+		ULID(1): input[ULID(1)],
+		ULID(4): input[ULID(4)],
+	}
+
+	m := newTestFetcherMetrics()
+	testutil.Ok(t, f.Filter(ctx, input, m.Synced))
+
+	testutil.Equals(t, 3.0, promtest.ToFloat64(m.Synced.WithLabelValues(labelExcludedMeta)))
+	testutil.Equals(t, expected, input)
+}
+
 type sourcesAndResolution struct {
 	sources    []ulid.ULID
 	resolution int64
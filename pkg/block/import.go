@@ -0,0 +1,180 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package block
+
+import (
+	"context"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/textparse"
+	"github.com/prometheus/prometheus/tsdb"
+	tsdb_errors "github.com/prometheus/prometheus/tsdb/errors"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+)
+
+// maxSamplesInAppender is the number of samples after which the appender of the block being created is committed,
+// to limit the samples kept in memory.
+const maxSamplesInAppender = 5000
+
+// CreateBlocksFromOpenMetrics writes the samples of the given OpenMetrics text, which must all have timestamps, into
+// blocks in dir. A block is created for each time range of the given duration which contains samples, aligned to
+// multiples of the duration like blocks created by Prometheus, so that they can be compacted further. It returns the
+// IDs of the created blocks in the order of their time ranges.
+func CreateBlocksFromOpenMetrics(ctx context.Context, logger log.Logger, input []byte, dir string, blockDuration int64) ([]ulid.ULID, error) {
+	if blockDuration <= 0 {
+		return nil, errors.Errorf("block duration must be positive, got %d", blockDuration)
+	}
+
+	mint, maxt := int64(math.MaxInt64), int64(math.MinInt64)
+	if err := parseOpenMetrics(input, func(_ labels.Labels, ts int64, _ float64) error {
+		if ts < mint {
+			mint = ts
+		}
+		if ts > maxt {
+			maxt = ts
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if maxt < mint {
+		return nil, nil
+	}
+
+	var ids []ulid.ULID
+	for t := blockDuration * floorDiv(mint, blockDuration); t <= maxt; t += blockDuration {
+		id, err := createOpenMetricsBlock(ctx, logger, input, dir, t, t+blockDuration)
+		if err != nil {
+			return nil, errors.Wrapf(err, "create block for range %d-%d", t, t+blockDuration)
+		}
+		if id == (ulid.ULID{}) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// createOpenMetricsBlock writes the samples of the input within [mint, maxt) into a block in dir. It returns an empty
+// ID if there are no samples in the range.
+func createOpenMetricsBlock(ctx context.Context, logger log.Logger, input []byte, dir string, mint, maxt int64) (_ ulid.ULID, err error) {
+	// The block writer only accepts samples which are at most half of the block size older than the newest appended
+	// sample, so it has to be twice as large as the range for the samples to be appended in any order.
+	w, err := tsdb.NewBlockWriter(logger, dir, 2*(maxt-mint))
+	if err != nil {
+		return ulid.ULID{}, errors.Wrap(err, "create block writer")
+	}
+	defer func() {
+		err = tsdb_errors.NewMulti(err, w.Close()).Err()
+	}()
+
+	var (
+		app            = w.Appender(ctx)
+		samples, total int
+	)
+	if err := parseOpenMetrics(input, func(lset labels.Labels, ts int64, v float64) error {
+		if ts < mint || ts >= maxt {
+			return nil
+		}
+		if _, err := app.Append(0, lset, ts, v); err != nil {
+			return errors.Wrapf(err, "append sample of series %s", lset)
+		}
+		total++
+		if samples++; samples < maxSamplesInAppender {
+			return nil
+		}
+		if err := app.Commit(); err != nil {
+			return errors.Wrap(err, "commit")
+		}
+		app, samples = w.Appender(ctx), 0
+		return nil
+	}); err != nil {
+		return ulid.ULID{}, err
+	}
+	if err := app.Commit(); err != nil {
+		return ulid.ULID{}, errors.Wrap(err, "commit")
+	}
+	id, err := w.Flush(ctx)
+	if err == tsdb.ErrNoSeriesAppended || (err == nil && id == ulid.ULID{}) {
+		return ulid.ULID{}, nil
+	}
+	if err != nil {
+		return ulid.ULID{}, errors.Wrap(err, "flush")
+	}
+
+	// The head drops samples which are older than the last one of their series on commit.
+	m, err := metadata.ReadFromDir(filepath.Join(dir, id.String()))
+	if err != nil {
+		return ulid.ULID{}, errors.Wrapf(err, "read meta of block %s", id)
+	}
+	if m.Stats.NumSamples != uint64(total) {
+		return ulid.ULID{}, errors.Errorf("%d of %d samples were out of order or duplicated, the samples of each series must have increasing timestamps", uint64(total)-m.Stats.NumSamples, total)
+	}
+	return id, nil
+}
+
+// parseOpenMetrics calls f for each sample of the OpenMetrics text input.
+func parseOpenMetrics(input []byte, f func(lset labels.Labels, ts int64, v float64) error) error {
+	p := textparse.NewOpenMetricsParser(input)
+	for {
+		e, err := p.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "parse OpenMetrics input")
+		}
+		if e != textparse.EntrySeries {
+			continue
+		}
+
+		var lset labels.Labels
+		_, ts, v := p.Series()
+		p.Metric(&lset)
+		if ts == nil {
+			return errors.Errorf("expected timestamp for series %s, got none", lset)
+		}
+		if err := f(lset, *ts, v); err != nil {
+			return err
+		}
+	}
+}
+
+// floorDiv returns a divided by b, rounded towards negative infinity.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+// OverlappingBlocks returns the blocks of metas which overlap with the time range of m and have the same external
+// labels and resolution, i.e. which are in the same compaction group. The block m itself is not included.
+func OverlappingBlocks(metas []*metadata.Meta, m *metadata.Meta) []*metadata.Meta {
+	var res []*metadata.Meta
+	for _, o := range metas {
+		if o.ULID == m.ULID || o.Thanos.Downsample.Resolution != m.Thanos.Downsample.Resolution {
+			continue
+		}
+		if !labels.Equal(labels.FromMap(o.Thanos.Labels), labels.FromMap(m.Thanos.Labels)) {
+			continue
+		}
+		if o.MinTime < m.MaxTime && m.MinTime < o.MaxTime {
+			res = append(res, o)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ULID.Compare(res[j].ULID) < 0
+	})
+	return res
+}
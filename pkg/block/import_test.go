@@ -0,0 +1,78 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package block
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/tsdb"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestCreateBlocksFromOpenMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	tmpDir, err := ioutil.TempDir("", "test-openmetrics-blocks")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(tmpDir)) }()
+
+	const hour = int64(3600 * 1000)
+	input := []byte(`# HELP http_requests_total Total number of requests.
+# TYPE http_requests_total counter
+http_requests_total{code="200"} 0 0
+http_requests_total{code="200"} 1 3600
+http_requests_total{code="200"} 2 30000
+http_requests_total{code="500"} 1 7300
+# EOF
+`)
+	ids, err := CreateBlocksFromOpenMetrics(ctx, log.NewNopLogger(), input, tmpDir, 2*hour)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(ids))
+
+	for i, exp := range []struct {
+		mint, maxt         int64
+		series, numSamples uint64
+	}{
+		{mint: 0, maxt: 3600*1000 + 1, series: 1, numSamples: 2},
+		{mint: 7300 * 1000, maxt: 7300*1000 + 1, series: 1, numSamples: 1},
+		{mint: 30000 * 1000, maxt: 30000*1000 + 1, series: 1, numSamples: 1},
+	} {
+		m, err := metadata.ReadFromDir(filepath.Join(tmpDir, ids[i].String()))
+		testutil.Ok(t, err)
+		testutil.Equals(t, exp.mint, m.MinTime)
+		testutil.Equals(t, exp.maxt, m.MaxTime)
+		testutil.Equals(t, exp.series, m.Stats.NumSeries)
+		testutil.Equals(t, exp.numSamples, m.Stats.NumSamples)
+		testutil.Equals(t, m.MinTime/(2*hour), (m.MaxTime-1)/(2*hour))
+	}
+
+	// Samples without timestamps and out of order samples are rejected.
+	_, err = CreateBlocksFromOpenMetrics(ctx, log.NewNopLogger(), []byte("http_requests_total 1\n# EOF\n"), tmpDir, 2*hour)
+	testutil.NotOk(t, err)
+	_, err = CreateBlocksFromOpenMetrics(ctx, log.NewNopLogger(), []byte("http_requests_total 2 60\nhttp_requests_total 1 0\n# EOF\n"), tmpDir, 2*hour)
+	testutil.NotOk(t, err)
+}
+
+func TestOverlappingBlocks(t *testing.T) {
+	meta := func(id uint64, mint, maxt int64, res int64, lset map[string]string) *metadata.Meta {
+		return &metadata.Meta{
+			BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(id, nil), MinTime: mint, MaxTime: maxt},
+			Thanos:    metadata.Thanos{Labels: lset, Downsample: metadata.ThanosDownsample{Resolution: res}},
+		}
+	}
+	a := map[string]string{"cluster": "a"}
+	m := meta(1, 10, 20, 0, a)
+	b2, b3, b4, b5, b6 := meta(2, 0, 11, 0, a), meta(3, 19, 30, 0, a), meta(4, 20, 30, 0, a), meta(5, 10, 20, 300000, a), meta(6, 10, 20, 0, map[string]string{"cluster": "b"})
+
+	testutil.Equals(t, []*metadata.Meta{b2, b3}, OverlappingBlocks([]*metadata.Meta{m, b6, b5, b4, b3, b2}, m))
+	testutil.Equals(t, 0, len(OverlappingBlocks([]*metadata.Meta{m, b4, b5, b6}, m)))
+}
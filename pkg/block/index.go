@@ -4,6 +4,7 @@
 package block
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"hash/crc32"
@@ -21,6 +22,7 @@ import (
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/prometheus/tsdb/chunks"
 	"github.com/prometheus/prometheus/tsdb/index"
 
@@ -172,6 +174,92 @@ func (i HealthStats) AnyErr() error {
 	return nil
 }
 
+// CardinalityStats are the label and metric cardinality statistics of a block index.
+type CardinalityStats struct {
+	Series uint64
+	// LabelValues is the number of values of each label name.
+	LabelValues map[string]int
+	// MetricSeries is the number of series of each metric name.
+	MetricSeries map[string]uint64
+}
+
+// Merge adds the statistics of another block. Series of the same label set in multiple blocks are counted once per
+// block. As values are not tracked, the number of values of a label name is the highest in any block, which is a lower
+// bound of the number of its distinct values.
+func (s *CardinalityStats) Merge(o CardinalityStats) {
+	if s.LabelValues == nil {
+		s.LabelValues = map[string]int{}
+	}
+	if s.MetricSeries == nil {
+		s.MetricSeries = map[string]uint64{}
+	}
+
+	s.Series += o.Series
+	for name, n := range o.LabelValues {
+		if n > s.LabelValues[name] {
+			s.LabelValues[name] = n
+		}
+	}
+	for name, n := range o.MetricSeries {
+		s.MetricSeries[name] += n
+	}
+}
+
+// GatherIndexCardinalityStats returns the cardinality statistics of the given index file. Only the postings are read,
+// not the series.
+func GatherIndexCardinalityStats(fn string) (stats CardinalityStats, err error) {
+	r, err := index.NewFileReader(fn)
+	if err != nil {
+		return stats, errors.Wrap(err, "open index file")
+	}
+	defer runutil.CloseWithErrCapture(&err, r, "gather index cardinality file reader")
+
+	countPostings := func(name, value string) (uint64, error) {
+		p, err := r.Postings(name, value)
+		if err != nil {
+			return 0, errors.Wrapf(err, "get postings of %s=%q", name, value)
+		}
+		var n uint64
+		for p.Next() {
+			n++
+		}
+		return n, errors.Wrapf(p.Err(), "iterate postings of %s=%q", name, value)
+	}
+
+	allName, allValue := index.AllPostingsKey()
+	if stats.Series, err = countPostings(allName, allValue); err != nil {
+		return stats, err
+	}
+
+	lnames, err := r.LabelNames()
+	if err != nil {
+		return stats, errors.Wrap(err, "label names")
+	}
+	// The names are copied, as the strings returned by the reader refer to the mmaped index file.
+	stats.LabelValues = make(map[string]int, len(lnames))
+	for _, name := range lnames {
+		lvals, err := r.LabelValues(name)
+		if err != nil {
+			return stats, errors.Wrapf(err, "label values of %s", name)
+		}
+		stats.LabelValues[string([]byte(name))] = len(lvals)
+	}
+
+	metrics, err := r.LabelValues(labels.MetricName)
+	if err != nil {
+		return stats, errors.Wrap(err, "metric label values")
+	}
+	stats.MetricSeries = make(map[string]uint64, len(metrics))
+	for _, m := range metrics {
+		n, err := countPostings(labels.MetricName, m)
+		if err != nil {
+			return stats, err
+		}
+		stats.MetricSeries[string([]byte(m))] = n
+	}
+	return stats, nil
+}
+
 type minMaxSumInt64 struct {
 	sum int64
 	min int64
@@ -379,7 +467,24 @@ func Repair(logger log.Logger, dir string, id ulid.ULID, source metadata.SourceT
 	if len(ignoreChkFns) == 0 {
 		return resid, errors.New("no ignore chunk function specified")
 	}
+	return repair(logger, dir, id, source, func(chks []chunks.Meta, mint, maxt int64) ([]chunks.Meta, error) {
+		return sanitizeChunkSequence(chks, mint, maxt, ignoreChkFns)
+	})
+}
 
+// RepairOutOfOrderChunks opens the block with given id in dir and creates a new one, in which the chunks of each
+// series are ordered by time and chunks overlapping in time are merged into new chunks. Of the samples with the same
+// timestamp, the sample of the chunk starting first is kept.
+func RepairOutOfOrderChunks(logger log.Logger, dir string, id ulid.ULID, source metadata.SourceType) (resid ulid.ULID, err error) {
+	return repair(logger, dir, id, source, func(chks []chunks.Meta, _, _ int64) ([]chunks.Meta, error) {
+		return mergeOverlappingChunks(chks)
+	})
+}
+
+// sanitizeFn returns the chunks of a series to write to the repaired block.
+type sanitizeFn func(chks []chunks.Meta, mint, maxt int64) ([]chunks.Meta, error)
+
+func repair(logger log.Logger, dir string, id ulid.ULID, source metadata.SourceType, sanitize sanitizeFn) (resid ulid.ULID, err error) {
 	bdir := filepath.Join(dir, id.String())
 	entropy := rand.New(rand.NewSource(time.Now().UnixNano()))
 	resid = ulid.MustNew(ulid.Now(), entropy)
@@ -431,7 +536,7 @@ func Repair(logger log.Logger, dir string, id ulid.ULID, source metadata.SourceT
 	resmeta.Stats = tsdb.BlockStats{} // Reset stats.
 	resmeta.Thanos.Source = source    // Update source.
 
-	if err := rewrite(logger, indexr, chunkr, indexw, chunkw, &resmeta, ignoreChkFns); err != nil {
+	if err := rewriteWith(logger, indexr, chunkr, indexw, chunkw, &resmeta, sanitize); err != nil {
 		return resid, errors.Wrap(err, "rewrite block")
 	}
 	resmeta.Thanos.SegmentFiles = GetSegmentFiles(resdir)
@@ -448,6 +553,10 @@ func Repair(logger log.Logger, dir string, id ulid.ULID, source metadata.SourceT
 
 var castagnoli = crc32.MakeTable(crc32.Castagnoli)
 
+// maxSamplesPerChunk is the maximum number of samples of the chunks encoded by repairs, like for the chunks of the
+// TSDB head.
+const maxSamplesPerChunk = 120
+
 func IgnoreCompleteOutsideChunk(mint, maxt int64, _, curr *chunks.Meta) (bool, error) {
 	if curr.MinTime > maxt || curr.MaxTime < mint {
 		// "Complete" outsider. Ignore.
@@ -529,6 +638,99 @@ OUTER:
 	return repl, nil
 }
 
+// mergeOverlappingChunks orders the chunks by time and merges chunks overlapping in time. Exact duplicates of a chunk
+// are dropped, while the samples of other overlapping chunks are merged and encoded into new chunks.
+func mergeOverlappingChunks(chks []chunks.Meta) ([]chunks.Meta, error) {
+	if len(chks) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(chks, func(i, j int) bool {
+		return chks[i].MinTime < chks[j].MinTime
+	})
+
+	repl := make([]chunks.Meta, 0, len(chks))
+	for i := 0; i < len(chks); {
+		// Find the chunks overlapping, transitively, with the current one.
+		j, maxt := i+1, chks[i].MaxTime
+		for ; j < len(chks) && chks[j].MinTime <= maxt; j++ {
+			if chks[j].MaxTime > maxt {
+				maxt = chks[j].MaxTime
+			}
+		}
+		if j-i == 1 {
+			repl = append(repl, chks[i])
+			i = j
+			continue
+		}
+
+		merged, err := mergeChunks(chks[i:j])
+		if err != nil {
+			return nil, err
+		}
+		repl = append(repl, merged...)
+		i = j
+	}
+	return repl, nil
+}
+
+// mergeChunks merges the samples of the given chunks, ordered by their start time, into new chunks of at most
+// maxSamplesPerChunk samples. Exact duplicates of the first chunk are merged without decoding them.
+func mergeChunks(chks []chunks.Meta) ([]chunks.Meta, error) {
+	duplicates := true
+	for _, c := range chks[1:] {
+		if c.MinTime != chks[0].MinTime || c.MaxTime != chks[0].MaxTime || !bytes.Equal(c.Chunk.Bytes(), chks[0].Chunk.Bytes()) {
+			duplicates = false
+			break
+		}
+	}
+	if duplicates {
+		return chks[:1], nil
+	}
+
+	type sample struct {
+		t int64
+		v float64
+	}
+	var samples []sample
+	for _, c := range chks {
+		if c.Chunk.Encoding() != chunkenc.EncXOR {
+			return nil, errors.Errorf("can't merge chunks with %s encoding", c.Chunk.Encoding())
+		}
+		it := c.Chunk.Iterator(nil)
+		for it.Next() {
+			t, v := it.At()
+			samples = append(samples, sample{t: t, v: v})
+		}
+		if it.Err() != nil {
+			return nil, errors.Wrap(it.Err(), "iterate chunk")
+		}
+	}
+	// The stable sort keeps the samples of the chunks starting first before the ones with the same timestamp.
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].t < samples[j].t })
+
+	var (
+		res []chunks.Meta
+		app chunkenc.Appender
+	)
+	for i, s := range samples {
+		if i > 0 && s.t == samples[i-1].t {
+			continue
+		}
+		if app == nil || res[len(res)-1].Chunk.NumSamples() >= maxSamplesPerChunk {
+			c := chunkenc.NewXORChunk()
+			a, err := c.Appender()
+			if err != nil {
+				return nil, errors.Wrap(err, "chunk appender")
+			}
+			app = a
+			res = append(res, chunks.Meta{MinTime: s.t, Chunk: c})
+		}
+		app.Append(s.t, s.v)
+		res[len(res)-1].MaxTime = s.t
+	}
+	return res, nil
+}
+
 type seriesRepair struct {
 	lset labels.Labels
 	chks []chunks.Meta
@@ -542,6 +744,20 @@ func rewrite(
 	indexw tsdb.IndexWriter, chunkw tsdb.ChunkWriter,
 	meta *metadata.Meta,
 	ignoreChkFns []ignoreFnType,
+) error {
+	return rewriteWith(logger, indexr, chunkr, indexw, chunkw, meta, func(chks []chunks.Meta, mint, maxt int64) ([]chunks.Meta, error) {
+		return sanitizeChunkSequence(chks, mint, maxt, ignoreChkFns)
+	})
+}
+
+// rewriteWith writes all data from the readers back into the writers with the chunks of each series returned by
+// sanitize.
+func rewriteWith(
+	logger log.Logger,
+	indexr tsdb.IndexReader, chunkr tsdb.ChunkReader,
+	indexw tsdb.IndexWriter, chunkw tsdb.ChunkWriter,
+	meta *metadata.Meta,
+	sanitize sanitizeFn,
 ) error {
 	symbols := indexr.Symbols()
 	for symbols.Next() {
@@ -585,7 +801,7 @@ func rewrite(
 			}
 		}
 
-		chks, err := sanitizeChunkSequence(chks, meta.MinTime, meta.MaxTime, ignoreChkFns)
+		chks, err := sanitize(chks, meta.MinTime, meta.MaxTime)
 		if err != nil {
 			return err
 		}
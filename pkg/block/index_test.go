@@ -14,6 +14,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/prometheus/tsdb/chunks"
 	"github.com/prometheus/prometheus/tsdb/index"
 
@@ -100,3 +101,77 @@ func TestGatherIndexHealthStatsReturnsOutOfOrderChunksErr(t *testing.T) {
 	testutil.Equals(t, 1, stats.OutOfOrderChunks)
 	testutil.NotOk(t, stats.OutOfOrderChunksErr())
 }
+
+func TestGatherIndexCardinalityStats(t *testing.T) {
+	ctx := context.Background()
+
+	tmpDir, err := ioutil.TempDir("", "test-cardinality")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(tmpDir)) }()
+
+	b, err := e2eutil.CreateBlock(ctx, tmpDir, []labels.Labels{
+		labels.FromStrings("__name__", "up", "job", "a", "instance", "1"),
+		labels.FromStrings("__name__", "up", "job", "a", "instance", "2"),
+		labels.FromStrings("__name__", "up", "job", "b", "instance", "1"),
+		labels.FromStrings("__name__", "requests_total", "job", "a", "instance", "1"),
+	}, 10, 0, 1000, nil, 124, metadata.NoneFunc)
+	testutil.Ok(t, err)
+
+	stats, err := GatherIndexCardinalityStats(filepath.Join(tmpDir, b.String(), IndexFilename))
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(4), stats.Series)
+	testutil.Equals(t, map[string]int{"__name__": 2, "job": 2, "instance": 2}, stats.LabelValues)
+	testutil.Equals(t, map[string]uint64{"up": 3, "requests_total": 1}, stats.MetricSeries)
+
+	var total CardinalityStats
+	total.Merge(stats)
+	total.Merge(CardinalityStats{Series: 1, LabelValues: map[string]int{"job": 1, "pod": 1}, MetricSeries: map[string]uint64{"up": 1}})
+	testutil.Equals(t, uint64(5), total.Series)
+	testutil.Equals(t, map[string]int{"__name__": 2, "job": 2, "instance": 2, "pod": 1}, total.LabelValues)
+	testutil.Equals(t, map[string]uint64{"up": 4, "requests_total": 1}, total.MetricSeries)
+}
+
+func TestMergeOverlappingChunks(t *testing.T) {
+	chunk := func(samples ...int64) chunks.Meta {
+		c := chunkenc.NewXORChunk()
+		app, err := c.Appender()
+		testutil.Ok(t, err)
+		for _, ts := range samples {
+			app.Append(ts, float64(ts))
+		}
+		return chunks.Meta{MinTime: samples[0], MaxTime: samples[len(samples)-1], Chunk: c}
+	}
+	samples := func(chks []chunks.Meta) (res [][]int64) {
+		for _, c := range chks {
+			var ts []int64
+			it := c.Chunk.Iterator(nil)
+			for it.Next() {
+				t, _ := it.At()
+				ts = append(ts, t)
+			}
+			testutil.Equals(t, ts[0], c.MinTime)
+			testutil.Equals(t, ts[len(ts)-1], c.MaxTime)
+			res = append(res, ts)
+		}
+		return res
+	}
+
+	chks, err := mergeOverlappingChunks([]chunks.Meta{
+		chunk(50, 60),
+		chunk(0, 10, 20),
+		chunk(15, 20, 30),
+		chunk(50, 60),
+		chunk(70, 80),
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, [][]int64{{0, 10, 15, 20, 30}, {50, 60}, {70, 80}}, samples(chks))
+
+	// Merged chunks are split like the chunks of the TSDB head.
+	long := make([]int64, 0, 200)
+	for i := int64(0); i < 200; i++ {
+		long = append(long, i)
+	}
+	chks, err = mergeOverlappingChunks([]chunks.Meta{chunk(long[:150]...), chunk(long[100:]...)})
+	testutil.Ok(t, err)
+	testutil.Equals(t, [][]int64{long[:120], long[120:]}, samples(chks))
+}
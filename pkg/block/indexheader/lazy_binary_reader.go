@@ -35,6 +35,8 @@ type LazyBinaryReaderMetrics struct {
 	unloadCount       prometheus.Counter
 	unloadFailedCount prometheus.Counter
 	loadDuration      prometheus.Histogram
+	loaded            prometheus.Gauge
+	reloadInterval    prometheus.Histogram
 }
 
 // NewLazyBinaryReaderMetrics makes new LazyBinaryReaderMetrics.
@@ -61,6 +63,15 @@ func NewLazyBinaryReaderMetrics(reg prometheus.Registerer) *LazyBinaryReaderMetr
 			Help:    "Duration of the index-header lazy loading in seconds.",
 			Buckets: []float64{0.01, 0.02, 0.05, 0.1, 0.2, 0.5, 1, 2, 5, 15, 30, 60, 120, 300},
 		}),
+		loaded: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "indexheader_lazy_loaded",
+			Help: "Number of lazily loaded index-headers currently loaded.",
+		}),
+		reloadInterval: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:    "indexheader_lazy_reload_interval_seconds",
+			Help:    "Time between the unload of idle index-headers and their next load. Short intervals mean index-headers churn because their idle timeout is too short.",
+			Buckets: []float64{60, 300, 900, 1800, 3600, 3 * 3600, 6 * 3600, 12 * 3600, 24 * 3600},
+		}),
 	}
 }
 
@@ -83,6 +94,8 @@ type LazyBinaryReader struct {
 
 	// Keep track of the last time it was used.
 	usedAt *atomic.Int64
+	// Keep track of the last time it was unloaded because idle, 0 if never.
+	unloadedAt *atomic.Int64
 }
 
 // NewLazyBinaryReader makes a new LazyBinaryReader. If the index-header does not exist
@@ -127,6 +140,7 @@ func NewLazyBinaryReader(
 		postingOffsetsInMemSampling: postingOffsetsInMemSampling,
 		metrics:                     metrics,
 		usedAt:                      atomic.NewInt64(time.Now().UnixNano()),
+		unloadedAt:                  atomic.NewInt64(0),
 		onClosed:                    onClosed,
 	}, nil
 }
@@ -255,6 +269,10 @@ func (r *LazyBinaryReader) load() (returnErr error) {
 	r.reader = reader
 	level.Debug(r.logger).Log("msg", "lazy loaded index-header file", "path", r.filepath, "elapsed", time.Since(startTime))
 	r.metrics.loadDuration.Observe(time.Since(startTime).Seconds())
+	r.metrics.loaded.Inc()
+	if unloadedAt := r.unloadedAt.Load(); unloadedAt > 0 {
+		r.metrics.reloadInterval.Observe(time.Duration(startTime.UnixNano() - unloadedAt).Seconds())
+	}
 
 	return nil
 }
@@ -282,6 +300,10 @@ func (r *LazyBinaryReader) unloadIfIdleSince(ts int64) error {
 	}
 
 	r.reader = nil
+	r.metrics.loaded.Dec()
+	if ts > 0 {
+		r.unloadedAt.Store(time.Now().UnixNano())
+	}
 	return nil
 }
 
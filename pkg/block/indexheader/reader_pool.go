@@ -10,10 +10,10 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
-	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/objstore"
 )
 
@@ -33,34 +33,40 @@ func NewReaderPoolMetrics(reg prometheus.Registerer) *ReaderPoolMetrics {
 // When the lazy reader is enabled, the pool keeps track of all instantiated readers
 // and automatically close them once the idle timeout is reached. A closed lazy reader
 // will be automatically re-opened upon next usage.
+// The strategy rules of the pool override the lazy reader settings for the blocks they match.
 type ReaderPool struct {
 	lazyReaderEnabled     bool
 	lazyReaderIdleTimeout time.Duration
+	protectionWindow      time.Duration
+	strategyRules         []StrategyRule
 	logger                log.Logger
 	metrics               *ReaderPoolMetrics
 
 	// Channel used to signal once the pool is closing.
 	close chan struct{}
 
-	// Keep track of all readers managed by the pool.
+	// Keep track of all readers managed by the pool, with their idle timeout.
 	lazyReadersMx sync.Mutex
-	lazyReaders   map[*LazyBinaryReader]struct{}
+	lazyReaders   map[*LazyBinaryReader]time.Duration
 }
 
-// NewReaderPool makes a new ReaderPool.
-func NewReaderPool(logger log.Logger, lazyReaderEnabled bool, lazyReaderIdleTimeout time.Duration, metrics *ReaderPoolMetrics) *ReaderPool {
+// NewReaderPool makes a new ReaderPool. Lazy readers used within the protection window are never closed, whatever
+// their idle timeout, so that recently queried blocks aren't unloaded by short idle timeouts.
+func NewReaderPool(logger log.Logger, lazyReaderEnabled bool, lazyReaderIdleTimeout, protectionWindow time.Duration, strategyRules []StrategyRule, metrics *ReaderPoolMetrics) *ReaderPool {
 	p := &ReaderPool{
 		logger:                logger,
 		metrics:               metrics,
 		lazyReaderEnabled:     lazyReaderEnabled,
 		lazyReaderIdleTimeout: lazyReaderIdleTimeout,
-		lazyReaders:           make(map[*LazyBinaryReader]struct{}),
+		protectionWindow:      protectionWindow,
+		strategyRules:         strategyRules,
+		lazyReaders:           make(map[*LazyBinaryReader]time.Duration),
 		close:                 make(chan struct{}),
 	}
 
 	// Start a goroutine to close idle readers (only if required).
-	if p.lazyReaderEnabled && p.lazyReaderIdleTimeout > 0 {
-		checkFreq := p.lazyReaderIdleTimeout / 10
+	if minIdleTimeout := p.minIdleTimeout(); minIdleTimeout > 0 {
+		checkFreq := minIdleTimeout / 10
 
 		go func() {
 			for {
@@ -77,17 +83,57 @@ func NewReaderPool(logger log.Logger, lazyReaderEnabled bool, lazyReaderIdleTime
 	return p
 }
 
+// minIdleTimeout returns the minimum idle timeout of lazy readers, or 0 if none is ever closed when idle.
+func (p *ReaderPool) minIdleTimeout() time.Duration {
+	var timeouts []time.Duration
+	if p.lazyReaderEnabled {
+		timeouts = append(timeouts, p.lazyReaderIdleTimeout)
+	}
+	for _, r := range p.strategyRules {
+		if r.Strategy == StrategyLazy {
+			timeouts = append(timeouts, time.Duration(r.IdleTimeout))
+		}
+	}
+
+	var min time.Duration
+	for _, t := range timeouts {
+		if t > 0 && t < p.protectionWindow {
+			t = p.protectionWindow
+		}
+		if t > 0 && (min == 0 || t < min) {
+			min = t
+		}
+	}
+	return min
+}
+
+// strategy returns the strategy and the idle timeout of the block of the meta.
+func (p *ReaderPool) strategy(meta *metadata.Meta) (Strategy, time.Duration) {
+	now := time.Now()
+	for _, r := range p.strategyRules {
+		if r.matches(meta, now) {
+			return r.Strategy, time.Duration(r.IdleTimeout)
+		}
+	}
+	if p.lazyReaderEnabled {
+		return StrategyLazy, p.lazyReaderIdleTimeout
+	}
+	return StrategyEager, 0
+}
+
 // NewBinaryReader creates and returns a new binary reader. If the pool has been configured
-// with lazy reader enabled, this function will return a lazy reader. The returned lazy reader
-// is tracked by the pool and automatically closed once the idle timeout expires.
-func (p *ReaderPool) NewBinaryReader(ctx context.Context, logger log.Logger, bkt objstore.BucketReader, dir string, id ulid.ULID, postingOffsetsInMemSampling int) (Reader, error) {
+// with lazy reader enabled, or a strategy rule choosing the lazy strategy matches the block,
+// this function will return a lazy reader. The returned lazy reader is tracked by the pool
+// and automatically closed once its idle timeout expires.
+func (p *ReaderPool) NewBinaryReader(ctx context.Context, logger log.Logger, bkt objstore.BucketReader, dir string, meta *metadata.Meta, postingOffsetsInMemSampling int) (Reader, error) {
 	var reader Reader
 	var err error
 
-	if p.lazyReaderEnabled {
-		reader, err = NewLazyBinaryReader(ctx, logger, bkt, dir, id, postingOffsetsInMemSampling, p.metrics.lazyReader, p.onLazyReaderClosed)
+	strategy, idleTimeout := p.strategy(meta)
+	if strategy == StrategyLazy {
+		reader, err = NewLazyBinaryReader(ctx, logger, bkt, dir, meta.ULID, postingOffsetsInMemSampling, p.metrics.lazyReader, p.onLazyReaderClosed)
 	} else {
-		reader, err = NewBinaryReader(ctx, logger, bkt, dir, id, postingOffsetsInMemSampling)
+		reader, err = NewBinaryReader(ctx, logger, bkt, dir, meta.ULID, postingOffsetsInMemSampling)
 	}
 
 	if err != nil {
@@ -95,9 +141,12 @@ func (p *ReaderPool) NewBinaryReader(ctx context.Context, logger log.Logger, bkt
 	}
 
 	// Keep track of lazy readers only if required.
-	if p.lazyReaderEnabled && p.lazyReaderIdleTimeout > 0 {
+	if strategy == StrategyLazy && idleTimeout > 0 {
+		if idleTimeout < p.protectionWindow {
+			idleTimeout = p.protectionWindow
+		}
 		p.lazyReadersMx.Lock()
-		p.lazyReaders[reader.(*LazyBinaryReader)] = struct{}{}
+		p.lazyReaders[reader.(*LazyBinaryReader)] = idleTimeout
 		p.lazyReadersMx.Unlock()
 	}
 
@@ -111,23 +160,24 @@ func (p *ReaderPool) Close() {
 }
 
 func (p *ReaderPool) closeIdleReaders() {
-	idleTimeoutAgo := time.Now().Add(-p.lazyReaderIdleTimeout).UnixNano()
+	now := time.Now()
 
-	for _, r := range p.getIdleReadersSince(idleTimeoutAgo) {
-		if err := r.unloadIfIdleSince(idleTimeoutAgo); err != nil && !errors.Is(err, errNotIdle) {
+	for r, idleTimeout := range p.getIdleReaders(now) {
+		if err := r.unloadIfIdleSince(now.Add(-idleTimeout).UnixNano()); err != nil && !errors.Is(err, errNotIdle) {
 			level.Warn(p.logger).Log("msg", "failed to close idle index-header reader", "err", err)
 		}
 	}
 }
 
-func (p *ReaderPool) getIdleReadersSince(ts int64) []*LazyBinaryReader {
+// getIdleReaders returns the readers idle since their idle timeout at the given time, with their idle timeout.
+func (p *ReaderPool) getIdleReaders(now time.Time) map[*LazyBinaryReader]time.Duration {
 	p.lazyReadersMx.Lock()
 	defer p.lazyReadersMx.Unlock()
 
-	var idle []*LazyBinaryReader
-	for r := range p.lazyReaders {
-		if r.isIdleSince(ts) {
-			idle = append(idle, r)
+	idle := map[*LazyBinaryReader]time.Duration{}
+	for r, idleTimeout := range p.lazyReaders {
+		if r.isIdleSince(now.Add(-idleTimeout).UnixNano()) {
+			idle[r] = idleTimeout
 		}
 	}
 
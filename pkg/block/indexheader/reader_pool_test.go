@@ -13,6 +13,7 @@ import (
 
 	"github.com/go-kit/log"
 	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 
 	"github.com/thanos-io/thanos/pkg/block"
@@ -57,13 +58,15 @@ func TestReaderPool_NewBinaryReader(t *testing.T) {
 	}, 100, 0, 1000, labels.Labels{{Name: "ext1", Value: "1"}}, 124, metadata.NoneFunc)
 	testutil.Ok(t, err)
 	testutil.Ok(t, block.Upload(ctx, log.NewNopLogger(), bkt, filepath.Join(tmpDir, blockID.String()), metadata.NoneFunc))
+	meta, err := metadata.ReadFromDir(filepath.Join(tmpDir, blockID.String()))
+	testutil.Ok(t, err)
 
 	for testName, testData := range tests {
 		t.Run(testName, func(t *testing.T) {
-			pool := NewReaderPool(log.NewNopLogger(), testData.lazyReaderEnabled, testData.lazyReaderIdleTimeout, NewReaderPoolMetrics(nil))
+			pool := NewReaderPool(log.NewNopLogger(), testData.lazyReaderEnabled, testData.lazyReaderIdleTimeout, 0, nil, NewReaderPoolMetrics(nil))
 			defer pool.Close()
 
-			r, err := pool.NewBinaryReader(ctx, log.NewNopLogger(), bkt, tmpDir, blockID, 3)
+			r, err := pool.NewBinaryReader(ctx, log.NewNopLogger(), bkt, tmpDir, meta, 3)
 			testutil.Ok(t, err)
 			defer func() { testutil.Ok(t, r.Close()) }()
 
@@ -95,12 +98,14 @@ func TestReaderPool_ShouldCloseIdleLazyReaders(t *testing.T) {
 	}, 100, 0, 1000, labels.Labels{{Name: "ext1", Value: "1"}}, 124, metadata.NoneFunc)
 	testutil.Ok(t, err)
 	testutil.Ok(t, block.Upload(ctx, log.NewNopLogger(), bkt, filepath.Join(tmpDir, blockID.String()), metadata.NoneFunc))
+	meta, err := metadata.ReadFromDir(filepath.Join(tmpDir, blockID.String()))
+	testutil.Ok(t, err)
 
 	metrics := NewReaderPoolMetrics(nil)
-	pool := NewReaderPool(log.NewNopLogger(), true, idleTimeout, metrics)
+	pool := NewReaderPool(log.NewNopLogger(), true, idleTimeout, 0, nil, metrics)
 	defer pool.Close()
 
-	r, err := pool.NewBinaryReader(ctx, log.NewNopLogger(), bkt, tmpDir, blockID, 3)
+	r, err := pool.NewBinaryReader(ctx, log.NewNopLogger(), bkt, tmpDir, meta, 3)
 	testutil.Ok(t, err)
 	defer func() { testutil.Ok(t, r.Close()) }()
 
@@ -119,6 +124,8 @@ func TestReaderPool_ShouldCloseIdleLazyReaders(t *testing.T) {
 	testutil.Equals(t, float64(1), promtestutil.ToFloat64(metrics.lazyReader.loadCount))
 	testutil.Equals(t, float64(1), promtestutil.ToFloat64(metrics.lazyReader.unloadCount))
 
+	testutil.Equals(t, float64(0), promtestutil.ToFloat64(metrics.lazyReader.loaded))
+
 	// Ensure it can still read data (will be re-opened).
 	labelNames, err = r.LabelNames()
 	testutil.Ok(t, err)
@@ -126,6 +133,8 @@ func TestReaderPool_ShouldCloseIdleLazyReaders(t *testing.T) {
 	testutil.Assert(t, pool.isTracking(r.(*LazyBinaryReader)))
 	testutil.Equals(t, float64(2), promtestutil.ToFloat64(metrics.lazyReader.loadCount))
 	testutil.Equals(t, float64(1), promtestutil.ToFloat64(metrics.lazyReader.unloadCount))
+	testutil.Equals(t, float64(1), promtestutil.ToFloat64(metrics.lazyReader.loaded))
+	testutil.Equals(t, 1, promtestutil.CollectAndCount(metrics.lazyReader.reloadInterval))
 
 	// We expect an explicit call to Close() to close the reader and release it from the pool too.
 	testutil.Ok(t, r.Close())
@@ -133,3 +142,98 @@ func TestReaderPool_ShouldCloseIdleLazyReaders(t *testing.T) {
 	testutil.Equals(t, float64(2), promtestutil.ToFloat64(metrics.lazyReader.loadCount))
 	testutil.Equals(t, float64(2), promtestutil.ToFloat64(metrics.lazyReader.unloadCount))
 }
+
+func TestReaderPool_StrategyRules(t *testing.T) {
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	bkt, err := filesystem.NewBucket(filepath.Join(tmpDir, "bkt"))
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, bkt.Close()) }()
+
+	blockID, err := e2eutil.CreateBlock(ctx, tmpDir, []labels.Labels{
+		{{Name: "a", Value: "1"}},
+		{{Name: "a", Value: "2"}},
+	}, 100, 0, 1000, labels.Labels{{Name: "ext1", Value: "1"}}, 124, metadata.NoneFunc)
+	testutil.Ok(t, err)
+	testutil.Ok(t, block.Upload(ctx, log.NewNopLogger(), bkt, filepath.Join(tmpDir, blockID.String()), metadata.NoneFunc))
+	meta, err := metadata.ReadFromDir(filepath.Join(tmpDir, blockID.String()))
+	testutil.Ok(t, err)
+
+	const idleTimeout = time.Second
+	rules := []StrategyRule{
+		// Recent blocks are loaded eagerly.
+		{MaxAge: model.Duration(time.Hour), Strategy: StrategyEager},
+		// Old blocks lazily, and unloaded when idle.
+		{Strategy: StrategyLazy, IdleTimeout: model.Duration(idleTimeout)},
+	}
+	metrics := NewReaderPoolMetrics(nil)
+	pool := NewReaderPool(log.NewNopLogger(), false, 0, 0, rules, metrics)
+	defer pool.Close()
+
+	r, err := pool.NewBinaryReader(ctx, log.NewNopLogger(), bkt, tmpDir, meta, 3)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, r.Close()) }()
+
+	lazyReader, ok := r.(*LazyBinaryReader)
+	testutil.Assert(t, ok, "expected lazy reader, got %T", r)
+	testutil.Assert(t, pool.isTracking(lazyReader))
+	testutil.Equals(t, float64(0), promtestutil.ToFloat64(metrics.lazyReader.loadCount))
+
+	labelNames, err := r.LabelNames()
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"a"}, labelNames)
+	testutil.Equals(t, float64(1), promtestutil.ToFloat64(metrics.lazyReader.loadCount))
+
+	// The reader is unloaded once idle.
+	time.Sleep(idleTimeout * 2)
+	testutil.Equals(t, float64(1), promtestutil.ToFloat64(metrics.lazyReader.unloadCount))
+
+	// Recent blocks are loaded eagerly.
+	recent := *meta
+	recent.MaxTime = time.Now().UnixNano() / int64(time.Millisecond)
+	r2, err := pool.NewBinaryReader(ctx, log.NewNopLogger(), bkt, tmpDir, &recent, 3)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, r2.Close()) }()
+	_, ok = r2.(*BinaryReader)
+	testutil.Assert(t, ok, "expected binary reader, got %T", r2)
+}
+
+func TestReaderPool_ProtectionWindow(t *testing.T) {
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	bkt, err := filesystem.NewBucket(filepath.Join(tmpDir, "bkt"))
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, bkt.Close()) }()
+
+	blockID, err := e2eutil.CreateBlock(ctx, tmpDir, []labels.Labels{
+		{{Name: "a", Value: "1"}},
+	}, 100, 0, 1000, labels.Labels{{Name: "ext1", Value: "1"}}, 124, metadata.NoneFunc)
+	testutil.Ok(t, err)
+	testutil.Ok(t, block.Upload(ctx, log.NewNopLogger(), bkt, filepath.Join(tmpDir, blockID.String()), metadata.NoneFunc))
+	meta, err := metadata.ReadFromDir(filepath.Join(tmpDir, blockID.String()))
+	testutil.Ok(t, err)
+
+	const (
+		idleTimeout      = 10 * time.Millisecond
+		protectionWindow = time.Hour
+	)
+	metrics := NewReaderPoolMetrics(nil)
+	pool := NewReaderPool(log.NewNopLogger(), true, idleTimeout, protectionWindow, nil, metrics)
+	defer pool.Close()
+	testutil.Equals(t, protectionWindow, pool.minIdleTimeout())
+
+	r, err := pool.NewBinaryReader(ctx, log.NewNopLogger(), bkt, tmpDir, meta, 3)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, r.Close()) }()
+
+	_, err = r.LabelNames()
+	testutil.Ok(t, err)
+
+	// The reader is idle for longer than its idle timeout, but was used within the protection window.
+	time.Sleep(idleTimeout * 2)
+	pool.closeIdleReaders()
+	testutil.Equals(t, float64(0), promtestutil.ToFloat64(metrics.lazyReader.unloadCount))
+	testutil.Equals(t, float64(1), promtestutil.ToFloat64(metrics.lazyReader.loaded))
+}
@@ -0,0 +1,110 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package indexheader
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	thanosmodel "github.com/thanos-io/thanos/pkg/model"
+)
+
+// Strategy is the strategy loading the index-header of blocks.
+type Strategy string
+
+const (
+	// StrategyEager loads the index-header of blocks when they are loaded.
+	StrategyEager Strategy = "eager"
+	// StrategyLazy memory maps the index-header of blocks once they are required by a query, and unloads it after
+	// the idle timeout, if any.
+	StrategyLazy Strategy = "lazy"
+)
+
+// StrategyConfig is the configuration of the strategies loading the index-header of blocks.
+type StrategyConfig struct {
+	// Rules choose the strategy of blocks. The first rule matching a block applies.
+	Rules []StrategyRule `yaml:"rules"`
+}
+
+// StrategyRule is a rule choosing the strategy of the blocks of given ages and sizes of index. The age of blocks is
+// the time since their max time, their size of index the one of their meta.json, if any. Blocks whose size of index
+// is unknown only match rules without size bounds.
+type StrategyRule struct {
+	MinAge       model.Duration    `yaml:"min_age"`
+	MaxAge       model.Duration    `yaml:"max_age"`
+	MinIndexSize thanosmodel.Bytes `yaml:"min_index_size"`
+	MaxIndexSize thanosmodel.Bytes `yaml:"max_index_size"`
+
+	Strategy Strategy `yaml:"strategy"`
+	// IdleTimeout is the inactivity after which lazily loaded index-headers are unloaded. 0 disables unloading.
+	IdleTimeout model.Duration `yaml:"idle_timeout"`
+}
+
+// ParseStrategyConfig parses and validates the configuration of strategies.
+func ParseStrategyConfig(conf []byte) (StrategyConfig, error) {
+	var c StrategyConfig
+	if err := yaml.UnmarshalStrict(conf, &c); err != nil {
+		return StrategyConfig{}, errors.Wrap(err, "parse index-header strategy config")
+	}
+	for i, r := range c.Rules {
+		if err := r.validate(); err != nil {
+			return StrategyConfig{}, errors.Wrapf(err, "rule %d", i)
+		}
+	}
+	return c, nil
+}
+
+func (r StrategyRule) validate() error {
+	switch r.Strategy {
+	case StrategyEager:
+		if r.IdleTimeout != 0 {
+			return errors.New("idle_timeout is only supported by the lazy strategy")
+		}
+	case StrategyLazy:
+		if r.IdleTimeout < 0 {
+			return errors.New("idle_timeout must not be negative")
+		}
+	default:
+		return errors.Errorf("unknown strategy %q, expected %q or %q", r.Strategy, StrategyEager, StrategyLazy)
+	}
+	if r.MaxAge != 0 && r.MinAge > r.MaxAge {
+		return errors.New("min_age must not be greater than max_age")
+	}
+	if r.MaxIndexSize != 0 && r.MinIndexSize > r.MaxIndexSize {
+		return errors.New("min_index_size must not be greater than max_index_size")
+	}
+	return nil
+}
+
+// matches returns true if the rule applies to the block of the given meta at the given time.
+func (r StrategyRule) matches(meta *metadata.Meta, now time.Time) bool {
+	age := now.Sub(time.Unix(0, meta.MaxTime*int64(time.Millisecond)))
+	if age < time.Duration(r.MinAge) || (r.MaxAge != 0 && age > time.Duration(r.MaxAge)) {
+		return false
+	}
+	if r.MinIndexSize == 0 && r.MaxIndexSize == 0 {
+		return true
+	}
+
+	size, ok := indexSize(meta)
+	if !ok {
+		return false
+	}
+	return size >= int64(r.MinIndexSize) && (r.MaxIndexSize == 0 || size <= int64(r.MaxIndexSize))
+}
+
+// indexSize returns the size of the index of the block of the meta, if known.
+func indexSize(meta *metadata.Meta) (int64, bool) {
+	for _, f := range meta.Thanos.Files {
+		if f.RelPath == block.IndexFilename && f.SizeBytes > 0 {
+			return f.SizeBytes, true
+		}
+	}
+	return 0, false
+}
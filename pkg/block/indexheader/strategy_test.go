@@ -0,0 +1,80 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package indexheader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestParseStrategyConfig(t *testing.T) {
+	c, err := ParseStrategyConfig([]byte(`rules:
+- max_age: 2d
+  strategy: eager
+- min_index_size: 1GiB
+  strategy: lazy
+  idle_timeout: 15m
+- strategy: lazy
+`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, []StrategyRule{
+		{MaxAge: model.Duration(48 * time.Hour), Strategy: StrategyEager},
+		{MinIndexSize: 1 << 30, Strategy: StrategyLazy, IdleTimeout: model.Duration(15 * time.Minute)},
+		{Strategy: StrategyLazy},
+	}, c.Rules)
+
+	c, err = ParseStrategyConfig([]byte(""))
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(c.Rules))
+
+	for _, conf := range []string{
+		"rules:\n- strategy: mmap\n",
+		"rules:\n- {}\n",
+		"rules:\n- strategy: eager\n  idle_timeout: 5m\n",
+		"rules:\n- strategy: lazy\n  min_age: 2d\n  max_age: 1d\n",
+		"rules:\n- strategy: lazy\n  min_index_size: 2GiB\n  max_index_size: 1GiB\n",
+		"rules:\n- strategy: lazy\n  unknown: true\n",
+	} {
+		_, err := ParseStrategyConfig([]byte(conf))
+		testutil.NotOk(t, err, "config %q", conf)
+	}
+}
+
+func TestStrategyRule_Matches(t *testing.T) {
+	now := time.Now()
+	newMeta := func(age time.Duration, indexSize int64) *metadata.Meta {
+		meta := &metadata.Meta{}
+		meta.MaxTime = now.Add(-age).UnixNano() / int64(time.Millisecond)
+		if indexSize > 0 {
+			meta.Thanos.Files = []metadata.File{{RelPath: "meta.json"}, {RelPath: block.IndexFilename, SizeBytes: indexSize}}
+		}
+		return meta
+	}
+
+	for _, tcase := range []struct {
+		rule     StrategyRule
+		meta     *metadata.Meta
+		expected bool
+	}{
+		{rule: StrategyRule{}, meta: newMeta(time.Hour, 0), expected: true},
+		{rule: StrategyRule{MinAge: model.Duration(2 * time.Hour)}, meta: newMeta(time.Hour, 0), expected: false},
+		{rule: StrategyRule{MinAge: model.Duration(2 * time.Hour)}, meta: newMeta(3*time.Hour, 0), expected: true},
+		{rule: StrategyRule{MaxAge: model.Duration(2 * time.Hour)}, meta: newMeta(3*time.Hour, 0), expected: false},
+		{rule: StrategyRule{MaxAge: model.Duration(2 * time.Hour)}, meta: newMeta(time.Hour, 0), expected: true},
+		// Blocks of unknown size of index only match rules without size bounds.
+		{rule: StrategyRule{MaxIndexSize: 1024}, meta: newMeta(time.Hour, 0), expected: false},
+		{rule: StrategyRule{MaxIndexSize: 1024}, meta: newMeta(time.Hour, 1024), expected: true},
+		{rule: StrategyRule{MinIndexSize: 1024}, meta: newMeta(time.Hour, 1023), expected: false},
+		{rule: StrategyRule{MinIndexSize: 1024, MinAge: model.Duration(2 * time.Hour)}, meta: newMeta(time.Hour, 2048), expected: false},
+		{rule: StrategyRule{MinIndexSize: 1024, MinAge: model.Duration(2 * time.Hour)}, meta: newMeta(3*time.Hour, 2048), expected: true},
+	} {
+		testutil.Equals(t, tcase.expected, tcase.rule.matches(tcase.meta, now), "rule %+v", tcase.rule)
+	}
+}
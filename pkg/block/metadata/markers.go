@@ -24,11 +24,16 @@ const (
 	// NoCompactMarkFilename is the known json filename for optional file storing details about why block has to be excluded from compaction.
 	// If such file is present in block dir, it means the block has to excluded from compaction (both vertical and horizontal) or rewrite (e.g deletions).
 	NoCompactMarkFilename = "no-compact-mark.json"
+	// NoDownsampleMarkFilename is the known json filename for optional file storing details about why block has to be excluded from downsampling.
+	// If such file is present in block dir, it means the block has to be excluded from downsampling, but it's still compacted.
+	NoDownsampleMarkFilename = "no-downsample-mark.json"
 
 	// DeletionMarkVersion1 is the version of deletion-mark file supported by Thanos.
 	DeletionMarkVersion1 = 1
 	// NoCompactMarkVersion1 is the version of no-compact-mark file supported by Thanos.
 	NoCompactMarkVersion1 = 1
+	// NoDownsampleMarkVersion1 is the version of no-downsample-mark file supported by Thanos.
+	NoDownsampleMarkVersion1 = 1
 )
 
 var (
@@ -88,6 +93,30 @@ type NoCompactMark struct {
 
 func (n *NoCompactMark) markerFilename() string { return NoCompactMarkFilename }
 
+// NoDownsampleReason is a reason for a block to be excluded from downsampling.
+type NoDownsampleReason string
+
+const (
+	// ManualNoDownsampleReason is a custom reason of excluding from downsampling that should be added when no-downsample mark is added for unknown/user specified reason.
+	ManualNoDownsampleReason NoDownsampleReason = "manual"
+)
+
+// NoDownsampleMark marker stores reason of block being excluded from downsampling if needed.
+type NoDownsampleMark struct {
+	// ID of the tsdb block.
+	ID ulid.ULID `json:"id"`
+	// Version of the file.
+	Version int `json:"version"`
+	// Details is a human readable string giving details of reason.
+	Details string `json:"details,omitempty"`
+
+	// NoDownsampleTime is a unix timestamp of when the block was marked for no downsample.
+	NoDownsampleTime int64              `json:"no_downsample_time"`
+	Reason           NoDownsampleReason `json:"reason"`
+}
+
+func (n *NoDownsampleMark) markerFilename() string { return NoDownsampleMarkFilename }
+
 // ReadMarker reads the given mark file from <dir>/<marker filename>.json in bucket.
 func ReadMarker(ctx context.Context, logger log.Logger, bkt objstore.InstrumentedBucketReader, dir string, marker Marker) error {
 	markerFile := path.Join(dir, marker.markerFilename())
@@ -117,6 +146,10 @@ func ReadMarker(ctx context.Context, logger log.Logger, bkt objstore.Instrumente
 		if version := marker.(*DeletionMark).Version; version != DeletionMarkVersion1 {
 			return errors.Errorf("unexpected deletion-mark file version %d, expected %d", version, DeletionMarkVersion1)
 		}
+	case NoDownsampleMarkFilename:
+		if version := marker.(*NoDownsampleMark).Version; version != NoDownsampleMarkVersion1 {
+			return errors.Errorf("unexpected no-downsample-mark file version %d, expected %d", version, NoDownsampleMarkVersion1)
+		}
 	}
 	return nil
 }
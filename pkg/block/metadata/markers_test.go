@@ -112,4 +112,38 @@ func TestReadMarker(t *testing.T) {
 		testutil.Ok(t, err)
 		testutil.Equals(t, *expected, n)
 	})
+	t.Run(NoDownsampleMarkFilename, func(t *testing.T) {
+		blockWithoutMark := ulid.MustNew(uint64(1), nil)
+		n := NoDownsampleMark{}
+		err := ReadMarker(ctx, log.NewNopLogger(), bkt, path.Join(tmpDir, blockWithoutMark.String()), &n)
+		testutil.NotOk(t, err)
+		testutil.Equals(t, ErrorMarkerNotFound, err)
+
+		blockWithDifferentVersionMark := ulid.MustNew(uint64(3), nil)
+		var buf bytes.Buffer
+		testutil.Ok(t, json.NewEncoder(&buf).Encode(&NoDownsampleMark{
+			ID:      blockWithDifferentVersionMark,
+			Version: 2,
+			Reason:  ManualNoDownsampleReason,
+		}))
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(tmpDir, blockWithDifferentVersionMark.String(), NoDownsampleMarkFilename), &buf))
+		err = ReadMarker(ctx, log.NewNopLogger(), bkt, path.Join(tmpDir, blockWithDifferentVersionMark.String()), &n)
+		testutil.NotOk(t, err)
+		testutil.Equals(t, "unexpected no-downsample-mark file version 2, expected 1", err.Error())
+
+		blockWithValidMark := ulid.MustNew(uint64(4), nil)
+		buf.Reset()
+		expected := &NoDownsampleMark{
+			ID:               blockWithValidMark,
+			Version:          1,
+			NoDownsampleTime: time.Now().Unix(),
+			Reason:           ManualNoDownsampleReason,
+			Details:          "yolo",
+		}
+		testutil.Ok(t, json.NewEncoder(&buf).Encode(expected))
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(tmpDir, blockWithValidMark.String(), NoDownsampleMarkFilename), &buf))
+		err = ReadMarker(ctx, log.NewNopLogger(), bkt, path.Join(tmpDir, blockWithValidMark.String()), &n)
+		testutil.Ok(t, err)
+		testutil.Equals(t, *expected, n)
+	})
 }
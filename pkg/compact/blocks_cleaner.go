@@ -5,10 +5,12 @@ package compact
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -43,18 +45,30 @@ func NewBlocksCleaner(logger log.Logger, bkt objstore.Bucket, ignoreDeletionMark
 func (s *BlocksCleaner) DeleteMarkedBlocks(ctx context.Context) error {
 	level.Info(s.logger).Log("msg", "started cleaning of blocks marked for deletion")
 
-	deletionMarkMap := s.ignoreDeletionMarkFilter.DeletionMarkBlocks()
-	for _, deletionMark := range deletionMarkMap {
-		if time.Since(time.Unix(deletionMark.DeletionTime, 0)).Seconds() > s.deleteDelay.Seconds() {
-			if err := block.Delete(ctx, s.logger, s.bkt, deletionMark.ID); err != nil {
-				s.blockCleanupFailures.Inc()
-				return errors.Wrap(err, "delete block")
-			}
-			s.blocksCleaned.Inc()
-			level.Info(s.logger).Log("msg", "deleted block marked for deletion", "block", deletionMark.ID)
+	for _, id := range s.BlocksToDelete() {
+		if err := block.Delete(ctx, s.logger, s.bkt, id); err != nil {
+			s.blockCleanupFailures.Inc()
+			return errors.Wrap(err, "delete block")
 		}
+		s.blocksCleaned.Inc()
+		level.Info(s.logger).Log("msg", "deleted block marked for deletion", "block", id)
 	}
 
 	level.Info(s.logger).Log("msg", "cleaning of blocks marked for deletion done")
 	return nil
 }
+
+// BlocksToDelete returns the IDs of the blocks which are marked for deletion for longer than deleteDelay, sorted, which
+// DeleteMarkedBlocks deletes.
+func (s *BlocksCleaner) BlocksToDelete() []ulid.ULID {
+	var ids []ulid.ULID
+	for _, deletionMark := range s.ignoreDeletionMarkFilter.DeletionMarkBlocks() {
+		if time.Since(time.Unix(deletionMark.DeletionTime, 0)).Seconds() > s.deleteDelay.Seconds() {
+			ids = append(ids, deletionMark.ID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].Compare(ids[j]) < 0
+	})
+	return ids
+}
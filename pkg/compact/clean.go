@@ -5,6 +5,7 @@ package compact
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/go-kit/log"
@@ -22,25 +23,65 @@ const (
 	PartialUploadThresholdAge = 2 * 24 * time.Hour
 )
 
+// PartialUpload describes a block which was not uploaded completely, i.e. its meta.json is missing or can't be read.
+type PartialUpload struct {
+	ID ulid.ULID
+	// Err is the reason why the block is partial.
+	Err error
+	// Objects is the number of the objects of the block in the bucket.
+	Objects int
+	// Size is the total size of the objects of the block in bytes.
+	Size int64
+}
+
+// isAbortedPartialUpload returns true if the partial block is older than the given thresholdAge, so its upload is
+// assumed to be aborted.
+// TODO(bwplotka): This is can cause data loss if blocks are:
+// * being uploaded longer than thresholdAge
+// * being uploaded and started after their thresholdAge
+// can be assumed in this case. Keep thresholdAge long for now.
+// Mitigate this by adding ModifiedTime to bkt and check that instead of ULID (block creation time).
+func isAbortedPartialUpload(id ulid.ULID, thresholdAge time.Duration) bool {
+	return ulid.Now()-id.Time() > uint64(thresholdAge/time.Millisecond)
+}
+
+// AbortedPartialUploads returns the given partial blocks which are assumed to be aborted, as they are older than the
+// given thresholdAge, sorted by ID, with the objects which cleaning them would delete.
+func AbortedPartialUploads(ctx context.Context, bkt objstore.BucketReader, partial map[ulid.ULID]error, thresholdAge time.Duration) ([]PartialUpload, error) {
+	var res []PartialUpload
+	for id, perr := range partial {
+		if !isAbortedPartialUpload(id, thresholdAge) {
+			continue
+		}
+
+		objects, size, err := block.SizeInBucket(ctx, bkt, id)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, PartialUpload{ID: id, Err: perr, Objects: objects, Size: size})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID.Compare(res[j].ID) < 0
+	})
+	return res, nil
+}
+
+// BestEffortCleanAbortedPartialUploads deletes the given partial blocks which are older than thresholdAge. Failed
+// deletions are only logged, as they are retried on the next call.
 func BestEffortCleanAbortedPartialUploads(
 	ctx context.Context,
 	logger log.Logger,
 	partial map[ulid.ULID]error,
 	bkt objstore.Bucket,
+	thresholdAge time.Duration,
 	deleteAttempts prometheus.Counter,
 	blockCleanups prometheus.Counter,
 	blockCleanupFailures prometheus.Counter,
 ) {
 	level.Info(logger).Log("msg", "started cleaning of aborted partial uploads")
 
-	// Delete partial blocks that are older than partialUploadThresholdAge.
-	// TODO(bwplotka): This is can cause data loss if blocks are:
-	// * being uploaded longer than partialUploadThresholdAge
-	// * being uploaded and started after their partialUploadThresholdAge
-	// can be assumed in this case. Keep partialUploadThresholdAge long for now.
-	// Mitigate this by adding ModifiedTime to bkt and check that instead of ULID (block creation time).
 	for id := range partial {
-		if ulid.Now()-id.Time() <= uint64(PartialUploadThresholdAge/time.Millisecond) {
+		if !isAbortedPartialUpload(id, thresholdAge) {
 			// Minimum delay has not expired, ignore for now.
 			continue
 		}
@@ -48,15 +89,15 @@ func BestEffortCleanAbortedPartialUploads(
 		deleteAttempts.Inc()
 		level.Info(logger).Log("msg", "found partially uploaded block; marking for deletion", "block", id)
 		// We don't gather any information about deletion marks for partial blocks, so let's simply remove it. We waited
-		// long PartialUploadThresholdAge already.
+		// long thresholdAge already.
 		// TODO(bwplotka): Fix some edge cases: https://github.com/thanos-io/thanos/issues/2470 .
 		if err := block.Delete(ctx, logger, bkt, id); err != nil {
 			blockCleanupFailures.Inc()
-			level.Warn(logger).Log("msg", "failed to delete aborted partial upload; will retry in next iteration", "block", id, "thresholdAge", PartialUploadThresholdAge, "err", err)
+			level.Warn(logger).Log("msg", "failed to delete aborted partial upload; will retry in next iteration", "block", id, "thresholdAge", thresholdAge, "err", err)
 			continue
 		}
 		blockCleanups.Inc()
-		level.Info(logger).Log("msg", "deleted aborted partial upload", "block", id, "thresholdAge", PartialUploadThresholdAge)
+		level.Info(logger).Log("msg", "deleted aborted partial upload", "block", id, "thresholdAge", thresholdAge)
 	}
 	level.Info(logger).Log("msg", "cleaning of aborted partial uploads done")
 }
@@ -65,12 +65,27 @@ func TestBestEffortCleanAbortedPartialUploads(t *testing.T) {
 	_, partial, err := metaFetcher.Fetch(ctx)
 	testutil.Ok(t, err)
 
-	BestEffortCleanAbortedPartialUploads(ctx, logger, partial, bkt, deleteAttempts, blockCleanups, blockCleanupFailures)
+	// Listing aborted uploads doesn't delete them and respects the threshold.
+	aborted, err := AbortedPartialUploads(ctx, bkt, partial, PartialUploadThresholdAge)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []PartialUpload{{ID: shouldDeleteID, Err: partial[shouldDeleteID], Objects: 1, Size: 4}}, aborted)
+
+	aborted, err = AbortedPartialUploads(ctx, bkt, partial, 1*time.Hour)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(aborted))
+	testutil.Equals(t, shouldDeleteID, aborted[0].ID)
+	testutil.Equals(t, shouldIgnoreID2, aborted[1].ID)
+
+	exists, err := bkt.Exists(ctx, path.Join(shouldDeleteID.String(), "chunks", "000001"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, true, exists)
+
+	BestEffortCleanAbortedPartialUploads(ctx, logger, partial, bkt, PartialUploadThresholdAge, deleteAttempts, blockCleanups, blockCleanupFailures)
 	testutil.Equals(t, 1.0, promtest.ToFloat64(deleteAttempts))
 	testutil.Equals(t, 1.0, promtest.ToFloat64(blockCleanups))
 	testutil.Equals(t, 0.0, promtest.ToFloat64(blockCleanupFailures))
 
-	exists, err := bkt.Exists(ctx, path.Join(shouldDeleteID.String(), "chunks", "000001"))
+	exists, err = bkt.Exists(ctx, path.Join(shouldDeleteID.String(), "chunks", "000001"))
 	testutil.Ok(t, err)
 	testutil.Equals(t, false, exists)
 
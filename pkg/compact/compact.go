@@ -115,20 +115,15 @@ func NewMetaSyncer(logger log.Logger, reg prometheus.Registerer, bkt objstore.Bu
 	}, nil
 }
 
-// UntilNextDownsampling calculates how long it will take until the next downsampling operation.
+// UntilNextDownsampling calculates how long it will take until the next downsampling operation of the given levels.
 // Returns an error if there will be no downsampling.
-func UntilNextDownsampling(m *metadata.Meta) (time.Duration, error) {
-	timeRange := time.Duration((m.MaxTime - m.MinTime) * int64(time.Millisecond))
-	switch m.Thanos.Downsample.Resolution {
-	case downsample.ResLevel2:
+func UntilNextDownsampling(m *metadata.Meta, levels downsample.Levels) (time.Duration, error) {
+	next, ok := levels.Next(m.Thanos.Downsample.Resolution)
+	if !ok {
 		return time.Duration(0), errors.New("no downsampling")
-	case downsample.ResLevel1:
-		return time.Duration(downsample.DownsampleRange1*time.Millisecond) - timeRange, nil
-	case downsample.ResLevel0:
-		return time.Duration(downsample.DownsampleRange0*time.Millisecond) - timeRange, nil
-	default:
-		panic(errors.Errorf("invalid resolution %v", m.Thanos.Downsample.Resolution))
 	}
+	timeRange := time.Duration((m.MaxTime - m.MinTime) * int64(time.Millisecond))
+	return time.Duration(next.MinSourceRange*int64(time.Millisecond)) - timeRange, nil
 }
 
 // SyncMetas synchronizes local state of block metas with what we have in the bucket.
@@ -228,8 +223,23 @@ func defaultGroupKey(res int64, lbls labels.Labels) string {
 	return fmt.Sprintf("%d@%v", res, lbls.Hash())
 }
 
+// groupKeyLabels returns the labels of the block forming its group key: all its labels if names is empty, or only
+// those with the given names otherwise.
+func groupKeyLabels(meta metadata.Thanos, names []string) labels.Labels {
+	if len(names) == 0 {
+		return labels.FromMap(meta.Labels)
+	}
+	lb := labels.NewBuilder(nil)
+	for _, n := range names {
+		if v, ok := meta.Labels[n]; ok {
+			lb.Set(n, v)
+		}
+	}
+	return lb.Labels()
+}
+
 // DefaultGrouper is the Thanos built-in grouper. It groups blocks based on downsample
-// resolution and block's labels.
+// resolution and block's labels, or only some of them if group key labels are set.
 type DefaultGrouper struct {
 	bkt                      objstore.Bucket
 	logger                   log.Logger
@@ -244,9 +254,13 @@ type DefaultGrouper struct {
 	blocksMarkedForDeletion  prometheus.Counter
 	blocksMarkedForNoCompact prometheus.Counter
 	hashFunc                 metadata.HashFunc
+	// groupKeyLabels are the names of the labels forming the group key of blocks with their resolution. All labels
+	// form it if empty.
+	groupKeyLabels []string
 }
 
-// NewDefaultGrouper makes a new DefaultGrouper.
+// NewDefaultGrouper makes a new DefaultGrouper. Blocks are grouped by the given group key labels, if any, and by all
+// their labels otherwise.
 func NewDefaultGrouper(
 	logger log.Logger,
 	bkt objstore.Bucket,
@@ -257,6 +271,7 @@ func NewDefaultGrouper(
 	garbageCollectedBlocks prometheus.Counter,
 	blocksMarkedForNoCompact prometheus.Counter,
 	hashFunc metadata.HashFunc,
+	groupKeyLabels []string,
 ) *DefaultGrouper {
 	return &DefaultGrouper{
 		bkt:                      bkt,
@@ -287,18 +302,38 @@ func NewDefaultGrouper(
 		garbageCollectedBlocks:   garbageCollectedBlocks,
 		blocksMarkedForDeletion:  blocksMarkedForDeletion,
 		hashFunc:                 hashFunc,
+		groupKeyLabels:           groupKeyLabels,
 	}
 }
 
 // Groups returns the compaction groups for all blocks currently known to the syncer.
 // It creates all groups from the scratch on every call.
 func (g *DefaultGrouper) Groups(blocks map[ulid.ULID]*metadata.Meta) (res []*Group, err error) {
+	// The blocks compacted from a group have the labels common to all the blocks of the group, which are all their
+	// labels unless group key labels are set.
+	groupLabels := map[string]labels.Labels{}
+	for _, m := range blocks {
+		groupKey := defaultGroupKey(m.Thanos.Downsample.Resolution, groupKeyLabels(m.Thanos, g.groupKeyLabels))
+		lbls, ok := groupLabels[groupKey]
+		if !ok {
+			groupLabels[groupKey] = labels.FromMap(m.Thanos.Labels)
+			continue
+		}
+		common := lbls[:0]
+		for _, l := range lbls {
+			if m.Thanos.Labels[l.Name] == l.Value {
+				common = append(common, l)
+			}
+		}
+		groupLabels[groupKey] = common
+	}
+
 	groups := map[string]*Group{}
 	for _, m := range blocks {
-		groupKey := DefaultGroupKey(m.Thanos)
+		groupKey := defaultGroupKey(m.Thanos.Downsample.Resolution, groupKeyLabels(m.Thanos, g.groupKeyLabels))
 		group, ok := groups[groupKey]
 		if !ok {
-			lbls := labels.FromMap(m.Thanos.Labels)
+			lbls := groupLabels[groupKey]
 			group, err = NewGroup(
 				log.With(g.logger, "group", fmt.Sprintf("%d@%v", m.Thanos.Downsample.Resolution, lbls.String()), "groupKey", groupKey),
 				g.bkt,
@@ -422,8 +457,11 @@ func (cg *Group) AppendMeta(meta *metadata.Meta) error {
 	cg.mtx.Lock()
 	defer cg.mtx.Unlock()
 
-	if !labels.Equal(cg.labels, labels.FromMap(meta.Thanos.Labels)) {
-		return errors.New("block and group labels do not match")
+	// Blocks may have more labels than their group, if they are grouped by some of their labels only.
+	for _, l := range cg.labels {
+		if meta.Thanos.Labels[l.Name] != l.Value {
+			return errors.New("block and group labels do not match")
+		}
 	}
 	if cg.resolution != meta.Thanos.Downsample.Resolution {
 		return errors.New("block and group resolution do not match")
@@ -582,10 +620,11 @@ type DownsampleProgressMetrics struct {
 // DownsampleProgressCalculator contains DownsampleMetrics, which are updated during the downsampling simulation process.
 type DownsampleProgressCalculator struct {
 	*DownsampleProgressMetrics
+	levels downsample.Levels
 }
 
-// NewDownsampleProgressCalculator creates a new DownsampleProgressCalculator.
-func NewDownsampleProgressCalculator(reg prometheus.Registerer) *DownsampleProgressCalculator {
+// NewDownsampleProgressCalculator creates a new DownsampleProgressCalculator for the given downsampling levels.
+func NewDownsampleProgressCalculator(reg prometheus.Registerer, levels downsample.Levels) *DownsampleProgressCalculator {
 	return &DownsampleProgressCalculator{
 		DownsampleProgressMetrics: &DownsampleProgressMetrics{
 			NumberOfBlocksDownsampled: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
@@ -593,71 +632,54 @@ func NewDownsampleProgressCalculator(reg prometheus.Registerer) *DownsampleProgr
 				Help: "number of blocks to be downsampled",
 			}, []string{"group"}),
 		},
+		levels: levels,
 	}
 }
 
 // ProgressCalculate calculates the number of blocks to be downsampled for the given groups.
 func (ds *DownsampleProgressCalculator) ProgressCalculate(ctx context.Context, groups []*Group) error {
-	sources5m := map[ulid.ULID]struct{}{}
-	sources1h := map[ulid.ULID]struct{}{}
+	sources := map[int64]map[ulid.ULID]struct{}{}
+	for _, lvl := range ds.levels {
+		sources[lvl.Resolution] = map[ulid.ULID]struct{}{}
+	}
 	groupBlocks := make(map[string]int, len(groups))
 
 	for _, group := range groups {
 		for _, m := range group.metasByMinTime {
-			switch m.Thanos.Downsample.Resolution {
-			case downsample.ResLevel0:
+			if m.Thanos.Downsample.Resolution == downsample.ResLevel0 {
 				continue
-			case downsample.ResLevel1:
-				for _, id := range m.Compaction.Sources {
-					sources5m[id] = struct{}{}
-				}
-			case downsample.ResLevel2:
-				for _, id := range m.Compaction.Sources {
-					sources1h[id] = struct{}{}
-				}
-			default:
+			}
+			resSources, ok := sources[m.Thanos.Downsample.Resolution]
+			if !ok {
 				return errors.Errorf("unexpected downsampling resolution %d", m.Thanos.Downsample.Resolution)
 			}
-
+			for _, id := range m.Compaction.Sources {
+				resSources[id] = struct{}{}
+			}
 		}
 	}
 
 	for _, group := range groups {
 		for _, m := range group.metasByMinTime {
-			switch m.Thanos.Downsample.Resolution {
-			case downsample.ResLevel0:
-				missing := false
-				for _, id := range m.Compaction.Sources {
-					if _, ok := sources5m[id]; !ok {
-						missing = true
-						break
-					}
-				}
-				if !missing {
-					continue
-				}
-
-				if m.MaxTime-m.MinTime < downsample.DownsampleRange0 {
-					continue
-				}
-				groupBlocks[group.key]++
-			case downsample.ResLevel1:
-				missing := false
-				for _, id := range m.Compaction.Sources {
-					if _, ok := sources1h[id]; !ok {
-						missing = true
-						break
-					}
-				}
-				if !missing {
-					continue
+			next, ok := ds.levels.Next(m.Thanos.Downsample.Resolution)
+			if !ok {
+				continue
+			}
+			missing := false
+			for _, id := range m.Compaction.Sources {
+				if _, ok := sources[next.Resolution][id]; !ok {
+					missing = true
+					break
 				}
+			}
+			if !missing {
+				continue
+			}
 
-				if m.MaxTime-m.MinTime < downsample.DownsampleRange1 {
-					continue
-				}
-				groupBlocks[group.key]++
+			if m.MaxTime-m.MinTime < next.MinSourceRange {
+				continue
 			}
+			groupBlocks[group.key]++
 		}
 	}
 
@@ -1373,6 +1395,67 @@ func (f *GatherNoCompactionMarkFilter) NoCompactMarkedBlocks() map[ulid.ULID]*me
 func (f *GatherNoCompactionMarkFilter) Filter(ctx context.Context, metas map[ulid.ULID]*metadata.Meta, synced *extprom.TxGaugeVec) error {
 	f.noCompactMarkedMap = make(map[ulid.ULID]*metadata.NoCompactMark)
 
+	err := gatherMarkers(ctx, f.logger, f.bkt, f.concurrency, metas, metadata.NoCompactMarkFilename,
+		func() metadata.Marker { return &metadata.NoCompactMark{} },
+		func(id ulid.ULID, m metadata.Marker) {
+			f.noCompactMarkedMap[id] = m.(*metadata.NoCompactMark)
+			synced.WithLabelValues(block.MarkedForNoCompactionMeta).Inc()
+		},
+	)
+	return errors.Wrap(err, "filter blocks marked for no compaction")
+}
+
+var _ block.MetadataFilter = &GatherNoDownsampleMarkFilter{}
+
+// GatherNoDownsampleMarkFilter is a block.Fetcher filter that passes all metas. While doing it, it gathers all no-downsample-mark.json markers.
+// Not go routine safe.
+type GatherNoDownsampleMarkFilter struct {
+	logger                log.Logger
+	bkt                   objstore.InstrumentedBucketReader
+	noDownsampleMarkedMap map[ulid.ULID]*metadata.NoDownsampleMark
+	concurrency           int
+}
+
+// NewGatherNoDownsampleMarkFilter creates GatherNoDownsampleMarkFilter.
+func NewGatherNoDownsampleMarkFilter(logger log.Logger, bkt objstore.InstrumentedBucketReader, concurrency int) *GatherNoDownsampleMarkFilter {
+	return &GatherNoDownsampleMarkFilter{
+		logger:      logger,
+		bkt:         bkt,
+		concurrency: concurrency,
+	}
+}
+
+// NoDownsampleMarkedBlocks returns block ids that were marked for no downsampling.
+func (f *GatherNoDownsampleMarkFilter) NoDownsampleMarkedBlocks() map[ulid.ULID]*metadata.NoDownsampleMark {
+	return f.noDownsampleMarkedMap
+}
+
+// Filter passes all metas, while gathering no downsample markers.
+func (f *GatherNoDownsampleMarkFilter) Filter(ctx context.Context, metas map[ulid.ULID]*metadata.Meta, synced *extprom.TxGaugeVec) error {
+	f.noDownsampleMarkedMap = make(map[ulid.ULID]*metadata.NoDownsampleMark)
+
+	err := gatherMarkers(ctx, f.logger, f.bkt, f.concurrency, metas, metadata.NoDownsampleMarkFilename,
+		func() metadata.Marker { return &metadata.NoDownsampleMark{} },
+		func(id ulid.ULID, m metadata.Marker) {
+			f.noDownsampleMarkedMap[id] = m.(*metadata.NoDownsampleMark)
+			synced.WithLabelValues(block.MarkedForNoDownsampleMeta).Inc()
+		},
+	)
+	return errors.Wrap(err, "filter blocks marked for no downsampling")
+}
+
+// gatherMarkers reads the markers of the given blocks concurrently and calls found, one at a time, for each of the
+// markers which exist. Partially uploaded markers are skipped.
+func gatherMarkers(
+	ctx context.Context,
+	logger log.Logger,
+	bkt objstore.InstrumentedBucketReader,
+	concurrency int,
+	metas map[ulid.ULID]*metadata.Meta,
+	markerFilename string,
+	newMarker func() metadata.Marker,
+	found func(id ulid.ULID, m metadata.Marker),
+) error {
 	// Make a copy of block IDs to check, in order to avoid concurrency issues
 	// between the scheduler and workers.
 	blockIDs := make([]ulid.ULID, 0, len(metas))
@@ -1382,22 +1465,22 @@ func (f *GatherNoCompactionMarkFilter) Filter(ctx context.Context, metas map[uli
 
 	var (
 		eg  errgroup.Group
-		ch  = make(chan ulid.ULID, f.concurrency)
+		ch  = make(chan ulid.ULID, concurrency)
 		mtx sync.Mutex
 	)
 
-	for i := 0; i < f.concurrency; i++ {
+	for i := 0; i < concurrency; i++ {
 		eg.Go(func() error {
 			var lastErr error
 			for id := range ch {
-				m := &metadata.NoCompactMark{}
+				m := newMarker()
 				// TODO(bwplotka): Hook up bucket cache here + reset API so we don't introduce API calls .
-				if err := metadata.ReadMarker(ctx, f.logger, f.bkt, id.String(), m); err != nil {
+				if err := metadata.ReadMarker(ctx, logger, bkt, id.String(), m); err != nil {
 					if errors.Cause(err) == metadata.ErrorMarkerNotFound {
 						continue
 					}
 					if errors.Cause(err) == metadata.ErrorUnmarshalMarker {
-						level.Warn(f.logger).Log("msg", "found partial no-compact-mark.json; if we will see it happening often for the same block, consider manually deleting no-compact-mark.json from the object storage", "block", id, "err", err)
+						level.Warn(logger).Log("msg", fmt.Sprintf("found partial %s; if we will see it happening often for the same block, consider manually deleting %s from the object storage", markerFilename, markerFilename), "block", id, "err", err)
 						continue
 					}
 					// Remember the last error and continue draining the channel.
@@ -1406,9 +1489,8 @@ func (f *GatherNoCompactionMarkFilter) Filter(ctx context.Context, metas map[uli
 				}
 
 				mtx.Lock()
-				f.noCompactMarkedMap[id] = m
+				found(id, m)
 				mtx.Unlock()
-				synced.WithLabelValues(block.MarkedForNoCompactionMeta).Inc()
 			}
 
 			return lastErr
@@ -1431,9 +1513,5 @@ func (f *GatherNoCompactionMarkFilter) Filter(ctx context.Context, metas map[uli
 		return nil
 	})
 
-	if err := eg.Wait(); err != nil {
-		return errors.Wrap(err, "filter blocks marked for no compaction")
-	}
-
-	return nil
+	return eg.Wait()
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/tsdb"
 
 	"github.com/thanos-io/thanos/pkg/block/metadata"
@@ -128,6 +129,49 @@ func TestGroupKey(t *testing.T) {
 	}
 }
 
+func TestDefaultGrouper_GroupKeyLabels(t *testing.T) {
+	newMeta := func(id uint64, lset map[string]string) *metadata.Meta {
+		m := &metadata.Meta{}
+		m.ULID = ulid.MustNew(id, nil)
+		m.MinTime, m.MaxTime = int64(id), int64(id)+1
+		m.Thanos.Labels = lset
+		return m
+	}
+	blocks := map[ulid.ULID]*metadata.Meta{}
+	for _, m := range []*metadata.Meta{
+		newMeta(1, map[string]string{"cluster": "a", "region": "eu", "replica": "x"}),
+		newMeta(2, map[string]string{"cluster": "a", "region": "eu", "replica": "y"}),
+		newMeta(3, map[string]string{"cluster": "a", "region": "eu"}),
+		newMeta(4, map[string]string{"cluster": "b", "replica": "x"}),
+	} {
+		blocks[m.ULID] = m
+	}
+
+	// By default, blocks are grouped by all their labels.
+	groups, err := NewDefaultGrouper(nil, nil, false, false, nil, nil, nil, nil, "", nil).Groups(blocks)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 4, len(groups))
+
+	groups, err = NewDefaultGrouper(nil, nil, false, false, nil, nil, nil, nil, "", []string{"cluster"}).Groups(blocks)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(groups))
+	for _, g := range groups {
+		switch g.Labels().Get("cluster") {
+		case "a":
+			testutil.Equals(t, []ulid.ULID{ulid.MustNew(1, nil), ulid.MustNew(2, nil), ulid.MustNew(3, nil)}, g.IDs())
+			testutil.Equals(t, labels.FromStrings("cluster", "a", "region", "eu"), g.Labels())
+		case "b":
+			testutil.Equals(t, []ulid.ULID{ulid.MustNew(4, nil)}, g.IDs())
+			testutil.Equals(t, labels.FromStrings("cluster", "b", "replica", "x"), g.Labels())
+		default:
+			t.Fatalf("unexpected group %s", g.Labels())
+		}
+	}
+
+	// Blocks without the labels of the group can't be added to it.
+	testutil.NotOk(t, groups[0].AppendMeta(newMeta(5, map[string]string{"region": "eu"})))
+}
+
 func TestGroupMaxMinTime(t *testing.T) {
 	g := &Group{
 		metasByMinTime: []*metadata.Meta{
@@ -210,7 +254,7 @@ func TestRetentionProgressCalculate(t *testing.T) {
 
 	var bkt objstore.Bucket
 	temp := promauto.With(reg).NewCounter(prometheus.CounterOpts{Name: "test_metric_for_group", Help: "this is a test metric for compact progress tests"})
-	grouper := NewDefaultGrouper(logger, bkt, false, false, reg, temp, temp, temp, "")
+	grouper := NewDefaultGrouper(logger, bkt, false, false, reg, temp, temp, temp, "", nil)
 
 	type groupedResult map[string]float64
 
@@ -376,7 +420,7 @@ func TestCompactProgressCalculate(t *testing.T) {
 
 	var bkt objstore.Bucket
 	temp := promauto.With(reg).NewCounter(prometheus.CounterOpts{Name: "test_metric_for_group", Help: "this is a test metric for compact progress tests"})
-	grouper := NewDefaultGrouper(logger, bkt, false, false, reg, temp, temp, temp, "")
+	grouper := NewDefaultGrouper(logger, bkt, false, false, reg, temp, temp, temp, "", nil)
 
 	for _, tcase := range []struct {
 		testName string
@@ -494,11 +538,11 @@ func TestDownsampleProgressCalculate(t *testing.T) {
 		keys[ind] = DefaultGroupKey(meta.Thanos)
 	}
 
-	ds := NewDownsampleProgressCalculator(reg)
+	ds := NewDownsampleProgressCalculator(reg, downsample.DefaultLevels)
 
 	var bkt objstore.Bucket
 	temp := promauto.With(reg).NewCounter(prometheus.CounterOpts{Name: "test_metric_for_group", Help: "this is a test metric for downsample progress tests"})
-	grouper := NewDefaultGrouper(logger, bkt, false, false, reg, temp, temp, temp, "")
+	grouper := NewDefaultGrouper(logger, bkt, false, false, reg, temp, temp, temp, "", nil)
 
 	for _, tcase := range []struct {
 		testName string
@@ -0,0 +1,116 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package downsample
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+)
+
+// Level is a downsampling resolution of a ladder of resolutions.
+type Level struct {
+	// Resolution of the downsampled blocks, in milliseconds.
+	Resolution int64
+	// MinSourceRange is the minimum time range, in milliseconds, of the blocks of the previous resolution of the
+	// ladder which are downsampled to this resolution, so that downsampled chunks get enough samples.
+	MinSourceRange int64
+}
+
+// Levels is a ladder of downsampling resolutions, sorted by increasing resolution. Raw blocks are downsampled
+// to the first resolution, and the blocks of each resolution to the next one.
+type Levels []Level
+
+// DefaultLevels are the standard downsampling resolutions of Thanos.
+var DefaultLevels = Levels{
+	{Resolution: ResLevel1, MinSourceRange: DownsampleRange0},
+	{Resolution: ResLevel2, MinSourceRange: DownsampleRange1},
+}
+
+// ParseLevels parses levels of the form "<resolution>:<min source range>", e.g. "6h:60d".
+// It returns DefaultLevels if no level is given.
+func ParseLevels(ss []string) (Levels, error) {
+	if len(ss) == 0 {
+		return DefaultLevels, nil
+	}
+
+	levels := make(Levels, 0, len(ss))
+	for _, s := range ss {
+		parts := strings.Split(s, ":")
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid downsampling level %q, expected <resolution>:<min source range>", s)
+		}
+		res, err := model.ParseDuration(parts[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse resolution of downsampling level %q", s)
+		}
+		rng, err := model.ParseDuration(parts[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse min source range of downsampling level %q", s)
+		}
+		if res <= 0 || rng <= 0 {
+			return nil, errors.Errorf("resolution and min source range of downsampling level %q must be positive", s)
+		}
+		levels = append(levels, Level{
+			Resolution:     time.Duration(res).Milliseconds(),
+			MinSourceRange: time.Duration(rng).Milliseconds(),
+		})
+	}
+
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Resolution < levels[j].Resolution })
+	for i := 1; i < len(levels); i++ {
+		if levels[i].Resolution == levels[i-1].Resolution {
+			return nil, errors.Errorf("duplicate downsampling resolution %v", time.Duration(levels[i].Resolution)*time.Millisecond)
+		}
+	}
+	return levels, nil
+}
+
+// Next returns the level the blocks of the given resolution are downsampled to, or false if they are not
+// downsampled any further.
+func (l Levels) Next(resolution int64) (Level, bool) {
+	if len(l) == 0 {
+		return Level{}, false
+	}
+	if resolution == ResLevel0 {
+		return l[0], true
+	}
+	for i, lvl := range l[:len(l)-1] {
+		if lvl.Resolution == resolution {
+			return l[i+1], true
+		}
+	}
+	return Level{}, false
+}
+
+// Resolutions returns the resolutions of the ladder, including raw data, sorted by increasing resolution.
+func (l Levels) Resolutions() []int64 {
+	res := make([]int64, 0, len(l)+1)
+	res = append(res, ResLevel0)
+	for _, lvl := range l {
+		res = append(res, lvl.Resolution)
+	}
+	return res
+}
+
+// ParseResolutions parses downsampling resolutions given as durations, e.g. "5m", and returns them in
+// milliseconds, sorted by increasing resolution. The raw resolution, ResLevel0, is not included.
+func ParseResolutions(ss []string) ([]int64, error) {
+	res := make([]int64, 0, len(ss))
+	for _, s := range ss {
+		d, err := model.ParseDuration(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse downsampling resolution %q", s)
+		}
+		if d <= 0 {
+			return nil, errors.Errorf("downsampling resolution %q must be positive", s)
+		}
+		res = append(res, time.Duration(d).Milliseconds())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res, nil
+}
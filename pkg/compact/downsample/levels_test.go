@@ -0,0 +1,60 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package downsample
+
+import (
+	"testing"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels(nil)
+	testutil.Ok(t, err)
+	testutil.Equals(t, DefaultLevels, levels)
+
+	levels, err = ParseLevels([]string{"6h:60d", "5m:40h", "1h:10d"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, Levels{
+		{Resolution: ResLevel1, MinSourceRange: DownsampleRange0},
+		{Resolution: ResLevel2, MinSourceRange: DownsampleRange1},
+		{Resolution: 6 * 60 * 60 * 1000, MinSourceRange: 60 * 24 * 60 * 60 * 1000},
+	}, levels)
+	testutil.Equals(t, []int64{ResLevel0, ResLevel1, ResLevel2, 6 * 60 * 60 * 1000}, levels.Resolutions())
+
+	for _, s := range [][]string{{"5m"}, {"5m:40h:1"}, {"5x:40h"}, {"5m:0s"}, {"5m:40h", "5m:10d"}} {
+		_, err := ParseLevels(s)
+		testutil.NotOk(t, err, s)
+	}
+}
+
+func TestLevels_Next(t *testing.T) {
+	levels, err := ParseLevels([]string{"5m:40h", "1h:10d", "6h:60d"})
+	testutil.Ok(t, err)
+
+	next, ok := levels.Next(ResLevel0)
+	testutil.Assert(t, ok)
+	testutil.Equals(t, ResLevel1, next.Resolution)
+
+	next, ok = levels.Next(ResLevel2)
+	testutil.Assert(t, ok)
+	testutil.Equals(t, int64(6*60*60*1000), next.Resolution)
+
+	_, ok = levels.Next(6 * 60 * 60 * 1000)
+	testutil.Assert(t, !ok)
+	// Blocks of resolutions which are not in the ladder are not downsampled.
+	_, ok = levels.Next(2 * 60 * 60 * 1000)
+	testutil.Assert(t, !ok)
+	_, ok = Levels{}.Next(ResLevel0)
+	testutil.Assert(t, !ok)
+}
+
+func TestParseResolutions(t *testing.T) {
+	res, err := ParseResolutions([]string{"1h", "5m"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []int64{ResLevel1, ResLevel2}, res)
+
+	_, err = ParseResolutions([]string{"0s"})
+	testutil.NotOk(t, err)
+}
@@ -6,6 +6,7 @@ package compact
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/go-kit/log"
@@ -13,6 +14,7 @@ import (
 	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
 
 	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
@@ -30,7 +32,23 @@ func ApplyRetentionPolicyByResolution(
 	blocksMarkedForDeletion prometheus.Counter,
 ) error {
 	level.Info(logger).Log("msg", "start optional retention")
-	for id, m := range metas {
+	for _, m := range BlocksExceedingRetention(metas, retentionByResolution) {
+		retentionDuration := retentionByResolution[ResolutionLevel(m.Thanos.Downsample.Resolution)]
+		maxTime := time.Unix(m.MaxTime/1000, 0)
+		level.Info(logger).Log("msg", "applying retention: marking block for deletion", "id", m.ULID, "maxTime", maxTime.String())
+		if err := block.MarkForDeletion(ctx, logger, bkt, m.ULID, fmt.Sprintf("block exceeding retention of %v", retentionDuration), blocksMarkedForDeletion); err != nil {
+			return errors.Wrap(err, "delete block")
+		}
+	}
+	level.Info(logger).Log("msg", "optional retention apply done")
+	return nil
+}
+
+// BlocksExceedingRetention returns the given blocks, sorted by ID, which exceed the retention of their resolution
+// based on their MaxTime, and are thus deleted by ApplyRetentionPolicyByResolution.
+func BlocksExceedingRetention(metas map[ulid.ULID]*metadata.Meta, retentionByResolution map[ResolutionLevel]time.Duration) []*metadata.Meta {
+	var res []*metadata.Meta
+	for _, m := range metas {
 		retentionDuration := retentionByResolution[ResolutionLevel(m.Thanos.Downsample.Resolution)]
 		if retentionDuration.Seconds() == 0 {
 			continue
@@ -38,12 +56,78 @@ func ApplyRetentionPolicyByResolution(
 
 		maxTime := time.Unix(m.MaxTime/1000, 0)
 		if time.Now().After(maxTime.Add(retentionDuration)) {
-			level.Info(logger).Log("msg", "applying retention: marking block for deletion", "id", id, "maxTime", maxTime.String())
-			if err := block.MarkForDeletion(ctx, logger, bkt, id, fmt.Sprintf("block exceeding retention of %v", retentionDuration), blocksMarkedForDeletion); err != nil {
-				return errors.Wrap(err, "delete block")
-			}
+			res = append(res, m)
 		}
 	}
-	level.Info(logger).Log("msg", "optional retention apply done")
-	return nil
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ULID.Compare(res[j].ULID) < 0
+	})
+	return res
+}
+
+// RetentionReport describes the blocks which applying retention would delete.
+type RetentionReport struct {
+	Blocks []RetentionBlock
+	// Totals are the totals of Blocks per external label set, sorted by labels.
+	Totals []RetentionTotal
+}
+
+// RetentionBlock is a block exceeding retention, with the number and total size in bytes of its objects.
+type RetentionBlock struct {
+	Meta      *metadata.Meta
+	Retention time.Duration
+	Objects   int
+	Size      int64
+}
+
+// RetentionTotal sums up the blocks exceeding retention which have the same external labels.
+type RetentionTotal struct {
+	Labels  labels.Labels
+	Blocks  int
+	Objects int
+	Size    int64
+}
+
+// RetentionDryRun returns the report of the blocks which ApplyRetentionPolicyByResolution would delete, without
+// marking any of them for deletion.
+func RetentionDryRun(
+	ctx context.Context,
+	bkt objstore.BucketReader,
+	metas map[ulid.ULID]*metadata.Meta,
+	retentionByResolution map[ResolutionLevel]time.Duration,
+) (RetentionReport, error) {
+	var (
+		report RetentionReport
+		totals = map[string]*RetentionTotal{}
+	)
+	for _, m := range BlocksExceedingRetention(metas, retentionByResolution) {
+		objects, size, err := block.SizeInBucket(ctx, bkt, m.ULID)
+		if err != nil {
+			return RetentionReport{}, err
+		}
+		report.Blocks = append(report.Blocks, RetentionBlock{
+			Meta:      m,
+			Retention: retentionByResolution[ResolutionLevel(m.Thanos.Downsample.Resolution)],
+			Objects:   objects,
+			Size:      size,
+		})
+
+		lset := labels.FromMap(m.Thanos.Labels)
+		t, ok := totals[lset.String()]
+		if !ok {
+			t = &RetentionTotal{Labels: lset}
+			totals[lset.String()] = t
+		}
+		t.Blocks++
+		t.Objects += objects
+		t.Size += size
+	}
+
+	for _, t := range totals {
+		report.Totals = append(report.Totals, *t)
+	}
+	sort.Slice(report.Totals, func(i, j int) bool {
+		return labels.Compare(report.Totals[i].Labels, report.Totals[j].Labels) < 0
+	})
+	return report, nil
 }
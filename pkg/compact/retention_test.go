@@ -278,6 +278,45 @@ func TestApplyRetentionPolicyByResolution(t *testing.T) {
 	}
 }
 
+func TestRetentionDryRun(t *testing.T) {
+	logger := log.NewNopLogger()
+	ctx := context.TODO()
+
+	inMem := objstore.NewInMemBucket()
+	bkt := objstore.WithNoopInstr(inMem)
+	uploadMockBlock(t, bkt, "01CPHBEX20729MJQZXE3W0BW40", time.Now().Add(-3*24*time.Hour), time.Now().Add(-2*24*time.Hour), int64(compact.ResolutionLevelRaw))
+	uploadMockBlock(t, bkt, "01CPHBEX20729MJQZXE3W0BW41", time.Now().Add(-2*24*time.Hour), time.Now().Add(-24*time.Hour), int64(compact.ResolutionLevelRaw))
+	uploadMockBlock(t, bkt, "01CPHBEX20729MJQZXE3W0BW42", time.Now().Add(-3*24*time.Hour), time.Now().Add(-2*24*time.Hour), int64(compact.ResolutionLevel5m))
+	uploadMockBlock(t, bkt, "01CPHBEX20729MJQZXE3W0BW43", time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour), int64(compact.ResolutionLevelRaw))
+
+	metaFetcher, err := block.NewMetaFetcher(logger, 32, bkt, "", nil, nil, nil)
+	testutil.Ok(t, err)
+	metas, _, err := metaFetcher.Fetch(ctx)
+	testutil.Ok(t, err)
+
+	retentionByResolution := map[compact.ResolutionLevel]time.Duration{
+		compact.ResolutionLevelRaw: 12 * time.Hour,
+		compact.ResolutionLevel5m:  24 * time.Hour,
+	}
+	objects := len(inMem.Objects())
+	report, err := compact.RetentionDryRun(ctx, bkt, metas, retentionByResolution)
+	testutil.Ok(t, err)
+	testutil.Equals(t, objects, len(inMem.Objects()))
+
+	testutil.Equals(t, 3, len(report.Blocks))
+	for i, id := range []string{"01CPHBEX20729MJQZXE3W0BW40", "01CPHBEX20729MJQZXE3W0BW41", "01CPHBEX20729MJQZXE3W0BW42"} {
+		testutil.Equals(t, id, report.Blocks[i].Meta.ULID.String())
+		testutil.Equals(t, 4, report.Blocks[i].Objects)
+	}
+	testutil.Equals(t, 12*time.Hour, report.Blocks[0].Retention)
+	testutil.Equals(t, 24*time.Hour, report.Blocks[2].Retention)
+
+	testutil.Equals(t, 1, len(report.Totals))
+	testutil.Equals(t, 3, report.Totals[0].Blocks)
+	testutil.Equals(t, 12, report.Totals[0].Objects)
+	testutil.Equals(t, report.Blocks[0].Size+report.Blocks[1].Size+report.Blocks[2].Size, report.Totals[0].Size)
+}
+
 func uploadMockBlock(t *testing.T, bkt objstore.Bucket, id string, minTime, maxTime time.Time, resolutionLevel int64) {
 	t.Helper()
 	meta1 := metadata.Meta{
@@ -0,0 +1,60 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package compact
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore/tiered"
+)
+
+// MoveBlocksToColdTier moves blocks of the hot tier, whose MaxTime is older than the cut-over age of the tiered
+// bucket, to the cold tier. Blocks marked for deletion are not moved.
+func MoveBlocksToColdTier(
+	ctx context.Context,
+	logger log.Logger,
+	bkt *tiered.Bucket,
+	metas map[ulid.ULID]*metadata.Meta,
+	blocksMoved prometheus.Counter,
+) error {
+	level.Info(logger).Log("msg", "start moving blocks to cold tier")
+	for id, m := range metas {
+		maxTime := time.Unix(m.MaxTime/1000, 0)
+		if time.Now().Before(maxTime.Add(bkt.CutOverAge())) {
+			continue
+		}
+
+		ok, err := bkt.InHotTier(ctx, id)
+		if err != nil {
+			return errors.Wrapf(err, "check tier of block %s", id)
+		}
+		if !ok {
+			continue
+		}
+		marked, err := bkt.Exists(ctx, path.Join(id.String(), metadata.DeletionMarkFilename))
+		if err != nil {
+			return errors.Wrapf(err, "check deletion mark of block %s", id)
+		}
+		if marked {
+			continue
+		}
+
+		level.Info(logger).Log("msg", "moving block to cold tier", "id", id, "maxTime", maxTime.String())
+		if err := bkt.MoveToColdTier(ctx, id); err != nil {
+			return errors.Wrapf(err, "move block %s to cold tier", id)
+		}
+		blocksMoved.Inc()
+	}
+	level.Info(logger).Log("msg", "moving blocks to cold tier done")
+	return nil
+}
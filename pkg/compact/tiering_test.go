@@ -0,0 +1,84 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package compact_test
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/compact"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/objstore/tiered"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestMoveBlocksToColdTier(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	hot, cold := objstore.NewInMemBucket(), objstore.NewInMemBucket()
+	bkt, err := tiered.NewBucket(logger, hot, cold, 24*time.Hour)
+	testutil.Ok(t, err)
+
+	metas := map[ulid.ULID]*metadata.Meta{}
+	uploadBlock := func(id string, maxTime time.Time) ulid.ULID {
+		blockID := ulid.MustParse(id)
+		meta := &metadata.Meta{}
+		meta.ULID = blockID
+		meta.MaxTime = maxTime.Unix() * 1000
+		metas[blockID] = meta
+
+		var buf bytes.Buffer
+		testutil.Ok(t, meta.Write(&buf))
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id, "chunks", "000001"), strings.NewReader("chunks")))
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id, "index"), strings.NewReader("index")))
+		testutil.Ok(t, bkt.Upload(ctx, path.Join(id, metadata.MetaFilename), &buf))
+		return blockID
+	}
+
+	recent := uploadBlock("01CPHBEX20729MJQZXE3W0BW48", time.Now().Add(-time.Hour))
+	old := uploadBlock("01CPHBEX20729MJQZXE3W0BW49", time.Now().Add(-48*time.Hour))
+	oldMarked := uploadBlock("01CPHBEX20729MJQZXE3W0BW50", time.Now().Add(-48*time.Hour))
+	testutil.Ok(t, bkt.Upload(ctx, path.Join(oldMarked.String(), metadata.DeletionMarkFilename), strings.NewReader("{}")))
+
+	blocksMoved := prometheus.NewCounter(prometheus.CounterOpts{})
+	testutil.Ok(t, compact.MoveBlocksToColdTier(ctx, logger, bkt, metas, blocksMoved))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(blocksMoved))
+
+	for _, id := range []ulid.ULID{recent, oldMarked} {
+		ok, err := bkt.InHotTier(ctx, id)
+		testutil.Ok(t, err)
+		testutil.Assert(t, ok, "expected block %s in hot tier", id)
+	}
+	ok, err := bkt.InHotTier(ctx, old)
+	testutil.Ok(t, err)
+	testutil.Assert(t, !ok, "expected block %s not in hot tier", old)
+
+	var hotObjects []string
+	for name := range hot.Objects() {
+		if strings.HasPrefix(name, old.String()) {
+			hotObjects = append(hotObjects, name)
+		}
+	}
+	testutil.Equals(t, 0, len(hotObjects))
+	testutil.Equals(t, 3, len(cold.Objects()))
+
+	// Moved blocks are still readable through the tiered bucket.
+	rc, err := bkt.Get(ctx, path.Join(old.String(), "index"))
+	testutil.Ok(t, err)
+	testutil.Ok(t, rc.Close())
+
+	// Next runs don't move blocks again.
+	testutil.Ok(t, compact.MoveBlocksToColdTier(ctx, logger, bkt, metas, blocksMoved))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(blocksMoved))
+}
@@ -7,8 +7,14 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
 	"github.com/prometheus/prometheus/tsdb/tombstones"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/runutil"
 )
 
 type ChangeLogger interface {
@@ -33,3 +39,111 @@ func (l *changeLog) DeleteSeries(del labels.Labels, intervals tombstones.Interva
 func (l *changeLog) ModifySeries(old, new labels.Labels) {
 	_, _ = fmt.Fprintf(l.w, "Relabelled %v %v\n", old.String(), new.String())
 }
+
+// ChangeStats summarizes the series changed by modifiers.
+type ChangeStats struct {
+	// DeletedSeries is the number of series with deleted samples, including series which are deleted completely.
+	DeletedSeries int
+	// RelabelledSeries is the number of series with modified labels.
+	RelabelledSeries int
+	// AffectedSeries is the number of series which are either deleted or relabelled.
+	AffectedSeries int
+	// AffectedChunkBytes is the size of the chunks of the affected series in the source blocks.
+	AffectedChunkBytes int64
+}
+
+// statsChangeLog is a change logger which records the changed series, so the changes can be summarized before they
+// are applied, e.g. in dry runs.
+type statsChangeLog struct {
+	ChangeLogger
+
+	deleted, relabelled map[string]struct{}
+}
+
+// NewStatsChangeLog returns a change logger which records the changed series and passes the changes to the given one.
+func NewStatsChangeLog(l ChangeLogger) *statsChangeLog {
+	return &statsChangeLog{
+		ChangeLogger: l,
+		deleted:      map[string]struct{}{},
+		relabelled:   map[string]struct{}{},
+	}
+}
+
+func (l *statsChangeLog) DeleteSeries(del labels.Labels, intervals tombstones.Intervals) {
+	l.deleted[del.String()] = struct{}{}
+	l.ChangeLogger.DeleteSeries(del, intervals)
+}
+
+func (l *statsChangeLog) ModifySeries(old, new labels.Labels) {
+	l.relabelled[old.String()] = struct{}{}
+	l.ChangeLogger.ModifySeries(old, new)
+}
+
+// Stats returns the summary of the recorded changes to the series of the given source blocks.
+func (l *statsChangeLog) Stats(readers ...block.Reader) (stats ChangeStats, err error) {
+	stats.DeletedSeries = len(l.deleted)
+	stats.RelabelledSeries = len(l.relabelled)
+
+	affected := make(map[string]struct{}, len(l.deleted)+len(l.relabelled))
+	for s := range l.deleted {
+		affected[s] = struct{}{}
+	}
+	for s := range l.relabelled {
+		affected[s] = struct{}{}
+	}
+	stats.AffectedSeries = len(affected)
+	if len(affected) == 0 {
+		return stats, nil
+	}
+
+	for _, b := range readers {
+		size, err := chunkBytes(b, affected)
+		if err != nil {
+			return stats, errors.Wrapf(err, "size of affected series of block %s", b.Meta().ULID)
+		}
+		stats.AffectedChunkBytes += size
+	}
+	return stats, nil
+}
+
+// chunkBytes returns the size of the chunks of the given series of the block.
+func chunkBytes(b block.Reader, series map[string]struct{}) (size int64, err error) {
+	indexr, err := b.Index()
+	if err != nil {
+		return 0, errors.Wrap(err, "open index reader")
+	}
+	defer runutil.CloseWithErrCapture(&err, indexr, "index reader")
+
+	chunkr, err := b.Chunks()
+	if err != nil {
+		return 0, errors.Wrap(err, "open chunk reader")
+	}
+	defer runutil.CloseWithErrCapture(&err, chunkr, "chunk reader")
+
+	k, v := index.AllPostingsKey()
+	all, err := indexr.Postings(k, v)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		lset labels.Labels
+		chks []chunks.Meta
+	)
+	for all.Next() {
+		if err := indexr.Series(all.At(), &lset, &chks); err != nil {
+			return 0, errors.Wrap(err, "read series")
+		}
+		if _, ok := series[lset.String()]; !ok {
+			continue
+		}
+		for _, c := range chks {
+			chk, err := chunkr.Chunk(c.Ref)
+			if err != nil {
+				return 0, errors.Wrapf(err, "read chunk %d of series %s", c.Ref, lset)
+			}
+			size += int64(len(chk.Bytes()))
+		}
+	}
+	return size, all.Err()
+}
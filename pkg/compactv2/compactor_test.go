@@ -587,6 +587,93 @@ func TestCompactor_WriteSeries_e2e(t *testing.T) {
 				NumChunks:  1,
 			},
 		},
+		{
+			name: "1 block + relabel modifier with matchers, only matching series relabeled",
+			input: [][]seriesSamples{
+				{
+					{lset: labels.Labels{{Name: "a", Value: "1"}},
+						chunks: [][]sample{{{0, 0}, {10, 10}}}},
+					{lset: labels.Labels{{Name: "a", Value: "2"}},
+						chunks: [][]sample{{{0, 0}, {10, 10}}}},
+				},
+			},
+			modifiers: []Modifier{WithRelabelModifier(
+				&relabel.Config{
+					Action:       relabel.Replace,
+					Regex:        relabel.MustNewRegexp("1|2"),
+					SourceLabels: model.LabelNames{"a"},
+					TargetLabel:  "a",
+					Replacement:  "0",
+				},
+			).WithMatchers([]*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "a", "1")})},
+			expected: []seriesSamples{
+				{lset: labels.Labels{{Name: "a", Value: "0"}},
+					chunks: [][]sample{{{0, 0}, {10, 10}}}},
+				{lset: labels.Labels{{Name: "a", Value: "2"}},
+					chunks: [][]sample{{{0, 0}, {10, 10}}}},
+			},
+			expectedChanges: "Relabelled {a=\"1\"} {a=\"0\"}\n",
+			expectedStats: tsdb.BlockStats{
+				NumSamples: 4,
+				NumSeries:  2,
+				NumChunks:  2,
+			},
+		},
+		{
+			name: "1 block + relabel modifier with interval, only samples within interval relabeled",
+			input: [][]seriesSamples{
+				{
+					{lset: labels.Labels{{Name: "a", Value: "1"}},
+						chunks: [][]sample{{{0, 0}, {5, 5}}, {{10, 10}, {20, 20}}}},
+				},
+			},
+			modifiers: []Modifier{WithRelabelModifier(
+				&relabel.Config{
+					Action:       relabel.Replace,
+					Regex:        relabel.MustNewRegexp("1"),
+					SourceLabels: model.LabelNames{"a"},
+					TargetLabel:  "a",
+					Replacement:  "0",
+				},
+			).WithInterval(tombstones.Interval{Mint: 8, Maxt: 15})},
+			expected: []seriesSamples{
+				{lset: labels.Labels{{Name: "a", Value: "0"}},
+					chunks: [][]sample{{{10, 10}}}},
+				{lset: labels.Labels{{Name: "a", Value: "1"}},
+					chunks: [][]sample{{{0, 0}, {5, 5}, {20, 20}}}},
+			},
+			expectedChanges: "Relabelled {a=\"1\"} {a=\"0\"}\n",
+			expectedStats: tsdb.BlockStats{
+				NumSamples: 4,
+				NumSeries:  2,
+				NumChunks:  2,
+			},
+		},
+		{
+			name: "1 block + relabel modifier with interval, samples within interval deleted because of no labels left after relabel",
+			input: [][]seriesSamples{
+				{
+					{lset: labels.Labels{{Name: "a", Value: "1"}},
+						chunks: [][]sample{{{0, 0}, {5, 5}}, {{10, 10}, {20, 20}}}},
+				},
+			},
+			modifiers: []Modifier{WithRelabelModifier(
+				&relabel.Config{
+					Action: relabel.LabelDrop,
+					Regex:  relabel.MustNewRegexp("a"),
+				},
+			).WithInterval(tombstones.Interval{Mint: 8, Maxt: 15})},
+			expected: []seriesSamples{
+				{lset: labels.Labels{{Name: "a", Value: "1"}},
+					chunks: [][]sample{{{0, 0}, {5, 5}, {20, 20}}}},
+			},
+			expectedChanges: "Deleted {a=\"1\"} [{10 15}]\n",
+			expectedStats: tsdb.BlockStats{
+				NumSamples: 3,
+				NumSeries:  1,
+				NumChunks:  1,
+			},
+		},
 	} {
 		t.Run(tcase.name, func(t *testing.T) {
 			tmpDir, err := ioutil.TempDir("", "test-series-writer")
@@ -642,6 +729,75 @@ func TestCompactor_WriteSeries_e2e(t *testing.T) {
 	}
 }
 
+func TestStatsChangeLog(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir("", "test-stats-change-log")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(tmpDir)) }()
+
+	chunkPool := chunkenc.NewPool()
+	id := ulid.MustNew(1, nil)
+	bdir := filepath.Join(tmpDir, id.String())
+	testutil.Ok(t, os.MkdirAll(bdir, os.ModePerm))
+	testutil.Ok(t, createBlockSeries(bdir, []seriesSamples{
+		{lset: labels.Labels{{Name: "a", Value: "1"}}, chunks: [][]sample{{{0, 0}, {1, 1}}, {{10, 10}, {11, 11}}}},
+		{lset: labels.Labels{{Name: "a", Value: "2"}}, chunks: [][]sample{{{0, 0}, {1, 1}}}},
+		{lset: labels.Labels{{Name: "a", Value: "3"}}, chunks: [][]sample{{{0, 0}, {1, 1}}}},
+	}))
+	testutil.Ok(t, metadata.Meta{BlockMeta: tsdb.BlockMeta{Version: 1, ULID: id}}.WriteToDir(logger, bdir))
+	b, err := tsdb.OpenBlock(logger, bdir, chunkPool)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, b.Close()) }()
+
+	changes := bytes.Buffer{}
+	statsChangeLog := NewStatsChangeLog(NewChangeLog(&changes))
+	d, err := block.NewDiskWriter(ctx, logger, filepath.Join(tmpDir, "new"))
+	testutil.Ok(t, err)
+	testutil.Ok(t, NewDryRun(tmpDir, logger, statsChangeLog, chunkPool).WriteSeries(ctx, []block.Reader{b}, d, NewProgressLogger(logger, 3),
+		WithDeletionModifier(metadata.DeletionRequest{
+			Matchers:  []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, "a", "1|2")},
+			Intervals: tombstones.Intervals{{Mint: 10, Maxt: 20}},
+		}),
+		WithRelabelModifier(&relabel.Config{
+			Action:       relabel.Replace,
+			Regex:        relabel.MustNewRegexp("1"),
+			SourceLabels: model.LabelNames{"a"},
+			TargetLabel:  "a",
+			Replacement:  "0",
+		}),
+	))
+	testutil.Equals(t, "Deleted {a=\"1\"} [{10 11}]\nRelabelled {a=\"1\"} {a=\"0\"}\n", changes.String())
+
+	stats, err := statsChangeLog.Stats(b)
+	testutil.Ok(t, err)
+
+	// Only the series {a="1"} is changed, so only the size of its chunks counts.
+	chunkr, err := b.Chunks()
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, chunkr.Close()) }()
+	indexr, err := b.Index()
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, indexr.Close()) }()
+	postings, err := indexr.Postings("a", "1")
+	testutil.Ok(t, err)
+	testutil.Assert(t, postings.Next())
+	var (
+		lset      labels.Labels
+		chks      []chunks.Meta
+		chunkSize int64
+	)
+	testutil.Ok(t, indexr.Series(postings.At(), &lset, &chks))
+	for _, c := range chks {
+		chk, err := chunkr.Chunk(c.Ref)
+		testutil.Ok(t, err)
+		chunkSize += int64(len(chk.Bytes()))
+	}
+
+	testutil.Equals(t, ChangeStats{DeletedSeries: 1, RelabelledSeries: 1, AffectedSeries: 1, AffectedChunkBytes: chunkSize}, stats)
+}
+
 type sample struct {
 	t int64
 	v float64
@@ -333,23 +333,115 @@ func (p *delChunkSeriesIterator) At() chunks.Meta { return p.curr }
 
 type RelabelModifier struct {
 	relabels []*relabel.Config
+
+	matchers [][]*labels.Matcher
+	interval *tombstones.Interval
 }
 
 func WithRelabelModifier(relabels ...*relabel.Config) *RelabelModifier {
 	return &RelabelModifier{relabels: relabels}
 }
 
+// WithMatchers restricts relabelling to series which match all matchers of any of the given sets.
+func (d *RelabelModifier) WithMatchers(matchers ...[]*labels.Matcher) *RelabelModifier {
+	d.matchers = append(d.matchers, matchers...)
+	return d
+}
+
+// WithInterval restricts relabelling to the samples within the given interval. Samples of relabelled series outside
+// of it are kept with the original labels.
+func (d *RelabelModifier) WithInterval(interval tombstones.Interval) *RelabelModifier {
+	d.interval = &interval
+	return d
+}
+
+func (d *RelabelModifier) selects(lbls labels.Labels) bool {
+	if len(d.matchers) == 0 {
+		return true
+	}
+MatchersLoop:
+	for _, ms := range d.matchers {
+		for _, m := range ms {
+			if !m.Matches(lbls.Get(m.Name)) {
+				continue MatchersLoop
+			}
+		}
+		return true
+	}
+	return false
+}
+
 func (d *RelabelModifier) Modify(_ index.StringIter, set storage.ChunkSeriesSet, log ChangeLogger, p ProgressLogger) (index.StringIter, storage.ChunkSeriesSet) {
 	// Gather symbols.
 	symbols := make(map[string]struct{})
 	chunkSeriesMap := make(map[string]*mergeChunkSeries)
 
+	seriesBuilder := func(lset labels.Labels) *mergeChunkSeries {
+		lbStr := lset.String()
+		if _, ok := chunkSeriesMap[lbStr]; !ok {
+			for _, lb := range lset {
+				symbols[lb.Name] = struct{}{}
+				symbols[lb.Value] = struct{}{}
+			}
+			chunkSeriesMap[lbStr] = newChunkSeriesBuilder(lset)
+		}
+		return chunkSeriesMap[lbStr]
+	}
+
 	for set.Next() {
 		s := set.At()
 		lbls := s.Labels()
 		chksIter := s.Iterator()
 
-		if processedLabels := relabel.Process(lbls, d.relabels...); len(processedLabels) == 0 {
+		processedLabels := lbls
+		if d.selects(lbls) {
+			processedLabels = relabel.Process(lbls, d.relabels...)
+		}
+
+		if d.interval != nil && !labels.Equal(lbls, processedLabels) {
+			// Only samples within the interval are relabelled, the others are kept in the original series.
+			var (
+				outside  tombstones.Intervals
+				modified bool
+				deleted  tombstones.Intervals
+			)
+			if d.interval.Mint > math.MinInt64 {
+				outside = outside.Add(tombstones.Interval{Mint: math.MinInt64, Maxt: d.interval.Mint - 1})
+			}
+			if d.interval.Maxt < math.MaxInt64 {
+				outside = outside.Add(tombstones.Interval{Mint: d.interval.Maxt + 1, Maxt: math.MaxInt64})
+			}
+			for chksIter.Next() {
+				c := chksIter.At()
+				if !(tombstones.Interval{Mint: c.MinTime, Maxt: c.MaxTime}).IsSubrange(tombstones.Intervals{*d.interval}) {
+					seriesBuilder(lbls).addIter(&tsdb.DeletedIterator{Iter: c.Chunk.Iterator(nil), Intervals: tombstones.Intervals{*d.interval}})
+				}
+				if !c.OverlapsClosedInterval(d.interval.Mint, d.interval.Maxt) {
+					continue
+				}
+				if len(processedLabels) == 0 {
+					for _, del := range intersection(tombstones.Interval{Mint: c.MinTime, Maxt: c.MaxTime}, tombstones.Intervals{*d.interval}) {
+						deleted = deleted.Add(del)
+					}
+					continue
+				}
+				seriesBuilder(processedLabels).addIter(&tsdb.DeletedIterator{Iter: c.Chunk.Iterator(nil), Intervals: outside})
+				modified = true
+			}
+			if err := chksIter.Err(); err != nil {
+				return errorOnlyStringIter{err}, nil
+			}
+
+			if len(deleted) > 0 {
+				log.DeleteSeries(lbls, deleted)
+			}
+			if modified {
+				log.ModifySeries(lbls, processedLabels)
+			}
+			continue
+		}
+
+		if len(processedLabels) == 0 {
 			// Special case: Delete whole series if no labels are present.
 			var (
 				minT int64 = math.MaxInt64
@@ -377,16 +469,7 @@ func (d *RelabelModifier) Modify(_ index.StringIter, set storage.ChunkSeriesSet,
 			log.DeleteSeries(lbls, deleted)
 			p.SeriesProcessed()
 		} else {
-			for _, lb := range processedLabels {
-				symbols[lb.Name] = struct{}{}
-				symbols[lb.Value] = struct{}{}
-			}
-
-			lbStr := processedLabels.String()
-			if _, ok := chunkSeriesMap[lbStr]; !ok {
-				chunkSeriesMap[lbStr] = newChunkSeriesBuilder(processedLabels)
-			}
-			cs := chunkSeriesMap[lbStr]
+			cs := seriesBuilder(processedLabels)
 
 			// We have to iterate over the chunks and populate them here as
 			// lazyPopulateChunkSeriesSet reuses chunks and previous chunks
@@ -15,6 +15,7 @@ type dedupSeriesSet struct {
 	set           storage.SeriesSet
 	replicaLabels map[string]struct{}
 	isCounter     bool
+	counterAware  bool
 
 	replicas []storage.Series
 	lset     labels.Labels
@@ -23,7 +24,19 @@ type dedupSeriesSet struct {
 }
 
 func NewSeriesSet(set storage.SeriesSet, replicaLabels map[string]struct{}, isCounter bool) storage.SeriesSet {
-	s := &dedupSeriesSet{set: set, replicaLabels: replicaLabels, isCounter: isCounter}
+	return newSeriesSet(set, replicaLabels, isCounter, false)
+}
+
+// NewCounterAwareSeriesSet is like NewSeriesSet, but deduplicates counters by carrying over the increases of the
+// replicas when switching between them instead of their values. This avoids jumps on replica switchovers when the
+// counter totals of replicas differ, e.g. when one has raw and the other downsampled data, or they saw different
+// counter resets.
+func NewCounterAwareSeriesSet(set storage.SeriesSet, replicaLabels map[string]struct{}, isCounter bool) storage.SeriesSet {
+	return newSeriesSet(set, replicaLabels, isCounter, true)
+}
+
+func newSeriesSet(set storage.SeriesSet, replicaLabels map[string]struct{}, isCounter, counterAware bool) storage.SeriesSet {
+	s := &dedupSeriesSet{set: set, replicaLabels: replicaLabels, isCounter: isCounter, counterAware: counterAware}
 	s.ok = s.set.Next()
 	if s.ok {
 		s.peek = s.set.At()
@@ -90,7 +103,7 @@ func (s *dedupSeriesSet) At() storage.Series {
 	// Clients may store the series, so we must make a copy of the slice before advancing.
 	repl := make([]storage.Series, len(s.replicas))
 	copy(repl, s.replicas)
-	return newDedupSeries(s.lset, repl, s.isCounter)
+	return newDedupSeries(s.lset, repl, s.isCounter, s.counterAware)
 }
 
 func (s *dedupSeriesSet) Err() error {
@@ -112,11 +125,12 @@ type dedupSeries struct {
 	lset     labels.Labels
 	replicas []storage.Series
 
-	isCounter bool
+	isCounter    bool
+	counterAware bool
 }
 
-func newDedupSeries(lset labels.Labels, replicas []storage.Series, isCounter bool) *dedupSeries {
-	return &dedupSeries{lset: lset, isCounter: isCounter, replicas: replicas}
+func newDedupSeries(lset labels.Labels, replicas []storage.Series, isCounter, counterAware bool) *dedupSeries {
+	return &dedupSeries{lset: lset, isCounter: isCounter, counterAware: counterAware, replicas: replicas}
 }
 
 func (s *dedupSeries) Labels() labels.Labels {
@@ -124,25 +138,24 @@ func (s *dedupSeries) Labels() labels.Labels {
 }
 
 func (s *dedupSeries) Iterator() chunkenc.Iterator {
-	var it adjustableSeriesIterator
-	if s.isCounter {
-		it = &counterErrAdjustSeriesIterator{Iterator: s.replicas[0].Iterator()}
-	} else {
-		it = noopAdjustableSeriesIterator{Iterator: s.replicas[0].Iterator()}
-	}
-
+	it := s.adjustableIterator(s.replicas[0])
 	for _, o := range s.replicas[1:] {
-		var replicaIter adjustableSeriesIterator
-		if s.isCounter {
-			replicaIter = &counterErrAdjustSeriesIterator{Iterator: o.Iterator()}
-		} else {
-			replicaIter = noopAdjustableSeriesIterator{Iterator: o.Iterator()}
-		}
-		it = newDedupSeriesIterator(it, replicaIter)
+		it = newDedupSeriesIterator(it, s.adjustableIterator(o))
 	}
 	return it
 }
 
+func (s *dedupSeries) adjustableIterator(replica storage.Series) adjustableSeriesIterator {
+	switch {
+	case s.isCounter && s.counterAware:
+		return &counterIncreaseAdjustSeriesIterator{Iterator: replica.Iterator()}
+	case s.isCounter:
+		return &counterErrAdjustSeriesIterator{Iterator: replica.Iterator()}
+	default:
+		return noopAdjustableSeriesIterator{Iterator: replica.Iterator()}
+	}
+}
+
 // adjustableSeriesIterator iterates over the data of a time series and allows to adjust current value based on
 // given lastValue iterated.
 type adjustableSeriesIterator interface {
@@ -200,6 +213,71 @@ func (it *counterErrAdjustSeriesIterator) At() (int64, float64) {
 	return t, v + it.errAdjust
 }
 
+// counterIncreaseAdjustSeriesIterator is the adjustableSeriesIterator used when we deduplicate counters with
+// NewCounterAwareSeriesSet. When switching to this replica, its values are adjusted so that the deduplicated series
+// continues from the last value with the increase this replica saw since its previous sample. Taking the example
+// above, but with replica 2 having a higher total, e.g. because it saw an additional counter reset:
+//
+// Replica 1 counter total: 20    30    40    -     -     40     45
+// Replica 2 counter total:    125   135   145   -     -     147
+//
+// counterErrAdjustSeriesIterator only adjusts replicas with lower values, so switching to replica 2 results in a jump
+// of 100, which rate() sees as an increase. Carrying over the increases works regardless of the totals of replicas.
+type counterIncreaseAdjustSeriesIterator struct {
+	chunkenc.Iterator
+
+	started   bool
+	hasPrev   bool
+	prevV     float64
+	errAdjust float64
+}
+
+func (it *counterIncreaseAdjustSeriesIterator) Next() bool {
+	if it.started {
+		_, it.prevV = it.Iterator.At()
+		it.hasPrev = true
+	}
+	it.started = true
+	return it.Iterator.Next()
+}
+
+func (it *counterIncreaseAdjustSeriesIterator) Seek(t int64) bool {
+	// Don't use underlying Seek, but iterate over next to keep track of the previous sample.
+	for {
+		if it.started {
+			if ts, _ := it.Iterator.At(); ts >= t {
+				return true
+			}
+		}
+		if !it.Next() {
+			return false
+		}
+	}
+}
+
+func (it *counterIncreaseAdjustSeriesIterator) adjustAtValue(lastValue float64) {
+	if lastValue == float64(math.MinInt64) {
+		// No sample was picked yet.
+		return
+	}
+	_, v := it.Iterator.At()
+	// Without a previous sample the increase is unknown, so the series continues from the last value.
+	var increase float64
+	if it.hasPrev {
+		increase = v - it.prevV
+		if increase < 0 {
+			// The counter was reset.
+			increase = v
+		}
+	}
+	it.errAdjust = lastValue + increase - v
+}
+
+func (it *counterIncreaseAdjustSeriesIterator) At() (int64, float64) {
+	t, v := it.Iterator.At()
+	return t, v + it.errAdjust
+}
+
 type dedupSeriesIterator struct {
 	a, b adjustableSeriesIterator
 
@@ -439,6 +439,46 @@ func TestDedupSeriesSet(t *testing.T) {
 	}
 }
 
+func TestCounterAwareDedupSeriesSet(t *testing.T) {
+	// The counter totals of the replicas differ, e.g. because the second one saw an additional counter reset.
+	input := []series{
+		{
+			lset:    labels.Labels{{Name: "replica", Value: "01"}},
+			samples: []sample{{10000, 20}, {20000, 30}, {30000, 40}, {90000, 45}, {100000, 50}},
+		}, {
+			lset:    labels.Labels{{Name: "replica", Value: "02"}},
+			samples: []sample{{10001, 120}, {20001, 130}, {40001, 145}, {50001, 150}, {60001, 155}, {70001, 160}},
+		},
+	}
+	dedupLabels := map[string]struct{}{"replica": {}}
+
+	for _, tcase := range []struct {
+		name string
+		set  storage.SeriesSet
+		exp  []sample
+	}{
+		{
+			name: "counter values",
+			set:  NewSeriesSet(&mockedSeriesSet{series: input}, dedupLabels, true),
+			// Switching to the second replica results in a jump of its higher total.
+			exp: []sample{{10000, 20}, {20000, 30}, {30000, 40}, {50001, 150}, {60001, 155}, {70001, 160}, {100000, 160}},
+		},
+		{
+			name: "counter increases",
+			set:  NewCounterAwareSeriesSet(&mockedSeriesSet{series: input}, dedupLabels, true),
+			exp:  []sample{{10000, 20}, {20000, 30}, {30000, 40}, {50001, 45}, {60001, 50}, {70001, 55}, {100000, 60}},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			testutil.Assert(t, tcase.set.Next())
+			testutil.Equals(t, labels.Labels{}, tcase.set.At().Labels())
+			testutil.Equals(t, tcase.exp, expandSeries(t, tcase.set.At().Iterator()))
+			testutil.Assert(t, !tcase.set.Next())
+			testutil.Ok(t, tcase.set.Err())
+		})
+	}
+}
+
 func TestDedupSeriesIterator(t *testing.T) {
 	// The deltas between timestamps should be at least 10000 to not be affected
 	// by the initial penalty of 5000, that will cause the second iterator to seek
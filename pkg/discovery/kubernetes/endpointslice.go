@@ -0,0 +1,381 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package kubernetes discovers the addresses of endpoints by watching Kubernetes EndpointSlices.
+package kubernetes
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/backoff"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// watchTimeout is the duration after which watches are restarted from the last seen resource version.
+	watchTimeout = 5 * time.Minute
+)
+
+var (
+	watchBackoffConfig = backoff.Config{
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+	}
+
+	// errResourceVersionGone is returned when the resource version to watch from is too old, so that EndpointSlices
+	// have to be listed again.
+	errResourceVersionGone = errors.New("resource version gone")
+)
+
+// Selector selects the EndpointSlices of a namespace by labels, and the port of their endpoints.
+type Selector struct {
+	Namespace     string
+	LabelSelector string
+	// Port is the name of the port of the endpoints, or its number.
+	Port string
+}
+
+// ParseSelector parses selectors of the form <namespace>/<label selector>:<port name or number>, e.g.
+// monitoring/app.kubernetes.io/name=thanos-store:grpc.
+func ParseSelector(s string) (Selector, error) {
+	i, j := strings.Index(s, "/"), strings.LastIndex(s, ":")
+	if i <= 0 || j <= i+1 || j == len(s)-1 {
+		return Selector{}, errors.Errorf("invalid selector %q, expected <namespace>/<label selector>:<port>", s)
+	}
+	return Selector{Namespace: s[:i], LabelSelector: s[i+1 : j], Port: s[j+1:]}, nil
+}
+
+func (s Selector) String() string {
+	return fmt.Sprintf("%s/%s:%s", s.Namespace, s.LabelSelector, s.Port)
+}
+
+// EndpointSliceWatcher keeps the addresses of the ready endpoints of the EndpointSlices matching selectors in sync
+// by watching the Kubernetes API.
+type EndpointSliceWatcher struct {
+	logger    log.Logger
+	client    *http.Client
+	apiServer string
+	tokenFile string
+	selectors []Selector
+
+	mtx sync.RWMutex
+	// addrs holds the addresses of the EndpointSlices of each selector, by name of EndpointSlice.
+	addrs []map[string][]string
+
+	updates prometheus.Counter
+	errors  prometheus.Counter
+}
+
+// NewInClusterEndpointSliceWatcher returns a watcher of the EndpointSlices matching selectors, which connects to the
+// Kubernetes API with the service account of the pod it runs in.
+func NewInClusterEndpointSliceWatcher(logger log.Logger, reg prometheus.Registerer, selectors []Selector) (*EndpointSliceWatcher, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+	}
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, errors.Wrap(err, "read service account CA")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificate found in service account CA")
+	}
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}}
+	return NewEndpointSliceWatcher(logger, reg, "https://"+net.JoinHostPort(host, port), client, serviceAccountDir+"/token", selectors), nil
+}
+
+// NewEndpointSliceWatcher returns a watcher of the EndpointSlices matching selectors using the Kubernetes API at
+// apiServer. Requests are authenticated with the bearer token read from tokenFile before each request, if set.
+func NewEndpointSliceWatcher(logger log.Logger, reg prometheus.Registerer, apiServer string, client *http.Client, tokenFile string, selectors []Selector) *EndpointSliceWatcher {
+	w := &EndpointSliceWatcher{
+		logger:    log.With(logger, "component", "endpointslice-watcher"),
+		client:    client,
+		apiServer: strings.TrimSuffix(apiServer, "/"),
+		tokenFile: tokenFile,
+		selectors: selectors,
+		addrs:     make([]map[string][]string, len(selectors)),
+		updates: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "endpointslice_updates_total",
+			Help: "The number of updates of EndpointSlices received from the Kubernetes API.",
+		}),
+		errors: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "endpointslice_watch_failures_total",
+			Help: "The number of failed lists and watches of EndpointSlices.",
+		}),
+	}
+	for i := range w.addrs {
+		w.addrs[i] = map[string][]string{}
+	}
+	return w
+}
+
+// Addresses returns the sorted addresses of the ready endpoints of all selected EndpointSlices.
+func (w *EndpointSliceWatcher) Addresses() []string {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	seen := map[string]struct{}{}
+	var addrs []string
+	for _, slices := range w.addrs {
+		for _, as := range slices {
+			for _, a := range as {
+				if _, ok := seen[a]; ok {
+					continue
+				}
+				seen[a] = struct{}{}
+				addrs = append(addrs, a)
+			}
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// Run watches the EndpointSlices of all selectors until ctx is done, calling onUpdate after each change of their
+// addresses.
+func (w *EndpointSliceWatcher) Run(ctx context.Context, onUpdate func()) {
+	var wg sync.WaitGroup
+	for i := range w.selectors {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w.watchSelector(ctx, i, onUpdate)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (w *EndpointSliceWatcher) watchSelector(ctx context.Context, i int, onUpdate func()) {
+	sel := w.selectors[i]
+	b := backoff.New(ctx, watchBackoffConfig)
+	for b.Ongoing() {
+		rv, err := w.list(ctx, i)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.errors.Inc()
+				level.Warn(w.logger).Log("msg", "listing EndpointSlices failed", "selector", sel, "err", err)
+			}
+			b.Wait()
+			continue
+		}
+		onUpdate()
+
+		// Watch from the resource version of the list, until the watch fails.
+		for ctx.Err() == nil {
+			rv, err = w.watch(ctx, i, rv, onUpdate)
+			if err != nil {
+				break
+			}
+			b.Reset()
+		}
+		if err != nil && err != errResourceVersionGone && ctx.Err() == nil {
+			w.errors.Inc()
+			level.Warn(w.logger).Log("msg", "watching EndpointSlices failed", "selector", sel, "err", err)
+			b.Wait()
+		}
+	}
+}
+
+// list replaces the EndpointSlices of the selector with the listed ones, and returns the resource version to watch from.
+func (w *EndpointSliceWatcher) list(ctx context.Context, i int) (_ string, err error) {
+	resp, err := w.get(ctx, i, url.Values{})
+	if err != nil {
+		return "", err
+	}
+	defer runutil.ExhaustCloseWithErrCapture(&err, resp.Body, "close list response")
+
+	var list endpointSliceList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return "", errors.Wrap(err, "decode EndpointSlices")
+	}
+
+	slices := make(map[string][]string, len(list.Items))
+	for _, es := range list.Items {
+		slices[es.Metadata.Name] = es.addresses(w.selectors[i].Port)
+	}
+	w.mtx.Lock()
+	w.addrs[i] = slices
+	w.mtx.Unlock()
+	w.updates.Inc()
+
+	return list.Metadata.ResourceVersion, nil
+}
+
+// watch applies the changes of the EndpointSlices of the selector since the resource version rv until the watch
+// ends, and returns the last seen resource version.
+func (w *EndpointSliceWatcher) watch(ctx context.Context, i int, rv string, onUpdate func()) (_ string, err error) {
+	resp, err := w.get(ctx, i, url.Values{
+		"watch":               []string{"1"},
+		"resourceVersion":     []string{rv},
+		"allowWatchBookmarks": []string{"true"},
+		"timeoutSeconds":      []string{strconv.Itoa(int(watchTimeout.Seconds()))},
+	})
+	if err != nil {
+		return rv, err
+	}
+	// The body is not exhausted, as watches only end with the timeout.
+	defer runutil.CloseWithErrCapture(&err, resp.Body, "close watch response")
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev watchEvent
+		if err := dec.Decode(&ev); err != nil {
+			if ctx.Err() != nil || err == io.EOF {
+				return rv, nil
+			}
+			return rv, errors.Wrap(err, "decode watch event")
+		}
+
+		switch ev.Type {
+		case "ERROR":
+			var st status
+			if err := json.Unmarshal(ev.Object, &st); err == nil && st.Code == http.StatusGone {
+				return rv, errResourceVersionGone
+			}
+			return rv, errors.Errorf("watch error: %s", ev.Object)
+		case "BOOKMARK":
+			var es endpointSlice
+			if err := json.Unmarshal(ev.Object, &es); err != nil {
+				return rv, errors.Wrap(err, "decode bookmark")
+			}
+			rv = es.Metadata.ResourceVersion
+		case "ADDED", "MODIFIED", "DELETED":
+			var es endpointSlice
+			if err := json.Unmarshal(ev.Object, &es); err != nil {
+				return rv, errors.Wrap(err, "decode EndpointSlice")
+			}
+			rv = es.Metadata.ResourceVersion
+
+			w.mtx.Lock()
+			if ev.Type == "DELETED" {
+				delete(w.addrs[i], es.Metadata.Name)
+			} else {
+				w.addrs[i][es.Metadata.Name] = es.addresses(w.selectors[i].Port)
+			}
+			w.mtx.Unlock()
+			w.updates.Inc()
+			onUpdate()
+		}
+	}
+}
+
+func (w *EndpointSliceWatcher) get(ctx context.Context, i int, params url.Values) (*http.Response, error) {
+	sel := w.selectors[i]
+	params.Set("labelSelector", sel.LabelSelector)
+	u := fmt.Sprintf("%s/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices?%s", w.apiServer, url.PathEscape(sel.Namespace), params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create request")
+	}
+	if w.tokenFile != "" {
+		// Tokens of service accounts are rotated, so they are read before each request.
+		token, err := ioutil.ReadFile(w.tokenFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read token")
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "request EndpointSlices")
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer runutil.ExhaustCloseWithLogOnErr(w.logger, resp.Body, "close response")
+		if resp.StatusCode == http.StatusGone {
+			return nil, errResourceVersionGone
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, errors.Errorf("request EndpointSlices: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return resp, nil
+}
+
+type objectMeta struct {
+	Name            string `json:"name"`
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+type endpointSliceList struct {
+	Metadata objectMeta      `json:"metadata"`
+	Items    []endpointSlice `json:"items"`
+}
+
+type endpointSlice struct {
+	Metadata  objectMeta `json:"metadata"`
+	Endpoints []struct {
+		Addresses  []string `json:"addresses"`
+		Conditions struct {
+			Ready *bool `json:"ready"`
+		} `json:"conditions"`
+	} `json:"endpoints"`
+	Ports []struct {
+		Name string `json:"name"`
+		Port *int32 `json:"port"`
+	} `json:"ports"`
+}
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+type status struct {
+	Code int `json:"code"`
+}
+
+// addresses returns the addresses of the ready endpoints of the EndpointSlice, with the given port.
+func (es endpointSlice) addresses(port string) []string {
+	p := port
+	if _, err := strconv.Atoi(port); err != nil {
+		p = ""
+		for _, ep := range es.Ports {
+			if ep.Name == port && ep.Port != nil {
+				p = strconv.Itoa(int(*ep.Port))
+				break
+			}
+		}
+		if p == "" {
+			return nil
+		}
+	}
+
+	var addrs []string
+	for _, e := range es.Endpoints {
+		// Endpoints whose readiness is unknown are considered ready.
+		if e.Conditions.Ready != nil && !*e.Conditions.Ready {
+			continue
+		}
+		for _, a := range e.Addresses {
+			addrs = append(addrs, net.JoinHostPort(a, p))
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,122 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestParseSelector(t *testing.T) {
+	s, err := ParseSelector("monitoring/app.kubernetes.io/name=thanos-store,tier in (a,b):grpc")
+	testutil.Ok(t, err)
+	testutil.Equals(t, Selector{Namespace: "monitoring", LabelSelector: "app.kubernetes.io/name=thanos-store,tier in (a,b)", Port: "grpc"}, s)
+
+	for _, s := range []string{"", "monitoring", "monitoring/app=store", "/app=store:grpc", "monitoring/:grpc", "monitoring/app=store:"} {
+		_, err := ParseSelector(s)
+		testutil.NotOk(t, err, s)
+	}
+}
+
+func endpointSliceJSON(name, rv string, port int, ready map[string]bool) string {
+	eps := ""
+	for addr, r := range ready {
+		if eps != "" {
+			eps += ","
+		}
+		eps += fmt.Sprintf(`{"addresses":[%q],"conditions":{"ready":%t}}`, addr, r)
+	}
+	return fmt.Sprintf(`{"metadata":{"name":%q,"resourceVersion":%q},"endpoints":[%s],"ports":[{"name":"http","port":10902},{"name":"grpc","port":%d}]}`, name, rv, eps, port)
+}
+
+func TestEndpointSliceWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "endpointslice")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+	tokenFile := filepath.Join(dir, "token")
+	testutil.Ok(t, ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600))
+
+	events := make(chan string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutil.Equals(t, "/apis/discovery.k8s.io/v1/namespaces/monitoring/endpointslices", r.URL.Path)
+		testutil.Equals(t, "app=store", r.URL.Query().Get("labelSelector"))
+		testutil.Equals(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		if r.URL.Query().Get("watch") == "" {
+			fmt.Fprintf(w, `{"metadata":{"resourceVersion":"1"},"items":[%s]}`, endpointSliceJSON("store-a", "1", 10901, map[string]bool{"10.0.0.1": true, "10.0.0.2": false}))
+			return
+		}
+		testutil.Equals(t, "1", r.URL.Query().Get("resourceVersion"))
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				fmt.Fprintln(w, ev)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	updates := make(chan struct{}, 10)
+	sel, err := ParseSelector("monitoring/app=store:grpc")
+	testutil.Ok(t, err)
+	watcher := NewEndpointSliceWatcher(log.NewNopLogger(), nil, srv.URL, srv.Client(), tokenFile, []Selector{sel})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watcher.Run(ctx, func() { updates <- struct{}{} })
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitUpdate := func() {
+		select {
+		case <-updates:
+		case <-time.After(10 * time.Second):
+			t.Fatal("no update")
+		}
+	}
+
+	// Only ready endpoints are listed.
+	waitUpdate()
+	testutil.Equals(t, []string{"10.0.0.1:10901"}, watcher.Addresses())
+
+	events <- fmt.Sprintf(`{"type":"ADDED","object":%s}`, endpointSliceJSON("store-b", "2", 10901, map[string]bool{"10.0.0.3": true}))
+	waitUpdate()
+	testutil.Equals(t, []string{"10.0.0.1:10901", "10.0.0.3:10901"}, watcher.Addresses())
+
+	events <- fmt.Sprintf(`{"type":"MODIFIED","object":%s}`, endpointSliceJSON("store-a", "3", 10901, map[string]bool{"10.0.0.1": true, "10.0.0.2": true}))
+	waitUpdate()
+	testutil.Equals(t, []string{"10.0.0.1:10901", "10.0.0.2:10901", "10.0.0.3:10901"}, watcher.Addresses())
+
+	events <- fmt.Sprintf(`{"type":"DELETED","object":%s}`, endpointSliceJSON("store-b", "4", 10901, nil))
+	waitUpdate()
+	testutil.Equals(t, []string{"10.0.0.1:10901", "10.0.0.2:10901"}, watcher.Addresses())
+
+	// Once the resource version is gone, EndpointSlices are listed again.
+	events <- `{"type":"ERROR","object":{"kind":"Status","code":410}}`
+	waitUpdate()
+	testutil.Equals(t, []string{"10.0.0.1:10901"}, watcher.Addresses())
+}
@@ -28,7 +28,8 @@ type UnaryClient interface {
 type GRPCClient struct {
 	proxy exemplarspb.ExemplarsServer
 
-	replicaLabels map[string]struct{}
+	replicaLabels         map[string]struct{}
+	maxExemplarsPerSeries int
 }
 
 type exemplarsServer struct {
@@ -68,9 +69,16 @@ func NewGRPCClient(es exemplarspb.ExemplarsServer) *GRPCClient {
 }
 
 func NewGRPCClientWithDedup(es exemplarspb.ExemplarsServer, replicaLabels []string) *GRPCClient {
+	return NewGRPCClientWithDedupAndLimit(es, replicaLabels, 0)
+}
+
+// NewGRPCClientWithDedupAndLimit returns a client which deduplicates the exemplars by the given replica labels and
+// returns at most maxExemplarsPerSeries of the most recent exemplars of each series, or all of them if it's 0.
+func NewGRPCClientWithDedupAndLimit(es exemplarspb.ExemplarsServer, replicaLabels []string, maxExemplarsPerSeries int) *GRPCClient {
 	c := &GRPCClient{
-		proxy:         es,
-		replicaLabels: map[string]struct{}{},
+		proxy:                 es,
+		replicaLabels:         map[string]struct{}{},
+		maxExemplarsPerSeries: maxExemplarsPerSeries,
 	}
 
 	for _, label := range replicaLabels {
@@ -90,9 +98,29 @@ func (rr *GRPCClient) Exemplars(ctx context.Context, req *exemplarspb.ExemplarsR
 	}
 
 	resp.data = dedupExemplarsResponse(resp.data, rr.replicaLabels)
+	if truncated := limitExemplarsPerSeries(resp.data, rr.maxExemplarsPerSeries); truncated > 0 {
+		resp.warnings = append(resp.warnings, errors.Errorf("exemplars of %d series were limited to the %d most recent ones per series", truncated, rr.maxExemplarsPerSeries))
+	}
 	return resp.data, resp.warnings, nil
 }
 
+// limitExemplarsPerSeries keeps the limit most recent exemplars of each series, which must be sorted by timestamp.
+// It returns the number of series whose exemplars were truncated.
+func limitExemplarsPerSeries(exemplarsData []*exemplarspb.ExemplarData, limit int) int {
+	if limit <= 0 {
+		return 0
+	}
+
+	truncated := 0
+	for _, e := range exemplarsData {
+		if len(e.Exemplars) > limit {
+			e.Exemplars = e.Exemplars[len(e.Exemplars)-limit:]
+			truncated++
+		}
+	}
+	return truncated
+}
+
 func dedupExemplarsResponse(exemplarsData []*exemplarspb.ExemplarData, replicaLabels map[string]struct{}) []*exemplarspb.ExemplarData {
 	if len(exemplarsData) == 0 {
 		return exemplarsData
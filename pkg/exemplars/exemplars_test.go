@@ -227,3 +227,31 @@ func TestDedupExemplarsResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestLimitExemplarsPerSeries(t *testing.T) {
+	exemplars := func(ts ...int64) []*exemplarspb.Exemplar {
+		var res []*exemplarspb.Exemplar
+		for _, t := range ts {
+			res = append(res, &exemplarspb.Exemplar{Value: float64(t), Ts: t})
+		}
+		return res
+	}
+	data := []*exemplarspb.ExemplarData{
+		{SeriesLabels: labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "a", Value: "1"}}}, Exemplars: exemplars(1, 2, 3)},
+		{SeriesLabels: labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "a", Value: "2"}}}, Exemplars: exemplars(4, 5)},
+	}
+
+	testutil.Equals(t, 0, limitExemplarsPerSeries(data, 0))
+	testutil.Equals(t, exemplars(1, 2, 3), data[0].Exemplars)
+
+	testutil.Equals(t, 1, limitExemplarsPerSeries(data, 2))
+	testutil.Equals(t, exemplars(2, 3), data[0].Exemplars)
+	testutil.Equals(t, exemplars(4, 5), data[1].Exemplars)
+}
+
+func TestSupportsPrometheusVersion(t *testing.T) {
+	testutil.Assert(t, !SupportsPrometheusVersion("2.25.2"))
+	testutil.Assert(t, SupportsPrometheusVersion("2.26.0"))
+	testutil.Assert(t, SupportsPrometheusVersion("2.32.1"))
+	testutil.Assert(t, SupportsPrometheusVersion(""))
+}
@@ -18,6 +18,25 @@ import (
 type ExemplarStore struct {
 	ExemplarsClient
 	LabelSets []labels.Labels
+
+	// Name identifies the store, e.g. by its address, in errors and warnings.
+	Name string
+	// MinTime and MaxTime are the time range of the exemplars advertised by the store. Both are zero if the store
+	// doesn't advertise its time range.
+	MinTime, MaxTime int64
+}
+
+// String returns the name of the store.
+func (s *ExemplarStore) String() string {
+	return s.Name
+}
+
+// Overlaps returns false if the store advertises a time range which doesn't overlap with [mint, maxt].
+func (s *ExemplarStore) Overlaps(mint, maxt int64) bool {
+	if s.MinTime == 0 && s.MaxTime == 0 {
+		return true
+	}
+	return s.MinTime <= maxt && mint <= s.MaxTime
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
@@ -6,8 +6,14 @@ package exemplars
 import (
 	"context"
 	"net/url"
+	"strings"
 
+	"github.com/blang/semver/v4"
+	"github.com/gogo/status"
+	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"google.golang.org/grpc/codes"
 
 	"github.com/thanos-io/thanos/pkg/exemplars/exemplarspb"
 	"github.com/thanos-io/thanos/pkg/promclient"
@@ -15,6 +21,19 @@ import (
 	"github.com/thanos-io/thanos/pkg/tracing"
 )
 
+// minPrometheusVersion is the first Prometheus version serving the exemplars API.
+var minPrometheusVersion = semver.MustParse("2.26.0")
+
+// SupportsPrometheusVersion returns false if the given Prometheus version is known to not serve the exemplars API.
+// Unknown versions are assumed to serve it.
+func SupportsPrometheusVersion(version string) bool {
+	v, err := semver.Parse(version)
+	if err != nil {
+		return true
+	}
+	return v.GTE(minPrometheusVersion)
+}
+
 // Prometheus implements exemplarspb.Exemplars gRPC that allows to fetch exemplars from Prometheus.
 type Prometheus struct {
 	base   *url.URL
@@ -34,13 +53,28 @@ func NewPrometheus(base *url.URL, client *promclient.Client, extLabels func() la
 
 // Exemplars returns all specified exemplars from Prometheus.
 func (p *Prometheus) Exemplars(r *exemplarspb.ExemplarsRequest, s exemplarspb.Exemplars_ExemplarsServer) error {
-	exemplars, err := p.client.ExemplarsInGRPC(s.Context(), p.base, r.Query, r.Start, r.End)
+	expr, err := parser.ParseExpr(r.Query)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Prometheus does not know about external labels, so matchers of external labels are checked here and
+	// removed from the query.
+	extLset := p.extLabels()
+	match, selectors := selectorsMatchesExternalLabels(parser.ExtractSelectors(expr), extLset)
+	if !match {
+		return nil
+	}
+	if len(selectors) == 0 {
+		return status.Error(codes.InvalidArgument, errors.New("no matchers specified (excluding external labels)").Error())
+	}
+
+	exemplars, err := p.client.ExemplarsInGRPC(s.Context(), p.base, selectorsToQuery(selectors), r.Start, r.End)
 	if err != nil {
 		return err
 	}
 
 	// Prometheus does not add external labels, so we need to add on our own.
-	extLset := p.extLabels()
 	for _, e := range exemplars {
 		// Make sure the returned series labels are sorted.
 		e.SetSeriesLabels(labelpb.ExtendSortedLabels(e.SeriesLabels.PromLabels(), extLset))
@@ -55,3 +89,17 @@ func (p *Prometheus) Exemplars(r *exemplarspb.ExemplarsRequest, s exemplarspb.Ex
 	}
 	return nil
 }
+
+// selectorsToQuery returns a query with the given selectors. The exemplars API returns the exemplars of all the
+// selectors of the query, so the original query does not need to be preserved.
+func selectorsToQuery(selectors [][]*labels.Matcher) string {
+	qs := make([]string, 0, len(selectors))
+	for _, matchers := range selectors {
+		ms := make([]string, 0, len(matchers))
+		for _, m := range matchers {
+			ms = append(ms, m.String())
+		}
+		qs = append(qs, "{"+strings.Join(ms, ", ")+"}")
+	}
+	return strings.Join(qs, " + ")
+}
@@ -0,0 +1,97 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package exemplars
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/thanos-io/thanos/pkg/exemplars/exemplarspb"
+	"github.com/thanos-io/thanos/pkg/promclient"
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestPrometheus_Exemplars(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutil.Equals(t, "/api/v1/query_exemplars", r.URL.Path)
+		queries = append(queries, r.URL.Query().Get("query"))
+		_, _ = w.Write([]byte(`{"status":"success","data":[{"seriesLabels":{"__name__":"http_requests_total","job":"api"},"exemplars":[{"labels":{"traceID":"abc"},"value":"1","timestamp":1600096945.479}]}]}`))
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	testutil.Ok(t, err)
+	p := NewPrometheus(u, promclient.NewDefaultClient(), func() labels.Labels { return labels.FromStrings("cluster", "eu", "replica", "0") })
+
+	for _, tcase := range []struct {
+		name      string
+		query     string
+		expQuery  string
+		expResult bool
+		expErr    bool
+	}{
+		{
+			name:      "no external label matchers",
+			query:     `rate(http_requests_total{job="api"}[5m])`,
+			expQuery:  `{job="api", __name__="http_requests_total"}`,
+			expResult: true,
+		},
+		{
+			name:      "matching external label matchers are removed",
+			query:     `http_requests_total{cluster=~"eu|us"} / on(job) up{cluster="eu"}`,
+			expQuery:  `{__name__="http_requests_total"} + {__name__="up"}`,
+			expResult: true,
+		},
+		{
+			name:      "selectors with non matching external label matchers are removed",
+			query:     `http_requests_total{cluster="us"} + up{cluster="eu"}`,
+			expQuery:  `{__name__="up"}`,
+			expResult: true,
+		},
+		{
+			name:  "no selector matches external labels",
+			query: `http_requests_total{cluster="us"}`,
+		},
+		{
+			name:   "only external label matchers",
+			query:  `{cluster="eu"}`,
+			expErr: true,
+		},
+		{
+			name:   "invalid query",
+			query:  `up{`,
+			expErr: true,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			queries = nil
+			s := &testExemplarServer{}
+			err := p.Exemplars(&exemplarspb.ExemplarsRequest{Query: tcase.query, Start: 0, End: 1000}, s)
+			if tcase.expErr {
+				testutil.NotOk(t, err)
+				return
+			}
+			testutil.Ok(t, err)
+
+			if !tcase.expResult {
+				testutil.Equals(t, 0, len(queries))
+				testutil.Equals(t, 0, len(s.responses))
+				return
+			}
+			testutil.Equals(t, []string{tcase.expQuery}, queries)
+			testutil.Equals(t, 1, len(s.responses))
+			// External labels are added to the series labels.
+			testutil.Equals(t,
+				labels.FromStrings("__name__", "http_requests_total", "cluster", "eu", "job", "api", "replica", "0"),
+				labelpb.ZLabelsToPromLabels(s.responses[0].GetData().SeriesLabels.Labels),
+			)
+		})
+	}
+}
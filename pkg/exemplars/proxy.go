@@ -47,7 +47,7 @@ func NewProxy(logger log.Logger, exemplars func() []*exemplarspb.ExemplarStore,
 }
 
 type exemplarsStream struct {
-	client  exemplarspb.ExemplarsClient
+	client  *exemplarspb.ExemplarStore
 	request *exemplarspb.ExemplarsRequest
 	channel chan<- *exemplarspb.ExemplarData
 	server  exemplarspb.Exemplars_ExemplarsServer
@@ -80,6 +80,11 @@ func (s *Proxy) Exemplars(req *exemplarspb.ExemplarsRequest, srv exemplarspb.Exe
 	)
 
 	for _, st := range s.exemplars() {
+		if !st.Overlaps(req.Start, req.End) {
+			level.Debug(s.logger).Log("msg", "skipping exemplars store, its time range doesn't overlap with the request", "store", st, "mint", st.MinTime, "maxt", st.MaxTime)
+			continue
+		}
+
 		query := ""
 	Matchers:
 		for _, matchers := range selectors {
@@ -125,8 +130,7 @@ func (s *Proxy) Exemplars(req *exemplarspb.ExemplarsRequest, srv exemplarspb.Exe
 		}
 
 		es := &exemplarsStream{
-
-			client:  st.ExemplarsClient,
+			client:  st,
 			request: r,
 			channel: respChan,
 			server:  srv,
@@ -163,7 +167,7 @@ func (s *Proxy) Exemplars(req *exemplarspb.ExemplarsRequest, srv exemplarspb.Exe
 func (stream *exemplarsStream) receive(ctx context.Context) error {
 	exemplars, err := stream.client.Exemplars(ctx, stream.request)
 	if err != nil {
-		err = errors.Wrapf(err, "fetching exemplars from exemplars client %v", stream.client)
+		err = errors.Wrapf(err, "fetching exemplars from exemplars store %s", stream.client)
 
 		if stream.request.PartialResponseStrategy == storepb.PartialResponseStrategy_ABORT {
 			return err
@@ -183,7 +187,7 @@ func (stream *exemplarsStream) receive(ctx context.Context) error {
 		}
 
 		if err != nil {
-			err = errors.Wrapf(err, "receiving exemplars from exemplars client %v", stream.client)
+			err = errors.Wrapf(err, "receiving exemplars from exemplars store %s", stream.client)
 
 			if stream.request.PartialResponseStrategy == storepb.PartialResponseStrategy_ABORT {
 				return err
@@ -197,7 +201,7 @@ func (stream *exemplarsStream) receive(ctx context.Context) error {
 		}
 
 		if w := exemplar.GetWarning(); w != "" {
-			if err := stream.server.Send(exemplarspb.NewWarningExemplarsResponse(errors.New(w))); err != nil {
+			if err := stream.server.Send(exemplarspb.NewWarningExemplarsResponse(errors.Errorf("exemplars store %s: %s", stream.client, w))); err != nil {
 				return errors.Wrapf(err, "sending exemplars warning to server %v", stream.server)
 			}
 			continue
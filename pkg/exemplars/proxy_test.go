@@ -128,11 +128,97 @@ func TestProxy(t *testing.T) {
 						response: exemplarspb.NewWarningExemplarsResponse(errors.New("warning from client")),
 					},
 					LabelSets: []labels.Labels{labels.FromMap(map[string]string{"cluster": "A"})},
+					Name:      "store-a",
 				},
 			},
 			server: &testExemplarServer{},
 			wantResponses: []*exemplarspb.ExemplarsResponse{
-				exemplarspb.NewWarningExemplarsResponse(errors.New("warning from client")),
+				exemplarspb.NewWarningExemplarsResponse(errors.New("exemplars store store-a: warning from client")),
+			},
+		},
+		{
+			name: "failing store with partial response",
+			request: &exemplarspb.ExemplarsRequest{
+				Query:                   "http_request_duration_bucket",
+				PartialResponseStrategy: storepb.PartialResponseStrategy_WARN,
+			},
+			clients: []*exemplarspb.ExemplarStore{
+				{
+					ExemplarsClient: &testExemplarClient{
+						response: exemplarspb.NewExemplarsResponse(&exemplarspb.ExemplarData{
+							SeriesLabels: labelpb.ZLabelSet{Labels: labelpb.ZLabelsFromPromLabels(labels.FromMap(map[string]string{"__name__": "http_request_duration_bucket"}))},
+							Exemplars:    []*exemplarspb.Exemplar{{Value: 1}},
+						}),
+					},
+					LabelSets: []labels.Labels{labels.FromMap(map[string]string{"cluster": "A"})},
+					Name:      "store-a",
+				},
+				{
+					ExemplarsClient: &testExemplarClient{exemplarErr: errors.New("unavailable")},
+					LabelSets:       []labels.Labels{labels.FromMap(map[string]string{"cluster": "B"})},
+					Name:            "store-b",
+				},
+			},
+			server: &testExemplarServer{},
+			wantResponses: []*exemplarspb.ExemplarsResponse{
+				exemplarspb.NewExemplarsResponse(&exemplarspb.ExemplarData{
+					SeriesLabels: labelpb.ZLabelSet{Labels: labelpb.ZLabelsFromPromLabels(labels.FromMap(map[string]string{"__name__": "http_request_duration_bucket"}))},
+					Exemplars:    []*exemplarspb.Exemplar{{Value: 1}},
+				}),
+				exemplarspb.NewWarningExemplarsResponse(errors.New("fetching exemplars from exemplars store store-b: unavailable")),
+			},
+		},
+		{
+			name: "failing store without partial response",
+			request: &exemplarspb.ExemplarsRequest{
+				Query:                   "http_request_duration_bucket",
+				PartialResponseStrategy: storepb.PartialResponseStrategy_ABORT,
+			},
+			clients: []*exemplarspb.ExemplarStore{
+				{
+					ExemplarsClient: &testExemplarClient{exemplarErr: errors.New("unavailable")},
+					LabelSets:       []labels.Labels{labels.FromMap(map[string]string{"cluster": "B"})},
+					Name:            "store-b",
+				},
+			},
+			server:    &testExemplarServer{},
+			wantError: errors.New("fetching exemplars from exemplars store store-b: unavailable"),
+		},
+		{
+			name: "stores outside of the requested time range are skipped",
+			request: &exemplarspb.ExemplarsRequest{
+				Query:                   "http_request_duration_bucket",
+				Start:                   100,
+				End:                     200,
+				PartialResponseStrategy: storepb.PartialResponseStrategy_ABORT,
+			},
+			clients: []*exemplarspb.ExemplarStore{
+				{
+					ExemplarsClient: &testExemplarClient{
+						response: exemplarspb.NewExemplarsResponse(&exemplarspb.ExemplarData{
+							SeriesLabels: labelpb.ZLabelSet{Labels: labelpb.ZLabelsFromPromLabels(labels.FromMap(map[string]string{"foo": "bar"}))},
+							Exemplars:    []*exemplarspb.Exemplar{{Value: 1}},
+						}),
+					},
+					LabelSets: []labels.Labels{labels.FromMap(map[string]string{"cluster": "A"})},
+					Name:      "store-a",
+					MinTime:   150,
+					MaxTime:   300,
+				},
+				{
+					ExemplarsClient: &testExemplarClient{exemplarErr: errors.New("unavailable")},
+					LabelSets:       []labels.Labels{labels.FromMap(map[string]string{"cluster": "B"})},
+					Name:            "store-b",
+					MinTime:         201,
+					MaxTime:         300,
+				},
+			},
+			server: &testExemplarServer{},
+			wantResponses: []*exemplarspb.ExemplarsResponse{
+				exemplarspb.NewExemplarsResponse(&exemplarspb.ExemplarData{
+					SeriesLabels: labelpb.ZLabelSet{Labels: labelpb.ZLabelsFromPromLabels(labels.FromMap(map[string]string{"foo": "bar"}))},
+					Exemplars:    []*exemplarspb.Exemplar{{Value: 1}},
+				}),
 			},
 		},
 		{
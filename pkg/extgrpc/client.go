@@ -4,6 +4,7 @@
 package extgrpc
 
 import (
+	"context"
 	"math"
 
 	"github.com/go-kit/log"
@@ -11,16 +12,41 @@ import (
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
 
+	"github.com/thanos-io/thanos/pkg/extgrpc/zstd"
+	"github.com/thanos-io/thanos/pkg/tenancy"
 	"github.com/thanos-io/thanos/pkg/tls"
 	"github.com/thanos-io/thanos/pkg/tracing"
 )
 
 // StoreClientGRPCOpts creates gRPC dial options for connecting to a store client.
 func StoreClientGRPCOpts(logger log.Logger, reg *prometheus.Registry, tracer opentracing.Tracer, secure, skipVerify bool, cert, key, caCert, serverName string) ([]grpc.DialOption, error) {
+	dialOpts := storeClientGRPCOpts(reg, tracer)
+	if !secure {
+		return append(dialOpts, grpc.WithInsecure()), nil
+	}
+
+	level.Info(logger).Log("msg", "enabling client to server TLS")
+
+	tlsCfg, err := tls.NewClientConfig(logger, cert, key, caCert, serverName, skipVerify)
+	if err != nil {
+		return nil, err
+	}
+	return append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg))), nil
+}
+
+// StoreClientGRPCOptsWithCredentials creates gRPC dial options for connecting to a store client with the given
+// transport credentials, e.g. the ones of a SPIFFE workload identity.
+func StoreClientGRPCOptsWithCredentials(reg *prometheus.Registry, tracer opentracing.Tracer, creds credentials.TransportCredentials) []grpc.DialOption {
+	return append(storeClientGRPCOpts(reg, tracer), grpc.WithTransportCredentials(creds))
+}
+
+func storeClientGRPCOpts(reg *prometheus.Registry, tracer opentracing.Tracer) []grpc.DialOption {
 	grpcMets := grpc_prometheus.NewClientMetrics()
 	grpcMets.EnableClientHandlingTimeHistogram(
 		grpc_prometheus.WithHistogramBuckets([]float64{0.001, 0.01, 0.1, 0.3, 0.6, 1, 3, 6, 9, 20, 30, 60, 90, 120, 240, 360, 720}),
@@ -35,28 +61,62 @@ func StoreClientGRPCOpts(logger log.Logger, reg *prometheus.Registry, tracer ope
 			grpc_middleware.ChainUnaryClient(
 				grpcMets.UnaryClientInterceptor(),
 				tracing.UnaryClientInterceptor(tracer),
+				tenancy.UnaryClientInterceptor,
 			),
 		),
 		grpc.WithStreamInterceptor(
 			grpc_middleware.ChainStreamClient(
 				grpcMets.StreamClientInterceptor(),
 				tracing.StreamClientInterceptor(tracer),
+				tenancy.StreamClientInterceptor,
 			),
 		),
 	}
 	if reg != nil {
 		reg.MustRegister(grpcMets)
 	}
+	return dialOpts
+}
 
-	if !secure {
-		return append(dialOpts, grpc.WithInsecure()), nil
-	}
+// CompressionNone is the compression option disabling the compression of StoreAPI calls.
+const CompressionNone = "none"
 
-	level.Info(logger).Log("msg", "enabling client to server TLS")
+// CompressionOptions are the compressions of StoreAPI calls.
+var CompressionOptions = []string{CompressionNone, zstd.Name}
 
-	tlsCfg, err := tls.NewClientConfig(logger, cert, key, caCert, serverName, skipVerify)
-	if err != nil {
-		return nil, err
+// compressedStoreMethods are the StoreAPI methods which are compressed, as their responses can be large.
+var compressedStoreMethods = map[string]struct{}{
+	"/thanos.Store/Series":      {},
+	"/thanos.Store/LabelNames":  {},
+	"/thanos.Store/LabelValues": {},
+}
+
+// StoreClientCompressionOpts returns the gRPC dial options compressing the Series, LabelNames and LabelValues calls
+// of a store client with the given compression. Servers compress their responses with the compression of the
+// requests. As the options are chained, the ones given last take precedence, so that CompressionNone can override a
+// compression of the previous options.
+func StoreClientCompressionOpts(compression string) ([]grpc.DialOption, error) {
+	var name string
+	switch compression {
+	case CompressionNone:
+		name = encoding.Identity
+	case zstd.Name:
+		name = compression
+	default:
+		return nil, errors.Errorf("unknown compression %q, expected one of %v", compression, CompressionOptions)
 	}
-	return append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg))), nil
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			if _, ok := compressedStoreMethods[method]; ok {
+				opts = append(opts, grpc.UseCompressor(name))
+			}
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			if _, ok := compressedStoreMethods[method]; ok {
+				opts = append(opts, grpc.UseCompressor(name))
+			}
+			return streamer(ctx, desc, cc, method, opts...)
+		}),
+	}, nil
 }
@@ -0,0 +1,52 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package extgrpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+
+	"github.com/thanos-io/thanos/pkg/extgrpc/zstd"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestStoreClientCompressionOpts(t *testing.T) {
+	_, err := StoreClientCompressionOpts("gzip")
+	testutil.NotOk(t, err)
+
+	// compressor returns the compressor of the call options of the given method.
+	compressor := func(method string, compression string) string {
+		var (
+			opts []grpc.CallOption
+			name string
+		)
+		dialOpts, err := StoreClientCompressionOpts(compression)
+		testutil.Ok(t, err)
+		// The interceptor given last is called after the compression is set.
+		cc, err := grpc.Dial("localhost:0", append(dialOpts, grpc.WithInsecure(), grpc.WithChainUnaryInterceptor(
+			func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+				opts = callOpts
+				return nil
+			},
+		))...)
+		testutil.Ok(t, err)
+		defer cc.Close()
+
+		testutil.Ok(t, cc.Invoke(context.Background(), method, nil, nil))
+		for _, o := range opts {
+			if c, ok := o.(grpc.CompressorCallOption); ok {
+				name = c.CompressorType
+			}
+		}
+		return name
+	}
+
+	testutil.Equals(t, zstd.Name, compressor("/thanos.Store/Series", zstd.Name))
+	testutil.Equals(t, zstd.Name, compressor("/thanos.Store/LabelValues", zstd.Name))
+	testutil.Equals(t, "", compressor("/thanos.Store/Info", zstd.Name))
+	testutil.Equals(t, encoding.Identity, compressor("/thanos.Store/Series", CompressionNone))
+}
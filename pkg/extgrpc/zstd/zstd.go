@@ -0,0 +1,84 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package zstd implements and registers the zstd compressor of gRPC. Importing it registers the compressor, so that
+// servers decompress the requests of clients using it and compress their responses with it.
+package zstd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+)
+
+// Name is the name of the zstd compressor, used as grpc-encoding of messages.
+const Name = "zstd"
+
+func init() {
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	if err != nil {
+		panic(err)
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		panic(err)
+	}
+	encoding.RegisterCompressor(&compressor{enc: enc, dec: dec})
+}
+
+// compressor compresses messages at once, as gRPC passes whole messages to it. EncodeAll and DecodeAll can be called
+// concurrently, so that a single encoder and decoder are shared by all streams.
+type compressor struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+
+	bufPool sync.Pool
+}
+
+func (c *compressor) Name() string {
+	return Name
+}
+
+func (c *compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	buf, ok := c.bufPool.Get().(*bytes.Buffer)
+	if !ok {
+		buf = &bytes.Buffer{}
+	}
+	return &writer{c: c, w: w, buf: buf}, nil
+}
+
+func (c *compressor) Decompress(r io.Reader) (io.Reader, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.dec.DecodeAll(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// writer buffers the message and writes it compressed on Close.
+type writer struct {
+	c   *compressor
+	w   io.Writer
+	buf *bytes.Buffer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *writer) Close() error {
+	defer func() {
+		w.buf.Reset()
+		w.c.bufPool.Put(w.buf)
+	}()
+	_, err := w.w.Write(w.c.enc.EncodeAll(w.buf.Bytes(), nil))
+	return err
+}
@@ -0,0 +1,42 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package zstd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/encoding"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestCompressor(t *testing.T) {
+	c := encoding.GetCompressor(Name)
+	testutil.Assert(t, c != nil, "zstd compressor not registered")
+
+	msg := []byte(strings.Repeat(`{__name__="up", job="thanos"}`, 1000))
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		w, err := c.Compress(&buf)
+		testutil.Ok(t, err)
+		_, err = w.Write(msg[:len(msg)/2])
+		testutil.Ok(t, err)
+		_, err = w.Write(msg[len(msg)/2:])
+		testutil.Ok(t, err)
+		testutil.Ok(t, w.Close())
+		testutil.Assert(t, buf.Len() < len(msg)/10, "message not compressed, %d bytes", buf.Len())
+
+		r, err := c.Decompress(&buf)
+		testutil.Ok(t, err)
+		b, err := ioutil.ReadAll(r)
+		testutil.Ok(t, err)
+		testutil.Equals(t, msg, b)
+	}
+
+	_, err := c.Decompress(bytes.NewReader(msg))
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,271 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package exthttp
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+const (
+	acceptEncodingHeader  = "Accept-Encoding"
+	contentEncodingHeader = "Content-Encoding"
+	contentLengthHeader   = "Content-Length"
+	varyHeader            = "Vary"
+
+	// EncodingZstd is the content encoding of zstd compressed bodies.
+	EncodingZstd = "zstd"
+	// EncodingGzip is the content encoding of gzip compressed bodies.
+	EncodingGzip = "gzip"
+)
+
+// supportedEncodings are the supported content encodings, in order of preference.
+var supportedEncodings = []string{EncodingZstd, EncodingGzip}
+
+var (
+	gzipWriterPool = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+	zstdWriterPool = sync.Pool{New: func() interface{} {
+		// The options are valid, so creating the encoder can't fail.
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return w
+	}}
+)
+
+// NegotiateEncoding returns the supported content encoding with the highest quality in the given Accept-Encoding
+// header, preferring zstd over gzip on ties. It returns an empty string if none is acceptable.
+func NegotiateEncoding(acceptEncoding string) string {
+	var (
+		qualities = map[string]float64{}
+		wildcard  = -1.0
+	)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, q := parseCoding(part)
+		if coding == "" {
+			continue
+		}
+		if coding == "*" {
+			wildcard = q
+			continue
+		}
+		qualities[coding] = q
+	}
+
+	var (
+		best    string
+		bestQ   float64
+		hasBest bool
+	)
+	for _, enc := range supportedEncodings {
+		q, ok := qualities[enc]
+		if !ok {
+			q = wildcard
+		}
+		if q <= 0 {
+			continue
+		}
+		if !hasBest || q > bestQ {
+			best, bestQ, hasBest = enc, q, true
+		}
+	}
+	return best
+}
+
+// parseCoding parses a content coding of an Accept-Encoding header with its quality, which is 1 if not given.
+func parseCoding(s string) (string, float64) {
+	parts := strings.Split(s, ";")
+	coding := strings.ToLower(strings.TrimSpace(parts[0]))
+	q := 1.0
+	for _, p := range parts[1:] {
+		p = strings.TrimSpace(p)
+		if !strings.HasPrefix(p, "q=") {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimPrefix(p, "q="), 64)
+		if err != nil {
+			return "", 0
+		}
+		q = v
+	}
+	return coding, q
+}
+
+// NewCompressionHandler compresses the responses of the given handler with the content encoding negotiated from
+// the Accept-Encoding header of requests, zstd or gzip. Responses which are already encoded, e.g. compressed
+// responses forwarded from downstream servers, are written as they are.
+func NewCompressionHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add(varyHeader, acceptEncodingHeader)
+
+		encoding := NegotiateEncoding(r.Header.Get(acceptEncodingHeader))
+		if encoding == "" || r.Method == http.MethodHead {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressResponseWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		h.ServeHTTP(cw, r)
+	})
+}
+
+// compressResponseWriter compresses the body of a response once its header is written.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding string
+
+	wroteHeader bool
+	w           io.Writer
+	closeFn     func() error
+}
+
+func (c *compressResponseWriter) WriteHeader(code int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+
+	h := c.Header()
+	if h.Get(contentEncodingHeader) == "" && code != http.StatusNoContent && code != http.StatusNotModified {
+		h.Set(contentEncodingHeader, c.encoding)
+		h.Del(contentLengthHeader)
+
+		switch c.encoding {
+		case EncodingZstd:
+			zw := zstdWriterPool.Get().(*zstd.Encoder)
+			zw.Reset(c.ResponseWriter)
+			c.w = zw
+			c.closeFn = func() error {
+				err := zw.Close()
+				zstdWriterPool.Put(zw)
+				return err
+			}
+		case EncodingGzip:
+			gw := gzipWriterPool.Get().(*gzip.Writer)
+			gw.Reset(c.ResponseWriter)
+			c.w = gw
+			c.closeFn = func() error {
+				err := gw.Close()
+				gzipWriterPool.Put(gw)
+				return err
+			}
+		}
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *compressResponseWriter) Write(b []byte) (int, error) {
+	if !c.wroteHeader {
+		if c.Header().Get("Content-Type") == "" {
+			// Detect the content type from the uncompressed body, as the server would.
+			c.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.w == nil {
+		return c.ResponseWriter.Write(b)
+	}
+	return c.w.Write(b)
+}
+
+// Flush flushes the compressed data written so far to the client.
+func (c *compressResponseWriter) Flush() {
+	if f, ok := c.w.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (c *compressResponseWriter) close() {
+	if c.closeFn != nil {
+		_ = c.closeFn()
+	}
+}
+
+// NewCompressionTransport requests compressed responses, zstd or gzip, for requests without an Accept-Encoding
+// header, and transparently decompresses their body. Requests with an Accept-Encoding header, e.g. forwarded from
+// clients, get the response body as encoded by the server.
+func NewCompressionTransport(rt http.RoundTripper) http.RoundTripper {
+	return &compressionTransport{rt: rt}
+}
+
+type compressionTransport struct {
+	rt http.RoundTripper
+}
+
+func (t *compressionTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Header.Get(acceptEncodingHeader) != "" {
+		return t.rt.RoundTrip(r)
+	}
+
+	// RoundTrippers must not modify the request.
+	r = r.Clone(r.Context())
+	r.Header.Set(acceptEncodingHeader, strings.Join(supportedEncodings, ", "))
+
+	resp, err := t.rt.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := strings.ToLower(resp.Header.Get(contentEncodingHeader))
+	if (encoding != EncodingZstd && encoding != EncodingGzip) || r.Method == http.MethodHead || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, nil
+	}
+	resp.Body = &decompressedBody{body: resp.Body, encoding: encoding}
+	resp.Header.Del(contentEncodingHeader)
+	resp.Header.Del(contentLengthHeader)
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// decompressedBody decompresses a response body on first read.
+type decompressedBody struct {
+	body     io.ReadCloser
+	encoding string
+
+	r       io.Reader
+	closeFn func()
+	err     error
+}
+
+func (d *decompressedBody) Read(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	if d.r == nil {
+		switch d.encoding {
+		case EncodingZstd:
+			zr, err := zstd.NewReader(d.body, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				d.err = errors.Wrap(err, "create zstd reader")
+				return 0, d.err
+			}
+			d.r, d.closeFn = zr, zr.Close
+		case EncodingGzip:
+			gr, err := gzip.NewReader(d.body)
+			if err != nil {
+				d.err = errors.Wrap(err, "create gzip reader")
+				return 0, d.err
+			}
+			d.r = gr
+		}
+	}
+	return d.r.Read(p)
+}
+
+func (d *decompressedBody) Close() error {
+	if d.closeFn != nil {
+		d.closeFn()
+	}
+	return d.body.Close()
+}
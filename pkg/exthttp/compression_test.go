@@ -0,0 +1,126 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package exthttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	for _, tcase := range []struct {
+		acceptEncoding string
+		exp            string
+	}{
+		{acceptEncoding: "", exp: ""},
+		{acceptEncoding: "identity", exp: ""},
+		{acceptEncoding: "gzip", exp: EncodingGzip},
+		{acceptEncoding: "gzip, deflate, br", exp: EncodingGzip},
+		{acceptEncoding: "gzip, zstd", exp: EncodingZstd},
+		{acceptEncoding: "ZSTD;q=0.5, gzip;q=0.8", exp: EncodingGzip},
+		{acceptEncoding: "zstd;q=0, gzip", exp: EncodingGzip},
+		{acceptEncoding: "*", exp: EncodingZstd},
+		{acceptEncoding: "*;q=0.5, gzip", exp: EncodingGzip},
+		{acceptEncoding: "gzip;q=0, *;q=0", exp: ""},
+		{acceptEncoding: "gzip;q=invalid", exp: ""},
+	} {
+		t.Run(tcase.acceptEncoding, func(t *testing.T) {
+			testutil.Equals(t, tcase.exp, NegotiateEncoding(tcase.acceptEncoding))
+		})
+	}
+}
+
+func TestCompressionHandlerAndTransport(t *testing.T) {
+	body := strings.Repeat(`{"status":"success","data":{"resultType":"matrix","result":[]}}`, 100)
+	srv := httptest.NewServer(NewCompressionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	})))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewCompressionTransport(NewTransport())}
+
+	t.Run("decompressed transparently", func(t *testing.T) {
+		resp, err := client.Get(srv.URL)
+		testutil.Ok(t, err)
+		defer resp.Body.Close()
+
+		b, err := ioutil.ReadAll(resp.Body)
+		testutil.Ok(t, err)
+		testutil.Equals(t, body, string(b))
+		testutil.Equals(t, "", resp.Header.Get("Content-Encoding"))
+		testutil.Assert(t, resp.Uncompressed, "expected response to be decompressed by the transport")
+	})
+
+	for _, tcase := range []struct {
+		acceptEncoding string
+		decode         func(t *testing.T, b []byte) []byte
+	}{
+		{
+			acceptEncoding: EncodingGzip,
+			decode: func(t *testing.T, b []byte) []byte {
+				r, err := gzip.NewReader(bytes.NewReader(b))
+				testutil.Ok(t, err)
+				d, err := ioutil.ReadAll(r)
+				testutil.Ok(t, err)
+				return d
+			},
+		},
+		{
+			acceptEncoding: EncodingZstd,
+			decode: func(t *testing.T, b []byte) []byte {
+				r, err := zstd.NewReader(nil)
+				testutil.Ok(t, err)
+				defer r.Close()
+				d, err := r.DecodeAll(b, nil)
+				testutil.Ok(t, err)
+				return d
+			},
+		},
+	} {
+		t.Run("forwarded "+tcase.acceptEncoding, func(t *testing.T) {
+			// Requests with an Accept-Encoding header get the compressed body, e.g. to forward it to clients.
+			req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+			testutil.Ok(t, err)
+			req.Header.Set("Accept-Encoding", tcase.acceptEncoding)
+
+			resp, err := client.Do(req)
+			testutil.Ok(t, err)
+			defer resp.Body.Close()
+
+			testutil.Equals(t, tcase.acceptEncoding, resp.Header.Get("Content-Encoding"))
+			testutil.Equals(t, "application/json", resp.Header.Get("Content-Type"))
+			testutil.Equals(t, "Accept-Encoding", resp.Header.Get("Vary"))
+
+			b, err := ioutil.ReadAll(resp.Body)
+			testutil.Ok(t, err)
+			testutil.Assert(t, len(b) < len(body), "expected compressed body")
+			testutil.Equals(t, body, string(tcase.decode(t, b)))
+		})
+	}
+}
+
+func TestCompressionHandlerPassesThroughEncodedResponses(t *testing.T) {
+	h := NewCompressionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", EncodingGzip)
+		_, _ = w.Write([]byte("already compressed"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "zstd, gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	testutil.Equals(t, EncodingGzip, rec.Header().Get("Content-Encoding"))
+	testutil.Equals(t, "already compressed", rec.Body.String())
+}
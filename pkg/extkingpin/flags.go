@@ -99,6 +99,33 @@ func RegisterGRPCFlags(cmd FlagClause) (
 		grpcMaxConnectionAge
 }
 
+// RegisterIPFilterFlags registers flags to pass the configuration of the IP filters restricting the clients of the
+// servers of the component.
+func RegisterIPFilterFlags(cmd FlagClause) *extflag.PathOrContent {
+	return extflag.RegisterPathOrContent(
+		cmd,
+		"ip-filter.config",
+		"YAML file with the CIDRs of the clients allowed or denied to connect to the HTTP and gRPC servers, and of the proxies trusted to send the addresses of clients. See format details: https://thanos.io/tip/operating/ip-filter.md/#configuration ",
+		extflag.WithEnvSubstitution(),
+	)
+}
+
+// RegisterSPIFFEFlags registers flags to obtain the mTLS identity of the gRPC servers and clients of the component from
+// the SPIFFE Workload API.
+func RegisterSPIFFEFlags(cmd FlagClause) (workloadAPIAddr *string, allowedIDs *[]string) {
+	workloadAPIAddr = cmd.Flag("spiffe.workload-api-addr", "[EXPERIMENTAL] Address of the SPIFFE Workload API, e.g. unix:///run/spire/sockets/agent.sock. If set, the gRPC server and clients use the X.509 SVID of the component for mTLS instead of the gRPC TLS certificate flags, and rotate it as the Workload API does. See details: https://thanos.io/tip/operating/spiffe.md ").
+		Default("").String()
+	allowedIDs = cmd.Flag("spiffe.allowed-id", "[EXPERIMENTAL] SPIFFE ID pattern of the peers allowed to connect to the gRPC server, and of the servers the gRPC clients connect to (repeatable). A * matches a path segment, e.g. spiffe://example.org/ns/monitoring/sa/*. If none is set, all peers of the trust domain of the component are allowed.").
+		PlaceHolder("<pattern>").Strings()
+	return workloadAPIAddr, allowedIDs
+}
+
+// RegisterTenantAccountingFlag registers the flag enabling the usage accounting of tenants.
+func RegisterTenantAccountingFlag(cmd FlagClause) *bool {
+	return cmd.Flag("tenant-accounting", "Count the usage of tenants in metrics with the tenant label, e.g. for chargeback: requests and scanned samples of queriers, requests of query frontends, bytes fetched from object storage by store gateways and ingested samples of receivers. See details: https://thanos.io/tip/operating/multi-tenancy.md/#usage-accounting ").
+		Default("false").Bool()
+}
+
 // RegisterCommonObjStoreFlags register flags commonly used to configure http servers with.
 func RegisterHTTPFlags(cmd FlagClause) (httpBindAddr *string, httpGracePeriod *model.Duration, httpTLSConfig *string) {
 	httpBindAddr = cmd.Flag("http-address", "Listen host:port for HTTP endpoints.").Default("0.0.0.0:10902").String()
@@ -110,6 +137,16 @@ func RegisterHTTPFlags(cmd FlagClause) (httpBindAddr *string, httpGracePeriod *m
 	return httpBindAddr, httpGracePeriod, httpTLSConfig
 }
 
+// RegisterHTTPOIDCFlags registers flags to pass the OIDC configuration to authenticate the requests of HTTP endpoints.
+func RegisterHTTPOIDCFlags(cmd FlagClause) *extflag.PathOrContent {
+	return extflag.RegisterPathOrContent(
+		cmd,
+		"http.oidc-config",
+		"YAML file with the OIDC configuration to authenticate the requests of all HTTP endpoints, except the probes, with the bearer tokens of an OIDC provider. See format details: https://thanos.io/tip/operating/https.md/#oidc-authentication ",
+		extflag.WithEnvSubstitution(),
+	)
+}
+
 // RegisterCommonObjStoreFlags register flags to specify object storage configuration.
 func RegisterCommonObjStoreFlags(cmd FlagClause, suffix string, required bool, extraDesc ...string) *extflag.PathOrContent {
 	help := fmt.Sprintf("YAML file that contains object store%s configuration. See format details: https://thanos.io/tip/thanos/storage.md/#configuration ", suffix)
@@ -131,13 +168,32 @@ func RegisterCommonTracingFlags(app FlagClause) *extflag.PathOrContent {
 	)
 }
 
+// RegisterProfilingFlags registers flags to pass a continuous profiling configuration to be used.
+func RegisterProfilingFlags(app FlagClause) *extflag.PathOrContent {
+	return extflag.RegisterPathOrContent(
+		app,
+		"profiling.config",
+		"YAML file with continuous profiling configuration. Profiles are captured periodically and uploaded if set. See format details: https://thanos.io/tip/operating/continuous-profiling.md/",
+		extflag.WithEnvSubstitution(),
+	)
+}
+
+// RegisterAuditFlags registers flags to pass an audit log configuration to be used.
+func RegisterAuditFlags(app FlagClause) *extflag.PathOrContent {
+	return extflag.RegisterPathOrContent(
+		app,
+		"audit.config",
+		"YAML file with audit log configuration. Queries are recorded and the audit log is uploaded to object storage if set. See format details: https://thanos.io/tip/operating/audit-log.md/",
+		extflag.WithEnvSubstitution(),
+	)
+}
+
 // RegisterRequestLoggingFlags registers flags to pass a request logging configuration to be used.
 func RegisterRequestLoggingFlags(app FlagClause) *extflag.PathOrContent {
 	return extflag.RegisterPathOrContent(
 		app,
 		"request.logging-config",
-		// TODO @yashrsharma44: Change the link with the documented link for yaml configuration.
-		"YAML file with request logging configuration. See format details: https://gist.github.com/yashrsharma44/02f5765c5710dd09ce5d14e854f22825",
+		"YAML file with request logging configuration. See format details: https://thanos.io/tip/operating/request-logging.md/",
 		extflag.WithEnvSubstitution(),
 	)
 }
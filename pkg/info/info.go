@@ -25,6 +25,8 @@ type InfoServer struct {
 	getRulesInfo          func() *infopb.RulesInfo
 	getTargetsInfo        func() *infopb.TargetsInfo
 	getMetricMetadataInfo func() *infopb.MetricMetadataInfo
+	getTSDBStatusInfo     func() *infopb.TSDBStatusInfo
+	getAdminInfo          func() *infopb.AdminInfo
 }
 
 // NewInfoServer creates a new server instance for given component
@@ -137,6 +139,36 @@ func WithMetricMetadataInfoFunc(getMetricMetadataInfo ...func() *infopb.MetricMe
 	}
 }
 
+// WithTSDBStatusInfoFunc determines the function that should be executed to obtain
+// the TSDB status information. If no function is provided, the default empty
+// TSDB status info is returned. Only the first function from the list is considered.
+func WithTSDBStatusInfoFunc(getTSDBStatusInfo ...func() *infopb.TSDBStatusInfo) ServerOptionFunc {
+	if len(getTSDBStatusInfo) == 0 {
+		return func(s *InfoServer) {
+			s.getTSDBStatusInfo = func() *infopb.TSDBStatusInfo { return &infopb.TSDBStatusInfo{} }
+		}
+	}
+
+	return func(s *InfoServer) {
+		s.getTSDBStatusInfo = getTSDBStatusInfo[0]
+	}
+}
+
+// WithAdminInfoFunc determines the function that should be executed to obtain
+// the admin information. If no function is provided, the default empty
+// admin info is returned. Only the first function from the list is considered.
+func WithAdminInfoFunc(getAdminInfo ...func() *infopb.AdminInfo) ServerOptionFunc {
+	if len(getAdminInfo) == 0 {
+		return func(s *InfoServer) {
+			s.getAdminInfo = func() *infopb.AdminInfo { return &infopb.AdminInfo{} }
+		}
+	}
+
+	return func(s *InfoServer) {
+		s.getAdminInfo = getAdminInfo[0]
+	}
+}
+
 // RegisterInfoServer registers the info server.
 func RegisterInfoServer(infoSrv infopb.InfoServer) func(*grpc.Server) {
 	return func(s *grpc.Server) {
@@ -170,6 +202,14 @@ func (srv *InfoServer) Info(ctx context.Context, req *infopb.InfoRequest) (*info
 		srv.getMetricMetadataInfo = func() *infopb.MetricMetadataInfo { return nil }
 	}
 
+	if srv.getTSDBStatusInfo == nil {
+		srv.getTSDBStatusInfo = func() *infopb.TSDBStatusInfo { return nil }
+	}
+
+	if srv.getAdminInfo == nil {
+		srv.getAdminInfo = func() *infopb.AdminInfo { return nil }
+	}
+
 	resp := &infopb.InfoResponse{
 		LabelSets:      srv.getLabelSet(),
 		ComponentType:  srv.component,
@@ -178,6 +218,8 @@ func (srv *InfoServer) Info(ctx context.Context, req *infopb.InfoRequest) (*info
 		Rules:          srv.getRulesInfo(),
 		Targets:        srv.getTargetsInfo(),
 		MetricMetadata: srv.getMetricMetadataInfo(),
+		TsdbStatus:     srv.getTSDBStatusInfo(),
+		Admin:          srv.getAdminInfo(),
 	}
 
 	return resp, nil
@@ -78,6 +78,11 @@ type InfoResponse struct {
 	Targets *TargetsInfo `protobuf:"bytes,6,opt,name=targets,proto3" json:"targets,omitempty"`
 	// ExemplarsInfo holds the metadata related to Exemplars API if exposed by the component otherwise it will be null.
 	Exemplars *ExemplarsInfo `protobuf:"bytes,7,opt,name=exemplars,proto3" json:"exemplars,omitempty"`
+	// TSDBStatusInfo holds the metadata related to TSDB status API if exposed by the component otherwise it will be null.
+	TsdbStatus *TSDBStatusInfo `protobuf:"bytes,8,opt,name=tsdb_status,json=tsdbStatus,proto3" json:"tsdb_status,omitempty"`
+
+	// AdminInfo holds the metadata related to Admin API if exposed by the component otherwise it will be null.
+	Admin *AdminInfo `protobuf:"bytes,9,opt,name=admin,proto3" json:"admin,omitempty"`
 }
 
 func (m *InfoResponse) Reset()         { *m = InfoResponse{} }
@@ -117,6 +122,11 @@ var xxx_messageInfo_InfoResponse proto.InternalMessageInfo
 type StoreInfo struct {
 	MinTime int64 `protobuf:"varint,1,opt,name=min_time,json=minTime,proto3" json:"min_time,omitempty"`
 	MaxTime int64 `protobuf:"varint,2,opt,name=max_time,json=maxTime,proto3" json:"max_time,omitempty"`
+	// supports_series_batches is true if the store can send the series of Series calls in SeriesBatch frames.
+	SupportsSeriesBatches bool `protobuf:"varint,3,opt,name=supports_series_batches,json=supportsSeriesBatches,proto3" json:"supports_series_batches,omitempty"`
+	// tsdb_infos hold the time ranges of the data of the store per resolution. Stores without them only have raw
+	// data between min_time and max_time.
+	TsdbInfos []TSDBInfo `protobuf:"bytes,4,rep,name=tsdb_infos,json=tsdbInfos,proto3" json:"tsdb_infos"`
 }
 
 func (m *StoreInfo) Reset()         { *m = StoreInfo{} }
@@ -302,6 +312,120 @@ func (m *ExemplarsInfo) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_ExemplarsInfo proto.InternalMessageInfo
 
+// TSDBStatusInfo holds the metadata related to TSDB status API exposed by the component.
+type TSDBStatusInfo struct {
+}
+
+func (m *TSDBStatusInfo) Reset()         { *m = TSDBStatusInfo{} }
+func (m *TSDBStatusInfo) String() string { return proto.CompactTextString(m) }
+func (*TSDBStatusInfo) ProtoMessage()    {}
+func (*TSDBStatusInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a1214ec45d2bf952, []int{7}
+}
+func (m *TSDBStatusInfo) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *TSDBStatusInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_TSDBStatusInfo.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *TSDBStatusInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TSDBStatusInfo.Merge(m, src)
+}
+func (m *TSDBStatusInfo) XXX_Size() int {
+	return m.Size()
+}
+func (m *TSDBStatusInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_TSDBStatusInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TSDBStatusInfo proto.InternalMessageInfo
+
+// AdminInfo holds the metadata related to Admin API exposed by the component.
+type AdminInfo struct {
+}
+
+func (m *AdminInfo) Reset()         { *m = AdminInfo{} }
+func (m *AdminInfo) String() string { return proto.CompactTextString(m) }
+func (*AdminInfo) ProtoMessage()    {}
+func (*AdminInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a1214ec45d2bf952, []int{8}
+}
+func (m *AdminInfo) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *AdminInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_AdminInfo.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *AdminInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AdminInfo.Merge(m, src)
+}
+func (m *AdminInfo) XXX_Size() int {
+	return m.Size()
+}
+func (m *AdminInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_AdminInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AdminInfo proto.InternalMessageInfo
+
+// TSDBInfo holds the time range of data of a resolution exposed by a store.
+type TSDBInfo struct {
+	MinTime    int64 `protobuf:"varint,1,opt,name=min_time,json=minTime,proto3" json:"min_time,omitempty"`
+	MaxTime    int64 `protobuf:"varint,2,opt,name=max_time,json=maxTime,proto3" json:"max_time,omitempty"`
+	Resolution int64 `protobuf:"varint,3,opt,name=resolution,proto3" json:"resolution,omitempty"`
+}
+
+func (m *TSDBInfo) Reset()         { *m = TSDBInfo{} }
+func (m *TSDBInfo) String() string { return proto.CompactTextString(m) }
+func (*TSDBInfo) ProtoMessage()    {}
+func (*TSDBInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a1214ec45d2bf952, []int{9}
+}
+func (m *TSDBInfo) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *TSDBInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_TSDBInfo.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *TSDBInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TSDBInfo.Merge(m, src)
+}
+func (m *TSDBInfo) XXX_Size() int {
+	return m.Size()
+}
+func (m *TSDBInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_TSDBInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TSDBInfo proto.InternalMessageInfo
+
 func init() {
 	proto.RegisterType((*InfoRequest)(nil), "thanos.info.InfoRequest")
 	proto.RegisterType((*InfoResponse)(nil), "thanos.info.InfoResponse")
@@ -310,40 +434,51 @@ func init() {
 	proto.RegisterType((*MetricMetadataInfo)(nil), "thanos.info.MetricMetadataInfo")
 	proto.RegisterType((*TargetsInfo)(nil), "thanos.info.TargetsInfo")
 	proto.RegisterType((*ExemplarsInfo)(nil), "thanos.info.ExemplarsInfo")
+	proto.RegisterType((*TSDBStatusInfo)(nil), "thanos.info.TSDBStatusInfo")
+	proto.RegisterType((*AdminInfo)(nil), "thanos.info.AdminInfo")
+	proto.RegisterType((*TSDBInfo)(nil), "thanos.info.TSDBInfo")
 }
 
 func init() { proto.RegisterFile("info/infopb/rpc.proto", fileDescriptor_a1214ec45d2bf952) }
 
 var fileDescriptor_a1214ec45d2bf952 = []byte{
-	// 437 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x93, 0xcf, 0x6e, 0xd3, 0x40,
-	0x10, 0xc6, 0xed, 0x26, 0x4d, 0xf0, 0x98, 0x80, 0x58, 0x15, 0xb4, 0xc9, 0xc1, 0x8d, 0xac, 0x1e,
-	0x72, 0x40, 0xb6, 0x14, 0x24, 0x84, 0xc4, 0x89, 0x56, 0x95, 0x40, 0xa2, 0x17, 0x37, 0xa7, 0x5e,
-	0xa2, 0x4d, 0x99, 0x06, 0x4b, 0xde, 0x3f, 0x78, 0xb7, 0x52, 0x7a, 0xe3, 0x11, 0x78, 0xac, 0x1c,
-	0x7b, 0xe4, 0x84, 0x20, 0x79, 0x11, 0xb4, 0xbb, 0x6e, 0x89, 0x45, 0x4f, 0xbd, 0xd8, 0xbb, 0xfb,
-	0xfb, 0xbe, 0xd9, 0x99, 0xf1, 0x18, 0x5e, 0x96, 0xe2, 0x4a, 0xe6, 0xf6, 0xa1, 0x16, 0x79, 0xad,
-	0x2e, 0x33, 0x55, 0x4b, 0x23, 0x49, 0x6c, 0xbe, 0x32, 0x21, 0x75, 0x66, 0xc1, 0x68, 0xa8, 0x8d,
-	0xac, 0x31, 0xaf, 0xd8, 0x02, 0x2b, 0xb5, 0xc8, 0xcd, 0x8d, 0x42, 0xed, 0x75, 0xa3, 0x83, 0xa5,
-	0x5c, 0x4a, 0xb7, 0xcc, 0xed, 0xca, 0x9f, 0xa6, 0x03, 0x88, 0x3f, 0x89, 0x2b, 0x59, 0xe0, 0xb7,
-	0x6b, 0xd4, 0x26, 0xfd, 0xde, 0x81, 0xa7, 0x7e, 0xaf, 0x95, 0x14, 0x1a, 0xc9, 0x5b, 0x00, 0x17,
-	0x6c, 0xae, 0xd1, 0x68, 0x1a, 0x8e, 0x3b, 0x93, 0x78, 0xfa, 0x22, 0x6b, 0xae, 0xbc, 0xf8, 0x6c,
-	0xd1, 0x39, 0x9a, 0xe3, 0xee, 0xfa, 0xd7, 0x61, 0x50, 0x44, 0x55, 0xb3, 0xd7, 0xe4, 0x08, 0x06,
-	0x27, 0x92, 0x2b, 0x29, 0x50, 0x98, 0xd9, 0x8d, 0x42, 0xba, 0x37, 0x0e, 0x27, 0x51, 0xd1, 0x3e,
-	0x24, 0xaf, 0x61, 0xdf, 0x25, 0x4c, 0x3b, 0xe3, 0x70, 0x12, 0x4f, 0x5f, 0x65, 0x3b, 0xb5, 0x64,
-	0xe7, 0x96, 0xb8, 0x64, 0xbc, 0xc8, 0xaa, 0xeb, 0xeb, 0x0a, 0x35, 0xed, 0x3e, 0xa0, 0x2e, 0x2c,
-	0xf1, 0x6a, 0x27, 0x22, 0x1f, 0xe1, 0x39, 0x47, 0x53, 0x97, 0x97, 0x73, 0x8e, 0x86, 0x7d, 0x61,
-	0x86, 0xd1, 0x7d, 0xe7, 0x3b, 0x6c, 0xf9, 0xce, 0x9c, 0xe6, 0xac, 0x91, 0xb8, 0x00, 0xcf, 0x78,
-	0xeb, 0x8c, 0x4c, 0xa1, 0x6f, 0x58, 0xbd, 0xb4, 0x0d, 0xe8, 0xb9, 0x08, 0xb4, 0x15, 0x61, 0xe6,
-	0x99, 0xb3, 0xde, 0x09, 0xc9, 0x3b, 0x88, 0x70, 0x85, 0x5c, 0x55, 0xac, 0xd6, 0xb4, 0xef, 0x5c,
-	0xa3, 0x96, 0xeb, 0xf4, 0x8e, 0x3a, 0xdf, 0x3f, 0x71, 0xfa, 0x01, 0xa2, 0xfb, 0xca, 0xc9, 0x10,
-	0x9e, 0xf0, 0x52, 0xcc, 0x4d, 0xc9, 0x91, 0x86, 0xe3, 0x70, 0xd2, 0x29, 0xfa, 0xbc, 0x14, 0xb3,
-	0x92, 0xa3, 0x43, 0x6c, 0xe5, 0xd1, 0x5e, 0x83, 0xd8, 0xca, 0xa2, 0x34, 0x86, 0xe8, 0xbe, 0x1d,
-	0xe9, 0x01, 0x90, 0xff, 0x6b, 0xb4, 0xdf, 0x7d, 0x27, 0xef, 0xf4, 0x14, 0x06, 0xad, 0x84, 0x1e,
-	0x77, 0xf1, 0xf4, 0x04, 0xba, 0xce, 0xfd, 0xbe, 0x79, 0xb7, 0x1b, 0xb5, 0x33, 0x68, 0xa3, 0xe1,
-	0x03, 0xc4, 0x8f, 0xdc, 0xf1, 0xd1, 0xfa, 0x4f, 0x12, 0xac, 0x37, 0x49, 0x78, 0xbb, 0x49, 0xc2,
-	0xdf, 0x9b, 0x24, 0xfc, 0xb1, 0x4d, 0x82, 0xdb, 0x6d, 0x12, 0xfc, 0xdc, 0x26, 0xc1, 0x45, 0xcf,
-	0xff, 0x00, 0x8b, 0x9e, 0x9b, 0xdf, 0x37, 0x7f, 0x03, 0x00, 0x00, 0xff, 0xff, 0xc9, 0x9c, 0xd8,
-	0x20, 0x16, 0x03, 0x00, 0x00,
+	// 571 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x94, 0xc1, 0x6e, 0xda, 0x4c,
+	0x10, 0xc7, 0x71, 0x48, 0x00, 0x8f, 0x3f, 0xf2, 0xb5, 0xab, 0xd0, 0x1a, 0x2a, 0x39, 0xc8, 0xca,
+	0x81, 0x43, 0x05, 0x12, 0x95, 0xa2, 0xaa, 0xed, 0xa5, 0xa4, 0x91, 0x5a, 0xa9, 0xb9, 0x18, 0x4e,
+	0xb9, 0xb8, 0x6b, 0xd8, 0x10, 0x4b, 0xb6, 0x77, 0xeb, 0x5d, 0x24, 0xf2, 0x16, 0x7d, 0x99, 0xbe,
+	0x03, 0xc7, 0x1c, 0x7b, 0xaa, 0x5a, 0xb8, 0xf6, 0x21, 0xaa, 0x9d, 0x05, 0x8a, 0x15, 0x4e, 0xb9,
+	0x80, 0x77, 0x7f, 0xff, 0x19, 0xcf, 0xfe, 0x67, 0xbc, 0xd0, 0x88, 0xb3, 0x1b, 0xde, 0xd3, 0x3f,
+	0x22, 0xea, 0xe5, 0x62, 0xdc, 0x15, 0x39, 0x57, 0x9c, 0x38, 0xea, 0x96, 0x66, 0x5c, 0x76, 0x35,
+	0x68, 0x35, 0xa5, 0xe2, 0x39, 0xeb, 0x25, 0x34, 0x62, 0x89, 0x88, 0x7a, 0xea, 0x4e, 0x30, 0x69,
+	0x74, 0xad, 0x93, 0x29, 0x9f, 0x72, 0x7c, 0xec, 0xe9, 0x27, 0xb3, 0xeb, 0xd7, 0xc1, 0xf9, 0x94,
+	0xdd, 0xf0, 0x80, 0x7d, 0x9d, 0x31, 0xa9, 0xfc, 0x3f, 0x65, 0xf8, 0xcf, 0xac, 0xa5, 0xe0, 0x99,
+	0x64, 0xe4, 0x1c, 0x00, 0x93, 0x85, 0x92, 0x29, 0xe9, 0x5a, 0xed, 0x72, 0xc7, 0xe9, 0x3f, 0xed,
+	0xae, 0x5f, 0x79, 0xfd, 0x59, 0xa3, 0x21, 0x53, 0x83, 0xc3, 0xc5, 0xcf, 0xd3, 0x52, 0x60, 0x27,
+	0xeb, 0xb5, 0x24, 0x67, 0x50, 0xbf, 0xe0, 0xa9, 0xe0, 0x19, 0xcb, 0xd4, 0xe8, 0x4e, 0x30, 0xf7,
+	0xa0, 0x6d, 0x75, 0xec, 0xa0, 0xb8, 0x49, 0x5e, 0xc2, 0x11, 0x16, 0xec, 0x96, 0xdb, 0x56, 0xc7,
+	0xe9, 0x3f, 0xeb, 0xee, 0x9c, 0xa5, 0x3b, 0xd4, 0x04, 0x8b, 0x31, 0x22, 0xad, 0xce, 0x67, 0x09,
+	0x93, 0xee, 0xe1, 0x1e, 0x75, 0xa0, 0x89, 0x51, 0xa3, 0x88, 0x7c, 0x84, 0xff, 0x53, 0xa6, 0xf2,
+	0x78, 0x1c, 0xa6, 0x4c, 0xd1, 0x09, 0x55, 0xd4, 0x3d, 0xc2, 0xb8, 0xd3, 0x42, 0xdc, 0x15, 0x6a,
+	0xae, 0xd6, 0x12, 0x4c, 0x70, 0x9c, 0x16, 0xf6, 0x48, 0x1f, 0xaa, 0x8a, 0xe6, 0x53, 0x6d, 0x40,
+	0x05, 0x33, 0xb8, 0x85, 0x0c, 0x23, 0xc3, 0x30, 0x74, 0x23, 0x24, 0xaf, 0xc1, 0x66, 0x73, 0x96,
+	0x8a, 0x84, 0xe6, 0xd2, 0xad, 0x62, 0x54, 0xab, 0x10, 0x75, 0xb9, 0xa1, 0x18, 0xf7, 0x4f, 0x4c,
+	0xde, 0x81, 0xa3, 0xe4, 0x24, 0x0a, 0xa5, 0xa2, 0x6a, 0x26, 0xdd, 0x1a, 0xc6, 0xbe, 0x28, 0xbe,
+	0x71, 0xf8, 0x61, 0x30, 0x44, 0x8c, 0xc1, 0xa0, 0xf5, 0x66, 0xad, 0x3d, 0xa2, 0x93, 0x34, 0xce,
+	0x5c, 0x7b, 0x8f, 0x47, 0xef, 0x35, 0x31, 0x1e, 0xa1, 0xc8, 0xff, 0x6e, 0x81, 0xbd, 0xb5, 0x99,
+	0x34, 0xa1, 0x96, 0xc6, 0x59, 0xa8, 0xe2, 0x94, 0xb9, 0x56, 0xdb, 0xea, 0x94, 0x83, 0x6a, 0x1a,
+	0x67, 0xa3, 0x38, 0x65, 0x88, 0xe8, 0xdc, 0xa0, 0x83, 0x35, 0xa2, 0x73, 0x44, 0xe7, 0xf0, 0x5c,
+	0xce, 0x84, 0xe0, 0xb9, 0x92, 0xa1, 0x64, 0x79, 0xcc, 0x64, 0x18, 0x51, 0x35, 0xbe, 0x65, 0x12,
+	0xbb, 0x5a, 0x0b, 0x1a, 0x1b, 0x3c, 0x44, 0x3a, 0x30, 0x90, 0xbc, 0x01, 0xac, 0x3b, 0xd4, 0x95,
+	0xe9, 0x96, 0xea, 0xc9, 0x6a, 0x3c, 0x38, 0xa6, 0x2e, 0x6c, 0x33, 0x5d, 0x5a, 0xae, 0xd7, 0xd2,
+	0x77, 0xc0, 0xde, 0xf6, 0xdb, 0x3f, 0x01, 0xf2, 0xb0, 0x89, 0x7a, 0xb0, 0x77, 0x1a, 0xe3, 0x5f,
+	0x42, 0xbd, 0xe0, 0xf8, 0xe3, 0x0e, 0xeb, 0x3f, 0x81, 0xe3, 0xa2, 0xf9, 0xba, 0x94, 0xad, 0xad,
+	0xfe, 0x17, 0xa8, 0x6d, 0x8a, 0x7e, 0xa4, 0x9b, 0x1e, 0x40, 0xce, 0x24, 0x4f, 0x66, 0x2a, 0xe6,
+	0x19, 0x1a, 0x58, 0x0e, 0x76, 0x76, 0xfa, 0x17, 0x70, 0x88, 0xd9, 0xdf, 0xae, 0xff, 0x8b, 0xa3,
+	0xb8, 0xf3, 0x29, 0xb7, 0x9a, 0x7b, 0x88, 0xf9, 0xa8, 0x07, 0x67, 0x8b, 0xdf, 0x5e, 0x69, 0xb1,
+	0xf4, 0xac, 0xfb, 0xa5, 0x67, 0xfd, 0x5a, 0x7a, 0xd6, 0xb7, 0x95, 0x57, 0xba, 0x5f, 0x79, 0xa5,
+	0x1f, 0x2b, 0xaf, 0x74, 0x5d, 0x31, 0x57, 0x4c, 0x54, 0xc1, 0x1b, 0xe2, 0xd5, 0xdf, 0x00, 0x00,
+	0x00, 0xff, 0xff, 0x11, 0xb6, 0xde, 0x27, 0x78, 0x04, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -471,6 +606,30 @@ func (m *InfoResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Admin != nil {
+		{
+			size, err := m.Admin.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRpc(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x4a
+	}
+	if m.TsdbStatus != nil {
+		{
+			size, err := m.TsdbStatus.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRpc(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x42
+	}
 	if m.Exemplars != nil {
 		{
 			size, err := m.Exemplars.MarshalToSizedBuffer(dAtA[:i])
@@ -575,6 +734,30 @@ func (m *StoreInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.TsdbInfos) > 0 {
+		for iNdEx := len(m.TsdbInfos) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.TsdbInfos[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintRpc(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if m.SupportsSeriesBatches {
+		i--
+		if m.SupportsSeriesBatches {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x18
+	}
 	if m.MaxTime != 0 {
 		i = encodeVarintRpc(dAtA, i, uint64(m.MaxTime))
 		i--
@@ -690,6 +873,90 @@ func (m *ExemplarsInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *TSDBStatusInfo) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TSDBStatusInfo) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TSDBStatusInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	return len(dAtA) - i, nil
+}
+
+func (m *AdminInfo) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *AdminInfo) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *AdminInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	return len(dAtA) - i, nil
+}
+
+func (m *TSDBInfo) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TSDBInfo) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TSDBInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Resolution != 0 {
+		i = encodeVarintRpc(dAtA, i, uint64(m.Resolution))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.MaxTime != 0 {
+		i = encodeVarintRpc(dAtA, i, uint64(m.MaxTime))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.MinTime != 0 {
+		i = encodeVarintRpc(dAtA, i, uint64(m.MinTime))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintRpc(dAtA []byte, offset int, v uint64) int {
 	offset -= sovRpc(v)
 	base := offset
@@ -746,6 +1013,14 @@ func (m *InfoResponse) Size() (n int) {
 		l = m.Exemplars.Size()
 		n += 1 + l + sovRpc(uint64(l))
 	}
+	if m.TsdbStatus != nil {
+		l = m.TsdbStatus.Size()
+		n += 1 + l + sovRpc(uint64(l))
+	}
+	if m.Admin != nil {
+		l = m.Admin.Size()
+		n += 1 + l + sovRpc(uint64(l))
+	}
 	return n
 }
 
@@ -761,6 +1036,15 @@ func (m *StoreInfo) Size() (n int) {
 	if m.MaxTime != 0 {
 		n += 1 + sovRpc(uint64(m.MaxTime))
 	}
+	if m.SupportsSeriesBatches {
+		n += 2
+	}
+	if len(m.TsdbInfos) > 0 {
+		for _, e := range m.TsdbInfos {
+			l = e.Size()
+			n += 1 + l + sovRpc(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -806,6 +1090,42 @@ func (m *ExemplarsInfo) Size() (n int) {
 	return n
 }
 
+func (m *TSDBStatusInfo) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
+}
+
+func (m *AdminInfo) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
+}
+
+func (m *TSDBInfo) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.MinTime != 0 {
+		n += 1 + sovRpc(uint64(m.MinTime))
+	}
+	if m.MaxTime != 0 {
+		n += 1 + sovRpc(uint64(m.MaxTime))
+	}
+	if m.Resolution != 0 {
+		n += 1 + sovRpc(uint64(m.Resolution))
+	}
+	return n
+}
+
 func sovRpc(x uint64) (n int) {
 	return (math_bits.Len64(x|1) + 6) / 7
 }
@@ -1137,6 +1457,78 @@ func (m *InfoResponse) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TsdbStatus", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.TsdbStatus == nil {
+				m.TsdbStatus = &TSDBStatusInfo{}
+			}
+			if err := m.TsdbStatus.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Admin", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Admin == nil {
+				m.Admin = &AdminInfo{}
+			}
+			if err := m.Admin.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -1225,6 +1617,60 @@ func (m *StoreInfo) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SupportsSeriesBatches", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SupportsSeriesBatches = bool(v != 0)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TsdbInfos", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.TsdbInfos = append(m.TsdbInfos, TSDBInfo{})
+			if err := m.TsdbInfos[len(m.TsdbInfos)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -1484,6 +1930,213 @@ func (m *ExemplarsInfo) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *TSDBStatusInfo) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRpc
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TSDBStatusInfo: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TSDBStatusInfo: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRpc(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *AdminInfo) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRpc
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AdminInfo: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AdminInfo: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRpc(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TSDBInfo) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRpc
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TSDBInfo: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TSDBInfo: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MinTime", wireType)
+			}
+			m.MinTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MinTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxTime", wireType)
+			}
+			m.MaxTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Resolution", wireType)
+			}
+			m.Resolution = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Resolution |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRpc(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func skipRpc(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0
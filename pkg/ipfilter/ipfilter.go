@@ -0,0 +1,206 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package ipfilter restricts the clients of servers by their IP address, taking proxies in front of the servers,
+// which send the address of the client with the PROXY protocol or in the X-Forwarded-For header, into account.
+package ipfilter
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the configuration of the IP filters of the servers of a component.
+type Config struct {
+	// HTTP are the rules of the HTTP server.
+	HTTP *Rules `yaml:"http"`
+	// GRPC are the rules of the gRPC server.
+	GRPC *Rules `yaml:"grpc"`
+	// RemoteWrite are the rules of the remote write server of Receivers.
+	RemoteWrite *Rules `yaml:"remote_write"`
+}
+
+// Rules are the rules of the IP filter of a server.
+type Rules struct {
+	// Allow are the CIDRs of the allowed clients. All clients are allowed if empty.
+	Allow []string `yaml:"allow"`
+	// Deny are the CIDRs of the denied clients. Deny takes precedence over Allow.
+	Deny []string `yaml:"deny"`
+	// TrustedProxies are the CIDRs of the proxies which are trusted to send the address of the client, i.e. whose
+	// PROXY protocol headers and X-Forwarded-For headers are used.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// ProxyProtocol enables the PROXY protocol, version 1 and 2, on connections of trusted proxies.
+	ProxyProtocol bool `yaml:"proxy_protocol"`
+}
+
+// ParseConfig parses the YAML configuration of the IP filters.
+func ParseConfig(content []byte) (Config, error) {
+	var conf Config
+	if err := yaml.UnmarshalStrict(content, &conf); err != nil {
+		return Config{}, errors.Wrap(err, "parsing YAML content of IP filter config")
+	}
+	// gRPC requests have no X-Forwarded-For header, so the clients of trusted proxies can only be checked with the
+	// PROXY protocol.
+	if conf.GRPC != nil && len(conf.GRPC.TrustedProxies) > 0 && !conf.GRPC.ProxyProtocol {
+		return Config{}, errors.New("trusted_proxies of the grpc server require proxy_protocol")
+	}
+	return conf, nil
+}
+
+// Filter allows or denies clients by their IP address.
+type Filter struct {
+	logger        log.Logger
+	allow         []*net.IPNet
+	deny          []*net.IPNet
+	trusted       []*net.IPNet
+	proxyProtocol bool
+
+	rejectedConns    prometheus.Counter
+	rejectedRequests prometheus.Counter
+}
+
+// NewFilter returns the Filter with the given rules. It returns nil if rules is nil.
+func NewFilter(logger log.Logger, reg prometheus.Registerer, rules *Rules) (*Filter, error) {
+	if rules == nil {
+		return nil, nil
+	}
+	f := &Filter{
+		logger:        logger,
+		proxyProtocol: rules.ProxyProtocol,
+		rejectedConns: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_ip_filter_rejected_connections_total",
+			Help: "Total number of connections rejected because of the address of the client or an invalid PROXY protocol header.",
+		}),
+		rejectedRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_ip_filter_rejected_requests_total",
+			Help: "Total number of HTTP requests of trusted proxies rejected because of the address of the client in the X-Forwarded-For header or of the proxy.",
+		}),
+	}
+	var err error
+	if f.allow, err = parseCIDRs(rules.Allow); err != nil {
+		return nil, errors.Wrap(err, "parse allowed CIDRs")
+	}
+	if f.deny, err = parseCIDRs(rules.Deny); err != nil {
+		return nil, errors.Wrap(err, "parse denied CIDRs")
+	}
+	if f.trusted, err = parseCIDRs(rules.TrustedProxies); err != nil {
+		return nil, errors.Wrap(err, "parse trusted proxy CIDRs")
+	}
+	return f, nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		// Single addresses are accepted as well.
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, errors.Errorf("invalid IP address %q", c)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed returns true if the client with the given IP address is allowed.
+func (f *Filter) Allowed(ip net.IP) bool {
+	if ip == nil || contains(f.deny, ip) {
+		return false
+	}
+	return len(f.allow) == 0 || contains(f.allow, ip)
+}
+
+// trustedProxy returns true if the peer with the given IP address is a trusted proxy.
+func (f *Filter) trustedProxy(ip net.IP) bool {
+	return ip != nil && contains(f.trusted, ip)
+}
+
+// Middleware returns the handler which rejects requests of trusted proxies for denied clients, taking the address of
+// the client from the X-Forwarded-For header. Requests of other peers are already filtered by the listener.
+func (f *Filter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip, ok := f.forwardedFor(r); ok && !f.Allowed(ip) {
+			f.rejectedRequests.Inc()
+			level.Debug(f.logger).Log("msg", "rejecting request of denied client", "client", ip, "remote_addr", r.RemoteAddr)
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedFor returns the address of the client in the X-Forwarded-For header, if the request comes from a trusted
+// proxy. It is the last address not being a trusted proxy, since only the trusted proxies append the addresses
+// reliably. Requests of trusted proxies without the header are made by the proxy itself, so its address is returned.
+func (f *Filter) forwardedFor(r *http.Request) (net.IP, bool) {
+	if !f.trustedProxy(hostIP(r.RemoteAddr)) {
+		return nil, false
+	}
+	var addrs []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		addrs = append(addrs, strings.Split(h, ",")...)
+	}
+	if len(addrs) == 0 {
+		return hostIP(r.RemoteAddr), true
+	}
+	var ip net.IP
+	for i := len(addrs) - 1; i >= 0; i-- {
+		ip = net.ParseIP(strings.TrimSpace(addrs[i]))
+		if !f.trustedProxy(ip) {
+			break
+		}
+	}
+	// An invalid address is returned as nil, which is not allowed.
+	return ip, true
+}
+
+// Listener returns the listener which closes the connections of denied clients. Connections of trusted proxies are
+// accepted, their clients are checked on the requests, or, if the PROXY protocol is enabled, on the header of the
+// connection.
+func (f *Filter) Listener(l net.Listener) net.Listener {
+	return &listener{Listener: l, f: f}
+}
+
+func hostIP(addr string) net.IP {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return net.ParseIP(host)
+}
+
+func addrIP(addr net.Addr) net.IP {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP
+	}
+	return hostIP(addr.String())
+}
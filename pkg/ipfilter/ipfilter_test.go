@@ -0,0 +1,173 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package ipfilter
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestParseConfig(t *testing.T) {
+	conf, err := ParseConfig([]byte(`
+http:
+  allow: [10.0.0.0/8]
+  trusted_proxies: [192.168.0.1]
+grpc:
+  deny: [10.1.0.0/16]
+  proxy_protocol: true
+`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, Config{
+		HTTP: &Rules{Allow: []string{"10.0.0.0/8"}, TrustedProxies: []string{"192.168.0.1"}},
+		GRPC: &Rules{Deny: []string{"10.1.0.0/16"}, ProxyProtocol: true},
+	}, conf)
+
+	_, err = ParseConfig([]byte("http:\n  allowed: [10.0.0.0/8]\n"))
+	testutil.NotOk(t, err)
+
+	// The clients of trusted proxies of the gRPC server can only be checked with the PROXY protocol.
+	_, err = ParseConfig([]byte("grpc:\n  allow: [10.0.0.0/8]\n  trusted_proxies: [192.168.0.1]\n"))
+	testutil.NotOk(t, err)
+
+	_, err = NewFilter(log.NewNopLogger(), nil, &Rules{Allow: []string{"10.0.0.0/33"}})
+	testutil.NotOk(t, err)
+
+	f, err := NewFilter(log.NewNopLogger(), nil, nil)
+	testutil.Ok(t, err)
+	testutil.Assert(t, f == nil, "expected no filter without rules")
+}
+
+func TestFilter_Allowed(t *testing.T) {
+	f, err := NewFilter(log.NewNopLogger(), nil, &Rules{Allow: []string{"10.0.0.0/8", "2001:db8::/32", "192.168.0.1"}, Deny: []string{"10.1.0.0/16"}})
+	testutil.Ok(t, err)
+
+	for ip, allowed := range map[string]bool{
+		"10.0.0.1":    true,
+		"10.1.0.1":    false,
+		"192.168.0.1": true,
+		"192.168.0.2": false,
+		"2001:db8::1": true,
+		"2001:db9::1": false,
+	} {
+		testutil.Equals(t, allowed, f.Allowed(net.ParseIP(ip)), "address %s", ip)
+	}
+	testutil.Assert(t, !f.Allowed(nil), "expected no address to be denied")
+
+	// Without allowed CIDRs, all clients which aren't denied are allowed.
+	f, err = NewFilter(log.NewNopLogger(), nil, &Rules{Deny: []string{"10.1.0.0/16"}})
+	testutil.Ok(t, err)
+	testutil.Assert(t, f.Allowed(net.ParseIP("192.168.0.1")), "expected address to be allowed")
+	testutil.Assert(t, !f.Allowed(net.ParseIP("10.1.2.3")), "expected address to be denied")
+}
+
+func TestFilter_Middleware(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	f, err := NewFilter(log.NewNopLogger(), reg, &Rules{Allow: []string{"10.0.0.0/8"}, TrustedProxies: []string{"192.168.0.0/24"}})
+	testutil.Ok(t, err)
+	h := f.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		remoteAddr    string
+		forwardedFor  []string
+		expectedCode  int
+		expectedCount float64
+	}{
+		// Requests of the proxy itself are checked with its address.
+		{remoteAddr: "192.168.0.1:1234", expectedCode: http.StatusForbidden, expectedCount: 1},
+		{remoteAddr: "192.168.0.1:1234", forwardedFor: []string{"10.0.0.1"}, expectedCode: http.StatusOK, expectedCount: 1},
+		{remoteAddr: "192.168.0.1:1234", forwardedFor: []string{"172.16.0.1"}, expectedCode: http.StatusForbidden, expectedCount: 2},
+		// Trusted proxies in the chain are skipped.
+		{remoteAddr: "192.168.0.1:1234", forwardedFor: []string{"172.16.0.1, 10.0.0.1, 192.168.0.2"}, expectedCode: http.StatusOK, expectedCount: 2},
+		{remoteAddr: "192.168.0.1:1234", forwardedFor: []string{"10.0.0.1", "172.16.0.1, 192.168.0.2"}, expectedCode: http.StatusForbidden, expectedCount: 3},
+		{remoteAddr: "192.168.0.1:1234", forwardedFor: []string{"invalid"}, expectedCode: http.StatusForbidden, expectedCount: 4},
+		// The header of untrusted peers is ignored, they are filtered by the listener.
+		{remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"172.16.0.1"}, expectedCode: http.StatusOK, expectedCount: 4},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tc.remoteAddr
+		for _, v := range tc.forwardedFor {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		testutil.Equals(t, tc.expectedCode, w.Code, "remote address %s, forwarded for %v", tc.remoteAddr, tc.forwardedFor)
+		testutil.Equals(t, tc.expectedCount, promtest.ToFloat64(f.rejectedRequests))
+	}
+
+	// Requests of allowed proxies without the header are accepted.
+	f, err = NewFilter(log.NewNopLogger(), nil, &Rules{Allow: []string{"10.0.0.0/8", "192.168.0.1"}, TrustedProxies: []string{"192.168.0.0/24"}})
+	testutil.Ok(t, err)
+	h = f.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for remoteAddr, code := range map[string]int{"192.168.0.1:1234": http.StatusOK, "192.168.0.2:1234": http.StatusForbidden} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		testutil.Equals(t, code, w.Code, "remote address %s", remoteAddr)
+	}
+}
+
+func TestFilter_Listener(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	f, err := NewFilter(log.NewNopLogger(), reg, &Rules{Allow: []string{"10.0.0.0/8"}, TrustedProxies: []string{"127.0.0.1"}, ProxyProtocol: true})
+	testutil.Ok(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	testutil.Ok(t, err)
+	fl := f.Listener(l)
+	defer func() { testutil.Ok(t, fl.Close()) }()
+
+	v2Header := func(ip net.IP, port uint16) []byte {
+		b := append([]byte{}, proxyV2Signature...)
+		b = append(b, 0x21, 0x11, 0, 12)
+		b = append(b, ip.To4()...)
+		b = append(b, 127, 0, 0, 1)
+		ports := make([]byte, 4)
+		binary.BigEndian.PutUint16(ports, port)
+		binary.BigEndian.PutUint16(ports[2:], 80)
+		return append(b, ports...)
+	}
+
+	for _, tc := range []struct {
+		header       []byte
+		expectedAddr string
+		expectedErr  bool
+	}{
+		{header: []byte("PROXY TCP4 10.0.0.1 10.0.0.2 1234 80\r\n"), expectedAddr: "10.0.0.1:1234"},
+		{header: []byte("PROXY TCP6 2001:db8::1 2001:db8::2 1234 80\r\n"), expectedErr: true},
+		{header: v2Header(net.ParseIP("10.0.0.3"), 4321), expectedAddr: "10.0.0.3:4321"},
+		{header: v2Header(net.ParseIP("172.16.0.1"), 4321), expectedErr: true},
+		{header: []byte("GET / HTTP/1.1\r\n"), expectedErr: true},
+	} {
+		c, err := net.Dial("tcp", l.Addr().String())
+		testutil.Ok(t, err)
+		_, err = c.Write(append(tc.header, []byte("hello")...))
+		testutil.Ok(t, err)
+		testutil.Ok(t, c.(*net.TCPConn).CloseWrite())
+
+		sc, err := fl.Accept()
+		testutil.Ok(t, err)
+		b, err := ioutil.ReadAll(sc)
+		if tc.expectedErr {
+			testutil.NotOk(t, err)
+		} else {
+			testutil.Ok(t, err)
+			testutil.Equals(t, "hello", string(b))
+			testutil.Equals(t, tc.expectedAddr, sc.RemoteAddr().String())
+		}
+		testutil.Ok(t, c.Close())
+		_ = sc.Close()
+	}
+	testutil.Equals(t, 3.0, promtest.ToFloat64(f.rejectedConns))
+}
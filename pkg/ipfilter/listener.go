@@ -0,0 +1,183 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package ipfilter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// proxyHeaderTimeout is the maximum duration to read the PROXY protocol header of a connection.
+const proxyHeaderTimeout = 10 * time.Second
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+type listener struct {
+	net.Listener
+	f *Filter
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip := addrIP(c.RemoteAddr())
+		if l.f.trustedProxy(ip) {
+			if l.f.proxyProtocol {
+				return &proxyConn{Conn: c, f: l.f, r: bufio.NewReader(c)}, nil
+			}
+			return c, nil
+		}
+		if l.f.Allowed(ip) {
+			return c, nil
+		}
+		l.f.rejectedConns.Inc()
+		level.Debug(l.f.logger).Log("msg", "rejecting connection of denied client", "client", c.RemoteAddr())
+		_ = c.Close()
+	}
+}
+
+// proxyConn is the connection of a trusted proxy sending a PROXY protocol header. The header is read on the first
+// read or request of the remote address, so that slow proxies don't block accepting connections.
+type proxyConn struct {
+	net.Conn
+	f *Filter
+	r *bufio.Reader
+
+	once       sync.Once
+	remoteAddr net.Addr
+	err        error
+}
+
+func (c *proxyConn) init() {
+	c.once.Do(func() {
+		c.remoteAddr = c.Conn.RemoteAddr()
+		if err := c.Conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout)); err != nil {
+			c.err = err
+			return
+		}
+		addr, err := readProxyHeader(c.r)
+		if err != nil {
+			c.err = errors.Wrapf(err, "read PROXY protocol header of %s", c.remoteAddr)
+		} else if err = c.Conn.SetReadDeadline(time.Time{}); err != nil {
+			c.err = err
+		} else if addr != nil {
+			c.remoteAddr = addr
+			if !c.f.Allowed(addrIP(addr)) {
+				c.err = errors.Errorf("client %s denied", addr)
+			}
+		}
+		if c.err != nil {
+			c.f.rejectedConns.Inc()
+			level.Debug(c.f.logger).Log("msg", "rejecting connection", "err", c.err)
+			_ = c.Conn.Close()
+		}
+	})
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr returns the address of the client sent in the PROXY protocol header.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.init()
+	return c.remoteAddr
+}
+
+// readProxyHeader reads the PROXY protocol header, version 1 or 2, and returns the source address in it. The address
+// is nil for connections of the proxy itself, e.g. health checks.
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	sig, err := r.Peek(len(proxyV2Signature))
+	if err == nil && bytes.Equal(sig, proxyV2Signature) {
+		return readProxyHeaderV2(r)
+	}
+	if sig, err = r.Peek(6); err != nil {
+		return nil, err
+	}
+	if string(sig) == "PROXY " {
+		return readProxyHeaderV1(r)
+	}
+	return nil, errors.New("no PROXY protocol header")
+}
+
+func readProxyHeaderV1(r *bufio.Reader) (net.Addr, error) {
+	// The header is at most 107 bytes long, including the CRLF.
+	var line []byte
+	for len(line) < 107 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("invalid PROXY protocol v1 header")
+	}
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errors.Errorf("invalid PROXY protocol v1 header %q", line)
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.Atoi(fields[4])
+	if ip == nil || err != nil {
+		return nil, errors.Errorf("invalid source address in PROXY protocol v1 header %q", line)
+	}
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+func readProxyHeaderV2(r *bufio.Reader) (net.Addr, error) {
+	hdr := make([]byte, 16)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	if hdr[12]>>4 != 2 {
+		return nil, errors.Errorf("unsupported PROXY protocol version %d", hdr[12]>>4)
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(hdr[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	// The LOCAL command is sent for connections of the proxy itself.
+	if hdr[12]&0xf == 0 {
+		return nil, nil
+	}
+	switch hdr[13] >> 4 {
+	case 1: // AF_INET.
+		if len(payload) < 12 {
+			return nil, errors.New("short IPv4 addresses in PROXY protocol v2 header")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 2: // AF_INET6.
+		if len(payload) < 36 {
+			return nil, errors.New("short IPv6 addresses in PROXY protocol v2 header")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	default:
+		// Unix sockets and unspecified families have no client address.
+		return nil, nil
+	}
+}
@@ -6,7 +6,7 @@ package logging
 import (
 	"fmt"
 	"math/rand"
-	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +16,8 @@ import (
 	"github.com/oklog/ulid"
 	"google.golang.org/grpc/status"
 	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
 )
 
 // NewRequestConfig parses the string into a req logging config structure.
@@ -28,34 +30,102 @@ func NewRequestConfig(configYAML []byte) (*RequestConfig, error) {
 	return reqLogConfig, nil
 }
 
+// seriesMethod is the full name of the Series method of the Store API, logged with a summary of its request.
+const seriesMethod = "/thanos.Store/Series"
+
+// maxMatchersLength is the maximum length of the matchers of Series requests in logs.
+const maxMatchersLength = 512
+
 // checkOptionsConfigEmpty checks if the OptionsConfig struct is empty and valid.
 // If invalid combination is present, return an error.
 func checkOptionsConfigEmpty(optcfg OptionsConfig) (bool, error) {
-	if optcfg.Level == "" && !optcfg.Decision.LogEnd && !optcfg.Decision.LogStart {
+	if optcfg.Level == "" && !optcfg.Decision.enabled() {
 		return true, nil
 	}
-	if optcfg.Level == "" && (optcfg.Decision.LogStart || optcfg.Decision.LogEnd) {
+	if optcfg.Level == "" && optcfg.Decision.enabled() {
 		return false, fmt.Errorf("level field is empty")
 	}
 	return false, nil
 }
 
+// enabled returns true if the decision logs any request.
+func (d DecisionConfig) enabled() bool {
+	return d.LogStart || d.LogEnd || d.LogFailuresOnly
+}
+
+// requestPolicy is the level and decision of logging of requests.
+type requestPolicy struct {
+	level    string
+	decision DecisionConfig
+}
+
+// override returns the policy with the level and decision replaced by the given ones, if set.
+func (p requestPolicy) override(level string, decision *DecisionConfig) (requestPolicy, error) {
+	if level != "" {
+		p.level = level
+	}
+	if decision != nil {
+		p.decision = *decision
+	}
+	return p, p.validate()
+}
+
+// validate returns an error if the level is unknown or the combination of decisions is not supported.
+func (p requestPolicy) validate() error {
+	if err := validateLevel(p.level); err != nil {
+		return err
+	}
+	if p.decision.LogStart && p.decision.LogFailuresOnly {
+		return fmt.Errorf("log start call cannot be combined with logging failures only")
+	}
+	if p.decision.LogStart && !p.decision.LogEnd {
+		return fmt.Errorf("log start call is not supported")
+	}
+	return nil
+}
+
+// grpcDecision returns the decision for a gRPC call which returned the given error.
+func (p requestPolicy) grpcDecision(err error) grpc_logging.Decision {
+	if !p.allows(string(grpc_logging.DefaultServerCodeToLevel(status.Code(err)))) {
+		return grpc_logging.NoLogCall
+	}
+	switch {
+	case p.decision.LogFailuresOnly:
+		if err == nil {
+			return grpc_logging.NoLogCall
+		}
+		return grpc_logging.LogFinishCall
+	case p.decision.LogStart:
+		return grpc_logging.LogStartAndFinishCall
+	case p.decision.LogEnd:
+		return grpc_logging.LogFinishCall
+	}
+	return grpc_logging.NoLogCall
+}
+
+// allows returns true if logs of the given runtime level are allowed by the level of the policy.
+func (p requestPolicy) allows(runtimeLevel string) bool {
+	for _, lvl := range MapAllowedLevels[p.level] {
+		if runtimeLevel == strings.ToLower(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
 // fillGlobalOptionConfig configures all the method to have global config for logging.
-func fillGlobalOptionConfig(reqLogConfig *RequestConfig, isgRPC bool) (string, bool, bool, error) {
-	globalLevel := "ERROR"
-	globalStart, globalEnd := false, false
+func fillGlobalOptionConfig(reqLogConfig *RequestConfig, isgRPC bool) (requestPolicy, error) {
+	policy := requestPolicy{level: "ERROR"}
 
 	globalOptionConfig := reqLogConfig.Options
 	isEmpty, err := checkOptionsConfigEmpty(globalOptionConfig)
 
 	// If the decision for logging is enabled with empty level field.
 	if err != nil {
-		return "", false, false, err
+		return requestPolicy{}, err
 	}
 	if !isEmpty {
-		globalLevel = globalOptionConfig.Level
-		globalStart = globalOptionConfig.Decision.LogStart
-		globalEnd = globalOptionConfig.Decision.LogEnd
+		policy = requestPolicy{level: globalOptionConfig.Level, decision: globalOptionConfig.Decision}
 	}
 
 	protocolOptionConfig := reqLogConfig.HTTP.Options
@@ -67,29 +137,38 @@ func fillGlobalOptionConfig(reqLogConfig *RequestConfig, isgRPC bool) (string, b
 	isEmpty, err = checkOptionsConfigEmpty(protocolOptionConfig)
 	// If the decision for logging is enabled with empty level field.
 	if err != nil {
-		return "", false, false, err
+		return requestPolicy{}, err
 	}
 
 	if !isEmpty {
-		globalLevel = protocolOptionConfig.Level
-		globalStart = protocolOptionConfig.Decision.LogStart
-		globalEnd = protocolOptionConfig.Decision.LogEnd
+		policy = requestPolicy{level: protocolOptionConfig.Level, decision: protocolOptionConfig.Decision}
 	}
-	return globalLevel, globalStart, globalEnd, nil
+	return policy, nil
 }
 
-// getGRPCLoggingOption returns the logging ENUM based on logStart and logEnd values.
-func getGRPCLoggingOption(logStart, logEnd bool) (grpc_logging.Decision, error) {
-	if !logStart && !logEnd {
-		return grpc_logging.NoLogCall, nil
-	}
-	if !logStart && logEnd {
-		return grpc_logging.LogFinishCall, nil
-	}
-	if logStart && logEnd {
-		return grpc_logging.LogStartAndFinishCall, nil
+// requestFieldExtractor returns the request ID of gRPC requests, generating one if the request has none. If
+// logSeries is true, a summary of the matchers and time range of Series requests is returned as well.
+func requestFieldExtractor(logSeries bool) tags.RequestFieldExtractorFunc {
+	return func(fullMethod string, req interface{}) map[string]string {
+		tagMap := tags.TagBasedRequestFieldExtractor("request-id")("", req)
+		// If a request-id does not exist for a given request.
+		if _, ok := tagMap["request-id"]; !ok {
+			entropy := ulid.Monotonic(rand.New(rand.NewSource(time.Now().UnixNano())), 0)
+			reqID := ulid.MustNew(ulid.Timestamp(time.Now()), entropy)
+			tagMap = map[string]string{"request-id": reqID.String()}
+		}
+
+		if r, ok := req.(*storepb.SeriesRequest); ok && logSeries && fullMethod == seriesMethod {
+			matchers := storepb.MatchersToString(r.Matchers...)
+			if len(matchers) > maxMatchersLength {
+				matchers = matchers[:maxMatchersLength] + "..."
+			}
+			tagMap["matchers"] = matchers
+			tagMap["min_time"] = strconv.FormatInt(r.MinTime, 10)
+			tagMap["max_time"] = strconv.FormatInt(r.MaxTime, 10)
+		}
+		return tagMap
 	}
-	return -1, fmt.Errorf("log start call is not supported")
 }
 
 // validateLevel validates the list of level entries.
@@ -109,20 +188,7 @@ func NewGRPCOption(configYAML []byte) ([]tags.Option, []grpc_logging.Option, err
 
 	// Configure tagOpts and logOpts.
 	tagOpts := []tags.Option{
-		tags.WithFieldExtractor(func(_ string, req interface{}) map[string]string {
-			tagMap := tags.TagBasedRequestFieldExtractor("request-id")("", req)
-			// If a request-id exists for a given request.
-			if tagMap != nil {
-				if _, ok := tagMap["request-id"]; ok {
-					return tagMap
-				}
-			}
-			entropy := ulid.Monotonic(rand.New(rand.NewSource(time.Now().UnixNano())), 0)
-			reqID := ulid.MustNew(ulid.Timestamp(time.Now()), entropy)
-			tagMap = make(map[string]string)
-			tagMap["request-id"] = reqID.String()
-			return tagMap
-		}),
+		tags.WithFieldExtractor(requestFieldExtractor(false)),
 	}
 	logOpts := []grpc_logging.Option{
 		grpc_logging.WithDecider(func(_ string, _ error) grpc_logging.Decision {
@@ -143,65 +209,54 @@ func NewGRPCOption(configYAML []byte) ([]tags.Option, []grpc_logging.Option, err
 		return tagOpts, logOpts, err
 	}
 
-	globalLevel, globalStart, globalEnd, err := fillGlobalOptionConfig(reqLogConfig, true)
+	globalPolicy, err := fillGlobalOptionConfig(reqLogConfig, true)
 	// If global options have invalid entries.
 	if err != nil {
 		return tagOpts, logOpts, err
 	}
 
-	// If the level entry does not matches our entries.
-	if err := validateLevel(globalLevel); err != nil {
-		return tagOpts, logOpts, err
-	}
-
-	// If the combination is valid, use them, otherwise return error.
-	reqLogDecision, err := getGRPCLoggingOption(globalStart, globalEnd)
-	if err != nil {
+	// If the level entry does not matches our entries, or the combination of decisions is invalid.
+	if err := globalPolicy.validate(); err != nil {
 		return tagOpts, logOpts, err
 	}
 
 	if len(reqLogConfig.GRPC.Config) == 0 {
+		tagOpts = []tags.Option{
+			tags.WithFieldExtractor(requestFieldExtractor(globalPolicy.decision.enabled())),
+		}
 		logOpts = []grpc_logging.Option{
 			grpc_logging.WithDecider(func(_ string, err error) grpc_logging.Decision {
-
-				runtimeLevel := grpc_logging.DefaultServerCodeToLevel(status.Code(err))
-				for _, lvl := range MapAllowedLevels[globalLevel] {
-					if string(runtimeLevel) == strings.ToLower(lvl) {
-						return reqLogDecision
-					}
-				}
-				return grpc_logging.NoLogCall
+				return globalPolicy.grpcDecision(err)
 			}),
 			grpc_logging.WithLevels(DefaultCodeToLevelGRPC),
 		}
 		return tagOpts, logOpts, nil
 	}
 
-	logOpts = []grpc_logging.Option{
-		grpc_logging.WithLevels(DefaultCodeToLevelGRPC),
-	}
-
-	methodNameSlice := []string{}
-
+	// Methods which are not configured are not logged.
+	methodPolicies := make(map[string]requestPolicy, len(reqLogConfig.GRPC.Config))
 	for _, eachConfig := range reqLogConfig.GRPC.Config {
 		eachConfigMethodName := interceptors.FullMethod(eachConfig.Service, eachConfig.Method)
-		methodNameSlice = append(methodNameSlice, eachConfigMethodName)
+		policy, err := globalPolicy.override(eachConfig.Level, eachConfig.Decision)
+		if err != nil {
+			return tagOpts, logOpts, fmt.Errorf("invalid config of method %v: %v", eachConfigMethodName, err)
+		}
+		methodPolicies[eachConfigMethodName] = policy
 	}
 
-	logOpts = append(logOpts, []grpc_logging.Option{
+	seriesPolicy, ok := methodPolicies[seriesMethod]
+	tagOpts = []tags.Option{
+		tags.WithFieldExtractor(requestFieldExtractor(ok && seriesPolicy.decision.enabled())),
+	}
+	logOpts = []grpc_logging.Option{
+		grpc_logging.WithLevels(DefaultCodeToLevelGRPC),
 		grpc_logging.WithDecider(func(runtimeMethodName string, err error) grpc_logging.Decision {
-
-			idx := sort.SearchStrings(methodNameSlice, runtimeMethodName)
-			if idx < len(methodNameSlice) && methodNameSlice[idx] == runtimeMethodName {
-				runtimeLevel := grpc_logging.DefaultServerCodeToLevel(status.Code(err))
-				for _, lvl := range MapAllowedLevels[globalLevel] {
-					if string(runtimeLevel) == strings.ToLower(lvl) {
-						return reqLogDecision
-					}
-				}
+			policy, ok := methodPolicies[runtimeMethodName]
+			if !ok {
+				return grpc_logging.NoLogCall
 			}
-			return grpc_logging.NoLogCall
+			return policy.grpcDecision(err)
 		}),
-	}...)
+	}
 	return tagOpts, logOpts, nil
 }
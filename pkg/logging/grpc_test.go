@@ -0,0 +1,104 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grpc-ecosystem/go-grpc-middleware/providers/kit/v2"
+	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tags"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestNewGRPCOption(t *testing.T) {
+	config := `
+options:
+  level: DEBUG
+  decision:
+    log_start: true
+    log_end: true
+grpc:
+  config:
+    - service: thanos.Store
+      method: Series
+    - service: thanos.Store
+      method: LabelNames
+      decision:
+        log_failures_only: true
+    - service: thanos.Store
+      method: LabelValues
+      level: ERROR
+      decision:
+        log_end: true
+`
+	tagOpts, logOpts, err := NewGRPCOption([]byte(config))
+	testutil.Ok(t, err)
+
+	b := bytes.Buffer{}
+	interceptors := []grpc.UnaryServerInterceptor{
+		tags.UnaryServerInterceptor(tagOpts...),
+		grpc_logging.UnaryServerInterceptor(kit.InterceptorLogger(log.NewLogfmtLogger(&b)), logOpts...),
+	}
+	call := func(method string, req interface{}, err error) string {
+		b.Reset()
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, err }
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], handler
+			handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, next)
+			}
+		}
+		_, _ = handler(context.Background(), req)
+		return b.String()
+	}
+
+	series := &storepb.SeriesRequest{
+		MinTime:  1,
+		MaxTime:  2,
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "up"}},
+	}
+	out := call("/thanos.Store/Series", series, nil)
+	testutil.Assert(t, strings.Contains(out, "started"), "expected start log, got %q", out)
+	testutil.Assert(t, strings.Contains(out, "finished"), "expected finish log, got %q", out)
+	testutil.Assert(t, strings.Contains(out, `grpc.request.matchers="{__name__=\"up\"}"`), "expected matchers, got %q", out)
+	testutil.Assert(t, strings.Contains(out, "grpc.request.min_time=1") && strings.Contains(out, "grpc.request.max_time=2"), "expected time range, got %q", out)
+
+	// Only failures are logged.
+	testutil.Equals(t, "", call("/thanos.Store/LabelNames", &storepb.LabelNamesRequest{}, nil))
+	out = call("/thanos.Store/LabelNames", &storepb.LabelNamesRequest{}, status.Error(codes.Internal, "boom"))
+	testutil.Assert(t, !strings.Contains(out, "started"), "expected no start log, got %q", out)
+	testutil.Assert(t, strings.Contains(out, "finished") && strings.Contains(out, "boom"), "expected failure log, got %q", out)
+
+	// Only calls at error level are logged.
+	testutil.Equals(t, "", call("/thanos.Store/LabelValues", &storepb.LabelValuesRequest{}, nil))
+	testutil.Equals(t, "", call("/thanos.Store/LabelValues", &storepb.LabelValuesRequest{}, status.Error(codes.NotFound, "not found")))
+	out = call("/thanos.Store/LabelValues", &storepb.LabelValuesRequest{}, status.Error(codes.Internal, "boom"))
+	testutil.Assert(t, strings.Contains(out, "finished"), "expected finish log, got %q", out)
+
+	// Methods which are not configured are not logged.
+	testutil.Equals(t, "", call("/thanos.Store/Info", &storepb.InfoRequest{}, status.Error(codes.Internal, "boom")))
+}
+
+func TestNewGRPCOption_InvalidConfig(t *testing.T) {
+	for _, config := range []string{
+		"options:\n  decision:\n    log_end: true\n",
+		"options:\n  level: TRACE\n",
+		"options:\n  level: INFO\n  decision:\n    log_start: true\n",
+		"options:\n  level: INFO\n  decision:\n    log_start: true\n    log_end: true\n    log_failures_only: true\n",
+		"grpc:\n  config:\n    - service: thanos.Store\n      method: Series\n      level: TRACE\n",
+	} {
+		_, _, err := NewGRPCOption([]byte(config))
+		testutil.NotOk(t, err, "config %q", config)
+	}
+}
@@ -6,7 +6,6 @@ package logging
 import (
 	"fmt"
 	"net"
-	"sort"
 	"strings"
 
 	"net/http"
@@ -23,17 +22,17 @@ type HTTPServerMiddleware struct {
 	logger log.Logger
 }
 
-func (m *HTTPServerMiddleware) preCall(name string, start time.Time, r *http.Request) {
-	logger := m.opts.filterLog(m.logger)
+func (m *HTTPServerMiddleware) preCall(name string, start time.Time, r *http.Request, filter FilterLogging) {
+	logger := filter(m.logger)
 	level.Debug(logger).Log("http.start_time", start.String(), "http.method", fmt.Sprintf("%s %s", r.Method, r.URL), "http.request_id", r.Header.Get("X-Request-ID"), "thanos.method_name", name, "msg", "started call")
 }
 
-func (m *HTTPServerMiddleware) postCall(name string, start time.Time, wrapped *httputil.ResponseWriterWithStatus, r *http.Request) {
+func (m *HTTPServerMiddleware) postCall(name string, start time.Time, wrapped *httputil.ResponseWriterWithStatus, r *http.Request, filter FilterLogging) {
 	status := wrapped.Status()
 	logger := log.With(m.logger, "http.method", fmt.Sprintf("%s %s", r.Method, r.URL), "http.request_id", r.Header.Get("X-Request-ID"), "http.status_code", fmt.Sprintf("%d", status),
 		"http.time_ms", fmt.Sprintf("%v", durationToMilliseconds(time.Since(start))), "http.remote_addr", r.RemoteAddr, "thanos.method_name", name)
 
-	logger = m.opts.filterLog(logger)
+	logger = filter(logger)
 	m.opts.levelFunc(logger, status).Log("msg", "finished call")
 }
 
@@ -58,24 +57,35 @@ func (m *HTTPServerMiddleware) HTTPMiddleware(name string, next http.Handler) ht
 			}
 		}
 
-		deciderURL := r.URL.String()
+		// The query of the URL is not part of the endpoint.
+		deciderURL := r.URL.Path
 		if len(port) > 0 {
 			deciderURL = net.JoinHostPort(deciderURL, port)
 		}
 		decision := m.opts.shouldLog(deciderURL, nil)
+		filter := m.opts.filterLog
+		if m.opts.methodFilterLog != nil {
+			filter = m.opts.methodFilterLog(deciderURL)
+		}
 
 		switch decision {
 		case NoLogCall:
 			next.ServeHTTP(w, r)
 
 		case LogStartAndFinishCall:
-			m.preCall(name, start, r)
+			m.preCall(name, start, r, filter)
 			next.ServeHTTP(wrapped, r)
-			m.postCall(name, start, wrapped, r)
+			m.postCall(name, start, wrapped, r, filter)
 
 		case LogFinishCall:
 			next.ServeHTTP(wrapped, r)
-			m.postCall(name, start, wrapped, r)
+			m.postCall(name, start, wrapped, r, filter)
+
+		case LogFailedCall:
+			next.ServeHTTP(wrapped, r)
+			if wrapped.Status() >= http.StatusBadRequest {
+				m.postCall(name, start, wrapped, r, filter)
+			}
 		}
 	}
 }
@@ -89,18 +99,24 @@ func NewHTTPServerMiddleware(logger log.Logger, opts ...Option) *HTTPServerMiddl
 	}
 }
 
-// getHTTPLoggingOption returns the logging ENUM based on logStart and logEnd values.
-func getHTTPLoggingOption(logStart, logEnd bool) (Decision, error) {
-	if !logStart && !logEnd {
-		return NoLogCall, nil
-	}
-	if !logStart && logEnd {
-		return LogFinishCall, nil
+// httpDecision returns the decision for HTTP requests.
+func (p requestPolicy) httpDecision() Decision {
+	switch {
+	case p.decision.LogFailuresOnly:
+		return LogFailedCall
+	case p.decision.LogStart:
+		return LogStartAndFinishCall
+	case p.decision.LogEnd:
+		return LogFinishCall
 	}
-	if logStart && logEnd {
-		return LogStartAndFinishCall, nil
+	return NoLogCall
+}
+
+// httpFilter returns the filter of the logs of HTTP requests by the level of the policy.
+func (p requestPolicy) httpFilter() FilterLogging {
+	return func(logger log.Logger) log.Logger {
+		return level.NewFilter(logger, getLevel(p.level))
 	}
-	return -1, fmt.Errorf("log start call is not supported")
 }
 
 // getLevel returns the level based logger.
@@ -139,55 +155,55 @@ func NewHTTPOption(configYAML []byte) ([]Option, error) {
 		return logOpts, err
 	}
 
-	globalLevel, globalStart, globalEnd, err := fillGlobalOptionConfig(reqLogConfig, false)
+	globalPolicy, err := fillGlobalOptionConfig(reqLogConfig, false)
 
 	// If global options have invalid entries.
 	if err != nil {
 		return logOpts, err
 	}
-	// If the level entry does not matches our entries.
-	if err := validateLevel(globalLevel); err != nil {
-		// fmt.Printf("HTTP")
-		return logOpts, err
-	}
-
-	// If the combination is valid, use them, otherwise return error.
-	reqLogDecision, err := getHTTPLoggingOption(globalStart, globalEnd)
-	if err != nil {
+	// If the level entry does not matches our entries, or the combination of decisions is invalid.
+	if err := globalPolicy.validate(); err != nil {
 		return logOpts, err
 	}
 
 	logOpts = []Option{
-		WithFilter(func(logger log.Logger) log.Logger {
-			return level.NewFilter(logger, getLevel(globalLevel))
-		}),
+		WithFilter(globalPolicy.httpFilter()),
 		WithLevels(DefaultCodeToLevel),
 	}
 
 	if len(reqLogConfig.HTTP.Config) == 0 {
 		logOpts = append(logOpts, []Option{WithDecider(func(_ string, err error) Decision {
-			return reqLogDecision
+			return globalPolicy.httpDecision()
 		}),
 		}...)
 		return logOpts, nil
 	}
 
-	methodNameSlice := []string{}
-
+	// Endpoints which are not configured are not logged.
+	endpointPolicies := make(map[string]requestPolicy, len(reqLogConfig.HTTP.Config))
 	for _, eachConfig := range reqLogConfig.HTTP.Config {
 		eachConfigName := fmt.Sprintf("%v:%v", eachConfig.Path, eachConfig.Port)
-		methodNameSlice = append(methodNameSlice, eachConfigName)
+		policy, err := globalPolicy.override(eachConfig.Level, eachConfig.Decision)
+		if err != nil {
+			return logOpts, fmt.Errorf("invalid config of endpoint %v: %v", eachConfigName, err)
+		}
+		endpointPolicies[eachConfigName] = policy
 	}
 
-	sort.Strings(methodNameSlice)
-
 	logOpts = append(logOpts, []Option{
 		WithDecider(func(runtimeMethodName string, err error) Decision {
-			idx := sort.SearchStrings(methodNameSlice, runtimeMethodName)
-			if idx < len(methodNameSlice) && methodNameSlice[idx] == runtimeMethodName {
-				return reqLogDecision
+			policy, ok := endpointPolicies[runtimeMethodName]
+			if !ok {
+				return NoLogCall
 			}
-			return NoLogCall
+			return policy.httpDecision()
+		}),
+		WithMethodFilter(func(runtimeMethodName string) FilterLogging {
+			policy, ok := endpointPolicies[runtimeMethodName]
+			if !ok {
+				return globalPolicy.httpFilter()
+			}
+			return policy.httpFilter()
 		}),
 	}...)
 	return logOpts, nil
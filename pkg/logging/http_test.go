@@ -81,3 +81,55 @@ func TestHTTPServerMiddleware(t *testing.T) {
 	testutil.Equals(t, "Test Works", string(body))
 	testutil.Assert(t, !strings.Contains(b.String(), "err="))
 }
+
+func TestNewHTTPOption(t *testing.T) {
+	config := `
+options:
+  level: DEBUG
+  decision:
+    log_start: true
+    log_end: true
+http:
+  config:
+    - path: /api/v1/query
+      port: 10902
+    - path: /api/v1/series
+      port: 10902
+      decision:
+        log_failures_only: true
+    - path: /api/v1/labels
+      port: 10902
+      level: ERROR
+`
+	opts, err := NewHTTPOption([]byte(config))
+	testutil.Ok(t, err)
+
+	b := bytes.Buffer{}
+	m := NewHTTPServerMiddleware(log.NewLogfmtLogger(io.Writer(&b)), opts...)
+	call := func(target string, status int) string {
+		b.Reset()
+		hm := m.HTTPMiddleware("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		hm(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+		return b.String()
+	}
+
+	// The query of the URL is ignored.
+	out := call("http://localhost:10902/api/v1/query?query=up", http.StatusOK)
+	testutil.Assert(t, strings.Contains(out, "started call") && strings.Contains(out, "finished call"), "expected start and finish logs, got %q", out)
+
+	// Only failures are logged.
+	testutil.Equals(t, "", call("http://localhost:10902/api/v1/series", http.StatusOK))
+	out = call("http://localhost:10902/api/v1/series", http.StatusBadRequest)
+	testutil.Assert(t, !strings.Contains(out, "started call") && strings.Contains(out, "finished call"), "expected finish log only, got %q", out)
+
+	// Only logs at error level are logged.
+	testutil.Equals(t, "", call("http://localhost:10902/api/v1/labels", http.StatusOK))
+	out = call("http://localhost:10902/api/v1/labels", http.StatusInternalServerError)
+	testutil.Assert(t, !strings.Contains(out, "started call") && strings.Contains(out, "finished call"), "expected finish log only, got %q", out)
+
+	// Endpoints which are not configured are not logged.
+	testutil.Equals(t, "", call("http://localhost:10902/api/v1/rules", http.StatusInternalServerError))
+	testutil.Equals(t, "", call("http://localhost:10901/api/v1/query", http.StatusOK))
+}
@@ -27,6 +27,8 @@ const (
 	LogFinishCall
 	// LogStartAndFinishCall - Logging of start and end of request is enabled.
 	LogStartAndFinishCall
+	// LogFailedCall - Only finish logs of failed requests are enabled.
+	LogFailedCall
 )
 
 var defaultOptions = &options{
@@ -69,6 +71,14 @@ func WithFilter(f FilterLogging) Option {
 	}
 }
 
+// WithMethodFilter customizes the function for deciding which level of logging should be allowed per method.
+// It takes precedence over WithFilter.
+func WithMethodFilter(f MethodFilterLogging) Option {
+	return func(o *options) {
+		o.methodFilterLog = f
+	}
+}
+
 // Interface for the additional methods.
 
 // Types for the Options.
@@ -109,12 +119,16 @@ func DefaultFilterLogging(logger log.Logger) log.Logger {
 	return level.NewFilter(logger, level.AllowAll())
 }
 
+// MethodFilterLogging returns the filter of the logs of the given method.
+type MethodFilterLogging func(methodName string) FilterLogging
+
 type options struct {
 	levelFunc         CodeToLevel
 	shouldLog         Decider
 	codeFunc          ErrorToCode
 	durationFieldFunc DurationToFields
 	filterLog         FilterLogging
+	methodFilterLog   MethodFilterLogging
 }
 
 // DefaultCodeToLevel is the helper mapper that maps HTTP Response codes to log levels.
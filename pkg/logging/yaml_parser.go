@@ -27,14 +27,22 @@ type OptionsConfig struct {
 type DecisionConfig struct {
 	LogStart bool `yaml:"log_start"`
 	LogEnd   bool `yaml:"log_end"`
+	// LogFailuresOnly logs the end of failed requests only. It cannot be combined with LogStart.
+	LogFailuresOnly bool `yaml:"log_failures_only"`
 }
 
+// HTTPProtocolConfig selects the HTTP requests to log. The level and decision override the ones of the options if set.
 type HTTPProtocolConfig struct {
-	Path string `yaml:"path"`
-	Port uint64 `yaml:"port"`
+	Path     string          `yaml:"path"`
+	Port     uint64          `yaml:"port"`
+	Level    string          `yaml:"level"`
+	Decision *DecisionConfig `yaml:"decision"`
 }
 
+// GRPCProtocolConfig selects the gRPC requests to log. The level and decision override the ones of the options if set.
 type GRPCProtocolConfig struct {
-	Service string `yaml:"service"`
-	Method  string `yaml:"method"`
+	Service  string          `yaml:"service"`
+	Method   string          `yaml:"method"`
+	Level    string          `yaml:"level"`
+	Decision *DecisionConfig `yaml:"decision"`
 }
@@ -0,0 +1,119 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/groupcache/singleflight"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/thanos-io/thanos/pkg/metadata/metadatapb"
+)
+
+var _ UnaryClient = &CachedClient{}
+
+// CachedClient is a UnaryClient which caches the metric metadata of the underlying client for the given TTL, so that
+// e.g. metadata lookups of Grafana on each keystroke don't fan out to every endpoint. Concurrent identical requests
+// share a single request to the underlying client. Partial responses, i.e. responses with warnings, are not cached.
+type CachedClient struct {
+	client UnaryClient
+	ttl    time.Duration
+	now    func() time.Time
+
+	g singleflight.Group
+
+	mtx     sync.Mutex
+	entries map[string]cacheEntry
+
+	requests prometheus.Counter
+	hits     prometheus.Counter
+}
+
+type cacheEntry struct {
+	metadata map[string][]metadatapb.Meta
+	expires  time.Time
+}
+
+type cacheResponse struct {
+	metadata map[string][]metadatapb.Meta
+	warnings storage.Warnings
+}
+
+// NewCachedClient returns a CachedClient caching the responses of client for ttl.
+func NewCachedClient(reg prometheus.Registerer, client UnaryClient, ttl time.Duration) *CachedClient {
+	return &CachedClient{
+		client:  client,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]cacheEntry{},
+		requests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_metric_metadata_cache_requests_total",
+			Help: "Total number of metric metadata requests to the cache.",
+		}),
+		hits: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_metric_metadata_cache_hits_total",
+			Help: "Total number of metric metadata requests served from the cache.",
+		}),
+	}
+}
+
+// MetricMetadata returns the cached metadata for the request if it didn't expire yet, otherwise it requests it from the
+// underlying client. The returned metadata is shared and must not be modified.
+func (c *CachedClient) MetricMetadata(ctx context.Context, req *metadatapb.MetricMetadataRequest) (map[string][]metadatapb.Meta, storage.Warnings, error) {
+	c.requests.Inc()
+
+	key := fmt.Sprintf("%s/%d/%s", req.Metric, req.Limit, req.PartialResponseStrategy)
+	if m, ok := c.get(key); ok {
+		c.hits.Inc()
+		return m, nil, nil
+	}
+
+	v, err := c.g.Do(key, func() (interface{}, error) {
+		// NOTE: First go routine context will go through.
+		m, warnings, err := c.client.MetricMetadata(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if len(warnings) == 0 {
+			c.set(key, m)
+		}
+		return cacheResponse{metadata: m, warnings: warnings}, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	resp := v.(cacheResponse)
+	return resp.metadata, resp.warnings, nil
+}
+
+func (c *CachedClient) get(key string) (map[string][]metadatapb.Meta, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || !c.now().Before(e.expires) {
+		return nil, false
+	}
+	return e.metadata, true
+}
+
+func (c *CachedClient) set(key string, m map[string][]metadatapb.Meta) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := c.now()
+	// Remove the expired entries, so the cache doesn't grow with the metrics which were requested over time.
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry{metadata: m, expires: now.Add(c.ttl)}
+}
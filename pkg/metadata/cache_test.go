@@ -0,0 +1,69 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package metadata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/thanos-io/thanos/pkg/metadata/metadatapb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+type countingClient struct {
+	calls    int
+	warnings storage.Warnings
+}
+
+func (c *countingClient) MetricMetadata(_ context.Context, req *metadatapb.MetricMetadataRequest) (map[string][]metadatapb.Meta, storage.Warnings, error) {
+	c.calls++
+	return map[string][]metadatapb.Meta{req.Metric: {{Type: "counter"}}}, c.warnings, nil
+}
+
+func TestCachedClient(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+
+	client := &countingClient{}
+	c := NewCachedClient(prometheus.NewRegistry(), client, time.Minute)
+	c.now = func() time.Time { return now }
+
+	req := &metadatapb.MetricMetadataRequest{Metric: "a", Limit: -1}
+	for i := 0; i < 2; i++ {
+		m, w, err := c.MetricMetadata(ctx, req)
+		testutil.Ok(t, err)
+		testutil.Equals(t, 0, len(w))
+		testutil.Equals(t, map[string][]metadatapb.Meta{"a": {{Type: "counter"}}}, m)
+	}
+	testutil.Equals(t, 1, client.calls)
+	testutil.Equals(t, 1.0, promtest.ToFloat64(c.hits))
+
+	// Other requests are cached separately.
+	_, _, err := c.MetricMetadata(ctx, &metadatapb.MetricMetadataRequest{Metric: "b", Limit: -1})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, client.calls)
+
+	// Expired responses are requested again and removed.
+	now = now.Add(time.Minute)
+	_, _, err = c.MetricMetadata(ctx, req)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, client.calls)
+	testutil.Equals(t, 1, len(c.entries))
+
+	// Partial responses are not cached.
+	client.warnings = storage.Warnings{errors.New("partial")}
+	req = &metadatapb.MetricMetadataRequest{Metric: "c", Limit: -1}
+	for i := 0; i < 2; i++ {
+		_, w, err := c.MetricMetadata(ctx, req)
+		testutil.Ok(t, err)
+		testutil.Equals(t, 1, len(w))
+	}
+	testutil.Equals(t, 5, client.calls)
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/textparse"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/thanos-io/thanos/pkg/metadata/metadatapb"
 	"github.com/thanos-io/thanos/pkg/tracing"
@@ -55,9 +56,40 @@ func (rr *GRPCClient) MetricMetadata(ctx context.Context, req *metadatapb.Metric
 		return nil, nil, errors.Wrap(err, "proxy MetricMetadata")
 	}
 
+	for metric, metas := range srv.metadataMap {
+		srv.metadataMap[metric] = dedupMetas(metas)
+	}
 	return srv.metadataMap, srv.warnings, nil
 }
 
+// dedupMetas removes the metadata of a metric which is less descriptive than another metadata of it, i.e. whose type,
+// help and unit are each either empty or equal to those of the other one. Prometheus replicas which didn't scrape a
+// target yet or scrape it with an older exposition format report e.g. the unknown type without help.
+func dedupMetas(metas []metadatapb.Meta) []metadatapb.Meta {
+	res := make([]metadatapb.Meta, 0, len(metas))
+Outer:
+	for i, m := range metas {
+		for j, o := range metas {
+			if i == j || !lessDescriptive(m, o) {
+				continue
+			}
+			// Of equally descriptive metadata, only the first one is kept.
+			if !lessDescriptive(o, m) || j < i {
+				continue Outer
+			}
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+// lessDescriptive returns true if each field of m is empty or equal to the one of o. The unknown type counts as empty.
+func lessDescriptive(m, o metadatapb.Meta) bool {
+	return (m.Type == "" || m.Type == string(textparse.MetricTypeUnknown) || m.Type == o.Type) &&
+		(m.Help == "" || m.Help == o.Help) &&
+		(m.Unit == "" || m.Unit == o.Unit)
+}
+
 type metadataServer struct {
 	// This field just exist to pseudo-implement the unused methods of the interface.
 	metadatapb.Metadata_MetricMetadataServer
@@ -0,0 +1,60 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/thanos-io/thanos/pkg/metadata/metadatapb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestDedupMetas(t *testing.T) {
+	counter := metadatapb.Meta{Type: "counter", Help: "Total number of requests.", Unit: ""}
+	for _, tc := range []struct {
+		name        string
+		metas, want []metadatapb.Meta
+	}{
+		{
+			name:  "single",
+			metas: []metadatapb.Meta{counter},
+			want:  []metadatapb.Meta{counter},
+		},
+		{
+			name: "less descriptive metadata is removed",
+			metas: []metadatapb.Meta{
+				{Type: "unknown"},
+				{Type: "counter"},
+				counter,
+				{Help: "Total number of requests."},
+			},
+			want: []metadatapb.Meta{counter},
+		},
+		{
+			name: "equally descriptive metadata is kept once",
+			metas: []metadatapb.Meta{
+				{Type: "unknown"},
+				{Type: ""},
+			},
+			want: []metadatapb.Meta{{Type: "unknown"}},
+		},
+		{
+			name: "conflicting metadata is kept",
+			metas: []metadatapb.Meta{
+				counter,
+				{Type: "gauge", Help: "Total number of requests."},
+				{Type: "counter", Help: "Number of requests."},
+			},
+			want: []metadatapb.Meta{
+				counter,
+				{Type: "gauge", Help: "Total number of requests."},
+				{Type: "counter", Help: "Number of requests."},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testutil.Equals(t, tc.want, dedupMetas(tc.metas))
+		})
+	}
+}
@@ -24,7 +24,8 @@ import (
 )
 
 const (
-	azureDefaultEndpoint = "blob.core.windows.net"
+	azureDefaultEndpoint      = "blob.core.windows.net"
+	azureDefaultAuthorityHost = "https://login.microsoftonline.com/"
 )
 
 // Set default retry values to default Azure values. 0 = use Default Azure.
@@ -52,16 +53,30 @@ var DefaultConfig = Config{
 
 // Config Azure storage configuration.
 type Config struct {
-	StorageAccountName string         `yaml:"storage_account"`
-	StorageAccountKey  string         `yaml:"storage_account_key"`
-	ContainerName      string         `yaml:"container"`
-	Endpoint           string         `yaml:"endpoint"`
-	MaxRetries         int            `yaml:"max_retries"`
-	MSIResource        string         `yaml:"msi_resource"`
-	UserAssignedID     string         `yaml:"user_assigned_id"`
-	PipelineConfig     PipelineConfig `yaml:"pipeline_config"`
-	ReaderConfig       ReaderConfig   `yaml:"reader_config"`
-	HTTPConfig         HTTPConfig     `yaml:"http_config"`
+	StorageAccountName     string                 `yaml:"storage_account"`
+	StorageAccountKey      string                 `yaml:"storage_account_key"`
+	ContainerName          string                 `yaml:"container"`
+	Endpoint               string                 `yaml:"endpoint"`
+	MaxRetries             int                    `yaml:"max_retries"`
+	MSIResource            string                 `yaml:"msi_resource"`
+	UserAssignedID         string                 `yaml:"user_assigned_id"`
+	UserAssignedResourceID string                 `yaml:"user_assigned_resource_id"`
+	WorkloadIdentity       WorkloadIdentityConfig `yaml:"workload_identity"`
+	PipelineConfig         PipelineConfig         `yaml:"pipeline_config"`
+	ReaderConfig           ReaderConfig           `yaml:"reader_config"`
+	HTTPConfig             HTTPConfig             `yaml:"http_config"`
+}
+
+// WorkloadIdentityConfig configures authentication with Azure AD workload identity federation, which exchanges
+// a token issued by a trusted identity provider, like the service account token of a Kubernetes pod, for an Azure AD token.
+// Empty fields default to the AZURE_TENANT_ID, AZURE_CLIENT_ID, AZURE_FEDERATED_TOKEN_FILE and AZURE_AUTHORITY_HOST
+// environment variables set by the Azure AD workload identity webhook.
+type WorkloadIdentityConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	TenantID      string `yaml:"tenant_id"`
+	ClientID      string `yaml:"client_id"`
+	TokenFile     string `yaml:"token_file"`
+	AuthorityHost string `yaml:"authority_host"`
 }
 
 type ReaderConfig struct {
@@ -101,7 +116,36 @@ type Bucket struct {
 func (conf *Config) validate() error {
 
 	var errMsg []string
-	if conf.MSIResource == "" {
+	if conf.WorkloadIdentity.Enabled {
+		if conf.StorageAccountName == "" {
+			errMsg = append(errMsg, "workload identity is enabled but storage account name is missing")
+		}
+		if conf.StorageAccountKey != "" {
+			errMsg = append(errMsg, "workload identity is enabled but storage account key is used")
+		}
+		if conf.MSIResource != "" || conf.UserAssignedID != "" || conf.UserAssignedResourceID != "" {
+			errMsg = append(errMsg, "workload identity can't be used together with a managed identity")
+		}
+		if conf.WorkloadIdentity.TenantID == "" {
+			errMsg = append(errMsg, "no tenant_id specified for workload identity, neither in the config file nor in the AZURE_TENANT_ID environment variable")
+		}
+		if conf.WorkloadIdentity.ClientID == "" {
+			errMsg = append(errMsg, "no client_id specified for workload identity, neither in the config file nor in the AZURE_CLIENT_ID environment variable")
+		}
+		if conf.WorkloadIdentity.TokenFile == "" {
+			errMsg = append(errMsg, "no token_file specified for workload identity, neither in the config file nor in the AZURE_FEDERATED_TOKEN_FILE environment variable")
+		}
+	} else if conf.UserAssignedResourceID != "" {
+		if conf.StorageAccountName == "" {
+			errMsg = append(errMsg, "UserAssignedResourceID is configured but storage account name is missing")
+		}
+		if conf.StorageAccountKey != "" {
+			errMsg = append(errMsg, "UserAssignedResourceID is configured but storage account key is used")
+		}
+		if conf.UserAssignedID != "" {
+			errMsg = append(errMsg, "UserAssignedID and UserAssignedResourceID are mutually exclusive")
+		}
+	} else if conf.MSIResource == "" {
 		if conf.UserAssignedID == "" {
 			if conf.StorageAccountName == "" ||
 				conf.StorageAccountKey == "" {
@@ -152,13 +196,37 @@ func (conf *Config) validate() error {
 	return nil
 }
 
-// parseConfig unmarshals a buffer into a Config with default values.
-func parseConfig(conf []byte) (Config, error) {
+// applyEnvDefaults fills the empty fields of the config with the values injected by the workload identity webhook.
+func (conf *WorkloadIdentityConfig) applyEnvDefaults() {
+	for _, f := range []struct {
+		field *string
+		env   string
+	}{
+		{field: &conf.TenantID, env: "AZURE_TENANT_ID"},
+		{field: &conf.ClientID, env: "AZURE_CLIENT_ID"},
+		{field: &conf.TokenFile, env: "AZURE_FEDERATED_TOKEN_FILE"},
+		{field: &conf.AuthorityHost, env: "AZURE_AUTHORITY_HOST"},
+	} {
+		if *f.field == "" {
+			*f.field = os.Getenv(f.env)
+		}
+	}
+	if conf.AuthorityHost == "" {
+		conf.AuthorityHost = azureDefaultAuthorityHost
+	}
+}
+
+// ParseConfig unmarshals a buffer into a Config with default values.
+func ParseConfig(conf []byte) (Config, error) {
 	config := DefaultConfig
 	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
 		return Config{}, err
 	}
 
+	if config.WorkloadIdentity.Enabled {
+		config.WorkloadIdentity.applyEnvDefaults()
+	}
+
 	// If we don't have config specific retry values but we do have the generic MaxRetries.
 	// This is for backwards compatibility but also ease of configuration.
 	if config.MaxRetries > 0 {
@@ -173,14 +241,27 @@ func parseConfig(conf []byte) (Config, error) {
 	return config, nil
 }
 
+// WithoutRetries returns the config with the retries of the Azure SDK disabled, so that every operation and every
+// read of a blob is tried once.
+func (conf Config) WithoutRetries() Config {
+	conf.MaxRetries = 0
+	conf.PipelineConfig.MaxTries = 1
+	conf.ReaderConfig.MaxRetryRequests = 0
+	return conf
+}
+
 // NewBucket returns a new Bucket using the provided Azure config.
 func NewBucket(logger log.Logger, azureConfig []byte, component string) (*Bucket, error) {
-	level.Debug(logger).Log("msg", "creating new Azure bucket connection", "component", component)
-
-	conf, err := parseConfig(azureConfig)
+	conf, err := ParseConfig(azureConfig)
 	if err != nil {
 		return nil, err
 	}
+	return NewBucketWithConfig(logger, conf, component)
+}
+
+// NewBucketWithConfig returns a new Bucket using the provided Azure config values.
+func NewBucketWithConfig(logger log.Logger, conf Config, component string) (*Bucket, error) {
+	level.Debug(logger).Log("msg", "creating new Azure bucket connection", "component", component)
 
 	if err := conf.validate(); err != nil {
 		return nil, err
@@ -293,6 +374,14 @@ func (b *Bucket) IsObjNotFoundErr(err error) bool {
 	return false
 }
 
+// StatusCode returns the HTTP status code of the error of a failed operation, if there is one.
+func StatusCode(err error) (int, bool) {
+	if serr, ok := errors.Cause(err).(blob.StorageError); ok && serr.Response() != nil {
+		return serr.Response().StatusCode, true
+	}
+	return 0, false
+}
+
 func (b *Bucket) getBlobReader(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
 	level.Debug(b.logger).Log("msg", "getting blob", "blob", name, "offset", offset, "length", length)
 	if name == "" {
@@ -159,13 +159,68 @@ container: "MyContainer"`),
 		wantFailParse:    false,
 		wantFailValidate: false,
 	},
+	{
+		name: "Valid User Assigned Identity Config with Resource ID",
+		config: []byte(`storage_account: "myAccount"
+user_assigned_resource_id: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/thanos"
+container: "MyContainer"`),
+		wantFailParse:    false,
+		wantFailValidate: false,
+	},
+	{
+		name: "User Assigned Identity with both client and resource ID",
+		config: []byte(`storage_account: "myAccount"
+user_assigned_id: "1234-56578678-655"
+user_assigned_resource_id: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/thanos"
+container: "MyContainer"`),
+		wantFailParse:    false,
+		wantFailValidate: true,
+	},
+	{
+		name: "Valid Workload Identity",
+		config: []byte(`storage_account: "myAccount"
+workload_identity:
+  enabled: true
+  tenant_id: "tenant"
+  client_id: "client"
+  token_file: "/var/run/secrets/azure/tokens/azure-identity-token"
+container: "MyContainer"`),
+		wantFailParse:    false,
+		wantFailValidate: false,
+	},
+	{
+		name: "Workload Identity with storage account key",
+		config: []byte(`storage_account: "myAccount"
+storage_account_key: "abc123"
+workload_identity:
+  enabled: true
+  tenant_id: "tenant"
+  client_id: "client"
+  token_file: "/var/run/secrets/azure/tokens/azure-identity-token"
+container: "MyContainer"`),
+		wantFailParse:    false,
+		wantFailValidate: true,
+	},
+	{
+		name: "Workload Identity with user assigned identity",
+		config: []byte(`storage_account: "myAccount"
+user_assigned_id: "1234-56578678-655"
+workload_identity:
+  enabled: true
+  tenant_id: "tenant"
+  client_id: "client"
+  token_file: "/var/run/secrets/azure/tokens/azure-identity-token"
+container: "MyContainer"`),
+		wantFailParse:    false,
+		wantFailValidate: true,
+	},
 }
 
 func TestConfig_validate(t *testing.T) {
 
 	for _, testCase := range tests {
 
-		conf, err := parseConfig(testCase.config)
+		conf, err := ParseConfig(testCase.config)
 
 		if (err != nil) != testCase.wantFailParse {
 			t.Errorf("%s error = %v, wantFailParse %v", testCase.name, err, testCase.wantFailParse)
@@ -182,7 +237,7 @@ func TestConfig_validate(t *testing.T) {
 
 func TestParseConfig_DefaultHTTPConfig(t *testing.T) {
 
-	cfg, err := parseConfig(validConfig)
+	cfg, err := ParseConfig(validConfig)
 	testutil.Ok(t, err)
 
 	if time.Duration(cfg.HTTPConfig.IdleConnTimeout) != time.Duration(90*time.Second) {
@@ -213,7 +268,7 @@ http_config:
     server_name: server
     insecure_skip_verify: false
   `)
-	cfg, err := parseConfig(input)
+	cfg, err := ParseConfig(input)
 	testutil.Ok(t, err)
 
 	testutil.Equals(t, "/certs/ca.crt", cfg.HTTPConfig.TLSConfig.CAFile)
@@ -233,9 +288,52 @@ http_config:
   tls_config:
     insecure_skip_verify: false
   `)
-	cfg, err := parseConfig(input)
+	cfg, err := ParseConfig(input)
 	testutil.Ok(t, err)
 	transport, err := DefaultTransport(cfg)
 	testutil.Ok(t, err)
 	testutil.Equals(t, true, transport.TLSClientConfig.InsecureSkipVerify)
 }
+
+func TestParseConfig_WorkloadIdentityEnvDefaults(t *testing.T) {
+	t.Setenv("AZURE_TENANT_ID", "env-tenant")
+	t.Setenv("AZURE_CLIENT_ID", "env-client")
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", "/env/token")
+	t.Setenv("AZURE_AUTHORITY_HOST", "")
+
+	cfg, err := ParseConfig([]byte(`storage_account: "myAccount"
+container: "MyContainer"
+workload_identity:
+  enabled: true
+  client_id: "client"`))
+	testutil.Ok(t, err)
+	testutil.Ok(t, cfg.validate())
+	testutil.Equals(t, WorkloadIdentityConfig{
+		Enabled:       true,
+		TenantID:      "env-tenant",
+		ClientID:      "client",
+		TokenFile:     "/env/token",
+		AuthorityHost: azureDefaultAuthorityHost,
+	}, cfg.WorkloadIdentity)
+
+	// Environment variables are ignored unless workload identity is enabled.
+	cfg, err = ParseConfig(validConfig)
+	testutil.Ok(t, err)
+	testutil.Equals(t, WorkloadIdentityConfig{}, cfg.WorkloadIdentity)
+}
+
+func TestConfig_WithoutRetries(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`storage_account: "myAccount"
+storage_account_key: "abc123"
+container: "MyContainer"
+max_retries: 5`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, int32(5), cfg.PipelineConfig.MaxTries)
+	testutil.Equals(t, 5, cfg.ReaderConfig.MaxRetryRequests)
+
+	cfg = cfg.WithoutRetries()
+	testutil.Ok(t, cfg.validate())
+	testutil.Equals(t, 0, cfg.MaxRetries)
+	testutil.Equals(t, int32(1), cfg.PipelineConfig.MaxTries)
+	testutil.Equals(t, 0, cfg.ReaderConfig.MaxRetryRequests)
+}
@@ -6,10 +6,12 @@ package azure
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
@@ -18,6 +20,8 @@ import (
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+
 	"github.com/thanos-io/thanos/pkg/objstore"
 )
 
@@ -38,7 +42,7 @@ func init() {
 }
 
 func getAzureStorageCredentials(logger log.Logger, conf Config) (blob.Credential, error) {
-	if conf.MSIResource != "" || conf.UserAssignedID != "" {
+	if conf.MSIResource != "" || conf.UserAssignedID != "" || conf.UserAssignedResourceID != "" || conf.WorkloadIdentity.Enabled {
 		spt, err := getServicePrincipalToken(logger, conf)
 		if err != nil {
 			return nil, err
@@ -50,7 +54,7 @@ func getAzureStorageCredentials(logger log.Logger, conf Config) (blob.Credential
 		return blob.NewTokenCredential(spt.Token().AccessToken, func(tc blob.TokenCredential) time.Duration {
 			err := spt.Refresh()
 			if err != nil {
-				level.Error(logger).Log("msg", "could not refresh MSI token", "err", err)
+				level.Error(logger).Log("msg", "could not refresh Azure AD token", "err", err)
 				// Retry later as the error can be related to API throttling
 				return 30 * time.Second
 			}
@@ -72,6 +76,20 @@ func getServicePrincipalToken(logger log.Logger, conf Config) (*adal.ServicePrin
 		resource = fmt.Sprintf("https://%s.%s", conf.StorageAccountName, conf.Endpoint)
 	}
 
+	if conf.WorkloadIdentity.Enabled {
+		level.Debug(logger).Log("msg", "using workload identity", "tenantId", conf.WorkloadIdentity.TenantID, "clientId", conf.WorkloadIdentity.ClientID)
+		oauthConfig, err := adal.NewOAuthConfig(conf.WorkloadIdentity.AuthorityHost, conf.WorkloadIdentity.TenantID)
+		if err != nil {
+			return nil, errors.Wrap(err, "create OAuth config for workload identity")
+		}
+		return adal.NewServicePrincipalTokenWithSecret(*oauthConfig, conf.WorkloadIdentity.ClientID, resource, &federatedTokenSecret{tokenFile: conf.WorkloadIdentity.TokenFile})
+	}
+
+	if conf.UserAssignedResourceID != "" {
+		level.Debug(logger).Log("msg", "using user assigned identity", "resourceId", conf.UserAssignedResourceID)
+		return adal.NewServicePrincipalTokenFromManagedIdentity(resource, &adal.ManagedIdentityOptions{IdentityResourceID: conf.UserAssignedResourceID})
+	}
+
 	msiConfig := auth.MSIConfig{
 		Resource: resource,
 	}
@@ -86,6 +104,22 @@ func getServicePrincipalToken(logger log.Logger, conf Config) (*adal.ServicePrin
 	return msiConfig.ServicePrincipalToken()
 }
 
+// federatedTokenSecret authenticates with a token of a trusted identity provider read from a file. The file is read
+// on every refresh, as the token in it is rotated.
+type federatedTokenSecret struct {
+	tokenFile string
+}
+
+func (s *federatedTokenSecret) SetAuthenticationValues(_ *adal.ServicePrincipalToken, v *url.Values) error {
+	token, err := ioutil.ReadFile(s.tokenFile)
+	if err != nil {
+		return errors.Wrapf(err, "read federated token file %s", s.tokenFile)
+	}
+	v.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	v.Set("client_assertion", strings.TrimSpace(string(token)))
+	return nil
+}
+
 func getContainerURL(ctx context.Context, logger log.Logger, conf Config) (blob.ContainerURL, error) {
 	credentials, err := getAzureStorageCredentials(logger, conf)
 
@@ -5,7 +5,13 @@ package azure
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
 
@@ -61,3 +67,44 @@ func Test_getContainerURL(t *testing.T) {
 		})
 	}
 }
+
+func Test_getServicePrincipalToken_WorkloadIdentity(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	testutil.Ok(t, ioutil.WriteFile(tokenFile, []byte("federated-token-1\n"), 0600))
+
+	var assertions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutil.Equals(t, "/tenant/oauth2/token", r.URL.Path)
+		testutil.Ok(t, r.ParseForm())
+		testutil.Equals(t, "client", r.PostForm.Get("client_id"))
+		testutil.Equals(t, "https://myaccount.blob.core.windows.net", r.PostForm.Get("resource"))
+		testutil.Equals(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer", r.PostForm.Get("client_assertion_type"))
+		assertions = append(assertions, r.PostForm.Get("client_assertion"))
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":"3600","expires_on":"%d","not_before":"%d","resource":"https://myaccount.blob.core.windows.net","token_type":"Bearer"}`,
+			len(assertions), time.Now().Add(time.Hour).Unix(), time.Now().Unix())
+	}))
+	defer srv.Close()
+
+	spt, err := getServicePrincipalToken(log.NewNopLogger(), Config{
+		StorageAccountName: "myaccount",
+		Endpoint:           azureDefaultEndpoint,
+		WorkloadIdentity: WorkloadIdentityConfig{
+			Enabled:       true,
+			TenantID:      "tenant",
+			ClientID:      "client",
+			TokenFile:     tokenFile,
+			AuthorityHost: srv.URL,
+		},
+	})
+	testutil.Ok(t, err)
+	testutil.Ok(t, spt.Refresh())
+	testutil.Equals(t, "token-1", spt.Token().AccessToken)
+
+	// The rotated federated token is used on refresh.
+	testutil.Ok(t, ioutil.WriteFile(tokenFile, []byte("federated-token-2"), 0600))
+	testutil.Ok(t, spt.Refresh())
+	testutil.Equals(t, "token-2", spt.Token().AccessToken)
+	testutil.Equals(t, []string{"federated-token-1", "federated-token-2"}, assertions)
+}
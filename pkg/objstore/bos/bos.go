@@ -94,6 +94,11 @@ func NewBucket(logger log.Logger, conf []byte, component string) (*Bucket, error
 	return bkt, nil
 }
 
+// DisableRetries makes the BOS client try every operation once.
+func (b *Bucket) DisableRetries() {
+	b.client.Config.Retry = bce.NewNoRetryPolicy()
+}
+
 // Name returns the bucket name for the provider.
 func (b *Bucket) Name() string {
 	return b.name
@@ -282,6 +287,14 @@ func (b *Bucket) IsObjNotFoundErr(err error) bool {
 	return false
 }
 
+// StatusCode returns the HTTP status code of the error of a failed operation, if there is one.
+func StatusCode(err error) (int, bool) {
+	if bosErr, ok := errors.Cause(err).(*bce.BceServiceError); ok && bosErr.StatusCode != 0 {
+		return bosErr.StatusCode, true
+	}
+	return 0, false
+}
+
 func (b *Bucket) getRange(_ context.Context, bucketName, objectKey string, off, length int64) (io.ReadCloser, error) {
 	if len(objectKey) == 0 {
 		return nil, errors.Errorf("given object name should not empty")
@@ -7,22 +7,28 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/objstore/azure"
 	"github.com/thanos-io/thanos/pkg/objstore/bos"
 	"github.com/thanos-io/thanos/pkg/objstore/cos"
+	"github.com/thanos-io/thanos/pkg/objstore/encryption"
 	"github.com/thanos-io/thanos/pkg/objstore/filesystem"
 	"github.com/thanos-io/thanos/pkg/objstore/gcs"
+	"github.com/thanos-io/thanos/pkg/objstore/mirror"
 	"github.com/thanos-io/thanos/pkg/objstore/oss"
 	"github.com/thanos-io/thanos/pkg/objstore/s3"
+	"github.com/thanos-io/thanos/pkg/objstore/sftp"
 	"github.com/thanos-io/thanos/pkg/objstore/swift"
+	"github.com/thanos-io/thanos/pkg/objstore/tiered"
 )
 
 type ObjProvider string
@@ -36,50 +42,193 @@ const (
 	COS        ObjProvider = "COS"
 	ALIYUNOSS  ObjProvider = "ALIYUNOSS"
 	BOS        ObjProvider = "BOS"
+	SFTP       ObjProvider = "SFTP"
+	TIERED     ObjProvider = "TIERED"
+	MIRRORED   ObjProvider = "MIRRORED"
 )
 
 type BucketConfig struct {
 	Type   ObjProvider `yaml:"type"`
 	Config interface{} `yaml:"config"`
+	// Encryption optionally enables client-side encryption of all objects.
+	Encryption encryption.Config `yaml:"encryption"`
+	// RateLimits optionally limits the rate of operations against the bucket.
+	RateLimits objstore.RateLimitConfig `yaml:"rate_limits"`
+	// Retry optionally retries failed operations against the bucket.
+	Retry objstore.RetryConfig `yaml:"retry"`
+}
+
+// TieredConfig is the config of a TIERED bucket, which keeps blocks in the hot bucket until their data is older
+// than the cut-over age, and in the cold bucket afterwards.
+type TieredConfig struct {
+	Hot        BucketConfig   `yaml:"hot"`
+	Cold       BucketConfig   `yaml:"cold"`
+	CutOverAge model.Duration `yaml:"cut_over_age"`
+}
+
+// MirroredConfig is the config of a MIRRORED bucket, which mirrors objects uploaded to the primary bucket to the
+// secondary bucket.
+type MirroredConfig struct {
+	Primary       BucketConfig `yaml:"primary"`
+	Secondary     BucketConfig `yaml:"secondary"`
+	mirror.Config `yaml:",inline"`
 }
 
 // NewBucket initializes and returns new object storage clients.
 // NOTE: confContentYaml can contain secrets.
 func NewBucket(logger log.Logger, confContentYaml []byte, reg prometheus.Registerer, component string) (objstore.InstrumentedBucket, error) {
+	bkt, _, err := NewBucketWithTiers(logger, confContentYaml, reg, component)
+	return bkt, err
+}
+
+// NewBucketWithTiers is like NewBucket, but if the bucket is of TIERED type, it also returns the tiered bucket, so
+// blocks can be moved between the tiers. The returned tiered bucket is nil for other types.
+// NOTE: confContentYaml can contain secrets.
+func NewBucketWithTiers(logger log.Logger, confContentYaml []byte, reg prometheus.Registerer, component string) (objstore.InstrumentedBucket, *tiered.Bucket, error) {
 	level.Info(logger).Log("msg", "loading bucket configuration")
 	bucketConf := &BucketConfig{}
 	if err := yaml.UnmarshalStrict(confContentYaml, bucketConf); err != nil {
-		return nil, errors.Wrap(err, "parsing config YAML file")
+		return nil, nil, errors.Wrap(err, "parsing config YAML file")
+	}
+
+	bucket, err := newBucket(logger, bucketConf, reg, component)
+	if err != nil {
+		return nil, nil, err
+	}
+	tieredBucket, _ := bucket.(*tiered.Bucket)
+	return objstore.NewTracingBucket(objstore.BucketWithMetrics(bucket.Name(), bucket, reg)), tieredBucket, nil
+}
+
+func newTieredBucket(logger log.Logger, config []byte, reg prometheus.Registerer, component string) (objstore.Bucket, error) {
+	var tieredConf TieredConfig
+	if err := yaml.UnmarshalStrict(config, &tieredConf); err != nil {
+		return nil, errors.Wrap(err, "parsing tiered config")
+	}
+	if isComposite(tieredConf.Hot.Type) || isComposite(tieredConf.Cold.Type) {
+		return nil, errors.New("tiers can't be tiered or mirrored buckets")
+	}
+
+	hot, err := newBucket(logger, &tieredConf.Hot, reg, component)
+	if err != nil {
+		return nil, errors.Wrap(err, "create hot tier")
+	}
+	cold, err := newBucket(logger, &tieredConf.Cold, reg, component)
+	if err != nil {
+		return nil, errors.Wrap(err, "create cold tier")
+	}
+	return tiered.NewBucket(logger, hot, cold, time.Duration(tieredConf.CutOverAge))
+}
+
+func newMirroredBucket(logger log.Logger, config []byte, reg prometheus.Registerer, component string) (objstore.Bucket, error) {
+	mirroredConf := MirroredConfig{Config: mirror.DefaultConfig}
+	if err := yaml.UnmarshalStrict(config, &mirroredConf); err != nil {
+		return nil, errors.Wrap(err, "parsing mirrored config")
+	}
+	if isComposite(mirroredConf.Primary.Type) || isComposite(mirroredConf.Secondary.Type) {
+		return nil, errors.New("primary and secondary buckets can't be tiered or mirrored buckets")
+	}
+
+	primary, err := newBucket(logger, &mirroredConf.Primary, reg, component)
+	if err != nil {
+		return nil, errors.Wrap(err, "create primary bucket")
+	}
+	secondary, err := newBucket(logger, &mirroredConf.Secondary, reg, component)
+	if err != nil {
+		return nil, errors.Wrap(err, "create secondary bucket")
 	}
+	return mirror.NewBucket(logger, primary, secondary, mirroredConf.Config, reg)
+}
 
+// isComposite returns true for types of buckets which are composed of other buckets.
+func isComposite(typ ObjProvider) bool {
+	return strings.EqualFold(string(typ), string(TIERED)) || strings.EqualFold(string(typ), string(MIRRORED))
+}
+
+func newBucket(logger log.Logger, bucketConf *BucketConfig, reg prometheus.Registerer, component string) (objstore.Bucket, error) {
 	config, err := yaml.Marshal(bucketConf.Config)
 	if err != nil {
 		return nil, errors.Wrap(err, "marshal content of bucket configuration")
 	}
 
-	var bucket objstore.Bucket
+	if isComposite(bucketConf.Type) && (bucketConf.Encryption.Type != "" || bucketConf.RateLimits != (objstore.RateLimitConfig{}) || bucketConf.Retry.Enabled()) {
+		return nil, errors.Errorf("encryption, rate limits and retries of %s buckets have to be configured for each of their buckets", bucketConf.Type)
+	}
+
+	var (
+		bucket     objstore.Bucket
+		statusCode objstore.StatusCodeFunc
+		// Failed operations are only retried with the retry configuration, instead of multiplying its attempts with
+		// the retries of the clients, if these can be disabled.
+		disableClientRetries = bucketConf.Retry.MaxRetries > 0
+	)
 	switch strings.ToUpper(string(bucketConf.Type)) {
 	case string(GCS):
 		bucket, err = gcs.NewBucket(context.Background(), logger, config, component)
+		statusCode = gcs.StatusCode
 	case string(S3):
+		if err = s3.ConfigureRetries(disableClientRetries); err != nil {
+			break
+		}
 		bucket, err = s3.NewBucket(logger, config, component)
+		statusCode = s3.StatusCode
 	case string(AZURE):
-		bucket, err = azure.NewBucket(logger, config, component)
+		var conf azure.Config
+		conf, err = azure.ParseConfig(config)
+		if err != nil {
+			break
+		}
+		if disableClientRetries {
+			conf = conf.WithoutRetries()
+		}
+		bucket, err = azure.NewBucketWithConfig(logger, conf, component)
+		statusCode = azure.StatusCode
 	case string(SWIFT):
 		bucket, err = swift.NewContainer(logger, config)
+		statusCode = swift.StatusCode
 	case string(COS):
 		bucket, err = cos.NewBucket(logger, config, component)
+		statusCode = cos.StatusCode
 	case string(ALIYUNOSS):
 		bucket, err = oss.NewBucket(logger, config, component)
+		statusCode = oss.StatusCode
 	case string(FILESYSTEM):
 		bucket, err = filesystem.NewBucketFromConfig(config)
 	case string(BOS):
-		bucket, err = bos.NewBucket(logger, config, component)
+		var b *bos.Bucket
+		b, err = bos.NewBucket(logger, config, component)
+		if err == nil && disableClientRetries {
+			b.DisableRetries()
+		}
+		bucket, statusCode = b, bos.StatusCode
+	case string(SFTP):
+		bucket, err = sftp.NewBucket(logger, config)
+	case string(TIERED):
+		bucket, err = newTieredBucket(logger, config, reg, component)
+	case string(MIRRORED):
+		bucket, err = newMirroredBucket(logger, config, reg, component)
 	default:
 		return nil, errors.Errorf("bucket with type %s is not supported", bucketConf.Type)
 	}
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("create %s client", bucketConf.Type))
 	}
-	return objstore.NewTracingBucket(objstore.BucketWithMetrics(bucket.Name(), bucket, reg)), nil
+	if bucketConf.Encryption.Type != "" {
+		bucket, err = encryption.NewBucketFromConfig(bucket, bucketConf.Encryption)
+		if err != nil {
+			return nil, errors.Wrap(err, "create encrypted bucket")
+		}
+	}
+	if bucketConf.RateLimits != (objstore.RateLimitConfig{}) {
+		bucket, err = objstore.NewRateLimitedBucket(bucket, bucketConf.RateLimits, reg)
+		if err != nil {
+			return nil, errors.Wrap(err, "create rate limited bucket")
+		}
+	}
+	if bucketConf.Retry.Enabled() {
+		bucket, err = objstore.NewRetryingBucket(bucket, bucketConf.Retry, statusCode, reg)
+		if err != nil {
+			return nil, errors.Wrap(err, "create retrying bucket")
+		}
+	}
+	return bucket, nil
 }
@@ -296,6 +296,14 @@ func (b *Bucket) IsObjNotFoundErr(err error) bool {
 	}
 }
 
+// StatusCode returns the HTTP status code of the error of a failed operation, if there is one.
+func StatusCode(err error) (int, bool) {
+	if cosErr, ok := errors.Cause(err).(*cos.ErrorResponse); ok && cosErr.Response != nil {
+		return cosErr.Response.StatusCode, true
+	}
+	return 0, false
+}
+
 func (b *Bucket) Close() error { return nil }
 
 type objectInfo struct {
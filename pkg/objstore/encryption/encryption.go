@@ -0,0 +1,437 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package encryption implements a bucket which encrypts objects on the client before they are uploaded
+// and decrypts them on read, independent of any server side encryption of the object storage provider.
+//
+// Every object is encrypted with its own random AES-256 data key, which is stored in the object header
+// wrapped with a key encryption key of a KeyProvider (envelope encryption). The content is encrypted with
+// AES-GCM in segments, so ranges of objects can be read without downloading the whole object.
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+const (
+	// segmentSize is the size of the plaintext of every encrypted segment but the last.
+	segmentSize = 64 * 1024
+	// tagSize is the size of the authentication tag appended to every encrypted segment.
+	tagSize = 16
+	// encryptedSegmentSize is the size of every encrypted segment but the last.
+	encryptedSegmentSize = segmentSize + tagSize
+
+	noncePrefixSize = 7
+	// fixedHeaderSize is the size of the header without the wrapped key: magic, version, nonce prefix and wrapped key size.
+	fixedHeaderSize = 4 + 1 + noncePrefixSize + 2
+	// maxWrappedKeySize is the maximum size of a wrapped key, so the header can be read with a single range request.
+	maxWrappedKeySize = 1024
+
+	formatVersion = 1
+
+	// unwrappedKeysCacheSize is the number of unwrapped data keys kept in memory, to avoid unwrapping
+	// the key of an object for every range read.
+	unwrappedKeysCacheSize = 10000
+)
+
+var magic = []byte("TENC")
+
+// Config holds the config of the key provider used to encrypt the objects of a bucket.
+// Encryption is disabled if no type is set.
+type Config struct {
+	Type   KeyProviderType `yaml:"type"`
+	Config interface{}     `yaml:"config"`
+}
+
+// Bucket encrypts objects before they are uploaded to the wrapped bucket and decrypts them on read.
+// Object names and attributes other than the size are not encrypted.
+type Bucket struct {
+	objstore.Bucket
+	keys KeyProvider
+
+	unwrapped *lru.Cache
+}
+
+// NewBucketFromConfig returns a Bucket encrypting the objects of bkt with the key provider of the config.
+func NewBucketFromConfig(bkt objstore.Bucket, conf Config) (*Bucket, error) {
+	keyConfig, err := yaml.Marshal(conf.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal content of key provider configuration")
+	}
+	keys, err := NewKeyProvider(conf.Type, keyConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewBucket(bkt, keys), nil
+}
+
+// NewBucket returns a Bucket encrypting the objects of bkt with keys wrapped by the key provider.
+func NewBucket(bkt objstore.Bucket, keys KeyProvider) *Bucket {
+	// Only fails for non-positive sizes.
+	unwrapped, _ := lru.New(unwrappedKeysCacheSize)
+	return &Bucket{Bucket: bkt, keys: keys, unwrapped: unwrapped}
+}
+
+// Upload encrypts the contents of the reader and uploads them as an object into the bucket.
+func (b *Bucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return errors.Wrap(err, "generate data key")
+	}
+	wrapped, err := b.keys.WrapKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	if len(wrapped) > maxWrappedKeySize {
+		return errors.Errorf("wrapped key too large: %d bytes", len(wrapped))
+	}
+	h := header{noncePrefix: make([]byte, noncePrefixSize), wrappedKey: wrapped}
+	if _, err := rand.Read(h.noncePrefix); err != nil {
+		return errors.Wrap(err, "generate nonce prefix")
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	return b.Bucket.Upload(ctx, name, newEncryptReader(r, aead, h))
+}
+
+// Get returns a reader for the decrypted content of the given object.
+func (b *Bucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	rc, err := b.Bucket.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReaderSize(rc, encryptedSegmentSize+1)
+	h, err := readHeader(br)
+	if err != nil {
+		_ = rc.Close()
+		return nil, errors.Wrapf(err, "read header of %s", name)
+	}
+	aead, err := b.aead(ctx, h)
+	if err != nil {
+		_ = rc.Close()
+		return nil, errors.Wrapf(err, "get key of %s", name)
+	}
+	return &decryptReader{r: br, c: rc, aead: aead, h: h, verifyLast: true}, nil
+}
+
+// GetRange returns a reader for the given range of the decrypted content of the given object.
+func (b *Bucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	if off < 0 {
+		return nil, errors.Errorf("invalid offset %d", off)
+	}
+	if length == 0 {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	hrc, err := b.Bucket.GetRange(ctx, name, 0, fixedHeaderSize+maxWrappedKeySize)
+	if err != nil {
+		return nil, err
+	}
+	h, err := readHeader(hrc)
+	_ = hrc.Close()
+	if err != nil {
+		return nil, errors.Wrapf(err, "read header of %s", name)
+	}
+	aead, err := b.aead(ctx, h)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get key of %s", name)
+	}
+
+	first := off / segmentSize
+	encOff := h.size() + first*encryptedSegmentSize
+	encLength := int64(-1)
+	if length > 0 {
+		last := (off + length - 1) / segmentSize
+		encLength = (last - first + 1) * encryptedSegmentSize
+	}
+	rc, err := b.Bucket.GetRange(ctx, name, encOff, encLength)
+	if err != nil {
+		return nil, err
+	}
+
+	dr := &decryptReader{r: bufio.NewReaderSize(rc, encryptedSegmentSize+1), c: rc, aead: aead, h: h, segment: uint32(first)}
+	if _, err := io.CopyN(ioutil.Discard, dr, off-first*segmentSize); err != nil && err != io.EOF {
+		_ = dr.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return dr, nil
+	}
+	return &limitedReadCloser{Reader: io.LimitReader(dr, length), Closer: dr}, nil
+}
+
+// Attributes returns information about the specified object, with the size of the decrypted content.
+func (b *Bucket) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	attrs, err := b.Bucket.Attributes(ctx, name)
+	if err != nil {
+		return attrs, err
+	}
+	if attrs.Size > 0 {
+		hrc, err := b.Bucket.GetRange(ctx, name, 0, fixedHeaderSize+maxWrappedKeySize)
+		if err != nil {
+			return attrs, err
+		}
+		h, err := readHeader(hrc)
+		_ = hrc.Close()
+		if err != nil {
+			return attrs, errors.Wrapf(err, "read header of %s", name)
+		}
+		attrs.Size = plaintextSize(attrs.Size - h.size())
+	}
+	return attrs, nil
+}
+
+// aead returns the cipher of the data key in the header.
+func (b *Bucket) aead(ctx context.Context, h header) (cipher.AEAD, error) {
+	if aead, ok := b.unwrapped.Get(string(h.wrappedKey)); ok {
+		return aead.(cipher.AEAD), nil
+	}
+	key, err := b.keys.UnwrapKey(ctx, h.wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	b.unwrapped.Add(string(h.wrappedKey), aead)
+	return aead, nil
+}
+
+// header is the header of an encrypted object.
+type header struct {
+	noncePrefix []byte
+	wrappedKey  []byte
+}
+
+func (h header) size() int64 { return int64(fixedHeaderSize + len(h.wrappedKey)) }
+
+func (h header) bytes() []byte {
+	b := make([]byte, 0, h.size())
+	b = append(b, magic...)
+	b = append(b, formatVersion)
+	b = append(b, h.noncePrefix...)
+	b = append(b, 0, 0)
+	binary.BigEndian.PutUint16(b[len(b)-2:], uint16(len(h.wrappedKey)))
+	return append(b, h.wrappedKey...)
+}
+
+// nonce returns the nonce of the segment. The last segment has its own nonce, so truncated objects are detected.
+func (h header) nonce(segment uint32, last bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, h.noncePrefix...)
+	nonce = append(nonce, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], segment)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+func readHeader(r io.Reader) (header, error) {
+	fixed := make([]byte, fixedHeaderSize)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return header{}, errors.Wrap(err, "object is not encrypted or corrupted")
+	}
+	if !bytes.Equal(fixed[:len(magic)], magic) {
+		return header{}, errors.New("object is not encrypted")
+	}
+	if v := fixed[len(magic)]; v != formatVersion {
+		return header{}, errors.Errorf("unsupported encryption format version %d", v)
+	}
+	h := header{
+		noncePrefix: fixed[len(magic)+1 : len(magic)+1+noncePrefixSize],
+		wrappedKey:  make([]byte, binary.BigEndian.Uint16(fixed[fixedHeaderSize-2:])),
+	}
+	if _, err := io.ReadFull(r, h.wrappedKey); err != nil {
+		return header{}, errors.Wrap(err, "read wrapped key")
+	}
+	return h, nil
+}
+
+// encryptedSize returns the size of the encrypted content, without the header, for the plaintext size.
+func encryptedSize(size int64) int64 {
+	segments := (size + segmentSize - 1) / segmentSize
+	if segments == 0 {
+		segments = 1
+	}
+	return size + segments*tagSize
+}
+
+// plaintextSize returns the size of the plaintext for the size of the encrypted content, without the header.
+func plaintextSize(size int64) int64 {
+	segments := (size + encryptedSegmentSize - 1) / encryptedSegmentSize
+	return size - segments*tagSize
+}
+
+// encryptReader encrypts the content of a reader segment by segment.
+type encryptReader struct {
+	r    io.Reader
+	aead cipher.AEAD
+	h    header
+	size int64
+
+	segment uint32
+	next    []byte
+	srcEOF  bool
+	out     []byte
+	done    bool
+}
+
+func newEncryptReader(r io.Reader, aead cipher.AEAD, h header) *encryptReader {
+	size := int64(-1)
+	if s, err := objstore.TryToGetSize(r); err == nil {
+		size = h.size() + encryptedSize(s)
+	}
+	return &encryptReader{r: r, aead: aead, h: h, size: size, out: h.bytes()}
+}
+
+// ObjectSize implements objstore.ObjectSizer, so the encrypted size is known upfront if the plaintext size is.
+func (e *encryptReader) ObjectSize() (int64, error) {
+	if e.size < 0 {
+		return 0, errors.New("size of the content is not known")
+	}
+	return e.size, nil
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for len(e.out) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		if err := e.encryptSegment(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, e.out)
+	e.out = e.out[n:]
+	return n, nil
+}
+
+func (e *encryptReader) encryptSegment() error {
+	if e.next == nil {
+		if err := e.readSegment(); err != nil {
+			return err
+		}
+	}
+	cur := e.next
+	e.next = nil
+	if !e.srcEOF {
+		if err := e.readSegment(); err != nil {
+			return err
+		}
+	}
+	last := e.srcEOF && len(e.next) == 0
+
+	e.out = e.aead.Seal(e.out[:0], e.h.nonce(e.segment, last), cur, nil)
+	e.segment++
+	e.done = last
+	return nil
+}
+
+func (e *encryptReader) readSegment() error {
+	buf := make([]byte, segmentSize)
+	n, err := io.ReadFull(e.r, buf)
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		e.srcEOF = true
+	default:
+		return err
+	}
+	e.next = buf[:n]
+	return nil
+}
+
+// decryptReader decrypts segments of an encrypted object.
+type decryptReader struct {
+	r    *bufio.Reader
+	c    io.Closer
+	aead cipher.AEAD
+	h    header
+	// verifyLast is true if the reader reads until the end of the object, so the last segment must be flagged as such.
+	verifyLast bool
+
+	segment uint32
+	out     []byte
+	done    bool
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.decryptSegment(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+func (d *decryptReader) decryptSegment() error {
+	buf := make([]byte, encryptedSegmentSize)
+	n, err := io.ReadFull(d.r, buf)
+	switch err {
+	case nil:
+	case io.EOF:
+		if d.verifyLast {
+			return errors.New("encrypted object is truncated")
+		}
+		d.done = true
+		return nil
+	case io.ErrUnexpectedEOF:
+	default:
+		return err
+	}
+	buf = buf[:n]
+
+	_, peekErr := d.r.Peek(1)
+	end := peekErr == io.EOF
+	var out []byte
+	switch {
+	case n < encryptedSegmentSize || (end && d.verifyLast):
+		// Only the last segment can be shorter.
+		out, err = d.aead.Open(buf[:0], d.h.nonce(d.segment, true), buf, nil)
+	case end:
+		// The end of a range can be the end of the object, or not.
+		out, err = d.aead.Open(nil, d.h.nonce(d.segment, false), buf, nil)
+		if err != nil {
+			out, err = d.aead.Open(buf[:0], d.h.nonce(d.segment, true), buf, nil)
+		}
+	default:
+		out, err = d.aead.Open(buf[:0], d.h.nonce(d.segment, false), buf, nil)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "decrypt segment %d", d.segment)
+	}
+	d.out = out
+	d.segment++
+	d.done = end
+	return nil
+}
+
+func (d *decryptReader) Close() error { return d.c.Close() }
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,152 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func writeKeyFile(t *testing.T, dir, name string) string {
+	key := make([]byte, keySize)
+	_, err := rand.Read(key)
+	testutil.Ok(t, err)
+	file := filepath.Join(dir, name)
+	testutil.Ok(t, ioutil.WriteFile(file, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600))
+	return file
+}
+
+func newTestBucket(t *testing.T, bkt objstore.Bucket, keyFile string, previousKeyFiles ...string) *Bucket {
+	keys, err := NewFileKeyProvider(FileKeyConfig{KeyFile: keyFile, PreviousKeyFiles: previousKeyFiles})
+	testutil.Ok(t, err)
+	return NewBucket(bkt, keys)
+}
+
+func TestBucket_AcceptanceTest(t *testing.T) {
+	objstore.AcceptanceTest(t, newTestBucket(t, objstore.NewInMemBucket(), writeKeyFile(t, t.TempDir(), "key")))
+}
+
+func TestBucket_UploadGet(t *testing.T) {
+	ctx := context.Background()
+	inmem := objstore.NewInMemBucket()
+	bkt := newTestBucket(t, inmem, writeKeyFile(t, t.TempDir(), "key"))
+
+	for _, size := range []int{0, 1, segmentSize - 1, segmentSize, segmentSize + 1, 3*segmentSize + 123} {
+		content := make([]byte, size)
+		_, err := rand.Read(content)
+		testutil.Ok(t, err)
+
+		testutil.Ok(t, bkt.Upload(ctx, "obj", bytes.NewReader(content)))
+
+		// The stored object doesn't contain the plaintext. Short contents can appear in the ciphertext by chance.
+		stored, err := inmem.Get(ctx, "obj")
+		testutil.Ok(t, err)
+		storedContent, err := ioutil.ReadAll(stored)
+		testutil.Ok(t, err)
+		if size > 16 {
+			testutil.Assert(t, !bytes.Contains(storedContent, content), "expected content to be encrypted")
+		}
+
+		rc, err := bkt.Get(ctx, "obj")
+		testutil.Ok(t, err)
+		got, err := ioutil.ReadAll(rc)
+		testutil.Ok(t, err)
+		testutil.Ok(t, rc.Close())
+		testutil.Equals(t, content, got)
+
+		attrs, err := bkt.Attributes(ctx, "obj")
+		testutil.Ok(t, err)
+		testutil.Equals(t, int64(size), attrs.Size)
+
+		for _, r := range [][2]int64{
+			{0, 1},
+			{0, int64(size)},
+			{int64(size) / 2, int64(size) / 3},
+			{segmentSize - 10, 20},
+			{segmentSize, segmentSize},
+			{int64(size) / 2, -1},
+			{0, math.MaxInt32},
+		} {
+			off, length := r[0], r[1]
+			if off >= int64(size) || length == 0 {
+				continue
+			}
+			rc, err := bkt.GetRange(ctx, "obj", off, length)
+			testutil.Ok(t, err)
+			got, err := ioutil.ReadAll(rc)
+			testutil.Ok(t, err)
+			testutil.Ok(t, rc.Close())
+
+			end := int64(size)
+			if length >= 0 && off+length < end {
+				end = off + length
+			}
+			testutil.Equals(t, content[off:end], got)
+		}
+	}
+}
+
+func TestBucket_Tampering(t *testing.T) {
+	ctx := context.Background()
+	inmem := objstore.NewInMemBucket()
+	bkt := newTestBucket(t, inmem, writeKeyFile(t, t.TempDir(), "key"))
+
+	content := make([]byte, 2*segmentSize)
+	testutil.Ok(t, bkt.Upload(ctx, "obj", bytes.NewReader(content)))
+	stored, err := inmem.Get(ctx, "obj")
+	testutil.Ok(t, err)
+	encrypted, err := ioutil.ReadAll(stored)
+	testutil.Ok(t, err)
+
+	// Modified content.
+	modified := append([]byte{}, encrypted...)
+	modified[len(modified)-100] ^= 1
+	testutil.Ok(t, inmem.Upload(ctx, "modified", bytes.NewReader(modified)))
+	rc, err := bkt.Get(ctx, "modified")
+	testutil.Ok(t, err)
+	_, err = ioutil.ReadAll(rc)
+	testutil.NotOk(t, err)
+
+	// Object truncated at a segment boundary.
+	truncated := encrypted[:len(encrypted)-encryptedSegmentSize]
+	testutil.Ok(t, inmem.Upload(ctx, "truncated", bytes.NewReader(truncated)))
+	rc, err = bkt.Get(ctx, "truncated")
+	testutil.Ok(t, err)
+	_, err = ioutil.ReadAll(rc)
+	testutil.NotOk(t, err)
+
+	// Plaintext objects are not read.
+	testutil.Ok(t, inmem.Upload(ctx, "plain", bytes.NewReader([]byte("plain"))))
+	_, err = bkt.Get(ctx, "plain")
+	testutil.NotOk(t, err)
+}
+
+func TestBucket_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	oldKey, newKey := writeKeyFile(t, dir, "old"), writeKeyFile(t, dir, "new")
+	inmem := objstore.NewInMemBucket()
+
+	testutil.Ok(t, newTestBucket(t, inmem, oldKey).Upload(ctx, "obj", bytes.NewReader([]byte("content"))))
+
+	// Objects encrypted with a previous key can still be read.
+	rc, err := newTestBucket(t, inmem, newKey, oldKey).Get(ctx, "obj")
+	testutil.Ok(t, err)
+	got, err := ioutil.ReadAll(rc)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []byte("content"), got)
+
+	// Objects encrypted with an unknown key can't.
+	_, err = newTestBucket(t, inmem, newKey).Get(ctx, "obj")
+	testutil.NotOk(t, err)
+}
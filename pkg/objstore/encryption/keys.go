@@ -0,0 +1,203 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type KeyProviderType string
+
+const (
+	FILE   KeyProviderType = "FILE"
+	AWSKMS KeyProviderType = "AWS_KMS"
+)
+
+// keySize is the size of the AES-256 keys used as data and key encryption keys.
+const keySize = 32
+
+// KeyProvider wraps the data encryption key of every object with a key encryption key, which is never stored
+// in the bucket.
+type KeyProvider interface {
+	// WrapKey encrypts the data encryption key of an object.
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	// UnwrapKey decrypts a data encryption key wrapped with WrapKey.
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// FileKeyConfig holds the config of key encryption keys stored in local files.
+type FileKeyConfig struct {
+	// KeyFile is the path of the file with the base64 encoded 256 bit key used to wrap keys of new objects.
+	KeyFile string `yaml:"key_file"`
+	// PreviousKeyFiles are the paths of files with keys used before, which are still needed to read older objects.
+	PreviousKeyFiles []string `yaml:"previous_key_files"`
+}
+
+// AWSKMSConfig holds the config of a key encryption key managed by AWS KMS.
+type AWSKMSConfig struct {
+	// KeyID is the ID, ARN or alias of the KMS key used to wrap keys of new objects.
+	KeyID    string `yaml:"key_id"`
+	Region   string `yaml:"region"`
+	Endpoint string `yaml:"endpoint"`
+}
+
+// NewKeyProvider returns the key provider of the given type, configured with the YAML config.
+func NewKeyProvider(typ KeyProviderType, conf []byte) (KeyProvider, error) {
+	switch strings.ToUpper(string(typ)) {
+	case string(FILE):
+		var c FileKeyConfig
+		if err := yaml.UnmarshalStrict(conf, &c); err != nil {
+			return nil, errors.Wrap(err, "parsing file key config")
+		}
+		return NewFileKeyProvider(c)
+	case string(AWSKMS):
+		var c AWSKMSConfig
+		if err := yaml.UnmarshalStrict(conf, &c); err != nil {
+			return nil, errors.Wrap(err, "parsing AWS KMS key config")
+		}
+		return NewAWSKMSKeyProvider(c)
+	default:
+		return nil, errors.Errorf("key provider with type %s is not supported", typ)
+	}
+}
+
+// fileKeyProvider wraps keys with AES-GCM using keys read from files. Wrapped keys are prefixed with
+// the ID of the key which wrapped them, so keys can be rotated while older objects can still be read.
+type fileKeyProvider struct {
+	current []byte
+	keys    map[string]cipher.AEAD
+}
+
+// keyIDSize is the size of the key ID prefix of keys wrapped by the file key provider.
+const keyIDSize = 8
+
+// NewFileKeyProvider returns a KeyProvider using the keys stored in the files of the config.
+func NewFileKeyProvider(conf FileKeyConfig) (KeyProvider, error) {
+	if conf.KeyFile == "" {
+		return nil, errors.New("missing key_file for file key provider")
+	}
+
+	p := &fileKeyProvider{keys: map[string]cipher.AEAD{}}
+	for i, file := range append([]string{conf.KeyFile}, conf.PreviousKeyFiles...) {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read key file %s", file)
+		}
+		key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(b)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode key file %s", file)
+		}
+		if len(key) != keySize {
+			return nil, errors.Errorf("key in %s must be %d bytes, got %d", file, keySize, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(key)
+		id := sum[:keyIDSize]
+		if i == 0 {
+			p.current = id
+		}
+		p.keys[string(id)] = aead
+	}
+	return p, nil
+}
+
+func (p *fileKeyProvider) WrapKey(_ context.Context, key []byte) ([]byte, error) {
+	aead := p.keys[string(p.current)]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "generate nonce")
+	}
+
+	wrapped := append(append([]byte{}, p.current...), nonce...)
+	return aead.Seal(wrapped, nonce, key, p.current), nil
+}
+
+func (p *fileKeyProvider) UnwrapKey(_ context.Context, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < keyIDSize {
+		return nil, errors.New("wrapped key too short")
+	}
+	id := wrapped[:keyIDSize]
+	aead, ok := p.keys[string(id)]
+	if !ok {
+		return nil, errors.New("object was encrypted with an unknown key")
+	}
+	wrapped = wrapped[keyIDSize:]
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped key too short")
+	}
+	key, err := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], id)
+	if err != nil {
+		return nil, errors.Wrap(err, "unwrap key")
+	}
+	return key, nil
+}
+
+// awsKMSKeyProvider wraps keys with a key managed by AWS KMS.
+type awsKMSKeyProvider struct {
+	keyID  string
+	client *kms.KMS
+}
+
+// NewAWSKMSKeyProvider returns a KeyProvider using the AWS KMS key of the config. Credentials are taken
+// from the default AWS credential chain.
+func NewAWSKMSKeyProvider(conf AWSKMSConfig) (KeyProvider, error) {
+	if conf.KeyID == "" {
+		return nil, errors.New("missing key_id for AWS KMS key provider")
+	}
+
+	cfg := aws.NewConfig()
+	if conf.Region != "" {
+		cfg = cfg.WithRegion(conf.Region)
+	}
+	if conf.Endpoint != "" {
+		cfg = cfg.WithEndpoint(conf.Endpoint)
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "create AWS session")
+	}
+	return &awsKMSKeyProvider{keyID: conf.KeyID, client: kms.New(sess)}, nil
+}
+
+func (p *awsKMSKeyProvider) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	out, err := p.client.EncryptWithContext(ctx, &kms.EncryptInput{KeyId: aws.String(p.keyID), Plaintext: key})
+	if err != nil {
+		return nil, errors.Wrap(err, "wrap key with AWS KMS")
+	}
+	return out.CiphertextBlob, nil
+}
+
+func (p *awsKMSKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	out, err := p.client.DecryptWithContext(ctx, &kms.DecryptInput{CiphertextBlob: wrapped})
+	if err != nil {
+		return nil, errors.Wrap(err, "unwrap key with AWS KMS")
+	}
+	return out.Plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "create cipher")
+	}
+	return cipher.NewGCM(block)
+}
@@ -7,9 +7,13 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid"
 
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"gopkg.in/yaml.v2"
@@ -19,16 +23,26 @@ import (
 	"github.com/pkg/errors"
 )
 
+// tmpFilePrefix is the prefix of the temporary files which objects are written to before they are renamed.
+// Such files are not listed.
+const tmpFilePrefix = ".thanos-upload-"
+
 // Config stores the configuration for storing and accessing blobs in filesystem.
 type Config struct {
 	Directory string `yaml:"directory"`
+	// Fsync makes uploads sync the written files and their directories to disk before they return, so uploaded
+	// objects are not lost on crashes of the machine.
+	Fsync bool `yaml:"fsync"`
 }
 
 // Bucket implements the objstore.Bucket interfaces against filesystem that binary runs on.
 // Methods from Bucket interface are thread-safe. Objects are assumed to be immutable.
+// Objects are written to temporary files which are renamed once complete, so partially written objects are never
+// visible, also on shared filesystems like NFS.
 // NOTE: It does not follow symbolic links.
 type Bucket struct {
 	rootDir string
+	fsync   bool
 }
 
 // NewBucketFromConfig returns a new filesystem.Bucket from config.
@@ -40,16 +54,21 @@ func NewBucketFromConfig(conf []byte) (*Bucket, error) {
 	if c.Directory == "" {
 		return nil, errors.New("missing directory for filesystem bucket")
 	}
-	return NewBucket(c.Directory)
+	return NewBucketWithConfig(c)
 }
 
 // NewBucket returns a new filesystem.Bucket.
 func NewBucket(rootDir string) (*Bucket, error) {
-	absDir, err := filepath.Abs(rootDir)
+	return NewBucketWithConfig(Config{Directory: rootDir})
+}
+
+// NewBucketWithConfig returns a new filesystem.Bucket from the given config.
+func NewBucketWithConfig(c Config) (*Bucket, error) {
+	absDir, err := filepath.Abs(c.Directory)
 	if err != nil {
 		return nil, err
 	}
-	return &Bucket{rootDir: absDir}, nil
+	return &Bucket{rootDir: absDir, fsync: c.Fsync}, nil
 }
 
 // Iter calls f for each entry in the given directory. The argument to f is the full
@@ -68,15 +87,24 @@ func (b *Bucket) Iter(ctx context.Context, dir string, f func(string) error, opt
 		return nil
 	}
 
-	files, err := ioutil.ReadDir(absDir)
+	// Entries are read without calling stat on each of them, which is expensive for directories with many entries,
+	// especially on network filesystems.
+	entries, err := os.ReadDir(absDir)
 	if err != nil {
 		return err
 	}
-	for _, file := range files {
-		name := filepath.Join(dir, file.Name())
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), tmpFilePrefix) {
+			// Skip objects which are being uploaded.
+			continue
+		}
+		name := filepath.Join(dir, entry.Name())
 
-		if file.IsDir() {
-			empty, err := isDirEmpty(filepath.Join(absDir, file.Name()))
+		if entry.IsDir() {
+			empty, err := isDirEmpty(filepath.Join(absDir, entry.Name()))
 			if err != nil {
 				return err
 			}
@@ -176,25 +204,63 @@ func (b *Bucket) Exists(_ context.Context, name string) (bool, error) {
 	return !info.IsDir(), nil
 }
 
-// Upload writes the file specified in src to into the memory.
+// Upload writes the contents of the reader to a temporary file, which is renamed to the object once complete.
 func (b *Bucket) Upload(_ context.Context, name string, r io.Reader) (err error) {
 	file := filepath.Join(b.rootDir, name)
-	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
 		return err
 	}
 
-	f, err := os.Create(file)
+	// Unlike ioutil.TempFile, this creates the file with the same permissions as os.Create.
+	tmpName := filepath.Join(dir, fmt.Sprintf("%s%s-%s", tmpFilePrefix, filepath.Base(file), ulid.MustNew(ulid.Now(), rand.New(rand.NewSource(time.Now().UnixNano())))))
+	tmp, err := os.OpenFile(tmpName, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
 	if err != nil {
-		return err
+		return errors.Wrapf(err, "create temporary file for %s", file)
 	}
-	defer runutil.CloseWithErrCapture(&err, f, "close")
+	defer func() {
+		if err != nil {
+			// The file was either closed already or its close error is irrelevant, as the upload failed.
+			_ = tmp.Close()
+			if rmErr := os.Remove(tmp.Name()); rmErr != nil && !os.IsNotExist(rmErr) {
+				err = errors.Wrapf(err, "remove temporary file %s: %v", tmp.Name(), rmErr)
+			}
+		}
+	}()
 
-	if _, err := io.Copy(f, r); err != nil {
-		return errors.Wrapf(err, "copy to %s", file)
+	if _, err := io.Copy(tmp, r); err != nil {
+		return errors.Wrapf(err, "copy to %s", tmp.Name())
+	}
+	if b.fsync {
+		if err := tmp.Sync(); err != nil {
+			return errors.Wrapf(err, "sync %s", tmp.Name())
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "close %s", tmp.Name())
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return errors.Wrapf(err, "rename %s to %s", tmp.Name(), file)
+	}
+	if b.fsync {
+		// Sync the directory to persist the rename.
+		if err := syncDir(dir); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+func syncDir(name string) (err error) {
+	d, err := os.Open(filepath.Clean(name))
+	if err != nil {
+		return err
+	}
+	defer runutil.CloseWithErrCapture(&err, d, "dir close")
+
+	return errors.Wrapf(d.Sync(), "sync %s", name)
+}
+
 func isDirEmpty(name string) (ok bool, err error) {
 	f, err := os.Open(filepath.Clean(name))
 	if err != nil {
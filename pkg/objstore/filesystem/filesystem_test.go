@@ -0,0 +1,72 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package filesystem
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestBucket_AcceptanceTest(t *testing.T) {
+	for _, fsync := range []bool{false, true} {
+		dir, err := ioutil.TempDir("", "filesystem-bucket")
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+		bkt, err := NewBucketWithConfig(Config{Directory: dir, Fsync: fsync})
+		testutil.Ok(t, err)
+		objstore.AcceptanceTest(t, bkt)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func TestBucket_Upload(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "filesystem-bucket")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	bkt, err := NewBucket(dir)
+	testutil.Ok(t, err)
+
+	t.Run("failed uploads leave no object behind", func(t *testing.T) {
+		testutil.NotOk(t, bkt.Upload(ctx, "dir/failed", io.MultiReader(strings.NewReader("partial"), failingReader{})))
+		ok, err := bkt.Exists(ctx, "dir/failed")
+		testutil.Ok(t, err)
+		testutil.Assert(t, !ok, "failed upload must not create the object")
+
+		entries, err := ioutil.ReadDir(filepath.Join(dir, "dir"))
+		testutil.Ok(t, err)
+		testutil.Equals(t, 0, len(entries))
+	})
+	t.Run("objects being uploaded are not listed", func(t *testing.T) {
+		testutil.Ok(t, bkt.Upload(ctx, "dir/obj", strings.NewReader("data")))
+		testutil.Ok(t, ioutil.WriteFile(filepath.Join(dir, "dir", tmpFilePrefix+"other-123"), []byte("partial"), 0600))
+
+		var names []string
+		testutil.Ok(t, bkt.Iter(ctx, "", func(name string) error {
+			names = append(names, name)
+			return nil
+		}, objstore.WithRecursiveIter))
+		testutil.Equals(t, []string{"dir/obj"}, names)
+	})
+	t.Run("iter stops when the context is cancelled", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		testutil.NotOk(t, bkt.Iter(cctx, "", func(string) error { return nil }))
+	})
+}
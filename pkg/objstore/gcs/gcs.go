@@ -8,6 +8,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"runtime"
 	"strings"
 	"testing"
@@ -17,6 +19,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/version"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"gopkg.in/yaml.v2"
@@ -31,13 +34,21 @@ const DirDelim = "/"
 type Config struct {
 	Bucket         string `yaml:"bucket"`
 	ServiceAccount string `yaml:"service_account"`
+	// Endpoint overrides the GCS JSON API endpoint, e.g. for private service connect or emulators like fake-gcs-server.
+	Endpoint string `yaml:"endpoint"`
+	// InsecureSkipAuth disables authentication, which is only useful with emulators.
+	InsecureSkipAuth bool `yaml:"insecure_skip_auth"`
+	// KMSKeyName is the Cloud KMS key used to encrypt uploaded objects, in the
+	// projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key> format.
+	KMSKeyName string `yaml:"kms_key_name"`
 }
 
 // Bucket implements the store.Bucket and shipper.Bucket interfaces against GCS.
 type Bucket struct {
-	logger log.Logger
-	bkt    *storage.BucketHandle
-	name   string
+	logger     log.Logger
+	bkt        *storage.BucketHandle
+	name       string
+	kmsKeyName string
 
 	closer io.Closer
 }
@@ -59,6 +70,25 @@ func NewBucketWithConfig(ctx context.Context, logger log.Logger, gc Config, comp
 
 	var opts []option.ClientOption
 
+	if gc.Endpoint != "" {
+		u, err := url.Parse(gc.Endpoint)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse endpoint")
+		}
+		opts = append(opts, option.WithEndpoint(gc.Endpoint))
+
+		if u.Scheme == "http" {
+			if !gc.InsecureSkipAuth {
+				return nil, errors.New("plain HTTP endpoints can only be used with insecure_skip_auth, to not send credentials in clear text")
+			}
+			// The client reads objects over HTTPS, unless the emulator environment variable is set.
+			opts = append(opts, option.WithHTTPClient(&http.Client{Transport: httpSchemeTransport{host: u.Host}}))
+		}
+	}
+	if gc.InsecureSkipAuth {
+		opts = append(opts, option.WithoutAuthentication())
+	}
+
 	// If ServiceAccount is provided, use them in GCS client, otherwise fallback to Google default logic.
 	if gc.ServiceAccount != "" {
 		credentials, err := google.CredentialsFromJSON(ctx, []byte(gc.ServiceAccount), storage.ScopeFullControl)
@@ -77,14 +107,28 @@ func NewBucketWithConfig(ctx context.Context, logger log.Logger, gc Config, comp
 		return nil, err
 	}
 	bkt := &Bucket{
-		logger: logger,
-		bkt:    gcsClient.Bucket(gc.Bucket),
-		closer: gcsClient,
-		name:   gc.Bucket,
+		logger:     logger,
+		bkt:        gcsClient.Bucket(gc.Bucket),
+		closer:     gcsClient,
+		name:       gc.Bucket,
+		kmsKeyName: gc.KMSKeyName,
 	}
 	return bkt, nil
 }
 
+// httpSchemeTransport sends requests to the given host over plain HTTP.
+type httpSchemeTransport struct {
+	host string
+}
+
+func (t httpSchemeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Host == t.host && r.URL.Scheme != "http" {
+		r = r.Clone(r.Context())
+		r.URL.Scheme = "http"
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
 // Name returns the bucket name for gcs.
 func (b *Bucket) Name() string {
 	return b.name
@@ -170,6 +214,7 @@ func (b *Bucket) Exists(ctx context.Context, name string) (bool, error) {
 // Upload writes the file specified in src to remote GCS location specified as target.
 func (b *Bucket) Upload(ctx context.Context, name string, r io.Reader) error {
 	w := b.bkt.Object(name).NewWriter(ctx)
+	w.KMSKeyName = b.kmsKeyName
 
 	if _, err := io.Copy(w, r); err != nil {
 		return err
@@ -187,6 +232,14 @@ func (b *Bucket) IsObjNotFoundErr(err error) bool {
 	return errors.Is(err, storage.ErrObjectNotExist)
 }
 
+// StatusCode returns the HTTP status code of the error of a failed operation, if there is one.
+func StatusCode(err error) (int, bool) {
+	if gerr, ok := errors.Cause(err).(*googleapi.Error); ok {
+		return gerr.Code, true
+	}
+	return 0, false
+}
+
 func (b *Bucket) Close() error {
 	return b.closer.Close()
 }
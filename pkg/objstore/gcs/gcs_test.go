@@ -5,11 +5,17 @@ package gcs
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/go-kit/log"
@@ -45,3 +51,77 @@ func TestBucket_Get_ShouldReturnErrorIfServerTruncateResponse(t *testing.T) {
 	_, err = ioutil.ReadAll(reader)
 	testutil.Equals(t, io.ErrUnexpectedEOF, err)
 }
+
+func TestBucket_CustomEndpointAndKMSKey(t *testing.T) {
+	t.Setenv("STORAGE_EMULATOR_HOST", "")
+
+	const kmsKeyName = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+
+	var (
+		mtx     sync.Mutex
+		objects = map[string]string{}
+	)
+	// A minimal emulator of the JSON API uploads and the XML API downloads.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/test-bucket/o":
+			testutil.Equals(t, kmsKeyName, r.URL.Query().Get("kmsKeyName"))
+			_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			testutil.Ok(t, err)
+			mr := multipart.NewReader(r.Body, params["boundary"])
+
+			metadata, err := mr.NextPart()
+			testutil.Ok(t, err)
+			var attrs struct {
+				Name string `json:"name"`
+			}
+			testutil.Ok(t, json.NewDecoder(metadata).Decode(&attrs))
+			media, err := mr.NextPart()
+			testutil.Ok(t, err)
+			content, err := ioutil.ReadAll(media)
+			testutil.Ok(t, err)
+
+			objects[attrs.Name] = string(content)
+			fmt.Fprintf(w, `{"bucket":"test-bucket","name":%q,"size":"%d","kmsKeyName":%q}`, attrs.Name, len(content), kmsKeyName)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/test-bucket/"):
+			content, ok := objects[strings.TrimPrefix(r.URL.Path, "/test-bucket/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+			_, _ = w.Write([]byte(content))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := Config{
+		Bucket:     "test-bucket",
+		Endpoint:   srv.URL + "/storage/v1/",
+		KMSKeyName: kmsKeyName,
+	}
+
+	// Credentials are never sent over plain HTTP.
+	_, err := NewBucketWithConfig(context.Background(), log.NewNopLogger(), cfg, "test")
+	testutil.NotOk(t, err)
+
+	cfg.InsecureSkipAuth = true
+	bkt, err := NewBucketWithConfig(context.Background(), log.NewNopLogger(), cfg, "test")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, bkt.Close()) }()
+
+	testutil.Ok(t, bkt.Upload(context.Background(), "dir/obj", strings.NewReader("content")))
+
+	reader, err := bkt.Get(context.Background(), "dir/obj")
+	testutil.Ok(t, err)
+	content, err := ioutil.ReadAll(reader)
+	testutil.Ok(t, err)
+	testutil.Ok(t, reader.Close())
+	testutil.Equals(t, "content", string(content))
+}
@@ -0,0 +1,288 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package mirror implements a bucket which mirrors uploaded objects to a secondary bucket.
+package mirror
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// Config configures how objects are mirrored to the secondary bucket.
+type Config struct {
+	// QueueSize is the maximum number of objects waiting to be mirrored.
+	QueueSize int `yaml:"queue_size"`
+	// Concurrency is the number of objects mirrored at once.
+	Concurrency int `yaml:"concurrency"`
+	// ReconcileInterval is how often objects which failed to be mirrored are queued again.
+	ReconcileInterval model.Duration `yaml:"reconcile_interval"`
+	// MirrorDeletes enables deleting objects from the secondary bucket when they are deleted.
+	MirrorDeletes bool `yaml:"mirror_deletes"`
+}
+
+// DefaultConfig is the default mirroring config.
+var DefaultConfig = Config{
+	QueueSize:         1000,
+	Concurrency:       4,
+	ReconcileInterval: model.Duration(5 * time.Minute),
+}
+
+func (c Config) validate() error {
+	if c.QueueSize <= 0 {
+		return errors.New("queue_size must be positive")
+	}
+	if c.Concurrency <= 0 {
+		return errors.New("concurrency must be positive")
+	}
+	if c.ReconcileInterval <= 0 {
+		return errors.New("reconcile_interval must be positive")
+	}
+	return nil
+}
+
+type op struct {
+	name   string
+	delete bool
+}
+
+// Bucket serves all operations from the primary bucket and mirrors uploaded objects to the secondary bucket in the
+// background. Objects are read back from the primary bucket to be mirrored, so uploads are not buffered in memory.
+// Objects which can't be mirrored, because the queue is full or mirroring failed, are tracked and queued again on
+// the next reconciliation.
+type Bucket struct {
+	objstore.Bucket
+
+	logger        log.Logger
+	secondary     objstore.Bucket
+	mirrorDeletes bool
+
+	queue  chan op
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mtx        sync.Mutex
+	closed     bool
+	unmirrored map[string]op
+
+	mirrored *prometheus.CounterVec
+	failures *prometheus.CounterVec
+	dropped  *prometheus.CounterVec
+}
+
+// NewBucket returns a bucket mirroring the primary bucket to the secondary one. It has to be closed to stop mirroring.
+func NewBucket(logger log.Logger, primary, secondary objstore.Bucket, conf Config, reg prometheus.Registerer) (*Bucket, error) {
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Bucket{
+		Bucket:        primary,
+		logger:        logger,
+		secondary:     secondary,
+		mirrorDeletes: conf.MirrorDeletes,
+		queue:         make(chan op, conf.QueueSize),
+		cancel:        cancel,
+		unmirrored:    map[string]op{},
+	}
+
+	constLabels := prometheus.Labels{"bucket": secondary.Name()}
+	b.mirrored = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name:        "thanos_objstore_mirror_operations_total",
+		Help:        "Total number of operations mirrored to the secondary bucket.",
+		ConstLabels: constLabels,
+	}, []string{"operation"})
+	b.failures = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name:        "thanos_objstore_mirror_operation_failures_total",
+		Help:        "Total number of operations which failed to be mirrored to the secondary bucket.",
+		ConstLabels: constLabels,
+	}, []string{"operation"})
+	b.dropped = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name:        "thanos_objstore_mirror_dropped_operations_total",
+		Help:        "Total number of operations which weren't queued to be mirrored, because the queue was full.",
+		ConstLabels: constLabels,
+	}, []string{"operation"})
+	for _, operation := range []string{objstore.OpUpload, objstore.OpDelete} {
+		b.mirrored.WithLabelValues(operation)
+		b.failures.WithLabelValues(operation)
+		b.dropped.WithLabelValues(operation)
+	}
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "thanos_objstore_mirror_queue_length",
+		Help:        "Number of operations waiting to be mirrored to the secondary bucket.",
+		ConstLabels: constLabels,
+	}, func() float64 { return float64(len(b.queue)) })
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "thanos_objstore_mirror_unreconciled_objects",
+		Help:        "Number of objects which failed to be mirrored to the secondary bucket and wait for reconciliation.",
+		ConstLabels: constLabels,
+	}, func() float64 {
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+		return float64(len(b.unmirrored))
+	})
+
+	for i := 0; i < conf.Concurrency; i++ {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			// Workers are not cancelled, so the queue is drained on close.
+			for o := range b.queue {
+				b.mirror(context.Background(), o)
+			}
+		}()
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		_ = runutil.Repeat(time.Duration(conf.ReconcileInterval), ctx.Done(), func() error {
+			b.reconcile()
+			return nil
+		})
+	}()
+	return b, nil
+}
+
+func opName(o op) string {
+	if o.delete {
+		return objstore.OpDelete
+	}
+	return objstore.OpUpload
+}
+
+// enqueue queues the operation to be mirrored, or keeps it for reconciliation if the queue is full.
+func (b *Bucket) enqueue(o op) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.closed {
+		b.unmirrored[o.name] = o
+		return
+	}
+	select {
+	case b.queue <- o:
+	default:
+		b.dropped.WithLabelValues(opName(o)).Inc()
+		b.unmirrored[o.name] = o
+	}
+}
+
+func (b *Bucket) mirror(ctx context.Context, o op) {
+	var err error
+	if o.delete {
+		err = b.secondary.Delete(ctx, o.name)
+		if b.secondary.IsObjNotFoundErr(err) {
+			err = nil
+		}
+	} else {
+		err = b.mirrorUpload(ctx, o.name)
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to mirror operation", "operation", opName(o), "name", o.name, "err", err)
+		b.failures.WithLabelValues(opName(o)).Inc()
+		if _, ok := b.unmirrored[o.name]; !ok {
+			b.unmirrored[o.name] = o
+		}
+		return
+	}
+	b.mirrored.WithLabelValues(opName(o)).Inc()
+	// Keep later operations on the same object which are still to be reconciled.
+	if u, ok := b.unmirrored[o.name]; ok && u == o {
+		delete(b.unmirrored, o.name)
+	}
+}
+
+func (b *Bucket) mirrorUpload(ctx context.Context, name string) error {
+	r, err := b.Bucket.Get(ctx, name)
+	if err != nil {
+		if b.Bucket.IsObjNotFoundErr(err) {
+			// The object was deleted in the meantime, so there is nothing to mirror.
+			return nil
+		}
+		return errors.Wrapf(err, "get %s from primary bucket", name)
+	}
+	defer runutil.CloseWithLogOnErr(b.logger, r, "primary bucket reader")
+
+	return errors.Wrapf(b.secondary.Upload(ctx, name, r), "upload %s to secondary bucket", name)
+}
+
+// reconcile queues again the operations which failed to be mirrored, as long as there is room in the queue.
+func (b *Bucket) reconcile() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.closed {
+		return
+	}
+	for _, o := range b.unmirrored {
+		select {
+		case b.queue <- o:
+		default:
+			return
+		}
+	}
+}
+
+// Upload uploads the object to the primary bucket and queues it to be mirrored to the secondary one.
+func (b *Bucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	if err := b.Bucket.Upload(ctx, name, r); err != nil {
+		return err
+	}
+	b.enqueue(op{name: name})
+	return nil
+}
+
+// Delete removes the object from the primary bucket and, if enabled, queues its removal from the secondary one.
+func (b *Bucket) Delete(ctx context.Context, name string) error {
+	if err := b.Bucket.Delete(ctx, name); err != nil {
+		return err
+	}
+	if b.mirrorDeletes {
+		b.enqueue(op{name: name, delete: true})
+	}
+	return nil
+}
+
+// Close waits for the queued operations to be mirrored and closes both buckets.
+func (b *Bucket) Close() error {
+	b.mtx.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.mtx.Unlock()
+
+	// Stop reconciliation, while the workers drain the queue.
+	b.cancel()
+	b.wg.Wait()
+
+	b.mtx.Lock()
+	if len(b.unmirrored) > 0 {
+		level.Warn(b.logger).Log("msg", "closing mirrored bucket with objects which weren't mirrored", "objects", len(b.unmirrored))
+	}
+	b.mtx.Unlock()
+
+	primaryErr := b.Bucket.Close()
+	if err := b.secondary.Close(); err != nil {
+		return err
+	}
+	return primaryErr
+}
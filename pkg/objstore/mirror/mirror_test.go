@@ -0,0 +1,119 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package mirror
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"go.uber.org/atomic"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/runutil"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+// blockingBucket blocks uploads until unblocked, and fails them while failing is set.
+type blockingBucket struct {
+	objstore.Bucket
+
+	unblock chan struct{}
+	fail    atomic.Bool
+}
+
+func (b *blockingBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	<-b.unblock
+	if b.fail.Load() {
+		return errors.New("unavailable")
+	}
+	return b.Bucket.Upload(ctx, name, r)
+}
+
+// waitForObjects waits until the bucket has n objects. It lists them, as the objects of the in-memory bucket can't
+// be accessed concurrently.
+func waitForObjects(t *testing.T, bkt objstore.Bucket, n int) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	testutil.Ok(t, runutil.Retry(10*time.Millisecond, ctx.Done(), func() error {
+		var got int
+		if err := bkt.Iter(ctx, "", func(string) error {
+			got++
+			return nil
+		}, objstore.WithRecursiveIter); err != nil {
+			return err
+		}
+		if got != n {
+			return errors.Errorf("expected %d objects, got %d", n, got)
+		}
+		return nil
+	}))
+}
+
+func TestBucket_AcceptanceTest(t *testing.T) {
+	primary, secondary := objstore.NewInMemBucket(), objstore.NewInMemBucket()
+	bkt, err := NewBucket(log.NewNopLogger(), primary, secondary, Config{QueueSize: 100, Concurrency: 1, ReconcileInterval: model.Duration(time.Minute), MirrorDeletes: true}, nil)
+	testutil.Ok(t, err)
+	objstore.AcceptanceTest(t, bkt)
+	testutil.Ok(t, bkt.Close())
+	testutil.Equals(t, primary.Objects(), secondary.Objects())
+}
+
+func TestBucket_Mirror(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := objstore.NewInMemBucket(), objstore.NewInMemBucket()
+	bkt, err := NewBucket(log.NewNopLogger(), primary, secondary, Config{QueueSize: 10, Concurrency: 1, ReconcileInterval: model.Duration(time.Minute)}, nil)
+	testutil.Ok(t, err)
+
+	testutil.Ok(t, bkt.Upload(ctx, "a", strings.NewReader("a")))
+	testutil.Ok(t, bkt.Upload(ctx, "b", strings.NewReader("b")))
+	waitForObjects(t, secondary, 2)
+	testutil.Equals(t, []byte("a"), secondary.Objects()["a"])
+	testutil.Equals(t, 2.0, promtest.ToFloat64(bkt.mirrored.WithLabelValues(objstore.OpUpload)))
+
+	// Deletions are not mirrored by default.
+	testutil.Ok(t, bkt.Delete(ctx, "a"))
+	testutil.Ok(t, bkt.Close())
+	testutil.Equals(t, 1, len(primary.Objects()))
+	testutil.Equals(t, 2, len(secondary.Objects()))
+}
+
+func TestBucket_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	secondary := &blockingBucket{Bucket: objstore.NewInMemBucket(), unblock: make(chan struct{})}
+	secondary.fail.Store(true)
+	bkt, err := NewBucket(log.NewNopLogger(), objstore.NewInMemBucket(), secondary, Config{QueueSize: 1, Concurrency: 1, ReconcileInterval: model.Duration(50 * time.Millisecond)}, nil)
+	testutil.Ok(t, err)
+
+	// The first upload blocks the worker, the second one fills the queue and the third one is dropped.
+	for _, name := range []string{"a", "b", "c"} {
+		testutil.Ok(t, bkt.Upload(ctx, name, strings.NewReader(name)))
+	}
+	retryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	testutil.Ok(t, runutil.Retry(10*time.Millisecond, retryCtx.Done(), func() error {
+		if v := promtest.ToFloat64(bkt.dropped.WithLabelValues(objstore.OpUpload)); v < 1 {
+			return errors.Errorf("expected dropped uploads, got %v", v)
+		}
+		return nil
+	}))
+
+	// Failed and dropped uploads are mirrored on reconciliation once the secondary bucket is available.
+	secondary.unblock <- struct{}{}
+	secondary.unblock <- struct{}{}
+	secondary.fail.Store(false)
+	close(secondary.unblock)
+
+	waitForObjects(t, secondary.Bucket, 3)
+	testutil.Ok(t, bkt.Close())
+	testutil.Equals(t, 0, len(bkt.unmirrored))
+	testutil.Assert(t, promtest.ToFloat64(bkt.failures.WithLabelValues(objstore.OpUpload)) >= 1)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package notifications receives the notifications of changes of the objects of buckets sent by object storage
+// providers, e.g. S3 event notifications to SQS or GCS notifications to Pub/Sub.
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type Type string
+
+const (
+	SQS    Type = "SQS"
+	PUBSUB Type = "PUBSUB"
+)
+
+// Config is the config of the notifications of changes of the objects of a bucket.
+type Config struct {
+	Type   Type        `yaml:"type"`
+	Config interface{} `yaml:"config"`
+}
+
+// Event is a change of an object of the bucket.
+type Event struct {
+	// Name of the object, as notified by the provider.
+	Name string
+	// Time of the change.
+	Time time.Time
+}
+
+// Receiver receives the notifications of changes of the objects of a bucket.
+type Receiver interface {
+	// Receive returns the events of the next notifications, waiting for some until a provider specific timeout.
+	// Notifications are acknowledged once returned, so they are not received again.
+	Receive(ctx context.Context) ([]Event, error)
+}
+
+// NewReceiver returns the receiver of the notifications of the given config.
+// NOTE: confContentYaml can contain secrets.
+func NewReceiver(ctx context.Context, logger log.Logger, confContentYaml []byte) (Receiver, error) {
+	conf := &Config{}
+	if err := yaml.UnmarshalStrict(confContentYaml, conf); err != nil {
+		return nil, errors.Wrap(err, "parsing notifications config YAML")
+	}
+	config, err := yaml.Marshal(conf.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal content of notifications configuration")
+	}
+
+	switch conf.Type {
+	case SQS:
+		return newSQSReceiver(logger, config)
+	case PUBSUB:
+		return newPubSubReceiver(ctx, logger, config)
+	default:
+		return nil, errors.Errorf("notifications with type %s is not supported", conf.Type)
+	}
+}
+
+// retryInterval is the interval between receives after failures.
+const retryInterval = 5 * time.Second
+
+// Watch receives the notifications of the receiver until ctx is done, and calls f after each batch of notifications
+// with at least one event for which match returns true. Failures are logged and retried, so callers should keep
+// syncing periodically to catch up with changes of which notifications are lost or late.
+func Watch(ctx context.Context, logger log.Logger, reg prometheus.Registerer, r Receiver, match func(name string) bool, f func()) {
+	var (
+		received = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_objstore_notifications_received_total",
+			Help: "Total number of events of changes of objects received from bucket notifications.",
+		})
+		failures = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_objstore_notifications_receive_failures_total",
+			Help: "Total number of failures to receive bucket notifications.",
+		})
+		lag = promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_objstore_notifications_lag_seconds",
+			Help: "Time between the last change of an object and the reception of its notification.",
+		})
+	)
+
+	for ctx.Err() == nil {
+		events, err := r.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failures.Inc()
+			level.Warn(logger).Log("msg", "receiving bucket notifications failed; retrying", "err", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryInterval):
+			}
+			continue
+		}
+
+		matched := false
+		for _, e := range events {
+			received.Inc()
+			if !e.Time.IsZero() {
+				lag.Set(time.Since(e.Time).Seconds())
+			}
+			if match(e.Name) {
+				matched = true
+			}
+		}
+		if matched {
+			f()
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestParseS3Events(t *testing.T) {
+	msg := `{"Records":[{"eventName":"ObjectCreated:Put","eventTime":"2022-01-03T10:00:00.000Z","s3":{"object":{"key":"tenant+a/01FRBBXCRPM0RXWWHAZ44CCDEN/meta.json"}}}]}`
+	exp := []Event{{Name: "tenant a/01FRBBXCRPM0RXWWHAZ44CCDEN/meta.json", Time: time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)}}
+
+	events, err := parseS3Events([]byte(msg))
+	testutil.Ok(t, err)
+	testutil.Equals(t, exp, events)
+
+	// Notifications sent through SNS wrap the S3 event notification.
+	sns, err := json.Marshal(map[string]string{"Type": "Notification", "Message": msg})
+	testutil.Ok(t, err)
+	events, err = parseS3Events(sns)
+	testutil.Ok(t, err)
+	testutil.Equals(t, exp, events)
+
+	events, err = parseS3Events([]byte(`{"Service":"Amazon S3","Event":"s3:TestEvent"}`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(events))
+
+	_, err = parseS3Events([]byte("not json"))
+	testutil.NotOk(t, err)
+}
+
+func TestParseGCSEvent(t *testing.T) {
+	e, err := parseGCSEvent(map[string]string{
+		"eventType": "OBJECT_FINALIZE",
+		"eventTime": "2022-01-03T10:00:00.123456Z",
+		"objectId":  "01FRBBXCRPM0RXWWHAZ44CCDEN/meta.json",
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, Event{Name: "01FRBBXCRPM0RXWWHAZ44CCDEN/meta.json", Time: time.Date(2022, 1, 3, 10, 0, 0, 123456000, time.UTC)}, e)
+
+	_, err = parseGCSEvent(map[string]string{"eventType": "OBJECT_FINALIZE"})
+	testutil.NotOk(t, err)
+}
+
+type receiverFunc func(ctx context.Context) ([]Event, error)
+
+func (f receiverFunc) Receive(ctx context.Context) ([]Event, error) { return f(ctx) }
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	batches := [][]Event{
+		{{Name: "a/index"}},
+		{{Name: "a/index"}, {Name: "a/meta.json"}},
+		nil,
+		{{Name: "b/meta.json"}},
+	}
+	calls := 0
+	r := receiverFunc(func(ctx context.Context) ([]Event, error) {
+		if len(batches) == 0 {
+			cancel()
+			return nil, ctx.Err()
+		}
+		b := batches[0]
+		batches = batches[1:]
+		return b, nil
+	})
+	Watch(ctx, log.NewNopLogger(), nil, r, func(name string) bool { return strings.HasSuffix(name, "/meta.json") }, func() { calls++ })
+	testutil.Equals(t, 2, calls)
+}
+
+func TestNewReceiver(t *testing.T) {
+	for _, tcase := range []struct {
+		conf string
+		err  error
+	}{
+		{conf: "type: UNKNOWN", err: errors.New("notifications with type UNKNOWN is not supported")},
+		{conf: "type: SQS\nconfig: {}", err: errors.New("missing queue_url for SQS notifications")},
+		{conf: "type: SQS\nconfig:\n  queue_url: https://sqs.eu-west-1.amazonaws.com/123/queue\n  wait_time: 1m", err: errors.New("wait_time of SQS notifications must be between 1s and 20s")},
+		{conf: "type: PUBSUB\nconfig: {}", err: errors.New("missing subscription for Pub/Sub notifications")},
+	} {
+		_, err := NewReceiver(context.Background(), log.NewNopLogger(), []byte(tcase.conf))
+		testutil.NotOk(t, err)
+		testutil.Equals(t, tcase.err.Error(), err.Error())
+	}
+}
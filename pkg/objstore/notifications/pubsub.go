@@ -0,0 +1,117 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// PubSubConfig is the config of the receiver of GCS notifications sent to a Pub/Sub topic.
+type PubSubConfig struct {
+	// Subscription is the full name of the subscription to the topic, i.e.
+	// projects/<project>/subscriptions/<subscription>.
+	Subscription   string `yaml:"subscription"`
+	ServiceAccount string `yaml:"service_account"`
+	MaxMessages    int64  `yaml:"max_messages"`
+}
+
+// DefaultPubSubConfig is the default config of Pub/Sub receivers.
+var DefaultPubSubConfig = PubSubConfig{
+	MaxMessages: 100,
+}
+
+type pubSubReceiver struct {
+	logger       log.Logger
+	subscription *pubsub.ProjectsSubscriptionsService
+	name         string
+	maxMessages  int64
+}
+
+// newPubSubReceiver returns a receiver of the notifications of the given Pub/Sub config. If no service account is
+// configured, credentials are taken from the default Google credential chain.
+func newPubSubReceiver(ctx context.Context, logger log.Logger, conf []byte) (*pubSubReceiver, error) {
+	config := DefaultPubSubConfig
+	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
+		return nil, errors.Wrap(err, "parsing Pub/Sub config")
+	}
+	if config.Subscription == "" {
+		return nil, errors.New("missing subscription for Pub/Sub notifications")
+	}
+
+	var opts []option.ClientOption
+	if config.ServiceAccount != "" {
+		credentials, err := google.CredentialsFromJSON(ctx, []byte(config.ServiceAccount), pubsub.PubsubScope)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create credentials from JSON")
+		}
+		opts = append(opts, option.WithCredentials(credentials))
+	}
+	svc, err := pubsub.NewService(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "create Pub/Sub client")
+	}
+	return &pubSubReceiver{
+		logger:       logger,
+		subscription: svc.Projects.Subscriptions,
+		name:         config.Subscription,
+		maxMessages:  config.MaxMessages,
+	}, nil
+}
+
+func (r *pubSubReceiver) Receive(ctx context.Context) ([]Event, error) {
+	resp, err := r.subscription.Pull(r.name, &pubsub.PullRequest{MaxMessages: r.maxMessages}).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "pull Pub/Sub messages")
+	}
+	if len(resp.ReceivedMessages) == 0 {
+		return nil, nil
+	}
+
+	var (
+		events []Event
+		ackIDs = make([]string, 0, len(resp.ReceivedMessages))
+	)
+	for _, m := range resp.ReceivedMessages {
+		ackIDs = append(ackIDs, m.AckId)
+		if m.Message == nil {
+			continue
+		}
+		e, err := parseGCSEvent(m.Message.Attributes)
+		if err != nil {
+			level.Warn(r.logger).Log("msg", "ignoring Pub/Sub message which is not a GCS notification", "id", m.Message.MessageId, "err", err)
+			continue
+		}
+		events = append(events, e)
+	}
+
+	if _, err := r.subscription.Acknowledge(r.name, &pubsub.AcknowledgeRequest{AckIds: ackIDs}).Context(ctx).Do(); err != nil {
+		return nil, errors.Wrap(err, "acknowledge Pub/Sub messages")
+	}
+	return events, nil
+}
+
+// parseGCSEvent returns the event of the GCS notification with the given attributes.
+func parseGCSEvent(attrs map[string]string) (Event, error) {
+	name, ok := attrs["objectId"]
+	if !ok {
+		return Event{}, errors.New("missing objectId attribute")
+	}
+	e := Event{Name: name}
+	if t, ok := attrs["eventTime"]; ok {
+		var err error
+		if e.Time, err = time.Parse(time.RFC3339Nano, t); err != nil {
+			return Event{}, errors.Wrapf(err, "parse event time %s", t)
+		}
+	}
+	return e, nil
+}
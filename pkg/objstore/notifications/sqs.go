@@ -0,0 +1,150 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SQSConfig is the config of the receiver of S3 event notifications sent to an SQS queue, directly or through SNS.
+type SQSConfig struct {
+	QueueURL string `yaml:"queue_url"`
+	Region   string `yaml:"region"`
+	Endpoint string `yaml:"endpoint"`
+	// WaitTime is the duration receives wait for notifications, at most 20s.
+	WaitTime model.Duration `yaml:"wait_time"`
+}
+
+// DefaultSQSConfig is the default config of SQS receivers.
+var DefaultSQSConfig = SQSConfig{
+	WaitTime: model.Duration(20 * time.Second),
+}
+
+type sqsReceiver struct {
+	logger   log.Logger
+	client   *sqs.SQS
+	queueURL string
+	waitTime time.Duration
+}
+
+// newSQSReceiver returns a receiver of the notifications of the given SQS config. Credentials are taken from the
+// default AWS credential chain.
+func newSQSReceiver(logger log.Logger, conf []byte) (*sqsReceiver, error) {
+	config := DefaultSQSConfig
+	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
+		return nil, errors.Wrap(err, "parsing SQS config")
+	}
+	if config.QueueURL == "" {
+		return nil, errors.New("missing queue_url for SQS notifications")
+	}
+	if config.WaitTime <= 0 || time.Duration(config.WaitTime) > 20*time.Second {
+		return nil, errors.New("wait_time of SQS notifications must be between 1s and 20s")
+	}
+
+	cfg := aws.NewConfig()
+	if config.Region != "" {
+		cfg = cfg.WithRegion(config.Region)
+	}
+	if config.Endpoint != "" {
+		cfg = cfg.WithEndpoint(config.Endpoint)
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "create AWS session")
+	}
+	return &sqsReceiver{
+		logger:   logger,
+		client:   sqs.New(sess),
+		queueURL: config.QueueURL,
+		waitTime: time.Duration(config.WaitTime),
+	}, nil
+}
+
+func (r *sqsReceiver) Receive(ctx context.Context) ([]Event, error) {
+	out, err := r.client.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(r.queueURL),
+		MaxNumberOfMessages: aws.Int64(10),
+		WaitTimeSeconds:     aws.Int64(int64(r.waitTime.Seconds())),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "receive SQS messages")
+	}
+	if len(out.Messages) == 0 {
+		return nil, nil
+	}
+
+	var (
+		events  []Event
+		entries = make([]*sqs.DeleteMessageBatchRequestEntry, 0, len(out.Messages))
+	)
+	for i, m := range out.Messages {
+		// Messages which can't be parsed are deleted as well, as they would never be.
+		e, err := parseS3Events([]byte(aws.StringValue(m.Body)))
+		if err != nil {
+			level.Warn(r.logger).Log("msg", "ignoring SQS message which is not an S3 event notification", "id", aws.StringValue(m.MessageId), "err", err)
+		}
+		events = append(events, e...)
+		entries = append(entries, &sqs.DeleteMessageBatchRequestEntry{Id: aws.String(strconv.Itoa(i)), ReceiptHandle: m.ReceiptHandle})
+	}
+
+	del, err := r.client.DeleteMessageBatchWithContext(ctx, &sqs.DeleteMessageBatchInput{QueueUrl: aws.String(r.queueURL), Entries: entries})
+	if err != nil {
+		return nil, errors.Wrap(err, "delete SQS messages")
+	}
+	for _, f := range del.Failed {
+		level.Warn(r.logger).Log("msg", "deleting SQS message failed; it will be received again", "err", aws.StringValue(f.Message))
+	}
+	return events, nil
+}
+
+// s3EventMessage is an S3 event notification, or an SNS notification wrapping it.
+type s3EventMessage struct {
+	Records []struct {
+		EventTime time.Time `json:"eventTime"`
+		S3        struct {
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+// parseS3Events returns the events of the given S3 event notification. Test events sent when configuring
+// notifications have no records.
+func parseS3Events(b []byte) ([]Event, error) {
+	var m s3EventMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, errors.Wrap(err, "unmarshal S3 event notification")
+	}
+	if m.Type == "Notification" {
+		return parseS3Events([]byte(m.Message))
+	}
+
+	events := make([]Event, 0, len(m.Records))
+	for _, r := range m.Records {
+		// Keys of S3 event notifications are URL encoded.
+		name, err := url.QueryUnescape(r.S3.Object.Key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode object key %s", r.S3.Object.Key)
+		}
+		events = append(events, Event{Name: name, Time: r.EventTime})
+	}
+	return events, nil
+}
@@ -14,9 +14,11 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/uber/jaeger-client-go"
 
 	"github.com/thanos-io/thanos/pkg/runutil"
 )
@@ -313,6 +315,18 @@ func BucketWithMetrics(name string, b Bucket, reg prometheus.Registerer) *metric
 			Buckets:     []float64{0.001, 0.01, 0.1, 0.3, 0.6, 1, 3, 6, 9, 20, 30, 60, 90, 120},
 		}, []string{"operation"}),
 
+		readBytes: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name:        "thanos_objstore_bucket_read_bytes_total",
+			Help:        "Total number of bytes read from objects of the bucket.",
+			ConstLabels: prometheus.Labels{"bucket": name},
+		}, []string{"operation"}),
+
+		writtenBytes: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name:        "thanos_objstore_bucket_written_bytes_total",
+			Help:        "Total number of bytes of objects successfully uploaded to the bucket.",
+			ConstLabels: prometheus.Labels{"bucket": name},
+		}, []string{"operation"}),
+
 		lastSuccessfulUploadTime: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
 			Name: "thanos_objstore_bucket_last_successful_upload_time",
 			Help: "Second timestamp of the last successful upload to the bucket.",
@@ -331,6 +345,9 @@ func BucketWithMetrics(name string, b Bucket, reg prometheus.Registerer) *metric
 		bkt.opsFailures.WithLabelValues(op)
 		bkt.opsDuration.WithLabelValues(op)
 	}
+	bkt.readBytes.WithLabelValues(OpGet)
+	bkt.readBytes.WithLabelValues(OpGetRange)
+	bkt.writtenBytes.WithLabelValues(OpUpload)
 	bkt.lastSuccessfulUploadTime.WithLabelValues(b.Name())
 	return bkt
 }
@@ -343,6 +360,8 @@ type metricBucket struct {
 	isOpFailureExpected IsOpFailureExpectedFunc
 
 	opsDuration              *prometheus.HistogramVec
+	readBytes                *prometheus.CounterVec
+	writtenBytes             *prometheus.CounterVec
 	lastSuccessfulUploadTime *prometheus.GaugeVec
 }
 
@@ -353,6 +372,8 @@ func (b *metricBucket) WithExpectedErrs(fn IsOpFailureExpectedFunc) Bucket {
 		opsFailures:              b.opsFailures,
 		isOpFailureExpected:      fn,
 		opsDuration:              b.opsDuration,
+		readBytes:                b.readBytes,
+		writtenBytes:             b.writtenBytes,
 		lastSuccessfulUploadTime: b.lastSuccessfulUploadTime,
 	}
 }
@@ -365,13 +386,16 @@ func (b *metricBucket) Iter(ctx context.Context, dir string, f func(name string)
 	const op = OpIter
 	b.ops.WithLabelValues(op).Inc()
 
+	start := time.Now()
 	err := b.bkt.Iter(ctx, dir, f, options...)
 	if err != nil {
 		if !b.isOpFailureExpected(err) && ctx.Err() != context.Canceled {
 			b.opsFailures.WithLabelValues(op).Inc()
 		}
+		return err
 	}
-	return err
+	observeWithTraceExemplar(ctx, b.opsDuration.WithLabelValues(op), time.Since(start).Seconds())
+	return nil
 }
 
 func (b *metricBucket) Attributes(ctx context.Context, name string) (ObjectAttributes, error) {
@@ -386,7 +410,7 @@ func (b *metricBucket) Attributes(ctx context.Context, name string) (ObjectAttri
 		}
 		return attrs, err
 	}
-	b.opsDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	observeWithTraceExemplar(ctx, b.opsDuration.WithLabelValues(op), time.Since(start).Seconds())
 	return attrs, nil
 }
 
@@ -402,10 +426,12 @@ func (b *metricBucket) Get(ctx context.Context, name string) (io.ReadCloser, err
 		return nil, err
 	}
 	return newTimingReadCloser(
+		ctx,
 		rc,
 		op,
 		b.opsDuration,
 		b.opsFailures,
+		b.readBytes,
 		b.isOpFailureExpected,
 	), nil
 }
@@ -422,10 +448,12 @@ func (b *metricBucket) GetRange(ctx context.Context, name string, off, length in
 		return nil, err
 	}
 	return newTimingReadCloser(
+		ctx,
 		rc,
 		op,
 		b.opsDuration,
 		b.opsFailures,
+		b.readBytes,
 		b.isOpFailureExpected,
 	), nil
 }
@@ -442,7 +470,7 @@ func (b *metricBucket) Exists(ctx context.Context, name string) (bool, error) {
 		}
 		return false, err
 	}
-	b.opsDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	observeWithTraceExemplar(ctx, b.opsDuration.WithLabelValues(op), time.Since(start).Seconds())
 	return ok, nil
 }
 
@@ -450,6 +478,14 @@ func (b *metricBucket) Upload(ctx context.Context, name string, r io.Reader) err
 	const op = OpUpload
 	b.ops.WithLabelValues(op).Inc()
 
+	// Readers are only wrapped if their size is unknown, as bucket implementations rely on the type of the reader.
+	size, err := TryToGetSize(r)
+	var cr *countingReader
+	if err != nil {
+		cr = newCountingReader(r)
+		r = cr
+	}
+
 	start := time.Now()
 	if err := b.bkt.Upload(ctx, name, r); err != nil {
 		if !b.isOpFailureExpected(err) && ctx.Err() != context.Canceled {
@@ -457,8 +493,12 @@ func (b *metricBucket) Upload(ctx context.Context, name string, r io.Reader) err
 		}
 		return err
 	}
+	if cr != nil {
+		size = cr.n
+	}
+	b.writtenBytes.WithLabelValues(op).Add(float64(size))
 	b.lastSuccessfulUploadTime.WithLabelValues(b.bkt.Name()).SetToCurrentTime()
-	b.opsDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	observeWithTraceExemplar(ctx, b.opsDuration.WithLabelValues(op), time.Since(start).Seconds())
 	return nil
 }
 
@@ -473,7 +513,7 @@ func (b *metricBucket) Delete(ctx context.Context, name string) error {
 		}
 		return err
 	}
-	b.opsDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	observeWithTraceExemplar(ctx, b.opsDuration.WithLabelValues(op), time.Since(start).Seconds())
 
 	return nil
 }
@@ -497,12 +537,14 @@ type timingReadCloser struct {
 
 	start             time.Time
 	op                string
+	exemplar          prometheus.Labels
 	duration          *prometheus.HistogramVec
 	failed            *prometheus.CounterVec
+	readBytes         prometheus.Counter
 	isFailureExpected IsOpFailureExpectedFunc
 }
 
-func newTimingReadCloser(rc io.ReadCloser, op string, dur *prometheus.HistogramVec, failed *prometheus.CounterVec, isFailureExpected IsOpFailureExpectedFunc) *timingReadCloser {
+func newTimingReadCloser(ctx context.Context, rc io.ReadCloser, op string, dur *prometheus.HistogramVec, failed *prometheus.CounterVec, readBytes *prometheus.CounterVec, isFailureExpected IsOpFailureExpectedFunc) *timingReadCloser {
 	// Initialize the metrics with 0.
 	dur.WithLabelValues(op)
 	failed.WithLabelValues(op)
@@ -510,8 +552,10 @@ func newTimingReadCloser(rc io.ReadCloser, op string, dur *prometheus.HistogramV
 		ReadCloser:        rc,
 		start:             time.Now(),
 		op:                op,
+		exemplar:          traceExemplar(ctx),
 		duration:          dur,
 		failed:            failed,
+		readBytes:         readBytes.WithLabelValues(op),
 		isFailureExpected: isFailureExpected,
 	}
 }
@@ -522,7 +566,7 @@ func (rc *timingReadCloser) Close() error {
 		rc.failed.WithLabelValues(rc.op).Inc()
 	}
 	if !rc.alreadyGotErr && err == nil {
-		rc.duration.WithLabelValues(rc.op).Observe(time.Since(rc.start).Seconds())
+		observe(rc.duration.WithLabelValues(rc.op), time.Since(rc.start).Seconds(), rc.exemplar)
 		rc.alreadyGotErr = true
 	}
 	return err
@@ -530,6 +574,9 @@ func (rc *timingReadCloser) Close() error {
 
 func (rc *timingReadCloser) Read(b []byte) (n int, err error) {
 	n, err = rc.ReadCloser.Read(b)
+	if n > 0 {
+		rc.readBytes.Add(float64(n))
+	}
 	// Report metric just once.
 	if !rc.alreadyGotErr && err != nil && err != io.EOF {
 		if !rc.isFailureExpected(err) {
@@ -539,3 +586,46 @@ func (rc *timingReadCloser) Read(b []byte) (n int, err error) {
 	}
 	return n, err
 }
+
+// countingReader counts the bytes read from the reader.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func newCountingReader(r io.Reader) *countingReader {
+	return &countingReader{Reader: r}
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// traceExemplar returns the exemplar labels of the sampled trace of the context, or nil if there is none.
+func traceExemplar(ctx context.Context) prometheus.Labels {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+	spanCtx, ok := span.Context().(jaeger.SpanContext)
+	if !ok || !spanCtx.IsSampled() {
+		return nil
+	}
+	return prometheus.Labels{"traceID": spanCtx.TraceID().String()}
+}
+
+// observe observes the value, with the exemplar if it is set.
+func observe(o prometheus.Observer, v float64, exemplar prometheus.Labels) {
+	if eo, ok := o.(prometheus.ExemplarObserver); ok && exemplar != nil {
+		eo.ObserveWithExemplar(v, exemplar)
+		return
+	}
+	o.Observe(v)
+}
+
+// observeWithTraceExemplar observes the value, with the sampled trace of the context as exemplar.
+func observeWithTraceExemplar(ctx context.Context, o prometheus.Observer, v float64) {
+	observe(o, v, traceExemplar(ctx))
+}
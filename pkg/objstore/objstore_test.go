@@ -5,10 +5,16 @@ package objstore
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"io/ioutil"
+	"strings"
 	"testing"
 
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
 	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/uber/jaeger-client-go"
 
 	"github.com/thanos-io/thanos/pkg/testutil"
 )
@@ -66,6 +72,65 @@ func TestMetricBucket_Close(t *testing.T) {
 	testutil.Assert(t, promtest.ToFloat64(bkt.lastSuccessfulUploadTime) > lastUpload)
 }
 
+func TestMetricBucket_Bytes(t *testing.T) {
+	ctx := context.Background()
+	bkt := BucketWithMetrics("abc", NewInMemBucket(), nil)
+
+	testutil.Ok(t, bkt.Upload(ctx, "sized", strings.NewReader("hello world")))
+	// The size of this reader is unknown, so the written bytes are counted.
+	testutil.Ok(t, bkt.Upload(ctx, "not-sized", io.MultiReader(strings.NewReader("hello"))))
+	testutil.Equals(t, 16.0, promtest.ToFloat64(bkt.writtenBytes.WithLabelValues(OpUpload)))
+
+	rc, err := bkt.Get(ctx, "sized")
+	testutil.Ok(t, err)
+	_, err = ioutil.ReadAll(rc)
+	testutil.Ok(t, err)
+	testutil.Ok(t, rc.Close())
+	rc, err = bkt.GetRange(ctx, "sized", 6, 3)
+	testutil.Ok(t, err)
+	_, err = ioutil.ReadAll(rc)
+	testutil.Ok(t, err)
+	testutil.Ok(t, rc.Close())
+	testutil.Equals(t, 11.0, promtest.ToFloat64(bkt.readBytes.WithLabelValues(OpGet)))
+	testutil.Equals(t, 3.0, promtest.ToFloat64(bkt.readBytes.WithLabelValues(OpGetRange)))
+}
+
+func TestMetricBucket_Exemplars(t *testing.T) {
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+	defer func() { testutil.Ok(t, closer.Close()) }()
+	span := tracer.StartSpan("test")
+	defer span.Finish()
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	reg := prometheus.NewRegistry()
+	bkt := BucketWithMetrics("abc", NewInMemBucket(), reg)
+	_, err := bkt.Exists(ctx, "obj")
+	testutil.Ok(t, err)
+	// Operations without a trace are observed without exemplars.
+	_, err = bkt.Exists(context.Background(), "obj")
+	testutil.Ok(t, err)
+
+	mfs, err := reg.Gather()
+	testutil.Ok(t, err)
+	var traceIDs []string
+	for _, mf := range mfs {
+		if mf.GetName() != "thanos_objstore_bucket_operation_duration_seconds" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, b := range m.GetHistogram().GetBucket() {
+				if b.GetExemplar() == nil {
+					continue
+				}
+				for _, l := range b.GetExemplar().GetLabel() {
+					traceIDs = append(traceIDs, l.GetValue())
+				}
+			}
+		}
+	}
+	testutil.Equals(t, []string{span.Context().(jaeger.SpanContext).TraceID().String()}, traceIDs)
+}
+
 func TestTracingReader(t *testing.T) {
 	r := bytes.NewReader([]byte("hello world"))
 	tr := newTracingReadCloser(NopCloserWithSize(r), nil)
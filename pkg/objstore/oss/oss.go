@@ -362,3 +362,11 @@ func (b *Bucket) IsObjNotFoundErr(err error) bool {
 	}
 	return false
 }
+
+// StatusCode returns the HTTP status code of the error of a failed operation, if there is one.
+func StatusCode(err error) (int, bool) {
+	if aliErr, ok := errors.Cause(err).(alioss.ServiceError); ok {
+		return aliErr.StatusCode, true
+	}
+	return 0, false
+}
@@ -0,0 +1,161 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package objstore
+
+import (
+	"context"
+	"io"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+// Operation classes which are rate limited together.
+const (
+	OpClassList   = "list"
+	OpClassGet    = "get"
+	OpClassUpload = "upload"
+	OpClassDelete = "delete"
+)
+
+// RateLimitConfig configures rate limits of bucket operations per operation class.
+type RateLimitConfig struct {
+	// List limits Iter calls.
+	List OperationRateLimit `yaml:"list"`
+	// Get limits Get, GetRange, Exists and Attributes calls.
+	Get    OperationRateLimit `yaml:"get"`
+	Upload OperationRateLimit `yaml:"upload"`
+	Delete OperationRateLimit `yaml:"delete"`
+}
+
+// OperationRateLimit configures a token bucket limiter. Operations are not limited if no rate is set.
+type OperationRateLimit struct {
+	// RequestsPerSecond is the rate at which tokens are added to the bucket.
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	// Burst is the size of the bucket. Defaults to the rate, rounded up.
+	Burst int `yaml:"burst"`
+}
+
+func (c OperationRateLimit) validate() error {
+	if c.RequestsPerSecond < 0 {
+		return errors.New("requests_per_second must not be negative")
+	}
+	if c.Burst < 0 {
+		return errors.New("burst must not be negative")
+	}
+	return nil
+}
+
+func (c OperationRateLimit) limiter() *rate.Limiter {
+	if c.RequestsPerSecond <= 0 {
+		return nil
+	}
+	burst := c.Burst
+	if burst == 0 {
+		burst = int(math.Ceil(c.RequestsPerSecond))
+	}
+	return rate.NewLimiter(rate.Limit(c.RequestsPerSecond), burst)
+}
+
+// RateLimitedBucket delays operations, so they don't exceed the configured rate per operation class.
+type RateLimitedBucket struct {
+	Bucket
+
+	limiters map[string]*rate.Limiter
+
+	waitDuration *prometheus.CounterVec
+}
+
+// NewRateLimitedBucket returns a bucket which rate limits operations against the given bucket.
+func NewRateLimitedBucket(bkt Bucket, conf RateLimitConfig, reg prometheus.Registerer) (*RateLimitedBucket, error) {
+	b := &RateLimitedBucket{
+		Bucket:   bkt,
+		limiters: map[string]*rate.Limiter{},
+		waitDuration: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name:        "thanos_objstore_bucket_rate_limited_seconds_total",
+			Help:        "Total time operations against a bucket waited for the rate limiter.",
+			ConstLabels: prometheus.Labels{"bucket": bkt.Name()},
+		}, []string{"operation_class"}),
+	}
+	for class, c := range map[string]OperationRateLimit{
+		OpClassList:   conf.List,
+		OpClassGet:    conf.Get,
+		OpClassUpload: conf.Upload,
+		OpClassDelete: conf.Delete,
+	} {
+		if err := c.validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s rate limit", class)
+		}
+		if l := c.limiter(); l != nil {
+			b.limiters[class] = l
+			b.waitDuration.WithLabelValues(class)
+		}
+	}
+	return b, nil
+}
+
+func (b *RateLimitedBucket) wait(ctx context.Context, class string) error {
+	l, ok := b.limiters[class]
+	if !ok {
+		return nil
+	}
+
+	start := time.Now()
+	defer func() { b.waitDuration.WithLabelValues(class).Add(time.Since(start).Seconds()) }()
+
+	return errors.Wrapf(l.Wait(ctx), "wait for %s rate limit", class)
+}
+
+func (b *RateLimitedBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...IterOption) error {
+	if err := b.wait(ctx, OpClassList); err != nil {
+		return err
+	}
+	return b.Bucket.Iter(ctx, dir, f, options...)
+}
+
+func (b *RateLimitedBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	if err := b.wait(ctx, OpClassGet); err != nil {
+		return nil, err
+	}
+	return b.Bucket.Get(ctx, name)
+}
+
+func (b *RateLimitedBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	if err := b.wait(ctx, OpClassGet); err != nil {
+		return nil, err
+	}
+	return b.Bucket.GetRange(ctx, name, off, length)
+}
+
+func (b *RateLimitedBucket) Exists(ctx context.Context, name string) (bool, error) {
+	if err := b.wait(ctx, OpClassGet); err != nil {
+		return false, err
+	}
+	return b.Bucket.Exists(ctx, name)
+}
+
+func (b *RateLimitedBucket) Attributes(ctx context.Context, name string) (ObjectAttributes, error) {
+	if err := b.wait(ctx, OpClassGet); err != nil {
+		return ObjectAttributes{}, err
+	}
+	return b.Bucket.Attributes(ctx, name)
+}
+
+func (b *RateLimitedBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	if err := b.wait(ctx, OpClassUpload); err != nil {
+		return err
+	}
+	return b.Bucket.Upload(ctx, name, r)
+}
+
+func (b *RateLimitedBucket) Delete(ctx context.Context, name string) error {
+	if err := b.wait(ctx, OpClassDelete); err != nil {
+		return err
+	}
+	return b.Bucket.Delete(ctx, name)
+}
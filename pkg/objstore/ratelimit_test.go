@@ -0,0 +1,59 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package objstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestRateLimitedBucket_AcceptanceTest(t *testing.T) {
+	bkt, err := NewRateLimitedBucket(NewInMemBucket(), RateLimitConfig{
+		List:   OperationRateLimit{RequestsPerSecond: 1000},
+		Get:    OperationRateLimit{RequestsPerSecond: 1000},
+		Upload: OperationRateLimit{RequestsPerSecond: 1000},
+		Delete: OperationRateLimit{RequestsPerSecond: 1000},
+	}, nil)
+	testutil.Ok(t, err)
+	AcceptanceTest(t, bkt)
+}
+
+func TestRateLimitedBucket(t *testing.T) {
+	ctx := context.Background()
+	bkt, err := NewRateLimitedBucket(NewInMemBucket(), RateLimitConfig{
+		Get: OperationRateLimit{RequestsPerSecond: 10, Burst: 2},
+	}, nil)
+	testutil.Ok(t, err)
+
+	// Operations of classes without limits are not delayed.
+	for i := 0; i < 10; i++ {
+		testutil.Ok(t, bkt.Upload(ctx, "obj", strings.NewReader("hello")))
+	}
+	testutil.Equals(t, 1, promtest.CollectAndCount(bkt.waitDuration))
+	testutil.Equals(t, 0.0, promtest.ToFloat64(bkt.waitDuration.WithLabelValues(OpClassGet)))
+
+	// Bursts are allowed, later operations wait for the rate.
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		_, err := bkt.Exists(ctx, "obj")
+		testutil.Ok(t, err)
+	}
+	testutil.Assert(t, time.Since(start) >= 150*time.Millisecond, "expected operations to be delayed, took %v", time.Since(start))
+	testutil.Assert(t, promtest.ToFloat64(bkt.waitDuration.WithLabelValues(OpClassGet)) > 0)
+
+	// Waiting stops with the context.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = bkt.Get(cancelCtx, "obj")
+	testutil.NotOk(t, err)
+
+	_, err = NewRateLimitedBucket(NewInMemBucket(), RateLimitConfig{Delete: OperationRateLimit{RequestsPerSecond: -1}}, nil)
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,310 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package objstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jpillora/backoff"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+)
+
+// DefaultRetryableStatusCodes are the HTTP status codes of failed operations which are retried by default.
+var DefaultRetryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+const (
+	defaultMinRetryBackoff = 100 * time.Millisecond
+	defaultMaxRetryBackoff = 10 * time.Second
+)
+
+// RetryConfig configures retries of failed bucket operations.
+type RetryConfig struct {
+	// MaxRetries is the maximum number of retries of a failed operation. Operations are not retried if it is 0.
+	MaxRetries int `yaml:"max_retries"`
+	// MinBackoff is the wait before the first retry, which is doubled for each of the next ones. 0 = 100ms.
+	MinBackoff model.Duration `yaml:"min_backoff"`
+	// MaxBackoff is the maximum wait between retries. 0 = 10s.
+	MaxBackoff model.Duration `yaml:"max_backoff"`
+	// RetryableStatusCodes are the HTTP status codes of failed operations which are retried. Empty = 429, 500, 502, 503 and 504.
+	RetryableStatusCodes []int `yaml:"retryable_status_codes"`
+	// AttemptTimeout limits the duration of every attempt. For reads it limits the time until the reader is returned. 0 = no limit.
+	AttemptTimeout model.Duration `yaml:"attempt_timeout"`
+}
+
+// Enabled returns true if the config retries operations or limits their attempts.
+func (c RetryConfig) Enabled() bool {
+	return c.MaxRetries != 0 || c.AttemptTimeout != 0
+}
+
+func (c RetryConfig) validate() error {
+	if c.MaxRetries < 0 {
+		return errors.New("max_retries must not be negative")
+	}
+	if c.MinBackoff < 0 || c.MaxBackoff < 0 || c.AttemptTimeout < 0 {
+		return errors.New("backoffs and attempt_timeout must not be negative")
+	}
+	if c.MinBackoff > 0 && c.MaxBackoff > 0 && c.MinBackoff > c.MaxBackoff {
+		return errors.New("min_backoff must not be greater than max_backoff")
+	}
+	return nil
+}
+
+// StatusCodeFunc returns the HTTP status code of the error of a failed operation, if there is one.
+type StatusCodeFunc func(err error) (int, bool)
+
+// RetryingBucket retries failed operations with exponential backoff. Failures with a status code are retried if the
+// status code is retryable, while failures without one, like network errors and timed out attempts, are always retried.
+// Uploads are only retried if the reader can seek, and Iter calls only if no object was passed to the callback yet.
+type RetryingBucket struct {
+	Bucket
+
+	maxRetries     int
+	backoff        backoff.Backoff
+	attemptTimeout time.Duration
+	retryableCodes map[int]struct{}
+	statusCode     StatusCodeFunc
+
+	retries *prometheus.CounterVec
+}
+
+// NewRetryingBucket returns a bucket which retries failed operations against the given bucket. The status code
+// function is optional, if it's nil all failures are retried.
+func NewRetryingBucket(bkt Bucket, conf RetryConfig, statusCode StatusCodeFunc, reg prometheus.Registerer) (*RetryingBucket, error) {
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+
+	b := &RetryingBucket{
+		Bucket:     bkt,
+		maxRetries: conf.MaxRetries,
+		backoff: backoff.Backoff{
+			Factor: 2,
+			Min:    defaultMinRetryBackoff,
+			Max:    defaultMaxRetryBackoff,
+			Jitter: true,
+		},
+		attemptTimeout: time.Duration(conf.AttemptTimeout),
+		retryableCodes: map[int]struct{}{},
+		statusCode:     statusCode,
+		retries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name:        "thanos_objstore_bucket_operation_retries_total",
+			Help:        "Total number of retries of failed operations against a bucket.",
+			ConstLabels: prometheus.Labels{"bucket": bkt.Name()},
+		}, []string{"operation"}),
+	}
+	if conf.MinBackoff > 0 {
+		b.backoff.Min = time.Duration(conf.MinBackoff)
+	}
+	if conf.MaxBackoff > 0 {
+		b.backoff.Max = time.Duration(conf.MaxBackoff)
+	}
+	if b.backoff.Min > b.backoff.Max {
+		b.backoff.Min = b.backoff.Max
+	}
+
+	codes := conf.RetryableStatusCodes
+	if len(codes) == 0 {
+		codes = DefaultRetryableStatusCodes
+	}
+	for _, c := range codes {
+		b.retryableCodes[c] = struct{}{}
+	}
+
+	for _, op := range []string{OpIter, OpGet, OpGetRange, OpExists, OpUpload, OpDelete, OpAttributes} {
+		b.retries.WithLabelValues(op)
+	}
+	return b, nil
+}
+
+// noRetryError marks errors of attempts which must not be retried.
+type noRetryError struct {
+	err error
+}
+
+func (e noRetryError) Error() string { return e.err.Error() }
+
+func (b *RetryingBucket) isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || b.Bucket.IsObjNotFoundErr(err) {
+		return false
+	}
+	if b.statusCode == nil {
+		return true
+	}
+	code, ok := b.statusCode(err)
+	if !ok {
+		return true
+	}
+	_, ok = b.retryableCodes[code]
+	return ok
+}
+
+// retry calls f until it succeeds, fails with an error which is not retryable or runs out of retries.
+func (b *RetryingBucket) retry(ctx context.Context, op string, f func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		err := f(ctx)
+		if err == nil {
+			return nil
+		}
+		if nr, ok := err.(noRetryError); ok {
+			return nr.err
+		}
+		if attempt >= b.maxRetries || !b.isRetryable(ctx, err) {
+			return err
+		}
+
+		b.retries.WithLabelValues(op).Inc()
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(b.backoff.ForAttempt(float64(attempt))):
+		}
+	}
+}
+
+// withAttemptTimeout calls f with a context which is cancelled when the attempt timeout passes.
+func (b *RetryingBucket) withAttemptTimeout(f func(ctx context.Context) error) func(ctx context.Context) error {
+	if b.attemptTimeout <= 0 {
+		return f
+	}
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, b.attemptTimeout)
+		defer cancel()
+		return f(ctx)
+	}
+}
+
+// read retries calls returning readers. As readers often can't be read after their context is cancelled,
+// the attempt timeout only applies until the reader is returned, and the context is cancelled when it's closed.
+func (b *RetryingBucket) read(ctx context.Context, op string, f func(ctx context.Context) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	err := b.retry(ctx, op, func(ctx context.Context) error {
+		if b.attemptTimeout <= 0 {
+			var err error
+			rc, err = f(ctx)
+			return err
+		}
+
+		attemptCtx, cancel := context.WithCancel(ctx)
+		timer := time.AfterFunc(b.attemptTimeout, cancel)
+		r, err := f(attemptCtx)
+		if !timer.Stop() {
+			// The attempt timed out, so the reader may not be usable anymore.
+			if r != nil {
+				_ = r.Close()
+			}
+			cancel()
+			if err == nil {
+				err = errors.Errorf("attempt timed out after %v", b.attemptTimeout)
+			}
+			return err
+		}
+		if err != nil {
+			cancel()
+			return err
+		}
+		rc = &cancelOnCloseReader{ReadCloser: r, cancel: cancel}
+		return nil
+	})
+	return rc, err
+}
+
+type cancelOnCloseReader struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnCloseReader) ObjectSize() (int64, error) { return TryToGetSize(r.ReadCloser) }
+
+func (r *cancelOnCloseReader) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
+
+func (b *RetryingBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...IterOption) error {
+	called := false
+	return b.retry(ctx, OpIter, b.withAttemptTimeout(func(ctx context.Context) error {
+		err := b.Bucket.Iter(ctx, dir, func(name string) error {
+			called = true
+			return f(name)
+		}, options...)
+		if err != nil && called {
+			// Retrying would pass the same objects to the callback again.
+			return noRetryError{err: err}
+		}
+		return err
+	}))
+}
+
+func (b *RetryingBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return b.read(ctx, OpGet, func(ctx context.Context) (io.ReadCloser, error) {
+		return b.Bucket.Get(ctx, name)
+	})
+}
+
+func (b *RetryingBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	return b.read(ctx, OpGetRange, func(ctx context.Context) (io.ReadCloser, error) {
+		return b.Bucket.GetRange(ctx, name, off, length)
+	})
+}
+
+func (b *RetryingBucket) Exists(ctx context.Context, name string) (exists bool, err error) {
+	err = b.retry(ctx, OpExists, b.withAttemptTimeout(func(ctx context.Context) error {
+		exists, err = b.Bucket.Exists(ctx, name)
+		return err
+	}))
+	return exists, err
+}
+
+func (b *RetryingBucket) Attributes(ctx context.Context, name string) (attrs ObjectAttributes, err error) {
+	err = b.retry(ctx, OpAttributes, b.withAttemptTimeout(func(ctx context.Context) error {
+		attrs, err = b.Bucket.Attributes(ctx, name)
+		return err
+	}))
+	return attrs, err
+}
+
+func (b *RetryingBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	seeker, canRetry := r.(io.Seeker)
+	var start int64
+	if canRetry {
+		var err error
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			canRetry = false
+		}
+	}
+
+	first := true
+	return b.retry(ctx, OpUpload, b.withAttemptTimeout(func(ctx context.Context) error {
+		if !first {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return noRetryError{err: errors.Wrap(err, "rewind reader to retry upload")}
+			}
+		}
+		first = false
+
+		err := b.Bucket.Upload(ctx, name, r)
+		if err != nil && !canRetry {
+			return noRetryError{err: err}
+		}
+		return err
+	}))
+}
+
+func (b *RetryingBucket) Delete(ctx context.Context, name string) error {
+	return b.retry(ctx, OpDelete, b.withAttemptTimeout(func(ctx context.Context) error {
+		return b.Bucket.Delete(ctx, name)
+	}))
+}
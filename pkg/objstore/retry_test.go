@@ -0,0 +1,174 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+type statusCodeError int
+
+func (e statusCodeError) Error() string { return http.StatusText(int(e)) }
+
+func testStatusCode(err error) (int, bool) {
+	if sErr, ok := errors.Cause(err).(statusCodeError); ok {
+		return int(sErr), true
+	}
+	return 0, false
+}
+
+// flakyBucket fails the next operations with the given errors.
+type flakyBucket struct {
+	Bucket
+
+	errs  []error
+	delay time.Duration
+}
+
+func (b *flakyBucket) fail(ctx context.Context) error {
+	if b.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(b.delay):
+		}
+	}
+	if len(b.errs) == 0 {
+		return nil
+	}
+	err := b.errs[0]
+	b.errs = b.errs[1:]
+	return err
+}
+
+func (b *flakyBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...IterOption) error {
+	if err := b.Bucket.Iter(ctx, dir, f, options...); err != nil {
+		return err
+	}
+	return b.fail(ctx)
+}
+
+func (b *flakyBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	if err := b.fail(ctx); err != nil {
+		return nil, err
+	}
+	return b.Bucket.Get(ctx, name)
+}
+
+func (b *flakyBucket) Exists(ctx context.Context, name string) (bool, error) {
+	if err := b.fail(ctx); err != nil {
+		return false, err
+	}
+	return b.Bucket.Exists(ctx, name)
+}
+
+func (b *flakyBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	if err := b.fail(ctx); err != nil {
+		// Consume part of the reader before failing, like a failed upload would.
+		_, _ = r.Read(make([]byte, 2))
+		return err
+	}
+	return b.Bucket.Upload(ctx, name, r)
+}
+
+func newTestRetryingBucket(t *testing.T, bkt Bucket, conf RetryConfig) *RetryingBucket {
+	conf.MinBackoff = model.Duration(time.Millisecond)
+	conf.MaxBackoff = model.Duration(time.Millisecond)
+	b, err := NewRetryingBucket(bkt, conf, testStatusCode, nil)
+	testutil.Ok(t, err)
+	return b
+}
+
+func TestRetryingBucket_AcceptanceTest(t *testing.T) {
+	AcceptanceTest(t, newTestRetryingBucket(t, NewInMemBucket(), RetryConfig{MaxRetries: 3, AttemptTimeout: model.Duration(time.Minute)}))
+}
+
+func TestRetryingBucket(t *testing.T) {
+	ctx := context.Background()
+	inmem := NewInMemBucket()
+	testutil.Ok(t, inmem.Upload(ctx, "obj", strings.NewReader("hello")))
+
+	flaky := &flakyBucket{Bucket: inmem}
+	bkt := newTestRetryingBucket(t, flaky, RetryConfig{MaxRetries: 2})
+
+	t.Run("retryable failures are retried", func(t *testing.T) {
+		flaky.errs = []error{statusCodeError(http.StatusServiceUnavailable), errors.New("connection reset")}
+		ok, err := bkt.Exists(ctx, "obj")
+		testutil.Ok(t, err)
+		testutil.Assert(t, ok)
+		testutil.Equals(t, 2.0, promtest.ToFloat64(bkt.retries.WithLabelValues(OpExists)))
+	})
+	t.Run("retries are limited", func(t *testing.T) {
+		flaky.errs = []error{statusCodeError(http.StatusInternalServerError), statusCodeError(http.StatusInternalServerError), statusCodeError(http.StatusInternalServerError)}
+		_, err := bkt.Get(ctx, "obj")
+		testutil.NotOk(t, err)
+		testutil.Equals(t, 2.0, promtest.ToFloat64(bkt.retries.WithLabelValues(OpGet)))
+	})
+	t.Run("other status codes are not retried", func(t *testing.T) {
+		flaky.errs = []error{errors.Wrap(statusCodeError(http.StatusForbidden), "get")}
+		_, err := bkt.Get(ctx, "obj")
+		testutil.NotOk(t, err)
+		testutil.Equals(t, 2.0, promtest.ToFloat64(bkt.retries.WithLabelValues(OpGet)))
+	})
+	t.Run("uploads are retried from the start", func(t *testing.T) {
+		flaky.errs = []error{statusCodeError(http.StatusTooManyRequests)}
+		testutil.Ok(t, bkt.Upload(ctx, "uploaded", strings.NewReader("hello world")))
+
+		rc, err := inmem.Get(ctx, "uploaded")
+		testutil.Ok(t, err)
+		content, err := ioutil.ReadAll(rc)
+		testutil.Ok(t, err)
+		testutil.Equals(t, "hello world", string(content))
+	})
+	t.Run("uploads which can't seek are not retried", func(t *testing.T) {
+		flaky.errs = []error{statusCodeError(http.StatusTooManyRequests)}
+		testutil.NotOk(t, bkt.Upload(ctx, "not-seekable", io.MultiReader(bytes.NewReader([]byte("hello")))))
+	})
+	t.Run("iter is not retried after objects were passed", func(t *testing.T) {
+		flaky.errs = []error{statusCodeError(http.StatusServiceUnavailable)}
+		testutil.NotOk(t, bkt.Iter(ctx, "", func(string) error { return nil }))
+		testutil.Equals(t, 0.0, promtest.ToFloat64(bkt.retries.WithLabelValues(OpIter)))
+		flaky.errs = nil
+	})
+}
+
+func TestRetryingBucket_AttemptTimeout(t *testing.T) {
+	ctx := context.Background()
+	inmem := NewInMemBucket()
+	testutil.Ok(t, inmem.Upload(ctx, "obj", strings.NewReader("hello")))
+
+	flaky := &flakyBucket{Bucket: inmem, delay: time.Second}
+	bkt := newTestRetryingBucket(t, flaky, RetryConfig{MaxRetries: 1, AttemptTimeout: model.Duration(50 * time.Millisecond)})
+
+	_, err := bkt.Exists(ctx, "obj")
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 1.0, promtest.ToFloat64(bkt.retries.WithLabelValues(OpExists)))
+
+	_, err = bkt.Get(ctx, "obj")
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 1.0, promtest.ToFloat64(bkt.retries.WithLabelValues(OpGet)))
+
+	// Readers can be read after the attempt timeout.
+	flaky.delay = 0
+	rc, err := bkt.Get(ctx, "obj")
+	testutil.Ok(t, err)
+	time.Sleep(100 * time.Millisecond)
+	content, err := ioutil.ReadAll(rc)
+	testutil.Ok(t, err)
+	testutil.Ok(t, rc.Close())
+	testutil.Equals(t, "hello", string(content))
+}
@@ -15,9 +15,14 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	awscredentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/minio/minio-go/v7"
@@ -87,6 +92,12 @@ type Config struct {
 	PartSize    uint64    `yaml:"part_size"`
 	SSEConfig   SSEConfig `yaml:"sse_config"`
 	STSEndpoint string    `yaml:"sts_endpoint"`
+	// RoleARN is the role assumed to access the bucket, using the other credentials only to assume it.
+	RoleARN         string `yaml:"role_arn"`
+	RoleExternalID  string `yaml:"role_external_id"`
+	RoleSessionName string `yaml:"role_session_name"`
+	// WebIdentityTokenFile is the path of a file with an OIDC token used to assume RoleARN, e.g. a service account token of a Kubernetes pod.
+	WebIdentityTokenFile string `yaml:"web_identity_token_file"`
 }
 
 // SSEConfig deals with the configuration of SSE for Minio. The following options are valid:
@@ -209,6 +220,84 @@ func (s *overrideSignerType) Retrieve() (credentials.Value, error) {
 	return v, nil
 }
 
+// assumeRoleProvider provides the temporary credentials of an assumed role, which are refreshed by the AWS SDK
+// before they expire.
+type assumeRoleProvider struct {
+	creds *awscredentials.Credentials
+}
+
+// newAssumeRoleProvider returns a provider assuming the role of the config. The role is assumed with the web identity
+// token file if set, otherwise with the access key of the config or the credentials of the default AWS credential chain.
+func newAssumeRoleProvider(config Config) (*assumeRoleProvider, error) {
+	awsConfig := aws.NewConfig()
+	if config.Region != "" {
+		awsConfig = awsConfig.WithRegion(config.Region)
+	}
+	if config.STSEndpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(config.STSEndpoint)
+	}
+	if config.AccessKey != "" {
+		awsConfig = awsConfig.WithCredentials(awscredentials.NewStaticCredentials(config.AccessKey, config.SecretKey, ""))
+	}
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "create AWS session")
+	}
+
+	if config.WebIdentityTokenFile != "" {
+		return &assumeRoleProvider{creds: stscreds.NewWebIdentityCredentials(sess, config.RoleARN, config.RoleSessionName, config.WebIdentityTokenFile)}, nil
+	}
+	return &assumeRoleProvider{creds: stscreds.NewCredentials(sess, config.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = config.RoleSessionName
+		if config.RoleExternalID != "" {
+			p.ExternalID = aws.String(config.RoleExternalID)
+		}
+	})}, nil
+}
+
+func (p *assumeRoleProvider) Retrieve() (credentials.Value, error) {
+	v, err := p.creds.Get()
+	if err != nil {
+		return credentials.Value{}, errors.Wrap(err, "assume role")
+	}
+	return credentials.Value{
+		AccessKeyID:     v.AccessKeyID,
+		SecretAccessKey: v.SecretAccessKey,
+		SessionToken:    v.SessionToken,
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
+
+func (p *assumeRoleProvider) IsExpired() bool {
+	return p.creds.IsExpired()
+}
+
+var (
+	retriesMtx sync.Mutex
+	// retriesDisabled is whether the minio clients of the process try every operation once, nil until the retries of a
+	// bucket are configured.
+	retriesDisabled *bool
+)
+
+// ConfigureRetries sets whether the minio clients of the process retry failed operations. Minio only supports a
+// number of retries for the whole process, so an error is returned if an earlier bucket configured it differently.
+func ConfigureRetries(disable bool) error {
+	retriesMtx.Lock()
+	defer retriesMtx.Unlock()
+
+	if retriesDisabled != nil {
+		if *retriesDisabled != disable {
+			return errors.New("the retries of the minio clients can't be disabled for some S3 buckets of the process only, either all or none of them have to set retry max_retries")
+		}
+		return nil
+	}
+	retriesDisabled = &disable
+	if disable {
+		minio.MaxRetry = 1
+	}
+	return nil
+}
+
 // NewBucketWithConfig returns a new Bucket using the provided s3 config values.
 func NewBucketWithConfig(logger log.Logger, config Config, component string) (*Bucket, error) {
 	var chain []credentials.Provider
@@ -224,7 +313,13 @@ func NewBucketWithConfig(logger log.Logger, config Config, component string) (*B
 	if err := validate(config); err != nil {
 		return nil, err
 	}
-	if config.AccessKey != "" {
+	if config.RoleARN != "" {
+		p, err := newAssumeRoleProvider(config)
+		if err != nil {
+			return nil, err
+		}
+		chain = []credentials.Provider{wrapCredentialsProvider(p)}
+	} else if config.AccessKey != "" {
 		chain = []credentials.Provider{wrapCredentialsProvider(&credentials.Static{
 			Value: credentials.Value{
 				AccessKeyID:     config.AccessKey,
@@ -338,6 +433,18 @@ func validate(conf Config) error {
 		return errors.New("no s3 secret_key specified while access_key is present in config file; either both should be present in config or envvars/IAM should be used.")
 	}
 
+	if conf.RoleARN == "" && (conf.RoleExternalID != "" || conf.RoleSessionName != "" || conf.WebIdentityTokenFile != "") {
+		return errors.New("role_arn must be set if role_external_id, role_session_name or web_identity_token_file are set")
+	}
+
+	if conf.WebIdentityTokenFile != "" && conf.AccessKey != "" {
+		return errors.New("access_key and web_identity_token_file are mutually exclusive, as the web identity token is used to assume role_arn")
+	}
+
+	if conf.WebIdentityTokenFile != "" && conf.RoleExternalID != "" {
+		return errors.New("role_external_id is not supported with web_identity_token_file")
+	}
+
 	if conf.SSEConfig.Type == SSEC && conf.SSEConfig.EncryptionKey == "" {
 		return errors.New("encryption_key must be set if sse_config.type is set to 'SSE-C'")
 	}
@@ -510,6 +617,12 @@ func (b *Bucket) IsObjNotFoundErr(err error) bool {
 	return minio.ToErrorResponse(errors.Cause(err)).Code == "NoSuchKey"
 }
 
+// StatusCode returns the HTTP status code of the error of a failed operation, if there is one.
+func StatusCode(err error) (int, bool) {
+	code := minio.ToErrorResponse(errors.Cause(err)).StatusCode
+	return code, code != 0
+}
+
 func (b *Bucket) Close() error { return nil }
 
 // getServerSideEncryption returns the SSE to use.
@@ -5,15 +5,20 @@ package s3
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/pkg/errors"
 
 	"github.com/thanos-io/thanos/pkg/testutil"
 )
@@ -231,6 +236,138 @@ http_config:
 	testutil.Equals(t, "bucket-owner-full-control", cfg2.PutUserMetadata["X-Amz-Acl"])
 }
 
+func TestValidate_AssumeRole(t *testing.T) {
+	for _, tcase := range []struct {
+		name  string
+		input string
+		ok    bool
+	}{
+		{
+			name:  "role with default credentials",
+			input: "bucket: b\nendpoint: e\nrole_arn: arn:aws:iam::123456789012:role/thanos\nrole_session_name: thanos",
+			ok:    true,
+		},
+		{
+			name:  "role with access key and external ID",
+			input: "bucket: b\nendpoint: e\naccess_key: a\nsecret_key: s\nrole_arn: arn:aws:iam::123456789012:role/thanos\nrole_external_id: id",
+			ok:    true,
+		},
+		{
+			name:  "role with web identity",
+			input: "bucket: b\nendpoint: e\nrole_arn: arn:aws:iam::123456789012:role/thanos\nweb_identity_token_file: /var/run/secrets/token",
+			ok:    true,
+		},
+		{
+			name:  "web identity without role",
+			input: "bucket: b\nendpoint: e\nweb_identity_token_file: /var/run/secrets/token",
+		},
+		{
+			name:  "external ID without role",
+			input: "bucket: b\nendpoint: e\nrole_external_id: id",
+		},
+		{
+			name:  "web identity with access key",
+			input: "bucket: b\nendpoint: e\naccess_key: a\nsecret_key: s\nrole_arn: arn:aws:iam::123456789012:role/thanos\nweb_identity_token_file: /var/run/secrets/token",
+		},
+		{
+			name:  "web identity with external ID",
+			input: "bucket: b\nendpoint: e\nrole_arn: arn:aws:iam::123456789012:role/thanos\nrole_external_id: id\nweb_identity_token_file: /var/run/secrets/token",
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			cfg, err := parseConfig([]byte(tcase.input))
+			testutil.Ok(t, err)
+			if tcase.ok {
+				testutil.Ok(t, validate(cfg))
+			} else {
+				testutil.NotOk(t, validate(cfg))
+			}
+		})
+	}
+}
+
+func TestBucket_AssumeRole(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	testutil.Ok(t, ioutil.WriteFile(tokenFile, []byte("web-identity-token"), 0600))
+
+	for _, tcase := range []struct {
+		name      string
+		configure func(cfg *Config)
+		expected  map[string]string
+	}{
+		{
+			name: "access key",
+			configure: func(cfg *Config) {
+				cfg.AccessKey = "source"
+				cfg.SecretKey = "source"
+				cfg.RoleExternalID = "external-id"
+			},
+			expected: map[string]string{
+				"Action":          "AssumeRole",
+				"RoleArn":         "arn:aws:iam::123456789012:role/thanos",
+				"RoleSessionName": "thanos",
+				"ExternalId":      "external-id",
+			},
+		},
+		{
+			name: "web identity",
+			configure: func(cfg *Config) {
+				cfg.WebIdentityTokenFile = tokenFile
+			},
+			expected: map[string]string{
+				"Action":           "AssumeRoleWithWebIdentity",
+				"RoleArn":          "arn:aws:iam::123456789012:role/thanos",
+				"RoleSessionName":  "thanos",
+				"WebIdentityToken": "web-identity-token",
+			},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			// The server acts both as STS and S3 endpoint.
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost {
+					testutil.Ok(t, r.ParseForm())
+					for k, v := range tcase.expected {
+						testutil.Equals(t, v, r.PostForm.Get(k), k)
+					}
+					action := r.PostForm.Get("Action")
+					fmt.Fprintf(w, `<%[1]sResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><%[1]sResult><Credentials>`+
+						`<AccessKeyId>assumed</AccessKeyId><SecretAccessKey>assumed</SecretAccessKey><SessionToken>session-token</SessionToken>`+
+						`<Expiration>%s</Expiration></Credentials></%[1]sResult></%[1]sResponse>`, action, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+					return
+				}
+
+				// S3 requests are signed with the credentials of the assumed role.
+				testutil.Assert(t, strings.Contains(r.Header.Get("Authorization"), "Credential=assumed/"), "unexpected authorization %s", r.Header.Get("Authorization"))
+				testutil.Equals(t, "session-token", r.Header.Get("X-Amz-Security-Token"))
+				w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+				_, err := w.Write([]byte("content"))
+				testutil.Ok(t, err)
+			}))
+			defer srv.Close()
+
+			cfg := DefaultConfig
+			cfg.Bucket = "test-bucket"
+			cfg.Endpoint = srv.Listener.Addr().String()
+			cfg.Insecure = true
+			cfg.Region = "test"
+			cfg.STSEndpoint = srv.URL
+			cfg.RoleARN = "arn:aws:iam::123456789012:role/thanos"
+			cfg.RoleSessionName = "thanos"
+			tcase.configure(&cfg)
+
+			bkt, err := NewBucketWithConfig(log.NewNopLogger(), cfg, "test")
+			testutil.Ok(t, err)
+
+			reader, err := bkt.Get(context.Background(), "test")
+			testutil.Ok(t, err)
+			content, err := ioutil.ReadAll(reader)
+			testutil.Ok(t, err)
+			testutil.Equals(t, "content", string(content))
+		})
+	}
+}
+
 func TestParseConfig_PartSize(t *testing.T) {
 	input := []byte(`bucket: "bucket-name"
 endpoint: "s3-endpoint"
@@ -367,3 +504,28 @@ func TestBucket_Get_ShouldReturnErrorIfServerTruncateResponse(t *testing.T) {
 	_, err = ioutil.ReadAll(reader)
 	testutil.Equals(t, io.ErrUnexpectedEOF, err)
 }
+
+func TestStatusCode(t *testing.T) {
+	code, ok := StatusCode(errors.Wrap(minio.ErrorResponse{StatusCode: http.StatusServiceUnavailable, Code: "SlowDown"}, "upload"))
+	testutil.Assert(t, ok)
+	testutil.Equals(t, http.StatusServiceUnavailable, code)
+
+	_, ok = StatusCode(errors.New("connection reset"))
+	testutil.Assert(t, !ok)
+}
+
+func TestConfigureRetries(t *testing.T) {
+	defer func(maxRetry int) {
+		minio.MaxRetry = maxRetry
+		retriesDisabled = nil
+	}(minio.MaxRetry)
+
+	retriesDisabled = nil
+	testutil.Ok(t, ConfigureRetries(true))
+	testutil.Equals(t, 1, minio.MaxRetry)
+	testutil.Ok(t, ConfigureRetries(true))
+
+	// Minio retries are set for the whole process, so buckets with their retries enabled can't be created anymore.
+	testutil.NotOk(t, ConfigureRetries(false))
+	testutil.Equals(t, 1, minio.MaxRetry)
+}
@@ -0,0 +1,465 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package sftp implements the objstore.Bucket interface against a directory of a remote host accessible over SFTP.
+// Objects are stored with the same layout as in the filesystem provider.
+package sftp
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"github.com/prometheus/common/model"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// Config stores the configuration for storing and accessing blobs over SFTP.
+type Config struct {
+	// Host is the address of the SSH server, in host:port format. The port defaults to 22.
+	Host string `yaml:"host"`
+	User string `yaml:"user"`
+	// Password is used to authenticate if set.
+	Password string `yaml:"password"`
+	// PrivateKeyFile is the path of the PEM encoded private key used to authenticate if set.
+	PrivateKeyFile       string `yaml:"private_key_file"`
+	PrivateKeyPassphrase string `yaml:"private_key_passphrase"`
+	// KnownHostsFile is the path of a file in the OpenSSH known_hosts format with the keys of the host.
+	KnownHostsFile string `yaml:"known_hosts_file"`
+	// HostPublicKey is the public key of the host, in the OpenSSH authorized_keys format.
+	HostPublicKey string `yaml:"host_public_key"`
+	// InsecureSkipVerify disables the verification of the host key. Only use it for testing.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	// Directory is the absolute path of the directory on the host in which objects are stored.
+	Directory   string         `yaml:"directory"`
+	DialTimeout model.Duration `yaml:"dial_timeout"`
+}
+
+// DefaultConfig is the default config for an SFTP client.
+var DefaultConfig = Config{
+	DialTimeout: model.Duration(30 * time.Second),
+}
+
+func (conf *Config) validate() error {
+	if conf.Host == "" {
+		return errors.New("missing host for SFTP bucket")
+	}
+	if conf.User == "" {
+		return errors.New("missing user for SFTP bucket")
+	}
+	if conf.Password == "" && conf.PrivateKeyFile == "" {
+		return errors.New("one of password or private_key_file is required for SFTP bucket")
+	}
+	if conf.KnownHostsFile == "" && conf.HostPublicKey == "" && !conf.InsecureSkipVerify {
+		return errors.New("one of known_hosts_file or host_public_key is required to verify the host key, unless insecure_skip_verify is set")
+	}
+	if conf.Directory == "" {
+		return errors.New("missing directory for SFTP bucket")
+	}
+	if !path.IsAbs(conf.Directory) {
+		return errors.New("directory for SFTP bucket must be an absolute path")
+	}
+	return nil
+}
+
+// parseConfig unmarshals a buffer into a Config with default values.
+func parseConfig(conf []byte) (Config, error) {
+	config := DefaultConfig
+	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Bucket implements the objstore.Bucket interface against a directory of a remote host accessible over SFTP.
+// The connection is established lazily and re-established when it's lost.
+type Bucket struct {
+	logger    log.Logger
+	name      string
+	addr      string
+	rootDir   string
+	sshConfig *ssh.ClientConfig
+
+	mtx    sync.Mutex
+	conn   *ssh.Client
+	client *sftp.Client
+}
+
+// NewBucket returns a new Bucket using the provided SFTP config.
+func NewBucket(logger log.Logger, conf []byte) (*Bucket, error) {
+	config, err := parseConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	return NewBucketWithConfig(logger, config)
+}
+
+// NewBucketWithConfig returns a new Bucket using the provided SFTP config values.
+func NewBucketWithConfig(logger log.Logger, config Config) (*Bucket, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	var auth []ssh.AuthMethod
+	if config.PrivateKeyFile != "" {
+		b, err := ioutil.ReadFile(config.PrivateKeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read private key file %s", config.PrivateKeyFile)
+		}
+		var signer ssh.Signer
+		if config.PrivateKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(b, []byte(config.PrivateKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(b)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse private key file %s", config.PrivateKeyFile)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if config.Password != "" {
+		auth = append(auth, ssh.Password(config.Password))
+	}
+
+	hostKeyCallback, err := newHostKeyCallback(config)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := config.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	return &Bucket{
+		logger:  logger,
+		name:    fmt.Sprintf("%s@%s:%s", config.User, config.Host, config.Directory),
+		addr:    addr,
+		rootDir: path.Clean(config.Directory),
+		sshConfig: &ssh.ClientConfig{
+			User:            config.User,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         time.Duration(config.DialTimeout),
+		},
+	}, nil
+}
+
+func newHostKeyCallback(config Config) (ssh.HostKeyCallback, error) {
+	if config.InsecureSkipVerify {
+		return ssh.InsecureIgnoreHostKey(), nil //nolint:gosec
+	}
+	if config.HostPublicKey != "" {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(config.HostPublicKey))
+		if err != nil {
+			return nil, errors.Wrap(err, "parse host public key")
+		}
+		return ssh.FixedHostKey(key), nil
+	}
+	callback, err := knownhosts.New(config.KnownHostsFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read known hosts file %s", config.KnownHostsFile)
+	}
+	return callback, nil
+}
+
+// sftpClient returns the client of the current connection, connecting if there is none.
+func (b *Bucket) sftpClient() (*sftp.Client, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.client != nil {
+		return b.client, nil
+	}
+
+	conn, err := ssh.Dial("tcp", b.addr, b.sshConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "connect to %s", b.addr)
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		runutil.CloseWithLogOnErr(b.logger, conn, "ssh connection")
+		return nil, errors.Wrap(err, "start SFTP session")
+	}
+	b.conn, b.client = conn, client
+
+	// Forget the connection once it's closed, so the next request reconnects.
+	go func() {
+		err := conn.Wait()
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+		if b.conn == conn {
+			level.Debug(b.logger).Log("msg", "SFTP connection closed", "addr", b.addr, "err", err)
+			b.conn, b.client = nil, nil
+		}
+	}()
+	return client, nil
+}
+
+func (b *Bucket) path(name string) string {
+	return path.Join(b.rootDir, name)
+}
+
+// Iter calls f for each entry in the given directory. The argument to f is the full
+// object name including the prefix of the inspected directory.
+func (b *Bucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	client, err := b.sftpClient()
+	if err != nil {
+		return err
+	}
+	return b.iter(ctx, client, dir, f, objstore.ApplyIterOptions(options...))
+}
+
+func (b *Bucket) iter(ctx context.Context, client *sftp.Client, dir string, f func(string) error, params objstore.IterParams) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	absDir := b.path(dir)
+	files, err := client.ReadDir(absDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		// Listing a file rather than a directory returns no objects, as in the filesystem provider.
+		if info, statErr := client.Stat(absDir); statErr == nil && !info.IsDir() {
+			return nil
+		}
+		return errors.Wrapf(err, "read dir %s", absDir)
+	}
+
+	// Return entries in lexicographical order, like the filesystem provider does.
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	dir = strings.TrimSuffix(dir, objstore.DirDelim)
+	for _, file := range files {
+		name := file.Name()
+		if dir != "" {
+			name = dir + objstore.DirDelim + name
+		}
+
+		if file.IsDir() {
+			entries, err := client.ReadDir(b.path(name))
+			if err != nil {
+				return errors.Wrapf(err, "read dir %s", b.path(name))
+			}
+			if len(entries) == 0 {
+				// Skip empty directories.
+				continue
+			}
+
+			name += objstore.DirDelim
+
+			if params.Recursive {
+				// Recursively list files in the subdirectory.
+				if err := b.iter(ctx, client, name, f, params); err != nil {
+					return err
+				}
+				continue
+			}
+		} else if isTempFile(file.Name()) {
+			continue
+		}
+		if err := f(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns a reader for the given object name.
+func (b *Bucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return b.GetRange(ctx, name, 0, -1)
+}
+
+type rangeReaderCloser struct {
+	io.Reader
+	f *sftp.File
+}
+
+func (r *rangeReaderCloser) Close() error {
+	return r.f.Close()
+}
+
+// GetRange returns a new range reader for the given object name and range.
+func (b *Bucket) GetRange(_ context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	if name == "" {
+		return nil, errors.New("object name is empty")
+	}
+
+	client, err := b.sftpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	file := b.path(name)
+	f, err := client.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open %s", file)
+	}
+
+	if off > 0 {
+		if _, err := f.Seek(off, io.SeekStart); err != nil {
+			runutil.CloseWithLogOnErr(b.logger, f, "sftp file")
+			return nil, errors.Wrapf(err, "seek %v", off)
+		}
+	}
+
+	if length == -1 {
+		return f, nil
+	}
+	return &rangeReaderCloser{Reader: io.LimitReader(f, length), f: f}, nil
+}
+
+// Attributes returns information about the specified object.
+func (b *Bucket) Attributes(_ context.Context, name string) (objstore.ObjectAttributes, error) {
+	client, err := b.sftpClient()
+	if err != nil {
+		return objstore.ObjectAttributes{}, err
+	}
+
+	file := b.path(name)
+	stat, err := client.Stat(file)
+	if err != nil {
+		return objstore.ObjectAttributes{}, errors.Wrapf(err, "stat %s", file)
+	}
+	return objstore.ObjectAttributes{
+		Size:         stat.Size(),
+		LastModified: stat.ModTime(),
+	}, nil
+}
+
+// Exists checks if the given object exists.
+func (b *Bucket) Exists(_ context.Context, name string) (bool, error) {
+	client, err := b.sftpClient()
+	if err != nil {
+		return false, err
+	}
+
+	file := b.path(name)
+	info, err := client.Stat(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "stat %s", file)
+	}
+	return !info.IsDir(), nil
+}
+
+// tempFileSuffix is the suffix of files objects are uploaded to, before they are renamed to their final name.
+const tempFileSuffix = ".tmp-upload"
+
+func isTempFile(name string) bool {
+	return strings.HasSuffix(name, tempFileSuffix)
+}
+
+// Upload writes the contents of the reader as an object into the bucket. The object is first written
+// to a temporary file and then renamed, so partially uploaded objects are never visible.
+func (b *Bucket) Upload(_ context.Context, name string, r io.Reader) (err error) {
+	client, err := b.sftpClient()
+	if err != nil {
+		return err
+	}
+
+	file := b.path(name)
+	if err := client.MkdirAll(path.Dir(file)); err != nil {
+		return errors.Wrapf(err, "mkdir %s", path.Dir(file))
+	}
+
+	tmp := fmt.Sprintf("%s.%s%s", file, ulid.MustNew(ulid.Now(), rand.Reader), tempFileSuffix)
+	f, err := client.Create(tmp)
+	if err != nil {
+		return errors.Wrapf(err, "create %s", tmp)
+	}
+	defer func() {
+		if err != nil {
+			if rerr := client.Remove(tmp); rerr != nil && !os.IsNotExist(rerr) {
+				level.Warn(b.logger).Log("msg", "failed to remove temporary upload file", "file", tmp, "err", rerr)
+			}
+		}
+	}()
+
+	if _, err := f.ReadFrom(r); err != nil {
+		runutil.CloseWithLogOnErr(b.logger, f, "sftp file")
+		return errors.Wrapf(err, "copy to %s", tmp)
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrapf(err, "close %s", tmp)
+	}
+	if err := client.PosixRename(tmp, file); err != nil {
+		return errors.Wrapf(err, "rename %s to %s", tmp, file)
+	}
+	return nil
+}
+
+// Delete removes the object with the given name, and the directories left empty by its removal.
+func (b *Bucket) Delete(_ context.Context, name string) error {
+	client, err := b.sftpClient()
+	if err != nil {
+		return err
+	}
+
+	file := b.path(name)
+	if err := client.Remove(file); err != nil {
+		return errors.Wrapf(err, "rm %s", file)
+	}
+	for dir := path.Dir(file); dir != b.rootDir && strings.HasPrefix(dir, b.rootDir); dir = path.Dir(dir) {
+		entries, err := client.ReadDir(dir)
+		if err != nil {
+			return errors.Wrapf(err, "read dir %s", dir)
+		}
+		if len(entries) > 0 {
+			break
+		}
+		if err := client.RemoveDirectory(dir); err != nil {
+			return errors.Wrapf(err, "rmdir %s", dir)
+		}
+	}
+	return nil
+}
+
+// IsObjNotFoundErr returns true if error means that object is not found. Relevant to Get operations.
+func (b *Bucket) IsObjNotFoundErr(err error) bool {
+	return os.IsNotExist(errors.Cause(err))
+}
+
+// Close closes the SFTP session and the underlying SSH connection.
+func (b *Bucket) Close() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.client == nil {
+		return nil
+	}
+	err := b.client.Close()
+	if cerr := b.conn.Close(); err == nil {
+		err = cerr
+	}
+	b.conn, b.client = nil, nil
+	return err
+}
+
+// Name returns the bucket name.
+func (b *Bucket) Name() string {
+	return fmt.Sprintf("sftp: %s", b.name)
+}
@@ -0,0 +1,149 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package sftp
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+// startServer starts an in-process SSH server with the SFTP subsystem, accepting the given password.
+func startServer(t *testing.T, password string) (addr string, hostKey ssh.PublicKey) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	testutil.Ok(t, err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	testutil.Ok(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, p []byte) (*ssh.Permissions, error) {
+			if string(p) != password {
+				return nil, ssh.ErrNoAuth
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	testutil.Ok(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveConn(conn, config)
+		}
+	}()
+	return l.Addr().String(), signer.PublicKey()
+}
+
+func serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newCh := range chans {
+		if newCh.ChannelType() != "session" {
+			_ = newCh.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		ch, reqs, err := newCh.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range reqs {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				_ = req.Reply(ok, nil)
+				if !ok {
+					continue
+				}
+				server, err := sftp.NewServer(ch)
+				if err != nil {
+					return
+				}
+				_ = server.Serve()
+				_ = server.Close()
+				return
+			}
+		}()
+	}
+}
+
+func TestBucket_AcceptanceTest(t *testing.T) {
+	addr, hostKey := startServer(t, "secret")
+
+	bkt, err := NewBucketWithConfig(log.NewNopLogger(), Config{
+		Host:          addr,
+		User:          "thanos",
+		Password:      "secret",
+		HostPublicKey: string(ssh.MarshalAuthorizedKey(hostKey)),
+		Directory:     t.TempDir(),
+	})
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, bkt.Close()) }()
+
+	objstore.AcceptanceTest(t, bkt)
+}
+
+func TestBucket_HostKeyVerification(t *testing.T) {
+	ctx := context.Background()
+	addr, hostKey := startServer(t, "secret")
+	dir := t.TempDir()
+
+	// Known hosts files are supported.
+	knownHosts := filepath.Join(dir, "known_hosts")
+	testutil.Ok(t, ioutil.WriteFile(knownHosts, []byte(knownhosts.Line([]string{addr}, hostKey)+"\n"), 0600))
+	bkt, err := NewBucketWithConfig(log.NewNopLogger(), Config{
+		Host:           addr,
+		User:           "thanos",
+		Password:       "secret",
+		KnownHostsFile: knownHosts,
+		Directory:      dir,
+	})
+	testutil.Ok(t, err)
+	_, err = bkt.Exists(ctx, "obj")
+	testutil.Ok(t, err)
+	testutil.Ok(t, bkt.Close())
+
+	// Connections to hosts with other keys are refused.
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	testutil.Ok(t, err)
+	otherKey, err := ssh.NewPublicKey(otherPub)
+	testutil.Ok(t, err)
+	bkt, err = NewBucketWithConfig(log.NewNopLogger(), Config{
+		Host:          addr,
+		User:          "thanos",
+		Password:      "secret",
+		HostPublicKey: string(ssh.MarshalAuthorizedKey(otherKey)),
+		Directory:     dir,
+	})
+	testutil.Ok(t, err)
+	_, err = bkt.Exists(ctx, "obj")
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.Contains(err.Error(), "host key mismatch"), "unexpected error %s", err)
+
+	// Host keys have to be verified unless explicitly disabled.
+	_, err = NewBucket(log.NewNopLogger(), []byte("host: "+addr+"\nuser: thanos\npassword: secret\ndirectory: "+dir))
+	testutil.NotOk(t, err)
+}
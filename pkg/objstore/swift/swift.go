@@ -11,6 +11,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 	"github.com/ncw/swift"
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
+	"go.uber.org/atomic"
 	"gopkg.in/yaml.v2"
 
 	"github.com/thanos-io/thanos/pkg/objstore"
@@ -38,30 +40,39 @@ var DefaultConfig = Config{
 	Retries:        3,
 	ConnectTimeout: model.Duration(10 * time.Second),
 	Timeout:        model.Duration(5 * time.Minute),
+	// Tokens are refreshed before the longest requests could outlive them.
+	TokenRefreshMargin: model.Duration(5 * time.Minute),
 }
 
 type Config struct {
-	AuthVersion            int            `yaml:"auth_version"`
-	AuthUrl                string         `yaml:"auth_url"`
-	Username               string         `yaml:"username"`
-	UserDomainName         string         `yaml:"user_domain_name"`
-	UserDomainID           string         `yaml:"user_domain_id"`
-	UserId                 string         `yaml:"user_id"`
-	Password               string         `yaml:"password"`
-	DomainId               string         `yaml:"domain_id"`
-	DomainName             string         `yaml:"domain_name"`
-	ProjectID              string         `yaml:"project_id"`
-	ProjectName            string         `yaml:"project_name"`
-	ProjectDomainID        string         `yaml:"project_domain_id"`
-	ProjectDomainName      string         `yaml:"project_domain_name"`
-	RegionName             string         `yaml:"region_name"`
-	ContainerName          string         `yaml:"container_name"`
-	ChunkSize              int64          `yaml:"large_object_chunk_size"`
-	SegmentContainerName   string         `yaml:"large_object_segments_container_name"`
-	Retries                int            `yaml:"retries"`
-	ConnectTimeout         model.Duration `yaml:"connect_timeout"`
-	Timeout                model.Duration `yaml:"timeout"`
-	UseDynamicLargeObjects bool           `yaml:"use_dynamic_large_objects"`
+	AuthVersion                 int            `yaml:"auth_version"`
+	AuthUrl                     string         `yaml:"auth_url"`
+	Username                    string         `yaml:"username"`
+	UserDomainName              string         `yaml:"user_domain_name"`
+	UserDomainID                string         `yaml:"user_domain_id"`
+	UserId                      string         `yaml:"user_id"`
+	Password                    string         `yaml:"password"`
+	DomainId                    string         `yaml:"domain_id"`
+	DomainName                  string         `yaml:"domain_name"`
+	ApplicationCredentialID     string         `yaml:"application_credential_id"`
+	ApplicationCredentialName   string         `yaml:"application_credential_name"`
+	ApplicationCredentialSecret string         `yaml:"application_credential_secret"`
+	TrustID                     string         `yaml:"trust_id"`
+	ProjectID                   string         `yaml:"project_id"`
+	ProjectName                 string         `yaml:"project_name"`
+	ProjectDomainID             string         `yaml:"project_domain_id"`
+	ProjectDomainName           string         `yaml:"project_domain_name"`
+	RegionName                  string         `yaml:"region_name"`
+	ContainerName               string         `yaml:"container_name"`
+	ChunkSize                   int64          `yaml:"large_object_chunk_size"`
+	SegmentContainerName        string         `yaml:"large_object_segments_container_name"`
+	Retries                     int            `yaml:"retries"`
+	ConnectTimeout              model.Duration `yaml:"connect_timeout"`
+	Timeout                     model.Duration `yaml:"timeout"`
+	UseDynamicLargeObjects      bool           `yaml:"use_dynamic_large_objects"`
+	// TokenRefreshMargin is how long before their expiry tokens are refreshed. 0 leaves refreshing to the client,
+	// which only does so shortly before expiry or after requests failed with an expired token.
+	TokenRefreshMargin model.Duration `yaml:"token_refresh_margin"`
 }
 
 func parseConfig(conf []byte) (*Config, error) {
@@ -70,6 +81,20 @@ func parseConfig(conf []byte) (*Config, error) {
 	return &sc, err
 }
 
+func (sc *Config) validate() error {
+	if (sc.ApplicationCredentialID != "" || sc.ApplicationCredentialName != "") && sc.ApplicationCredentialSecret == "" {
+		return errors.New("application_credential_secret is required for application credentials")
+	}
+	if sc.ApplicationCredentialSecret != "" && sc.TrustID != "" {
+		return errors.New("application credentials can't be trust scoped, as they are scoped to their project")
+	}
+	if sc.UserDomainName != "" && sc.DomainName != "" && sc.UserDomainName != sc.DomainName ||
+		sc.UserDomainID != "" && sc.DomainId != "" && sc.UserDomainID != sc.DomainId {
+		return errors.New("user_domain_name and user_domain_id are aliases of domain_name and domain_id, so they must not differ")
+	}
+	return nil
+}
+
 func configFromEnv() (*Config, error) {
 	c := swift.Connection{}
 	if err := c.ApplyEnvironment(); err != nil {
@@ -77,25 +102,30 @@ func configFromEnv() (*Config, error) {
 	}
 
 	config := Config{
-		AuthVersion:            c.AuthVersion,
-		AuthUrl:                c.AuthUrl,
-		Password:               c.ApiKey,
-		Username:               c.UserName,
-		UserId:                 c.UserId,
-		DomainId:               c.DomainId,
-		DomainName:             c.Domain,
-		ProjectID:              c.TenantId,
-		ProjectName:            c.Tenant,
-		ProjectDomainID:        c.TenantDomainId,
-		ProjectDomainName:      c.TenantDomain,
-		RegionName:             c.Region,
-		ContainerName:          os.Getenv("OS_CONTAINER_NAME"),
-		ChunkSize:              DefaultConfig.ChunkSize,
-		SegmentContainerName:   os.Getenv("SWIFT_SEGMENTS_CONTAINER_NAME"),
-		Retries:                c.Retries,
-		ConnectTimeout:         model.Duration(c.ConnectTimeout),
-		Timeout:                model.Duration(c.Timeout),
-		UseDynamicLargeObjects: false,
+		AuthVersion:                 c.AuthVersion,
+		AuthUrl:                     c.AuthUrl,
+		Password:                    c.ApiKey,
+		Username:                    c.UserName,
+		UserId:                      c.UserId,
+		DomainId:                    c.DomainId,
+		DomainName:                  c.Domain,
+		ApplicationCredentialID:     c.ApplicationCredentialId,
+		ApplicationCredentialName:   c.ApplicationCredentialName,
+		ApplicationCredentialSecret: c.ApplicationCredentialSecret,
+		TrustID:                     c.TrustId,
+		ProjectID:                   c.TenantId,
+		ProjectName:                 c.Tenant,
+		ProjectDomainID:             c.TenantDomainId,
+		ProjectDomainName:           c.TenantDomain,
+		RegionName:                  c.Region,
+		ContainerName:               os.Getenv("OS_CONTAINER_NAME"),
+		ChunkSize:                   DefaultConfig.ChunkSize,
+		SegmentContainerName:        os.Getenv("SWIFT_SEGMENTS_CONTAINER_NAME"),
+		Retries:                     c.Retries,
+		ConnectTimeout:              model.Duration(c.ConnectTimeout),
+		Timeout:                     model.Duration(c.Timeout),
+		UseDynamicLargeObjects:      false,
+		TokenRefreshMargin:          DefaultConfig.TokenRefreshMargin,
 	}
 	if os.Getenv("SWIFT_CHUNK_SIZE") != "" {
 		var err error
@@ -111,26 +141,59 @@ func configFromEnv() (*Config, error) {
 }
 
 func connectionFromConfig(sc *Config) *swift.Connection {
+	// The domain of the user may differ from the one of the project, which is set separately.
+	domain, domainID := sc.DomainName, sc.DomainId
+	if sc.UserDomainName != "" {
+		domain = sc.UserDomainName
+	}
+	if sc.UserDomainID != "" {
+		domainID = sc.UserDomainID
+	}
 	connection := swift.Connection{
-		Domain:         sc.DomainName,
-		DomainId:       sc.DomainId,
-		UserName:       sc.Username,
-		UserId:         sc.UserId,
-		ApiKey:         sc.Password,
-		AuthUrl:        sc.AuthUrl,
-		Retries:        sc.Retries,
-		Region:         sc.RegionName,
-		AuthVersion:    sc.AuthVersion,
-		Tenant:         sc.ProjectName,
-		TenantId:       sc.ProjectID,
-		TenantDomain:   sc.ProjectDomainName,
-		TenantDomainId: sc.ProjectDomainID,
-		ConnectTimeout: time.Duration(sc.ConnectTimeout),
-		Timeout:        time.Duration(sc.Timeout),
+		Domain:                      domain,
+		DomainId:                    domainID,
+		UserName:                    sc.Username,
+		UserId:                      sc.UserId,
+		ApiKey:                      sc.Password,
+		ApplicationCredentialId:     sc.ApplicationCredentialID,
+		ApplicationCredentialName:   sc.ApplicationCredentialName,
+		ApplicationCredentialSecret: sc.ApplicationCredentialSecret,
+		TrustId:                     sc.TrustID,
+		AuthUrl:                     sc.AuthUrl,
+		Retries:                     sc.Retries,
+		Region:                      sc.RegionName,
+		AuthVersion:                 sc.AuthVersion,
+		Tenant:                      sc.ProjectName,
+		TenantId:                    sc.ProjectID,
+		TenantDomain:                sc.ProjectDomainName,
+		TenantDomainId:              sc.ProjectDomainID,
+		ConnectTimeout:              time.Duration(sc.ConnectTimeout),
+		Timeout:                     time.Duration(sc.Timeout),
 	}
 	return &connection
 }
 
+// expiryTrackingAuth records the expiry of the tokens it authenticates, which the client only keeps internally.
+type expiryTrackingAuth struct {
+	swift.Authenticator
+	expiry *atomic.Int64
+}
+
+// Expires implements swift.Expireser, which the client calls after each authentication.
+func (a *expiryTrackingAuth) Expires() time.Time {
+	e, ok := a.Authenticator.(swift.Expireser)
+	if !ok {
+		return time.Time{}
+	}
+	t := e.Expires()
+	if t.IsZero() {
+		a.expiry.Store(0)
+	} else {
+		a.expiry.Store(t.UnixNano())
+	}
+	return t
+}
+
 type Container struct {
 	logger                 log.Logger
 	name                   string
@@ -138,6 +201,10 @@ type Container struct {
 	chunkSize              int64
 	useDynamicLargeObjects bool
 	segmentsContainer      string
+
+	tokenRefreshMargin time.Duration
+	tokenExpiry        *atomic.Int64
+	refreshMtx         sync.Mutex
 }
 
 func NewContainer(logger log.Logger, conf []byte) (*Container, error) {
@@ -165,10 +232,19 @@ func ensureContainer(connection *swift.Connection, name string, createIfNotExist
 }
 
 func NewContainerFromConfig(logger log.Logger, sc *Config, createContainer bool) (*Container, error) {
+	if err := sc.validate(); err != nil {
+		return nil, err
+	}
 	connection := connectionFromConfig(sc)
 	if err := connection.Authenticate(); err != nil {
 		return nil, errors.Wrap(err, "authentication")
 	}
+	// The authenticator is created on the first authentication and used for the next ones.
+	tokenExpiry := atomic.NewInt64(0)
+	if !connection.Expires.IsZero() {
+		tokenExpiry.Store(connection.Expires.UnixNano())
+	}
+	connection.Auth = &expiryTrackingAuth{Authenticator: connection.Auth, expiry: tokenExpiry}
 
 	if err := ensureContainer(connection, sc.ContainerName, createContainer); err != nil {
 		return nil, err
@@ -186,9 +262,34 @@ func NewContainerFromConfig(logger log.Logger, sc *Config, createContainer bool)
 		chunkSize:              sc.ChunkSize,
 		useDynamicLargeObjects: sc.UseDynamicLargeObjects,
 		segmentsContainer:      sc.SegmentContainerName,
+		tokenRefreshMargin:     time.Duration(sc.TokenRefreshMargin),
+		tokenExpiry:            tokenExpiry,
 	}, nil
 }
 
+// refreshToken authenticates again if the token expires within the refresh margin, so requests don't fail because
+// the token expires while they are running. Tokens without expiry are never refreshed.
+func (c *Container) refreshToken() error {
+	if c.tokenRefreshMargin <= 0 || !c.tokenExpiresSoon() {
+		return nil
+	}
+
+	c.refreshMtx.Lock()
+	defer c.refreshMtx.Unlock()
+
+	// Another request might have refreshed the token in the meantime.
+	if !c.tokenExpiresSoon() {
+		return nil
+	}
+	level.Debug(c.logger).Log("msg", "refreshing token before expiry", "expiry", time.Unix(0, c.tokenExpiry.Load()))
+	return errors.Wrap(c.connection.Authenticate(), "refresh token")
+}
+
+func (c *Container) tokenExpiresSoon() bool {
+	expiry := c.tokenExpiry.Load()
+	return expiry != 0 && time.Until(time.Unix(0, expiry)) < c.tokenRefreshMargin
+}
+
 // Name returns the container name for swift.
 func (c *Container) Name() string {
 	return c.name
@@ -208,6 +309,9 @@ func (c *Container) Iter(_ context.Context, dir string, f func(string) error, op
 	if objstore.ApplyIterOptions(options...).Recursive {
 		listOptions.Delimiter = rune(0)
 	}
+	if err := c.refreshToken(); err != nil {
+		return err
+	}
 
 	return c.connection.ObjectsWalk(c.name, listOptions, func(opts *swift.ObjectsOpts) (interface{}, error) {
 		objects, err := c.connection.ObjectNames(c.name, opts)
@@ -230,6 +334,9 @@ func (c *Container) get(name string, headers swift.Headers, checkHash bool) (io.
 	if name == "" {
 		return nil, errors.New("object name cannot be empty")
 	}
+	if err := c.refreshToken(); err != nil {
+		return nil, err
+	}
 	file, _, err := c.connection.ObjectOpen(c.name, name, checkHash, headers)
 	if err != nil {
 		return nil, errors.Wrap(err, "open object")
@@ -256,6 +363,9 @@ func (c *Container) Attributes(_ context.Context, name string) (objstore.ObjectA
 	if name == "" {
 		return objstore.ObjectAttributes{}, errors.New("object name cannot be empty")
 	}
+	if err := c.refreshToken(); err != nil {
+		return objstore.ObjectAttributes{}, err
+	}
 	info, _, err := c.connection.Object(c.name, name)
 	if err != nil {
 		return objstore.ObjectAttributes{}, errors.Wrap(err, "get object attributes")
@@ -268,6 +378,9 @@ func (c *Container) Attributes(_ context.Context, name string) (objstore.ObjectA
 
 // Exists checks if the given object exists.
 func (c *Container) Exists(_ context.Context, name string) (bool, error) {
+	if err := c.refreshToken(); err != nil {
+		return false, err
+	}
 	found := true
 	_, _, err := c.connection.Object(c.name, name)
 	if c.IsObjNotFoundErr(err) {
@@ -282,6 +395,14 @@ func (c *Container) IsObjNotFoundErr(err error) bool {
 	return errors.Is(err, swift.ObjectNotFound)
 }
 
+// StatusCode returns the HTTP status code of the error of a failed operation, if there is one.
+func StatusCode(err error) (int, bool) {
+	if serr, ok := errors.Cause(err).(*swift.Error); ok && serr.StatusCode != 0 {
+		return serr.StatusCode, true
+	}
+	return 0, false
+}
+
 // Upload writes the contents of the reader as an object into the container.
 func (c *Container) Upload(_ context.Context, name string, r io.Reader) (err error) {
 	size, err := objstore.TryToGetSize(r)
@@ -290,6 +411,9 @@ func (c *Container) Upload(_ context.Context, name string, r io.Reader) (err err
 		// Anything higher or equal to chunk size so the SLO is used.
 		size = c.chunkSize
 	}
+	if err := c.refreshToken(); err != nil {
+		return err
+	}
 	var file io.WriteCloser
 	if size >= c.chunkSize {
 		opts := swift.LargeObjectOpts{
@@ -322,6 +446,9 @@ func (c *Container) Upload(_ context.Context, name string, r io.Reader) (err err
 
 // Delete removes the object with the given name.
 func (c *Container) Delete(_ context.Context, name string) error {
+	if err := c.refreshToken(); err != nil {
+		return err
+	}
 	return errors.Wrap(c.connection.LargeObjectDelete(c.name, name), "delete object")
 }
 
@@ -5,6 +5,9 @@ package swift
 
 import (
 	"testing"
+	"time"
+
+	"go.uber.org/atomic"
 
 	"github.com/thanos-io/thanos/pkg/testutil"
 )
@@ -34,3 +37,92 @@ tenant_name: something`)
 	// Must result in unmarshal error as there's no `tenant_name` in SwiftConfig.
 	testutil.NotOk(t, err)
 }
+
+func TestConfig_Validate(t *testing.T) {
+	for _, tcase := range []struct {
+		name  string
+		input string
+		ok    bool
+	}{
+		{
+			name: "application credential",
+			input: `auth_url: http://identity.something.com/v3
+application_credential_id: id
+application_credential_secret: secret`,
+			ok: true,
+		},
+		{
+			name: "application credential without secret",
+			input: `auth_url: http://identity.something.com/v3
+application_credential_name: name
+username: thanos
+user_domain_name: userDomain`,
+		},
+		{
+			name: "trust scoped application credential",
+			input: `auth_url: http://identity.something.com/v3
+application_credential_id: id
+application_credential_secret: secret
+trust_id: trust`,
+		},
+		{
+			name: "trust scoped token",
+			input: `auth_url: http://identity.something.com/v3
+username: thanos
+password: secret
+user_domain_name: userDomain
+trust_id: trust`,
+			ok: true,
+		},
+		{
+			name: "different user and project domains",
+			input: `auth_url: http://identity.something.com/v3
+username: thanos
+user_domain_name: userDomain
+project_name: thanosProject
+project_domain_name: projectDomain`,
+			ok: true,
+		},
+		{
+			name: "conflicting user domains",
+			input: `auth_url: http://identity.something.com/v3
+username: thanos
+user_domain_name: userDomain
+domain_name: otherDomain`,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			cfg, err := parseConfig([]byte(tcase.input))
+			testutil.Ok(t, err)
+			if tcase.ok {
+				testutil.Ok(t, cfg.validate())
+			} else {
+				testutil.NotOk(t, cfg.validate())
+			}
+		})
+	}
+}
+
+func TestConnectionFromConfig_Domains(t *testing.T) {
+	cfg, err := parseConfig([]byte(`auth_url: http://identity.something.com/v3
+username: thanos
+user_domain_id: userDomainID
+project_name: thanosProject
+project_domain_name: projectDomain`))
+	testutil.Ok(t, err)
+
+	c := connectionFromConfig(cfg)
+	testutil.Equals(t, "userDomainID", c.DomainId)
+	testutil.Equals(t, "projectDomain", c.TenantDomain)
+}
+
+func TestContainer_RefreshToken(t *testing.T) {
+	c := &Container{tokenRefreshMargin: 5 * time.Minute, tokenExpiry: atomic.NewInt64(0)}
+	testutil.Assert(t, !c.tokenExpiresSoon(), "tokens without expiry must not be refreshed")
+
+	c.tokenExpiry.Store(time.Now().Add(time.Hour).UnixNano())
+	testutil.Assert(t, !c.tokenExpiresSoon())
+
+	c.tokenExpiry.Store(time.Now().Add(time.Minute).UnixNano())
+	testutil.Assert(t, c.tokenExpiresSoon())
+}
@@ -0,0 +1,164 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package objstore
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ValidateTenantID returns an error if the tenant ID can't be used as an object prefix.
+func ValidateTenantID(tenantID string) error {
+	if tenantID == "" {
+		return errors.New("tenant ID must not be empty")
+	}
+	if tenantID == "." || tenantID == ".." || strings.ContainsAny(tenantID, `/\`) {
+		return errors.Errorf("tenant ID %q must not be a relative path element or contain path separators", tenantID)
+	}
+	return nil
+}
+
+// TenantBucket restricts all operations to the objects under the prefix of a tenant ID, so multiple tenants can
+// share a bucket. Object names are relative to the prefix, and names which would resolve to objects outside of it
+// are rejected.
+type TenantBucket struct {
+	bkt    Bucket
+	tenant string
+	prefix string
+}
+
+// NewTenantBucket returns a bucket which stores the objects of the given tenant under its prefix of the given bucket.
+func NewTenantBucket(bkt Bucket, tenantID string) (*TenantBucket, error) {
+	if err := ValidateTenantID(tenantID); err != nil {
+		return nil, err
+	}
+	return &TenantBucket{bkt: bkt, tenant: tenantID, prefix: tenantID + DirDelim}, nil
+}
+
+// WithExpectedErrs returns the tenant bucket with the given filter of expected errors, if the underlying bucket is
+// instrumented.
+func (b *TenantBucket) WithExpectedErrs(fn IsOpFailureExpectedFunc) Bucket {
+	if ib, ok := b.bkt.(InstrumentedBucket); ok {
+		return &TenantBucket{bkt: ib.WithExpectedErrs(fn), tenant: b.tenant, prefix: b.prefix}
+	}
+	return b
+}
+
+func (b *TenantBucket) ReaderWithExpectedErrs(fn IsOpFailureExpectedFunc) BucketReader {
+	return b.WithExpectedErrs(fn)
+}
+
+// Tenant returns the tenant ID of the bucket.
+func (b *TenantBucket) Tenant() string {
+	return b.tenant
+}
+
+// fullName returns the name of the object in the underlying bucket, or an error if it's outside of the tenant prefix.
+func (b *TenantBucket) fullName(name string) (string, error) {
+	if strings.HasPrefix(name, DirDelim) {
+		return "", errors.Errorf("object name %q of tenant %s must be relative", name, b.tenant)
+	}
+	for _, elem := range strings.Split(name, DirDelim) {
+		if elem == ".." {
+			return "", errors.Errorf("object name %q of tenant %s must not refer to parent directories", name, b.tenant)
+		}
+	}
+	return b.prefix + name, nil
+}
+
+// objectName returns the name of the object in the underlying bucket, like fullName, but rejects empty names, which
+// would refer to the tenant prefix itself.
+func (b *TenantBucket) objectName(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("object name must not be empty")
+	}
+	return b.fullName(name)
+}
+
+// Iter calls f for each entry in the given directory of the tenant. The names are relative to the tenant prefix.
+func (b *TenantBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...IterOption) error {
+	fullDir, err := b.fullName(dir)
+	if err != nil {
+		return err
+	}
+	return b.bkt.Iter(ctx, fullDir, func(name string) error {
+		if !strings.HasPrefix(name, b.prefix) {
+			return errors.Errorf("listed object %q is outside of the prefix of tenant %s", name, b.tenant)
+		}
+		return f(strings.TrimPrefix(name, b.prefix))
+	}, options...)
+}
+
+// Get returns a reader for the given object name of the tenant.
+func (b *TenantBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	fullName, err := b.objectName(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.bkt.Get(ctx, fullName)
+}
+
+// GetRange returns a new range reader for the given object name of the tenant and range.
+func (b *TenantBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	fullName, err := b.objectName(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.bkt.GetRange(ctx, fullName, off, length)
+}
+
+// Exists checks if the given object of the tenant exists.
+func (b *TenantBucket) Exists(ctx context.Context, name string) (bool, error) {
+	fullName, err := b.objectName(name)
+	if err != nil {
+		return false, err
+	}
+	return b.bkt.Exists(ctx, fullName)
+}
+
+// Attributes returns information about the specified object of the tenant.
+func (b *TenantBucket) Attributes(ctx context.Context, name string) (ObjectAttributes, error) {
+	fullName, err := b.objectName(name)
+	if err != nil {
+		return ObjectAttributes{}, err
+	}
+	return b.bkt.Attributes(ctx, fullName)
+}
+
+// Upload uploads the object under the tenant prefix.
+func (b *TenantBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	fullName, err := b.objectName(name)
+	if err != nil {
+		return err
+	}
+	return b.bkt.Upload(ctx, fullName, r)
+}
+
+// Delete removes the object of the tenant with the given name.
+func (b *TenantBucket) Delete(ctx context.Context, name string) error {
+	fullName, err := b.objectName(name)
+	if err != nil {
+		return err
+	}
+	return b.bkt.Delete(ctx, fullName)
+}
+
+// IsObjNotFoundErr returns true if error means that object is not found.
+func (b *TenantBucket) IsObjNotFoundErr(err error) bool {
+	return b.bkt.IsObjNotFoundErr(err)
+}
+
+// Name returns the bucket name and the tenant prefix.
+func (b *TenantBucket) Name() string {
+	return path.Join(b.bkt.Name(), b.tenant)
+}
+
+// Close closes the underlying bucket, which might be shared with other tenants.
+func (b *TenantBucket) Close() error {
+	return b.bkt.Close()
+}
@@ -0,0 +1,71 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package objstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestTenantBucket_AcceptanceTest(t *testing.T) {
+	bkt, err := NewTenantBucket(NewInMemBucket(), "tenant-a")
+	testutil.Ok(t, err)
+	AcceptanceTest(t, bkt)
+}
+
+func TestTenantBucket(t *testing.T) {
+	ctx := context.Background()
+	inmem := NewInMemBucket()
+	a, err := NewTenantBucket(inmem, "a")
+	testutil.Ok(t, err)
+	ab, err := NewTenantBucket(inmem, "ab")
+	testutil.Ok(t, err)
+
+	testutil.Ok(t, a.Upload(ctx, "dir/obj", strings.NewReader("a")))
+	testutil.Ok(t, ab.Upload(ctx, "dir/obj", strings.NewReader("ab")))
+	testutil.Ok(t, inmem.Upload(ctx, "obj", strings.NewReader("root")))
+
+	var names []string
+	for name := range inmem.Objects() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	testutil.Equals(t, []string{"a/dir/obj", "ab/dir/obj", "obj"}, names)
+
+	t.Run("names are relative to the tenant prefix", func(t *testing.T) {
+		var listed []string
+		testutil.Ok(t, a.Iter(ctx, "", func(name string) error {
+			listed = append(listed, name)
+			return nil
+		}, WithRecursiveIter))
+		testutil.Equals(t, []string{"dir/obj"}, listed)
+
+		ok, err := a.Exists(ctx, "obj")
+		testutil.Ok(t, err)
+		testutil.Assert(t, !ok, "object outside of the tenant prefix must not exist")
+	})
+	t.Run("names outside of the tenant prefix are rejected", func(t *testing.T) {
+		for _, name := range []string{"../ab/dir/obj", "dir/../../obj", "/obj"} {
+			_, err := a.Get(ctx, name)
+			testutil.NotOk(t, err)
+			testutil.Assert(t, !a.IsObjNotFoundErr(err), "unexpected not found error for %s", name)
+			testutil.NotOk(t, a.Upload(ctx, name, strings.NewReader("x")))
+			testutil.NotOk(t, a.Delete(ctx, name))
+		}
+		testutil.Equals(t, 3, len(inmem.Objects()))
+	})
+}
+
+func TestValidateTenantID(t *testing.T) {
+	for _, id := range []string{"default-tenant", "team_a.prod"} {
+		testutil.Ok(t, ValidateTenantID(id))
+	}
+	for _, id := range []string{"", ".", "..", "a/b", `a\b`} {
+		testutil.NotOk(t, ValidateTenantID(id))
+	}
+}
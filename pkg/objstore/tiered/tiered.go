@@ -0,0 +1,208 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package tiered implements a bucket which keeps recent blocks in a hot bucket and old blocks in a cold one.
+package tiered
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// Bucket combines a low-latency hot bucket, to which all objects are uploaded, with a cold archive bucket, to
+// which blocks are moved once their data is older than the cut-over age. Reads are served from the hot bucket,
+// falling back to the cold one for objects which are not there, so blocks can be read from either tier,
+// including while they are being moved.
+type Bucket struct {
+	logger     log.Logger
+	hot, cold  objstore.Bucket
+	cutOverAge time.Duration
+}
+
+// NewBucket returns a tiered bucket of the given hot and cold buckets.
+func NewBucket(logger log.Logger, hot, cold objstore.Bucket, cutOverAge time.Duration) (*Bucket, error) {
+	if cutOverAge <= 0 {
+		return nil, errors.New("cut-over age must be positive")
+	}
+	return &Bucket{logger: logger, hot: hot, cold: cold, cutOverAge: cutOverAge}, nil
+}
+
+// Name returns the names of both tiers.
+func (b *Bucket) Name() string {
+	return fmt.Sprintf("%s,%s", b.hot.Name(), b.cold.Name())
+}
+
+// CutOverAge returns the age of block data after which blocks belong to the cold tier.
+func (b *Bucket) CutOverAge() time.Duration {
+	return b.cutOverAge
+}
+
+// Iter calls f for each entry in the given directory of either tier, in lexicographical order.
+func (b *Bucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	names := map[string]struct{}{}
+	for _, bkt := range []objstore.Bucket{b.hot, b.cold} {
+		if err := bkt.Iter(ctx, dir, func(name string) error {
+			names[name] = struct{}{}
+			return nil
+		}, options...); err != nil {
+			return errors.Wrapf(err, "iter %s", bkt.Name())
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		if err := f(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns a reader for the given object name.
+func (b *Bucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	r, err := b.hot.Get(ctx, name)
+	if err == nil || !b.hot.IsObjNotFoundErr(err) {
+		return r, err
+	}
+	return b.cold.Get(ctx, name)
+}
+
+// GetRange returns a new range reader for the given object name and range.
+func (b *Bucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	r, err := b.hot.GetRange(ctx, name, off, length)
+	if err == nil || !b.hot.IsObjNotFoundErr(err) {
+		return r, err
+	}
+	return b.cold.GetRange(ctx, name, off, length)
+}
+
+// Exists checks if the given object exists in either tier.
+func (b *Bucket) Exists(ctx context.Context, name string) (bool, error) {
+	ok, err := b.hot.Exists(ctx, name)
+	if err != nil || ok {
+		return ok, err
+	}
+	return b.cold.Exists(ctx, name)
+}
+
+// Attributes returns information about the specified object.
+func (b *Bucket) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	attrs, err := b.hot.Attributes(ctx, name)
+	if err == nil || !b.hot.IsObjNotFoundErr(err) {
+		return attrs, err
+	}
+	return b.cold.Attributes(ctx, name)
+}
+
+// Upload uploads the object to the hot tier.
+func (b *Bucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	return b.hot.Upload(ctx, name, r)
+}
+
+// Delete removes the object from both tiers. It returns a not found error only if the object was in neither.
+func (b *Bucket) Delete(ctx context.Context, name string) error {
+	hotErr := b.hot.Delete(ctx, name)
+	if hotErr != nil && !b.hot.IsObjNotFoundErr(hotErr) {
+		return hotErr
+	}
+	coldErr := b.cold.Delete(ctx, name)
+	if coldErr == nil {
+		return nil
+	}
+	if !b.cold.IsObjNotFoundErr(coldErr) {
+		return coldErr
+	}
+	// The object wasn't in the cold tier, so it's only missing if it wasn't in the hot one either.
+	return hotErr
+}
+
+// IsObjNotFoundErr returns true if error means that object is not found in either tier.
+func (b *Bucket) IsObjNotFoundErr(err error) bool {
+	return b.hot.IsObjNotFoundErr(err) || b.cold.IsObjNotFoundErr(err)
+}
+
+// Close closes both tiers.
+func (b *Bucket) Close() error {
+	hotErr := b.hot.Close()
+	if err := b.cold.Close(); err != nil {
+		return err
+	}
+	return hotErr
+}
+
+// InHotTier returns true if the meta file of the block is in the hot tier.
+func (b *Bucket) InHotTier(ctx context.Context, id ulid.ULID) (bool, error) {
+	return b.hot.Exists(ctx, path.Join(id.String(), metadata.MetaFilename))
+}
+
+// MoveToColdTier copies the block to the cold tier and removes it from the hot one. The meta file is copied and
+// removed last, so the block is complete in the cold tier once it has the meta file, and moves which were interrupted
+// are resumed as long as the meta file is in the hot tier. Reads fall back to the cold tier for every object, so
+// blocks can be read while they are being moved.
+func (b *Bucket) MoveToColdTier(ctx context.Context, id ulid.ULID) error {
+	metaFile := path.Join(id.String(), metadata.MetaFilename)
+
+	var objects []string
+	if err := b.hot.Iter(ctx, id.String(), func(name string) error {
+		if name != metaFile {
+			objects = append(objects, name)
+		}
+		return nil
+	}, objstore.WithRecursiveIter); err != nil {
+		return errors.Wrapf(err, "list objects of block %s", id)
+	}
+
+	for _, name := range append(objects, metaFile) {
+		if err := b.copyToColdTier(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range append(objects, metaFile) {
+		if err := b.hot.Delete(ctx, name); err != nil && !b.hot.IsObjNotFoundErr(err) {
+			return errors.Wrapf(err, "delete %s from hot tier", name)
+		}
+	}
+	level.Debug(b.logger).Log("msg", "moved block to cold tier", "id", id)
+	return nil
+}
+
+func (b *Bucket) copyToColdTier(ctx context.Context, name string) error {
+	// Objects are uploaded at once, so existing ones were copied completely by a previous move.
+	ok, err := b.cold.Exists(ctx, name)
+	if err != nil {
+		return errors.Wrapf(err, "check if %s exists in cold tier", name)
+	}
+	if ok {
+		return nil
+	}
+
+	r, err := b.hot.Get(ctx, name)
+	if err != nil {
+		return errors.Wrapf(err, "get %s from hot tier", name)
+	}
+	defer runutil.CloseWithLogOnErr(b.logger, r, "hot tier reader")
+
+	if err := b.cold.Upload(ctx, name, r); err != nil {
+		return errors.Wrapf(err, "upload %s to cold tier", name)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tiered
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestBucket_AcceptanceTest(t *testing.T) {
+	bkt, err := NewBucket(log.NewNopLogger(), objstore.NewInMemBucket(), objstore.NewInMemBucket(), time.Hour)
+	testutil.Ok(t, err)
+	objstore.AcceptanceTest(t, bkt)
+}
+
+func TestBucket_ReadsBothTiers(t *testing.T) {
+	ctx := context.Background()
+	hot, cold := objstore.NewInMemBucket(), objstore.NewInMemBucket()
+	bkt, err := NewBucket(log.NewNopLogger(), hot, cold, time.Hour)
+	testutil.Ok(t, err)
+
+	testutil.Ok(t, hot.Upload(ctx, "a/hot", strings.NewReader("hot")))
+	testutil.Ok(t, hot.Upload(ctx, "b/both", strings.NewReader("hot")))
+	testutil.Ok(t, cold.Upload(ctx, "b/both", strings.NewReader("cold")))
+	testutil.Ok(t, cold.Upload(ctx, "c/cold", strings.NewReader("cold")))
+
+	var names []string
+	testutil.Ok(t, bkt.Iter(ctx, "", func(name string) error {
+		names = append(names, name)
+		return nil
+	}))
+	testutil.Equals(t, []string{"a/", "b/", "c/"}, names)
+
+	for name, exp := range map[string]string{"a/hot": "hot", "b/both": "hot", "c/cold": "cold"} {
+		rc, err := bkt.GetRange(ctx, name, 0, -1)
+		testutil.Ok(t, err)
+		content, err := ioutil.ReadAll(rc)
+		testutil.Ok(t, err)
+		testutil.Ok(t, rc.Close())
+		testutil.Equals(t, exp, string(content))
+	}
+
+	// Uploads go to the hot tier, deletions remove objects from both.
+	testutil.Ok(t, bkt.Upload(ctx, "d/new", strings.NewReader("new")))
+	testutil.Equals(t, 3, len(hot.Objects()))
+	testutil.Ok(t, bkt.Delete(ctx, "b/both"))
+	testutil.Equals(t, 2, len(hot.Objects()))
+	testutil.Equals(t, 1, len(cold.Objects()))
+	testutil.Ok(t, bkt.Delete(ctx, "c/cold"))
+	testutil.Assert(t, bkt.IsObjNotFoundErr(bkt.Delete(ctx, "c/cold")))
+}
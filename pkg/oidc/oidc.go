@@ -0,0 +1,392 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package oidc implements the authentication of HTTP requests with the JWT bearer tokens issued by an OpenID Connect
+// provider.
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+const (
+	// minJWKSRefreshInterval is the minimum time between refreshes of the JWKS triggered by tokens with unknown keys.
+	minJWKSRefreshInterval = 10 * time.Second
+	fetchTimeout           = 10 * time.Second
+)
+
+// Config is the configuration of the OIDC authentication.
+type Config struct {
+	// Issuer is the URL of the OIDC provider. The iss claim of tokens has to be equal to it.
+	Issuer string `yaml:"issuer"`
+	// Audiences are the accepted audiences. If set, the aud claim of tokens has to contain one of them.
+	Audiences []string `yaml:"audiences"`
+	// JWKSURL is the URL of the JSON Web Key Set with the keys of the issuer. If not set, it is discovered through
+	// the OIDC discovery endpoint of the issuer.
+	JWKSURL string `yaml:"jwks_url"`
+	// JWKSRefreshInterval is the interval of refreshing the JSON Web Key Set.
+	JWKSRefreshInterval model.Duration `yaml:"jwks_refresh_interval"`
+	// TenantClaim is the claim of tokens with the tenant of the request. If set, tokens without it are rejected and
+	// the tenant header of authenticated requests is overwritten with it.
+	TenantClaim string `yaml:"tenant_claim"`
+}
+
+// ParseConfig parses the YAML content of the OIDC configuration.
+func ParseConfig(confContentYaml []byte) (Config, error) {
+	conf := Config{JWKSRefreshInterval: model.Duration(time.Hour)}
+	if err := yaml.UnmarshalStrict(confContentYaml, &conf); err != nil {
+		return Config{}, errors.Wrap(err, "parsing OIDC config YAML")
+	}
+	if conf.Issuer == "" {
+		return Config{}, errors.New("no issuer specified in OIDC config")
+	}
+	if conf.JWKSRefreshInterval <= 0 {
+		return Config{}, errors.New("jwks_refresh_interval of OIDC config has to be positive")
+	}
+	return conf, nil
+}
+
+// Authenticator authenticates HTTP requests with the bearer tokens of an OIDC provider. The JSON Web Key Set of the
+// provider is fetched lazily, and refreshed periodically and on tokens signed with unknown keys.
+type Authenticator struct {
+	logger log.Logger
+	conf   Config
+	client *http.Client
+	parser *jwt.Parser
+
+	mtx         sync.Mutex
+	keys        map[string]interface{}
+	lastRefresh time.Time
+	// refreshing is closed once the refresh of the JWKS in flight is done, nil if there is none.
+	refreshing chan struct{}
+
+	requests         *prometheus.CounterVec
+	refreshes        prometheus.Counter
+	refreshFailures  prometheus.Counter
+	lastRefreshGauge prometheus.Gauge
+}
+
+// NewAuthenticator returns an Authenticator with the given YAML configuration.
+func NewAuthenticator(logger log.Logger, reg prometheus.Registerer, confContentYaml []byte) (*Authenticator, error) {
+	conf, err := ParseConfig(confContentYaml)
+	if err != nil {
+		return nil, err
+	}
+	level.Info(logger).Log("msg", "enabling OIDC authentication of HTTP requests", "issuer", conf.Issuer)
+
+	a := &Authenticator{
+		logger: log.With(logger, "component", "oidc"),
+		conf:   conf,
+		client: &http.Client{Timeout: fetchTimeout},
+		parser: &jwt.Parser{ValidMethods: []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}},
+		keys:   map[string]interface{}{},
+		requests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_http_oidc_requests_total",
+			Help: "Total number of HTTP requests authenticated with OIDC by result.",
+		}, []string{"result"}),
+		refreshes: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_http_oidc_jwks_refreshes_total",
+			Help: "Total number of refreshes of the JSON Web Key Set of the OIDC provider.",
+		}),
+		refreshFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_http_oidc_jwks_refresh_failures_total",
+			Help: "Total number of failed refreshes of the JSON Web Key Set of the OIDC provider.",
+		}),
+		lastRefreshGauge: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_http_oidc_jwks_last_refresh_success_timestamp_seconds",
+			Help: "Timestamp of the last successful refresh of the JSON Web Key Set of the OIDC provider.",
+		}),
+	}
+	for _, result := range []string{"authenticated", "unauthenticated", "forbidden"} {
+		a.requests.WithLabelValues(result)
+	}
+	return a, nil
+}
+
+// Middleware returns the middleware which only passes on requests with a valid bearer token. If the tenant claim is
+// configured, the tenant header of the requests is set to the tenant of the token.
+func (a *Authenticator) Middleware(tenantHeader string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := a.Authenticate(r)
+			if err != nil {
+				a.requests.WithLabelValues("unauthenticated").Inc()
+				level.Debug(a.logger).Log("msg", "rejecting unauthenticated request", "path", r.URL.Path, "err", err)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "unauthorized: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if a.conf.TenantClaim != "" {
+				tenant, ok := claims[a.conf.TenantClaim].(string)
+				if !ok || tenant == "" {
+					a.requests.WithLabelValues("forbidden").Inc()
+					http.Error(w, fmt.Sprintf("forbidden: token has no %s claim", a.conf.TenantClaim), http.StatusForbidden)
+					return
+				}
+				r.Header.Set(tenantHeader, tenant)
+			}
+			a.requests.WithLabelValues("authenticated").Inc()
+			next.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+type ctxKey int
+
+const claimsKey = ctxKey(0)
+
+// ContextWithClaims returns the context with the claims of the token of the request.
+func ContextWithClaims(ctx context.Context, claims jwt.MapClaims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// ClaimsFromContext returns the claims of the token of the request, if authenticated.
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	claims, ok := ctx.Value(claimsKey).(jwt.MapClaims)
+	return claims, ok
+}
+
+// Authenticate returns the claims of the valid bearer token of the request.
+func (a *Authenticator) Authenticate(r *http.Request) (jwt.MapClaims, error) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, errors.New("missing bearer token")
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(strings.TrimPrefix(auth, "Bearer "), claims, a.key); err != nil {
+		return nil, errors.Wrap(err, "invalid token")
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, errors.New("token has no expiration time")
+	}
+	if !claims.VerifyIssuer(a.conf.Issuer, true) {
+		return nil, errors.Errorf("token is not issued by %s", a.conf.Issuer)
+	}
+	if len(a.conf.Audiences) > 0 {
+		ok := false
+		for _, aud := range a.conf.Audiences {
+			if claims.VerifyAudience(aud, true) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return nil, errors.New("token has no accepted audience")
+		}
+	}
+	return claims, nil
+}
+
+// key returns the key of the JSON Web Key Set the token is signed with. The JWKS is fetched without holding the
+// mutex and at most one refresh is in flight, so slow providers don't serialize the requests with known keys.
+func (a *Authenticator) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	a.mtx.Lock()
+	key, ok := a.keys[kid]
+	switch {
+	case ok && time.Since(a.lastRefresh) > time.Duration(a.conf.JWKSRefreshInterval):
+		// The key is known, so the periodic refresh doesn't have to be waited for.
+		if done := a.startRefresh(); done != nil {
+			go a.refresh(done)
+		}
+		a.mtx.Unlock()
+		return key, nil
+	case ok:
+		a.mtx.Unlock()
+		return key, nil
+	case a.refreshing == nil && time.Since(a.lastRefresh) <= minJWKSRefreshInterval:
+		a.mtx.Unlock()
+		return nil, errors.Errorf("unknown key %q", kid)
+	}
+	done := a.refreshing
+	if done == nil {
+		done = a.startRefresh()
+		a.mtx.Unlock()
+		a.refresh(done)
+	} else {
+		a.mtx.Unlock()
+		<-done
+	}
+
+	a.mtx.Lock()
+	key, ok = a.keys[kid]
+	a.mtx.Unlock()
+	if !ok {
+		return nil, errors.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// startRefresh marks a refresh of the JSON Web Key Set as in flight and returns the channel closed once it is done,
+// or nil if one is in flight already. It has to be called with the mutex held.
+func (a *Authenticator) startRefresh() chan struct{} {
+	if a.refreshing != nil {
+		return nil
+	}
+	a.lastRefresh = time.Now()
+	a.refreshing = make(chan struct{})
+	return a.refreshing
+}
+
+// refresh fetches the JSON Web Key Set and closes done afterwards. On failure the previous keys are kept. It has to
+// be called without the mutex held, which is only taken to swap the keys.
+func (a *Authenticator) refresh(done chan struct{}) {
+	a.refreshes.Inc()
+
+	keys, err := a.fetchKeys()
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	defer close(done)
+	a.refreshing = nil
+
+	if err != nil {
+		a.refreshFailures.Inc()
+		level.Warn(a.logger).Log("msg", "refreshing JSON Web Key Set failed", "err", err)
+		return
+	}
+	a.keys = keys
+	a.lastRefreshGauge.SetToCurrentTime()
+}
+
+func (a *Authenticator) fetchKeys() (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	jwksURL := a.conf.JWKSURL
+	if jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := a.get(ctx, strings.TrimSuffix(a.conf.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, errors.Wrap(err, "discover JWKS URL")
+		}
+		if discovery.JWKSURI == "" {
+			return nil, errors.New("no jwks_uri in OIDC discovery document")
+		}
+		jwksURL = discovery.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := a.get(ctx, jwksURL, &jwks); err != nil {
+		return nil, errors.Wrap(err, "get JWKS")
+	}
+
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			level.Warn(a.logger).Log("msg", "skipping invalid key of JSON Web Key Set", "kid", k.Kid, "err", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (a *Authenticator) get(ctx context.Context, url string, v interface{}) (err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "get %s", url)
+	}
+	defer runutil.ExhaustCloseWithErrCapture(&err, resp.Body, "close response body")
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("get %s: unexpected status code %d", url, resp.StatusCode)
+	}
+	return errors.Wrapf(json.NewDecoder(resp.Body).Decode(v), "decode response of %s", url)
+}
+
+// jsonWebKey is a public key of a JSON Web Key Set, see RFC 7517.
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	// N and E are the modulus and the exponent of RSA keys.
+	N string `json:"n"`
+	E string `json:"e"`
+	// Crv, X and Y are the curve and the coordinates of EC keys.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode modulus")
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode x coordinate")
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode y coordinate")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, errors.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
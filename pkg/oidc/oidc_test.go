@@ -0,0 +1,244 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+type testProvider struct {
+	*httptest.Server
+
+	mtx  sync.Mutex
+	keys []jsonWebKey
+	// gate, if set, blocks the requests of the JWKS until closed. Blocked requests are sent to blocked.
+	gate    chan struct{}
+	blocked chan struct{}
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	p := &testProvider{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		testutil.Ok(t, json.NewEncoder(w).Encode(map[string]string{"issuer": p.URL, "jwks_uri": p.URL + "/keys"}))
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		p.mtx.Lock()
+		gate, blocked := p.gate, p.blocked
+		p.mtx.Unlock()
+		if gate != nil {
+			blocked <- struct{}{}
+			<-gate
+		}
+
+		p.mtx.Lock()
+		defer p.mtx.Unlock()
+		testutil.Ok(t, json.NewEncoder(w).Encode(map[string]interface{}{"keys": p.keys}))
+	})
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *testProvider) addKey(k jsonWebKey) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.keys = append(p.keys, k)
+}
+
+func encode(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	testutil.Ok(t, err)
+	return s
+}
+
+func TestAuthenticator(t *testing.T) {
+	p := newTestProvider(t)
+	defer p.Close()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	testutil.Ok(t, err)
+	p.addKey(jsonWebKey{Kid: "rsa", Kty: "RSA", Use: "sig", N: encode(rsaKey.N), E: encode(big.NewInt(int64(rsaKey.E)))})
+
+	reg := prometheus.NewRegistry()
+	a, err := NewAuthenticator(log.NewNopLogger(), reg, []byte(fmt.Sprintf(`
+issuer: %s
+audiences: [thanos]
+tenant_claim: tenant
+`, p.URL)))
+	testutil.Ok(t, err)
+
+	var tenant string
+	h := a.Middleware("THANOS-TENANT")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.Header.Get("THANOS-TENANT")
+		// The claims are passed on in the context.
+		if claims, ok := ClaimsFromContext(r.Context()); !ok || claims["tenant"] != tenant {
+			tenant = "no claims"
+		}
+	}))
+
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := jwt.MapClaims{"iss": p.URL, "aud": []string{"other", "thanos"}, "exp": exp, "tenant": "team-a"}
+	for _, tcase := range []struct {
+		name   string
+		auth   string
+		code   int
+		tenant string
+	}{
+		{name: "no token", auth: "", code: http.StatusUnauthorized},
+		{name: "valid token", auth: "Bearer " + sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, valid), code: http.StatusOK, tenant: "team-a"},
+		{
+			name: "wrong issuer",
+			auth: "Bearer " + sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{"iss": "https://other", "aud": "thanos", "exp": exp, "tenant": "team-a"}),
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong audience",
+			auth: "Bearer " + sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{"iss": p.URL, "aud": "other", "exp": exp, "tenant": "team-a"}),
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "expired token",
+			auth: "Bearer " + sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{"iss": p.URL, "aud": "thanos", "exp": time.Now().Add(-time.Minute).Unix(), "tenant": "team-a"}),
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "no expiration time",
+			auth: "Bearer " + sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{"iss": p.URL, "aud": "thanos", "tenant": "team-a"}),
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "no tenant",
+			auth: "Bearer " + sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{"iss": p.URL, "aud": "thanos", "exp": exp}),
+			code: http.StatusForbidden,
+		},
+		{name: "HMAC signed token", auth: "Bearer " + sign(t, jwt.SigningMethodHS256, "rsa", []byte("secret"), valid), code: http.StatusUnauthorized},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			tenant = ""
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/query", nil)
+			// The tenant header of the client is never passed on.
+			r.Header.Set("THANOS-TENANT", "spoofed")
+			if tcase.auth != "" {
+				r.Header.Set("Authorization", tcase.auth)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			testutil.Equals(t, tcase.code, w.Code)
+			testutil.Equals(t, tcase.tenant, tenant)
+		})
+	}
+
+	// Tokens signed with new keys are accepted after refreshing the JWKS.
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.Ok(t, err)
+	p.addKey(jsonWebKey{Kid: "ec", Kty: "EC", Crv: "P-256", X: encode(ecKey.X), Y: encode(ecKey.Y)})
+	a.lastRefresh = time.Now().Add(-time.Minute)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/query", nil)
+	r.Header.Set("Authorization", "Bearer "+sign(t, jwt.SigningMethodES256, "ec", ecKey, valid))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	testutil.Equals(t, http.StatusOK, w.Code)
+
+	testutil.Equals(t, 2.0, promtest.ToFloat64(a.refreshes))
+	testutil.Equals(t, 0.0, promtest.ToFloat64(a.refreshFailures))
+	testutil.Equals(t, 2.0, promtest.ToFloat64(a.requests.WithLabelValues("authenticated")))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(a.requests.WithLabelValues("forbidden")))
+}
+
+func TestAuthenticatorRefreshOutsideLock(t *testing.T) {
+	p := newTestProvider(t)
+	defer p.Close()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	testutil.Ok(t, err)
+	p.addKey(jsonWebKey{Kid: "rsa", Kty: "RSA", Use: "sig", N: encode(rsaKey.N), E: encode(big.NewInt(int64(rsaKey.E)))})
+
+	a, err := NewAuthenticator(log.NewNopLogger(), prometheus.NewRegistry(), []byte(fmt.Sprintf("issuer: %s", p.URL)))
+	testutil.Ok(t, err)
+
+	exp := time.Now().Add(time.Hour).Unix()
+	authenticate := func(token string) error {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/query", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		_, err := a.Authenticate(r)
+		return err
+	}
+	rsaToken := sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{"iss": p.URL, "exp": exp})
+	testutil.Ok(t, authenticate(rsaToken))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.Ok(t, err)
+	p.addKey(jsonWebKey{Kid: "ec", Kty: "EC", Crv: "P-256", X: encode(ecKey.X), Y: encode(ecKey.Y)})
+	ecToken := sign(t, jwt.SigningMethodES256, "ec", ecKey, jwt.MapClaims{"iss": p.URL, "exp": exp})
+
+	p.mtx.Lock()
+	p.gate, p.blocked = make(chan struct{}), make(chan struct{}, 10)
+	p.mtx.Unlock()
+	a.mtx.Lock()
+	a.lastRefresh = time.Now().Add(-time.Minute)
+	a.mtx.Unlock()
+
+	// Concurrent tokens with the unknown key wait for a single refresh.
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- authenticate(ecToken)
+		}()
+	}
+	<-p.blocked
+
+	// Tokens with known keys are authenticated while the JWKS is fetched.
+	testutil.Ok(t, authenticate(rsaToken))
+
+	close(p.gate)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		testutil.Ok(t, err)
+	}
+	testutil.Equals(t, 0, len(p.blocked))
+	testutil.Equals(t, 2.0, promtest.ToFloat64(a.refreshes))
+}
+
+func TestParseConfig(t *testing.T) {
+	conf, err := ParseConfig([]byte(`issuer: https://issuer`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, "https://issuer", conf.Issuer)
+	testutil.Equals(t, time.Hour, time.Duration(conf.JWKSRefreshInterval))
+
+	_, err = ParseConfig([]byte(`audiences: [thanos]`))
+	testutil.NotOk(t, err)
+
+	_, err = ParseConfig([]byte("issuer: https://issuer\nunknown: field"))
+	testutil.NotOk(t, err)
+}
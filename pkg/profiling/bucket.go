@@ -0,0 +1,66 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"path"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/objstore/client"
+)
+
+// BucketConfig is the configuration of uploading profiles to object storage.
+type BucketConfig struct {
+	// Prefix is the prefix of the uploaded profiles in the bucket.
+	Prefix string `yaml:"prefix"`
+	// Objstore is the configuration of the bucket, in the same format as the one of the --objstore.config flag.
+	Objstore client.BucketConfig `yaml:"objstore"`
+}
+
+// DefaultBucketConfig is the default configuration of uploading profiles to object storage.
+var DefaultBucketConfig = BucketConfig{
+	Prefix: "profiles",
+}
+
+// timeFormat is the format of the time of profiles in object names. It has no colons, so that objects can be stored
+// in the filesystem of all platforms.
+const timeFormat = "20060102T150405Z"
+
+type bucketUploader struct {
+	bkt    objstore.Bucket
+	prefix string
+}
+
+func newBucketUploader(logger log.Logger, conf []byte) (*bucketUploader, error) {
+	config := DefaultBucketConfig
+	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
+		return nil, errors.Wrap(err, "parsing profiling bucket config YAML")
+	}
+	bktConf, err := yaml.Marshal(config.Objstore)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal bucket configuration")
+	}
+	// The metrics of the bucket are not registered, as they would collide with the ones of the bucket of the component.
+	bkt, err := client.NewBucket(logger, bktConf, nil, "profiling")
+	if err != nil {
+		return nil, errors.Wrap(err, "create profiling bucket")
+	}
+	return &bucketUploader{bkt: bkt, prefix: config.Prefix}, nil
+}
+
+// upload uploads the profile to <prefix>/<component>/<instance>/<profile>/<start time>.pb.gz.
+func (u *bucketUploader) upload(ctx context.Context, p profile) error {
+	name := path.Join(u.prefix, p.labels["component"], p.labels["instance"], p.name, p.start.UTC().Format(timeFormat)+".pb.gz")
+	return u.bkt.Upload(ctx, name, bytes.NewReader(p.data))
+}
+
+func (u *bucketUploader) close() error {
+	return u.bkt.Close()
+}
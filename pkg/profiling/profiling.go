@@ -0,0 +1,220 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package profiling captures pprof profiles of the running process periodically and uploads them, so that memory and
+// CPU usage can be investigated after the fact, e.g. after an OOM kill.
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+type Provider string
+
+const (
+	PYROSCOPE Provider = "PYROSCOPE"
+	BUCKET    Provider = "BUCKET"
+)
+
+// Profiles which can be captured.
+const (
+	ProfileCPU       = "cpu"
+	ProfileHeap      = "heap"
+	ProfileGoroutine = "goroutine"
+)
+
+// Config is the configuration of continuous profiling.
+type Config struct {
+	Type   Provider    `yaml:"type"`
+	Config interface{} `yaml:"config"`
+	// Interval is the interval between the captures of the profiles.
+	Interval model.Duration `yaml:"interval"`
+	// CPUDuration is the duration of CPU profiles. It has to be shorter than the interval.
+	CPUDuration model.Duration `yaml:"cpu_duration"`
+	// Profiles are the profiles to capture.
+	Profiles []string `yaml:"profiles"`
+	// Labels are added to the uploaded profiles, in addition to the component and instance labels.
+	Labels map[string]string `yaml:"labels"`
+}
+
+// DefaultConfig is the default configuration of continuous profiling, without provider.
+var DefaultConfig = Config{
+	Interval:    model.Duration(time.Minute),
+	CPUDuration: model.Duration(10 * time.Second),
+	Profiles:    []string{ProfileCPU, ProfileHeap, ProfileGoroutine},
+}
+
+func parseConfig(conf []byte) (Config, error) {
+	config := DefaultConfig
+	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
+		return Config{}, errors.Wrap(err, "parsing profiling config YAML")
+	}
+
+	if config.Interval <= 0 {
+		return Config{}, errors.New("interval has to be positive")
+	}
+	if config.CPUDuration <= 0 || config.CPUDuration >= config.Interval {
+		return Config{}, errors.New("cpu_duration has to be positive and shorter than interval")
+	}
+	if len(config.Profiles) == 0 {
+		return Config{}, errors.New("no profiles configured")
+	}
+	for _, p := range config.Profiles {
+		if p != ProfileCPU && p != ProfileHeap && p != ProfileGoroutine {
+			return Config{}, errors.Errorf("unsupported profile %q, expected one of %s, %s or %s", p, ProfileCPU, ProfileHeap, ProfileGoroutine)
+		}
+	}
+	return config, nil
+}
+
+// profile is a captured profile in the gzip compressed protobuf format of pprof.
+type profile struct {
+	name   string
+	start  time.Time
+	end    time.Time
+	data   []byte
+	labels map[string]string
+}
+
+// uploader uploads captured profiles.
+type uploader interface {
+	upload(ctx context.Context, p profile) error
+	close() error
+}
+
+// Profiler captures and uploads profiles periodically.
+type Profiler struct {
+	logger      log.Logger
+	uploader    uploader
+	interval    time.Duration
+	cpuDuration time.Duration
+	profiles    []string
+	labels      map[string]string
+
+	uploads        *prometheus.CounterVec
+	uploadFailures *prometheus.CounterVec
+}
+
+// NewProfiler returns a Profiler for the given configuration. The profiles have the name of the component as the
+// component label, and the hostname as the instance label.
+func NewProfiler(logger log.Logger, reg prometheus.Registerer, confContentYaml []byte, component string) (*Profiler, error) {
+	level.Info(logger).Log("msg", "loading profiling configuration")
+	config, err := parseConfig(confContentYaml)
+	if err != nil {
+		return nil, err
+	}
+
+	var typeConfig []byte
+	if config.Config != nil {
+		typeConfig, err = yaml.Marshal(config.Config)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal content of profiling configuration")
+		}
+	}
+
+	var u uploader
+	switch strings.ToUpper(string(config.Type)) {
+	case string(PYROSCOPE):
+		u, err = newPyroscopeUploader(logger, typeConfig)
+	case string(BUCKET):
+		u, err = newBucketUploader(logger, typeConfig)
+	default:
+		return nil, errors.Errorf("profiling with type %s is not supported", config.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{"component": component}
+	if hostname, err := os.Hostname(); err == nil {
+		labels["instance"] = hostname
+	}
+	for k, v := range config.Labels {
+		labels[k] = v
+	}
+
+	return &Profiler{
+		logger:      log.With(logger, "component", "profiling"),
+		uploader:    u,
+		interval:    time.Duration(config.Interval),
+		cpuDuration: time.Duration(config.CPUDuration),
+		profiles:    config.Profiles,
+		labels:      labels,
+		uploads: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_profiling_uploads_total",
+			Help: "Total number of uploaded profiles.",
+		}, []string{"profile"}),
+		uploadFailures: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_profiling_upload_failures_total",
+			Help: "Total number of profiles which failed to be captured or uploaded.",
+		}, []string{"profile"}),
+	}, nil
+}
+
+// Run captures and uploads the profiles every interval until the context is canceled.
+func (p *Profiler) Run(ctx context.Context) error {
+	defer func() {
+		if err := p.uploader.close(); err != nil {
+			level.Warn(p.logger).Log("msg", "closing profile uploader failed", "err", err)
+		}
+	}()
+
+	return runutil.Repeat(p.interval, ctx.Done(), func() error {
+		for _, name := range p.profiles {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err := p.captureAndUpload(ctx, name); err != nil {
+				level.Warn(p.logger).Log("msg", "capturing or uploading profile failed", "profile", name, "err", err)
+				p.uploadFailures.WithLabelValues(name).Inc()
+				continue
+			}
+			p.uploads.WithLabelValues(name).Inc()
+		}
+		return nil
+	})
+}
+
+func (p *Profiler) captureAndUpload(ctx context.Context, name string) error {
+	prof := profile{name: name, start: time.Now(), labels: p.labels}
+
+	var buf bytes.Buffer
+	if name == ProfileCPU {
+		// This fails if a CPU profile is being captured already, e.g. by the pprof endpoint.
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return errors.Wrap(err, "start CPU profile")
+		}
+		select {
+		case <-time.After(p.cpuDuration):
+		case <-ctx.Done():
+		}
+		pprof.StopCPUProfile()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	} else if err := pprof.Lookup(name).WriteTo(&buf, 0); err != nil {
+		return errors.Wrapf(err, "write %s profile", name)
+	}
+	prof.end = time.Now()
+	prof.data = buf.Bytes()
+
+	// The upload should finish before the next capture.
+	uctx, cancel := context.WithTimeout(ctx, p.interval)
+	defer cancel()
+	return p.uploader.upload(uctx, prof)
+}
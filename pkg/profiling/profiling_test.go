@@ -0,0 +1,144 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package profiling
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/thanos-io/thanos/pkg/runutil"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestParseConfig(t *testing.T) {
+	c, err := parseConfig([]byte("type: PYROSCOPE\n"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, DefaultConfig.Profiles, c.Profiles)
+
+	for _, conf := range []string{
+		"interval: 0s\n",
+		"interval: 10s\ncpu_duration: 10s\n",
+		"profiles: []\n",
+		"profiles: [mutex]\n",
+		"unknown: true\n",
+	} {
+		_, err := parseConfig([]byte(conf))
+		testutil.NotOk(t, err, "config %q", conf)
+	}
+
+	_, err = NewProfiler(log.NewNopLogger(), nil, []byte("type: PARCA\n"), "store")
+	testutil.NotOk(t, err)
+	_, err = NewProfiler(log.NewNopLogger(), nil, []byte("type: PYROSCOPE\n"), "store")
+	testutil.NotOk(t, err)
+}
+
+func TestProfiler_Pyroscope(t *testing.T) {
+	names := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(b) == 0 || r.URL.Path != "/ingest" || r.URL.Query().Get("format") != "pprof" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		names <- r.URL.Query().Get("name")
+	}))
+	defer srv.Close()
+
+	conf := fmt.Sprintf(`type: PYROSCOPE
+config:
+  url: %s
+  auth_token: secret
+interval: 1m
+cpu_duration: 100ms
+labels:
+  cluster: eu-1
+  instance: store-0
+`, srv.URL)
+	reg := prometheus.NewRegistry()
+	p, err := NewProfiler(log.NewNopLogger(), reg, []byte(conf), "store")
+	testutil.Ok(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Run(ctx) }()
+
+	var got []string
+	for len(got) < 3 {
+		select {
+		case n := <-names:
+			got = append(got, n)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("profiles not uploaded, got %v", got)
+		}
+	}
+	cancel()
+	testutil.Ok(t, <-done)
+
+	sort.Strings(got)
+	testutil.Equals(t, []string{
+		"thanos.cpu{cluster=eu-1,component=store,instance=store-0}",
+		"thanos.goroutine{cluster=eu-1,component=store,instance=store-0}",
+		"thanos.heap{cluster=eu-1,component=store,instance=store-0}",
+	}, got)
+	testutil.Equals(t, 1.0, promtest.ToFloat64(p.uploads.WithLabelValues(ProfileCPU)))
+	testutil.Equals(t, 0.0, promtest.ToFloat64(p.uploadFailures.WithLabelValues(ProfileCPU)))
+}
+
+func TestProfiler_Bucket(t *testing.T) {
+	dir := t.TempDir()
+	conf := fmt.Sprintf(`type: BUCKET
+config:
+  prefix: profiles
+  objstore:
+    type: FILESYSTEM
+    config:
+      directory: %s
+interval: 1m
+profiles: [heap, goroutine]
+labels:
+  instance: store-0
+`, dir)
+	p, err := NewProfiler(log.NewNopLogger(), prometheus.NewRegistry(), []byte(conf), "store")
+	testutil.Ok(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Run(ctx) }()
+
+	rctx, rcancel := context.WithTimeout(ctx, 10*time.Second)
+	defer rcancel()
+	testutil.Ok(t, runutil.Retry(100*time.Millisecond, rctx.Done(), func() error {
+		if promtest.ToFloat64(p.uploads.WithLabelValues(ProfileGoroutine)) != 1 {
+			return errors.New("goroutine profile not uploaded")
+		}
+		return nil
+	}))
+	cancel()
+	testutil.Ok(t, <-done)
+
+	for _, name := range []string{ProfileHeap, ProfileGoroutine} {
+		files, err := ioutil.ReadDir(filepath.Join(dir, "profiles", "store", "store-0", name))
+		testutil.Ok(t, err)
+		testutil.Equals(t, 1, len(files))
+		testutil.Assert(t, strings.HasSuffix(files[0].Name(), ".pb.gz"), "unexpected file %s", files[0].Name())
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, "profiles", "store", "store-0", name, files[0].Name()))
+		testutil.Ok(t, err)
+		// Profiles are gzip compressed.
+		testutil.Assert(t, len(b) > 2 && b[0] == 0x1f && b[1] == 0x8b, "profile is not gzip compressed")
+	}
+}
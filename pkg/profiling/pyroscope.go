@@ -0,0 +1,128 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/httpconfig"
+	"github.com/thanos-io/thanos/pkg/runutil"
+	"github.com/thanos-io/thanos/pkg/tls"
+)
+
+// maxResponseSize is the maximum size of responses of Pyroscope read for errors.
+const maxResponseSize = 64 << 10
+
+// PyroscopeConfig is the configuration of pushing profiles to the ingest API of Pyroscope.
+type PyroscopeConfig struct {
+	// URL is the URL of the Pyroscope server, e.g. http://pyroscope:4040.
+	URL string `yaml:"url"`
+	// ApplicationName is the name of the application of the profiles.
+	ApplicationName string `yaml:"application_name"`
+	// AuthToken is sent as bearer token, if set.
+	AuthToken string               `yaml:"auth_token"`
+	TLSConfig httpconfig.TLSConfig `yaml:"tls_config"`
+}
+
+// DefaultPyroscopeConfig is the default configuration of pushing profiles to Pyroscope.
+var DefaultPyroscopeConfig = PyroscopeConfig{
+	ApplicationName: "thanos",
+}
+
+type pyroscopeUploader struct {
+	client    *http.Client
+	url       string
+	appName   string
+	authToken string
+}
+
+func newPyroscopeUploader(logger log.Logger, conf []byte) (*pyroscopeUploader, error) {
+	config := DefaultPyroscopeConfig
+	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
+		return nil, errors.Wrap(err, "parsing Pyroscope config YAML")
+	}
+	if config.URL == "" {
+		return nil, errors.New("no Pyroscope URL configured")
+	}
+	if config.ApplicationName == "" {
+		return nil, errors.New("no Pyroscope application name configured")
+	}
+
+	tlsCfg, err := tls.NewClientConfig(logger, config.TLSConfig.CertFile, config.TLSConfig.KeyFile, config.TLSConfig.CAFile, config.TLSConfig.ServerName, config.TLSConfig.InsecureSkipVerify)
+	if err != nil {
+		return nil, errors.Wrap(err, "create TLS config of Pyroscope client")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+
+	return &pyroscopeUploader{
+		client:    &http.Client{Transport: transport},
+		url:       strings.TrimSuffix(config.URL, "/") + "/ingest",
+		appName:   config.ApplicationName,
+		authToken: config.AuthToken,
+	}, nil
+}
+
+// name returns the name of the profile in the format of Pyroscope, i.e. the application name followed by the labels,
+// e.g. thanos.cpu{component=store,instance=host}.
+func (u *pyroscopeUploader) name(p profile) string {
+	keys := make([]string, 0, len(p.labels))
+	for k := range p.labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+p.labels[k])
+	}
+	return u.appName + "." + p.name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (u *pyroscopeUploader) upload(ctx context.Context, p profile) (err error) {
+	params := url.Values{}
+	params.Set("name", u.name(p))
+	params.Set("from", strconv.FormatInt(p.start.Unix(), 10))
+	params.Set("until", strconv.FormatInt(p.end.Unix(), 10))
+	params.Set("format", "pprof")
+	params.Set("spyName", "gospy")
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, u.url+"?"+params.Encode(), bytes.NewReader(p.data))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "binary/octet-stream")
+	if u.authToken != "" {
+		r.Header.Set("Authorization", "Bearer "+u.authToken)
+	}
+
+	resp, err := u.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer runutil.ExhaustCloseWithErrCapture(&err, resp.Body, "Pyroscope response body")
+
+	if resp.StatusCode/100 != 2 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+		return errors.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	return nil
+}
+
+func (u *pyroscopeUploader) close() error {
+	u.client.CloseIdleConnections()
+	return nil
+}
@@ -42,6 +42,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 	"github.com/thanos-io/thanos/pkg/targets/targetspb"
 	"github.com/thanos-io/thanos/pkg/tracing"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus/tsdbstatuspb"
 )
 
 var (
@@ -385,16 +386,39 @@ func (p *QueryOptions) AddTo(values url.Values) error {
 	return nil
 }
 
-// QueryInstant performs an instant query using a default HTTP client and returns results in model.Vector type.
-func (c *Client) QueryInstant(ctx context.Context, base *url.URL, query string, t time.Time, opts QueryOptions) (model.Vector, []string, error) {
+// queryResult is the result of a query to the query API, decoded later depending on its type.
+type queryResult struct {
+	Data struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+
+	Error     string `json:"error,omitempty"`
+	ErrorType string `json:"errorType,omitempty"`
+	// Extra field supported by Thanos Querier.
+	Warnings []string `json:"warnings"`
+}
+
+// unexpectedTypeError returns the error of a result which doesn't have the expected type.
+func (m *queryResult) unexpectedTypeError() error {
+	if m.Warnings != nil {
+		return errors.Errorf("error: %s, type: %s, warning: %s", m.Error, m.ErrorType, strings.Join(m.Warnings, ", "))
+	}
+	if m.Error != "" {
+		return errors.Errorf("error: %s, type: %s", m.Error, m.ErrorType)
+	}
+	return errors.Errorf("received status code: 200, unknown response type: '%q'", m.Data.ResultType)
+}
+
+func (c *Client) queryInstant(ctx context.Context, base *url.URL, query string, t time.Time, opts QueryOptions) (*queryResult, error) {
 	params, err := url.ParseQuery(base.RawQuery)
 	if err != nil {
-		return nil, nil, errors.Wrapf(err, "parse raw query %s", base.RawQuery)
+		return nil, errors.Wrapf(err, "parse raw query %s", base.RawQuery)
 	}
 	params.Add("query", query)
 	params.Add("time", t.Format(time.RFC3339Nano))
 	if err := opts.AddTo(params); err != nil {
-		return nil, nil, errors.Wrap(err, "add thanos opts query params")
+		return nil, errors.Wrap(err, "add thanos opts query params")
 	}
 
 	u := *base
@@ -413,25 +437,23 @@ func (c *Client) QueryInstant(ctx context.Context, base *url.URL, query string,
 
 	body, _, err := c.req2xx(ctx, &u, method)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "read query instant response")
+		return nil, errors.Wrap(err, "read query instant response")
 	}
 
 	// Decode only ResultType and load Result only as RawJson since we don't know
 	// structure of the Result yet.
-	var m struct {
-		Data struct {
-			ResultType string          `json:"resultType"`
-			Result     json.RawMessage `json:"result"`
-		} `json:"data"`
-
-		Error     string `json:"error,omitempty"`
-		ErrorType string `json:"errorType,omitempty"`
-		// Extra field supported by Thanos Querier.
-		Warnings []string `json:"warnings"`
+	var m queryResult
+	if err = json.Unmarshal(body, &m); err != nil {
+		return nil, errors.Wrap(err, "unmarshal query instant response")
 	}
+	return &m, nil
+}
 
-	if err = json.Unmarshal(body, &m); err != nil {
-		return nil, nil, errors.Wrap(err, "unmarshal query instant response")
+// QueryInstant performs an instant query using a default HTTP client and returns results in model.Vector type.
+func (c *Client) QueryInstant(ctx context.Context, base *url.URL, query string, t time.Time, opts QueryOptions) (model.Vector, []string, error) {
+	m, err := c.queryInstant(ctx, base, query, t, opts)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var vectorResult model.Vector
@@ -449,18 +471,30 @@ func (c *Client) QueryInstant(ctx context.Context, base *url.URL, query string,
 			return nil, nil, errors.Wrap(err, "decode result into ValueTypeScalar")
 		}
 	default:
-		if m.Warnings != nil {
-			return nil, nil, errors.Errorf("error: %s, type: %s, warning: %s", m.Error, m.ErrorType, strings.Join(m.Warnings, ", "))
-		}
-		if m.Error != "" {
-			return nil, nil, errors.Errorf("error: %s, type: %s", m.Error, m.ErrorType)
-		}
-		return nil, nil, errors.Errorf("received status code: 200, unknown response type: '%q'", m.Data.ResultType)
+		return nil, nil, m.unexpectedTypeError()
 	}
 
 	return vectorResult, m.Warnings, nil
 }
 
+// QueryInstantMatrix performs an instant query of a range vector, e.g. a range selector, and returns the raw samples
+// of its series in model.Matrix type.
+func (c *Client) QueryInstantMatrix(ctx context.Context, base *url.URL, query string, t time.Time, opts QueryOptions) (model.Matrix, []string, error) {
+	m, err := c.queryInstant(ctx, base, query, t, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m.Data.ResultType != string(parser.ValueTypeMatrix) {
+		return nil, nil, m.unexpectedTypeError()
+	}
+
+	var matrixResult model.Matrix
+	if err = json.Unmarshal(m.Data.Result, &matrixResult); err != nil {
+		return nil, nil, errors.Wrap(err, "decode result into ValueTypeMatrix")
+	}
+	return matrixResult, m.Warnings, nil
+}
+
 // PromqlQueryInstant performs instant query and returns results in promql.Vector type that is compatible with promql package.
 func (c *Client) PromqlQueryInstant(ctx context.Context, base *url.URL, query string, t time.Time, opts QueryOptions) (promql.Vector, []string, error) {
 	vectorResult, warnings, err := c.QueryInstant(ctx, base, query, t, opts)
@@ -614,6 +648,46 @@ func (c *Client) AlertmanagerAlerts(ctx context.Context, base *url.URL) ([]*mode
 	return v.Data, nil
 }
 
+// IsReady returns no error if Prometheus is ready to serve traffic. Prometheus is not ready while it replays its WAL.
+func (c *Client) IsReady(ctx context.Context, base *url.URL) error {
+	u := *base
+	u.Path = path.Join(u.Path, "/-/ready")
+
+	span, ctx := tracing.StartSpan(ctx, "/prom_ready HTTP[client]")
+	defer span.Finish()
+
+	_, _, err := c.req2xx(ctx, &u, http.MethodGet)
+	return err
+}
+
+// ConfigReloadSuccessful returns whether the last configuration reload of Prometheus succeeded, from the
+// /api/v1/status/runtimeinfo Prometheus endpoint. For Prometheus versions < 2.20.0 it returns true.
+func (c *Client) ConfigReloadSuccessful(ctx context.Context, base *url.URL) (bool, error) {
+	u := *base
+	u.Path = path.Join(u.Path, "/api/v1/status/runtimeinfo")
+
+	span, ctx := tracing.StartSpan(ctx, "/prom_runtimeinfo HTTP[client]")
+	defer span.Finish()
+
+	body, code, err := c.req2xx(ctx, &u, http.MethodGet)
+	if err != nil {
+		if code == http.StatusNotFound {
+			return true, nil
+		}
+		return false, err
+	}
+
+	var b struct {
+		Data struct {
+			ReloadConfigSuccess bool `json:"reloadConfigSuccess"`
+		} `json:"data"`
+	}
+	if err = json.Unmarshal(body, &b); err != nil {
+		return false, errors.Wrap(err, "unmarshal runtime info API response")
+	}
+	return b.Data.ReloadConfigSuccess, nil
+}
+
 // BuildVersion returns Prometheus version from /api/v1/status/buildinfo Prometheus endpoint.
 // For Prometheus versions < 2.14.0 it returns "0" as Prometheus version.
 func (c *Client) BuildVersion(ctx context.Context, base *url.URL) (string, error) {
@@ -838,3 +912,14 @@ func (c *Client) TargetsInGRPC(ctx context.Context, base *url.URL, stateTargets
 	}
 	return v.Data, c.get2xxResultWithGRPCErrors(ctx, "/prom_targets HTTP[client]", &u, &v)
 }
+
+// TSDBStatusInGRPC returns the head and cardinality statistics from Prometheus TSDB status API. It uses gRPC errors.
+func (c *Client) TSDBStatusInGRPC(ctx context.Context, base *url.URL) (*tsdbstatuspb.TSDBStatus, error) {
+	u := *base
+	u.Path = path.Join(u.Path, "/api/v1/status/tsdb")
+
+	var v struct {
+		Data *tsdbstatuspb.TSDBStatus `json:"data"`
+	}
+	return v.Data, c.get2xxResultWithGRPCErrors(ctx, "/prom_tsdb_status HTTP[client]", &u, &v)
+}
@@ -16,9 +16,11 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/prometheus/model/labels"
 	"google.golang.org/grpc"
 
+	"github.com/thanos-io/thanos/pkg/admin/adminpb"
 	"github.com/thanos-io/thanos/pkg/component"
 	"github.com/thanos-io/thanos/pkg/exemplars/exemplarspb"
 	"github.com/thanos-io/thanos/pkg/info/infopb"
@@ -29,6 +31,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 	"github.com/thanos-io/thanos/pkg/targets/targetspb"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus/tsdbstatuspb"
 )
 
 const (
@@ -39,6 +42,7 @@ const (
 type GRPCEndpointSpec struct {
 	addr           string
 	isStrictStatic bool
+	dialOpts       []grpc.DialOption
 }
 
 // NewGRPCEndpointSpec creates gRPC endpoint spec.
@@ -47,6 +51,13 @@ func NewGRPCEndpointSpec(addr string, isStrictStatic bool) *GRPCEndpointSpec {
 	return &GRPCEndpointSpec{addr: addr, isStrictStatic: isStrictStatic}
 }
 
+// WithDialOptions sets the dial options of the endpoint, which are appended to the ones of the endpoint set, e.g. to
+// override the compression of the endpoint.
+func (es *GRPCEndpointSpec) WithDialOptions(opts ...grpc.DialOption) *GRPCEndpointSpec {
+	es.dialOpts = opts
+	return es
+}
+
 // IsStrictStatic returns true if the endpoint has been statically defined and it is under a strict mode.
 func (es *GRPCEndpointSpec) IsStrictStatic() bool {
 	return es.isStrictStatic
@@ -166,14 +177,34 @@ func (e *stringError) Error() string {
 	return e.originalErr.Error()
 }
 
+const (
+	// maxRecentErrors is the number of the most recent errors kept in the status of an endpoint.
+	maxRecentErrors = 5
+	// maxHealthHistory is the number of the most recent health checks kept in the status of an endpoint.
+	maxHealthHistory = 10
+)
+
 type EndpointStatus struct {
-	Name          string              `json:"name"`
+	Name string `json:"name"`
+	// LastCheck is the time of the last successful health check.
 	LastCheck     time.Time           `json:"lastCheck"`
 	LastError     *stringError        `json:"lastError"`
 	LabelSets     []labels.Labels     `json:"labelSets"`
 	ComponentType component.Component `json:"-"`
 	MinTime       int64               `json:"minTime"`
 	MaxTime       int64               `json:"maxTime"`
+	// APIs are the APIs advertised by the endpoint on the last successful health check.
+	APIs []string `json:"apis"`
+	// RecentErrors are the errors of the most recent failed health checks, the latest last.
+	RecentErrors []string `json:"recentErrors"`
+	// HealthHistory are the results of the most recent health checks, the latest last.
+	HealthHistory []EndpointHealthCheck `json:"healthHistory"`
+}
+
+// EndpointHealthCheck is the result of a health check of an endpoint.
+type EndpointHealthCheck struct {
+	Time    time.Time `json:"time"`
+	Healthy bool      `json:"healthy"`
 }
 
 // endpointSetNodeCollector is a metric collector reporting the number of available storeAPIs for Querier.
@@ -257,6 +288,24 @@ type EndpointSet struct {
 	// Map of statuses used only by UI.
 	endpointStatuses         map[string]*EndpointStatus
 	unhealthyEndpointTimeout time.Duration
+
+	// stalenessGracePeriod is how long endpoints which fail their health checks or disappear from the endpoint
+	// specifications keep being queried with their last known metadata.
+	stalenessGracePeriod time.Duration
+	staleEndpoints       prometheus.Gauge
+	removedEndpoints     prometheus.Counter
+}
+
+// EndpointSetOption configures an EndpointSet.
+type EndpointSetOption func(*EndpointSet)
+
+// WithEndpointStalenessGracePeriod keeps querying endpoints which fail their health checks or disappear from the
+// endpoint specifications with their last known metadata, until the grace period since their last successful
+// health check elapses, so that short outages of endpoints or of their discovery don't cause gaps in queries.
+func WithEndpointStalenessGracePeriod(d time.Duration) EndpointSetOption {
+	return func(e *EndpointSet) {
+		e.stalenessGracePeriod = d
+	}
 }
 
 // NewEndpointSet returns a new set of Thanos APIs.
@@ -266,10 +315,13 @@ func NewEndpointSet(
 	endpointSpecs func() []*GRPCEndpointSpec,
 	dialOpts []grpc.DialOption,
 	unhealthyEndpointTimeout time.Duration,
+	opts ...EndpointSetOption,
 ) *EndpointSet {
 	endpointsMetric := newEndpointSetNodeCollector()
+	var registerer prometheus.Registerer
 	if reg != nil {
 		reg.MustRegister(endpointsMetric)
+		registerer = reg
 	}
 
 	if logger == nil {
@@ -289,6 +341,17 @@ func NewEndpointSet(
 		endpointStatuses:         make(map[string]*EndpointStatus),
 		unhealthyEndpointTimeout: unhealthyEndpointTimeout,
 		endpointSpec:             endpointSpecs,
+		staleEndpoints: promauto.With(registerer).NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_query_stale_endpoints",
+			Help: "Number of endpoints which failed their health checks or disappeared from discovery, but are still queried within the staleness grace period.",
+		}),
+		removedEndpoints: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_query_removed_endpoints_total",
+			Help: "Total number of endpoints which were removed for failing their health checks or disappearing from discovery.",
+		}),
+	}
+	for _, o := range opts {
+		o(es)
 	}
 	return es
 }
@@ -311,20 +374,33 @@ func (e *EndpointSet) Update(ctx context.Context) {
 	activeEndpoints := e.getActiveEndpoints(ctx, endpoints)
 	level.Debug(e.logger).Log("msg", "checked requested endpoints", "activeEndpoints", len(activeEndpoints), "cachedEndpoints", len(endpoints))
 
-	stats := newEndpointAPIStats()
+	var (
+		stats = newEndpointAPIStats()
+		stale = 0
+		now   = time.Now()
+	)
 
-	// Close endpoints which are not active this time (are not in active endpoints map).
+	// Close endpoints which are not active this time (are not in active endpoints map), unless they are still within
+	// the staleness grace period.
 	for addr, er := range endpoints {
 		if _, ok := activeEndpoints[addr]; ok {
 			stats[er.ComponentType()][labelpb.PromLabelSetsToString(er.LabelSets())]++
 			continue
 		}
+		if now.Sub(er.lastHealthy) < e.stalenessGracePeriod {
+			stats[er.ComponentType()][labelpb.PromLabelSetsToString(er.LabelSets())]++
+			stale++
+			level.Debug(er.logger).Log("msg", "keeping stale endpoint within the staleness grace period", "address", addr, "lastHealthy", er.lastHealthy)
+			continue
+		}
 
 		er.Close()
 		delete(endpoints, addr)
+		e.removedEndpoints.Inc()
 		e.updateEndpointStatus(er, errors.New(unhealthyEndpointMessage))
 		level.Info(er.logger).Log("msg", unhealthyEndpointMessage, "address", addr, "extLset", labelpb.PromLabelSetsToString(er.LabelSets()))
 	}
+	e.staleEndpoints.Set(float64(stale))
 
 	// Add endpoints that are not yet in activeEndpoints map.
 	for addr, er := range activeEndpoints {
@@ -371,6 +447,20 @@ func (e *EndpointSet) GetStoreClients() []store.Client {
 	return stores
 }
 
+// GetTSDBInfos returns the time ranges of the data per resolution of all active stores.
+func (e *EndpointSet) GetTSDBInfos() []infopb.TSDBInfo {
+	e.endpointsMtx.RLock()
+	defer e.endpointsMtx.RUnlock()
+
+	var infos []infopb.TSDBInfo
+	for _, er := range e.endpoints {
+		if er.HasStoreAPI() {
+			infos = append(infos, er.TSDBInfos()...)
+		}
+	}
+	return infos
+}
+
 // GetRulesClients returns a list of all active rules clients.
 func (e *EndpointSet) GetRulesClients() []rulespb.RulesClient {
 	e.endpointsMtx.RLock()
@@ -413,6 +503,37 @@ func (e *EndpointSet) GetMetricMetadataClients() []metadatapb.MetadataClient {
 	return metadataClients
 }
 
+// GetTSDBStatusClients returns a list of all active TSDB status clients.
+func (e *EndpointSet) GetTSDBStatusClients() []tsdbstatuspb.TSDBStatusClient {
+	e.endpointsMtx.RLock()
+	defer e.endpointsMtx.RUnlock()
+
+	tsdbStatusClients := make([]tsdbstatuspb.TSDBStatusClient, 0, len(e.endpoints))
+	for _, er := range e.endpoints {
+		if er.HasTSDBStatusAPI() {
+			tsdbStatusClients = append(tsdbStatusClients, er.clients.tsdbStatus)
+		}
+	}
+	return tsdbStatusClients
+}
+
+// GetAdminEndpoints returns a list of all active endpoints exposing the admin API.
+func (e *EndpointSet) GetAdminEndpoints() []*adminpb.AdminEndpoint {
+	e.endpointsMtx.RLock()
+	defer e.endpointsMtx.RUnlock()
+
+	adminEndpoints := make([]*adminpb.AdminEndpoint, 0, len(e.endpoints))
+	for _, er := range e.endpoints {
+		if er.HasAdminAPI() {
+			adminEndpoints = append(adminEndpoints, &adminpb.AdminEndpoint{
+				AdminClient: er.clients.admin,
+				Name:        er.addr,
+			})
+		}
+	}
+	return adminEndpoints
+}
+
 // GetExemplarsStores returns a list of all active exemplars stores.
 func (e *EndpointSet) GetExemplarsStores() []*exemplarspb.ExemplarStore {
 	e.endpointsMtx.RLock()
@@ -421,9 +542,13 @@ func (e *EndpointSet) GetExemplarsStores() []*exemplarspb.ExemplarStore {
 	exemplarStores := make([]*exemplarspb.ExemplarStore, 0, len(e.endpoints))
 	for _, er := range e.endpoints {
 		if er.HasExemplarsAPI() {
+			mint, maxt := er.ExemplarsTimeRange()
 			exemplarStores = append(exemplarStores, &exemplarspb.ExemplarStore{
 				ExemplarsClient: er.clients.exemplar,
 				LabelSets:       labelpb.ZLabelSetsToPromLabelSets(er.metadata.LabelSets...),
+				Name:            er.addr,
+				MinTime:         mint,
+				MaxTime:         maxt,
 			})
 		}
 	}
@@ -468,7 +593,11 @@ func (e *EndpointSet) getActiveEndpoints(ctx context.Context, endpoints map[stri
 			er, seenAlready := endpoints[addr]
 			if !seenAlready {
 				// New endpoint or was unactive and was removed in the past - create the new one.
-				conn, err := grpc.DialContext(ctx, addr, e.dialOpts...)
+				dialOpts := e.dialOpts
+				if len(spec.dialOpts) > 0 {
+					dialOpts = append(append(make([]grpc.DialOption, 0, len(e.dialOpts)+len(spec.dialOpts)), e.dialOpts...), spec.dialOpts...)
+				}
+				conn, err := grpc.DialContext(ctx, addr, dialOpts...)
 				if err != nil {
 					e.updateEndpointStatus(&endpointRef{addr: addr}, err)
 					level.Warn(e.logger).Log("msg", "update of node failed", "err", errors.Wrap(err, "dialing connection"), "address", addr)
@@ -525,6 +654,7 @@ func (e *EndpointSet) getActiveEndpoints(ctx context.Context, endpoints map[stri
 			}
 
 			er.Update(metadata)
+			er.lastHealthy = time.Now()
 			e.updateEndpointStatus(er, nil)
 
 			mtx.Lock()
@@ -552,21 +682,42 @@ func (e *EndpointSet) updateEndpointStatus(er *endpointRef, err error) {
 		status.MaxTime = maxt
 	}
 
+	now := time.Now()
 	if err == nil {
-		status.LastCheck = time.Now()
+		status.LastCheck = now
 		mint, maxt := er.TimeRange()
 		status.LabelSets = er.LabelSets()
 		status.ComponentType = er.ComponentType()
 		status.MinTime = mint
 		status.MaxTime = maxt
+		status.APIs = er.apisPresent()
 		status.LastError = nil
 	} else {
 		status.LastError = &stringError{originalErr: err}
+		status.RecentErrors = appendRecentError(status.RecentErrors, err.Error())
 	}
+	status.HealthHistory = appendHealthCheck(status.HealthHistory, EndpointHealthCheck{Time: now, Healthy: err == nil})
 
 	e.endpointStatuses[er.addr] = &status
 }
 
+// appendRecentError returns a new slice with the error appended to the errors, keeping at most maxRecentErrors. The
+// slices of the statuses are never modified in place, as they are shared with the copies returned by GetEndpointStatus.
+func appendRecentError(errs []string, err string) []string {
+	if len(errs) >= maxRecentErrors {
+		errs = errs[len(errs)-maxRecentErrors+1:]
+	}
+	return append(append(make([]string, 0, len(errs)+1), errs...), err)
+}
+
+// appendHealthCheck returns a new slice with the check appended to the checks, keeping at most maxHealthHistory.
+func appendHealthCheck(checks []EndpointHealthCheck, check EndpointHealthCheck) []EndpointHealthCheck {
+	if len(checks) >= maxHealthHistory {
+		checks = checks[len(checks)-maxHealthHistory+1:]
+	}
+	return append(append(make([]EndpointHealthCheck, 0, len(checks)+1), checks...), check)
+}
+
 func (e *EndpointSet) GetEndpointStatus() []EndpointStatus {
 	e.endpointsStatusesMtx.RLock()
 	defer e.endpointsStatusesMtx.RUnlock()
@@ -609,6 +760,8 @@ type endpointRef struct {
 
 	// Metadata can change during runtime.
 	metadata *endpointMetadata
+	// lastHealthy is the time of the last successful health check.
+	lastHealthy time.Time
 
 	logger log.Logger
 }
@@ -643,9 +796,23 @@ func (er *endpointRef) Update(metadata *endpointMetadata) {
 	}
 
 	if metadata.Exemplars != nil {
-		// min/max range is also provided by in the response of Info rpc call
-		// but we are not using this metadata anywhere right now so ignoring.
 		clients.exemplar = exemplarspb.NewExemplarsClient(er.cc)
+	} else {
+		// Endpoints stop advertising the exemplars API e.g. when the Prometheus behind a sidecar is downgraded to a
+		// version without it, so the client is dropped to not fan out requests which would fail.
+		clients.exemplar = nil
+	}
+
+	if metadata.TsdbStatus != nil {
+		clients.tsdbStatus = tsdbstatuspb.NewTSDBStatusClient(er.cc)
+	} else {
+		clients.tsdbStatus = nil
+	}
+
+	if metadata.Admin != nil {
+		clients.admin = adminpb.NewAdminClient(er.cc)
+	} else {
+		clients.admin = nil
 	}
 
 	er.clients = clients
@@ -698,6 +865,20 @@ func (er *endpointRef) HasExemplarsAPI() bool {
 	return er.clients != nil && er.clients.exemplar != nil
 }
 
+func (er *endpointRef) HasTSDBStatusAPI() bool {
+	er.mtx.RLock()
+	defer er.mtx.RUnlock()
+
+	return er.clients != nil && er.clients.tsdbStatus != nil
+}
+
+func (er *endpointRef) HasAdminAPI() bool {
+	er.mtx.RLock()
+	defer er.mtx.RUnlock()
+
+	return er.clients != nil && er.clients.admin != nil
+}
+
 func (er *endpointRef) LabelSets() []labels.Labels {
 	er.mtx.RLock()
 	defer er.mtx.RUnlock()
@@ -732,6 +913,43 @@ func (er *endpointRef) TimeRange() (mint, maxt int64) {
 	return er.metadata.Store.MinTime, er.metadata.Store.MaxTime
 }
 
+// SupportsSeriesBatches returns true if the store advertises the support of batches of series.
+func (er *endpointRef) SupportsSeriesBatches() bool {
+	er.mtx.RLock()
+	defer er.mtx.RUnlock()
+
+	return er.metadata != nil && er.metadata.Store != nil && er.metadata.Store.SupportsSeriesBatches
+}
+
+// TSDBInfos returns the time ranges of the data per resolution advertised by the store. Stores which do not
+// advertise them are assumed to have raw data over their whole time range.
+func (er *endpointRef) TSDBInfos() []infopb.TSDBInfo {
+	er.mtx.RLock()
+	defer er.mtx.RUnlock()
+
+	if er.metadata == nil || er.metadata.Store == nil {
+		return nil
+	}
+	if len(er.metadata.Store.TsdbInfos) > 0 {
+		return er.metadata.Store.TsdbInfos
+	}
+	return []infopb.TSDBInfo{{
+		MinTime: er.metadata.Store.MinTime,
+		MaxTime: er.metadata.Store.MaxTime,
+	}}
+}
+
+// ExemplarsTimeRange returns the time range of the exemplars advertised by the endpoint, which is zero if unknown.
+func (er *endpointRef) ExemplarsTimeRange() (mint, maxt int64) {
+	er.mtx.RLock()
+	defer er.mtx.RUnlock()
+
+	if er.metadata == nil || er.metadata.Exemplars == nil {
+		return 0, 0
+	}
+	return er.metadata.Exemplars.MinTime, er.metadata.Exemplars.MaxTime
+}
+
 func (er *endpointRef) String() string {
 	mint, maxt := er.TimeRange()
 	return fmt.Sprintf("Addr: %s LabelSets: %v Mint: %d Maxt: %d", er.addr, labelpb.PromLabelSetsToString(er.LabelSets()), mint, maxt)
@@ -768,6 +986,14 @@ func (er *endpointRef) apisPresent() []string {
 		apisPresent = append(apisPresent, "MetricMetadataAPI")
 	}
 
+	if er.HasTSDBStatusAPI() {
+		apisPresent = append(apisPresent, "tsdbStatusAPI")
+	}
+
+	if er.HasAdminAPI() {
+		apisPresent = append(apisPresent, "adminAPI")
+	}
+
 	return apisPresent
 }
 
@@ -777,6 +1003,8 @@ type endpointClients struct {
 	metricMetadata metadatapb.MetadataClient
 	exemplar       exemplarspb.ExemplarsClient
 	target         targetspb.TargetsClient
+	tsdbStatus     tsdbstatuspb.TSDBStatusClient
+	admin          adminpb.AdminClient
 	info           infopb.InfoClient
 }
 
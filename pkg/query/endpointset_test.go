@@ -16,6 +16,8 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/thanos-io/thanos/pkg/component"
 	"github.com/thanos-io/thanos/pkg/info/infopb"
 	"github.com/thanos-io/thanos/pkg/store"
@@ -695,6 +697,59 @@ func TestEndpointSet_Update_NoneAvailable(t *testing.T) {
 	testutil.Equals(t, expected, endpointSet.endpointsMetric.storeNodes)
 }
 
+func TestEndpointSet_Update_StalenessGracePeriod(t *testing.T) {
+	extlsetFn := func(addr string) []labelpb.ZLabelSet {
+		return []labelpb.ZLabelSet{{Labels: []labelpb.ZLabel{{Name: "addr", Value: addr}}}}
+	}
+	endpoints, err := startTestEndpoints([]testEndpointMeta{
+		{InfoResponse: sidecarInfo, extlsetFn: extlsetFn},
+		{InfoResponse: sidecarInfo, extlsetFn: extlsetFn},
+	})
+	testutil.Ok(t, err)
+	defer endpoints.Close()
+
+	addrs := endpoints.EndpointAddresses()
+	discovered := addrs
+	reg := prometheus.NewRegistry()
+	endpointSet := NewEndpointSet(nil, reg,
+		func() (specs []*GRPCEndpointSpec) {
+			for _, addr := range discovered {
+				specs = append(specs, NewGRPCEndpointSpec(addr, false))
+			}
+			return specs
+		},
+		testGRPCOpts, time.Minute, WithEndpointStalenessGracePeriod(time.Hour))
+	endpointSet.gRPCInfoCallTimeout = 2 * time.Second
+	defer endpointSet.Close()
+
+	endpointSet.Update(context.Background())
+	testutil.Equals(t, 2, len(endpointSet.endpoints))
+
+	// Endpoints failing their health checks or disappearing from discovery are still queried within the grace period.
+	endpoints.CloseOne(addrs[0])
+	discovered = addrs[:1]
+	endpointSet.Update(context.Background())
+	testutil.Equals(t, 2, len(endpointSet.GetStoreClients()))
+	testutil.Equals(t, 2.0, promtestutil.ToFloat64(endpointSet.staleEndpoints))
+	testutil.Equals(t, 0.0, promtestutil.ToFloat64(endpointSet.removedEndpoints))
+	testutil.Equals(t, "addr", endpointSet.endpoints[addrs[0]].LabelSets()[0][0].Name)
+
+	// Endpoints reappearing in discovery are not stale anymore.
+	discovered = addrs
+	endpointSet.Update(context.Background())
+	testutil.Equals(t, 2, len(endpointSet.endpoints))
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(endpointSet.staleEndpoints))
+
+	// Stale endpoints are removed once the grace period elapsed.
+	endpointSet.stalenessGracePeriod = 0
+	endpointSet.Update(context.Background())
+	testutil.Equals(t, 1, len(endpointSet.endpoints))
+	_, ok := endpointSet.endpoints[addrs[1]]
+	testutil.Assert(t, ok)
+	testutil.Equals(t, 0.0, promtestutil.ToFloat64(endpointSet.staleEndpoints))
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(endpointSet.removedEndpoints))
+}
+
 // TestEndpoint_Update_QuerierStrict tests what happens when the strict mode is enabled/disabled.
 func TestEndpoint_Update_QuerierStrict(t *testing.T) {
 	endpoints, err := startTestEndpoints([]testEndpointMeta{
@@ -1125,6 +1180,44 @@ func TestUpdateEndpointStateForgetsPreviousErrors(t *testing.T) {
 	testutil.Equals(t, `null`, string(b))
 }
 
+func TestUpdateEndpointStateHealthHistory(t *testing.T) {
+	mockEndpointSet := &EndpointSet{
+		endpointStatuses: map[string]*EndpointStatus{},
+	}
+	mockEndpointRef := &endpointRef{
+		addr: "mockedStore",
+		metadata: &endpointMetadata{
+			&infopb.InfoResponse{ComponentType: component.Sidecar.String(), Store: &infopb.StoreInfo{}},
+		},
+		clients: &endpointClients{store: storepb.NewStoreClient(nil)},
+	}
+
+	mockEndpointSet.updateEndpointStatus(mockEndpointRef, nil)
+	status := mockEndpointSet.GetEndpointStatus()[0]
+	testutil.Equals(t, []string{"storeAPI"}, status.APIs)
+	testutil.Equals(t, 0, len(status.RecentErrors))
+	testutil.Equals(t, 1, len(status.HealthHistory))
+	testutil.Assert(t, status.HealthHistory[0].Healthy, "expected a healthy check")
+
+	for i := 0; i < maxHealthHistory; i++ {
+		mockEndpointSet.updateEndpointStatus(mockEndpointRef, errors.Errorf("err %d", i))
+	}
+	status = mockEndpointSet.GetEndpointStatus()[0]
+	testutil.Equals(t, []string{"err 5", "err 6", "err 7", "err 8", "err 9"}, status.RecentErrors)
+	testutil.Equals(t, maxHealthHistory, len(status.HealthHistory))
+	for _, c := range status.HealthHistory {
+		testutil.Assert(t, !c.Healthy, "expected only failed checks")
+	}
+
+	// Recovering keeps the recent errors and the previously returned statuses untouched.
+	mockEndpointSet.updateEndpointStatus(mockEndpointRef, nil)
+	recovered := mockEndpointSet.GetEndpointStatus()[0]
+	testutil.Equals(t, status.RecentErrors, recovered.RecentErrors)
+	testutil.Assert(t, recovered.HealthHistory[maxHealthHistory-1].Healthy, "expected the latest check to be healthy")
+	testutil.Assert(t, !status.HealthHistory[maxHealthHistory-1].Healthy, "expected the previous status to be unchanged")
+	testutil.Equals(t, (*stringError)(nil), recovered.LastError)
+}
+
 func exposedAPIs(c string) *APIs {
 	switch c {
 	case component.Sidecar.String():
@@ -213,6 +213,10 @@ func (s *storeRef) Addr() string {
 	return s.addr
 }
 
+func (s *storeRef) SupportsSeriesBatches() bool {
+	return false
+}
+
 func (s *storeRef) close() {
 	runutil.CloseWithLogOnErr(s.logger, s.cc, fmt.Sprintf("store %v connection close", s.addr))
 }
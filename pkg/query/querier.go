@@ -22,9 +22,11 @@ import (
 	"github.com/thanos-io/thanos/pkg/dedup"
 	"github.com/thanos-io/thanos/pkg/extprom"
 	"github.com/thanos-io/thanos/pkg/gate"
+	"github.com/thanos-io/thanos/pkg/info/infopb"
 	"github.com/thanos-io/thanos/pkg/store"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/tenancy"
 	"github.com/thanos-io/thanos/pkg/tracing"
 )
 
@@ -36,14 +38,49 @@ import (
 // partialResponse controls `partialResponseDisabled` option of StoreAPI and partial response behavior of proxy.
 type QueryableCreator func(deduplicate bool, replicaLabels []string, storeDebugMatchers [][]*labels.Matcher, maxResolutionMillis int64, partialResponse, enableQueryPushdown, skipChunks bool) storage.Queryable
 
+type ctxKey int
+
+// limitKey is the context key for the limit of the StoreAPI requests of queriers.
+const limitKey = ctxKey(0)
+
+// WithLimit returns a context which makes the queriers created with it ask the StoreAPIs to return at most limit
+// series, label names or label values per request. Zero means no limit.
+func WithLimit(ctx context.Context, limit int64) context.Context {
+	return context.WithValue(ctx, limitKey, limit)
+}
+
+func limitFromContext(ctx context.Context) int64 {
+	limit, _ := ctx.Value(limitKey).(int64)
+	return limit
+}
+
+// QueryableCreatorOption configures the queryables of a QueryableCreator.
+type QueryableCreatorOption func(*queryable)
+
+// WithTSDBInfos makes the queriers of queries with automatic downsampling, see WithAutoDownsampling, pick the max
+// source resolution of each sub-range of their selects based on the time ranges of the resolutions returned by f.
+func WithTSDBInfos(f func() []infopb.TSDBInfo) QueryableCreatorOption {
+	return func(q *queryable) {
+		q.tsdbInfos = f
+	}
+}
+
+// WithCounterAwareDedup makes the queriers, if enabled, deduplicate the counters of replicas by carrying over their
+// increases when switching between them, see dedup.NewCounterAwareSeriesSet.
+func WithCounterAwareDedup(enabled bool) QueryableCreatorOption {
+	return func(q *queryable) {
+		q.counterAwareDedup = enabled
+	}
+}
+
 // NewQueryableCreator creates QueryableCreator.
-func NewQueryableCreator(logger log.Logger, reg prometheus.Registerer, proxy storepb.StoreServer, maxConcurrentSelects int, selectTimeout time.Duration) QueryableCreator {
+func NewQueryableCreator(logger log.Logger, reg prometheus.Registerer, proxy storepb.StoreServer, maxConcurrentSelects int, selectTimeout time.Duration, opts ...QueryableCreatorOption) QueryableCreator {
 	duration := promauto.With(
 		extprom.WrapRegistererWithPrefix("concurrent_selects_", reg),
 	).NewHistogram(gate.DurationHistogramOpts)
 
 	return func(deduplicate bool, replicaLabels []string, storeDebugMatchers [][]*labels.Matcher, maxResolutionMillis int64, partialResponse, enableQueryPushdown, skipChunks bool) storage.Queryable {
-		return &queryable{
+		q := &queryable{
 			logger:              logger,
 			replicaLabels:       replicaLabels,
 			storeDebugMatchers:  storeDebugMatchers,
@@ -59,6 +96,10 @@ func NewQueryableCreator(logger log.Logger, reg prometheus.Registerer, proxy sto
 			selectTimeout:        selectTimeout,
 			enableQueryPushdown:  enableQueryPushdown,
 		}
+		for _, o := range opts {
+			o(q)
+		}
+		return q
 	}
 }
 
@@ -75,11 +116,16 @@ type queryable struct {
 	maxConcurrentSelects int
 	selectTimeout        time.Duration
 	enableQueryPushdown  bool
+	tsdbInfos            func() []infopb.TSDBInfo
+	counterAwareDedup    bool
 }
 
 // Querier returns a new storage querier against the underlying proxy store API.
 func (q *queryable) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
-	return newQuerier(ctx, q.logger, mint, maxt, q.replicaLabels, q.storeDebugMatchers, q.proxy, q.deduplicate, q.maxResolutionMillis, q.partialResponse, q.enableQueryPushdown, q.skipChunks, q.gateProviderFn(), q.selectTimeout), nil
+	qr := newQuerier(ctx, q.logger, mint, maxt, q.replicaLabels, q.storeDebugMatchers, q.proxy, q.deduplicate, q.maxResolutionMillis, q.partialResponse, q.enableQueryPushdown, q.skipChunks, q.gateProviderFn(), q.selectTimeout)
+	qr.tsdbInfos = q.tsdbInfos
+	qr.counterAwareDedup = q.counterAwareDedup
+	return qr, nil
 }
 
 type querier struct {
@@ -97,6 +143,8 @@ type querier struct {
 	skipChunks          bool
 	selectGate          gate.Gate
 	selectTimeout       time.Duration
+	tsdbInfos           func() []infopb.TSDBInfo
+	counterAwareDedup   bool
 }
 
 // newQuerier creates implementation of storage.Querier that fetches data from the proxy
@@ -228,6 +276,17 @@ func (q *querier) Select(_ bool, hints *storage.SelectHints, ms ...*labels.Match
 	// The querier has a context but it gets canceled, as soon as query evaluation is completed, by the engine.
 	// We want to prevent this from happening for the async store API calls we make while preserving tracing context.
 	ctx := tracing.CopyTraceContext(context.Background(), q.ctx)
+	if qs := q.ctx.Value(store.QueryStatsKey); qs != nil {
+		// Keep recording the statistics of the query, if requested.
+		ctx = context.WithValue(ctx, store.QueryStatsKey, qs)
+	}
+	if tenant, ok := tenancy.TenantFromContext(q.ctx); ok {
+		// Send the tenant to the stores, e.g. for their usage accounting.
+		ctx = tenancy.ContextWithTenant(ctx, tenant)
+	}
+	if limit := limitFromContext(q.ctx); limit > 0 {
+		ctx = WithLimit(ctx, limit)
+	}
 	ctx, cancel := context.WithTimeout(ctx, q.selectTimeout)
 	span, ctx := tracing.StartSpan(ctx, "querier_select", opentracing.Tags{
 		"minTime":  hints.Start,
@@ -287,23 +346,49 @@ func (q *querier) selectFn(ctx context.Context, hints *storage.SelectHints, ms .
 
 	// TODO(bwplotka): Use inprocess gRPC.
 	resp := &seriesServer{ctx: ctx}
+	ranges := []resolutionRange{{mint: hints.Start, maxt: hints.End, maxResolution: q.maxResolutionMillis}}
+	if q.tsdbInfos != nil && autoDownsamplingFromContext(q.ctx) {
+		ranges = planResolutions(q.tsdbInfos(), hints.Start, hints.End, q.maxResolutionMillis)
+	}
+	// Stores evaluate pushed down functions at the steps of the whole time range, which sub-ranges don't match.
 	var queryHints *storepb.QueryHints
-	if q.enableQueryPushdown {
+	if q.enableQueryPushdown && len(ranges) == 1 {
 		queryHints = storeHintsFromPromHints(hints)
 	}
-	if err := q.proxy.Series(&storepb.SeriesRequest{
-		MinTime:                 hints.Start,
-		MaxTime:                 hints.End,
-		Matchers:                sms,
-		MaxResolutionWindow:     q.maxResolutionMillis,
-		Aggregates:              aggrs,
-		QueryHints:              queryHints,
-		PartialResponseDisabled: !q.partialResponse,
-		SkipChunks:              q.skipChunks,
-		Step:                    hints.Step,
-		Range:                   hints.Range,
-	}, resp); err != nil {
-		return nil, errors.Wrap(err, "proxy Series()")
+	// Stores only return the series of the shard, so that each shard only fetches its own series.
+	var shardInfo *storepb.ShardInfo
+	if shard, ok := shardFromContext(q.ctx); ok {
+		var ignore map[string]struct{}
+		if q.isDedupEnabled() {
+			ignore = q.replicaLabels
+		}
+		shardInfo = storeShardInfo(shard, ignore)
+	}
+	for _, r := range ranges {
+		if err := q.proxy.Series(&storepb.SeriesRequest{
+			MinTime:                 r.mint,
+			MaxTime:                 r.maxt,
+			Matchers:                sms,
+			MaxResolutionWindow:     r.maxResolution,
+			Aggregates:              aggrs,
+			QueryHints:              queryHints,
+			SelectHints:             storeHintsFromPromHints(hints),
+			ShardInfo:               shardInfo,
+			PartialResponseDisabled: !q.partialResponse,
+			SkipChunks:              q.skipChunks,
+			Step:                    hints.Step,
+			Range:                   hints.Range,
+			Limit:                   limitFromContext(ctx),
+		}, resp); err != nil {
+			return nil, errors.Wrap(err, "proxy Series()")
+		}
+	}
+	if len(ranges) > 1 {
+		// Each sub-range returns sorted series, so the same series of different sub-ranges have to be brought
+		// together to have their chunks merged.
+		sort.SliceStable(resp.seriesSet, func(i, j int) bool {
+			return labels.Compare(labelpb.ZLabelsToPromLabels(resp.seriesSet[i].Labels), labelpb.ZLabelsToPromLabels(resp.seriesSet[j].Labels)) < 0
+		})
 	}
 
 	var warns storage.Warnings
@@ -314,7 +399,7 @@ func (q *querier) selectFn(ctx context.Context, hints *storage.SelectHints, ms .
 	// Delete the metric's name from the result because that's what the
 	// PromQL does either way and we want our iterator to work with data
 	// that was either pushed down or not.
-	if q.enableQueryPushdown && (hints.Func == "max_over_time" || hints.Func == "min_over_time") {
+	if queryHints != nil && (hints.Func == "max_over_time" || hints.Func == "min_over_time" || hints.Func == "last_over_time") {
 		for i := range resp.seriesSet {
 			lbls := resp.seriesSet[i].Labels
 			for j, lbl := range lbls {
@@ -350,7 +435,11 @@ func (q *querier) selectFn(ctx context.Context, hints *storage.SelectHints, ms .
 
 	// The merged series set assembles all potentially-overlapping time ranges of the same series into a single one.
 	// TODO(bwplotka): We could potentially dedup on chunk level, use chunk iterator for that when available.
-	return dedup.NewSeriesSet(set, q.replicaLabels, len(aggrs) == 1 && aggrs[0] == storepb.Aggr_COUNTER), nil
+	isCounter := len(aggrs) == 1 && aggrs[0] == storepb.Aggr_COUNTER
+	if q.counterAwareDedup {
+		return dedup.NewCounterAwareSeriesSet(set, q.replicaLabels, isCounter), nil
+	}
+	return dedup.NewSeriesSet(set, q.replicaLabels, isCounter), nil
 }
 
 // sortDedupLabels re-sorts the set so that the same series with different replica
@@ -394,6 +483,7 @@ func (q *querier) LabelValues(name string, matchers ...*labels.Matcher) ([]strin
 		Start:                   q.mint,
 		End:                     q.maxt,
 		Matchers:                pbMatchers,
+		Limit:                   limitFromContext(ctx),
 	})
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "proxy LabelValues()")
@@ -426,6 +516,7 @@ func (q *querier) LabelNames(matchers ...*labels.Matcher) ([]string, storage.War
 		Start:                   q.mint,
 		End:                     q.maxt,
 		Matchers:                pbMatchers,
+		Limit:                   limitFromContext(ctx),
 	})
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "proxy LabelNames()")
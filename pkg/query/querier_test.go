@@ -27,7 +27,9 @@ import (
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/prometheus/util/gate"
 
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/component"
+	"github.com/thanos-io/thanos/pkg/info/infopb"
 	"github.com/thanos-io/thanos/pkg/store"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
@@ -57,6 +59,94 @@ func TestQueryableCreator_MaxResolution(t *testing.T) {
 
 }
 
+type ctxStoreServer struct {
+	storepb.StoreServer
+
+	ctx context.Context
+}
+
+func (s *ctxStoreServer) Series(_ *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	s.ctx = srv.Context()
+	return nil
+}
+
+func TestQuerier_Select_KeepsQueryStats(t *testing.T) {
+	testProxy := &ctxStoreServer{}
+	qs := store.NewQueryStats()
+	ctx := context.WithValue(context.Background(), store.QueryStatsKey, qs)
+
+	q, err := NewQueryableCreator(nil, nil, testProxy, 2, 5*time.Second)(false, nil, nil, 0, false, false, false).Querier(ctx, 0, 42)
+	testutil.Ok(t, err)
+	t.Cleanup(func() { testutil.Ok(t, q.Close()) })
+
+	set := q.Select(false, nil, labels.MustNewMatcher(labels.MatchEqual, "a", "a"))
+	testutil.Assert(t, !set.Next(), "expected no series")
+	testutil.Ok(t, set.Err())
+	testutil.Equals(t, qs, testProxy.ctx.Value(store.QueryStatsKey))
+}
+
+type resolutionStoreServer struct {
+	storepb.StoreServer
+
+	t    testing.TB
+	reqs []storepb.SeriesRequest
+}
+
+func (s *resolutionStoreServer) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	s.reqs = append(s.reqs, *r)
+	// Every sub-range returns the same series, with samples at its bounds.
+	for _, name := range []string{"a", "b"} {
+		if err := srv.Send(storeSeriesResponse(s.t, labels.FromStrings("__name__", name), []sample{{r.MinTime, 1}, {r.MaxTime, 2}})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestQuerier_Select_AutoDownsampling(t *testing.T) {
+	testProxy := &resolutionStoreServer{t: t}
+	infos := []infopb.TSDBInfo{
+		{MinTime: 200, MaxTime: 300, Resolution: downsample.ResLevel0},
+		{MinTime: 0, MaxTime: 300, Resolution: downsample.ResLevel1},
+	}
+	queryableCreator := NewQueryableCreator(nil, nil, testProxy, 2, 5*time.Second, WithTSDBInfos(func() []infopb.TSDBInfo { return infos }))
+
+	// Without automatic downsampling, the whole range is queried with the requested max resolution.
+	q, err := queryableCreator(false, nil, nil, 0, false, false, false).Querier(context.Background(), 0, 300)
+	testutil.Ok(t, err)
+	t.Cleanup(func() { testutil.Ok(t, q.Close()) })
+
+	set := q.Select(false, nil, labels.MustNewMatcher(labels.MatchRegexp, "__name__", ".+"))
+	for set.Next() {
+	}
+	testutil.Ok(t, set.Err())
+	testutil.Equals(t, 1, len(testProxy.reqs))
+	testutil.Equals(t, int64(0), testProxy.reqs[0].MaxResolutionWindow)
+
+	// With it, the range without raw data is queried with a higher max resolution.
+	testProxy.reqs = nil
+	q, err = queryableCreator(false, nil, nil, 0, false, false, false).Querier(WithAutoDownsampling(context.Background()), 0, 300)
+	testutil.Ok(t, err)
+	t.Cleanup(func() { testutil.Ok(t, q.Close()) })
+
+	set = q.Select(false, nil, labels.MustNewMatcher(labels.MatchRegexp, "__name__", ".+"))
+	var got []series
+	for set.Next() {
+		got = append(got, series{lset: set.At().Labels(), samples: expandSeries(t, set.At().Iterator())})
+	}
+	testutil.Ok(t, set.Err())
+	testutil.Equals(t, 2, len(testProxy.reqs))
+	testutil.Equals(t, [3]int64{0, 199, downsample.ResLevel1}, [3]int64{testProxy.reqs[0].MinTime, testProxy.reqs[0].MaxTime, testProxy.reqs[0].MaxResolutionWindow})
+	testutil.Equals(t, [3]int64{200, 300, 0}, [3]int64{testProxy.reqs[1].MinTime, testProxy.reqs[1].MaxTime, testProxy.reqs[1].MaxResolutionWindow})
+
+	// The same series of different sub-ranges are merged.
+	expected := []sample{{0, 1}, {199, 2}, {200, 1}, {300, 2}}
+	testutil.Equals(t, []series{
+		{lset: labels.FromStrings("__name__", "a"), samples: expected},
+		{lset: labels.FromStrings("__name__", "b"), samples: expected},
+	}, got)
+}
+
 // Tests E2E how PromQL works with downsampled data.
 func TestQuerier_DownsampledData(t *testing.T) {
 	testProxy := &testStoreServer{
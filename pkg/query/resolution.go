@@ -0,0 +1,95 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package query
+
+import (
+	"context"
+	"sort"
+
+	"github.com/thanos-io/thanos/pkg/info/infopb"
+)
+
+// autoDownsamplingKey is the context key marking queries with an automatic max source resolution.
+const autoDownsamplingKey = ctxKey(1)
+
+// WithAutoDownsampling returns a context which makes the queriers created with it, if they have the time ranges of
+// the resolutions of the stores, pick the max source resolution of each sub-range of their selects based on them.
+func WithAutoDownsampling(ctx context.Context) context.Context {
+	return context.WithValue(ctx, autoDownsamplingKey, true)
+}
+
+func autoDownsamplingFromContext(ctx context.Context) bool {
+	auto, _ := ctx.Value(autoDownsamplingKey).(bool)
+	return auto
+}
+
+// resolutionRange is a time range queried with a max source resolution.
+type resolutionRange struct {
+	mint, maxt    int64
+	maxResolution int64
+}
+
+// planResolutions splits the time range between mint and maxt into sub-ranges covered by the same resolutions of
+// the given infos, and picks the max source resolution of each of them. Sub-ranges covered by a resolution not
+// higher than maxResolution, or not covered at all, are queried with maxResolution, so stores pick the highest
+// resolution they have up to it. Sub-ranges only covered by higher resolutions, e.g. after the retention of raw data,
+// are queried with the lowest of them instead of being left empty. Adjacent sub-ranges with the same max source
+// resolution are merged.
+func planResolutions(infos []infopb.TSDBInfo, mint, maxt, maxResolution int64) []resolutionRange {
+	bounds := []int64{mint, maxt + 1}
+	for _, info := range infos {
+		if info.MinTime > mint && info.MinTime <= maxt {
+			bounds = append(bounds, info.MinTime)
+		}
+		if info.MaxTime >= mint && info.MaxTime < maxt {
+			bounds = append(bounds, info.MaxTime+1)
+		}
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	var ranges []resolutionRange
+	for i := 0; i < len(bounds)-1; i++ {
+		start, end := bounds[i], bounds[i+1]-1
+		if start > end {
+			continue
+		}
+
+		res := int64(-1)
+		for _, info := range infos {
+			// Sub-ranges are split at the bounds of all infos, so infos overlapping with them cover them.
+			if info.MinTime > start || info.MaxTime < end {
+				continue
+			}
+			if info.Resolution <= maxResolution {
+				res = maxResolution
+				break
+			}
+			if res < 0 || info.Resolution < res {
+				res = info.Resolution
+			}
+		}
+		if res < 0 {
+			res = maxResolution
+		}
+
+		if n := len(ranges); n > 0 && ranges[n-1].maxResolution == res {
+			ranges[n-1].maxt = end
+			continue
+		}
+		ranges = append(ranges, resolutionRange{mint: start, maxt: end, maxResolution: res})
+	}
+	return ranges
+}
+
+// MaxPlannedResolution returns the highest max source resolution picked between mint and maxt for queries with an
+// automatic max source resolution, given the time ranges of the resolutions of the stores.
+func MaxPlannedResolution(infos []infopb.TSDBInfo, mint, maxt, maxResolution int64) int64 {
+	res := maxResolution
+	for _, r := range planResolutions(infos, mint, maxt, maxResolution) {
+		if r.maxResolution > res {
+			res = r.maxResolution
+		}
+	}
+	return res
+}
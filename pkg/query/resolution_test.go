@@ -0,0 +1,64 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package query
+
+import (
+	"testing"
+
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+	"github.com/thanos-io/thanos/pkg/info/infopb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestPlanResolutions(t *testing.T) {
+	// Raw data is kept for the last 100ms, 5m downsampled data for the last 200ms and 1h downsampled data for all of it.
+	infos := []infopb.TSDBInfo{
+		{MinTime: 200, MaxTime: 300, Resolution: downsample.ResLevel0},
+		{MinTime: 100, MaxTime: 300, Resolution: downsample.ResLevel1},
+		{MinTime: 0, MaxTime: 300, Resolution: downsample.ResLevel2},
+	}
+
+	for _, tcase := range []struct {
+		name          string
+		mint, maxt    int64
+		maxResolution int64
+		expected      []resolutionRange
+	}{
+		{
+			name: "covered by raw data",
+			mint: 200, maxt: 300,
+			expected: []resolutionRange{{mint: 200, maxt: 300, maxResolution: downsample.ResLevel0}},
+		},
+		{
+			name: "past the retention of raw data",
+			mint: 0, maxt: 300,
+			expected: []resolutionRange{
+				{mint: 0, maxt: 99, maxResolution: downsample.ResLevel2},
+				{mint: 100, maxt: 199, maxResolution: downsample.ResLevel1},
+				{mint: 200, maxt: 300, maxResolution: downsample.ResLevel0},
+			},
+		},
+		{
+			name: "max resolution covering the 5m downsampled data",
+			mint: 0, maxt: 300, maxResolution: downsample.ResLevel1,
+			expected: []resolutionRange{
+				{mint: 0, maxt: 99, maxResolution: downsample.ResLevel2},
+				{mint: 100, maxt: 300, maxResolution: downsample.ResLevel1},
+			},
+		},
+		{
+			name: "not covered at all",
+			mint: 400, maxt: 500, maxResolution: downsample.ResLevel1,
+			expected: []resolutionRange{{mint: 400, maxt: 500, maxResolution: downsample.ResLevel1}},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			testutil.Equals(t, tcase.expected, planResolutions(infos, tcase.mint, tcase.maxt, tcase.maxResolution))
+		})
+	}
+
+	testutil.Equals(t, int64(downsample.ResLevel2), MaxPlannedResolution(infos, 0, 300, downsample.ResLevel0))
+	testutil.Equals(t, int64(downsample.ResLevel0), MaxPlannedResolution(infos, 250, 300, downsample.ResLevel0))
+	testutil.Equals(t, int64(downsample.ResLevel0), MaxPlannedResolution(nil, 0, 300, downsample.ResLevel0))
+}
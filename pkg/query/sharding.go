@@ -0,0 +1,49 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package query
+
+import (
+	"context"
+	"sort"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// shardKey is the context key for the shard of the series selected by queriers.
+const shardKey = ctxKey(2)
+
+// ShardInfo is a shard of the series of a query, the series whose values of the By labels hash to Index modulo
+// Total. Aggregations grouped by a superset of the By labels of their series can be evaluated for each shard on its
+// own, as all series of a group are in the same shard.
+type ShardInfo struct {
+	Index int
+	Total int
+	// By are the sorted names of the labels the series are sharded by.
+	By []string
+}
+
+// WithShard returns a context which makes the queriers created with it only select the series of the given shard.
+func WithShard(ctx context.Context, shard ShardInfo) context.Context {
+	by := append([]string(nil), shard.By...)
+	sort.Strings(by)
+	shard.By = by
+	return context.WithValue(ctx, shardKey, shard)
+}
+
+func shardFromContext(ctx context.Context) (ShardInfo, bool) {
+	shard, ok := ctx.Value(shardKey).(ShardInfo)
+	return shard, ok
+}
+
+// storeShardInfo returns the shard of the series selected from the stores. Labels in ignore, e.g. the replica labels
+// removed by deduplication, are not hashed, so that all replicas of a series are in the same shard.
+func storeShardInfo(shard ShardInfo, ignore map[string]struct{}) *storepb.ShardInfo {
+	by := make([]string, 0, len(shard.By))
+	for _, n := range shard.By {
+		if _, ok := ignore[n]; !ok {
+			by = append(by, n)
+		}
+	}
+	return &storepb.ShardInfo{ShardIndex: int64(shard.Index), TotalShards: int64(shard.Total), Labels: by}
+}
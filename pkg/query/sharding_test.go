@@ -0,0 +1,66 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package query
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestStoreShardInfo(t *testing.T) {
+	var series []storepb.Series
+	for job := 0; job < 10; job++ {
+		for replica := 0; replica < 2; replica++ {
+			series = append(series, storepb.Series{Labels: labelpb.ZLabelsFromPromLabels(labels.FromStrings(
+				"__name__", "up",
+				"instance", fmt.Sprintf("instance-%d-%d", job, replica),
+				"job", fmt.Sprintf("job-%d", job),
+				"replica", fmt.Sprintf("%d", replica),
+			))})
+		}
+	}
+
+	const total = 3
+	for _, ignore := range []map[string]struct{}{nil, {"replica": {}}} {
+		shardOfJob := map[string]int{}
+		seen := 0
+		for i := 0; i < total; i++ {
+			shard, ok := shardFromContext(WithShard(context.Background(), ShardInfo{Index: i, Total: total, By: []string{"replica", "job"}}))
+			testutil.Assert(t, ok)
+			testutil.Equals(t, []string{"job", "replica"}, shard.By)
+
+			matcher := storeShardInfo(shard, ignore).Matcher()
+			var filtered []storepb.Series
+			for _, s := range series {
+				if matcher.MatchesZLabels(s.Labels) {
+					filtered = append(filtered, s)
+				}
+			}
+			for _, s := range filtered {
+				job := labelpb.ZLabelsToPromLabels(s.Labels).Get("job")
+				if ignore == nil {
+					continue
+				}
+				// All replicas of a job are in the same shard, as the replica label is ignored.
+				if prev, ok := shardOfJob[job]; ok {
+					testutil.Equals(t, i, prev)
+				}
+				shardOfJob[job] = i
+			}
+			seen += len(filtered)
+		}
+		// Each series is in exactly one shard.
+		testutil.Equals(t, len(series), seen)
+	}
+
+	_, ok := shardFromContext(context.Background())
+	testutil.Assert(t, !ok)
+}
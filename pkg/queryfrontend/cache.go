@@ -18,15 +18,26 @@ type thanosCacheKeyGenerator struct {
 	resolutions []int64
 }
 
-func newThanosCacheKeyGenerator(interval time.Duration) thanosCacheKeyGenerator {
+// newThanosCacheKeyGenerator returns a thanosCacheKeyGenerator for the given downsampling resolutions, sorted by
+// increasing resolution. If no resolutions are given, the default resolutions of the compactor are used.
+func newThanosCacheKeyGenerator(interval time.Duration, downsampleResolutions []int64) thanosCacheKeyGenerator {
+	if len(downsampleResolutions) == 0 {
+		downsampleResolutions = defaultResolutions
+	}
+	resolutions := make([]int64, 0, len(downsampleResolutions)+1)
+	for i := len(downsampleResolutions) - 1; i >= 0; i-- {
+		resolutions = append(resolutions, downsampleResolutions[i])
+	}
 	return thanosCacheKeyGenerator{
 		interval:    interval,
-		resolutions: []int64{downsample.ResLevel2, downsample.ResLevel1, downsample.ResLevel0},
+		resolutions: append(resolutions, downsample.ResLevel0),
 	}
 }
 
 // GenerateCacheKey generates a cache key based on the Request and interval.
-// TODO(yeya24): Add other request params as request key.
+// Series requests include dedup and replica labels in the key as they change the returned label sets.
+// Range queries of a shard include the shard in the key, so that the results of each shard of each split interval
+// are cached on their own and reused by later requests, even if the results of other shards change.
 func (t thanosCacheKeyGenerator) GenerateCacheKey(userID string, r queryrange.Request) string {
 	currentInterval := r.GetStart() / t.interval.Milliseconds()
 	switch tr := r.(type) {
@@ -34,11 +45,15 @@ func (t thanosCacheKeyGenerator) GenerateCacheKey(userID string, r queryrange.Re
 		i := 0
 		for ; i < len(t.resolutions) && t.resolutions[i] > tr.MaxSourceResolution; i++ {
 		}
-		return fmt.Sprintf("fe:%s:%s:%d:%d:%d", userID, tr.Query, tr.Step, currentInterval, i)
+		key := fmt.Sprintf("fe:%s:%s:%d:%d:%d", userID, tr.Query, tr.Step, currentInterval, i)
+		if tr.Shard != nil {
+			key += fmt.Sprintf(":%d:%d:%v", tr.Shard.Index, tr.Shard.Total, tr.Shard.By)
+		}
+		return key
 	case *ThanosLabelsRequest:
 		return fmt.Sprintf("fe:%s:%s:%s:%d", userID, tr.Label, tr.Matchers, currentInterval)
 	case *ThanosSeriesRequest:
-		return fmt.Sprintf("fe:%s:%s:%d", userID, tr.Matchers, currentInterval)
+		return fmt.Sprintf("fe:%s:%s:%t:%s:%d", userID, tr.Matchers, tr.Dedup, tr.ReplicaLabels, currentInterval)
 	}
 	return fmt.Sprintf("fe:%s:%s:%d:%d", userID, r.GetQuery(), r.GetStep(), currentInterval)
 }
@@ -9,11 +9,12 @@ import (
 	"github.com/cortexproject/cortex/pkg/querier/queryrange"
 	"github.com/prometheus/prometheus/model/labels"
 
+	"github.com/thanos-io/thanos/pkg/query"
 	"github.com/thanos-io/thanos/pkg/testutil"
 )
 
 func TestGenerateCacheKey(t *testing.T) {
-	splitter := newThanosCacheKeyGenerator(hour)
+	splitter := newThanosCacheKeyGenerator(hour, nil)
 
 	for _, tc := range []struct {
 		name     string
@@ -67,6 +68,16 @@ func TestGenerateCacheKey(t *testing.T) {
 			},
 			expected: "fe::up:10000:0:1",
 		},
+		{
+			name: "shard, different cache key",
+			req: &ThanosQueryRangeRequest{
+				Query: "sum by (job) (up)",
+				Start: 0,
+				Step:  10 * seconds,
+				Shard: &query.ShardInfo{Index: 1, Total: 3, By: []string{"job"}},
+			},
+			expected: "fe::sum by (job) (up):10000:0:2:1:3:[job]",
+		},
 		{
 			name: "1h downsampling resolution, different cache key",
 			req: &ThanosQueryRangeRequest{
@@ -132,8 +143,41 @@ func TestGenerateCacheKey(t *testing.T) {
 			},
 			expected: `fe::up:[[foo="bar"] [baz="qux"]]:0`,
 		},
+		{
+			name: "series, single matcher",
+			req: &ThanosSeriesRequest{
+				Start:    0,
+				Matchers: [][]*labels.Matcher{{labels.MustNewMatcher(labels.MatchEqual, "foo", "bar")}},
+			},
+			expected: `fe::[[foo="bar"]]:false:[]:0`,
+		},
+		{
+			name: "series, dedup with replica labels",
+			req: &ThanosSeriesRequest{
+				Start:         0,
+				Dedup:         true,
+				ReplicaLabels: []string{"replica"},
+				Matchers:      [][]*labels.Matcher{{labels.MustNewMatcher(labels.MatchEqual, "foo", "bar")}},
+			},
+			expected: `fe::[[foo="bar"]]:true:[replica]:0`,
+		},
 	} {
 		key := splitter.GenerateCacheKey("", tc.req)
 		testutil.Equals(t, tc.expected, key)
 	}
 }
+
+func TestGenerateCacheKey_CustomResolutions(t *testing.T) {
+	splitter := newThanosCacheKeyGenerator(hour, []int64{300 * seconds, hour, 6 * hour})
+
+	for res, expected := range map[int64]string{
+		0:             "fe::up:10000:0:3",
+		300 * seconds: "fe::up:10000:0:2",
+		hour:          "fe::up:10000:0:1",
+		2 * hour:      "fe::up:10000:0:1",
+		6 * hour:      "fe::up:10000:0:0",
+	} {
+		key := splitter.GenerateCacheKey("", &ThanosQueryRangeRequest{Query: "up", Step: 10 * seconds, MaxSourceResolution: res})
+		testutil.Equals(t, expected, key)
+	}
+}
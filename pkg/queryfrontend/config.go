@@ -203,6 +203,20 @@ type Config struct {
 	CacheCompression       string
 	RequestLoggingDecision string
 	DownstreamURL          string
+
+	// RetryBudgetRatio and RetryBudgetMinPerSecond bound the retries of all requests together.
+	RetryBudgetRatio        float64
+	RetryBudgetMinPerSecond float64
+
+	// HedgeDownstreamURL is the optional downstream split queries are also sent to after HedgeDelay.
+	HedgeDownstreamURL string
+	HedgeDelay         time.Duration
+
+	TenantCachePathOrContent extflag.PathOrContent
+
+	// SchedulerConfig holds the config of the per-tenant scheduling of downstream requests.
+	SchedulerConfig              SchedulerConfig
+	TenantSchedulerPathOrContent extflag.PathOrContent
 }
 
 // QueryRangeConfig holds the config for query range tripperware.
@@ -213,12 +227,26 @@ type QueryRangeConfig struct {
 
 	ResultsCacheConfig *queryrange.ResultsCacheConfig
 	CachePathOrContent extflag.PathOrContent
+	// TenantCacheConfig holds the optional per-tenant overrides of ResultsCacheConfig.
+	TenantCacheConfig *TenantCacheConfig
 
 	AlignRangeWithStep     bool
 	RequestDownsampled     bool
 	SplitQueriesByInterval time.Duration
 	MaxRetries             int
 	Limits                 *cortexvalidation.Limits
+
+	// DownsampleResolutions are the downsampling resolutions requested in turn by RequestDownsampled,
+	// sorted by increasing resolution. The default resolutions are used if empty.
+	DownsampleResolutions []int64
+	// Shards shards range queries of aggregations grouped by labels into this number of queries.
+	Shards int
+
+	// InstantSplitInterval splits instant queries of range functions over longer ranges.
+	// Split instant queries share the other settings of range queries.
+	InstantSplitInterval time.Duration
+	// InstantShards shards instant queries of aggregations grouped by labels into this number of queries.
+	InstantShards int
 }
 
 // LabelsConfig holds the config for labels tripperware.
@@ -230,6 +258,8 @@ type LabelsConfig struct {
 
 	ResultsCacheConfig *queryrange.ResultsCacheConfig
 	CachePathOrContent extflag.PathOrContent
+	// TenantCacheConfig holds the optional per-tenant overrides of ResultsCacheConfig.
+	TenantCacheConfig *TenantCacheConfig
 
 	SplitQueriesByInterval time.Duration
 	MaxRetries             int
@@ -237,13 +267,21 @@ type LabelsConfig struct {
 	Limits *cortexvalidation.Limits
 }
 
+// validateResultsCacheConfig validates the results cache config, which Cortex only knows without zstd compression.
+func validateResultsCacheConfig(cfg queryrange.ResultsCacheConfig) error {
+	if cfg.Compression == CacheCompressionZstd {
+		cfg.Compression = ""
+	}
+	return cfg.Validate()
+}
+
 // Validate a fully initialized config.
 func (cfg *Config) Validate() error {
 	if cfg.QueryRangeConfig.ResultsCacheConfig != nil {
 		if cfg.QueryRangeConfig.SplitQueriesByInterval <= 0 {
 			return errors.New("split queries interval should be greater than 0 when caching is enabled")
 		}
-		if err := cfg.QueryRangeConfig.ResultsCacheConfig.Validate(); err != nil {
+		if err := validateResultsCacheConfig(*cfg.QueryRangeConfig.ResultsCacheConfig); err != nil {
 			return errors.Wrap(err, "invalid ResultsCache config for query_range tripperware")
 		}
 	}
@@ -252,7 +290,7 @@ func (cfg *Config) Validate() error {
 		if cfg.LabelsConfig.SplitQueriesByInterval <= 0 {
 			return errors.New("split queries interval should be greater than 0  when caching is enabled")
 		}
-		if err := cfg.LabelsConfig.ResultsCacheConfig.Validate(); err != nil {
+		if err := validateResultsCacheConfig(*cfg.LabelsConfig.ResultsCacheConfig); err != nil {
 			return errors.Wrap(err, "invalid ResultsCache config for labels tripperware")
 		}
 	}
@@ -265,5 +303,17 @@ func (cfg *Config) Validate() error {
 		return errors.New("downstream URL should be configured")
 	}
 
+	if cfg.RetryBudgetRatio < 0 || cfg.RetryBudgetMinPerSecond < 0 {
+		return errors.New("retry budget ratio and minimum retries per second cannot be negative")
+	}
+
+	if cfg.SchedulerConfig.MaxOutstandingPerTenant < 0 {
+		return errors.New("max outstanding requests per tenant cannot be negative")
+	}
+
+	if cfg.HedgeDownstreamURL != "" && cfg.HedgeDelay <= 0 {
+		return errors.New("hedge delay should be greater than 0 when hedge downstream URL is configured")
+	}
+
 	return nil
 }
@@ -14,11 +14,17 @@ import (
 
 // DownsampledMiddleware creates a new Middleware that requests downsampled data
 // should response to original request with auto max_source_resolution not contain data points.
-func DownsampledMiddleware(merger queryrange.Merger, registerer prometheus.Registerer) queryrange.Middleware {
+// Data of the given downsampling resolutions, sorted by increasing resolution, is requested in turn.
+// If no resolutions are given, the default resolutions of the compactor are used.
+func DownsampledMiddleware(merger queryrange.Merger, resolutions []int64, registerer prometheus.Registerer) queryrange.Middleware {
+	if len(resolutions) == 0 {
+		resolutions = defaultResolutions
+	}
 	return queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
 		return downsampled{
-			next:   next,
-			merger: merger,
+			next:        next,
+			merger:      merger,
+			resolutions: resolutions,
 			additionalQueriesCount: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
 				Namespace: "thanos",
 				Name:      "frontend_downsampled_extra_queries_total",
@@ -29,14 +35,16 @@ func DownsampledMiddleware(merger queryrange.Merger, registerer prometheus.Regis
 }
 
 type downsampled struct {
-	next   queryrange.Handler
-	merger queryrange.Merger
+	next        queryrange.Handler
+	merger      queryrange.Merger
+	resolutions []int64
 
 	// Metrics.
 	additionalQueriesCount prometheus.Counter
 }
 
-var resolutions = []int64{downsample.ResLevel1, downsample.ResLevel2}
+// defaultResolutions are the downsampling resolutions of the default downsampling levels of the compactor.
+var defaultResolutions = []int64{downsample.ResLevel1, downsample.ResLevel2}
 
 func (d downsampled) Do(ctx context.Context, req queryrange.Request) (queryrange.Response, error) {
 	tqrr, ok := req.(*ThanosQueryRangeRequest)
@@ -52,7 +60,7 @@ func (d downsampled) Do(ctx context.Context, req queryrange.Request) (queryrange
 	)
 
 forLoop:
-	for i < len(resolutions) {
+	for i < len(d.resolutions) {
 		if i > 0 {
 			d.additionalQueriesCount.Inc()
 		}
@@ -63,10 +71,10 @@ forLoop:
 		}
 		resps = append(resps, resp)
 		// Set MaxSourceResolution for next request, if any.
-		for i < len(resolutions) {
-			if tqrr.MaxSourceResolution < resolutions[i] {
+		for i < len(d.resolutions) {
+			if tqrr.MaxSourceResolution < d.resolutions[i] {
 				tqrr.AutoDownsampling = false
-				tqrr.MaxSourceResolution = resolutions[i]
+				tqrr.MaxSourceResolution = d.resolutions[i]
 				break
 			}
 			i++
@@ -95,7 +103,7 @@ forLoop:
 // -1 is returned if r contains no data points.
 // Each SampleStream within r.Data.Result must be sorted by timestamp.
 func minResponseTime(r queryrange.Response) int64 {
-	var res = r.(*queryrange.PrometheusResponse).Data.Result
+	var res = promResponse(r).Data.Result
 	if len(res) == 0 || len(res[0].Samples) == 0 {
 		return -1
 	}
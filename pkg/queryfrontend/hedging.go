@@ -0,0 +1,163 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+type hedgedRoundTripper struct {
+	primary, hedge http.RoundTripper
+	delay          time.Duration
+
+	hedgedRequests prometheus.Counter
+	hedgedWins     prometheus.Counter
+}
+
+// NewHedgedRoundTripper returns a http.RoundTripper which sends range queries, labels and series requests
+// to the hedge round tripper as well if the primary one did not respond within the given delay. The first
+// successful response is returned and the other request is cancelled. All other requests only go to primary.
+func NewHedgedRoundTripper(primary, hedge http.RoundTripper, delay time.Duration, reg prometheus.Registerer) http.RoundTripper {
+	return &hedgedRoundTripper{
+		primary: primary,
+		hedge:   hedge,
+		delay:   delay,
+		hedgedRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_query_frontend_hedged_requests_total",
+			Help: "Total number of requests sent to the hedge downstream because primary downstream was too slow.",
+		}),
+		hedgedWins: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_query_frontend_hedged_requests_won_total",
+			Help: "Total number of hedged requests whose response was used instead of the primary downstream one.",
+		}),
+	}
+}
+
+type hedgedResult struct {
+	resp   *http.Response
+	err    error
+	cancel context.CancelFunc
+	hedged bool
+}
+
+func (h *hedgedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch getOperation(req) {
+	case rangeQueryOp, labelNamesOp, labelValuesOp, seriesOp:
+	default:
+		return h.primary.RoundTrip(req)
+	}
+
+	// Buffer the body, so that it can be sent twice.
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+
+	var (
+		results = make(chan hedgedResult, 2)
+		// Cancel functions of the primary and the hedged request, in that order.
+		cancels = make([]context.CancelFunc, 0, 2)
+	)
+	send := func(rt http.RoundTripper, hedged bool) {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+
+		r := req.Clone(ctx)
+		if body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		go func() {
+			resp, err := rt.RoundTrip(r)
+			results <- hedgedResult{resp: resp, err: err, cancel: cancel, hedged: hedged}
+		}()
+	}
+
+	send(h.primary, false)
+	inflight := 1
+
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+
+	for {
+		var res hedgedResult
+		select {
+		case <-timer.C:
+			h.hedgedRequests.Inc()
+			send(h.hedge, true)
+			inflight++
+			continue
+		case res = <-results:
+			inflight--
+		}
+
+		if res.err == nil && res.resp.StatusCode/100 != 5 {
+			if res.hedged {
+				h.hedgedWins.Inc()
+			}
+			// Cancel the other request and discard its response once it arrives.
+			if inflight > 0 {
+				if res.hedged {
+					cancels[0]()
+				} else {
+					cancels[1]()
+				}
+				go drainHedgedResults(results, inflight)
+			}
+			res.resp.Body = &cancelOnCloseBody{ReadCloser: res.resp.Body, cancel: res.cancel}
+			return res.resp, nil
+		}
+
+		// The attempt failed; wait for the other one if it is still in flight.
+		if inflight > 0 {
+			closeHedgedResult(res)
+			continue
+		}
+		// Don't send the hedge request for failed requests, retries are handled by the retry middleware.
+		if res.err != nil {
+			res.cancel()
+			return nil, res.err
+		}
+		res.resp.Body = &cancelOnCloseBody{ReadCloser: res.resp.Body, cancel: res.cancel}
+		return res.resp, nil
+	}
+}
+
+func drainHedgedResults(results <-chan hedgedResult, n int) {
+	for ; n > 0; n-- {
+		closeHedgedResult(<-results)
+	}
+}
+
+func closeHedgedResult(r hedgedResult) {
+	r.cancel()
+	if r.resp != nil {
+		_, _ = io.Copy(ioutil.Discard, r.resp.Body)
+		_ = r.resp.Body.Close()
+	}
+}
+
+// cancelOnCloseBody releases the context of a request once its response body is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
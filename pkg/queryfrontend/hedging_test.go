@@ -0,0 +1,99 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestHedgedRoundTripper(t *testing.T) {
+	testRequest := &ThanosQueryRangeRequest{
+		Path:  "/api/v1/query_range",
+		Start: 0,
+		End:   2 * hour,
+		Step:  10 * seconds,
+	}
+
+	for _, tc := range []struct {
+		name                 string
+		req                  *ThanosQueryRangeRequest
+		primaryDelay         time.Duration
+		expectedBody         string
+		expectedHedged       float64
+		expectedHedgedWins   float64
+		expectedHedgeQueries int32
+	}{
+		{
+			name:         "primary responds in time, no hedged request",
+			req:          testRequest,
+			expectedBody: "primary",
+		},
+		{
+			name:                 "primary is slow, hedged request wins",
+			req:                  testRequest,
+			primaryDelay:         5 * time.Second,
+			expectedBody:         "hedge",
+			expectedHedged:       1,
+			expectedHedgedWins:   1,
+			expectedHedgeQueries: 1,
+		},
+		{
+			name:         "instant queries are never hedged",
+			req:          &ThanosQueryRangeRequest{Path: "/api/v1/query", Start: 0, End: 2 * hour, Step: 10 * seconds},
+			primaryDelay: 500 * time.Millisecond,
+			expectedBody: "primary",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			primary, err := newFakeRoundTripper()
+			testutil.Ok(t, err)
+			defer primary.Close()
+			primary.setHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(tc.primaryDelay):
+				case <-r.Context().Done():
+					return
+				}
+				_, _ = w.Write([]byte("primary"))
+			}))
+
+			hedge, err := newFakeRoundTripper()
+			testutil.Ok(t, err)
+			defer hedge.Close()
+			var hedgeQueries int32
+			hedge.setHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hedgeQueries, 1)
+				_, _ = w.Write([]byte("hedge"))
+			}))
+
+			h := NewHedgedRoundTripper(primary, hedge, 100*time.Millisecond, prometheus.NewRegistry()).(*hedgedRoundTripper)
+
+			ctx := user.InjectOrgID(context.Background(), "1")
+			httpReq, err := NewThanosQueryRangeCodec(true).EncodeRequest(ctx, tc.req)
+			testutil.Ok(t, err)
+
+			resp, err := h.RoundTrip(httpReq)
+			testutil.Ok(t, err)
+			body, err := ioutil.ReadAll(resp.Body)
+			testutil.Ok(t, err)
+			testutil.Ok(t, resp.Body.Close())
+
+			testutil.Equals(t, tc.expectedBody, string(body))
+			testutil.Equals(t, tc.expectedHedged, promtest.ToFloat64(h.hedgedRequests))
+			testutil.Equals(t, tc.expectedHedgedWins, promtest.ToFloat64(h.hedgedWins))
+			testutil.Equals(t, tc.expectedHedgeQueries, atomic.LoadInt32(&hedgeQueries))
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cortexproject/cortex/pkg/querier/queryrange"
+	"github.com/cortexproject/cortex/pkg/util/spanlogger"
+	"github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/weaveworks/common/httpgrpc"
+
+	queryv1 "github.com/thanos-io/thanos/pkg/api/query"
+)
+
+// queryInstantCodec is used to encode/decode Thanos instant query requests and responses.
+type queryInstantCodec struct {
+	queryrange.Codec
+	partialResponse bool
+}
+
+// NewThanosQueryInstantCodec initializes a queryInstantCodec.
+func NewThanosQueryInstantCodec(partialResponse bool) *queryInstantCodec {
+	return &queryInstantCodec{
+		Codec:           queryrange.PrometheusCodec,
+		partialResponse: partialResponse,
+	}
+}
+
+// MergeResponse returns the single response. Merging split instant queries depends on the split query,
+// so it is done by the split middleware.
+func (c queryInstantCodec) MergeResponse(responses ...queryrange.Response) (queryrange.Response, error) {
+	if len(responses) != 1 {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "cannot merge %d instant query responses", len(responses))
+	}
+	return responses[0], nil
+}
+
+func (c queryInstantCodec) DecodeRequest(_ context.Context, r *http.Request, _ []string) (queryrange.Request, error) {
+	var (
+		result ThanosQueryInstantRequest
+		err    error
+	)
+	// Queries without time are evaluated now, which has to be fixed here so all split queries use the same time.
+	result.Time, err = parseTimeParam(r, "time", time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	result.Dedup, err = parseEnableDedupParam(r.FormValue(queryv1.DedupParam))
+	if err != nil {
+		return nil, err
+	}
+
+	result.MaxSourceResolution, err = parseDownsamplingParamMillis(r.FormValue(queryv1.MaxSourceResolutionParam))
+	if err != nil {
+		return nil, err
+	}
+
+	result.PartialResponse, err = parsePartialResponseParam(r.FormValue(queryv1.PartialResponseParam), c.partialResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r.Form[queryv1.ReplicaLabelsParam]) > 0 {
+		result.ReplicaLabels = r.Form[queryv1.ReplicaLabelsParam]
+	}
+
+	result.StoreMatchers, err = parseMatchersParam(r.Form, queryv1.StoreMatcherParam)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Query = r.FormValue("query")
+	result.Path = r.URL.Path
+
+	result.Shard, err = parseShardParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range r.Header.Values(cacheControlHeader) {
+		if strings.Contains(value, noStoreValue) {
+			result.CachingOptions.Disabled = true
+			break
+		}
+	}
+
+	return &result, nil
+}
+
+func (c queryInstantCodec) EncodeRequest(ctx context.Context, r queryrange.Request) (*http.Request, error) {
+	thanosReq, ok := r.(*ThanosQueryInstantRequest)
+	if !ok {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, "invalid request format")
+	}
+	params := url.Values{
+		"time":                       []string{encodeTime(thanosReq.Time)},
+		"query":                      []string{thanosReq.Query},
+		queryv1.DedupParam:           []string{strconv.FormatBool(thanosReq.Dedup)},
+		queryv1.PartialResponseParam: []string{strconv.FormatBool(thanosReq.PartialResponse)},
+		queryv1.ReplicaLabelsParam:   thanosReq.ReplicaLabels,
+	}
+
+	if thanosReq.MaxSourceResolution != 0 {
+		params[queryv1.MaxSourceResolutionParam] = []string{encodeDurationMillis(thanosReq.MaxSourceResolution)}
+	}
+
+	if len(thanosReq.StoreMatchers) > 0 {
+		params[queryv1.StoreMatcherParam] = matchersToStringSlice(thanosReq.StoreMatchers)
+	}
+
+	encodeShardParams(params, thanosReq.Shard)
+
+	req, err := http.NewRequest(http.MethodPost, thanosReq.Path, bytes.NewBufferString(params.Encode()))
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, "error creating request: %s", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req.WithContext(ctx), nil
+}
+
+func (c queryInstantCodec) DecodeResponse(ctx context.Context, r *http.Response, _ queryrange.Request) (queryrange.Response, error) {
+	if r.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(r.Body)
+		return nil, httpgrpc.Errorf(r.StatusCode, string(body))
+	}
+	log, _ := spanlogger.New(ctx, "ParseQueryResponse") //nolint:ineffassign,staticcheck
+	defer log.Finish()
+
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error(err) //nolint:errcheck
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error decoding response: %v", err)
+	}
+
+	log.LogFields(otlog.Int("bytes", len(buf)))
+
+	var resp ThanosQueryInstantResponse
+	if err := json.Unmarshal(buf, &resp); err != nil {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error decoding response: %v", err)
+	}
+	for h, hv := range r.Header {
+		resp.Headers = append(resp.Headers, &queryrange.PrometheusResponseHeader{Name: h, Values: hv})
+	}
+	return &resp, nil
+}
+
+func (c queryInstantCodec) EncodeResponse(ctx context.Context, res queryrange.Response) (*http.Response, error) {
+	sp, _ := opentracing.StartSpanFromContext(ctx, "APIResponse.ToHTTPResponse")
+	defer sp.Finish()
+
+	resp, ok := res.(*ThanosQueryInstantResponse)
+	if !ok {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "invalid response format")
+	}
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error encoding response: %v", err)
+	}
+
+	sp.LogFields(otlog.Int("bytes", len(b)))
+	return &http.Response{
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+		},
+		Body:       ioutil.NopCloser(bytes.NewBuffer(b)),
+		StatusCode: http.StatusOK,
+	}, nil
+}
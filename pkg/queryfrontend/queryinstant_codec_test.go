@@ -0,0 +1,77 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	queryv1 "github.com/thanos-io/thanos/pkg/api/query"
+	"github.com/thanos-io/thanos/pkg/query"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestQueryInstantCodec_EncodeAndDecodeRequest(t *testing.T) {
+	codec := NewThanosQueryInstantCodec(true)
+	ctx := context.TODO()
+
+	req := &ThanosQueryInstantRequest{
+		Path:                "/api/v1/query",
+		Time:                123000,
+		Query:               "up",
+		Dedup:               true,
+		MaxSourceResolution: 300000,
+		ReplicaLabels:       []string{"replica"},
+		StoreMatchers:       [][]*labels.Matcher{{labels.MustNewMatcher(labels.MatchEqual, "cluster", "a")}},
+		Shard:               &query.ShardInfo{Index: 1, Total: 3, By: []string{"job"}},
+	}
+	httpReq, err := codec.EncodeRequest(ctx, req)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "123", httpReq.FormValue("time"))
+	testutil.Equals(t, "300", httpReq.FormValue(queryv1.MaxSourceResolutionParam))
+	testutil.Equals(t, "false", httpReq.FormValue(queryv1.PartialResponseParam))
+	testutil.Equals(t, "1", httpReq.FormValue(queryv1.ShardIndexParam))
+	testutil.Equals(t, "3", httpReq.FormValue(queryv1.ShardCountParam))
+
+	decoded, err := codec.DecodeRequest(ctx, httpReq, nil)
+	testutil.Ok(t, err)
+	testutil.Equals(t, req, decoded)
+
+	// Requests without time are evaluated now.
+	httpReq, err = http.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+	testutil.Ok(t, err)
+	decoded, err = codec.DecodeRequest(ctx, httpReq, nil)
+	testutil.Ok(t, err)
+	testutil.Assert(t, decoded.(*ThanosQueryInstantRequest).Time > 0, "expected time to be set")
+	testutil.Assert(t, decoded.(*ThanosQueryInstantRequest).PartialResponse, "expected default partial response")
+}
+
+func TestQueryInstantCodec_DecodeAndEncodeResponse(t *testing.T) {
+	codec := NewThanosQueryInstantCodec(true)
+	ctx := context.TODO()
+
+	// Results of any type are passed through as is.
+	for _, body := range []string{
+		`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"a"},"value":[1,"1"]}]}}`,
+		`{"status":"success","data":{"resultType":"scalar","result":[1,"1"]},"warnings":["partial"]}`,
+		`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"job":"a"},"values":[[1,"1"]]}]}}`,
+	} {
+		resp, err := codec.DecodeResponse(ctx, &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil)
+		testutil.Ok(t, err)
+
+		httpResp, err := codec.EncodeResponse(ctx, resp)
+		testutil.Ok(t, err)
+		b, err := ioutil.ReadAll(httpResp.Body)
+		testutil.Ok(t, err)
+		testutil.Equals(t, body, string(b))
+	}
+}
@@ -6,6 +6,8 @@ package queryfrontend
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"io/ioutil"
 	"math"
 	"net/http"
 	"net/url"
@@ -21,6 +23,7 @@ import (
 	"github.com/weaveworks/common/httpgrpc"
 
 	queryv1 "github.com/thanos-io/thanos/pkg/api/query"
+	"github.com/thanos-io/thanos/pkg/query"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 )
 
@@ -30,6 +33,11 @@ const (
 
 	// Value that cacheControlHeader has if the response indicates that the results should not be cached.
 	noStoreValue = "no-store"
+
+	// AlignRangeWithStepParam is the parameter which disables the step alignment of a range query when set to false.
+	AlignRangeWithStepParam = "align_range_with_step"
+	// AlignRangeWithStepHeader is the header which disables the step alignment of a range query when set to false.
+	AlignRangeWithStepHeader = "X-Thanos-Align-Range-With-Step"
 )
 
 var (
@@ -116,6 +124,17 @@ func (c queryRangeCodec) DecodeRequest(_ context.Context, r *http.Request, _ []s
 		return nil, err
 	}
 
+	result.NoStepAlign, err = parseNoStepAlign(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Shard, err = parseShardParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Stats = r.FormValue(queryv1.Stats)
 	result.Query = r.FormValue("query")
 	result.Path = r.URL.Path
 
@@ -156,6 +175,12 @@ func (c queryRangeCodec) EncodeRequest(ctx context.Context, r queryrange.Request
 		params[queryv1.StoreMatcherParam] = matchersToStringSlice(thanosReq.StoreMatchers)
 	}
 
+	if thanosReq.Stats != "" {
+		params[queryv1.Stats] = []string{thanosReq.Stats}
+	}
+
+	encodeShardParams(params, thanosReq.Shard)
+
 	req, err := http.NewRequest(http.MethodPost, thanosReq.Path, bytes.NewBufferString(params.Encode()))
 	if err != nil {
 		return nil, httpgrpc.Errorf(http.StatusBadRequest, "error creating request: %s", err.Error())
@@ -165,6 +190,73 @@ func (c queryRangeCodec) EncodeRequest(ctx context.Context, r queryrange.Request
 	return req.WithContext(ctx), nil
 }
 
+// DecodeResponse decodes the response of a downstream querier. If query statistics were requested,
+// they are kept with the response instead of being dropped.
+func (c queryRangeCodec) DecodeResponse(ctx context.Context, r *http.Response, req queryrange.Request) (queryrange.Response, error) {
+	thanosReq, ok := req.(*ThanosQueryRangeRequest)
+	if !ok || thanosReq.Stats == "" || r.StatusCode/100 != 2 {
+		return c.Codec.DecodeResponse(ctx, r, req)
+	}
+
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error decoding response: %v", err)
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(buf))
+
+	resp, err := c.Codec.DecodeResponse(ctx, r, req)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := decodeStats(buf)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error decoding response stats: %v", err)
+	}
+	if stats == nil {
+		return resp, nil
+	}
+	return &queryRangeResponseWithStats{PrometheusResponse: resp.(*queryrange.PrometheusResponse), Stats: stats}, nil
+}
+
+// MergeResponse merges the responses of split or sharded queries, including their query statistics.
+func (c queryRangeCodec) MergeResponse(responses ...queryrange.Response) (queryrange.Response, error) {
+	var stats map[string]interface{}
+	promResponses := make([]queryrange.Response, 0, len(responses))
+	for _, res := range responses {
+		if sr, ok := res.(*queryRangeResponseWithStats); ok {
+			stats = mergeStats(stats, sr.Stats)
+		}
+		promResponses = append(promResponses, promResponse(res))
+	}
+
+	resp, err := c.Codec.MergeResponse(promResponses...)
+	if err != nil || stats == nil {
+		return resp, err
+	}
+	return &queryRangeResponseWithStats{PrometheusResponse: resp.(*queryrange.PrometheusResponse), Stats: stats}, nil
+}
+
+// EncodeResponse encodes the response for the client, including the query statistics if there are any.
+func (c queryRangeCodec) EncodeResponse(ctx context.Context, res queryrange.Response) (*http.Response, error) {
+	sr, ok := res.(*queryRangeResponseWithStats)
+	if !ok {
+		return c.Codec.EncodeResponse(ctx, res)
+	}
+
+	b, err := json.Marshal(sr)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error encoding response: %v", err)
+	}
+	return &http.Response{
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+		},
+		Body:          ioutil.NopCloser(bytes.NewBuffer(b)),
+		StatusCode:    http.StatusOK,
+		ContentLength: int64(len(b)),
+	}, nil
+}
+
 func parseDurationMillis(s string) (int64, error) {
 	if d, err := strconv.ParseFloat(s, 64); err == nil {
 		ts := d * float64(time.Second/time.Millisecond)
@@ -221,6 +313,56 @@ func parsePartialResponseParam(s string, defaultEnablePartialResponse bool) (boo
 	return defaultEnablePartialResponse, nil
 }
 
+// parseNoStepAlign returns true if the request opted out of step alignment with either the parameter or the header.
+// The parameter is checked before the header.
+func parseNoStepAlign(r *http.Request) (bool, error) {
+	for _, v := range []struct{ name, value string }{
+		{name: AlignRangeWithStepParam, value: r.FormValue(AlignRangeWithStepParam)},
+		{name: AlignRangeWithStepHeader, value: r.Header.Get(AlignRangeWithStepHeader)},
+	} {
+		if v.value == "" {
+			continue
+		}
+		align, err := strconv.ParseBool(v.value)
+		if err != nil {
+			return false, httpgrpc.Errorf(http.StatusBadRequest, errCannotParse, v.name)
+		}
+		if !align {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseShardParams returns the shard of the series the request selects, or nil if it isn't sharded.
+func parseShardParams(r *http.Request) (*query.ShardInfo, error) {
+	if r.FormValue(queryv1.ShardCountParam) == "" {
+		return nil, nil
+	}
+	total, err := strconv.Atoi(r.FormValue(queryv1.ShardCountParam))
+	if err != nil || total < 1 {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, errCannotParse, queryv1.ShardCountParam)
+	}
+	index, err := strconv.Atoi(r.FormValue(queryv1.ShardIndexParam))
+	if err != nil || index < 0 || index >= total {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, errCannotParse, queryv1.ShardIndexParam)
+	}
+	if len(r.Form[queryv1.ShardByParam]) == 0 {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, errCannotParse, queryv1.ShardByParam)
+	}
+	return &query.ShardInfo{Index: index, Total: total, By: r.Form[queryv1.ShardByParam]}, nil
+}
+
+// encodeShardParams adds the parameters of the shard to params, if the request is sharded.
+func encodeShardParams(params url.Values, shard *query.ShardInfo) {
+	if shard == nil {
+		return
+	}
+	params[queryv1.ShardIndexParam] = []string{strconv.Itoa(shard.Index)}
+	params[queryv1.ShardCountParam] = []string{strconv.Itoa(shard.Total)}
+	params[queryv1.ShardByParam] = shard.By
+}
+
 func parseMatchersParam(ss url.Values, matcherParam string) ([][]*labels.Matcher, error) {
 	matchers := make([][]*labels.Matcher, 0, len(ss[matcherParam]))
 	for _, s := range ss[matcherParam] {
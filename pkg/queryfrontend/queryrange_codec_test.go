@@ -6,7 +6,9 @@ package queryfrontend
 
 import (
 	"context"
+	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/cortexproject/cortex/pkg/querier/queryrange"
@@ -15,6 +17,7 @@ import (
 
 	queryv1 "github.com/thanos-io/thanos/pkg/api/query"
 	"github.com/thanos-io/thanos/pkg/compact"
+	"github.com/thanos-io/thanos/pkg/query"
 	"github.com/thanos-io/thanos/pkg/testutil"
 )
 
@@ -22,6 +25,7 @@ func TestQueryRangeCodec_DecodeRequest(t *testing.T) {
 	for _, tc := range []struct {
 		name            string
 		url             string
+		header          http.Header
 		partialResponse bool
 		expectedError   error
 		expectedRequest *ThanosQueryRangeRequest
@@ -166,10 +170,92 @@ func TestQueryRangeCodec_DecodeRequest(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:            "cannot parse align_range_with_step",
+			url:             "/api/v1/query_range?start=123&end=456&step=1&align_range_with_step=bar",
+			partialResponse: false,
+			expectedError:   httpgrpc.Errorf(http.StatusBadRequest, "cannot parse parameter align_range_with_step"),
+		},
+		{
+			name:            "step alignment disabled with parameter",
+			url:             "/api/v1/query_range?start=123&end=456&step=1&align_range_with_step=false",
+			partialResponse: false,
+			expectedRequest: &ThanosQueryRangeRequest{
+				Path:          "/api/v1/query_range",
+				Start:         123000,
+				End:           456000,
+				Step:          1000,
+				Dedup:         true,
+				StoreMatchers: [][]*labels.Matcher{},
+				NoStepAlign:   true,
+			},
+		},
+		{
+			name:            "step alignment disabled with header",
+			url:             "/api/v1/query_range?start=123&end=456&step=1",
+			header:          http.Header{AlignRangeWithStepHeader: []string{"false"}},
+			partialResponse: false,
+			expectedRequest: &ThanosQueryRangeRequest{
+				Path:          "/api/v1/query_range",
+				Start:         123000,
+				End:           456000,
+				Step:          1000,
+				Dedup:         true,
+				StoreMatchers: [][]*labels.Matcher{},
+				NoStepAlign:   true,
+			},
+		},
+		{
+			name:            "step alignment disabled with header and enabled with parameter",
+			url:             "/api/v1/query_range?start=123&end=456&step=1&align_range_with_step=true",
+			header:          http.Header{AlignRangeWithStepHeader: []string{"false"}},
+			partialResponse: false,
+			expectedRequest: &ThanosQueryRangeRequest{
+				Path:          "/api/v1/query_range",
+				Start:         123000,
+				End:           456000,
+				Step:          1000,
+				Dedup:         true,
+				StoreMatchers: [][]*labels.Matcher{},
+				NoStepAlign:   true,
+			},
+		},
+		{
+			name:            "step alignment disabled with parameter is checked before invalid header",
+			url:             "/api/v1/query_range?start=123&end=456&step=1&align_range_with_step=false",
+			header:          http.Header{AlignRangeWithStepHeader: []string{"bar"}},
+			partialResponse: false,
+			expectedRequest: &ThanosQueryRangeRequest{
+				Path:          "/api/v1/query_range",
+				Start:         123000,
+				End:           456000,
+				Step:          1000,
+				Dedup:         true,
+				StoreMatchers: [][]*labels.Matcher{},
+				NoStepAlign:   true,
+			},
+		},
+		{
+			name:            "invalid parameter is reported before invalid header",
+			url:             "/api/v1/query_range?start=123&end=456&step=1&align_range_with_step=foo",
+			header:          http.Header{AlignRangeWithStepHeader: []string{"bar"}},
+			partialResponse: false,
+			expectedError:   httpgrpc.Errorf(http.StatusBadRequest, "cannot parse parameter align_range_with_step"),
+		},
+		{
+			name:            "cannot parse header",
+			url:             "/api/v1/query_range?start=123&end=456&step=1&align_range_with_step=true",
+			header:          http.Header{AlignRangeWithStepHeader: []string{"bar"}},
+			partialResponse: false,
+			expectedError:   httpgrpc.Errorf(http.StatusBadRequest, "cannot parse parameter X-Thanos-Align-Range-With-Step"),
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			r, err := http.NewRequest(http.MethodGet, tc.url, nil)
 			testutil.Ok(t, err)
+			for k, v := range tc.header {
+				r.Header[k] = v
+			}
 
 			codec := NewThanosQueryRangeCodec(tc.partialResponse)
 			req, err := codec.DecodeRequest(context.Background(), r, nil)
@@ -208,6 +294,20 @@ func TestQueryRangeCodec_EncodeRequest(t *testing.T) {
 					r.FormValue("step") == "1"
 			},
 		},
+		{
+			name: "Shard set",
+			req: &ThanosQueryRangeRequest{
+				Start: 123000,
+				End:   456000,
+				Step:  1000,
+				Shard: &query.ShardInfo{Index: 1, Total: 3, By: []string{"cluster", "job"}},
+			},
+			checkFunc: func(r *http.Request) bool {
+				return r.FormValue(queryv1.ShardIndexParam) == "1" &&
+					r.FormValue(queryv1.ShardCountParam) == "3" &&
+					strings.Join(r.Form[queryv1.ShardByParam], ",") == "cluster,job"
+			},
+		},
 		{
 			name: "Dedup enabled",
 			req: &ThanosQueryRangeRequest{
@@ -268,6 +368,21 @@ func TestQueryRangeCodec_EncodeRequest(t *testing.T) {
 					r.FormValue(queryv1.MaxSourceResolutionParam) == "3600"
 			},
 		},
+		{
+			name: "Stats requested",
+			req: &ThanosQueryRangeRequest{
+				Start: 123000,
+				End:   456000,
+				Step:  1000,
+				Stats: "all",
+			},
+			checkFunc: func(r *http.Request) bool {
+				return r.FormValue("start") == "123" &&
+					r.FormValue("end") == "456" &&
+					r.FormValue("step") == "1" &&
+					r.FormValue(queryv1.Stats) == "all"
+			},
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			// Default partial response value doesn't matter when encoding requests.
@@ -283,6 +398,51 @@ func TestQueryRangeCodec_EncodeRequest(t *testing.T) {
 	}
 }
 
+func TestQueryRangeCodec_MergeResponseStats(t *testing.T) {
+	codec := NewThanosQueryRangeCodec(false)
+	ctx := context.TODO()
+	req := &ThanosQueryRangeRequest{Start: 0, End: 60000, Step: 30000, Stats: "all"}
+
+	bodies := []string{
+		`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"foo":"bar"},"values":[[0,"1"]]}],"stats":{"samples":{"totalQueryableSamples":10,"peakSamples":4},"storeBytes":100}}}`,
+		`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"foo":"bar"},"values":[[30,"2"]]}],"stats":{"samples":{"totalQueryableSamples":5,"peakSamples":7},"storeBytes":50}}}`,
+	}
+	responses := make([]queryrange.Response, 0, len(bodies))
+	for _, body := range bodies {
+		res, err := codec.DecodeResponse(ctx, &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, req)
+		testutil.Ok(t, err)
+		responses = append(responses, res)
+	}
+
+	merged, err := codec.MergeResponse(responses...)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(promResponse(merged).Data.Result))
+	testutil.Equals(t, 2, len(promResponse(merged).Data.Result[0].Samples))
+
+	httpRes, err := codec.EncodeResponse(ctx, merged)
+	testutil.Ok(t, err)
+	b, err := ioutil.ReadAll(httpRes.Body)
+	testutil.Ok(t, err)
+	stats, err := decodeStats(b)
+	testutil.Ok(t, err)
+	testutil.Equals(t, map[string]interface{}{
+		"samples":    map[string]interface{}{"totalQueryableSamples": float64(15), "peakSamples": float64(7)},
+		"storeBytes": float64(150),
+	}, stats)
+
+	// Responses of requests without stats are left untouched.
+	res, err := codec.DecodeResponse(ctx, &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(bodies[0])),
+	}, &ThanosQueryRangeRequest{Start: 0, End: 60000, Step: 30000})
+	testutil.Ok(t, err)
+	_, ok := res.(*queryrange.PrometheusResponse)
+	testutil.Assert(t, ok, "expected plain Prometheus response")
+}
+
 func BenchmarkQueryRangeCodecEncodeAndDecodeRequest(b *testing.B) {
 	codec := NewThanosQueryRangeCodec(true)
 	ctx := context.TODO()
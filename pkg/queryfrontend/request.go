@@ -11,6 +11,8 @@ import (
 	otlog "github.com/opentracing/opentracing-go/log"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/timestamp"
+
+	"github.com/thanos-io/thanos/pkg/query"
 )
 
 // TODO(yeya24): add partial result when needed.
@@ -33,6 +35,12 @@ type ThanosQueryRangeRequest struct {
 	ReplicaLabels       []string
 	StoreMatchers       [][]*labels.Matcher
 	CachingOptions      queryrange.CachingOptions
+	// NoStepAlign is true if the request opted out of aligning its start and end with its step.
+	NoStepAlign bool
+	// Stats is the value of the stats parameter, which requests query statistics from the queriers.
+	Stats string
+	// Shard is the shard of the series the query selects, if it is sharded.
+	Shard *query.ShardInfo
 }
 
 // GetStart returns the start timestamp of the request in milliseconds.
@@ -79,6 +87,11 @@ func (r *ThanosQueryRangeRequest) LogToSpan(sp opentracing.Span) {
 		otlog.Object("storeMatchers", r.StoreMatchers),
 		otlog.Bool("auto-downsampling", r.AutoDownsampling),
 		otlog.Int64("max_source_resolution (ms)", r.MaxSourceResolution),
+		otlog.Bool("no_step_align", r.NoStepAlign),
+		otlog.String("stats", r.Stats),
+	}
+	if r.Shard != nil {
+		fields = append(fields, otlog.Object("shard", *r.Shard))
 	}
 
 	sp.LogFields(fields...)
@@ -98,6 +111,83 @@ func (r *ThanosQueryRangeRequest) ProtoMessage() {}
 
 func (r *ThanosQueryRangeRequest) GetStoreMatchers() [][]*labels.Matcher { return r.StoreMatchers }
 
+// ThanosQueryInstantRequest is a request of an instant query.
+type ThanosQueryInstantRequest struct {
+	Path                string
+	Time                int64
+	Query               string
+	Dedup               bool
+	PartialResponse     bool
+	MaxSourceResolution int64
+	ReplicaLabels       []string
+	StoreMatchers       [][]*labels.Matcher
+	CachingOptions      queryrange.CachingOptions
+	// Shard is the shard of the series the query selects, if it is sharded.
+	Shard *query.ShardInfo
+}
+
+// GetStart returns the evaluation timestamp of the request in milliseconds.
+func (r *ThanosQueryInstantRequest) GetStart() int64 { return r.Time }
+
+// GetEnd returns the evaluation timestamp of the request in milliseconds.
+func (r *ThanosQueryInstantRequest) GetEnd() int64 { return r.Time }
+
+// GetStep returns 0, as instant queries have no step.
+func (r *ThanosQueryInstantRequest) GetStep() int64 { return 0 }
+
+// GetQuery returns the query of the request.
+func (r *ThanosQueryInstantRequest) GetQuery() string { return r.Query }
+
+func (r *ThanosQueryInstantRequest) GetCachingOptions() queryrange.CachingOptions {
+	return r.CachingOptions
+}
+
+// WithStartEnd clone the current request with a different evaluation timestamp, which is the end timestamp.
+func (r *ThanosQueryInstantRequest) WithStartEnd(_, end int64) queryrange.Request {
+	q := *r
+	q.Time = end
+	return &q
+}
+
+// WithQuery clone the current request with a different query.
+func (r *ThanosQueryInstantRequest) WithQuery(query string) queryrange.Request {
+	q := *r
+	q.Query = query
+	return &q
+}
+
+// LogToSpan writes information about this request to an OpenTracing span.
+func (r *ThanosQueryInstantRequest) LogToSpan(sp opentracing.Span) {
+	fields := []otlog.Field{
+		otlog.String("query", r.GetQuery()),
+		otlog.String("time", timestamp.Time(r.Time).String()),
+		otlog.Bool("dedup", r.Dedup),
+		otlog.Bool("partial_response", r.PartialResponse),
+		otlog.Object("replicaLabels", r.ReplicaLabels),
+		otlog.Object("storeMatchers", r.StoreMatchers),
+		otlog.Int64("max_source_resolution (ms)", r.MaxSourceResolution),
+	}
+	if r.Shard != nil {
+		fields = append(fields, otlog.Object("shard", *r.Shard))
+	}
+
+	sp.LogFields(fields...)
+}
+
+// Reset implements proto.Message interface required by queryrange.Request,
+// which is not used in thanos.
+func (r *ThanosQueryInstantRequest) Reset() {}
+
+// String implements proto.Message interface required by queryrange.Request,
+// which is not used in thanos.
+func (r *ThanosQueryInstantRequest) String() string { return "" }
+
+// ProtoMessage implements proto.Message interface required by queryrange.Request,
+// which is not used in thanos.
+func (r *ThanosQueryInstantRequest) ProtoMessage() {}
+
+func (r *ThanosQueryInstantRequest) GetStoreMatchers() [][]*labels.Matcher { return r.StoreMatchers }
+
 type ThanosLabelsRequest struct {
 	Start           int64
 	End             int64
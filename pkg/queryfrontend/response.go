@@ -4,6 +4,7 @@
 package queryfrontend
 
 import (
+	"encoding/json"
 	"unsafe"
 
 	"github.com/cortexproject/cortex/pkg/querier/queryrange"
@@ -44,3 +45,39 @@ func (m *ThanosLabelsResponse) GetHeaders() []*queryrange.PrometheusResponseHead
 func (m *ThanosSeriesResponse) GetHeaders() []*queryrange.PrometheusResponseHeader {
 	return headersToQueryRangeHeaders(m.Headers)
 }
+
+// ThanosQueryInstantResponse is the response of an instant query. The result is kept as is, so results of any type
+// are passed through, and is only decoded when vectors of split queries are merged.
+// Unlike other responses, it is never stored in the results cache, so it doesn't need to be a protobuf message.
+type ThanosQueryInstantResponse struct {
+	Status    string                                 `json:"status"`
+	Data      ThanosQueryInstantData                 `json:"data,omitempty"`
+	ErrorType string                                 `json:"errorType,omitempty"`
+	Error     string                                 `json:"error,omitempty"`
+	Warnings  []string                               `json:"warnings,omitempty"`
+	Headers   []*queryrange.PrometheusResponseHeader `json:"-"`
+}
+
+// ThanosQueryInstantData is the data of an instant query response.
+type ThanosQueryInstantData struct {
+	ResultType string          `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+	Stats      json.RawMessage `json:"stats,omitempty"`
+}
+
+// GetHeaders returns the HTTP headers in the response.
+func (m *ThanosQueryInstantResponse) GetHeaders() []*queryrange.PrometheusResponseHeader {
+	return m.Headers
+}
+
+// Reset implements proto.Message interface required by queryrange.Response,
+// which is not used in thanos.
+func (m *ThanosQueryInstantResponse) Reset() {}
+
+// String implements proto.Message interface required by queryrange.Response,
+// which is not used in thanos.
+func (m *ThanosQueryInstantResponse) String() string { return "" }
+
+// ProtoMessage implements proto.Message interface required by queryrange.Response,
+// which is not used in thanos.
+func (m *ThanosQueryInstantResponse) ProtoMessage() {}
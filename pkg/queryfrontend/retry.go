@@ -0,0 +1,187 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// This is a modified copy from
+// https://github.com/cortexproject/cortex/blob/master/pkg/querier/queryrange/retry.go.
+
+package queryfrontend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cortexproject/cortex/pkg/querier/queryrange"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/httpgrpc"
+)
+
+// retryBudgetWindow is the period over which requests and retries are accounted by the retry budget.
+const retryBudgetWindow = 10 * time.Second
+
+// RetryBudget bounds the number of retries issued across all requests. Within a sliding window, retries
+// can't exceed the given ratio of requests plus a minimum number of retries per second, so that a failing
+// downstream does not get amplified load from every in-flight request retrying at the same time.
+type RetryBudget struct {
+	ratio        float64
+	minPerSecond float64
+	now          func() time.Time
+
+	mtx sync.Mutex
+	// Counters of the current and the previous window, used to estimate the sliding window.
+	windowStart               time.Time
+	requests, retries         float64
+	prevRequests, prevRetries float64
+}
+
+// NewRetryBudget returns a RetryBudget allowing ratio retries per request and at least minPerSecond retries per second.
+// A nil RetryBudget is returned if both values are 0, which means retries are not bounded globally.
+func NewRetryBudget(ratio, minPerSecond float64) *RetryBudget {
+	if ratio <= 0 && minPerSecond <= 0 {
+		return nil
+	}
+	return &RetryBudget{ratio: ratio, minPerSecond: minPerSecond, now: time.Now}
+}
+
+// rotate moves the windows forward. Must be called with the lock held.
+func (b *RetryBudget) rotate(now time.Time) {
+	if b.windowStart.IsZero() {
+		b.windowStart = now
+		return
+	}
+	elapsed := now.Sub(b.windowStart)
+	if elapsed < retryBudgetWindow {
+		return
+	}
+	if elapsed < 2*retryBudgetWindow {
+		b.prevRequests, b.prevRetries = b.requests, b.retries
+	} else {
+		b.prevRequests, b.prevRetries = 0, 0
+	}
+	b.requests, b.retries = 0, 0
+	b.windowStart = now.Add(-(elapsed % retryBudgetWindow))
+}
+
+// deposit records a request in the budget.
+func (b *RetryBudget) deposit() {
+	if b == nil {
+		return
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.rotate(b.now())
+	b.requests++
+}
+
+// withdraw returns true and records a retry if the budget allows one more retry.
+func (b *RetryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	now := b.now()
+	b.rotate(now)
+
+	// Weight the previous window by how much of it still overlaps the sliding window.
+	weight := 1 - float64(now.Sub(b.windowStart))/float64(retryBudgetWindow)
+	requests := b.requests + weight*b.prevRequests
+	retries := b.retries + weight*b.prevRetries
+
+	allowed := b.ratio*requests + b.minPerSecond*retryBudgetWindow.Seconds()
+	if retries+1 > allowed {
+		return false
+	}
+	b.retries++
+	return true
+}
+
+type retryMiddlewareMetrics struct {
+	retriesCount    prometheus.Histogram
+	budgetExhausted prometheus.Counter
+}
+
+func newRetryMiddlewareMetrics(reg prometheus.Registerer) *retryMiddlewareMetrics {
+	return &retryMiddlewareMetrics{
+		retriesCount: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Namespace: "cortex",
+			Name:      "query_frontend_retries",
+			Help:      "Number of times a request is retried.",
+			Buckets:   []float64{0, 1, 2, 3, 4, 5},
+		}),
+		budgetExhausted: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "thanos",
+			Name:      "query_frontend_retry_budget_exhausted_total",
+			Help:      "Total number of retries which were not issued because the retry budget was exhausted.",
+		}),
+	}
+}
+
+// RetryMiddleware returns a middleware that retries requests if they fail with 5xx or a non-HTTP error.
+// Each request is tried at most maxRetries times, and every retry must be allowed by the given budget.
+func RetryMiddleware(logger log.Logger, maxRetries int, budget *RetryBudget, reg prometheus.Registerer) queryrange.Middleware {
+	metrics := newRetryMiddlewareMetrics(reg)
+	return queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
+		return retry{
+			logger:     logger,
+			next:       next,
+			maxRetries: maxRetries,
+			budget:     budget,
+			metrics:    metrics,
+		}
+	})
+}
+
+type retry struct {
+	logger     log.Logger
+	next       queryrange.Handler
+	maxRetries int
+	budget     *RetryBudget
+
+	metrics *retryMiddlewareMetrics
+}
+
+func (r retry) Do(ctx context.Context, req queryrange.Request) (queryrange.Response, error) {
+	tries := 0
+	defer func() { r.metrics.retriesCount.Observe(float64(tries)) }()
+
+	r.budget.deposit()
+
+	var lastErr error
+	for ; tries < r.maxRetries; tries++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if tries > 0 && !r.budget.withdraw() {
+			r.metrics.budgetExhausted.Inc()
+			level.Warn(r.logger).Log("msg", "retry budget exhausted, not retrying request", "try", tries, "err", lastErr)
+			return nil, lastErr
+		}
+
+		resp, err := r.next.Do(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+
+		// Retry if we get a HTTP 5xx or a non-HTTP error.
+		httpResp, ok := httpgrpc.HTTPResponseFromError(err)
+		if !ok || httpResp.Code/100 == 5 {
+			lastErr = err
+			level.Error(r.logger).Log("msg", "error processing request", "try", tries, "err", err)
+			continue
+		}
+
+		return nil, err
+	}
+	return nil, lastErr
+}
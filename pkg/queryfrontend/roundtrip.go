@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	cortexcache "github.com/cortexproject/cortex/pkg/chunk/cache"
 	"github.com/cortexproject/cortex/pkg/querier/queryrange"
 	"github.com/cortexproject/cortex/pkg/util/validation"
 
@@ -47,37 +48,48 @@ func NewTripperware(config Config, reg prometheus.Registerer, logger log.Logger)
 		}
 	}
 
+	// Retry budget is shared by all tripperwares to bound the total number of retries sent downstream.
+	retryBudget := NewRetryBudget(config.RetryBudgetRatio, config.RetryBudgetMinPerSecond)
+
 	queryRangeCodec := NewThanosQueryRangeCodec(config.QueryRangeConfig.PartialResponseStrategy)
+	queryInstantCodec := NewThanosQueryInstantCodec(config.QueryRangeConfig.PartialResponseStrategy)
 	labelsCodec := NewThanosLabelsCodec(config.LabelsConfig.PartialResponseStrategy, config.DefaultTimeRange)
 
-	queryRangeTripperware, err := newQueryRangeTripperware(config.QueryRangeConfig, queryRangeLimits, queryRangeCodec,
+	queryRangeTripperware, err := newQueryRangeTripperware(config.QueryRangeConfig, queryRangeLimits, queryRangeCodec, retryBudget,
 		prometheus.WrapRegistererWith(prometheus.Labels{"tripperware": "query_range"}, reg), logger)
 	if err != nil {
 		return nil, err
 	}
 
-	labelsTripperware, err := newLabelsTripperware(config.LabelsConfig, labelsLimits, labelsCodec,
+	queryInstantTripperware, err := newQueryInstantTripperware(config.QueryRangeConfig, queryRangeLimits, queryInstantCodec, retryBudget,
+		prometheus.WrapRegistererWith(prometheus.Labels{"tripperware": "query_instant"}, reg), logger)
+	if err != nil {
+		return nil, err
+	}
+
+	labelsTripperware, err := newLabelsTripperware(config.LabelsConfig, labelsLimits, labelsCodec, retryBudget,
 		prometheus.WrapRegistererWith(prometheus.Labels{"tripperware": "labels"}, reg), logger)
 	if err != nil {
 		return nil, err
 	}
 
 	return func(next http.RoundTripper) http.RoundTripper {
-		return newRoundTripper(next, queryRangeTripperware(next), labelsTripperware(next), reg)
+		return newRoundTripper(next, queryRangeTripperware(next), queryInstantTripperware(next), labelsTripperware(next), reg)
 	}, nil
 }
 
 type roundTripper struct {
-	next, queryRange, labels http.RoundTripper
+	next, queryRange, queryInstant, labels http.RoundTripper
 
 	queriesCount *prometheus.CounterVec
 }
 
-func newRoundTripper(next, queryRange, metadata http.RoundTripper, reg prometheus.Registerer) roundTripper {
+func newRoundTripper(next, queryRange, queryInstant, metadata http.RoundTripper, reg prometheus.Registerer) roundTripper {
 	r := roundTripper{
-		next:       next,
-		queryRange: queryRange,
-		labels:     metadata,
+		next:         next,
+		queryRange:   queryRange,
+		queryInstant: queryInstant,
+		labels:       metadata,
 		queriesCount: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
 			Name: "thanos_query_frontend_queries_total",
 			Help: "Total queries passing through query frontend",
@@ -96,6 +108,7 @@ func (r roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	switch op := getOperation(req); op {
 	case instantQueryOp:
 		r.queriesCount.WithLabelValues(instantQueryOp).Inc()
+		return r.queryInstant.RoundTrip(req)
 	case rangeQueryOp:
 		r.queriesCount.WithLabelValues(rangeQueryOp).Inc()
 		return r.queryRange.RoundTrip(req)
@@ -131,11 +144,12 @@ func getOperation(r *http.Request) string {
 }
 
 // newQueryRangeTripperware returns a Tripperware for range queries configured with middlewares of
-// limit, step align, downsampled, split by interval, cache requests and retry.
+// limit, step align, downsampled, split by interval, sharding by grouping labels, cache requests and retry.
 func newQueryRangeTripperware(
 	config QueryRangeConfig,
 	limits queryrange.Limits,
 	codec *queryRangeCodec,
+	retryBudget *RetryBudget,
 	reg prometheus.Registerer,
 	logger log.Logger,
 ) (queryrange.Tripperware, error) {
@@ -147,7 +161,7 @@ func newQueryRangeTripperware(
 		queryRangeMiddleware = append(
 			queryRangeMiddleware,
 			queryrange.InstrumentMiddleware("step_align", m),
-			queryrange.StepAlignMiddleware,
+			StepAlignMiddleware,
 		)
 	}
 
@@ -155,7 +169,7 @@ func newQueryRangeTripperware(
 		queryRangeMiddleware = append(
 			queryRangeMiddleware,
 			queryrange.InstrumentMiddleware("downsampled", m),
-			DownsampledMiddleware(codec, reg),
+			DownsampledMiddleware(codec, config.DownsampleResolutions, reg),
 		)
 	}
 
@@ -171,11 +185,24 @@ func newQueryRangeTripperware(
 		)
 	}
 
+	// Shards are cached on their own, so the sharding is done before caching.
+	if config.Shards > 1 {
+		queryRangeMiddleware = append(
+			queryRangeMiddleware,
+			queryrange.InstrumentMiddleware("shard_range", m),
+			RangeShardMiddleware(config.Shards, limits, codec, reg),
+		)
+	}
+
 	if config.ResultsCacheConfig != nil {
+		resultsCacheConfig, err := newResultsCacheConfig(*config.ResultsCacheConfig, config.TenantCacheConfig, reg, logger)
+		if err != nil {
+			return nil, err
+		}
 		queryCacheMiddleware, _, err := queryrange.NewResultsCacheMiddleware(
 			logger,
-			*config.ResultsCacheConfig,
-			newThanosCacheKeyGenerator(config.SplitQueriesByInterval),
+			resultsCacheConfig,
+			newThanosCacheKeyGenerator(config.SplitQueriesByInterval, config.DownsampleResolutions),
 			limits,
 			codec,
 			queryrange.PrometheusResponseExtractor{},
@@ -198,7 +225,7 @@ func newQueryRangeTripperware(
 		queryRangeMiddleware = append(
 			queryRangeMiddleware,
 			queryrange.InstrumentMiddleware("retry", m),
-			queryrange.NewRetryMiddleware(logger, config.MaxRetries, queryrange.NewRetryMiddlewareMetrics(reg)),
+			RetryMiddleware(logger, config.MaxRetries, retryBudget, reg),
 		)
 	}
 
@@ -210,12 +237,71 @@ func newQueryRangeTripperware(
 	}, nil
 }
 
+// newQueryInstantTripperware returns a Tripperware for instant queries configured with middlewares of
+// limit, split by interval with caching of the split queries, sharding by grouping labels, and retry. Instant queries
+// are passed through as is if both splitting and sharding are disabled.
+func newQueryInstantTripperware(
+	config QueryRangeConfig,
+	limits queryrange.Limits,
+	codec *queryInstantCodec,
+	retryBudget *RetryBudget,
+	reg prometheus.Registerer,
+	logger log.Logger,
+) (queryrange.Tripperware, error) {
+	if config.InstantSplitInterval <= 0 && config.InstantShards < 2 {
+		return func(next http.RoundTripper) http.RoundTripper { return next }, nil
+	}
+
+	var cache cortexcache.Cache
+	if config.ResultsCacheConfig != nil {
+		resultsCacheConfig, err := newResultsCacheConfig(*config.ResultsCacheConfig, config.TenantCacheConfig, reg, logger)
+		if err != nil {
+			return nil, err
+		}
+		cache, err = cortexcache.New(resultsCacheConfig.CacheConfig, reg, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "create instant query cache")
+		}
+	}
+
+	m := queryrange.NewInstrumentMiddlewareMetrics(reg)
+	queryInstantMiddleware := []queryrange.Middleware{
+		queryrange.NewLimitsMiddleware(limits),
+		queryrange.InstrumentMiddleware("split_instant", m),
+		InstantSplitMiddleware(config.InstantSplitInterval, limits, cache, reg),
+	}
+
+	if config.InstantShards > 1 {
+		queryInstantMiddleware = append(
+			queryInstantMiddleware,
+			queryrange.InstrumentMiddleware("shard_instant", m),
+			InstantShardMiddleware(config.InstantShards, limits, reg),
+		)
+	}
+
+	if config.MaxRetries > 0 {
+		queryInstantMiddleware = append(
+			queryInstantMiddleware,
+			queryrange.InstrumentMiddleware("retry", m),
+			RetryMiddleware(logger, config.MaxRetries, retryBudget, reg),
+		)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		rt := queryrange.NewRoundTripper(next, codec, nil, queryInstantMiddleware...)
+		return queryrange.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return rt.RoundTrip(r)
+		})
+	}, nil
+}
+
 // newLabelsTripperware returns a Tripperware for labels and series requests
 // configured with middlewares of split by interval and retry.
 func newLabelsTripperware(
 	config LabelsConfig,
 	limits queryrange.Limits,
 	codec *labelsCodec,
+	retryBudget *RetryBudget,
 	reg prometheus.Registerer,
 	logger log.Logger,
 ) (queryrange.Tripperware, error) {
@@ -235,10 +321,14 @@ func newLabelsTripperware(
 	}
 
 	if config.ResultsCacheConfig != nil {
+		resultsCacheConfig, err := newResultsCacheConfig(*config.ResultsCacheConfig, config.TenantCacheConfig, reg, logger)
+		if err != nil {
+			return nil, err
+		}
 		queryCacheMiddleware, _, err := queryrange.NewResultsCacheMiddleware(
 			logger,
-			*config.ResultsCacheConfig,
-			newThanosCacheKeyGenerator(config.SplitQueriesByInterval),
+			resultsCacheConfig,
+			newThanosCacheKeyGenerator(config.SplitQueriesByInterval, nil),
 			limits,
 			codec,
 			ThanosResponseExtractor{},
@@ -261,7 +351,7 @@ func newLabelsTripperware(
 		labelsMiddleware = append(
 			labelsMiddleware,
 			queryrange.InstrumentMiddleware("retry", m),
-			queryrange.NewRetryMiddleware(logger, config.MaxRetries, queryrange.NewRetryMiddlewareMetrics(reg)),
+			RetryMiddleware(logger, config.MaxRetries, retryBudget, reg),
 		)
 	}
 	return func(next http.RoundTripper) http.RoundTripper {
@@ -272,13 +362,53 @@ func newLabelsTripperware(
 	}, nil
 }
 
-// Don't go to response cache if StoreMatchers are set.
+// newResultsCacheConfig returns the results cache config, with the per-tenant cache injected if tenant overrides are configured,
+// and the zstd compressing cache injected if zstd compression is configured.
+func newResultsCacheConfig(
+	config queryrange.ResultsCacheConfig,
+	tenantConfig *TenantCacheConfig,
+	reg prometheus.Registerer,
+	logger log.Logger,
+) (queryrange.ResultsCacheConfig, error) {
+	if tenantConfig != nil {
+		c, err := newTenantCache(config.CacheConfig, *tenantConfig, reg, logger)
+		if err != nil {
+			return config, errors.Wrap(err, "create tenant cache")
+		}
+		config.CacheConfig.Cache = c
+	}
+	if config.Compression == CacheCompressionZstd {
+		c, err := cortexcache.New(config.CacheConfig, reg, logger)
+		if err != nil {
+			return config, errors.Wrap(err, "create cache")
+		}
+		zc, err := newZstdCache(c, logger)
+		if err != nil {
+			return config, errors.Wrap(err, "create zstd cache")
+		}
+		config.CacheConfig.Cache = zc
+		config.Compression = ""
+	}
+	return config, nil
+}
+
+// Don't go to response cache if StoreMatchers are set or the request opted out of step alignment.
 func shouldCache(r queryrange.Request) bool {
 	if thanosReq, ok := r.(ThanosRequest); ok {
 		if len(thanosReq.GetStoreMatchers()) > 0 {
 			return false
 		}
 	}
+	if tqrr, ok := r.(*ThanosQueryRangeRequest); ok {
+		// Results of unaligned requests can't be merged with cached extents of aligned ones.
+		if tqrr.NoStepAlign {
+			return false
+		}
+		// Statistics describe the evaluation of the query, so they can't be served from the cache.
+		if tqrr.Stats != "" {
+			return false
+		}
+	}
 
 	return !r.GetCachingOptions().Disabled
 }
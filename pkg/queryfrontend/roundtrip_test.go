@@ -23,6 +23,7 @@ import (
 	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/weaveworks/common/user"
 
+	"github.com/thanos-io/thanos/pkg/query"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/testutil"
 )
@@ -228,6 +229,81 @@ func TestRoundTripRetryMiddleware(t *testing.T) {
 	}
 }
 
+// TestRoundTripRetryBudget tests that the retry budget is shared by all requests.
+func TestRoundTripRetryBudget(t *testing.T) {
+	testRequest := &ThanosQueryRangeRequest{
+		Path:  "/api/v1/query_range",
+		Start: 0,
+		End:   2 * hour,
+		Step:  10 * seconds,
+	}
+	testLabelsRequest := &ThanosLabelsRequest{Path: "/api/v1/labels", Start: 0, End: 2 * hour}
+
+	tpw, err := NewTripperware(
+		Config{
+			QueryRangeConfig: QueryRangeConfig{
+				MaxRetries:             3,
+				Limits:                 defaultLimits,
+				SplitQueriesByInterval: day,
+			},
+			LabelsConfig: LabelsConfig{
+				MaxRetries:             3,
+				Limits:                 defaultLimits,
+				SplitQueriesByInterval: day,
+			},
+			// Allow 3 retries in total within the budget window.
+			RetryBudgetMinPerSecond: 0.3,
+		}, nil, log.NewNopLogger(),
+	)
+	testutil.Ok(t, err)
+
+	for _, tc := range []struct {
+		name        string
+		req         queryrange.Request
+		codec       queryrange.Codec
+		handlerFunc func(fail bool) (*int, http.Handler)
+		expected    int
+	}{
+		{
+			name:        "query range request uses 2 retries, 1 retry left in the budget",
+			req:         testRequest,
+			codec:       NewThanosQueryRangeCodec(true),
+			handlerFunc: promqlResults,
+			expected:    3,
+		},
+		{
+			name:        "labels request takes the remaining retry from the same budget",
+			req:         testLabelsRequest,
+			codec:       NewThanosLabelsCodec(true, 2*time.Hour),
+			handlerFunc: labelsResults,
+			expected:    2,
+		},
+		{
+			name:        "budget exhausted, no retries",
+			req:         testRequest,
+			codec:       NewThanosQueryRangeCodec(true),
+			handlerFunc: promqlResults,
+			expected:    1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rt, err := newFakeRoundTripper()
+			testutil.Ok(t, err)
+			defer rt.Close()
+			res, handler := tc.handlerFunc(true)
+			rt.setHandler(handler)
+
+			ctx := user.InjectOrgID(context.Background(), "1")
+			httpReq, err := tc.codec.EncodeRequest(ctx, tc.req)
+			testutil.Ok(t, err)
+
+			_, err = tpw(rt).RoundTrip(httpReq)
+			testutil.NotOk(t, err)
+			testutil.Equals(t, tc.expected, *res)
+		})
+	}
+}
+
 // TestRoundTripSplitIntervalMiddleware tests the split interval middleware.
 func TestRoundTripSplitIntervalMiddleware(t *testing.T) {
 	testRequest := &ThanosQueryRangeRequest{
@@ -499,6 +575,74 @@ func TestRoundTripQueryRangeCacheMiddleware(t *testing.T) {
 	}
 }
 
+// TestRoundTripQueryRangeShardCacheMiddleware tests that the results of the shards of range queries are cached on their own.
+func TestRoundTripQueryRangeShardCacheMiddleware(t *testing.T) {
+	testRequest := &ThanosQueryRangeRequest{
+		Path:  "/api/v1/query_range",
+		Start: 0,
+		End:   2 * hour,
+		Step:  10 * seconds,
+		Query: "sum by (job) (up)",
+	}
+	shardRequest := func(index, total int) *ThanosQueryRangeRequest {
+		r := *testRequest
+		r.Shard = &query.ShardInfo{Index: index, Total: total, By: []string{"job"}}
+		return &r
+	}
+
+	cacheConf := &queryrange.ResultsCacheConfig{
+		CacheConfig: cortexcache.Config{
+			EnableFifoCache: true,
+			Fifocache: cortexcache.FifoCacheConfig{
+				MaxSizeBytes: "1MiB",
+				MaxSizeItems: 1000,
+				Validity:     time.Hour,
+			},
+		},
+	}
+
+	tpw, err := NewTripperware(
+		Config{
+			QueryRangeConfig: QueryRangeConfig{
+				Limits:                 defaultLimits,
+				ResultsCacheConfig:     cacheConf,
+				SplitQueriesByInterval: day,
+				Shards:                 3,
+			},
+		}, nil, log.NewNopLogger(),
+	)
+	testutil.Ok(t, err)
+
+	rt, err := newFakeRoundTripper()
+	testutil.Ok(t, err)
+	defer rt.Close()
+	res, handler := promqlResults(false)
+	rt.setHandler(handler)
+
+	for _, tc := range []struct {
+		name     string
+		req      queryrange.Request
+		expected int
+	}{
+		{name: "first request, one request per shard", req: testRequest, expected: 3},
+		{name: "same request as the first one, directly use cache", req: testRequest, expected: 3},
+		{name: "request of one of the shards, directly use cache", req: shardRequest(1, 3), expected: 3},
+		{name: "request of a shard of another shard count", req: shardRequest(1, 2), expected: 4},
+		{name: "same request as the previous one, directly use cache", req: shardRequest(1, 2), expected: 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := user.InjectOrgID(context.Background(), "1")
+			httpReq, err := NewThanosQueryRangeCodec(true).EncodeRequest(ctx, tc.req)
+			testutil.Ok(t, err)
+
+			_, err = tpw(rt).RoundTrip(httpReq)
+			testutil.Ok(t, err)
+
+			testutil.Equals(t, tc.expected, *res)
+		})
+	}
+}
+
 // TestRoundTripLabelsCacheMiddleware tests the cache middleware for labels requests.
 func TestRoundTripLabelsCacheMiddleware(t *testing.T) {
 	testRequest := &ThanosLabelsRequest{
@@ -641,6 +785,16 @@ func TestRoundTripSeriesCacheMiddleware(t *testing.T) {
 		Matchers: [][]*labels.Matcher{{labels.MustNewMatcher(labels.MatchEqual, "foo", "baz")}},
 	}
 
+	// Same query params as testRequest, but with dedup enabled.
+	testRequestDedup := &ThanosSeriesRequest{
+		Path:          "/api/v1/series",
+		Start:         0,
+		End:           2 * hour,
+		Dedup:         true,
+		ReplicaLabels: []string{"replica"},
+		Matchers:      [][]*labels.Matcher{{labels.MustNewMatcher(labels.MatchEqual, "foo", "bar")}},
+	}
+
 	// Same query params as testRequest, but with storeMatchers
 	testRequestWithStoreMatchers := &ThanosLabelsRequest{
 		Path:          "/api/v1/series",
@@ -686,7 +840,9 @@ func TestRoundTripSeriesCacheMiddleware(t *testing.T) {
 		{name: "first request", req: testRequest, expected: 1},
 		{name: "same request as the first one, directly use cache", req: testRequest, expected: 1},
 		{name: "different series request, not use cache", req: testRequest2, expected: 2},
-		{name: "storeMatchers requests won't go to cache", req: testRequestWithStoreMatchers, expected: 3},
+		{name: "dedup series request, not use cache", req: testRequestDedup, expected: 3},
+		{name: "same dedup series request, use cache", req: testRequestDedup, expected: 3},
+		{name: "storeMatchers requests won't go to cache", req: testRequestWithStoreMatchers, expected: 4},
 	} {
 
 		t.Run(tc.name, func(t *testing.T) {
@@ -0,0 +1,266 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultTenant is used for requests without a tenant, matching the org ID used by the query frontend.
+const defaultTenant = "anonymous"
+
+// SchedulerConfig holds the config of the per-tenant request scheduler.
+type SchedulerConfig struct {
+	// MaxConcurrent is the maximum number of requests sent downstream concurrently. 0 disables the scheduler.
+	MaxConcurrent int
+	// MaxOutstandingPerTenant is the maximum number of queued requests of a single tenant.
+	MaxOutstandingPerTenant int
+	// Tenants holds the per-tenant overrides of the scheduling settings.
+	Tenants map[string]TenantSchedulerConfig
+}
+
+// TenantSchedulerConfig holds the scheduling settings which can be overridden per tenant.
+type TenantSchedulerConfig struct {
+	// Weight is the share of downstream capacity the tenant gets relative to other tenants with queued requests.
+	Weight int `yaml:"weight"`
+	// MaxOutstandingRequests is the maximum number of queued requests of the tenant.
+	MaxOutstandingRequests int `yaml:"max_outstanding_requests"`
+}
+
+// NewTenantSchedulerConfig parses the YAML content of per-tenant scheduling overrides.
+func NewTenantSchedulerConfig(confContentYaml []byte) (map[string]TenantSchedulerConfig, error) {
+	config := struct {
+		Tenants map[string]TenantSchedulerConfig `yaml:"tenants"`
+	}{}
+	if err := yaml.UnmarshalStrict(confContentYaml, &config); err != nil {
+		return nil, errors.Wrap(err, "parsing tenant scheduler config YAML file")
+	}
+	for tenant, c := range config.Tenants {
+		if c.Weight < 0 || c.MaxOutstandingRequests < 0 {
+			return nil, errors.Errorf("weight and max_outstanding_requests of tenant %s cannot be negative", tenant)
+		}
+	}
+	return config.Tenants, nil
+}
+
+// Scheduler is a http.RoundTripper which limits the number of concurrent downstream requests and queues
+// the others per tenant. Queued requests are dequeued with weighted fair round robin between tenants,
+// so tenants sending many requests can't starve the others.
+type Scheduler struct {
+	next http.RoundTripper
+	cfg  SchedulerConfig
+
+	mtx     sync.Mutex
+	running int
+	queues  map[string]*tenantQueue
+	// active holds the queues with queued requests, in the order they became active.
+	active []*tenantQueue
+
+	queueLength        *prometheus.GaugeVec
+	discardedRequests  *prometheus.CounterVec
+	queueDuration      prometheus.Histogram
+	concurrentRequests prometheus.Gauge
+}
+
+type tenantQueue struct {
+	tenant        string
+	weight        int
+	currentWeight int
+	maxQueued     int
+	queued        []*queuedRequest
+}
+
+type queuedRequest struct {
+	// ready is closed once the request got a slot to run.
+	ready chan struct{}
+}
+
+// NewScheduler returns a Scheduler sending requests to next. If the scheduler is disabled in cfg, next is returned.
+func NewScheduler(next http.RoundTripper, cfg SchedulerConfig, reg prometheus.Registerer) http.RoundTripper {
+	if cfg.MaxConcurrent <= 0 {
+		return next
+	}
+	return &Scheduler{
+		next:   next,
+		cfg:    cfg,
+		queues: map[string]*tenantQueue{},
+		queueLength: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thanos_query_frontend_queue_length",
+			Help: "Number of requests queued by the query frontend scheduler per tenant.",
+		}, []string{"tenant"}),
+		discardedRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_query_frontend_discarded_requests_total",
+			Help: "Total number of requests rejected by the query frontend scheduler because the tenant queue was full.",
+		}, []string{"tenant"}),
+		queueDuration: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:    "thanos_query_frontend_queue_duration_seconds",
+			Help:    "Time requests spent queued by the query frontend scheduler.",
+			Buckets: []float64{0.001, 0.01, 0.1, 0.5, 1, 5, 10, 30, 60},
+		}),
+		concurrentRequests: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_query_frontend_scheduler_running_requests",
+			Help: "Number of requests currently sent downstream by the query frontend scheduler.",
+		}),
+	}
+}
+
+func (s *Scheduler) RoundTrip(r *http.Request) (*http.Response, error) {
+	tenant, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		tenant = defaultTenant
+	}
+
+	start := time.Now()
+	if err := s.acquire(r, tenant); err != nil {
+		return nil, err
+	}
+	s.queueDuration.Observe(time.Since(start).Seconds())
+	defer s.release()
+
+	return s.next.RoundTrip(r)
+}
+
+// acquire returns once the request got a slot to run, or with an error if it was rejected or cancelled.
+func (s *Scheduler) acquire(r *http.Request, tenant string) error {
+	s.mtx.Lock()
+	if s.running < s.cfg.MaxConcurrent && len(s.active) == 0 {
+		s.running++
+		s.concurrentRequests.Set(float64(s.running))
+		s.mtx.Unlock()
+		return nil
+	}
+
+	q := s.queueFor(tenant)
+	if q.maxQueued > 0 && len(q.queued) >= q.maxQueued {
+		s.mtx.Unlock()
+		s.discardedRequests.WithLabelValues(tenant).Inc()
+		return httpgrpc.Errorf(http.StatusTooManyRequests, "too many outstanding requests for tenant %s", tenant)
+	}
+
+	qr := &queuedRequest{ready: make(chan struct{})}
+	if len(q.queued) == 0 {
+		s.active = append(s.active, q)
+	}
+	q.queued = append(q.queued, qr)
+	s.queueLength.WithLabelValues(tenant).Set(float64(len(q.queued)))
+	s.mtx.Unlock()
+
+	select {
+	case <-qr.ready:
+		return nil
+	case <-r.Context().Done():
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	select {
+	case <-qr.ready:
+		// The request got a slot in the meantime, hand it over to the next one.
+		s.running--
+		s.dispatch()
+	default:
+		s.remove(q, qr)
+	}
+	return r.Context().Err()
+}
+
+// release frees the slot of a finished request and hands it over to the next queued request.
+func (s *Scheduler) release() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.running--
+	s.dispatch()
+}
+
+// dispatch hands free slots over to queued requests. It must be called with the lock held.
+func (s *Scheduler) dispatch() {
+	for s.running < s.cfg.MaxConcurrent && len(s.active) > 0 {
+		q := s.pick()
+		qr := q.queued[0]
+		q.queued = q.queued[1:]
+		if len(q.queued) == 0 {
+			s.deactivate(q)
+		} else {
+			s.queueLength.WithLabelValues(q.tenant).Set(float64(len(q.queued)))
+		}
+
+		s.running++
+		close(qr.ready)
+	}
+	s.concurrentRequests.Set(float64(s.running))
+}
+
+// pick picks the queue to dequeue from with smooth weighted round robin. It must be called with the lock held.
+func (s *Scheduler) pick() *tenantQueue {
+	var (
+		best  *tenantQueue
+		total int
+	)
+	for _, q := range s.active {
+		q.currentWeight += q.weight
+		total += q.weight
+		if best == nil || q.currentWeight > best.currentWeight {
+			best = q
+		}
+	}
+	best.currentWeight -= total
+	return best
+}
+
+// queueFor returns the queue of the tenant, creating it if needed. It must be called with the lock held.
+func (s *Scheduler) queueFor(tenant string) *tenantQueue {
+	if q, ok := s.queues[tenant]; ok {
+		return q
+	}
+	q := &tenantQueue{tenant: tenant, weight: 1, maxQueued: s.cfg.MaxOutstandingPerTenant}
+	if c, ok := s.cfg.Tenants[tenant]; ok {
+		if c.Weight > 0 {
+			q.weight = c.Weight
+		}
+		if c.MaxOutstandingRequests > 0 {
+			q.maxQueued = c.MaxOutstandingRequests
+		}
+	}
+	s.queues[tenant] = q
+	return q
+}
+
+// remove removes a cancelled request from its queue. It must be called with the lock held.
+func (s *Scheduler) remove(q *tenantQueue, qr *queuedRequest) {
+	for i, e := range q.queued {
+		if e == qr {
+			q.queued = append(q.queued[:i], q.queued[i+1:]...)
+			break
+		}
+	}
+	if len(q.queued) == 0 {
+		s.deactivate(q)
+		return
+	}
+	s.queueLength.WithLabelValues(q.tenant).Set(float64(len(q.queued)))
+}
+
+// deactivate removes an empty queue from the active queues and drops it, so that queues and their metrics don't
+// accumulate for tenants taken from request headers. It must be called with the lock held.
+func (s *Scheduler) deactivate(q *tenantQueue) {
+	for i, e := range s.active {
+		if e == q {
+			s.active = append(s.active[:i], s.active[i+1:]...)
+			break
+		}
+	}
+	delete(s.queues, q.tenant)
+	s.queueLength.DeleteLabelValues(q.tenant)
+}
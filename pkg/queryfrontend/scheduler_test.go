@@ -0,0 +1,191 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestNewTenantSchedulerConfig(t *testing.T) {
+	conf, err := NewTenantSchedulerConfig([]byte(`
+tenants:
+  interactive:
+    weight: 5
+  batch:
+    max_outstanding_requests: 10
+`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, map[string]TenantSchedulerConfig{
+		"interactive": {Weight: 5},
+		"batch":       {MaxOutstandingRequests: 10},
+	}, conf)
+
+	_, err = NewTenantSchedulerConfig([]byte(`
+tenants:
+  batch:
+    weight: -1
+`))
+	testutil.NotOk(t, err)
+}
+
+func TestScheduler(t *testing.T) {
+	var (
+		mtx     sync.Mutex
+		order   []string
+		unblock = make(chan struct{})
+	)
+	next := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		tenant, _ := user.ExtractOrgID(r.Context())
+		if tenant == "blocker" {
+			<-unblock
+		}
+		mtx.Lock()
+		order = append(order, tenant)
+		mtx.Unlock()
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	s := NewScheduler(next, SchedulerConfig{
+		MaxConcurrent:           1,
+		MaxOutstandingPerTenant: 4,
+		Tenants:                 map[string]TenantSchedulerConfig{"a": {Weight: 3}},
+	}, prometheus.NewRegistry()).(*Scheduler)
+
+	newRequest := func(ctx context.Context, tenant string) *http.Request {
+		r, err := http.NewRequest(http.MethodGet, "/api/v1/query_range", nil)
+		testutil.Ok(t, err)
+		return r.WithContext(user.InjectOrgID(ctx, tenant))
+	}
+	queued := func() int {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		n := 0
+		for _, q := range s.queues {
+			n += len(q.queued)
+		}
+		return n
+	}
+
+	var wg sync.WaitGroup
+	roundTrip := func(r *http.Request, expectedQueued int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = s.RoundTrip(r)
+		}()
+		testutil.Ok(t, waitFor(func() bool { return queued() == expectedQueued }))
+	}
+
+	// Occupy the only slot, so the following requests are queued.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := s.RoundTrip(newRequest(context.Background(), "blocker"))
+		testutil.Ok(t, err)
+	}()
+	testutil.Ok(t, waitFor(func() bool {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		return s.running == 1
+	}))
+
+	for i := 0; i < 4; i++ {
+		roundTrip(newRequest(context.Background(), "a"), i+1)
+	}
+	for i := 0; i < 4; i++ {
+		roundTrip(newRequest(context.Background(), "b"), i+5)
+	}
+
+	// Queue of tenant b is full.
+	_, err := s.RoundTrip(newRequest(context.Background(), "b"))
+	resp, ok := httpgrpc.HTTPResponseFromError(err)
+	testutil.Assert(t, ok, "expected httpgrpc error")
+	testutil.Equals(t, int32(http.StatusTooManyRequests), resp.Code)
+
+	// Cancelled requests are removed from the queue.
+	ctx, cancel := context.WithCancel(context.Background())
+	roundTrip(newRequest(ctx, "c"), 9)
+	cancel()
+	testutil.Ok(t, waitFor(func() bool { return queued() == 8 }))
+
+	close(unblock)
+	wg.Wait()
+
+	// Tenant a gets three slots for every slot of tenant b while both have queued requests.
+	testutil.Equals(t, []string{"blocker", "a", "a", "b", "a", "a", "b", "b", "b"}, order)
+
+	// Empty queues are dropped together with their metrics.
+	testutil.Equals(t, 0, s.running)
+	testutil.Equals(t, 0, len(s.queues))
+	testutil.Equals(t, 0, promtestutil.CollectAndCount(s.queueLength))
+}
+
+func TestScheduler_CancelRacingWithDispatch(t *testing.T) {
+	next := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+	s := NewScheduler(next, SchedulerConfig{MaxConcurrent: 1}, prometheus.NewRegistry()).(*Scheduler)
+
+	newRequest := func(ctx context.Context, tenant string) *http.Request {
+		r, err := http.NewRequest(http.MethodGet, "/api/v1/query_range", nil)
+		testutil.Ok(t, err)
+		return r.WithContext(user.InjectOrgID(ctx, tenant))
+	}
+
+	// Occupy the only slot, so the next request is queued.
+	testutil.Ok(t, s.acquire(newRequest(context.Background(), "a"), "a"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.RoundTrip(newRequest(ctx, "b"))
+		errc <- err
+	}()
+	testutil.Ok(t, waitFor(func() bool {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		return len(s.active) == 1
+	}))
+
+	// Cancel the queued request, and hand it the freed slot before it takes the lock to leave the queue.
+	s.mtx.Lock()
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+	s.running--
+	s.dispatch()
+	s.mtx.Unlock()
+
+	testutil.Equals(t, context.Canceled, <-errc)
+
+	// The slot handed to the cancelled request is freed.
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	testutil.Equals(t, 0, s.running)
+	testutil.Equals(t, 0, len(s.queues))
+}
+
+func waitFor(f func() bool) error {
+	for i := 0; i < 500; i++ {
+		if f() {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return context.DeadlineExceeded
+}
@@ -0,0 +1,99 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/cortexproject/cortex/pkg/querier/queryrange"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/thanos-io/thanos/pkg/query"
+)
+
+// InstantShardMiddleware creates a new Middleware that shards instant queries of aggregations grouped by labels,
+// e.g. sum by (job) (rate(x[5m])), into the given number of queries, each selecting the series whose values of the
+// grouping labels hash to its shard, and concatenates their results.
+func InstantShardMiddleware(shards int, limits queryrange.Limits, registerer prometheus.Registerer) queryrange.Middleware {
+	return queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
+		return shardInstant{
+			next:   next,
+			limits: limits,
+			shards: shards,
+			shardCounter: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+				Namespace: "thanos",
+				Name:      "frontend_sharded_instant_queries_total",
+				Help:      "Total number of underlying instant query requests after sharding by the grouping labels is applied",
+			}),
+		}
+	})
+}
+
+type shardInstant struct {
+	next   queryrange.Handler
+	limits queryrange.Limits
+	shards int
+
+	// Metrics.
+	shardCounter prometheus.Counter
+}
+
+func (s shardInstant) Do(ctx context.Context, r queryrange.Request) (queryrange.Response, error) {
+	req, ok := r.(*ThanosQueryInstantRequest)
+	if !ok || req.Shard != nil || s.shards < 2 {
+		return s.next.Do(ctx, r)
+	}
+	by, ok := shardableBy(req.Query)
+	if !ok {
+		return s.next.Do(ctx, r)
+	}
+
+	reqs := make([]queryrange.Request, 0, s.shards)
+	for i := 0; i < s.shards; i++ {
+		shardReq := *req
+		shardReq.Shard = &query.ShardInfo{Index: i, Total: s.shards, By: by}
+		reqs = append(reqs, &shardReq)
+	}
+	s.shardCounter.Add(float64(len(reqs)))
+
+	reqResps, err := queryrange.DoRequests(ctx, s.next, reqs, s.limits)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		merged   model.Vector
+		warnings []string
+	)
+	for _, reqResp := range reqResps {
+		resp, ok := reqResp.Response.(*ThanosQueryInstantResponse)
+		if !ok || resp.Data.ResultType != model.ValVector.String() {
+			return nil, httpgrpc.Errorf(http.StatusInternalServerError, "unexpected response of sharded instant query")
+		}
+		var v model.Vector
+		if err := json.Unmarshal(resp.Data.Result, &v); err != nil {
+			return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error decoding response: %v", err)
+		}
+		// The groups of the shards are disjoint, so their results are simply concatenated.
+		merged = append(merged, v...)
+		warnings = append(warnings, resp.Warnings...)
+	}
+	sort.Sort(merged)
+
+	result, err := json.Marshal(merged)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error encoding response: %v", err)
+	}
+	return &ThanosQueryInstantResponse{
+		Status:   queryrange.StatusSuccess,
+		Data:     ThanosQueryInstantData{ResultType: model.ValVector.String(), Result: result},
+		Warnings: warnings,
+	}, nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cortexproject/cortex/pkg/querier/queryrange"
+	cortexvalidation "github.com/cortexproject/cortex/pkg/util/validation"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/pkg/query"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestInstantShardMiddleware(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		shards []query.ShardInfo
+	)
+	next := queryrange.HandlerFunc(func(_ context.Context, r queryrange.Request) (queryrange.Response, error) {
+		req := r.(*ThanosQueryInstantRequest)
+
+		var v model.Vector
+		mtx.Lock()
+		if req.Shard != nil {
+			shards = append(shards, *req.Shard)
+			v = model.Vector{{Metric: model.Metric{"job": model.LabelValue(fmt.Sprintf("%d", req.Shard.Index))}, Value: 1}}
+		}
+		mtx.Unlock()
+
+		result, err := json.Marshal(v)
+		testutil.Ok(t, err)
+		return &ThanosQueryInstantResponse{
+			Status:   queryrange.StatusSuccess,
+			Data:     ThanosQueryInstantData{ResultType: "vector", Result: result},
+			Warnings: []string{"warning"},
+		}, nil
+	})
+
+	limits, err := cortexvalidation.NewOverrides(cortexvalidation.Limits{MaxQueryParallelism: 4}, nil)
+	testutil.Ok(t, err)
+
+	h := InstantShardMiddleware(3, limits, prometheus.NewRegistry()).Wrap(next)
+	ctx := user.InjectOrgID(context.Background(), "1")
+	req := &ThanosQueryInstantRequest{Time: 1000, Query: `sum by (job) (rate(up[5m]))`}
+
+	resp, err := h.Do(ctx, req)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(shards))
+	for _, s := range shards {
+		testutil.Equals(t, 3, s.Total)
+		testutil.Equals(t, []string{"job"}, s.By)
+	}
+
+	// The results of all shards are concatenated.
+	var v model.Vector
+	testutil.Ok(t, json.Unmarshal(resp.(*ThanosQueryInstantResponse).Data.Result, &v))
+	testutil.Equals(t, model.Vector{
+		{Metric: model.Metric{"job": "0"}, Value: 1},
+		{Metric: model.Metric{"job": "1"}, Value: 1},
+		{Metric: model.Metric{"job": "2"}, Value: 1},
+	}, v)
+	testutil.Equals(t, []string{"warning", "warning", "warning"}, resp.(*ThanosQueryInstantResponse).Warnings)
+
+	// Queries which can't be sharded are passed through.
+	shards = nil
+	_, err = h.Do(ctx, req.WithQuery(`sum(rate(up[5m]))`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(shards))
+}
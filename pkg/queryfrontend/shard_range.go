@@ -0,0 +1,136 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+
+	"github.com/cortexproject/cortex/pkg/querier/queryrange"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/thanos-io/thanos/pkg/query"
+)
+
+// unshardableFuncs are the functions whose results can't be computed from the shards of the series of their
+// arguments, as they create series or change their labels.
+var unshardableFuncs = map[string]struct{}{
+	"absent":           {},
+	"absent_over_time": {},
+	"label_join":       {},
+	"label_replace":    {},
+	"scalar":           {},
+	"vector":           {},
+}
+
+// RangeShardMiddleware creates a new Middleware that shards range queries of aggregations grouped by labels,
+// e.g. sum by (job) (rate(x[5m])), into the given number of queries, each selecting the series whose values of the
+// grouping labels hash to its shard, and merges their results.
+func RangeShardMiddleware(shards int, limits queryrange.Limits, merger queryrange.Merger, registerer prometheus.Registerer) queryrange.Middleware {
+	return queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
+		return shardRange{
+			next:   next,
+			limits: limits,
+			merger: merger,
+			shards: shards,
+			shardCounter: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+				Namespace: "thanos",
+				Name:      "frontend_sharded_range_queries_total",
+				Help:      "Total number of underlying range query requests after sharding by the grouping labels is applied",
+			}),
+		}
+	})
+}
+
+type shardRange struct {
+	next   queryrange.Handler
+	limits queryrange.Limits
+	merger queryrange.Merger
+	shards int
+
+	// Metrics.
+	shardCounter prometheus.Counter
+}
+
+func (s shardRange) Do(ctx context.Context, r queryrange.Request) (queryrange.Response, error) {
+	req, ok := r.(*ThanosQueryRangeRequest)
+	if !ok || req.Shard != nil || s.shards < 2 {
+		return s.next.Do(ctx, r)
+	}
+	by, ok := shardableBy(req.Query)
+	if !ok {
+		return s.next.Do(ctx, r)
+	}
+
+	reqs := make([]queryrange.Request, 0, s.shards)
+	for i := 0; i < s.shards; i++ {
+		shardReq := *req
+		shardReq.Shard = &query.ShardInfo{Index: i, Total: s.shards, By: by}
+		reqs = append(reqs, &shardReq)
+	}
+	s.shardCounter.Add(float64(len(reqs)))
+
+	reqResps, err := queryrange.DoRequests(ctx, s.next, reqs, s.limits)
+	if err != nil {
+		return nil, err
+	}
+
+	// The series of the shards are disjoint, so merging them only concatenates and sorts them.
+	resps := make([]queryrange.Response, 0, len(reqResps))
+	for _, reqResp := range reqResps {
+		resps = append(resps, reqResp.Response)
+	}
+	return s.merger.MergeResponse(resps...)
+}
+
+// shardableBy returns the grouping labels of the query if it is an aggregation by labels whose groups only depend on
+// the series with the same values of these labels, so that it can be evaluated for each shard of them on its own.
+func shardableBy(q string) ([]string, bool) {
+	expr, err := parser.ParseExpr(q)
+	if err != nil {
+		return nil, false
+	}
+	aggr, ok := unwrapParens(expr).(*parser.AggregateExpr)
+	if !ok || aggr.Without || len(aggr.Grouping) == 0 {
+		return nil, false
+	}
+	for _, l := range aggr.Grouping {
+		// Functions drop the metric name, so series of different shards may end up in the same group.
+		if l == labels.MetricName {
+			return nil, false
+		}
+	}
+	if aggr.Param != nil {
+		switch unwrapParens(aggr.Param).(type) {
+		case *parser.NumberLiteral, *parser.StringLiteral:
+		default:
+			return nil, false
+		}
+	}
+
+	shardable := true
+	parser.Inspect(aggr.Expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.AggregateExpr:
+			// Nested aggregations may group series of different shards together.
+			shardable = false
+		case *parser.BinaryExpr:
+			// Vector matching may match series of different shards.
+			if n.LHS.Type() == parser.ValueTypeVector && n.RHS.Type() == parser.ValueTypeVector {
+				shardable = false
+			}
+		case *parser.Call:
+			if _, ok := unshardableFuncs[n.Func.Name]; ok {
+				shardable = false
+			}
+		}
+		return nil
+	})
+	if !shardable {
+		return nil, false
+	}
+	return aggr.Grouping, true
+}
@@ -0,0 +1,111 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+	"github.com/cortexproject/cortex/pkg/querier/queryrange"
+	cortexvalidation "github.com/cortexproject/cortex/pkg/util/validation"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/pkg/query"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestShardableBy(t *testing.T) {
+	for _, tc := range []struct {
+		query      string
+		expectedBy []string
+	}{
+		{query: `up`},
+		{query: `sum(rate(up[5m]))`},
+		{query: `sum without (instance) (up)`},
+		{query: `sum by (__name__) (up)`},
+		{query: `sum by (job) (up / on (instance) up)`},
+		{query: `sum by (job) (max by (job, instance) (up))`},
+		{query: `sum by (job) (label_replace(up, "job", "$1", "instance", "(.*)"))`},
+		{query: `topk by (job) (scalar(up), up)`},
+		{query: `sum by (job) (rate(up[5m]))`, expectedBy: []string{"job"}},
+		{query: `(sum(rate(up[5m])) by (job, cluster))`, expectedBy: []string{"job", "cluster"}},
+		{query: `topk by (job) (3, up * 2)`, expectedBy: []string{"job"}},
+		{query: `count_values by (job) ("value", up)`, expectedBy: []string{"job"}},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			by, ok := shardableBy(tc.query)
+			if tc.expectedBy == nil {
+				testutil.Assert(t, !ok, "expected query not to be shardable")
+				return
+			}
+			testutil.Assert(t, ok, "expected query to be shardable")
+			testutil.Equals(t, tc.expectedBy, by)
+		})
+	}
+}
+
+func TestRangeShardMiddleware(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		shards []query.ShardInfo
+	)
+	next := queryrange.HandlerFunc(func(_ context.Context, r queryrange.Request) (queryrange.Response, error) {
+		req := r.(*ThanosQueryRangeRequest)
+
+		var streams []queryrange.SampleStream
+		mtx.Lock()
+		if req.Shard != nil {
+			shards = append(shards, *req.Shard)
+			// Shards return the series in reverse order, so that the merged result has to be sorted.
+			streams = []queryrange.SampleStream{{
+				Labels:  []cortexpb.LabelAdapter{{Name: "job", Value: fmt.Sprintf("%d", 2-req.Shard.Index)}},
+				Samples: []cortexpb.Sample{{Value: 1, TimestampMs: req.Start}},
+			}}
+		}
+		mtx.Unlock()
+
+		return &queryrange.PrometheusResponse{
+			Status: queryrange.StatusSuccess,
+			Data:   queryrange.PrometheusData{ResultType: "matrix", Result: streams},
+		}, nil
+	})
+
+	limits, err := cortexvalidation.NewOverrides(cortexvalidation.Limits{MaxQueryParallelism: 4}, nil)
+	testutil.Ok(t, err)
+
+	h := RangeShardMiddleware(3, limits, NewThanosQueryRangeCodec(true), prometheus.NewRegistry()).Wrap(next)
+	ctx := user.InjectOrgID(context.Background(), "1")
+	req := &ThanosQueryRangeRequest{Start: 0, End: 60000, Step: 30000, Query: `sum by (job) (rate(up[5m]))`}
+
+	resp, err := h.Do(ctx, req)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(shards))
+	for _, s := range shards {
+		testutil.Equals(t, 3, s.Total)
+		testutil.Equals(t, []string{"job"}, s.By)
+	}
+
+	// The series of all shards are merged.
+	result := resp.(*queryrange.PrometheusResponse).Data.Result
+	testutil.Equals(t, 3, len(result))
+	for i, s := range result {
+		testutil.Equals(t, []cortexpb.LabelAdapter{{Name: "job", Value: fmt.Sprintf("%d", i)}}, s.Labels)
+	}
+
+	// Queries which can't be sharded and requests of a shard are passed through.
+	shards = nil
+	_, err = h.Do(ctx, req.WithQuery(`sum(rate(up[5m]))`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(shards))
+
+	shardReq := *req
+	shardReq.Shard = &query.ShardInfo{Index: 0, Total: 2, By: []string{"job"}}
+	_, err = h.Do(ctx, &shardReq)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []query.ShardInfo{{Index: 0, Total: 2, By: []string{"job"}}}, shards)
+}
@@ -0,0 +1,325 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	cortexcache "github.com/cortexproject/cortex/pkg/chunk/cache"
+	"github.com/cortexproject/cortex/pkg/querier/queryrange"
+	"github.com/cortexproject/cortex/pkg/tenant"
+	"github.com/cortexproject/cortex/pkg/util/validation"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/weaveworks/common/httpgrpc"
+)
+
+// instantSplitMergeOps maps the range functions, whose range can be split into smaller ranges,
+// to the aggregation which merges the results of the smaller ranges.
+var instantSplitMergeOps = map[string]parser.ItemType{
+	"sum_over_time":   parser.SUM,
+	"count_over_time": parser.SUM,
+	"max_over_time":   parser.MAX,
+	"min_over_time":   parser.MIN,
+}
+
+// InstantSplitMiddleware creates a new Middleware that splits instant queries of range functions over long ranges,
+// e.g. sum_over_time(x[30d]) or sum by (job) (sum_over_time(x[30d])), into queries over smaller ranges aligned to
+// the interval, and merges their results. Results of complete intervals are cached, if a cache is given.
+func InstantSplitMiddleware(interval time.Duration, limits queryrange.Limits, cache cortexcache.Cache, registerer prometheus.Registerer) queryrange.Middleware {
+	return queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
+		return splitInstant{
+			next:     next,
+			limits:   limits,
+			cache:    cache,
+			interval: interval.Milliseconds(),
+			splitCounter: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+				Namespace: "thanos",
+				Name:      "frontend_split_instant_queries_total",
+				Help:      "Total number of underlying instant query requests after the split by interval is applied",
+			}),
+			cacheHits: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+				Namespace: "thanos",
+				Name:      "frontend_split_instant_queries_cache_hits_total",
+				Help:      "Total number of underlying instant query requests served from the cache",
+			}),
+		}
+	})
+}
+
+type splitInstant struct {
+	next     queryrange.Handler
+	limits   queryrange.Limits
+	cache    cortexcache.Cache
+	interval int64
+
+	// Metrics.
+	splitCounter prometheus.Counter
+	cacheHits    prometheus.Counter
+}
+
+// splittableInstantQuery is a parsed instant query which can be split.
+type splittableInstantQuery struct {
+	expr    parser.Expr
+	matrix  *parser.MatrixSelector
+	mergeOp parser.ItemType
+}
+
+// instantWindow is an inclusive time range in milliseconds the range of a split instant query covers.
+type instantWindow struct {
+	start, end int64
+}
+
+func (s splitInstant) Do(ctx context.Context, r queryrange.Request) (queryrange.Response, error) {
+	req, ok := r.(*ThanosQueryInstantRequest)
+	if !ok {
+		return s.next.Do(ctx, r)
+	}
+	q, ok := parseSplittableInstantQuery(req.Query)
+	if !ok {
+		return s.next.Do(ctx, r)
+	}
+	windows := splitInstantWindows(req.Time, q.matrix.Range.Milliseconds(), s.interval)
+	if len(windows) < 2 {
+		return s.next.Do(ctx, r)
+	}
+
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, err.Error())
+	}
+
+	// Only windows of complete intervals which are old enough are cached, they don't depend on the evaluation time.
+	keys := make(map[instantWindow]string, len(windows))
+	if s.cache != nil && shouldCache(req) {
+		maxCacheTime := int64(model.Now().Add(-validation.MaxDurationPerTenant(tenantIDs, s.limits.MaxCacheFreshness)))
+		for _, w := range windows {
+			if w.end-w.start+1 == s.interval && w.end%s.interval == 0 && w.end <= maxCacheTime {
+				keys[w] = cortexcache.HashKey(q.cacheKey(tenant.JoinTenantIDs(tenantIDs), req, w, s.interval))
+			}
+		}
+	}
+	cached := s.fetch(ctx, keys)
+
+	var (
+		reqs     []queryrange.Request
+		reqWins  []instantWindow
+		vectors  = make([]model.Vector, 0, len(windows))
+		warnings []string
+	)
+	for _, w := range windows {
+		if v, ok := cached[w]; ok {
+			vectors = append(vectors, v)
+			continue
+		}
+		reqs = append(reqs, req.WithQuery(q.windowQuery(req.Time, w)))
+		reqWins = append(reqWins, w)
+	}
+	s.splitCounter.Add(float64(len(windows)))
+	s.cacheHits.Add(float64(len(windows) - len(reqs)))
+
+	reqResps, err := queryrange.DoRequests(ctx, s.next, reqs, s.limits)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		storeKeys []string
+		storeBufs [][]byte
+	)
+	for i, reqResp := range reqResps {
+		resp, ok := reqResp.Response.(*ThanosQueryInstantResponse)
+		if !ok || resp.Data.ResultType != model.ValVector.String() {
+			return nil, httpgrpc.Errorf(http.StatusInternalServerError, "unexpected response of split instant query")
+		}
+		var v model.Vector
+		if err := json.Unmarshal(resp.Data.Result, &v); err != nil {
+			return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error decoding response: %v", err)
+		}
+		vectors = append(vectors, v)
+		warnings = append(warnings, resp.Warnings...)
+
+		// Partial results must not be cached.
+		if key, ok := keys[reqWins[i]]; ok && len(resp.Warnings) == 0 {
+			storeKeys = append(storeKeys, key)
+			storeBufs = append(storeBufs, resp.Data.Result)
+		}
+	}
+	if len(storeKeys) > 0 {
+		s.cache.Store(ctx, storeKeys, storeBufs)
+	}
+
+	result, err := json.Marshal(mergeVectors(q.mergeOp, model.Time(req.Time), vectors))
+	if err != nil {
+		return nil, httpgrpc.Errorf(http.StatusInternalServerError, "error encoding response: %v", err)
+	}
+	return &ThanosQueryInstantResponse{
+		Status:   queryrange.StatusSuccess,
+		Data:     ThanosQueryInstantData{ResultType: model.ValVector.String(), Result: result},
+		Warnings: warnings,
+	}, nil
+}
+
+// fetch returns the cached vectors of the windows with keys.
+func (s splitInstant) fetch(ctx context.Context, keys map[instantWindow]string) map[instantWindow]model.Vector {
+	if len(keys) == 0 {
+		return nil
+	}
+	windows := make(map[string]instantWindow, len(keys))
+	ks := make([]string, 0, len(keys))
+	for w, k := range keys {
+		windows[k] = w
+		ks = append(ks, k)
+	}
+
+	found, bufs, _ := s.cache.Fetch(ctx, ks)
+	cached := make(map[instantWindow]model.Vector, len(found))
+	for i, k := range found {
+		var v model.Vector
+		if err := json.Unmarshal(bufs[i], &v); err != nil {
+			continue
+		}
+		cached[windows[k]] = v
+	}
+	return cached
+}
+
+// parseSplittableInstantQuery returns the parsed query if it is a range function over a plain selector
+// which can be split, optionally aggregated with the aggregation merging its results.
+func parseSplittableInstantQuery(query string) (*splittableInstantQuery, bool) {
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		return nil, false
+	}
+
+	var aggr *parser.AggregateExpr
+	inner := unwrapParens(expr)
+	if a, ok := inner.(*parser.AggregateExpr); ok {
+		if a.Param != nil {
+			return nil, false
+		}
+		aggr = a
+		inner = unwrapParens(a.Expr)
+	}
+
+	call, ok := inner.(*parser.Call)
+	if !ok || len(call.Args) != 1 {
+		return nil, false
+	}
+	mergeOp, ok := instantSplitMergeOps[call.Func.Name]
+	if !ok || (aggr != nil && aggr.Op != mergeOp) {
+		return nil, false
+	}
+	matrix, ok := call.Args[0].(*parser.MatrixSelector)
+	if !ok {
+		return nil, false
+	}
+	vs, ok := matrix.VectorSelector.(*parser.VectorSelector)
+	if !ok || vs.OriginalOffset != 0 || vs.Timestamp != nil || vs.StartOrEnd != 0 {
+		return nil, false
+	}
+	return &splittableInstantQuery{expr: expr, matrix: matrix, mergeOp: mergeOp}, true
+}
+
+func unwrapParens(expr parser.Expr) parser.Expr {
+	for {
+		p, ok := expr.(*parser.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = p.Expr
+	}
+}
+
+// windowQuery returns the query over the window, evaluated at the given time.
+func (q *splittableInstantQuery) windowQuery(evalTime int64, w instantWindow) string {
+	rng, offset := q.matrix.Range, q.matrix.VectorSelector.(*parser.VectorSelector).OriginalOffset
+	defer func() {
+		q.matrix.Range = rng
+		q.matrix.VectorSelector.(*parser.VectorSelector).OriginalOffset = offset
+	}()
+
+	q.matrix.Range = time.Duration(w.end-w.start) * time.Millisecond
+	q.matrix.VectorSelector.(*parser.VectorSelector).OriginalOffset = time.Duration(evalTime-w.end) * time.Millisecond
+	return q.expr.String()
+}
+
+// cacheKey returns the cache key of the window, which doesn't depend on the evaluation time or the original range.
+func (q *splittableInstantQuery) cacheKey(userID string, req *ThanosQueryInstantRequest, w instantWindow, interval int64) string {
+	rng := q.matrix.Range
+	defer func() { q.matrix.Range = rng }()
+
+	q.matrix.Range = time.Duration(interval) * time.Millisecond
+	key := fmt.Sprintf("fe:instant:%s:%s:%t:%v:%d:%d:%d", userID, q.expr.String(), req.Dedup, req.ReplicaLabels, req.MaxSourceResolution, w.start, w.end)
+	if req.Shard != nil {
+		// Requests of a shard only return the results of its series.
+		key += fmt.Sprintf(":%d:%d:%v", req.Shard.Index, req.Shard.Total, req.Shard.By)
+	}
+	return key
+}
+
+// splitInstantWindows splits the inclusive range [evalTime-rng, evalTime] selected by a range selector at evalTime
+// into non-overlapping windows ending at multiples of the interval, from the oldest to the newest.
+func splitInstantWindows(evalTime, rng, interval int64) []instantWindow {
+	if interval <= 0 || rng <= interval {
+		return nil
+	}
+
+	var windows []instantWindow
+	start := evalTime - rng
+	for start < evalTime {
+		end := (start/interval + 1) * interval
+		if start < 0 && start%interval != 0 {
+			end -= interval
+		}
+		// Avoid a last window of a single millisecond, which would need a zero range.
+		if end+1 >= evalTime {
+			end = evalTime
+		}
+		windows = append(windows, instantWindow{start: start, end: end})
+		start = end + 1
+	}
+	return windows
+}
+
+// mergeVectors merges the vectors of the split queries with the given aggregation.
+func mergeVectors(op parser.ItemType, ts model.Time, vectors []model.Vector) model.Vector {
+	merged := map[model.Fingerprint]*model.Sample{}
+	for _, v := range vectors {
+		for _, s := range v {
+			fp := s.Metric.Fingerprint()
+			m, ok := merged[fp]
+			if !ok {
+				merged[fp] = &model.Sample{Metric: s.Metric, Value: s.Value, Timestamp: ts}
+				continue
+			}
+			switch op {
+			case parser.SUM:
+				m.Value += s.Value
+			case parser.MAX:
+				if s.Value > m.Value {
+					m.Value = s.Value
+				}
+			case parser.MIN:
+				if s.Value < m.Value {
+					m.Value = s.Value
+				}
+			}
+		}
+	}
+
+	result := make(model.Vector, 0, len(merged))
+	for _, s := range merged {
+		result = append(result, s)
+	}
+	sort.Sort(result)
+	return result
+}
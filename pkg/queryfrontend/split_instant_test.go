@@ -0,0 +1,143 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	cortexcache "github.com/cortexproject/cortex/pkg/chunk/cache"
+	"github.com/cortexproject/cortex/pkg/querier/queryrange"
+	cortexvalidation "github.com/cortexproject/cortex/pkg/util/validation"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestSplitInstantWindows(t *testing.T) {
+	for _, tc := range []struct {
+		name                    string
+		evalTime, rng, interval int64
+		expected                []instantWindow
+	}{
+		{
+			name:     "range not longer than interval",
+			evalTime: 100, rng: 10, interval: 10,
+		},
+		{
+			name:     "unaligned range",
+			evalTime: 100, rng: 35, interval: 10,
+			expected: []instantWindow{{65, 70}, {71, 80}, {81, 90}, {91, 100}},
+		},
+		{
+			name:     "no window of a single millisecond",
+			evalTime: 101, rng: 35, interval: 10,
+			expected: []instantWindow{{66, 70}, {71, 80}, {81, 90}, {91, 101}},
+		},
+		{
+			name:     "aligned start",
+			evalTime: 95, rng: 25, interval: 10,
+			expected: []instantWindow{{70, 80}, {81, 90}, {91, 95}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testutil.Equals(t, tc.expected, splitInstantWindows(tc.evalTime, tc.rng, tc.interval))
+		})
+	}
+}
+
+func TestParseSplittableInstantQuery(t *testing.T) {
+	for _, tc := range []struct {
+		query         string
+		expectedQuery string
+	}{
+		{query: `up`},
+		{query: `rate(up[30d])`},
+		{query: `avg_over_time(up[30d])`},
+		{query: `sum_over_time(up[30d] offset 1h)`},
+		{query: `max(sum_over_time(up[30d]))`},
+		{query: `sum(count_over_time(up[30d])) by (job)`, expectedQuery: `sum by(job) (count_over_time(up[1m] offset 1s))`},
+		{query: `max_over_time(up{job="a"}[30d])`, expectedQuery: `max_over_time(up{job="a"}[1m] offset 1s)`},
+		{query: `(min(min_over_time(up[30d])))`, expectedQuery: `(min(min_over_time(up[1m] offset 1s)))`},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			q, ok := parseSplittableInstantQuery(tc.query)
+			if tc.expectedQuery == "" {
+				testutil.Assert(t, !ok, "expected query not to be splittable")
+				return
+			}
+			testutil.Assert(t, ok, "expected query to be splittable")
+			testutil.Equals(t, tc.expectedQuery, q.windowQuery(100000, instantWindow{start: 39000, end: 99000}))
+		})
+	}
+}
+
+func TestInstantSplitMiddleware(t *testing.T) {
+	day := 24 * time.Hour
+	evalTime := time.Date(2021, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	var (
+		mtx     sync.Mutex
+		queries []string
+	)
+	next := queryrange.HandlerFunc(func(_ context.Context, r queryrange.Request) (queryrange.Response, error) {
+		mtx.Lock()
+		queries = append(queries, r.GetQuery())
+		mtx.Unlock()
+
+		// Every split query returns the same samples, so the merge is easy to check.
+		result, err := json.Marshal(model.Vector{
+			{Metric: model.Metric{"job": "a"}, Value: 1},
+			{Metric: model.Metric{"job": "b"}, Value: 2},
+		})
+		testutil.Ok(t, err)
+		return &ThanosQueryInstantResponse{
+			Status: queryrange.StatusSuccess,
+			Data:   ThanosQueryInstantData{ResultType: "vector", Result: result},
+		}, nil
+	})
+
+	cache, err := cortexcache.New(cortexcache.Config{
+		EnableFifoCache: true,
+		Fifocache:       cortexcache.FifoCacheConfig{MaxSizeItems: 100, Validity: time.Hour},
+	}, prometheus.NewRegistry(), log.NewNopLogger())
+	testutil.Ok(t, err)
+	defer cache.Stop()
+
+	limits, err := cortexvalidation.NewOverrides(cortexvalidation.Limits{MaxQueryParallelism: 4}, nil)
+	testutil.Ok(t, err)
+
+	h := InstantSplitMiddleware(day, limits, cache, prometheus.NewRegistry()).Wrap(next)
+	ctx := user.InjectOrgID(context.Background(), "1")
+	req := &ThanosQueryInstantRequest{
+		Time:  evalTime.UnixNano() / int64(time.Millisecond),
+		Query: `sum by (job) (sum_over_time(up[3d]))`,
+	}
+
+	for _, expectedQueries := range []int{4, 2} {
+		queries = nil
+		resp, err := h.Do(ctx, req)
+		testutil.Ok(t, err)
+		testutil.Equals(t, expectedQueries, len(queries))
+
+		var v model.Vector
+		testutil.Ok(t, json.Unmarshal(resp.(*ThanosQueryInstantResponse).Data.Result, &v))
+		testutil.Equals(t, model.Vector{
+			{Metric: model.Metric{"job": "a"}, Value: 4, Timestamp: model.Time(req.Time)},
+			{Metric: model.Metric{"job": "b"}, Value: 8, Timestamp: model.Time(req.Time)},
+		}, v)
+	}
+
+	// Queries which can't be split are passed through.
+	queries = nil
+	_, err = h.Do(ctx, req.WithQuery(`rate(up[3d])`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{`rate(up[3d])`}, queries)
+}
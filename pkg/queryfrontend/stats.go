@@ -0,0 +1,101 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/cortexproject/cortex/pkg/querier/queryrange"
+)
+
+// queryRangeResponseWithStats is a range query response together with the query statistics
+// returned by the downstream queriers, when they were requested with the stats parameter.
+type queryRangeResponseWithStats struct {
+	*queryrange.PrometheusResponse
+
+	// Stats holds the statistics merged from all downstream responses. They are kept as generic
+	// JSON objects, so that any statistics returned by the queriers are passed through.
+	Stats map[string]interface{}
+}
+
+// MarshalJSON implements json.Marshaler. Stats are placed in the data section as the querier does.
+func (r *queryRangeResponseWithStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string                    `json:"resultType"`
+			Result     []queryrange.SampleStream `json:"result"`
+			Stats      map[string]interface{}    `json:"stats,omitempty"`
+		} `json:"data"`
+		ErrorType string `json:"errorType,omitempty"`
+		Error     string `json:"error,omitempty"`
+	}{
+		Status: r.Status,
+		Data: struct {
+			ResultType string                    `json:"resultType"`
+			Result     []queryrange.SampleStream `json:"result"`
+			Stats      map[string]interface{}    `json:"stats,omitempty"`
+		}{
+			ResultType: r.Data.ResultType,
+			Result:     r.Data.Result,
+			Stats:      r.Stats,
+		},
+		ErrorType: r.ErrorType,
+		Error:     r.Error,
+	})
+}
+
+// promResponse returns the Prometheus response, unwrapping the statistics if needed.
+func promResponse(r queryrange.Response) *queryrange.PrometheusResponse {
+	if sr, ok := r.(*queryRangeResponseWithStats); ok {
+		return sr.PrometheusResponse
+	}
+	return r.(*queryrange.PrometheusResponse)
+}
+
+// decodeStats extracts the statistics from the data section of a query API response body.
+func decodeStats(body []byte) (map[string]interface{}, error) {
+	var resp struct {
+		Data struct {
+			Stats map[string]interface{} `json:"stats"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data.Stats, nil
+}
+
+// mergeStats merges the statistics of src into dst. Numbers are summed up, except peak values
+// for which the maximum is kept, and nested objects are merged recursively.
+func mergeStats(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+	for k, v := range src {
+		switch sv := v.(type) {
+		case float64:
+			dv, ok := dst[k].(float64)
+			switch {
+			case !ok:
+				dst[k] = sv
+			case strings.HasPrefix(strings.ToLower(k), "peak"):
+				if sv > dv {
+					dst[k] = sv
+				}
+			default:
+				dst[k] = dv + sv
+			}
+		case map[string]interface{}:
+			dv, _ := dst[k].(map[string]interface{})
+			dst[k] = mergeStats(dv, sv)
+		default:
+			if _, ok := dst[k]; !ok {
+				dst[k] = v
+			}
+		}
+	}
+	return dst
+}
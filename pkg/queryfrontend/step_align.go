@@ -0,0 +1,35 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// This is a modified copy from
+// https://github.com/cortexproject/cortex/blob/master/pkg/querier/queryrange/step_align.go.
+
+package queryfrontend
+
+import (
+	"context"
+
+	"github.com/cortexproject/cortex/pkg/querier/queryrange"
+)
+
+// StepAlignMiddleware aligns the start and end of request to the step to improve the cacheability
+// of the query results. Requests which opted out of the alignment are passed through unmodified.
+var StepAlignMiddleware = queryrange.MiddlewareFunc(func(next queryrange.Handler) queryrange.Handler {
+	return stepAlign{
+		next: next,
+	}
+})
+
+type stepAlign struct {
+	next queryrange.Handler
+}
+
+func (s stepAlign) Do(ctx context.Context, r queryrange.Request) (queryrange.Response, error) {
+	if tqrr, ok := r.(*ThanosQueryRangeRequest); ok && tqrr.NoStepAlign {
+		return s.next.Do(ctx, r)
+	}
+
+	start := (r.GetStart() / r.GetStep()) * r.GetStep()
+	end := (r.GetEnd() / r.GetStep()) * r.GetStep()
+	return s.next.Do(ctx, r.WithStartEnd(start, end))
+}
@@ -0,0 +1,48 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cortexproject/cortex/pkg/querier/queryrange"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestStepAlignMiddleware(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		req      *ThanosQueryRangeRequest
+		expected *ThanosQueryRangeRequest
+	}{
+		{
+			name:     "aligned by default",
+			req:      &ThanosQueryRangeRequest{Start: 1500, End: 10500, Step: 1000},
+			expected: &ThanosQueryRangeRequest{Start: 1000, End: 10000, Step: 1000},
+		},
+		{
+			name:     "already aligned",
+			req:      &ThanosQueryRangeRequest{Start: 1000, End: 10000, Step: 1000},
+			expected: &ThanosQueryRangeRequest{Start: 1000, End: 10000, Step: 1000},
+		},
+		{
+			name:     "opted out of alignment",
+			req:      &ThanosQueryRangeRequest{Start: 1500, End: 10500, Step: 1000, NoStepAlign: true},
+			expected: &ThanosQueryRangeRequest{Start: 1500, End: 10500, Step: 1000, NoStepAlign: true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got queryrange.Request
+			h := StepAlignMiddleware.Wrap(queryrange.HandlerFunc(func(_ context.Context, r queryrange.Request) (queryrange.Response, error) {
+				got = r
+				return nil, nil
+			}))
+			_, err := h.Do(context.Background(), tc.req)
+			testutil.Ok(t, err)
+			testutil.Equals(t, tc.expected, got)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"time"
+
+	cortexcache "github.com/cortexproject/cortex/pkg/chunk/cache"
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaveworks/common/user"
+	"gopkg.in/yaml.v2"
+)
+
+// TenantCacheConfig holds the per-tenant overrides of the response cache configuration.
+// Tenants with an override get their own cache, so they can't evict cached results of other tenants when the cache
+// is in-memory.
+type TenantCacheConfig struct {
+	// Default is applied to the cache shared by every tenant without an override. If it is not set, the shared cache
+	// uses the response cache configuration as it is.
+	Default *TenantCacheOverride `yaml:"default"`
+	// Overrides maps a tenant to its cache settings.
+	Overrides map[string]TenantCacheOverride `yaml:"overrides"`
+}
+
+// TenantCacheOverride holds the response cache settings which can be overridden per tenant.
+type TenantCacheOverride struct {
+	// MaxSize represents overall maximum number of bytes the tenant's cache can contain. Only used by the in-memory cache.
+	MaxSize string `yaml:"max_size"`
+	// MaxSizeItems represents the maximum number of entries in the tenant's cache. Only used by the in-memory cache.
+	MaxSizeItems int `yaml:"max_size_items"`
+	// Validity represents the expiry duration of the tenant's cached items.
+	Validity time.Duration `yaml:"validity"`
+}
+
+// NewTenantCacheConfig parses the YAML content of per-tenant response cache overrides.
+func NewTenantCacheConfig(confContentYaml []byte) (*TenantCacheConfig, error) {
+	config := &TenantCacheConfig{}
+	if err := yaml.UnmarshalStrict(confContentYaml, config); err != nil {
+		return nil, errors.Wrap(err, "parsing tenant cache config YAML file")
+	}
+
+	overrides := make([]TenantCacheOverride, 0, len(config.Overrides)+1)
+	for _, o := range config.Overrides {
+		overrides = append(overrides, o)
+	}
+	if config.Default != nil {
+		overrides = append(overrides, *config.Default)
+	}
+	for _, o := range overrides {
+		fifoConfig := cortexcache.FifoCacheConfig{MaxSizeBytes: o.MaxSize}
+		if err := fifoConfig.Validate(); err != nil {
+			return nil, errors.Wrap(err, "invalid max_size of tenant cache")
+		}
+		if o.MaxSizeItems < 0 || o.Validity < 0 {
+			return nil, errors.New("max_size_items and validity of tenant cache cannot be negative")
+		}
+	}
+	return config, nil
+}
+
+// apply returns a copy of the cache config with the override applied.
+func (o TenantCacheOverride) apply(cfg cortexcache.Config) cortexcache.Config {
+	if cfg.EnableFifoCache {
+		if o.MaxSize != "" {
+			cfg.Fifocache.MaxSizeBytes = o.MaxSize
+		}
+		if o.MaxSizeItems > 0 {
+			cfg.Fifocache.MaxSizeItems = o.MaxSizeItems
+		}
+		if o.Validity > 0 {
+			cfg.Fifocache.Validity = o.Validity
+		}
+	}
+	if o.Validity > 0 {
+		cfg.Memcache.Expiration = o.Validity
+		cfg.Redis.Expiration = o.Validity
+	}
+	return cfg
+}
+
+// tenantCache is a cortex cache which routes requests to a separate cache for tenants which
+// have a cache override, and to the shared cache for all others. Dedicated caches are only created for the tenants
+// named in the config, as tenants are taken from request headers.
+type tenantCache struct {
+	shared cortexcache.Cache
+	caches map[string]cortexcache.Cache
+}
+
+func newTenantCache(cfg cortexcache.Config, conf TenantCacheConfig, reg prometheus.Registerer, logger log.Logger) (*tenantCache, error) {
+	sharedCfg := cfg
+	if conf.Default != nil {
+		sharedCfg = conf.Default.apply(cfg)
+	}
+	shared, err := cortexcache.New(sharedCfg, reg, logger)
+	if err != nil {
+		return nil, err
+	}
+	c := &tenantCache{
+		shared: shared,
+		caches: make(map[string]cortexcache.Cache, len(conf.Overrides)),
+	}
+	for tenant, override := range conf.Overrides {
+		tcfg := override.apply(cfg)
+		// Prefix names the cache in metrics, so every tenant gets its own series.
+		tcfg.Prefix = tenant + "-"
+		tc, err := cortexcache.New(tcfg, reg, logger)
+		if err != nil {
+			c.Stop()
+			return nil, errors.Wrapf(err, "create cache of tenant %s", tenant)
+		}
+		c.caches[tenant] = tc
+	}
+	return c, nil
+}
+
+func (c *tenantCache) cacheFor(ctx context.Context) cortexcache.Cache {
+	tenant, err := user.ExtractOrgID(ctx)
+	if err != nil {
+		return c.shared
+	}
+	if tc, ok := c.caches[tenant]; ok {
+		return tc
+	}
+	return c.shared
+}
+
+func (c *tenantCache) Store(ctx context.Context, keys []string, bufs [][]byte) {
+	c.cacheFor(ctx).Store(ctx, keys, bufs)
+}
+
+func (c *tenantCache) Fetch(ctx context.Context, keys []string) ([]string, [][]byte, []string) {
+	return c.cacheFor(ctx).Fetch(ctx, keys)
+}
+
+func (c *tenantCache) Stop() {
+	for _, tc := range c.caches {
+		tc.Stop()
+	}
+	c.shared.Stop()
+}
@@ -0,0 +1,105 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cortexcache "github.com/cortexproject/cortex/pkg/chunk/cache"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaveworks/common/user"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestNewTenantCacheConfig(t *testing.T) {
+	conf, err := NewTenantCacheConfig([]byte(`
+default:
+  max_size_items: 10
+overrides:
+  team-a:
+    max_size: 1MB
+    validity: 1h
+`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, &TenantCacheConfig{
+		Default:   &TenantCacheOverride{MaxSizeItems: 10},
+		Overrides: map[string]TenantCacheOverride{"team-a": {MaxSize: "1MB", Validity: time.Hour}},
+	}, conf)
+
+	_, err = NewTenantCacheConfig([]byte(`
+overrides:
+  team-a:
+    max_size: lots
+`))
+	testutil.NotOk(t, err)
+}
+
+func TestTenantCache(t *testing.T) {
+	cfg := cortexcache.Config{
+		EnableFifoCache: true,
+		Fifocache: cortexcache.FifoCacheConfig{
+			MaxSizeItems: 10,
+			Validity:     time.Hour,
+		},
+	}
+
+	for _, tc := range []struct {
+		name string
+		conf TenantCacheConfig
+		// Number of items found for each tenant after both stored 5 items.
+		expectedFoundA, expectedFoundB int
+	}{
+		{
+			name:           "no overrides, both tenants share the default cache",
+			expectedFoundA: 5,
+			expectedFoundB: 5,
+		},
+		{
+			name:           "override for tenant a, items of tenant b are not evicted by tenant a",
+			conf:           TenantCacheConfig{Overrides: map[string]TenantCacheOverride{"a": {MaxSizeItems: 2}}},
+			expectedFoundA: 2,
+			expectedFoundB: 5,
+		},
+		{
+			name:           "default override applies to the cache shared by tenants without an override",
+			conf:           TenantCacheConfig{Default: &TenantCacheOverride{MaxSizeItems: 3}},
+			expectedFoundA: 0,
+			expectedFoundB: 3,
+		},
+		{
+			name:           "default override and override for tenant a",
+			conf:           TenantCacheConfig{Default: &TenantCacheOverride{MaxSizeItems: 3}, Overrides: map[string]TenantCacheOverride{"a": {MaxSizeItems: 2}}},
+			expectedFoundA: 2,
+			expectedFoundB: 3,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := newTenantCache(cfg, tc.conf, prometheus.NewRegistry(), log.NewNopLogger())
+			testutil.Ok(t, err)
+			defer c.Stop()
+
+			ctxA := user.InjectOrgID(context.Background(), "a")
+			ctxB := user.InjectOrgID(context.Background(), "b")
+
+			keysA := []string{"a1", "a2", "a3", "a4", "a5"}
+			keysB := []string{"b1", "b2", "b3", "b4", "b5"}
+			bufs := [][]byte{{1}, {2}, {3}, {4}, {5}}
+			c.Store(ctxA, keysA, bufs)
+			c.Store(ctxB, keysB, bufs)
+
+			found, _, _ := c.Fetch(ctxA, keysA)
+			testutil.Equals(t, tc.expectedFoundA, len(found))
+			found, _, _ = c.Fetch(ctxB, keysB)
+			testutil.Equals(t, tc.expectedFoundB, len(found))
+
+			// Only tenants named in the config get a dedicated cache.
+			c.Store(user.InjectOrgID(context.Background(), "c"), []string{"c1"}, [][]byte{{1}})
+			testutil.Equals(t, len(tc.conf.Overrides), len(c.caches))
+		})
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cortexproject/cortex/pkg/frontend/v1/frontendv1pb"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/backoff"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/httpgrpc"
+	httpgrpcserver "github.com/weaveworks/common/httpgrpc/server"
+	"google.golang.org/grpc"
+)
+
+var workerBackoffConfig = backoff.Config{
+	MinBackoff: 50 * time.Millisecond,
+	MaxBackoff: time.Second,
+}
+
+// QuerierWorker pulls queries from query frontends over gRPC and executes them with the HTTP handler of the querier.
+// The querier runs a fixed number of queries concurrently per query frontend, so query frontends only dispatch
+// queries to queriers with free capacity, and stop dispatching to queriers as soon as their connection breaks.
+type QuerierWorker struct {
+	logger      log.Logger
+	handler     *httpgrpcserver.Server
+	id          string
+	concurrency int
+	dialOpts    []grpc.DialOption
+
+	mtx       sync.Mutex
+	frontends map[string]*frontendConn
+	stopped   bool
+
+	connectedFrontends prometheus.Gauge
+	requests           *prometheus.CounterVec
+}
+
+type frontendConn struct {
+	conn   *grpc.ClientConn
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewQuerierWorker returns a worker executing the queries pulled from query frontends with handler. The id identifies
+// the querier to query frontends, and concurrency is the number of queries run concurrently per query frontend.
+func NewQuerierWorker(logger log.Logger, reg prometheus.Registerer, handler http.Handler, id string, concurrency int, dialOpts []grpc.DialOption) *QuerierWorker {
+	return &QuerierWorker{
+		logger:      log.With(logger, "component", "querier-worker"),
+		handler:     httpgrpcserver.NewServer(handler),
+		id:          id,
+		concurrency: concurrency,
+		dialOpts:    dialOpts,
+		frontends:   map[string]*frontendConn{},
+		connectedFrontends: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_query_frontend_worker_connected_frontends",
+			Help: "Number of query frontends the querier pulls queries from.",
+		}),
+		requests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_query_frontend_worker_requests_total",
+			Help: "Total number of queries pulled from query frontends, by HTTP status code of their response.",
+		}, []string{"code"}),
+	}
+}
+
+// SetFrontends connects to the query frontends of the given addresses, and disconnects from the others.
+func (w *QuerierWorker) SetFrontends(addrs []string) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.stopped {
+		return
+	}
+
+	keep := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		keep[addr] = struct{}{}
+		if _, ok := w.frontends[addr]; ok {
+			continue
+		}
+		fc, err := w.connect(addr)
+		if err != nil {
+			level.Warn(w.logger).Log("msg", "connecting to query frontend failed", "address", addr, "err", err)
+			continue
+		}
+		w.frontends[addr] = fc
+	}
+	for addr, fc := range w.frontends {
+		if _, ok := keep[addr]; ok {
+			continue
+		}
+		w.disconnect(addr, fc)
+		delete(w.frontends, addr)
+	}
+	w.connectedFrontends.Set(float64(len(w.frontends)))
+}
+
+// Stop notifies query frontends of the shutdown of the querier and disconnects from them. Queries still running are
+// cancelled, so that query frontends retry them on other queriers.
+func (w *QuerierWorker) Stop() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.stopped = true
+	for addr, fc := range w.frontends {
+		w.disconnect(addr, fc)
+	}
+	w.frontends = map[string]*frontendConn{}
+	w.connectedFrontends.Set(0)
+}
+
+func (w *QuerierWorker) connect(addr string) (*frontendConn, error) {
+	conn, err := grpc.Dial(addr, w.dialOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "dial")
+	}
+	level.Info(w.logger).Log("msg", "pulling queries from query frontend", "address", addr, "concurrency", w.concurrency)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fc := &frontendConn{conn: conn, cancel: cancel}
+	client := frontendv1pb.NewFrontendClient(conn)
+	for i := 0; i < w.concurrency; i++ {
+		fc.wg.Add(1)
+		go func() {
+			defer fc.wg.Done()
+			w.processLoop(ctx, client, addr)
+		}()
+	}
+	return fc, nil
+}
+
+func (w *QuerierWorker) disconnect(addr string, fc *frontendConn) {
+	// Query frontends stop dispatching queries to queriers which are shutting down right away, instead of once
+	// their connections break.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if _, err := frontendv1pb.NewFrontendClient(fc.conn).NotifyClientShutdown(ctx, &frontendv1pb.NotifyClientShutdownRequest{ClientID: w.id}); err != nil {
+		level.Warn(w.logger).Log("msg", "notifying query frontend of the shutdown failed", "address", addr, "err", err)
+	}
+	cancel()
+
+	fc.cancel()
+	fc.wg.Wait()
+	if err := fc.conn.Close(); err != nil {
+		level.Warn(w.logger).Log("msg", "closing connection to query frontend failed", "address", addr, "err", err)
+	}
+	level.Info(w.logger).Log("msg", "stopped pulling queries from query frontend", "address", addr)
+}
+
+// processLoop pulls queries from the query frontend until ctx is done, reconnecting with a backoff on errors.
+func (w *QuerierWorker) processLoop(ctx context.Context, client frontendv1pb.FrontendClient, addr string) {
+	b := backoff.New(ctx, workerBackoffConfig)
+	for b.Ongoing() {
+		if err := w.process(ctx, client); err != nil {
+			if ctx.Err() == nil {
+				level.Warn(w.logger).Log("msg", "pulling queries from query frontend failed", "address", addr, "err", err)
+			}
+			b.Wait()
+			continue
+		}
+		b.Reset()
+	}
+}
+
+// process pulls queries from a single stream to the query frontend, one at a time.
+func (w *QuerierWorker) process(ctx context.Context, client frontendv1pb.FrontendClient) error {
+	stream, err := client.Process(ctx)
+	if err != nil {
+		return errors.Wrap(err, "open stream")
+	}
+
+	// Queries are cancelled once the stream is closed, which is how query frontends cancel them.
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		switch req.Type {
+		case frontendv1pb.GET_ID:
+			if err := stream.Send(&frontendv1pb.ClientToFrontend{ClientID: w.id}); err != nil {
+				return err
+			}
+		case frontendv1pb.HTTP_REQUEST:
+			// The query runs in the background, so that the stream is still received from and its closing noticed.
+			// The query frontend sends the next query only once it got the response, so queries never overlap.
+			go func(r *httpgrpc.HTTPRequest) {
+				resp := w.handle(ctx, r)
+				if err := stream.Send(&frontendv1pb.ClientToFrontend{HttpResponse: resp}); err != nil {
+					level.Warn(w.logger).Log("msg", "sending response to query frontend failed", "err", err)
+				}
+			}(req.HttpRequest)
+		default:
+			return errors.Errorf("unknown request type %v", req.Type)
+		}
+	}
+}
+
+func (w *QuerierWorker) handle(ctx context.Context, r *httpgrpc.HTTPRequest) *httpgrpc.HTTPResponse {
+	resp, err := w.handler.Handle(ctx, r)
+	if err != nil {
+		var ok bool
+		if resp, ok = httpgrpc.HTTPResponseFromError(err); !ok {
+			resp = &httpgrpc.HTTPResponse{Code: http.StatusInternalServerError, Body: []byte(err.Error())}
+		}
+	}
+	w.requests.WithLabelValues(strconv.Itoa(int(resp.Code))).Inc()
+	return resp
+}
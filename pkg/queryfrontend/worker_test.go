@@ -0,0 +1,82 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cortexproject/cortex/pkg/frontend/transport"
+	cortexfrontendv1 "github.com/cortexproject/cortex/pkg/frontend/v1"
+	"github.com/cortexproject/cortex/pkg/frontend/v1/frontendv1pb"
+	cortexvalidation "github.com/cortexproject/cortex/pkg/util/validation"
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/weaveworks/common/user"
+	"google.golang.org/grpc"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestQuerierWorker(t *testing.T) {
+	limits, err := cortexvalidation.NewOverrides(cortexvalidation.Limits{}, nil)
+	testutil.Ok(t, err)
+	fe, err := cortexfrontendv1.New(cortexfrontendv1.Config{MaxOutstandingPerTenant: 10}, limits, log.NewNopLogger(), nil)
+	testutil.Ok(t, err)
+	testutil.Ok(t, services.StartAndAwaitRunning(context.Background(), fe))
+	defer func() { testutil.Ok(t, services.StopAndAwaitTerminated(context.Background(), fe)) }()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	testutil.Ok(t, err)
+	srv := grpc.NewServer()
+	frontendv1pb.RegisterFrontendServer(srv, fe)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/fail" {
+			http.Error(w, "failed", http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(r.URL.Query().Get("query")))
+	})
+	w := NewQuerierWorker(log.NewNopLogger(), nil, handler, "querier", 2, []grpc.DialOption{grpc.WithInsecure()})
+	w.SetFrontends([]string{l.Addr().String()})
+	testutil.Equals(t, 1.0, promtest.ToFloat64(w.connectedFrontends))
+
+	rt := transport.AdaptGrpcRoundTripperToHTTPRoundTripper(fe)
+	roundTrip := func(url string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req = req.WithContext(user.InjectOrgID(req.Context(), "tenant"))
+		resp, err := rt.RoundTrip(req)
+		testutil.Ok(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		testutil.Ok(t, err)
+		return resp.StatusCode, string(b)
+	}
+
+	code, body := roundTrip("/api/v1/query?query=up")
+	testutil.Equals(t, http.StatusOK, code)
+	testutil.Equals(t, "up", body)
+
+	// Errors of the handler are sent as responses.
+	code, body = roundTrip("/api/v1/fail")
+	testutil.Equals(t, http.StatusInternalServerError, code)
+	testutil.Equals(t, "failed\n", body)
+
+	testutil.Equals(t, 1.0, promtest.ToFloat64(w.requests.WithLabelValues("200")))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(w.requests.WithLabelValues("500")))
+
+	// Stopped workers do not connect to query frontends anymore.
+	w.Stop()
+	testutil.Equals(t, 0.0, promtest.ToFloat64(w.connectedFrontends))
+	w.SetFrontends([]string{l.Addr().String()})
+	testutil.Equals(t, 0, len(w.frontends))
+}
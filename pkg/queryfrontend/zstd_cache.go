@@ -0,0 +1,65 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"context"
+
+	cortexcache "github.com/cortexproject/cortex/pkg/chunk/cache"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// CacheCompressionZstd is the results cache compression type compressing the cached responses with zstd.
+// Unlike snappy, it is not supported by Cortex, so the cache is wrapped by the query frontend.
+const CacheCompressionZstd = "zstd"
+
+// zstdCache compresses the cached responses with zstd, which makes large matrix responses much smaller than snappy.
+type zstdCache struct {
+	next    cortexcache.Cache
+	logger  log.Logger
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newZstdCache(next cortexcache.Cache, logger log.Logger) (*zstdCache, error) {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create zstd encoder")
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create zstd decoder")
+	}
+	return &zstdCache{next: next, logger: logger, encoder: encoder, decoder: decoder}, nil
+}
+
+func (z *zstdCache) Store(ctx context.Context, keys []string, bufs [][]byte) {
+	cs := make([][]byte, 0, len(bufs))
+	for _, buf := range bufs {
+		cs = append(cs, z.encoder.EncodeAll(buf, nil))
+	}
+	z.next.Store(ctx, keys, cs)
+}
+
+func (z *zstdCache) Fetch(ctx context.Context, keys []string) ([]string, [][]byte, []string) {
+	found, bufs, missing := z.next.Fetch(ctx, keys)
+	ds := make([][]byte, 0, len(bufs))
+	for _, buf := range bufs {
+		d, err := z.decoder.DecodeAll(buf, nil)
+		if err != nil {
+			level.Error(z.logger).Log("msg", "failed to decode cache entry", "err", err)
+			return nil, nil, keys
+		}
+		ds = append(ds, d)
+	}
+	return found, ds, missing
+}
+
+func (z *zstdCache) Stop() {
+	z.next.Stop()
+	z.decoder.Close()
+}
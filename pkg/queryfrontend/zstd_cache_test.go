@@ -0,0 +1,50 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package queryfrontend
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	cortexcache "github.com/cortexproject/cortex/pkg/chunk/cache"
+	"github.com/cortexproject/cortex/pkg/querier/queryrange"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestZstdCache(t *testing.T) {
+	ctx := context.Background()
+	mock := cortexcache.NewMockCache()
+
+	cfg, err := newResultsCacheConfig(queryrange.ResultsCacheConfig{
+		Compression: CacheCompressionZstd,
+		CacheConfig: cortexcache.Config{Cache: mock},
+	}, nil, prometheus.NewRegistry(), log.NewNopLogger())
+	testutil.Ok(t, err)
+	testutil.Equals(t, "", cfg.Compression)
+	testutil.Ok(t, validateResultsCacheConfig(queryrange.ResultsCacheConfig{Compression: CacheCompressionZstd}))
+
+	c := cfg.CacheConfig.Cache
+	value := bytes.Repeat([]byte("matrix"), 1000)
+	c.Store(ctx, []string{"a"}, [][]byte{value})
+
+	// The response is cached compressed.
+	_, bufs, _ := mock.Fetch(ctx, []string{"a"})
+	testutil.Equals(t, 1, len(bufs))
+	testutil.Assert(t, len(bufs[0]) < len(value), "expected compressed cache entry")
+
+	found, bufs, missing := c.Fetch(ctx, []string{"a", "b"})
+	testutil.Equals(t, []string{"a"}, found)
+	testutil.Equals(t, [][]byte{value}, bufs)
+	testutil.Equals(t, []string{"b"}, missing)
+
+	// Entries which aren't zstd compressed are missed.
+	mock.Store(ctx, []string{"c"}, [][]byte{[]byte("plain")})
+	found, _, missing = c.Fetch(ctx, []string{"c"})
+	testutil.Equals(t, 0, len(found))
+	testutil.Equals(t, []string{"c"}, missing)
+}
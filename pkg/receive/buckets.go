@@ -0,0 +1,149 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package receive
+
+import (
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/errutil"
+	"github.com/thanos-io/thanos/pkg/extprom"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/objstore/client"
+	"github.com/thanos-io/thanos/pkg/objstore/mirror"
+)
+
+// TenantBucketsConfig is the configuration of the buckets the blocks of specific tenants are uploaded to, instead of
+// or in addition to the default bucket.
+type TenantBucketsConfig struct {
+	Tenants map[string]TenantBucketConfig `yaml:"tenants"`
+}
+
+// TenantBucketConfig is the bucket of a tenant.
+type TenantBucketConfig struct {
+	// Bucket is the object store configuration of the bucket, in the same format as the default bucket.
+	Bucket interface{} `yaml:"bucket"`
+	// Replicate keeps uploading the blocks of the tenant to the default bucket, and mirrors them to the bucket of the
+	// tenant. Otherwise, the blocks of the tenant are only uploaded to the bucket of the tenant.
+	Replicate bool `yaml:"replicate"`
+}
+
+// ParseTenantBucketsConfig parses the YAML configuration of the buckets of tenants.
+func ParseTenantBucketsConfig(content []byte) (*TenantBucketsConfig, error) {
+	conf := &TenantBucketsConfig{}
+	if err := yaml.UnmarshalStrict(content, conf); err != nil {
+		return nil, errors.Wrap(err, "parse tenant buckets config")
+	}
+	for tenant, c := range conf.Tenants {
+		if c.Bucket == nil {
+			return nil, errors.Errorf("no bucket configured for tenant %q", tenant)
+		}
+	}
+	return conf, nil
+}
+
+// TenantBuckets are the buckets the blocks of tenants are uploaded to.
+type TenantBuckets struct {
+	defaultBkt objstore.Bucket
+	tenants    map[string]objstore.Bucket
+	// clients and mirrors are the distinct buckets of tenants, since tenants with the same configuration share them.
+	clients []objstore.Bucket
+	mirrors []objstore.Bucket
+}
+
+// NewTenantBuckets creates the clients of the buckets of tenants. Tenants without a bucket use the default bucket.
+// The returned buckets have to be closed before the default bucket.
+//
+// The metrics of the buckets are labeled with the tenant which created them, as different buckets may have the same
+// name. The metrics of the default bucket have to be registered with TenantBucketsRegisterer for consistent labels.
+func NewTenantBuckets(logger log.Logger, reg prometheus.Registerer, conf *TenantBucketsConfig, defaultBkt objstore.Bucket, component string) (*TenantBuckets, error) {
+	b := &TenantBuckets{defaultBkt: defaultBkt, tenants: map[string]objstore.Bucket{}}
+
+	tenants := make([]string, 0, len(conf.Tenants))
+	for tenant := range conf.Tenants {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+
+	// Bucket clients register metrics labeled by the bucket name, so the same configuration must create one client,
+	// which is labeled with the first of its tenants.
+	var (
+		clients  = map[string]objstore.Bucket{}
+		mirrored = map[string]objstore.Bucket{}
+	)
+	for _, tenant := range tenants {
+		c := conf.Tenants[tenant]
+		content, err := yaml.Marshal(c.Bucket)
+		if err != nil {
+			_ = b.Close()
+			return nil, errors.Wrapf(err, "marshal bucket config of tenant %q", tenant)
+		}
+
+		bkt, ok := clients[string(content)]
+		if !ok {
+			bkt, err = client.NewBucket(logger, content, extprom.WrapRegistererWith(prometheus.Labels{"tenant": tenant}, reg), component)
+			if err != nil {
+				_ = b.Close()
+				return nil, errors.Wrapf(err, "create bucket of tenant %q", tenant)
+			}
+			clients[string(content)] = bkt
+			b.clients = append(b.clients, bkt)
+		}
+		if !c.Replicate {
+			b.tenants[tenant] = bkt
+			continue
+		}
+
+		mirrorBkt, ok := mirrored[string(content)]
+		if !ok {
+			mirrorBkt, err = mirror.NewBucket(log.With(logger, "tenant", tenant), sharedBucket{defaultBkt}, sharedBucket{bkt}, mirror.DefaultConfig, extprom.WrapRegistererWith(prometheus.Labels{"tenant": tenant}, reg))
+			if err != nil {
+				_ = b.Close()
+				return nil, errors.Wrapf(err, "create replicated bucket of tenant %q", tenant)
+			}
+			mirrored[string(content)] = mirrorBkt
+			b.mirrors = append(b.mirrors, mirrorBkt)
+		}
+		b.tenants[tenant] = mirrorBkt
+	}
+	return b, nil
+}
+
+// TenantBucketsRegisterer returns the registerer of the metrics of the default bucket used with the buckets of tenants.
+// It adds an empty tenant label, as metrics of the same name have to have the same labels.
+func TenantBucketsRegisterer(reg prometheus.Registerer) prometheus.Registerer {
+	return extprom.WrapRegistererWith(prometheus.Labels{"tenant": ""}, reg)
+}
+
+// Bucket returns the bucket the blocks of the tenant are uploaded to.
+func (b *TenantBuckets) Bucket(tenantID string) objstore.Bucket {
+	if bkt, ok := b.tenants[tenantID]; ok {
+		return bkt
+	}
+	return b.defaultBkt
+}
+
+// Close closes the buckets of tenants, waiting for the blocks of replicated tenants to be mirrored. The default
+// bucket is not closed.
+func (b *TenantBuckets) Close() error {
+	merr := errutil.MultiError{}
+	for _, bkt := range b.mirrors {
+		merr.Add(bkt.Close())
+	}
+	for _, bkt := range b.clients {
+		merr.Add(bkt.Close())
+	}
+	return merr.Err()
+}
+
+// sharedBucket is a bucket mirrored by the replicated buckets of tenants, which must not close it.
+type sharedBucket struct {
+	objstore.Bucket
+}
+
+func (sharedBucket) Close() error { return nil }
@@ -0,0 +1,133 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package receive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/objstore/client"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestParseTenantBucketsConfig(t *testing.T) {
+	conf, err := ParseTenantBucketsConfig([]byte(`
+tenants:
+  a:
+    bucket:
+      type: FILESYSTEM
+      config:
+        directory: /tmp/a
+    replicate: true
+`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, true, conf.Tenants["a"].Replicate)
+
+	_, err = ParseTenantBucketsConfig([]byte(`tenants: {a: {replicate: true}}`))
+	testutil.NotOk(t, err)
+	_, err = ParseTenantBucketsConfig([]byte(`tenants: {a: {bucket: {type: FILESYSTEM}, region: eu}}`))
+	testutil.NotOk(t, err)
+}
+
+func TestTenantBuckets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test_tenant_buckets")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	conf, err := ParseTenantBucketsConfig([]byte(fmt.Sprintf(`
+tenants:
+  eu:
+    bucket: {type: FILESYSTEM, config: {directory: %[1]s}}
+  eu-replicated:
+    bucket: {type: FILESYSTEM, config: {directory: %[1]s}}
+    replicate: true
+  eu-other:
+    bucket: {type: FILESYSTEM, config: {directory: %[1]s}}
+`, filepath.Join(dir, "eu"))))
+	testutil.Ok(t, err)
+
+	defaultBkt := objstore.NewInMemBucket()
+	b, err := NewTenantBuckets(log.NewNopLogger(), prometheus.NewRegistry(), conf, defaultBkt, "receive")
+	testutil.Ok(t, err)
+
+	ctx := context.Background()
+	testutil.Equals(t, objstore.Bucket(defaultBkt), b.Bucket("us"))
+	// Tenants with the same bucket share its client.
+	testutil.Equals(t, b.Bucket("eu"), b.Bucket("eu-other"))
+	testutil.Equals(t, 1, len(b.clients))
+
+	// Blocks of tenants with a bucket are only uploaded to it.
+	testutil.Ok(t, b.Bucket("eu").Upload(ctx, "eu/meta.json", bytes.NewReader([]byte("eu"))))
+	_, err = os.Stat(filepath.Join(dir, "eu", "eu", "meta.json"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(defaultBkt.Objects()))
+
+	// Blocks of replicated tenants are uploaded to the default bucket and mirrored to the bucket of the tenant.
+	testutil.Ok(t, b.Bucket("eu-replicated").Upload(ctx, "eu-replicated/meta.json", bytes.NewReader([]byte("eu-replicated"))))
+	testutil.Equals(t, []byte("eu-replicated"), defaultBkt.Objects()["eu-replicated/meta.json"])
+
+	// Closing waits for the blocks to be mirrored and leaves the default bucket open.
+	testutil.Ok(t, b.Close())
+	content, err := ioutil.ReadFile(filepath.Join(dir, "eu", "eu-replicated", "meta.json"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, "eu-replicated", string(content))
+	testutil.Ok(t, defaultBkt.Upload(ctx, "us/meta.json", bytes.NewReader([]byte("us"))))
+}
+
+func TestTenantBuckets_SameBucketName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test_tenant_buckets")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	// Different configurations of buckets with the same name, also the one of the default bucket, create clients
+	// with metrics of the same name.
+	bucketConf := fmt.Sprintf("{type: FILESYSTEM, config: {directory: %s}}", dir)
+	conf, err := ParseTenantBucketsConfig([]byte(fmt.Sprintf(`
+tenants:
+  a:
+    bucket: %[1]s
+    replicate: true
+  b:
+    bucket: {type: FILESYSTEM, config: {directory: %[2]s}, retry: {max_retries: 1}}
+    replicate: true
+`, bucketConf, dir)))
+	testutil.Ok(t, err)
+
+	reg := prometheus.NewRegistry()
+	defaultBkt, err := client.NewBucket(log.NewNopLogger(), []byte(bucketConf), TenantBucketsRegisterer(reg), "receive")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, defaultBkt.Close()) }()
+
+	b, err := NewTenantBuckets(log.NewNopLogger(), reg, conf, defaultBkt, "receive")
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(b.clients))
+	testutil.Equals(t, 2, len(b.mirrors))
+	testutil.Ok(t, b.Close())
+
+	mfs, err := reg.Gather()
+	testutil.Ok(t, err)
+	tenants := map[string]struct{}{}
+	for _, mf := range mfs {
+		if mf.GetName() != "thanos_objstore_bucket_operations_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "tenant" {
+					tenants[l.GetValue()] = struct{}{}
+				}
+			}
+		}
+	}
+	testutil.Equals(t, map[string]struct{}{"": {}, "a": {}, "b": {}}, tenants)
+}
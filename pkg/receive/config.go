@@ -43,6 +43,9 @@ type HashringConfig struct {
 	Hashring  string   `json:"hashring,omitempty"`
 	Tenants   []string `json:"tenants,omitempty"`
 	Endpoints []string `json:"endpoints"`
+	// TenantReplicationFactors are the replication factors of tenants of the hashring, overriding the replication
+	// factor of the receivers.
+	TenantReplicationFactors map[string]uint64 `json:"tenant_replication_factors,omitempty"`
 }
 
 // ConfigWatcher is able to watch a file containing a hashring configuration
@@ -292,8 +295,17 @@ func readFile(logger log.Logger, path string) ([]byte, error) {
 // parseConfig parses the raw configuration content and returns a HashringConfig.
 func parseConfig(content []byte) ([]HashringConfig, error) {
 	var config []HashringConfig
-	err := json.Unmarshal(content, &config)
-	return config, err
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, err
+	}
+	for _, h := range config {
+		for tenant, rf := range h.TenantReplicationFactors {
+			if rf == 0 {
+				return nil, errors.Errorf("replication factor of tenant %q of hashring %q must be positive", tenant, h.Hashring)
+			}
+		}
+	}
+	return config, nil
 }
 
 // hashAsMetricValue generates metric value from hash of data.
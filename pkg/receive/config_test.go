@@ -44,6 +44,16 @@ func TestValidateConfig(t *testing.T) {
 			},
 			err: nil, // means it's valid.
 		},
+		{
+			name: "zero tenant replication factor",
+			cfg: []HashringConfig{
+				{
+					Endpoints:                []string{"node1"},
+					TenantReplicationFactors: map[string]uint64{"tenant1": 0},
+				},
+			},
+			err: errParseConfigurationFile,
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			content, err := json.Marshal(tc.cfg)
@@ -36,10 +36,12 @@ import (
 
 	"github.com/thanos-io/thanos/pkg/errutil"
 	extpromhttp "github.com/thanos-io/thanos/pkg/extprom/http"
+	"github.com/thanos-io/thanos/pkg/ipfilter"
 	"github.com/thanos-io/thanos/pkg/runutil"
 	"github.com/thanos-io/thanos/pkg/server/http/middleware"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 	"github.com/thanos-io/thanos/pkg/store/storepb/prompb"
+	"github.com/thanos-io/thanos/pkg/tenancy"
 	"github.com/thanos-io/thanos/pkg/tracing"
 )
 
@@ -81,6 +83,14 @@ type Options struct {
 	TLSConfig         *tls.Config
 	DialOpts          []grpc.DialOption
 	ForwardTimeout    time.Duration
+	// Authentication is the optional middleware authenticating remote write requests.
+	Authentication func(http.Handler) http.Handler
+	// IPFilter optionally restricts the clients of the remote write server.
+	IPFilter *ipfilter.Filter
+	// TenantAccounting optionally counts the ingested samples of tenants.
+	TenantAccounting *tenancy.Accounting
+	// WriteLimiter optionally rejects the series of remote write requests exceeding the limits of their tenant.
+	WriteLimiter *WriteLimiter
 }
 
 // Handler serves a Prometheus remote write receiving HTTP endpoint.
@@ -168,7 +178,11 @@ func NewHandler(logger log.Logger, o *Options) *Handler {
 		return next
 	}
 
-	h.router.Post("/api/v1/receive", instrf("receive", readyf(middleware.RequestID(http.HandlerFunc(h.receiveHTTP)))))
+	var receive http.Handler = http.HandlerFunc(h.receiveHTTP)
+	if o.Authentication != nil {
+		receive = o.Authentication(receive)
+	}
+	h.router.Post("/api/v1/receive", instrf("receive", readyf(middleware.RequestID(receive))))
 
 	return h
 }
@@ -227,6 +241,11 @@ func (h *Handler) Run() error {
 	if err != nil {
 		return err
 	}
+	var handler http.Handler = h.router
+	if h.options.IPFilter != nil {
+		h.listener = h.options.IPFilter.Listener(h.listener)
+		handler = h.options.IPFilter.Middleware(handler)
+	}
 
 	// Monitor incoming connections with conntrack.
 	h.listener = conntrack.NewListener(h.listener,
@@ -236,7 +255,7 @@ func (h *Handler) Run() error {
 	errlog := stdlog.New(log.NewStdlibAdapter(level.Error(h.logger)), "", 0)
 
 	httpSrv := &http.Server{
-		Handler:   h.router,
+		Handler:   handler,
 		ErrorLog:  errlog,
 		TLSConfig: h.options.TLSConfig,
 	}
@@ -269,9 +288,9 @@ func (h *Handler) handleRequest(ctx context.Context, rep uint64, tenant string,
 	}
 
 	// The replica value in the header is one-indexed, thus we need >.
-	if rep > h.options.ReplicationFactor {
+	if rf := h.tenantReplicationFactor(tenant); rep > rf {
 		level.Error(h.logger).Log("err", errBadReplica, "msg", "write request rejected",
-			"request_replica", rep, "replication_factor", h.options.ReplicationFactor)
+			"request_replica", rep, "replication_factor", rf)
 		return errBadReplica
 	}
 
@@ -346,6 +365,18 @@ func (h *Handler) receiveHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Series exceeding the limits are rejected, while the other ones are still written.
+	var limitsErr error
+	if h.options.WriteLimiter != nil {
+		if limitsErr = h.options.WriteLimiter.Validate(tenant, &wreq); limitsErr != nil {
+			level.Debug(h.logger).Log("msg", "rejected series exceeding write limits", "err", limitsErr)
+		}
+		if len(wreq.Timeseries) == 0 {
+			http.Error(w, limitsErr.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	err = h.handleRequest(ctx, rep, tenant, &wreq)
 	if err != nil {
 		level.Debug(h.logger).Log("msg", "failed to handle request", "err", err)
@@ -353,6 +384,17 @@ func (h *Handler) receiveHTTP(w http.ResponseWriter, r *http.Request) {
 
 	switch determineWriteErrorCause(err, 1) {
 	case nil:
+		// Replicated requests were counted by the receiver they were sent to first.
+		if r.Header.Get(h.options.ReplicaHeader) == "" {
+			samples := 0
+			for _, ts := range wreq.Timeseries {
+				samples += len(ts.Samples)
+			}
+			h.options.TenantAccounting.SamplesIngested(tenant, samples)
+		}
+		if limitsErr != nil {
+			http.Error(w, limitsErr.Error(), http.StatusBadRequest)
+		}
 		return
 	case errNotReady:
 		http.Error(w, err.Error(), http.StatusServiceUnavailable)
@@ -416,9 +458,23 @@ func (h *Handler) forward(ctx context.Context, tenant string, r replica, wreq *p
 	return h.fanoutForward(ctx, tenant, replicas, wreqs, len(wreqs))
 }
 
+// tenantReplicationFactor returns the replication factor of the tenant, which is the one of the receiver unless
+// the hashring overrides it.
+func (h *Handler) tenantReplicationFactor(tenant string) uint64 {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	if hr, ok := h.hashring.(replicationFactorHashring); ok {
+		if rf, ok := hr.ReplicationFactor(tenant); ok {
+			return rf
+		}
+	}
+	return h.options.ReplicationFactor
+}
+
 // writeQuorum returns minimum number of replicas that has to confirm write success before claiming replication success.
-func (h *Handler) writeQuorum() int {
-	return int((h.options.ReplicationFactor / 2) + 1)
+func writeQuorum(replicationFactor uint64) int {
+	return int((replicationFactor / 2) + 1)
 }
 
 // fanoutForward fans out concurrently given set of write requests. It returns status immediately when quorum of
@@ -441,6 +497,7 @@ func (h *Handler) fanoutForward(pctx context.Context, tenant string, replicas ma
 		logTags = append(logTags, "request-id", id)
 	}
 
+	rf := h.tenantReplicationFactor(tenant)
 	ec := make(chan error)
 
 	var wg sync.WaitGroup
@@ -450,7 +507,7 @@ func (h *Handler) fanoutForward(pctx context.Context, tenant string, replicas ma
 		// If the request is not yet replicated, let's replicate it.
 		// If the replication factor isn't greater than 1, let's
 		// just forward the requests.
-		if !replicas[endpoint].replicated && h.options.ReplicationFactor > 1 {
+		if !replicas[endpoint].replicated && rf > 1 {
 			go func(endpoint string) {
 				defer wg.Done()
 
@@ -617,6 +674,7 @@ func (h *Handler) fanoutForward(pctx context.Context, tenant string, replicas ma
 func (h *Handler) replicate(ctx context.Context, tenant string, wreq *prompb.WriteRequest) error {
 	wreqs := make(map[string]*prompb.WriteRequest)
 	replicas := make(map[string]replica)
+	rf := h.tenantReplicationFactor(tenant)
 	var i uint64
 
 	// It is possible that hashring is ready in testReady() but unready now,
@@ -627,7 +685,7 @@ func (h *Handler) replicate(ctx context.Context, tenant string, wreq *prompb.Wri
 		return errors.New("hashring is not ready")
 	}
 
-	for i = 0; i < h.options.ReplicationFactor; i++ {
+	for i = 0; i < rf; i++ {
 		endpoint, err := h.hashring.GetN(tenant, &wreq.Timeseries[0], i)
 		if err != nil {
 			h.mtx.RUnlock()
@@ -638,7 +696,7 @@ func (h *Handler) replicate(ctx context.Context, tenant string, wreq *prompb.Wri
 	}
 	h.mtx.RUnlock()
 
-	quorum := h.writeQuorum()
+	quorum := writeQuorum(rf)
 	// fanoutForward only returns an error if successThreshold (quorum) is not reached.
 	if err := h.fanoutForward(ctx, tenant, replicas, wreqs, quorum); err != nil {
 		return errors.Wrap(determineWriteErrorCause(err, quorum), "quorum not reached")
@@ -686,6 +744,7 @@ func isConflict(err error) bool {
 // isNotReady returns whether or not the given error represents a not ready error.
 func isNotReady(err error) bool {
 	return err == errNotReady ||
+		err == ErrNotReady ||
 		err == tsdb.ErrNotReady ||
 		status.Code(err) == codes.Unavailable
 }
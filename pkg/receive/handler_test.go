@@ -63,6 +63,12 @@ func TestDetermineWriteErrorCause(t *testing.T) {
 			threshold: 1,
 			exp:       errConflict,
 		},
+		{
+			name:      "matching storage not ready",
+			err:       errors.Wrap(ErrNotReady, "get appender"),
+			threshold: 1,
+			exp:       errNotReady,
+		},
 		{
 			name: "non-matching multierror",
 			err: errutil.NonNilMultiError([]error{
@@ -665,6 +671,39 @@ func TestReceiveQuorum(t *testing.T) {
 	}
 }
 
+func TestReceiveTenantReplicationFactor(t *testing.T) {
+	wreq := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels:  []labelpb.ZLabel{{Name: "foo", Value: "bar"}},
+				Samples: []prompb.Sample{{Value: 1, Timestamp: 1}, {Value: 2, Timestamp: 2}},
+			},
+		},
+	}
+	appendables := []*fakeAppendable{
+		{appender: newFakeAppender(nil, nil, nil)},
+		{appender: newFakeAppender(nil, nil, nil)},
+		{appender: newFakeAppender(nil, nil, nil)},
+	}
+	handlers, hashring := newTestHandlerHashring(appendables, 3)
+	// The bulk tenant is not replicated, while the others are replicated 3 times.
+	hashring.(*multiHashring).replicationFactors[0] = map[string]uint64{"bulk": 1}
+
+	rec, err := makeRequest(handlers[0], "bulk", wreq)
+	testutil.Ok(t, err)
+	testutil.Equals(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var got int
+	for _, a := range appendables {
+		got += len(a.appender.(*fakeAppender).Get(labels.FromStrings("foo", "bar")))
+	}
+	testutil.Equals(t, len(wreq.Timeseries[0].Samples), got)
+
+	// Requests replicated more times than the replication factor of the tenant are rejected.
+	testutil.Equals(t, errBadReplica, handlers[0].handleRequest(context.Background(), 2, "bulk", wreq))
+	testutil.Equals(t, uint64(3), handlers[0].tenantReplicationFactor("other"))
+}
+
 func TestReceiveWithConsistencyDelay(t *testing.T) {
 	appenderErrFn := func() error { return errors.New("failed to get appender") }
 	conflictErrFn := func() error { return storage.ErrOutOfBounds }
@@ -1159,6 +1198,7 @@ func benchmarkHandlerMultiTSDBReceiveRemoteWrite(b testutil.TB) {
 		nil,
 		false,
 		metadata.NoneFunc,
+		false,
 	)
 	defer func() { testutil.Ok(b, m.Close()) }()
 	handler.writer = NewWriter(logger, m)
@@ -37,6 +37,12 @@ type Hashring interface {
 	GetN(tenant string, timeSeries *prompb.TimeSeries, n uint64) (string, error)
 }
 
+// replicationFactorHashring is implemented by hashrings overriding the replication factor of some tenants.
+type replicationFactorHashring interface {
+	// ReplicationFactor returns the replication factor of the given tenant, or false if it is not overridden.
+	ReplicationFactor(tenant string) (uint64, bool)
+}
+
 // SingleNodeHashring always returns the same node.
 type SingleNodeHashring string
 
@@ -79,6 +85,8 @@ type multiHashring struct {
 	cache      map[string]Hashring
 	hashrings  []Hashring
 	tenantSets []map[string]struct{}
+	// replicationFactors are the replication factors of tenants per hashring.
+	replicationFactors []map[string]uint64
 
 	// We need a mutex to guard concurrent access
 	// to the cache map, as this is both written to
@@ -120,6 +128,21 @@ func (m *multiHashring) GetN(tenant string, ts *prompb.TimeSeries, n uint64) (st
 	return "", errors.New("no matching hashring to handle tenant")
 }
 
+// ReplicationFactor returns the replication factor of the tenant in the hashring handling it, or false if it is not
+// overridden.
+func (m *multiHashring) ReplicationFactor(tenant string) (uint64, bool) {
+	for i, t := range m.tenantSets {
+		if t != nil {
+			if _, ok := t[tenant]; !ok {
+				continue
+			}
+		}
+		rf, ok := m.replicationFactors[i][tenant]
+		return rf, ok
+	}
+	return 0, false
+}
+
 // newMultiHashring creates a multi-tenant hashring for a given slice of
 // groups.
 // Which hashring to use for a tenant is determined
@@ -139,6 +162,7 @@ func newMultiHashring(cfg []HashringConfig) Hashring {
 			t[tenant] = struct{}{}
 		}
 		m.tenantSets = append(m.tenantSets, t)
+		m.replicationFactors = append(m.replicationFactors, h.TenantReplicationFactors)
 	}
 	return m
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb/prompb"
+	"github.com/thanos-io/thanos/pkg/testutil"
 )
 
 func TestHashringGet(t *testing.T) {
@@ -148,3 +149,35 @@ func TestHashringGet(t *testing.T) {
 		}
 	}
 }
+
+func TestHashringReplicationFactor(t *testing.T) {
+	hs := newMultiHashring([]HashringConfig{
+		{
+			Endpoints:                []string{"node1", "node2", "node3"},
+			Tenants:                  []string{"critical", "default"},
+			TenantReplicationFactors: map[string]uint64{"critical": 3},
+		},
+		{
+			Endpoints:                []string{"node1", "node2", "node3"},
+			TenantReplicationFactors: map[string]uint64{"bulk": 1, "critical": 1},
+		},
+	})
+	hr, ok := hs.(replicationFactorHashring)
+	testutil.Assert(t, ok, "expected hashring to override replication factors")
+
+	for _, tc := range []struct {
+		tenant string
+		rf     uint64
+		ok     bool
+	}{
+		{tenant: "critical", rf: 3, ok: true},
+		{tenant: "bulk", rf: 1, ok: true},
+		// Tenants without a replication factor in their hashring use the one of the receivers.
+		{tenant: "default"},
+		{tenant: "other"},
+	} {
+		rf, ok := hr.ReplicationFactor(tc.tenant)
+		testutil.Equals(t, tc.ok, ok, tc.tenant)
+		testutil.Equals(t, tc.rf, rf, tc.tenant)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package receive
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/errutil"
+	"github.com/thanos-io/thanos/pkg/store/storepb/prompb"
+)
+
+// Reasons of rejected series and exemplars.
+const (
+	reasonLabelCount          = "label_count"
+	reasonLabelNameLength     = "label_name_length"
+	reasonLabelValueLength    = "label_value_length"
+	reasonExemplarsPerRequest = "exemplars_per_request"
+)
+
+// WriteLimitsConfig is the configuration of the limits of the write requests of tenants.
+type WriteLimitsConfig struct {
+	// Default are the limits of all tenants.
+	Default WriteLimits `yaml:"default"`
+	// Tenants are the limits of specific tenants. Their unset limits are the default ones.
+	Tenants map[string]WriteLimits `yaml:"tenants"`
+}
+
+// WriteLimits are the limits of the write requests of a tenant. 0 means no limit.
+type WriteLimits struct {
+	MaxLabelsPerSeries     int `yaml:"max_labels_per_series"`
+	MaxLabelNameLength     int `yaml:"max_label_name_length"`
+	MaxLabelValueLength    int `yaml:"max_label_value_length"`
+	MaxExemplarsPerRequest int `yaml:"max_exemplars_per_request"`
+}
+
+// ParseWriteLimitsConfig parses the YAML configuration of write limits.
+func ParseWriteLimitsConfig(content []byte) (*WriteLimitsConfig, error) {
+	conf := &WriteLimitsConfig{}
+	if err := yaml.UnmarshalStrict(content, conf); err != nil {
+		return nil, errors.Wrap(err, "parse write limits config")
+	}
+	for tenant, l := range conf.Tenants {
+		if err := l.validate(); err != nil {
+			return nil, errors.Wrapf(err, "limits of tenant %q", tenant)
+		}
+	}
+	if err := conf.Default.validate(); err != nil {
+		return nil, errors.Wrap(err, "default limits")
+	}
+	return conf, nil
+}
+
+func (l WriteLimits) validate() error {
+	if l.MaxLabelsPerSeries < 0 || l.MaxLabelNameLength < 0 || l.MaxLabelValueLength < 0 || l.MaxExemplarsPerRequest < 0 {
+		return errors.New("limits must not be negative")
+	}
+	return nil
+}
+
+// TenantLimits returns the limits of the given tenant.
+func (c *WriteLimitsConfig) TenantLimits(tenant string) WriteLimits {
+	l := c.Default
+	t, ok := c.Tenants[tenant]
+	if !ok {
+		return l
+	}
+	if t.MaxLabelsPerSeries > 0 {
+		l.MaxLabelsPerSeries = t.MaxLabelsPerSeries
+	}
+	if t.MaxLabelNameLength > 0 {
+		l.MaxLabelNameLength = t.MaxLabelNameLength
+	}
+	if t.MaxLabelValueLength > 0 {
+		l.MaxLabelValueLength = t.MaxLabelValueLength
+	}
+	if t.MaxExemplarsPerRequest > 0 {
+		l.MaxExemplarsPerRequest = t.MaxExemplarsPerRequest
+	}
+	return l
+}
+
+// WriteLimiter validates write requests against the limits of their tenant, read from a file that is reloaded
+// periodically.
+type WriteLimiter struct {
+	logger log.Logger
+	path   string
+
+	mtx    sync.RWMutex
+	config *WriteLimitsConfig
+
+	reloadSuccess     prometheus.Gauge
+	rejectedSeries    *prometheus.CounterVec
+	rejectedExemplars *prometheus.CounterVec
+}
+
+// NewWriteLimiter returns a limiter of write requests with the limits of the given file.
+func NewWriteLimiter(logger log.Logger, reg prometheus.Registerer, path string) (*WriteLimiter, error) {
+	l := &WriteLimiter{
+		logger: logger,
+		path:   path,
+		reloadSuccess: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_receive_limits_config_last_reload_successful",
+			Help: "Whether the last write limits configuration file reload attempt was successful.",
+		}),
+		rejectedSeries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_receive_limits_rejected_series_total",
+			Help: "The number of series rejected for exceeding the write limits of their tenant.",
+		}, []string{"tenant", "reason"}),
+		rejectedExemplars: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_receive_limits_rejected_exemplars_total",
+			Help: "The number of exemplars rejected for exceeding the write limits of their tenant.",
+		}, []string{"tenant", "reason"}),
+	}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload reads the limits from the file again. The previous limits are kept if the file is invalid.
+func (l *WriteLimiter) Reload() error {
+	content, err := ioutil.ReadFile(l.path)
+	if err != nil {
+		l.reloadSuccess.Set(0)
+		return errors.Wrap(err, "read write limits config file")
+	}
+	config, err := ParseWriteLimitsConfig(content)
+	if err != nil {
+		l.reloadSuccess.Set(0)
+		return err
+	}
+
+	l.mtx.Lock()
+	l.config = config
+	l.mtx.Unlock()
+	l.reloadSuccess.Set(1)
+	return nil
+}
+
+// Run reloads the limits at the given interval until the context is canceled.
+func (l *WriteLimiter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Reload(); err != nil {
+				level.Error(l.logger).Log("msg", "failed to reload write limits config file", "err", err, "path", l.path)
+			}
+		}
+	}
+}
+
+// Validate removes the series and exemplars exceeding the limits of the tenant from the write request, and returns
+// an error describing them, if any.
+func (l *WriteLimiter) Validate(tenant string, wreq *prompb.WriteRequest) error {
+	l.mtx.RLock()
+	limits := l.config.TenantLimits(tenant)
+	l.mtx.RUnlock()
+
+	var (
+		rejectedSeries    = map[string]int{}
+		rejectedExemplars = 0
+		exemplars         = 0
+		kept              = wreq.Timeseries[:0]
+	)
+	for _, ts := range wreq.Timeseries {
+		if reason := limits.violation(ts); reason != "" {
+			rejectedSeries[reason]++
+			continue
+		}
+		if limits.MaxExemplarsPerRequest > 0 && exemplars+len(ts.Exemplars) > limits.MaxExemplarsPerRequest {
+			n := limits.MaxExemplarsPerRequest - exemplars
+			rejectedExemplars += len(ts.Exemplars) - n
+			ts.Exemplars = ts.Exemplars[:n]
+		}
+		exemplars += len(ts.Exemplars)
+		kept = append(kept, ts)
+	}
+	wreq.Timeseries = kept
+
+	var errs errutil.MultiError
+	if n := rejectedSeries[reasonLabelCount]; n > 0 {
+		l.rejectedSeries.WithLabelValues(tenant, reasonLabelCount).Add(float64(n))
+		errs.Add(errors.Errorf("%d series with more than %d labels", n, limits.MaxLabelsPerSeries))
+	}
+	if n := rejectedSeries[reasonLabelNameLength]; n > 0 {
+		l.rejectedSeries.WithLabelValues(tenant, reasonLabelNameLength).Add(float64(n))
+		errs.Add(errors.Errorf("%d series with label names longer than %d", n, limits.MaxLabelNameLength))
+	}
+	if n := rejectedSeries[reasonLabelValueLength]; n > 0 {
+		l.rejectedSeries.WithLabelValues(tenant, reasonLabelValueLength).Add(float64(n))
+		errs.Add(errors.Errorf("%d series with label values longer than %d", n, limits.MaxLabelValueLength))
+	}
+	if rejectedExemplars > 0 {
+		l.rejectedExemplars.WithLabelValues(tenant, reasonExemplarsPerRequest).Add(float64(rejectedExemplars))
+		errs.Add(errors.Errorf("%d exemplars over the limit of %d per request", rejectedExemplars, limits.MaxExemplarsPerRequest))
+	}
+	if err := errs.Err(); err != nil {
+		return errors.Wrapf(err, "write limits of tenant %s exceeded", tenant)
+	}
+	return nil
+}
+
+// violation returns the reason why the series exceeds the limits, if any.
+func (l WriteLimits) violation(ts prompb.TimeSeries) string {
+	if l.MaxLabelsPerSeries > 0 && len(ts.Labels) > l.MaxLabelsPerSeries {
+		return reasonLabelCount
+	}
+	for _, lbl := range ts.Labels {
+		if l.MaxLabelNameLength > 0 && len(lbl.Name) > l.MaxLabelNameLength {
+			return reasonLabelNameLength
+		}
+		if l.MaxLabelValueLength > 0 && len(lbl.Value) > l.MaxLabelValueLength {
+			return reasonLabelValueLength
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package receive
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb/prompb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestParseWriteLimitsConfig(t *testing.T) {
+	conf, err := ParseWriteLimitsConfig([]byte(`
+default:
+  max_labels_per_series: 3
+  max_label_value_length: 10
+tenants:
+  a:
+    max_labels_per_series: 5
+    max_exemplars_per_request: 1
+`))
+	testutil.Ok(t, err)
+	testutil.Equals(t, WriteLimits{MaxLabelsPerSeries: 3, MaxLabelValueLength: 10}, conf.TenantLimits("b"))
+	testutil.Equals(t, WriteLimits{MaxLabelsPerSeries: 5, MaxLabelValueLength: 10, MaxExemplarsPerRequest: 1}, conf.TenantLimits("a"))
+
+	_, err = ParseWriteLimitsConfig([]byte(`default: {max_labels: 3}`))
+	testutil.NotOk(t, err)
+	_, err = ParseWriteLimitsConfig([]byte(`tenants: {a: {max_label_name_length: -1}}`))
+	testutil.NotOk(t, err)
+}
+
+func TestWriteLimiter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test_write_limiter")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	path := filepath.Join(dir, "limits.yaml")
+	testutil.Ok(t, ioutil.WriteFile(path, []byte(`
+default:
+  max_labels_per_series: 2
+  max_label_name_length: 8
+  max_label_value_length: 8
+  max_exemplars_per_request: 2
+`), os.ModePerm))
+
+	l, err := NewWriteLimiter(log.NewNopLogger(), prometheus.NewRegistry(), path)
+	testutil.Ok(t, err)
+
+	exemplars := []prompb.Exemplar{{Value: 1}, {Value: 2}}
+	valid := prompb.TimeSeries{Labels: []labelpb.ZLabel{{Name: "__name__", Value: "up"}}, Exemplars: exemplars}
+	wreq := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		valid,
+		{Labels: []labelpb.ZLabel{{Name: "__name__", Value: "up"}, {Name: "a", Value: "1"}, {Name: "b", Value: "1"}}},
+		{Labels: []labelpb.ZLabel{{Name: "__name__", Value: "up"}, {Name: "very_long_name", Value: "1"}}},
+		{Labels: []labelpb.ZLabel{{Name: "__name__", Value: "very_long_value"}}},
+		{Labels: []labelpb.ZLabel{{Name: "__name__", Value: "down"}}, Exemplars: exemplars},
+	}}
+	err = l.Validate("tenant", wreq)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, "write limits of tenant tenant exceeded: 4 errors: 1 series with more than 2 labels; 1 series with label names longer than 8; 1 series with label values longer than 8; 2 exemplars over the limit of 2 per request", err.Error())
+	testutil.Equals(t, []prompb.TimeSeries{valid, {Labels: []labelpb.ZLabel{{Name: "__name__", Value: "down"}}, Exemplars: []prompb.Exemplar{}}}, wreq.Timeseries)
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(l.rejectedSeries.WithLabelValues("tenant", reasonLabelCount)))
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(l.rejectedSeries.WithLabelValues("tenant", reasonLabelNameLength)))
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(l.rejectedSeries.WithLabelValues("tenant", reasonLabelValueLength)))
+	testutil.Equals(t, 2.0, promtestutil.ToFloat64(l.rejectedExemplars.WithLabelValues("tenant", reasonExemplarsPerRequest)))
+
+	// Invalid limits are not loaded.
+	testutil.Ok(t, ioutil.WriteFile(path, []byte(`default: {max_labels_per_series: -1}`), os.ModePerm))
+	testutil.NotOk(t, l.Reload())
+	testutil.Equals(t, 0.0, promtestutil.ToFloat64(l.reloadSuccess))
+
+	wreq = &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{Labels: []labelpb.ZLabel{{Name: "__name__", Value: "up"}, {Name: "a", Value: "1"}, {Name: "b", Value: "1"}}}}}
+	testutil.NotOk(t, l.Validate("tenant", wreq))
+	testutil.Equals(t, 0, len(wreq.Timeseries))
+
+	testutil.Ok(t, ioutil.WriteFile(path, []byte(`default: {max_labels_per_series: 3}`), os.ModePerm))
+	testutil.Ok(t, l.Reload())
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(l.reloadSuccess))
+
+	wreq = &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{Labels: []labelpb.ZLabel{{Name: "__name__", Value: "up"}, {Name: "a", Value: "1"}, {Name: "b", Value: "1"}}}}}
+	testutil.Ok(t, l.Validate("tenant", wreq))
+	testutil.Equals(t, 1, len(wreq.Timeseries))
+}
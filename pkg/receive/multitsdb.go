@@ -10,12 +10,14 @@ import (
 	"path"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
 	"go.uber.org/atomic"
@@ -29,6 +31,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/shipper"
 	"github.com/thanos-io/thanos/pkg/store"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus"
 )
 
 type MultiTSDB struct {
@@ -44,6 +47,28 @@ type MultiTSDB struct {
 	tenants               map[string]*tenant
 	allowOutOfOrderUpload bool
 	hashFunc              metadata.HashFunc
+	tenantBucketPrefix    bool
+	tenantTSDBOpts        func(tenantID string, opts *tsdb.Options)
+	tenantBucket          func(tenantID string) objstore.Bucket
+}
+
+// MultiTSDBOption configures a MultiTSDB.
+type MultiTSDBOption func(*MultiTSDB)
+
+// WithTenantTSDBOptions overrides the TSDB options of tenants. The given function is called with a copy of the
+// TSDB options of the MultiTSDB before opening the TSDB of each tenant.
+func WithTenantTSDBOptions(f func(tenantID string, opts *tsdb.Options)) MultiTSDBOption {
+	return func(t *MultiTSDB) {
+		t.tenantTSDBOpts = f
+	}
+}
+
+// WithTenantBuckets overrides the bucket the blocks of tenants are uploaded to. The given function is called with
+// the ID of each tenant before opening its TSDB, if blocks are uploaded.
+func WithTenantBuckets(f func(tenantID string) objstore.Bucket) MultiTSDBOption {
+	return func(t *MultiTSDB) {
+		t.tenantBucket = f
+	}
 }
 
 // NewMultiTSDB creates new MultiTSDB.
@@ -58,12 +83,14 @@ func NewMultiTSDB(
 	bucket objstore.Bucket,
 	allowOutOfOrderUpload bool,
 	hashFunc metadata.HashFunc,
+	tenantBucketPrefix bool,
+	opts ...MultiTSDBOption,
 ) *MultiTSDB {
 	if l == nil {
 		l = log.NewNopLogger()
 	}
 
-	return &MultiTSDB{
+	t := &MultiTSDB{
 		dataDir:               dataDir,
 		logger:                log.With(l, "component", "multi-tsdb"),
 		reg:                   reg,
@@ -75,14 +102,23 @@ func NewMultiTSDB(
 		bucket:                bucket,
 		allowOutOfOrderUpload: allowOutOfOrderUpload,
 		hashFunc:              hashFunc,
+		tenantBucketPrefix:    tenantBucketPrefix,
+	}
+	for _, o := range opts {
+		o(t)
 	}
+	return t
 }
 
 type tenant struct {
 	readyS        *ReadyStorage
 	storeTSDB     *store.TSDBStore
 	exemplarsTSDB *exemplars.TSDB
+	statusTSDB    *tsdbstatus.TSDB
 	ship          *shipper.Shipper
+	// lastAppend is the time of the last write request of the tenant, or of the start of its TSDB if later, in
+	// milliseconds.
+	lastAppend atomic.Int64
 
 	mtx *sync.RWMutex
 }
@@ -110,18 +146,36 @@ func (t *tenant) exemplars() *exemplars.TSDB {
 	return t.exemplarsTSDB
 }
 
+func (t *tenant) tsdbStatus() *tsdbstatus.TSDB {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+	return t.statusTSDB
+}
+
 func (t *tenant) shipper() *shipper.Shipper {
 	t.mtx.RLock()
 	defer t.mtx.RUnlock()
 	return t.ship
 }
 
-func (t *tenant) set(storeTSDB *store.TSDBStore, tenantTSDB *tsdb.DB, ship *shipper.Shipper, exemplarsTSDB *exemplars.TSDB) {
+func (t *tenant) set(storeTSDB *store.TSDBStore, tenantTSDB *tsdb.DB, ship *shipper.Shipper, exemplarsTSDB *exemplars.TSDB, statusTSDB *tsdbstatus.TSDB) {
 	t.readyS.Set(tenantTSDB)
 	t.mtx.Lock()
 	t.storeTSDB = storeTSDB
 	t.ship = ship
 	t.exemplarsTSDB = exemplarsTSDB
+	t.statusTSDB = statusTSDB
+	t.mtx.Unlock()
+}
+
+// unset detaches the TSDB from the tenant, so that requests of the tenant fail as not ready until it is set again.
+func (t *tenant) unset() {
+	t.readyS.reset()
+	t.mtx.Lock()
+	t.storeTSDB = nil
+	t.ship = nil
+	t.exemplarsTSDB = nil
+	t.statusTSDB = nil
 	t.mtx.Unlock()
 }
 
@@ -235,6 +289,74 @@ func (t *MultiTSDB) Sync(ctx context.Context) (int, error) {
 	return int(uploaded.Load()), merr.Err()
 }
 
+// PruneIdle flushes, uploads and closes the TSDBs of tenants without write requests for at least the given duration,
+// and removes the tenants. The TSDB of a removed tenant is opened again on its next write request. Write requests of
+// a tenant being pruned fail as not ready, so that they are retried. It returns the number of pruned tenants.
+func (t *MultiTSDB) PruneIdle(ctx context.Context, idleTimeout time.Duration) (int, error) {
+	cutoff := timestamp.FromTime(time.Now().Add(-idleTimeout))
+
+	t.mtx.RLock()
+	idle := map[string]*tenant{}
+	for id, tenant := range t.tenants {
+		if tenant.readyStorage().Get() != nil && tenant.lastAppend.Load() < cutoff {
+			idle[id] = tenant
+		}
+	}
+	t.mtx.RUnlock()
+
+	merr := errutil.MultiError{}
+	pruned := 0
+	for id, tenant := range idle {
+		ok, err := t.pruneTenant(ctx, id, tenant, cutoff)
+		if err != nil {
+			merr.Add(errors.Wrapf(err, "prune tenant %s", id))
+		}
+		if ok {
+			pruned++
+		}
+	}
+	return pruned, merr.Err()
+}
+
+// pruneTenant flushes, uploads and closes the TSDB of the tenant and removes the tenant, unless it was written to
+// after the cutoff in the meantime. The TSDB is closed even if flushing or uploading fails, as the WAL and the blocks
+// which aren't uploaded are kept on disk and picked up once the TSDB is opened again.
+func (t *MultiTSDB) pruneTenant(ctx context.Context, tenantID string, tenant *tenant, cutoff int64) (bool, error) {
+	t.mtx.Lock()
+	db := tenant.readyStorage().Get()
+	if t.tenants[tenantID] != tenant || db == nil || tenant.lastAppend.Load() >= cutoff {
+		t.mtx.Unlock()
+		return false, nil
+	}
+	ship := tenant.shipper()
+	tenant.unset()
+	t.mtx.Unlock()
+
+	logger := log.With(t.logger, "tenant", tenantID)
+	level.Info(logger).Log("msg", "pruning idle TSDB")
+
+	merr := errutil.MultiError{}
+	head := db.Head()
+	if head.NumSeries() > 0 {
+		if err := db.CompactHead(tsdb.NewRangeHead(head, head.MinTime(), head.MaxTime()-1)); err != nil {
+			merr.Add(errors.Wrap(err, "flush head"))
+		}
+	}
+	if ship != nil && merr.Err() == nil {
+		if _, err := ship.Sync(ctx); err != nil {
+			merr.Add(errors.Wrap(err, "upload"))
+		}
+	}
+	if err := db.Close(); err != nil {
+		merr.Add(errors.Wrap(err, "close"))
+	}
+
+	t.mtx.Lock()
+	delete(t.tenants, tenantID)
+	t.mtx.Unlock()
+	return true, merr.Err()
+}
+
 func (t *MultiTSDB) RemoveLockFilesIfAny() error {
 	fis, err := ioutil.ReadDir(t.dataDir)
 	if err != nil {
@@ -289,13 +411,60 @@ func (t *MultiTSDB) TSDBExemplars() map[string]*exemplars.TSDB {
 	return res
 }
 
+func (t *MultiTSDB) TSDBStatuses() map[string]*tsdbstatus.TSDB {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	res := make(map[string]*tsdbstatus.TSDB, len(t.tenants))
+	for k, tenant := range t.tenants {
+		s := tenant.tsdbStatus()
+		if s != nil {
+			res[k] = s
+		}
+	}
+	return res
+}
+
+// TSDBs returns the TSDB of each tenant which is ready.
+func (t *MultiTSDB) TSDBs() map[string]*tsdb.DB {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	res := make(map[string]*tsdb.DB, len(t.tenants))
+	for k, tenant := range t.tenants {
+		db := tenant.readyStorage().Get()
+		if db != nil {
+			res[k] = db
+		}
+	}
+	return res
+}
+
 func (t *MultiTSDB) startTSDB(logger log.Logger, tenantID string, tenant *tenant) error {
 	reg := prometheus.WrapRegistererWith(prometheus.Labels{"tenant": tenantID}, t.reg)
 	lset := labelpb.ExtendSortedLabels(t.labels, labels.FromStrings(t.tenantLabelName, tenantID))
 	dataDir := t.defaultTenantDataDir(tenantID)
 
+	bkt := t.bucket
+	if bkt != nil && t.tenantBucket != nil {
+		bkt = t.tenantBucket(tenantID)
+	}
+	if bkt != nil && t.tenantBucketPrefix {
+		tenantBkt, err := objstore.NewTenantBucket(bkt, tenantID)
+		if err != nil {
+			t.mtx.Lock()
+			delete(t.tenants, tenantID)
+			t.mtx.Unlock()
+			return errors.Wrap(err, "create tenant bucket")
+		}
+		bkt = tenantBkt
+	}
+
 	level.Info(logger).Log("msg", "opening TSDB")
 	opts := *t.tsdbOpts
+	if t.tenantTSDBOpts != nil {
+		t.tenantTSDBOpts(tenantID, &opts)
+	}
 	s, err := tsdb.Open(
 		dataDir,
 		logger,
@@ -310,12 +479,12 @@ func (t *MultiTSDB) startTSDB(logger log.Logger, tenantID string, tenant *tenant
 		return err
 	}
 	var ship *shipper.Shipper
-	if t.bucket != nil {
+	if bkt != nil {
 		ship = shipper.New(
 			logger,
-			reg,
+			&UnRegisterer{Registerer: reg},
 			dataDir,
-			t.bucket,
+			bkt,
 			func() labels.Labels { return lset },
 			metadata.ReceiveSource,
 			false,
@@ -323,7 +492,10 @@ func (t *MultiTSDB) startTSDB(logger log.Logger, tenantID string, tenant *tenant
 			t.hashFunc,
 		)
 	}
-	tenant.set(store.NewTSDBStore(logger, s, component.Receive, lset), s, ship, exemplars.NewTSDB(s, lset))
+	// The idle time of tenants counts from the start of their TSDB, so that the tenants opened on startup are not
+	// pruned before they had the chance to write.
+	tenant.lastAppend.Store(timestamp.FromTime(time.Now()))
+	tenant.set(store.NewTSDBStore(logger, s, component.Receive, lset), s, ship, exemplars.NewTSDB(s, lset), tsdbstatus.NewTSDB(s, lset))
 	level.Info(logger).Log("msg", "TSDB is now ready")
 	return nil
 }
@@ -372,6 +544,7 @@ func (t *MultiTSDB) TenantAppendable(tenantID string) (Appendable, error) {
 	if err != nil {
 		return nil, err
 	}
+	tenant.lastAppend.Store(timestamp.FromTime(time.Now()))
 	return tenant.readyStorage(), nil
 }
 
@@ -394,6 +567,14 @@ func (s *ReadyStorage) Set(db *tsdb.DB) {
 	s.a = &adapter{db: db}
 }
 
+// reset unsets the storage.
+func (s *ReadyStorage) reset() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.a = nil
+}
+
 // Get the storage.
 func (s *ReadyStorage) Get() *tsdb.DB {
 	if x := s.get(); x != nil {
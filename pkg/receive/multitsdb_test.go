@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,12 +18,14 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/exemplars/exemplarspb"
+	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/runutil"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
@@ -49,6 +53,7 @@ func TestMultiTSDB(t *testing.T) {
 			nil,
 			false,
 			metadata.NoneFunc,
+			false,
 		)
 		defer func() { testutil.Ok(t, m.Close()) }()
 
@@ -133,6 +138,7 @@ func TestMultiTSDB(t *testing.T) {
 			nil,
 			false,
 			metadata.NoneFunc,
+			false,
 		)
 		defer func() { testutil.Ok(t, m.Close()) }()
 
@@ -412,6 +418,254 @@ func checkExemplarsResponse(t *testing.T, name string, expected, data []exemplar
 	}
 }
 
+func TestMultiTSDBTenantBucketPrefix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multitsdb-prefix")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	bkt := objstore.NewInMemBucket()
+	m := NewMultiTSDB(dir, log.NewNopLogger(), prometheus.NewRegistry(), &tsdb.Options{
+		MinBlockDuration:  (2 * time.Hour).Milliseconds(),
+		MaxBlockDuration:  (2 * time.Hour).Milliseconds(),
+		RetentionDuration: (6 * time.Hour).Milliseconds(),
+		NoLockfile:        true,
+	}, labels.FromStrings("replica", "01"),
+		"tenant_id",
+		bkt,
+		false,
+		metadata.NoneFunc,
+		true,
+	)
+	defer func() { testutil.Ok(t, m.Close()) }()
+
+	app, err := m.TenantAppendable("foo")
+	testutil.Ok(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var a storage.Appender
+	testutil.Ok(t, runutil.Retry(1*time.Second, ctx.Done(), func() error {
+		a, err = app.Appender(context.Background())
+		return err
+	}))
+	for i := int64(1); i <= 3; i++ {
+		_, err = a.Append(0, labels.FromStrings("a", "1"), i, float64(i))
+		testutil.Ok(t, err)
+	}
+	testutil.Ok(t, a.Commit())
+
+	testutil.Ok(t, m.Flush())
+	uploaded, err := m.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, uploaded)
+
+	testutil.Assert(t, len(bkt.Objects()) > 0, "expected uploaded objects")
+	for name := range bkt.Objects() {
+		testutil.Assert(t, strings.HasPrefix(name, "foo/"), "object %s is not under the tenant prefix", name)
+	}
+}
+
+func TestMultiTSDBTenantTSDBOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multitsdb-tenant-opts")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	m := NewMultiTSDB(dir, log.NewNopLogger(), prometheus.NewRegistry(), &tsdb.Options{
+		MinBlockDuration:  (2 * time.Hour).Milliseconds(),
+		MaxBlockDuration:  (2 * time.Hour).Milliseconds(),
+		RetentionDuration: (6 * time.Hour).Milliseconds(),
+		NoLockfile:        true,
+	}, labels.FromStrings("replica", "01"),
+		"tenant_id",
+		nil,
+		false,
+		metadata.NoneFunc,
+		false,
+		WithTenantTSDBOptions(func(tenantID string, opts *tsdb.Options) {
+			opts.EnableMemorySnapshotOnShutdown = tenantID == "foo"
+		}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, tenant := range []string{"foo", "bar"} {
+		app, err := m.TenantAppendable(tenant)
+		testutil.Ok(t, err)
+
+		var a storage.Appender
+		testutil.Ok(t, runutil.Retry(1*time.Second, ctx.Done(), func() error {
+			a, err = app.Appender(context.Background())
+			return err
+		}))
+		_, err = a.Append(0, labels.FromStrings("a", "1"), 1, 1)
+		testutil.Ok(t, err)
+		testutil.Ok(t, a.Commit())
+	}
+	testutil.Ok(t, m.Close())
+
+	// Only the TSDB of the tenant with snapshots enabled took a snapshot of its in-memory data on shutdown.
+	for tenant, exp := range map[string]bool{"foo": true, "bar": false} {
+		snapshots, err := filepath.Glob(filepath.Join(dir, tenant, "chunk_snapshot.*"))
+		testutil.Ok(t, err)
+		testutil.Equals(t, exp, len(snapshots) > 0, "tenant %s", tenant)
+	}
+}
+
+func TestMultiTSDBTenantStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multitsdb-stats")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	m := NewMultiTSDB(dir, log.NewNopLogger(), prometheus.NewRegistry(), &tsdb.Options{
+		MinBlockDuration:  (2 * time.Hour).Milliseconds(),
+		MaxBlockDuration:  (2 * time.Hour).Milliseconds(),
+		RetentionDuration: (6 * time.Hour).Milliseconds(),
+		NoLockfile:        true,
+	}, labels.FromStrings("replica", "01"),
+		"tenant_id",
+		objstore.NewInMemBucket(),
+		false,
+		metadata.NoneFunc,
+		false,
+	)
+	defer func() { testutil.Ok(t, m.Close()) }()
+
+	app, err := m.TenantAppendable("foo")
+	testutil.Ok(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var a storage.Appender
+	testutil.Ok(t, runutil.Retry(1*time.Second, ctx.Done(), func() error {
+		a, err = app.Appender(context.Background())
+		return err
+	}))
+	for i := int64(1); i <= 3; i++ {
+		_, err = a.Append(0, labels.FromStrings("a", "1"), i, float64(i))
+		testutil.Ok(t, err)
+	}
+	testutil.Ok(t, a.Commit())
+
+	stats, err := m.TenantStats()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(stats))
+	testutil.Equals(t, "foo", stats[0].Tenant)
+	testutil.Equals(t, uint64(1), stats[0].HeadSeries)
+	testutil.Equals(t, uint64(len("a")+len("1")), stats[0].SymbolTableSizeBytes)
+	testutil.Assert(t, stats[0].WALSizeBytes > 0, "expected WAL size")
+	testutil.Assert(t, stats[0].LastAppendTime != nil, "expected last append time")
+	testutil.Equals(t, 0, stats[0].PendingUploadBlocks)
+
+	// Flushed blocks are pending until they are uploaded.
+	testutil.Ok(t, m.Flush())
+	stats, err = m.TenantStats()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, stats[0].PendingUploadBlocks)
+	testutil.Assert(t, stats[0].UploadLagSeconds > 0, "expected upload lag")
+
+	_, err = m.Sync(ctx)
+	testutil.Ok(t, err)
+	stats, err = m.TenantStats()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, stats[0].PendingUploadBlocks)
+	testutil.Equals(t, float64(0), stats[0].UploadLagSeconds)
+}
+
+func TestMultiTSDBPruneIdle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multitsdb-prune-idle")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	// The TSDB of tenant baz is opened on startup.
+	testutil.Ok(t, os.Mkdir(filepath.Join(dir, "baz"), 0750))
+
+	bkt := objstore.NewInMemBucket()
+	m := NewMultiTSDB(dir, log.NewNopLogger(), prometheus.NewRegistry(), &tsdb.Options{
+		MinBlockDuration:  (2 * time.Hour).Milliseconds(),
+		MaxBlockDuration:  (2 * time.Hour).Milliseconds(),
+		RetentionDuration: (6 * time.Hour).Milliseconds(),
+		NoLockfile:        true,
+	}, labels.FromStrings("replica", "01"),
+		"tenant_id",
+		bkt,
+		false,
+		metadata.NoneFunc,
+		true,
+	)
+	testutil.Ok(t, m.Open())
+	defer func() { testutil.Ok(t, m.Close()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Tenants opened on startup are idle from the start of their TSDB on.
+	pruned, err := m.PruneIdle(ctx, time.Hour)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, pruned)
+	testutil.Assert(t, m.TSDBs()["baz"] != nil, "expected TSDB of tenant baz")
+
+	appendSamples := func(tenant string, mint, maxt int64) {
+		app, err := m.TenantAppendable(tenant)
+		testutil.Ok(t, err)
+
+		var a storage.Appender
+		testutil.Ok(t, runutil.Retry(1*time.Second, ctx.Done(), func() error {
+			a, err = app.Appender(context.Background())
+			return err
+		}))
+		for i := mint; i <= maxt; i++ {
+			_, err = a.Append(0, labels.FromStrings("a", "1"), i, float64(i))
+			testutil.Ok(t, err)
+		}
+		testutil.Ok(t, a.Commit())
+	}
+	appendSamples("foo", 1, 3)
+	appendSamples("bar", 1, 3)
+
+	pruned, err = m.PruneIdle(ctx, time.Hour)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, pruned)
+
+	// Only the tenant without write requests for the idle timeout is pruned, after its head is uploaded.
+	m.tenants["foo"].lastAppend.Store(timestamp.FromTime(time.Now().Add(-2 * time.Hour)))
+	pruned, err = m.PruneIdle(ctx, time.Hour)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, pruned)
+
+	dbs := m.TSDBs()
+	testutil.Equals(t, 2, len(dbs))
+	testutil.Assert(t, dbs["bar"] != nil, "expected TSDB of tenant bar")
+	testutil.Assert(t, dbs["baz"] != nil, "expected TSDB of tenant baz")
+	for name := range bkt.Objects() {
+		testutil.Assert(t, strings.HasPrefix(name, "foo/"), "object %s is not under the tenant prefix", name)
+	}
+	testutil.Assert(t, len(bkt.Objects()) > 0, "expected uploaded objects")
+
+	// The TSDB of the pruned tenant is opened again on its next write request, with its previous data.
+	appendSamples("foo", 4, 4)
+	db := m.TSDBs()["foo"]
+	testutil.Assert(t, db != nil, "expected TSDB of tenant foo")
+
+	q, err := db.Querier(ctx, 0, 10)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, q.Close()) }()
+
+	set := q.Select(false, nil, labels.MustNewMatcher(labels.MatchEqual, "a", "1"))
+	var samples int
+	for set.Next() {
+		it := set.At().Iterator()
+		for it.Next() {
+			samples++
+		}
+		testutil.Ok(t, it.Err())
+	}
+	testutil.Ok(t, set.Err())
+	testutil.Equals(t, 4, samples)
+}
+
 func BenchmarkMultiTSDB(b *testing.B) {
 	dir, err := ioutil.TempDir("", "multitsdb")
 	testutil.Ok(b, err)
@@ -427,6 +681,7 @@ func BenchmarkMultiTSDB(b *testing.B) {
 		nil,
 		false,
 		metadata.NoneFunc,
+		false,
 	)
 	defer func() { testutil.Ok(b, m.Close()) }()
 
@@ -0,0 +1,120 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package receive
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/fileutil"
+
+	"github.com/thanos-io/thanos/pkg/shipper"
+)
+
+// TenantStats are the statistics of the TSDB of a tenant, telling which tenants drive the resource usage of a
+// receiver.
+type TenantStats struct {
+	Tenant string `json:"tenant"`
+	// HeadSeries is the number of series in the head block.
+	HeadSeries uint64 `json:"headSeries"`
+	// WALSizeBytes is the size of the write-ahead log on disk.
+	WALSizeBytes int64 `json:"walSizeBytes"`
+	// SymbolTableSizeBytes is the size of the symbols of the head block.
+	SymbolTableSizeBytes uint64 `json:"symbolTableSizeBytes"`
+	// LastAppendTime is the time of the last write request of the tenant, or of the start of its TSDB if later.
+	LastAppendTime *time.Time `json:"lastAppendTime,omitempty"`
+	// PendingUploadBlocks is the number of blocks which are not uploaded yet, and UploadLagSeconds the time since
+	// the end of the oldest of them. Both are only set if blocks are uploaded.
+	PendingUploadBlocks int     `json:"pendingUploadBlocks,omitempty"`
+	UploadLagSeconds    float64 `json:"uploadLagSeconds,omitempty"`
+}
+
+// TenantStats returns the statistics of the TSDBs of all tenants which are ready, sorted by tenant.
+func (t *MultiTSDB) TenantStats() ([]TenantStats, error) {
+	t.mtx.RLock()
+	tenants := make(map[string]*tenant, len(t.tenants))
+	for id, tenant := range t.tenants {
+		tenants[id] = tenant
+	}
+	t.mtx.RUnlock()
+
+	stats := make([]TenantStats, 0, len(tenants))
+	for id, tenant := range tenants {
+		db := tenant.readyStorage().Get()
+		if db == nil {
+			continue
+		}
+		s, err := tenantStats(db, tenant.shipper() != nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "stats of tenant %v", id)
+		}
+		s.Tenant = id
+		if last := tenant.lastAppend.Load(); last > 0 {
+			lastAppend := timestamp.Time(last)
+			s.LastAppendTime = &lastAppend
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Tenant < stats[j].Tenant })
+	return stats, nil
+}
+
+func tenantStats(db *tsdb.DB, upload bool) (TenantStats, error) {
+	var s TenantStats
+
+	head := db.Head()
+	s.HeadSeries = head.NumSeries()
+
+	ir, err := head.Index()
+	if err != nil {
+		return s, errors.Wrap(err, "head index")
+	}
+	symbols := ir.Symbols()
+	for symbols.Next() {
+		s.SymbolTableSizeBytes += uint64(len(symbols.At()))
+	}
+	if err := symbols.Err(); err != nil {
+		return s, errors.Wrap(err, "iterate head symbols")
+	}
+	if err := ir.Close(); err != nil {
+		return s, errors.Wrap(err, "close head index")
+	}
+
+	walDir := filepath.Join(db.Dir(), "wal")
+	if _, err := os.Stat(walDir); err == nil {
+		if s.WALSizeBytes, err = fileutil.DirSize(walDir); err != nil {
+			return s, errors.Wrap(err, "WAL size")
+		}
+	}
+
+	if !upload {
+		return s, nil
+	}
+	uploaded := map[ulid.ULID]struct{}{}
+	meta, err := shipper.ReadMetaFile(db.Dir())
+	if err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return s, errors.Wrap(err, "read shipper meta file")
+	}
+	if meta != nil {
+		for _, id := range meta.Uploaded {
+			uploaded[id] = struct{}{}
+		}
+	}
+	for _, b := range db.Blocks() {
+		if _, ok := uploaded[b.Meta().ULID]; ok {
+			continue
+		}
+		s.PendingUploadBlocks++
+		if lag := time.Since(timestamp.Time(b.Meta().MaxTime)).Seconds(); lag > s.UploadLagSeconds {
+			s.UploadLagSeconds = lag
+		}
+	}
+	return s, nil
+}
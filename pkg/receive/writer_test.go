@@ -140,6 +140,7 @@ func TestWriter(t *testing.T) {
 				nil,
 				false,
 				metadata.NoneFunc,
+				false,
 			)
 			defer func() { testutil.Ok(t, m.Close()) }()
 
@@ -9,6 +9,8 @@
 // 	* Watch on changes against certain file e.g (`cfgFile`).
 // 	* Optionally, specify different output file for watched `cfgFile` (`cfgOutputFile`).
 // 	This will also try decompress the `cfgFile` if needed and substitute ALL the envvars using Kubernetes substitution format: (`$(var)`)
+// 	* Optionally, render the `cfgFile` as a Go template into the output file (`cfgTemplate`), which can include other files matching a glob.
+// 	* Optionally, validate the output file before triggering reloads (`cfgValidate`), so that a bad config is never loaded.
 // 	* Watch on changes against certain directories (`watchedDirs`).
 //
 // Once any of those two changes, Prometheus on given `reloadURL` will be notified, causing Prometheus to reload configuration and rules.
@@ -50,6 +52,15 @@
 //   global:
 //     external_labels:
 //       replica: '$(HOSTNAME)'
+//
+// With CfgTemplate, the config file can also use the `env`, `include` and `indent` template functions, e.g. to assemble
+// scrape configs from several files:
+//
+//   global:
+//     external_labels:
+//       replica: '{{ env "HOSTNAME" }}'
+//   scrape_configs:
+//     {{ include "scrape_configs/*.yaml" | indent 2 }}
 package reloader
 
 import (
@@ -68,6 +79,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -89,11 +101,16 @@ type Reloader struct {
 	httpClient    http.Client
 	cfgFile       string
 	cfgOutputFile string
+	cfgTemplate   bool
+	cfgValidate   func([]byte) error
 	watchInterval time.Duration
 	retryInterval time.Duration
 	watchedDirs   []string
 	watcher       *watcher
 
+	// includeDirs are the directories of the files included by the config template, watched for changes.
+	includeDirs map[string]struct{}
+
 	lastCfgHash         []byte
 	lastWatchedDirsHash []byte
 
@@ -116,6 +133,14 @@ type Options struct {
 	// will be substituted and the output written into the given path. Prometheus should then use
 	// cfgOutputFile as its config file path.
 	CfgOutputFile string
+	// CfgTemplate renders the config file as a Go template into CfgOutputFile, before environment variables are
+	// substituted. Besides the `$(var)` substitution, templates can use the `env "VAR"` function, the
+	// `include "glob"` function returning the content of the files matching the glob (relative to the directory of
+	// the config file), and the `indent N` function. Files matching the globs are watched for changes too.
+	CfgTemplate bool
+	// CfgValidate validates the content of CfgOutputFile before it is written. If it returns an error, the output
+	// file is left untouched and no reload is triggered, so that a bad config never replaces a working one.
+	CfgValidate func([]byte) error
 	// WatchedDirs is a collection of paths for the reloader to watch over.
 	WatchedDirs []string
 	// DelayInterval controls how long the reloader will wait without receiving
@@ -141,6 +166,9 @@ func New(logger log.Logger, reg prometheus.Registerer, o *Options) *Reloader {
 		reloadURL:     o.ReloadURL,
 		cfgFile:       o.CfgFile,
 		cfgOutputFile: o.CfgOutputFile,
+		cfgTemplate:   o.CfgTemplate,
+		cfgValidate:   o.CfgValidate,
+		includeDirs:   map[string]struct{}{},
 		watcher:       newWatcher(logger, reg, o.DelayInterval),
 		watchedDirs:   o.WatchedDirs,
 		watchInterval: o.WatchInterval,
@@ -223,6 +251,12 @@ func (r *Reloader) Watch(ctx context.Context) error {
 			return errors.Wrapf(err, "add directory %s to watcher", dir)
 		}
 	}
+	// Files included later in other directories are still picked up every watch interval.
+	for dir := range r.includeDirs {
+		if err := r.watcher.addDirectory(dir); err != nil {
+			return errors.Wrapf(err, "add included directory %s to watcher", dir)
+		}
+	}
 
 	// Start watching the file-system.
 	var wg sync.WaitGroup
@@ -298,11 +332,30 @@ func (r *Reloader) apply(ctx context.Context) error {
 				}
 			}
 
+			if r.cfgTemplate {
+				b, err = r.renderTemplate(b)
+				if err != nil {
+					return errors.Wrap(err, "render config template")
+				}
+			}
+
 			b, err = expandEnv(b)
 			if err != nil {
 				return errors.Wrap(err, "expand environment variables")
 			}
 
+			if r.cfgValidate != nil {
+				if err := r.cfgValidate(b); err != nil {
+					return errors.Wrap(err, "validate config")
+				}
+			}
+			if r.cfgTemplate {
+				// Included files change the output without changing the config file.
+				h := sha256.New()
+				_, _ = h.Write(b)
+				cfgHash = h.Sum(nil)
+			}
+
 			tmpFile := r.cfgOutputFile + ".tmp"
 			defer func() {
 				_ = os.Remove(tmpFile)
@@ -439,6 +492,57 @@ func ReloadURLFromBase(u *url.URL) *url.URL {
 	return &r
 }
 
+// renderTemplate executes the config template b.
+func (r *Reloader) renderTemplate(b []byte) ([]byte, error) {
+	baseDir := filepath.Dir(r.cfgFile)
+	tmpl, err := template.New(filepath.Base(r.cfgFile)).Funcs(template.FuncMap{
+		"env": func(name string) (string, error) {
+			v, ok := os.LookupEnv(name)
+			if !ok {
+				return "", errors.Errorf("found reference to unset environment variable %q", name)
+			}
+			return v, nil
+		},
+		"include": func(pattern string) (string, error) {
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(baseDir, pattern)
+			}
+			r.includeDirs[filepath.Dir(pattern)] = struct{}{}
+
+			// Matches are sorted, so that the output does not change unless the included files do.
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				return "", errors.Wrapf(err, "glob %s", pattern)
+			}
+			var out []byte
+			for _, m := range matches {
+				c, err := ioutil.ReadFile(filepath.Clean(m))
+				if err != nil {
+					return "", errors.Wrapf(err, "read included file %s", m)
+				}
+				if len(out) > 0 && out[len(out)-1] != '\n' {
+					out = append(out, '\n')
+				}
+				out = append(out, c...)
+			}
+			return string(bytes.TrimRight(out, "\n")), nil
+		},
+		"indent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return strings.Replace(s, "\n", "\n"+pad, -1)
+		},
+	}).Parse(string(b))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse")
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return nil, errors.Wrap(err, "execute")
+	}
+	return buf.Bytes(), nil
+}
+
 var envRe = regexp.MustCompile(`\$\(([a-zA-Z_0-9]+)\)`)
 
 func expandEnv(b []byte) (r []byte, err error) {
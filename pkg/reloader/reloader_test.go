@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	promtest "github.com/prometheus/client_golang/prometheus/testutil"
 	"go.uber.org/atomic"
@@ -593,3 +594,69 @@ config:
 	// Check no reload request made
 	testutil.Equals(t, 0, reloads.Load().(int))
 }
+
+func TestReloader_ConfigTemplateApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reloader-cfg-test")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+	testutil.Ok(t, os.MkdirAll(filepath.Join(dir, "in", "scrape"), os.ModePerm))
+	testutil.Ok(t, os.Mkdir(filepath.Join(dir, "out"), os.ModePerm))
+
+	var (
+		input  = filepath.Join(dir, "in", "cfg.yaml.tmpl")
+		output = filepath.Join(dir, "out", "cfg.yaml")
+	)
+	reloader := New(nil, nil, &Options{
+		CfgFile:       input,
+		CfgOutputFile: output,
+		CfgTemplate:   true,
+		CfgValidate: func(b []byte) error {
+			if strings.Contains(string(b), "invalid") {
+				return errors.New("invalid config")
+			}
+			return nil
+		},
+		WatchInterval: 0, // Apply once per Watch call.
+		RetryInterval: 100 * time.Millisecond,
+	})
+
+	testutil.Ok(t, os.Setenv("TEST_RELOADER_THANOS_ENV", "2"))
+	testutil.Ok(t, ioutil.WriteFile(input, []byte(`global:
+  external_labels:
+    replica: '{{ env "TEST_RELOADER_THANOS_ENV" }}'
+    other: $(TEST_RELOADER_THANOS_ENV)
+scrape_configs:
+  {{ include "scrape/*.yaml" | indent 2 }}
+`), os.ModePerm))
+	testutil.Ok(t, ioutil.WriteFile(filepath.Join(dir, "in", "scrape", "b.yaml"), []byte("- job_name: b\n"), os.ModePerm))
+	testutil.Ok(t, ioutil.WriteFile(filepath.Join(dir, "in", "scrape", "a.yaml"), []byte("- job_name: a\n  static_configs: []\n"), os.ModePerm))
+
+	ctx := context.Background()
+	testutil.Ok(t, reloader.Watch(ctx))
+	expected := `global:
+  external_labels:
+    replica: '2'
+    other: 2
+scrape_configs:
+  - job_name: a
+    static_configs: []
+  - job_name: b
+`
+	f, err := ioutil.ReadFile(output)
+	testutil.Ok(t, err)
+	testutil.Equals(t, expected, string(f))
+	testutil.Equals(t, map[string]struct{}{filepath.Join(dir, "in", "scrape"): {}}, reloader.includeDirs)
+
+	// A bad render leaves the output file untouched.
+	testutil.Ok(t, ioutil.WriteFile(filepath.Join(dir, "in", "scrape", "c.yaml"), []byte("- invalid\n"), os.ModePerm))
+	testutil.NotOk(t, reloader.Watch(ctx))
+	f, err = ioutil.ReadFile(output)
+	testutil.Ok(t, err)
+	testutil.Equals(t, expected, string(f))
+
+	testutil.Ok(t, ioutil.WriteFile(input, []byte(`{{ env "TEST_RELOADER_THANOS_UNSET_ENV" }}`), os.ModePerm))
+	err = reloader.Watch(ctx)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.Contains(err.Error(), `found reference to unset environment variable "TEST_RELOADER_THANOS_UNSET_ENV"`), "expect error since the envvar is not set.")
+}
@@ -85,9 +85,18 @@ func RunReplicate(
 	singleRun bool,
 	minTime, maxTime *thanosmodel.TimeOrDurationValue,
 	blockIDs []ulid.ULID,
+	concurrency int,
+	stateFile, stateObject string,
 ) error {
 	logger = log.With(logger, "component", "replicate")
 
+	if concurrency < 1 {
+		return errors.New("replication concurrency must be positive")
+	}
+	if stateFile != "" && stateObject != "" {
+		return errors.New("only one of the replication state file and state object can be set")
+	}
+
 	level.Debug(logger).Log("msg", "setting up http listen-group")
 
 	httpProbe := prober.NewHTTP()
@@ -180,6 +189,16 @@ func RunReplicate(
 		return errors.Wrapf(err, "create meta fetcher with bucket %v", fromBkt)
 	}
 
+	var state *StateStore
+	switch {
+	case stateFile != "":
+		if state, err = NewFileStateStore(logger, stateFile); err != nil {
+			return err
+		}
+	case stateObject != "":
+		state = NewBucketStateStore(logger, toBkt, stateObject)
+	}
+
 	blockFilter := NewBlockFilter(
 		logger,
 		labelSelector,
@@ -202,7 +221,7 @@ func RunReplicate(
 		logger := log.With(logger, "replication-run-id", runID.String())
 		level.Info(logger).Log("msg", "running replication attempt")
 
-		if err := newReplicationScheme(logger, metrics, blockFilter, fetcher, fromBkt, toBkt, reg).execute(ctx); err != nil {
+		if err := newReplicationScheme(logger, metrics, blockFilter, fetcher, fromBkt, toBkt, concurrency, state, reg).execute(ctx); err != nil {
 			return errors.Wrap(err, "replication execute")
 		}
 
@@ -11,6 +11,8 @@ import (
 	"io/ioutil"
 	"path"
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -19,6 +21,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/prometheus/model/labels"
+	"golang.org/x/sync/errgroup"
 
 	thanosblock "github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
@@ -115,6 +118,9 @@ func (bf *BlockFilter) Filter(b *metadata.Meta) bool {
 	return true
 }
 
+// stateSaveInterval is how often the replication state is saved while blocks are replicated.
+const stateSaveInterval = 30 * time.Second
+
 type blockFilterFunc func(b *metadata.Meta) bool
 
 // TODO: Add filters field.
@@ -125,6 +131,9 @@ type replicationScheme struct {
 	blockFilter blockFilterFunc
 	fetcher     thanosblock.MetadataFetcher
 
+	concurrency int
+	state       *StateStore
+
 	logger  log.Logger
 	metrics *replicationMetrics
 
@@ -162,6 +171,8 @@ func newReplicationScheme(
 	fetcher thanosblock.MetadataFetcher,
 	from objstore.InstrumentedBucketReader,
 	to objstore.Bucket,
+	concurrency int,
+	state *StateStore,
 	reg prometheus.Registerer,
 ) *replicationScheme {
 	if logger == nil {
@@ -174,12 +185,14 @@ func newReplicationScheme(
 		fetcher:     fetcher,
 		fromBkt:     from,
 		toBkt:       to,
+		concurrency: concurrency,
+		state:       state,
 		metrics:     metrics,
 		reg:         reg,
 	}
 }
 
-func (rs *replicationScheme) execute(ctx context.Context) error {
+func (rs *replicationScheme) execute(ctx context.Context) (err error) {
 	availableBlocks := []*metadata.Meta{}
 
 	metas, partials, err := rs.fetcher.Fetch(ctx)
@@ -187,30 +200,88 @@ func (rs *replicationScheme) execute(ctx context.Context) error {
 		return err
 	}
 
+	replicated := map[ulid.ULID]struct{}{}
+	if rs.state != nil {
+		if replicated, err = rs.state.Load(ctx); err != nil {
+			return errors.Wrap(err, "load replication state")
+		}
+	}
+
 	for id := range partials {
 		level.Info(rs.logger).Log("msg", "block meta not uploaded yet. Skipping.", "block_uuid", id.String())
 	}
 
 	for id, meta := range metas {
-		if rs.blockFilter(meta) {
-			level.Info(rs.logger).Log("msg", "adding block to be replicated", "block_uuid", id.String())
-			availableBlocks = append(availableBlocks, meta)
+		if !rs.blockFilter(meta) {
+			continue
 		}
+		if _, ok := replicated[id]; ok {
+			level.Debug(rs.logger).Log("msg", "skipping block as replicated according to state", "block_uuid", id.String())
+			rs.metrics.blocksAlreadyReplicated.Inc()
+			continue
+		}
+		level.Info(rs.logger).Log("msg", "adding block to be replicated", "block_uuid", id.String())
+		availableBlocks = append(availableBlocks, meta)
 	}
 
 	// In order to prevent races in compactions by the target environment, we
-	// need to replicate oldest start timestamp first.
+	// need to replicate oldest start timestamp first. With concurrency, blocks
+	// are still started in this order.
 	sort.Slice(availableBlocks, func(i, j int) bool {
 		return availableBlocks[i].BlockMeta.MinTime < availableBlocks[j].BlockMeta.MinTime
 	})
 
-	for _, b := range availableBlocks {
-		if err := rs.ensureBlockIsReplicated(ctx, b.BlockMeta.ULID); err != nil {
-			return errors.Wrapf(err, "ensure block %v is replicated", b.BlockMeta.ULID.String())
-		}
+	var (
+		mtx      sync.Mutex
+		lastSave = time.Now()
+	)
+	if rs.state != nil {
+		// Save the progress even if replication fails or is cancelled, so it resumes from there.
+		defer func() {
+			if serr := rs.state.Save(context.Background(), replicated); serr != nil && err == nil {
+				err = errors.Wrap(serr, "save replication state")
+			}
+		}()
 	}
 
-	return nil
+	blocks := make(chan *metadata.Meta)
+	g, gctx := errgroup.WithContext(ctx)
+	for i := 0; i < rs.concurrency; i++ {
+		g.Go(func() error {
+			for b := range blocks {
+				if err := rs.ensureBlockIsReplicated(gctx, b.BlockMeta.ULID); err != nil {
+					return errors.Wrapf(err, "ensure block %v is replicated", b.BlockMeta.ULID.String())
+				}
+				if rs.state == nil {
+					continue
+				}
+
+				mtx.Lock()
+				replicated[b.BlockMeta.ULID] = struct{}{}
+				if time.Since(lastSave) >= stateSaveInterval {
+					if err := rs.state.Save(gctx, replicated); err != nil {
+						level.Warn(rs.logger).Log("msg", "failed to save replication state", "err", err)
+					}
+					lastSave = time.Now()
+				}
+				mtx.Unlock()
+			}
+			return nil
+		})
+	}
+	g.Go(func() error {
+		defer close(blocks)
+		for _, b := range availableBlocks {
+			select {
+			case <-gctx.Done():
+				return gctx.Err()
+			case blocks <- b:
+			}
+		}
+		return nil
+	})
+
+	return g.Wait()
 }
 
 // ensureBlockIsReplicated ensures that a block present in the origin bucket is
@@ -8,15 +8,19 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/tsdb"
 
@@ -347,7 +351,7 @@ func TestReplicationSchemeAll(t *testing.T) {
 		fetcher, err := block.NewMetaFetcher(logger, 32, objstore.WithNoopInstr(originBucket), "", nil, nil, nil)
 		testutil.Ok(t, err)
 
-		r := newReplicationScheme(logger, newReplicationMetrics(nil), filter, fetcher, objstore.WithNoopInstr(originBucket), targetBucket, nil)
+		r := newReplicationScheme(logger, newReplicationMetrics(nil), filter, fetcher, objstore.WithNoopInstr(originBucket), targetBucket, 1, nil, nil)
 
 		err = r.execute(ctx)
 		testutil.Ok(t, err)
@@ -355,3 +359,71 @@ func TestReplicationSchemeAll(t *testing.T) {
 		c.assert(ctx, t, originBucket, targetBucket)
 	}
 }
+
+// failingBucket fails uploads of objects with the given prefix.
+type failingBucket struct {
+	objstore.Bucket
+
+	prefix string
+}
+
+func (b *failingBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	if strings.HasPrefix(name, b.prefix) {
+		return errors.New("upload failed")
+	}
+	return b.Bucket.Upload(ctx, name, r)
+}
+
+func TestReplicationSchemeState(t *testing.T) {
+	ctx := context.Background()
+	logger := testLogger(t.Name())
+
+	originBucket := objstore.NewInMemBucket()
+	var ids []ulid.ULID
+	for i := 0; i < 3; i++ {
+		id := testULID(int64(i))
+		meta := testMeta(id)
+		meta.MinTime = int64(i)
+
+		b, err := json.Marshal(meta)
+		testutil.Ok(t, err)
+		testutil.Ok(t, originBucket.Upload(ctx, path.Join(id.String(), "meta.json"), bytes.NewReader(b)))
+		testutil.Ok(t, originBucket.Upload(ctx, path.Join(id.String(), "chunks", "000001"), bytes.NewReader(nil)))
+		testutil.Ok(t, originBucket.Upload(ctx, path.Join(id.String(), "index"), bytes.NewReader(nil)))
+		ids = append(ids, id)
+	}
+
+	matcher, err := labels.NewMatcher(labels.MatchEqual, "test-labelname", "test-labelvalue")
+	testutil.Ok(t, err)
+	filter := NewBlockFilter(logger, labels.Selector{matcher}, []compact.ResolutionLevel{compact.ResolutionLevelRaw}, []int{1}, nil).Filter
+	fetcher, err := block.NewMetaFetcher(logger, 32, objstore.WithNoopInstr(originBucket), "", nil, nil, nil)
+	testutil.Ok(t, err)
+
+	dir, err := ioutil.TempDir("", "replicate-state")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+	state, err := NewFileStateStore(logger, filepath.Join(dir, "state.json"))
+	testutil.Ok(t, err)
+
+	replicated, err := state.Load(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(replicated))
+
+	// Replication of the second block fails, so only the first one is recorded.
+	targetBucket := objstore.NewInMemBucket()
+	failing := &failingBucket{Bucket: targetBucket, prefix: ids[1].String()}
+	testutil.NotOk(t, newReplicationScheme(logger, newReplicationMetrics(nil), filter, fetcher, objstore.WithNoopInstr(originBucket), failing, 1, state, nil).execute(ctx))
+
+	replicated, err = state.Load(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, map[ulid.ULID]struct{}{ids[0]: {}}, replicated)
+
+	// Replication resumes without checking the recorded block, which isn't replicated again after it's removed.
+	testutil.Ok(t, block.Delete(ctx, logger, targetBucket, ids[0]))
+	testutil.Ok(t, newReplicationScheme(logger, newReplicationMetrics(nil), filter, fetcher, objstore.WithNoopInstr(originBucket), targetBucket, 2, state, nil).execute(ctx))
+	testutil.Equals(t, 6, len(targetBucket.Objects()))
+
+	replicated, err = state.Load(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, map[ulid.ULID]struct{}{ids[0]: {}, ids[1]: {}, ids[2]: {}}, replicated)
+}
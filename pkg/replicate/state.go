@@ -0,0 +1,101 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package replicate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/objstore/filesystem"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// stateVersion1 is the version of the replication state format.
+const stateVersion1 = 1
+
+// replicationState is the persisted format of the replicated blocks.
+type replicationState struct {
+	Version int         `json:"version"`
+	Blocks  []ulid.ULID `json:"blocks"`
+}
+
+// StateStore persists the IDs of blocks which were replicated completely, so replication which was interrupted
+// resumes without checking them again.
+type StateStore struct {
+	logger log.Logger
+	bkt    objstore.Bucket
+	name   string
+}
+
+// NewBucketStateStore returns a state store which keeps the state in the object with the given name of the bucket.
+func NewBucketStateStore(logger log.Logger, bkt objstore.Bucket, name string) *StateStore {
+	return &StateStore{logger: logger, bkt: bkt, name: name}
+}
+
+// NewFileStateStore returns a state store which keeps the state in the given local file. The file is replaced
+// atomically, so it's complete even if replication is killed while the state is saved.
+func NewFileStateStore(logger log.Logger, file string) (*StateStore, error) {
+	bkt, err := filesystem.NewBucketWithConfig(filesystem.Config{Directory: filepath.Dir(file), Fsync: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "create state directory of %s", file)
+	}
+	return NewBucketStateStore(logger, bkt, filepath.Base(file)), nil
+}
+
+// Load returns the IDs of the replicated blocks, which are none if the state wasn't saved yet.
+func (s *StateStore) Load(ctx context.Context) (map[ulid.ULID]struct{}, error) {
+	replicated := map[ulid.ULID]struct{}{}
+
+	r, err := s.bkt.Get(ctx, s.name)
+	if s.bkt.IsObjNotFoundErr(err) {
+		return replicated, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "get replication state %s", s.name)
+	}
+	defer runutil.CloseWithLogOnErr(s.logger, r, "replication state reader")
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read replication state %s", s.name)
+	}
+	var state replicationState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal replication state %s", s.name)
+	}
+	if state.Version != stateVersion1 {
+		return nil, errors.Errorf("unexpected replication state version %d", state.Version)
+	}
+
+	for _, id := range state.Blocks {
+		replicated[id] = struct{}{}
+	}
+	return replicated, nil
+}
+
+// Save replaces the state with the given IDs of replicated blocks.
+func (s *StateStore) Save(ctx context.Context, replicated map[ulid.ULID]struct{}) error {
+	state := replicationState{Version: stateVersion1, Blocks: make([]ulid.ULID, 0, len(replicated))}
+	for id := range replicated {
+		state.Blocks = append(state.Blocks, id)
+	}
+	sort.Slice(state.Blocks, func(i, j int) bool {
+		return state.Blocks[i].Compare(state.Blocks[j]) < 0
+	})
+
+	b, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "marshal replication state")
+	}
+	return errors.Wrapf(s.bkt.Upload(ctx, s.name, bytes.NewReader(b)), "upload replication state %s", s.name)
+}
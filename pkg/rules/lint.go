@@ -0,0 +1,174 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v3"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// Severity is the severity of a lint finding.
+type Severity string
+
+const (
+	// SeverityError is the severity of findings making the rules invalid for Thanos Ruler.
+	SeverityError Severity = "error"
+	// SeverityWarning is the severity of findings which are likely mistakes.
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is an issue found in a rule file by Lint.
+type Finding struct {
+	File     string   `json:"file"`
+	Group    string   `json:"group"`
+	Rule     string   `json:"rule,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	s := fmt.Sprintf("%s: group %q", f.File, f.Group)
+	if f.Rule != "" {
+		s += fmt.Sprintf(", rule %q", f.Rule)
+	}
+	return fmt.Sprintf("%s: %s: %s", s, f.Severity, f.Message)
+}
+
+// LintOptions configures the checks of Lint.
+type LintOptions struct {
+	// ReplicaLabels are the replica labels the queriers of the ruler deduplicate series by. Rules using them are
+	// reported, as they are removed from the series the rules are evaluated on.
+	ReplicaLabels []string
+	// Matches returns true if series match the given matchers. If set, rules with selectors matching no series
+	// are reported.
+	Matches func(ctx context.Context, matchers []*labels.Matcher) (bool, error)
+}
+
+// tenantFields are the fields of rule groups selecting tenants in other rulers, which Thanos Ruler doesn't support.
+var tenantFields = []string{"source_tenants", "tenant"}
+
+type lintGroup struct {
+	Name     string                 `yaml:"name"`
+	Strategy string                 `yaml:"partial_response_strategy"`
+	Rules    []rulefmt.RuleNode     `yaml:"rules"`
+	Native   map[string]interface{} `yaml:",inline"`
+}
+
+// Lint checks the Thanos specific extensions and semantics of the rule groups of the given rule file: partial
+// response strategies, unsupported tenant fields, the use of replica labels and, optionally, selectors matching no
+// series. Other issues of the rule file are reported by ValidateAndCount.
+func Lint(ctx context.Context, file string, content []byte, opts LintOptions) ([]Finding, error) {
+	var groups struct {
+		Groups []lintGroup `yaml:"groups"`
+	}
+	if err := yaml.Unmarshal(content, &groups); err != nil {
+		return nil, errors.Wrapf(err, "parse %s", file)
+	}
+
+	var findings []Finding
+	for _, g := range groups.Groups {
+		report := func(rule string, severity Severity, format string, args ...interface{}) {
+			findings = append(findings, Finding{File: file, Group: g.Name, Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+		}
+
+		for _, f := range tenantFields {
+			if _, ok := g.Native[f]; ok {
+				report("", SeverityError, "field %s is not supported by Thanos Ruler, which evaluates rules against all the data of its queriers", f)
+			}
+		}
+
+		var strategy storepb.PartialResponseStrategy
+		if err := strategy.UnmarshalJSON([]byte(fmt.Sprintf("%q", g.Strategy))); err != nil {
+			report("", SeverityError, "invalid partial_response_strategy %q, possible values are %s", g.Strategy, strings.Join(storepb.PartialResponseStrategyValues, ","))
+		}
+
+		for _, r := range g.Rules {
+			name := r.Record.Value
+			if r.Alert.Value != "" {
+				name = r.Alert.Value
+				if strategy == storepb.PartialResponseStrategy_WARN {
+					report(name, SeverityWarning, "alert is evaluated with partial_response_strategy warn, so it may resolve or fire on partial data when stores are unavailable")
+				}
+			}
+
+			expr, err := parser.ParseExpr(r.Expr.Value)
+			if err != nil {
+				// Invalid expressions are reported by ValidateAndCount.
+				continue
+			}
+			for _, msg := range lintReplicaLabels(expr, opts.ReplicaLabels) {
+				report(name, SeverityWarning, "%s", msg)
+			}
+			if opts.Matches == nil {
+				continue
+			}
+			for _, s := range parser.ExtractSelectors(expr) {
+				ok, err := opts.Matches(ctx, s)
+				if err != nil {
+					return nil, errors.Wrapf(err, "match selector %s of rule %s", selectorString(s), name)
+				}
+				if !ok {
+					report(name, SeverityWarning, "selector %s matches no series", selectorString(s))
+				}
+			}
+		}
+	}
+	return findings, nil
+}
+
+// lintReplicaLabels returns the messages of the uses of replica labels in the given expression. Queriers remove
+// replica labels when deduplicating series, so selectors matching them select nothing and aggregations by them
+// aggregate the series of all replicas together.
+func lintReplicaLabels(expr parser.Expr, replicaLabels []string) (msgs []string) {
+	if len(replicaLabels) == 0 {
+		return nil
+	}
+	isReplica := func(name string) bool {
+		for _, l := range replicaLabels {
+			if l == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			for _, m := range n.LabelMatchers {
+				if isReplica(m.Name) {
+					msgs = append(msgs, fmt.Sprintf("selector %s matches replica label %s, which is removed by deduplication", n, m.Name))
+				}
+			}
+		case *parser.AggregateExpr:
+			if n.Without {
+				return nil
+			}
+			for _, l := range n.Grouping {
+				if isReplica(l) {
+					msgs = append(msgs, fmt.Sprintf("%s aggregates by replica label %s, which is removed by deduplication, so the series of all replicas are aggregated together", n.Op, l))
+				}
+			}
+		}
+		return nil
+	})
+	return msgs
+}
+
+func selectorString(matchers []*labels.Matcher) string {
+	s := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		s = append(s, m.String())
+	}
+	return "{" + strings.Join(s, ", ") + "}"
+}
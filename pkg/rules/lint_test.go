@@ -0,0 +1,60 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package rules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestLint(t *testing.T) {
+	content := []byte(`
+groups:
+  - name: valid
+    rules:
+      - record: job:up:sum
+        expr: sum by (job) (up)
+  - name: invalid-strategy
+    partial_response_strategy: ignore
+    rules: []
+  - name: tenants
+    source_tenants: [team-a]
+    rules: []
+  - name: warn
+    partial_response_strategy: warn
+    rules:
+      - alert: Down
+        expr: up{replica="a"} == 0
+      - record: replica:up:sum
+        expr: sum by (replica) (up) + sum without (replica) (missing)
+`)
+
+	findings, err := Lint(context.Background(), "rules.yaml", content, LintOptions{
+		ReplicaLabels: []string{"replica"},
+		Matches: func(_ context.Context, matchers []*labels.Matcher) (bool, error) {
+			for _, m := range matchers {
+				if m.Name == labels.MetricName && m.Value == "missing" {
+					return false, nil
+				}
+			}
+			return true, nil
+		},
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []Finding{
+		{File: "rules.yaml", Group: "invalid-strategy", Severity: SeverityError, Message: `invalid partial_response_strategy "ignore", possible values are ABORT,WARN`},
+		{File: "rules.yaml", Group: "tenants", Severity: SeverityError, Message: "field source_tenants is not supported by Thanos Ruler, which evaluates rules against all the data of its queriers"},
+		{File: "rules.yaml", Group: "warn", Rule: "Down", Severity: SeverityWarning, Message: "alert is evaluated with partial_response_strategy warn, so it may resolve or fire on partial data when stores are unavailable"},
+		{File: "rules.yaml", Group: "warn", Rule: "Down", Severity: SeverityWarning, Message: `selector up{replica="a"} matches replica label replica, which is removed by deduplication`},
+		{File: "rules.yaml", Group: "warn", Rule: "replica:up:sum", Severity: SeverityWarning, Message: "sum aggregates by replica label replica, which is removed by deduplication, so the series of all replicas are aggregated together"},
+		{File: "rules.yaml", Group: "warn", Rule: "replica:up:sum", Severity: SeverityWarning, Message: `selector {__name__="missing"} matches no series`},
+	}, findings)
+
+	_, err = Lint(context.Background(), "rules.yaml", []byte("groups: {"), LintOptions{})
+	testutil.NotOk(t, err)
+}
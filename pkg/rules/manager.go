@@ -422,6 +422,9 @@ func (m *Manager) Rules(r *rulespb.RulesRequest, s rulespb.Rules_RulesServer) (e
 	}
 
 	enrichRulesWithExtLabels(pgs, m.extLset)
+	if pgs, err = filterRules(pgs, r); err != nil {
+		return err
+	}
 
 	for _, pg := range pgs {
 		tracing.DoInSpan(s.Context(), "send_rule_group_response", func(_ context.Context) {
@@ -45,6 +45,11 @@ func (p *Prometheus) Rules(r *rulespb.RulesRequest, s rulespb.Rules_RulesServer)
 	// Prometheus does not add external labels, so we need to add on our own.
 	enrichRulesWithExtLabels(groups, p.extLabels())
 
+	// Prometheus doesn't support the other filters, so rules are filtered here to not send them to the querier.
+	if groups, err = filterRules(groups, r); err != nil {
+		return err
+	}
+
 	for _, g := range groups {
 		if err := s.Send(&rulespb.RulesResponse{Result: &rulespb.RulesResponse_Group{Group: g}}); err != nil {
 			return err
@@ -10,7 +10,10 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/thanos-io/thanos/pkg/rules/rulespb"
 	"github.com/thanos-io/thanos/pkg/tracing"
@@ -58,13 +61,102 @@ func (rr *GRPCClient) Rules(ctx context.Context, req *rulespb.RulesRequest) (*ru
 		return nil, nil, errors.Wrap(err, "proxy Rules")
 	}
 
+	// Rulers and sidecars filter the rules themselves, but older versions ignore the filters.
+	groups, err := filterRules(resp.groups, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// TODO(bwplotka): Move to SortInterface with equal method and heap.
-	resp.groups = dedupGroups(resp.groups)
-	for _, g := range resp.groups {
+	groups = dedupGroups(groups)
+	for _, g := range groups {
 		g.Rules = dedupRules(g.Rules, rr.replicaLabels)
 	}
 
-	return &rulespb.RuleGroups{Groups: resp.groups}, resp.warnings, nil
+	return &rulespb.RuleGroups{Groups: groups}, resp.warnings, nil
+}
+
+// filterRules returns the rule groups with only the rules matching the matchers, names, health and alert states of the
+// request. Groups without matching rules are omitted. The groups are returned unchanged if none of these are set.
+func filterRules(groups []*rulespb.RuleGroup, req *rulespb.RulesRequest) ([]*rulespb.RuleGroup, error) {
+	if len(req.MatcherString) == 0 && len(req.RuleName) == 0 && req.Health == "" && len(req.AlertState) == 0 {
+		return groups, nil
+	}
+
+	matcherSets := make([][]*labels.Matcher, 0, len(req.MatcherString))
+	for _, s := range req.MatcherString {
+		ms, err := parser.ParseMetricSelector(s)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, errors.Wrapf(err, "parse matcher %q", s).Error())
+		}
+		matcherSets = append(matcherSets, ms)
+	}
+	names := make(map[string]struct{}, len(req.RuleName))
+	for _, n := range req.RuleName {
+		names[n] = struct{}{}
+	}
+
+	res := make([]*rulespb.RuleGroup, 0, len(groups))
+	for _, g := range groups {
+		var rules []*rulespb.Rule
+		for _, r := range g.Rules {
+			if matchesRule(r, matcherSets, names, req.Health, req.AlertState) {
+				rules = append(rules, r)
+			}
+		}
+		if len(rules) == 0 {
+			continue
+		}
+		filtered := *g
+		filtered.Rules = rules
+		res = append(res, &filtered)
+	}
+	return res, nil
+}
+
+func matchesRule(r *rulespb.Rule, matcherSets [][]*labels.Matcher, names map[string]struct{}, health string, states []rulespb.AlertState) bool {
+	if len(names) > 0 {
+		if _, ok := names[r.GetName()]; !ok {
+			return false
+		}
+	}
+	if health != "" && r.GetHealth() != health {
+		return false
+	}
+	if len(states) > 0 {
+		if r.GetAlert() == nil || !containsAlertState(states, r.GetAlert().State) {
+			return false
+		}
+	}
+	if len(matcherSets) == 0 {
+		return true
+	}
+
+	lset := r.GetLabels()
+	for _, ms := range matcherSets {
+		if matchesLabels(ms, lset) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesLabels(ms []*labels.Matcher, lset labels.Labels) bool {
+	for _, m := range ms {
+		if !m.Matches(lset.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAlertState(states []rulespb.AlertState, s rulespb.AlertState) bool {
+	for _, state := range states {
+		if state == s {
+			return true
+		}
+	}
+	return false
 }
 
 // dedupRules re-sorts the set so that the same series with different replica
@@ -954,3 +954,64 @@ func TestDedupGroups(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterRules(t *testing.T) {
+	alert := func(name, health string, state rulespb.AlertState, lset labelpb.ZLabelSet) *rulespb.Rule {
+		return rulespb.NewAlertingRule(&rulespb.Alert{Name: name, Health: health, State: state, Labels: lset})
+	}
+	recording := func(name, health string, lset labelpb.ZLabelSet) *rulespb.Rule {
+		return rulespb.NewRecordingRule(&rulespb.RecordingRule{Name: name, Health: health, Labels: lset})
+	}
+	clusterA := labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "cluster", Value: "a"}}}
+	clusterB := labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "cluster", Value: "b"}}}
+
+	a1 := alert("a1", "ok", rulespb.AlertState_FIRING, clusterA)
+	a2 := alert("a2", "err", rulespb.AlertState_INACTIVE, clusterA)
+	a3 := alert("a3", "ok", rulespb.AlertState_FIRING, clusterB)
+	r1 := recording("r1", "ok", clusterA)
+	groups := []*rulespb.RuleGroup{
+		{Name: "g1", Rules: []*rulespb.Rule{a1, a2, r1}},
+		{Name: "g2", Rules: []*rulespb.Rule{a3}},
+	}
+
+	for _, tc := range []struct {
+		name string
+		req  *rulespb.RulesRequest
+		want []*rulespb.RuleGroup
+	}{
+		{
+			name: "no filters",
+			req:  &rulespb.RulesRequest{},
+			want: groups,
+		},
+		{
+			name: "matchers",
+			req:  &rulespb.RulesRequest{MatcherString: []string{`{cluster="b"}`}},
+			want: []*rulespb.RuleGroup{{Name: "g2", Rules: []*rulespb.Rule{a3}}},
+		},
+		{
+			name: "rule names",
+			req:  &rulespb.RulesRequest{RuleName: []string{"a2", "r1"}},
+			want: []*rulespb.RuleGroup{{Name: "g1", Rules: []*rulespb.Rule{a2, r1}}},
+		},
+		{
+			name: "health",
+			req:  &rulespb.RulesRequest{Health: "ok"},
+			want: []*rulespb.RuleGroup{{Name: "g1", Rules: []*rulespb.Rule{a1, r1}}, {Name: "g2", Rules: []*rulespb.Rule{a3}}},
+		},
+		{
+			name: "firing alerts of a cluster",
+			req:  &rulespb.RulesRequest{MatcherString: []string{`{cluster="a"}`}, AlertState: []rulespb.AlertState{rulespb.AlertState_FIRING}},
+			want: []*rulespb.RuleGroup{{Name: "g1", Rules: []*rulespb.Rule{a1}}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := filterRules(groups, tc.req)
+			testutil.Ok(t, err)
+			testutil.Equals(t, tc.want, got)
+		})
+	}
+
+	_, err := filterRules(groups, &rulespb.RulesRequest{MatcherString: []string{"{"}})
+	testutil.NotOk(t, err)
+}
@@ -131,6 +131,17 @@ func (r *Rule) GetLastEvaluation() time.Time {
 	}
 }
 
+func (r *Rule) GetHealth() string {
+	switch {
+	case r.GetRecording() != nil:
+		return r.GetRecording().Health
+	case r.GetAlert() != nil:
+		return r.GetAlert().Health
+	default:
+		return ""
+	}
+}
+
 // Compare compares recording and alerting rules r1 and r2 and returns:
 //
 //   < 0 if r1 < r2  if rule r1 is not equal and lexically before rule r2
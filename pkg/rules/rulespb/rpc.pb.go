@@ -36,16 +36,16 @@ var _ = time.Kitchen
 // proto package needs to be updated.
 const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
 
-/// AlertState represents state of the alert. Has to match 1:1 Prometheus AlertState:
+// / AlertState represents state of the alert. Has to match 1:1 Prometheus AlertState:
 //
 // StateInactive is the state of an alert that is neither firing nor pending.
-//StateInactive AlertState = iota
+// StateInactive AlertState = iota
 // StatePending is the state of an alert that has been active for less than
 // the configured threshold duration.
-//StatePending
+// StatePending
 // StateFiring is the state of an alert that has been active for longer than
 // the configured threshold duration.
-//StateFiring
+// StateFiring
 type AlertState int32
 
 const (
@@ -108,6 +108,15 @@ func (RulesRequest_Type) EnumDescriptor() ([]byte, []int) {
 type RulesRequest struct {
 	Type                    RulesRequest_Type               `protobuf:"varint,1,opt,name=type,proto3,enum=thanos.RulesRequest_Type" json:"type,omitempty"`
 	PartialResponseStrategy storepb.PartialResponseStrategy `protobuf:"varint,2,opt,name=partial_response_strategy,json=partialResponseStrategy,proto3,enum=thanos.PartialResponseStrategy" json:"partial_response_strategy,omitempty"`
+	/// matcher_string are series selectors, one of which has to match the labels of returned rules, like match[] of the
+	/// Prometheus HTTP API. Rule groups without returned rules are omitted if any of the filters below are set.
+	MatcherString []string `protobuf:"bytes,3,rep,name=matcher_string,json=matcherString,proto3" json:"matcher_string,omitempty"`
+	/// rule_name are the names of the returned rules.
+	RuleName []string `protobuf:"bytes,4,rep,name=rule_name,json=ruleName,proto3" json:"rule_name,omitempty"`
+	/// health is the health of the returned rules, i.e. 'ok', 'err' or 'unknown'.
+	Health string `protobuf:"bytes,5,opt,name=health,proto3" json:"health,omitempty"`
+	/// alert_state are the states of the returned alerting rules. Only alerting rules are returned if set.
+	AlertState []AlertState `protobuf:"varint,6,rep,packed,name=alert_state,json=alertState,proto3,enum=thanos.AlertState" json:"alert_state,omitempty"`
 }
 
 func (m *RulesRequest) Reset()         { *m = RulesRequest{} }
@@ -228,12 +237,12 @@ func (*RulesResponse) XXX_OneofWrappers() []interface{} {
 	}
 }
 
-/// RuleGroups is set of rule groups.
-/// This and below APIs are meant to be used for unmarshaling and marshsaling rules from/to Prometheus API.
-/// That's why json tag has to be customized and matching https://github.com/prometheus/prometheus/blob/c530b4b456cc5f9ec249f771dff187eb7715dc9b/web/api/v1/api.go#L955
-/// NOTE: See rules_custom_test.go for compatibility tests.
-///
-/// For rule parsing from YAML configuration other struct is used: https://github.com/prometheus/prometheus/blob/20b1f596f6fb16107ef0c244d240b0ad6da36829/pkg/rulefmt/rulefmt.go#L105
+// / RuleGroups is set of rule groups.
+// / This and below APIs are meant to be used for unmarshaling and marshsaling rules from/to Prometheus API.
+// / That's why json tag has to be customized and matching https://github.com/prometheus/prometheus/blob/c530b4b456cc5f9ec249f771dff187eb7715dc9b/web/api/v1/api.go#L955
+// / NOTE: See rules_custom_test.go for compatibility tests.
+// /
+// / For rule parsing from YAML configuration other struct is used: https://github.com/prometheus/prometheus/blob/20b1f596f6fb16107ef0c244d240b0ad6da36829/pkg/rulefmt/rulefmt.go#L105
 type RuleGroups struct {
 	Groups []*RuleGroup `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups"`
 }
@@ -271,7 +280,7 @@ func (m *RuleGroups) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_RuleGroups proto.InternalMessageInfo
 
-/// RuleGroup has info for rules which are part of a group.
+// / RuleGroup has info for rules which are part of a group.
 type RuleGroup struct {
 	Name                      string    `protobuf:"bytes,1,opt,name=name,proto3" json:"name"`
 	File                      string    `protobuf:"bytes,2,opt,name=file,proto3" json:"file"`
@@ -552,70 +561,75 @@ func init() {
 func init() { proto.RegisterFile("rules/rulespb/rpc.proto", fileDescriptor_91b1d28f30eb5efb) }
 
 var fileDescriptor_91b1d28f30eb5efb = []byte{
-	// 999 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x56, 0xcd, 0x6e, 0xdb, 0x46,
-	0x10, 0x26, 0x2d, 0x91, 0x12, 0xc7, 0x76, 0xa2, 0x6e, 0x62, 0x98, 0x56, 0x0a, 0xd1, 0x10, 0x90,
-	0xc2, 0x2d, 0x1a, 0xa9, 0xb0, 0x91, 0x14, 0x39, 0x15, 0xa2, 0xad, 0xc6, 0x02, 0x0c, 0x37, 0x58,
-	0x09, 0x3d, 0xa4, 0x07, 0x95, 0x92, 0x37, 0x32, 0x01, 0x8a, 0x64, 0x76, 0x57, 0x2e, 0xf4, 0x00,
-	0xbd, 0xe7, 0xdc, 0x17, 0xe9, 0xbd, 0x27, 0x1f, 0x73, 0xec, 0x49, 0x6d, 0xed, 0x9b, 0x0e, 0x7d,
-	0x86, 0x62, 0x77, 0x49, 0x51, 0x56, 0xe5, 0x3a, 0x69, 0xd5, 0x8b, 0x66, 0x77, 0xe6, 0x9b, 0xfd,
-	0x99, 0xf9, 0xf6, 0x13, 0x61, 0x9b, 0x8e, 0x02, 0xc2, 0xea, 0xf2, 0x37, 0xee, 0xd5, 0x69, 0xdc,
-	0xaf, 0xc5, 0x34, 0xe2, 0x11, 0x32, 0xf9, 0xb9, 0x17, 0x46, 0xac, 0xbc, 0xc3, 0x78, 0x44, 0x49,
-	0x5d, 0xfe, 0xc6, 0xbd, 0x3a, 0x1f, 0xc7, 0x84, 0x29, 0x48, 0x1a, 0x0a, 0xbc, 0x1e, 0x09, 0x16,
-	0x42, 0x0f, 0x07, 0xd1, 0x20, 0x92, 0xc3, 0xba, 0x18, 0x25, 0x5e, 0x67, 0x10, 0x45, 0x83, 0x80,
-	0xd4, 0xe5, 0xac, 0x37, 0x7a, 0x5d, 0xe7, 0xfe, 0x90, 0x30, 0xee, 0x0d, 0x63, 0x05, 0xa8, 0xfe,
-	0xa2, 0xc3, 0x06, 0x16, 0x47, 0xc1, 0xe4, 0xcd, 0x88, 0x30, 0x8e, 0x9e, 0x40, 0x5e, 0x2c, 0x6b,
-	0xeb, 0xbb, 0xfa, 0xde, 0xbd, 0xfd, 0x9d, 0x9a, 0x3a, 0x54, 0x6d, 0x1e, 0x53, 0xeb, 0x8c, 0x63,
-	0x82, 0x25, 0x0c, 0x7d, 0x07, 0x3b, 0xb1, 0x47, 0xb9, 0xef, 0x05, 0x5d, 0x4a, 0x58, 0x1c, 0x85,
-	0x8c, 0x74, 0x19, 0xa7, 0x1e, 0x27, 0x83, 0xb1, 0xbd, 0x26, 0xd7, 0x70, 0xd2, 0x35, 0x5e, 0x2a,
-	0x20, 0x4e, 0x70, 0xed, 0x04, 0x86, 0xb7, 0xe3, 0xe5, 0x81, 0xea, 0x27, 0x90, 0x17, 0x5b, 0xa1,
-	0x02, 0xe4, 0x1a, 0x27, 0x27, 0x25, 0x0d, 0x59, 0x60, 0x34, 0x4e, 0x9a, 0xb8, 0x53, 0xd2, 0x11,
-	0x80, 0x89, 0x9b, 0x87, 0xdf, 0xe0, 0xa3, 0xd2, 0x5a, 0xf5, 0x7b, 0xd8, 0x4c, 0xce, 0xa7, 0x16,
-	0x40, 0x9f, 0x82, 0x31, 0xa0, 0xd1, 0x28, 0x96, 0xb7, 0x58, 0xdf, 0xff, 0x68, 0xfe, 0x16, 0x2f,
-	0x44, 0xe0, 0x58, 0xc3, 0x0a, 0x81, 0xca, 0x50, 0xf8, 0xc1, 0xa3, 0xa1, 0x1f, 0x0e, 0xe4, 0x71,
-	0xad, 0x63, 0x0d, 0xa7, 0x0e, 0xb7, 0x08, 0x26, 0x25, 0x6c, 0x14, 0xf0, 0xea, 0x21, 0xc0, 0x2c,
-	0x97, 0xa1, 0xa7, 0x60, 0xca, 0x64, 0x66, 0xeb, 0xbb, 0xb9, 0xa5, 0xeb, 0xbb, 0x30, 0x9d, 0x38,
-	0x09, 0x08, 0x27, 0xb6, 0xfa, 0x67, 0x0e, 0xac, 0x19, 0x02, 0x7d, 0x0c, 0xf9, 0xd0, 0x1b, 0xaa,
-	0x42, 0x5b, 0x6e, 0x71, 0x3a, 0x71, 0xe4, 0x1c, 0xcb, 0x5f, 0x11, 0x7d, 0xed, 0x07, 0x44, 0x9d,
-	0x49, 0x45, 0xc5, 0x1c, 0xcb, 0x5f, 0xf4, 0x04, 0x0c, 0xc9, 0x1f, 0x3b, 0x27, 0xf7, 0xdf, 0x98,
-	0xdf, 0xdf, 0xb5, 0xa6, 0x13, 0x47, 0x85, 0xb1, 0x32, 0x68, 0x0f, 0x8a, 0x7e, 0xc8, 0x09, 0xbd,
-	0xf0, 0x02, 0x3b, 0xbf, 0xab, 0xef, 0xe9, 0xee, 0xc6, 0x74, 0xe2, 0xcc, 0x7c, 0x78, 0x36, 0x42,
-	0x18, 0x1e, 0x91, 0x0b, 0x2f, 0x18, 0x79, 0xdc, 0x8f, 0xc2, 0xee, 0xd9, 0x88, 0xaa, 0x01, 0x23,
-	0xfd, 0x28, 0x3c, 0x63, 0xb6, 0x21, 0x93, 0xd1, 0x74, 0xe2, 0xdc, 0xcb, 0x60, 0x1d, 0x7f, 0x48,
-	0xf0, 0x4e, 0x36, 0x3f, 0x4a, 0xb2, 0xda, 0x2a, 0x09, 0x75, 0xe1, 0x7e, 0xe0, 0x31, 0xde, 0xcd,
-	0x10, 0xb6, 0x29, 0xdb, 0x52, 0xae, 0x29, 0x76, 0xd6, 0x52, 0x76, 0xd6, 0x3a, 0x29, 0x3b, 0xdd,
-	0xf2, 0xe5, 0xc4, 0xd1, 0xc4, 0x3e, 0x22, 0xb5, 0x39, 0xcb, 0x7c, 0xfb, 0x9b, 0xa3, 0xe3, 0x05,
-	0x1f, 0x72, 0xc0, 0x08, 0xfc, 0xa1, 0xcf, 0x6d, 0x6b, 0x57, 0xdf, 0xcb, 0xa9, 0xfb, 0x4b, 0x07,
-	0x56, 0x06, 0x5d, 0xc0, 0xf6, 0x2d, 0xdc, 0xb3, 0x8b, 0xef, 0x45, 0x51, 0xf7, 0xd1, 0x74, 0xe2,
-	0xdc, 0x46, 0x53, 0x7c, 0xdb, 0xe2, 0xd5, 0x10, 0xf2, 0xa2, 0x23, 0xe8, 0x29, 0x58, 0x94, 0xf4,
-	0x23, 0x7a, 0x26, 0x58, 0xa6, 0x28, 0xb9, 0x35, 0x6b, 0x59, 0x1a, 0x10, 0xc8, 0x63, 0x0d, 0x67,
-	0x48, 0xf4, 0x18, 0x0c, 0x2f, 0x20, 0x94, 0x4b, 0x12, 0xac, 0xef, 0x6f, 0xa6, 0x29, 0x0d, 0xe1,
-	0x14, 0x0c, 0x96, 0xd1, 0x39, 0x96, 0xfe, 0x9c, 0x83, 0x4d, 0x19, 0x6c, 0x85, 0x8c, 0x7b, 0x61,
-	0x9f, 0xa0, 0xe7, 0x60, 0x4a, 0xb1, 0x60, 0x8b, 0x2f, 0xe1, 0xd5, 0x89, 0x70, 0xb7, 0x09, 0x77,
-	0xef, 0x25, 0x95, 0x4e, 0x80, 0x38, 0xb1, 0xe8, 0x18, 0xd6, 0xbd, 0x30, 0x8c, 0xb8, 0xac, 0x31,
-	0x4b, 0xce, 0xb0, 0x24, 0xff, 0x41, 0x92, 0x3f, 0x8f, 0xc6, 0xf3, 0x13, 0x74, 0x00, 0x06, 0xe3,
-	0x1e, 0x27, 0x76, 0x4e, 0x16, 0x1b, 0xdd, 0xb8, 0x47, 0x5b, 0x44, 0x54, 0xcf, 0x24, 0x08, 0x2b,
-	0x83, 0xda, 0x60, 0x79, 0x7d, 0xee, 0x5f, 0x90, 0xae, 0xc7, 0x25, 0x69, 0xef, 0xe0, 0xcb, 0x74,
-	0xe2, 0x20, 0x95, 0xd0, 0xe0, 0x9f, 0x47, 0x43, 0x9f, 0x93, 0x61, 0xcc, 0xc7, 0x92, 0x2f, 0xc5,
-	0xd4, 0x2f, 0x98, 0x22, 0x68, 0x43, 0x24, 0x91, 0x2d, 0xb5, 0xab, 0x74, 0x60, 0x65, 0xfe, 0x89,
-	0x29, 0xe6, 0xff, 0xc9, 0x94, 0x1f, 0x0d, 0x30, 0x64, 0x39, 0xb2, 0x62, 0xe9, 0x1f, 0x50, 0xac,
-	0x54, 0x4b, 0xd6, 0x96, 0x6a, 0x89, 0x03, 0xc6, 0x9b, 0x11, 0xa1, 0x63, 0x59, 0xff, 0xe4, 0xd6,
-	0xd2, 0x81, 0x95, 0x41, 0x5f, 0x42, 0xe9, 0x6f, 0x4f, 0x7d, 0x4e, 0x27, 0xd2, 0x18, 0xbe, 0x7f,
-	0xb6, 0xf0, 0xb4, 0x33, 0x7a, 0x19, 0xff, 0x91, 0x5e, 0xe6, 0xbf, 0xa7, 0xd7, 0x73, 0x30, 0xe5,
-	0x43, 0x60, 0x76, 0x41, 0xaa, 0xe1, 0xd6, 0x8d, 0x92, 0xa5, 0x4f, 0x41, 0x29, 0xb2, 0x02, 0xe2,
-	0xc4, 0xa2, 0x2a, 0x98, 0xe7, 0xc4, 0x0b, 0xf8, 0xb9, 0xd4, 0x01, 0x4b, 0x61, 0x94, 0x07, 0x27,
-	0x16, 0x3d, 0x03, 0x50, 0xf2, 0x45, 0x69, 0x44, 0xa5, 0xc4, 0x58, 0xee, 0xf6, 0x74, 0xe2, 0x3c,
-	0x90, 0x2a, 0x24, 0x9c, 0x19, 0xdd, 0xb0, 0x35, 0x73, 0xde, 0x25, 0xa5, 0xb0, 0x22, 0x29, 0x5d,
-	0x5f, 0xa5, 0x94, 0x56, 0x7f, 0xca, 0xc1, 0xe6, 0x0d, 0x45, 0xba, 0xe3, 0x6f, 0x6a, 0x46, 0xad,
-	0xb5, 0x5b, 0xa8, 0x95, 0x31, 0x24, 0xf7, 0xa1, 0x0c, 0xc9, 0x9a, 0x93, 0x7f, 0xcf, 0xe6, 0x18,
-	0xab, 0x6a, 0x8e, 0xb9, 0xa2, 0xe6, 0x14, 0x56, 0xd9, 0x9c, 0xcf, 0x0e, 0x00, 0x32, 0x15, 0x40,
-	0x1b, 0x50, 0x6c, 0x9d, 0x36, 0x0e, 0x3b, 0xad, 0x6f, 0x9b, 0x25, 0x0d, 0xad, 0x43, 0xe1, 0x65,
-	0xf3, 0xf4, 0xa8, 0x75, 0xfa, 0x42, 0x7d, 0x1b, 0x7d, 0xdd, 0xc2, 0x62, 0xbc, 0xb6, 0xff, 0x15,
-	0x18, 0xf2, 0xdb, 0x08, 0x3d, 0x4b, 0x07, 0x0f, 0x97, 0x7d, 0xd3, 0x95, 0xb7, 0x16, 0xbc, 0x4a,
-	0xa0, 0xbe, 0xd0, 0xdd, 0xc7, 0x97, 0x7f, 0x54, 0xb4, 0xcb, 0xab, 0x8a, 0xfe, 0xee, 0xaa, 0xa2,
-	0xff, 0x7e, 0x55, 0xd1, 0xdf, 0x5e, 0x57, 0xb4, 0x77, 0xd7, 0x15, 0xed, 0xd7, 0xeb, 0x8a, 0xf6,
-	0xaa, 0x90, 0x7c, 0xc7, 0xf6, 0x4c, 0x79, 0xb9, 0x83, 0xbf, 0x02, 0x00, 0x00, 0xff, 0xff, 0x8f,
-	0x24, 0x78, 0x7a, 0xdf, 0x0a, 0x00, 0x00,
+	// 1074 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x56, 0xc1, 0x6e, 0xdb, 0x46,
+	0x13, 0x16, 0x45, 0x91, 0x12, 0x47, 0xb6, 0xe3, 0x7f, 0x93, 0xfc, 0xa6, 0xed, 0x42, 0x14, 0x04,
+	0xb8, 0x50, 0x8b, 0x46, 0x2a, 0x64, 0x24, 0x45, 0x4e, 0x85, 0x69, 0xbb, 0xb1, 0x01, 0xc3, 0x0d,
+	0x56, 0x46, 0x0f, 0xe9, 0x41, 0x5d, 0xcb, 0x1b, 0x99, 0x00, 0x45, 0x32, 0xe4, 0xca, 0x85, 0x1f,
+	0xa0, 0xf7, 0x9c, 0xfb, 0x22, 0x7d, 0x05, 0x1f, 0x03, 0xf4, 0xd2, 0x93, 0xda, 0xda, 0x37, 0x1d,
+	0xfa, 0x0c, 0xc5, 0xce, 0x92, 0x94, 0xec, 0xca, 0x75, 0xd2, 0xba, 0x17, 0xee, 0xee, 0xcc, 0x37,
+	0xcb, 0xdd, 0x99, 0x6f, 0x3e, 0x12, 0x56, 0xe2, 0x91, 0xcf, 0x93, 0x36, 0x3e, 0xa3, 0xe3, 0x76,
+	0x1c, 0xf5, 0x5b, 0x51, 0x1c, 0x8a, 0x90, 0x98, 0xe2, 0x94, 0x05, 0x61, 0xb2, 0xb6, 0x9a, 0x88,
+	0x30, 0xe6, 0x6d, 0x7c, 0x46, 0xc7, 0x6d, 0x71, 0x1e, 0xf1, 0x44, 0x41, 0x32, 0x97, 0xcf, 0x8e,
+	0xb9, 0x7f, 0xc3, 0xf5, 0x68, 0x10, 0x0e, 0x42, 0x9c, 0xb6, 0xe5, 0x2c, 0xb5, 0x3a, 0x83, 0x30,
+	0x1c, 0xf8, 0xbc, 0x8d, 0xab, 0xe3, 0xd1, 0xeb, 0xb6, 0xf0, 0x86, 0x3c, 0x11, 0x6c, 0x18, 0x29,
+	0x40, 0xe3, 0xe7, 0x22, 0x2c, 0x50, 0x79, 0x14, 0xca, 0xdf, 0x8c, 0x78, 0x22, 0xc8, 0x13, 0x28,
+	0xc9, 0x6d, 0x6d, 0xad, 0xae, 0x35, 0x97, 0x3a, 0xab, 0x2d, 0x75, 0xa8, 0xd6, 0x2c, 0xa6, 0x75,
+	0x74, 0x1e, 0x71, 0x8a, 0x30, 0xf2, 0x2d, 0xac, 0x46, 0x2c, 0x16, 0x1e, 0xf3, 0x7b, 0x31, 0x4f,
+	0xa2, 0x30, 0x48, 0x78, 0x2f, 0x11, 0x31, 0x13, 0x7c, 0x70, 0x6e, 0x17, 0x71, 0x0f, 0x27, 0xdb,
+	0xe3, 0xa5, 0x02, 0xd2, 0x14, 0xd7, 0x4d, 0x61, 0x74, 0x25, 0x9a, 0xef, 0x20, 0x1b, 0xb0, 0x34,
+	0x64, 0xa2, 0x7f, 0xca, 0x63, 0xb9, 0xa7, 0x17, 0x0c, 0x6c, 0xbd, 0xae, 0x37, 0x2d, 0xba, 0x98,
+	0x5a, 0xbb, 0x68, 0x24, 0xeb, 0x60, 0xc9, 0x6c, 0xf6, 0x02, 0x36, 0xe4, 0x76, 0x09, 0x11, 0x15,
+	0x69, 0x38, 0x64, 0x43, 0x4e, 0xfe, 0x0f, 0xe6, 0x29, 0x67, 0xbe, 0x38, 0xb5, 0x8d, 0xba, 0xd6,
+	0xb4, 0x68, 0xba, 0x22, 0x9b, 0x50, 0x65, 0x3e, 0x8f, 0x45, 0x2f, 0x11, 0x4c, 0x70, 0xdb, 0xac,
+	0xeb, 0xcd, 0xa5, 0x0e, 0xc9, 0x8e, 0xba, 0x25, 0x5d, 0x5d, 0xe9, 0xa1, 0xc0, 0xf2, 0x79, 0xe3,
+	0x63, 0x28, 0xc9, 0xbb, 0x93, 0x32, 0xe8, 0x5b, 0x07, 0x07, 0xcb, 0x05, 0x62, 0x81, 0xb1, 0x75,
+	0xb0, 0x4b, 0x8f, 0x96, 0x35, 0x02, 0x60, 0xd2, 0xdd, 0xed, 0xaf, 0xe9, 0xce, 0x72, 0xb1, 0xf1,
+	0x1d, 0x2c, 0xa6, 0x09, 0x53, 0x37, 0x22, 0x9f, 0x80, 0x31, 0x88, 0xc3, 0x51, 0x84, 0x69, 0xad,
+	0x76, 0xfe, 0x37, 0x9b, 0xd6, 0x17, 0xd2, 0xb1, 0x57, 0xa0, 0x0a, 0x41, 0xd6, 0xa0, 0xfc, 0x3d,
+	0x8b, 0x03, 0x79, 0x5b, 0x99, 0x3f, 0x6b, 0xaf, 0x40, 0x33, 0x83, 0x5b, 0x01, 0x33, 0xe6, 0xc9,
+	0xc8, 0x17, 0x8d, 0x6d, 0x80, 0x3c, 0x36, 0x21, 0x4f, 0xc1, 0xc4, 0xe0, 0xc4, 0xd6, 0xea, 0xfa,
+	0xdc, 0xfd, 0x5d, 0x98, 0x8c, 0x9d, 0x14, 0x44, 0xd3, 0xb1, 0xf1, 0x87, 0x0e, 0x56, 0x8e, 0x20,
+	0x1f, 0x41, 0x09, 0x33, 0x28, 0x8f, 0x68, 0xb9, 0x95, 0xc9, 0xd8, 0xc1, 0x35, 0xc5, 0xa7, 0xf4,
+	0xbe, 0xf6, 0x7c, 0xae, 0xce, 0xa4, 0xbc, 0x72, 0x4d, 0xf1, 0x49, 0x9e, 0x80, 0x81, 0x84, 0xc6,
+	0x02, 0x55, 0x3b, 0x0b, 0xb3, 0xef, 0x77, 0xad, 0xc9, 0xd8, 0x51, 0x6e, 0xaa, 0x06, 0xd2, 0x84,
+	0x8a, 0x17, 0x08, 0x1e, 0x9f, 0x31, 0xdf, 0x2e, 0xd5, 0xb5, 0xa6, 0xe6, 0x2e, 0x4c, 0xc6, 0x4e,
+	0x6e, 0xa3, 0xf9, 0x8c, 0x50, 0x58, 0xe7, 0x67, 0xcc, 0x1f, 0x31, 0xe1, 0x85, 0x41, 0xef, 0x64,
+	0x14, 0xab, 0x49, 0xc2, 0xfb, 0x61, 0x70, 0x92, 0x60, 0x4d, 0x35, 0x97, 0x4c, 0xc6, 0xce, 0xd2,
+	0x14, 0x76, 0xe4, 0x0d, 0x39, 0x5d, 0x9d, 0xae, 0x77, 0xd2, 0xa8, 0xae, 0x0a, 0x22, 0x3d, 0x78,
+	0xe0, 0xb3, 0x44, 0xf4, 0xa6, 0x08, 0xdb, 0xc4, 0xb2, 0xac, 0xb5, 0x54, 0xbb, 0xb4, 0xb2, 0x76,
+	0x69, 0x1d, 0x65, 0xed, 0xe2, 0xae, 0x5d, 0x8c, 0x9d, 0x82, 0x7c, 0x8f, 0x0c, 0xdd, 0xcd, 0x23,
+	0xdf, 0xfe, 0xea, 0x68, 0xf4, 0x86, 0x8d, 0x38, 0x60, 0xf8, 0xde, 0xd0, 0x13, 0xb6, 0x55, 0xd7,
+	0x9a, 0xba, 0xba, 0x3f, 0x1a, 0xa8, 0x1a, 0xc8, 0x19, 0xac, 0xdc, 0xd2, 0x0c, 0x76, 0xe5, 0xbd,
+	0x7a, 0xc6, 0x5d, 0x9f, 0x8c, 0x9d, 0xdb, 0xfa, 0x86, 0xde, 0xb6, 0x79, 0x23, 0x80, 0x92, 0xac,
+	0x08, 0x79, 0x0a, 0x56, 0xcc, 0xfb, 0x61, 0x7c, 0x22, 0x59, 0xa6, 0x28, 0xf9, 0x38, 0x2f, 0x59,
+	0xe6, 0x90, 0xc8, 0xbd, 0x02, 0x9d, 0x22, 0xc9, 0x06, 0x18, 0xd8, 0x0c, 0x48, 0x82, 0x6a, 0x67,
+	0xf1, 0x5a, 0xb7, 0x48, 0x06, 0xa3, 0x77, 0x86, 0xa5, 0x3f, 0xe9, 0xb0, 0x88, 0xce, 0xfd, 0x20,
+	0x11, 0x2c, 0xe8, 0x73, 0xf2, 0x1c, 0x4c, 0x54, 0xaf, 0xe4, 0x66, 0x27, 0xbc, 0x3a, 0x90, 0xe6,
+	0x2e, 0x17, 0xee, 0x52, 0x9a, 0xe9, 0x14, 0x48, 0xd3, 0x91, 0xec, 0x41, 0x95, 0x05, 0x41, 0x28,
+	0x30, 0xc7, 0x49, 0x7a, 0x86, 0x39, 0xf1, 0x0f, 0xd3, 0xf8, 0x59, 0x34, 0x9d, 0x5d, 0x90, 0x4d,
+	0x30, 0x54, 0xd7, 0xeb, 0x98, 0xec, 0x39, 0x5d, 0xaf, 0x6a, 0x86, 0x20, 0xaa, 0x06, 0xd2, 0x05,
+	0x8b, 0xf5, 0x85, 0x77, 0xc6, 0x7b, 0x4c, 0x20, 0x69, 0xef, 0xe0, 0xcb, 0x64, 0xec, 0x10, 0x15,
+	0xb0, 0x25, 0x3e, 0x0b, 0x87, 0x9e, 0xe0, 0xc3, 0x48, 0x9c, 0x23, 0x5f, 0x2a, 0x99, 0x5d, 0x32,
+	0x45, 0xd2, 0x86, 0x2b, 0x71, 0x52, 0x6f, 0x45, 0x03, 0x55, 0xc3, 0xdf, 0x31, 0xc5, 0xfc, 0x2f,
+	0x99, 0xf2, 0x83, 0x01, 0x06, 0xa6, 0x63, 0x9a, 0x2c, 0xed, 0x03, 0x92, 0x95, 0x69, 0x49, 0x71,
+	0xae, 0x96, 0x38, 0x60, 0xbc, 0x19, 0xf1, 0xf8, 0x1c, 0xf3, 0x9f, 0xde, 0x1a, 0x0d, 0x54, 0x0d,
+	0xe4, 0x0b, 0x58, 0xfe, 0x4b, 0xab, 0xcf, 0xe8, 0x44, 0xe6, 0xa3, 0x0f, 0x4e, 0x6e, 0xb4, 0xf6,
+	0x94, 0x5e, 0xc6, 0xbf, 0xa4, 0x97, 0xf9, 0xcf, 0xe9, 0xf5, 0x1c, 0x4c, 0x6c, 0x84, 0xc4, 0x2e,
+	0xa3, 0x1a, 0x3e, 0xbe, 0x96, 0xb2, 0xac, 0x15, 0x94, 0x22, 0x2b, 0x20, 0x4d, 0x47, 0xd2, 0xc8,
+	0xbf, 0x56, 0x15, 0x4c, 0x0d, 0x62, 0x94, 0x25, 0xff, 0x72, 0x3d, 0x03, 0x50, 0xf2, 0x15, 0xc7,
+	0x61, 0x8c, 0x12, 0x63, 0xb9, 0x2b, 0x93, 0xb1, 0xf3, 0x10, 0x55, 0x48, 0x1a, 0xa7, 0x74, 0xa3,
+	0x56, 0x6e, 0xbc, 0x4b, 0x4a, 0xe1, 0x9e, 0xa4, 0xb4, 0x7a, 0x9f, 0x52, 0xda, 0xf8, 0x51, 0x87,
+	0xc5, 0x6b, 0x8a, 0x74, 0xc7, 0x67, 0x2a, 0xa7, 0x56, 0xf1, 0x16, 0x6a, 0x4d, 0x19, 0xa2, 0x7f,
+	0x28, 0x43, 0xa6, 0xc5, 0x29, 0xbd, 0x67, 0x71, 0x8c, 0xfb, 0x2a, 0x8e, 0x79, 0x4f, 0xc5, 0x29,
+	0xdf, 0x67, 0x71, 0x3e, 0xdd, 0x04, 0x98, 0xaa, 0x00, 0x59, 0x80, 0xca, 0xfe, 0xe1, 0xd6, 0xf6,
+	0xd1, 0xfe, 0x37, 0xbb, 0xcb, 0x05, 0x52, 0x85, 0xf2, 0xcb, 0xdd, 0xc3, 0x9d, 0xfd, 0xc3, 0x17,
+	0xea, 0xdf, 0xe8, 0xab, 0x7d, 0x2a, 0xe7, 0xc5, 0xce, 0x97, 0x60, 0xe0, 0xbf, 0x11, 0x79, 0x96,
+	0x4d, 0x1e, 0xcd, 0xfb, 0xc9, 0x5c, 0x7b, 0x7c, 0xc3, 0xaa, 0x04, 0xea, 0x73, 0xcd, 0xdd, 0xb8,
+	0xf8, 0xbd, 0x56, 0xb8, 0xb8, 0xac, 0x69, 0xef, 0x2e, 0x6b, 0xda, 0x6f, 0x97, 0x35, 0xed, 0xed,
+	0x55, 0xad, 0xf0, 0xee, 0xaa, 0x56, 0xf8, 0xe5, 0xaa, 0x56, 0x78, 0x55, 0x4e, 0x7f, 0xac, 0x8f,
+	0x4d, 0xbc, 0xdc, 0xe6, 0x9f, 0x01, 0x00, 0x00, 0xff, 0xff, 0xa0, 0x7c, 0xe2, 0x4e, 0x70, 0x0b,
+	0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -749,6 +763,49 @@ func (m *RulesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.AlertState) > 0 {
+		dAtA2 := make([]byte, len(m.AlertState)*10)
+		var j1 int
+		for _, num := range m.AlertState {
+			for num >= 1<<7 {
+				dAtA2[j1] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j1++
+			}
+			dAtA2[j1] = uint8(num)
+			j1++
+		}
+		i -= j1
+		copy(dAtA[i:], dAtA2[:j1])
+		i = encodeVarintRpc(dAtA, i, uint64(j1))
+		i--
+		dAtA[i] = 0x32
+	}
+	if len(m.Health) > 0 {
+		i -= len(m.Health)
+		copy(dAtA[i:], m.Health)
+		i = encodeVarintRpc(dAtA, i, uint64(len(m.Health)))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if len(m.RuleName) > 0 {
+		for iNdEx := len(m.RuleName) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.RuleName[iNdEx])
+			copy(dAtA[i:], m.RuleName[iNdEx])
+			i = encodeVarintRpc(dAtA, i, uint64(len(m.RuleName[iNdEx])))
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if len(m.MatcherString) > 0 {
+		for iNdEx := len(m.MatcherString) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.MatcherString[iNdEx])
+			copy(dAtA[i:], m.MatcherString[iNdEx])
+			i = encodeVarintRpc(dAtA, i, uint64(len(m.MatcherString[iNdEx])))
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
 	if m.PartialResponseStrategy != 0 {
 		i = encodeVarintRpc(dAtA, i, uint64(m.PartialResponseStrategy))
 		i--
@@ -896,12 +953,12 @@ func (m *RuleGroup) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0x40
 	}
-	n2, err2 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.LastEvaluation, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.LastEvaluation):])
-	if err2 != nil {
-		return 0, err2
+	n4, err4 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.LastEvaluation, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.LastEvaluation):])
+	if err4 != nil {
+		return 0, err4
 	}
-	i -= n2
-	i = encodeVarintRpc(dAtA, i, uint64(n2))
+	i -= n4
+	i = encodeVarintRpc(dAtA, i, uint64(n4))
 	i--
 	dAtA[i] = 0x32
 	if m.EvaluationDurationSeconds != 0 {
@@ -1054,12 +1111,12 @@ func (m *AlertInstance) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		dAtA[i] = 0x2a
 	}
 	if m.ActiveAt != nil {
-		n5, err5 := github_com_gogo_protobuf_types.StdTimeMarshalTo(*m.ActiveAt, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(*m.ActiveAt):])
-		if err5 != nil {
-			return 0, err5
+		n7, err7 := github_com_gogo_protobuf_types.StdTimeMarshalTo(*m.ActiveAt, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(*m.ActiveAt):])
+		if err7 != nil {
+			return 0, err7
 		}
-		i -= n5
-		i = encodeVarintRpc(dAtA, i, uint64(n5))
+		i -= n7
+		i = encodeVarintRpc(dAtA, i, uint64(n7))
 		i--
 		dAtA[i] = 0x22
 	}
@@ -1111,12 +1168,12 @@ func (m *Alert) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
-	n8, err8 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.LastEvaluation, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.LastEvaluation):])
-	if err8 != nil {
-		return 0, err8
+	n10, err10 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.LastEvaluation, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.LastEvaluation):])
+	if err10 != nil {
+		return 0, err10
 	}
-	i -= n8
-	i = encodeVarintRpc(dAtA, i, uint64(n8))
+	i -= n10
+	i = encodeVarintRpc(dAtA, i, uint64(n10))
 	i--
 	dAtA[i] = 0x5a
 	if m.EvaluationDurationSeconds != 0 {
@@ -1221,12 +1278,12 @@ func (m *RecordingRule) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
-	n11, err11 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.LastEvaluation, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.LastEvaluation):])
-	if err11 != nil {
-		return 0, err11
+	n13, err13 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.LastEvaluation, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.LastEvaluation):])
+	if err13 != nil {
+		return 0, err13
 	}
-	i -= n11
-	i = encodeVarintRpc(dAtA, i, uint64(n11))
+	i -= n13
+	i = encodeVarintRpc(dAtA, i, uint64(n13))
 	i--
 	dAtA[i] = 0x3a
 	if m.EvaluationDurationSeconds != 0 {
@@ -1299,6 +1356,29 @@ func (m *RulesRequest) Size() (n int) {
 	if m.PartialResponseStrategy != 0 {
 		n += 1 + sovRpc(uint64(m.PartialResponseStrategy))
 	}
+	if len(m.MatcherString) > 0 {
+		for _, s := range m.MatcherString {
+			l = len(s)
+			n += 1 + l + sovRpc(uint64(l))
+		}
+	}
+	if len(m.RuleName) > 0 {
+		for _, s := range m.RuleName {
+			l = len(s)
+			n += 1 + l + sovRpc(uint64(l))
+		}
+	}
+	l = len(m.Health)
+	if l > 0 {
+		n += 1 + l + sovRpc(uint64(l))
+	}
+	if len(m.AlertState) > 0 {
+		l = 0
+		for _, e := range m.AlertState {
+			l += sovRpc(uint64(e))
+		}
+		n += 1 + sovRpc(uint64(l)) + l
+	}
 	return n
 }
 
@@ -1602,6 +1682,171 @@ func (m *RulesRequest) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MatcherString", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.MatcherString = append(m.MatcherString, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RuleName", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.RuleName = append(m.RuleName, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Health", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Health = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
+			if wireType == 0 {
+				var v AlertState
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowRpc
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= AlertState(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.AlertState = append(m.AlertState, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowRpc
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthRpc
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthRpc
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				if elementCount != 0 && len(m.AlertState) == 0 {
+					m.AlertState = make([]AlertState, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v AlertState
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowRpc
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= AlertState(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.AlertState = append(m.AlertState, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field AlertState", wireType)
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
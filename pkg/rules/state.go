@@ -0,0 +1,304 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// alertForStateMetricName is the name of the series Prometheus restores the 'for' state of alerts from.
+const alertForStateMetricName = "ALERTS_FOR_STATE"
+
+// alertStateSnapshot is the 'for' state of the active alerts of a ruler at a given time.
+type alertStateSnapshot struct {
+	// Timestamp of the snapshot, in milliseconds.
+	Timestamp int64        `json:"timestamp"`
+	Alerts    []alertState `json:"alerts"`
+}
+
+type alertState struct {
+	// Labels of the ALERTS_FOR_STATE series of the alert.
+	Labels   labels.Labels `json:"labels"`
+	ActiveAt time.Time     `json:"active_at"`
+}
+
+// AlertStateObjectName returns the name of the object storing the alert state of the ruler with the given
+// external labels under the given prefix.
+func AlertStateObjectName(prefix string, lset labels.Labels) string {
+	return path.Join(prefix, fmt.Sprintf("%016x.json", lset.Hash()))
+}
+
+// AlertStatePersister persists the 'for' state of the active alerts of rule groups to object storage, and restores
+// it through the ALERTS_FOR_STATE series Prometheus rule managers restore alerts from on startup. It allows alerts
+// to keep their 'for' state when the ruler is rescheduled without its data directory, e.g. in stateless mode.
+type AlertStatePersister struct {
+	logger log.Logger
+	bkt    objstore.Bucket
+	name   string
+
+	mtx      sync.RWMutex
+	restored alertStateSnapshot
+}
+
+// NewAlertStatePersister returns an AlertStatePersister storing alert state in the given object of the bucket.
+func NewAlertStatePersister(logger log.Logger, bkt objstore.Bucket, name string) *AlertStatePersister {
+	return &AlertStatePersister{logger: logger, bkt: bkt, name: name}
+}
+
+// Load reads the last persisted alert state, to be served by the Queryable. It is a no-op if no state was persisted.
+func (p *AlertStatePersister) Load(ctx context.Context) error {
+	rc, err := p.bkt.Get(ctx, p.name)
+	if err != nil {
+		if p.bkt.IsObjNotFoundErr(err) {
+			level.Info(p.logger).Log("msg", "no persisted alert state found", "object", p.name)
+			return nil
+		}
+		return errors.Wrapf(err, "get alert state %s", p.name)
+	}
+	defer runutil.CloseWithLogOnErr(p.logger, rc, "alert state reader")
+
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return errors.Wrapf(err, "read alert state %s", p.name)
+	}
+	var s alertStateSnapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.Wrapf(err, "unmarshal alert state %s", p.name)
+	}
+
+	p.mtx.Lock()
+	p.restored = s
+	p.mtx.Unlock()
+
+	level.Info(p.logger).Log("msg", "loaded persisted alert state", "object", p.name, "alerts", len(s.Alerts), "time", timestamp.Time(s.Timestamp))
+	return nil
+}
+
+// Save persists the state of the active alerts of the given groups at the given time.
+func (p *AlertStatePersister) Save(ctx context.Context, groups []Group, ts time.Time) error {
+	s := alertStateSnapshot{Timestamp: timestamp.FromTime(ts), Alerts: []alertState{}}
+	for _, g := range groups {
+		for _, r := range g.Rules() {
+			ar, ok := r.(*rules.AlertingRule)
+			if !ok {
+				continue
+			}
+			ar.ForEachActiveAlert(func(a *rules.Alert) {
+				s.Alerts = append(s.Alerts, alertState{Labels: forStateLabels(ar, a), ActiveAt: a.ActiveAt})
+			})
+		}
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "marshal alert state")
+	}
+	return errors.Wrapf(p.bkt.Upload(ctx, p.name, bytes.NewReader(b)), "upload alert state %s", p.name)
+}
+
+// forStateLabels returns the labels of the ALERTS_FOR_STATE series of the given alert, the same way Prometheus
+// does when restoring it.
+func forStateLabels(r *rules.AlertingRule, a *rules.Alert) labels.Labels {
+	lb := labels.NewBuilder(r.Labels())
+	for _, l := range a.Labels {
+		lb.Set(l.Name, l.Value)
+	}
+	lb.Set(labels.MetricName, alertForStateMetricName)
+	lb.Set(labels.AlertName, r.Name())
+	return lb.Labels()
+}
+
+// Queryable returns a queryable serving the ALERTS_FOR_STATE series of the loaded alert state, with a single sample
+// at the time of the snapshot. The series of the given queryable, if any, are merged with them.
+func (p *AlertStatePersister) Queryable(q storage.Queryable) storage.Queryable {
+	return storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		p.mtx.RLock()
+		s := p.restored
+		p.mtx.RUnlock()
+
+		sq := &alertStateQuerier{snapshot: s, mint: mint, maxt: maxt}
+		if q == nil {
+			return sq, nil
+		}
+		lq, err := q.Querier(ctx, mint, maxt)
+		if err != nil {
+			return nil, err
+		}
+		return storage.NewMergeQuerier([]storage.Querier{lq, sq}, nil, storage.ChainedSeriesMerge), nil
+	})
+}
+
+type alertStateQuerier struct {
+	snapshot   alertStateSnapshot
+	mint, maxt int64
+}
+
+func (q *alertStateQuerier) Select(_ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	if q.snapshot.Timestamp < q.mint || q.snapshot.Timestamp > q.maxt {
+		return storage.EmptySeriesSet()
+	}
+
+	var series []storage.Series
+Alerts:
+	for _, a := range q.snapshot.Alerts {
+		for _, m := range matchers {
+			if !m.Matches(a.Labels.Get(m.Name)) {
+				continue Alerts
+			}
+		}
+		v := float64(a.ActiveAt.Unix())
+		series = append(series, storage.NewListSeries(a.Labels, []tsdbutil.Sample{forStateSample{t: q.snapshot.Timestamp, v: v}}))
+	}
+	sort.Slice(series, func(i, j int) bool { return labels.Compare(series[i].Labels(), series[j].Labels()) < 0 })
+	return &listSeriesSet{series: series, i: -1}
+}
+
+func (q *alertStateQuerier) LabelValues(string, ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, errors.New("not implemented")
+}
+
+func (q *alertStateQuerier) LabelNames(...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, errors.New("not implemented")
+}
+
+func (q *alertStateQuerier) Close() error { return nil }
+
+type forStateSample struct {
+	t int64
+	v float64
+}
+
+func (s forStateSample) T() int64   { return s.t }
+func (s forStateSample) V() float64 { return s.v }
+
+type listSeriesSet struct {
+	series []storage.Series
+	i      int
+}
+
+func (s *listSeriesSet) Next() bool {
+	s.i++
+	return s.i < len(s.series)
+}
+
+func (s *listSeriesSet) At() storage.Series         { return s.series[s.i] }
+func (s *listSeriesSet) Err() error                 { return nil }
+func (s *listSeriesSet) Warnings() storage.Warnings { return nil }
+
+// MatrixQueryFunc evaluates the query of a range vector at the given time, returning the raw samples of its series.
+type MatrixQueryFunc func(ctx context.Context, q string, t time.Time) (promql.Matrix, error)
+
+// QueryAPIAlertStateQueryable returns a queryable serving the ALERTS_FOR_STATE series selected through the query API
+// with the given function, e.g. the series remote written by a stateless ruler before its restart. Labels of the
+// series not selected by the matchers, like external labels, are dropped, and only the series with the latest sample
+// is kept when several of them end up with the same labels, e.g. series of several replicas. The series of the given
+// queryable, if any, are merged with them.
+func QueryAPIAlertStateQueryable(query MatrixQueryFunc, q storage.Queryable) storage.Queryable {
+	return storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		qq := &queryAPIAlertStateQuerier{ctx: ctx, query: query, mint: mint, maxt: maxt}
+		if q == nil {
+			return qq, nil
+		}
+		lq, err := q.Querier(ctx, mint, maxt)
+		if err != nil {
+			return nil, err
+		}
+		return storage.NewMergeQuerier([]storage.Querier{lq, qq}, nil, storage.ChainedSeriesMerge), nil
+	})
+}
+
+type queryAPIAlertStateQuerier struct {
+	ctx        context.Context
+	query      MatrixQueryFunc
+	mint, maxt int64
+}
+
+func (q *queryAPIAlertStateQuerier) Select(_ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	var (
+		names       = map[string]struct{}{}
+		strMatchers = make([]string, 0, len(matchers))
+		forState    bool
+	)
+	for _, m := range matchers {
+		if m.Name == labels.MetricName && m.Type == labels.MatchEqual && m.Value == alertForStateMetricName {
+			forState = true
+		}
+		names[m.Name] = struct{}{}
+		strMatchers = append(strMatchers, m.String())
+	}
+	// Only the 'for' state of alerts is restored from the query API.
+	if !forState || q.maxt <= q.mint {
+		return storage.EmptySeriesSet()
+	}
+
+	expr := fmt.Sprintf("{%s}[%s]", strings.Join(strMatchers, ","), model.Duration(time.Duration(q.maxt-q.mint)*time.Millisecond))
+	matrix, err := q.query(q.ctx, expr, timestamp.Time(q.maxt))
+	if err != nil {
+		return storage.ErrSeriesSet(errors.Wrapf(err, "query %s", expr))
+	}
+
+	latest := map[uint64]promql.Series{}
+	for _, s := range matrix {
+		if len(s.Points) == 0 {
+			continue
+		}
+		lb := labels.NewBuilder(s.Metric)
+		for _, l := range s.Metric {
+			if _, ok := names[l.Name]; !ok {
+				lb.Del(l.Name)
+			}
+		}
+		s.Metric = lb.Labels()
+
+		h := s.Metric.Hash()
+		if l, ok := latest[h]; ok && l.Points[len(l.Points)-1].T >= s.Points[len(s.Points)-1].T {
+			continue
+		}
+		latest[h] = s
+	}
+
+	series := make([]storage.Series, 0, len(latest))
+	for _, s := range latest {
+		samples := make([]tsdbutil.Sample, 0, len(s.Points))
+		for _, p := range s.Points {
+			samples = append(samples, forStateSample{t: p.T, v: p.V})
+		}
+		series = append(series, storage.NewListSeries(s.Metric, samples))
+	}
+	sort.Slice(series, func(i, j int) bool { return labels.Compare(series[i].Labels(), series[j].Labels()) < 0 })
+	return &listSeriesSet{series: series, i: -1}
+}
+
+func (q *queryAPIAlertStateQuerier) LabelValues(string, ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, errors.New("not implemented")
+}
+
+func (q *queryAPIAlertStateQuerier) LabelNames(...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, errors.New("not implemented")
+}
+
+func (q *queryAPIAlertStateQuerier) Close() error { return nil }
@@ -0,0 +1,143 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package rules
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestAlertStatePersister(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	name := AlertStateObjectName("rule-state", labels.FromStrings("replica", "1"))
+
+	newGroup := func(q storage.Queryable) *rules.Group {
+		expr, err := parser.ParseExpr("up == 0")
+		testutil.Ok(t, err)
+		opts := &rules.ManagerOptions{
+			Context:    ctx,
+			Logger:     log.NewNopLogger(),
+			Appendable: nopAppendable{},
+			Queryable:  q,
+			QueryFunc: func(context.Context, string, time.Time) (promql.Vector, error) {
+				return promql.Vector{{Metric: labels.FromStrings("__name__", "up", "job", "a")}}, nil
+			},
+			NotifyFunc:      func(context.Context, string, ...*rules.Alert) {},
+			OutageTolerance: time.Hour,
+		}
+		return rules.NewGroup(rules.GroupOptions{
+			Name:     "group",
+			File:     "file.yaml",
+			Interval: time.Minute,
+			Rules: []rules.Rule{
+				rules.NewAlertingRule("Down", expr, time.Hour, labels.FromStrings("severity", "page"), nil, nil, "", false, log.NewNopLogger()),
+			},
+			Opts: opts,
+		})
+	}
+
+	start := time.Unix(10000, 0)
+
+	// Nothing is restored until a state was persisted.
+	p := NewAlertStatePersister(log.NewNopLogger(), bkt, name)
+	testutil.Ok(t, p.Load(ctx))
+	g := newGroup(p.Queryable(nil))
+	g.Eval(ctx, start)
+	g.RestoreForState(start)
+	testutil.Equals(t, start, g.AlertingRules()[0].ActiveAlerts()[0].ActiveAt)
+
+	// The alert is pending for 20 minutes before the state is persisted.
+	g.Eval(ctx, start.Add(20*time.Minute))
+	testutil.Ok(t, p.Save(ctx, []Group{{Group: g}}, start.Add(20*time.Minute)))
+
+	// A new ruler restores the state after 10 minutes of downtime, shifting it by the downtime.
+	p = NewAlertStatePersister(log.NewNopLogger(), bkt, name)
+	testutil.Ok(t, p.Load(ctx))
+	restart := start.Add(30 * time.Minute)
+	g = newGroup(p.Queryable(nil))
+	g.Eval(ctx, restart)
+	g.RestoreForState(restart)
+	testutil.Equals(t, start.Add(10*time.Minute).UTC(), g.AlertingRules()[0].ActiveAlerts()[0].ActiveAt)
+
+	// States older than the outage tolerance are not restored.
+	restart = start.Add(2 * time.Hour)
+	g = newGroup(p.Queryable(nil))
+	g.Eval(ctx, restart)
+	g.RestoreForState(restart)
+	testutil.Equals(t, restart, g.AlertingRules()[0].ActiveAlerts()[0].ActiveAt)
+}
+
+func TestQueryAPIAlertStateQueryable(t *testing.T) {
+	ctx := context.Background()
+	start := time.Unix(10000, 0)
+
+	var queries []string
+	query := func(_ context.Context, q string, ts time.Time) (promql.Matrix, error) {
+		queries = append(queries, q)
+		testutil.Equals(t, start.Add(30*time.Minute).UTC(), ts)
+		return promql.Matrix{
+			{
+				Metric: labels.FromStrings("__name__", "ALERTS_FOR_STATE", "alertname", "Down", "job", "a", "replica", "0", "severity", "page"),
+				Points: []promql.Point{{T: timestamp.FromTime(start.Add(15 * time.Minute)), V: float64(start.Add(-time.Hour).Unix())}},
+			},
+			{
+				Metric: labels.FromStrings("__name__", "ALERTS_FOR_STATE", "alertname", "Down", "job", "a", "replica", "1", "severity", "page"),
+				Points: []promql.Point{
+					{T: timestamp.FromTime(start.Add(10 * time.Minute)), V: float64(start.Unix())},
+					{T: timestamp.FromTime(start.Add(20 * time.Minute)), V: float64(start.Unix())},
+				},
+			},
+		}, nil
+	}
+
+	expr, err := parser.ParseExpr("up == 0")
+	testutil.Ok(t, err)
+	g := rules.NewGroup(rules.GroupOptions{
+		Name:     "group",
+		File:     "file.yaml",
+		Interval: time.Minute,
+		Rules: []rules.Rule{
+			rules.NewAlertingRule("Down", expr, time.Hour, labels.FromStrings("severity", "page"), nil, nil, "", false, log.NewNopLogger()),
+		},
+		Opts: &rules.ManagerOptions{
+			Context:    ctx,
+			Logger:     log.NewNopLogger(),
+			Appendable: nopAppendable{},
+			Queryable:  QueryAPIAlertStateQueryable(query, nil),
+			QueryFunc: func(context.Context, string, time.Time) (promql.Vector, error) {
+				return promql.Vector{{Metric: labels.FromStrings("__name__", "up", "job", "a")}}, nil
+			},
+			NotifyFunc:      func(context.Context, string, ...*rules.Alert) {},
+			OutageTolerance: time.Hour,
+		},
+	})
+
+	// The state of the latest series is restored, shifted by the 10 minutes since its last sample.
+	restart := start.Add(30 * time.Minute)
+	g.Eval(ctx, restart)
+	g.RestoreForState(restart)
+	testutil.Equals(t, start.Add(10*time.Minute).UTC(), g.AlertingRules()[0].ActiveAlerts()[0].ActiveAt)
+	testutil.Equals(t, []string{`{__name__="ALERTS_FOR_STATE",alertname="Down",job="a",severity="page"}[1h]`}, queries)
+
+	// Other series are not queried.
+	q, err := QueryAPIAlertStateQueryable(query, nil).Querier(ctx, 0, timestamp.FromTime(restart))
+	testutil.Ok(t, err)
+	ss := q.Select(false, nil, labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"))
+	testutil.Assert(t, !ss.Next())
+	testutil.Ok(t, ss.Err())
+	testutil.Equals(t, 1, len(queries))
+}
@@ -83,6 +83,25 @@ func Repeat(interval time.Duration, stopc <-chan struct{}, f func() error) error
 	}
 }
 
+// RepeatOrTrigger executes f every interval seconds, and whenever trigger receives, until stopc is closed or f returns
+// an error. It executes f once right after being called.
+func RepeatOrTrigger(interval time.Duration, stopc <-chan struct{}, trigger <-chan struct{}, f func() error) error {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	for {
+		if err := f(); err != nil {
+			return err
+		}
+		select {
+		case <-stopc:
+			return nil
+		case <-tick.C:
+		case <-trigger:
+		}
+	}
+}
+
 // Retry executes f every interval seconds until timeout or no error is returned from f.
 func Retry(interval time.Duration, stopc <-chan struct{}, f func() error) error {
 	return RetryWithLog(log.NewNopLogger(), interval, stopc, f)
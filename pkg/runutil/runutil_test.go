@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/thanos-io/thanos/pkg/testutil"
@@ -125,6 +126,27 @@ func TestCloseMoreThanOnce(t *testing.T) {
 	testutil.Equals(t, true, lc.WasCalled)
 }
 
+func TestRepeatOrTrigger(t *testing.T) {
+	var (
+		stopc   = make(chan struct{})
+		trigger = make(chan struct{})
+		runs    int
+	)
+	go func() {
+		trigger <- struct{}{}
+		trigger <- struct{}{}
+		close(stopc)
+	}()
+	testutil.Ok(t, RepeatOrTrigger(time.Hour, stopc, trigger, func() error {
+		runs++
+		return nil
+	}))
+	testutil.Equals(t, 3, runs)
+
+	err := errors.New("failed")
+	testutil.Equals(t, err, RepeatOrTrigger(time.Hour, nil, nil, func() error { return err }))
+}
+
 func TestDeleteAll(t *testing.T) {
 	dir, err := ioutil.TempDir("", "example")
 	testutil.Ok(t, err)
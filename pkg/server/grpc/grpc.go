@@ -30,6 +30,8 @@ import (
 	"google.golang.org/grpc/status"
 
 	"github.com/thanos-io/thanos/pkg/component"
+	// Register the compressors clients may use.
+	_ "github.com/thanos-io/thanos/pkg/extgrpc/zstd"
 	"github.com/thanos-io/thanos/pkg/prober"
 	"github.com/thanos-io/thanos/pkg/tracing"
 )
@@ -126,6 +128,9 @@ func (s *Server) ListenAndServe() error {
 	if err != nil {
 		return errors.Wrapf(err, "listen gRPC on address %s", s.opts.listen)
 	}
+	if s.opts.ipFilter != nil {
+		l = s.opts.ipFilter.Listener(l)
+	}
 	s.listener = l
 
 	level.Info(s.logger).Log("msg", "listening for serving gRPC", "address", s.opts.listen)
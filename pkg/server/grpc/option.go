@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+
+	"github.com/thanos-io/thanos/pkg/ipfilter"
 )
 
 type options struct {
@@ -19,6 +21,7 @@ type options struct {
 	network     string
 
 	tlsConfig *tls.Config
+	ipFilter  *ipfilter.Filter
 
 	grpcOpts []grpc.ServerOption
 }
@@ -88,3 +91,10 @@ func WithMaxConnAge(t time.Duration) Option {
 		o.maxConnAge = t
 	})
 }
+
+// WithIPFilter restricts the clients of the gRPC server with the given filter. A nil filter allows all clients.
+func WithIPFilter(f *ipfilter.Filter) Option {
+	return optionFunc(func(o *options) {
+		o.ipFilter = f
+	})
+}
@@ -5,6 +5,8 @@ package http
 
 import (
 	"context"
+	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/pprof"
 
@@ -49,13 +51,18 @@ func New(logger log.Logger, reg *prometheus.Registry, comp component.Component,
 	registerMetrics(mux, reg)
 	registerProbes(mux, prober, logger)
 	registerProfiler(mux)
+	registerStatus(mux, options.flags, options.config, options.statuses, logger)
 
-	var h http.Handler
+	var h http.Handler = mux
+	if options.authentication != nil {
+		h = authenticated(mux, options.authentication)
+	}
+	if options.ipFilter != nil {
+		h = options.ipFilter.Middleware(h)
+	}
 	if options.enableH2C {
 		h2s := &http2.Server{}
-		h = h2c.NewHandler(mux, h2s)
-	} else {
-		h = mux
+		h = h2c.NewHandler(h, h2s)
 	}
 
 	return &Server{
@@ -75,7 +82,16 @@ func (s *Server) ListenAndServe() error {
 	if err != nil {
 		return errors.Wrap(err, "server could not be started")
 	}
-	return errors.Wrap(toolkit_web.ListenAndServe(s.srv, s.opts.tlsConfigPath, s.logger), "serve HTTP and metrics")
+	if s.opts.ipFilter == nil {
+		return errors.Wrap(toolkit_web.ListenAndServe(s.srv, s.opts.tlsConfigPath, s.logger), "serve HTTP and metrics")
+	}
+
+	l, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return errors.Wrap(err, "listen HTTP")
+	}
+	defer l.Close()
+	return errors.Wrap(toolkit_web.Serve(s.opts.ipFilter.Listener(l), s.srv, s.opts.tlsConfigPath, s.logger), "serve HTTP and metrics")
 }
 
 // Shutdown gracefully shuts down the server by waiting,
@@ -131,3 +147,68 @@ func registerProbes(mux *http.ServeMux, p *prober.HTTPProbe, logger log.Logger)
 		mux.Handle("/-/ready", p.ReadyHandler(logger))
 	}
 }
+
+// authenticated returns the handler which passes requests of the probes directly to the mux and all others through the
+// authentication middleware, so that orchestrators can probe the server without credentials.
+func authenticated(mux *http.ServeMux, mw func(http.Handler) http.Handler) http.Handler {
+	auth := mw(mux)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/-/healthy", "/-/ready":
+			mux.ServeHTTP(w, r)
+		default:
+			auth.ServeHTTP(w, r)
+		}
+	})
+}
+
+// statusResponse is the response of the status endpoints, in the format of the Prometheus HTTP API.
+type statusResponse struct {
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+func registerStatus(mux *http.ServeMux, flags map[string]string, config func() (map[string]string, error), statuses map[string]func() (interface{}, error), logger log.Logger) {
+	if flags != nil {
+		mux.HandleFunc("/api/v1/status/flags", func(w http.ResponseWriter, _ *http.Request) {
+			respondStatus(w, http.StatusOK, statusResponse{Status: "success", Data: flags}, logger)
+		})
+	}
+	if config != nil {
+		mux.HandleFunc("/api/v1/status/config", func(w http.ResponseWriter, _ *http.Request) {
+			c, err := config()
+			if err != nil {
+				respondStatus(w, http.StatusInternalServerError, statusResponse{Status: "error", ErrorType: "internal", Error: err.Error()}, logger)
+				return
+			}
+			respondStatus(w, http.StatusOK, statusResponse{Status: "success", Data: c}, logger)
+		})
+	}
+	for name, f := range statuses {
+		f := f
+		mux.HandleFunc("/api/v1/status/"+name, func(w http.ResponseWriter, _ *http.Request) {
+			data, err := f()
+			if err != nil {
+				respondStatus(w, http.StatusInternalServerError, statusResponse{Status: "error", ErrorType: "internal", Error: err.Error()}, logger)
+				return
+			}
+			respondStatus(w, http.StatusOK, statusResponse{Status: "success", Data: data}, logger)
+		})
+	}
+}
+
+func respondStatus(w http.ResponseWriter, code int, resp statusResponse, logger log.Logger) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		level.Error(logger).Log("msg", "error marshaling status response", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(b); err != nil {
+		level.Error(logger).Log("msg", "error writing status response", "err", err)
+	}
+}
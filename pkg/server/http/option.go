@@ -6,6 +6,8 @@ package http
 import (
 	"net/http"
 	"time"
+
+	"github.com/thanos-io/thanos/pkg/ipfilter"
 )
 
 type options struct {
@@ -14,6 +16,13 @@ type options struct {
 	tlsConfigPath string
 	mux           *http.ServeMux
 	enableH2C     bool
+
+	flags    map[string]string
+	config   func() (map[string]string, error)
+	statuses map[string]func() (interface{}, error)
+
+	authentication func(http.Handler) http.Handler
+	ipFilter       *ipfilter.Filter
 }
 
 // Option overrides behavior of Server.
@@ -61,3 +70,37 @@ func WithMux(mux *http.ServeMux) Option {
 		o.mux = mux
 	})
 }
+
+// WithStatus exposes the given flags on /api/v1/status/flags and the configuration returned by config on
+// /api/v1/status/config. Secrets have to be redacted by the caller.
+func WithStatus(flags map[string]string, config func() (map[string]string, error)) Option {
+	return optionFunc(func(o *options) {
+		o.flags = flags
+		o.config = config
+	})
+}
+
+// WithStatusFunc exposes the data returned by f on /api/v1/status/<name>.
+func WithStatusFunc(name string, f func() (interface{}, error)) Option {
+	return optionFunc(func(o *options) {
+		if o.statuses == nil {
+			o.statuses = map[string]func() (interface{}, error){}
+		}
+		o.statuses[name] = f
+	})
+}
+
+// WithAuthentication wraps all endpoints of the server, except the probes, with the given authentication middleware.
+// A nil middleware disables the authentication.
+func WithAuthentication(mw func(http.Handler) http.Handler) Option {
+	return optionFunc(func(o *options) {
+		o.authentication = mw
+	})
+}
+
+// WithIPFilter restricts the clients of the server with the given filter. A nil filter allows all clients.
+func WithIPFilter(f *ipfilter.Filter) Option {
+	return optionFunc(func(o *options) {
+		o.ipFilter = f
+	})
+}
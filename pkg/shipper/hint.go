@@ -0,0 +1,51 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package shipper
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// uploadHintTimeout is the timeout of each upload hint request.
+const uploadHintTimeout = 10 * time.Second
+
+// NotifyUploads sends a POST request to each of the given URLs, e.g. the /api/v1/blocks/sync endpoints of store
+// gateways, to tell them new blocks were uploaded, so that they load them without waiting for their next periodic
+// sync. Failures are only logged, as the blocks are loaded by the periodic syncs anyway.
+func NotifyUploads(ctx context.Context, logger log.Logger, urls []string) {
+	for _, u := range urls {
+		if err := notifyUpload(ctx, u); err != nil {
+			level.Warn(logger).Log("msg", "sending upload hint failed", "url", u, "err", err)
+		}
+	}
+}
+
+func notifyUpload(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, uploadHintTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "send request")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
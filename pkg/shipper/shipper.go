@@ -14,6 +14,7 @@ import (
 	"path"
 	"path/filepath"
 	"sort"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -37,6 +38,8 @@ type metrics struct {
 	uploads           prometheus.Counter
 	uploadFailures    prometheus.Counter
 	uploadedCompacted prometheus.Gauge
+	deferredUploads   prometheus.Counter
+	blackout          prometheus.Gauge
 }
 
 func newMetrics(reg prometheus.Registerer, uploadCompacted bool) *metrics {
@@ -58,6 +61,14 @@ func newMetrics(reg prometheus.Registerer, uploadCompacted bool) *metrics {
 		Name: "thanos_shipper_upload_failures_total",
 		Help: "Total number of block upload failures",
 	})
+	m.deferredUploads = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "thanos_shipper_deferred_uploads_total",
+		Help: "Total number of block uploads deferred because of an upload blackout window",
+	})
+	m.blackout = promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+		Name: "thanos_shipper_upload_blackout",
+		Help: "If 1 it means uploads are deferred because of an upload blackout window.",
+	})
 	uploadCompactedGaugeOpts := prometheus.GaugeOpts{
 		Name: "thanos_shipper_upload_compacted_done",
 		Help: "If 1 it means shipper uploaded all compacted blocks from the filesystem.",
@@ -83,6 +94,36 @@ type Shipper struct {
 	uploadCompacted        bool
 	allowOutOfOrderUploads bool
 	hashFunc               metadata.HashFunc
+
+	uploadBandwidthLimit int64
+	blackoutWindows      []BlackoutWindow
+	uploadHintURLs       []string
+	now                  func() time.Time
+}
+
+// Option configures the shipper.
+type Option func(s *Shipper)
+
+// WithUploadBandwidthLimit limits uploads to the given number of bytes per second. Uploads are not limited if the
+// limit is not positive.
+func WithUploadBandwidthLimit(bytesPerSecond int64) Option {
+	return func(s *Shipper) {
+		s.uploadBandwidthLimit = bytesPerSecond
+	}
+}
+
+// WithUploadBlackoutWindows defers the uploads of blocks to the end of the given windows.
+func WithUploadBlackoutWindows(windows []BlackoutWindow) Option {
+	return func(s *Shipper) {
+		s.blackoutWindows = windows
+	}
+}
+
+// WithUploadHintURLs makes the shipper notify the given URLs with NotifyUploads after syncs which uploaded blocks.
+func WithUploadHintURLs(urls []string) Option {
+	return func(s *Shipper) {
+		s.uploadHintURLs = urls
+	}
 }
 
 // New creates a new shipper that detects new TSDB blocks in dir and uploads them to
@@ -98,6 +139,7 @@ func New(
 	uploadCompacted bool,
 	allowOutOfOrderUploads bool,
 	hashFunc metadata.HashFunc,
+	opts ...Option,
 ) *Shipper {
 	if logger == nil {
 		logger = log.NewNopLogger()
@@ -106,7 +148,7 @@ func New(
 		lbls = func() labels.Labels { return nil }
 	}
 
-	return &Shipper{
+	s := &Shipper{
 		logger:                 logger,
 		dir:                    dir,
 		bucket:                 bucket,
@@ -116,7 +158,26 @@ func New(
 		allowOutOfOrderUploads: allowOutOfOrderUploads,
 		uploadCompacted:        uploadCompacted,
 		hashFunc:               hashFunc,
+		now:                    time.Now,
 	}
+	for _, o := range opts {
+		o(s)
+	}
+	if s.uploadBandwidthLimit > 0 {
+		s.bucket = newBandwidthLimitedBucket(s.bucket, s.uploadBandwidthLimit)
+	}
+	return s
+}
+
+// inBlackout returns true if uploads are deferred because of a blackout window.
+func (s *Shipper) inBlackout() bool {
+	now := s.now()
+	for _, w := range s.blackoutWindows {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
 }
 
 // Timestamps returns the minimum timestamp for which data is available and the highest timestamp
@@ -211,8 +272,17 @@ func (c *lazyOverlapChecker) IsOverlapping(ctx context.Context, newMeta tsdb.Blo
 		}
 	}
 
+	// Blocks made only of sources of another block, like the sources of compacted blocks uploaded before, are
+	// deduplicated by the compactor, so they do not count as overlaps.
+	all := append([]tsdb.BlockMeta{newMeta}, c.metas...)
+	metas := make([]tsdb.BlockMeta, 0, len(all))
+	for i, m := range all {
+		if !isDuplicate(m, all, i) {
+			metas = append(metas, m)
+		}
+	}
+
 	// TODO(bwplotka) so confusing! we need to sort it first. Add comment to TSDB code.
-	metas := append([]tsdb.BlockMeta{newMeta}, c.metas...)
 	sort.Slice(metas, func(i, j int) bool {
 		return metas[i].MinTime < metas[j].MinTime
 	})
@@ -247,23 +317,48 @@ func (s *Shipper) Sync(ctx context.Context) (uploaded int, err error) {
 		hasUploaded[id] = struct{}{}
 	}
 
-	// Reset the uploaded slice so we can rebuild it only with blocks that still exist locally.
+	// Reset the uploaded slice so we can rebuild it only with blocks that still exist locally, and the uploaded
+	// sources of local compacted blocks.
 	meta.Uploaded = nil
+	inMeta := map[ulid.ULID]struct{}{}
+	markUploaded := func(ids ...ulid.ULID) {
+		for _, id := range ids {
+			if _, ok := inMeta[id]; ok {
+				continue
+			}
+			inMeta[id] = struct{}{}
+			meta.Uploaded = append(meta.Uploaded, id)
+		}
+	}
 
 	var (
 		checker    = newLazyOverlapChecker(s.logger, s.bucket, s.labels)
 		uploadErrs int
+		deferred   int
+		blackout   = s.inBlackout()
 	)
+	if blackout {
+		s.metrics.blackout.Set(1)
+	} else {
+		s.metrics.blackout.Set(0)
+	}
 
 	metas, err := s.blockMetasFromOldest()
 	if err != nil {
 		return 0, err
 	}
 	for _, m := range metas {
+		// Keep the uploaded sources of local blocks, which tell if blocks compacted from them need to be uploaded.
+		for _, id := range m.Compaction.Sources {
+			if _, ok := hasUploaded[id]; ok {
+				markUploaded(id)
+			}
+		}
+
 		// Do not sync a block if we already uploaded or ignored it. If it's no longer found in the bucket,
 		// it was generally removed by the compaction process.
 		if _, uploaded := hasUploaded[m.ULID]; uploaded {
-			meta.Uploaded = append(meta.Uploaded, m.ULID)
+			markUploaded(m.ULID)
 			continue
 		}
 
@@ -278,6 +373,13 @@ func (s *Shipper) Sync(ctx context.Context) (uploaded int, err error) {
 			if !s.uploadCompacted {
 				continue
 			}
+
+			// Blocks compacted locally by Prometheus are not worth uploading if all their sources were uploaded already.
+			if len(m.Compaction.Sources) > 0 && containsAll(hasUploaded, m.Compaction.Sources) {
+				level.Debug(s.logger).Log("msg", "skipping compacted block with all sources uploaded", "block", m.ULID)
+				markUploaded(m.ULID)
+				continue
+			}
 		}
 
 		// Check against bucket if the meta file for this block exists.
@@ -286,7 +388,14 @@ func (s *Shipper) Sync(ctx context.Context) (uploaded int, err error) {
 			return 0, errors.Wrap(err, "check exists")
 		}
 		if ok {
-			meta.Uploaded = append(meta.Uploaded, m.ULID)
+			markUploaded(m.ULID)
+			continue
+		}
+
+		// Blocks are uploaded once the blackout window is over, which keeps uploads in order.
+		if blackout {
+			deferred++
+			s.metrics.deferredUploads.Inc()
 			continue
 		}
 
@@ -308,7 +417,10 @@ func (s *Shipper) Sync(ctx context.Context) (uploaded int, err error) {
 			uploadErrs++
 			continue
 		}
-		meta.Uploaded = append(meta.Uploaded, m.ULID)
+		// Uploaded compacted blocks hold the data of all their sources. Those that were uploaded already are
+		// deduplicated by the compactor.
+		markUploaded(m.ULID)
+		markUploaded(m.Compaction.Sources...)
 		uploaded++
 		s.metrics.uploads.Inc()
 	}
@@ -317,17 +429,54 @@ func (s *Shipper) Sync(ctx context.Context) (uploaded int, err error) {
 	}
 
 	s.metrics.dirSyncs.Inc()
+	if uploaded > 0 {
+		NotifyUploads(ctx, s.logger, s.uploadHintURLs)
+	}
 	if uploadErrs > 0 {
 		s.metrics.uploadFailures.Add(float64(uploadErrs))
 		return uploaded, errors.Errorf("failed to sync %v blocks", uploadErrs)
 	}
 
+	if deferred > 0 {
+		level.Info(s.logger).Log("msg", "deferred block uploads because of an upload blackout window", "blocks", deferred)
+		return uploaded, nil
+	}
 	if s.uploadCompacted {
 		s.metrics.uploadedCompacted.Set(1)
 	}
 	return uploaded, nil
 }
 
+// isDuplicate returns true if the sources of m are a subset of the sources of another block of metas than the i-th.
+func isDuplicate(m tsdb.BlockMeta, metas []tsdb.BlockMeta, i int) bool {
+	if len(m.Compaction.Sources) == 0 {
+		return false
+	}
+	for j, o := range metas {
+		if j == i || len(o.Compaction.Sources) <= len(m.Compaction.Sources) || o.MinTime > m.MinTime || o.MaxTime < m.MaxTime {
+			continue
+		}
+		sources := make(map[ulid.ULID]struct{}, len(o.Compaction.Sources))
+		for _, id := range o.Compaction.Sources {
+			sources[id] = struct{}{}
+		}
+		if containsAll(sources, m.Compaction.Sources) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAll returns true if all ids are in set.
+func containsAll(set map[ulid.ULID]struct{}, ids []ulid.ULID) bool {
+	for _, id := range ids {
+		if _, ok := set[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 // sync uploads the block if not exists in remote storage.
 // TODO(khyatisoneji): Double check if block does not have deletion-mark.json for some reason, otherwise log it or return error.
 func (s *Shipper) upload(ctx context.Context, meta *metadata.Meta) error {
@@ -351,7 +500,7 @@ func (s *Shipper) upload(ctx context.Context, meta *metadata.Meta) error {
 	}()
 
 	dir := filepath.Join(s.dir, meta.ULID.String())
-	if err := hardlinkBlock(dir, updir); err != nil {
+	if err := block.HardlinkBlock(dir, updir); err != nil {
 		return errors.Wrap(err, "hard link block")
 	}
 	// Attach current labels and write a new meta file with Thanos extensions.
@@ -402,34 +551,6 @@ func (s *Shipper) blockMetasFromOldest() (metas []*metadata.Meta, _ error) {
 	return metas, nil
 }
 
-func hardlinkBlock(src, dst string) error {
-	chunkDir := filepath.Join(dst, block.ChunksDirname)
-
-	if err := os.MkdirAll(chunkDir, 0750); err != nil {
-		return errors.Wrap(err, "create chunks dir")
-	}
-
-	fis, err := ioutil.ReadDir(filepath.Join(src, block.ChunksDirname))
-	if err != nil {
-		return errors.Wrap(err, "read chunk dir")
-	}
-	files := make([]string, 0, len(fis))
-	for _, fi := range fis {
-		files = append(files, fi.Name())
-	}
-	for i, fn := range files {
-		files[i] = filepath.Join(block.ChunksDirname, fn)
-	}
-	files = append(files, block.MetaFilename, block.IndexFilename)
-
-	for _, fn := range files {
-		if err := os.Link(filepath.Join(src, fn), filepath.Join(dst, fn)); err != nil {
-			return errors.Wrapf(err, "hard link file %s", fn)
-		}
-	}
-	return nil
-}
-
 // Meta defines the format thanos.shipper.json file that the shipper places in the data directory.
 type Meta struct {
 	Version  int         `json:"version"`
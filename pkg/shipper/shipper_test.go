@@ -8,14 +8,18 @@ import (
 	"io/ioutil"
 	"math"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"path/filepath"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/oklog/ulid"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/tsdb"
 
@@ -212,3 +216,130 @@ func TestShipperAddsSegmentFiles(t *testing.T) {
 
 	testutil.Equals(t, []string{segmentFile}, meta.Thanos.SegmentFiles)
 }
+
+func TestShipperSyncLocallyCompactedBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shipper-test")
+	testutil.Ok(t, err)
+	defer func() {
+		testutil.Ok(t, os.RemoveAll(dir))
+	}()
+
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	lbls := labels.FromStrings("prometheus", "prom-1")
+	s := New(nil, nil, dir, bkt, func() labels.Labels { return lbls }, metadata.TestSource, true, false, metadata.NoneFunc)
+
+	writeBlock := func(id ulid.ULID, mint, maxt int64, lvl int, sources ...ulid.ULID) {
+		blockDir := path.Join(dir, id.String())
+		testutil.Ok(t, os.MkdirAll(path.Join(blockDir, block.ChunksDirname), os.ModePerm))
+		testutil.Ok(t, metadata.Meta{
+			BlockMeta: tsdb.BlockMeta{
+				ULID:       id,
+				MinTime:    mint,
+				MaxTime:    maxt,
+				Version:    1,
+				Stats:      tsdb.BlockStats{NumSamples: 1},
+				Compaction: tsdb.BlockMetaCompaction{Level: lvl, Sources: sources},
+			},
+		}.WriteToDir(log.NewNopLogger(), blockDir))
+		testutil.Ok(t, ioutil.WriteFile(filepath.Join(blockDir, block.IndexFilename), []byte("index"), 0666))
+		testutil.Ok(t, ioutil.WriteFile(filepath.Join(blockDir, block.ChunksDirname, "000001"), []byte("chunks"), 0666))
+	}
+	uploadedMeta := func() []ulid.ULID {
+		m, err := ReadMetaFile(dir)
+		testutil.Ok(t, err)
+		return m.Uploaded
+	}
+
+	a, b, c, d, e := ulid.MustNew(1, nil), ulid.MustNew(2, nil), ulid.MustNew(3, nil), ulid.MustNew(4, nil), ulid.MustNew(5, nil)
+	writeBlock(a, 0, 1000, 1, a)
+	writeBlock(b, 1000, 2000, 1, b)
+	n, err := s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, n)
+
+	// Prometheus compacts blocks which were all uploaded already, so the compacted block is not uploaded.
+	writeBlock(c, 0, 2000, 2, a, b)
+	testutil.Ok(t, os.RemoveAll(path.Join(dir, a.String())))
+	testutil.Ok(t, os.RemoveAll(path.Join(dir, b.String())))
+	n, err = s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, n)
+	testutil.Equals(t, []ulid.ULID{a, b, c}, uploadedMeta())
+
+	// Prometheus compacts them further with a block which was not uploaded yet, so the compacted block is uploaded
+	// even though it overlaps with its uploaded sources.
+	writeBlock(e, 0, 3000, 3, a, b, d)
+	testutil.Ok(t, os.RemoveAll(path.Join(dir, c.String())))
+	n, err = s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, n)
+	testutil.Equals(t, []ulid.ULID{a, b, e, d}, uploadedMeta())
+	ok, err := bkt.Exists(ctx, path.Join(e.String(), block.MetaFilename))
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok, "compacted block was not uploaded")
+
+	// Compacted blocks overlapping with other blocks than their sources are still not uploaded.
+	f, g, h := ulid.MustNew(6, nil), ulid.MustNew(7, nil), ulid.MustNew(8, nil)
+	writeBlock(g, 2500, 4000, 2, f, g)
+	_, err = s.Sync(ctx)
+	testutil.NotOk(t, err)
+	testutil.Ok(t, os.RemoveAll(path.Join(dir, g.String())))
+	writeBlock(h, 3000, 4000, 2, f, h)
+	n, err = s.Sync(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, n)
+}
+
+func TestShipperSyncDefersUploadsInBlackoutWindows(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shipper-test")
+	testutil.Ok(t, err)
+	defer func() {
+		testutil.Ok(t, os.RemoveAll(dir))
+	}()
+
+	w, err := ParseBlackoutWindow("Mon-Fri 09:00-17:00")
+	testutil.Ok(t, err)
+	bkt := objstore.NewInMemBucket()
+	lbls := labels.FromStrings("prometheus", "prom-1")
+	hints := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutil.Equals(t, http.MethodPost, r.Method)
+		hints++
+	}))
+	defer srv.Close()
+	s := New(nil, nil, dir, bkt, func() labels.Labels { return lbls }, metadata.TestSource, false, false, metadata.NoneFunc, WithUploadBlackoutWindows([]BlackoutWindow{w}), WithUploadHintURLs([]string{srv.URL}))
+
+	id := ulid.MustNew(1, nil)
+	blockDir := path.Join(dir, id.String())
+	testutil.Ok(t, os.MkdirAll(path.Join(blockDir, block.ChunksDirname), os.ModePerm))
+	testutil.Ok(t, metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{
+			ULID:    id,
+			MaxTime: 2000,
+			MinTime: 1000,
+			Version: 1,
+			Stats:   tsdb.BlockStats{NumSamples: 1},
+		},
+	}.WriteToDir(log.NewNopLogger(), blockDir))
+	testutil.Ok(t, ioutil.WriteFile(filepath.Join(blockDir, block.IndexFilename), []byte("index"), 0666))
+	testutil.Ok(t, ioutil.WriteFile(filepath.Join(blockDir, block.ChunksDirname, "000001"), []byte("chunks"), 0666))
+
+	// 2022-01-03 is a Monday.
+	s.now = func() time.Time { return time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC) }
+	uploaded, err := s.Sync(context.Background())
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, uploaded)
+	testutil.Equals(t, 1.0, promtest.ToFloat64(s.metrics.deferredUploads))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(s.metrics.blackout))
+	testutil.Equals(t, 0, len(bkt.Objects()))
+	testutil.Equals(t, 0, hints)
+
+	s.now = func() time.Time { return time.Date(2022, 1, 3, 17, 0, 0, 0, time.UTC) }
+	uploaded, err = s.Sync(context.Background())
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, uploaded)
+	testutil.Equals(t, 1.0, promtest.ToFloat64(s.metrics.deferredUploads))
+	testutil.Equals(t, 0.0, promtest.ToFloat64(s.metrics.blackout))
+	testutil.Equals(t, 1, hints)
+}
@@ -0,0 +1,153 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package shipper
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// BlackoutWindow is a daily time window, in UTC, during which blocks are not uploaded.
+type BlackoutWindow struct {
+	// Weekdays the window starts on.
+	Weekdays [7]bool
+	// Start and End are the offsets of the window from midnight. Windows ending before they start end on the
+	// next day.
+	Start, End time.Duration
+}
+
+// ParseBlackoutWindow parses a window of the form "[<weekdays>] <HH:MM>-<HH:MM>", where weekdays are a comma
+// separated list of days or ranges of days like "Mon-Fri,Sun". Windows without weekdays apply every day.
+func ParseBlackoutWindow(s string) (BlackoutWindow, error) {
+	var w BlackoutWindow
+
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+		for i := range w.Weekdays {
+			w.Weekdays[i] = true
+		}
+	case 2:
+		for _, days := range strings.Split(fields[0], ",") {
+			from, to := days, days
+			if i := strings.Index(days, "-"); i >= 0 {
+				from, to = days[:i], days[i+1:]
+			}
+			fromDay, ok := weekdays[strings.ToLower(from)]
+			if !ok {
+				return w, errors.Errorf("invalid weekday %q in blackout window %q", from, s)
+			}
+			toDay, ok := weekdays[strings.ToLower(to)]
+			if !ok {
+				return w, errors.Errorf("invalid weekday %q in blackout window %q", to, s)
+			}
+			for d := fromDay; ; d = (d + 1) % 7 {
+				w.Weekdays[d] = true
+				if d == toDay {
+					break
+				}
+			}
+		}
+	default:
+		return w, errors.Errorf("invalid blackout window %q, expected [<weekdays>] <HH:MM>-<HH:MM>", s)
+	}
+
+	times := strings.Split(fields[len(fields)-1], "-")
+	if len(times) != 2 {
+		return w, errors.Errorf("invalid time range in blackout window %q, expected <HH:MM>-<HH:MM>", s)
+	}
+	var err error
+	if w.Start, err = parseTimeOfDay(times[0]); err != nil {
+		return w, errors.Wrapf(err, "blackout window %q", s)
+	}
+	if w.End, err = parseTimeOfDay(times[1]); err != nil {
+		return w, errors.Wrapf(err, "blackout window %q", s)
+	}
+	if w.Start == w.End {
+		return w, errors.Errorf("empty blackout window %q", s)
+	}
+	return w, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	// 24:00 can end windows lasting until midnight.
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains returns true if t is within the window.
+func (w BlackoutWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	if w.Start < w.End {
+		return w.Weekdays[t.Weekday()] && sinceMidnight >= w.Start && sinceMidnight < w.End
+	}
+	// The window spans midnight, so it started either today or the day before.
+	if w.Weekdays[t.Weekday()] && sinceMidnight >= w.Start {
+		return true
+	}
+	return w.Weekdays[(t.Weekday()+6)%7] && sinceMidnight < w.End
+}
+
+// bandwidthLimitedBucket limits the number of bytes uploaded per second to the wrapped bucket.
+type bandwidthLimitedBucket struct {
+	objstore.Bucket
+
+	limiter *rate.Limiter
+}
+
+func newBandwidthLimitedBucket(bkt objstore.Bucket, bytesPerSecond int64) *bandwidthLimitedBucket {
+	return &bandwidthLimitedBucket{
+		Bucket:  bkt,
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), int(bytesPerSecond)),
+	}
+}
+
+func (b *bandwidthLimitedBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	return b.Bucket.Upload(ctx, name, &bandwidthLimitedReader{ctx: ctx, r: r, limiter: b.limiter})
+}
+
+type bandwidthLimitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (r *bandwidthLimitedReader) Read(p []byte) (int, error) {
+	// Reads can't be larger than the burst of the limiter, otherwise they would never be allowed.
+	if len(p) > r.limiter.Burst() {
+		p = p[:r.limiter.Burst()]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, errors.Wrap(werr, "wait for upload bandwidth limit")
+		}
+	}
+	return n, err
+}
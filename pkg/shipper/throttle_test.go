@@ -0,0 +1,79 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package shipper
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestParseBlackoutWindow(t *testing.T) {
+	w, err := ParseBlackoutWindow("Mon-Fri 09:00-17:30")
+	testutil.Ok(t, err)
+	testutil.Equals(t, BlackoutWindow{
+		Weekdays: [7]bool{false, true, true, true, true, true, false},
+		Start:    9 * time.Hour,
+		End:      17*time.Hour + 30*time.Minute,
+	}, w)
+
+	w, err = ParseBlackoutWindow("fri-mon,wed 22:00-24:00")
+	testutil.Ok(t, err)
+	testutil.Equals(t, [7]bool{true, true, false, true, false, true, true}, w.Weekdays)
+	testutil.Equals(t, 24*time.Hour, w.End)
+
+	w, err = ParseBlackoutWindow("23:00-01:00")
+	testutil.Ok(t, err)
+	testutil.Equals(t, [7]bool{true, true, true, true, true, true, true}, w.Weekdays)
+
+	for _, s := range []string{"", "Mon-Fri", "Mon-Fri 09:00", "Mon-Fry 09:00-17:00", "09:00-25:00", "09:00-09:00", "Mon 09:00-17:00 UTC"} {
+		_, err := ParseBlackoutWindow(s)
+		testutil.NotOk(t, err, s)
+	}
+}
+
+func TestBlackoutWindow_Contains(t *testing.T) {
+	// 2022-01-03 is a Monday.
+	at := func(day, hour, min int) time.Time { return time.Date(2022, 1, 2+day, hour, min, 0, 0, time.UTC) }
+
+	w, err := ParseBlackoutWindow("Mon-Fri 09:00-17:00")
+	testutil.Ok(t, err)
+	testutil.Assert(t, w.Contains(at(1, 9, 0)))
+	testutil.Assert(t, w.Contains(at(5, 16, 59)))
+	testutil.Assert(t, !w.Contains(at(1, 8, 59)))
+	testutil.Assert(t, !w.Contains(at(1, 17, 0)))
+	testutil.Assert(t, !w.Contains(at(6, 12, 0)))
+	// Times are compared in UTC.
+	testutil.Assert(t, w.Contains(at(1, 12, 0).In(time.FixedZone("UTC+10", 10*3600))))
+
+	// Windows spanning midnight end on the next day.
+	w, err = ParseBlackoutWindow("Fri 22:00-02:00")
+	testutil.Ok(t, err)
+	testutil.Assert(t, w.Contains(at(5, 23, 0)))
+	testutil.Assert(t, w.Contains(at(6, 1, 59)))
+	testutil.Assert(t, !w.Contains(at(6, 2, 0)))
+	testutil.Assert(t, !w.Contains(at(5, 1, 0)))
+	testutil.Assert(t, !w.Contains(at(6, 23, 0)))
+}
+
+func TestBandwidthLimitedBucket(t *testing.T) {
+	bkt := newBandwidthLimitedBucket(objstore.NewInMemBucket(), 1000)
+
+	start := time.Now()
+	// The first 1000 bytes are within the burst, the others wait for the limiter.
+	testutil.Ok(t, bkt.Upload(context.Background(), "obj", bytes.NewReader(make([]byte, 1500))))
+	testutil.Assert(t, time.Since(start) >= 400*time.Millisecond, "upload was not limited")
+
+	ok, err := bkt.Exists(context.Background(), "obj")
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testutil.NotOk(t, bkt.Upload(ctx, "obj2", bytes.NewReader(make([]byte, 1500))))
+}
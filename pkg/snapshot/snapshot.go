@@ -0,0 +1,336 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package snapshot records manifests of the blocks and their markers in a bucket at a point in time, and verifies
+// and restores the bucket against them.
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// ManifestVersion1 is the version of the manifest format.
+const ManifestVersion1 = 1
+
+// markers are the filenames of the markers of blocks, which are recorded separately from the block objects.
+var markers = map[string]struct{}{
+	metadata.DeletionMarkFilename:     {},
+	metadata.NoCompactMarkFilename:    {},
+	metadata.NoDownsampleMarkFilename: {},
+}
+
+// Manifest lists the blocks of a bucket with their objects and markers at the time of the snapshot.
+type Manifest struct {
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	Blocks  []Block   `json:"blocks"`
+}
+
+// Block is a block of a manifest.
+type Block struct {
+	ID ulid.ULID `json:"id"`
+	// Objects are the objects of the block, without markers, sorted by name.
+	Objects []Object `json:"objects"`
+	// Markers are the filenames of the markers of the block, sorted.
+	Markers []string `json:"markers,omitempty"`
+}
+
+// Object is an object of a block.
+type Object struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// Create lists the blocks of the bucket with their objects and markers. Blocks without meta.json, i.e. blocks which
+// are being uploaded or partially deleted, are not included.
+func Create(ctx context.Context, logger log.Logger, bkt objstore.BucketReader) (*Manifest, error) {
+	m := &Manifest{Version: ManifestVersion1, Time: time.Now().UTC()}
+	err := bkt.Iter(ctx, "", func(name string) error {
+		id, ok := block.IsBlockDir(name)
+		if !ok {
+			return nil
+		}
+		b, err := listBlock(ctx, bkt, id)
+		if err != nil {
+			return err
+		}
+		if !hasObject(b, path.Join(id.String(), block.MetaFilename)) {
+			level.Debug(logger).Log("msg", "skipping block without meta.json", "id", id)
+			return nil
+		}
+		m.Blocks = append(m.Blocks, b)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list blocks")
+	}
+	sort.Slice(m.Blocks, func(i, j int) bool {
+		return m.Blocks[i].ID.Compare(m.Blocks[j].ID) < 0
+	})
+	return m, nil
+}
+
+// listBlock returns the objects and markers of the given block in the bucket.
+func listBlock(ctx context.Context, bkt objstore.BucketReader, id ulid.ULID) (Block, error) {
+	b := Block{ID: id}
+	err := bkt.Iter(ctx, id.String(), func(name string) error {
+		if _, ok := markers[path.Base(name)]; ok {
+			b.Markers = append(b.Markers, path.Base(name))
+			return nil
+		}
+		attrs, err := bkt.Attributes(ctx, name)
+		if err != nil {
+			if bkt.IsObjNotFoundErr(err) {
+				// Deleted concurrently.
+				return nil
+			}
+			return errors.Wrapf(err, "get attributes of %s", name)
+		}
+		b.Objects = append(b.Objects, Object{Name: name, Size: attrs.Size})
+		return nil
+	}, objstore.WithRecursiveIter)
+	if err != nil {
+		return Block{}, errors.Wrapf(err, "list objects of block %s", id)
+	}
+	sort.Slice(b.Objects, func(i, j int) bool { return b.Objects[i].Name < b.Objects[j].Name })
+	sort.Strings(b.Markers)
+	return b, nil
+}
+
+func hasObject(b Block, name string) bool {
+	for _, o := range b.Objects {
+		if o.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes the manifest as JSON to w.
+func (m *Manifest) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(m)
+}
+
+// Read reads a manifest written by Write from r.
+func Read(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, errors.Wrap(err, "decode manifest")
+	}
+	if m.Version != ManifestVersion1 {
+		return nil, errors.Errorf("unexpected manifest version %d", m.Version)
+	}
+	return &m, nil
+}
+
+// DifferenceType is the type of a difference between a bucket and a manifest.
+type DifferenceType string
+
+const (
+	// BlockMissing means that a block of the manifest doesn't exist in the bucket or has no meta.json.
+	BlockMissing DifferenceType = "block missing"
+	// BlockAdded means that a block of the bucket isn't in the manifest.
+	BlockAdded DifferenceType = "block added"
+	// ObjectMissing means that an object of a block of the manifest doesn't exist in the bucket.
+	ObjectMissing DifferenceType = "object missing"
+	// ObjectChanged means that an object of a block has a different size than in the manifest.
+	ObjectChanged DifferenceType = "object changed"
+	// ObjectAdded means that a block of the bucket has an object which isn't in the manifest.
+	ObjectAdded DifferenceType = "object added"
+	// MarkerMissing means that a marker of a block of the manifest doesn't exist in the bucket.
+	MarkerMissing DifferenceType = "marker missing"
+	// MarkerAdded means that a block of the bucket has a marker which isn't in the manifest.
+	MarkerAdded DifferenceType = "marker added"
+)
+
+// Difference is a difference between a bucket and a manifest.
+type Difference struct {
+	ID ulid.ULID
+	// Name is the name of the differing object or marker in the bucket. It's empty for differences of whole blocks.
+	Name string
+	Type DifferenceType
+}
+
+// Diff returns the differences of the actual manifest from the expected one, sorted by block and name.
+func Diff(expected, actual *Manifest) []Difference {
+	actualBlocks := make(map[ulid.ULID]Block, len(actual.Blocks))
+	for _, b := range actual.Blocks {
+		actualBlocks[b.ID] = b
+	}
+
+	var diffs []Difference
+	for _, exp := range expected.Blocks {
+		act, ok := actualBlocks[exp.ID]
+		if !ok {
+			diffs = append(diffs, Difference{ID: exp.ID, Type: BlockMissing})
+			continue
+		}
+		delete(actualBlocks, exp.ID)
+
+		sizes := make(map[string]int64, len(act.Objects))
+		for _, o := range act.Objects {
+			sizes[o.Name] = o.Size
+		}
+		for _, o := range exp.Objects {
+			size, ok := sizes[o.Name]
+			switch {
+			case !ok:
+				diffs = append(diffs, Difference{ID: exp.ID, Name: o.Name, Type: ObjectMissing})
+			case size != o.Size:
+				diffs = append(diffs, Difference{ID: exp.ID, Name: o.Name, Type: ObjectChanged})
+			}
+			delete(sizes, o.Name)
+		}
+		for name := range sizes {
+			diffs = append(diffs, Difference{ID: exp.ID, Name: name, Type: ObjectAdded})
+		}
+
+		actMarkers := make(map[string]struct{}, len(act.Markers))
+		for _, mk := range act.Markers {
+			actMarkers[mk] = struct{}{}
+		}
+		for _, mk := range exp.Markers {
+			if _, ok := actMarkers[mk]; !ok {
+				diffs = append(diffs, Difference{ID: exp.ID, Name: path.Join(exp.ID.String(), mk), Type: MarkerMissing})
+			}
+			delete(actMarkers, mk)
+		}
+		for mk := range actMarkers {
+			diffs = append(diffs, Difference{ID: exp.ID, Name: path.Join(exp.ID.String(), mk), Type: MarkerAdded})
+		}
+	}
+	for id := range actualBlocks {
+		diffs = append(diffs, Difference{ID: id, Type: BlockAdded})
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if c := diffs[i].ID.Compare(diffs[j].ID); c != 0 {
+			return c < 0
+		}
+		return diffs[i].Name < diffs[j].Name
+	})
+	return diffs
+}
+
+// Verify returns the differences of the bucket from the manifest.
+func Verify(ctx context.Context, logger log.Logger, bkt objstore.BucketReader, m *Manifest) ([]Difference, error) {
+	actual, err := Create(ctx, logger, bkt)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(m, actual), nil
+}
+
+// Restore reverts the differences of the bucket from the manifest, by copying the missing and changed objects and
+// markers from the backup bucket and deleting the markers which were added since. Blocks and objects which were
+// added are kept. It returns the differences which were, or with dryRun would be, reverted.
+func Restore(ctx context.Context, logger log.Logger, bkt objstore.Bucket, backup objstore.BucketReader, m *Manifest, dryRun bool) ([]Difference, error) {
+	diffs, err := Verify(ctx, logger, bkt, m)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make(map[ulid.ULID]Block, len(m.Blocks))
+	for _, b := range m.Blocks {
+		blocks[b.ID] = b
+	}
+
+	var restored []Difference
+	for _, d := range diffs {
+		if d.Type == BlockAdded || d.Type == ObjectAdded {
+			continue
+		}
+		restored = append(restored, d)
+		if dryRun {
+			level.Info(logger).Log("msg", "dry run, would restore", "id", d.ID, "object", d.Name, "difference", d.Type)
+			continue
+		}
+
+		b := blocks[d.ID]
+		switch d.Type {
+		case BlockMissing:
+			err = restoreBlock(ctx, logger, bkt, backup, b)
+		case ObjectMissing, ObjectChanged:
+			err = copyObject(ctx, logger, bkt, backup, objectOf(b, d.Name))
+		case MarkerMissing:
+			err = copyNamed(ctx, logger, bkt, backup, d.Name)
+		case MarkerAdded:
+			err = bkt.Delete(ctx, d.Name)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "restore %s of block %s", d.Type, d.ID)
+		}
+		level.Info(logger).Log("msg", "restored", "id", d.ID, "object", d.Name, "difference", d.Type)
+	}
+	return restored, nil
+}
+
+func objectOf(b Block, name string) Object {
+	for _, o := range b.Objects {
+		if o.Name == name {
+			return o
+		}
+	}
+	return Object{Name: name}
+}
+
+// restoreBlock copies all objects and markers of the block from the backup bucket. The meta.json is copied after the
+// other objects, so the block is not loaded while it's incomplete.
+func restoreBlock(ctx context.Context, logger log.Logger, bkt objstore.Bucket, backup objstore.BucketReader, b Block) error {
+	objs := make([]Object, len(b.Objects))
+	copy(objs, b.Objects)
+	sort.SliceStable(objs, func(i, j int) bool {
+		return !strings.HasSuffix(objs[i].Name, block.MetaFilename) && strings.HasSuffix(objs[j].Name, block.MetaFilename)
+	})
+	for _, o := range objs {
+		if err := copyObject(ctx, logger, bkt, backup, o); err != nil {
+			return err
+		}
+	}
+	for _, mk := range b.Markers {
+		if err := copyNamed(ctx, logger, bkt, backup, path.Join(b.ID.String(), mk)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyObject copies the object from the backup bucket, if it has the size recorded in the manifest.
+func copyObject(ctx context.Context, logger log.Logger, bkt objstore.Bucket, backup objstore.BucketReader, o Object) error {
+	attrs, err := backup.Attributes(ctx, o.Name)
+	if err != nil {
+		return errors.Wrapf(err, "get attributes of %s in backup bucket", o.Name)
+	}
+	if attrs.Size != o.Size {
+		return errors.Errorf("object %s has size %d in backup bucket, but %d in manifest", o.Name, attrs.Size, o.Size)
+	}
+	return copyNamed(ctx, logger, bkt, backup, o.Name)
+}
+
+func copyNamed(ctx context.Context, logger log.Logger, bkt objstore.Bucket, backup objstore.BucketReader, name string) error {
+	r, err := backup.Get(ctx, name)
+	if err != nil {
+		return errors.Wrapf(err, "get %s from backup bucket", name)
+	}
+	defer runutil.CloseWithLogOnErr(logger, r, "backup object reader")
+
+	return errors.Wrapf(bkt.Upload(ctx, name, r), "upload %s", name)
+}
@@ -0,0 +1,104 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/testutil"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
+)
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	counter := promauto.With(nil).NewCounter(prometheus.CounterOpts{})
+
+	tmpDir, err := ioutil.TempDir("", "test-snapshot")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(tmpDir)) }()
+
+	bkt, backup := objstore.NewInMemBucket(), objstore.NewInMemBucket()
+	createBlock := func(mint, maxt int64) ulid.ULID {
+		id, err := e2eutil.CreateBlock(ctx, tmpDir, []labels.Labels{labels.FromStrings("a", "1")}, 10, mint, maxt, labels.FromStrings("ext", "1"), 0, metadata.NoneFunc)
+		testutil.Ok(t, err)
+		testutil.Ok(t, block.Upload(ctx, logger, bkt, filepath.Join(tmpDir, id.String()), metadata.NoneFunc))
+		testutil.Ok(t, block.Upload(ctx, logger, backup, filepath.Join(tmpDir, id.String()), metadata.NoneFunc))
+		return id
+	}
+	id1, id2 := createBlock(0, 1000), createBlock(1000, 2000)
+	testutil.Ok(t, block.MarkForNoCompact(ctx, logger, bkt, id2, metadata.ManualNoCompactReason, "", counter))
+	testutil.Ok(t, block.MarkForNoCompact(ctx, logger, backup, id2, metadata.ManualNoCompactReason, "", counter))
+
+	m, err := Create(ctx, logger, bkt)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(m.Blocks))
+	testutil.Equals(t, []string{metadata.NoCompactMarkFilename}, m.Blocks[1].Markers)
+
+	var b bytes.Buffer
+	testutil.Ok(t, m.Write(&b))
+	read, err := Read(&b)
+	testutil.Ok(t, err)
+	testutil.Equals(t, m.Blocks, read.Blocks)
+	testutil.Assert(t, m.Time.Equal(read.Time), "time must be kept")
+
+	diffs, err := Verify(ctx, logger, bkt, m)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(diffs))
+
+	// Lose a block and a chunk, mark a block for deletion, unmark another one and add a new block.
+	testutil.Ok(t, block.Delete(ctx, logger, bkt, id1))
+	chunk := path.Join(id2.String(), block.ChunksDirname, "000001")
+	testutil.Ok(t, bkt.Delete(ctx, chunk))
+	testutil.Ok(t, block.MarkForDeletion(ctx, logger, bkt, id2, "", counter))
+	testutil.Ok(t, bkt.Delete(ctx, path.Join(id2.String(), metadata.NoCompactMarkFilename)))
+	id3 := createBlock(2000, 3000)
+
+	exp := []Difference{
+		{ID: id1, Type: BlockMissing},
+		{ID: id2, Name: chunk, Type: ObjectMissing},
+		{ID: id2, Name: path.Join(id2.String(), metadata.DeletionMarkFilename), Type: MarkerAdded},
+		{ID: id2, Name: path.Join(id2.String(), metadata.NoCompactMarkFilename), Type: MarkerMissing},
+		{ID: id3, Type: BlockAdded},
+	}
+	diffs, err = Verify(ctx, logger, bkt, m)
+	testutil.Ok(t, err)
+	testutil.Equals(t, exp, diffs)
+
+	objects := len(bkt.Objects())
+	restored, err := Restore(ctx, logger, bkt, backup, m, true)
+	testutil.Ok(t, err)
+	testutil.Equals(t, exp[:4], restored)
+	testutil.Equals(t, objects, len(bkt.Objects()))
+
+	restored, err = Restore(ctx, logger, bkt, backup, m, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, exp[:4], restored)
+
+	// Only the new block is left.
+	diffs, err = Verify(ctx, logger, bkt, m)
+	testutil.Ok(t, err)
+	testutil.Equals(t, exp[4:], diffs)
+
+	// Objects which differ from the manifest in the backup bucket are not restored.
+	testutil.Ok(t, bkt.Delete(ctx, chunk))
+	testutil.Ok(t, backup.Upload(ctx, chunk, bytes.NewReader([]byte("corrupted"))))
+	_, err = Restore(ctx, logger, bkt, backup, m, false)
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,380 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package spiffe obtains the mTLS identities of components, X.509 SVIDs, from the SPIFFE Workload API, e.g. of the
+// SPIRE agent, and authorizes peers by their SPIFFE IDs.
+package spiffe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/thanos-io/thanos/pkg/spiffe/workloadpb"
+)
+
+const (
+	// workloadAPIHeader is the metadata the Workload API requires on requests to protect against SSRF attacks.
+	workloadAPIHeader = "workload.spiffe.io"
+
+	minBackoff = 1 * time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// svid is the X.509 SVID of the workload with the bundle of its trust domain.
+type svid struct {
+	id          *url.URL
+	certificate *tls.Certificate
+	bundle      *x509.CertPool
+}
+
+// Source is the source of the X.509 SVID of the workload, obtained from the SPIFFE Workload API. The SVID is rotated
+// whenever the Workload API sends a new one. Peers are authorized by their SPIFFE IDs.
+type Source struct {
+	logger     log.Logger
+	addr       string
+	allowedIDs []string
+
+	mtx       sync.RWMutex
+	svid      *svid
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	updates       prometheus.Counter
+	errors        prometheus.Counter
+	lastUpdate    prometheus.Gauge
+	expiry        prometheus.Gauge
+	rejectedPeers prometheus.Counter
+}
+
+// NewSource returns a Source of the SVID from the Workload API at the given address, e.g.
+// unix:///run/spire/sockets/agent.sock or tcp://127.0.0.1:8081. Peers are authorized if their SPIFFE ID matches one
+// of the allowed ID patterns, in which * matches a path segment, e.g. spiffe://example.org/ns/monitoring/sa/*, or, if
+// there are none, if they are members of the trust domain of the SVID.
+func NewSource(logger log.Logger, reg prometheus.Registerer, addr string, allowedIDs []string) (*Source, error) {
+	for _, p := range allowedIDs {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid SPIFFE ID pattern %q", p)
+		}
+		if !strings.HasPrefix(p, "spiffe://") {
+			return nil, errors.Errorf("SPIFFE ID pattern %q doesn't start with spiffe://", p)
+		}
+	}
+	if _, _, err := workloadAPIDialer(addr); err != nil {
+		return nil, err
+	}
+	return &Source{
+		logger:     logger,
+		addr:       addr,
+		allowedIDs: allowedIDs,
+		ready:      make(chan struct{}),
+		updates: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_spiffe_svid_updates_total",
+			Help: "Total number of X.509 SVIDs received from the SPIFFE Workload API.",
+		}),
+		errors: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_spiffe_workload_api_errors_total",
+			Help: "Total number of errors of the stream of X.509 SVIDs from the SPIFFE Workload API, including invalid SVIDs.",
+		}),
+		lastUpdate: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_spiffe_svid_last_update_timestamp_seconds",
+			Help: "Timestamp of the last update of the X.509 SVID.",
+		}),
+		expiry: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_spiffe_svid_expiry_timestamp_seconds",
+			Help: "Timestamp of the expiry of the current X.509 SVID.",
+		}),
+		rejectedPeers: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_spiffe_rejected_peers_total",
+			Help: "Total number of TLS handshakes rejected because of an invalid or not allowed SVID of the peer.",
+		}),
+	}, nil
+}
+
+// workloadAPIDialer returns the network and address to dial the Workload API at the given address.
+func workloadAPIDialer(addr string) (network, address string, err error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "parse SPIFFE Workload API address %q", addr)
+	}
+	switch u.Scheme {
+	case "unix":
+		if u.Path == "" {
+			return "", "", errors.Errorf("no socket path in SPIFFE Workload API address %q", addr)
+		}
+		return "unix", u.Path, nil
+	case "tcp":
+		if u.Host == "" {
+			return "", "", errors.Errorf("no host in SPIFFE Workload API address %q", addr)
+		}
+		return "tcp", u.Host, nil
+	default:
+		return "", "", errors.Errorf("unsupported scheme of SPIFFE Workload API address %q, it has to be unix or tcp", addr)
+	}
+}
+
+// Run streams the SVIDs from the Workload API until the context is canceled, reconnecting with backoff on errors.
+func (s *Source) Run(ctx context.Context) error {
+	network, address, err := workloadAPIDialer(s.addr)
+	if err != nil {
+		return err
+	}
+	conn, err := grpc.DialContext(ctx, s.addr,
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, address)
+		}),
+	)
+	if err != nil {
+		return errors.Wrap(err, "dial SPIFFE Workload API")
+	}
+	defer conn.Close()
+
+	client := workloadpb.NewSpiffeWorkloadAPIClient(conn)
+	backoff := minBackoff
+	for {
+		err := s.watch(ctx, client, func() { backoff = minBackoff })
+		if ctx.Err() != nil {
+			return nil
+		}
+		s.errors.Inc()
+		level.Warn(s.logger).Log("msg", "streaming X.509 SVIDs from SPIFFE Workload API failed, retrying", "backoff", backoff, "err", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// watch streams the SVIDs until the stream fails. It calls received on every valid SVID.
+func (s *Source) watch(ctx context.Context, client workloadpb.SpiffeWorkloadAPIClient, received func()) error {
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(ctx, workloadAPIHeader, "true"))
+	defer cancel()
+
+	stream, err := client.FetchX509SVID(ctx, &workloadpb.X509SVIDRequest{})
+	if err != nil {
+		return errors.Wrap(err, "fetch X.509 SVIDs")
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return errors.Wrap(err, "receive X.509 SVIDs")
+		}
+		sv, err := parseSVIDResponse(resp)
+		if err != nil {
+			// Keep the previous SVID, it might still be valid.
+			s.errors.Inc()
+			level.Error(s.logger).Log("msg", "received invalid X.509 SVID from SPIFFE Workload API", "err", err)
+			continue
+		}
+		received()
+		s.update(sv)
+	}
+}
+
+func (s *Source) update(sv *svid) {
+	s.mtx.Lock()
+	s.svid = sv
+	s.mtx.Unlock()
+
+	s.updates.Inc()
+	s.lastUpdate.SetToCurrentTime()
+	s.expiry.Set(float64(sv.certificate.Leaf.NotAfter.Unix()))
+	level.Info(s.logger).Log("msg", "updated X.509 SVID", "id", sv.id, "expiry", sv.certificate.Leaf.NotAfter)
+	s.readyOnce.Do(func() { close(s.ready) })
+}
+
+// parseSVIDResponse returns the default SVID of the response, which is the first one.
+func parseSVIDResponse(resp *workloadpb.X509SVIDResponse) (*svid, error) {
+	if len(resp.Svids) == 0 {
+		return nil, errors.New("no SVIDs in response")
+	}
+	s := resp.Svids[0]
+
+	certs, err := x509.ParseCertificates(s.X509Svid)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse SVID certificates")
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no SVID certificates")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(s.X509SvidKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse SVID key")
+	}
+	id, err := spiffeID(certs[0])
+	if err != nil {
+		return nil, err
+	}
+	if id.String() != s.SpiffeId {
+		return nil, errors.Errorf("SPIFFE ID %s of the SVID certificate differs from the one of the response %s", id, s.SpiffeId)
+	}
+
+	bundle, err := x509.ParseCertificates(s.Bundle)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse bundle")
+	}
+	if len(bundle) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	pool := x509.NewCertPool()
+	for _, c := range bundle {
+		pool.AddCert(c)
+	}
+
+	cert := &tls.Certificate{PrivateKey: key, Leaf: certs[0]}
+	for _, c := range certs {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	return &svid{id: id, certificate: cert, bundle: pool}, nil
+}
+
+// spiffeID returns the SPIFFE ID of the SVID certificate, which is its only URI SAN.
+func spiffeID(cert *x509.Certificate) (*url.URL, error) {
+	if len(cert.URIs) != 1 {
+		return nil, errors.Errorf("SVID certificate has %d URI SANs, it has to have exactly one", len(cert.URIs))
+	}
+	id := cert.URIs[0]
+	if id.Scheme != "spiffe" || id.Host == "" {
+		return nil, errors.Errorf("invalid SPIFFE ID %s", id)
+	}
+	return id, nil
+}
+
+// WaitReady blocks until the first SVID was received or the context is canceled.
+func (s *Source) WaitReady(ctx context.Context) error {
+	select {
+	case <-s.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Source) current() (*svid, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if s.svid == nil {
+		return nil, errors.New("no X.509 SVID received from SPIFFE Workload API yet")
+	}
+	return s.svid, nil
+}
+
+// ServerTLSConfig returns the TLS configuration of servers, which present the current SVID and require clients to
+// present an SVID of the trust domain with an allowed SPIFFE ID.
+func (s *Source) ServerTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// The client certificate is verified against the current bundle by verifyPeer.
+		ClientAuth: tls.RequireAnyClientCert,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			sv, err := s.current()
+			if err != nil {
+				return nil, err
+			}
+			return sv.certificate, nil
+		},
+		VerifyPeerCertificate: s.verifyPeer,
+	}
+}
+
+// ClientTLSConfig returns the TLS configuration of clients, which present the current SVID and require servers to
+// present an SVID of the trust domain with an allowed SPIFFE ID.
+func (s *Source) ClientTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// SVIDs don't identify servers by their host name, the server certificate is verified against the current
+		// bundle by verifyPeer instead.
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			sv, err := s.current()
+			if err != nil {
+				return nil, err
+			}
+			return sv.certificate, nil
+		},
+		VerifyPeerCertificate: s.verifyPeer,
+	}
+}
+
+func (s *Source) verifyPeer(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	id, err := s.verify(rawCerts)
+	if err != nil {
+		s.rejectedPeers.Inc()
+		level.Warn(s.logger).Log("msg", "rejecting peer", "id", id, "err", err)
+	}
+	return err
+}
+
+// verify verifies the certificates of the peer against the bundle of the current SVID and returns the SPIFFE ID of the
+// peer if it is allowed.
+func (s *Source) verify(rawCerts [][]byte) (string, error) {
+	sv, err := s.current()
+	if err != nil {
+		return "", err
+	}
+	if len(rawCerts) == 0 {
+		return "", errors.New("no peer certificates")
+	}
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		c, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return "", errors.Wrap(err, "parse peer certificate")
+		}
+		certs = append(certs, c)
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+
+	id, err := spiffeID(certs[0])
+	if err != nil {
+		return "", err
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         sv.bundle,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return id.String(), errors.Wrap(err, "verify peer SVID")
+	}
+	if !s.allowed(id, sv.id) {
+		return id.String(), errors.Errorf("SPIFFE ID %s not allowed", id)
+	}
+	return id.String(), nil
+}
+
+// allowed returns true if the SPIFFE ID of the peer matches an allowed ID pattern or, without patterns, if the peer
+// is a member of the trust domain of the given own ID.
+func (s *Source) allowed(id, own *url.URL) bool {
+	if len(s.allowedIDs) == 0 {
+		return id.Host == own.Host
+	}
+	for _, p := range s.allowedIDs {
+		if ok, _ := path.Match(p, id.String()); ok {
+			return true
+		}
+	}
+	return false
+}
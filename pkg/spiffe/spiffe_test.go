@@ -0,0 +1,209 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package spiffe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/thanos-io/thanos/pkg/runutil"
+	"github.com/thanos-io/thanos/pkg/spiffe/workloadpb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.Ok(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	testutil.Ok(t, err)
+	cert, err := x509.ParseCertificate(der)
+	testutil.Ok(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) svid(t *testing.T, id string) *workloadpb.X509SVID {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.Ok(t, err)
+	u, err := url.Parse(id)
+	testutil.Ok(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{u},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	testutil.Ok(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	testutil.Ok(t, err)
+	return &workloadpb.X509SVID{SpiffeId: id, X509Svid: der, X509SvidKey: keyDER, Bundle: ca.cert.Raw}
+}
+
+type testWorkloadAPI struct {
+	svids chan *workloadpb.X509SVID
+}
+
+func (w *testWorkloadAPI) FetchX509SVID(_ *workloadpb.X509SVIDRequest, srv workloadpb.SpiffeWorkloadAPI_FetchX509SVIDServer) error {
+	md, _ := metadata.FromIncomingContext(srv.Context())
+	if len(md.Get(workloadAPIHeader)) != 1 || md.Get(workloadAPIHeader)[0] != "true" {
+		return status.Error(codes.InvalidArgument, "security header missing from request")
+	}
+	for {
+		select {
+		case <-srv.Context().Done():
+			return nil
+		case s := <-w.svids:
+			if err := srv.Send(&workloadpb.X509SVIDResponse{Svids: []*workloadpb.X509SVID{s}}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handshake returns the error of the TLS handshake of a client and server with the given configurations.
+func handshake(clientCfg, serverCfg *tls.Config) error {
+	c, s := net.Pipe()
+	defer c.Close()
+	defer s.Close()
+
+	errc := make(chan error, 1)
+	go func() { errc <- tls.Server(s, serverCfg).Handshake() }()
+	cerr := tls.Client(c, clientCfg).Handshake()
+	if cerr != nil {
+		// Unblock the server.
+		c.Close()
+	}
+	serr := <-errc
+	if cerr != nil {
+		return cerr
+	}
+	return serr
+}
+
+func TestSource(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", sock)
+	testutil.Ok(t, err)
+	api := &testWorkloadAPI{svids: make(chan *workloadpb.X509SVID, 1)}
+	srv := grpc.NewServer()
+	workloadpb.RegisterSpiffeWorkloadAPIServer(srv, api)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	_, err = NewSource(log.NewNopLogger(), nil, "http://localhost", nil)
+	testutil.NotOk(t, err)
+	_, err = NewSource(log.NewNopLogger(), nil, "unix://"+sock, []string{"example.org/*"})
+	testutil.NotOk(t, err)
+
+	reg := prometheus.NewRegistry()
+	query, err := NewSource(log.NewNopLogger(), reg, "unix://"+sock, []string{"spiffe://example.org/ns/monitoring/sa/*"})
+	testutil.Ok(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- query.Run(ctx) }()
+
+	// Handshakes fail until the first SVID is received.
+	_, err = query.ServerTLSConfig().GetCertificate(nil)
+	testutil.NotOk(t, err)
+
+	ca := newTestCA(t)
+	api.svids <- ca.svid(t, "spiffe://example.org/ns/monitoring/sa/query")
+	waitCtx, waitCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer waitCancel()
+	testutil.Ok(t, query.WaitReady(waitCtx))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(query.updates))
+
+	peer := func(id string, ca *testCA, allowedIDs []string) *Source {
+		s, err := NewSource(log.NewNopLogger(), nil, "unix://"+sock, allowedIDs)
+		testutil.Ok(t, err)
+		sv, err := parseSVIDResponse(&workloadpb.X509SVIDResponse{Svids: []*workloadpb.X509SVID{ca.svid(t, id)}})
+		testutil.Ok(t, err)
+		s.update(sv)
+		return s
+	}
+
+	// Allowed peers, in both directions.
+	store := peer("spiffe://example.org/ns/monitoring/sa/store", ca, nil)
+	testutil.Ok(t, handshake(query.ClientTLSConfig(), store.ServerTLSConfig()))
+	testutil.Ok(t, handshake(store.ClientTLSConfig(), query.ServerTLSConfig()))
+
+	// Not allowed SPIFFE ID.
+	other := peer("spiffe://example.org/ns/other/sa/store", ca, nil)
+	testutil.NotOk(t, handshake(query.ClientTLSConfig(), other.ServerTLSConfig()))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(query.rejectedPeers))
+
+	// Peers of other trust domains are not allowed without patterns, even if they are signed by the same CA.
+	foreign := peer("spiffe://other.org/ns/monitoring/sa/query", ca, nil)
+	testutil.NotOk(t, handshake(foreign.ClientTLSConfig(), store.ServerTLSConfig()))
+
+	// SVIDs of another CA.
+	newCA := newTestCA(t)
+	untrusted := peer("spiffe://example.org/ns/monitoring/sa/store", newCA, nil)
+	testutil.NotOk(t, handshake(query.ClientTLSConfig(), untrusted.ServerTLSConfig()))
+	testutil.Equals(t, 2.0, promtest.ToFloat64(query.rejectedPeers))
+
+	// The SVID is rotated, including the bundle.
+	api.svids <- newCA.svid(t, "spiffe://example.org/ns/monitoring/sa/query")
+	testutil.Ok(t, runutil.Retry(50*time.Millisecond, waitCtx.Done(), func() error {
+		if promtest.ToFloat64(query.updates) != 2 {
+			return errors.New("SVID not rotated yet")
+		}
+		return nil
+	}))
+	testutil.Ok(t, handshake(query.ClientTLSConfig(), untrusted.ServerTLSConfig()))
+	testutil.NotOk(t, handshake(query.ClientTLSConfig(), store.ServerTLSConfig()))
+
+	// Invalid SVIDs are ignored.
+	invalid := newCA.svid(t, "spiffe://example.org/ns/monitoring/sa/query")
+	invalid.SpiffeId = "spiffe://example.org/ns/monitoring/sa/other"
+	api.svids <- invalid
+	testutil.Ok(t, runutil.Retry(50*time.Millisecond, waitCtx.Done(), func() error {
+		if promtest.ToFloat64(query.errors) != 1 {
+			return errors.New("invalid SVID not received yet")
+		}
+		return nil
+	}))
+	testutil.Ok(t, handshake(query.ClientTLSConfig(), untrusted.ServerTLSConfig()))
+
+	cancel()
+	testutil.Ok(t, <-done)
+}
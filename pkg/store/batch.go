@@ -0,0 +1,71 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+const (
+	// MaxSeriesBatchSize is the maximum number of series of batches, whatever the requested batch size.
+	MaxSeriesBatchSize = 1000
+	// maxSeriesBatchChunksSize is the size of the chunks of batches at which they are sent, so that batches of series
+	// with many chunks stay small enough to be streamed.
+	maxSeriesBatchChunksSize = 1 << 20
+)
+
+// batchingStoreServer sends the series of Series calls in batches, if requested.
+type batchingStoreServer struct {
+	storepb.StoreServer
+}
+
+func (s *batchingStoreServer) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	if r.SeriesBatchSize <= 0 {
+		return s.StoreServer.Series(r, srv)
+	}
+	size := int(r.SeriesBatchSize)
+	if size > MaxSeriesBatchSize {
+		size = MaxSeriesBatchSize
+	}
+
+	bsrv := &batchingSeriesServer{Store_SeriesServer: srv, size: size, builder: storepb.NewSeriesBatchBuilder()}
+	err := s.StoreServer.Series(r, bsrv)
+	// The series sent before an error are sent as well, as they would have been without batches.
+	if ferr := bsrv.flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// batchingSeriesServer adds the series sent to batches, which are sent once they are full, or before any other
+// response, so that the order of responses is kept.
+type batchingSeriesServer struct {
+	storepb.Store_SeriesServer
+
+	size    int
+	builder *storepb.SeriesBatchBuilder
+}
+
+func (s *batchingSeriesServer) Send(r *storepb.SeriesResponse) error {
+	series := r.GetSeries()
+	if series == nil {
+		if err := s.flush(); err != nil {
+			return err
+		}
+		return s.Store_SeriesServer.Send(r)
+	}
+
+	s.builder.Add(series)
+	if s.builder.Len() >= s.size || s.builder.ChunksSize() >= maxSeriesBatchChunksSize {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *batchingSeriesServer) flush() error {
+	if s.builder.Len() == 0 {
+		return nil
+	}
+	return s.Store_SeriesServer.Send(storepb.NewSeriesBatchResponse(s.builder.Build()))
+}
@@ -0,0 +1,102 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+// seriesStoreServer is a store server sending the given responses to Series calls.
+type seriesStoreServer struct {
+	storepb.StoreServer
+
+	resps []*storepb.SeriesResponse
+	err   error
+}
+
+func (s *seriesStoreServer) Series(_ *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	for _, r := range s.resps {
+		if err := srv.Send(r); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+// responsesServer records all the responses sent.
+type responsesServer struct {
+	storepb.Store_SeriesServer
+
+	resps []*storepb.SeriesResponse
+}
+
+func (s *responsesServer) Send(r *storepb.SeriesResponse) error {
+	s.resps = append(s.resps, r)
+	return nil
+}
+
+func (s *responsesServer) Context() context.Context {
+	return context.Background()
+}
+
+func TestBatchingStoreServer_Series(t *testing.T) {
+	series := make([]*storepb.SeriesResponse, 0, 5)
+	for _, v := range []string{"a", "b", "c", "d", "e"} {
+		series = append(series, storeSeriesResponse(t, labels.FromStrings("a", v), []sample{{1, 1}}))
+	}
+	warning := storepb.NewWarnSeriesResponse(errors.New("warning"))
+	resps := []*storepb.SeriesResponse{series[0], series[1], series[2], warning, series[3], series[4]}
+
+	t.Run("without batches", func(t *testing.T) {
+		srv := &responsesServer{}
+		testutil.Ok(t, (&batchingStoreServer{StoreServer: &seriesStoreServer{resps: resps}}).Series(&storepb.SeriesRequest{}, srv))
+		testutil.Equals(t, resps, srv.resps)
+	})
+
+	t.Run("with batches", func(t *testing.T) {
+		srv := &responsesServer{}
+		testutil.Ok(t, (&batchingStoreServer{StoreServer: &seriesStoreServer{resps: resps}}).Series(&storepb.SeriesRequest{SeriesBatchSize: 2}, srv))
+
+		// Batches are sent once full, before other responses and on return.
+		testutil.Equals(t, 4, len(srv.resps))
+		testutil.Equals(t, warning, srv.resps[2])
+		var got []storepb.Series
+		for i, expected := range []int{2, 1, 0, 2} {
+			if expected == 0 {
+				continue
+			}
+			b := srv.resps[i].GetBatch()
+			testutil.Assert(t, b != nil, "response %d is not a batch", i)
+			all, err := b.AllSeries()
+			testutil.Ok(t, err)
+			testutil.Equals(t, expected, len(all))
+			for _, s := range all {
+				got = append(got, *s)
+			}
+		}
+		seriesEquals(t, []rawSeries{
+			{lset: labels.FromStrings("a", "a"), chunks: [][]sample{{{1, 1}}}},
+			{lset: labels.FromStrings("a", "b"), chunks: [][]sample{{{1, 1}}}},
+			{lset: labels.FromStrings("a", "c"), chunks: [][]sample{{{1, 1}}}},
+			{lset: labels.FromStrings("a", "d"), chunks: [][]sample{{{1, 1}}}},
+			{lset: labels.FromStrings("a", "e"), chunks: [][]sample{{{1, 1}}}},
+		}, got)
+	})
+
+	t.Run("with error", func(t *testing.T) {
+		srv := &responsesServer{}
+		err := (&batchingStoreServer{StoreServer: &seriesStoreServer{resps: series[:1], err: errors.New("failed")}}).Series(&storepb.SeriesRequest{SeriesBatchSize: 2}, srv)
+		testutil.NotOk(t, err)
+		testutil.Equals(t, "failed", err.Error())
+		// The series sent before the error are still sent.
+		testutil.Equals(t, 1, len(srv.resps))
+	})
+}
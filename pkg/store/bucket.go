@@ -45,6 +45,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/component"
 	"github.com/thanos-io/thanos/pkg/extprom"
 	"github.com/thanos-io/thanos/pkg/gate"
+	"github.com/thanos-io/thanos/pkg/info/infopb"
 	"github.com/thanos-io/thanos/pkg/model"
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/pool"
@@ -54,7 +55,9 @@ import (
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 	"github.com/thanos-io/thanos/pkg/strutil"
+	"github.com/thanos-io/thanos/pkg/tenancy"
 	"github.com/thanos-io/thanos/pkg/tracing"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus/tsdbstatuspb"
 )
 
 const (
@@ -298,7 +301,12 @@ type BucketStore struct {
 	// seriesLimiterFactory creates a new limiter used to limit the number of touched series by each Series() call,
 	// or LabelName and LabelValues calls when used with matchers.
 	seriesLimiterFactory SeriesLimiterFactory
-	partitioner          Partitioner
+	// chunkBytesLimiterFactory creates a new limiter used to limit the bytes of chunks fetched by each Series() call.
+	chunkBytesLimiterFactory BytesLimiterFactory
+	// dataBytesLimiterFactory creates a new limiter used to limit the bytes of postings, series and chunks downloaded
+	// from object storage by each Series() call.
+	dataBytesLimiterFactory BytesLimiterFactory
+	partitioner             Partitioner
 
 	filterConfig             *FilterConfig
 	advLabelSets             []labelpb.ZLabelSet
@@ -309,6 +317,17 @@ type BucketStore struct {
 
 	// Enables hints in the Series() response.
 	enableSeriesResponseHints bool
+
+	// accounting counts the bytes fetched for the Series() calls of tenants, if not nil.
+	accounting *tenancy.Accounting
+	// tenantLimiter limits the concurrency and the in-flight downloaded bytes of the Series() calls of each tenant,
+	// if not nil.
+	tenantLimiter *TenantLimiter
+
+	// indexHeaderStrategyRules choose how the index-header of blocks is loaded, overriding the lazy reader settings.
+	indexHeaderStrategyRules []indexheader.StrategyRule
+	// indexHeaderProtectionWindow is the minimum time lazily loaded index-headers stay loaded after their last use.
+	indexHeaderProtectionWindow time.Duration
 }
 
 func (b *BucketStore) validate() error {
@@ -375,6 +394,53 @@ func WithFilterConfig(filter *FilterConfig) BucketStoreOption {
 	}
 }
 
+// WithTenantAccounting sets the Accounting which counts the bytes fetched from object storage for the Series calls of
+// tenants, as sent by queriers.
+func WithTenantAccounting(accounting *tenancy.Accounting) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.accounting = accounting
+	}
+}
+
+// WithTenantLimiter sets the limiter of the concurrency and the in-flight downloaded bytes of the Series calls of each
+// tenant, as sent by queriers.
+func WithTenantLimiter(l *TenantLimiter) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.tenantLimiter = l
+	}
+}
+
+// WithIndexHeaderStrategyRules sets the rules choosing whether the index-header of blocks is loaded eagerly or lazily,
+// which override the lazy reader settings for the blocks they match.
+func WithIndexHeaderStrategyRules(rules []indexheader.StrategyRule) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.indexHeaderStrategyRules = rules
+	}
+}
+
+// WithIndexHeaderProtectionWindow sets the minimum time lazily loaded index-headers stay loaded after their last use,
+// whatever their idle timeout.
+func WithIndexHeaderProtectionWindow(d time.Duration) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.indexHeaderProtectionWindow = d
+	}
+}
+
+// WithChunkBytesLimiterFactory sets the factory of the limiters of the bytes of chunks fetched by each Series() call.
+func WithChunkBytesLimiterFactory(factory BytesLimiterFactory) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.chunkBytesLimiterFactory = factory
+	}
+}
+
+// WithDataBytesLimiterFactory sets the factory of the limiters of the bytes downloaded from object storage by each
+// Series() call.
+func WithDataBytesLimiterFactory(factory BytesLimiterFactory) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.dataBytesLimiterFactory = factory
+	}
+}
+
 // WithDebugLogging enables debug logging.
 func WithDebugLogging() BucketStoreOption {
 	return func(s *BucketStore) {
@@ -412,6 +478,8 @@ func NewBucketStore(
 		queryGate:                   gate.NewNoop(),
 		chunksLimiterFactory:        chunksLimiterFactory,
 		seriesLimiterFactory:        seriesLimiterFactory,
+		chunkBytesLimiterFactory:    NewBytesLimiterFactory(0),
+		dataBytesLimiterFactory:     NewBytesLimiterFactory(0),
 		partitioner:                 partitioner,
 		enableCompatibilityLabel:    enableCompatibilityLabel,
 		postingOffsetsInMemSampling: postingOffsetsInMemSampling,
@@ -424,7 +492,7 @@ func NewBucketStore(
 
 	// Depend on the options
 	indexReaderPoolMetrics := indexheader.NewReaderPoolMetrics(extprom.WrapRegistererWithPrefix("thanos_bucket_store_", s.reg))
-	s.indexReaderPool = indexheader.NewReaderPool(s.logger, lazyIndexReaderEnabled, lazyIndexReaderIdleTimeout, indexReaderPoolMetrics)
+	s.indexReaderPool = indexheader.NewReaderPool(s.logger, lazyIndexReaderEnabled, lazyIndexReaderIdleTimeout, s.indexHeaderProtectionWindow, s.indexHeaderStrategyRules, indexReaderPoolMetrics)
 	s.metrics = newBucketStoreMetrics(s.reg) // TODO(metalmatze): Might be possible via Option too
 
 	if err := s.validate(); err != nil {
@@ -585,7 +653,7 @@ func (s *BucketStore) addBlock(ctx context.Context, meta *metadata.Meta) (err er
 		s.logger,
 		s.bkt,
 		s.dir,
-		meta.ULID,
+		meta,
 		s.postingOffsetsInMemSampling,
 	)
 	if err != nil {
@@ -683,6 +751,44 @@ func (s *BucketStore) TimeRange() (mint, maxt int64) {
 	return mint, maxt
 }
 
+// TSDBInfos returns the time ranges covered by the blocks of each resolution, sorted by resolution and time.
+func (s *BucketStore) TSDBInfos() []infopb.TSDBInfo {
+	s.mtx.RLock()
+	ranges := make([]infopb.TSDBInfo, 0, len(s.blocks))
+	for _, b := range s.blocks {
+		ranges = append(ranges, infopb.TSDBInfo{
+			MinTime:    b.meta.MinTime,
+			MaxTime:    b.meta.MaxTime,
+			Resolution: b.meta.Thanos.Downsample.Resolution,
+		})
+	}
+	s.mtx.RUnlock()
+
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].Resolution != ranges[j].Resolution {
+			return ranges[i].Resolution < ranges[j].Resolution
+		}
+		return ranges[i].MinTime < ranges[j].MinTime
+	})
+
+	// Merge the adjacent and overlapping blocks of each resolution.
+	infos := make([]infopb.TSDBInfo, 0, len(ranges))
+	for _, r := range ranges {
+		r.MinTime, r.MaxTime = s.limitMinTime(r.MinTime), s.limitMaxTime(r.MaxTime)
+		if r.MinTime > r.MaxTime {
+			continue
+		}
+		if n := len(infos); n > 0 && infos[n-1].Resolution == r.Resolution && r.MinTime <= infos[n-1].MaxTime {
+			if r.MaxTime > infos[n-1].MaxTime {
+				infos[n-1].MaxTime = r.MaxTime
+			}
+			continue
+		}
+		infos = append(infos, r)
+	}
+	return infos
+}
+
 func (s *BucketStore) LabelSet() []labelpb.ZLabelSet {
 	labelSets := s.advLabelSets
 
@@ -783,6 +889,7 @@ func blockSeries(
 	skipChunks bool, // If true, chunks are not loaded.
 	minTime, maxTime int64, // Series must have data in this time range to be returned.
 	loadAggregates []storepb.Aggr, // List of aggregates to load when loading chunks.
+	shard *storepb.ShardMatcher, // Matcher of the shard the returned series are in, nil for all series.
 ) (storepb.SeriesSet, *queryStats, error) {
 	ps, err := indexr.ExpandedPostings(ctx, matchers)
 	if err != nil {
@@ -823,7 +930,16 @@ func blockSeries(
 			continue
 		}
 
-		s := seriesEntry{}
+		if err := indexr.LookupLabelsSymbols(symbolizedLset, &lset); err != nil {
+			return nil, nil, errors.Wrap(err, "Lookup labels symbols")
+		}
+		extendedLset := labelpb.ExtendSortedLabels(lset, extLset)
+		if !shard.MatchesLabels(extendedLset) {
+			// The series of other shards are skipped before their chunks are loaded.
+			continue
+		}
+
+		s := seriesEntry{lset: extendedLset}
 		if !skipChunks {
 			// Schedule loading chunks.
 			s.refs = make([]chunks.ChunkRef, 0, len(chks))
@@ -846,11 +962,6 @@ func blockSeries(
 				return nil, nil, errors.Wrap(err, "exceeded chunks limit")
 			}
 		}
-		if err := indexr.LookupLabelsSymbols(symbolizedLset, &lset); err != nil {
-			return nil, nil, errors.Wrap(err, "Lookup labels symbols")
-		}
-
-		s.lset = labelpb.ExtendSortedLabels(lset, extLset)
 		res = append(res, s)
 	}
 
@@ -974,6 +1085,19 @@ func debugFoundBlockSetOverview(logger log.Logger, mint, maxt, maxResolutionMill
 
 // Series implements the storepb.StoreServer interface.
 func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_SeriesServer) (err error) {
+	// The calls of a tenant wait for the turn of the tenant before taking up the turns of other tenants.
+	var tenantReq *TenantRequest
+	if s.tenantLimiter != nil {
+		tracing.DoInSpan(srv.Context(), "store_tenant_gate_ismyturn", func(ctx context.Context) {
+			tenantReq, err = s.tenantLimiter.Start(srv.Context())
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to wait for turn of tenant")
+		}
+
+		defer tenantReq.Done()
+	}
+
 	if s.queryGate != nil {
 		tracing.DoInSpan(srv.Context(), "store_query_gate_ismyturn", func(ctx context.Context) {
 			err = s.queryGate.Start(srv.Context())
@@ -989,20 +1113,26 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 	if err != nil {
 		return status.Error(codes.InvalidArgument, err.Error())
 	}
+	pushdown := newRangeFuncPushdown(req)
 	req.MinTime = s.limitMinTime(req.MinTime)
 	req.MaxTime = s.limitMaxTime(req.MaxTime)
 
 	var (
-		ctx              = srv.Context()
-		stats            = &queryStats{}
-		res              []storepb.SeriesSet
-		mtx              sync.Mutex
-		g, gctx          = errgroup.WithContext(ctx)
-		resHints         = &hintspb.SeriesResponseHints{}
-		reqBlockMatchers []*labels.Matcher
-		chunksLimiter    = s.chunksLimiterFactory(s.metrics.queriesDropped.WithLabelValues("chunks"))
-		seriesLimiter    = s.seriesLimiterFactory(s.metrics.queriesDropped.WithLabelValues("series"))
+		ctx               = srv.Context()
+		stats             = &queryStats{}
+		res               []storepb.SeriesSet
+		mtx               sync.Mutex
+		g, gctx           = errgroup.WithContext(ctx)
+		resHints          = &hintspb.SeriesResponseHints{}
+		reqBlockMatchers  []*labels.Matcher
+		chunksLimiter     = s.chunksLimiterFactory(s.metrics.queriesDropped.WithLabelValues("chunks"))
+		seriesLimiter     = s.seriesLimiterFactory(s.metrics.queriesDropped.WithLabelValues("series"))
+		chunkBytesLimiter = s.chunkBytesLimiterFactory(s.metrics.queriesDropped.WithLabelValues("chunk_bytes"))
+		dataBytesLimiter  = s.dataBytesLimiterFactory(s.metrics.queriesDropped.WithLabelValues("data_bytes"))
 	)
+	if tenantReq != nil {
+		dataBytesLimiter = multiBytesLimiter{dataBytesLimiter, tenantReq}
+	}
 
 	if req.Hints != nil {
 		reqHints := &hintspb.SeriesRequestHints{}
@@ -1041,8 +1171,11 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 			var chunkr *bucketChunkReader
 			// We must keep the readers open until all their data has been sent.
 			indexr := b.indexReader()
+			indexr.bytesLimiter = dataBytesLimiter
 			if !req.SkipChunks {
 				chunkr = b.chunkReader()
+				chunkr.chunkBytesLimiter = chunkBytesLimiter
+				chunkr.dataBytesLimiter = dataBytesLimiter
 				defer runutil.CloseWithLogOnErr(s.logger, chunkr, "series block")
 			}
 
@@ -1069,6 +1202,7 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 					req.SkipChunks,
 					req.MinTime, req.MaxTime,
 					req.Aggregates,
+					req.ShardInfo.Matcher(),
 				)
 				if err != nil {
 					return errors.Wrapf(err, "fetch series for block %s", b.meta.ULID)
@@ -1111,6 +1245,10 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 		s.metrics.cachedPostingsCompressionTimeSeconds.WithLabelValues(labelDecode).Add(stats.CachedPostingsDecompressionTimeSum.Seconds())
 		s.metrics.cachedPostingsOriginalSizeBytes.Add(float64(stats.CachedPostingsOriginalSizeSum))
 		s.metrics.cachedPostingsCompressedSizeBytes.Add(float64(stats.CachedPostingsCompressedSizeSum))
+		if s.accounting != nil {
+			tenant, _ := tenancy.TenantFromIncomingContext(ctx)
+			s.accounting.BytesFetched(tenant, int(stats.PostingsFetchedSizeSum+stats.SeriesFetchedSizeSum+stats.ChunksFetchedSizeSum))
+		}
 
 		level.Debug(s.logger).Log("msg", "stats query processed",
 			"request", req,
@@ -1142,20 +1280,39 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 		// NOTE: We "carefully" assume series and chunks are sorted within each SeriesSet. This should be guaranteed by
 		// blockSeries method. In worst case deduplication logic won't deduplicate correctly, which will be accounted later.
 		set := storepb.MergeSeriesSets(res...)
+		splitAggrs := req.SplitAggregates && !req.SkipChunks
+		var split []storepb.Series
 		for set.Next() {
+			if req.Limit > 0 && int64(stats.mergedSeriesCount) >= req.Limit {
+				break
+			}
 			var series storepb.Series
 
-			stats.mergedSeriesCount++
-
 			var lset labels.Labels
 			if req.SkipChunks {
 				lset, _ = set.At()
 			} else {
 				lset, series.Chunks = set.At()
+				if pushdown != nil {
+					if series.Chunks, err = pushdown.reduce(series.Chunks); err != nil {
+						err = status.Error(codes.Internal, errors.Wrapf(err, "push down %s", pushdown.fn).Error())
+						return
+					}
+					// Series without samples in the range of any step have no result.
+					if len(series.Chunks) == 0 {
+						continue
+					}
+				}
 
 				stats.mergedChunksCount += len(series.Chunks)
 				s.metrics.chunkSizeBytes.Observe(float64(chunksSize(series.Chunks)))
 			}
+			stats.mergedSeriesCount++
+
+			if splitAggrs {
+				split = append(split, storepb.SplitAggregates(lset, series.Chunks, req.Aggregates)...)
+				continue
+			}
 			series.Labels = labelpb.ZLabelsFromPromLabels(lset)
 			if err = srv.Send(storepb.NewSeriesResponse(&series)); err != nil {
 				err = status.Error(codes.Unknown, errors.Wrap(err, "send series response").Error())
@@ -1166,6 +1323,17 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 			err = status.Error(codes.Unknown, errors.Wrap(set.Err(), "expand series set").Error())
 			return
 		}
+
+		// The aggregate label changes the order of series, so they are sent once all of them are split.
+		sort.Slice(split, func(i, j int) bool {
+			return labels.Compare(split[i].PromLabels(), split[j].PromLabels()) < 0
+		})
+		for i := range split {
+			if err = srv.Send(storepb.NewSeriesResponse(&split[i])); err != nil {
+				err = status.Error(codes.Unknown, errors.Wrap(err, "send series response").Error())
+				return
+			}
+		}
 		stats.MergeDuration = time.Since(begin)
 		s.metrics.seriesMergeDuration.Observe(stats.MergeDuration.Seconds())
 
@@ -1271,7 +1439,7 @@ func (s *BucketStore) LabelNames(ctx context.Context, req *storepb.LabelNamesReq
 
 				result = strutil.MergeSlices(res, extRes)
 			} else {
-				seriesSet, _, err := blockSeries(newCtx, b.extLset, indexr, nil, reqSeriesMatchers, nil, seriesLimiter, true, req.Start, req.End, nil)
+				seriesSet, _, err := blockSeries(newCtx, b.extLset, indexr, nil, reqSeriesMatchers, nil, seriesLimiter, true, req.Start, req.End, nil, nil)
 				if err != nil {
 					return errors.Wrapf(err, "fetch series for block %s", b.meta.ULID)
 				}
@@ -1319,7 +1487,7 @@ func (s *BucketStore) LabelNames(ctx context.Context, req *storepb.LabelNamesReq
 	}
 
 	return &storepb.LabelNamesResponse{
-		Names: strutil.MergeSlices(sets...),
+		Names: truncateToLimit(strutil.MergeSlices(sets...), req.Limit),
 		Hints: anyHints,
 	}, nil
 }
@@ -1402,7 +1570,7 @@ func (s *BucketStore) LabelValues(ctx context.Context, req *storepb.LabelValuesR
 				}
 				result = res
 			} else {
-				seriesSet, _, err := blockSeries(newCtx, b.extLset, indexr, nil, reqSeriesMatchers, nil, seriesLimiter, true, req.Start, req.End, nil)
+				seriesSet, _, err := blockSeries(newCtx, b.extLset, indexr, nil, reqSeriesMatchers, nil, seriesLimiter, true, req.Start, req.End, nil, nil)
 				if err != nil {
 					return errors.Wrapf(err, "fetch series for block %s", b.meta.ULID)
 				}
@@ -1450,11 +1618,75 @@ func (s *BucketStore) LabelValues(ctx context.Context, req *storepb.LabelValuesR
 	}
 
 	return &storepb.LabelValuesResponse{
-		Values: strutil.MergeSlices(sets...),
+		Values: truncateToLimit(strutil.MergeSlices(sets...), req.Limit),
 		Hints:  anyHints,
 	}, nil
 }
 
+// TSDBStatus implements the tsdbstatuspb.TSDBStatusServer interface. Blocks have no head, so for each set of blocks with
+// the same external labels it only returns the number of distinct values per label name, read from the index headers.
+func (s *BucketStore) TSDBStatus(_ *tsdbstatuspb.TSDBStatusRequest, srv tsdbstatuspb.TSDBStatus_TSDBStatusServer) error {
+	type blockSetReaders struct {
+		lset    labels.Labels
+		readers []*bucketIndexReader
+	}
+	sets := map[uint64]*blockSetReaders{}
+
+	s.mtx.RLock()
+	for _, b := range s.blocks {
+		h := b.extLset.Hash()
+		set, ok := sets[h]
+		if !ok {
+			set = &blockSetReaders{lset: b.extLset}
+			sets[h] = set
+		}
+		set.readers = append(set.readers, b.indexReader())
+	}
+	s.mtx.RUnlock()
+
+	defer func() {
+		for _, set := range sets {
+			for _, indexr := range set.readers {
+				runutil.CloseWithLogOnErr(s.logger, indexr, "tsdb status")
+			}
+		}
+	}()
+
+	for _, set := range sets {
+		values := map[string]map[string]struct{}{}
+		for _, indexr := range set.readers {
+			names, err := indexr.block.indexHeaderReader.LabelNames()
+			if err != nil {
+				return status.Error(codes.Internal, errors.Wrapf(err, "label names for block %s", indexr.block.meta.ULID).Error())
+			}
+			for _, n := range names {
+				vals, err := indexr.block.indexHeaderReader.LabelValues(n)
+				if err != nil {
+					return status.Error(codes.Internal, errors.Wrapf(err, "label values of %s for block %s", n, indexr.block.meta.ULID).Error())
+				}
+				if _, ok := values[n]; !ok {
+					values[n] = map[string]struct{}{}
+				}
+				for _, v := range vals {
+					values[n][v] = struct{}{}
+				}
+			}
+		}
+
+		counts := make([]tsdbstatuspb.Statistic, 0, len(values))
+		for n, vals := range values {
+			counts = append(counts, tsdbstatuspb.Statistic{Name: n, Value: uint64(len(vals))})
+		}
+		if err := srv.Send(tsdbstatuspb.NewTSDBStatusResponse(&tsdbstatuspb.TSDBStatus{
+			LabelValueCountByLabelName: tsdbstatuspb.TopStatistics(counts, tsdbstatuspb.MaxStatistics),
+			LabelSet:                   labelpb.ZLabelSet{Labels: labelpb.ZLabelsFromPromLabels(set.lset)},
+		})); err != nil {
+			return status.Error(codes.Unknown, errors.Wrap(err, "send tsdb status response").Error())
+		}
+	}
+	return nil
+}
+
 // bucketBlockSet holds all blocks of an equal label set. It internally splits
 // them up by downsampling resolution and allows querying.
 type bucketBlockSet struct {
@@ -1464,8 +1696,8 @@ type bucketBlockSet struct {
 	blocks      [][]*bucketBlock // Ordered buckets for the existing resolutions.
 }
 
-// newBucketBlockSet initializes a new set with the known downsampling windows hard-configured.
-// The set currently does not support arbitrary ranges.
+// newBucketBlockSet initializes a new set with the default downsampling resolutions. Blocks of other
+// resolutions, e.g. of custom downsampling levels of the compactor, add their resolution to the set.
 func newBucketBlockSet(lset labels.Labels) *bucketBlockSet {
 	return &bucketBlockSet{
 		labels:      lset,
@@ -1481,9 +1713,16 @@ func (s *bucketBlockSet) add(b *bucketBlock) error {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	i := int64index(s.resolutions, b.meta.Thanos.Downsample.Resolution)
+	res := b.meta.Thanos.Downsample.Resolution
+	if res < 0 {
+		return errors.Errorf("unsupported downsampling resolution %d", res)
+	}
+	i := int64index(s.resolutions, res)
 	if i < 0 {
-		return errors.Errorf("unsupported downsampling resolution %d", b.meta.Thanos.Downsample.Resolution)
+		// Keep resolutions sorted from high to low.
+		i = sort.Search(len(s.resolutions), func(j int) bool { return s.resolutions[j] < res })
+		s.resolutions = append(s.resolutions[:i], append([]int64{res}, s.resolutions[i:]...)...)
+		s.blocks = append(s.blocks[:i], append([][]*bucketBlock{nil}, s.blocks[i:]...)...)
 	}
 	bs := append(s.blocks[i], b)
 	s.blocks[i] = bs
@@ -1763,6 +2002,8 @@ type bucketIndexReader struct {
 	block *bucketBlock
 	dec   *index.Decoder
 	stats *queryStats
+	// bytesLimiter limits the bytes of postings and series downloaded from object storage, if not nil.
+	bytesLimiter BytesLimiter
 
 	mtx          sync.Mutex
 	loadedSeries map[storage.SeriesRef][]byte
@@ -2055,6 +2296,9 @@ func (r *bucketIndexReader) fetchPostings(ctx context.Context, keys []labels.Lab
 
 		// Fetch from object storage concurrently and update stats and posting list.
 		g.Go(func() error {
+			if err := reserveBytes(r.bytesLimiter, uint64(length)); err != nil {
+				return errors.Wrap(err, "exceeded data bytes limit")
+			}
 			begin := time.Now()
 
 			b, err := r.block.readIndexRange(ctx, start, length)
@@ -2218,6 +2462,9 @@ func (r *bucketIndexReader) PreloadSeries(ctx context.Context, ids []storage.Ser
 }
 
 func (r *bucketIndexReader) loadSeries(ctx context.Context, ids []storage.SeriesRef, refetch bool, start, end uint64) error {
+	if err := reserveBytes(r.bytesLimiter, end-start); err != nil {
+		return errors.Wrap(err, "exceeded data bytes limit")
+	}
 	begin := time.Now()
 
 	b, err := r.block.readIndexRange(ctx, int64(start), int64(end-start))
@@ -2435,6 +2682,10 @@ type bucketChunkReader struct {
 	mtx        sync.Mutex
 	stats      *queryStats
 	chunkBytes []*[]byte // Byte slice to return to the chunk pool on close.
+
+	// chunkBytesLimiter and dataBytesLimiter limit the bytes of chunks downloaded from object storage, if not nil.
+	chunkBytesLimiter BytesLimiter
+	dataBytesLimiter  BytesLimiter
 }
 
 func newBucketChunkReader(block *bucketBlock) *bucketChunkReader {
@@ -2492,9 +2743,23 @@ func (r *bucketChunkReader) load(ctx context.Context, res []seriesEntry, aggrs [
 	return g.Wait()
 }
 
+// reserveBytes reserves the given number of chunk bytes from the limiters of the reader.
+func (r *bucketChunkReader) reserveBytes(num uint64) error {
+	if err := reserveBytes(r.chunkBytesLimiter, num); err != nil {
+		return errors.Wrap(err, "exceeded chunk bytes limit")
+	}
+	if err := reserveBytes(r.dataBytesLimiter, num); err != nil {
+		return errors.Wrap(err, "exceeded data bytes limit")
+	}
+	return nil
+}
+
 // loadChunks will read range [start, end] from the segment file with sequence number seq.
 // This data range covers chunks starting at supplied offsets.
 func (r *bucketChunkReader) loadChunks(ctx context.Context, res []seriesEntry, aggrs []storepb.Aggr, seq int, part Part, pIdxs []loadIdx) error {
+	if err := r.reserveBytes(uint64(part.End - part.Start)); err != nil {
+		return err
+	}
 	fetchBegin := time.Now()
 
 	// Get a reader for the required range.
@@ -2586,6 +2851,9 @@ func (r *bucketChunkReader) loadChunks(ctx context.Context, res []seriesEntry, a
 		r.mtx.Unlock()
 		locked = false
 
+		if err := r.reserveBytes(uint64(chunkLen)); err != nil {
+			return err
+		}
 		fetchBegin = time.Now()
 
 		// Read entire chunk into new buffer.
@@ -13,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/go-kit/log"
 	"github.com/gogo/status"
 	"github.com/oklog/ulid"
@@ -680,6 +681,64 @@ func TestBucketStore_Series_ChunksLimiter_e2e(t *testing.T) {
 	}
 }
 
+func TestBucketStore_Series_BytesLimiter_e2e(t *testing.T) {
+	cases := map[string]struct {
+		maxChunkBytesLimit units.Base2Bytes
+		maxDataBytesLimit  units.Base2Bytes
+		expectedErr        string
+	}{
+		"should succeed if no limit is exceeded": {
+			maxChunkBytesLimit: 1 * units.MiB,
+			maxDataBytesLimit:  1 * units.MiB,
+		},
+		"should fail if the max chunk bytes limit is exceeded": {
+			maxChunkBytesLimit: 1,
+			expectedErr:        "exceeded chunk bytes limit",
+		},
+		"should fail if the max data bytes limit is exceeded": {
+			maxDataBytesLimit: 1,
+			expectedErr:       "exceeded data bytes limit",
+		},
+	}
+
+	for testName, testData := range cases {
+		t.Run(testName, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			bkt := objstore.NewInMemBucket()
+
+			dir, err := ioutil.TempDir("", "test_bucket_bytes_limiter_e2e")
+			testutil.Ok(t, err)
+			defer func() { testutil.Ok(t, os.RemoveAll(dir)) }()
+
+			s := prepareStoreWithTestBlocks(t, dir, bkt, false, NewChunksLimiterFactory(0), NewSeriesLimiterFactory(0), emptyRelabelConfig, allowAllFilterConf)
+			s.store.chunkBytesLimiterFactory = NewBytesLimiterFactory(testData.maxChunkBytesLimit)
+			s.store.dataBytesLimiterFactory = NewBytesLimiterFactory(testData.maxDataBytesLimit)
+			testutil.Ok(t, s.store.SyncBlocks(ctx))
+
+			req := &storepb.SeriesRequest{
+				Matchers: []storepb.LabelMatcher{
+					{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "1"},
+				},
+				MinTime: minTimeDuration.PrometheusTimestamp(),
+				MaxTime: maxTimeDuration.PrometheusTimestamp(),
+			}
+
+			s.cache.SwapWith(noopCache{})
+			srv := newStoreSeriesServer(ctx)
+			err = s.store.Series(req, srv)
+
+			if testData.expectedErr == "" {
+				testutil.Ok(t, err)
+			} else {
+				testutil.NotOk(t, err)
+				testutil.Assert(t, strings.Contains(err.Error(), testData.expectedErr))
+				testutil.Equals(t, codes.ResourceExhausted, status.Code(err))
+			}
+		})
+	}
+}
+
 func TestBucketStore_LabelNames_e2e(t *testing.T) {
 	objtesting.ForeachStore(t, func(t *testing.T, bkt objstore.Bucket) {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -44,6 +44,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/compact"
 	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/gate"
+	"github.com/thanos-io/thanos/pkg/info/infopb"
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/objstore/filesystem"
 	"github.com/thanos-io/thanos/pkg/pool"
@@ -54,6 +55,7 @@ import (
 	storetestutil "github.com/thanos-io/thanos/pkg/store/storepb/testutil"
 	"github.com/thanos-io/thanos/pkg/testutil"
 	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus/tsdbstatuspb"
 )
 
 var emptyRelabelConfig = make([]*relabel.Config, 0)
@@ -427,6 +429,76 @@ func TestBucketBlockSet_remove(t *testing.T) {
 	testutil.Equals(t, input[2].id, res[1].meta.ULID)
 }
 
+func TestBucketBlockSet_customResolutions(t *testing.T) {
+	defer testutil.TolerantVerifyLeak(t)
+
+	set := newBucketBlockSet(labels.Labels{})
+
+	// A custom 6h resolution on top of the default ones.
+	const resLevel3 = 6 * 60 * 60 * 1000
+	type resBlock struct {
+		id         ulid.ULID
+		mint, maxt int64
+		res        int64
+	}
+	input := []resBlock{
+		{id: ulid.MustNew(1, nil), mint: 0, maxt: 100, res: resLevel3},
+		{id: ulid.MustNew(2, nil), mint: 100, maxt: 200, res: downsample.ResLevel2},
+		{id: ulid.MustNew(3, nil), mint: 200, maxt: 300, res: downsample.ResLevel0},
+	}
+	for _, in := range input {
+		var m metadata.Meta
+		m.ULID = in.id
+		m.MinTime = in.mint
+		m.MaxTime = in.maxt
+		m.Thanos.Downsample.Resolution = in.res
+		testutil.Ok(t, set.add(&bucketBlock{meta: &m}))
+	}
+	testutil.Equals(t, []int64{resLevel3, downsample.ResLevel2, downsample.ResLevel1, downsample.ResLevel0}, set.resolutions)
+
+	var ids []ulid.ULID
+	for _, b := range set.getFor(0, 300, resLevel3, nil) {
+		ids = append(ids, b.meta.ULID)
+	}
+	testutil.Equals(t, []ulid.ULID{input[0].id, input[1].id, input[2].id}, ids)
+
+	// Blocks of resolutions higher than the max resolution are not returned.
+	ids = ids[:0]
+	for _, b := range set.getFor(0, 300, downsample.ResLevel2, nil) {
+		ids = append(ids, b.meta.ULID)
+	}
+	testutil.Equals(t, []ulid.ULID{input[1].id, input[2].id}, ids)
+}
+
+func TestBucketStore_TSDBInfos(t *testing.T) {
+	s := &BucketStore{blocks: map[ulid.ULID]*bucketBlock{}}
+	for i, in := range []struct {
+		mint, maxt int64
+		res        int64
+	}{
+		{mint: 200, maxt: 300, res: downsample.ResLevel0},
+		{mint: 0, maxt: 100, res: downsample.ResLevel0},
+		{mint: 100, maxt: 200, res: downsample.ResLevel0},
+		{mint: 400, maxt: 500, res: downsample.ResLevel0},
+		{mint: 0, maxt: 200, res: downsample.ResLevel1},
+		{mint: 100, maxt: 300, res: downsample.ResLevel1},
+	} {
+		var m metadata.Meta
+		m.ULID = ulid.MustNew(uint64(i), nil)
+		m.MinTime = in.mint
+		m.MaxTime = in.maxt
+		m.Thanos.Downsample.Resolution = in.res
+		s.blocks[m.ULID] = &bucketBlock{meta: &m}
+	}
+
+	// Adjacent and overlapping blocks of the same resolution are merged.
+	testutil.Equals(t, []infopb.TSDBInfo{
+		{MinTime: 0, MaxTime: 300, Resolution: downsample.ResLevel0},
+		{MinTime: 400, MaxTime: 500, Resolution: downsample.ResLevel0},
+		{MinTime: 0, MaxTime: 300, Resolution: downsample.ResLevel1},
+	}, s.TSDBInfos())
+}
+
 func TestBucketBlockSet_labelMatchers(t *testing.T) {
 	defer testutil.TolerantVerifyLeak(t)
 
@@ -1496,7 +1568,7 @@ func TestBucketSeries_OneBlock_InMemIndexCacheSegfault(t *testing.T) {
 		bkt:             objstore.WithNoopInstr(bkt),
 		logger:          logger,
 		indexCache:      indexCache,
-		indexReaderPool: indexheader.NewReaderPool(log.NewNopLogger(), false, 0, indexheader.NewReaderPoolMetrics(nil)),
+		indexReaderPool: indexheader.NewReaderPool(log.NewNopLogger(), false, 0, 0, nil, indexheader.NewReaderPoolMetrics(nil)),
 		metrics:         newBucketStoreMetrics(nil),
 		blockSets: map[uint64]*bucketBlockSet{
 			labels.Labels{{Name: "ext1", Value: "1"}}.Hash(): {blocks: [][]*bucketBlock{{b1, b2}}},
@@ -1505,9 +1577,11 @@ func TestBucketSeries_OneBlock_InMemIndexCacheSegfault(t *testing.T) {
 			b1.meta.ULID: b1,
 			b2.meta.ULID: b2,
 		},
-		queryGate:            gate.NewNoop(),
-		chunksLimiterFactory: NewChunksLimiterFactory(0),
-		seriesLimiterFactory: NewSeriesLimiterFactory(0),
+		queryGate:                gate.NewNoop(),
+		chunksLimiterFactory:     NewChunksLimiterFactory(0),
+		seriesLimiterFactory:     NewSeriesLimiterFactory(0),
+		chunkBytesLimiterFactory: NewBytesLimiterFactory(0),
+		dataBytesLimiterFactory:  NewBytesLimiterFactory(0),
 	}
 
 	t.Run("invoke series for one block. Fill the cache on the way.", func(t *testing.T) {
@@ -2093,6 +2167,34 @@ func TestLabelNamesAndValuesHints(t *testing.T) {
 	}
 }
 
+type tsdbStatusTestServer struct {
+	tsdbstatuspb.TSDBStatus_TSDBStatusServer
+
+	statuses []*tsdbstatuspb.TSDBStatus
+}
+
+func (s *tsdbStatusTestServer) Send(r *tsdbstatuspb.TSDBStatusResponse) error {
+	s.statuses = append(s.statuses, r.GetStatus())
+	return nil
+}
+
+func TestBucketStore_TSDBStatus(t *testing.T) {
+	_, store, _, _, _, _, close := setupStoreForHintsTest(t)
+	defer close()
+
+	srv := &tsdbStatusTestServer{}
+	testutil.Ok(t, store.TSDBStatus(&tsdbstatuspb.TSDBStatusRequest{}, srv))
+
+	// Both blocks have the same external labels, so the distinct label values of their two series each are counted together.
+	testutil.Equals(t, []*tsdbstatuspb.TSDBStatus{{
+		LabelValueCountByLabelName: []tsdbstatuspb.Statistic{
+			{Name: "i", Value: 4},
+			{Name: "foo", Value: 1},
+		},
+		LabelSet: labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "ext1", Value: "1"}}},
+	}}, srv.statuses)
+}
+
 func labelNamesFromSeriesSet(series []*storepb.Series) []string {
 	labelsMap := map[string]struct{}{}
 
@@ -2290,7 +2392,7 @@ func benchmarkBlockSeriesWithConcurrency(b *testing.B, concurrency int, blockMet
 				indexReader := blk.indexReader()
 				chunkReader := blk.chunkReader()
 
-				seriesSet, _, err := blockSeries(context.Background(), nil, indexReader, chunkReader, matchers, chunksLimiter, seriesLimiter, req.SkipChunks, req.MinTime, req.MaxTime, req.Aggregates)
+				seriesSet, _, err := blockSeries(context.Background(), nil, indexReader, chunkReader, matchers, chunksLimiter, seriesLimiter, req.SkipChunks, req.MinTime, req.MaxTime, req.Aggregates, nil)
 				testutil.Ok(b, err)
 
 				// Ensure at least 1 series has been returned (as expected).
@@ -0,0 +1,45 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"context"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+type seriesBuffersKey struct{}
+
+// seriesBuffersFromContext returns the buffers the series received for the request can be decoded into, if any.
+func seriesBuffersFromContext(ctx context.Context) *storepb.SeriesBuffers {
+	bufs, _ := ctx.Value(seriesBuffersKey{}).(*storepb.SeriesBuffers)
+	return bufs
+}
+
+// recyclingStoreServer provides the Series calls of the wrapped server with buffers for the series it receives from
+// other stores, which are recycled once the calls are over. This is only safe for the servers of gRPC streams, which
+// marshal the responses they are sent right away.
+type recyclingStoreServer struct {
+	storepb.StoreServer
+}
+
+func (s *recyclingStoreServer) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	bufs := storepb.NewSeriesBuffers()
+	defer bufs.Release()
+
+	return s.StoreServer.Series(r, &seriesBuffersServer{
+		Store_SeriesServer: srv,
+		ctx:                context.WithValue(srv.Context(), seriesBuffersKey{}, bufs),
+	})
+}
+
+type seriesBuffersServer struct {
+	storepb.Store_SeriesServer
+
+	ctx context.Context
+}
+
+func (s *seriesBuffersServer) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,99 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"google.golang.org/grpc"
+
+	"github.com/thanos-io/thanos/pkg/component"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+// serveStore serves the store server with gRPC and returns a client of it.
+func serveStore(t *testing.T, storeSrv storepb.StoreServer) storepb.StoreClient {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	testutil.Ok(t, err)
+	srv := grpc.NewServer()
+	RegisterStoreServer(storeSrv)(srv)
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(srv.Stop)
+
+	cc, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	testutil.Ok(t, err)
+	t.Cleanup(func() { _ = cc.Close() })
+	return storepb.NewStoreClient(cc)
+}
+
+func TestProxyStore_Series_Buffers(t *testing.T) {
+	var (
+		resps    []*storepb.SeriesResponse
+		expected []rawSeries
+	)
+	for i := 0; i < 500; i++ {
+		lset := labels.FromStrings("a", fmt.Sprintf("%03d", i), "b", "1")
+		resps = append(resps, storeSeriesResponse(t, lset, []sample{{1, float64(i)}, {2, 2}}, []sample{{3, 3}}))
+		expected = append(expected, rawSeries{lset: lset, chunks: [][]sample{{{1, float64(i)}, {2, 2}}, {{3, 3}}}})
+	}
+	backend := serveStore(t, &seriesStoreServer{resps: resps})
+
+	cls := []Client{
+		&testClient{StoreClient: backend, minTime: math.MinInt64, maxTime: math.MaxInt64},
+	}
+	proxy := serveStore(t, NewProxyStore(nil, nil, func() []Client { return cls }, component.Query, nil, 0*time.Second))
+
+	// Requests are answered correctly when their buffers are recycled from previous ones.
+	for i := 0; i < 3; i++ {
+		stream, err := proxy.Series(context.Background(), &storepb.SeriesRequest{
+			MinTime:  math.MinInt64,
+			MaxTime:  math.MaxInt64,
+			Matchers: []storepb.LabelMatcher{{Name: "b", Value: "1", Type: storepb.LabelMatcher_EQ}},
+		})
+		testutil.Ok(t, err)
+
+		var got []storepb.Series
+		for {
+			r, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, "", r.GetWarning())
+			got = append(got, *r.GetSeries())
+		}
+		seriesEquals(t, expected, got)
+	}
+}
+
+func TestRecyclingStoreServer_Series(t *testing.T) {
+	var bufs *storepb.SeriesBuffers
+	srv := &recyclingStoreServer{StoreServer: &contextStoreServer{series: func(srv storepb.Store_SeriesServer) {
+		bufs = seriesBuffersFromContext(srv.Context())
+	}}}
+	testutil.Ok(t, srv.Series(&storepb.SeriesRequest{}, &responsesServer{}))
+	testutil.Assert(t, bufs != nil, "no buffers provided")
+
+	testutil.Assert(t, seriesBuffersFromContext(context.Background()) == nil, "unexpected buffers")
+}
+
+// contextStoreServer calls the given function on Series calls.
+type contextStoreServer struct {
+	storepb.StoreServer
+
+	series func(srv storepb.Store_SeriesServer)
+}
+
+func (s *contextStoreServer) Series(_ *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
+	s.series(srv)
+	return nil
+}
@@ -227,17 +227,17 @@ func (cb *CachingBucket) Get(ctx context.Context, name string) (io.ReadCloser, e
 	existsKey := existsVerb.String()
 
 	hits := cfg.Cache.Fetch(ctx, []string{contentKey, existsKey})
-	if hits[contentKey] != nil {
+
+	// If we know that file doesn't exist, we can return that. Useful for deletion marks.
+	// This is checked first, as deleting an object can't remove its cached content.
+	if cachedNotExists(hits[existsKey]) {
 		cb.operationHits.WithLabelValues(objstore.OpGet, cfgName).Inc()
-		return objstore.NopCloserWithSize(bytes.NewBuffer(hits[contentKey])), nil
+		return nil, errObjNotFound
 	}
 
-	// If we know that file doesn't exist, we can return that. Useful for deletion marks.
-	if ex := hits[existsKey]; ex != nil {
-		if exists, err := strconv.ParseBool(string(ex)); err == nil && !exists {
-			cb.operationHits.WithLabelValues(objstore.OpGet, cfgName).Inc()
-			return nil, errObjNotFound
-		}
+	if hits[contentKey] != nil {
+		cb.operationHits.WithLabelValues(objstore.OpGet, cfgName).Inc()
+		return objstore.NopCloserWithSize(bytes.NewBuffer(hits[contentKey])), nil
 	}
 
 	getTime := time.Now()
@@ -264,6 +264,78 @@ func (cb *CachingBucket) Get(ctx context.Context, name string) (io.ReadCloser, e
 	}, nil
 }
 
+// cachedNotExists returns true if the cached result of an Exists call says that the object doesn't exist.
+func cachedNotExists(ex []byte) bool {
+	if ex == nil {
+		return false
+	}
+	exists, err := strconv.ParseBool(string(ex))
+	return err == nil && !exists
+}
+
+// Upload uploads the object and updates the cached results of operations on it, so that the uploaded object
+// is visible right away to readers using the same cache.
+func (cb *CachingBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	_, getCfg := cb.cfg.FindGetConfig(name)
+
+	var content *cappedBuffer
+	if getCfg != nil {
+		content = &cappedBuffer{maxSize: getCfg.MaxCacheableSize}
+		r = io.TeeReader(r, content)
+	}
+
+	uploadTime := time.Now()
+	if err := cb.Bucket.Upload(ctx, name, r); err != nil {
+		return err
+	}
+
+	existsVerb := cachekey.BucketCacheKey{Verb: cachekey.ExistsVerb, Name: name}
+	existsKey := existsVerb.String()
+	if _, cfg := cb.cfg.FindExistConfig(name); cfg != nil {
+		storeExistsCacheEntry(ctx, existsKey, true, uploadTime, cfg.Cache, cfg.ExistsTTL, cfg.DoesntExistTTL)
+	}
+	if getCfg != nil {
+		storeExistsCacheEntry(ctx, existsKey, true, uploadTime, getCfg.Cache, getCfg.ExistsTTL, getCfg.DoesntExistTTL)
+
+		// Content larger than the max cacheable size is not cached, so there is nothing to update.
+		if content.buf != nil {
+			contentVerb := cachekey.BucketCacheKey{Verb: cachekey.ContentVerb, Name: name}
+			if ttl := getCfg.ContentTTL - time.Since(uploadTime); ttl > 0 {
+				getCfg.Cache.Store(ctx, map[string][]byte{contentVerb.String(): content.buf.Bytes()}, ttl)
+			}
+		}
+	}
+	if _, cfg := cb.cfg.FindAttributesConfig(name); cfg != nil {
+		// Attributes like the last modification time are only known to the bucket.
+		if attrs, err := cb.Bucket.Attributes(ctx, name); err == nil {
+			attrVerb := cachekey.BucketCacheKey{Verb: cachekey.AttributesVerb, Name: name}
+			if raw, err := json.Marshal(attrs); err == nil {
+				cfg.Cache.Store(ctx, map[string][]byte{attrVerb.String(): raw}, cfg.TTL)
+			}
+		}
+	}
+	return nil
+}
+
+// Delete removes the object and caches that it doesn't exist anymore, so that readers using the same cache
+// don't see it anymore right away.
+func (cb *CachingBucket) Delete(ctx context.Context, name string) error {
+	deleteTime := time.Now()
+	if err := cb.Bucket.Delete(ctx, name); err != nil {
+		return err
+	}
+
+	existsVerb := cachekey.BucketCacheKey{Verb: cachekey.ExistsVerb, Name: name}
+	existsKey := existsVerb.String()
+	if _, cfg := cb.cfg.FindExistConfig(name); cfg != nil {
+		storeExistsCacheEntry(ctx, existsKey, false, deleteTime, cfg.Cache, cfg.ExistsTTL, cfg.DoesntExistTTL)
+	}
+	if _, cfg := cb.cfg.FindGetConfig(name); cfg != nil {
+		storeExistsCacheEntry(ctx, existsKey, false, deleteTime, cfg.Cache, cfg.ExistsTTL, cfg.DoesntExistTTL)
+	}
+	return nil
+}
+
 func (cb *CachingBucket) IsObjNotFoundErr(err error) bool {
 	return err == errObjNotFound || cb.Bucket.IsObjNotFoundErr(err)
 }
@@ -294,9 +366,16 @@ func (cb *CachingBucket) cachedAttributes(ctx context.Context, name, cfgName str
 	attrVerb := cachekey.BucketCacheKey{Verb: cachekey.AttributesVerb, Name: name}
 	key := attrVerb.String()
 
+	existsVerb := cachekey.BucketCacheKey{Verb: cachekey.ExistsVerb, Name: name}
+	existsKey := existsVerb.String()
+
 	cb.operationRequests.WithLabelValues(objstore.OpAttributes, cfgName).Inc()
 
-	hits := cache.Fetch(ctx, []string{key})
+	hits := cache.Fetch(ctx, []string{key, existsKey})
+	if cachedNotExists(hits[existsKey]) {
+		cb.operationHits.WithLabelValues(objstore.OpAttributes, cfgName).Inc()
+		return objstore.ObjectAttributes{}, errObjNotFound
+	}
 	if raw, ok := hits[key]; ok {
 		var attrs objstore.ObjectAttributes
 		err := json.Unmarshal(raw, &attrs)
@@ -603,6 +682,28 @@ func (g *getReader) Read(p []byte) (n int, err error) {
 	return n, err
 }
 
+// cappedBuffer buffers written bytes until their size exceeds the max size.
+type cappedBuffer struct {
+	buf     *bytes.Buffer
+	maxSize int
+	full    bool
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	if c.full {
+		return len(p), nil
+	}
+	if c.buf == nil {
+		c.buf = new(bytes.Buffer)
+	}
+	if c.buf.Len()+len(p) > c.maxSize {
+		// Object is larger than max size, stop buffering.
+		c.buf, c.full = nil, true
+		return len(p), nil
+	}
+	return c.buf.Write(p)
+}
+
 // JSONIterCodec encodes iter results into JSON. Suitable for root dir.
 type JSONIterCodec struct{}
 
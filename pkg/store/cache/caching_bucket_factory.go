@@ -52,10 +52,11 @@ type CachingWithBackendConfig struct {
 	// How long to cache result of Iter call in root directory.
 	BlocksIterTTL time.Duration `yaml:"blocks_iter_ttl"`
 
-	// Config for Exists and Get operations for metadata files.
+	// Config for Exists, Get and Attributes operations for metadata files.
 	MetafileExistsTTL      time.Duration `yaml:"metafile_exists_ttl"`
 	MetafileDoesntExistTTL time.Duration `yaml:"metafile_doesnt_exist_ttl"`
 	MetafileContentTTL     time.Duration `yaml:"metafile_content_ttl"`
+	MetafileAttributesTTL  time.Duration `yaml:"metafile_attributes_ttl"`
 }
 
 func (cfg *CachingWithBackendConfig) Defaults() {
@@ -67,6 +68,7 @@ func (cfg *CachingWithBackendConfig) Defaults() {
 	cfg.MetafileExistsTTL = 2 * time.Hour
 	cfg.MetafileDoesntExistTTL = 15 * time.Minute
 	cfg.MetafileContentTTL = 24 * time.Hour
+	cfg.MetafileAttributesTTL = 24 * time.Hour
 	cfg.MetafileMaxSize = 1024 * 1024 // Equal to default MaxItemSize in memcached client.
 }
 
@@ -74,30 +76,68 @@ func (cfg *CachingWithBackendConfig) Defaults() {
 func NewCachingBucketFromYaml(yamlContent []byte, bucket objstore.Bucket, logger log.Logger, reg prometheus.Registerer, r *route.Router) (objstore.InstrumentedBucket, error) {
 	level.Info(logger).Log("msg", "loading caching bucket configuration")
 
+	config, err := parseCachingBucketConfig(yamlContent)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := cache.NewCachingBucketConfig()
+
+	// Configure cache paths.
+	cfg.CacheAttributes("chunks", nil, isTSDBChunkFile, config.ChunkObjectAttrsTTL)
+	cfg.CacheGetRange("chunks", nil, isTSDBChunkFile, config.ChunkSubrangeSize, config.ChunkObjectAttrsTTL, config.ChunkSubrangeTTL, config.MaxChunksGetRangeRequests)
+	configureMetafileCaching(cfg, config)
+
+	// Cache Iter requests for root.
+	cfg.CacheIter("blocks-iter", nil, isBlocksRootDir, config.BlocksIterTTL, JSONIterCodec{})
+
+	return newCachingBucket(config, bucket, cfg, logger, reg, r)
+}
+
+// NewMetafileCachingBucketFromYaml uses YAML configuration to create new caching bucket which only caches the
+// Exists, Get and Attributes operations for metadata files. Unlike NewCachingBucketFromYaml, it doesn't cache
+// listing of blocks, so it can be used by components which have to notice new and deleted blocks right away,
+// like the compactor. The groupcache provider is not supported, as it requires to serve peers.
+func NewMetafileCachingBucketFromYaml(yamlContent []byte, bucket objstore.Bucket, logger log.Logger, reg prometheus.Registerer) (objstore.InstrumentedBucket, error) {
+	level.Info(logger).Log("msg", "loading metadata caching bucket configuration")
+
+	config, err := parseCachingBucketConfig(yamlContent)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToUpper(string(config.Type)) == string(GroupcacheBucketCacheProvider) {
+		return nil, errors.Errorf("cache type %s is not supported for caching metadata files", config.Type)
+	}
+
+	cfg := cache.NewCachingBucketConfig()
+	configureMetafileCaching(cfg, config)
+
+	return newCachingBucket(config, bucket, cfg, logger, reg, nil)
+}
+
+func parseCachingBucketConfig(yamlContent []byte) (*CachingWithBackendConfig, error) {
 	config := &CachingWithBackendConfig{}
 	config.Defaults()
 
 	if err := yaml.UnmarshalStrict(yamlContent, config); err != nil {
 		return nil, errors.Wrap(err, "parsing config YAML file")
 	}
+	return config, nil
+}
 
+func configureMetafileCaching(cfg *cache.CachingBucketConfig, config *CachingWithBackendConfig) {
+	cfg.CacheExists("meta.jsons", nil, isMetaFile, config.MetafileExistsTTL, config.MetafileDoesntExistTTL)
+	cfg.CacheGet("meta.jsons", nil, isMetaFile, int(config.MetafileMaxSize), config.MetafileContentTTL, config.MetafileExistsTTL, config.MetafileDoesntExistTTL)
+	cfg.CacheAttributes("meta.jsons", nil, isMetaFile, config.MetafileAttributesTTL)
+}
+
+func newCachingBucket(config *CachingWithBackendConfig, bucket objstore.Bucket, cfg *cache.CachingBucketConfig, logger log.Logger, reg prometheus.Registerer, r *route.Router) (objstore.InstrumentedBucket, error) {
 	backendConfig, err := yaml.Marshal(config.BackendConfig)
 	if err != nil {
 		return nil, errors.Wrap(err, "marshal content of cache backend configuration")
 	}
 
 	var c cache.Cache
-	cfg := cache.NewCachingBucketConfig()
-
-	// Configure cache paths.
-	cfg.CacheAttributes("chunks", nil, isTSDBChunkFile, config.ChunkObjectAttrsTTL)
-	cfg.CacheGetRange("chunks", nil, isTSDBChunkFile, config.ChunkSubrangeSize, config.ChunkObjectAttrsTTL, config.ChunkSubrangeTTL, config.MaxChunksGetRangeRequests)
-	cfg.CacheExists("meta.jsons", nil, isMetaFile, config.MetafileExistsTTL, config.MetafileDoesntExistTTL)
-	cfg.CacheGet("meta.jsons", nil, isMetaFile, int(config.MetafileMaxSize), config.MetafileContentTTL, config.MetafileExistsTTL, config.MetafileDoesntExistTTL)
-
-	// Cache Iter requests for root.
-	cfg.CacheIter("blocks-iter", nil, isBlocksRootDir, config.BlocksIterTTL, JSONIterCodec{})
-
 	switch strings.ToUpper(string(config.Type)) {
 	case string(MemcachedBucketCacheProvider):
 		var memcached cacheutil.RemoteCacheClient
@@ -146,7 +186,8 @@ var chunksMatcher = regexp.MustCompile(`^.*/chunks/\d+$`)
 func isTSDBChunkFile(name string) bool { return chunksMatcher.MatchString(name) }
 
 func isMetaFile(name string) bool {
-	return strings.HasSuffix(name, "/"+metadata.MetaFilename) || strings.HasSuffix(name, "/"+metadata.DeletionMarkFilename)
+	return strings.HasSuffix(name, "/"+metadata.MetaFilename) || strings.HasSuffix(name, "/"+metadata.DeletionMarkFilename) || strings.HasSuffix(name, "/"+metadata.NoCompactMarkFilename) ||
+		strings.HasSuffix(name, "/"+metadata.NoDownsampleMarkFilename)
 }
 
 func isBlocksRootDir(name string) bool {
@@ -10,15 +10,19 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"path"
 	"sort"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
 	promtest "github.com/prometheus/client_golang/prometheus/testutil"
 
+	"github.com/thanos-io/thanos/pkg/block/metadata"
 	thanoscache "github.com/thanos-io/thanos/pkg/cache"
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/runutil"
@@ -674,4 +678,73 @@ func verifyObjectAttrs(t *testing.T, cb *CachingBucket, file string, expectedLen
 	}
 }
 
+func TestUploadDelete(t *testing.T) {
+	inmem := objstore.NewInMemBucket()
+
+	cache := newMockCache()
+
+	cfg := thanoscache.NewCachingBucketConfig()
+	const cfgName = "metafile"
+	cfg.CacheGet(cfgName, cache, matchAll, 5, 10*time.Minute, 10*time.Minute, 2*time.Minute)
+	cfg.CacheExists(cfgName, cache, matchAll, 10*time.Minute, 2*time.Minute)
+	cfg.CacheAttributes(cfgName, cache, matchAll, time.Minute)
+
+	cb, err := NewCachingBucket(inmem, cfg, nil, nil)
+	testutil.Ok(t, err)
+
+	verifyGet(t, cb, testFilename, nil, false, cfgName)
+	verifyExists(t, cb, testFilename, false, true, cfgName)
+
+	// Uploaded objects are visible right away.
+	data := []byte("hej")
+	testutil.Ok(t, cb.Upload(context.Background(), testFilename, bytes.NewBuffer(data)))
+	verifyGet(t, cb, testFilename, data, true, cfgName)
+	verifyExists(t, cb, testFilename, true, true, cfgName)
+	verifyObjectAttrs(t, cb, testFilename, len(data), true, cfgName)
+
+	// Content of objects larger than the max cacheable size isn't cached on upload.
+	const bigFilename = "/random_object_big"
+	bigData := []byte("hello world")
+	testutil.Ok(t, cb.Upload(context.Background(), bigFilename, bytes.NewBuffer(bigData)))
+	verifyGet(t, cb, bigFilename, bigData, false, cfgName)
+	verifyExists(t, cb, bigFilename, true, true, cfgName)
+
+	// Deleted objects are gone right away, even though their content and attributes are still cached.
+	testutil.Ok(t, cb.Delete(context.Background(), testFilename))
+	verifyGet(t, cb, testFilename, nil, true, cfgName)
+	verifyExists(t, cb, testFilename, false, true, cfgName)
+	verifyObjectAttrs(t, cb, testFilename, -1, true, cfgName)
+}
+
+func TestNewMetafileCachingBucketFromYaml(t *testing.T) {
+	inmem := objstore.NewInMemBucket()
+
+	_, err := NewMetafileCachingBucketFromYaml([]byte("type: GROUPCACHE\nconfig:\n  self_url: http://localhost:10902\n  peers: [http://localhost:10902]\n  groupcache_group: test"), inmem, log.NewNopLogger(), nil)
+	testutil.NotOk(t, err)
+
+	bkt, err := NewMetafileCachingBucketFromYaml([]byte("type: IN-MEMORY\nconfig:\n  max_size: 10MB\n  max_item_size: 1MB"), inmem, log.NewNopLogger(), nil)
+	testutil.Ok(t, err)
+
+	metaFile := path.Join(ulid.MustNew(1, nil).String(), metadata.MetaFilename)
+	testutil.Ok(t, inmem.Upload(context.Background(), metaFile, strings.NewReader("{}")))
+
+	ok, err := bkt.Exists(context.Background(), metaFile)
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok)
+
+	// Only metadata files are cached, the result for other objects is always fresh.
+	testutil.Ok(t, inmem.Delete(context.Background(), metaFile))
+	ok, err = bkt.Exists(context.Background(), metaFile)
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok)
+
+	ok, err = bkt.Exists(context.Background(), "chunks/000001")
+	testutil.Ok(t, err)
+	testutil.Assert(t, !ok)
+	testutil.Ok(t, inmem.Upload(context.Background(), "chunks/000001", strings.NewReader("chunk")))
+	ok, err = bkt.Exists(context.Background(), "chunks/000001")
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok)
+}
+
 func matchAll(string) bool { return true }
@@ -4,11 +4,14 @@
 package store
 
 import (
+	"fmt"
 	"sync"
 
-	"github.com/pkg/errors"
+	"github.com/alecthomas/units"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/atomic"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type ChunksLimiter interface {
@@ -25,6 +28,13 @@ type SeriesLimiter interface {
 	Reserve(num uint64) error
 }
 
+type BytesLimiter interface {
+	// Reserve num bytes out of the total number of bytes enforced by the limiter.
+	// Returns an error if the limit has been exceeded. This function must be
+	// goroutine safe.
+	Reserve(num uint64) error
+}
+
 // ChunksLimiterFactory is used to create a new ChunksLimiter. The factory is useful for
 // projects depending on Thanos (eg. Cortex) which have dynamic limits.
 type ChunksLimiterFactory func(failedCounter prometheus.Counter) ChunksLimiter
@@ -32,6 +42,25 @@ type ChunksLimiterFactory func(failedCounter prometheus.Counter) ChunksLimiter
 // SeriesLimiterFactory is used to create a new SeriesLimiter.
 type SeriesLimiterFactory func(failedCounter prometheus.Counter) SeriesLimiter
 
+// BytesLimiterFactory is used to create a new BytesLimiter.
+type BytesLimiterFactory func(failedCounter prometheus.Counter) BytesLimiter
+
+// LimitExceededError is returned by limiters when a request exceeds their limit. It translates into the
+// ResourceExhausted gRPC code, so that queriers fail the query instead of returning a partial response.
+type LimitExceededError struct {
+	Limit    uint64
+	Reserved uint64
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("limit %v violated (got %v)", e.Limit, e.Reserved)
+}
+
+// GRPCStatus returns the ResourceExhausted status of the error.
+func (e *LimitExceededError) GRPCStatus() *status.Status {
+	return status.New(codes.ResourceExhausted, e.Error())
+}
+
 // Limiter is a simple mechanism for checking if something has passed a certain threshold.
 type Limiter struct {
 	limit    uint64
@@ -56,7 +85,7 @@ func (l *Limiter) Reserve(num uint64) error {
 		// We need to protect from the counter being incremented twice due to concurrency
 		// while calling Reserve().
 		l.failedOnce.Do(l.failedCounter.Inc)
-		return errors.Errorf("limit %v violated (got %v)", l.limit, reserved)
+		return &LimitExceededError{Limit: l.limit, Reserved: reserved}
 	}
 	return nil
 }
@@ -74,3 +103,26 @@ func NewSeriesLimiterFactory(limit uint64) SeriesLimiterFactory {
 		return NewLimiter(limit, failedCounter)
 	}
 }
+
+// NewBytesLimiterFactory makes a new BytesLimiterFactory with a static limit.
+func NewBytesLimiterFactory(limit units.Base2Bytes) BytesLimiterFactory {
+	return func(failedCounter prometheus.Counter) BytesLimiter {
+		return NewLimiter(uint64(limit), failedCounter)
+	}
+}
+
+// truncateToLimit returns the first limit elements of the sorted strings, or all of them if limit is 0.
+func truncateToLimit(s []string, limit int64) []string {
+	if limit > 0 && int64(len(s)) > limit {
+		return s[:limit]
+	}
+	return s
+}
+
+// reserveBytes reserves num bytes from the limiter, if not nil.
+func reserveBytes(l BytesLimiter, num uint64) error {
+	if l == nil {
+		return nil
+	}
+	return l.Reserve(num)
+}
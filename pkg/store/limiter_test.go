@@ -6,9 +6,13 @@ package store
 import (
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	"github.com/thanos-io/thanos/pkg/testutil"
 )
 
@@ -25,6 +29,9 @@ func TestLimiter(t *testing.T) {
 	testutil.NotOk(t, l.Reserve(1))
 	testutil.Equals(t, float64(1), prom_testutil.ToFloat64(c))
 
-	testutil.NotOk(t, l.Reserve(2))
+	err := l.Reserve(2)
+	testutil.NotOk(t, err)
 	testutil.Equals(t, float64(1), prom_testutil.ToFloat64(c))
+	testutil.Equals(t, "limit 10 violated (got 13)", err.Error())
+	testutil.Equals(t, codes.ResourceExhausted, status.Code(errors.Cause(errors.Wrap(err, "exceeded limit"))))
 }
@@ -154,18 +154,24 @@ func (p *PrometheusStore) Series(r *storepb.SeriesRequest, s storepb.Store_Serie
 		return status.Error(codes.InvalidArgument, "no matchers specified (excluding external labels)")
 	}
 
-	// Don't ask for more than available time. This includes potential `minTime` flag limit.
-	availableMinTime, _ := p.timestamps()
-	if r.MinTime < availableMinTime {
-		r.MinTime = availableMinTime
+	// Don't ask for more than available time. This includes potential `minTime` and `maxTime` flag limits.
+	var ok bool
+	if r.MinTime, r.MaxTime, ok = p.clampTimeRange(r.MinTime, r.MaxTime); !ok {
+		return nil
 	}
 
+	// Prometheus returns the series of all shards, so the series of other shards are dropped before sending.
+	s = newShardSeriesServer(s, r.ShardInfo)
+
 	if r.SkipChunks {
 		labelMaps, err := p.client.SeriesInGRPC(s.Context(), p.base, matchers, r.MinTime, r.MaxTime)
 		if err != nil {
 			return err
 		}
-		for _, lbm := range labelMaps {
+		for i, lbm := range labelMaps {
+			if r.Limit > 0 && int64(i) >= r.Limit {
+				break
+			}
 			lset := make([]labelpb.ZLabel, 0, len(lbm)+len(extLset))
 			for k, v := range lbm {
 				lset = append(lset, labelpb.ZLabel{Name: k, Value: v})
@@ -217,13 +223,13 @@ func (p *PrometheusStore) Series(r *storepb.SeriesRequest, s storepb.Store_Serie
 	// remote read.
 	contentType := httpResp.Header.Get("Content-Type")
 	if strings.HasPrefix(contentType, "application/x-protobuf") {
-		return p.handleSampledPrometheusResponse(s, httpResp, queryPrometheusSpan, extLset)
+		return p.handleSampledPrometheusResponse(s, httpResp, queryPrometheusSpan, extLset, r.Limit)
 	}
 
 	if !strings.HasPrefix(contentType, "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse") {
 		return errors.Errorf("not supported remote read content type: %s", contentType)
 	}
-	return p.handleStreamedPrometheusResponse(s, httpResp, queryPrometheusSpan, extLset)
+	return p.handleStreamedPrometheusResponse(s, httpResp, queryPrometheusSpan, extLset, r.Limit)
 }
 
 func (p *PrometheusStore) queryPrometheus(s storepb.Store_SeriesServer, r *storepb.SeriesRequest) error {
@@ -292,7 +298,7 @@ func (p *PrometheusStore) queryPrometheus(s storepb.Store_SeriesServer, r *store
 	return nil
 }
 
-func (p *PrometheusStore) handleSampledPrometheusResponse(s storepb.Store_SeriesServer, httpResp *http.Response, querySpan tracing.Span, extLset labels.Labels) error {
+func (p *PrometheusStore) handleSampledPrometheusResponse(s storepb.Store_SeriesServer, httpResp *http.Response, querySpan tracing.Span, extLset labels.Labels, limit int64) error {
 	level.Debug(p.logger).Log("msg", "started handling ReadRequest_SAMPLED response type.")
 
 	resp, err := p.fetchSampledResponse(s.Context(), httpResp)
@@ -305,7 +311,11 @@ func (p *PrometheusStore) handleSampledPrometheusResponse(s storepb.Store_Series
 	defer span.Finish()
 	span.SetTag("series_count", len(resp.Results[0].Timeseries))
 
+	var numSeries int64
 	for _, e := range resp.Results[0].Timeseries {
+		if limit > 0 && numSeries >= limit {
+			break
+		}
 		lset := labelpb.ExtendSortedLabels(labelpb.ZLabelsToPromLabels(e.Labels), extLset)
 		if len(e.Samples) == 0 {
 			// As found in https://github.com/thanos-io/thanos/issues/381
@@ -331,12 +341,13 @@ func (p *PrometheusStore) handleSampledPrometheusResponse(s storepb.Store_Series
 		})); err != nil {
 			return err
 		}
+		numSeries++
 	}
 	level.Debug(p.logger).Log("msg", "handled ReadRequest_SAMPLED request.", "series", len(resp.Results[0].Timeseries))
 	return nil
 }
 
-func (p *PrometheusStore) handleStreamedPrometheusResponse(s storepb.Store_SeriesServer, httpResp *http.Response, querySpan tracing.Span, extLset labels.Labels) error {
+func (p *PrometheusStore) handleStreamedPrometheusResponse(s storepb.Store_SeriesServer, httpResp *http.Response, querySpan tracing.Span, extLset labels.Labels, limit int64) error {
 	level.Debug(p.logger).Log("msg", "started handling ReadRequest_STREAMED_XOR_CHUNKS streamed read response.")
 
 	framesNum := 0
@@ -355,7 +366,13 @@ func (p *PrometheusStore) handleStreamedPrometheusResponse(s storepb.Store_Serie
 	seriesStats := &storepb.SeriesStatsCounter{}
 
 	// TODO(bwplotka): Put read limit as a flag.
+	// Series may be split over multiple frames, so the limit is applied only once the next series starts.
+	var (
+		numSeries      int64
+		lastSeriesHash uint64
+	)
 	stream := remote.NewChunkedReader(bodySizer, remote.DefaultChunkedReadLimit, *data)
+Frames:
 	for {
 		res := &prompb.ChunkedReadResponse{}
 		err := stream.NextProto(res)
@@ -372,6 +389,13 @@ func (p *PrometheusStore) handleStreamedPrometheusResponse(s storepb.Store_Serie
 
 		framesNum++
 		for _, series := range res.ChunkedSeries {
+			if h := labelpb.HashWithPrefix("", series.Labels); numSeries == 0 || h != lastSeriesHash {
+				if limit > 0 && numSeries >= limit {
+					break Frames
+				}
+				numSeries++
+				lastSeriesHash = h
+			}
 			seriesStats.CountSeries(series.Labels)
 			thanosChks := make([]storepb.AggrChunk, len(series.Chunks))
 			for i, chk := range series.Chunks {
@@ -588,15 +612,20 @@ func (p *PrometheusStore) LabelNames(ctx context.Context, r *storepb.LabelNamesR
 		return &storepb.LabelNamesResponse{Names: nil}, nil
 	}
 
+	start, end, ok := p.clampTimeRange(r.Start, r.End)
+	if !ok {
+		return &storepb.LabelNamesResponse{Names: nil}, nil
+	}
+
 	var lbls []string
 	version, parseErr := semver.Parse(p.promVersion())
 	if len(matchers) == 0 || (parseErr == nil && version.GTE(baseVer)) {
-		lbls, err = p.client.LabelNamesInGRPC(ctx, p.base, matchers, r.Start, r.End)
+		lbls, err = p.client.LabelNamesInGRPC(ctx, p.base, matchers, start, end)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		sers, err := p.client.SeriesInGRPC(ctx, p.base, matchers, r.Start, r.End)
+		sers, err := p.client.SeriesInGRPC(ctx, p.base, matchers, start, end)
 		if err != nil {
 			return nil, err
 		}
@@ -621,7 +650,7 @@ func (p *PrometheusStore) LabelNames(ctx context.Context, r *storepb.LabelNamesR
 		sort.Strings(lbls)
 	}
 
-	return &storepb.LabelNamesResponse{Names: lbls}, nil
+	return &storepb.LabelNamesResponse{Names: truncateToLimit(lbls, r.Limit)}, nil
 }
 
 // LabelValues returns all known label values for a given label name.
@@ -645,6 +674,11 @@ func (p *PrometheusStore) LabelValues(ctx context.Context, r *storepb.LabelValue
 		return &storepb.LabelValuesResponse{Values: nil}, nil
 	}
 
+	start, end, ok := p.clampTimeRange(r.Start, r.End)
+	if !ok {
+		return &storepb.LabelValuesResponse{Values: nil}, nil
+	}
+
 	var (
 		sers []map[string]string
 		vals []string
@@ -652,12 +686,12 @@ func (p *PrometheusStore) LabelValues(ctx context.Context, r *storepb.LabelValue
 
 	version, parseErr := semver.Parse(p.promVersion())
 	if len(matchers) == 0 || (parseErr == nil && version.GTE(baseVer)) {
-		vals, err = p.client.LabelValuesInGRPC(ctx, p.base, r.Label, matchers, r.Start, r.End)
+		vals, err = p.client.LabelValuesInGRPC(ctx, p.base, r.Label, matchers, start, end)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		sers, err = p.client.SeriesInGRPC(ctx, p.base, matchers, r.Start, r.End)
+		sers, err = p.client.SeriesInGRPC(ctx, p.base, matchers, start, end)
 		if err != nil {
 			return nil, err
 		}
@@ -675,7 +709,7 @@ func (p *PrometheusStore) LabelValues(ctx context.Context, r *storepb.LabelValue
 	}
 
 	sort.Strings(vals)
-	return &storepb.LabelValuesResponse{Values: vals}, nil
+	return &storepb.LabelValuesResponse{Values: truncateToLimit(vals, r.Limit)}, nil
 }
 
 func (p *PrometheusStore) LabelSet() []labelpb.ZLabelSet {
@@ -697,3 +731,16 @@ func (p *PrometheusStore) LabelSet() []labelpb.ZLabelSet {
 func (p *PrometheusStore) Timestamps() (mint int64, maxt int64) {
 	return p.timestamps()
 }
+
+// clampTimeRange limits the given time range to the time range of the data served, and returns whether any of it
+// is left.
+func (p *PrometheusStore) clampTimeRange(mint, maxt int64) (int64, int64, bool) {
+	availableMinTime, availableMaxTime := p.timestamps()
+	if mint < availableMinTime {
+		mint = availableMinTime
+	}
+	if maxt > availableMaxTime {
+		maxt = availableMaxTime
+	}
+	return mint, maxt, mint <= maxt
+}
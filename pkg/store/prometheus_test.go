@@ -61,10 +61,10 @@ func testPrometheusStoreSeriesE2e(t *testing.T, prefix string) {
 	u, err := url.Parse(fmt.Sprintf("http://%s", p.Addr()))
 	testutil.Ok(t, err)
 
-	limitMinT := int64(0)
+	limitMinT, limitMaxT := int64(0), int64(math.MaxInt64)
 	proxy, err := NewPrometheusStore(nil, nil, promclient.NewDefaultClient(), u, component.Sidecar,
 		func() labels.Labels { return labels.FromStrings("region", "eu-west") },
-		func() (int64, int64) { return limitMinT, -1 }, nil) // Maxt does not matter.
+		func() (int64, int64) { return limitMinT, limitMaxT }, nil)
 	testutil.Ok(t, err)
 
 	// Query all three samples except for the first one. Since we round up queried data
@@ -131,6 +131,45 @@ func testPrometheusStoreSeriesE2e(t *testing.T, prefix string) {
 		samples := expandChunk(chk.Iterator(nil))
 		testutil.Equals(t, []sample{{baseT + 200, 2}, {baseT + 300, 3}}, samples)
 	}
+	// Query all samples, but limit maxt time to exclude the last one.
+	{
+		limitMaxT = baseT + 299
+		srv := newStoreSeriesServer(ctx)
+		testutil.Ok(t, proxy.Series(&storepb.SeriesRequest{
+			MinTime: 0,
+			MaxTime: baseT + 300,
+			Matchers: []storepb.LabelMatcher{
+				{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "b"},
+			},
+		}, srv))
+		// Revert for next cases.
+		limitMaxT = math.MaxInt64
+
+		testutil.Equals(t, 1, len(srv.SeriesSet))
+		testutil.Equals(t, 1, len(srv.SeriesSet[0].Chunks))
+
+		chk, err := chunkenc.FromData(chunkenc.EncXOR, srv.SeriesSet[0].Chunks[0].Raw.Data)
+		testutil.Ok(t, err)
+
+		samples := expandChunk(chk.Iterator(nil))
+		testutil.Equals(t, []sample{{baseT + 100, 1}, {baseT + 200, 2}}, samples)
+	}
+	// Nothing is queried outside of the limits.
+	{
+		limitMinT = baseT + 301
+		srv := newStoreSeriesServer(ctx)
+		testutil.Ok(t, proxy.Series(&storepb.SeriesRequest{
+			MinTime: 0,
+			MaxTime: baseT + 300,
+			Matchers: []storepb.LabelMatcher{
+				{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "b"},
+			},
+		}, srv))
+		// Revert for next cases.
+		limitMinT = 0
+
+		testutil.Equals(t, 0, len(srv.SeriesSet))
+	}
 	// Querying by external labels only.
 	{
 		srv := newStoreSeriesServer(ctx)
@@ -450,6 +489,35 @@ func TestPrometheusStore_Info(t *testing.T) {
 	testutil.Equals(t, int64(456), resp.MaxTime)
 }
 
+func TestPrometheusStore_TimeRangeLimits(t *testing.T) {
+	defer testutil.TolerantVerifyLeak(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// No Prometheus is queried for requests outside of the time range served.
+	proxy, err := NewPrometheusStore(nil, nil, promclient.NewDefaultClient(), nil, component.Sidecar,
+		func() labels.Labels { return labels.FromStrings("region", "eu-west") },
+		func() (int64, int64) { return 123, 456 }, nil)
+	testutil.Ok(t, err)
+
+	srv := newStoreSeriesServer(ctx)
+	testutil.Ok(t, proxy.Series(&storepb.SeriesRequest{
+		MinTime:  457,
+		MaxTime:  1000,
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "b"}},
+	}, srv))
+	testutil.Equals(t, 0, len(srv.SeriesSet))
+
+	namesResp, err := proxy.LabelNames(ctx, &storepb.LabelNamesRequest{Start: 0, End: 122})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string(nil), namesResp.Names)
+
+	valuesResp, err := proxy.LabelValues(ctx, &storepb.LabelValuesRequest{Label: "a", Start: 457, End: 1000})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string(nil), valuesResp.Values)
+}
+
 func testSeries_SplitSamplesIntoChunksWithMaxSizeOf120(t *testing.T, appender storage.Appender, newStore func() storepb.StoreServer) {
 	baseT := timestamp.FromTime(time.Now().AddDate(0, 0, -2)) / 1000 * 1000
 
@@ -51,6 +51,9 @@ type Client interface {
 	String() string
 	// Addr returns address of a Client.
 	Addr() string
+
+	// SupportsSeriesBatches returns true if the store can send the series of Series calls in batches.
+	SupportsSeriesBatches() bool
 }
 
 // ProxyStore implements the store API that proxies request to all given underlying stores.
@@ -61,9 +64,21 @@ type ProxyStore struct {
 	selectorLabels labels.Labels
 
 	responseTimeout time.Duration
+	seriesBatchSize int
 	metrics         *proxyStoreMetrics
 }
 
+// ProxyStoreOption overrides options of the ProxyStore.
+type ProxyStoreOption func(s *ProxyStore)
+
+// WithSeriesBatchSize sets the maximum number of series of the batches requested from the stores supporting them.
+// Batches are not requested if the size is 0, which is the default.
+func WithSeriesBatchSize(size int) ProxyStoreOption {
+	return func(s *ProxyStore) {
+		s.seriesBatchSize = size
+	}
+}
+
 type proxyStoreMetrics struct {
 	emptyStreamResponses prometheus.Counter
 }
@@ -79,9 +94,13 @@ func newProxyStoreMetrics(reg prometheus.Registerer) *proxyStoreMetrics {
 	return &m
 }
 
+// RegisterStoreServer returns the function registering the store server. The registered server sends the series in
+// batches to clients requesting them, so all components registering their server with it support batches of series.
+// The series the server receives from other stores, e.g. in the case of the ProxyStore, are decoded into buffers
+// pooled across requests.
 func RegisterStoreServer(storeSrv storepb.StoreServer) func(*grpc.Server) {
 	return func(s *grpc.Server) {
-		storepb.RegisterStoreServer(s, storeSrv)
+		storepb.RegisterStoreServer(s, &batchingStoreServer{StoreServer: &recyclingStoreServer{StoreServer: storeSrv}})
 	}
 }
 
@@ -94,6 +113,7 @@ func NewProxyStore(
 	component component.StoreAPI,
 	selectorLabels labels.Labels,
 	responseTimeout time.Duration,
+	options ...ProxyStoreOption,
 ) *ProxyStore {
 	if logger == nil {
 		logger = log.NewNopLogger()
@@ -108,6 +128,9 @@ func NewProxyStore(
 		responseTimeout: responseTimeout,
 		metrics:         metrics,
 	}
+	for _, option := range options {
+		option(s)
+	}
 	return s
 }
 
@@ -255,6 +278,8 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 	}
 	storeMatchers, _ := storepb.PromMatchersToMatchers(matchers...) // Error would be returned by matchesExternalLabels, so skip check.
 
+	// Series received from stores are only decoded into pooled buffers if they are provided for the request.
+	bufs := seriesBuffersFromContext(srv.Context())
 	g, gctx := errgroup.WithContext(srv.Context())
 
 	// Allow to buffer max 10 series response.
@@ -276,7 +301,13 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 				MaxResolutionWindow:     r.MaxResolutionWindow,
 				SkipChunks:              r.SkipChunks,
 				QueryHints:              r.QueryHints,
+				SelectHints:             r.SelectHints,
+				ShardInfo:               r.ShardInfo,
+				Step:                    r.Step,
+				Range:                   r.Range,
 				PartialResponseDisabled: r.PartialResponseDisabled,
+				Limit:                   r.Limit,
+				SplitAggregates:         r.SplitAggregates,
 			}
 			wg = &sync.WaitGroup{}
 		)
@@ -311,7 +342,13 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 				"store.addr": st.Addr(),
 			})
 
-			sc, err := st.Series(seriesCtx, r)
+			storeReq := r
+			if s.seriesBatchSize > 0 && st.SupportsSeriesBatches() {
+				batchReq := *r
+				batchReq.SeriesBatchSize = int64(s.seriesBatchSize)
+				storeReq = &batchReq
+			}
+			sc, err := st.Series(seriesCtx, storeReq)
 			if err != nil {
 				err = errors.Wrapf(err, "fetch series for %s %s", storeID, st)
 				span.SetTag("err", err.Error())
@@ -320,6 +357,10 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 					level.Error(reqLogger).Log("err", err, "msg", "partial response disabled; aborting request")
 					return err
 				}
+				if isResourceExhausted(err) {
+					level.Error(reqLogger).Log("err", err, "msg", "store limits exceeded; aborting request")
+					return err
+				}
 				respSender.send(storepb.NewWarnSeriesResponse(err))
 				continue
 			}
@@ -327,7 +368,7 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 			// Schedule streamSeriesSet that translates gRPC streamed response
 			// into seriesSet (if series) or respCh if warnings.
 			seriesSet = append(seriesSet, startStreamSeriesSet(seriesCtx, reqLogger, span, closeSeries,
-				wg, sc, respSender, st.String(), !r.PartialResponseDisabled, s.responseTimeout, s.metrics.emptyStreamResponses))
+				wg, sc, bufs, respSender, st.String(), st.Addr(), !r.PartialResponseDisabled, s.responseTimeout, s.metrics.emptyStreamResponses))
 		}
 
 		level.Debug(reqLogger).Log("msg", "Series: started fanout streams", "status", strings.Join(storeDebugMsgs, ";"))
@@ -345,8 +386,13 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 		// https://github.com/thanos-io/thanos/issues/2332
 		// Series are not necessarily merged across themselves.
 		mergedSet := storepb.MergeSeriesSets(seriesSet...)
+		// Stores which don't support sharding return the series of all shards.
+		shard := r.ShardInfo.Matcher()
 		for mergedSet.Next() {
 			lset, chk := mergedSet.At()
+			if !shard.MatchesLabels(lset) {
+				continue
+			}
 			respSender.send(storepb.NewSeriesResponse(&storepb.Series{Labels: labelpb.ZLabelsFromPromLabels(lset), Chunks: chk}))
 		}
 		return mergedSet.Err()
@@ -364,11 +410,21 @@ func (s *ProxyStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSe
 	if err := g.Wait(); err != nil {
 		// TODO(bwplotka): Replace with request logger.
 		level.Error(reqLogger).Log("err", err)
+		if isResourceExhausted(err) {
+			// Keep the code for queriers using this querier as store.
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
 		return err
 	}
 	return nil
 }
 
+// isResourceExhausted returns true if the error comes from a store rejecting the request for exceeding its limits.
+// Such requests fail even if partial responses are enabled, as their results would be arbitrarily incomplete.
+func isResourceExhausted(err error) bool {
+	return status.Code(errors.Cause(err)) == codes.ResourceExhausted
+}
+
 type directSender interface {
 	send(*storepb.SeriesResponse)
 }
@@ -417,8 +473,10 @@ func startStreamSeriesSet(
 	closeSeries context.CancelFunc,
 	wg *sync.WaitGroup,
 	stream storepb.Store_SeriesClient,
+	bufs *storepb.SeriesBuffers,
 	warnCh directSender,
 	name string,
+	addr string,
 	partialResponse bool,
 	responseTimeout time.Duration,
 	emptyStreamResponses prometheus.Counter,
@@ -439,8 +497,10 @@ func startStreamSeriesSet(
 	go func() {
 		seriesStats := &storepb.SeriesStatsCounter{}
 		bytesProcessed := 0
+		start := time.Now()
 
 		defer func() {
+			recordStoreStats(ctx, addr, seriesStats, bytesProcessed, start)
 			span.SetTag("processed.series", seriesStats.Series)
 			span.SetTag("processed.chunks", seriesStats.Chunks)
 			span.SetTag("processed.samples", seriesStats.Samples)
@@ -457,25 +517,39 @@ func startStreamSeriesSet(
 			}
 		}()
 
-		rCh := make(chan *recvResponse)
+		rCh := make(chan recvResponse)
 		done := make(chan struct{})
 		go func() {
+			recv := storepb.NewSeriesReceiver(s.stream, bufs)
+			defer recv.Close()
+
 			for {
-				r, err := s.stream.Recv()
+				r, err := recv.Recv()
 				select {
 				case <-done:
 					close(rCh)
 					return
-				case rCh <- &recvResponse{r: r, err: err}:
+				case rCh <- recvResponse{r: r, err: err}:
 				}
 			}
 		}()
+		send := func(series *storepb.Series) bool {
+			seriesStats.Count(series)
+
+			select {
+			case s.recvCh <- series:
+				return true
+			case <-ctx.Done():
+				s.handleErr(errors.Wrapf(ctx.Err(), "failed to receive any data from %s", s.name), done)
+				return false
+			}
+		}
 		// The `defer` only executed when function return, we do `defer cancel` in for loop,
 		// so make the loop body as a function, release timers created by context as early.
 		handleRecvResponse := func() (next bool) {
 			frameTimeoutCtx, cancel := frameCtx(s.responseTimeout)
 			defer cancel()
-			var rr *recvResponse
+			var rr recvResponse
 			select {
 			case <-ctx.Done():
 				s.handleErr(errors.Wrapf(ctx.Err(), "failed to receive any data from %s", s.name), done)
@@ -503,14 +577,20 @@ func startStreamSeriesSet(
 			}
 
 			if series := rr.r.GetSeries(); series != nil {
-				seriesStats.Count(series)
+				return send(series)
+			}
 
-				select {
-				case s.recvCh <- series:
-				case <-ctx.Done():
-					s.handleErr(errors.Wrapf(ctx.Err(), "failed to receive any data from %s", s.name), done)
+			if batch := rr.r.GetBatch(); batch != nil {
+				all, err := batch.AllSeries()
+				if err != nil {
+					s.handleErr(errors.Wrapf(err, "decode series batch from %s", s.name), done)
 					return false
 				}
+				for _, series := range all {
+					if !send(series) {
+						return false
+					}
+				}
 			}
 			return true
 		}
@@ -527,7 +607,7 @@ func (s *streamSeriesSet) handleErr(err error, done chan struct{}) {
 	defer close(done)
 	s.closeSeries()
 
-	if s.partialResponse {
+	if s.partialResponse && !isResourceExhausted(err) {
 		level.Warn(s.logger).Log("err", err, "msg", "returning partial response")
 		s.warnCh.send(storepb.NewWarnSeriesResponse(err))
 		return
@@ -650,6 +730,7 @@ func (s *ProxyStore) LabelNames(ctx context.Context, r *storepb.LabelNamesReques
 				Start:                   r.Start,
 				End:                     r.End,
 				Matchers:                r.Matchers,
+				Limit:                   r.Limit,
 			})
 			if err != nil {
 				err = errors.Wrapf(err, "fetch label names from store %s", st)
@@ -678,7 +759,7 @@ func (s *ProxyStore) LabelNames(ctx context.Context, r *storepb.LabelNamesReques
 
 	level.Debug(s.logger).Log("msg", strings.Join(storeDebugMsgs, ";"))
 	return &storepb.LabelNamesResponse{
-		Names:    strutil.MergeUnsortedSlices(names...),
+		Names:    truncateToLimit(strutil.MergeUnsortedSlices(names...), r.Limit),
 		Warnings: warnings,
 	}, nil
 }
@@ -712,6 +793,7 @@ func (s *ProxyStore) LabelValues(ctx context.Context, r *storepb.LabelValuesRequ
 				Start:                   r.Start,
 				End:                     r.End,
 				Matchers:                r.Matchers,
+				Limit:                   r.Limit,
 			})
 			if err != nil {
 				err = errors.Wrapf(err, "fetch label values from store %s", st)
@@ -740,7 +822,7 @@ func (s *ProxyStore) LabelValues(ctx context.Context, r *storepb.LabelValuesRequ
 
 	level.Debug(s.logger).Log("msg", strings.Join(storeDebugMsgs, ";"))
 	return &storepb.LabelValuesResponse{
-		Values:   strutil.MergeUnsortedSlices(all...),
+		Values:   truncateToLimit(strutil.MergeUnsortedSlices(all...), r.Limit),
 		Warnings: warnings,
 	}, nil
 }
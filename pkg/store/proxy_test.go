@@ -40,6 +40,8 @@ type testClient struct {
 	labelSets []labels.Labels
 	minTime   int64
 	maxTime   int64
+
+	supportsSeriesBatches bool
 }
 
 func (c testClient) LabelSets() []labels.Labels {
@@ -58,6 +60,10 @@ func (c testClient) Addr() string {
 	return "testaddr"
 }
 
+func (c testClient) SupportsSeriesBatches() bool {
+	return c.supportsSeriesBatches
+}
+
 func TestProxyStore_Info(t *testing.T) {
 	defer testutil.TolerantVerifyLeak(t)
 
@@ -418,6 +424,39 @@ func TestProxyStore_Series(t *testing.T) {
 			},
 			expectedErr: errors.New("fetch series for {ext=\"1\"} test: error!"),
 		},
+		{
+			title: "partial response enabled; store limits exceeded",
+			storeAPIs: []Client{
+				&testClient{
+					StoreClient: &mockedStoreAPI{
+						RespSeries: []*storepb.SeriesResponse{
+							storeSeriesResponse(t, labels.FromStrings("a", "b"), []sample{{1, 1}, {2, 2}, {3, 3}}),
+						},
+					},
+					labelSets: []labels.Labels{labels.FromStrings("ext", "1")},
+					minTime:   1,
+					maxTime:   300,
+				},
+				&testClient{
+					StoreClient: &mockedStoreAPI{
+						RespSeries: []*storepb.SeriesResponse{
+							storeSeriesResponse(t, labels.FromStrings("a", "c"), []sample{{1, 1}, {2, 2}, {3, 3}}),
+						},
+						injectedError:      status.Error(codes.ResourceExhausted, "exceeded chunk bytes limit"),
+						injectedErrorIndex: 1,
+					},
+					labelSets: []labels.Labels{labels.FromStrings("ext", "1")},
+					minTime:   1,
+					maxTime:   300,
+				},
+			},
+			req: &storepb.SeriesRequest{
+				MinTime:  1,
+				MaxTime:  300,
+				Matchers: []storepb.LabelMatcher{{Name: "ext", Value: "1", Type: storepb.LabelMatcher_EQ}},
+			},
+			expectedErr: status.Error(codes.ResourceExhausted, "test: receive series from test: rpc error: code = ResourceExhausted desc = exceeded chunk bytes limit"),
+		},
 		{
 			title: "storeAPI available for time range; available series for ext=1 external label matcher; allowed by store debug matcher",
 			storeAPIs: []Client{
@@ -1064,12 +1103,174 @@ func TestProxyStore_Series_RequestParamsProxied(t *testing.T) {
 			storepb.Aggr_COUNT,
 		},
 		MaxResolutionWindow: 1234,
+		Step:                60000,
+		Range:               300000,
+		SelectHints: &storepb.QueryHints{
+			StepMillis: 60000,
+			Func:       &storepb.Func{Name: "rate"},
+			Grouping:   &storepb.Grouping{By: true, Labels: []string{"job"}},
+			Range:      &storepb.Range{Millis: 300000},
+		},
+		SplitAggregates: true,
+		ShardInfo:       &storepb.ShardInfo{ShardIndex: 1, TotalShards: 2, Labels: []string{"job"}},
 	}
 	testutil.Ok(t, q.Series(req, s))
 
 	testutil.Assert(t, proto.Equal(req, m.LastSeriesReq), "request was not proxied properly to underlying storeAPI: %s vs %s", req, m.LastSeriesReq)
 }
 
+func TestProxyStore_Series_Shard(t *testing.T) {
+	defer testutil.TolerantVerifyLeak(t)
+
+	// The store doesn't support sharding and returns the series of all shards.
+	var resps []*storepb.SeriesResponse
+	for i := 0; i < 20; i++ {
+		resps = append(resps, storeSeriesResponse(t, labels.FromStrings("a", fmt.Sprintf("%02d", i)), []sample{{0, 0}}))
+	}
+	cls := []Client{
+		&testClient{
+			StoreClient: &mockedStoreAPI{RespSeries: resps},
+			minTime:     math.MinInt64,
+			maxTime:     math.MaxInt64,
+		},
+	}
+	q := NewProxyStore(nil,
+		nil,
+		func() []Client { return cls },
+		component.Query,
+		nil,
+		0*time.Second,
+	)
+
+	seen := map[string]int{}
+	for i := int64(0); i < 3; i++ {
+		shard := &storepb.ShardInfo{ShardIndex: i, TotalShards: 3, Labels: []string{"a"}}
+		s := newStoreSeriesServer(context.Background())
+		testutil.Ok(t, q.Series(&storepb.SeriesRequest{
+			MinTime:   math.MinInt64,
+			MaxTime:   math.MaxInt64,
+			Matchers:  []storepb.LabelMatcher{{Name: "a", Value: ".+", Type: storepb.LabelMatcher_RE}},
+			ShardInfo: shard,
+		}, s))
+		testutil.Assert(t, len(s.SeriesSet) < 20, "shard %d returned all series", i)
+
+		for _, series := range s.SeriesSet {
+			testutil.Assert(t, shard.Matcher().MatchesZLabels(series.Labels), "series %v is not in shard %d", series.Labels, i)
+			seen[labelpb.ZLabelsToPromLabels(series.Labels).String()]++
+		}
+	}
+	testutil.Equals(t, 20, len(seen))
+	for lset, n := range seen {
+		testutil.Equals(t, 1, n, "series %s", lset)
+	}
+}
+
+func TestProxyStore_Series_Batches(t *testing.T) {
+	defer testutil.TolerantVerifyLeak(t)
+
+	b := storepb.NewSeriesBatchBuilder()
+	b.Add(storeSeriesResponse(t, labels.FromStrings("a", "a"), []sample{{0, 0}, {2, 1}}).GetSeries())
+	b.Add(storeSeriesResponse(t, labels.FromStrings("a", "b"), []sample{{1, 1}}).GetSeries())
+	batching := &mockedStoreAPI{
+		RespSeries: []*storepb.SeriesResponse{
+			storepb.NewSeriesBatchResponse(b.Build()),
+			storepb.NewWarnSeriesResponse(errors.New("warning")),
+		},
+	}
+	nonBatching := &mockedStoreAPI{
+		RespSeries: []*storepb.SeriesResponse{
+			storeSeriesResponse(t, labels.FromStrings("a", "c"), []sample{{3, 3}}),
+		},
+	}
+	cls := []Client{
+		&testClient{
+			StoreClient:           batching,
+			minTime:               1,
+			maxTime:               300,
+			supportsSeriesBatches: true,
+		},
+		&testClient{
+			StoreClient: nonBatching,
+			minTime:     1,
+			maxTime:     300,
+		},
+	}
+	q := NewProxyStore(nil,
+		nil,
+		func() []Client { return cls },
+		component.Query,
+		nil,
+		0*time.Second,
+		WithSeriesBatchSize(10),
+	)
+
+	s := newStoreSeriesServer(context.Background())
+	testutil.Ok(t, q.Series(&storepb.SeriesRequest{
+		MinTime:  1,
+		MaxTime:  300,
+		Matchers: []storepb.LabelMatcher{{Name: "a", Value: ".*", Type: storepb.LabelMatcher_RE}},
+	}, s))
+
+	// Batches are only requested from stores supporting them, and their series are sent one by one.
+	testutil.Equals(t, int64(10), batching.LastSeriesReq.SeriesBatchSize)
+	testutil.Equals(t, int64(0), nonBatching.LastSeriesReq.SeriesBatchSize)
+	seriesEquals(t, []rawSeries{
+		{lset: labels.FromStrings("a", "a"), chunks: [][]sample{{{0, 0}, {2, 1}}}},
+		{lset: labels.FromStrings("a", "b"), chunks: [][]sample{{{1, 1}}}},
+		{lset: labels.FromStrings("a", "c"), chunks: [][]sample{{{3, 3}}}},
+	}, s.SeriesSet)
+	testutil.Equals(t, []string{"warning"}, s.Warnings)
+}
+
+func TestProxyStore_Series_QueryStats(t *testing.T) {
+	defer testutil.TolerantVerifyLeak(t)
+
+	m := &mockedStoreAPI{
+		RespSeries: []*storepb.SeriesResponse{
+			storeSeriesResponse(t, labels.FromStrings("a", "a"), []sample{{0, 0}, {2, 1}, {3, 2}}),
+			storeSeriesResponse(t, labels.FromStrings("a", "b"), []sample{{2, 2}, {3, 3}}, []sample{{4, 4}}),
+		},
+	}
+	cls := []Client{
+		&testClient{
+			StoreClient: m,
+			labelSets:   []labels.Labels{labels.FromStrings("ext", "1")},
+			minTime:     1,
+			maxTime:     300,
+		},
+	}
+	q := NewProxyStore(nil,
+		nil,
+		func() []Client { return cls },
+		component.Query,
+		nil,
+		0*time.Second,
+	)
+	req := &storepb.SeriesRequest{
+		MinTime:  1,
+		MaxTime:  300,
+		Matchers: []storepb.LabelMatcher{{Name: "ext", Value: "1", Type: storepb.LabelMatcher_EQ}},
+	}
+
+	// Statistics are not recorded if not requested.
+	testutil.Ok(t, q.Series(req, newStoreSeriesServer(context.Background())))
+
+	qs := NewQueryStats()
+	ctx := context.WithValue(context.Background(), QueryStatsKey, qs)
+	testutil.Ok(t, q.Series(req, newStoreSeriesServer(ctx)))
+	testutil.Ok(t, q.Series(req, newStoreSeriesServer(ctx)))
+
+	stores := qs.Stores()
+	testutil.Equals(t, 1, len(stores))
+	st := stores["testaddr"]
+	testutil.Equals(t, 2, st.Requests)
+	testutil.Equals(t, 4, st.Series)
+	testutil.Equals(t, 6, st.Chunks)
+	testutil.Equals(t, 12, st.Samples)
+	testutil.Assert(t, st.Bytes > 0, "bytes must be recorded")
+	testutil.Equals(t, st, qs.Total())
+}
+
 func TestProxyStore_Series_RegressionFillResponseChannel(t *testing.T) {
 	defer testutil.TolerantVerifyLeak(t)
 
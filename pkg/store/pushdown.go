@@ -0,0 +1,209 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// rangeFuncPushdown evaluates a range vector function over the chunks of series at the steps of a query, so that
+// stores return a single sample per step instead of all the chunks. It is only used for functions which give the
+// same result when the querier evaluates them again over the returned samples.
+type rangeFuncPushdown struct {
+	fn         string
+	aggrs      []storepb.Aggr
+	rng, step  int64
+	mint, maxt int64
+}
+
+// newRangeFuncPushdown returns the pushdown of the function of the query hints of the given request, or nil if it
+// cannot be pushed down. The function must be min_over_time, max_over_time or last_over_time, and the step of range
+// queries has to be larger than the range, so that the ranges of consecutive steps, which are closed, don't overlap.
+// It must be called before the time range of the request is limited, as the steps are aligned on its start.
+func newRangeFuncPushdown(req *storepb.SeriesRequest) *rangeFuncPushdown {
+	h := req.QueryHints
+	if h == nil || h.Func == nil || h.Range == nil || h.Range.Millis <= 0 || req.SkipChunks {
+		return nil
+	}
+	switch h.Func.Name {
+	case "min_over_time", "max_over_time", "last_over_time":
+	default:
+		return nil
+	}
+
+	p := &rangeFuncPushdown{
+		fn:    h.Func.Name,
+		aggrs: req.Aggregates,
+		rng:   h.Range.Millis,
+		step:  h.StepMillis,
+		mint:  req.MinTime,
+		maxt:  req.MaxTime,
+	}
+	// Instant queries are evaluated once, at the end of the time range. Time ranges not matching the steps of the
+	// query, e.g. of subqueries, can't be pushed down.
+	if p.step == 0 {
+		if p.maxt-p.mint != p.rng {
+			return nil
+		}
+		return p
+	}
+	if p.step <= p.rng || (p.maxt-p.mint-p.rng)%p.step != 0 {
+		return nil
+	}
+	return p
+}
+
+// reduce returns the chunks of the samples of the function evaluated at each step over the given chunks of a series.
+// Steps without samples in their range are skipped.
+func (p *rangeFuncPushdown) reduce(chks []storepb.AggrChunk) ([]storepb.AggrChunk, error) {
+	samples, err := p.samples(chks)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		reduced []pushdownSample
+		i       int
+	)
+	for t := p.mint + p.rng; t <= p.maxt; t += p.step {
+		for i < len(samples) && samples[i].t < t-p.rng {
+			i++
+		}
+		v, found := 0.0, false
+		for ; i < len(samples) && samples[i].t <= t; i++ {
+			switch {
+			case !found:
+				v = samples[i].v
+			case p.fn == "min_over_time":
+				v = math.Min(v, samples[i].v)
+			case p.fn == "max_over_time":
+				v = math.Max(v, samples[i].v)
+			default:
+				v = samples[i].v
+			}
+			found = true
+		}
+		if found {
+			reduced = append(reduced, pushdownSample{t: t, v: v})
+		}
+		if p.step == 0 {
+			break
+		}
+	}
+	return encodeXORChunks(reduced)
+}
+
+func encodeXORChunks(samples []pushdownSample) ([]storepb.AggrChunk, error) {
+	var res []storepb.AggrChunk
+	for len(samples) > 0 {
+		n := MaxSamplesPerChunk
+		if n > len(samples) {
+			n = len(samples)
+		}
+		c := chunkenc.NewXORChunk()
+		app, err := c.Appender()
+		if err != nil {
+			return nil, errors.Wrap(err, "create appender")
+		}
+		for _, s := range samples[:n] {
+			app.Append(s.t, s.v)
+		}
+		res = append(res, storepb.AggrChunk{
+			MinTime: samples[0].t,
+			MaxTime: samples[n-1].t,
+			Raw:     &storepb.Chunk{Type: storepb.Chunk_XOR, Data: c.Bytes()},
+		})
+		samples = samples[n:]
+	}
+	return res, nil
+}
+
+type pushdownSample struct {
+	t int64
+	v float64
+}
+
+// samples returns the sorted samples of the given chunks, within the time range of the query. Like the querier does,
+// the samples of downsampled chunks are read from the aggregate of the request, or computed as the average of the
+// count and sum aggregates.
+func (p *rangeFuncPushdown) samples(chks []storepb.AggrChunk) ([]pushdownSample, error) {
+	var samples []pushdownSample
+	for _, c := range chks {
+		it, err := p.iterator(c)
+		if err != nil {
+			return nil, err
+		}
+		for it.Next() {
+			t, v := it.At()
+			if t < p.mint || t > p.maxt {
+				continue
+			}
+			samples = append(samples, pushdownSample{t: t, v: v})
+		}
+		if it.Err() != nil {
+			return nil, errors.Wrap(it.Err(), "iterate chunk")
+		}
+	}
+
+	// Chunks of overlapping blocks may overlap, keep the first sample of each timestamp.
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].t < samples[j].t })
+	res := samples[:0]
+	for _, s := range samples {
+		if len(res) > 0 && s.t == res[len(res)-1].t {
+			continue
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func (p *rangeFuncPushdown) iterator(c storepb.AggrChunk) (chunkenc.Iterator, error) {
+	if c.Raw != nil {
+		return xorIterator(c.Raw)
+	}
+
+	var agg *storepb.Chunk
+	switch {
+	case len(p.aggrs) == 1 && p.aggrs[0] == storepb.Aggr_MIN:
+		agg = c.Min
+	case len(p.aggrs) == 1 && p.aggrs[0] == storepb.Aggr_MAX:
+		agg = c.Max
+	case len(p.aggrs) == 1 && p.aggrs[0] == storepb.Aggr_SUM:
+		agg = c.Sum
+	case len(p.aggrs) == 1 && p.aggrs[0] == storepb.Aggr_COUNT:
+		agg = c.Count
+	case len(p.aggrs) == 2 && c.Count != nil && c.Sum != nil:
+		cnt, err := xorIterator(c.Count)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := xorIterator(c.Sum)
+		if err != nil {
+			return nil, err
+		}
+		return downsample.NewAverageChunkIterator(cnt, sum), nil
+	}
+	if agg == nil {
+		return nil, errors.Errorf("no chunk of aggregates %v to push down %s", p.aggrs, p.fn)
+	}
+	return xorIterator(agg)
+}
+
+func xorIterator(c *storepb.Chunk) (chunkenc.Iterator, error) {
+	if c.Type != storepb.Chunk_XOR {
+		return nil, errors.Errorf("unsupported chunk encoding %d", c.Type)
+	}
+	chk, err := chunkenc.FromData(chunkenc.EncXOR, c.Data)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode chunk")
+	}
+	return chk.Iterator(nil), nil
+}
@@ -0,0 +1,67 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestRangeFuncPushdown(t *testing.T) {
+	hints := func(fn string, rng, step int64) *storepb.QueryHints {
+		return &storepb.QueryHints{StepMillis: step, Func: &storepb.Func{Name: fn}, Range: &storepb.Range{Millis: rng}}
+	}
+	for _, tcase := range []struct {
+		name string
+		req  *storepb.SeriesRequest
+		ok   bool
+	}{
+		{name: "no hints", req: &storepb.SeriesRequest{MinTime: 0, MaxTime: 100}},
+		{name: "not pushable function", req: &storepb.SeriesRequest{MinTime: 0, MaxTime: 100, QueryHints: hints("rate", 10, 30)}},
+		{name: "range query", req: &storepb.SeriesRequest{MinTime: 0, MaxTime: 100, QueryHints: hints("max_over_time", 10, 30)}, ok: true},
+		{name: "overlapping ranges", req: &storepb.SeriesRequest{MinTime: 0, MaxTime: 100, QueryHints: hints("max_over_time", 30, 30)}},
+		{name: "unaligned steps", req: &storepb.SeriesRequest{MinTime: 0, MaxTime: 101, QueryHints: hints("max_over_time", 10, 30)}},
+		{name: "instant query", req: &storepb.SeriesRequest{MinTime: 90, MaxTime: 100, QueryHints: hints("last_over_time", 10, 0)}, ok: true},
+		{name: "instant subquery", req: &storepb.SeriesRequest{MinTime: 0, MaxTime: 100, QueryHints: hints("last_over_time", 10, 0)}},
+		{name: "skip chunks", req: &storepb.SeriesRequest{MinTime: 0, MaxTime: 100, QueryHints: hints("max_over_time", 10, 30), SkipChunks: true}},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			testutil.Equals(t, tcase.ok, newRangeFuncPushdown(tcase.req) != nil)
+		})
+	}
+
+	samples := []pushdownSample{{t: 5, v: 1}, {t: 10, v: 4}, {t: 35, v: 3}, {t: 40, v: 2}, {t: 65, v: 7}, {t: 90, v: 6}}
+	raw, err := encodeXORChunks(samples[:3])
+	testutil.Ok(t, err)
+	aggr, err := encodeXORChunks(samples[3:])
+	testutil.Ok(t, err)
+	chks := []storepb.AggrChunk{raw[0], {MinTime: 40, MaxTime: 90, Max: aggr[0].Raw, Min: aggr[0].Raw}}
+
+	for fn, exp := range map[string][]pushdownSample{
+		// Steps are evaluated at 10, 40, 70 and 100, over the closed ranges ending at them.
+		"max_over_time":  {{t: 10, v: 4}, {t: 40, v: 3}, {t: 70, v: 7}, {t: 100, v: 6}},
+		"min_over_time":  {{t: 10, v: 1}, {t: 40, v: 2}, {t: 70, v: 7}, {t: 100, v: 6}},
+		"last_over_time": {{t: 10, v: 4}, {t: 40, v: 2}, {t: 70, v: 7}, {t: 100, v: 6}},
+	} {
+		t.Run(fn, func(t *testing.T) {
+			aggrs := []storepb.Aggr{storepb.Aggr_MAX}
+			if fn == "min_over_time" {
+				aggrs = []storepb.Aggr{storepb.Aggr_MIN}
+			}
+			p := newRangeFuncPushdown(&storepb.SeriesRequest{MinTime: 0, MaxTime: 100, Aggregates: aggrs, QueryHints: hints(fn, 10, 30)})
+			res, err := p.reduce(chks)
+			testutil.Ok(t, err)
+			testutil.Equals(t, 1, len(res))
+			testutil.Equals(t, int64(10), res[0].MinTime)
+			testutil.Equals(t, int64(100), res[0].MaxTime)
+
+			p = &rangeFuncPushdown{mint: 0, maxt: 100}
+			got, err := p.samples(res)
+			testutil.Ok(t, err)
+			testutil.Equals(t, exp, got)
+		})
+	}
+}
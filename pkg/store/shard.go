@@ -0,0 +1,31 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// shardSeriesServer only sends the series of a shard, for stores which can't select them before fetching them.
+type shardSeriesServer struct {
+	storepb.Store_SeriesServer
+
+	matcher *storepb.ShardMatcher
+}
+
+// newShardSeriesServer returns a server only sending the series of the shard, or srv if the shard is nil.
+func newShardSeriesServer(srv storepb.Store_SeriesServer, shard *storepb.ShardInfo) storepb.Store_SeriesServer {
+	matcher := shard.Matcher()
+	if matcher == nil {
+		return srv
+	}
+	return &shardSeriesServer{Store_SeriesServer: srv, matcher: matcher}
+}
+
+func (s *shardSeriesServer) Send(r *storepb.SeriesResponse) error {
+	if series := r.GetSeries(); series != nil && !s.matcher.MatchesZLabels(series.Labels) {
+		return nil
+	}
+	return s.Store_SeriesServer.Send(r)
+}
@@ -0,0 +1,103 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// QueryStatsKey is the context key for the QueryStats which the proxy store records the fan-out of its Series calls to.
+const QueryStatsKey = ctxKey(1)
+
+// StoreStats are the statistics of the Series calls to a single store.
+type StoreStats struct {
+	Requests int `json:"requests"`
+	Series   int `json:"series"`
+	Chunks   int `json:"chunks"`
+	Samples  int `json:"samples"`
+	Bytes    int `json:"bytes"`
+	// DurationSeconds is the summed up time from the start of the Series calls until the streams were closed.
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+func (s *StoreStats) merge(o StoreStats) {
+	s.Requests += o.Requests
+	s.Series += o.Series
+	s.Chunks += o.Chunks
+	s.Samples += o.Samples
+	s.Bytes += o.Bytes
+	s.DurationSeconds += o.DurationSeconds
+}
+
+// QueryStats collects the statistics of the Series calls of the proxy store, per store address, across all selects
+// of a query. It's safe for concurrent use.
+type QueryStats struct {
+	mtx    sync.Mutex
+	stores map[string]StoreStats
+}
+
+// NewQueryStats returns empty QueryStats.
+func NewQueryStats() *QueryStats {
+	return &QueryStats{stores: map[string]StoreStats{}}
+}
+
+func (s *QueryStats) add(addr string, st StoreStats) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	curr := s.stores[addr]
+	curr.merge(st)
+	s.stores[addr] = curr
+}
+
+// Stores returns a copy of the statistics per store address.
+func (s *QueryStats) Stores() map[string]StoreStats {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	stores := make(map[string]StoreStats, len(s.stores))
+	for addr, st := range s.stores {
+		stores[addr] = st
+	}
+	return stores
+}
+
+// Total returns the statistics summed up over all stores.
+func (s *QueryStats) Total() StoreStats {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var total StoreStats
+	for _, st := range s.stores {
+		total.merge(st)
+	}
+	return total
+}
+
+// queryStatsFromContext returns the QueryStats of the context, or nil if the statistics are not requested.
+func queryStatsFromContext(ctx context.Context) *QueryStats {
+	qs, _ := ctx.Value(QueryStatsKey).(*QueryStats)
+	return qs
+}
+
+// recordStoreStats records the statistics of a finished Series stream of the store with the given address, if the
+// statistics are requested in the context.
+func recordStoreStats(ctx context.Context, addr string, seriesStats *storepb.SeriesStatsCounter, bytes int, start time.Time) {
+	qs := queryStatsFromContext(ctx)
+	if qs == nil {
+		return
+	}
+	qs.add(addr, StoreStats{
+		Requests:        1,
+		Series:          seriesStats.Series,
+		Chunks:          seriesStats.Chunks,
+		Samples:         seriesStats.Samples,
+		Bytes:           bytes,
+		DurationSeconds: time.Since(start).Seconds(),
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package storepb
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+)
+
+// NewSeriesBatchResponse returns a response with the given batch of series.
+func NewSeriesBatchResponse(batch *SeriesBatch) *SeriesResponse {
+	return &SeriesResponse{
+		Result: &SeriesResponse_Batch{
+			Batch: batch,
+		},
+	}
+}
+
+// SeriesBatchBuilder builds batches of series, interning the label names and values of their series. The series
+// added to batches are copied, so that their memory can be reused once they are added.
+type SeriesBatchBuilder struct {
+	batch   *SeriesBatch
+	symbols map[string]uint32
+	// buf holds the data of the chunks of the batch, so that they are allocated together.
+	buf []byte
+}
+
+// NewSeriesBatchBuilder returns a new builder of batches of series.
+func NewSeriesBatchBuilder() *SeriesBatchBuilder {
+	return &SeriesBatchBuilder{
+		batch:   &SeriesBatch{},
+		symbols: map[string]uint32{},
+	}
+}
+
+// Add adds the series to the batch.
+func (b *SeriesBatchBuilder) Add(s *Series) {
+	bs := BatchedSeries{
+		Labels: make([]uint32, 0, 2*len(s.Labels)),
+		Chunks: make([]AggrChunk, 0, len(s.Chunks)),
+	}
+	for _, l := range s.Labels {
+		bs.Labels = append(bs.Labels, b.symbol(l.Name), b.symbol(l.Value))
+	}
+	for _, c := range s.Chunks {
+		bs.Chunks = append(bs.Chunks, AggrChunk{
+			MinTime: c.MinTime,
+			MaxTime: c.MaxTime,
+			Raw:     b.chunk(c.Raw),
+			Count:   b.chunk(c.Count),
+			Sum:     b.chunk(c.Sum),
+			Min:     b.chunk(c.Min),
+			Max:     b.chunk(c.Max),
+			Counter: b.chunk(c.Counter),
+		})
+	}
+	b.batch.Series = append(b.batch.Series, bs)
+}
+
+func (b *SeriesBatchBuilder) symbol(s string) uint32 {
+	if ref, ok := b.symbols[s]; ok {
+		return ref
+	}
+	// The string is copied, as it might reference memory which is reused, e.g. of a pooled buffer.
+	s = string(append([]byte(nil), s...))
+	ref := uint32(len(b.batch.Symbols))
+	b.batch.Symbols = append(b.batch.Symbols, s)
+	b.symbols[s] = ref
+	return ref
+}
+
+func (b *SeriesBatchBuilder) chunk(c *Chunk) *Chunk {
+	if c == nil {
+		return nil
+	}
+	n := len(b.buf)
+	b.buf = append(b.buf, c.Data...)
+	return &Chunk{Type: c.Type, Data: b.buf[n:len(b.buf):len(b.buf)]}
+}
+
+// Len returns the number of series of the batch.
+func (b *SeriesBatchBuilder) Len() int {
+	return len(b.batch.Series)
+}
+
+// ChunksSize returns the size of the data of the chunks of the batch.
+func (b *SeriesBatchBuilder) ChunksSize() int {
+	return len(b.buf)
+}
+
+// Build returns the batch and resets the builder.
+func (b *SeriesBatchBuilder) Build() *SeriesBatch {
+	batch := b.batch
+	b.batch = &SeriesBatch{}
+	b.symbols = map[string]uint32{}
+	b.buf = nil
+	return batch
+}
+
+// AllSeries returns the series of the batch, with their labels resolved from the symbols of the batch.
+func (m *SeriesBatch) AllSeries() ([]*Series, error) {
+	all := make([]*Series, 0, len(m.Series))
+	for i, bs := range m.Series {
+		if len(bs.Labels)%2 != 0 {
+			return nil, errors.Errorf("series %d of batch has an odd number of label references", i)
+		}
+		s := &Series{
+			Labels: make([]labelpb.ZLabel, 0, len(bs.Labels)/2),
+			Chunks: bs.Chunks,
+		}
+		for j := 0; j < len(bs.Labels); j += 2 {
+			name, value := bs.Labels[j], bs.Labels[j+1]
+			if int(name) >= len(m.Symbols) || int(value) >= len(m.Symbols) {
+				return nil, errors.Errorf("series %d of batch references unknown symbol", i)
+			}
+			s.Labels = append(s.Labels, labelpb.ZLabel{Name: m.Symbols[name], Value: m.Symbols[value]})
+		}
+		all = append(all, s)
+	}
+	return all, nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package storepb
+
+import (
+	"testing"
+
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestSeriesBatchBuilder(t *testing.T) {
+	series := []*Series{
+		{
+			Labels: []labelpb.ZLabel{{Name: "__name__", Value: "up"}, {Name: "job", Value: "a"}},
+			Chunks: []AggrChunk{{MinTime: 1, MaxTime: 2, Raw: &Chunk{Type: Chunk_XOR, Data: []byte{1, 2, 3}}}},
+		},
+		{
+			Labels: []labelpb.ZLabel{{Name: "__name__", Value: "up"}, {Name: "job", Value: "b"}},
+			Chunks: []AggrChunk{
+				{MinTime: 1, MaxTime: 2, Count: &Chunk{Type: Chunk_XOR, Data: []byte{4}}, Sum: &Chunk{Type: Chunk_XOR, Data: []byte{5, 6}}},
+				{MinTime: 3, MaxTime: 4, Raw: &Chunk{Type: Chunk_XOR, Data: []byte{7}}},
+			},
+		},
+		{
+			Labels: []labelpb.ZLabel{{Name: "job", Value: "a"}},
+		},
+	}
+
+	b := NewSeriesBatchBuilder()
+	for _, s := range series {
+		b.Add(s)
+	}
+	testutil.Equals(t, 3, b.Len())
+	testutil.Equals(t, 7, b.ChunksSize())
+
+	// The chunks are copied.
+	series[0].Chunks[0].Raw.Data[0] = 0
+	expected := *series[0].Chunks[0].Raw
+	expected.Data = []byte{1, 2, 3}
+
+	batch := b.Build()
+	testutil.Equals(t, 0, b.Len())
+	testutil.Equals(t, []string{"__name__", "up", "job", "a", "b"}, batch.Symbols)
+
+	// The batch is smaller than the series sent one by one.
+	var size int
+	for _, s := range series {
+		size += NewSeriesResponse(s).Size()
+	}
+	testutil.Assert(t, NewSeriesBatchResponse(batch).Size() < size, "batch is larger than series")
+
+	data, err := NewSeriesBatchResponse(batch).Marshal()
+	testutil.Ok(t, err)
+	var resp SeriesResponse
+	testutil.Ok(t, resp.Unmarshal(data))
+
+	got, err := resp.GetBatch().AllSeries()
+	testutil.Ok(t, err)
+	testutil.Equals(t, len(series), len(got))
+	for i := range series {
+		testutil.Equals(t, series[i].Labels, got[i].Labels)
+		testutil.Equals(t, len(series[i].Chunks), len(got[i].Chunks))
+	}
+	testutil.Equals(t, expected, *got[0].Chunks[0].Raw)
+	testutil.Equals(t, series[1].Chunks, got[1].Chunks)
+
+	_, err = (&SeriesBatch{Symbols: []string{"a"}, Series: []BatchedSeries{{Labels: []uint32{0}}}}).AllSeries()
+	testutil.NotOk(t, err)
+	_, err = (&SeriesBatch{Symbols: []string{"a"}, Series: []BatchedSeries{{Labels: []uint32{0, 1}}}}).AllSeries()
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,376 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package storepb
+
+import (
+	"io"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+)
+
+const (
+	slabSeries     = 128
+	slabLabels     = 1024
+	slabAggrChunks = 512
+	slabChunks     = 512
+)
+
+// seriesSlab holds the memory of decoded series.
+type seriesSlab struct {
+	series     []Series
+	labels     []labelpb.ZLabel
+	aggrChunks []AggrChunk
+	chunks     []Chunk
+}
+
+var seriesSlabPool = sync.Pool{New: func() interface{} {
+	return &seriesSlab{
+		series:     make([]Series, 0, slabSeries),
+		labels:     make([]labelpb.ZLabel, 0, slabLabels),
+		aggrChunks: make([]AggrChunk, 0, slabAggrChunks),
+		chunks:     make([]Chunk, 0, slabChunks),
+	}
+}}
+
+// reset clears the slab, so that it does not reference the received messages anymore.
+func (s *seriesSlab) reset() {
+	for i := range s.series {
+		s.series[i] = Series{}
+	}
+	for i := range s.labels {
+		s.labels[i] = labelpb.ZLabel{}
+	}
+	for i := range s.aggrChunks {
+		s.aggrChunks[i] = AggrChunk{}
+	}
+	for i := range s.chunks {
+		s.chunks[i] = Chunk{}
+	}
+	s.series, s.labels, s.aggrChunks, s.chunks = s.series[:0], s.labels[:0], s.aggrChunks[:0], s.chunks[:0]
+}
+
+// SeriesBuffers holds the memory of the series received by SeriesReceivers, which is pooled across requests.
+type SeriesBuffers struct {
+	mtx      sync.Mutex
+	slabs    []*seriesSlab
+	released bool
+}
+
+// NewSeriesBuffers returns new buffers of series.
+func NewSeriesBuffers() *SeriesBuffers {
+	return &SeriesBuffers{}
+}
+
+// Release recycles the memory of the buffers. The series received with them must not be used anymore. The memory of
+// receivers which are not closed yet is recycled once they are.
+func (b *SeriesBuffers) Release() {
+	b.mtx.Lock()
+	slabs := b.slabs
+	b.slabs = nil
+	b.released = true
+	b.mtx.Unlock()
+
+	recycleSlabs(slabs)
+}
+
+func (b *SeriesBuffers) put(slabs []*seriesSlab) {
+	b.mtx.Lock()
+	if !b.released {
+		b.slabs = append(b.slabs, slabs...)
+		b.mtx.Unlock()
+		return
+	}
+	b.mtx.Unlock()
+
+	recycleSlabs(slabs)
+}
+
+func recycleSlabs(slabs []*seriesSlab) {
+	for _, s := range slabs {
+		s.reset()
+		seriesSlabPool.Put(s)
+	}
+}
+
+// SeriesReceiver receives the responses of a Series stream. The series of gRPC streams are decoded into the memory of
+// its buffers and reference the data of the received messages, instead of being allocated and copied one by one.
+type SeriesReceiver struct {
+	stream Store_SeriesClient
+	bufs   *SeriesBuffers
+
+	slab  *seriesSlab
+	slabs []*seriesSlab
+}
+
+// NewSeriesReceiver returns a receiver of the responses of the stream. If bufs is nil, responses are received as with
+// the Recv method of the stream.
+func NewSeriesReceiver(stream Store_SeriesClient, bufs *SeriesBuffers) *SeriesReceiver {
+	return &SeriesReceiver{stream: stream, bufs: bufs}
+}
+
+// Recv receives the next response of the stream.
+func (r *SeriesReceiver) Recv() (*SeriesResponse, error) {
+	c, ok := r.stream.(*storeSeriesClient)
+	if !ok || r.bufs == nil {
+		return r.stream.Recv()
+	}
+	f := &seriesFrame{r: r}
+	if err := c.ClientStream.RecvMsg(f); err != nil {
+		return nil, err
+	}
+	return f.resp, nil
+}
+
+// Close hands the memory of the receiver back to its buffers. It must be called once no more responses are received.
+func (r *SeriesReceiver) Close() {
+	if r.bufs == nil {
+		return
+	}
+	r.bufs.put(r.slabs)
+	r.slab, r.slabs = nil, nil
+}
+
+func (r *SeriesReceiver) nextSlab() *seriesSlab {
+	r.slab = seriesSlabPool.Get().(*seriesSlab)
+	r.slabs = append(r.slabs, r.slab)
+	return r.slab
+}
+
+func (r *SeriesReceiver) series() *Series {
+	if r.slab == nil || len(r.slab.series) == cap(r.slab.series) {
+		r.nextSlab()
+	}
+	r.slab.series = r.slab.series[:len(r.slab.series)+1]
+	return &r.slab.series[len(r.slab.series)-1]
+}
+
+func (r *SeriesReceiver) labels(n int) []labelpb.ZLabel {
+	if n == 0 {
+		return nil
+	}
+	if n > slabLabels {
+		return make([]labelpb.ZLabel, n)
+	}
+	if r.slab == nil || len(r.slab.labels)+n > cap(r.slab.labels) {
+		r.nextSlab()
+	}
+	i := len(r.slab.labels)
+	r.slab.labels = r.slab.labels[:i+n]
+	// The capacity is limited, so that appending to the labels never overwrites the ones of other series.
+	return r.slab.labels[i : i+n : i+n]
+}
+
+func (r *SeriesReceiver) aggrChunks(n int) []AggrChunk {
+	if n == 0 {
+		return nil
+	}
+	if n > slabAggrChunks {
+		return make([]AggrChunk, n)
+	}
+	if r.slab == nil || len(r.slab.aggrChunks)+n > cap(r.slab.aggrChunks) {
+		r.nextSlab()
+	}
+	i := len(r.slab.aggrChunks)
+	r.slab.aggrChunks = r.slab.aggrChunks[:i+n]
+	return r.slab.aggrChunks[i : i+n : i+n]
+}
+
+func (r *SeriesReceiver) chunk() *Chunk {
+	if r.slab == nil || len(r.slab.chunks) == cap(r.slab.chunks) {
+		r.nextSlab()
+	}
+	r.slab.chunks = r.slab.chunks[:len(r.slab.chunks)+1]
+	return &r.slab.chunks[len(r.slab.chunks)-1]
+}
+
+// seriesFrame is the message Series responses are received in by receivers. Responses with a series are decoded with
+// the memory of the receiver, others as usual.
+type seriesFrame struct {
+	r    *SeriesReceiver
+	resp *SeriesResponse
+}
+
+func (m *seriesFrame) Reset() { m.resp = nil }
+
+func (m *seriesFrame) String() string {
+	if m.resp == nil {
+		return ""
+	}
+	return m.resp.String()
+}
+
+func (*seriesFrame) ProtoMessage() {}
+
+func (m *seriesFrame) Unmarshal(data []byte) error {
+	num, wireType, value, rest, err := nextField(data)
+	if err != nil || num != 1 || wireType != 2 || len(rest) != 0 {
+		m.resp = &SeriesResponse{}
+		return m.resp.Unmarshal(data)
+	}
+
+	s, err := m.r.decodeSeries(value)
+	if err != nil {
+		return err
+	}
+	m.resp = NewSeriesResponse(s)
+	return nil
+}
+
+func (r *SeriesReceiver) decodeSeries(data []byte) (*Series, error) {
+	var numLabels, numChunks int
+	for b := data; len(b) > 0; {
+		num, wireType, _, rest, err := nextField(b)
+		if err != nil {
+			return nil, err
+		}
+		if (num == 1 || num == 2) && wireType != 2 {
+			return nil, errors.Errorf("proto: wrong wireType = %d for field %d of Series", wireType, num)
+		}
+		switch num {
+		case 1:
+			numLabels++
+		case 2:
+			numChunks++
+		}
+		b = rest
+	}
+
+	s := r.series()
+	s.Labels = r.labels(numLabels)
+	s.Chunks = r.aggrChunks(numChunks)
+	var l, c int
+	for b := data; len(b) > 0; {
+		// The fields were validated above.
+		num, _, value, rest, _ := nextField(b)
+		b = rest
+		switch num {
+		case 1:
+			if err := s.Labels[l].Unmarshal(value); err != nil {
+				return nil, err
+			}
+			l++
+		case 2:
+			if err := r.decodeAggrChunk(&s.Chunks[c], value); err != nil {
+				return nil, err
+			}
+			c++
+		}
+	}
+	return s, nil
+}
+
+func (r *SeriesReceiver) decodeAggrChunk(c *AggrChunk, data []byte) error {
+	for len(data) > 0 {
+		num, wireType, value, rest, err := nextField(data)
+		if err != nil {
+			return err
+		}
+		data = rest
+
+		switch num {
+		case 1, 2:
+			if wireType != 0 {
+				return errors.Errorf("proto: wrong wireType = %d for field %d of AggrChunk", wireType, num)
+			}
+			v, _ := proto.DecodeVarint(value)
+			if num == 1 {
+				c.MinTime = int64(v)
+			} else {
+				c.MaxTime = int64(v)
+			}
+		case 3, 4, 5, 6, 7, 8:
+			if wireType != 2 {
+				return errors.Errorf("proto: wrong wireType = %d for field %d of AggrChunk", wireType, num)
+			}
+			chk := r.chunk()
+			if err := decodeChunk(chk, value); err != nil {
+				return err
+			}
+			switch num {
+			case 3:
+				c.Raw = chk
+			case 4:
+				c.Count = chk
+			case 5:
+				c.Sum = chk
+			case 6:
+				c.Min = chk
+			case 7:
+				c.Max = chk
+			case 8:
+				c.Counter = chk
+			}
+		}
+	}
+	return nil
+}
+
+// decodeChunk decodes the chunk, whose data references the given one.
+func decodeChunk(c *Chunk, data []byte) error {
+	for len(data) > 0 {
+		num, wireType, value, rest, err := nextField(data)
+		if err != nil {
+			return err
+		}
+		data = rest
+
+		switch num {
+		case 1:
+			if wireType != 0 {
+				return errors.Errorf("proto: wrong wireType = %d for field Type of Chunk", wireType)
+			}
+			v, _ := proto.DecodeVarint(value)
+			c.Type = Chunk_Encoding(v)
+		case 2:
+			if wireType != 2 {
+				return errors.Errorf("proto: wrong wireType = %d for field Data of Chunk", wireType)
+			}
+			c.Data = value
+		}
+	}
+	return nil
+}
+
+// nextField returns the number, the wire type and the value of the first field of data, and the data after it. The
+// value of varint fields is their encoded varint, the one of length-delimited fields their data, with a capacity
+// limited to it.
+func nextField(data []byte) (num int32, wireType int, value, rest []byte, err error) {
+	key, n := proto.DecodeVarint(data)
+	if n == 0 {
+		return 0, 0, nil, nil, io.ErrUnexpectedEOF
+	}
+	num, wireType = int32(key>>3), int(key&0x7)
+	if num <= 0 {
+		return 0, 0, nil, nil, errors.Errorf("proto: illegal field number %d", num)
+	}
+
+	switch wireType {
+	case 0:
+		_, m := proto.DecodeVarint(data[n:])
+		if m == 0 {
+			return 0, 0, nil, nil, io.ErrUnexpectedEOF
+		}
+		return num, wireType, data[n : n+m], data[n+m:], nil
+	case 2:
+		l, m := proto.DecodeVarint(data[n:])
+		if m == 0 || l > uint64(len(data)-n-m) {
+			return 0, 0, nil, nil, io.ErrUnexpectedEOF
+		}
+		start, end := n+m, n+m+int(l)
+		return num, wireType, data[start:end:end], data[end:], nil
+	}
+
+	m, err := skipTypes(data)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+	if m > len(data) {
+		return 0, 0, nil, nil, io.ErrUnexpectedEOF
+	}
+	return num, wireType, nil, data[m:], nil
+}
@@ -0,0 +1,135 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package storepb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+// responsesStoreServer sends the given responses to Series calls.
+type responsesStoreServer struct {
+	UnimplementedStoreServer
+
+	resps []*SeriesResponse
+}
+
+func (s *responsesStoreServer) Series(_ *SeriesRequest, srv Store_SeriesServer) error {
+	for _, r := range s.resps {
+		if err := srv.Send(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receiveAll returns all the responses of a Series call to the server at the given address.
+func receiveAll(t *testing.T, addr string, bufs *SeriesBuffers) []*SeriesResponse {
+	cc, err := grpc.Dial(addr, grpc.WithInsecure())
+	testutil.Ok(t, err)
+	defer cc.Close()
+
+	stream, err := NewStoreClient(cc).Series(context.Background(), &SeriesRequest{})
+	testutil.Ok(t, err)
+
+	recv := NewSeriesReceiver(stream, bufs)
+	defer recv.Close()
+
+	var resps []*SeriesResponse
+	for {
+		r, err := recv.Recv()
+		if err == io.EOF {
+			return resps
+		}
+		testutil.Ok(t, err)
+		resps = append(resps, r)
+	}
+}
+
+func TestSeriesReceiver(t *testing.T) {
+	resps := []*SeriesResponse{
+		NewSeriesResponse(&Series{
+			Labels: []labelpb.ZLabel{{Name: "__name__", Value: "up"}, {Name: "job", Value: "a"}},
+			Chunks: []AggrChunk{
+				{MinTime: -10, MaxTime: 2, Raw: &Chunk{Type: Chunk_XOR, Data: []byte{1, 2, 3}}},
+				{MinTime: 3, MaxTime: 4, Count: &Chunk{Data: []byte{4}}, Sum: &Chunk{Data: []byte{5}}, Counter: &Chunk{}},
+			},
+		}),
+		NewSeriesResponse(&Series{Labels: []labelpb.ZLabel{{Name: "job", Value: "b"}}}),
+		NewWarnSeriesResponse(errors.New("warning")),
+		NewSeriesBatchResponse(&SeriesBatch{
+			Symbols: []string{"job", "c"},
+			Series:  []BatchedSeries{{Labels: []uint32{0, 1}, Chunks: []AggrChunk{{MinTime: 1, MaxTime: 2, Raw: &Chunk{Data: []byte{6}}}}}},
+		}),
+	}
+	// Enough series to use several slabs, and one too large for them.
+	for i := 0; i < 2*slabSeries; i++ {
+		resps = append(resps, NewSeriesResponse(&Series{
+			Labels: []labelpb.ZLabel{{Name: "i", Value: fmt.Sprint(i)}},
+			Chunks: []AggrChunk{{MinTime: int64(i), MaxTime: int64(i), Raw: &Chunk{Data: []byte{byte(i)}}}},
+		}))
+	}
+	large := &Series{}
+	for i := 0; i < slabLabels+1; i++ {
+		large.Labels = append(large.Labels, labelpb.ZLabel{Name: fmt.Sprint(i), Value: "v"})
+	}
+	resps = append(resps, NewSeriesResponse(large))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	testutil.Ok(t, err)
+	srv := grpc.NewServer()
+	RegisterStoreServer(srv, &responsesStoreServer{resps: resps})
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	// Responses are received as without buffers.
+	testutil.Equals(t, resps, receiveAll(t, l.Addr().String(), nil))
+
+	bufs := NewSeriesBuffers()
+	got := receiveAll(t, l.Addr().String(), bufs)
+	testutil.Equals(t, resps, got)
+	for _, r := range got {
+		if s := r.GetSeries(); s != nil {
+			// Appending to the labels and chunks of series does not overwrite the ones of others.
+			testutil.Equals(t, len(s.Labels), cap(s.Labels))
+			testutil.Equals(t, len(s.Chunks), cap(s.Chunks))
+		}
+	}
+	testutil.Assert(t, len(bufs.slabs) > 1, "expected several slabs, got %d", len(bufs.slabs))
+
+	bufs.Release()
+	testutil.Equals(t, 0, len(bufs.slabs))
+
+	// Receivers closed after the release recycle their memory right away.
+	bufs.put([]*seriesSlab{seriesSlabPool.Get().(*seriesSlab)})
+	testutil.Equals(t, 0, len(bufs.slabs))
+}
+
+func TestSeriesFrame_Unmarshal(t *testing.T) {
+	data, err := NewSeriesResponse(&Series{
+		Labels: []labelpb.ZLabel{{Name: "job", Value: "a"}},
+		Chunks: []AggrChunk{{MinTime: 1, MaxTime: 2, Raw: &Chunk{Data: []byte{1, 2, 3}}}},
+	}).Marshal()
+	testutil.Ok(t, err)
+
+	for i := 1; i < len(data); i++ {
+		f := &seriesFrame{r: NewSeriesReceiver(nil, NewSeriesBuffers())}
+		testutil.NotOk(t, f.Unmarshal(data[:i]), "truncated at %d", i)
+	}
+
+	f := &seriesFrame{r: NewSeriesReceiver(nil, NewSeriesBuffers())}
+	testutil.Ok(t, f.Unmarshal(data))
+	// The data of chunks references the one of the message.
+	data[len(data)-1] = 4
+	testutil.Equals(t, []byte{1, 2, 4}, f.resp.GetSeries().Chunks[0].Raw.Data)
+}
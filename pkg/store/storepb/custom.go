@@ -293,6 +293,62 @@ func (m AggrChunk) Compare(b AggrChunk) int {
 	return 0
 }
 
+// AggrLabelName is the name of the label holding the aggregate of the series returned for requests splitting
+// aggregates.
+const AggrLabelName = "__thanos_aggr__"
+
+// Get returns the chunk of the given aggregate, or nil if it was not loaded.
+func (m AggrChunk) Get(a Aggr) *Chunk {
+	switch a {
+	case Aggr_COUNT:
+		return m.Count
+	case Aggr_SUM:
+		return m.Sum
+	case Aggr_MIN:
+		return m.Min
+	case Aggr_MAX:
+		return m.Max
+	case Aggr_COUNTER:
+		return m.Counter
+	}
+	return nil
+}
+
+// SplitAggregates returns the raw chunks of a series, and the chunks of each of the given aggregates of its
+// downsampled chunks, as separate series sorted by labels. The series of aggregates have the name of their aggregate
+// in the AggrLabelName label. Series without chunks are omitted.
+func SplitAggregates(lset labels.Labels, chks []AggrChunk, aggrs []Aggr) []Series {
+	var raw []AggrChunk
+	aggrChks := make([][]AggrChunk, len(aggrs))
+	for _, c := range chks {
+		if c.Raw != nil {
+			raw = append(raw, c)
+			continue
+		}
+		for i, a := range aggrs {
+			if x := c.Get(a); x != nil {
+				aggrChks[i] = append(aggrChks[i], AggrChunk{MinTime: c.MinTime, MaxTime: c.MaxTime, Raw: x})
+			}
+		}
+	}
+
+	var res []Series
+	if len(raw) > 0 {
+		res = append(res, Series{Labels: labelpb.ZLabelsFromPromLabels(lset), Chunks: raw})
+	}
+	for i, a := range aggrs {
+		if len(aggrChks[i]) == 0 {
+			continue
+		}
+		alset := labels.NewBuilder(lset).Set(AggrLabelName, strings.ToLower(a.String())).Labels()
+		res = append(res, Series{Labels: labelpb.ZLabelsFromPromLabels(alset), Chunks: aggrChks[i]})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return labels.Compare(res[i].PromLabels(), res[j].PromLabels()) < 0
+	})
+	return res
+}
+
 // Compare returns positive 1 if chunk is smaller -1 if larger.
 // It returns 0 if chunks are exactly the same.
 func (m *Chunk) Compare(b *Chunk) int {
@@ -541,3 +597,36 @@ func (m *QueryHints) IsSafeToExecute() bool {
 
 	return false
 }
+
+// ShardMatcher matches the series of a shard. It is not safe for concurrent use.
+type ShardMatcher struct {
+	by    []string
+	index uint64
+	total uint64
+	buf   []byte
+}
+
+// Matcher returns a matcher of the series of the shard, or nil, which matches all series, if the shard is nil.
+func (m *ShardInfo) Matcher() *ShardMatcher {
+	if m == nil || m.TotalShards < 1 {
+		return nil
+	}
+	by := append([]string(nil), m.Labels...)
+	sort.Strings(by)
+	return &ShardMatcher{by: by, index: uint64(m.ShardIndex), total: uint64(m.TotalShards), buf: make([]byte, 0, 1024)}
+}
+
+// MatchesLabels returns true if the series with the given sorted labels is in the shard.
+func (s *ShardMatcher) MatchesLabels(lset labels.Labels) bool {
+	if s == nil {
+		return true
+	}
+	var h uint64
+	h, s.buf = lset.HashForLabels(s.buf, s.by...)
+	return h%s.total == s.index
+}
+
+// MatchesZLabels returns true if the series with the given sorted labels is in the shard.
+func (s *ShardMatcher) MatchesZLabels(lset []labelpb.ZLabel) bool {
+	return s.MatchesLabels(labelpb.ZLabelsToPromLabels(lset))
+}
@@ -668,3 +668,30 @@ func TestSeriesRequestToPromQL(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitAggregates(t *testing.T) {
+	raw := &Chunk{Type: Chunk_XOR, Data: []byte("raw")}
+	cnt := &Chunk{Type: Chunk_XOR, Data: []byte("count")}
+	sum := &Chunk{Type: Chunk_XOR, Data: []byte("sum")}
+	max := &Chunk{Type: Chunk_XOR, Data: []byte("max")}
+
+	lset := labels.FromStrings("__name__", "up", "job", "a")
+	chks := []AggrChunk{
+		{MinTime: 0, MaxTime: 10, Count: cnt, Sum: sum, Max: max},
+		{MinTime: 11, MaxTime: 20, Raw: raw},
+	}
+	testutil.Equals(t, []Series{
+		{
+			Labels: labelpb.ZLabelsFromPromLabels(labels.FromStrings("__name__", "up", AggrLabelName, "count", "job", "a")),
+			Chunks: []AggrChunk{{MinTime: 0, MaxTime: 10, Raw: cnt}},
+		},
+		{
+			Labels: labelpb.ZLabelsFromPromLabels(labels.FromStrings("__name__", "up", AggrLabelName, "max", "job", "a")),
+			Chunks: []AggrChunk{{MinTime: 0, MaxTime: 10, Raw: max}},
+		},
+		{
+			Labels: labelpb.ZLabelsFromPromLabels(lset),
+			Chunks: []AggrChunk{{MinTime: 11, MaxTime: 20, Raw: raw}},
+		},
+	}, SplitAggregates(lset, chks, []Aggr{Aggr_MAX, Aggr_COUNT, Aggr_MIN}))
+}
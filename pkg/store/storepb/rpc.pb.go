@@ -289,6 +289,24 @@ type SeriesRequest struct {
 	// query_hints are the hints coming from the PromQL engine when
 	// requesting a storage.SeriesSet for a given expression.
 	QueryHints *QueryHints `protobuf:"bytes,12,opt,name=query_hints,json=queryHints,proto3" json:"query_hints,omitempty"`
+	// limit is the maximum number of series to return. Stores stop sending series once the limit is reached.
+	// 0 means no limit.
+	Limit int64 `protobuf:"varint,13,opt,name=limit,proto3" json:"limit,omitempty"`
+	// series_batch_size is the maximum number of series of the SeriesBatch frames the store may send instead of
+	// single series. 0 means series are sent one by one. It is set only for stores advertising the support of batches.
+	SeriesBatchSize int64 `protobuf:"varint,14,opt,name=series_batch_size,json=seriesBatchSize,proto3" json:"series_batch_size,omitempty"`
+	// split_aggregates requests stores to return the aggregates of downsampled chunks as separate series, labelled
+	// with the name of their aggregate in the __thanos_aggr__ label, instead of a single series with aggregated chunks.
+	// Raw chunks are returned in the series without the label.
+	SplitAggregates bool `protobuf:"varint,15,opt,name=split_aggregates,json=splitAggregates,proto3" json:"split_aggregates,omitempty"`
+	// select_hints are the hints coming from the PromQL engine when requesting a storage.SeriesSet for a given
+	// expression. Unlike query_hints, which allow stores to push down functions and are only set when pushdown is
+	// enabled, they are always set by queriers and propagated by proxies to leaf stores, which may use them for
+	// decisions not changing the results, e.g. limits.
+	SelectHints *QueryHints `protobuf:"bytes,16,opt,name=select_hints,json=selectHints,proto3" json:"select_hints,omitempty"`
+	// shard_info selects only the series of a shard. Stores which don't support it return all series, which are then
+	// filtered by the proxy.
+	ShardInfo *ShardInfo `protobuf:"bytes,17,opt,name=shard_info,json=shardInfo,proto3" json:"shard_info,omitempty"`
 }
 
 func (m *SeriesRequest) Reset()         { *m = SeriesRequest{} }
@@ -489,6 +507,7 @@ type SeriesResponse struct {
 	//	*SeriesResponse_Series
 	//	*SeriesResponse_Warning
 	//	*SeriesResponse_Hints
+	//	*SeriesResponse_Batch
 	Result isSeriesResponse_Result `protobuf_oneof:"result"`
 }
 
@@ -540,10 +559,14 @@ type SeriesResponse_Warning struct {
 type SeriesResponse_Hints struct {
 	Hints *types.Any `protobuf:"bytes,3,opt,name=hints,proto3,oneof" json:"hints,omitempty"`
 }
+type SeriesResponse_Batch struct {
+	Batch *SeriesBatch `protobuf:"bytes,4,opt,name=batch,proto3,oneof" json:"batch,omitempty"`
+}
 
 func (*SeriesResponse_Series) isSeriesResponse_Result()  {}
 func (*SeriesResponse_Warning) isSeriesResponse_Result() {}
 func (*SeriesResponse_Hints) isSeriesResponse_Result()   {}
+func (*SeriesResponse_Batch) isSeriesResponse_Result()   {}
 
 func (m *SeriesResponse) GetResult() isSeriesResponse_Result {
 	if m != nil {
@@ -573,12 +596,20 @@ func (m *SeriesResponse) GetHints() *types.Any {
 	return nil
 }
 
+func (m *SeriesResponse) GetBatch() *SeriesBatch {
+	if x, ok := m.GetResult().(*SeriesResponse_Batch); ok {
+		return x.Batch
+	}
+	return nil
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*SeriesResponse) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
 		(*SeriesResponse_Series)(nil),
 		(*SeriesResponse_Warning)(nil),
 		(*SeriesResponse_Hints)(nil),
+		(*SeriesResponse_Batch)(nil),
 	}
 }
 
@@ -593,6 +624,8 @@ type LabelNamesRequest struct {
 	// implementation of a specific store.
 	Hints    *types.Any     `protobuf:"bytes,5,opt,name=hints,proto3" json:"hints,omitempty"`
 	Matchers []LabelMatcher `protobuf:"bytes,6,rep,name=matchers,proto3" json:"matchers"`
+	// limit is the maximum number of label names to return. 0 means no limit.
+	Limit int64 `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
 }
 
 func (m *LabelNamesRequest) Reset()         { *m = LabelNamesRequest{} }
@@ -682,6 +715,8 @@ type LabelValuesRequest struct {
 	// implementation of a specific store.
 	Hints    *types.Any     `protobuf:"bytes,6,opt,name=hints,proto3" json:"hints,omitempty"`
 	Matchers []LabelMatcher `protobuf:"bytes,7,rep,name=matchers,proto3" json:"matchers"`
+	// limit is the maximum number of label values to return. 0 means no limit.
+	Limit int64 `protobuf:"varint,8,opt,name=limit,proto3" json:"limit,omitempty"`
 }
 
 func (m *LabelValuesRequest) Reset()         { *m = LabelValuesRequest{} }
@@ -759,6 +794,130 @@ func (m *LabelValuesResponse) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_LabelValuesResponse proto.InternalMessageInfo
 
+// SeriesBatch holds multiple series in a single frame. The label names and values of all its series are interned
+// in the symbols, so that each of them is sent once per batch.
+type SeriesBatch struct {
+	// symbols are the label names and values of the series of the batch.
+	Symbols []string `protobuf:"bytes,1,rep,name=symbols,proto3" json:"symbols,omitempty"`
+	// series are the series of the batch, in the order they would have been sent one by one.
+	Series []BatchedSeries `protobuf:"bytes,2,rep,name=series,proto3" json:"series"`
+}
+
+func (m *SeriesBatch) Reset()         { *m = SeriesBatch{} }
+func (m *SeriesBatch) String() string { return proto.CompactTextString(m) }
+func (*SeriesBatch) ProtoMessage()    {}
+func (*SeriesBatch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a938d55a388af629, []int{14}
+}
+func (m *SeriesBatch) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SeriesBatch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SeriesBatch.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *SeriesBatch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SeriesBatch.Merge(m, src)
+}
+func (m *SeriesBatch) XXX_Size() int {
+	return m.Size()
+}
+func (m *SeriesBatch) XXX_DiscardUnknown() {
+	xxx_messageInfo_SeriesBatch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SeriesBatch proto.InternalMessageInfo
+
+// BatchedSeries is a series of a SeriesBatch.
+type BatchedSeries struct {
+	// labels are the indexes of the names and values of the labels of the series in the symbols of the batch, in
+	// pairs of name and value. The labels are sorted by name.
+	Labels []uint32    `protobuf:"varint,1,rep,packed,name=labels,proto3" json:"labels,omitempty"`
+	Chunks []AggrChunk `protobuf:"bytes,2,rep,name=chunks,proto3" json:"chunks"`
+}
+
+func (m *BatchedSeries) Reset()         { *m = BatchedSeries{} }
+func (m *BatchedSeries) String() string { return proto.CompactTextString(m) }
+func (*BatchedSeries) ProtoMessage()    {}
+func (*BatchedSeries) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a938d55a388af629, []int{15}
+}
+func (m *BatchedSeries) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *BatchedSeries) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_BatchedSeries.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *BatchedSeries) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchedSeries.Merge(m, src)
+}
+func (m *BatchedSeries) XXX_Size() int {
+	return m.Size()
+}
+func (m *BatchedSeries) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchedSeries.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchedSeries proto.InternalMessageInfo
+
+// ShardInfo is a shard of the series, the series whose values of the labels hash to shard_index modulo total_shards.
+type ShardInfo struct {
+	ShardIndex  int64 `protobuf:"varint,1,opt,name=shard_index,json=shardIndex,proto3" json:"shard_index,omitempty"`
+	TotalShards int64 `protobuf:"varint,2,opt,name=total_shards,json=totalShards,proto3" json:"total_shards,omitempty"`
+	// labels are the names of the labels the series are sharded by.
+	Labels []string `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty"`
+}
+
+func (m *ShardInfo) Reset()         { *m = ShardInfo{} }
+func (m *ShardInfo) String() string { return proto.CompactTextString(m) }
+func (*ShardInfo) ProtoMessage()    {}
+func (*ShardInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a938d55a388af629, []int{16}
+}
+func (m *ShardInfo) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ShardInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ShardInfo.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ShardInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ShardInfo.Merge(m, src)
+}
+func (m *ShardInfo) XXX_Size() int {
+	return m.Size()
+}
+func (m *ShardInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_ShardInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ShardInfo proto.InternalMessageInfo
+
 func init() {
 	proto.RegisterEnum("thanos.StoreType", StoreType_name, StoreType_value)
 	proto.RegisterEnum("thanos.Aggr", Aggr_name, Aggr_value)
@@ -776,89 +935,106 @@ func init() {
 	proto.RegisterType((*LabelNamesResponse)(nil), "thanos.LabelNamesResponse")
 	proto.RegisterType((*LabelValuesRequest)(nil), "thanos.LabelValuesRequest")
 	proto.RegisterType((*LabelValuesResponse)(nil), "thanos.LabelValuesResponse")
+	proto.RegisterType((*SeriesBatch)(nil), "thanos.SeriesBatch")
+	proto.RegisterType((*BatchedSeries)(nil), "thanos.BatchedSeries")
+	proto.RegisterType((*ShardInfo)(nil), "thanos.ShardInfo")
 }
 
 func init() { proto.RegisterFile("store/storepb/rpc.proto", fileDescriptor_a938d55a388af629) }
 
 var fileDescriptor_a938d55a388af629 = []byte{
-	// 1230 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x5f, 0x6f, 0x13, 0x47,
-	0x10, 0xf7, 0xdd, 0xf9, 0xfc, 0x67, 0x9c, 0xa4, 0xc7, 0x62, 0xe0, 0x62, 0x24, 0xc7, 0xba, 0xaa,
-	0x52, 0x84, 0xa8, 0xdd, 0x9a, 0x0a, 0xa9, 0x15, 0x2f, 0x49, 0x30, 0x24, 0x2a, 0x31, 0x65, 0x9d,
-	0x90, 0x96, 0xaa, 0xb2, 0xce, 0xce, 0x72, 0x3e, 0xe1, 0xfb, 0xc3, 0xed, 0x5e, 0xc1, 0xaf, 0xed,
-	0x7b, 0x55, 0xf5, 0x23, 0x54, 0xfd, 0x10, 0xfd, 0x08, 0xbc, 0x95, 0xc7, 0xaa, 0x0f, 0xa8, 0x85,
-	0x2f, 0x52, 0xed, 0x9f, 0xb3, 0x7d, 0x69, 0x80, 0xa2, 0xf0, 0x72, 0xda, 0x99, 0xdf, 0xec, 0xec,
-	0xcc, 0xfc, 0x76, 0xe6, 0x16, 0x2e, 0x51, 0x16, 0x25, 0xa4, 0x23, 0xbe, 0xf1, 0xa8, 0x93, 0xc4,
-	0xe3, 0x76, 0x9c, 0x44, 0x2c, 0x42, 0x25, 0x36, 0x71, 0xc3, 0x88, 0x36, 0xd6, 0xf3, 0x06, 0x6c,
-	0x16, 0x13, 0x2a, 0x4d, 0x1a, 0x75, 0x2f, 0xf2, 0x22, 0xb1, 0xec, 0xf0, 0x95, 0xd2, 0xb6, 0xf2,
-	0x1b, 0xe2, 0x24, 0x0a, 0x4e, 0xec, 0x53, 0x2e, 0xa7, 0xee, 0x88, 0x4c, 0x4f, 0x42, 0x5e, 0x14,
-	0x79, 0x53, 0xd2, 0x11, 0xd2, 0x28, 0x7d, 0xd8, 0x71, 0xc3, 0x99, 0x84, 0x9c, 0x0f, 0x60, 0xf5,
-	0x28, 0xf1, 0x19, 0xc1, 0x84, 0xc6, 0x51, 0x48, 0x89, 0xf3, 0xa3, 0x06, 0x2b, 0x4a, 0xf3, 0x38,
-	0x25, 0x94, 0xa1, 0x2d, 0x00, 0xe6, 0x07, 0x84, 0x92, 0xc4, 0x27, 0xd4, 0xd6, 0x5a, 0xc6, 0x66,
-	0xad, 0x7b, 0x99, 0xef, 0x0e, 0x08, 0x9b, 0x90, 0x94, 0x0e, 0xc7, 0x51, 0x3c, 0x6b, 0x1f, 0xf8,
-	0x01, 0x19, 0x08, 0x93, 0xed, 0xe2, 0xb3, 0x17, 0x1b, 0x05, 0xbc, 0xb4, 0x09, 0x5d, 0x84, 0x12,
-	0x23, 0xa1, 0x1b, 0x32, 0x5b, 0x6f, 0x69, 0x9b, 0x55, 0xac, 0x24, 0x64, 0x43, 0x39, 0x21, 0xf1,
-	0xd4, 0x1f, 0xbb, 0xb6, 0xd1, 0xd2, 0x36, 0x0d, 0x9c, 0x89, 0xce, 0x2a, 0xd4, 0xf6, 0xc2, 0x87,
-	0x91, 0x8a, 0xc1, 0xf9, 0x45, 0x87, 0x15, 0x29, 0xcb, 0x28, 0xd1, 0x18, 0x4a, 0x22, 0xd1, 0x2c,
-	0xa0, 0xd5, 0xb6, 0x2c, 0x6c, 0xfb, 0x0e, 0xd7, 0x6e, 0xdf, 0xe0, 0x21, 0xfc, 0xf5, 0x62, 0xe3,
-	0x33, 0xcf, 0x67, 0x93, 0x74, 0xd4, 0x1e, 0x47, 0x41, 0x47, 0x1a, 0x7c, 0xec, 0x47, 0x6a, 0xd5,
-	0x89, 0x1f, 0x79, 0x9d, 0x5c, 0xcd, 0xda, 0x0f, 0xc4, 0x6e, 0xac, 0x5c, 0xa3, 0x75, 0xa8, 0x04,
-	0x7e, 0x38, 0xe4, 0x89, 0x88, 0xc0, 0x0d, 0x5c, 0x0e, 0xfc, 0x90, 0x67, 0x2a, 0x20, 0xf7, 0xa9,
-	0x84, 0x54, 0xe8, 0x81, 0xfb, 0x54, 0x40, 0x1d, 0xa8, 0x0a, 0xaf, 0x07, 0xb3, 0x98, 0xd8, 0xc5,
-	0x96, 0xb6, 0xb9, 0xd6, 0x3d, 0x97, 0x45, 0x37, 0xc8, 0x00, 0xbc, 0xb0, 0x41, 0xd7, 0x01, 0xc4,
-	0x81, 0x43, 0x4a, 0x18, 0xb5, 0x4d, 0x91, 0xcf, 0x7c, 0x87, 0x0c, 0x69, 0x40, 0x98, 0x2a, 0x6b,
-	0x75, 0xaa, 0x64, 0xea, 0xfc, 0x56, 0x84, 0x55, 0x59, 0xf2, 0x8c, 0xaa, 0xe5, 0x80, 0xb5, 0xd7,
-	0x07, 0xac, 0xe7, 0x03, 0xbe, 0xce, 0x21, 0x36, 0x9e, 0x90, 0x84, 0xda, 0x86, 0x38, 0xbd, 0x9e,
-	0xab, 0xe6, 0xbe, 0x04, 0x55, 0x00, 0x73, 0x5b, 0xd4, 0x85, 0x0b, 0xdc, 0x65, 0x42, 0x68, 0x34,
-	0x4d, 0x99, 0x1f, 0x85, 0xc3, 0x27, 0x7e, 0x78, 0x1c, 0x3d, 0x11, 0x49, 0x1b, 0xf8, 0x7c, 0xe0,
-	0x3e, 0xc5, 0x73, 0xec, 0x48, 0x40, 0xe8, 0x2a, 0x80, 0xeb, 0x79, 0x09, 0xf1, 0x5c, 0x46, 0x64,
-	0xae, 0x6b, 0xdd, 0x95, 0xec, 0xb4, 0x2d, 0xcf, 0x4b, 0xf0, 0x12, 0x8e, 0xbe, 0x80, 0xf5, 0xd8,
-	0x4d, 0x98, 0xef, 0x4e, 0xf9, 0x29, 0x82, 0xf9, 0xe1, 0xb1, 0x4f, 0xdd, 0xd1, 0x94, 0x1c, 0xdb,
-	0xa5, 0x96, 0xb6, 0x59, 0xc1, 0x97, 0x94, 0x41, 0x76, 0x33, 0x6e, 0x2a, 0x18, 0x7d, 0x7b, 0xca,
-	0x5e, 0xca, 0x12, 0x97, 0x11, 0x6f, 0x66, 0x97, 0x05, 0x2d, 0x1b, 0xd9, 0xc1, 0x5f, 0xe5, 0x7d,
-	0x0c, 0x94, 0xd9, 0x7f, 0x9c, 0x67, 0x00, 0xda, 0x80, 0x1a, 0x7d, 0xe4, 0xc7, 0xc3, 0xf1, 0x24,
-	0x0d, 0x1f, 0x51, 0xbb, 0x22, 0x42, 0x01, 0xae, 0xda, 0x11, 0x1a, 0x74, 0x05, 0xcc, 0x89, 0x1f,
-	0x32, 0x6a, 0x57, 0x5b, 0x9a, 0x28, 0xa8, 0xec, 0xc0, 0x76, 0xd6, 0x81, 0xed, 0xad, 0x70, 0x86,
-	0xa5, 0x09, 0x42, 0x50, 0xa4, 0x8c, 0xc4, 0x36, 0x88, 0xb2, 0x89, 0x35, 0xaa, 0x83, 0x99, 0xb8,
-	0xa1, 0x47, 0xec, 0x9a, 0x50, 0x4a, 0x01, 0x5d, 0x83, 0xda, 0xe3, 0x94, 0x24, 0xb3, 0xa1, 0xf4,
-	0xbd, 0x22, 0x7c, 0xa3, 0x2c, 0x8b, 0x7b, 0x1c, 0xda, 0xe5, 0x08, 0x86, 0xc7, 0xf3, 0xb5, 0xf3,
-	0xab, 0x06, 0xb0, 0x80, 0x44, 0xe8, 0x8c, 0xc4, 0xc3, 0xc0, 0x9f, 0x4e, 0x7d, 0xaa, 0xae, 0x09,
-	0x70, 0xd5, 0xbe, 0xd0, 0xa0, 0x16, 0x14, 0x1f, 0xa6, 0xe1, 0x58, 0xdc, 0x92, 0xda, 0x82, 0x9c,
-	0x5b, 0x69, 0x38, 0xc6, 0x02, 0x41, 0x57, 0xa1, 0xe2, 0x25, 0x51, 0x1a, 0xfb, 0xa1, 0x27, 0xb8,
-	0xae, 0x75, 0xad, 0xcc, 0xea, 0xb6, 0xd2, 0xe3, 0xb9, 0x05, 0xfa, 0x30, 0x4b, 0xc5, 0x14, 0xa6,
-	0xf3, 0x4e, 0xc5, 0x5c, 0xa9, 0x32, 0x73, 0x1a, 0x50, 0xe4, 0x07, 0xf0, 0x5a, 0x84, 0xae, 0xba,
-	0xbd, 0x55, 0x2c, 0xd6, 0x4e, 0x17, 0x2a, 0x99, 0x5b, 0xb4, 0x06, 0xfa, 0x68, 0x26, 0xd0, 0x0a,
-	0xd6, 0x47, 0x33, 0x3e, 0x59, 0xd4, 0x1c, 0xe0, 0x37, 0xb7, 0x9a, 0xb5, 0xae, 0xb3, 0x01, 0xa6,
-	0xf0, 0xcf, 0x0d, 0x72, 0x99, 0x2a, 0xc9, 0xf9, 0x49, 0x83, 0xb5, 0xac, 0x79, 0xd4, 0x4c, 0xd9,
-	0x84, 0xd2, 0x7c, 0xc8, 0xf1, 0x48, 0xd7, 0xe6, 0x5d, 0x2b, 0xb4, 0xbb, 0x05, 0xac, 0x70, 0xd4,
-	0x80, 0xf2, 0x13, 0x37, 0x09, 0x79, 0xfe, 0x62, 0xa0, 0xed, 0x16, 0x70, 0xa6, 0x40, 0x57, 0x33,
-	0xe6, 0x8d, 0xd7, 0x33, 0xbf, 0x5b, 0x50, 0xdc, 0x6f, 0x57, 0xa0, 0x94, 0x10, 0x9a, 0x4e, 0x99,
-	0xf3, 0xbb, 0x0e, 0xe7, 0x44, 0xbb, 0xf5, 0xdd, 0x60, 0xd1, 0xd1, 0x6f, 0xec, 0x00, 0xed, 0x0c,
-	0x1d, 0xa0, 0x9f, 0xb1, 0x03, 0xea, 0x60, 0x52, 0xe6, 0x26, 0x4c, 0x4d, 0x3f, 0x29, 0x20, 0x0b,
-	0x0c, 0x12, 0x1e, 0xab, 0x01, 0xc0, 0x97, 0x8b, 0x46, 0x30, 0xdf, 0xde, 0x08, 0xcb, 0x83, 0xa8,
-	0xf4, 0xff, 0x07, 0x91, 0x93, 0x00, 0x5a, 0xae, 0x9c, 0xa2, 0xb3, 0x0e, 0x26, 0xbf, 0x3e, 0xf2,
-	0x0f, 0x51, 0xc5, 0x52, 0x40, 0x0d, 0xa8, 0x28, 0xa6, 0xa8, 0xad, 0x0b, 0x60, 0x2e, 0x2f, 0x62,
-	0x35, 0xde, 0x1a, 0xab, 0xf3, 0x87, 0xae, 0x0e, 0xbd, 0xef, 0x4e, 0xd3, 0x05, 0x5f, 0x75, 0x30,
-	0xc5, 0x0d, 0x54, 0x17, 0x58, 0x0a, 0x6f, 0x66, 0x51, 0x3f, 0x03, 0x8b, 0xc6, 0xfb, 0x62, 0xb1,
-	0x78, 0x0a, 0x8b, 0xe6, 0x29, 0x2c, 0x96, 0xde, 0x8d, 0xc5, 0xf2, 0x3b, 0xb0, 0x98, 0xc2, 0xf9,
-	0x5c, 0x41, 0x15, 0x8d, 0x17, 0xa1, 0xf4, 0xbd, 0xd0, 0x28, 0x1e, 0x95, 0xf4, 0xbe, 0x88, 0xbc,
-	0xf2, 0x1d, 0x54, 0xe7, 0x7f, 0x65, 0x54, 0x83, 0xf2, 0x61, 0xff, 0xcb, 0xfe, 0xdd, 0xa3, 0xbe,
-	0x55, 0x40, 0x55, 0x30, 0xef, 0x1d, 0xf6, 0xf0, 0x37, 0x96, 0x86, 0x2a, 0x50, 0xc4, 0x87, 0x77,
-	0x7a, 0x96, 0xce, 0x2d, 0x06, 0x7b, 0x37, 0x7b, 0x3b, 0x5b, 0xd8, 0x32, 0xb8, 0xc5, 0xe0, 0xe0,
-	0x2e, 0xee, 0x59, 0x45, 0xae, 0xc7, 0xbd, 0x9d, 0xde, 0xde, 0xfd, 0x9e, 0x65, 0x72, 0xfd, 0xcd,
-	0xde, 0xf6, 0xe1, 0x6d, 0xab, 0x74, 0x65, 0x1b, 0x8a, 0xfc, 0xb7, 0x86, 0xca, 0x60, 0xe0, 0xad,
-	0x23, 0xe9, 0x75, 0xe7, 0xee, 0x61, 0xff, 0xc0, 0xd2, 0xb8, 0x6e, 0x70, 0xb8, 0x6f, 0xe9, 0x7c,
-	0xb1, 0xbf, 0xd7, 0xb7, 0x0c, 0xb1, 0xd8, 0xfa, 0x5a, 0xba, 0x13, 0x56, 0x3d, 0x6c, 0x99, 0xdd,
-	0x1f, 0x74, 0x30, 0x45, 0x8c, 0xe8, 0x53, 0x28, 0xf2, 0x67, 0x10, 0x3a, 0x9f, 0x55, 0x74, 0xe9,
-	0x91, 0xd4, 0xa8, 0xe7, 0x95, 0xaa, 0x7e, 0x9f, 0x43, 0x49, 0xce, 0x2f, 0x74, 0x21, 0x3f, 0xcf,
-	0xb2, 0x6d, 0x17, 0x4f, 0xaa, 0xe5, 0xc6, 0x4f, 0x34, 0xb4, 0x03, 0xb0, 0xe8, 0x2b, 0xb4, 0x9e,
-	0x63, 0x71, 0x79, 0x4a, 0x35, 0x1a, 0xa7, 0x41, 0xea, 0xfc, 0x5b, 0x50, 0x5b, 0xa2, 0x15, 0xe5,
-	0x4d, 0x73, 0xcd, 0xd3, 0xb8, 0x7c, 0x2a, 0x26, 0xfd, 0x74, 0xfb, 0xb0, 0x26, 0x9e, 0xa5, 0xbc,
-	0x2b, 0x64, 0x31, 0x6e, 0x40, 0x0d, 0x93, 0x20, 0x62, 0x44, 0xe8, 0xd1, 0x3c, 0xfd, 0xe5, 0xd7,
-	0x6b, 0xe3, 0xc2, 0x09, 0xad, 0x7a, 0xe5, 0x16, 0xb6, 0x3f, 0x7a, 0xf6, 0x4f, 0xb3, 0xf0, 0xec,
-	0x65, 0x53, 0x7b, 0xfe, 0xb2, 0xa9, 0xfd, 0xfd, 0xb2, 0xa9, 0xfd, 0xfc, 0xaa, 0x59, 0x78, 0xfe,
-	0xaa, 0x59, 0xf8, 0xf3, 0x55, 0xb3, 0xf0, 0xa0, 0xac, 0x1e, 0xda, 0xa3, 0x92, 0xb8, 0x33, 0xd7,
-	0xfe, 0x0d, 0x00, 0x00, 0xff, 0xff, 0x5a, 0xb7, 0x5e, 0x64, 0xd2, 0x0b, 0x00, 0x00,
+	// 1455 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0xcd, 0x6e, 0xdb, 0xc6,
+	0x16, 0x16, 0xf5, 0xaf, 0x23, 0xff, 0xd0, 0x13, 0x3b, 0xa1, 0x15, 0xc0, 0xd6, 0xe5, 0xc5, 0x05,
+	0x7c, 0xdd, 0x54, 0x4a, 0x95, 0x36, 0x40, 0x8b, 0x6c, 0x6c, 0x47, 0x89, 0x8d, 0xc6, 0x4e, 0x43,
+	0xd9, 0x71, 0x9a, 0xb6, 0x10, 0x28, 0x69, 0x4c, 0x13, 0xa1, 0x48, 0x86, 0x33, 0x6a, 0xac, 0x2c,
+	0xdb, 0x17, 0x28, 0xfa, 0x08, 0x7d, 0x8d, 0xbe, 0x40, 0x56, 0x45, 0x96, 0x45, 0x17, 0x41, 0x9b,
+	0xa0, 0x2f, 0xd1, 0x55, 0x31, 0x67, 0x86, 0x94, 0xe8, 0xda, 0x09, 0xd2, 0x64, 0x23, 0xcc, 0x39,
+	0xdf, 0x99, 0x33, 0xe7, 0x6f, 0xbe, 0xa1, 0xe0, 0x12, 0xe3, 0x41, 0x44, 0x9b, 0xf8, 0x1b, 0xf6,
+	0x9a, 0x51, 0xd8, 0x6f, 0x84, 0x51, 0xc0, 0x03, 0x52, 0xe4, 0xc7, 0xb6, 0x1f, 0xb0, 0xda, 0x72,
+	0xda, 0x80, 0x8f, 0x43, 0xca, 0xa4, 0x49, 0x6d, 0xd1, 0x09, 0x9c, 0x00, 0x97, 0x4d, 0xb1, 0x52,
+	0xda, 0x7a, 0x7a, 0x43, 0x18, 0x05, 0xc3, 0x53, 0xfb, 0x94, 0x4b, 0xcf, 0xee, 0x51, 0xef, 0x34,
+	0xe4, 0x04, 0x81, 0xe3, 0xd1, 0x26, 0x4a, 0xbd, 0xd1, 0x51, 0xd3, 0xf6, 0xc7, 0x12, 0x32, 0xe7,
+	0x61, 0xf6, 0x30, 0x72, 0x39, 0xb5, 0x28, 0x0b, 0x03, 0x9f, 0x51, 0xf3, 0x7b, 0x0d, 0x66, 0x94,
+	0xe6, 0xf1, 0x88, 0x32, 0x4e, 0x36, 0x00, 0xb8, 0x3b, 0xa4, 0x8c, 0x46, 0x2e, 0x65, 0x86, 0x56,
+	0xcf, 0xad, 0x55, 0x5b, 0x97, 0xc5, 0xee, 0x21, 0xe5, 0xc7, 0x74, 0xc4, 0xba, 0xfd, 0x20, 0x1c,
+	0x37, 0xf6, 0xdd, 0x21, 0xed, 0xa0, 0xc9, 0x66, 0xfe, 0xd9, 0x8b, 0xd5, 0x8c, 0x35, 0xb5, 0x89,
+	0x5c, 0x84, 0x22, 0xa7, 0xbe, 0xed, 0x73, 0x23, 0x5b, 0xd7, 0xd6, 0x2a, 0x96, 0x92, 0x88, 0x01,
+	0xa5, 0x88, 0x86, 0x9e, 0xdb, 0xb7, 0x8d, 0x5c, 0x5d, 0x5b, 0xcb, 0x59, 0xb1, 0x68, 0xce, 0x42,
+	0x75, 0xc7, 0x3f, 0x0a, 0x54, 0x0c, 0xe6, 0x8f, 0x59, 0x98, 0x91, 0xb2, 0x8c, 0x92, 0xf4, 0xa1,
+	0x88, 0x89, 0xc6, 0x01, 0xcd, 0x36, 0x64, 0x61, 0x1b, 0x77, 0x84, 0x76, 0xf3, 0x86, 0x08, 0xe1,
+	0xb7, 0x17, 0xab, 0x1f, 0x3b, 0x2e, 0x3f, 0x1e, 0xf5, 0x1a, 0xfd, 0x60, 0xd8, 0x94, 0x06, 0x1f,
+	0xba, 0x81, 0x5a, 0x35, 0xc3, 0x47, 0x4e, 0x33, 0x55, 0xb3, 0xc6, 0x43, 0xdc, 0x6d, 0x29, 0xd7,
+	0x64, 0x19, 0xca, 0x43, 0xd7, 0xef, 0x8a, 0x44, 0x30, 0xf0, 0x9c, 0x55, 0x1a, 0xba, 0xbe, 0xc8,
+	0x14, 0x21, 0xfb, 0x44, 0x42, 0x2a, 0xf4, 0xa1, 0x7d, 0x82, 0x50, 0x13, 0x2a, 0xe8, 0x75, 0x7f,
+	0x1c, 0x52, 0x23, 0x5f, 0xd7, 0xd6, 0xe6, 0x5a, 0x0b, 0x71, 0x74, 0x9d, 0x18, 0xb0, 0x26, 0x36,
+	0xe4, 0x3a, 0x00, 0x1e, 0xd8, 0x65, 0x94, 0x33, 0xa3, 0x80, 0xf9, 0x24, 0x3b, 0x64, 0x48, 0x1d,
+	0xca, 0x55, 0x59, 0x2b, 0x9e, 0x92, 0x99, 0xf9, 0x57, 0x01, 0x66, 0x65, 0xc9, 0xe3, 0x56, 0x4d,
+	0x07, 0xac, 0x9d, 0x1f, 0x70, 0x36, 0x1d, 0xf0, 0x75, 0x01, 0xf1, 0xfe, 0x31, 0x8d, 0x98, 0x91,
+	0xc3, 0xd3, 0x17, 0x53, 0xd5, 0xdc, 0x95, 0xa0, 0x0a, 0x20, 0xb1, 0x25, 0x2d, 0x58, 0x12, 0x2e,
+	0x23, 0xca, 0x02, 0x6f, 0xc4, 0xdd, 0xc0, 0xef, 0x3e, 0x71, 0xfd, 0x41, 0xf0, 0x04, 0x93, 0xce,
+	0x59, 0x17, 0x86, 0xf6, 0x89, 0x95, 0x60, 0x87, 0x08, 0x91, 0x2b, 0x00, 0xb6, 0xe3, 0x44, 0xd4,
+	0xb1, 0x39, 0x95, 0xb9, 0xce, 0xb5, 0x66, 0xe2, 0xd3, 0x36, 0x1c, 0x27, 0xb2, 0xa6, 0x70, 0xf2,
+	0x19, 0x2c, 0x87, 0x76, 0xc4, 0x5d, 0xdb, 0x13, 0xa7, 0x60, 0xe7, 0xbb, 0x03, 0x97, 0xd9, 0x3d,
+	0x8f, 0x0e, 0x8c, 0x62, 0x5d, 0x5b, 0x2b, 0x5b, 0x97, 0x94, 0x41, 0x3c, 0x19, 0x37, 0x15, 0x4c,
+	0xbe, 0x3a, 0x63, 0x2f, 0xe3, 0x91, 0xcd, 0xa9, 0x33, 0x36, 0x4a, 0xd8, 0x96, 0xd5, 0xf8, 0xe0,
+	0x2f, 0xd2, 0x3e, 0x3a, 0xca, 0xec, 0x1f, 0xce, 0x63, 0x80, 0xac, 0x42, 0x95, 0x3d, 0x72, 0xc3,
+	0x6e, 0xff, 0x78, 0xe4, 0x3f, 0x62, 0x46, 0x19, 0x43, 0x01, 0xa1, 0xda, 0x42, 0x0d, 0x59, 0x87,
+	0xc2, 0xb1, 0xeb, 0x73, 0x66, 0x54, 0xea, 0x1a, 0x16, 0x54, 0xde, 0xc0, 0x46, 0x7c, 0x03, 0x1b,
+	0x1b, 0xfe, 0xd8, 0x92, 0x26, 0x84, 0x40, 0x9e, 0x71, 0x1a, 0x1a, 0x80, 0x65, 0xc3, 0x35, 0x59,
+	0x84, 0x42, 0x64, 0xfb, 0x0e, 0x35, 0xaa, 0xa8, 0x94, 0x02, 0xb9, 0x06, 0xd5, 0xc7, 0x23, 0x1a,
+	0x8d, 0xbb, 0xd2, 0xf7, 0x0c, 0xfa, 0x26, 0x71, 0x16, 0xf7, 0x04, 0xb4, 0x2d, 0x10, 0x0b, 0x1e,
+	0x27, 0x6b, 0xe1, 0xca, 0x73, 0x87, 0x2e, 0x37, 0x66, 0xa5, 0x2b, 0x14, 0xc8, 0x3a, 0x2c, 0xc8,
+	0xcb, 0xd9, 0xed, 0x89, 0x7e, 0x76, 0x99, 0xfb, 0x94, 0x1a, 0x73, 0x68, 0x31, 0x2f, 0x81, 0x4d,
+	0xa1, 0xef, 0xb8, 0x4f, 0x29, 0xf9, 0x3f, 0xe8, 0x2c, 0xf4, 0x5c, 0xde, 0x9d, 0x6a, 0xdd, 0x3c,
+	0xa6, 0x3c, 0x8f, 0xfa, 0x8d, 0x49, 0xc7, 0x3e, 0x81, 0x19, 0x46, 0x3d, 0xda, 0xe7, 0x2a, 0x44,
+	0xfd, 0xdc, 0x10, 0xab, 0xd2, 0x4e, 0xc6, 0x78, 0x15, 0x80, 0x1d, 0xdb, 0xd1, 0xa0, 0xeb, 0xfa,
+	0x47, 0x81, 0xb1, 0x80, 0x9b, 0x26, 0x97, 0x46, 0x20, 0x78, 0xfb, 0x2b, 0x2c, 0x5e, 0x9a, 0x3f,
+	0x69, 0x00, 0x13, 0x6f, 0xd8, 0x10, 0x4e, 0xc3, 0xee, 0xd0, 0xf5, 0x3c, 0x97, 0xa9, 0xe1, 0x07,
+	0xa1, 0xda, 0x45, 0x0d, 0xa9, 0x43, 0xfe, 0x68, 0xe4, 0xf7, 0x71, 0xf6, 0xab, 0x93, 0x91, 0xbb,
+	0x35, 0xf2, 0xfb, 0x16, 0x22, 0xe4, 0x0a, 0x94, 0x9d, 0x28, 0x18, 0x85, 0xae, 0xef, 0xe0, 0x04,
+	0x57, 0x5b, 0x7a, 0x6c, 0x75, 0x5b, 0xe9, 0xad, 0xc4, 0x82, 0xfc, 0x37, 0x6e, 0x50, 0x01, 0x4d,
+	0x13, 0xfe, 0xb1, 0x84, 0x52, 0xf5, 0xcb, 0xac, 0x41, 0x5e, 0x1c, 0x20, 0x3a, 0xec, 0xdb, 0xea,
+	0x4e, 0x56, 0x2c, 0x5c, 0x9b, 0x2d, 0x28, 0xc7, 0x6e, 0xc9, 0x1c, 0x64, 0x7b, 0x63, 0x44, 0xcb,
+	0x56, 0xb6, 0x37, 0x16, 0x7c, 0xa9, 0xd8, 0x4d, 0xdc, 0xc7, 0x4a, 0x4c, 0x48, 0xe6, 0x2a, 0x14,
+	0xd0, 0xbf, 0x30, 0x48, 0x65, 0xaa, 0x24, 0xf3, 0x67, 0x0d, 0xe6, 0x62, 0x4a, 0x50, 0x4c, 0xb9,
+	0x06, 0xc5, 0x84, 0xba, 0x45, 0xa4, 0x73, 0x49, 0x59, 0x51, 0xbb, 0x9d, 0xb1, 0x14, 0x4e, 0x6a,
+	0x50, 0x7a, 0x62, 0x47, 0xbe, 0xc8, 0x1f, 0x69, 0x7a, 0x3b, 0x63, 0xc5, 0x0a, 0x72, 0x25, 0x9e,
+	0xe7, 0xdc, 0xf9, 0xf3, 0xbc, 0x9d, 0x89, 0x27, 0xfa, 0x03, 0x28, 0xe0, 0x54, 0xa9, 0x3a, 0x5e,
+	0x48, 0x1f, 0x89, 0x83, 0x25, 0x8c, 0xd1, 0x66, 0xb3, 0x0c, 0xc5, 0x88, 0xb2, 0x91, 0xc7, 0xcd,
+	0x5f, 0xb2, 0xb0, 0x80, 0x8c, 0xb3, 0x67, 0x0f, 0x27, 0xa4, 0xf6, 0x5a, 0x12, 0xd0, 0xde, 0x81,
+	0x04, 0xb2, 0xef, 0x48, 0x02, 0x8b, 0x50, 0x60, 0xdc, 0x8e, 0xb8, 0x7a, 0x00, 0xa4, 0x40, 0x74,
+	0xc8, 0x51, 0x7f, 0xa0, 0x38, 0x50, 0x2c, 0x27, 0x5c, 0x50, 0x78, 0x33, 0x17, 0x4c, 0x73, 0x71,
+	0xf1, 0x2d, 0xb8, 0x38, 0xb9, 0xe4, 0xa5, 0xa9, 0x4b, 0x6e, 0x46, 0x40, 0xa6, 0xeb, 0xa9, 0x26,
+	0x62, 0x11, 0x0a, 0x62, 0x02, 0xe5, 0xd3, 0x59, 0xb1, 0xa4, 0x40, 0x6a, 0x50, 0x56, 0xcd, 0x66,
+	0x46, 0x16, 0x81, 0x44, 0x9e, 0x64, 0x90, 0x7b, 0x63, 0x06, 0xe6, 0x9f, 0x59, 0x75, 0xe8, 0x7d,
+	0xdb, 0x1b, 0x4d, 0xba, 0x28, 0x02, 0x14, 0x5a, 0x75, 0x07, 0xa4, 0xf0, 0xfa, 0xde, 0x66, 0xdf,
+	0xa1, 0xb7, 0xb9, 0xf7, 0xd5, 0xdb, 0xfc, 0x19, 0xbd, 0x2d, 0x9c, 0xd1, 0xdb, 0xe2, 0xdb, 0xf5,
+	0xb6, 0xf4, 0x6f, 0x7a, 0x5b, 0x9e, 0xee, 0xed, 0x08, 0x2e, 0xa4, 0xca, 0xac, 0x9a, 0x7b, 0x11,
+	0x8a, 0xdf, 0xa2, 0x46, 0x75, 0x57, 0x49, 0xef, 0xad, 0xbd, 0x5f, 0x43, 0x75, 0xea, 0x16, 0x8b,
+	0x2f, 0x38, 0x36, 0x1e, 0xf6, 0x02, 0x2f, 0x3e, 0x2f, 0x16, 0xc9, 0xb5, 0x84, 0x77, 0xb2, 0x98,
+	0xeb, 0x52, 0x9c, 0x2b, 0x6e, 0xa4, 0x83, 0xd4, 0xc7, 0xa2, 0x32, 0x35, 0x1f, 0xc0, 0x6c, 0x0a,
+	0x9e, 0x62, 0x42, 0xe1, 0x7e, 0x36, 0xf9, 0x34, 0x6b, 0x42, 0x51, 0xbd, 0xbd, 0xd9, 0xf4, 0xf7,
+	0x92, 0x78, 0x8b, 0xf0, 0x0d, 0x8e, 0x3d, 0x4b, 0x33, 0xd3, 0x81, 0x4a, 0xf2, 0x8e, 0xe0, 0x6b,
+	0xa1, 0x9e, 0x9b, 0x01, 0x3d, 0x49, 0x5e, 0x0b, 0x89, 0x0f, 0xe8, 0x09, 0xf9, 0x0f, 0xcc, 0xf0,
+	0x80, 0xdb, 0x5e, 0x17, 0x75, 0x4c, 0x7d, 0x31, 0x55, 0x51, 0x87, 0x6e, 0xd8, 0x79, 0x1c, 0xbd,
+	0xfe, 0x0d, 0x54, 0x92, 0xaf, 0x3c, 0x52, 0x85, 0xd2, 0xc1, 0xde, 0xe7, 0x7b, 0x77, 0x0f, 0xf7,
+	0xf4, 0x0c, 0xa9, 0x40, 0xe1, 0xde, 0x41, 0xdb, 0xfa, 0x52, 0xd7, 0x48, 0x19, 0xf2, 0xd6, 0xc1,
+	0x9d, 0xb6, 0x9e, 0x15, 0x16, 0x9d, 0x9d, 0x9b, 0xed, 0xad, 0x0d, 0x4b, 0xcf, 0x09, 0x8b, 0xce,
+	0xfe, 0x5d, 0xab, 0xad, 0xe7, 0x85, 0xde, 0x6a, 0x6f, 0xb5, 0x77, 0xee, 0xb7, 0xf5, 0x82, 0xd0,
+	0xdf, 0x6c, 0x6f, 0x1e, 0xdc, 0xd6, 0x8b, 0xeb, 0x9b, 0x90, 0x17, 0x29, 0x92, 0x12, 0xe4, 0xac,
+	0x8d, 0x43, 0xe9, 0x75, 0xeb, 0xee, 0xc1, 0xde, 0xbe, 0xae, 0x09, 0x5d, 0xe7, 0x60, 0x57, 0xcf,
+	0x8a, 0xc5, 0xee, 0xce, 0x9e, 0x9e, 0xc3, 0xc5, 0xc6, 0x03, 0xe9, 0x0e, 0xad, 0xda, 0x96, 0x5e,
+	0x68, 0x7d, 0x97, 0x85, 0x02, 0xc6, 0x48, 0x3e, 0x82, 0x3c, 0x16, 0x24, 0x61, 0xe8, 0xa9, 0x8f,
+	0xee, 0xda, 0x62, 0x5a, 0xa9, 0x06, 0xec, 0x53, 0x28, 0xaa, 0xde, 0x2c, 0xa5, 0x69, 0x3d, 0xde,
+	0x76, 0xf1, 0xb4, 0x5a, 0x6e, 0xbc, 0xaa, 0x91, 0x2d, 0x80, 0x09, 0x1d, 0x91, 0xe5, 0xd4, 0xf0,
+	0x4f, 0x53, 0x7e, 0xad, 0x76, 0x16, 0xa4, 0xce, 0xbf, 0x05, 0xd5, 0xa9, 0xb9, 0x27, 0x69, 0xd3,
+	0x14, 0xe7, 0xd4, 0x2e, 0x9f, 0x89, 0x49, 0x3f, 0xad, 0x3d, 0x98, 0xc3, 0xbf, 0x39, 0x82, 0x4c,
+	0x64, 0x31, 0x6e, 0x40, 0xd5, 0xa2, 0xc3, 0x80, 0x53, 0xd4, 0x93, 0x24, 0xfd, 0xe9, 0x7f, 0x43,
+	0xb5, 0xa5, 0x53, 0x5a, 0xf5, 0xaf, 0x29, 0xb3, 0xf9, 0xbf, 0x67, 0x7f, 0xac, 0x64, 0x9e, 0xbd,
+	0x5c, 0xd1, 0x9e, 0xbf, 0x5c, 0xd1, 0x7e, 0x7f, 0xb9, 0xa2, 0xfd, 0xf0, 0x6a, 0x25, 0xf3, 0xfc,
+	0xd5, 0x4a, 0xe6, 0xd7, 0x57, 0x2b, 0x99, 0x87, 0x25, 0xf5, 0xc7, 0xad, 0x57, 0xc4, 0x4b, 0x75,
+	0xed, 0xef, 0x00, 0x00, 0x00, 0xff, 0xff, 0xa9, 0x01, 0x0a, 0xeb, 0x22, 0x0e, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1358,6 +1534,54 @@ func (m *SeriesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.ShardInfo != nil {
+		{
+			size, err := m.ShardInfo.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRpc(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x8a
+	}
+	if m.SelectHints != nil {
+		{
+			size, err := m.SelectHints.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRpc(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x82
+	}
+	if m.SplitAggregates {
+		i--
+		if m.SplitAggregates {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x78
+	}
+	if m.SeriesBatchSize != 0 {
+		i = encodeVarintRpc(dAtA, i, uint64(m.SeriesBatchSize))
+		i--
+		dAtA[i] = 0x70
+	}
+	if m.Limit != 0 {
+		i = encodeVarintRpc(dAtA, i, uint64(m.Limit))
+		i--
+		dAtA[i] = 0x68
+	}
 	if m.QueryHints != nil {
 		{
 			size, err := m.QueryHints.MarshalToSizedBuffer(dAtA[:i])
@@ -1418,20 +1642,20 @@ func (m *SeriesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		dAtA[i] = 0x30
 	}
 	if len(m.Aggregates) > 0 {
-		dAtA4 := make([]byte, len(m.Aggregates)*10)
-		var j3 int
+		dAtA6 := make([]byte, len(m.Aggregates)*10)
+		var j5 int
 		for _, num := range m.Aggregates {
 			for num >= 1<<7 {
-				dAtA4[j3] = uint8(uint64(num)&0x7f | 0x80)
+				dAtA6[j5] = uint8(uint64(num)&0x7f | 0x80)
 				num >>= 7
-				j3++
+				j5++
 			}
-			dAtA4[j3] = uint8(num)
-			j3++
+			dAtA6[j5] = uint8(num)
+			j5++
 		}
-		i -= j3
-		copy(dAtA[i:], dAtA4[:j3])
-		i = encodeVarintRpc(dAtA, i, uint64(j3))
+		i -= j5
+		copy(dAtA[i:], dAtA6[:j5])
+		i = encodeVarintRpc(dAtA, i, uint64(j5))
 		i--
 		dAtA[i] = 0x2a
 	}
@@ -1719,6 +1943,27 @@ func (m *SeriesResponse_Hints) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	}
 	return len(dAtA) - i, nil
 }
+func (m *SeriesResponse_Batch) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SeriesResponse_Batch) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.Batch != nil {
+		{
+			size, err := m.Batch.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRpc(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x22
+	}
+	return len(dAtA) - i, nil
+}
 func (m *LabelNamesRequest) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -1739,6 +1984,11 @@ func (m *LabelNamesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Limit != 0 {
+		i = encodeVarintRpc(dAtA, i, uint64(m.Limit))
+		i--
+		dAtA[i] = 0x38
+	}
 	if len(m.Matchers) > 0 {
 		for iNdEx := len(m.Matchers) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -1866,6 +2116,11 @@ func (m *LabelValuesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Limit != 0 {
+		i = encodeVarintRpc(dAtA, i, uint64(m.Limit))
+		i--
+		dAtA[i] = 0x40
+	}
 	if len(m.Matchers) > 0 {
 		for iNdEx := len(m.Matchers) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -1980,6 +2235,149 @@ func (m *LabelValuesResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *SeriesBatch) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SeriesBatch) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SeriesBatch) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Series) > 0 {
+		for iNdEx := len(m.Series) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Series[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintRpc(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Symbols) > 0 {
+		for iNdEx := len(m.Symbols) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Symbols[iNdEx])
+			copy(dAtA[i:], m.Symbols[iNdEx])
+			i = encodeVarintRpc(dAtA, i, uint64(len(m.Symbols[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *BatchedSeries) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *BatchedSeries) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *BatchedSeries) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Chunks) > 0 {
+		for iNdEx := len(m.Chunks) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Chunks[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintRpc(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Labels) > 0 {
+		dAtA18 := make([]byte, len(m.Labels)*10)
+		var j17 int
+		for _, num := range m.Labels {
+			for num >= 1<<7 {
+				dAtA18[j17] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j17++
+			}
+			dAtA18[j17] = uint8(num)
+			j17++
+		}
+		i -= j17
+		copy(dAtA[i:], dAtA18[:j17])
+		i = encodeVarintRpc(dAtA, i, uint64(j17))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *ShardInfo) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ShardInfo) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ShardInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Labels) > 0 {
+		for iNdEx := len(m.Labels) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Labels[iNdEx])
+			copy(dAtA[i:], m.Labels[iNdEx])
+			i = encodeVarintRpc(dAtA, i, uint64(len(m.Labels[iNdEx])))
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if m.TotalShards != 0 {
+		i = encodeVarintRpc(dAtA, i, uint64(m.TotalShards))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.ShardIndex != 0 {
+		i = encodeVarintRpc(dAtA, i, uint64(m.ShardIndex))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintRpc(dAtA []byte, offset int, v uint64) int {
 	offset -= sovRpc(v)
 	base := offset
@@ -2112,6 +2510,23 @@ func (m *SeriesRequest) Size() (n int) {
 		l = m.QueryHints.Size()
 		n += 1 + l + sovRpc(uint64(l))
 	}
+	if m.Limit != 0 {
+		n += 1 + sovRpc(uint64(m.Limit))
+	}
+	if m.SeriesBatchSize != 0 {
+		n += 1 + sovRpc(uint64(m.SeriesBatchSize))
+	}
+	if m.SplitAggregates {
+		n += 2
+	}
+	if m.SelectHints != nil {
+		l = m.SelectHints.Size()
+		n += 2 + l + sovRpc(uint64(l))
+	}
+	if m.ShardInfo != nil {
+		l = m.ShardInfo.Size()
+		n += 2 + l + sovRpc(uint64(l))
+	}
 	return n
 }
 
@@ -2228,6 +2643,18 @@ func (m *SeriesResponse_Hints) Size() (n int) {
 	}
 	return n
 }
+func (m *SeriesResponse_Batch) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Batch != nil {
+		l = m.Batch.Size()
+		n += 1 + l + sovRpc(uint64(l))
+	}
+	return n
+}
 func (m *LabelNamesRequest) Size() (n int) {
 	if m == nil {
 		return 0
@@ -2256,6 +2683,9 @@ func (m *LabelNamesRequest) Size() (n int) {
 			n += 1 + l + sovRpc(uint64(l))
 		}
 	}
+	if m.Limit != 0 {
+		n += 1 + sovRpc(uint64(m.Limit))
+	}
 	return n
 }
 
@@ -2316,6 +2746,9 @@ func (m *LabelValuesRequest) Size() (n int) {
 			n += 1 + l + sovRpc(uint64(l))
 		}
 	}
+	if m.Limit != 0 {
+		n += 1 + sovRpc(uint64(m.Limit))
+	}
 	return n
 }
 
@@ -2344,13 +2777,77 @@ func (m *LabelValuesResponse) Size() (n int) {
 	return n
 }
 
-func sovRpc(x uint64) (n int) {
-	return (math_bits.Len64(x|1) + 6) / 7
-}
-func sozRpc(x uint64) (n int) {
-	return sovRpc(uint64((x << 1) ^ uint64((int64(x) >> 63))))
-}
-func (m *WriteResponse) Unmarshal(dAtA []byte) error {
+func (m *SeriesBatch) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Symbols) > 0 {
+		for _, s := range m.Symbols {
+			l = len(s)
+			n += 1 + l + sovRpc(uint64(l))
+		}
+	}
+	if len(m.Series) > 0 {
+		for _, e := range m.Series {
+			l = e.Size()
+			n += 1 + l + sovRpc(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *BatchedSeries) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Labels) > 0 {
+		l = 0
+		for _, e := range m.Labels {
+			l += sovRpc(uint64(e))
+		}
+		n += 1 + sovRpc(uint64(l)) + l
+	}
+	if len(m.Chunks) > 0 {
+		for _, e := range m.Chunks {
+			l = e.Size()
+			n += 1 + l + sovRpc(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *ShardInfo) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.ShardIndex != 0 {
+		n += 1 + sovRpc(uint64(m.ShardIndex))
+	}
+	if m.TotalShards != 0 {
+		n += 1 + sovRpc(uint64(m.TotalShards))
+	}
+	if len(m.Labels) > 0 {
+		for _, s := range m.Labels {
+			l = len(s)
+			n += 1 + l + sovRpc(uint64(l))
+		}
+	}
+	return n
+}
+
+func sovRpc(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozRpc(x uint64) (n int) {
+	return sovRpc(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *WriteResponse) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3118,6 +3615,136 @@ func (m *SeriesRequest) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
+			}
+			m.Limit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Limit |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SeriesBatchSize", wireType)
+			}
+			m.SeriesBatchSize = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SeriesBatchSize |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 15:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SplitAggregates", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SplitAggregates = bool(v != 0)
+		case 16:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SelectHints", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.SelectHints == nil {
+				m.SelectHints = &QueryHints{}
+			}
+			if err := m.SelectHints.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 17:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ShardInfo", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.ShardInfo == nil {
+				m.ShardInfo = &ShardInfo{}
+			}
+			if err := m.ShardInfo.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -3700,6 +4327,41 @@ func (m *SeriesResponse) Unmarshal(dAtA []byte) error {
 			}
 			m.Result = &SeriesResponse_Hints{v}
 			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Batch", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &SeriesBatch{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Result = &SeriesResponse_Batch{v}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -3897,6 +4559,25 @@ func (m *LabelNamesRequest) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
+			}
+			m.Limit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Limit |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -4276,6 +4957,25 @@ func (m *LabelValuesRequest) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
+			}
+			m.Limit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Limit |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -4447,6 +5147,402 @@ func (m *LabelValuesResponse) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *SeriesBatch) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRpc
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SeriesBatch: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SeriesBatch: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Symbols", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Symbols = append(m.Symbols, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Series", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Series = append(m.Series, BatchedSeries{})
+			if err := m.Series[len(m.Series)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRpc(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *BatchedSeries) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRpc
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: BatchedSeries: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: BatchedSeries: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType == 0 {
+				var v uint32
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowRpc
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= uint32(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.Labels = append(m.Labels, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowRpc
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthRpc
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthRpc
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				var count int
+				for _, integer := range dAtA[iNdEx:postIndex] {
+					if integer < 128 {
+						count++
+					}
+				}
+				elementCount = count
+				if elementCount != 0 && len(m.Labels) == 0 {
+					m.Labels = make([]uint32, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint32
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowRpc
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= uint32(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Labels = append(m.Labels, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Chunks", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Chunks = append(m.Chunks, AggrChunk{})
+			if err := m.Chunks[len(m.Chunks)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRpc(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ShardInfo) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRpc
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ShardInfo: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ShardInfo: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ShardIndex", wireType)
+			}
+			m.ShardIndex = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ShardIndex |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TotalShards", wireType)
+			}
+			m.TotalShards = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TotalShards |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Labels = append(m.Labels, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRpc(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func skipRpc(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0
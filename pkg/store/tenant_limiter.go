@@ -0,0 +1,156 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alecthomas/units"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	promgate "github.com/prometheus/prometheus/util/gate"
+	"go.uber.org/atomic"
+
+	"github.com/thanos-io/thanos/pkg/tenancy"
+)
+
+// TenantLimiter limits the concurrency and the in-flight downloaded bytes of the Series() calls of each tenant, as
+// sent by queriers in the gRPC metadata of requests. The heavy queries of a tenant wait for the turn of the tenant
+// instead of taking up the slots of the gate of the whole store. Requests without tenant share the limits of the
+// anonymous tenant.
+type TenantLimiter struct {
+	maxConcurrent    int
+	maxInFlightBytes uint64
+
+	mtx     sync.Mutex
+	tenants map[string]*tenantLimits
+
+	inFlight      *prometheus.GaugeVec
+	inFlightBytes *prometheus.GaugeVec
+	rejected      prometheus.Counter
+}
+
+type tenantLimits struct {
+	// gate limits the concurrency of the tenant, if not nil.
+	gate  *promgate.Gate
+	bytes atomic.Uint64
+	// calls is the number of Series() calls of the tenant waiting for their turn or in flight. The limits of tenants
+	// without calls are removed.
+	calls int
+}
+
+// NewTenantLimiter returns a limiter of the Series() calls of tenants. 0 disables a limit.
+func NewTenantLimiter(reg prometheus.Registerer, maxConcurrent int, maxInFlightBytes units.Base2Bytes) *TenantLimiter {
+	return &TenantLimiter{
+		maxConcurrent:    maxConcurrent,
+		maxInFlightBytes: uint64(maxInFlightBytes),
+		tenants:          map[string]*tenantLimits{},
+		inFlight: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thanos_bucket_store_tenant_series_in_flight",
+			Help: "Number of Series calls of tenants that are currently in flight.",
+		}, []string{"tenant"}),
+		inFlightBytes: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thanos_bucket_store_tenant_in_flight_bytes",
+			Help: "Number of bytes downloaded from object storage by the Series calls of tenants that are currently in flight.",
+		}, []string{"tenant"}),
+		// The counter has no tenant label, as the series of tenants without calls in flight are deleted.
+		rejected: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_bucket_store_tenant_in_flight_bytes_exceeded_total",
+			Help: "Number of Series calls of tenants failed for exceeding the in-flight bytes limit of their tenant.",
+		}),
+	}
+}
+
+// Start waits for the turn of the tenant of the context and returns the limiter of the bytes downloaded by the Series()
+// call, which must be released with Done once the call is over.
+func (l *TenantLimiter) Start(ctx context.Context) (*TenantRequest, error) {
+	tenant, _ := tenancy.TenantFromIncomingContext(ctx)
+	if tenant == "" {
+		tenant = tenancy.AnonymousTenant
+	}
+
+	l.mtx.Lock()
+	t, ok := l.tenants[tenant]
+	if !ok {
+		t = &tenantLimits{}
+		if l.maxConcurrent > 0 {
+			t.gate = promgate.New(l.maxConcurrent)
+		}
+		l.tenants[tenant] = t
+	}
+	t.calls++
+	l.mtx.Unlock()
+
+	r := &TenantRequest{l: l, tenant: tenant, t: t}
+	if t.gate != nil {
+		if err := t.gate.Start(ctx); err != nil {
+			l.release(tenant, t)
+			return nil, err
+		}
+	}
+	l.inFlight.WithLabelValues(tenant).Inc()
+	return r, nil
+}
+
+func (l *TenantLimiter) release(tenant string, t *tenantLimits) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	t.calls--
+	if t.calls == 0 {
+		delete(l.tenants, tenant)
+		l.inFlight.DeleteLabelValues(tenant)
+		l.inFlightBytes.DeleteLabelValues(tenant)
+	}
+}
+
+// TenantRequest is a Series() call of a tenant started by a TenantLimiter.
+type TenantRequest struct {
+	l      *TenantLimiter
+	tenant string
+	t      *tenantLimits
+
+	reserved   atomic.Uint64
+	failedOnce sync.Once
+}
+
+// Reserve implements BytesLimiter. It fails if the bytes of all the in-flight calls of the tenant exceed the limit.
+func (r *TenantRequest) Reserve(num uint64) error {
+	r.reserved.Add(num)
+	bytes := r.t.bytes.Add(num)
+	r.l.inFlightBytes.WithLabelValues(r.tenant).Add(float64(num))
+
+	if r.l.maxInFlightBytes > 0 && bytes > r.l.maxInFlightBytes {
+		r.failedOnce.Do(r.l.rejected.Inc)
+		return errors.Wrapf(&LimitExceededError{Limit: r.l.maxInFlightBytes, Reserved: bytes}, "in-flight bytes of tenant %s", r.tenant)
+	}
+	return nil
+}
+
+// Done releases the turn and the bytes of the call.
+func (r *TenantRequest) Done() {
+	reserved := r.reserved.Load()
+	r.t.bytes.Sub(reserved)
+	r.l.inFlightBytes.WithLabelValues(r.tenant).Sub(float64(reserved))
+	r.l.inFlight.WithLabelValues(r.tenant).Dec()
+	if r.t.gate != nil {
+		r.t.gate.Done()
+	}
+	r.l.release(r.tenant, r.t)
+}
+
+// multiBytesLimiter reserves bytes from all its limiters.
+type multiBytesLimiter []BytesLimiter
+
+// Reserve implements BytesLimiter.
+func (m multiBytesLimiter) Reserve(num uint64) error {
+	for _, l := range m {
+		if err := l.Reserve(num); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestTenantLimiter(t *testing.T) {
+	l := NewTenantLimiter(prometheus.NewRegistry(), 1, 100)
+
+	tenantCtx := func(ctx context.Context, tenant string) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs("thanos-tenant", tenant))
+	}
+
+	a1, err := l.Start(tenantCtx(context.Background(), "a"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(l.inFlight.WithLabelValues("a")))
+
+	// Other tenants and requests without tenant have their own turns.
+	b, err := l.Start(tenantCtx(context.Background(), "b"))
+	testutil.Ok(t, err)
+	anonymous, err := l.Start(context.Background())
+	testutil.Ok(t, err)
+	testutil.Equals(t, "anonymous", anonymous.tenant)
+
+	// The calls of a tenant wait for the turn of the tenant.
+	ctx, cancel := context.WithTimeout(tenantCtx(context.Background(), "a"), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.Start(ctx)
+	testutil.NotOk(t, err)
+
+	started := make(chan *TenantRequest)
+	go func() {
+		a2, err := l.Start(tenantCtx(context.Background(), "a"))
+		testutil.Ok(t, err)
+		started <- a2
+	}()
+
+	// The in-flight bytes of a tenant are limited across its calls.
+	testutil.Ok(t, a1.Reserve(60))
+	testutil.Ok(t, b.Reserve(60))
+	testutil.Equals(t, 60.0, promtestutil.ToFloat64(l.inFlightBytes.WithLabelValues("a")))
+	a1.Done()
+
+	a2 := <-started
+	testutil.Ok(t, a2.Reserve(60))
+	err = a2.Reserve(60)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, "in-flight bytes of tenant a: limit 100 violated (got 120)", err.Error())
+	testutil.Equals(t, codes.ResourceExhausted, status.Code(errors.Cause(err)))
+	testutil.Equals(t, 1.0, promtestutil.ToFloat64(l.rejected))
+	a2.Done()
+	b.Done()
+	anonymous.Done()
+
+	// The limits and the metrics of tenants without calls are removed.
+	testutil.Equals(t, 0, len(l.tenants))
+	testutil.Equals(t, 0, promtestutil.CollectAndCount(l.inFlight))
+	testutil.Equals(t, 0, promtestutil.CollectAndCount(l.inFlightBytes))
+}
+
+func TestMultiBytesLimiter(t *testing.T) {
+	l1, l2 := NewLimiter(10, prometheus.NewCounter(prometheus.CounterOpts{})), NewLimiter(5, prometheus.NewCounter(prometheus.CounterOpts{}))
+	m := multiBytesLimiter{l1, l2}
+
+	testutil.Ok(t, m.Reserve(5))
+	testutil.NotOk(t, m.Reserve(1))
+	testutil.Equals(t, uint64(6), l1.reserved.Load())
+}
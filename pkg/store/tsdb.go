@@ -153,9 +153,23 @@ func (s *TSDBStore) Series(r *storepb.SeriesRequest, srv storepb.Store_SeriesSer
 	set := q.Select(false, nil, matchers...)
 
 	// Stream at most one series per frame; series may be split over multiple frames according to maxBytesInFrame.
+	var (
+		numSeries int64
+		shard     = r.ShardInfo.Matcher()
+	)
 	for set.Next() {
 		series := set.At()
-		storeSeries := storepb.Series{Labels: labelpb.ZLabelsFromPromLabels(labelpb.ExtendSortedLabels(series.Labels(), s.extLset))}
+		lset := labelpb.ExtendSortedLabels(series.Labels(), s.extLset)
+		if !shard.MatchesLabels(lset) {
+			continue
+		}
+
+		if r.Limit > 0 && numSeries >= r.Limit {
+			break
+		}
+		numSeries++
+
+		storeSeries := storepb.Series{Labels: labelpb.ZLabelsFromPromLabels(lset)}
 		if r.SkipChunks {
 			if err := srv.Send(storepb.NewSeriesResponse(&storeSeries)); err != nil {
 				return status.Error(codes.Aborted, err.Error())
@@ -250,7 +264,7 @@ func (s *TSDBStore) LabelNames(ctx context.Context, r *storepb.LabelNamesRequest
 		sort.Strings(res)
 	}
 
-	return &storepb.LabelNamesResponse{Names: res}, nil
+	return &storepb.LabelNamesResponse{Names: truncateToLimit(res, r.Limit)}, nil
 }
 
 // LabelValues returns all known label values for a given label name.
@@ -285,5 +299,5 @@ func (s *TSDBStore) LabelValues(ctx context.Context, r *storepb.LabelValuesReque
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	return &storepb.LabelValuesResponse{Values: res}, nil
+	return &storepb.LabelValuesResponse{Values: truncateToLimit(res, r.Limit)}, nil
 }
@@ -81,6 +81,8 @@ func TestTSDBStore_Series(t *testing.T) {
 		_, err = appender.Append(0, labels.FromStrings("a", "1"), int64(i), float64(i))
 		testutil.Ok(t, err)
 	}
+	_, err = appender.Append(0, labels.FromStrings("a", "2"), 1, 1)
+	testutil.Ok(t, err)
 	err = appender.Commit()
 	testutil.Ok(t, err)
 
@@ -171,6 +173,23 @@ func TestTSDBStore_Series(t *testing.T) {
 				},
 			},
 		},
+		{
+			title: "limit series",
+			req: &storepb.SeriesRequest{
+				MinTime: 1,
+				MaxTime: 3,
+				Matchers: []storepb.LabelMatcher{
+					{Type: storepb.LabelMatcher_RE, Name: "a", Value: "1|2"},
+				},
+				Limit: 1,
+			},
+			expectedSeries: []rawSeries{
+				{
+					lset:   labels.FromStrings("a", "1", "region", "eu-west"),
+					chunks: [][]sample{{{1, 1}, {2, 2}, {3, 3}}},
+				},
+			},
+		},
 	} {
 		if ok := t.Run(tc.title, func(t *testing.T) {
 			srv := newStoreSeriesServer(ctx)
@@ -188,6 +207,50 @@ func TestTSDBStore_Series(t *testing.T) {
 	}
 }
 
+func TestTSDBStore_Series_Shard(t *testing.T) {
+	defer testutil.TolerantVerifyLeak(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := e2eutil.NewTSDB()
+	defer func() { testutil.Ok(t, db.Close()) }()
+	testutil.Ok(t, err)
+
+	tsdbStore := NewTSDBStore(nil, db, component.Rule, labels.FromStrings("region", "eu-west"))
+
+	appender := db.Appender(context.Background())
+	for i := 0; i < 20; i++ {
+		_, err = appender.Append(0, labels.FromStrings("a", fmt.Sprintf("%d", i)), 1, 1)
+		testutil.Ok(t, err)
+	}
+	testutil.Ok(t, appender.Commit())
+
+	// Each series has to be returned by exactly one of the shards.
+	seen := map[string]int{}
+	for i := int64(0); i < 3; i++ {
+		shard := &storepb.ShardInfo{ShardIndex: i, TotalShards: 3, Labels: []string{"a"}}
+		srv := newStoreSeriesServer(ctx)
+		testutil.Ok(t, tsdbStore.Series(&storepb.SeriesRequest{
+			MinTime:    0,
+			MaxTime:    math.MaxInt64,
+			Matchers:   []storepb.LabelMatcher{{Type: storepb.LabelMatcher_RE, Name: "a", Value: ".+"}},
+			ShardInfo:  shard,
+			SkipChunks: true,
+		}, srv))
+		testutil.Assert(t, len(srv.SeriesSet) < 20, "shard %d returned all series", i)
+
+		for _, s := range srv.SeriesSet {
+			testutil.Assert(t, shard.Matcher().MatchesZLabels(s.Labels), "series %v is not in shard %d", s.Labels, i)
+			seen[labelpb.ZLabelsToPromLabels(s.Labels).String()]++
+		}
+	}
+	testutil.Equals(t, 20, len(seen))
+	for lset, n := range seen {
+		testutil.Equals(t, 1, n, "series %s", lset)
+	}
+}
+
 func TestTSDBStore_LabelAPIs(t *testing.T) {
 	t.Cleanup(func() { testutil.TolerantVerifyLeak(t) })
 	testLabelAPIs(t, func(extLset labels.Labels, appendFn func(app storage.Appender)) storepb.StoreServer {
@@ -56,11 +56,23 @@ func (rr *GRPCClient) Targets(ctx context.Context, req *targetspb.TargetsRequest
 		return nil, nil, errors.Wrap(err, "proxy Targets")
 	}
 
+	// Not all endpoints filter by state, e.g. Prometheus versions without the state parameter.
+	filterTargetsByState(resp.targets, req.State)
 	resp.targets = dedupTargets(resp.targets, rr.replicaLabels)
 
 	return resp.targets, resp.warnings, nil
 }
 
+// filterTargetsByState removes the targets which don't have the given state.
+func filterTargetsByState(targets *targetspb.TargetDiscovery, state targetspb.TargetsRequest_State) {
+	switch state {
+	case targetspb.TargetsRequest_ACTIVE:
+		targets.DroppedTargets = targets.DroppedTargets[:0]
+	case targetspb.TargetsRequest_DROPPED:
+		targets.ActiveTargets = targets.ActiveTargets[:0]
+	}
+}
+
 // dedupTargets re-sorts the set so that the same target with different replica
 // labels are coming right after each other.
 func dedupTargets(targets *targetspb.TargetDiscovery, replicaLabels map[string]struct{}) *targetspb.TargetDiscovery {
@@ -79,29 +91,23 @@ func dedupDroppedTargets(droppedTargets []*targetspb.DroppedTarget, replicaLabel
 		return droppedTargets
 	}
 
-	// Sort each target's label names such that they are comparable.
+	// Remove replica labels and sort each target's label names such that they are comparable. Replica labels have to
+	// be removed before sorting, otherwise the same target of different replicas might not end up next to each other.
 	for _, t := range droppedTargets {
+		t.DiscoveredLabels.Labels = removeReplicaLabels(t.DiscoveredLabels.Labels, replicaLabels)
 		sort.Slice(t.DiscoveredLabels.Labels, func(i, j int) bool {
 			return t.DiscoveredLabels.Labels[i].Name < t.DiscoveredLabels.Labels[j].Name
 		})
 	}
 
-	// Sort targets globally based on synthesized deduplication labels, also considering replica labels and their values.
+	// Sort targets globally based on synthesized deduplication labels.
 	sort.Slice(droppedTargets, func(i, j int) bool {
 		return droppedTargets[i].Compare(droppedTargets[j]) < 0
 	})
 
-	// Remove targets based on synthesized deduplication labels, this time ignoring replica labels
+	// Remove targets based on synthesized deduplication labels.
 	i := 0
-	droppedTargets[i].DiscoveredLabels.Labels = removeReplicaLabels(
-		droppedTargets[i].DiscoveredLabels.Labels,
-		replicaLabels,
-	)
 	for j := 1; j < len(droppedTargets); j++ {
-		droppedTargets[j].DiscoveredLabels.Labels = removeReplicaLabels(
-			droppedTargets[j].DiscoveredLabels.Labels,
-			replicaLabels,
-		)
 		if droppedTargets[i].Compare(droppedTargets[j]) != 0 {
 			// Effectively retain targets[j] in the resulting slice.
 			i++
@@ -118,38 +124,27 @@ func dedupActiveTargets(activeTargets []*targetspb.ActiveTarget, replicaLabels m
 		return activeTargets
 	}
 
-	// Sort each target's label names such that they are comparable.
+	// Remove replica labels and sort each target's label names such that they are comparable. Replica labels have to
+	// be removed before sorting, otherwise the same target of different replicas might not end up next to each other.
 	for _, t := range activeTargets {
+		t.DiscoveredLabels.Labels = removeReplicaLabels(t.DiscoveredLabels.Labels, replicaLabels)
 		sort.Slice(t.DiscoveredLabels.Labels, func(i, j int) bool {
 			return t.DiscoveredLabels.Labels[i].Name < t.DiscoveredLabels.Labels[j].Name
 		})
+		t.Labels.Labels = removeReplicaLabels(t.Labels.Labels, replicaLabels)
+		sort.Slice(t.Labels.Labels, func(i, j int) bool {
+			return t.Labels.Labels[i].Name < t.Labels.Labels[j].Name
+		})
 	}
 
-	// Sort targets globally based on synthesized deduplication labels, also considering replica labels and their values.
+	// Sort targets globally based on synthesized deduplication labels.
 	sort.Slice(activeTargets, func(i, j int) bool {
 		return activeTargets[i].Compare(activeTargets[j]) < 0
 	})
 
-	// Remove targets based on synthesized deduplication labels, this time ignoring replica labels and last scrape.
+	// Remove targets based on synthesized deduplication labels, this time ignoring last scrape.
 	i := 0
-	activeTargets[i].DiscoveredLabels.Labels = removeReplicaLabels(
-		activeTargets[i].DiscoveredLabels.Labels,
-		replicaLabels,
-	)
-	activeTargets[i].Labels.Labels = removeReplicaLabels(
-		activeTargets[i].Labels.Labels,
-		replicaLabels,
-	)
 	for j := 1; j < len(activeTargets); j++ {
-		activeTargets[j].DiscoveredLabels.Labels = removeReplicaLabels(
-			activeTargets[j].DiscoveredLabels.Labels,
-			replicaLabels,
-		)
-		activeTargets[j].Labels.Labels = removeReplicaLabels(
-			activeTargets[j].Labels.Labels,
-			replicaLabels,
-		)
-
 		if activeTargets[i].Compare(activeTargets[j]) != 0 {
 			// Effectively retain targets[j] in the resulting slice.
 			i++
@@ -268,6 +268,24 @@ func TestDedupTargets(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:          "dropped interleaved by replica label",
+			replicaLabels: []string{"replica"},
+			targets: &targetspb.TargetDiscovery{
+				DroppedTargets: []*targetspb.DroppedTarget{
+					{DiscoveredLabels: labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "job", Value: "a"}, {Name: "replica", Value: "0"}, {Name: "zone", Value: "1"}}}},
+					{DiscoveredLabels: labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "job", Value: "a"}, {Name: "replica", Value: "1"}, {Name: "zone", Value: "0"}}}},
+					{DiscoveredLabels: labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "job", Value: "a"}, {Name: "replica", Value: "0"}, {Name: "zone", Value: "0"}}}},
+					{DiscoveredLabels: labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "job", Value: "a"}, {Name: "replica", Value: "1"}, {Name: "zone", Value: "1"}}}},
+				},
+			},
+			want: &targetspb.TargetDiscovery{
+				DroppedTargets: []*targetspb.DroppedTarget{
+					{DiscoveredLabels: labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "job", Value: "a"}, {Name: "zone", Value: "0"}}}},
+					{DiscoveredLabels: labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "job", Value: "a"}, {Name: "zone", Value: "1"}}}},
+				},
+			},
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			replicaLabels := make(map[string]struct{})
@@ -278,3 +296,26 @@ func TestDedupTargets(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterTargetsByState(t *testing.T) {
+	newTargets := func() *targetspb.TargetDiscovery {
+		return &targetspb.TargetDiscovery{
+			ActiveTargets:  []*targetspb.ActiveTarget{{ScrapePool: "a"}},
+			DroppedTargets: []*targetspb.DroppedTarget{{}},
+		}
+	}
+
+	targets := newTargets()
+	filterTargetsByState(targets, targetspb.TargetsRequest_ANY)
+	testutil.Equals(t, newTargets(), targets)
+
+	targets = newTargets()
+	filterTargetsByState(targets, targetspb.TargetsRequest_ACTIVE)
+	testutil.Equals(t, 1, len(targets.ActiveTargets))
+	testutil.Equals(t, 0, len(targets.DroppedTargets))
+
+	targets = newTargets()
+	filterTargetsByState(targets, targetspb.TargetsRequest_DROPPED)
+	testutil.Equals(t, 0, len(targets.ActiveTargets))
+	testutil.Equals(t, 1, len(targets.DroppedTargets))
+}
@@ -0,0 +1,124 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tenancy
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// AnonymousTenant is the tenant of requests without tenant, the same as the one of the query frontend.
+	AnonymousTenant = "anonymous"
+
+	// grpcTenantKey is the key of the gRPC metadata with the tenant of the requests of queriers to stores.
+	grpcTenantKey = "thanos-tenant"
+)
+
+// Accounting counts the usage of resources by tenant, so that it can be charged back to them without scraping logs.
+// Every component only counts the resources it uses. A nil Accounting counts nothing.
+type Accounting struct {
+	queries         *prometheus.CounterVec
+	samplesScanned  *prometheus.CounterVec
+	bytesFetched    *prometheus.CounterVec
+	samplesIngested *prometheus.CounterVec
+}
+
+// NewAccounting returns an Accounting registering its metrics with the given registerer.
+func NewAccounting(reg prometheus.Registerer) *Accounting {
+	return &Accounting{
+		queries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_tenant_queries_total",
+			Help: "Total number of requests of the query, series and labels APIs by tenant.",
+		}, []string{"tenant"}),
+		samplesScanned: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_tenant_samples_scanned_total",
+			Help: "Total number of samples fetched from stores to evaluate the queries of tenants.",
+		}, []string{"tenant"}),
+		bytesFetched: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_tenant_bucket_fetched_bytes_total",
+			Help: "Total number of bytes of postings, series and chunks fetched from object storage for the Series requests of tenants.",
+		}, []string{"tenant"}),
+		samplesIngested: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_tenant_samples_ingested_total",
+			Help: "Total number of samples of the remote write requests of tenants which were ingested.",
+		}, []string{"tenant"}),
+	}
+}
+
+// Query counts a request of the query, series or labels APIs of the tenant.
+func (a *Accounting) Query(tenant string) {
+	if a == nil {
+		return
+	}
+	a.queries.WithLabelValues(tenantOrAnonymous(tenant)).Inc()
+}
+
+// SamplesScanned counts samples fetched from stores for a query of the tenant.
+func (a *Accounting) SamplesScanned(tenant string, samples int) {
+	if a == nil {
+		return
+	}
+	a.samplesScanned.WithLabelValues(tenantOrAnonymous(tenant)).Add(float64(samples))
+}
+
+// BytesFetched counts bytes fetched from object storage for a request of the tenant.
+func (a *Accounting) BytesFetched(tenant string, bytes int) {
+	if a == nil {
+		return
+	}
+	a.bytesFetched.WithLabelValues(tenantOrAnonymous(tenant)).Add(float64(bytes))
+}
+
+// SamplesIngested counts ingested samples of the tenant.
+func (a *Accounting) SamplesIngested(tenant string, samples int) {
+	if a == nil {
+		return
+	}
+	a.samplesIngested.WithLabelValues(tenantOrAnonymous(tenant)).Add(float64(samples))
+}
+
+func tenantOrAnonymous(tenant string) string {
+	if tenant == "" {
+		return AnonymousTenant
+	}
+	return tenant
+}
+
+// TenantFromIncomingContext returns the tenant of the context, or the one sent by the querier in the gRPC metadata of
+// the incoming request of the context.
+func TenantFromIncomingContext(ctx context.Context) (string, bool) {
+	if tenant, ok := TenantFromContext(ctx); ok {
+		return tenant, true
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	if v := md.Get(grpcTenantKey); len(v) > 0 && v[0] != "" {
+		return v[0], true
+	}
+	return "", false
+}
+
+// UnaryClientInterceptor sends the tenant of the context of gRPC requests in their metadata, if any.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+}
+
+// StreamClientInterceptor sends the tenant of the context of gRPC streams in their metadata, if any.
+func StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(outgoingContext(ctx), desc, cc, method, opts...)
+}
+
+func outgoingContext(ctx context.Context) context.Context {
+	if tenant, ok := TenantFromContext(ctx); ok && tenant != "" {
+		return metadata.AppendToOutgoingContext(ctx, grpcTenantKey, tenant)
+	}
+	return ctx
+}
@@ -0,0 +1,70 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tenancy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestAccounting(t *testing.T) {
+	// A nil Accounting counts nothing.
+	var nop *Accounting
+	nop.Query("a")
+	nop.SamplesScanned("a", 1)
+	nop.BytesFetched("a", 1)
+	nop.SamplesIngested("a", 1)
+
+	a := NewAccounting(prometheus.NewRegistry())
+	a.Query("a")
+	a.Query("")
+	a.SamplesScanned("a", 10)
+	a.SamplesScanned("a", 5)
+	a.BytesFetched("b", 1024)
+	a.SamplesIngested("c", 3)
+
+	testutil.Equals(t, 1.0, promtest.ToFloat64(a.queries.WithLabelValues("a")))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(a.queries.WithLabelValues(AnonymousTenant)))
+	testutil.Equals(t, 15.0, promtest.ToFloat64(a.samplesScanned.WithLabelValues("a")))
+	testutil.Equals(t, 1024.0, promtest.ToFloat64(a.bytesFetched.WithLabelValues("b")))
+	testutil.Equals(t, 3.0, promtest.ToFloat64(a.samplesIngested.WithLabelValues("c")))
+}
+
+func TestClientInterceptors(t *testing.T) {
+	var sent metadata.MD
+	invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil, nil
+	}
+
+	testutil.Ok(t, UnaryClientInterceptor(context.Background(), "/thanos.Store/Info", nil, nil, nil, invoker))
+	testutil.Equals(t, 0, len(sent.Get(grpcTenantKey)))
+
+	ctx := ContextWithTenant(context.Background(), "team-a")
+	testutil.Ok(t, UnaryClientInterceptor(ctx, "/thanos.Store/Info", nil, nil, nil, invoker))
+	testutil.Equals(t, []string{"team-a"}, sent.Get(grpcTenantKey))
+
+	_, err := StreamClientInterceptor(ctx, nil, nil, "/thanos.Store/Series", streamer)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"team-a"}, sent.Get(grpcTenantKey))
+
+	// The store reads the tenant from the incoming metadata.
+	tenant, ok := TenantFromIncomingContext(metadata.NewIncomingContext(context.Background(), sent))
+	testutil.Assert(t, ok, "expected tenant")
+	testutil.Equals(t, "team-a", tenant)
+
+	_, ok = TenantFromIncomingContext(context.Background())
+	testutil.Assert(t, !ok, "expected no tenant")
+}
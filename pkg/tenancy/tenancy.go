@@ -0,0 +1,203 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package tenancy implements the enforcement of tenancy in the querier: every request has to be made by a known
+// tenant and only sees the series of the tenant, which are selected by the tenant label.
+package tenancy
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+const (
+	// DefaultTenantHeader is the default header with the tenant of requests, the same as the one of receivers.
+	DefaultTenantHeader = "THANOS-TENANT"
+	// DefaultTenantLabel is the default label with the tenant of series, the same as the one of receivers.
+	DefaultTenantLabel = "tenant_id"
+
+	// unlistedTenant is the tenant label value of the metrics of requests of tenants if there is no allowlist, to
+	// bound the cardinality of the metrics by tenants chosen by clients.
+	unlistedTenant = "unlisted"
+)
+
+// CertificateField is the field of the subject of client certificates the tenant is derived from.
+type CertificateField string
+
+const (
+	CertificateFieldNone               CertificateField = ""
+	CertificateFieldOrganization       CertificateField = "organization"
+	CertificateFieldOrganizationalUnit CertificateField = "organizationalUnit"
+	CertificateFieldCommonName         CertificateField = "commonName"
+)
+
+// CertificateFields are the supported certificate fields.
+var CertificateFields = []string{
+	string(CertificateFieldNone),
+	string(CertificateFieldOrganization),
+	string(CertificateFieldOrganizationalUnit),
+	string(CertificateFieldCommonName),
+}
+
+var (
+	// ErrNoTenant is returned if the tenant of a request is unknown.
+	ErrNoTenant = errors.New("no tenant")
+	// ErrTenantNotAllowed is returned if the tenant of a request is not allowed.
+	ErrTenantNotAllowed = errors.New("tenant not allowed")
+)
+
+type ctxKey int
+
+const tenantKey = ctxKey(0)
+
+// ContextWithTenant returns the context with the tenant of the request.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// TenantFromContext returns the tenant of the request, if set.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey).(string)
+	return tenant, ok
+}
+
+// Enforcer determines the tenant of requests and enforces that they only select the series of it.
+type Enforcer struct {
+	logger    log.Logger
+	header    string
+	labelName string
+	certField CertificateField
+	allowed   map[string]struct{}
+
+	requests *prometheus.CounterVec
+}
+
+// NewEnforcer returns an Enforcer taking the tenant from the client certificate field, if configured, or otherwise
+// from the header of requests. If allowed is not empty, only the given tenants are allowed. The series of
+// tenants are selected by the label with the given name.
+func NewEnforcer(logger log.Logger, reg prometheus.Registerer, header, labelName string, certField CertificateField, allowed []string) *Enforcer {
+	e := &Enforcer{
+		logger:    logger,
+		header:    header,
+		labelName: labelName,
+		certField: certField,
+		requests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_query_tenant_requests_total",
+			Help: "Total number of API requests by tenant, handler and whether they were allowed.",
+		}, []string{"tenant", "handler", "allowed"}),
+	}
+	if len(allowed) > 0 {
+		e.allowed = make(map[string]struct{}, len(allowed))
+		for _, t := range allowed {
+			e.allowed[t] = struct{}{}
+		}
+	}
+	return e
+}
+
+// LabelName returns the name of the tenant label.
+func (e *Enforcer) LabelName() string {
+	return e.labelName
+}
+
+// Tenant returns the tenant of the request of the given handler. It returns ErrNoTenant or ErrTenantNotAllowed if the
+// request has no or no allowed tenant.
+func (e *Enforcer) Tenant(r *http.Request, handler string) (string, error) {
+	tenant := e.tenant(r)
+	if tenant == "" {
+		e.requests.WithLabelValues("", handler, "false").Inc()
+		level.Warn(e.logger).Log("msg", "rejecting request without tenant", "handler", handler, "remote_addr", r.RemoteAddr)
+		return "", ErrNoTenant
+	}
+	if _, ok := e.allowed[tenant]; e.allowed != nil && !ok {
+		// Unknown tenants are not used as label values to bound the cardinality.
+		e.requests.WithLabelValues("", handler, "false").Inc()
+		level.Warn(e.logger).Log("msg", "rejecting request of tenant not allowed", "tenant", tenant, "handler", handler, "remote_addr", r.RemoteAddr)
+		return "", errors.Wrapf(ErrTenantNotAllowed, "tenant %s", tenant)
+	}
+	if e.allowed != nil {
+		e.requests.WithLabelValues(tenant, handler, "true").Inc()
+	} else {
+		e.requests.WithLabelValues(unlistedTenant, handler, "true").Inc()
+	}
+	level.Debug(e.logger).Log("msg", "request of tenant", "tenant", tenant, "handler", handler, "path", r.URL.Path)
+	return tenant, nil
+}
+
+// tenant returns the tenant of the request. If the certificate field is configured, the tenant is only taken from
+// the client certificate, so that requests without it can't choose their tenant with the header.
+func (e *Enforcer) tenant(r *http.Request) string {
+	if e.certField == CertificateFieldNone {
+		return strings.TrimSpace(r.Header.Get(e.header))
+	}
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	subject := r.TLS.PeerCertificates[0].Subject
+	var values []string
+	switch e.certField {
+	case CertificateFieldOrganization:
+		values = subject.Organization
+	case CertificateFieldOrganizationalUnit:
+		values = subject.OrganizationalUnit
+	case CertificateFieldCommonName:
+		values = []string{subject.CommonName}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// EnforcedQueryable returns the queryable whose queriers only select the series with the tenant label equal to the
+// tenant of the context of the querier. Queriers of contexts without tenant fail.
+func EnforcedQueryable(q storage.Queryable, labelName string) storage.Queryable {
+	return storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		tenant, ok := TenantFromContext(ctx)
+		if !ok {
+			return nil, ErrNoTenant
+		}
+		querier, err := q.Querier(ctx, mint, maxt)
+		if err != nil {
+			return nil, err
+		}
+		return &enforcedQuerier{Querier: querier, matcher: labels.MustNewMatcher(labels.MatchEqual, labelName, tenant)}, nil
+	})
+}
+
+type enforcedQuerier struct {
+	storage.Querier
+	matcher *labels.Matcher
+}
+
+func (q *enforcedQuerier) Select(sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	return q.Querier.Select(sortSeries, hints, q.enforce(matchers)...)
+}
+
+func (q *enforcedQuerier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return q.Querier.LabelValues(name, q.enforce(matchers)...)
+}
+
+func (q *enforcedQuerier) LabelNames(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return q.Querier.LabelNames(q.enforce(matchers)...)
+}
+
+// enforce returns the matchers with the tenant matcher. Matchers of the tenant label are kept, so that selecting
+// another tenant selects nothing.
+func (q *enforcedQuerier) enforce(matchers []*labels.Matcher) []*labels.Matcher {
+	ms := make([]*labels.Matcher, 0, len(matchers)+1)
+	ms = append(ms, matchers...)
+	ms = append(ms, q.matcher)
+	sort.SliceStable(ms, func(i, j int) bool { return ms[i].Name < ms[j].Name })
+	return ms
+}
@@ -0,0 +1,120 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tenancy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestEnforcer_Tenant(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	e := NewEnforcer(log.NewNopLogger(), reg, DefaultTenantHeader, DefaultTenantLabel, CertificateFieldOrganization, []string{"a", "b"})
+
+	r := httptest.NewRequest("GET", "/api/v1/query", nil)
+	_, err := e.Tenant(r, "query")
+	testutil.Assert(t, errors.Is(err, ErrNoTenant), "expected no tenant error, got %v", err)
+
+	// The header is not used if the tenant is taken from the client certificate.
+	r.Header.Set(DefaultTenantHeader, "a")
+	_, err = e.Tenant(r, "query")
+	testutil.Assert(t, errors.Is(err, ErrNoTenant), "expected no tenant error, got %v", err)
+
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{Organization: []string{"c"}}}}}
+	_, err = e.Tenant(r, "query")
+	testutil.Assert(t, errors.Is(err, ErrTenantNotAllowed), "expected tenant not allowed error, got %v", err)
+
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{Organization: []string{"a"}}}}}
+	tenant, err := e.Tenant(r, "query")
+	testutil.Ok(t, err)
+	testutil.Equals(t, "a", tenant)
+
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{Organization: []string{"b"}}}}}
+	tenant, err = e.Tenant(r, "series")
+	testutil.Ok(t, err)
+	testutil.Equals(t, "b", tenant)
+
+	// Without the field in the certificate, the request is rejected instead of using the header.
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "b"}}}}
+	_, err = e.Tenant(r, "series")
+	testutil.Assert(t, errors.Is(err, ErrNoTenant), "expected no tenant error, got %v", err)
+
+	testutil.Equals(t, 3.0, promtest.ToFloat64(e.requests.WithLabelValues("", "query", "false")))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(e.requests.WithLabelValues("", "series", "false")))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(e.requests.WithLabelValues("a", "query", "true")))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(e.requests.WithLabelValues("b", "series", "true")))
+
+	// Without allowlist, all tenants are allowed, but not used as label values.
+	e = NewEnforcer(log.NewNopLogger(), prometheus.NewRegistry(), "X-Tenant", DefaultTenantLabel, CertificateFieldNone, nil)
+	r = httptest.NewRequest("GET", "/api/v1/query", nil)
+	r.Header.Set("X-Tenant", "c")
+	tenant, err = e.Tenant(r, "query")
+	testutil.Ok(t, err)
+	testutil.Equals(t, "c", tenant)
+	testutil.Equals(t, 1.0, promtest.ToFloat64(e.requests.WithLabelValues(unlistedTenant, "query", "true")))
+	testutil.Equals(t, 0.0, promtest.ToFloat64(e.requests.WithLabelValues("c", "query", "true")))
+}
+
+type testQuerier struct {
+	storage.Querier
+	matchers []*labels.Matcher
+}
+
+func (q *testQuerier) Select(_ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	q.matchers = matchers
+	return storage.EmptySeriesSet()
+}
+
+func (q *testQuerier) LabelValues(_ string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	q.matchers = matchers
+	return nil, nil, nil
+}
+
+func (q *testQuerier) LabelNames(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	q.matchers = matchers
+	return nil, nil, nil
+}
+
+func TestEnforcedQueryable(t *testing.T) {
+	tq := &testQuerier{}
+	q := EnforcedQueryable(storage.QueryableFunc(func(context.Context, int64, int64) (storage.Querier, error) {
+		return tq, nil
+	}), "tenant_id")
+
+	_, err := q.Querier(context.Background(), 0, 1)
+	testutil.Assert(t, errors.Is(err, ErrNoTenant), "expected no tenant error, got %v", err)
+
+	querier, err := q.Querier(ContextWithTenant(context.Background(), "a"), 0, 1)
+	testutil.Ok(t, err)
+
+	tenantMatcher := labels.MustNewMatcher(labels.MatchEqual, "tenant_id", "a")
+	jobMatcher := labels.MustNewMatcher(labels.MatchEqual, "job", "x")
+	nameMatcher := labels.MustNewMatcher(labels.MatchEqual, "__name__", "up")
+
+	querier.Select(false, nil, nameMatcher, jobMatcher)
+	testutil.Equals(t, []*labels.Matcher{nameMatcher, jobMatcher, tenantMatcher}, tq.matchers)
+
+	_, _, err = querier.LabelNames()
+	testutil.Ok(t, err)
+	testutil.Equals(t, []*labels.Matcher{tenantMatcher}, tq.matchers)
+
+	// Selecting another tenant keeps the enforced matcher, so nothing is selected.
+	otherMatcher := labels.MustNewMatcher(labels.MatchEqual, "tenant_id", "b")
+	_, _, err = querier.LabelValues("job", otherMatcher)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []*labels.Matcher{otherMatcher, tenantMatcher}, tq.matchers)
+}
@@ -15,10 +15,13 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-// NewServerConfig provides new server TLS configuration.
-func NewServerConfig(logger log.Logger, cert, key, clientCA string) (*tls.Config, error) {
+// NewServerConfig provides new server TLS configuration. The server certificate, key and client CA files are
+// reloaded when they change, so that rotated certificates are used without restarts.
+func NewServerConfig(logger log.Logger, reg prometheus.Registerer, cert, key, clientCA string) (*tls.Config, error) {
 	if key == "" && cert == "" {
 		if clientCA != "" {
 			return nil, errors.New("when a client CA is used a server key and certificate must also be provided")
@@ -34,44 +37,83 @@ func NewServerConfig(logger log.Logger, cert, key, clientCA string) (*tls.Config
 		return nil, errors.New("both server key and certificate must be provided")
 	}
 
-	tlsCfg := &tls.Config{
-		MinVersion: tls.VersionTLS12,
-	}
-
 	mngr := &serverTLSManager{
-		srvCertPath: cert,
-		srvKeyPath:  key,
+		logger:       logger,
+		srvCertPath:  cert,
+		srvKeyPath:   key,
+		clientCAPath: clientCA,
+		reloads: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_tls_server_reloads_total",
+			Help: "Total number of reloads of the server certificate and client CA files after they changed.",
+		}),
+		reloadFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_tls_server_reload_failures_total",
+			Help: "Total number of failed reloads of the server certificate and client CA files after they changed.",
+		}),
+		lastReloadSuccess: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "thanos_tls_server_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful reload of the server certificate or client CA files.",
+		}),
 	}
 
-	tlsCfg.GetCertificate = mngr.getCertificate
+	// The base configuration is returned for every handshake with the current client CAs.
+	mngr.base = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: mngr.getCertificate,
+	}
 
 	if clientCA != "" {
-		caPEM, err := ioutil.ReadFile(filepath.Clean(clientCA))
+		stat, err := os.Stat(clientCA)
 		if err != nil {
 			return nil, errors.Wrap(err, "reading client CA")
 		}
-
-		certPool := x509.NewCertPool()
-		if !certPool.AppendCertsFromPEM(caPEM) {
-			return nil, errors.Wrap(err, "building client CA")
+		certPool, err := loadCertPool(clientCA)
+		if err != nil {
+			return nil, err
 		}
-		tlsCfg.ClientCAs = certPool
-		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		mngr.clientCAs = certPool
+		mngr.clientCAModTime = stat.ModTime()
+		mngr.base.ClientCAs = certPool
+		mngr.base.ClientAuth = tls.RequireAndVerifyClientCert
 
 		level.Info(logger).Log("msg", "server TLS client verification enabled")
 	}
 
+	tlsCfg := mngr.base.Clone()
+	tlsCfg.GetConfigForClient = mngr.getConfigForClient
 	return tlsCfg, nil
 }
 
-type serverTLSManager struct {
-	srvCertPath string
-	srvKeyPath  string
+func loadCertPool(path string) (*x509.CertPool, error) {
+	caPEM, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading client CA")
+	}
+
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.Errorf("building client CA: no certificates found in %s", path)
+	}
+	return certPool, nil
+}
 
-	mtx            sync.Mutex
-	srvCert        *tls.Certificate
-	srvCertModTime time.Time
-	srvKeyModTime  time.Time
+type serverTLSManager struct {
+	logger       log.Logger
+	srvCertPath  string
+	srvKeyPath   string
+	clientCAPath string
+	base         *tls.Config
+
+	mtx             sync.Mutex
+	srvCert         *tls.Certificate
+	srvCertModTime  time.Time
+	srvKeyModTime   time.Time
+	clientCAs       *x509.CertPool
+	clientCAModTime time.Time
+
+	reloads           prometheus.Counter
+	reloadFailures    prometheus.Counter
+	lastReloadSuccess prometheus.Gauge
 }
 
 func (m *serverTLSManager) getCertificate(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
@@ -90,8 +132,21 @@ func (m *serverTLSManager) getCertificate(clientHello *tls.ClientHelloInfo) (*tl
 	if m.srvCert == nil || !statCert.ModTime().Equal(m.srvCertModTime) || !statKey.ModTime().Equal(m.srvKeyModTime) {
 		cert, err := tls.LoadX509KeyPair(m.srvCertPath, m.srvKeyPath)
 		if err != nil {
-			return nil, errors.Wrap(err, "server credentials")
+			if m.srvCert == nil {
+				return nil, errors.Wrap(err, "server credentials")
+			}
+			// The certificate and the key might not be updated both yet, keep the previous ones until they change again.
+			m.reloadFailures.Inc()
+			level.Warn(m.logger).Log("msg", "reloading server certificate failed, using the previous one until it changes again", "err", err)
+			m.srvCertModTime = statCert.ModTime()
+			m.srvKeyModTime = statKey.ModTime()
+			return m.srvCert, nil
+		}
+		if m.srvCert != nil {
+			m.reloads.Inc()
+			level.Info(m.logger).Log("msg", "reloaded server certificate", "cert", m.srvCertPath, "key", m.srvKeyPath)
 		}
+		m.lastReloadSuccess.SetToCurrentTime()
 		m.srvCertModTime = statCert.ModTime()
 		m.srvKeyModTime = statKey.ModTime()
 		m.srvCert = &cert
@@ -99,6 +154,38 @@ func (m *serverTLSManager) getCertificate(clientHello *tls.ClientHelloInfo) (*tl
 	return m.srvCert, nil
 }
 
+// getConfigForClient returns the configuration for the handshake with the client CAs reloaded if they changed.
+func (m *serverTLSManager) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	if m.clientCAPath == "" {
+		return nil, nil
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	stat, err := os.Stat(m.clientCAPath)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.ModTime().Equal(m.clientCAModTime) {
+		m.clientCAModTime = stat.ModTime()
+		certPool, err := loadCertPool(m.clientCAPath)
+		if err != nil {
+			m.reloadFailures.Inc()
+			level.Warn(m.logger).Log("msg", "reloading client CA failed, using the previous one until it changes again", "err", err)
+		} else {
+			m.reloads.Inc()
+			m.lastReloadSuccess.SetToCurrentTime()
+			level.Info(m.logger).Log("msg", "reloaded client CA", "ca", m.clientCAPath)
+			m.clientCAs = certPool
+		}
+	}
+
+	cfg := m.base.Clone()
+	cfg.ClientCAs = m.clientCAs
+	return cfg, nil
+}
+
 // NewClientConfig provides new client TLS configuration.
 func NewClientConfig(logger log.Logger, cert, key, caCert, serverName string, skipVerify bool) (*tls.Config, error) {
 	var certPool *x509.CertPool
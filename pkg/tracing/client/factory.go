@@ -18,6 +18,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/tracing/elasticapm"
 	"github.com/thanos-io/thanos/pkg/tracing/jaeger"
 	"github.com/thanos-io/thanos/pkg/tracing/lightstep"
+	"github.com/thanos-io/thanos/pkg/tracing/otlp"
 	"github.com/thanos-io/thanos/pkg/tracing/stackdriver"
 )
 
@@ -28,6 +29,7 @@ const (
 	JAEGER      TracingProvider = "JAEGER"
 	ELASTIC_APM TracingProvider = "ELASTIC_APM"
 	LIGHTSTEP   TracingProvider = "LIGHTSTEP"
+	OTLP        TracingProvider = "OTLP"
 )
 
 type TracingConfig struct {
@@ -61,6 +63,8 @@ func NewTracer(ctx context.Context, logger log.Logger, metrics *prometheus.Regis
 		return elasticapm.NewTracer(config)
 	case string(LIGHTSTEP):
 		return lightstep.NewTracer(ctx, config)
+	case string(OTLP):
+		return otlp.NewTracer(ctx, logger, metrics, config)
 	default:
 		return nil, nil, errors.Errorf("tracing with type %s is not supported", tracingConf.Type)
 	}
@@ -0,0 +1,123 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/thanos-io/thanos/pkg/runutil"
+	"github.com/thanos-io/thanos/pkg/tls"
+	"github.com/thanos-io/thanos/pkg/tracing/otlp/otlppb"
+)
+
+// maxResponseSize is the maximum size of responses of HTTP collectors read for errors.
+const maxResponseSize = 64 << 10
+
+// exporter sends spans to a collector.
+type exporter interface {
+	export(ctx context.Context, req *otlppb.ExportTraceServiceRequest) error
+	close() error
+}
+
+func newExporter(logger log.Logger, c Config) (exporter, error) {
+	if c.Protocol == ProtocolHTTP {
+		return newHTTPExporter(logger, c)
+	}
+	return newGRPCExporter(logger, c)
+}
+
+type grpcExporter struct {
+	conn    *grpc.ClientConn
+	client  otlppb.TraceServiceClient
+	headers metadata.MD
+}
+
+func newGRPCExporter(logger log.Logger, c Config) (*grpcExporter, error) {
+	var opts []grpc.DialOption
+	if c.Insecure {
+		opts = append(opts, grpc.WithInsecure())
+	} else {
+		tlsCfg, err := tls.NewClientConfig(logger, c.TLSConfig.CertFile, c.TLSConfig.KeyFile, c.TLSConfig.CAFile, c.TLSConfig.ServerName, c.TLSConfig.InsecureSkipVerify)
+		if err != nil {
+			return nil, errors.Wrap(err, "create TLS config of OTLP exporter")
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	}
+	// The connection is established in the background and retried on failures.
+	conn, err := grpc.Dial(c.Endpoint, opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dial OTLP collector %s", c.Endpoint)
+	}
+	return &grpcExporter{conn: conn, client: otlppb.NewTraceServiceClient(conn), headers: metadata.New(c.Headers)}, nil
+}
+
+func (e *grpcExporter) export(ctx context.Context, req *otlppb.ExportTraceServiceRequest) error {
+	if len(e.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, e.headers)
+	}
+	_, err := e.client.Export(ctx, req)
+	return err
+}
+
+func (e *grpcExporter) close() error {
+	return e.conn.Close()
+}
+
+type httpExporter struct {
+	client   *http.Client
+	endpoint string
+	headers  map[string]string
+}
+
+func newHTTPExporter(logger log.Logger, c Config) (*httpExporter, error) {
+	tlsCfg, err := tls.NewClientConfig(logger, c.TLSConfig.CertFile, c.TLSConfig.KeyFile, c.TLSConfig.CAFile, c.TLSConfig.ServerName, c.TLSConfig.InsecureSkipVerify)
+	if err != nil {
+		return nil, errors.Wrap(err, "create TLS config of OTLP exporter")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	return &httpExporter{client: &http.Client{Transport: transport}, endpoint: c.Endpoint, headers: c.Headers}, nil
+}
+
+func (e *httpExporter) export(ctx context.Context, req *otlppb.ExportTraceServiceRequest) (err error) {
+	body, err := req.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "marshal export request")
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range e.headers {
+		r.Header.Set(k, v)
+	}
+	r.Header.Set("Content-Type", "application/x-protobuf")
+
+	resp, err := e.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer runutil.ExhaustCloseWithErrCapture(&err, resp.Body, "OTLP collector response body")
+
+	if resp.StatusCode/100 != 2 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+		return errors.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	return nil
+}
+
+func (e *httpExporter) close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package otlp implements a tracer exporting spans with the OpenTelemetry protocol (OTLP) over gRPC or HTTP, e.g. to
+// an OpenTelemetry collector. Spans are recorded and sampled by the Jaeger client with 128 bit trace IDs, and
+// converted to OTLP when they are finished.
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/uber/jaeger-client-go"
+	"github.com/uber/jaeger-client-go/config"
+	jaeger_prometheus "github.com/uber/jaeger-lib/metrics/prometheus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/httpconfig"
+	"github.com/thanos-io/thanos/pkg/tracing"
+)
+
+const (
+	// ProtocolGRPC exports spans with the TraceService of OTLP over gRPC.
+	ProtocolGRPC = "grpc"
+	// ProtocolHTTP exports spans as binary protobuf over HTTP.
+	ProtocolHTTP = "http"
+
+	defaultGRPCEndpoint = "localhost:4317"
+	defaultHTTPEndpoint = "http://localhost:4318/v1/traces"
+)
+
+// Config - YAML configuration.
+type Config struct {
+	ServiceName string `yaml:"service_name"`
+	// Protocol is either grpc or http.
+	Protocol string `yaml:"protocol"`
+	// Endpoint is the host:port of the gRPC collector, or the URL of the HTTP collector, by default localhost:4317 or
+	// http://localhost:4318/v1/traces.
+	Endpoint string `yaml:"endpoint"`
+	// Insecure disables TLS for gRPC collectors. HTTP collectors use TLS with https URLs.
+	Insecure bool `yaml:"insecure"`
+	// Headers are added to every export request, e.g. for authentication.
+	Headers   map[string]string    `yaml:"headers"`
+	TLSConfig httpconfig.TLSConfig `yaml:"tls_config"`
+	// Timeout is the timeout of export requests.
+	Timeout model.Duration `yaml:"timeout"`
+	// ResourceAttributes are added to the resource of the spans, besides service.name and host.name.
+	ResourceAttributes map[string]string `yaml:"resource_attributes"`
+	// SamplerType is one of const, probabilistic and ratelimiting, with the semantics of the Jaeger client.
+	SamplerType  string  `yaml:"sampler_type"`
+	SamplerParam float64 `yaml:"sampler_param"`
+	// MaxQueueSize is the maximum number of spans waiting to be exported, further spans are dropped.
+	MaxQueueSize int `yaml:"max_queue_size"`
+	// MaxExportBatchSize is the maximum number of spans of an export request.
+	MaxExportBatchSize int `yaml:"max_export_batch_size"`
+	// BatchTimeout is the maximum time spans wait until they are exported.
+	BatchTimeout model.Duration `yaml:"batch_timeout"`
+}
+
+// DefaultConfig is the default configuration, which samples all traces.
+var DefaultConfig = Config{
+	ServiceName:        "thanos",
+	Protocol:           ProtocolGRPC,
+	Timeout:            model.Duration(10 * time.Second),
+	SamplerType:        jaeger.SamplerTypeConst,
+	SamplerParam:       1,
+	MaxQueueSize:       2048,
+	MaxExportBatchSize: 512,
+	BatchTimeout:       model.Duration(5 * time.Second),
+}
+
+// parseConfig parses the YAML configuration and applies the defaults.
+func parseConfig(conf []byte) (Config, error) {
+	c := DefaultConfig
+	if err := yaml.UnmarshalStrict(conf, &c); err != nil {
+		return Config{}, errors.Wrap(err, "parsing OTLP tracing config YAML")
+	}
+	switch c.Protocol {
+	case ProtocolGRPC:
+		if c.Endpoint == "" {
+			c.Endpoint = defaultGRPCEndpoint
+		}
+	case ProtocolHTTP:
+		if c.Endpoint == "" {
+			c.Endpoint = defaultHTTPEndpoint
+		}
+		if !strings.HasPrefix(c.Endpoint, "http://") && !strings.HasPrefix(c.Endpoint, "https://") {
+			return Config{}, errors.Errorf("endpoint %q of HTTP protocol has to be an http or https URL", c.Endpoint)
+		}
+	default:
+		return Config{}, errors.Errorf("unsupported protocol %q, expected %s or %s", c.Protocol, ProtocolGRPC, ProtocolHTTP)
+	}
+	if c.Timeout <= 0 || c.BatchTimeout <= 0 {
+		return Config{}, errors.New("timeout and batch_timeout have to be positive")
+	}
+	if c.MaxQueueSize <= 0 || c.MaxExportBatchSize <= 0 {
+		return Config{}, errors.New("max_queue_size and max_export_batch_size have to be positive")
+	}
+	return c, nil
+}
+
+func newSampler(c Config) (jaeger.Sampler, error) {
+	switch c.SamplerType {
+	case jaeger.SamplerTypeConst:
+		return jaeger.NewConstSampler(c.SamplerParam != 0), nil
+	case jaeger.SamplerTypeProbabilistic:
+		return jaeger.NewProbabilisticSampler(c.SamplerParam)
+	case jaeger.SamplerTypeRateLimiting:
+		return jaeger.NewRateLimitingSampler(c.SamplerParam), nil
+	default:
+		return nil, errors.Errorf("unsupported sampler type %q", c.SamplerType)
+	}
+}
+
+// Tracer extends opentracing.Tracer.
+type Tracer struct {
+	opentracing.Tracer
+}
+
+// GetTraceIDFromSpanContext return TraceID from span.Context, in the hex format of OpenTelemetry.
+func (t *Tracer) GetTraceIDFromSpanContext(ctx opentracing.SpanContext) (string, bool) {
+	if c, ok := ctx.(jaeger.SpanContext); ok {
+		return fmt.Sprintf("%016x%016x", c.TraceID().High, c.TraceID().Low), true
+	}
+	return "", false
+}
+
+// NewTracer creates a tracer exporting spans with OTLP from YAML.
+func NewTracer(ctx context.Context, logger log.Logger, metrics *prometheus.Registry, conf []byte) (opentracing.Tracer, io.Closer, error) {
+	c, err := parseConfig(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	sampler, err := newSampler(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	exp, err := newExporter(logger, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	level.Info(logger).Log("msg", "exporting traces with OTLP", "protocol", c.Protocol, "endpoint", c.Endpoint)
+
+	r := newReporter(logger, metrics, exp, resource(c), c)
+	cfg := config.Configuration{
+		ServiceName: c.ServiceName,
+		Gen128Bit:   true,
+		Headers: &jaeger.HeadersConfig{
+			JaegerDebugHeader: strings.ToLower(tracing.ForceTracingBaggageKey),
+		},
+	}
+	cfg.Headers.ApplyDefaults()
+	jaegerTracer, closer, err := cfg.NewTracer(
+		config.Reporter(r),
+		config.Sampler(sampler),
+		config.Metrics(jaeger_prometheus.New(jaeger_prometheus.WithRegisterer(metrics))),
+		config.Logger(&jaegerLogger{logger: logger}),
+	)
+	if err != nil {
+		r.Close()
+		return nil, nil, err
+	}
+	return &Tracer{jaegerTracer}, closer, nil
+}
+
+// resource returns the attributes of the resource of all spans.
+func resource(c Config) map[string]string {
+	attrs := map[string]string{"service.name": c.ServiceName}
+	if hostname, err := os.Hostname(); err == nil {
+		attrs["host.name"] = hostname
+	}
+	for k, v := range c.ResourceAttributes {
+		attrs[k] = v
+	}
+	return attrs
+}
@@ -0,0 +1,197 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package otlp
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/uber/jaeger-client-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/thanos-io/thanos/pkg/testutil"
+	"github.com/thanos-io/thanos/pkg/tracing"
+	"github.com/thanos-io/thanos/pkg/tracing/otlp/otlppb"
+)
+
+type testCollector struct {
+	reqs    chan *otlppb.ExportTraceServiceRequest
+	headers chan string
+}
+
+func newTestCollector() *testCollector {
+	return &testCollector{reqs: make(chan *otlppb.ExportTraceServiceRequest, 10), headers: make(chan string, 10)}
+}
+
+func (c *testCollector) Export(ctx context.Context, req *otlppb.ExportTraceServiceRequest) (*otlppb.ExportTraceServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.headers <- fmt.Sprint(md.Get("authorization"))
+	c.reqs <- req
+	return &otlppb.ExportTraceServiceResponse{}, nil
+}
+
+func (c *testCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil || r.Header.Get("Content-Type") != "application/x-protobuf" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	req := &otlppb.ExportTraceServiceRequest{}
+	if err := req.Unmarshal(b); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.headers <- fmt.Sprint([]string{r.Header.Get("Authorization")})
+	c.reqs <- req
+}
+
+func TestParseConfig(t *testing.T) {
+	c, err := parseConfig([]byte("protocol: http\n"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, defaultHTTPEndpoint, c.Endpoint)
+	testutil.Equals(t, 512, c.MaxExportBatchSize)
+
+	c, err = parseConfig(nil)
+	testutil.Ok(t, err)
+	testutil.Equals(t, defaultGRPCEndpoint, c.Endpoint)
+
+	for _, conf := range []string{
+		"protocol: thrift\n",
+		"protocol: http\nendpoint: localhost:4318\n",
+		"max_queue_size: 0\n",
+		"sampler: const\n",
+	} {
+		_, err := parseConfig([]byte(conf))
+		testutil.NotOk(t, err, "config %q", conf)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	collector := newTestCollector()
+
+	l, err := net.Listen("tcp", "localhost:0")
+	testutil.Ok(t, err)
+	srv := grpc.NewServer()
+	otlppb.RegisterTraceServiceServer(srv, collector)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	httpSrv := httptest.NewServer(collector)
+	defer httpSrv.Close()
+
+	for _, conf := range []string{
+		fmt.Sprintf("protocol: grpc\nendpoint: %s\ninsecure: true\n", l.Addr()),
+		fmt.Sprintf("protocol: http\nendpoint: %s/v1/traces\n", httpSrv.URL),
+	} {
+		t.Run(conf, func(t *testing.T) {
+			conf += "service_name: querier\nheaders:\n  authorization: Bearer secret\nresource_attributes:\n  cluster: eu-1\n"
+			tr, closer, err := NewTracer(context.Background(), log.NewNopLogger(), prometheus.NewRegistry(), []byte(conf))
+			testutil.Ok(t, err)
+
+			parent := tr.StartSpan("query")
+			ext.SpanKindRPCServer.Set(parent)
+			child := tr.StartSpan("select", opentracing.ChildOf(parent.Context()))
+			child.SetTag("series", 10)
+			ext.Error.Set(child, true)
+			child.LogKV("event", "error", "message", "oops")
+			child.Finish()
+			parent.Finish()
+
+			traceID, ok := tr.(tracing.Tracer).GetTraceIDFromSpanContext(parent.Context())
+			testutil.Assert(t, ok, "expected trace ID")
+			testutil.Equals(t, 32, len(traceID))
+
+			// Spans are exported on close.
+			testutil.Ok(t, closer.Close())
+
+			var spans []*otlppb.Span
+			for len(spans) < 2 {
+				select {
+				case req := <-collector.reqs:
+					testutil.Equals(t, 1, len(req.ResourceSpans))
+					rs := req.ResourceSpans[0]
+					testutil.Assert(t, hasAttribute(rs.Resource.Attributes, "service.name", "querier"), "service name missing from %v", rs.Resource.Attributes)
+					testutil.Assert(t, hasAttribute(rs.Resource.Attributes, "cluster", "eu-1"), "resource attribute missing from %v", rs.Resource.Attributes)
+					testutil.Equals(t, "[Bearer secret]", <-collector.headers)
+					for _, ss := range rs.ScopeSpans {
+						spans = append(spans, ss.Spans...)
+					}
+				case <-time.After(10 * time.Second):
+					t.Fatal("spans not exported")
+				}
+			}
+			testutil.Equals(t, 2, len(spans))
+
+			s, p := spans[0], spans[1]
+			testutil.Equals(t, "select", s.Name)
+			testutil.Equals(t, "query", p.Name)
+			testutil.Equals(t, traceID, hex.EncodeToString(s.TraceId))
+			testutil.Equals(t, traceID, hex.EncodeToString(p.TraceId))
+			testutil.Equals(t, p.SpanId, s.ParentSpanId)
+			testutil.Equals(t, 0, len(p.ParentSpanId))
+
+			testutil.Equals(t, int32(spanKindServer), p.Kind)
+			testutil.Equals(t, int32(spanKindInternal), s.Kind)
+			testutil.Equals(t, otlppb.Status{Code: statusCodeError, Message: "oops"}, s.Status)
+			testutil.Assert(t, hasAttribute(s.Attributes, "series", int64(10)), "attribute missing from %v", s.Attributes)
+			testutil.Equals(t, 1, len(s.Events))
+			testutil.Equals(t, "error", s.Events[0].Name)
+			testutil.Assert(t, s.EndTimeUnixNano >= s.StartTimeUnixNano, "end before start")
+		})
+	}
+}
+
+func TestReporter_DropsSpansIfQueueIsFull(t *testing.T) {
+	c, err := parseConfig([]byte("protocol: http\nendpoint: http://localhost:1/v1/traces\nmax_queue_size: 2\nbatch_timeout: 1h\n"))
+	testutil.Ok(t, err)
+	exp, err := newExporter(log.NewNopLogger(), c)
+	testutil.Ok(t, err)
+	r := newReporter(log.NewNopLogger(), prometheus.NewRegistry(), exp, nil, c)
+
+	tr, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+	defer closer.Close()
+	for i := 0; i < 3; i++ {
+		sp := tr.StartSpan("span")
+		sp.Finish()
+		r.Report(sp.(*jaeger.Span))
+	}
+	testutil.Equals(t, 1.0, promtest.ToFloat64(r.dropped))
+
+	// The queued spans fail to export on close.
+	r.Close()
+	testutil.Equals(t, 3.0, promtest.ToFloat64(r.dropped))
+	testutil.Equals(t, 1.0, promtest.ToFloat64(r.exportFailures))
+}
+
+func hasAttribute(attrs []otlppb.KeyValue, key string, value interface{}) bool {
+	for _, a := range attrs {
+		if a.Key != key {
+			continue
+		}
+		switch v := a.Value.Value.(type) {
+		case *otlppb.AnyValue_StringValue:
+			return v.StringValue == value
+		case *otlppb.AnyValue_IntValue:
+			return v.IntValue == value
+		case *otlppb.AnyValue_BoolValue:
+			return v.BoolValue == value
+		case *otlppb.AnyValue_DoubleValue:
+			return v.DoubleValue == value
+		}
+	}
+	return false
+}
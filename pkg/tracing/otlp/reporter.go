@@ -0,0 +1,291 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package otlp
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/version"
+	"github.com/uber/jaeger-client-go"
+
+	"github.com/thanos-io/thanos/pkg/tracing/otlp/otlppb"
+)
+
+// Kinds of spans and codes of statuses of OTLP.
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+	spanKindProducer = 4
+	spanKindConsumer = 5
+
+	statusCodeError = 2
+)
+
+// reporter is a jaeger.Reporter converting finished spans to OTLP, and exporting them in batches in the background.
+// Spans are dropped if the queue is full, e.g. if the collector is unavailable.
+type reporter struct {
+	logger        log.Logger
+	exporter      exporter
+	resource      otlppb.Resource
+	timeout       time.Duration
+	maxQueueSize  int
+	maxBatchSize  int
+	flushInterval time.Duration
+
+	mtx   sync.Mutex
+	queue []*otlppb.Span
+
+	flushc    chan struct{}
+	stopc     chan struct{}
+	donec     chan struct{}
+	closeOnce sync.Once
+
+	exported       prometheus.Counter
+	dropped        prometheus.Counter
+	exportFailures prometheus.Counter
+}
+
+func newReporter(logger log.Logger, reg prometheus.Registerer, exp exporter, resourceAttrs map[string]string, c Config) *reporter {
+	r := &reporter{
+		logger:        logger,
+		exporter:      exp,
+		resource:      otlppb.Resource{Attributes: stringAttributes(resourceAttrs)},
+		timeout:       time.Duration(c.Timeout),
+		maxQueueSize:  c.MaxQueueSize,
+		maxBatchSize:  c.MaxExportBatchSize,
+		flushInterval: time.Duration(c.BatchTimeout),
+		flushc:        make(chan struct{}, 1),
+		stopc:         make(chan struct{}),
+		donec:         make(chan struct{}),
+		exported: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_tracing_otlp_exported_spans_total",
+			Help: "Total number of spans exported with OTLP.",
+		}),
+		dropped: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_tracing_otlp_dropped_spans_total",
+			Help: "Total number of spans dropped because the queue was full or the export failed.",
+		}),
+		exportFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "thanos_tracing_otlp_export_failures_total",
+			Help: "Total number of failed OTLP export requests.",
+		}),
+	}
+	go r.run()
+	return r
+}
+
+// Report implements jaeger.Reporter.
+func (r *reporter) Report(span *jaeger.Span) {
+	s := spanToOTLP(span)
+
+	r.mtx.Lock()
+	if len(r.queue) >= r.maxQueueSize {
+		r.mtx.Unlock()
+		r.dropped.Inc()
+		return
+	}
+	r.queue = append(r.queue, s)
+	full := len(r.queue) >= r.maxBatchSize
+	r.mtx.Unlock()
+
+	if full {
+		select {
+		case r.flushc <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close implements jaeger.Reporter. It exports the queued spans.
+func (r *reporter) Close() {
+	r.closeOnce.Do(func() {
+		close(r.stopc)
+		<-r.donec
+		if err := r.exporter.close(); err != nil {
+			level.Warn(r.logger).Log("msg", "closing OTLP exporter failed", "err", err)
+		}
+	})
+}
+
+func (r *reporter) run() {
+	defer close(r.donec)
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stopc:
+			r.flush()
+			return
+		case <-ticker.C:
+		case <-r.flushc:
+		}
+		r.flush()
+	}
+}
+
+// flush exports all queued spans in batches.
+func (r *reporter) flush() {
+	r.mtx.Lock()
+	spans := r.queue
+	r.queue = nil
+	r.mtx.Unlock()
+
+	for len(spans) > 0 {
+		n := r.maxBatchSize
+		if n > len(spans) {
+			n = len(spans)
+		}
+		r.export(spans[:n])
+		spans = spans[n:]
+	}
+}
+
+func (r *reporter) export(spans []*otlppb.Span) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	req := &otlppb.ExportTraceServiceRequest{
+		ResourceSpans: []*otlppb.ResourceSpans{{
+			Resource: r.resource,
+			ScopeSpans: []*otlppb.ScopeSpans{{
+				Scope: otlppb.InstrumentationScope{Name: "thanos", Version: version.Version},
+				Spans: spans,
+			}},
+		}},
+	}
+	if err := r.exporter.export(ctx, req); err != nil {
+		level.Warn(r.logger).Log("msg", "exporting spans with OTLP failed", "spans", len(spans), "err", err)
+		r.exportFailures.Inc()
+		r.dropped.Add(float64(len(spans)))
+		return
+	}
+	r.exported.Add(float64(len(spans)))
+}
+
+// spanToOTLP converts a finished span of the Jaeger client to OTLP. The span.kind and error tags are converted to the
+// kind and status of the span, logs are converted to events.
+func spanToOTLP(span *jaeger.Span) *otlppb.Span {
+	ctx := span.SpanContext()
+	traceID := make([]byte, 16)
+	binary.BigEndian.PutUint64(traceID[:8], ctx.TraceID().High)
+	binary.BigEndian.PutUint64(traceID[8:], ctx.TraceID().Low)
+	spanID := make([]byte, 8)
+	binary.BigEndian.PutUint64(spanID, uint64(ctx.SpanID()))
+
+	s := &otlppb.Span{
+		TraceId:           traceID,
+		SpanId:            spanID,
+		Name:              span.OperationName(),
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: uint64(span.StartTime().UnixNano()),
+		EndTimeUnixNano:   uint64(span.StartTime().Add(span.Duration()).UnixNano()),
+	}
+	if ctx.ParentID() != 0 {
+		s.ParentSpanId = make([]byte, 8)
+		binary.BigEndian.PutUint64(s.ParentSpanId, uint64(ctx.ParentID()))
+	}
+
+	tags := span.Tags()
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := tags[k]
+		switch k {
+		case string(ext.SpanKind):
+			s.Kind = spanKind(fmt.Sprint(v))
+		case string(ext.Error):
+			if b, ok := v.(bool); ok && b {
+				s.Status.Code = statusCodeError
+			}
+		default:
+			s.Attributes = append(s.Attributes, attribute(k, v))
+		}
+	}
+
+	for _, l := range span.Logs() {
+		e := otlppb.Event{TimeUnixNano: uint64(l.Timestamp.UnixNano()), Name: "log"}
+		for _, f := range l.Fields {
+			if f.Key() == "event" {
+				e.Name = fmt.Sprint(f.Value())
+				continue
+			}
+			if f.Key() == "message" && s.Status.Code == statusCodeError && s.Status.Message == "" {
+				s.Status.Message = fmt.Sprint(f.Value())
+			}
+			e.Attributes = append(e.Attributes, attribute(f.Key(), f.Value()))
+		}
+		s.Events = append(s.Events, e)
+	}
+	return s
+}
+
+func spanKind(kind string) int32 {
+	switch ext.SpanKindEnum(kind) {
+	case ext.SpanKindRPCServerEnum:
+		return spanKindServer
+	case ext.SpanKindRPCClientEnum:
+		return spanKindClient
+	case ext.SpanKindProducerEnum:
+		return spanKindProducer
+	case ext.SpanKindConsumerEnum:
+		return spanKindConsumer
+	default:
+		return spanKindInternal
+	}
+}
+
+func attribute(key string, value interface{}) otlppb.KeyValue {
+	kv := otlppb.KeyValue{Key: key}
+	switch v := value.(type) {
+	case string:
+		kv.Value.Value = &otlppb.AnyValue_StringValue{StringValue: v}
+	case bool:
+		kv.Value.Value = &otlppb.AnyValue_BoolValue{BoolValue: v}
+	case int:
+		kv.Value.Value = &otlppb.AnyValue_IntValue{IntValue: int64(v)}
+	case int32:
+		kv.Value.Value = &otlppb.AnyValue_IntValue{IntValue: int64(v)}
+	case int64:
+		kv.Value.Value = &otlppb.AnyValue_IntValue{IntValue: v}
+	case uint16:
+		kv.Value.Value = &otlppb.AnyValue_IntValue{IntValue: int64(v)}
+	case uint32:
+		kv.Value.Value = &otlppb.AnyValue_IntValue{IntValue: int64(v)}
+	case float32:
+		kv.Value.Value = &otlppb.AnyValue_DoubleValue{DoubleValue: float64(v)}
+	case float64:
+		kv.Value.Value = &otlppb.AnyValue_DoubleValue{DoubleValue: v}
+	default:
+		kv.Value.Value = &otlppb.AnyValue_StringValue{StringValue: fmt.Sprint(v)}
+	}
+	return kv
+}
+
+func stringAttributes(attrs map[string]string) []otlppb.KeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]otlppb.KeyValue, 0, len(attrs))
+	for _, k := range keys {
+		kvs = append(kvs, attribute(k, attrs[k]))
+	}
+	return kvs
+}
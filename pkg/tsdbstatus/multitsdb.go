@@ -0,0 +1,34 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tsdbstatus
+
+import (
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/thanos-io/thanos/pkg/tsdbstatus/tsdbstatuspb"
+)
+
+// MultiTSDB implements tsdbstatuspb.TSDBStatusServer that allows to fetch the TSDB status of a MultiTSDB instance.
+type MultiTSDB struct {
+	tsdbStatusServers func() map[string]*TSDB
+}
+
+// NewMultiTSDB creates new tsdbstatus.MultiTSDB.
+func NewMultiTSDB(tsdbStatusServers func() map[string]*TSDB) *MultiTSDB {
+	return &MultiTSDB{
+		tsdbStatusServers: tsdbStatusServers,
+	}
+}
+
+// TSDBStatus returns the TSDB status of each tenant of a MultiTSDB instance.
+func (m *MultiTSDB) TSDBStatus(r *tsdbstatuspb.TSDBStatusRequest, s tsdbstatuspb.TSDBStatus_TSDBStatusServer) error {
+	for tenant, ts := range m.tsdbStatusServers() {
+		if err := ts.TSDBStatus(r, s); err != nil {
+			return status.Error(codes.Aborted, errors.Wrapf(err, "get tsdb status for tenant %s", tenant).Error())
+		}
+	}
+	return nil
+}
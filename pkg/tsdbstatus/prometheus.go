@@ -0,0 +1,45 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tsdbstatus
+
+import (
+	"net/url"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/thanos-io/thanos/pkg/promclient"
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus/tsdbstatuspb"
+)
+
+// Prometheus implements tsdbstatuspb.TSDBStatus gRPC that allows to fetch the TSDB status from Prometheus HTTP
+// api/v1/status/tsdb endpoint.
+type Prometheus struct {
+	base   *url.URL
+	client *promclient.Client
+
+	extLabels func() labels.Labels
+}
+
+// NewPrometheus creates new tsdbstatus.Prometheus.
+func NewPrometheus(base *url.URL, client *promclient.Client, extLabels func() labels.Labels) *Prometheus {
+	return &Prometheus{
+		base:      base,
+		client:    client,
+		extLabels: extLabels,
+	}
+}
+
+// TSDBStatus returns the TSDB status of Prometheus.
+func (p *Prometheus) TSDBStatus(_ *tsdbstatuspb.TSDBStatusRequest, s tsdbstatuspb.TSDBStatus_TSDBStatusServer) error {
+	st, err := p.client.TSDBStatusInGRPC(s.Context(), p.base)
+	if err != nil {
+		return err
+	}
+
+	// Prometheus does not know about external labels, so we need to add them on our own for deduplication.
+	st.LabelSet = labelpb.ZLabelSet{Labels: labelpb.ZLabelsFromPromLabels(p.extLabels())}
+
+	return s.Send(tsdbstatuspb.NewTSDBStatusResponse(st))
+}
@@ -0,0 +1,139 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tsdbstatus
+
+import (
+	"context"
+	"io"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus/tsdbstatuspb"
+)
+
+// Proxy implements tsdbstatuspb.TSDBStatus gRPC that fans out requests to given tsdbstatuspb.TSDBStatus.
+type Proxy struct {
+	logger       log.Logger
+	tsdbStatuses func() []tsdbstatuspb.TSDBStatusClient
+}
+
+func RegisterTSDBStatusServer(tsdbStatusSrv tsdbstatuspb.TSDBStatusServer) func(*grpc.Server) {
+	return func(s *grpc.Server) {
+		tsdbstatuspb.RegisterTSDBStatusServer(s, tsdbStatusSrv)
+	}
+}
+
+// NewProxy returns new tsdbstatus.Proxy.
+func NewProxy(logger log.Logger, tsdbStatuses func() []tsdbstatuspb.TSDBStatusClient) *Proxy {
+	return &Proxy{
+		logger:       logger,
+		tsdbStatuses: tsdbStatuses,
+	}
+}
+
+func (s *Proxy) TSDBStatus(req *tsdbstatuspb.TSDBStatusRequest, srv tsdbstatuspb.TSDBStatus_TSDBStatusServer) error {
+	var (
+		g, gctx  = errgroup.WithContext(srv.Context())
+		respChan = make(chan *tsdbstatuspb.TSDBStatus, 10)
+		statuses []*tsdbstatuspb.TSDBStatus
+	)
+
+	for _, tsdbStatusClient := range s.tsdbStatuses() {
+		rs := &tsdbStatusStream{
+			client:  tsdbStatusClient,
+			request: req,
+			channel: respChan,
+			server:  srv,
+		}
+		g.Go(func() error { return rs.receive(gctx) })
+	}
+
+	go func() {
+		_ = g.Wait()
+		close(respChan)
+	}()
+
+	for resp := range respChan {
+		// TODO: Stream it
+		statuses = append(statuses, resp)
+	}
+
+	if err := g.Wait(); err != nil {
+		level.Error(s.logger).Log("err", err)
+		return err
+	}
+
+	for _, st := range statuses {
+		if err := srv.Send(tsdbstatuspb.NewTSDBStatusResponse(st)); err != nil {
+			return status.Error(codes.Unknown, errors.Wrap(err, "send tsdb status response").Error())
+		}
+	}
+
+	return nil
+}
+
+type tsdbStatusStream struct {
+	client  tsdbstatuspb.TSDBStatusClient
+	request *tsdbstatuspb.TSDBStatusRequest
+	channel chan<- *tsdbstatuspb.TSDBStatus
+	server  tsdbstatuspb.TSDBStatus_TSDBStatusServer
+}
+
+func (stream *tsdbStatusStream) receive(ctx context.Context) error {
+	statuses, err := stream.client.TSDBStatus(ctx, stream.request)
+	if err != nil {
+		err = errors.Wrapf(err, "fetching tsdb status from tsdb status client %v", stream.client)
+
+		if stream.request.PartialResponseStrategy == storepb.PartialResponseStrategy_ABORT {
+			return err
+		}
+
+		if serr := stream.server.Send(tsdbstatuspb.NewWarningTSDBStatusResponse(err)); serr != nil {
+			return serr
+		}
+		// Not an error if response strategy is warning.
+		return nil
+	}
+
+	for {
+		st, err := statuses.Recv()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			err = errors.Wrapf(err, "receiving tsdb status from tsdb status client %v", stream.client)
+
+			if stream.request.PartialResponseStrategy == storepb.PartialResponseStrategy_ABORT {
+				return err
+			}
+
+			if err := stream.server.Send(tsdbstatuspb.NewWarningTSDBStatusResponse(err)); err != nil {
+				return errors.Wrapf(err, "sending tsdb status error to server %v", stream.server)
+			}
+			// Not an error if response strategy is warning.
+			return nil
+		}
+
+		if w := st.GetWarning(); w != "" {
+			if err := stream.server.Send(tsdbstatuspb.NewWarningTSDBStatusResponse(errors.New(w))); err != nil {
+				return errors.Wrapf(err, "sending tsdb status warning to server %v", stream.server)
+			}
+			continue
+		}
+
+		select {
+		case stream.channel <- st.GetStatus():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
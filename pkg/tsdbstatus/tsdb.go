@@ -0,0 +1,58 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tsdbstatus
+
+import (
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/index"
+
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus/tsdbstatuspb"
+)
+
+// TSDB allows fetching the TSDB status from a TSDB instance.
+type TSDB struct {
+	db        *tsdb.DB
+	extLabels labels.Labels
+}
+
+// NewTSDB creates new tsdbstatus.TSDB.
+func NewTSDB(db *tsdb.DB, extLabels labels.Labels) *TSDB {
+	return &TSDB{
+		db:        db,
+		extLabels: extLabels,
+	}
+}
+
+// TSDBStatus returns the head statistics of the TSDB instance, same as the Prometheus /api/v1/status/tsdb endpoint
+// apart from the chunk count, which is not exposed by the TSDB.
+func (t *TSDB) TSDBStatus(_ *tsdbstatuspb.TSDBStatusRequest, s tsdbstatuspb.TSDBStatus_TSDBStatusServer) error {
+	return s.Send(tsdbstatuspb.NewTSDBStatusResponse(t.status()))
+}
+
+func (t *TSDB) status() *tsdbstatuspb.TSDBStatus {
+	stats := t.db.Head().Stats(labels.MetricName)
+	return &tsdbstatuspb.TSDBStatus{
+		HeadStats: tsdbstatuspb.HeadStats{
+			NumSeries:     stats.NumSeries,
+			NumLabelPairs: int64(stats.IndexPostingStats.NumLabelPairs),
+			MinTime:       stats.MinTime,
+			MaxTime:       stats.MaxTime,
+		},
+		SeriesCountByMetricName:     statisticsFromPromStats(stats.IndexPostingStats.CardinalityMetricsStats),
+		LabelValueCountByLabelName:  statisticsFromPromStats(stats.IndexPostingStats.CardinalityLabelStats),
+		MemoryInBytesByLabelName:    statisticsFromPromStats(stats.IndexPostingStats.LabelValueStats),
+		SeriesCountByLabelValuePair: statisticsFromPromStats(stats.IndexPostingStats.LabelValuePairsStats),
+		LabelSet:                    labelpb.ZLabelSet{Labels: labelpb.ZLabelsFromPromLabels(t.extLabels)},
+	}
+}
+
+func statisticsFromPromStats(stats []index.Stat) []tsdbstatuspb.Statistic {
+	res := make([]tsdbstatuspb.Statistic, 0, len(stats))
+	for _, s := range stats {
+		res = append(res, tsdbstatuspb.Statistic{Name: s.Name, Value: s.Count})
+	}
+	return res
+}
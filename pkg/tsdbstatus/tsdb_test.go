@@ -0,0 +1,43 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tsdbstatus
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus/tsdbstatuspb"
+)
+
+func TestTSDB_TSDBStatus(t *testing.T) {
+	db, err := e2eutil.NewTSDB()
+	testutil.Ok(t, err)
+	defer func() {
+		testutil.Ok(t, db.Close())
+		testutil.Ok(t, os.RemoveAll(db.Dir()))
+	}()
+
+	app := db.Appender(context.Background())
+	for i, lset := range []labels.Labels{
+		labels.FromStrings("__name__", "up", "job", "a"),
+		labels.FromStrings("__name__", "up", "job", "b"),
+		labels.FromStrings("__name__", "go_goroutines", "job", "a"),
+	} {
+		_, err := app.Append(0, lset, int64(i+1)*1000, 1)
+		testutil.Ok(t, err)
+	}
+	testutil.Ok(t, app.Commit())
+
+	st := NewTSDB(db, labels.FromStrings("replica", "0")).status()
+	testutil.Equals(t, tsdbstatuspb.HeadStats{NumSeries: 3, NumLabelPairs: 4, MinTime: 1000, MaxTime: 3000}, st.HeadStats)
+	testutil.Equals(t, []tsdbstatuspb.Statistic{{Name: "up", Value: 2}, {Name: "go_goroutines", Value: 1}}, st.SeriesCountByMetricName)
+	testutil.Equals(t, []tsdbstatuspb.Statistic{{Name: "__name__", Value: 2}, {Name: "job", Value: 2}}, tsdbstatuspb.TopStatistics(st.LabelValueCountByLabelName, tsdbstatuspb.MaxStatistics))
+	testutil.Equals(t, labelpb.ZLabelSet{Labels: []labelpb.ZLabel{{Name: "replica", Value: "0"}}}, st.LabelSet)
+}
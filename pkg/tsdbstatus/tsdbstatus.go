@@ -0,0 +1,210 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tsdbstatus
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/thanos-io/thanos/pkg/tsdbstatus/tsdbstatuspb"
+)
+
+var _ UnaryClient = &GRPCClient{}
+
+// UnaryClient is gRPC tsdbstatuspb.TSDBStatus client which expands streaming TSDB status API. Useful for consumers that
+// does not support streaming.
+type UnaryClient interface {
+	TSDBStatus(ctx context.Context, req *tsdbstatuspb.TSDBStatusRequest) (*tsdbstatuspb.TSDBStatus, storage.Warnings, error)
+}
+
+// GRPCClient allows to retrieve the TSDB status from local gRPC streaming server implementation.
+// TODO(bwplotka): Switch to native gRPC transparent client->server adapter once available.
+type GRPCClient struct {
+	proxy tsdbstatuspb.TSDBStatusServer
+
+	replicaLabels map[string]struct{}
+}
+
+func NewGRPCClient(ts tsdbstatuspb.TSDBStatusServer) *GRPCClient {
+	return NewGRPCClientWithDedup(ts, nil)
+}
+
+func NewGRPCClientWithDedup(ts tsdbstatuspb.TSDBStatusServer, replicaLabels []string) *GRPCClient {
+	c := &GRPCClient{
+		proxy:         ts,
+		replicaLabels: map[string]struct{}{},
+	}
+
+	for _, label := range replicaLabels {
+		c.replicaLabels[label] = struct{}{}
+	}
+	return c
+}
+
+// TSDBStatus returns the TSDB status of all TSDBs merged into one. The statuses of the replicas of a TSDB, i.e. TSDBs
+// which have the same external labels apart from the replica labels, are deduplicated by taking the maximum of each
+// statistic, while the statuses of different TSDBs are summed up. The number of label values can't be summed up, as
+// different TSDBs usually share label values, so the maximum is taken instead.
+func (rr *GRPCClient) TSDBStatus(ctx context.Context, req *tsdbstatuspb.TSDBStatusRequest) (*tsdbstatuspb.TSDBStatus, storage.Warnings, error) {
+	resp := &tsdbStatusServer{ctx: ctx}
+
+	if err := rr.proxy.TSDBStatus(req, resp); err != nil {
+		return nil, nil, errors.Wrap(err, "proxy TSDBStatus")
+	}
+
+	return dedupAndMergeStatuses(resp.statuses, rr.replicaLabels), resp.warnings, nil
+}
+
+// dedupAndMergeStatuses merges the statuses of the replicas of each TSDB first, and then the statuses of all TSDBs.
+func dedupAndMergeStatuses(statuses []*tsdbstatuspb.TSDBStatus, replicaLabels map[string]struct{}) *tsdbstatuspb.TSDBStatus {
+	var (
+		keys     []string
+		replicas = map[string]*tsdbstatuspb.TSDBStatus{}
+	)
+	for _, s := range statuses {
+		key := removeReplicaLabels(s.LabelSet.PromLabels(), replicaLabels).String()
+		curr, ok := replicas[key]
+		if !ok {
+			curr = emptyStatus()
+			replicas[key] = curr
+			keys = append(keys, key)
+		}
+		mergeStatus(curr, s, maxOf)
+	}
+
+	res := emptyStatus()
+	for _, key := range keys {
+		mergeStatus(res, replicas[key], sum)
+	}
+	return res
+}
+
+func removeReplicaLabels(lset labels.Labels, replicaLabels map[string]struct{}) labels.Labels {
+	res := make(labels.Labels, 0, len(lset))
+	for _, l := range lset {
+		if _, ok := replicaLabels[l.Name]; !ok {
+			res = append(res, l)
+		}
+	}
+	return res
+}
+
+func emptyStatus() *tsdbstatuspb.TSDBStatus {
+	return &tsdbstatuspb.TSDBStatus{
+		SeriesCountByMetricName:     []tsdbstatuspb.Statistic{},
+		LabelValueCountByLabelName:  []tsdbstatuspb.Statistic{},
+		MemoryInBytesByLabelName:    []tsdbstatuspb.Statistic{},
+		SeriesCountByLabelValuePair: []tsdbstatuspb.Statistic{},
+	}
+}
+
+type combineFunc func(a, b uint64) uint64
+
+func sum(a, b uint64) uint64 { return a + b }
+
+func maxOf(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// mergeStatus merges the status s into the status dst, combining the statistics with the given function.
+func mergeStatus(dst, s *tsdbstatuspb.TSDBStatus, combine combineFunc) {
+	dst.HeadStats = mergeHeadStats(dst.HeadStats, s.HeadStats, combine)
+	dst.SeriesCountByMetricName = mergeStatistics(dst.SeriesCountByMetricName, s.SeriesCountByMetricName, combine)
+	dst.LabelValueCountByLabelName = mergeStatistics(dst.LabelValueCountByLabelName, s.LabelValueCountByLabelName, maxOf)
+	dst.MemoryInBytesByLabelName = mergeStatistics(dst.MemoryInBytesByLabelName, s.MemoryInBytesByLabelName, combine)
+	dst.SeriesCountByLabelValuePair = mergeStatistics(dst.SeriesCountByLabelValuePair, s.SeriesCountByLabelValuePair, combine)
+}
+
+// mergeHeadStats combines the head statistics with the given function. The time range covers the ranges of both heads,
+// ignoring empty heads, as their time range is not defined.
+func mergeHeadStats(a, b tsdbstatuspb.HeadStats, combine combineFunc) tsdbstatuspb.HeadStats {
+	res := tsdbstatuspb.HeadStats{
+		NumSeries:     combine(a.NumSeries, b.NumSeries),
+		NumLabelPairs: int64(combine(nonNegative(a.NumLabelPairs), nonNegative(b.NumLabelPairs))),
+		// Prometheus returns a negative chunk count if it is not known.
+		ChunkCount: int64(combine(nonNegative(a.ChunkCount), nonNegative(b.ChunkCount))),
+	}
+	switch {
+	case a.NumSeries > 0 && b.NumSeries > 0:
+		res.MinTime, res.MaxTime = a.MinTime, a.MaxTime
+		if b.MinTime < res.MinTime {
+			res.MinTime = b.MinTime
+		}
+		if b.MaxTime > res.MaxTime {
+			res.MaxTime = b.MaxTime
+		}
+	case a.NumSeries > 0:
+		res.MinTime, res.MaxTime = a.MinTime, a.MaxTime
+	case b.NumSeries > 0:
+		res.MinTime, res.MaxTime = b.MinTime, b.MaxTime
+	}
+	return res
+}
+
+func nonNegative(v int64) uint64 {
+	if v < 0 {
+		return 0
+	}
+	return uint64(v)
+}
+
+// mergeStatistics combines the statistics of the same name with the given function and returns the top ones.
+func mergeStatistics(a, b []tsdbstatuspb.Statistic, combine combineFunc) []tsdbstatuspb.Statistic {
+	values := make(map[string]uint64, len(a)+len(b))
+	for _, s := range a {
+		values[s.Name] = s.Value
+	}
+	for _, s := range b {
+		if v, ok := values[s.Name]; ok {
+			values[s.Name] = combine(v, s.Value)
+			continue
+		}
+		values[s.Name] = s.Value
+	}
+
+	res := make([]tsdbstatuspb.Statistic, 0, len(values))
+	for name, v := range values {
+		res = append(res, tsdbstatuspb.Statistic{Name: name, Value: v})
+	}
+	return tsdbstatuspb.TopStatistics(res, tsdbstatuspb.MaxStatistics)
+}
+
+type tsdbStatusServer struct {
+	// This field just exist to pseudo-implement the unused methods of the interface.
+	tsdbstatuspb.TSDBStatus_TSDBStatusServer
+	ctx context.Context
+
+	warnings []error
+	statuses []*tsdbstatuspb.TSDBStatus
+	mu       sync.Mutex
+}
+
+func (srv *tsdbStatusServer) Send(res *tsdbstatuspb.TSDBStatusResponse) error {
+	if res.GetWarning() != "" {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		srv.warnings = append(srv.warnings, errors.New(res.GetWarning()))
+		return nil
+	}
+
+	if res.GetStatus() == nil {
+		return errors.New("no tsdb status")
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.statuses = append(srv.statuses, res.GetStatus())
+
+	return nil
+}
+
+func (srv *tsdbStatusServer) Context() context.Context {
+	return srv.ctx
+}
@@ -0,0 +1,114 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tsdbstatus
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/testutil"
+	"github.com/thanos-io/thanos/pkg/tsdbstatus/tsdbstatuspb"
+)
+
+func testStatus(lset labels.Labels, head tsdbstatuspb.HeadStats, seriesByMetric, valuesByLabel []tsdbstatuspb.Statistic) *tsdbstatuspb.TSDBStatus {
+	return &tsdbstatuspb.TSDBStatus{
+		HeadStats:                  head,
+		SeriesCountByMetricName:    seriesByMetric,
+		LabelValueCountByLabelName: valuesByLabel,
+		LabelSet:                   labelpb.ZLabelSet{Labels: labelpb.ZLabelsFromPromLabels(lset)},
+	}
+}
+
+func TestDedupAndMergeStatuses(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		statuses      []*tsdbstatuspb.TSDBStatus
+		replicaLabels []string
+		want          *tsdbstatuspb.TSDBStatus
+	}{
+		{
+			name: "no statuses",
+			want: emptyStatus(),
+		},
+		{
+			name: "different TSDBs are summed up",
+			statuses: []*tsdbstatuspb.TSDBStatus{
+				testStatus(labels.FromStrings("prometheus", "a"),
+					tsdbstatuspb.HeadStats{NumSeries: 10, NumLabelPairs: 20, ChunkCount: 30, MinTime: 100, MaxTime: 200},
+					[]tsdbstatuspb.Statistic{{Name: "up", Value: 6}, {Name: "go_goroutines", Value: 4}},
+					[]tsdbstatuspb.Statistic{{Name: "instance", Value: 5}},
+				),
+				testStatus(labels.FromStrings("prometheus", "b"),
+					tsdbstatuspb.HeadStats{NumSeries: 5, NumLabelPairs: 10, ChunkCount: -1, MinTime: 50, MaxTime: 150},
+					[]tsdbstatuspb.Statistic{{Name: "up", Value: 5}},
+					[]tsdbstatuspb.Statistic{{Name: "instance", Value: 3}, {Name: "job", Value: 1}},
+				),
+				// Store gateways only have label value counts and no head.
+				testStatus(labels.FromStrings("prometheus", "c"),
+					tsdbstatuspb.HeadStats{},
+					nil,
+					[]tsdbstatuspb.Statistic{{Name: "instance", Value: 7}},
+				),
+			},
+			want: &tsdbstatuspb.TSDBStatus{
+				HeadStats:                   tsdbstatuspb.HeadStats{NumSeries: 15, NumLabelPairs: 30, ChunkCount: 30, MinTime: 50, MaxTime: 200},
+				SeriesCountByMetricName:     []tsdbstatuspb.Statistic{{Name: "up", Value: 11}, {Name: "go_goroutines", Value: 4}},
+				LabelValueCountByLabelName:  []tsdbstatuspb.Statistic{{Name: "instance", Value: 7}, {Name: "job", Value: 1}},
+				MemoryInBytesByLabelName:    []tsdbstatuspb.Statistic{},
+				SeriesCountByLabelValuePair: []tsdbstatuspb.Statistic{},
+			},
+		},
+		{
+			name:          "replicas are deduplicated",
+			replicaLabels: []string{"replica"},
+			statuses: []*tsdbstatuspb.TSDBStatus{
+				testStatus(labels.FromStrings("prometheus", "a", "replica", "0"),
+					tsdbstatuspb.HeadStats{NumSeries: 10, NumLabelPairs: 20, MinTime: 100, MaxTime: 200},
+					[]tsdbstatuspb.Statistic{{Name: "up", Value: 6}, {Name: "go_goroutines", Value: 4}},
+					nil,
+				),
+				testStatus(labels.FromStrings("prometheus", "a", "replica", "1"),
+					tsdbstatuspb.HeadStats{NumSeries: 12, NumLabelPairs: 20, MinTime: 110, MaxTime: 210},
+					[]tsdbstatuspb.Statistic{{Name: "up", Value: 7}, {Name: "go_goroutines", Value: 3}},
+					nil,
+				),
+				testStatus(labels.FromStrings("prometheus", "b", "replica", "0"),
+					tsdbstatuspb.HeadStats{NumSeries: 1, NumLabelPairs: 2, MinTime: 120, MaxTime: 220},
+					[]tsdbstatuspb.Statistic{{Name: "up", Value: 1}},
+					nil,
+				),
+			},
+			want: &tsdbstatuspb.TSDBStatus{
+				HeadStats:                   tsdbstatuspb.HeadStats{NumSeries: 13, NumLabelPairs: 22, MinTime: 100, MaxTime: 220},
+				SeriesCountByMetricName:     []tsdbstatuspb.Statistic{{Name: "up", Value: 8}, {Name: "go_goroutines", Value: 4}},
+				LabelValueCountByLabelName:  []tsdbstatuspb.Statistic{},
+				MemoryInBytesByLabelName:    []tsdbstatuspb.Statistic{},
+				SeriesCountByLabelValuePair: []tsdbstatuspb.Statistic{},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			replicaLabels := map[string]struct{}{}
+			for _, l := range tc.replicaLabels {
+				replicaLabels[l] = struct{}{}
+			}
+			testutil.Equals(t, tc.want, dedupAndMergeStatuses(tc.statuses, replicaLabels))
+		})
+	}
+}
+
+func TestMergeStatistics_KeepsTopStatistics(t *testing.T) {
+	var a, b []tsdbstatuspb.Statistic
+	for i := 0; i < tsdbstatuspb.MaxStatistics; i++ {
+		a = append(a, tsdbstatuspb.Statistic{Name: string(rune('a' + i)), Value: uint64(i)})
+		b = append(b, tsdbstatuspb.Statistic{Name: string(rune('A' + i)), Value: uint64(i) + 5})
+	}
+
+	merged := mergeStatistics(a, b, sum)
+	testutil.Equals(t, tsdbstatuspb.MaxStatistics, len(merged))
+	testutil.Equals(t, tsdbstatuspb.Statistic{Name: "J", Value: 14}, merged[0])
+	testutil.Equals(t, tsdbstatuspb.Statistic{Name: "C", Value: 7}, merged[len(merged)-1])
+}
@@ -0,0 +1,39 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tsdbstatuspb
+
+import "sort"
+
+// MaxStatistics is the number of entries of each cardinality statistic, same as in Prometheus.
+const MaxStatistics = 10
+
+func NewTSDBStatusResponse(status *TSDBStatus) *TSDBStatusResponse {
+	return &TSDBStatusResponse{
+		Result: &TSDBStatusResponse_Status{
+			Status: status,
+		},
+	}
+}
+
+func NewWarningTSDBStatusResponse(warning error) *TSDBStatusResponse {
+	return &TSDBStatusResponse{
+		Result: &TSDBStatusResponse_Warning{
+			Warning: warning.Error(),
+		},
+	}
+}
+
+// TopStatistics sorts the statistics by value in descending order, then by name, and returns the first n of them.
+func TopStatistics(stats []Statistic, n int) []Statistic {
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Value != stats[j].Value {
+			return stats[i].Value > stats[j].Value
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
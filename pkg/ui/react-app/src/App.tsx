@@ -8,7 +8,7 @@ import { Alerts, Config, Flags, Rules, ServiceDiscovery, Status, Targets, TSDBSt
 import PathPrefixProps from './types/PathPrefixProps';
 import ThanosComponentProps from './thanos/types/ThanosComponentProps';
 import Navigation from './thanos/Navbar';
-import { Stores, ErrorBoundary, Blocks } from './thanos/pages';
+import { Stores, ErrorBoundary, Blocks, BlockPage } from './thanos/pages';
 import { ThemeContext, themeName, themeSetting } from './contexts/ThemeContext';
 import { Theme, themeLocalStorageKey } from './Theme';
 import { useLocalStorage } from './hooks/useLocalStorage';
@@ -60,6 +60,7 @@ const App: FC<PathPrefixProps & ThanosComponentProps> = ({ pathPrefix, thanosCom
               <Targets path="/targets" pathPrefix={pathPrefix} />
               <Stores path="/stores" pathPrefix={pathPrefix} />
               <Blocks path="/blocks" pathPrefix={pathPrefix} />
+              <BlockPage path="/blocks/:ulid" pathPrefix={pathPrefix} />
               <Blocks path="/loaded" pathPrefix={pathPrefix} view="loaded" />
               <NotFound pathPrefix={pathPrefix} default defaultRoute={defaultRouteConfig[thanosComponent]} />
             </Router>
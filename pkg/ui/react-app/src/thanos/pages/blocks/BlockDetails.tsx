@@ -6,11 +6,12 @@ import { Button, Modal, ModalBody, Form, Input, ModalHeader, ModalFooter } from
 import { download } from './helpers';
 
 export interface BlockDetailsProps {
+  pathPrefix?: string;
   block: Block | undefined;
   selectBlock: React.Dispatch<React.SetStateAction<Block | undefined>>;
 }
 
-export const BlockDetails: FC<BlockDetailsProps> = ({ block, selectBlock }) => {
+export const BlockDetails: FC<BlockDetailsProps> = ({ pathPrefix = '', block, selectBlock }) => {
   const [modalAction, setModalAction] = useState<string>('');
   const [detailValue, setDetailValue] = useState<string | null>(null);
 
@@ -100,6 +101,11 @@ export const BlockDetails: FC<BlockDetailsProps> = ({ block, selectBlock }) => {
               <Button>Download meta.json</Button>
             </a>
           </div>
+          <div data-testid="drill-down" style={{ marginTop: '12px' }}>
+            <a href={`${pathPrefix}/blocks/${block.ulid}`}>
+              <Button>Show block details</Button>
+            </a>
+          </div>
           <div style={{ marginTop: '12px' }}>
             <Button
               onClick={() => {
@@ -0,0 +1,67 @@
+import React, { FC, ChangeEvent } from 'react';
+import { Input } from 'reactstrap';
+import styles from './blocks.module.css';
+
+interface BlockLabelFilterProps {
+  search: string;
+  labelNames: string[];
+  labelName: string;
+  labelValues: string[];
+  labelValue: string;
+  onChangeSearch: ({ target }: ChangeEvent<HTMLInputElement>) => void;
+  onChangeLabelName: ({ target }: ChangeEvent<HTMLInputElement>) => void;
+  onChangeLabelValue: ({ target }: ChangeEvent<HTMLInputElement>) => void;
+}
+
+export const BlockLabelFilter: FC<BlockLabelFilterProps> = ({
+  search,
+  labelNames,
+  labelName,
+  labelValues,
+  labelValue,
+  onChangeSearch,
+  onChangeLabelName,
+  onChangeLabelValue,
+}) => {
+  return (
+    <div className={styles.blockFilter}>
+      <Input
+        data-testid="label-search"
+        placeholder="Search blocks by external labels, e.g. tenant=team-a"
+        style={{ width: '360px', marginRight: '12px', marginBottom: '1rem' }}
+        value={search}
+        onChange={onChangeSearch}
+      />
+      <p style={{ marginRight: '4px' }}>Filter by label</p>
+      <Input
+        data-testid="label-name"
+        type="select"
+        style={{ width: '180px', marginRight: '4px', marginBottom: '1rem' }}
+        value={labelName}
+        onChange={onChangeLabelName}
+      >
+        <option value="">-</option>
+        {labelNames.map((name) => (
+          <option key={name} value={name}>
+            {name}
+          </option>
+        ))}
+      </Input>
+      <Input
+        data-testid="label-value"
+        type="select"
+        style={{ width: '180px', marginBottom: '1rem' }}
+        value={labelValue}
+        onChange={onChangeLabelValue}
+        disabled={labelName === ''}
+      >
+        <option value="">All</option>
+        {labelValues.map((value) => (
+          <option key={value} value={value}>
+            {value}
+          </option>
+        ))}
+      </Input>
+    </div>
+  );
+};
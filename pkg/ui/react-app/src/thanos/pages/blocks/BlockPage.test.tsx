@@ -0,0 +1,70 @@
+import React from 'react';
+import { mount } from 'enzyme';
+import { UncontrolledAlert } from 'reactstrap';
+import { act } from 'react-dom/test-utils';
+import BlockPage from './BlockPage';
+import { sampleAPIResponse } from './__testdata__/testdata';
+
+const sampleBlock = sampleAPIResponse.data.blocks[0];
+
+describe('BlockPage', () => {
+  beforeEach(() => {
+    fetchMock.resetMocks();
+  });
+
+  it('renders the details of the block', async () => {
+    const mock = fetchMock.mockResponse(
+      JSON.stringify({
+        status: 'success',
+        data: {
+          meta: sampleBlock,
+          indexSize: 2048,
+          chunksSize: 3 * 1024 * 1024,
+          files: [
+            { name: 'chunks/000001', size: 3 * 1024 * 1024 },
+            { name: 'index', size: 2048 },
+            { name: 'meta.json', size: 512 },
+          ],
+          noCompactMark: {
+            id: sampleBlock.ulid,
+            version: 1,
+            no_compact_time: 1608034500,
+            reason: 'manual',
+            details: 'test',
+          },
+        },
+      })
+    );
+
+    let page: any;
+    await act(async () => {
+      page = mount(<BlockPage ulid={sampleBlock.ulid} />);
+    });
+    page.update();
+
+    expect(mock).toHaveBeenCalledWith(`/api/v1/blocks/${sampleBlock.ulid}`, {
+      cache: 'no-store',
+      credentials: 'same-origin',
+    });
+    expect(page.find({ 'data-testid': 'ulid' }).text()).toBe(sampleBlock.ulid);
+    expect(page.find({ 'data-testid': 'index-size' }).find('td').text()).toBe('2.00 KiB');
+    expect(page.find({ 'data-testid': 'chunks-size' }).find('td').text()).toBe('3.00 MiB');
+    expect(page.find({ 'data-testid': 'Deletion Mark' }).find('td').text()).toBe('None');
+    expect(page.find({ 'data-testid': 'No Compaction Mark' }).find('td').text()).toContain('(manual): test');
+    expect(page.find('table[data-testid="files"] tbody tr')).toHaveLength(3);
+  });
+
+  it('displays an error alert', async () => {
+    fetchMock.mockReject(new Error('Error fetching block'));
+
+    let page: any;
+    await act(async () => {
+      page = mount(<BlockPage ulid={sampleBlock.ulid} />);
+    });
+    page.update();
+
+    const alert = page.find(UncontrolledAlert);
+    expect(alert.prop('color')).toBe('danger');
+    expect(alert.text()).toContain('Error fetching block');
+  });
+});
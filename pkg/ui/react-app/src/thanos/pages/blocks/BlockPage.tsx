@@ -0,0 +1,122 @@
+import React, { FC } from 'react';
+import { RouteComponentProps } from '@reach/router';
+import { Table } from 'reactstrap';
+import moment from 'moment';
+import { withStatusIndicator } from '../../../components/withStatusIndicator';
+import { useFetch } from '../../../hooks/useFetch';
+import PathPrefixProps from '../../../types/PathPrefixProps';
+import { BlockDetailsData, BlockMarker } from './block';
+import { formatBytes } from './helpers';
+
+const MarkerRow: FC<{ name: string; marker?: BlockMarker; time?: number }> = ({ name, marker, time }) => (
+  <tr data-testid={name}>
+    <th>{name}</th>
+    <td>
+      {marker ? (
+        <>
+          {time ? moment.unix(time).format('LLL') : '-'}
+          {marker.reason && ` (${marker.reason})`}
+          {marker.details && `: ${marker.details}`}
+        </>
+      ) : (
+        'None'
+      )}
+    </td>
+  </tr>
+);
+
+export const BlockPageContent: FC<{ data: BlockDetailsData }> = ({ data }) => {
+  const { meta, indexSize, chunksSize, files, deletionMark, noCompactMark } = data;
+
+  return (
+    <>
+      <h2 data-testid="ulid">{meta.ulid}</h2>
+      <Table size="sm" bordered>
+        <tbody>
+          <tr>
+            <th>Start Time</th>
+            <td>{moment.unix(meta.minTime / 1000).format('LLL')}</td>
+          </tr>
+          <tr>
+            <th>End Time</th>
+            <td>{moment.unix(meta.maxTime / 1000).format('LLL')}</td>
+          </tr>
+          <tr>
+            <th>Duration</th>
+            <td>{moment.duration(meta.maxTime - meta.minTime, 'ms').humanize()}</td>
+          </tr>
+          <tr data-testid="stats">
+            <th>Series / Samples / Chunks</th>
+            <td>
+              {meta.stats.numSeries} / {meta.stats.numSamples} / {meta.stats.numChunks}
+            </td>
+          </tr>
+          <tr>
+            <th>Resolution / Level / Source</th>
+            <td>
+              {meta.thanos.downsample.resolution} / {meta.compaction.level} / {meta.thanos.source}
+            </td>
+          </tr>
+          <tr data-testid="labels">
+            <th>Labels</th>
+            <td>
+              {Object.entries(meta.thanos.labels)
+                .map(([key, value]) => `${key}="${value}"`)
+                .join(', ')}
+            </td>
+          </tr>
+          <tr data-testid="index-size">
+            <th>Index Size</th>
+            <td>{formatBytes(indexSize)}</td>
+          </tr>
+          <tr data-testid="chunks-size">
+            <th>Chunks Size</th>
+            <td>{formatBytes(chunksSize)}</td>
+          </tr>
+          <MarkerRow name="Deletion Mark" marker={deletionMark} time={deletionMark?.deletion_time} />
+          <MarkerRow name="No Compaction Mark" marker={noCompactMark} time={noCompactMark?.no_compact_time} />
+        </tbody>
+      </Table>
+      <h4>Files</h4>
+      <Table size="sm" bordered data-testid="files">
+        <thead>
+          <tr>
+            <th>Name</th>
+            <th>Size</th>
+          </tr>
+        </thead>
+        <tbody>
+          {files.map((f) => (
+            <tr key={f.name}>
+              <td>{f.name}</td>
+              <td>{formatBytes(f.size)}</td>
+            </tr>
+          ))}
+        </tbody>
+      </Table>
+    </>
+  );
+};
+
+const BlockPageWithStatusIndicator = withStatusIndicator(BlockPageContent);
+
+interface BlockPageProps {
+  ulid?: string;
+}
+
+export const BlockPage: FC<RouteComponentProps & PathPrefixProps & BlockPageProps> = ({ pathPrefix = '', ulid = '' }) => {
+  const { response, error, isLoading } = useFetch<BlockDetailsData>(`${pathPrefix}/api/v1/blocks/${ulid}`);
+  const { status: responseStatus } = response;
+  const badResponse = responseStatus !== 'success' && responseStatus !== 'start fetching';
+
+  return (
+    <BlockPageWithStatusIndicator
+      data={response.data}
+      error={badResponse ? new Error(responseStatus) : error}
+      isLoading={isLoading}
+      componentTitle="block details"
+    />
+  );
+};
+
+export default BlockPage;
@@ -10,7 +10,8 @@ import { SourceView } from './SourceView';
 import { BlockDetails } from './BlockDetails';
 import { BlockSearchInput } from './BlockSearchInput';
 import { BlockFilterCompaction } from './BlockFilterCompaction';
-import { sortBlocks } from './helpers';
+import { BlockLabelFilter } from './BlockLabelFilter';
+import { getBlocksByLabels, getLabelNames, getLabelValues, sortBlocks } from './helpers';
 import styles from './blocks.module.css';
 import TimeRange from './TimeRange';
 import Checkbox from '../../../components/Checkbox';
@@ -22,7 +23,7 @@ export interface BlockListProps {
   refreshedAt: string;
 }
 
-export const BlocksContent: FC<{ data: BlockListProps }> = ({ data }) => {
+export const BlocksContent: FC<{ data: BlockListProps } & PathPrefixProps> = ({ data, pathPrefix = '' }) => {
   const [selectedBlock, selectBlock] = useState<Block>();
   const [searchState, setSearchState] = useState<string>('');
 
@@ -53,6 +54,9 @@ export const BlocksContent: FC<{ data: BlockListProps }> = ({ data }) => {
       'find-overlapping': findOverlappingParam,
       'filter-compaction': filterCompactionParam,
       'compaction-level': compactionLevelParam,
+      search: labelSearchParam,
+      'label-name': labelNameParam,
+      'label-value': labelValueParam,
     },
     setQuery,
   ] = useQueryParams({
@@ -62,6 +66,9 @@ export const BlocksContent: FC<{ data: BlockListProps }> = ({ data }) => {
     'find-overlapping': withDefault(BooleanParam, false),
     'filter-compaction': withDefault(BooleanParam, false),
     'compaction-level': withDefault(NumberParam, 0),
+    search: withDefault(StringParam, ''),
+    'label-name': withDefault(StringParam, label),
+    'label-value': withDefault(StringParam, ''),
   });
 
   const [filterCompaction, setFilterCompaction] = useState<boolean>(filterCompactionParam);
@@ -70,7 +77,17 @@ export const BlocksContent: FC<{ data: BlockListProps }> = ({ data }) => {
   const [compactionLevelInput, setCompactionLevelInput] = useState<string>(compactionLevelParam.toString());
   const [blockSearch, setBlockSearch] = useState<string>(blockSearchParam);
 
-  const blockPools = useMemo(() => sortBlocks(blocks, label, findOverlappingBlocks), [blocks, label, findOverlappingBlocks]);
+  const labelNames = useMemo(() => getLabelNames(blocks), [blocks]);
+  const labelValues = useMemo(() => getLabelValues(blocks, labelNameParam), [blocks, labelNameParam]);
+  const filteredBlocks = useMemo(
+    () => getBlocksByLabels(blocks, labelSearchParam, labelNameParam, labelValueParam),
+    [blocks, labelSearchParam, labelNameParam, labelValueParam]
+  );
+
+  const blockPools = useMemo(
+    () => sortBlocks(filteredBlocks, label, findOverlappingBlocks),
+    [filteredBlocks, label, findOverlappingBlocks]
+  );
 
   const setViewTime = (times: number[]): void => {
     setQuery({
@@ -148,6 +165,20 @@ export const BlocksContent: FC<{ data: BlockListProps }> = ({ data }) => {
               defaultValue={compactionLevelInput}
             />
           </div>
+          <BlockLabelFilter
+            search={labelSearchParam}
+            labelNames={labelNames}
+            labelName={labelNameParam}
+            labelValues={labelValues}
+            labelValue={labelValueParam}
+            onChangeSearch={({ target }: ChangeEvent<HTMLInputElement>): void => setQuery({ search: target.value })}
+            onChangeLabelName={({ target }: ChangeEvent<HTMLInputElement>): void =>
+              setQuery({ 'label-name': target.value, 'label-value': '' })
+            }
+            onChangeLabelValue={({ target }: ChangeEvent<HTMLInputElement>): void =>
+              setQuery({ 'label-value': target.value })
+            }
+          />
           <div className={styles.container}>
             <div className={styles.grid}>
               <div className={styles.sources}>
@@ -172,7 +203,7 @@ export const BlocksContent: FC<{ data: BlockListProps }> = ({ data }) => {
                 onChange={setViewTime}
               />
             </div>
-            <BlockDetails selectBlock={selectBlock} block={selectedBlock} />
+            <BlockDetails pathPrefix={pathPrefix} selectBlock={selectBlock} block={selectedBlock} />
           </div>
         </>
       ) : (
@@ -198,6 +229,7 @@ export const Blocks: FC<RouteComponentProps & PathPrefixProps & BlocksProps> = (
   return (
     <BlocksWithStatusIndicator
       data={response.data}
+      pathPrefix={pathPrefix}
       error={badResponse ? new Error(responseStatus) : error}
       isLoading={isLoading}
     />
@@ -33,3 +33,26 @@ export interface LabelSet {
 export interface BlocksPool {
   [key: string]: Block[][];
 }
+
+export interface BlockFile {
+  name: string;
+  size: number;
+}
+
+export interface BlockMarker {
+  id: string;
+  version: number;
+  details?: string;
+  deletion_time?: number;
+  no_compact_time?: number;
+  reason?: string;
+}
+
+export interface BlockDetailsData {
+  meta: Block;
+  indexSize: number;
+  chunksSize: number;
+  files: BlockFile[];
+  deletionMark?: BlockMarker;
+  noCompactMark?: BlockMarker;
+}
@@ -1,4 +1,4 @@
-import { sortBlocks, isOverlapping } from './helpers';
+import { sortBlocks, isOverlapping, getBlocksByLabels, getLabelNames, getLabelValues, formatBytes } from './helpers';
 
 // Number of blocks in data: 8.
 const overlapCaseData = {
@@ -256,3 +256,43 @@ describe('isOverlapping helper', () => {
     expect(isOverlapping({ ...b, minTime: 10, maxTime: 20 }, { ...b, minTime: 20, maxTime: 30 })).toBe(false);
   });
 });
+
+describe('label filter helpers', () => {
+  const b = overlapCaseData.blocks[0];
+  const blocks = [
+    { ...b, ulid: '1', thanos: { ...b.thanos, labels: { tenant: 'team-a', replica: '0' } } },
+    { ...b, ulid: '2', thanos: { ...b.thanos, labels: { tenant: 'team-b', replica: '0' } } },
+    { ...b, ulid: '3', thanos: { ...b.thanos, labels: { tenant: 'team-a', replica: '1', cluster: 'EU' } } },
+  ];
+  const ulids = (filtered: typeof blocks): string[] => filtered.map((block) => block.ulid);
+
+  it('should return all label names and values', () => {
+    expect(getLabelNames(blocks)).toEqual(['cluster', 'replica', 'tenant']);
+    expect(getLabelValues(blocks, 'tenant')).toEqual(['team-a', 'team-b']);
+    expect(getLabelValues(blocks, 'cluster')).toEqual(['EU']);
+  });
+
+  it('should return all blocks without filters', () => {
+    expect(getBlocksByLabels(blocks, '', '', '')).toEqual(blocks);
+    expect(getBlocksByLabels(blocks, ' ', 'tenant', '')).toEqual(blocks);
+  });
+
+  it('should search external labels case-insensitively', () => {
+    expect(ulids(getBlocksByLabels(blocks, 'team-a', '', ''))).toEqual(['1', '3']);
+    expect(ulids(getBlocksByLabels(blocks, 'cluster=eu', '', ''))).toEqual(['3']);
+    expect(ulids(getBlocksByLabels(blocks, 'team-a replica=0', '', ''))).toEqual(['1']);
+  });
+
+  it('should filter by label value', () => {
+    expect(ulids(getBlocksByLabels(blocks, '', 'tenant', 'team-b'))).toEqual(['2']);
+    expect(ulids(getBlocksByLabels(blocks, 'replica=1', 'tenant', 'team-a'))).toEqual(['3']);
+  });
+});
+
+describe('formatBytes helper', () => {
+  it('should format sizes with binary units', () => {
+    expect(formatBytes(512)).toBe('512 B');
+    expect(formatBytes(1536)).toBe('1.50 KiB');
+    expect(formatBytes(3 * 1024 * 1024 * 1024)).toBe('3.00 GiB');
+  });
+});
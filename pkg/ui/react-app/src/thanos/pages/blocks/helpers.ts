@@ -146,3 +146,52 @@ export const getBlocksByCompactionLevel = (blocks: Block[], compactionLevel: num
   const blockResult = blocks.filter((block) => block.compaction.level === compactionLevel);
   return blockResult;
 };
+
+export const getLabelNames = (blocks: Block[]): string[] => {
+  const names = new Set<string>();
+  blocks.forEach((block) => Object.keys(block.thanos.labels).forEach((name) => names.add(name)));
+  return Array.from(names).sort();
+};
+
+export const getLabelValues = (blocks: Block[], labelName: string): string[] => {
+  const values = new Set<string>();
+  blocks.forEach((block) => {
+    const value = block.thanos.labels[labelName];
+    if (value !== undefined) {
+      values.add(value);
+    }
+  });
+  return Array.from(values).sort();
+};
+
+export const getBlocksByLabels = (blocks: Block[], search: string, labelName: string, labelValue: string): Block[] => {
+  const terms = search
+    .toLowerCase()
+    .split(/\s+/)
+    .filter((term) => term !== '');
+  if (terms.length === 0 && (labelName === '' || labelValue === '')) {
+    return blocks;
+  }
+
+  return blocks.filter((block) => {
+    const labels = block.thanos.labels;
+    if (labelName !== '' && labelValue !== '' && labels[labelName] !== labelValue) {
+      return false;
+    }
+    const text = Object.entries(labels)
+      .map(([key, value]) => `${key}=${value}`)
+      .join(' ')
+      .toLowerCase();
+    return terms.every((term) => text.includes(term));
+  });
+};
+
+export const formatBytes = (bytes: number): string => {
+  const units = ['B', 'KiB', 'MiB', 'GiB', 'TiB'];
+  let i = 0;
+  while (bytes >= 1024 && i < units.length - 1) {
+    bytes /= 1024;
+    i++;
+  }
+  return `${i === 0 ? bytes : bytes.toFixed(2)} ${units[i]}`;
+};
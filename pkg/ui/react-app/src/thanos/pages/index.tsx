@@ -1,5 +1,6 @@
 import Stores from './stores/Stores';
 import ErrorBoundary from './errorBoundary/ErrorBoundary';
 import Blocks from './blocks/Blocks';
+import BlockPage from './blocks/BlockPage';
 
-export { ErrorBoundary, Stores, Blocks };
+export { ErrorBoundary, Stores, Blocks, BlockPage };
@@ -27,6 +27,7 @@ var reactRouterPaths = []string{
 	"/",
 	"/alerts",
 	"/blocks",
+	"/blocks/:ulid",
 	"/config",
 	"/flags",
 	"/global",
@@ -0,0 +1,128 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package verifier
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+// OutOfOrderChunks verifies that the chunks of each series in block indexes are ordered by time and don't overlap,
+// which is a known symptom of corrupted uploads.
+// If repair is enabled, the affected blocks are rewritten with the chunks of each series ordered by time and the
+// overlapping chunks merged. The repaired block is uploaded to the bucket, and only then the original block is backed
+// up and deleted, or marked for deletion with a delete delay.
+type OutOfOrderChunks struct{}
+
+func (OutOfOrderChunks) IssueID() string { return "out_of_order_chunks" }
+
+func (OutOfOrderChunks) VerifyRepair(ctx Context, idMatcher func(ulid.ULID) bool, repair bool) error {
+	level.Info(ctx.Logger).Log("msg", "started verifying issue", "with-repair", repair)
+
+	metas, _, err := ctx.Fetcher.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	var found, repaired int
+	for id, meta := range metas {
+		if idMatcher != nil && !idMatcher(id) {
+			continue
+		}
+
+		affected, err := verifyOutOfOrderChunks(ctx, id, meta, repair)
+		if affected {
+			found++
+		}
+		if err != nil {
+			level.Error(ctx.Logger).Log("msg", "could not verify or repair out-of-order chunks", "id", id, "err", err)
+			continue
+		}
+		if affected && repair {
+			repaired++
+		}
+	}
+
+	level.Info(ctx.Logger).Log("msg", "verified issue", "with-repair", repair, "affected-blocks", found, "repaired-blocks", repaired)
+	return nil
+}
+
+// verifyOutOfOrderChunks returns true if the block has out-of-order or overlapping chunks, and repairs it if enabled.
+func verifyOutOfOrderChunks(ctx Context, id ulid.ULID, meta *metadata.Meta, repair bool) (affected bool, err error) {
+	tmpdir, err := ioutil.TempDir("", fmt.Sprintf("out-of-order-chunks-block-%s-", id))
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpdir); err != nil {
+			level.Warn(ctx.Logger).Log("msg", "failed to delete dir", "tmpdir", tmpdir, "err", err)
+		}
+	}()
+
+	indexFn := filepath.Join(tmpdir, block.IndexFilename)
+	if err := objstore.DownloadFile(ctx, ctx.Logger, ctx.Bkt, path.Join(id.String(), block.IndexFilename), indexFn); err != nil {
+		return false, errors.Wrapf(err, "download index file %s", path.Join(id.String(), block.IndexFilename))
+	}
+	stats, err := block.GatherIndexHealthStats(ctx.Logger, indexFn, meta.MinTime, meta.MaxTime)
+	if err != nil {
+		return false, errors.Wrapf(err, "gather index issues %s", id)
+	}
+	if stats.OutOfOrderChunks == 0 && stats.DuplicatedChunks == 0 {
+		level.Debug(ctx.Logger).Log("msg", "no issue", "id", id)
+		return false, nil
+	}
+
+	level.Warn(ctx.Logger).Log("msg", "detected out-of-order chunks", "id", id, "err", stats.OutOfOrderChunksErr(), "duplicated-chunks", stats.DuplicatedChunks)
+	if !repair {
+		return true, nil
+	}
+
+	if meta.Thanos.Downsample.Resolution > 0 {
+		return true, errors.New("cannot repair downsampled blocks")
+	}
+
+	level.Info(ctx.Logger).Log("msg", "downloading block for repair", "id", id)
+	bdir := filepath.Join(tmpdir, id.String())
+	if err := block.Download(ctx, ctx.Logger, ctx.Bkt, id, bdir); err != nil {
+		return true, errors.Wrapf(err, "download block %s", id)
+	}
+
+	level.Info(ctx.Logger).Log("msg", "repairing block", "id", id)
+	resid, err := block.RepairOutOfOrderChunks(ctx.Logger, tmpdir, id, metadata.BucketRepairSource)
+	if err != nil {
+		return true, errors.Wrapf(err, "repair failed for block %s", id)
+	}
+	level.Info(ctx.Logger).Log("msg", "verifying repaired block", "id", id, "newID", resid)
+
+	resdir := filepath.Join(tmpdir, resid.String())
+	stats, err = block.GatherIndexHealthStats(ctx.Logger, filepath.Join(resdir, block.IndexFilename), meta.MinTime, meta.MaxTime)
+	if err != nil {
+		return true, errors.Wrapf(err, "gather index issues of repaired block %s", resid)
+	}
+	if stats.OutOfOrderChunks > 0 || stats.DuplicatedChunks > 0 {
+		return true, errors.Errorf("repaired block %s still has out-of-order chunks", resid)
+	}
+
+	level.Info(ctx.Logger).Log("msg", "uploading repaired block", "newID", resid)
+	if err := block.Upload(ctx, ctx.Logger, ctx.Bkt, resdir, metadata.NoneFunc); err != nil {
+		return true, errors.Wrapf(err, "upload of %s failed", resid)
+	}
+
+	level.Info(ctx.Logger).Log("msg", "safe deleting broken block", "id", id)
+	if err := BackupAndDeleteDownloaded(ctx, bdir, id); err != nil {
+		return true, errors.Wrapf(err, "safe deleting old block %s failed", id)
+	}
+	return true, nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package verifier
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/testutil"
+)
+
+func TestOutOfOrderChunks(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir("", "out-of-order-chunks-test")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(tmpDir)) }()
+
+	// A block whose series has out-of-order chunks, overlapping with each other.
+	id := ulid.MustNew(1, nil)
+	bdir := filepath.Join(tmpDir, id.String())
+	chunk := func(mint, maxt int64) chunks.Meta {
+		c := chunkenc.NewXORChunk()
+		app, err := c.Appender()
+		testutil.Ok(t, err)
+		for ts := mint; ts <= maxt; ts += 10 {
+			app.Append(ts, float64(ts))
+		}
+		return chunks.Meta{MinTime: mint, MaxTime: maxt, Chunk: c}
+	}
+	chks := []chunks.Meta{chunk(200, 290), chunk(0, 90), chunk(50, 140)}
+
+	cw, err := chunks.NewWriter(filepath.Join(bdir, block.ChunksDirname))
+	testutil.Ok(t, err)
+	testutil.Ok(t, cw.WriteChunks(chks...))
+	testutil.Ok(t, cw.Close())
+
+	lset := labels.FromStrings("a", "1")
+	iw, err := index.NewWriter(ctx, filepath.Join(bdir, block.IndexFilename))
+	testutil.Ok(t, err)
+	for _, s := range []string{"1", "a"} {
+		testutil.Ok(t, iw.AddSymbol(s))
+	}
+	testutil.Ok(t, iw.AddSeries(0, lset, chks...))
+	testutil.Ok(t, iw.Close())
+
+	meta := metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{
+			ULID:    id,
+			MinTime: 0,
+			MaxTime: 300,
+			Version: metadata.TSDBVersion1,
+			Stats:   tsdb.BlockStats{NumSeries: 1, NumChunks: 3, NumSamples: 30},
+		},
+		Thanos: metadata.Thanos{Labels: map[string]string{"replica": "a"}, Source: metadata.TestSource},
+	}
+	testutil.Ok(t, meta.WriteToDir(logger, bdir))
+
+	bkt := objstore.NewInMemBucket()
+	backupBkt, err := objstore.NewTenantBucket(bkt, "backup")
+	testutil.Ok(t, err)
+	testutil.Ok(t, block.Upload(ctx, logger, bkt, bdir, metadata.NoneFunc))
+
+	fetcher, err := block.NewMetaFetcher(logger, 1, objstore.WithNoopInstr(bkt), "", nil, nil, nil)
+	testutil.Ok(t, err)
+	vctx := Context{
+		Context:     ctx,
+		Logger:      logger,
+		Bkt:         bkt,
+		BackupBkt:   backupBkt,
+		Fetcher:     fetcher,
+		DeleteDelay: 1,
+		metrics:     newVerifierMetrics(nil),
+	}
+
+	// Verification alone doesn't change the bucket.
+	objects := len(bkt.Objects())
+	testutil.Ok(t, OutOfOrderChunks{}.VerifyRepair(vctx, nil, false))
+	testutil.Equals(t, objects, len(bkt.Objects()))
+
+	testutil.Ok(t, OutOfOrderChunks{}.VerifyRepair(vctx, nil, true))
+
+	// The original block is backed up and marked for deletion.
+	ok, err := bkt.Exists(ctx, path.Join(id.String(), metadata.DeletionMarkFilename))
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok)
+	ok, err = TSDBBlockExistsInBucket(ctx, backupBkt, id)
+	testutil.Ok(t, err)
+	testutil.Assert(t, ok)
+
+	// The repaired block has the chunks of the series in order, with the overlapping ones merged.
+	metas, _, err := fetcher.Fetch(ctx)
+	testutil.Ok(t, err)
+	var repaired *metadata.Meta
+	for mid, m := range metas {
+		if mid != id {
+			repaired = m
+		}
+	}
+	testutil.Assert(t, repaired != nil)
+	testutil.Equals(t, metadata.BucketRepairSource, repaired.Thanos.Source)
+	testutil.Equals(t, meta.Thanos.Labels, repaired.Thanos.Labels)
+	testutil.Equals(t, uint64(25), repaired.Stats.NumSamples)
+
+	rdir := filepath.Join(tmpDir, repaired.ULID.String())
+	testutil.Ok(t, block.Download(ctx, logger, bkt, repaired.ULID, rdir))
+	testutil.Ok(t, block.VerifyIndex(logger, filepath.Join(rdir, block.IndexFilename), repaired.MinTime, repaired.MaxTime))
+
+	// Repaired blocks aren't repaired again.
+	testutil.Ok(t, OutOfOrderChunks{}.VerifyRepair(vctx, func(mid ulid.ULID) bool { return mid == repaired.ULID }, true))
+	metas, _, err = fetcher.Fetch(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(metas))
+}
@@ -17,7 +17,7 @@ import (
 )
 
 // OverlappedBlocksIssue checks bucket for blocks with overlapped time ranges.
-// No repair is available for this issue.
+// See OverlappedBlocksRepair for the repair of this issue.
 type OverlappedBlocksIssue struct{}
 
 func (OverlappedBlocksIssue) IssueID() string { return "overlapped_blocks" }
@@ -0,0 +1,238 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package verifier
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/compact"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+)
+
+// OverlappedBlocksRepair checks bucket for blocks with overlapped time ranges within the same group.
+// If repair is enabled, exact duplicates in each set of overlapping blocks are safely deleted and the remaining
+// blocks are merged into a single block with vertical compaction, after all of them are backed up. Downsampled
+// blocks are only reported, as their aggregated chunks can't be merged.
+type OverlappedBlocksRepair struct{}
+
+func (OverlappedBlocksRepair) IssueID() string { return "overlapped_blocks_repair" }
+
+func (OverlappedBlocksRepair) VerifyRepair(ctx Context, idMatcher func(ulid.ULID) bool, repair bool) error {
+	if idMatcher != nil {
+		return errors.Errorf("id matching is not supported")
+	}
+
+	level.Info(ctx.Logger).Log("msg", "started verifying issue", "with-repair", repair)
+
+	metas, _, err := ctx.Fetcher.Fetch(ctx)
+	if err != nil {
+		return errors.Wrap(err, "fetch metas")
+	}
+
+	groups := map[string][]*metadata.Meta{}
+	for _, meta := range metas {
+		groupKey := compact.DefaultGroupKey(meta.Thanos)
+		groups[groupKey] = append(groups[groupKey], meta)
+	}
+
+	var merged, removed int
+	for k, groupMetas := range groups {
+		for _, overlapping := range overlappingSets(groupMetas) {
+			toKill, toMerge := splitDuplicates(overlapping)
+			level.Warn(ctx.Logger).Log("msg", "found overlapped blocks", "group", k, "overlap", sprintMetas(blockMetas(overlapping)),
+				"duplicates", sprintMetas(blockMetas(toKill)), "to-merge", len(toMerge))
+
+			if len(toMerge) > 1 && toMerge[0].Thanos.Downsample.Resolution > 0 {
+				level.Warn(ctx.Logger).Log("msg", "overlapped blocks are downsampled and can't be merged, only duplicates can be removed", "group", k)
+				toMerge = nil
+			}
+			if !repair {
+				continue
+			}
+
+			for _, m := range toKill {
+				if err := BackupAndDelete(ctx, m.ULID); err != nil {
+					return errors.Wrapf(err, "remove duplicated block %s", m.ULID)
+				}
+				level.Info(ctx.Logger).Log("msg", "removed duplicated block", "id", m.ULID)
+				removed++
+			}
+
+			if len(toMerge) < 2 {
+				continue
+			}
+			if err := mergeOverlapped(ctx, toMerge); err != nil {
+				return errors.Wrapf(err, "merge overlapped blocks of group %s", k)
+			}
+			merged++
+		}
+	}
+
+	level.Info(ctx.Logger).Log("msg", "verified issue", "with-repair", repair, "removed-duplicates", removed, "merged-overlaps", merged)
+	return nil
+}
+
+// overlappingSets returns the sets of blocks which overlap with each other, transitively, in the given group.
+// Blocks which don't overlap with any other block are not included.
+func overlappingSets(metas []*metadata.Meta) (res [][]*metadata.Meta) {
+	sorted := make([]*metadata.Meta, len(metas))
+	copy(sorted, metas)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].MinTime == sorted[j].MinTime {
+			return sorted[i].ULID.Compare(sorted[j].ULID) < 0
+		}
+		return sorted[i].MinTime < sorted[j].MinTime
+	})
+
+	var (
+		cur  []*metadata.Meta
+		maxt int64
+	)
+	for _, m := range sorted {
+		if len(cur) > 0 && m.MinTime < maxt {
+			cur = append(cur, m)
+			if m.MaxTime > maxt {
+				maxt = m.MaxTime
+			}
+			continue
+		}
+		if len(cur) > 1 {
+			res = append(res, cur)
+		}
+		cur, maxt = []*metadata.Meta{m}, m.MaxTime
+	}
+	if len(cur) > 1 {
+		res = append(res, cur)
+	}
+	return res
+}
+
+// splitDuplicates returns the blocks which duplicate another one of the given blocks, and the unique ones, which
+// include the first block of each set of duplicates.
+func splitDuplicates(metas []*metadata.Meta) (dups, unique []*metadata.Meta) {
+	byID := make(map[ulid.ULID]*metadata.Meta, len(metas))
+	for _, m := range metas {
+		byID[m.ULID] = m
+	}
+
+	isDup := map[ulid.ULID]struct{}{}
+	for _, d := range duplicatedBlocks(blockMetas(metas)) {
+		for _, m := range d[1:] {
+			isDup[m.ULID] = struct{}{}
+			dups = append(dups, byID[m.ULID])
+		}
+	}
+	for _, m := range metas {
+		if _, ok := isDup[m.ULID]; !ok {
+			unique = append(unique, m)
+		}
+	}
+	return dups, unique
+}
+
+func blockMetas(metas []*metadata.Meta) []tsdb.BlockMeta {
+	res := make([]tsdb.BlockMeta, 0, len(metas))
+	for _, m := range metas {
+		res = append(res, m.BlockMeta)
+	}
+	return res
+}
+
+// mergeOverlapped downloads and backs up the given overlapping blocks, merges them into a new block which is
+// uploaded to the bucket, and only then deletes the merged blocks from it.
+func mergeOverlapped(ctx Context, metas []*metadata.Meta) error {
+	tmpdir, err := ioutil.TempDir("", "overlapped-blocks-repair-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpdir); err != nil {
+			level.Warn(ctx.Logger).Log("msg", "failed to delete dir", "tmpdir", tmpdir, "err", err)
+		}
+	}()
+
+	var (
+		dirs       []string
+		mint, maxt = metas[0].MinTime, metas[0].MaxTime
+	)
+	for _, m := range metas {
+		found, err := TSDBBlockExistsInBucket(ctx, ctx.BackupBkt, m.ULID)
+		if err != nil {
+			return err
+		}
+		if found {
+			return errors.Errorf("%s dir seems to exists in backup bucket. Remove this block manually if you are sure it is safe to do", m.ULID)
+		}
+
+		bdir := filepath.Join(tmpdir, m.ULID.String())
+		level.Info(ctx.Logger).Log("msg", "downloading block to be merged", "id", m.ULID)
+		if err := block.Download(ctx, ctx.Logger, ctx.Bkt, m.ULID, bdir); err != nil {
+			return errors.Wrapf(err, "download block %s", m.ULID)
+		}
+		if err := backupDownloaded(ctx, ctx.Logger, bdir, ctx.BackupBkt, m.ULID); err != nil {
+			return err
+		}
+		dirs = append(dirs, bdir)
+
+		if m.MinTime < mint {
+			mint = m.MinTime
+		}
+		if m.MaxTime > maxt {
+			maxt = m.MaxTime
+		}
+	}
+
+	comp, err := tsdb.NewLeveledCompactor(ctx, nil, ctx.Logger, []int64{maxt - mint}, downsample.NewPool(), storage.NewCompactingChunkSeriesMerger(storage.ChainedSeriesMerge))
+	if err != nil {
+		return errors.Wrap(err, "create compactor")
+	}
+	resid, err := comp.Compact(tmpdir, dirs, nil)
+	if err != nil {
+		return errors.Wrapf(err, "compact blocks %v", dirs)
+	}
+
+	if resid == (ulid.ULID{}) {
+		level.Info(ctx.Logger).Log("msg", "merged block would have no samples, deleting overlapped blocks", "blocks", sprintMetas(blockMetas(metas)))
+	} else {
+		bdir := filepath.Join(tmpdir, resid.String())
+		newMeta, err := metadata.InjectThanos(ctx.Logger, bdir, metadata.Thanos{
+			Labels:       metas[0].Thanos.Labels,
+			Downsample:   metas[0].Thanos.Downsample,
+			Source:       metadata.BucketRepairSource,
+			SegmentFiles: block.GetSegmentFiles(bdir),
+		}, nil)
+		if err != nil {
+			return errors.Wrapf(err, "failed to finalize the block %s", bdir)
+		}
+		if err := os.Remove(filepath.Join(bdir, "tombstones")); err != nil {
+			return errors.Wrap(err, "remove tombstones")
+		}
+		if err := block.VerifyIndex(ctx.Logger, filepath.Join(bdir, block.IndexFilename), newMeta.MinTime, newMeta.MaxTime); err != nil {
+			return errors.Wrapf(err, "merged block is invalid %s", resid)
+		}
+
+		level.Info(ctx.Logger).Log("msg", "uploading merged block", "newID", resid, "blocks", sprintMetas(blockMetas(metas)))
+		if err := block.Upload(ctx, ctx.Logger, ctx.Bkt, bdir, metadata.NoneFunc); err != nil {
+			return errors.Wrapf(err, "upload of %s failed", resid)
+		}
+	}
+
+	for _, m := range metas {
+		if err := deleteBackedUp(ctx, m.ULID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package verifier
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/testutil"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
+)
+
+func TestOverlappingSets(t *testing.T) {
+	meta := func(id uint64, mint, maxt int64) *metadata.Meta {
+		return &metadata.Meta{BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(id, nil), MinTime: mint, MaxTime: maxt}}
+	}
+	b1, b2, b3, b4, b5 := meta(1, 0, 10), meta(2, 5, 20), meta(3, 15, 30), meta(4, 30, 40), meta(5, 35, 36)
+
+	testutil.Equals(t, [][]*metadata.Meta{{b1, b2, b3}, {b4, b5}}, overlappingSets([]*metadata.Meta{b5, b3, b4, b1, b2}))
+	testutil.Equals(t, 0, len(overlappingSets([]*metadata.Meta{b1, b3, b4})))
+}
+
+func TestOverlappedBlocksRepair(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	tmpDir, err := ioutil.TempDir("", "overlapped-blocks-repair-test")
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, os.RemoveAll(tmpDir)) }()
+
+	bkt := objstore.NewInMemBucket()
+	backupBkt, err := objstore.NewTenantBucket(bkt, "backup")
+	testutil.Ok(t, err)
+
+	extLset := labels.FromStrings("replica", "a")
+	var ids []ulid.ULID
+	for _, b := range []struct {
+		series     []labels.Labels
+		mint, maxt int64
+	}{
+		{series: []labels.Labels{labels.FromStrings("a", "1"), labels.FromStrings("a", "2")}, mint: 0, maxt: 100},
+		{series: []labels.Labels{labels.FromStrings("a", "2"), labels.FromStrings("a", "3")}, mint: 50, maxt: 150},
+		{series: []labels.Labels{labels.FromStrings("a", "4")}, mint: 200, maxt: 300},
+	} {
+		id, err := e2eutil.CreateBlock(ctx, tmpDir, b.series, 10, b.mint, b.maxt, extLset, 0, metadata.NoneFunc)
+		testutil.Ok(t, err)
+		testutil.Ok(t, block.Upload(ctx, logger, bkt, filepath.Join(tmpDir, id.String()), metadata.NoneFunc))
+		ids = append(ids, id)
+	}
+
+	fetcher, err := block.NewMetaFetcher(logger, 1, objstore.WithNoopInstr(bkt), "", nil, nil, nil)
+	testutil.Ok(t, err)
+	vctx := Context{
+		Context:   ctx,
+		Logger:    logger,
+		Bkt:       bkt,
+		BackupBkt: backupBkt,
+		Fetcher:   fetcher,
+		metrics:   newVerifierMetrics(nil),
+	}
+
+	// Verification alone doesn't change the bucket.
+	objects := len(bkt.Objects())
+	testutil.Ok(t, OverlappedBlocksRepair{}.VerifyRepair(vctx, nil, false))
+	testutil.Equals(t, objects, len(bkt.Objects()))
+
+	testutil.Ok(t, OverlappedBlocksRepair{}.VerifyRepair(vctx, nil, true))
+
+	metas, _, err := fetcher.Fetch(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(metas))
+	testutil.Assert(t, metas[ids[2]] != nil, "non-overlapping block must be kept")
+
+	for id, m := range metas {
+		if id == ids[2] {
+			continue
+		}
+		testutil.Equals(t, int64(0), m.MinTime)
+		testutil.Equals(t, int64(150), m.MaxTime)
+		testutil.Equals(t, uint64(3), m.Stats.NumSeries)
+		testutil.Equals(t, []ulid.ULID{ids[0], ids[1]}, m.Compaction.Sources)
+		testutil.Equals(t, extLset.Map(), m.Thanos.Labels)
+		testutil.Equals(t, metadata.BucketRepairSource, m.Thanos.Source)
+	}
+
+	// The merged blocks are backed up under the prefix.
+	for _, id := range ids[:2] {
+		ok, err := TSDBBlockExistsInBucket(ctx, backupBkt, id)
+		testutil.Ok(t, err)
+		testutil.Assert(t, ok, "block %s must be backed up", id)
+	}
+	testutil.Ok(t, OverlappedBlocksRepair{}.VerifyRepair(vctx, nil, true))
+}
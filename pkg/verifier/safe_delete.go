@@ -72,18 +72,7 @@ func BackupAndDelete(ctx Context, id ulid.ULID) error {
 	}
 
 	// Block uploaded, so we are ok to remove from src bucket.
-	if ctx.DeleteDelay.Seconds() == 0 {
-		level.Info(ctx.Logger).Log("msg", "Deleting block", "id", id.String())
-		if err := block.Delete(ctx, ctx.Logger, ctx.Bkt, id); err != nil {
-			return errors.Wrap(err, "delete from source")
-		}
-	}
-
-	level.Info(ctx.Logger).Log("msg", "Marking block as deleted", "id", id.String())
-	if err := block.MarkForDeletion(ctx, ctx.Logger, ctx.Bkt, id, "manual verify-repair", ctx.metrics.blocksMarkedForDeletion); err != nil {
-		return errors.Wrap(err, "marking delete from source")
-	}
-	return nil
+	return deleteBackedUp(ctx, id)
 }
 
 // BackupAndDeleteDownloaded works much like BackupAndDelete in that it will
@@ -108,6 +97,12 @@ func BackupAndDeleteDownloaded(ctx Context, bdir string, id ulid.ULID) error {
 	}
 
 	// Block uploaded, so we are ok to remove from src bucket.
+	return deleteBackedUp(ctx, id)
+}
+
+// deleteBackedUp removes a TSDB block which was backed up already from the source bucket, or marks it for deletion
+// if deleteDelay is non zero.
+func deleteBackedUp(ctx Context, id ulid.ULID) error {
 	if ctx.DeleteDelay.Seconds() == 0 {
 		level.Info(ctx.Logger).Log("msg", "Deleting block", "id", id.String())
 		if err := block.Delete(ctx, ctx.Logger, ctx.Bkt, id); err != nil {
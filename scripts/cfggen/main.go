@@ -21,6 +21,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/httpconfig"
 
 	"github.com/thanos-io/thanos/pkg/alert"
+	"github.com/thanos-io/thanos/pkg/audit"
 	"github.com/thanos-io/thanos/pkg/cacheutil"
 	"github.com/thanos-io/thanos/pkg/logging"
 	"github.com/thanos-io/thanos/pkg/objstore/azure"
@@ -31,13 +32,16 @@ import (
 	"github.com/thanos-io/thanos/pkg/objstore/gcs"
 	"github.com/thanos-io/thanos/pkg/objstore/oss"
 	"github.com/thanos-io/thanos/pkg/objstore/s3"
+	"github.com/thanos-io/thanos/pkg/objstore/sftp"
 	"github.com/thanos-io/thanos/pkg/objstore/swift"
+	"github.com/thanos-io/thanos/pkg/profiling"
 	"github.com/thanos-io/thanos/pkg/queryfrontend"
 	storecache "github.com/thanos-io/thanos/pkg/store/cache"
 	trclient "github.com/thanos-io/thanos/pkg/tracing/client"
 	"github.com/thanos-io/thanos/pkg/tracing/elasticapm"
 	"github.com/thanos-io/thanos/pkg/tracing/jaeger"
 	"github.com/thanos-io/thanos/pkg/tracing/lightstep"
+	"github.com/thanos-io/thanos/pkg/tracing/otlp"
 	"github.com/thanos-io/thanos/pkg/tracing/stackdriver"
 )
 
@@ -54,6 +58,7 @@ var (
 		client.ALIYUNOSS:  oss.Config{},
 		client.FILESYSTEM: filesystem.Config{},
 		client.BOS:        bos.Config{},
+		client.SFTP:       sftp.DefaultConfig,
 	}
 
 	tracingConfigs = map[trclient.TracingProvider]interface{}{
@@ -61,6 +66,14 @@ var (
 		trclient.STACKDRIVER: stackdriver.Config{},
 		trclient.ELASTIC_APM: elasticapm.Config{},
 		trclient.LIGHTSTEP:   lightstep.Config{},
+		trclient.OTLP:        otlp.DefaultConfig,
+	}
+	profilingConfigs = map[profiling.Provider]interface{}{
+		profiling.PYROSCOPE: profiling.DefaultPyroscopeConfig,
+		profiling.BUCKET: profiling.BucketConfig{
+			Prefix:   profiling.DefaultBucketConfig.Prefix,
+			Objstore: client.BucketConfig{Type: client.FILESYSTEM, Config: filesystem.Config{}},
+		},
 	}
 	indexCacheConfigs = map[storecache.IndexCacheProvider]interface{}{
 		storecache.INMEMORY:  storecache.InMemoryIndexCacheConfig{},
@@ -79,6 +92,10 @@ func init() {
 	configs = map[string]interface{}{}
 	configs[name(logging.RequestConfig{})] = logging.RequestConfig{}
 
+	auditCfg := audit.DefaultConfig
+	auditCfg.Objstore = client.BucketConfig{Type: client.FILESYSTEM, Config: filesystem.Config{}}
+	configs[name(audit.Config{})] = auditCfg
+
 	alertmgrCfg := alert.DefaultAlertmanagerConfig()
 	alertmgrCfg.EndpointsConfig.FileSDConfigs = []httpconfig.FileSDConfig{{}}
 	configs[name(alert.AlertingConfig{})] = alert.AlertingConfig{Alertmanagers: []alert.AlertmanagerConfig{alertmgrCfg}}
@@ -93,6 +110,12 @@ func init() {
 	for typ, config := range tracingConfigs {
 		configs[name(config)] = trclient.TracingConfig{Type: typ, Config: config}
 	}
+	for typ, config := range profilingConfigs {
+		profilingCfg := profiling.DefaultConfig
+		profilingCfg.Type = typ
+		profilingCfg.Config = config
+		configs[name(config)] = profilingCfg
+	}
 	for typ, config := range indexCacheConfigs {
 		configs[name(config)] = storecache.IndexCacheConfig{Type: typ, Config: config}
 	}
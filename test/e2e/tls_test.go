@@ -16,11 +16,14 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/fortytw2/leaktest"
 	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
@@ -58,7 +61,8 @@ func TestGRPCServerCertAutoRotate(t *testing.T) {
 	genCerts(t, certSrv, keySrv, caClt)
 	genCerts(t, certClt, keyClt, caSrv)
 
-	configSrv, err := thTLS.NewServerConfig(logger, certSrv, keySrv, caSrv)
+	reg := prometheus.NewRegistry()
+	configSrv, err := thTLS.NewServerConfig(logger, reg, certSrv, keySrv, caSrv)
 	testutil.Ok(t, err)
 
 	srv := grpc.NewServer(grpc.KeepaliveParams(keepalive.ServerParameters{MaxConnectionAge: 1 * time.Millisecond}), grpc.Creds(credentials.NewTLS(configSrv)))
@@ -98,6 +102,25 @@ func TestGRPCServerCertAutoRotate(t *testing.T) {
 	resp, err = clt.UnaryEcho(context.Background(), &pb.EchoRequest{Message: expMessage})
 	testutil.Ok(t, err)
 	testutil.Equals(t, expMessage, resp.Message)
+
+	// Rotate the client CA, sign the client certificate with the new one and check for a good state.
+	testutil.Ok(t, os.Remove(caSrv))
+	testutil.Ok(t, os.Remove(caSrv+".priv"))
+	genCerts(t, certClt, keyClt, caSrv)
+	time.Sleep(50 * time.Millisecond) // Wait for the server MaxConnectionAge to expire.
+	resp, err = clt.UnaryEcho(context.Background(), &pb.EchoRequest{Message: expMessage})
+	testutil.Ok(t, err)
+	testutil.Equals(t, expMessage, resp.Message)
+
+	// The server certificate was reloaded once, the rewritten and the new client CA twice.
+	testutil.Ok(t, promtest.GatherAndCompare(reg, strings.NewReader(`
+		# HELP thanos_tls_server_reload_failures_total Total number of failed reloads of the server certificate and client CA files after they changed.
+		# TYPE thanos_tls_server_reload_failures_total counter
+		thanos_tls_server_reload_failures_total 0
+		# HELP thanos_tls_server_reloads_total Total number of reloads of the server certificate and client CA files after they changed.
+		# TYPE thanos_tls_server_reloads_total counter
+		thanos_tls_server_reloads_total 3
+	`), "thanos_tls_server_reloads_total", "thanos_tls_server_reload_failures_total"))
 }
 
 var caRoot = &x509.Certificate{